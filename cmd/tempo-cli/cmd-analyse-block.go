@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dustin/go-humanize"
+	"github.com/google/uuid"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+type analyseBlockCmd struct {
+	backendOptions
+
+	TenantID string `arg:"" help:"tenant-id within the bucket"`
+	BlockID  string `arg:"" help:"block ID to analyse"`
+	Top      int    `help:"number of services, attributes and columns to list" default:"20"`
+}
+
+func (cmd *analyseBlockCmd) Run(ctx *globalOptions) error {
+	blockID, err := uuid.Parse(cmd.BlockID)
+	if err != nil {
+		return err
+	}
+
+	r, _, _, err := loadBackend(&cmd.backendOptions, ctx)
+	if err != nil {
+		return err
+	}
+
+	meta, err := r.BlockMeta(context.TODO(), blockID, cmd.TenantID)
+	if err != nil {
+		return err
+	}
+
+	block, err := encoding.OpenBlock(meta, r)
+	if err != nil {
+		return err
+	}
+
+	measurable, ok := block.(common.Measurable)
+	if !ok {
+		return fmt.Errorf("blocks of version %s have no stats", meta.Version)
+	}
+	stats, err := measurable.Stats(context.TODO())
+	if errors.Is(err, common.ErrUnsupported) {
+		return fmt.Errorf("block has no stats, it was written without parquet_stats")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("spans    :", humanize.Comma(int64(stats.Spans)))
+	fmt.Println("services :", len(stats.ServiceSpans))
+
+	fmt.Println()
+	fmt.Println("Spans by service:")
+	printTop([]string{"service", "spans"}, stats.ServiceSpans, cmd.Top, func(v uint64) string {
+		return fmt.Sprintf("%s (%d %%)", humanize.Comma(int64(v)), percent(v, stats.Spans))
+	})
+
+	fmt.Println()
+	fmt.Println("Estimated cardinality by attribute:")
+	printTop([]string{"attribute", "cardinality"}, stats.AttributeCardinality, cmd.Top, func(v uint64) string {
+		return humanize.Comma(int64(v))
+	})
+
+	var total uint64
+	for _, size := range stats.ColumnSizes {
+		total += size
+	}
+	fmt.Println()
+	fmt.Println("Size by column:")
+	printTop([]string{"column", "size"}, stats.ColumnSizes, cmd.Top, func(v uint64) string {
+		return fmt.Sprintf("%s (%d %%)", humanize.Bytes(v), percent(v, total))
+	})
+
+	return nil
+}
+
+// printTop prints the n largest values of m in descending order
+func printTop(header []string, m map[string]uint64, n int, format func(uint64) string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+
+	w := tablewriter.NewWriter(os.Stdout)
+	w.SetHeader(header)
+	for _, k := range keys {
+		w.Append([]string{k, format(m[k])})
+	}
+	w.Render()
+}
+
+func percent(v, total uint64) uint64 {
+	if total == 0 {
+		return 0
+	}
+	return v * 100 / total
+}
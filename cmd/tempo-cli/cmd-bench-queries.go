@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/grafana/tempo/pkg/util"
+)
+
+// loggedQuery is a line of a query log
+type loggedQuery struct {
+	// Query is the TraceQL text of the query
+	Query string `json:"query"`
+	// OffsetSeconds is when the query was run, relative to the start of the capture
+	OffsetSeconds float64 `json:"offset_seconds"`
+	// Tenant ran the query, the org id of the command is used if it is empty
+	Tenant string `json:"tenant"`
+	// RangeSeconds is the time range the query searched, the range of the command is used if it is 0
+	RangeSeconds float64 `json:"range_seconds"`
+}
+
+// queryResult is the outcome of a replayed query
+type queryResult struct {
+	query   string
+	latency time.Duration
+	err     error
+}
+
+type benchQueriesCmd struct {
+	APIEndpoint string `arg:"" help:"tempo api endpoint of the cluster to replay the queries against"`
+	LogFile     string `arg:"" type:"path" help:"query log with a json object per line holding the query and its offset_seconds"`
+
+	OrgID       string        `help:"orgID of the queries without a tenant"`
+	Speed       float64       `help:"replay speed relative to the capture, 2 replays twice as fast. 0 sends the queries back to back" default:"1"`
+	Range       time.Duration `help:"time range ending now searched by the queries without a range" default:"1h"`
+	MaxInFlight int           `help:"max queries in flight, later queries are delayed until a query completes" default:"20"`
+	Top         int           `help:"number of distinct queries listed with their latencies, slowest first" default:"10"`
+}
+
+func (cmd *benchQueriesCmd) Run(_ *globalOptions) error {
+	if cmd.Speed < 0 {
+		return errors.New("speed must not be negative")
+	}
+	if cmd.MaxInFlight <= 0 {
+		return errors.New("max in flight must be positive")
+	}
+	if cmd.Top < 0 {
+		return errors.New("top must not be negative")
+	}
+
+	queries, err := readQueryLog(cmd.LogFile)
+	if err != nil {
+		return err
+	}
+	if len(queries) == 0 {
+		return fmt.Errorf("no queries in %s", cmd.LogFile)
+	}
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+	level.Info(logger).Log("msg", "replaying queries", "queries", len(queries), "endpoint", cmd.APIEndpoint)
+
+	start := time.Now()
+	results := cmd.replay(queries)
+	elapsed := time.Since(start)
+
+	printBenchResults(os.Stdout, logger, results, elapsed, cmd.Top)
+	return nil
+}
+
+// replay sends the queries at their offsets scaled by the speed and returns their results in log order
+func (cmd *benchQueriesCmd) replay(queries []loggedQuery) []queryResult {
+	var (
+		results  = make([]queryResult, len(queries))
+		inFlight = make(chan struct{}, cmd.MaxInFlight)
+		clients  = map[string]*util.Client{}
+		wg       sync.WaitGroup
+	)
+
+	first := queries[0].OffsetSeconds
+	start := time.Now()
+	for i, q := range queries {
+		if cmd.Speed > 0 {
+			offset := time.Duration((q.OffsetSeconds - first) / cmd.Speed * float64(time.Second))
+			time.Sleep(time.Until(start.Add(offset)))
+		}
+
+		tenant := q.Tenant
+		if tenant == "" {
+			tenant = cmd.OrgID
+		}
+		client, ok := clients[tenant]
+		if !ok {
+			client = util.NewClient(cmd.APIEndpoint, tenant)
+			clients[tenant] = client
+		}
+
+		queryRange := cmd.Range
+		if q.RangeSeconds > 0 {
+			queryRange = time.Duration(q.RangeSeconds * float64(time.Second))
+		}
+
+		inFlight <- struct{}{}
+		wg.Add(1)
+		go func(i int, q loggedQuery) {
+			defer func() {
+				<-inFlight
+				wg.Done()
+			}()
+
+			now := time.Now()
+			_, err := client.SearchTraceQLWithRange(q.Query, now.Add(-queryRange).Unix(), now.Unix())
+			results[i] = queryResult{
+				query:   q.Query,
+				latency: time.Since(now),
+				err:     err,
+			}
+		}(i, q)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// readQueryLog reads the queries of a log ordered by their offset
+func readQueryLog(path string) ([]loggedQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open query log %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var queries []loggedQuery
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var q loggedQuery
+		err = json.Unmarshal(scanner.Bytes(), &q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of query log %s: %w", line, path, err)
+		}
+		if q.Query == "" {
+			return nil, fmt.Errorf("line %d of query log %s has no query", line, path)
+		}
+		queries = append(queries, q)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read query log %s: %w", path, err)
+	}
+
+	sort.SliceStable(queries, func(i, j int) bool { return queries[i].OffsetSeconds < queries[j].OffsetSeconds })
+	return queries, nil
+}
+
+// latencyStats is the latency distribution of a set of query results
+type latencyStats struct {
+	name               string
+	count, errors      int
+	p50, p90, p99, max time.Duration
+}
+
+func newLatencyStats(name string, results []queryResult) latencyStats {
+	s := latencyStats{name: name, count: len(results)}
+
+	latencies := make([]time.Duration, 0, len(results))
+	for _, r := range results {
+		if r.err != nil {
+			s.errors++
+			continue
+		}
+		latencies = append(latencies, r.latency)
+	}
+	if len(latencies) == 0 {
+		return s
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		// nearest rank
+		idx := int(p*float64(len(latencies))+0.5) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= len(latencies) {
+			idx = len(latencies) - 1
+		}
+		return latencies[idx]
+	}
+	s.p50 = percentile(.5)
+	s.p90 = percentile(.9)
+	s.p99 = percentile(.99)
+	s.max = latencies[len(latencies)-1]
+	return s
+}
+
+// printBenchResults writes the latency distribution of all queries and of the slowest distinct queries as a table
+func printBenchResults(out io.Writer, logger log.Logger, results []queryResult, elapsed time.Duration, top int) {
+	byQuery := map[string][]queryResult{}
+	var (
+		failed   int
+		firstErr error
+	)
+	for _, r := range results {
+		byQuery[r.query] = append(byQuery[r.query], r)
+		if r.err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = r.err
+			}
+		}
+	}
+
+	perQuery := make([]latencyStats, 0, len(byQuery))
+	for q, rs := range byQuery {
+		perQuery = append(perQuery, newLatencyStats(q, rs))
+	}
+	sort.Slice(perQuery, func(i, j int) bool {
+		if perQuery[i].p99 != perQuery[j].p99 {
+			return perQuery[i].p99 > perQuery[j].p99
+		}
+		return perQuery[i].name < perQuery[j].name
+	})
+	if len(perQuery) > top {
+		perQuery = perQuery[:top]
+	}
+
+	w := tablewriter.NewWriter(out)
+	w.SetHeader([]string{"query", "count", "errors", "p50", "p90", "p99", "max"})
+	w.SetAutoWrapText(false)
+	appendStats := func(s latencyStats) {
+		w.Append([]string{s.name, fmt.Sprint(s.count), fmt.Sprint(s.errors), s.p50.String(), s.p90.String(), s.p99.String(), s.max.String()})
+	}
+	appendStats(newLatencyStats("all", results))
+	for _, s := range perQuery {
+		appendStats(s)
+	}
+	w.Render()
+
+	level.Info(logger).Log("msg", "replayed queries", "queries", len(results), "distinct", len(byQuery), "elapsed", elapsed.Round(time.Millisecond))
+	if firstErr != nil {
+		level.Warn(logger).Log("msg", "queries failed", "failed", failed, "err", firstErr)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadQueryLog(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "queries.log")
+	require.NoError(t, os.WriteFile(logFile, []byte(`{"query": "{ .b }", "offset_seconds": 2, "tenant": "t1"}
+
+{"query": "{ .a }", "offset_seconds": 1, "range_seconds": 60}
+{"query": "{ .c }", "offset_seconds": 2}
+`), 0o644))
+
+	queries, err := readQueryLog(logFile)
+	require.NoError(t, err)
+	assert.Equal(t, []loggedQuery{
+		{Query: "{ .a }", OffsetSeconds: 1, RangeSeconds: 60},
+		{Query: "{ .b }", OffsetSeconds: 2, Tenant: "t1"},
+		{Query: "{ .c }", OffsetSeconds: 2},
+	}, queries)
+
+	require.NoError(t, os.WriteFile(logFile, []byte(`{"offset_seconds": 1}`), 0o644))
+	_, err = readQueryLog(logFile)
+	assert.EqualError(t, err, "line 1 of query log "+logFile+" has no query")
+}
+
+func TestNewLatencyStats(t *testing.T) {
+	var results []queryResult
+	for i := 1; i <= 100; i++ {
+		results = append(results, queryResult{latency: time.Duration(i) * time.Millisecond})
+	}
+	results = append(results, queryResult{err: assert.AnError})
+
+	s := newLatencyStats("all", results)
+	assert.Equal(t, latencyStats{
+		name:   "all",
+		count:  101,
+		errors: 1,
+		p50:    50 * time.Millisecond,
+		p90:    90 * time.Millisecond,
+		p99:    99 * time.Millisecond,
+		max:    100 * time.Millisecond,
+	}, s)
+}
+
+func TestBenchQueriesReplay(t *testing.T) {
+	var (
+		mtx     sync.Mutex
+		tenants []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+		mtx.Unlock()
+
+		if strings.Contains(r.URL.Query().Get("q"), "fail") {
+			http.Error(w, "failed", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"traces": []}`))
+	}))
+	defer srv.Close()
+
+	cmd := &benchQueriesCmd{
+		APIEndpoint: srv.URL,
+		OrgID:       "default",
+		Range:       time.Hour,
+		MaxInFlight: 2,
+	}
+	results := cmd.replay([]loggedQuery{
+		{Query: "{ .a }"},
+		{Query: "{ .fail }", Tenant: "t1"},
+		{Query: "{ .a }"},
+	})
+	require.Len(t, results, 3)
+	assert.NoError(t, results[0].err)
+	assert.Error(t, results[1].err)
+	assert.NoError(t, results[2].err)
+	assert.ElementsMatch(t, []string{"default", "t1", "default"}, tenants)
+
+	var out, logs bytes.Buffer
+	printBenchResults(&out, log.NewLogfmtLogger(&logs), results, time.Second, 1)
+	assert.Contains(t, out.String(), "all")
+	assert.Contains(t, logs.String(), `level=info msg="replayed queries" queries=3 distinct=2`)
+	assert.Contains(t, logs.String(), `level=warn msg="queries failed" failed=1`)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+type convertBlockCmd struct {
+	backendOptions
+
+	TenantID   string `arg:"" help:"tenant-id within the bucket"`
+	BlockID    string `arg:"" help:"block ID to convert"`
+	Version    string `arg:"" help:"block version to convert to" enum:"v2,vParquet,vParquet2"`
+	KeepSource bool   `help:"don't mark the converted block compacted, queriers then see the traces of both blocks"`
+}
+
+func (cmd *convertBlockCmd) Run(opts *globalOptions) error {
+	blockID, err := uuid.Parse(cmd.BlockID)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	blockCfg := *cfg.StorageConfig.Trace.Block
+	blockCfg.Version = cmd.Version
+	if err := common.ValidateConfig(&blockCfg); err != nil {
+		return err
+	}
+
+	r, w, c, err := loadBackend(&cmd.backendOptions, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	meta, err := r.BlockMeta(ctx, blockID, cmd.TenantID)
+	if err != nil {
+		return err
+	}
+	if meta.Version == cmd.Version {
+		return fmt.Errorf("block %s is already a %s block", meta.BlockID, meta.Version)
+	}
+
+	newMeta, err := encoding.ConvertBlock(ctx, &blockCfg, meta, cmd.Version, r, w)
+	if err != nil {
+		return err
+	}
+
+	// traces deleted from the block stay deleted in the new block
+	t, err := tempodb.ReadTombstones(ctx, r, meta)
+	if err != nil {
+		return err
+	}
+	if len(t.TraceIDs) > 0 {
+		ids := make([]common.ID, 0, len(t.TraceIDs))
+		for _, id := range t.TraceIDs {
+			b, err := hex.DecodeString(id)
+			if err != nil {
+				return fmt.Errorf("invalid tombstone %s: %w", id, err)
+			}
+			ids = append(ids, b)
+		}
+		if err := tempodb.WriteTombstones(ctx, r, w, newMeta, ids); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("converted %s block %s to %s block %s with %d objects\n", meta.Version, meta.BlockID, newMeta.Version, newMeta.BlockID, newMeta.TotalObjects)
+
+	if cmd.KeepSource {
+		return nil
+	}
+	if err := c.MarkBlockCompacted(meta.BlockID, meta.TenantID); err != nil {
+		return err
+	}
+	fmt.Println("block", meta.BlockID, "marked compacted")
+
+	return nil
+}
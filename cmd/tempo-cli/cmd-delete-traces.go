@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/grafana/tempo/pkg/boundedwaitgroup"
+	"github.com/grafana/tempo/pkg/model/trace"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/traceql"
+	"github.com/grafana/tempo/tempodb"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+type deleteTracesCmd struct {
+	backendOptions
+
+	TenantID string `arg:"" help:"tenant-id within the bucket"`
+	Query    string `arg:"" help:"TraceQL spanset filter selecting the traces to delete, i.e. { .service.name = \"foo\" && status = error }"`
+	Start    string `arg:"" help:"start of time range to search (YYYY-MM-DDThh:mm:ss)"`
+	End      string `arg:"" help:"end of time range to search (YYYY-MM-DDThh:mm:ss)"`
+	Confirm  bool   `help:"write tombstones for the listed traces, without it the matching traces are only listed"`
+}
+
+func (cmd *deleteTracesCmd) Run(opts *globalOptions) error {
+	filter, err := newDeleteFilter(cmd.Query)
+	if err != nil {
+		return err
+	}
+
+	startTime, err := time.Parse(layoutString, cmd.Start)
+	if err != nil {
+		return err
+	}
+	endTime, err := time.Parse(layoutString, cmd.End)
+	if err != nil {
+		return err
+	}
+
+	r, w, _, err := loadBackend(&cmd.backendOptions, opts)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	blockmetas, err := blocksInRange(ctx, r, cmd.TenantID, startTime, endTime)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Blocks In Range:", len(blockmetas))
+
+	searchOpts := common.SearchOptions{}
+	tempodb.SearchConfig{}.ApplyToOptions(&searchOpts)
+
+	// search each block for candidates and match the filter on the trace parts they contain
+	blocks := make([]common.BackendBlock, 0, len(blockmetas))
+	matched := map[string]common.ID{}
+	for _, meta := range blockmetas {
+		block, err := encoding.OpenBlock(meta, r)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, block)
+
+		resp, err := block.Search(ctx, filter.searchRequest(), searchOpts)
+		if err != nil {
+			return fmt.Errorf("error searching block %s: %w", meta.BlockID, err)
+		}
+
+		for _, t := range resp.Traces {
+			id, err := hex.DecodeString(t.TraceID)
+			if err != nil {
+				return err
+			}
+			tr, err := block.FindTraceByID(ctx, id, searchOpts)
+			if err != nil {
+				return fmt.Errorf("error finding trace %s in block %s: %w", t.TraceID, meta.BlockID, err)
+			}
+			if filter.matches(tr) {
+				matched[hex.EncodeToString(id)] = id
+			}
+		}
+	}
+
+	holds, err := r.LegalHolds(ctx, cmd.TenantID)
+	if err != nil {
+		return fmt.Errorf("error reading legal holds: %w", err)
+	}
+
+	// a matching trace is deleted from all blocks in range that contain a part of it, unless it is under a legal hold
+	toDelete := map[uuid.UUID][]common.ID{}
+	held := map[string]struct{}{}
+	for _, block := range blocks {
+		meta := block.BlockMeta()
+		for _, id := range matched {
+			if holds.HoldsTrace(id, meta) {
+				held[hex.EncodeToString(id)] = struct{}{}
+				continue
+			}
+			tr, err := block.FindTraceByID(ctx, id, searchOpts)
+			if err != nil {
+				return fmt.Errorf("error finding trace %s in block %s: %w", hex.EncodeToString(id), meta.BlockID, err)
+			}
+			if tr != nil {
+				toDelete[meta.BlockID] = append(toDelete[meta.BlockID], id)
+			}
+		}
+	}
+
+	fmt.Println("Matching Traces:", len(matched))
+	if len(held) > 0 {
+		ids := make([]string, 0, len(held))
+		for id := range held {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		fmt.Printf("  under legal hold: %d traces\n", len(ids))
+		for _, id := range ids {
+			fmt.Println("    ", id)
+		}
+	}
+	for _, meta := range blockmetas {
+		ids := toDelete[meta.BlockID]
+		if len(ids) == 0 {
+			continue
+		}
+		sort.Slice(ids, func(i, j int) bool { return hex.EncodeToString(ids[i]) < hex.EncodeToString(ids[j]) })
+
+		fmt.Printf("  block %s: %d traces\n", meta.BlockID, len(ids))
+		for _, id := range ids {
+			fmt.Println("    ", hex.EncodeToString(id))
+		}
+	}
+
+	if !cmd.Confirm {
+		fmt.Println("Dry run, rerun with --confirm to delete the traces listed above")
+		return nil
+	}
+
+	for _, meta := range blockmetas {
+		ids := toDelete[meta.BlockID]
+		if len(ids) == 0 {
+			continue
+		}
+		err := tempodb.WriteTombstones(ctx, r, w, meta, ids)
+		if errors.Is(err, tempodb.ErrTombstonedBlockCompacted) {
+			fmt.Printf("Block %s was compacted while its tombstones were written, rerun the command to delete the traces from the compacted blocks\n", meta.BlockID)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error writing tombstones of block %s: %w", meta.BlockID, err)
+		}
+		fmt.Printf("Tombstoned %d traces in block %s\n", len(ids), meta.BlockID)
+	}
+	fmt.Println("The traces are deleted when the compactor rewrites the blocks")
+
+	return nil
+}
+
+// blocksInRange returns the metas of the blocks of a tenant that overlap the passed range. Blocks flagged
+// corrupt are skipped, they are rewritten by salvaging which doesn't apply tombstones.
+func blocksInRange(ctx context.Context, r backend.Reader, tenantID string, start, end time.Time) ([]*backend.BlockMeta, error) {
+	blockIDs, err := r.Blocks(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load in parallel
+	wg := boundedwaitgroup.New(20)
+	resultsCh := make(chan *backend.BlockMeta, len(blockIDs))
+	for _, id := range blockIDs {
+		wg.Add(1)
+
+		go func(id2 uuid.UUID) {
+			defer wg.Done()
+
+			meta, err := r.BlockMeta(ctx, id2, tenantID)
+			if errors.Is(err, backend.ErrDoesNotExist) {
+				return
+			}
+			if err != nil {
+				fmt.Println("Error reading block meta:", err)
+				return
+			}
+			if meta.Corrupt {
+				fmt.Println("Skipping corrupt block:", meta.BlockID)
+				return
+			}
+			if meta.StartTime.Unix() <= end.Unix() &&
+				meta.EndTime.Unix() >= start.Unix() {
+				resultsCh <- meta
+			}
+		}(id)
+	}
+
+	wg.Wait()
+	close(resultsCh)
+
+	blockmetas := []*backend.BlockMeta{}
+	for m := range resultsCh {
+		blockmetas = append(blockmetas, m)
+	}
+	sort.Slice(blockmetas, func(i, j int) bool { return blockmetas[i].StartTime.Before(blockmetas[j].StartTime) })
+
+	return blockmetas, nil
+}
+
+// deleteFilter is the subset of TraceQL supported for deleting traces: a single spanset filter of conditions
+// joined by &&. A trace matches if any of its spans matches all conditions. Blocks can't evaluate TraceQL,
+// so candidates are found with a tag search and the filter is matched on the candidate traces.
+type deleteFilter struct {
+	conditions []traceql.BinaryOperation
+}
+
+func newDeleteFilter(query string) (*deleteFilter, error) {
+	expr, err := traceql.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(expr.Pipeline.Elements) != 1 {
+		return nil, errors.New("unsupported query: only a single spanset filter is supported")
+	}
+	spansetFilter, ok := expr.Pipeline.Elements[0].(traceql.SpansetFilter)
+	if !ok {
+		return nil, errors.New("unsupported query: only a single spanset filter is supported")
+	}
+
+	f := &deleteFilter{}
+	if err := f.addConditions(spansetFilter.Expression); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *deleteFilter) addConditions(e traceql.FieldExpression) error {
+	op, ok := e.(traceql.BinaryOperation)
+	if !ok {
+		return fmt.Errorf("unsupported condition %s: only comparisons joined by && are supported", e)
+	}
+	if op.Op == traceql.OpAnd {
+		if err := f.addConditions(op.LHS); err != nil {
+			return err
+		}
+		return f.addConditions(op.RHS)
+	}
+
+	// conditions must compare an attribute on the left with a value on the right
+	att, isAttribute := op.LHS.(traceql.Attribute)
+	_, isStatic := op.RHS.(traceql.Static)
+	if !isAttribute || !isStatic {
+		return fmt.Errorf("unsupported condition %s: only comparisons of an attribute with a value are supported", e)
+	}
+	if att.Parent {
+		return fmt.Errorf("unsupported condition %s: parent attributes are not supported", e)
+	}
+
+	switch {
+	case att.Intrinsic == traceql.IntrinsicDuration:
+		if op.Op != traceql.OpGreater && op.Op != traceql.OpGreaterEqual && op.Op != traceql.OpLess && op.Op != traceql.OpLessEqual {
+			return fmt.Errorf("unsupported condition %s: duration only supports >, >=, < and <=", e)
+		}
+	case att.Intrinsic == traceql.IntrinsicName || att.Intrinsic == traceql.IntrinsicStatus || att.Intrinsic == traceql.IntrinsicNone:
+		if op.Op != traceql.OpEqual {
+			return fmt.Errorf("unsupported condition %s: only = is supported", e)
+		}
+	default:
+		return fmt.Errorf("unsupported condition %s: intrinsic %s is not supported", e, att.Intrinsic)
+	}
+
+	f.conditions = append(f.conditions, op)
+	return nil
+}
+
+// searchRequest returns a tag search that finds a superset of the matching traces. Only string values are
+// searched for, the search doesn't find numbers and booleans in all block versions.
+func (f *deleteFilter) searchRequest() *tempopb.SearchRequest {
+	req := &tempopb.SearchRequest{
+		Tags:  map[string]string{},
+		Limit: math.MaxUint32,
+	}
+
+	for _, c := range f.conditions {
+		att := c.LHS.(traceql.Attribute)
+		static := c.RHS.(traceql.Static)
+
+		switch {
+		case att.Intrinsic == traceql.IntrinsicName:
+			req.Tags[trace.SpanNameTag] = static.S
+		case att.Intrinsic == traceql.IntrinsicStatus:
+			req.Tags[trace.StatusCodeTag] = static.Status.String()
+		case att.Intrinsic == traceql.IntrinsicNone && static.Type == traceql.TypeString:
+			req.Tags[att.Name] = static.S
+		}
+	}
+
+	return req
+}
+
+func (f *deleteFilter) matches(tr *tempopb.Trace) bool {
+	if tr == nil {
+		return false
+	}
+
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				if f.matchesSpan(b, s) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (f *deleteFilter) matchesSpan(b *v1.ResourceSpans, s *v1.Span) bool {
+	for _, c := range f.conditions {
+		att := c.LHS.(traceql.Attribute)
+		static := c.RHS.(traceql.Static)
+
+		switch att.Intrinsic {
+		case traceql.IntrinsicName:
+			if static.Type != traceql.TypeString || s.Name != static.S {
+				return false
+			}
+		case traceql.IntrinsicStatus:
+			if static.Type != traceql.TypeStatus || !matchesStatus(s.Status, static.Status) {
+				return false
+			}
+		case traceql.IntrinsicDuration:
+			if static.Type != traceql.TypeDuration || !compareDuration(c.Op, time.Duration(s.EndTimeUnixNano-s.StartTimeUnixNano), static.D) {
+				return false
+			}
+		default:
+			found := false
+			if att.Scope != traceql.AttributeScopeResource {
+				found = matchesAttribute(s.Attributes, att.Name, static)
+			}
+			if !found && att.Scope != traceql.AttributeScopeSpan && b.Resource != nil {
+				found = matchesAttribute(b.Resource.Attributes, att.Name, static)
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchesAttribute(attrs []*v1common.KeyValue, name string, static traceql.Static) bool {
+	for _, kv := range attrs {
+		if kv.Key != name || kv.Value == nil {
+			continue
+		}
+
+		switch v := kv.Value.Value.(type) {
+		case *v1common.AnyValue_StringValue:
+			if static.Type == traceql.TypeString && v.StringValue == static.S {
+				return true
+			}
+		case *v1common.AnyValue_IntValue:
+			if static.Type == traceql.TypeInt && v.IntValue == int64(static.N) {
+				return true
+			}
+		case *v1common.AnyValue_DoubleValue:
+			if static.Type == traceql.TypeFloat && v.DoubleValue == static.F {
+				return true
+			}
+		case *v1common.AnyValue_BoolValue:
+			if static.Type == traceql.TypeBoolean && v.BoolValue == static.B {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesStatus(status *v1.Status, s traceql.Status) bool {
+	code := v1.Status_STATUS_CODE_UNSET
+	if status != nil {
+		code = status.Code
+	}
+
+	switch s {
+	case traceql.StatusError:
+		return code == v1.Status_STATUS_CODE_ERROR
+	case traceql.StatusOk:
+		return code == v1.Status_STATUS_CODE_OK
+	case traceql.StatusUnset:
+		return code == v1.Status_STATUS_CODE_UNSET
+	}
+	return false
+}
+
+func compareDuration(op traceql.Operator, d, static time.Duration) bool {
+	switch op {
+	case traceql.OpGreater:
+		return d > static
+	case traceql.OpGreaterEqual:
+		return d >= static
+	case traceql.OpLess:
+		return d < static
+	case traceql.OpLessEqual:
+		return d <= static
+	}
+	return false
+}
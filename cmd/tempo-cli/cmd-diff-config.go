@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/drone/envsubst"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/tempo/cmd/tempo/app"
+	"github.com/grafana/tempo/pkg/util"
+)
+
+const (
+	// the field is set in the local config file but the component runs with another value
+	driftUnapplied = "unapplied"
+	// the field is set in the local config file but the component runs with the default value
+	driftDefaulted = "defaulted"
+	// the field is not set in the local config file and the component runs with a non default value
+	driftUnexpected = "unexpected"
+)
+
+// perProcessFields are the fields whose values differ between the components of a cluster by design, like the target
+// or the ids and addresses that default to the hostname and ip of the component. They are matched by the end of their
+// path.
+var perProcessFields = []string{
+	"target",
+	"instance_id",
+	"instance_addr",
+	"lifecycler.id",
+	"lifecycler.address",
+	"memberlist.node_name",
+	"memberlist.advertise_addr",
+}
+
+type configDrift struct {
+	endpoint string
+	field    string
+	status   string
+	local    interface{}
+	running  interface{}
+}
+
+type diffConfigCmd struct {
+	Endpoints []string `arg:"" help:"http endpoints of the running components, e.g. http://distributor:3200"`
+
+	FailOnDrift bool `help:"exit with an error if any component runs with a config that differs from the local config file"`
+	ExpandEnv   bool `help:"expand environment variables in the local config file like the -config.expand-env flag of tempo"`
+}
+
+func (cmd *diffConfigCmd) Run(opts *globalOptions) error {
+	if opts.ConfigFile == "" {
+		return errors.New("a local config file is required, set it with --config-file")
+	}
+
+	defaults, local, set, err := readLocalConfig(opts.ConfigFile, cmd.ExpandEnv)
+	if err != nil {
+		return err
+	}
+
+	var drifts []configDrift
+	for _, endpoint := range cmd.Endpoints {
+		running, err := fetchRuntimeConfig(endpoint)
+		if err != nil {
+			return err
+		}
+
+		drifts = append(drifts, diffRuntimeConfig(endpoint, defaults, local, flattenConfig(running), set)...)
+	}
+
+	if len(drifts) == 0 {
+		fmt.Println("all components run with the local config")
+		return nil
+	}
+
+	w := tablewriter.NewWriter(os.Stdout)
+	w.SetHeader([]string{"endpoint", "field", "status", "local", "running"})
+	w.SetAutoWrapText(false)
+	for _, d := range drifts {
+		w.Append([]string{d.endpoint, d.field, d.status, fmt.Sprint(d.local), fmt.Sprint(d.running)})
+	}
+	w.Render()
+
+	if cmd.FailOnDrift {
+		return fmt.Errorf("found %d fields that differ from the local config", len(drifts))
+	}
+
+	return nil
+}
+
+// readLocalConfig returns the flattened default config, the config of the file applied over the defaults and the
+// fields set in the file. Environment variables are expanded the same way tempo expands them with -config.expand-env.
+func readLocalConfig(configFile string, expandEnv bool) (defaults, local, set map[string]interface{}, err error) {
+	buff, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read configFile %s: %w", configFile, err)
+	}
+
+	if expandEnv {
+		s, err := envsubst.EvalEnv(string(buff))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to expand env vars from configFile %s: %w", configFile, err)
+		}
+		buff = []byte(s)
+	}
+
+	defaultCfg := app.Config{}
+	defaultCfg.RegisterFlagsAndApplyDefaults("", &flag.FlagSet{})
+
+	localCfg := app.Config{}
+	localCfg.RegisterFlagsAndApplyDefaults("", &flag.FlagSet{})
+	if err = yaml.UnmarshalStrict(buff, &localCfg); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse configFile %s: %w", configFile, err)
+	}
+
+	// the fields explicitly set in the file, everything else in localCfg is a default
+	setFields := map[interface{}]interface{}{}
+	if err = yaml.Unmarshal(buff, &setFields); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse configFile %s: %w", configFile, err)
+	}
+
+	defaultYaml, err := util.YAMLMarshalUnmarshal(defaultCfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	localYaml, err := util.YAMLMarshalUnmarshal(localCfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return flattenConfig(defaultYaml), flattenConfig(localYaml), flattenConfig(setFields), nil
+}
+
+// fetchRuntimeConfig reads the effective config of the component at endpoint from its /status/config api
+func fetchRuntimeConfig(endpoint string) (map[interface{}]interface{}, error) {
+	url := strings.TrimSuffix(endpoint, "/") + "/status/config"
+
+	resp, err := http.Get(url) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("error fetching config from %s: %w", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config from %s: %w", url, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET request to %s failed with response: %d body: %s", url, resp.StatusCode, string(body))
+	}
+
+	cfg := map[interface{}]interface{}{}
+	if err = yaml.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("error decoding config from %s: %w", url, err)
+	}
+
+	return cfg, nil
+}
+
+// diffRuntimeConfig returns the fields of the running config that differ from the local config, sorted by field name.
+// Per process fields are left out.
+func diffRuntimeConfig(endpoint string, defaults, local, running, set map[string]interface{}) []configDrift {
+	fields := map[string]struct{}{}
+	for f := range local {
+		fields[f] = struct{}{}
+	}
+	for f := range running {
+		fields[f] = struct{}{}
+	}
+
+	var drifts []configDrift
+	for f := range fields {
+		if isPerProcessField(f) {
+			continue
+		}
+
+		localV, runningV := local[f], running[f]
+		if reflect.DeepEqual(localV, runningV) {
+			continue
+		}
+
+		status := driftUnexpected
+		if _, ok := set[f]; ok {
+			status = driftUnapplied
+			if defaultV, ok := defaults[f]; ok && reflect.DeepEqual(defaultV, runningV) {
+				status = driftDefaulted
+			}
+		}
+
+		drifts = append(drifts, configDrift{
+			endpoint: endpoint,
+			field:    f,
+			status:   status,
+			local:    localV,
+			running:  runningV,
+		})
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].field < drifts[j].field
+	})
+
+	return drifts
+}
+
+func isPerProcessField(field string) bool {
+	for _, f := range perProcessFields {
+		if field == f || strings.HasSuffix(field, "."+f) {
+			return true
+		}
+	}
+	return false
+}
+
+// flattenConfig turns a nested config into a map of dotted field paths to leaf values. lists are leaves.
+func flattenConfig(cfg map[interface{}]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	flattenConfigInto(out, "", cfg)
+	return out
+}
+
+func flattenConfigInto(out map[string]interface{}, prefix string, cfg map[interface{}]interface{}) {
+	for k, v := range cfg {
+		field := util.PrefixConfig(prefix, fmt.Sprint(k))
+
+		if m, ok := v.(map[interface{}]interface{}); ok && len(m) > 0 {
+			flattenConfigInto(out, field, m)
+			continue
+		}
+
+		out[field] = v
+	}
+}
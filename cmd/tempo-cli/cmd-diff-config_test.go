@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLocalConfigExpandEnv(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "tempo.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("server:\n  http_listen_port: ${HTTP_PORT}\n"), 0o644))
+	t.Setenv("HTTP_PORT", "3201")
+
+	_, local, set, err := readLocalConfig(configFile, true)
+	require.NoError(t, err)
+	assert.Equal(t, 3201, local["server.http_listen_port"])
+	assert.Contains(t, set, "server.http_listen_port")
+
+	// without expansion the variable isn't a valid port
+	_, _, _, err = readLocalConfig(configFile, false)
+	require.Error(t, err)
+}
+
+func TestDiffRuntimeConfig(t *testing.T) {
+	defaults := map[string]interface{}{"a": 1, "b": 1, "c": 1, "target": "all"}
+	local := map[string]interface{}{"a": 2, "b": 2, "c": 1, "target": "all", "ingester.lifecycler.id": "laptop"}
+	set := map[string]interface{}{"a": 2, "b": 2}
+	running := map[string]interface{}{
+		"a":                            2,
+		"b":                            1,
+		"c":                            3,
+		"target":                       "querier",
+		"ingester.lifecycler.id":       "querier-0",
+		"distributor.ring.instance_id": "querier-0",
+	}
+
+	// per process fields don't drift
+	drifts := diffRuntimeConfig("http://querier", defaults, local, running, set)
+	assert.Equal(t, []configDrift{
+		{endpoint: "http://querier", field: "b", status: driftDefaulted, local: 2, running: 1},
+		{endpoint: "http://querier", field: "c", status: driftUnexpected, local: 1, running: 3},
+	}, drifts)
+}
+
+func TestFetchRuntimeConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status/config" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("target: querier\nserver:\n  http_listen_port: 3200\n"))
+	}))
+	defer srv.Close()
+
+	cfg, err := fetchRuntimeConfig(srv.URL + "/")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"target": "querier", "server.http_listen_port": 3200}, flattenConfig(cfg))
+
+	_, err = fetchRuntimeConfig(srv.URL + "/missing")
+	require.Error(t, err)
+}
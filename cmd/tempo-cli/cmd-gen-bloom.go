@@ -93,7 +93,7 @@ func (cmd *bloomCmd) Run(ctx *globalOptions) error {
 	}
 
 	// replay file and add records to bloom filter
-	bloom := common.NewBloom(cmd.BloomFP, uint(cmd.BloomShardSize), uint(meta.TotalObjects))
+	bloom := common.NewAdaptiveBloom(cmd.BloomFP, uint(cmd.BloomShardSize), uint(meta.TotalObjects))
 	if bloom.GetShardCount() != int(meta.BloomShardCount) {
 		err := fmt.Errorf("shards in generated bloom filter do not match block meta, please use prod settings for bloom shard size and FP")
 		fmt.Println(err.Error())
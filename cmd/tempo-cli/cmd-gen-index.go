@@ -130,8 +130,11 @@ func (cmd *indexCmd) Run(ctx *globalOptions) error {
 		return err
 	}
 
-	// write using IndexWriter
+	// write using IndexWriter of the index version of the block
 	indexWriter := v2.NewIndexWriter(int(meta.IndexPageSize))
+	if meta.IndexVersion == common.IndexVersionV3 {
+		indexWriter = v2.NewIndexWriterV3(int(meta.IndexPageSize))
+	}
 	indexBytes, err := indexWriter.Write(records)
 	if err != nil {
 		fmt.Println("error writing records to indexWriter", err)
@@ -158,6 +161,9 @@ func (cmd *indexCmd) Run(ctx *globalOptions) error {
 	}
 
 	indexReader, err := v2.NewIndexReader(backend.NewContextReaderWithAllReader(indexFile), int(meta.IndexPageSize), len(records))
+	if meta.IndexVersion == common.IndexVersionV3 {
+		indexReader, err = v2.NewIndexReaderV3(backend.NewContextReaderWithAllReader(indexFile), len(records))
+	}
 	if err != nil {
 		fmt.Println("error reading index file")
 		return err
@@ -8,7 +8,6 @@ import (
 	"github.com/google/uuid"
 
 	pq "github.com/grafana/tempo/pkg/parquetquery"
-	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 	"github.com/segmentio/parquet-go"
 )
 
@@ -31,13 +30,25 @@ func (cmd *listColumnCmd) Run(ctx *globalOptions) error {
 		return err
 	}
 
-	rr := vparquet.NewBackendReaderAt(context.Background(), r, vparquet.DataFileName, meta.BlockID, meta.TenantID)
-	pf, err := parquet.OpenFile(rr, int64(meta.Size))
+	files, err := openParquetFiles(r, meta)
 	if err != nil {
 		return err
 	}
 
-	colIndex, _ := pq.GetColumnIndexByPath(pf, cmd.Column)
+	// the columns of blocks with split columns are in either file
+	var (
+		pf       *parquet.File
+		colIndex = -1
+	)
+	for _, f := range files {
+		if colIndex, _ = pq.GetColumnIndexByPath(f.File, cmd.Column); colIndex >= 0 {
+			pf = f.File
+			break
+		}
+	}
+	if pf == nil {
+		return fmt.Errorf("column %s not found", cmd.Column)
+	}
 
 	for i, rg := range pf.RowGroups() {
 
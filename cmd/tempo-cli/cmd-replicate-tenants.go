@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log"
+
+	"github.com/grafana/tempo/tempodb/replication"
+)
+
+type replicateTenantsCmd struct {
+	backendOptions
+
+	Dest           backendOptions `embed:"" prefix:"dest-"`
+	DestConfigFile string         `type:"path" help:"Path to the tempo config file of the destination cluster"`
+
+	Interval time.Duration `help:"time between replications, replicates once if 0" default:"0"`
+	Tenants  []string      `arg:"" optional:"" help:"tenants to replicate, all tenants of the source if none"`
+}
+
+func (cmd *replicateTenantsCmd) Run(opts *globalOptions) error {
+	srcR, _, _, err := loadBackend(&cmd.backendOptions, opts)
+	if err != nil {
+		return err
+	}
+
+	dstR, dstW, dstC, err := loadBackend(&cmd.Dest, &globalOptions{ConfigFile: cmd.DestConfigFile})
+	if err != nil {
+		return err
+	}
+
+	logger := log.NewLogfmtLogger(os.Stderr)
+	r := replication.New(srcR, dstR, dstW, dstC, cmd.Tenants, logger)
+
+	if cmd.Interval <= 0 {
+		return r.ReplicateAll(context.Background())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	return r.Run(ctx, cmd.Interval)
+}
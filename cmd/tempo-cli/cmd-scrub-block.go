@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+type scrubBlockCmd struct {
+	backendOptions
+
+	TenantID string `arg:"" help:"tenant-id within the bucket"`
+	BlockID  string `arg:"" help:"block ID to scrub"`
+	Flag     bool   `help:"flag the block corrupt if pages can't be read, the compactor salvages flagged blocks"`
+}
+
+func (cmd *scrubBlockCmd) Run(ctx *globalOptions) error {
+	blockID, err := uuid.Parse(cmd.BlockID)
+	if err != nil {
+		return err
+	}
+
+	r, w, _, err := loadBackend(&cmd.backendOptions, ctx)
+	if err != nil {
+		return err
+	}
+
+	meta, err := r.BlockMeta(context.TODO(), blockID, cmd.TenantID)
+	if err != nil {
+		return err
+	}
+
+	block, err := encoding.OpenBlock(meta, r)
+	if err != nil {
+		return err
+	}
+
+	salvageable, ok := block.(common.Salvageable)
+	if !ok {
+		return fmt.Errorf("scrubbing %s blocks: %w", meta.Version, common.ErrUnsupported)
+	}
+
+	iter, err := salvageable.SalvageIterator()
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	objects := 0
+	for {
+		id, _, err := iter.Next(context.TODO())
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if id == nil {
+			break
+		}
+		objects++
+	}
+
+	lost := iter.Lost()
+	lostPages := 0
+	for _, l := range lost {
+		lostPages += l.Records
+	}
+
+	fmt.Println("objects readable :", objects, "/", meta.TotalObjects)
+	fmt.Println("pages lost       :", lostPages, "/", meta.TotalRecords)
+	for _, l := range lost {
+		fmt.Printf("  lost ids (%s, %s]: %d pages\n", hex.EncodeToString(l.After), hex.EncodeToString(l.Through), l.Records)
+	}
+
+	if len(lost) == 0 || !cmd.Flag {
+		return nil
+	}
+
+	meta.Corrupt = true
+	err = w.WriteBlockMeta(context.TODO(), meta)
+	if err != nil {
+		return err
+	}
+	fmt.Println("block flagged corrupt")
+
+	return nil
+}
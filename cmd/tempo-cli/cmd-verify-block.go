@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb/encoding"
+)
+
+type verifyBlockCmd struct {
+	backendOptions
+
+	TenantID string `arg:"" help:"tenant-id within the bucket"`
+	BlockID  string `arg:"" help:"block ID to verify"`
+}
+
+func (cmd *verifyBlockCmd) Run(ctx *globalOptions) error {
+	blockID, err := uuid.Parse(cmd.BlockID)
+	if err != nil {
+		return err
+	}
+
+	r, _, _, err := loadBackend(&cmd.backendOptions, ctx)
+	if err != nil {
+		return err
+	}
+
+	meta, err := r.BlockMeta(context.TODO(), blockID, cmd.TenantID)
+	if err != nil {
+		return err
+	}
+
+	report, err := encoding.VerifyBlock(context.TODO(), meta, r)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("objects read :", report.Objects, "/", meta.TotalObjects)
+	fatal := 0
+	for _, p := range report.Problems {
+		kind := "reparable"
+		if p.Fatal {
+			kind = "fatal"
+			fatal++
+		}
+		fmt.Printf("  %-9s : %s\n", kind, p.Msg)
+	}
+
+	if len(report.Problems) == 0 {
+		fmt.Println("block verified")
+		return nil
+	}
+	return fmt.Errorf("block is corrupt: %d fatal and %d reparable problems", fatal, len(report.Problems)-fatal)
+}
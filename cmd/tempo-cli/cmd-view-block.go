@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/dustin/go-humanize"
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
+)
+
+// bloomHeaderSize is the size of the header of a serialized bloom filter: m and k as uint64 followed by the
+// length of the bitset as uint64.
+const bloomHeaderSize = 24
+
+type viewBlockCmd struct {
+	backendOptions
+
+	TenantID string `arg:"" help:"tenant-id within the bucket"`
+	BlockID  string `arg:"" help:"block ID to view"`
+}
+
+func (cmd *viewBlockCmd) Run(ctx *globalOptions) error {
+	blockID, err := uuid.Parse(cmd.BlockID)
+	if err != nil {
+		return err
+	}
+
+	r, _, _, err := loadBackend(&cmd.backendOptions, ctx)
+	if err != nil {
+		return err
+	}
+
+	meta, err := r.BlockMeta(context.TODO(), blockID, cmd.TenantID)
+	if err != nil {
+		return err
+	}
+
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n***************     block meta    *********************\n\n\n")
+	fmt.Println(string(metaJSON))
+
+	fmt.Printf("\n***************       bloom       *********************\n\n\n")
+	if err := printBloom(r, meta); err != nil {
+		return err
+	}
+
+	switch meta.Version {
+	case v2.VersionString:
+		fmt.Printf("\n***************       index       *********************\n\n\n")
+		return printIndexSummary(r, meta)
+	case vparquet.VersionString, vparquet2.VersionString:
+		fmt.Printf("\n***************     row groups    *********************\n\n\n")
+		return printRowGroups(r, meta)
+	}
+
+	fmt.Println("Unknown block version, no layout available:", meta.Version)
+	return nil
+}
+
+// printBloom prints the size, fill ratio and false positive rates of every bloom shard. The expected rate is
+// based on the number of objects in the block, the actual rate on the bits set in the shard.
+func printBloom(r backend.Reader, meta *backend.BlockMeta) error {
+	shardCount := common.ValidateShardCount(int(meta.BloomShardCount))
+	objectsPerShard := float64(meta.TotalObjects) / float64(shardCount)
+
+	fmt.Println("Shards          : ", shardCount)
+	fmt.Println("Configured FP   : ", meta.BloomFP)
+	fmt.Println()
+	fmt.Printf("%-10s %12s %8s %8s %14s %14s\n", "shard", "size", "k", "fill", "expected fp", "actual fp")
+
+	var totalSize uint64
+	for i := 0; i < shardCount; i++ {
+		b, err := r.Read(context.TODO(), common.BloomName(i), meta.BlockID, meta.TenantID, false)
+		if err != nil {
+			return fmt.Errorf("error reading bloom shard %d: %w", i, err)
+		}
+		totalSize += uint64(len(b))
+
+		m, k, fill, err := bloomStats(b)
+		if err != nil {
+			return fmt.Errorf("error parsing bloom shard %d: %w", i, err)
+		}
+
+		expectedFP := math.Pow(1-math.Exp(-float64(k)*objectsPerShard/float64(m)), float64(k))
+		actualFP := math.Pow(fill, float64(k))
+
+		fmt.Printf("%-10s %12s %8d %8.4f %14.2e %14.2e\n", common.BloomName(i), humanize.Bytes(uint64(len(b))), k, fill, expectedFP, actualFP)
+	}
+
+	fmt.Println()
+	fmt.Println("Total Size      : ", humanize.Bytes(totalSize))
+	return nil
+}
+
+// bloomStats returns the number of bits, the number of hash functions and the ratio of bits set of a serialized
+// bloom filter.
+func bloomStats(b []byte) (m uint64, k uint64, fill float64, err error) {
+	if len(b) < bloomHeaderSize {
+		return 0, 0, 0, fmt.Errorf("bloom too short: %d bytes", len(b))
+	}
+
+	m = binary.BigEndian.Uint64(b[0:8])
+	k = binary.BigEndian.Uint64(b[8:16])
+	if m == 0 {
+		return 0, 0, 0, fmt.Errorf("bloom has no bits")
+	}
+
+	set := 0
+	for _, c := range b[bloomHeaderSize:] {
+		set += bits.OnesCount8(c)
+	}
+
+	return m, k, float64(set) / float64(m), nil
+}
+
+func printIndexSummary(r backend.Reader, meta *backend.BlockMeta) error {
+	b, err := v2.NewBackendBlock(meta, r)
+	if err != nil {
+		return err
+	}
+
+	reader, err := b.NewIndexReader()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Index Page Size : ", humanize.Bytes(uint64(meta.IndexPageSize)))
+	if meta.IndexVersion != "" {
+		fmt.Println("Index Version   : ", meta.IndexVersion)
+	}
+	fmt.Println("Total Records   : ", meta.TotalRecords)
+	fmt.Println("Data Encoding   : ", meta.DataEncoding)
+	fmt.Println()
+
+	if meta.TotalRecords == 0 {
+		return nil
+	}
+
+	for _, i := range []int{0, int(meta.TotalRecords) - 1} {
+		record, err := reader.At(context.TODO(), i)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			continue
+		}
+
+		fmt.Printf("Index entry: %10v     ID: %s     Start: %10v     Length: %10v\n", i, hex.EncodeToString(record.ID), record.Start, record.Length)
+	}
+
+	return nil
+}
+
+func printRowGroups(r backend.Reader, meta *backend.BlockMeta) error {
+	files, err := openParquetFiles(r, meta)
+	if err != nil {
+		return err
+	}
+
+	if meta.FooterSize > 0 && meta.HotSize == 0 {
+		fmt.Println("Footer Offset   : ", meta.Size-uint64(meta.FooterSize))
+		fmt.Println("Footer Size     : ", humanize.Bytes(uint64(meta.FooterSize)))
+	}
+	if meta.HotSize > 0 {
+		fmt.Println("Hot Size        : ", humanize.Bytes(meta.HotSize))
+		fmt.Println("Cold Size       : ", humanize.Bytes(meta.Size-meta.HotSize))
+	}
+	fmt.Println("Total Rows      : ", files[0].NumRows())
+
+	for _, f := range files {
+		fmt.Println()
+		if len(files) > 1 {
+			fmt.Println(f.name)
+		}
+
+		fmt.Printf("%-10s %12s %10s %12s %12s\n", "row group", "offset", "rows", "compressed", "uncompressed")
+		for i, rg := range f.Metadata().RowGroups {
+			offset := rg.FileOffset
+			if offset == 0 && len(rg.Columns) > 0 {
+				// not all writers set the offset of the row group, use the first page of the first column
+				offset = rg.Columns[0].MetaData.DataPageOffset
+				if dict := rg.Columns[0].MetaData.DictionaryPageOffset; dict > 0 && dict < offset {
+					offset = dict
+				}
+			}
+
+			fmt.Printf("%-10d %12d %10d %12s %12s\n", i, offset, rg.NumRows, humanize.Bytes(uint64(rg.TotalCompressedSize)), humanize.Bytes(uint64(rg.TotalByteSize)))
+		}
+	}
+
+	return nil
+}
@@ -7,8 +7,6 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/segmentio/parquet-go"
-
-	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 )
 
 type viewSchemaCmd struct {
@@ -37,26 +35,30 @@ func (cmd *viewSchemaCmd) Run(ctx *globalOptions) error {
 	fmt.Printf("\n***************     block meta    *********************\n\n\n")
 	fmt.Printf("%+v\n", meta)
 
-	rr := vparquet.NewBackendReaderAt(context.Background(), r, vparquet.DataFileName, meta.BlockID, meta.TenantID)
-	pf, err := parquet.OpenFile(rr, int64(meta.Size))
+	files, err := openParquetFiles(r, meta)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("\n***************       schema      ********************\n\n\n")
-	fmt.Println(pf.Schema().String())
-
 	columnSizes := map[string]int64{}
-	for _, rg := range pf.RowGroups() {
-		for _, cc := range rg.ColumnChunks() {
-			path, _ := getNodePathByIndex(pf.Root(), "", cc.Column())
+	for _, pf := range files {
+		fmt.Printf("\n***************       schema      ********************\n\n\n")
+		if len(files) > 1 {
+			fmt.Printf("%s\n\n", pf.name)
+		}
+		fmt.Println(pf.Schema().String())
 
-			var size int64
-			for pg := 0; pg < cc.OffsetIndex().NumPages(); pg++ {
-				size += cc.OffsetIndex().CompressedPageSize(pg)
-			}
+		for _, rg := range pf.RowGroups() {
+			for _, cc := range rg.ColumnChunks() {
+				path, _ := getNodePathByIndex(pf.Root(), "", cc.Column())
+
+				var size int64
+				for pg := 0; pg < cc.OffsetIndex().NumPages(); pg++ {
+					size += cc.OffsetIndex().CompressedPageSize(pg)
+				}
 
-			columnSizes[path] = columnSizes[path] + size
+				columnSizes[path] = columnSizes[path] + size
+			}
 		}
 	}
 	sizes := []string{}
@@ -48,6 +48,7 @@ var cli struct {
 	} `cmd:""`
 
 	View struct {
+		Block  viewBlockCmd  `cmd:"" help:"View block meta, bloom stats and index or row group layout of a block"`
 		Index  viewIndexCmd  `cmd:"" help:"View contents of block index"`
 		Schema viewSchemaCmd `cmd:"" help:"View parquet schema"`
 	} `cmd:""`
@@ -74,6 +75,38 @@ var cli struct {
 	Parquet struct {
 		Convert convertParquet `cmd:"" help:"convert from an existing file to tempodb parquet schema"`
 	} `cmd:""`
+
+	Replicate struct {
+		Tenants replicateTenantsCmd `cmd:"" help:"Replicate the blocks and tenant indexes of tenants to the backend of another cluster"`
+	} `cmd:""`
+
+	Scrub struct {
+		Block scrubBlockCmd `cmd:"" help:"Read every page of a block and list the trace ids that can't be read"`
+	} `cmd:""`
+
+	Convert struct {
+		Block convertBlockCmd `cmd:"" help:"Rewrite a block in another block version, e.g. before rolling back to a release that can't read its version"`
+	} `cmd:""`
+
+	Analyse struct {
+		Block analyseBlockCmd `cmd:"" help:"Show the span counts per service, attribute cardinalities and column sizes recorded in the stats of a block"`
+	} `cmd:""`
+
+	Verify struct {
+		Block verifyBlockCmd `cmd:"" help:"Re-read a block and check its files, bloom filters and ids against its meta"`
+	} `cmd:""`
+
+	Diff struct {
+		Config diffConfigCmd `cmd:"" help:"Diff the effective config of running components against a local config file"`
+	} `cmd:""`
+
+	Delete struct {
+		Traces deleteTracesCmd `cmd:"" help:"Tombstone the traces matching a TraceQL filter, the compactor deletes them when rewriting the blocks"`
+	} `cmd:""`
+
+	Bench struct {
+		Queries benchQueriesCmd `cmd:"" help:"Replay a query log against a cluster and report the latency distribution of the queries"`
+	} `cmd:""`
 }
 
 func main() {
@@ -87,24 +120,33 @@ func main() {
 	ctx.FatalIfErrorf(err)
 }
 
-func loadBackend(b *backendOptions, g *globalOptions) (backend.Reader, backend.Writer, backend.Compactor, error) {
+func loadConfig(g *globalOptions) (*app.Config, error) {
 	// Defaults
-	cfg := app.Config{}
+	cfg := &app.Config{}
 	cfg.RegisterFlagsAndApplyDefaults("", &flag.FlagSet{})
 
 	// Existing config
 	if g.ConfigFile != "" {
 		buff, err := os.ReadFile(g.ConfigFile)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to read configFile %s: %w", g.ConfigFile, err)
+			return nil, fmt.Errorf("failed to read configFile %s: %w", g.ConfigFile, err)
 		}
 
-		err = yaml.UnmarshalStrict(buff, &cfg)
+		err = yaml.UnmarshalStrict(buff, cfg)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to parse configFile %s: %w", g.ConfigFile, err)
+			return nil, fmt.Errorf("failed to parse configFile %s: %w", g.ConfigFile, err)
 		}
 	}
 
+	return cfg, nil
+}
+
+func loadBackend(b *backendOptions, g *globalOptions) (backend.Reader, backend.Writer, backend.Compactor, error) {
+	cfg, err := loadConfig(g)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// cli overrides
 	if b.Backend != "" {
 		cfg.StorageConfig.Trace.Backend = b.Backend
@@ -121,7 +163,6 @@ func loadBackend(b *backendOptions, g *globalOptions) (backend.Reader, backend.W
 		cfg.StorageConfig.Trace.S3.Endpoint = b.S3Endpoint
 	}
 
-	var err error
 	var r backend.RawReader
 	var w backend.RawWriter
 	var c backend.Compactor
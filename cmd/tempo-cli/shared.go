@@ -11,6 +11,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/pkg/boundedwaitgroup"
 	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
+	"github.com/segmentio/parquet-go"
 )
 
 type unifiedBlockMeta struct {
@@ -121,6 +124,36 @@ func loadBlock(r backend.Reader, c backend.Compactor, tenantID string, id uuid.U
 	}, nil
 }
 
+// parquetFile is one of the parquet files of a block
+type parquetFile struct {
+	*parquet.File
+	name string
+	size uint64
+}
+
+// openParquetFiles opens the parquet files of a block: the hot and cold file of blocks with split columns or the
+// data file of all others.
+func openParquetFiles(r backend.Reader, meta *backend.BlockMeta) ([]parquetFile, error) {
+	files := []parquetFile{{name: vparquet.DataFileName, size: meta.Size}}
+	if meta.HotSize > 0 {
+		files = []parquetFile{
+			{name: vparquet2.HotFileName, size: meta.HotSize},
+			{name: vparquet2.ColdFileName, size: meta.Size - meta.HotSize},
+		}
+	}
+
+	for i := range files {
+		rr := vparquet.NewBackendReaderAt(context.Background(), r, files[i].name, meta.BlockID, meta.TenantID)
+		pf, err := parquet.OpenFile(rr, int64(files[i].size))
+		if err != nil {
+			return nil, fmt.Errorf("error opening %s: %w", files[i].name, err)
+		}
+		files[i].File = pf
+	}
+
+	return files, nil
+}
+
 func printAsJSON(value interface{}) error {
 	traceJSON, err := json.Marshal(value)
 	if err != nil {
@@ -90,6 +90,7 @@ func Handler(r *http.Request) (*tempopb.SearchResponse, *HTTPError) {
 		DataEncoding:  searchReq.DataEncoding,
 		Size:          searchReq.Size_,
 		FooterSize:    searchReq.FooterSize,
+		IndexVersion:  searchReq.IndexVersion,
 	}
 
 	block, err := encoding.OpenBlock(meta, reader)
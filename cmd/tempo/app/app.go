@@ -78,6 +78,8 @@ type App struct {
 	HTTPAuthMiddleware       middleware.Interface
 	TracesConsumerMiddleware receiver.Middleware
 
+	blocklistNotificationsRegistered bool
+
 	ModuleManager *modules.Manager
 	serviceMap    map[string]services.Service
 	deps          map[string][]string
@@ -188,6 +190,7 @@ func (t *App) Run() error {
 	// before starting servers, register /ready handler and gRPC health check service.
 	t.Server.HTTP.Path("/ready").Handler(t.readyHandler(sm))
 	t.Server.HTTP.Path("/status").Handler(t.statusHandler()).Methods("GET")
+	t.Server.HTTP.Path("/status/dashboard").Handler(t.statusDashboardHandler()).Methods("GET")
 	t.Server.HTTP.Path("/status/{endpoint}").Handler(t.statusHandler()).Methods("GET")
 	grpc_health_v1.RegisterHealthServer(t.Server.GRPC, grpcutil.NewHealthCheck(sm))
 
@@ -299,6 +302,13 @@ func (t *App) readyHandler(sm *services.Manager) http.HandlerFunc {
 				msg.WriteString(fmt.Sprintf("%v: %d\n", st, len(ls)))
 			}
 
+			// the ingester replays the wal while starting
+			if t.ingester != nil {
+				if status := t.ingester.ReplayStatus(); status != "" {
+					msg.WriteString("Ingester " + status + "\n")
+				}
+			}
+
 			http.Error(w, msg.String(), http.StatusServiceUnavailable)
 			return
 		}
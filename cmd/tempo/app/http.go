@@ -2,13 +2,139 @@ package app
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/klauspost/compress/gzhttp"
+	"github.com/klauspost/compress/zstd"
 	"github.com/weaveworks/common/middleware"
+
+	"github.com/grafana/tempo/pkg/api"
+)
+
+const (
+	headerAcceptEncoding  = "Accept-Encoding"
+	headerContentEncoding = "Content-Encoding"
+	headerContentLength   = "Content-Length"
+	headerVary            = "Vary"
+
+	encodingZstd = "zstd"
 )
 
-func httpGzipMiddleware() middleware.Interface {
+var zstdEncoderPool = sync.Pool{
+	New: func() interface{} {
+		// zstd.NewWriter only returns an error on invalid options
+		e, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+		return e
+	},
+}
+
+// httpCompressionMiddleware compresses responses with zstd if the client prefers it and falls back to gzip
+// otherwise.
+func httpCompressionMiddleware() middleware.Interface {
 	return middleware.Func(func(handler http.Handler) http.Handler {
-		return gzhttp.GzipHandler(handler)
+		gzipHandler := gzhttp.GzipHandler(handler)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// server-sent events must reach the client as they are written
+			if r.Header.Get(api.HeaderAccept) == api.HeaderAcceptEventStream {
+				handler.ServeHTTP(w, r)
+				return
+			}
+
+			if !acceptsZstd(r.Header.Get(headerAcceptEncoding)) {
+				gzipHandler.ServeHTTP(w, r)
+				return
+			}
+
+			zw := &zstdResponseWriter{ResponseWriter: w}
+			defer zw.close()
+
+			handler.ServeHTTP(zw, r)
+		})
 	})
 }
+
+// acceptsZstd returns true if zstd is accepted and preferred over, or equally weighted to, gzip.
+func acceptsZstd(acceptEncoding string) bool {
+	zstdQ, gzipQ := 0.0, 0.0
+	for _, enc := range strings.Split(acceptEncoding, ",") {
+		name, q := parseEncoding(enc)
+		switch name {
+		case encodingZstd:
+			zstdQ = q
+		case "gzip":
+			gzipQ = q
+		}
+	}
+	return zstdQ > 0 && zstdQ >= gzipQ
+}
+
+// parseEncoding parses a single Accept-Encoding entry, e.g. "zstd;q=0.8", into the encoding and its weight.
+func parseEncoding(s string) (string, float64) {
+	name, params, _ := strings.Cut(s, ";")
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	q := 1.0
+	for _, p := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || strings.TrimSpace(k) != "q" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return name, 0
+		}
+		q = f
+	}
+	return name, q
+}
+
+// zstdResponseWriter compresses the response body with zstd. Responses already carrying a content encoding
+// are passed through untouched.
+type zstdResponseWriter struct {
+	http.ResponseWriter
+
+	encoder     *zstd.Encoder
+	wroteHeader bool
+}
+
+func (w *zstdResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	h.Add(headerVary, headerAcceptEncoding)
+	if h.Get(headerContentEncoding) == "" && code != http.StatusNoContent && code != http.StatusNotModified {
+		h.Set(headerContentEncoding, encodingZstd)
+		h.Del(headerContentLength)
+
+		w.encoder = zstdEncoderPool.Get().(*zstd.Encoder)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *zstdResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.encoder.Write(b)
+}
+
+func (w *zstdResponseWriter) close() {
+	if w.encoder == nil {
+		return
+	}
+	_ = w.encoder.Close()
+	w.encoder.Reset(nil)
+	zstdEncoderPool.Put(w.encoder)
+	w.encoder = nil
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/api"
+)
+
+func TestAcceptsZstd(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       bool
+	}{
+		{"", false},
+		{"gzip", false},
+		{"zstd", true},
+		{"gzip, deflate, br, zstd", true},
+		{"zstd;q=0.5, gzip", false},
+		{"ZSTD; q=0.8, gzip;q=0.5", true},
+		{"zstd;q=0", false},
+		{"zstd;q=foo", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.acceptEncoding, func(t *testing.T) {
+			assert.Equal(t, tc.expected, acceptsZstd(tc.acceptEncoding))
+		})
+	}
+}
+
+func TestHTTPCompressionMiddleware(t *testing.T) {
+	body := strings.Repeat("tempo ", 1000)
+	handler := httpCompressionMiddleware().Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentLength, "6000")
+		_, _ = w.Write([]byte(body))
+	}))
+
+	tests := []struct {
+		acceptEncoding   string
+		expectedEncoding string
+		decode           func(io.Reader) (io.Reader, error)
+	}{
+		{
+			acceptEncoding: "",
+			decode:         func(r io.Reader) (io.Reader, error) { return r, nil },
+		},
+		{
+			acceptEncoding:   "gzip",
+			expectedEncoding: "gzip",
+			decode:           func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		{
+			acceptEncoding:   "gzip, zstd",
+			expectedEncoding: encodingZstd,
+			decode:           func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+			req.Header.Set(headerAcceptEncoding, tc.acceptEncoding)
+
+			res := httptest.NewRecorder()
+			handler.ServeHTTP(res, req)
+			require.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, tc.expectedEncoding, res.Header().Get(headerContentEncoding))
+
+			r, err := tc.decode(res.Body)
+			require.NoError(t, err)
+			actual, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, body, string(actual))
+		})
+	}
+}
+
+func TestHTTPCompressionMiddlewareEventStream(t *testing.T) {
+	handler := httpCompressionMiddleware().Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Flusher)
+		assert.True(t, ok)
+		_, _ = w.Write([]byte("event: result\ndata: {}\n\n"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search", nil)
+	req.Header.Set(headerAcceptEncoding, "gzip, zstd")
+	req.Header.Set(api.HeaderAccept, api.HeaderAcceptEventStream)
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Empty(t, res.Header().Get(headerContentEncoding))
+	assert.Equal(t, "event: result\ndata: {}\n\n", res.Body.String())
+}
@@ -112,13 +112,14 @@ func (t *App) initGeneratorRing() (services.Service, error) {
 }
 
 func (t *App) initOverrides() (services.Service, error) {
-	overrides, err := overrides.NewOverrides(t.cfg.LimitsConfig)
+	o, err := overrides.NewOverrides(t.cfg.LimitsConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create overrides %w", err)
 	}
-	t.overrides = overrides
+	t.overrides = o
 
 	prometheus.MustRegister(&t.cfg.LimitsConfig)
+	prometheus.MustRegister(overrides.NewExporter(t.overrides))
 
 	if t.cfg.LimitsConfig.PerTenantOverrideConfig != "" {
 		prometheus.MustRegister(t.overrides)
@@ -154,12 +155,13 @@ func (t *App) initIngester() (services.Service, error) {
 	tempopb.RegisterQuerierServer(t.Server.GRPC, t.ingester)
 	t.Server.HTTP.Path("/flush").Handler(http.HandlerFunc(t.ingester.FlushHandler))
 	t.Server.HTTP.Path("/shutdown").Handler(http.HandlerFunc(t.ingester.ShutdownHandler))
+	t.Server.HTTP.Path("/ingester/traces/delete").Handler(t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.ingester.DeleteTraceHandler)))
 	return t.ingester, nil
 }
 
 func (t *App) initGenerator() (services.Service, error) {
 	t.cfg.Generator.Ring.ListenPort = t.cfg.Server.GRPCListenPort
-	generator, err := generator.New(&t.cfg.Generator, t.overrides, prometheus.DefaultRegisterer, log.Logger)
+	generator, err := generator.New(&t.cfg.Generator, t.overrides, t.ring, t.generatorRing, t.cfg.IngesterClient, prometheus.DefaultRegisterer, log.Logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create metrics-generator %w", err)
 	}
@@ -185,6 +187,9 @@ func (t *App) initQuerier() (services.Service, error) {
 	// do not enable polling if this is the single binary. in that case the compactor will take care of polling
 	if t.cfg.Target == Querier {
 		t.store.EnablePolling(nil)
+		if err := t.registerBlocklistNotifications(); err != nil {
+			return nil, err
+		}
 	}
 
 	// todo: make ingester client a module instead of passing config everywhere
@@ -205,11 +210,23 @@ func (t *App) initQuerier() (services.Service, error) {
 		searchHandler := t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.querier.SearchHandler))
 		t.Server.HTTP.Handle(path.Join(api.PathPrefixQuerier, addHTTPAPIPrefix(&t.cfg, api.PathSearch)), searchHandler)
 
+		searchRecentHandler := t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.querier.SearchRecentHandler))
+		t.Server.HTTP.Handle(path.Join(api.PathPrefixQuerier, addHTTPAPIPrefix(&t.cfg, api.PathSearchRecent)), searchRecentHandler)
+
 		searchTagsHandler := t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.querier.SearchTagsHandler))
 		t.Server.HTTP.Handle(path.Join(api.PathPrefixQuerier, addHTTPAPIPrefix(&t.cfg, api.PathSearchTags)), searchTagsHandler)
 
 		searchTagValuesHandler := t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.querier.SearchTagValuesHandler))
 		t.Server.HTTP.Handle(path.Join(api.PathPrefixQuerier, addHTTPAPIPrefix(&t.cfg, api.PathSearchTagValues)), searchTagValuesHandler)
+
+		searchTagValuesV2Handler := t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.querier.SearchTagValuesV2Handler))
+		t.Server.HTTP.Handle(path.Join(api.PathPrefixQuerier, addHTTPAPIPrefix(&t.cfg, api.PathSearchTagValuesV2)), searchTagValuesV2Handler)
+
+		metricsQueryRangeHandler := t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.querier.MetricsQueryRangeHandler))
+		t.Server.HTTP.Handle(path.Join(api.PathPrefixQuerier, addHTTPAPIPrefix(&t.cfg, api.PathMetricsQueryRange)), metricsQueryRangeHandler)
+
+		metricsThresholdsHandler := t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.querier.MetricsThresholdsHandler))
+		t.Server.HTTP.Handle(path.Join(api.PathPrefixQuerier, addHTTPAPIPrefix(&t.cfg, api.PathMetricsThresholds)), metricsThresholdsHandler)
 	}
 
 	return t.querier, t.querier.CreateAndRegisterWorker(t.Server.HTTPServer.Handler)
@@ -218,7 +235,7 @@ func (t *App) initQuerier() (services.Service, error) {
 func (t *App) initQueryFrontend() (services.Service, error) {
 	// cortexTripper is a bridge between http and httpgrpc.
 	// It does the job of passing data to the cortex frontend code.
-	cortexTripper, v1, err := frontend.InitFrontend(t.cfg.Frontend.Config, frontend.CortexNoQuerierLimits{}, log.Logger, prometheus.DefaultRegisterer)
+	cortexTripper, v1, err := frontend.InitFrontend(t.cfg.Frontend.Config, frontend.CortexNoQuerierLimits{Overrides: t.overrides}, log.Logger, prometheus.DefaultRegisterer)
 	if err != nil {
 		return nil, err
 	}
@@ -233,11 +250,12 @@ func (t *App) initQueryFrontend() (services.Service, error) {
 	// wrap handlers with auth
 	middleware := middleware.Merge(
 		t.HTTPAuthMiddleware,
-		httpGzipMiddleware(),
+		httpCompressionMiddleware(),
 	)
 
 	traceByIDHandler := middleware.Wrap(queryFrontend.TraceByID)
 	searchHandler := middleware.Wrap(queryFrontend.Search)
+	metricsHandler := middleware.Wrap(queryFrontend.Metrics)
 
 	// register grpc server for queriers to connect to
 	frontend_v1pb.RegisterFrontendServer(t.Server.GRPC, t.frontend)
@@ -248,10 +266,31 @@ func (t *App) initQueryFrontend() (services.Service, error) {
 	// http search endpoints
 	if t.cfg.SearchEnabled {
 		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSearch), searchHandler)
+		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSearchRecent), searchHandler)
 		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSearchTags), searchHandler)
 		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSearchTagValues), searchHandler)
+		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSearchTagValuesV2), searchHandler)
+		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathMetricsQueryRange), metricsHandler)
 
 		t.store.EnablePolling(nil) // the query frontend does not need to have knowledge of the backend unless it is building jobs for backend search
+		if err := t.registerBlocklistNotifications(); err != nil {
+			return nil, err
+		}
+	}
+
+	// http saved queries and query history endpoints
+	if t.cfg.Frontend.SavedQueries.Enabled {
+		reader, writer, err := t.newRawBackend()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize saved queries: %w", err)
+		}
+		savedQueries := frontend.NewSavedQueries(t.cfg.Frontend.SavedQueries, reader, writer, log.Logger)
+
+		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSavedQueries), middleware.Wrap(http.HandlerFunc(savedQueries.SavedQueriesHandler)))
+		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSavedQuery), middleware.Wrap(http.HandlerFunc(savedQueries.SavedQueryHandler)))
+		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathQueryHistory), middleware.Wrap(http.HandlerFunc(savedQueries.QueryHistoryHandler)))
+		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSavedQueriesExport), middleware.Wrap(http.HandlerFunc(savedQueries.ExportHandler)))
+		t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathSavedQueriesImport), middleware.Wrap(http.HandlerFunc(savedQueries.ImportHandler)))
 	}
 
 	// http query echo endpoint
@@ -262,6 +301,19 @@ func (t *App) initQueryFrontend() (services.Service, error) {
 }
 
 func (t *App) initCompactor() (services.Service, error) {
+	if err := t.cfg.Compactor.Compactor.Validate(t.cfg.StorageConfig.Trace.Block); err != nil {
+		return nil, fmt.Errorf("invalid compactor config %w", err)
+	}
+
+	// http legal hold endpoints
+	reader, writer, err := t.newRawBackend()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize legal holds: %w", err)
+	}
+	legalHolds := compactor.NewLegalHolds(backend.NewReader(reader), backend.NewWriter(writer), log.Logger)
+	t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathLegalHolds), t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(legalHolds.LegalHoldsHandler)))
+	t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathLegalHold), t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(legalHolds.LegalHoldHandler)))
+
 	compactor, err := compactor.New(t.cfg.Compactor, t.store, t.overrides, prometheus.DefaultRegisterer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create compactor %w", err)
@@ -271,6 +323,9 @@ func (t *App) initCompactor() (services.Service, error) {
 	if t.compactor.Ring != nil {
 		t.Server.HTTP.Handle("/compactor/ring", t.compactor.Ring)
 	}
+	if err := t.registerBlocklistNotifications(); err != nil {
+		return nil, err
+	}
 
 	return t.compactor, nil
 }
@@ -285,6 +340,23 @@ func (t *App) initStore() (services.Service, error) {
 	return t.store, nil
 }
 
+// registerBlocklistNotifications registers the endpoint updating the polled blocklist from bucket notifications
+// once, if enabled. The events are shared with the other replicas through the kv store of the ring.
+func (t *App) registerBlocklistNotifications() error {
+	if !t.cfg.StorageConfig.Trace.BlocklistNotifications.Enabled || t.blocklistNotificationsRegistered {
+		return nil
+	}
+	t.blocklistNotificationsRegistered = true
+
+	err := t.store.EnableBlocklistNotifications(t.cfg.Ingester.LifecyclerConfig.RingConfig.KVStore)
+	if err != nil {
+		return err
+	}
+
+	t.Server.HTTP.Handle(addHTTPAPIPrefix(&t.cfg, api.PathBlocklistNotifications), t.HTTPAuthMiddleware.Wrap(http.HandlerFunc(t.store.BlocklistNotificationHandler)))
+	return nil
+}
+
 func (t *App) initMemberlistKV() (services.Service, error) {
 	reg := prometheus.DefaultRegisterer
 	t.cfg.MemberlistKV.MetricsRegisterer = reg
@@ -292,6 +364,7 @@ func (t *App) initMemberlistKV() (services.Service, error) {
 	t.cfg.MemberlistKV.Codecs = []codec.Codec{
 		ring.GetCodec(),
 		usagestats.JSONCodec,
+		tempo_storage.BlocklistEventsCodec,
 	}
 
 	dnsProviderReg := prometheus.WrapRegistererWithPrefix(
@@ -327,6 +400,22 @@ func (t *App) initUsageReport() (services.Service, error) {
 
 	usagestats.Target(t.cfg.Target)
 
+	reader, writer, err := t.newRawBackend()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize usage report: %w", err)
+	}
+
+	ur, err := usagestats.NewReporter(t.cfg.UsageReport, t.cfg.Ingester.LifecyclerConfig.RingConfig.KVStore, reader, writer, util_log.Logger, prometheus.DefaultRegisterer)
+	if err != nil {
+		level.Info(util_log.Logger).Log("msg", "failed to initialize usage report", "err", err)
+		return nil, nil
+	}
+	t.usageReport = ur
+	return ur, nil
+}
+
+// newRawBackend returns a raw reader and writer for the configured trace storage backend
+func (t *App) newRawBackend() (backend.RawReader, backend.RawWriter, error) {
 	var err error
 	var reader backend.RawReader
 	var writer backend.RawWriter
@@ -344,17 +433,7 @@ func (t *App) initUsageReport() (services.Service, error) {
 		err = fmt.Errorf("unknown backend %s", t.cfg.StorageConfig.Trace.Backend)
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize usage report: %w", err)
-	}
-
-	ur, err := usagestats.NewReporter(t.cfg.UsageReport, t.cfg.Ingester.LifecyclerConfig.RingConfig.KVStore, reader, writer, util_log.Logger, prometheus.DefaultRegisterer)
-	if err != nil {
-		level.Info(util_log.Logger).Log("msg", "failed to initialize usage report", "err", err)
-		return nil, nil
-	}
-	t.usageReport = ur
-	return ur, nil
+	return reader, writer, err
 }
 
 func (t *App) setupModuleManager() error {
@@ -401,6 +480,11 @@ func (t *App) setupModuleManager() error {
 		deps[SingleBinary] = append(deps[SingleBinary], MetricsGenerator)
 	}
 
+	if t.cfg.Generator.Replay.Enabled {
+		// Replaying recent spans requests them from the ingesters and checks the metrics-generator ring for changes
+		deps[MetricsGenerator] = append(deps[MetricsGenerator], Ring, MetricsGeneratorRing)
+	}
+
 	for mod, targets := range deps {
 		if err := mm.AddDependency(mod, targets...); err != nil {
 			return err
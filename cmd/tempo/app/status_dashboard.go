@@ -0,0 +1,280 @@
+package app
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/version"
+
+	"github.com/grafana/tempo/pkg/util/log"
+)
+
+// dashboardMetrics are the metrics summarized on the status dashboard per module. The values of all series of a
+// metric are summed.
+var dashboardMetrics = map[string][]string{
+	Distributor: {
+		"tempo_distributor_spans_received_total",
+		"tempo_distributor_inflight_bytes",
+		"tempo_discarded_spans_total",
+	},
+	Ingester: {
+		"tempo_ingester_live_traces",
+		"tempo_ingester_flush_queue_length",
+		"tempo_ingester_blocks_flushed_total",
+		"tempo_ingester_failed_flushes_total",
+		"tempo_ingester_replay_errors_total",
+		"tempo_ingester_replayed_wal_files",
+		"tempo_wal_corrupt_records_total",
+	},
+	MetricsGenerator: {
+		"tempo_metrics_generator_spans_received_total",
+		"tempo_metrics_generator_registry_active_series",
+	},
+	Querier: {
+		"tempodb_blocklist_length",
+	},
+	Compactor: {
+		"tempodb_blocklist_length",
+		"tempodb_compaction_outstanding_blocks",
+		"tempodb_compaction_errors_total",
+		"tempodb_retention_errors_total",
+	},
+}
+
+type dashboardData struct {
+	Target     string
+	Version    string
+	Now        time.Time
+	Services   []dashboardService
+	Rings      []dashboardRing
+	Components []dashboardComponent
+	Errors     []log.RecentError
+}
+
+type dashboardService struct {
+	Name    string
+	State   string
+	Failure string
+}
+
+type dashboardRing struct {
+	Name    string
+	Path    string
+	Healthy int
+	Total   int
+}
+
+type dashboardComponent struct {
+	Name    string
+	Metrics []dashboardMetric
+}
+
+type dashboardMetric struct {
+	Name  string
+	Value string
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="UTF-8">
+	<title>Tempo status</title>
+	<style>
+		body { font-family: sans-serif; margin: 2em; }
+		table { border-collapse: collapse; margin-bottom: 2em; }
+		th, td { border: 1px solid #ccc; padding: 4px 12px; text-align: left; }
+		.bad { color: #c00; }
+	</style>
+</head>
+<body>
+	<h1>Tempo status</h1>
+	<p>Target <b>{{ .Target }}</b>, version {{ .Version }}, at {{ .Now.Format "2006-01-02T15:04:05Z07:00" }}</p>
+
+	<h2>Services</h2>
+	<table>
+		<tr><th>Service</th><th>State</th><th>Failure</th></tr>
+		{{- range .Services }}
+		<tr><td>{{ .Name }}</td><td{{ if .Failure }} class="bad"{{ end }}>{{ .State }}</td><td>{{ .Failure }}</td></tr>
+		{{- end }}
+	</table>
+
+	{{- if .Rings }}
+	<h2>Rings</h2>
+	<table>
+		<tr><th>Ring</th><th>Healthy instances</th><th>Instances</th></tr>
+		{{- range .Rings }}
+		<tr><td><a href="{{ .Path }}">{{ .Name }}</a></td><td{{ if lt .Healthy .Total }} class="bad"{{ end }}>{{ .Healthy }}</td><td>{{ .Total }}</td></tr>
+		{{- end }}
+	</table>
+	{{- end }}
+
+	{{- range .Components }}
+	<h2>{{ .Name }}</h2>
+	<table>
+		<tr><th>Metric</th><th>Value</th></tr>
+		{{- range .Metrics }}
+		<tr><td>{{ .Name }}</td><td>{{ .Value }}</td></tr>
+		{{- end }}
+	</table>
+	{{- end }}
+
+	<h2>Recent errors</h2>
+	{{- if .Errors }}
+	<table>
+		<tr><th>Time</th><th>Log line</th></tr>
+		{{- range .Errors }}
+		<tr><td>{{ .Time.Format "2006-01-02T15:04:05Z07:00" }}</td><td>{{ .Line }}</td></tr>
+		{{- end }}
+	</table>
+	{{- else }}
+	<p>No errors logged.</p>
+	{{- end }}
+</body>
+</html>
+`))
+
+// statusDashboardHandler serves a small html page summarizing the state of the modules of this instance, so
+// operators get basic observability without dashboards.
+func (t *App) statusDashboardHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data := dashboardData{
+			Target:   t.cfg.Target,
+			Version:  version.Version,
+			Now:      time.Now().UTC(),
+			Services: t.dashboardServices(),
+			Rings:    t.dashboardRings(),
+			Errors:   log.RecentErrors(),
+		}
+
+		components, err := t.dashboardComponents(prometheus.DefaultGatherer)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data.Components = components
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := dashboardTemplate.Execute(w, data); err != nil {
+			level.Error(log.Logger).Log("msg", "error writing status dashboard", "err", err)
+		}
+	}
+}
+
+func (t *App) dashboardServices() []dashboardService {
+	list := make([]dashboardService, 0, len(t.serviceMap))
+	for name, s := range t.serviceMap {
+		state := s.State().String()
+		// the ingester replays the wal while starting
+		if name == Ingester && s.State() == services.Starting && t.ingester != nil {
+			if status := t.ingester.ReplayStatus(); status != "" {
+				state += " (" + status + ")"
+			}
+		}
+
+		var failure string
+		if err := s.FailureCase(); err != nil {
+			failure = err.Error()
+		}
+
+		list = append(list, dashboardService{
+			Name:    name,
+			State:   state,
+			Failure: failure,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func (t *App) dashboardRings() []dashboardRing {
+	var list []dashboardRing
+	add := func(name, path string, r ring.ReadRing) {
+		healthy := 0
+		if rs, err := r.GetAllHealthy(ring.Reporting); err == nil {
+			healthy = len(rs.Instances)
+		}
+		list = append(list, dashboardRing{
+			Name:    name,
+			Path:    path,
+			Healthy: healthy,
+			Total:   r.InstancesCount(),
+		})
+	}
+
+	if t.ring != nil {
+		add(Ring, "/ingester/ring", t.ring)
+	}
+	if t.generatorRing != nil {
+		add(MetricsGeneratorRing, "/metrics-generator/ring", t.generatorRing)
+	}
+	if t.distributor != nil && t.distributor.DistributorRing != nil {
+		add("distributor-ring", "/distributor/ring", t.distributor.DistributorRing)
+	}
+	if t.compactor != nil && t.compactor.Ring != nil {
+		add("compactor-ring", "/compactor/ring", t.compactor.Ring)
+	}
+	return list
+}
+
+// dashboardComponents summarizes the metrics of the modules running in this instance
+func (t *App) dashboardComponents(g prometheus.Gatherer) ([]dashboardComponent, error) {
+	families, err := g.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	sums := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			sums[f.GetName()] += metricValue(f.GetType(), m)
+		}
+	}
+
+	var list []dashboardComponent
+	for _, module := range []string{Distributor, Ingester, MetricsGenerator, Querier, Compactor} {
+		if _, ok := t.serviceMap[module]; !ok {
+			continue
+		}
+
+		c := dashboardComponent{Name: module}
+		for _, name := range dashboardMetrics[module] {
+			value := "-"
+			if v, ok := sums[name]; ok {
+				value = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+			c.Metrics = append(c.Metrics, dashboardMetric{
+				Name:  name,
+				Value: value,
+			})
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func metricValue(t dto.MetricType, m *dto.Metric) float64 {
+	switch t {
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue()
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue()
+	case dto.MetricType_HISTOGRAM:
+		return float64(m.GetHistogram().GetSampleCount())
+	case dto.MetricType_SUMMARY:
+		return float64(m.GetSummary().GetSampleCount())
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package app
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusDashboard(t *testing.T) {
+	app := &App{
+		cfg: Config{Target: SingleBinary},
+		serviceMap: map[string]services.Service{
+			Ingester:  services.NewIdleService(nil, nil),
+			Compactor: services.NewIdleService(nil, nil),
+		},
+	}
+
+	reg := prometheus.NewRegistry()
+	outstanding := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "tempodb_compaction_outstanding_blocks"}, []string{"tenant"})
+	reg.MustRegister(outstanding)
+	outstanding.WithLabelValues("a").Set(3)
+	outstanding.WithLabelValues("b").Set(4)
+
+	components, err := app.dashboardComponents(reg)
+	require.NoError(t, err)
+
+	// only the modules of the instance are summarized
+	require.Len(t, components, 2)
+	assert.Equal(t, Ingester, components[0].Name)
+	assert.Equal(t, Compactor, components[1].Name)
+	assert.Contains(t, components[1].Metrics, dashboardMetric{Name: "tempodb_compaction_outstanding_blocks", Value: "7"})
+	assert.Contains(t, components[1].Metrics, dashboardMetric{Name: "tempodb_retention_errors_total", Value: "-"})
+
+	w := httptest.NewRecorder()
+	app.statusDashboardHandler()(w, httptest.NewRequest("GET", "/status/dashboard", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "<td>compactor</td><td>New</td>")
+	assert.Contains(t, w.Body.String(), "<h2>ingester</h2>")
+}
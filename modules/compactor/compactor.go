@@ -12,11 +12,14 @@ import (
 	"github.com/grafana/dskit/services"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/modules/storage"
 	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/model/trace"
 	"github.com/grafana/tempo/pkg/util/log"
+	"github.com/grafana/tempo/tempodb/encoding/common"
 )
 
 const (
@@ -35,6 +38,12 @@ const (
 
 var (
 	ringOp = ring.NewOp([]ring.InstanceState{ring.ACTIVE}, nil)
+
+	metricTracesTruncated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "compactor_traces_truncated_total",
+		Help:      "The total number of traces truncated to the max bytes per trace during compaction.",
+	}, []string{"tenant"})
 )
 
 type Compactor struct {
@@ -50,6 +59,9 @@ type Compactor struct {
 
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
+
+	// stops the tracer of compaction jobs, nil if they are not traced
+	shutdownJobTracer func(context.Context) error
 }
 
 // New makes a new Compactor.
@@ -93,6 +105,15 @@ func New(cfg Config, store storage.Store, overrides *overrides.Overrides, reg pr
 		}
 	}
 
+	if cfg.Tracing.Endpoint != "" {
+		tracer, shutdown, err := newJobTracer(cfg.Tracing)
+		if err != nil {
+			return nil, err
+		}
+		c.cfg.Compactor.Tracer = tracer
+		c.shutdownJobTracer = shutdown
+	}
+
 	c.Service = services.NewBasicService(c.starting, c.running, c.stopping)
 
 	return c, nil
@@ -176,6 +197,14 @@ func (c *Compactor) running(ctx context.Context) error {
 
 // Called after distributor is asked to stop via StopAsync.
 func (c *Compactor) stopping(_ error) error {
+	if c.shutdownJobTracer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.shutdownJobTracer(ctx); err != nil {
+			level.Error(log.Logger).Log("msg", "failed to flush compaction job traces", "err", err)
+		}
+	}
+
 	if c.subservices != nil {
 		return services.StopManagerAndAwaitStopped(context.Background(), c.subservices)
 	}
@@ -215,34 +244,42 @@ func (c *Compactor) Owns(hash string) bool {
 
 // Combine implements tempodb.CompactorSharder
 func (c *Compactor) Combine(dataEncoding string, tenantID string, objs ...[]byte) ([]byte, bool, error) {
+	strategy := c.CombineStrategyForTenant(tenantID)
+
+	// the objects are in the order of the blocks, the last one is from the latest block
+	if strategy == common.CombineStrategyLatestWins && len(objs) > 0 {
+		return objs[len(objs)-1], false, nil
+	}
+
 	combinedObj, wasCombined, err := model.StaticCombiner.Combine(dataEncoding, objs...)
 	if err != nil {
 		return nil, false, err
 	}
 
 	maxBytes := c.overrides.MaxBytesPerTrace(tenantID)
-	if maxBytes == 0 || len(combinedObj) < maxBytes {
+	if strategy == common.CombineStrategyMergeAll || maxBytes == 0 || len(combinedObj) < maxBytes {
 		return combinedObj, wasCombined, nil
 	}
 
-	// technically neither of these conditions should ever be true, we are adding them as guard code
-	// for the following logic
-	if len(objs) == 0 {
-		return []byte{}, wasCombined, nil
+	truncatedObj, spansDiscarded, err := truncateObject(dataEncoding, combinedObj, maxBytes)
+	if err != nil {
+		return nil, false, err
 	}
-	if len(objs) == 1 {
-		return objs[0], wasCombined, nil
+	if spansDiscarded > 0 {
+		overrides.RecordDiscardedSpans(spansDiscarded, reasonCompactorDiscardedSpans, tenantID)
+		c.RecordTruncatedTrace(tenantID)
 	}
-
-	spansDiscarded := countSpans(dataEncoding, objs[1:]...)
-	overrides.RecordDiscardedSpans(spansDiscarded, reasonCompactorDiscardedSpans, tenantID)
-	return objs[0], wasCombined, nil
+	return truncatedObj, wasCombined, nil
 }
 
 func (c *Compactor) RecordDiscardedSpans(count int, tenantID string) {
 	overrides.RecordDiscardedSpans(count, reasonCompactorDiscardedSpans, tenantID)
 }
 
+func (c *Compactor) RecordTruncatedTrace(tenantID string) {
+	metricTracesTruncated.WithLabelValues(tenantID).Inc()
+}
+
 // BlockRetentionForTenant implements CompactorOverrides
 func (c *Compactor) BlockRetentionForTenant(tenantID string) time.Duration {
 	return c.overrides.BlockRetention(tenantID)
@@ -252,6 +289,14 @@ func (c *Compactor) MaxBytesPerTraceForTenant(tenantID string) int {
 	return c.overrides.MaxBytesPerTrace(tenantID)
 }
 
+func (c *Compactor) CombineStrategyForTenant(tenantID string) common.CombineStrategy {
+	return common.CombineStrategy(c.overrides.CompactionCombineStrategy(tenantID))
+}
+
+func (c *Compactor) BloomFPForTenant(tenantID string) float64 {
+	return c.overrides.BloomFilterFalsePositive(tenantID)
+}
+
 func (c *Compactor) isSharded() bool {
 	return c.cfg.ShardingRing.KVStore.Store != ""
 }
@@ -313,3 +358,39 @@ func countSpans(dataEncoding string, objs ...[]byte) int {
 
 	return spans
 }
+
+// truncateObject drops the oldest spans of the trace until it fits in maxBytes. It returns the truncated object and
+// the number of dropped spans.
+func truncateObject(dataEncoding string, obj []byte, maxBytes int) ([]byte, int, error) {
+	objDecoder, err := model.NewObjectDecoder(dataEncoding)
+	if err != nil {
+		return nil, 0, err
+	}
+	segmentDecoder, err := model.NewSegmentDecoder(dataEncoding)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tr, err := objDecoder.PrepareForRead(obj)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dropped := trace.TruncateOldestSpans(tr, maxBytes)
+	if dropped == 0 {
+		return obj, 0, nil
+	}
+
+	// objects of encodings without a fast range are written without start and end
+	start, end, _ := objDecoder.FastRange(obj)
+
+	segment, err := segmentDecoder.PrepareForWrite(tr, start, end)
+	if err != nil {
+		return nil, 0, err
+	}
+	truncatedObj, err := segmentDecoder.ToObject([][]byte{segment})
+	if err != nil {
+		return nil, 0, err
+	}
+	return truncatedObj, dropped, nil
+}
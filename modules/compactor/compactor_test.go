@@ -1,6 +1,7 @@
 package compactor
 
 import (
+	"fmt"
 	"math"
 	"testing"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/encoding/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -71,7 +73,76 @@ func TestCombineLimitsHit(t *testing.T) {
 	actual, wasCombined, err := c.Combine(model.CurrentEncoding, "test", obj1, obj2)
 	assert.NoError(t, err)
 	assert.Equal(t, true, wasCombined)
-	assert.Equal(t, encode(t, t1), actual) // only t1 was returned b/c the combined trace was greater than the threshold
+	// the oldest spans are dropped b/c the combined trace was greater than the threshold, the newest span is kept
+	assert.Equal(t, 1, countSpans(model.CurrentEncoding, actual))
+}
+
+func TestCombineStrategies(t *testing.T) {
+	trace := test.MakeTraceWithSpanCount(2, 10, nil)
+	t1 := &tempopb.Trace{
+		Batches: []*v1.ResourceSpans{
+			trace.Batches[0],
+		},
+	}
+	t2 := &tempopb.Trace{
+		Batches: []*v1.ResourceSpans{
+			trace.Batches[1],
+		},
+	}
+	obj1 := encode(t, t1)
+	obj2 := encode(t, t2)
+
+	tests := []struct {
+		strategy common.CombineStrategy
+		maxBytes int
+		expected []byte // nil if the trace is truncated
+	}{
+		{
+			strategy: common.CombineStrategyMergeAll,
+			maxBytes: 1,
+			expected: encode(t, trace), // entire trace is returned regardless of the limit
+		},
+		{
+			strategy: common.CombineStrategyLatestWins,
+			maxBytes: math.MaxInt,
+			expected: obj2, // the object of the latest block is returned
+		},
+		{
+			strategy: common.CombineStrategySizeCapped,
+			maxBytes: math.MaxInt,
+			expected: encode(t, trace),
+		},
+		{
+			strategy: common.CombineStrategySizeCapped,
+			maxBytes: len(encode(t, trace)) / 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tc.strategy, tc.maxBytes), func(t *testing.T) {
+			o, err := overrides.NewOverrides(overrides.Limits{
+				MaxBytesPerTrace:          tc.maxBytes,
+				CompactionCombineStrategy: string(tc.strategy),
+			})
+			require.NoError(t, err)
+
+			c := &Compactor{
+				overrides: o,
+			}
+
+			actual, _, err := c.Combine(model.CurrentEncoding, "test", obj1, obj2)
+			require.NoError(t, err)
+			if tc.expected != nil {
+				assert.Equal(t, tc.expected, actual)
+				return
+			}
+
+			// the trace was truncated
+			assert.LessOrEqual(t, len(actual), tc.maxBytes)
+			assert.Less(t, countSpans(model.CurrentEncoding, actual), 20)
+			assert.Greater(t, countSpans(model.CurrentEncoding, actual), 0)
+		})
+	}
 }
 
 func TestCombineDoesntEnforceZero(t *testing.T) {
@@ -16,6 +16,7 @@ type Config struct {
 	ShardingRing    RingConfig              `yaml:"ring,omitempty"`
 	Compactor       tempodb.CompactorConfig `yaml:"compaction"`
 	OverrideRingKey string                  `yaml:"override_ring_key"`
+	Tracing         TracingConfig           `yaml:"tracing"`
 }
 
 // RegisterFlagsAndApplyDefaults registers the flags.
@@ -30,6 +31,8 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 		CompactionCycle:         tempodb.DefaultCompactionCycle,
 	}
 
+	cfg.Tracing.SamplingRatio = 1
+
 	flagext.DefaultValues(&cfg.ShardingRing)
 	cfg.ShardingRing.KVStore.Store = "" // by default compactor is not sharded
 
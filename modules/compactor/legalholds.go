@@ -0,0 +1,185 @@
+package compactor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const (
+	// urlParamIncludeLifted lists lifted holds next to the active ones
+	urlParamIncludeLifted = "includeLifted"
+
+	maxLegalHoldBodySize = 1 << 20
+)
+
+// LegalHolds serves the legal holds of a tenant. Every change is a read-modify-write of a single object per tenant.
+// Updates are serialized within a compactor, concurrent writes from different compactors are last write wins.
+type LegalHolds struct {
+	r      backend.Reader
+	w      backend.Writer
+	logger log.Logger
+
+	mtx sync.Mutex
+}
+
+// NewLegalHolds returns a new LegalHolds
+func NewLegalHolds(r backend.Reader, w backend.Writer, logger log.Logger) *LegalHolds {
+	return &LegalHolds{
+		r:      r,
+		w:      w,
+		logger: logger,
+	}
+}
+
+// LegalHoldsHandler lists (GET) or places (POST) legal holds. Lifted holds are only listed with includeLifted=true.
+func (l *LegalHolds) LegalHoldsHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		includeLifted := false
+		if s := r.URL.Query().Get(urlParamIncludeLifted); s != "" {
+			includeLifted, err = strconv.ParseBool(s)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", urlParamIncludeLifted, err), http.StatusBadRequest)
+				return
+			}
+		}
+
+		holds, err := l.r.LegalHolds(r.Context(), tenantID)
+		if err != nil {
+			l.writeError(w, tenantID, err)
+			return
+		}
+		list := make([]backend.LegalHold, 0, len(holds.Holds))
+		for _, h := range holds.Holds {
+			if includeLifted || h.Active() {
+				list = append(list, h)
+			}
+		}
+		writeJSON(w, list)
+	case http.MethodPost:
+		var h backend.LegalHold
+		err := json.NewDecoder(io.LimitReader(r.Body, maxLegalHoldBodySize)).Decode(&h)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode request body: %s", err), http.StatusBadRequest)
+			return
+		}
+		if err := h.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.ID = uuid.New().String()
+		h.CreatedAt = time.Now()
+		h.LiftedAt = time.Time{}
+
+		err = l.update(r.Context(), tenantID, func(holds *backend.LegalHolds) bool {
+			holds.Holds = append(holds.Holds, h)
+			return true
+		})
+		if err != nil {
+			l.writeError(w, tenantID, err)
+			return
+		}
+		writeJSON(w, h)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// LegalHoldHandler retrieves (GET) or lifts (DELETE) a single legal hold. Lifted holds are kept for audit.
+func (l *LegalHolds) LegalHoldHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id := mux.Vars(r)[api.URLParamLegalHoldID]
+
+	switch r.Method {
+	case http.MethodGet:
+		holds, err := l.r.LegalHolds(r.Context(), tenantID)
+		if err != nil {
+			l.writeError(w, tenantID, err)
+			return
+		}
+		i := holds.Find(id)
+		if i < 0 {
+			http.Error(w, fmt.Sprintf("legal hold %s not found", id), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, holds.Holds[i])
+	case http.MethodDelete:
+		var lifted *backend.LegalHold
+		err = l.update(r.Context(), tenantID, func(holds *backend.LegalHolds) bool {
+			i := holds.Find(id)
+			if i < 0 {
+				return false
+			}
+			if holds.Holds[i].Active() {
+				holds.Holds[i].LiftedAt = time.Now()
+			}
+			h := holds.Holds[i]
+			lifted = &h
+			return true
+		})
+		if err != nil {
+			l.writeError(w, tenantID, err)
+			return
+		}
+		if lifted == nil {
+			http.Error(w, fmt.Sprintf("legal hold %s not found", id), http.StatusNotFound)
+			return
+		}
+		level.Info(l.logger).Log("msg", "lifted legal hold", "tenant", tenantID, "id", id)
+		writeJSON(w, lifted)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// update performs a read-modify-write of the legal holds of a tenant. Nothing is written if fn returns false.
+func (l *LegalHolds) update(ctx context.Context, tenantID string, fn func(holds *backend.LegalHolds) bool) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	holds, err := l.r.LegalHolds(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	if !fn(holds) {
+		return nil
+	}
+
+	return l.w.WriteLegalHolds(ctx, tenantID, holds)
+}
+
+func (l *LegalHolds) writeError(w http.ResponseWriter, tenantID string, err error) {
+	level.Error(l.logger).Log("msg", "failed to access legal holds", "tenant", tenantID, "err", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+	_ = json.NewEncoder(w).Encode(v)
+}
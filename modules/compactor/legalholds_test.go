@@ -0,0 +1,118 @@
+package compactor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+)
+
+func newTestLegalHolds(t *testing.T) http.Handler {
+	r, w, _, err := local.New(&local.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+
+	l := NewLegalHolds(backend.NewReader(r), backend.NewWriter(w), log.NewNopLogger())
+
+	router := mux.NewRouter()
+	router.HandleFunc(api.PathLegalHolds, l.LegalHoldsHandler)
+	router.HandleFunc(api.PathLegalHold, l.LegalHoldHandler)
+	return router
+}
+
+func doLegalHoldsRequest(t *testing.T, h http.Handler, tenant, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req = req.WithContext(user.InjectOrgID(context.Background(), tenant))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeLegalHolds(t *testing.T, rec *httptest.ResponseRecorder) []backend.LegalHold {
+	var holds []backend.LegalHold
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &holds))
+	return holds
+}
+
+func TestLegalHolds(t *testing.T) {
+	h := newTestLegalHolds(t)
+	now := time.Now()
+
+	// empty
+	rec := doLegalHoldsRequest(t, h, "test", http.MethodGet, api.PathLegalHolds, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	// place
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodPost, api.PathLegalHolds, backend.LegalHold{TraceID: "0102", Reason: "case 1"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var traceHold backend.LegalHold
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &traceHold))
+	assert.NotEmpty(t, traceHold.ID)
+	assert.False(t, traceHold.CreatedAt.IsZero())
+
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodPost, api.PathLegalHolds, backend.LegalHold{Start: now.Add(-time.Hour), End: now})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// invalid
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodPost, api.PathLegalHolds, backend.LegalHold{TraceID: "xyz"})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// holds are per tenant
+	rec = doLegalHoldsRequest(t, h, "other", http.MethodGet, api.PathLegalHolds, nil)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodGet, api.PathLegalHolds, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, decodeLegalHolds(t, rec), 2)
+
+	// get
+	path := strings.Replace(api.PathLegalHold, "{id}", traceHold.ID, 1)
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "case 1")
+
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodGet, strings.Replace(api.PathLegalHold, "{id}", "missing", 1), nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// lift
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodDelete, path, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var lifted backend.LegalHold
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &lifted))
+	assert.False(t, lifted.Active())
+
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodDelete, strings.Replace(api.PathLegalHold, "{id}", "missing", 1), nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// lifted holds are only listed for audit
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodGet, api.PathLegalHolds, nil)
+	assert.Len(t, decodeLegalHolds(t, rec), 1)
+
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodGet, api.PathLegalHolds+"?includeLifted=true", nil)
+	assert.Len(t, decodeLegalHolds(t, rec), 2)
+
+	rec = doLegalHoldsRequest(t, h, "test", http.MethodGet, api.PathLegalHolds+"?includeLifted=foo", nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
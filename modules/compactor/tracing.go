@@ -0,0 +1,53 @@
+package compactor
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	ot_bridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/sdk/resource"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+
+	"github.com/grafana/tempo/cmd/tempo/build"
+)
+
+// TracingConfig configures the traces the compactor records of its own compaction jobs. They are sent to their
+// own endpoint, independent of the tracing of the process, so slow compactions can be inspected in Tempo itself.
+type TracingConfig struct {
+	// Endpoint is the Jaeger thrift http endpoint the traces are sent to, e.g. http://tempo:14268/api/traces.
+	// Compaction jobs are not traced if empty.
+	Endpoint string `yaml:"endpoint"`
+	// SamplingRatio is the ratio of compaction jobs that are traced.
+	SamplingRatio float64 `yaml:"sampling_ratio"`
+}
+
+// newJobTracer returns a tracer exporting to the configured endpoint and a func to flush and stop it
+func newJobTracer(cfg TracingConfig) (opentracing.Tracer, func(context.Context) error, error) {
+	exp, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(cfg.Endpoint)))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create compaction job trace exporter")
+	}
+
+	resources, err := resource.New(context.Background(),
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String("tempo-compactor"),
+			semconv.ServiceVersionKey.String(build.Version),
+		),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to initialise compaction job trace resources")
+	}
+
+	tp := tracesdk.NewTracerProvider(
+		tracesdk.WithBatcher(exp),
+		tracesdk.WithResource(resources),
+		tracesdk.WithSampler(tracesdk.TraceIDRatioBased(cfg.SamplingRatio)),
+	)
+
+	tracer, _ := ot_bridge.NewTracerPair(tp.Tracer("compactor"))
+	return tracer, tp.Shutdown, nil
+}
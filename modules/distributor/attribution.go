@@ -0,0 +1,142 @@
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"go.opentelemetry.io/collector/client"
+
+	"github.com/grafana/tempo/modules/distributor/receiver"
+	"github.com/grafana/tempo/modules/overrides"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+const (
+	attributeIngestionReceiver  = "tempo.ingestion.receiver"
+	attributeIngestionSourceIP  = "tempo.ingestion.source_ip"
+	attributeIngestionPrincipal = "tempo.ingestion.principal"
+)
+
+// AttributionConfig configures where the principal stamped on the resources of a push is read from. The auth data
+// of a receiver authenticator is preferred over the request metadata, which receivers only record with
+// include_metadata enabled.
+type AttributionConfig struct {
+	// PrincipalAuthAttribute is the attribute of the auth data holding the principal, e.g. subject
+	PrincipalAuthAttribute string `yaml:"principal_auth_attribute"`
+	// PrincipalMetadataKey is the request header or grpc metadata holding the principal, e.g. set by an auth proxy
+	PrincipalMetadataKey string `yaml:"principal_metadata_key"`
+}
+
+// attributionKeys are the resource attributes stamped for the values of the ingestion attribution override
+var attributionKeys = map[string]string{
+	overrides.IngestionAttributionReceiver:  attributeIngestionReceiver,
+	overrides.IngestionAttributionSourceIP:  attributeIngestionSourceIP,
+	overrides.IngestionAttributionPrincipal: attributeIngestionPrincipal,
+}
+
+// stampAttribution sets the attribution attributes of the tenant on the resources of the batches. The values are
+// taken from the context of the push. Attributes with the keys of the tenant's attribution sent by the client are
+// always removed so they can't be spoofed, even if the value is unknown for a push, e.g. the receiver of pushes not
+// received by a receiver. Unknown values are not set.
+func stampAttribution(ctx context.Context, cfg AttributionConfig, attribution map[string]struct{}, batches []*v1.ResourceSpans) {
+	strip := make(map[string]struct{}, len(attribution))
+	for a := range attribution {
+		if key, ok := attributionKeys[a]; ok {
+			strip[key] = struct{}{}
+		}
+	}
+	if len(strip) == 0 {
+		return
+	}
+
+	var attrs []*v1_common.KeyValue
+
+	if _, ok := attribution[overrides.IngestionAttributionReceiver]; ok {
+		if recv, ok := receiver.ExtractReceiver(ctx); ok {
+			attrs = append(attrs, stringAttribute(attributeIngestionReceiver, recv))
+		}
+	}
+
+	info := client.FromContext(ctx)
+	if _, ok := attribution[overrides.IngestionAttributionSourceIP]; ok {
+		if ip := sourceIP(info.Addr); ip != "" {
+			attrs = append(attrs, stringAttribute(attributeIngestionSourceIP, ip))
+		}
+	}
+	if _, ok := attribution[overrides.IngestionAttributionPrincipal]; ok {
+		if principal := principal(info, cfg); principal != "" {
+			attrs = append(attrs, stringAttribute(attributeIngestionPrincipal, principal))
+		}
+	}
+
+	for _, b := range batches {
+		if b.Resource == nil {
+			if len(attrs) == 0 {
+				continue
+			}
+			b.Resource = &v1_resource.Resource{}
+		}
+		b.Resource.Attributes = append(removeAttributes(b.Resource.Attributes, strip), attrs...)
+	}
+}
+
+func sourceIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case nil:
+		return ""
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.IPAddr:
+		return a.IP.String()
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+func principal(info client.Info, cfg AttributionConfig) string {
+	if info.Auth != nil && cfg.PrincipalAuthAttribute != "" {
+		switch v := info.Auth.GetAttribute(cfg.PrincipalAuthAttribute).(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case fmt.Stringer:
+			return v.String()
+		}
+	}
+
+	if cfg.PrincipalMetadataKey != "" {
+		if values := info.Metadata.Get(cfg.PrincipalMetadataKey); len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	return ""
+}
+
+// removeAttributes removes the attributes with the keys in place
+func removeAttributes(attrs []*v1_common.KeyValue, keys map[string]struct{}) []*v1_common.KeyValue {
+	kept := attrs[:0]
+	for _, a := range attrs {
+		if _, ok := keys[a.Key]; !ok {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+func stringAttribute(key, value string) *v1_common.KeyValue {
+	return &v1_common.KeyValue{
+		Key: key,
+		Value: &v1_common.AnyValue{
+			Value: &v1_common.AnyValue_StringValue{StringValue: value},
+		},
+	}
+}
@@ -0,0 +1,146 @@
+package distributor
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/client"
+
+	"github.com/grafana/tempo/modules/distributor/receiver"
+	"github.com/grafana/tempo/modules/overrides"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+type fakeAuthData map[string]interface{}
+
+func (a fakeAuthData) GetAttribute(name string) interface{} {
+	return a[name]
+}
+
+func (a fakeAuthData) GetAttributeNames() []string {
+	names := make([]string, 0, len(a))
+	for n := range a {
+		names = append(names, n)
+	}
+	return names
+}
+
+func TestStampAttribution(t *testing.T) {
+	cfg := AttributionConfig{
+		PrincipalAuthAttribute: "subject",
+		PrincipalMetadataKey:   "x-api-key-name",
+	}
+	all := map[string]struct{}{overrides.IngestionAttributionReceiver: {}, overrides.IngestionAttributionSourceIP: {}, overrides.IngestionAttributionPrincipal: {}}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		attribution map[string]struct{}
+		resource    *v1_resource.Resource
+		expected    []*v1_common.KeyValue
+	}{
+		{
+			name: "all",
+			ctx: client.NewContext(receiver.InjectReceiver(context.Background(), "otlp"), client.Info{
+				Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4317},
+				Auth: fakeAuthData{"subject": "key-1"},
+			}),
+			attribution: all,
+			resource: &v1_resource.Resource{
+				Attributes: []*v1_common.KeyValue{stringAttribute("service.name", "svc")},
+			},
+			expected: []*v1_common.KeyValue{
+				stringAttribute("service.name", "svc"),
+				stringAttribute(attributeIngestionReceiver, "otlp"),
+				stringAttribute(attributeIngestionSourceIP, "10.0.0.1"),
+				stringAttribute(attributeIngestionPrincipal, "key-1"),
+			},
+		},
+		{
+			name: "spoofed attributes are overwritten",
+			ctx: client.NewContext(context.Background(), client.Info{
+				Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4317},
+			}),
+			attribution: map[string]struct{}{overrides.IngestionAttributionSourceIP: {}},
+			resource: &v1_resource.Resource{
+				Attributes: []*v1_common.KeyValue{stringAttribute(attributeIngestionSourceIP, "1.2.3.4")},
+			},
+			expected: []*v1_common.KeyValue{
+				stringAttribute(attributeIngestionSourceIP, "10.0.0.1"),
+			},
+		},
+		{
+			name:        "spoofed attributes are removed if the value is unknown",
+			ctx:         context.Background(),
+			attribution: all,
+			resource: &v1_resource.Resource{
+				Attributes: []*v1_common.KeyValue{
+					stringAttribute(attributeIngestionReceiver, "otlp"),
+					stringAttribute("service.name", "svc"),
+					stringAttribute(attributeIngestionPrincipal, "admin"),
+				},
+			},
+			expected: []*v1_common.KeyValue{
+				stringAttribute("service.name", "svc"),
+			},
+		},
+		{
+			name: "attributes of other values are kept",
+			ctx: client.NewContext(context.Background(), client.Info{
+				Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4317},
+			}),
+			attribution: map[string]struct{}{overrides.IngestionAttributionSourceIP: {}},
+			resource: &v1_resource.Resource{
+				Attributes: []*v1_common.KeyValue{stringAttribute(attributeIngestionPrincipal, "admin")},
+			},
+			expected: []*v1_common.KeyValue{
+				stringAttribute(attributeIngestionPrincipal, "admin"),
+				stringAttribute(attributeIngestionSourceIP, "10.0.0.1"),
+			},
+		},
+		{
+			name: "principal from metadata without auth data",
+			ctx: client.NewContext(context.Background(), client.Info{
+				Addr:     &net.UnixAddr{Name: "/tmp/sock"},
+				Metadata: client.NewMetadata(map[string][]string{"x-api-key-name": {"key-2"}}),
+			}),
+			attribution: all,
+			expected: []*v1_common.KeyValue{
+				stringAttribute(attributeIngestionSourceIP, "/tmp/sock"),
+				stringAttribute(attributeIngestionPrincipal, "key-2"),
+			},
+		},
+		{
+			name:        "unknown values are not set",
+			ctx:         context.Background(),
+			attribution: all,
+			resource:    &v1_resource.Resource{},
+			expected:    nil,
+		},
+		{
+			name: "not enabled",
+			ctx: client.NewContext(receiver.InjectReceiver(context.Background(), "otlp"), client.Info{
+				Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 4317},
+			}),
+			resource: &v1_resource.Resource{},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &v1.ResourceSpans{Resource: tc.resource}
+			stampAttribution(tc.ctx, cfg, tc.attribution, []*v1.ResourceSpans{b})
+
+			var actual []*v1_common.KeyValue
+			if b.Resource != nil {
+				actual = b.Resource.Attributes
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/grafana/dskit/flagext"
 	ring_client "github.com/grafana/dskit/ring/client"
+	"github.com/grafana/tempo/pkg/endpointslices"
 	"github.com/grafana/tempo/pkg/util"
 )
 
@@ -41,10 +42,54 @@ type Config struct {
 
 	SearchTagsDenyList []string `yaml:"search_tags_deny_list"`
 
+	// MaxInflightBytes is the max size of the batches this distributor is processing at once. Pushes above
+	// are rejected. 0 disables the limit.
+	MaxInflightBytes int64 `yaml:"max_inflight_bytes"`
+
+	IngesterDiscovery IngesterDiscoveryConfig `yaml:"ingester_discovery"`
+	IngesterBatching  IngesterBatchingConfig  `yaml:"ingester_batching"`
+	IngesterChunking  IngesterChunkingConfig  `yaml:"ingester_chunking"`
+
+	TenantMapping TenantMappingConfig `yaml:"tenant_mapping"`
+
+	// Attribution configures the principal of the ingestion attribution override
+	Attribution AttributionConfig `yaml:"attribution"`
+
 	// For testing.
 	factory func(addr string) (ring_client.PoolClient, error) `yaml:"-"`
 }
 
+const (
+	// IngesterDiscoveryRing connects to the addresses ingesters registered in the ring
+	IngesterDiscoveryRing = "ring"
+	// IngesterDiscoveryEndpointSlices connects to the current addresses of ingesters in the EndpointSlices of
+	// their Kubernetes service
+	IngesterDiscoveryEndpointSlices = "endpointslices"
+)
+
+// IngesterDiscoveryConfig configures how the addresses of ingesters are resolved. The ring is always used to
+// decide which ingesters receive a trace.
+type IngesterDiscoveryConfig struct {
+	Mode           string                `yaml:"mode"`
+	EndpointSlices endpointslices.Config `yaml:"endpoint_slices"`
+}
+
+// IngesterBatchingConfig configures combining the traces of concurrent pushes destined for the same ingester
+// into fewer calls. A batch is sent once it reaches MaxBatchBytes or is MaxWait old.
+type IngesterBatchingConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxBatchBytes int           `yaml:"max_batch_bytes"`
+	MaxWait       time.Duration `yaml:"max_wait"`
+}
+
+// IngesterChunkingConfig configures splitting pushes to an ingester that exceed its gRPC message size limit into
+// several calls. Traces larger than MaxMessageBytes are split into chunks of spans that are combined again by the
+// ingester.
+type IngesterChunkingConfig struct {
+	Enabled         bool `yaml:"enabled"`
+	MaxMessageBytes int  `yaml:"max_message_bytes"`
+}
+
 type LogReceivedSpansConfig struct {
 	Enabled              bool `yaml:"enabled"`
 	IncludeAllAttributes bool `yaml:"include_all_attributes"`
@@ -60,6 +105,17 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	cfg.OverrideRingKey = distributorRingKey
 	cfg.ExtendWrites = true
 
+	cfg.IngesterDiscovery.Mode = IngesterDiscoveryRing
+	cfg.IngesterDiscovery.EndpointSlices.ApplyDefaults()
+
+	f.BoolVar(&cfg.IngesterBatching.Enabled, util.PrefixConfig(prefix, "ingester-batching.enabled"), false, "Enable to combine concurrent pushes to the same ingester into batches.")
+	f.IntVar(&cfg.IngesterBatching.MaxBatchBytes, util.PrefixConfig(prefix, "ingester-batching.max-batch-bytes"), 1024*1024, "Size in bytes at which a batch is sent to the ingester.")
+	f.DurationVar(&cfg.IngesterBatching.MaxWait, util.PrefixConfig(prefix, "ingester-batching.max-wait"), 5*time.Millisecond, "Max time a push waits for other pushes to the same ingester before its batch is sent.")
+	f.BoolVar(&cfg.IngesterChunking.Enabled, util.PrefixConfig(prefix, "ingester-chunking.enabled"), false, "Enable to split pushes to an ingester that exceed the max message size into several calls.")
+	f.IntVar(&cfg.IngesterChunking.MaxMessageBytes, util.PrefixConfig(prefix, "ingester-chunking.max-message-bytes"), 4*1024*1024, "Max size in bytes of a push to an ingester. Should not exceed the grpc_server_max_recv_msg_size of the ingesters.")
+	f.StringVar(&cfg.Attribution.PrincipalAuthAttribute, util.PrefixConfig(prefix, "attribution.principal-auth-attribute"), "subject", "Attribute of the auth data of receiver authenticators stamped as the principal of pushes of tenants with ingestion attribution.")
+	f.StringVar(&cfg.Attribution.PrincipalMetadataKey, util.PrefixConfig(prefix, "attribution.principal-metadata-key"), "", "Request header or grpc metadata stamped as the principal of pushes of tenants with ingestion attribution if there's no auth data. Requires include_metadata on the receivers.")
+	f.Int64Var(&cfg.MaxInflightBytes, util.PrefixConfig(prefix, "max-inflight-bytes"), 0, "Max size of the batches being processed by a distributor at once, pushes above are rejected. 0 to disable.")
 	f.BoolVar(&cfg.LogReceivedTraces, util.PrefixConfig(prefix, "log-received-traces"), false, "Enable to log every received trace id to help debug ingestion.")
 	f.BoolVar(&cfg.LogReceivedSpans.Enabled, util.PrefixConfig(prefix, "log-received-spans.enabled"), false, "Enable to log every received span to help debug ingestion or calculate span error distributions using the logs.")
 	f.BoolVar(&cfg.LogReceivedSpans.IncludeAllAttributes, util.PrefixConfig(prefix, "log-received-spans.include-attributes"), false, "Enable to include span attributes in the logs.")
@@ -5,11 +5,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/gogo/googleapis/google/rpc"
 	"github.com/gogo/status"
 	"github.com/grafana/dskit/limiter"
 	"github.com/grafana/dskit/ring"
@@ -24,6 +26,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/weaveworks/common/logging"
 	"github.com/weaveworks/common/user"
+	"go.uber.org/atomic"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health/grpc_health_v1"
 
@@ -31,6 +34,7 @@ import (
 	generator_client "github.com/grafana/tempo/modules/generator/client"
 	ingester_client "github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/endpointslices"
 	_ "github.com/grafana/tempo/pkg/gogocodec" // force gogo codec registration
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/tempopb"
@@ -43,10 +47,20 @@ import (
 const (
 	// reasonRateLimited indicates that the tenants spans/second exceeded their limits
 	reasonRateLimited = "rate_limited"
+	// reasonIngestionPaused indicates that ingestion is paused for the tenant
+	reasonIngestionPaused = "ingestion_paused"
 	// reasonTraceTooLarge indicates that a single trace has too many spans
-	reasonTraceTooLarge = "trace_too_large"
+	reasonTraceTooLarge = overrides.ReasonTraceTooLarge
+	// reasonInflightBytesExceeded indicates that the distributor is already processing too many bytes
+	reasonInflightBytesExceeded = "inflight_bytes_exceeded"
 	// reasonLiveTracesExceeded indicates that tempo is already tracking too many live traces in the ingesters for this user
 	reasonLiveTracesExceeded = "live_traces_exceeded"
+	// reasonTenantDenied indicates that the tenant is denied by the tenant mapping
+	reasonTenantDenied = "tenant_denied"
+	// reasonReceiverNotAllowed indicates that the tenant may not use the receiver the spans were received with
+	reasonReceiverNotAllowed = "receiver_not_allowed"
+	// reasonWALFull indicates that the wal of an ingester reached its max disk usage
+	reasonWALFull = "wal_full"
 	// reasonInternalError indicates an unexpected error occurred processing these spans. analogous to a 500
 	reasonInternalError = "internal_error"
 
@@ -84,6 +98,22 @@ var (
 		Name:      "distributor_bytes_received_total",
 		Help:      "The total number of proto bytes received per tenant",
 	}, []string{"tenant"})
+	metricInflightBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "distributor_inflight_bytes",
+		Help:      "The total size of the batches currently being processed.",
+	})
+	metricInflightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "distributor_inflight_requests",
+		Help:      "The number of pushes currently being processed.",
+	})
+	metricRequestBytes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempo",
+		Name:      "distributor_request_bytes",
+		Help:      "The size of the batches of each push.",
+		Buckets:   prometheus.ExponentialBuckets(1024, 4, 10),
+	})
 	metricTracesPerBatch = promauto.NewHistogram(prometheus.HistogramOpts{
 		Namespace: "tempo",
 		Name:      "distributor_traces_per_batch",
@@ -122,6 +152,13 @@ type Distributor struct {
 	overrides       *overrides.Overrides
 	traceEncoder    model.SegmentDecoder
 
+	// resolves the current addresses of ingesters, nil if the ring addresses are used
+	ingesterEndpoints *endpointslices.Watcher
+	// combines concurrent pushes to the same ingester, nil if batching is disabled
+	ingesterBatcher *ingesterBatcher
+	// rewrites the tenant of pushes, nil if no tenants are mapped
+	tenantMapper *tenantMapper
+
 	// search
 	searchEnabled    bool
 	globalTagsToDrop map[string]struct{}
@@ -135,6 +172,13 @@ type Distributor struct {
 
 	// Per-user rate limiter.
 	ingestionRateLimiter *limiter.RateLimiter
+	ingestionRates       *ingestionRates
+
+	// received spans and bytes per service of the tenants with per service metrics
+	serviceMetrics *serviceMetrics
+
+	// size of the batches currently being processed
+	inflightBytes atomic.Int64
 
 	// Manager for subservices
 	subservices        *services.Manager
@@ -177,15 +221,36 @@ func New(cfg Config, clientCfg ingester_client.Config, ingestersRing ring.ReadRi
 		ingestionRateStrategy = newLocalIngestionRateStrategy(o)
 	}
 
+	var ingesterEndpoints *endpointslices.Watcher
+	ingesterDiscovery := ring_client.NewRingServiceDiscovery(ingestersRing)
+	switch cfg.IngesterDiscovery.Mode {
+	case IngesterDiscoveryRing, "":
+	case IngesterDiscoveryEndpointSlices:
+		w, err := endpointslices.NewWatcher(cfg.IngesterDiscovery.EndpointSlices, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ingester endpoint slices watcher %w", err)
+		}
+		ingesterEndpoints = w
+		ingesterDiscovery = endpointSlicesServiceDiscovery(ingesterDiscovery, w)
+		subservices = append(subservices, w)
+	default:
+		return nil, fmt.Errorf("unknown ingester discovery mode %s", cfg.IngesterDiscovery.Mode)
+	}
+
 	pool := ring_client.NewPool("distributor_pool",
 		clientCfg.PoolConfig,
-		ring_client.NewRingServiceDiscovery(ingestersRing),
+		ingesterDiscovery,
 		factory,
 		metricIngesterClients,
 		logger)
 
 	subservices = append(subservices, pool)
 
+	tenantMapper, err := newTenantMapper(cfg.TenantMapping)
+	if err != nil {
+		return nil, err
+	}
+
 	// turn list into map for efficient checking
 	tagsToDrop := map[string]struct{}{}
 	for _, tag := range cfg.SearchTagsDenyList {
@@ -197,8 +262,12 @@ func New(cfg Config, clientCfg ingester_client.Config, ingestersRing ring.ReadRi
 		clientCfg:               clientCfg,
 		ingestersRing:           ingestersRing,
 		pool:                    pool,
+		ingesterEndpoints:       ingesterEndpoints,
+		tenantMapper:            tenantMapper,
 		DistributorRing:         distributorRing,
 		ingestionRateLimiter:    limiter.NewRateLimiter(ingestionRateStrategy, 10*time.Second),
+		ingestionRates:          newIngestionRates(10 * time.Second),
+		serviceMetrics:          newServiceMetrics(serviceRankInterval),
 		searchEnabled:           searchEnabled,
 		metricsGeneratorEnabled: metricsGeneratorEnabled,
 		generatorClientCfg:      generatorClientCfg,
@@ -209,6 +278,15 @@ func New(cfg Config, clientCfg ingester_client.Config, ingestersRing ring.ReadRi
 		logger:                  logger,
 	}
 
+	if cfg.IngesterBatching.Enabled {
+		d.ingesterBatcher = newIngesterBatcher(cfg.IngesterBatching, d.pushToIngester)
+		subservices = append(subservices, d.ingesterBatcher)
+	}
+
+	o.RegisterUsage(overrides.MetricIngestionRateLimitBytes, func() map[string]float64 {
+		return d.ingestionRates.rates(time.Now())
+	})
+
 	if metricsGeneratorEnabled {
 		d.generatorsPool = ring_client.NewPool(
 			"distributor_metrics_generator_pool",
@@ -304,10 +382,69 @@ func (d *Distributor) PushBatches(ctx context.Context, batches []*v1.ResourceSpa
 	if spanCount == 0 {
 		return &tempopb.PushResponse{}, nil
 	}
+
+	// map the tenant before anything is recorded for it
+	if d.tenantMapper != nil {
+		mapped, ok := d.tenantMapper.mapTenant(userID)
+		if !ok {
+			overrides.RecordDiscardedSpans(spanCount, reasonTenantDenied, userID)
+			return nil, status.Errorf(codes.PermissionDenied,
+				"%s tenant %s is denied",
+				overrides.ErrorPrefixTenantDenied,
+				userID)
+		}
+		if mapped != userID {
+			userID = mapped
+			ctx = user.InjectOrgID(ctx, userID)
+		}
+	}
+
 	metricBytesIngested.WithLabelValues(userID).Add(float64(size))
 	metricSpansIngested.WithLabelValues(userID).Add(float64(spanCount))
+	metricRequestBytes.Observe(float64(size))
+	d.ingestionRates.add(time.Now(), userID, size)
+	d.serviceMetrics.observe(time.Now(), userID, d.overrides.IngestionServiceMetricsMaxServices(userID), batches)
+
+	// the batches are already decoded, count them while they are processed
+	inflight := d.inflightBytes.Add(int64(size))
+	metricInflightBytes.Set(float64(inflight))
+	metricInflightRequests.Inc()
+	defer func() {
+		metricInflightBytes.Set(float64(d.inflightBytes.Sub(int64(size))))
+		metricInflightRequests.Dec()
+	}()
+
+	if d.cfg.MaxInflightBytes > 0 && inflight > d.cfg.MaxInflightBytes {
+		overrides.RecordDiscardedSpans(spanCount, reasonInflightBytesExceeded, userID)
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"%s max inflight bytes (%d bytes) exceeded while adding %d bytes",
+			overrides.ErrorPrefixInflightBytesExceeded,
+			d.cfg.MaxInflightBytes,
+			size)
+	}
 
 	// check limits
+	if recv, ok := receiver.ExtractReceiver(ctx); ok {
+		if allowed := d.overrides.AllowedReceivers(userID); len(allowed) > 0 {
+			if _, ok := allowed[recv]; !ok {
+				overrides.RecordDiscardedSpans(spanCount, reasonReceiverNotAllowed, userID)
+				return nil, status.Errorf(codes.PermissionDenied,
+					"%s receiver %s is not allowed for tenant %s",
+					overrides.ErrorPrefixReceiverNotAllowed,
+					recv,
+					userID)
+			}
+		}
+	}
+
+	if d.overrides.IngestionPaused(userID) {
+		overrides.RecordDiscardedSpans(spanCount, reasonIngestionPaused, userID)
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"%s ingestion is paused for tenant %s",
+			overrides.ErrorPrefixIngestionPaused,
+			userID)
+	}
+
 	now := time.Now()
 	if !d.ingestionRateLimiter.AllowN(now, userID, size) {
 		overrides.RecordDiscardedSpans(spanCount, reasonRateLimited, userID)
@@ -318,6 +455,10 @@ func (d *Distributor) PushBatches(ctx context.Context, batches []*v1.ResourceSpa
 			size)
 	}
 
+	if attribution := d.overrides.IngestionAttribution(userID); len(attribution) > 0 {
+		stampAttribution(ctx, d.cfg.Attribution, attribution, batches)
+	}
+
 	keys, rebatchedTraces, err := requestsByTraceID(batches, userID, spanCount)
 	if err != nil {
 		overrides.RecordDiscardedSpans(spanCount, reasonInternalError, userID)
@@ -343,8 +484,10 @@ func (d *Distributor) PushBatches(ctx context.Context, batches []*v1.ResourceSpa
 
 	err = d.sendToIngestersViaBytes(ctx, userID, rebatchedTraces, searchData, keys)
 	if err != nil {
-		recordDiscaredSpans(err, userID, spanCount)
-		return nil, err
+		// a push the ingesters partially accepted succeeds, its discarded spans are only reported in the metrics
+		if !recordDiscaredSpans(err, userID, spanCount) {
+			return nil, err
+		}
 	}
 
 	if d.metricsGeneratorEnabled && len(d.overrides.MetricsGeneratorProcessors(userID)) > 0 {
@@ -355,14 +498,29 @@ func (d *Distributor) PushBatches(ctx context.Context, batches []*v1.ResourceSpa
 }
 
 func (d *Distributor) sendToIngestersViaBytes(ctx context.Context, userID string, traces []*rebatchedTrace, searchData [][]byte, keys []uint32) error {
-	// Marshal to bytes once
-	marshalledTraces := make([][]byte, len(traces))
+	maxBytes := 0
+	if d.cfg.IngesterChunking.Enabled {
+		maxBytes = d.cfg.IngesterChunking.MaxMessageBytes
+	}
+
+	// Marshal to bytes once. Traces larger than the max message size are marshalled in chunks.
+	marshalledTraces := make([][][]byte, len(traces))
 	for i, t := range traces {
-		b, err := d.traceEncoder.PrepareForWrite(t.trace, t.start, t.end)
-		if err != nil {
-			return errors.Wrap(err, "failed to marshal PushRequest")
+		chunkBytes := 0
+		if maxBytes > 0 {
+			chunkBytes = maxBytes - len(t.id) - requestItemOverhead
+			if len(searchData) > i {
+				chunkBytes -= len(searchData[i])
+			}
+		}
+
+		for _, chunk := range chunkTrace(t.trace, chunkBytes) {
+			b, err := d.traceEncoder.PrepareForWrite(chunk, t.start, t.end)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal PushRequest")
+			}
+			marshalledTraces[i] = append(marshalledTraces[i], b)
 		}
-		marshalledTraces[i] = b
 	}
 
 	op := ring.WriteNoExtend
@@ -370,40 +528,58 @@ func (d *Distributor) sendToIngestersViaBytes(ctx context.Context, userID string
 		op = ring.Write
 	}
 
+	// DoBatch returns once a quorum of ingesters acknowledged the push. With durable acks they only do so once the
+	// push is synced to disk.
+	durableAck := d.overrides.IngestionDurableAck(userID)
+	if durableAck {
+		ctx = ingester_client.WithDurableAck(ctx)
+	}
+
 	err := ring.DoBatch(ctx, op, d.ingestersRing, keys, func(ingester ring.InstanceDesc, indexes []int) error {
-		localCtx, cancel := context.WithTimeout(ctx, d.clientCfg.RemoteTimeout)
-		defer cancel()
-		localCtx = user.InjectOrgID(localCtx, userID)
+		reqs := ingesterRequests(traces, marshalledTraces, searchData, indexes, maxBytes)
 
-		req := tempopb.PushBytesRequest{
-			Traces:     make([]tempopb.PreallocBytes, len(indexes)),
-			Ids:        make([]tempopb.PreallocBytes, len(indexes)),
-			SearchData: make([]tempopb.PreallocBytes, len(indexes)),
+		addr, err := d.ingesterAddr(ingester)
+		if err != nil {
+			metricIngesterAppendFailures.WithLabelValues(ingester.Addr).Inc()
+			return err
 		}
 
-		for i, j := range indexes {
-			req.Traces[i].Slice = marshalledTraces[j][0:]
-			req.Ids[i].Slice = traces[j].id
+		// batched pushes are sent with the context of the batch, durable pushes bypass the batcher to keep their
+		// metadata
+		if len(reqs) == 1 && d.ingesterBatcher != nil && !durableAck {
+			return d.ingesterBatcher.Push(ctx, addr, userID, reqs[0])
+		}
 
-			// Search data optional
-			if len(searchData) > j {
-				req.SearchData[i].Slice = searchData[j]
+		// chunked requests bypass the batcher so they aren't combined above the max message size again. They are
+		// pushed one after the other to keep the chunks of a trace in order.
+		for _, req := range reqs {
+			err = d.pushToIngester(ctx, addr, userID, req)
+			if err != nil {
+				return err
 			}
 		}
+		return nil
+	}, func() {})
 
-		c, err := d.pool.GetClientFor(ingester.Addr)
-		if err != nil {
-			return err
-		}
+	return err
+}
 
-		_, err = c.(tempopb.PusherClient).PushBytesV2(localCtx, &req)
-		metricIngesterAppends.WithLabelValues(ingester.Addr).Inc()
-		if err != nil {
-			metricIngesterAppendFailures.WithLabelValues(ingester.Addr).Inc()
-		}
+// pushToIngester sends a single PushBytesV2 call to the ingester at addr
+func (d *Distributor) pushToIngester(ctx context.Context, addr string, userID string, req *tempopb.PushBytesRequest) error {
+	localCtx, cancel := context.WithTimeout(ctx, d.clientCfg.RemoteTimeout)
+	defer cancel()
+	localCtx = user.InjectOrgID(localCtx, userID)
+
+	c, err := d.pool.GetClientFor(addr)
+	if err != nil {
 		return err
-	}, func() {})
+	}
 
+	_, err = c.(tempopb.PusherClient).PushBytesV2(localCtx, req)
+	metricIngesterAppends.WithLabelValues(addr).Inc()
+	if err != nil {
+		metricIngesterAppendFailures.WithLabelValues(addr).Inc()
+	}
 	return err
 }
 
@@ -524,20 +700,58 @@ func requestsByTraceID(batches []*v1.ResourceSpans, userID string, spanCount int
 	return keys, traces, nil
 }
 
-func recordDiscaredSpans(err error, userID string, spanCount int) {
+// recordDiscaredSpans records the spans discarded by a failed push. It returns true if the ingesters only discarded
+// some of the spans of the push and accepted the rest.
+func recordDiscaredSpans(err error, userID string, spanCount int) bool {
 	s := status.Convert(err)
 	if s == nil {
-		return
+		return false
 	}
 	desc := s.Message()
 
 	if strings.HasPrefix(desc, overrides.ErrorPrefixLiveTracesExceeded) {
 		overrides.RecordDiscardedSpans(spanCount, reasonLiveTracesExceeded, userID)
-	} else if strings.HasPrefix(desc, overrides.ErrorPrefixTraceTooLarge) {
+	} else if strings.HasPrefix(desc, overrides.ErrorPrefixIngestionPaused) {
+		overrides.RecordDiscardedSpans(spanCount, reasonIngestionPaused, userID)
+	} else if strings.HasPrefix(desc, overrides.ErrorPrefixWALFull) {
+		overrides.RecordDiscardedSpans(spanCount, reasonWALFull, userID)
+	} else if strings.HasPrefix(desc, overrides.ErrorPrefixTraceTooLarge) || strings.HasPrefix(desc, overrides.ErrorPrefixTraceTruncated) {
+		// the ingester only discards the spans that exceed the trace limit and reports them per reason
+		if discarded, ok := discardedSpansFromStatus(s); ok {
+			total := 0
+			for reason, count := range discarded {
+				overrides.RecordDiscardedSpans(count, reason, userID)
+				total += count
+			}
+			return total < spanCount
+		}
 		overrides.RecordDiscardedSpans(spanCount, reasonTraceTooLarge, userID)
 	} else {
 		overrides.RecordDiscardedSpans(spanCount, reasonInternalError, userID)
 	}
+	return false
+}
+
+// discardedSpansFromStatus returns the number of spans discarded per reason attached by the ingester to a status
+func discardedSpansFromStatus(s *status.Status) (map[string]int, bool) {
+	for _, d := range s.Details() {
+		info, ok := d.(*rpc.ErrorInfo)
+		if !ok || info.Reason != overrides.ErrorInfoReasonDiscardedSpans {
+			continue
+		}
+
+		discarded := make(map[string]int, len(info.Metadata))
+		for reason, v := range info.Metadata {
+			count, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, false
+			}
+			discarded[reason] = count
+		}
+		return discarded, true
+	}
+
+	return nil, false
 }
 
 func logSpans(batches []*v1.ResourceSpans, filterByStatusError bool, logger log.Logger) {
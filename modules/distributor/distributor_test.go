@@ -14,6 +14,7 @@ import (
 
 	"github.com/go-kit/log"
 	kitlog "github.com/go-kit/log"
+	"github.com/gogo/googleapis/google/rpc"
 	"github.com/gogo/status"
 	"github.com/golang/protobuf/proto" //nolint: all  //ProtoReflect
 	"github.com/grafana/dskit/flagext"
@@ -21,6 +22,7 @@ import (
 	"github.com/grafana/dskit/ring"
 	ring_client "github.com/grafana/dskit/ring/client"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/weaveworks/common/logging"
@@ -665,6 +667,154 @@ func TestDistributor(t *testing.T) {
 	}
 }
 
+func TestDistributorIngestionPaused(t *testing.T) {
+	limits := &overrides.Limits{}
+	flagext.DefaultValues(limits)
+	limits.IngestionPaused = true
+
+	d := prepare(t, limits, nil, nil)
+
+	b := test.MakeBatch(10, []byte{})
+	response, err := d.PushBatches(ctx, []*v1.ResourceSpans{b})
+	require.Nil(t, response)
+
+	s, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.FailedPrecondition, s.Code())
+	assert.True(t, strings.HasPrefix(s.Message(), overrides.ErrorPrefixIngestionPaused))
+}
+
+func TestDistributorAllowedReceivers(t *testing.T) {
+	limits := &overrides.Limits{}
+	flagext.DefaultValues(limits)
+	limits.AllowedReceivers = overrides.ListToMap{"otlp": {}}
+
+	d := prepare(t, limits, nil, nil)
+
+	b := test.MakeBatch(10, []byte{})
+	response, err := d.PushBatches(receiver.InjectReceiver(ctx, "jaeger"), []*v1.ResourceSpans{b})
+	require.Nil(t, response)
+
+	s, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.PermissionDenied, s.Code())
+	assert.True(t, strings.HasPrefix(s.Message(), overrides.ErrorPrefixReceiverNotAllowed))
+
+	_, err = d.PushBatches(receiver.InjectReceiver(ctx, "otlp"), []*v1.ResourceSpans{b})
+	require.NoError(t, err)
+
+	// batches that weren't received by a receiver are not checked
+	_, err = d.PushBatches(ctx, []*v1.ResourceSpans{b})
+	require.NoError(t, err)
+}
+
+func TestDistributorIngestionAttribution(t *testing.T) {
+	limits := &overrides.Limits{}
+	flagext.DefaultValues(limits)
+	limits.IngestionAttribution = overrides.ListToMap{overrides.IngestionAttributionReceiver: {}}
+
+	d := prepare(t, limits, nil, nil)
+
+	b := test.MakeBatch(10, []byte{})
+	_, err := d.PushBatches(receiver.InjectReceiver(ctx, "otlp"), []*v1.ResourceSpans{b})
+	require.NoError(t, err)
+	assert.Contains(t, b.Resource.Attributes, stringAttribute(attributeIngestionReceiver, "otlp"))
+}
+
+func TestDistributorTenantMapping(t *testing.T) {
+	limits := &overrides.Limits{}
+	flagext.DefaultValues(limits)
+
+	d := prepare(t, limits, nil, nil)
+	var err error
+	d.tenantMapper, err = newTenantMapper(TenantMappingConfig{
+		Rules:        []TenantMappingRule{{Source: "test", Target: "mapped"}},
+		DenyUnmapped: true,
+	})
+	require.NoError(t, err)
+
+	b := test.MakeBatch(10, []byte{})
+	bytesBefore := testutil.ToFloat64(metricBytesIngested.WithLabelValues("mapped"))
+	_, err = d.PushBatches(ctx, []*v1.ResourceSpans{b})
+	require.NoError(t, err)
+	assert.Equal(t, float64(b.Size()), testutil.ToFloat64(metricBytesIngested.WithLabelValues("mapped"))-bytesBefore)
+
+	response, err := d.PushBatches(user.InjectOrgID(context.Background(), "unknown"), []*v1.ResourceSpans{b})
+	require.Nil(t, response)
+
+	s, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.PermissionDenied, s.Code())
+	assert.True(t, strings.HasPrefix(s.Message(), overrides.ErrorPrefixTenantDenied))
+}
+
+func TestDistributorMaxInflightBytes(t *testing.T) {
+	limits := &overrides.Limits{}
+	flagext.DefaultValues(limits)
+
+	d := prepare(t, limits, nil, nil)
+	d.cfg.MaxInflightBytes = 100_000
+
+	b := test.MakeBatch(10, []byte{})
+	_, err := d.PushBatches(ctx, []*v1.ResourceSpans{b})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), d.inflightBytes.Load())
+
+	// other pushes are being processed
+	d.inflightBytes.Store(d.cfg.MaxInflightBytes - int64(b.Size()) + 1)
+
+	response, err := d.PushBatches(ctx, []*v1.ResourceSpans{b})
+	require.Nil(t, response)
+
+	s, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.ResourceExhausted, s.Code())
+	assert.True(t, strings.HasPrefix(s.Message(), overrides.ErrorPrefixInflightBytesExceeded))
+	assert.Equal(t, d.cfg.MaxInflightBytes-int64(b.Size())+1, d.inflightBytes.Load())
+}
+
+func TestDiscardedSpansFromStatus(t *testing.T) {
+	st, err := status.New(codes.FailedPrecondition, overrides.ErrorPrefixTraceTruncated+" truncated").WithDetails(&rpc.ErrorInfo{
+		Reason: overrides.ErrorInfoReasonDiscardedSpans,
+		Metadata: map[string]string{
+			overrides.ReasonTraceTooLarge:  "3",
+			overrides.ReasonTraceTruncated: "5",
+		},
+	})
+	require.NoError(t, err)
+
+	discarded, ok := discardedSpansFromStatus(status.Convert(st.Err()))
+	require.True(t, ok)
+	assert.Equal(t, map[string]int{
+		overrides.ReasonTraceTooLarge:  3,
+		overrides.ReasonTraceTruncated: 5,
+	}, discarded)
+
+	// errors from older ingesters don't have details
+	_, ok = discardedSpansFromStatus(status.Convert(status.Error(codes.FailedPrecondition, overrides.ErrorPrefixTraceTooLarge)))
+	assert.False(t, ok)
+}
+
+func TestRecordDiscardedSpans(t *testing.T) {
+	discardedStatus := func(msg string, truncated string) error {
+		st, err := status.New(codes.FailedPrecondition, msg).WithDetails(&rpc.ErrorInfo{
+			Reason:   overrides.ErrorInfoReasonDiscardedSpans,
+			Metadata: map[string]string{overrides.ReasonTraceTruncated: truncated},
+		})
+		require.NoError(t, err)
+		return st.Err()
+	}
+
+	// a push with spans accepted by the ingesters succeeds
+	assert.True(t, recordDiscaredSpans(discardedStatus(overrides.ErrorPrefixTraceTruncated+" truncated", "8"), "test", 10))
+	// the batcher reports the discarded spans of a batch to a single push
+	assert.True(t, recordDiscaredSpans(discardedStatus(overrides.ErrorPrefixTraceTruncated+" truncated", "0"), "test", 10))
+
+	assert.False(t, recordDiscaredSpans(discardedStatus(overrides.ErrorPrefixTraceTooLarge+" too large", "10"), "test", 10))
+	assert.False(t, recordDiscaredSpans(status.Error(codes.FailedPrecondition, overrides.ErrorPrefixTraceTooLarge), "test", 10))
+	assert.False(t, recordDiscaredSpans(status.Error(codes.FailedPrecondition, overrides.ErrorPrefixLiveTracesExceeded), "test", 10))
+}
+
 func TestLogSpans(t *testing.T) {
 	for i, tc := range []struct {
 		LogReceivedTraces       bool // Backwards compatibility with old config
@@ -0,0 +1,173 @@
+package distributor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/status"
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/tempopb"
+)
+
+const (
+	flushReasonSize     = "size"
+	flushReasonWait     = "wait"
+	flushReasonShutdown = "shutdown"
+)
+
+var (
+	metricIngesterBatchFlushes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_ingester_batch_flushes_total",
+		Help:      "The total number of batches flushed to ingesters by reason.",
+	}, []string{"reason"})
+	metricIngesterBatchPushes = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempo",
+		Name:      "distributor_ingester_batch_pushes",
+		Help:      "The number of pushes combined into each batch sent to an ingester.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+)
+
+type pushBytesFunc func(ctx context.Context, addr string, userID string, req *tempopb.PushBytesRequest) error
+
+type batchKey struct {
+	addr   string
+	userID string
+}
+
+// pendingBatch collects the traces of concurrent pushes to the same ingester and tenant until it's flushed
+type pendingBatch struct {
+	req    tempopb.PushBytesRequest
+	size   int
+	pushes int
+	timer  *time.Timer
+
+	done        chan struct{}
+	err         error
+	errReported atomic.Bool
+}
+
+// ingesterBatcher combines the traces of concurrent pushes destined for the same ingester into a single
+// PushBytesV2 call. A batch is flushed once it reaches the max size or max wait, every push waits for the
+// result of the batch it was added to.
+type ingesterBatcher struct {
+	services.Service
+
+	cfg  IngesterBatchingConfig
+	push pushBytesFunc
+
+	mtx     sync.Mutex
+	batches map[batchKey]*pendingBatch
+}
+
+func newIngesterBatcher(cfg IngesterBatchingConfig, push pushBytesFunc) *ingesterBatcher {
+	b := &ingesterBatcher{
+		cfg:     cfg,
+		push:    push,
+		batches: map[batchKey]*pendingBatch{},
+	}
+	b.Service = services.NewIdleService(nil, b.stop)
+	return b
+}
+
+// Push adds the traces of req to the pending batch of the ingester and waits until the batch was pushed.
+func (b *ingesterBatcher) Push(ctx context.Context, addr string, userID string, req *tempopb.PushBytesRequest) error {
+	key := batchKey{addr: addr, userID: userID}
+
+	b.mtx.Lock()
+	batch, ok := b.batches[key]
+	if !ok {
+		batch = &pendingBatch{done: make(chan struct{})}
+		batch.timer = time.AfterFunc(b.cfg.MaxWait, func() {
+			b.flush(key, batch, flushReasonWait)
+		})
+		b.batches[key] = batch
+	}
+	batch.req.Traces = append(batch.req.Traces, req.Traces...)
+	batch.req.Ids = append(batch.req.Ids, req.Ids...)
+	batch.req.SearchData = append(batch.req.SearchData, req.SearchData...)
+	batch.size += req.Size()
+	batch.pushes++
+	full := batch.size >= b.cfg.MaxBatchBytes
+	b.mtx.Unlock()
+
+	if full {
+		b.flush(key, batch, flushReasonSize)
+	}
+
+	select {
+	case <-batch.done:
+		return batch.result()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush pushes the batch if it's still pending for the key.
+func (b *ingesterBatcher) flush(key batchKey, batch *pendingBatch, reason string) {
+	b.mtx.Lock()
+	if b.batches[key] != batch {
+		b.mtx.Unlock()
+		return
+	}
+	delete(b.batches, key)
+	batch.timer.Stop()
+	b.mtx.Unlock()
+
+	metricIngesterBatchFlushes.WithLabelValues(reason).Inc()
+	metricIngesterBatchPushes.Observe(float64(batch.pushes))
+
+	// the batch is shared by several pushes, it isn't bound to the context of any of them
+	batch.err = b.push(context.Background(), key.addr, key.userID, &batch.req)
+	close(batch.done)
+}
+
+// result returns the error of the batch. The ingester reports the discarded spans of the whole batch, the
+// first push to see the error records them and the others receive it with an empty count.
+func (p *pendingBatch) result() error {
+	if p.err == nil || p.errReported.CAS(false, true) {
+		return p.err
+	}
+
+	s := status.Convert(p.err)
+	if _, ok := discardedSpansFromStatus(s); !ok {
+		return p.err
+	}
+	st, err := status.New(s.Code(), s.Message()).WithDetails(&rpc.ErrorInfo{
+		Reason: overrides.ErrorInfoReasonDiscardedSpans,
+	})
+	if err != nil {
+		return p.err
+	}
+	return st.Err()
+}
+
+// stop flushes all pending batches
+func (b *ingesterBatcher) stop(_ error) error {
+	b.mtx.Lock()
+	pending := make(map[batchKey]*pendingBatch, len(b.batches))
+	for key, batch := range b.batches {
+		pending[key] = batch
+	}
+	b.mtx.Unlock()
+
+	var wg sync.WaitGroup
+	for key, batch := range pending {
+		wg.Add(1)
+		go func(key batchKey, batch *pendingBatch) {
+			defer wg.Done()
+			b.flush(key, batch, flushReasonShutdown)
+		}(key, batch)
+	}
+	wg.Wait()
+
+	return nil
+}
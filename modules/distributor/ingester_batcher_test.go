@@ -0,0 +1,151 @@
+package distributor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/tempopb"
+)
+
+func pushBytesRequest(ids ...string) *tempopb.PushBytesRequest {
+	req := &tempopb.PushBytesRequest{}
+	for _, id := range ids {
+		req.Traces = append(req.Traces, tempopb.PreallocBytes{Slice: []byte("trace-" + id)})
+		req.Ids = append(req.Ids, tempopb.PreallocBytes{Slice: []byte(id)})
+		req.SearchData = append(req.SearchData, tempopb.PreallocBytes{})
+	}
+	return req
+}
+
+func TestIngesterBatcher(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		pushed = map[batchKey][][]string{}
+	)
+	b := newIngesterBatcher(IngesterBatchingConfig{
+		MaxBatchBytes: 1024 * 1024,
+		MaxWait:       50 * time.Millisecond,
+	}, func(ctx context.Context, addr string, userID string, req *tempopb.PushBytesRequest) error {
+		var ids []string
+		for _, id := range req.Ids {
+			ids = append(ids, string(id.Slice))
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		key := batchKey{addr: addr, userID: userID}
+		pushed[key] = append(pushed[key], ids)
+		return nil
+	})
+
+	wg := sync.WaitGroup{}
+	for _, p := range []struct {
+		addr, userID string
+		ids          []string
+	}{
+		{"ingester-1", "a", []string{"1", "2"}},
+		{"ingester-1", "a", []string{"3"}},
+		{"ingester-2", "a", []string{"4"}},
+		{"ingester-1", "b", []string{"5"}},
+	} {
+		wg.Add(1)
+		go func(addr, userID string, ids []string) {
+			defer wg.Done()
+			assert.NoError(t, b.Push(context.Background(), addr, userID, pushBytesRequest(ids...)))
+		}(p.addr, p.userID, p.ids)
+	}
+	wg.Wait()
+
+	// concurrent pushes to the same ingester and tenant are sent as one batch
+	require.Len(t, pushed[batchKey{"ingester-1", "a"}], 1)
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, pushed[batchKey{"ingester-1", "a"}][0])
+	assert.Equal(t, [][]string{{"4"}}, pushed[batchKey{"ingester-2", "a"}])
+	assert.Equal(t, [][]string{{"5"}}, pushed[batchKey{"ingester-1", "b"}])
+	assert.Empty(t, b.batches)
+}
+
+func TestIngesterBatcherMaxBatchBytes(t *testing.T) {
+	pushes := 0
+	b := newIngesterBatcher(IngesterBatchingConfig{
+		MaxBatchBytes: 1,
+		MaxWait:       time.Hour,
+	}, func(ctx context.Context, addr string, userID string, req *tempopb.PushBytesRequest) error {
+		pushes++
+		return nil
+	})
+
+	// a full batch is sent without waiting
+	require.NoError(t, b.Push(context.Background(), "ingester-1", "a", pushBytesRequest("1")))
+	require.NoError(t, b.Push(context.Background(), "ingester-1", "a", pushBytesRequest("2")))
+	assert.Equal(t, 2, pushes)
+}
+
+func TestIngesterBatcherStop(t *testing.T) {
+	b := newIngesterBatcher(IngesterBatchingConfig{
+		MaxBatchBytes: 1024 * 1024,
+		MaxWait:       time.Hour,
+	}, func(ctx context.Context, addr string, userID string, req *tempopb.PushBytesRequest) error {
+		return nil
+	})
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- b.Push(context.Background(), "ingester-1", "a", pushBytesRequest("1"))
+	}()
+
+	require.Eventually(t, func() bool {
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		return len(b.batches) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// pending batches are flushed when stopping
+	require.NoError(t, b.stop(nil))
+	assert.NoError(t, <-errCh)
+}
+
+func TestIngesterBatcherDiscardedSpans(t *testing.T) {
+	st, err := status.New(codes.FailedPrecondition, overrides.ErrorPrefixTraceTooLarge+" too large").WithDetails(&rpc.ErrorInfo{
+		Reason: overrides.ErrorInfoReasonDiscardedSpans,
+		Metadata: map[string]string{
+			overrides.ReasonTraceTooLarge: "3",
+		},
+	})
+	require.NoError(t, err)
+
+	b := newIngesterBatcher(IngesterBatchingConfig{
+		MaxBatchBytes: 1024 * 1024,
+		MaxWait:       50 * time.Millisecond,
+	}, func(ctx context.Context, addr string, userID string, req *tempopb.PushBytesRequest) error {
+		return st.Err()
+	})
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- b.Push(context.Background(), "ingester-1", "a", pushBytesRequest("1"))
+		}()
+	}
+
+	// the discarded spans of the batch are only reported to one of the pushes
+	total := 0
+	for i := 0; i < 2; i++ {
+		err := <-errs
+		s := status.Convert(err)
+		assert.Equal(t, codes.FailedPrecondition, s.Code())
+
+		discarded, ok := discardedSpansFromStatus(s)
+		require.True(t, ok)
+		total += discarded[overrides.ReasonTraceTooLarge]
+	}
+	assert.Equal(t, 3, total)
+}
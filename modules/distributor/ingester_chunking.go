@@ -0,0 +1,112 @@
+package distributor
+
+import (
+	"encoding/binary"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+// requestItemOverhead is an upper bound of the bytes a trace adds to a PushBytesRequest besides its trace, id and
+// search data: the field tags and lengths of the three repeated fields
+const requestItemOverhead = 3 * (1 + binary.MaxVarintLen64)
+
+var metricIngesterChunkedTraces = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "distributor_ingester_chunked_traces_total",
+	Help:      "The total number of traces split into several chunks because they exceed the max ingester message size.",
+})
+
+// chunkTrace splits the spans of a trace into traces with at most maxBytes each. Spans keep their order and the
+// resource and instrumentation library they belong to. A single span larger than maxBytes is a chunk of its own.
+// The trace is returned as is if it fits or maxBytes is 0.
+func chunkTrace(trace *tempopb.Trace, maxBytes int) []*tempopb.Trace {
+	if maxBytes <= 0 || trace.Size() <= maxBytes {
+		return []*tempopb.Trace{trace}
+	}
+
+	var chunks []*tempopb.Trace
+	var chunk *tempopb.Trace
+	size := 0
+
+	for _, b := range trace.Batches {
+		var batch *v1.ResourceSpans
+		for _, ils := range b.InstrumentationLibrarySpans {
+			var chunkILS *v1.InstrumentationLibrarySpans
+			for _, s := range ils.Spans {
+				spanSize := s.Size() + requestItemOverhead
+				if chunk != nil && size+spanSize > maxBytes {
+					chunk = nil
+				}
+				if chunk == nil {
+					chunk = &tempopb.Trace{}
+					chunks = append(chunks, chunk)
+					batch, chunkILS = nil, nil
+					size = 0
+				}
+				if batch == nil {
+					batch = &v1.ResourceSpans{Resource: b.Resource}
+					chunk.Batches = append(chunk.Batches, batch)
+					size += batch.Size() + requestItemOverhead
+				}
+				if chunkILS == nil {
+					chunkILS = &v1.InstrumentationLibrarySpans{InstrumentationLibrary: ils.InstrumentationLibrary}
+					batch.InstrumentationLibrarySpans = append(batch.InstrumentationLibrarySpans, chunkILS)
+					size += chunkILS.Size() + requestItemOverhead
+				}
+
+				chunkILS.Spans = append(chunkILS.Spans, s)
+				size += spanSize
+			}
+		}
+	}
+
+	if len(chunks) > 1 {
+		metricIngesterChunkedTraces.Inc()
+	}
+	return chunks
+}
+
+// ingesterRequests builds the requests pushing the traces at indexes to an ingester. Every marshalled chunk of a
+// trace is pushed with the trace's id, the ingester combines them. With maxBytes 0 a single request is returned,
+// otherwise chunks are packed in order into requests of at most maxBytes. Chunks of a trace are never reordered,
+// so pushing the requests in order delivers them in order.
+func ingesterRequests(traces []*rebatchedTrace, marshalledTraces [][][]byte, searchData [][]byte, indexes []int, maxBytes int) []*tempopb.PushBytesRequest {
+	var reqs []*tempopb.PushBytesRequest
+	var req *tempopb.PushBytesRequest
+	size := 0
+
+	for _, j := range indexes {
+		for c, chunk := range marshalledTraces[j] {
+			var search []byte
+			// search data is optional and only sent with the first chunk
+			if c == 0 && len(searchData) > j {
+				search = searchData[j]
+			}
+
+			itemSize := len(chunk) + len(traces[j].id) + len(search) + requestItemOverhead
+			if req != nil && maxBytes > 0 && size+itemSize > maxBytes {
+				req = nil
+			}
+			if req == nil {
+				req = &tempopb.PushBytesRequest{
+					Traces:     make([]tempopb.PreallocBytes, 0, len(indexes)),
+					Ids:        make([]tempopb.PreallocBytes, 0, len(indexes)),
+					SearchData: make([]tempopb.PreallocBytes, 0, len(indexes)),
+				}
+				reqs = append(reqs, req)
+				size = 0
+			}
+
+			req.Traces = append(req.Traces, tempopb.PreallocBytes{Slice: chunk})
+			req.Ids = append(req.Ids, tempopb.PreallocBytes{Slice: traces[j].id})
+			req.SearchData = append(req.SearchData, tempopb.PreallocBytes{Slice: search})
+			size += itemSize
+		}
+	}
+
+	return reqs
+}
@@ -0,0 +1,112 @@
+package distributor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util/test"
+)
+
+func spanIDs(traces ...*tempopb.Trace) [][]byte {
+	var ids [][]byte
+	for _, tr := range traces {
+		for _, b := range tr.Batches {
+			for _, ils := range b.InstrumentationLibrarySpans {
+				for _, s := range ils.Spans {
+					ids = append(ids, s.SpanId)
+				}
+			}
+		}
+	}
+	return ids
+}
+
+func TestChunkTrace(t *testing.T) {
+	trace := test.MakeTrace(10, test.ValidTraceID(nil))
+
+	// traces that fit aren't split
+	chunks := chunkTrace(trace, 0)
+	require.Len(t, chunks, 1)
+	assert.Same(t, trace, chunks[0])
+	chunks = chunkTrace(trace, trace.Size())
+	require.Len(t, chunks, 1)
+	assert.Same(t, trace, chunks[0])
+
+	maxBytes := trace.Size() / 4
+	chunks = chunkTrace(trace, maxBytes)
+	require.Greater(t, len(chunks), 1)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, c.Size(), maxBytes)
+	}
+
+	// all spans are kept in order with their resource and instrumentation library
+	assert.Equal(t, spanIDs(trace), spanIDs(chunks...))
+	for _, c := range chunks {
+		for _, b := range c.Batches {
+			require.NotNil(t, b.Resource)
+			for _, ils := range b.InstrumentationLibrarySpans {
+				assert.NotEmpty(t, ils.Spans)
+			}
+		}
+	}
+
+	// a span larger than max bytes is a chunk of its own
+	span := trace.Batches[0].InstrumentationLibrarySpans[0].Spans[0]
+	chunks = chunkTrace(trace, span.Size())
+	assert.Equal(t, spanIDs(trace), spanIDs(chunks...))
+	for _, c := range chunks {
+		assert.Len(t, spanIDs(c), 1)
+	}
+}
+
+func TestIngesterRequests(t *testing.T) {
+	traces := []*rebatchedTrace{
+		{id: []byte{1}, trace: &tempopb.Trace{Batches: []*v1.ResourceSpans{}}},
+		{id: []byte{2}, trace: &tempopb.Trace{Batches: []*v1.ResourceSpans{}}},
+	}
+	marshalled := [][][]byte{
+		{make([]byte, 100), make([]byte, 100), make([]byte, 50)},
+		{make([]byte, 10)},
+	}
+	searchData := [][]byte{[]byte("search-1"), []byte("search-2")}
+
+	// without a max all chunks are sent in a single request
+	reqs := ingesterRequests(traces, marshalled, searchData, []int{0, 1}, 0)
+	require.Len(t, reqs, 1)
+	require.Len(t, reqs[0].Traces, 4)
+	assert.Equal(t, [][]byte{{1}, {1}, {1}, {2}}, requestIDs(reqs[0]))
+	// search data is sent with the first chunk of a trace
+	assert.Equal(t, []byte("search-1"), reqs[0].SearchData[0].Slice)
+	assert.Empty(t, reqs[0].SearchData[1].Slice)
+	assert.Equal(t, []byte("search-2"), reqs[0].SearchData[3].Slice)
+
+	// chunks are packed in order
+	reqs = ingesterRequests(traces, marshalled, searchData, []int{0, 1}, 200)
+	require.Len(t, reqs, 3)
+	var ids [][]byte
+	for _, req := range reqs {
+		assert.LessOrEqual(t, req.Size(), 200)
+		ids = append(ids, requestIDs(req)...)
+	}
+	assert.Equal(t, [][]byte{{1}, {1}, {1}, {2}}, ids)
+	assert.Equal(t, 100, len(reqs[0].Traces[0].Slice))
+	assert.Equal(t, 100, len(reqs[1].Traces[0].Slice))
+	assert.Equal(t, 50, len(reqs[2].Traces[0].Slice))
+
+	// only the traces at indexes are sent
+	reqs = ingesterRequests(traces, marshalled, nil, []int{1}, 200)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, [][]byte{{2}}, requestIDs(reqs[0]))
+}
+
+func requestIDs(req *tempopb.PushBytesRequest) [][]byte {
+	ids := make([][]byte, 0, len(req.Ids))
+	for _, id := range req.Ids {
+		ids = append(ids, id.Slice)
+	}
+	return ids
+}
@@ -0,0 +1,67 @@
+package distributor
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/grafana/dskit/ring"
+	ring_client "github.com/grafana/dskit/ring/client"
+
+	"github.com/grafana/tempo/pkg/endpointslices"
+)
+
+type endpointResolver interface {
+	EndpointForAddr(addr string) (endpointslices.Endpoint, bool)
+}
+
+// ingesterAddr returns the address to connect to for the given ingester
+func (d *Distributor) ingesterAddr(ingester ring.InstanceDesc) (string, error) {
+	if d.ingesterEndpoints == nil {
+		return ingester.Addr, nil
+	}
+	return resolveIngesterAddr(d.ingesterEndpoints, ingester.Addr)
+}
+
+// resolveIngesterAddr returns the current address of the pod that registered the given address in the ring. During
+// a rollout the ring can still contain the address of a pod that was already replaced. Not ready pods are
+// reported as an error instead of waiting for the connection to time out.
+func resolveIngesterAddr(r endpointResolver, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, nil
+	}
+
+	e, ok := r.EndpointForAddr(host)
+	if !ok {
+		// unknown to the EndpointSlices, e.g. before they have been listed. fall back to the ring
+		return addr, nil
+	}
+	if !e.Ready {
+		return "", fmt.Errorf("ingester %s (%s) is not ready", e.Name, addr)
+	}
+
+	if e.Port != 0 {
+		port = strconv.Itoa(e.Port)
+	}
+	return net.JoinHostPort(e.Addr, port), nil
+}
+
+// endpointSlicesServiceDiscovery adds the resolved addresses of the ingesters to the addresses of the ring so that
+// the pool keeps the connections to both.
+func endpointSlicesServiceDiscovery(next ring_client.PoolServiceDiscovery, r endpointResolver) ring_client.PoolServiceDiscovery {
+	return func() ([]string, error) {
+		addrs, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			resolved, err := resolveIngesterAddr(r, addr)
+			if err == nil && resolved != addr {
+				addrs = append(addrs, resolved)
+			}
+		}
+		return addrs, nil
+	}
+}
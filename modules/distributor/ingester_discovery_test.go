@@ -0,0 +1,72 @@
+package distributor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/endpointslices"
+)
+
+type mockEndpointResolver map[string]endpointslices.Endpoint
+
+func (m mockEndpointResolver) EndpointForAddr(addr string) (endpointslices.Endpoint, bool) {
+	e, ok := m[addr]
+	return e, ok
+}
+
+func TestResolveIngesterAddr(t *testing.T) {
+	r := mockEndpointResolver{
+		"10.0.0.1": {Name: "ingester-0", Addr: "10.0.0.3", Port: 9095, Ready: true},
+		"10.0.0.2": {Name: "ingester-1", Addr: "10.0.0.4", Ready: false},
+		"fd00::1":  {Name: "ingester-2", Addr: "fd00::2", Ready: true},
+	}
+
+	tests := []struct {
+		addr        string
+		expected    string
+		expectedErr string
+	}{
+		{addr: "10.0.0.1:9000", expected: "10.0.0.3:9095"},
+		{addr: "10.0.0.2:9095", expectedErr: "ingester ingester-1 (10.0.0.2:9095) is not ready"},
+		{addr: "[fd00::1]:9095", expected: "[fd00::2]:9095"},
+		// unknown addresses are used as is
+		{addr: "10.0.0.5:9095", expected: "10.0.0.5:9095"},
+		{addr: "ingester", expected: "ingester"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.addr, func(t *testing.T) {
+			actual, err := resolveIngesterAddr(r, tc.addr)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestEndpointSlicesServiceDiscovery(t *testing.T) {
+	r := mockEndpointResolver{
+		"10.0.0.1": {Name: "ingester-0", Addr: "10.0.0.3", Ready: true},
+		"10.0.0.2": {Name: "ingester-1", Addr: "10.0.0.2", Ready: true},
+	}
+
+	discovery := endpointSlicesServiceDiscovery(func() ([]string, error) {
+		return []string{"10.0.0.1:9095", "10.0.0.2:9095"}, nil
+	}, r)
+
+	addrs, err := discovery()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1:9095", "10.0.0.2:9095", "10.0.0.3:9095"}, addrs)
+
+	discovery = endpointSlicesServiceDiscovery(func() ([]string, error) {
+		return nil, errors.New("ring error")
+	}, r)
+	_, err = discovery()
+	require.EqualError(t, err, "ring error")
+}
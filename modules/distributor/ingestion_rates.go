@@ -0,0 +1,73 @@
+package distributor
+
+import (
+	"sync"
+	"time"
+)
+
+// ingestionRates measures the ingestion rate of each tenant in bytes/s over fixed windows. It is used to
+// report the usage of the ingestion rate limit.
+type ingestionRates struct {
+	mtx     sync.Mutex
+	window  time.Duration
+	tenants map[string]*tenantIngestionRate
+}
+
+type tenantIngestionRate struct {
+	windowStart time.Time
+	bytes       int64
+	// rate of the last complete window in bytes/s
+	rate float64
+}
+
+func newIngestionRates(window time.Duration) *ingestionRates {
+	return &ingestionRates{
+		window:  window,
+		tenants: map[string]*tenantIngestionRate{},
+	}
+}
+
+func (r *ingestionRates) add(now time.Time, tenant string, bytes int) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	t, ok := r.tenants[tenant]
+	if !ok {
+		t = &tenantIngestionRate{windowStart: now}
+		r.tenants[tenant] = t
+	}
+
+	t.roll(now, r.window)
+	t.bytes += int64(bytes)
+}
+
+// rates returns the ingestion rate of every tenant. Tenants that didn't push during the last window
+// are forgotten.
+func (r *ingestionRates) rates(now time.Time) map[string]float64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	rates := make(map[string]float64, len(r.tenants))
+	for tenant, t := range r.tenants {
+		if t.roll(now, r.window) && t.rate == 0 {
+			delete(r.tenants, tenant)
+			continue
+		}
+		rates[tenant] = t.rate
+	}
+
+	return rates
+}
+
+// roll completes the current window if it is older than window and returns true if it did.
+func (t *tenantIngestionRate) roll(now time.Time, window time.Duration) bool {
+	elapsed := now.Sub(t.windowStart)
+	if elapsed < window {
+		return false
+	}
+
+	t.rate = float64(t.bytes) / elapsed.Seconds()
+	t.windowStart = now
+	t.bytes = 0
+	return true
+}
@@ -0,0 +1,30 @@
+package distributor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIngestionRates(t *testing.T) {
+	r := newIngestionRates(10 * time.Second)
+	start := time.Now()
+
+	// nothing complete yet
+	r.add(start, "tenant", 100)
+	r.add(start.Add(5*time.Second), "tenant", 100)
+	assert.Equal(t, map[string]float64{"tenant": 0}, r.rates(start.Add(5*time.Second)))
+
+	// the first window is complete once it is older than the window
+	assert.Equal(t, map[string]float64{"tenant": 20}, r.rates(start.Add(10*time.Second)))
+
+	// the rate of the last complete window is reported until the next one completes
+	r.add(start.Add(12*time.Second), "tenant", 500)
+	assert.Equal(t, map[string]float64{"tenant": 20}, r.rates(start.Add(15*time.Second)))
+	assert.Equal(t, map[string]float64{"tenant": 50}, r.rates(start.Add(20*time.Second)))
+
+	// tenants are forgotten after a window without pushes
+	r.add(start.Add(20*time.Second), "other", 10)
+	assert.Equal(t, map[string]float64{"other": 1}, r.rates(start.Add(30*time.Second)))
+}
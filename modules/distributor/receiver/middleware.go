@@ -21,6 +21,26 @@ func (f ConsumeTracesFunc) ConsumeTraces(ctx context.Context, td pdata.Traces) e
 	return f(ctx, td)
 }
 
+type receiverKey struct{}
+
+// InjectReceiver returns a context that records the receiver the traces were received with
+func InjectReceiver(ctx context.Context, receiver string) context.Context {
+	return context.WithValue(ctx, receiverKey{}, receiver)
+}
+
+// ExtractReceiver returns the receiver the traces were received with, or false if they weren't received by one
+func ExtractReceiver(ctx context.Context) (string, bool) {
+	receiver, ok := ctx.Value(receiverKey{}).(string)
+	return receiver, ok
+}
+
+// receiverMiddleware records the receiver in the context of the traces it consumes
+func receiverMiddleware(receiver string, next consumer.Traces) consumer.Traces {
+	return ConsumeTracesFunc(func(ctx context.Context, td pdata.Traces) error {
+		return next.ConsumeTraces(InjectReceiver(ctx, receiver), td)
+	})
+}
+
 type Middleware interface {
 	Wrap(consumer.Traces) consumer.Traces
 }
@@ -77,3 +77,16 @@ func TestMultiTenancyMiddleware(t *testing.T) {
 		require.EqualError(t, m.Wrap(consumer).ConsumeTraces(ctx, pdata.Traces{}), "no org id")
 	})
 }
+
+func TestReceiverMiddleware(t *testing.T) {
+	consumer := newAssertingConsumer(t, func(t *testing.T, ctx context.Context) {
+		recv, ok := ExtractReceiver(ctx)
+		require.True(t, ok)
+		require.Equal(t, "otlp", recv)
+	})
+
+	require.NoError(t, receiverMiddleware("otlp", consumer).ConsumeTraces(context.Background(), pdata.Traces{}))
+
+	_, ok := ExtractReceiver(context.Background())
+	require.False(t, ok)
+}
@@ -142,7 +142,8 @@ func New(receiverCfg map[string]interface{}, pusher BatchPusher, middleware Midd
 			return nil, fmt.Errorf("receiver factory not found for type: %s", componentID.Type())
 		}
 
-		receiver, err := factoryBase.CreateTracesReceiver(ctx, params, cfg, middleware.Wrap(shim))
+		next := receiverMiddleware(string(componentID.Type()), middleware.Wrap(shim))
+		receiver, err := factoryBase.CreateTracesReceiver(ctx, params, cfg, next)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,225 @@
+package distributor
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.5.0"
+
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util"
+)
+
+const (
+	// serviceOverflow is the service of the spans of the services of a tenant beyond its max number of services
+	serviceOverflow = "__overflow__"
+	// serviceUnknown is the service of the spans of resources without a service name
+	serviceUnknown = "unknown_service"
+
+	// serviceRankInterval is how often the services of a tenant with their own series are chosen again
+	serviceRankInterval = time.Minute
+	// serviceCandidatesFactor bounds the number of services of a tenant whose spans are counted to rank them, as a
+	// multiple of its max number of services
+	serviceCandidatesFactor = 10
+)
+
+var (
+	metricServiceSpansIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_service_spans_received_total",
+		Help:      "The total number of spans received per tenant and service",
+	}, []string{"tenant", "service"})
+	metricServiceBytesIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_service_bytes_received_total",
+		Help:      "The total number of proto bytes received per tenant and service",
+	}, []string{"tenant", "service"})
+)
+
+// serviceMetrics counts the received spans and bytes of each service of a tenant. The number of services of a
+// tenant with their own series is bounded. Every rankInterval the services with the most recent spans get their
+// own series, spans of the other services are counted in the overflow series.
+type serviceMetrics struct {
+	rankInterval time.Duration
+
+	mtx     sync.RWMutex
+	tenants map[string]*tenantServices
+}
+
+// tenantServices ranks the services of a tenant
+type tenantServices struct {
+	mtx         sync.Mutex
+	maxServices int
+	lastRanked  time.Time
+	// spans of the candidate services since they were last ranked, the counts are halved at every ranking so the
+	// services not seen anymore are forgotten
+	spans map[string]uint64
+	// services with their own series
+	ranked map[string]struct{}
+}
+
+func newServiceMetrics(rankInterval time.Duration) *serviceMetrics {
+	return &serviceMetrics{
+		rankInterval: rankInterval,
+		tenants:      map[string]*tenantServices{},
+	}
+}
+
+// observe counts the spans and bytes of each service of the batches. maxServices is the number of services of the
+// tenant with their own series, the series of the tenant are removed if it's 0.
+func (m *serviceMetrics) observe(now time.Time, tenant string, maxServices int, batches []*v1.ResourceSpans) {
+	if maxServices <= 0 {
+		m.removeTenant(tenant)
+		return
+	}
+
+	s := m.tenant(tenant)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if now.Sub(s.lastRanked) >= m.rankInterval || maxServices != s.maxServices {
+		s.rank(now, tenant, maxServices)
+	}
+
+	for _, b := range batches {
+		spanCount := 0
+		for _, ils := range b.InstrumentationLibrarySpans {
+			spanCount += len(ils.Spans)
+		}
+		if spanCount == 0 {
+			continue
+		}
+
+		service := serviceName(b.Resource)
+		s.count(service, uint64(spanCount))
+
+		// services fill the free series until the next ranking
+		if _, ok := s.ranked[service]; !ok {
+			if len(s.ranked) >= s.maxServices {
+				service = serviceOverflow
+			} else {
+				s.ranked[service] = struct{}{}
+			}
+		}
+
+		metricServiceSpansIngested.WithLabelValues(tenant, service).Add(float64(spanCount))
+		metricServiceBytesIngested.WithLabelValues(tenant, service).Add(float64(b.Size()))
+	}
+}
+
+func (m *serviceMetrics) tenant(tenant string) *tenantServices {
+	m.mtx.RLock()
+	s, ok := m.tenants[tenant]
+	m.mtx.RUnlock()
+	if ok {
+		return s
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	s, ok = m.tenants[tenant]
+	if !ok {
+		s = &tenantServices{
+			spans:  map[string]uint64{},
+			ranked: map[string]struct{}{},
+		}
+		m.tenants[tenant] = s
+	}
+	return s
+}
+
+// removeTenant removes the series of a tenant whose per service metrics were disabled
+func (m *serviceMetrics) removeTenant(tenant string) {
+	m.mtx.RLock()
+	_, ok := m.tenants[tenant]
+	m.mtx.RUnlock()
+	if !ok {
+		return
+	}
+
+	m.mtx.Lock()
+	delete(m.tenants, tenant)
+	m.mtx.Unlock()
+
+	metricServiceSpansIngested.DeletePartialMatch(prometheus.Labels{"tenant": tenant})
+	metricServiceBytesIngested.DeletePartialMatch(prometheus.Labels{"tenant": tenant})
+}
+
+// count adds the spans of a service. Once the number of candidates is reached, a new service replaces the candidate
+// with the fewest spans and starts from its count, so heavy services are ranked even if they were seen last.
+func (s *tenantServices) count(service string, spans uint64) {
+	if _, ok := s.spans[service]; ok || len(s.spans) < s.maxServices*serviceCandidatesFactor {
+		s.spans[service] += spans
+		return
+	}
+
+	minService, minSpans := "", uint64(math.MaxUint64)
+	for svc, n := range s.spans {
+		if n < minSpans {
+			minService, minSpans = svc, n
+		}
+	}
+	delete(s.spans, minService)
+	s.spans[service] = minSpans + spans
+}
+
+// rank gives their own series to the maxServices services with the most spans and removes the series of the others
+func (s *tenantServices) rank(now time.Time, tenant string, maxServices int) {
+	services := make([]string, 0, len(s.spans))
+	for svc := range s.spans {
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		if s.spans[services[i]] != s.spans[services[j]] {
+			return s.spans[services[i]] > s.spans[services[j]]
+		}
+		return services[i] < services[j]
+	})
+	if len(services) > maxServices {
+		services = services[:maxServices]
+	}
+
+	ranked := make(map[string]struct{}, len(services))
+	for _, svc := range services {
+		ranked[svc] = struct{}{}
+	}
+	for svc := range s.ranked {
+		if _, ok := ranked[svc]; !ok {
+			metricServiceSpansIngested.DeleteLabelValues(tenant, svc)
+			metricServiceBytesIngested.DeleteLabelValues(tenant, svc)
+		}
+	}
+
+	for svc, n := range s.spans {
+		if n/2 == 0 {
+			delete(s.spans, svc)
+			continue
+		}
+		s.spans[svc] = n / 2
+	}
+
+	s.ranked = ranked
+	s.maxServices = maxServices
+	s.lastRanked = now
+}
+
+func serviceName(r *v1_resource.Resource) string {
+	if r == nil {
+		return serviceUnknown
+	}
+	for _, kv := range r.Attributes {
+		if kv.Key == semconv.AttributeServiceName {
+			if name := util.StringifyAnyValue(kv.Value); name != "" {
+				return name
+			}
+			break
+		}
+	}
+	return serviceUnknown
+}
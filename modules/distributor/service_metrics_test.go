@@ -0,0 +1,64 @@
+package distributor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+func TestServiceMetrics(t *testing.T) {
+	tenant := "service-metrics-tenant"
+	m := newServiceMetrics(time.Minute)
+	start := time.Now()
+
+	batch := func(service string, spans int) *v1.ResourceSpans {
+		b := &v1.ResourceSpans{
+			Resource:                    &v1_resource.Resource{},
+			InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: make([]*v1.Span, spans)}},
+		}
+		for i := range b.InstrumentationLibrarySpans[0].Spans {
+			b.InstrumentationLibrarySpans[0].Spans[i] = &v1.Span{}
+		}
+		if service != "" {
+			b.Resource.Attributes = []*v1_common.KeyValue{
+				{Key: "service.name", Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_StringValue{StringValue: service}}},
+			}
+		}
+		return b
+	}
+	spans := func(service string) float64 {
+		return testutil.ToFloat64(metricServiceSpansIngested.WithLabelValues(tenant, service))
+	}
+
+	m.observe(start, tenant, 2, []*v1.ResourceSpans{batch("a", 2), batch("", 1), batch("b", 3)})
+	assert.Equal(t, 2.0, spans("a"))
+	assert.Equal(t, 1.0, spans(serviceUnknown))
+	assert.Equal(t, 3.0, spans(serviceOverflow))
+
+	assert.Equal(t, float64(batch("a", 2).Size()), testutil.ToFloat64(metricServiceBytesIngested.WithLabelValues(tenant, "a")))
+
+	// the services keep their series until they are ranked
+	m.observe(start.Add(30*time.Second), tenant, 2, []*v1.ResourceSpans{batch("a", 1), batch("b", 1)})
+	assert.Equal(t, 3.0, spans("a"))
+	assert.Equal(t, 4.0, spans(serviceOverflow))
+
+	// the services with the most spans get their own series
+	m.observe(start.Add(time.Minute), tenant, 2, []*v1.ResourceSpans{batch("b", 5)})
+	assert.Equal(t, 5.0, spans("b"))
+	assert.Equal(t, 3.0, spans("a"))
+	assert.Equal(t, 0.0, spans(serviceUnknown))
+	assert.Equal(t, 4.0, spans(serviceOverflow))
+
+	// disabling the metrics removes the series of the tenant
+	m.observe(start.Add(90*time.Second), tenant, 0, []*v1.ResourceSpans{batch("b", 1)})
+	count, err := testutil.GatherAndCount(prometheus.DefaultGatherer, "tempo_distributor_service_spans_received_total")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
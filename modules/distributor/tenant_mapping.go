@@ -0,0 +1,114 @@
+package distributor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/dskit/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricTenantsMapped is labeled with the source of the rule rather than the tenants, whose number is unbounded if
+// the target references capture groups
+var metricTenantsMapped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "distributor_tenant_mapped_pushes_total",
+	Help:      "The total number of pushes whose tenant was rewritten per mapping rule.",
+}, []string{"rule"})
+
+// TenantMappingConfig configures rewriting the tenant of incoming pushes. The rules are applied in order and the
+// first matching rule rewrites the tenant, before limits are checked and the traces are ingested.
+type TenantMappingConfig struct {
+	Rules []TenantMappingRule `yaml:"rules"`
+	// DenyUnmapped rejects the pushes of tenants that don't match any rule
+	DenyUnmapped bool `yaml:"deny_unmapped"`
+}
+
+// TenantMappingRule rewrites the tenants matching Source to Target.
+type TenantMappingRule struct {
+	// Source is a regular expression matched against the whole tenant ID
+	Source string `yaml:"source"`
+	// Target is the new tenant ID. It can reference the capture groups of Source, e.g. $1
+	Target string `yaml:"target"`
+}
+
+type tenantMappingRule struct {
+	source *regexp.Regexp
+	target string
+	// rule is the source as configured, the label of the rule in metrics
+	rule string
+}
+
+type tenantMapper struct {
+	rules        []tenantMappingRule
+	denyUnmapped bool
+}
+
+// newTenantMapper returns a tenantMapper for the config, or nil if no tenants are mapped.
+func newTenantMapper(cfg TenantMappingConfig) (*tenantMapper, error) {
+	if len(cfg.Rules) == 0 && !cfg.DenyUnmapped {
+		return nil, nil
+	}
+
+	m := &tenantMapper{
+		rules:        make([]tenantMappingRule, 0, len(cfg.Rules)),
+		denyUnmapped: cfg.DenyUnmapped,
+	}
+	for _, r := range cfg.Rules {
+		if r.Target == "" {
+			return nil, fmt.Errorf("tenant mapping rule for source %s has no target", r.Source)
+		}
+		source, err := regexp.Compile("^(?:" + r.Source + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenant mapping source %s: %w", r.Source, err)
+		}
+		// targets referencing capture groups are validated when a tenant is mapped
+		if !strings.Contains(r.Target, "$") {
+			if err := validTenantID(r.Target); err != nil {
+				return nil, fmt.Errorf("invalid tenant mapping target %s: %w", r.Target, err)
+			}
+		}
+		m.rules = append(m.rules, tenantMappingRule{
+			source: source,
+			target: r.Target,
+			rule:   r.Source,
+		})
+	}
+
+	return m, nil
+}
+
+// mapTenant returns the tenant the pushes of userID are ingested as. It returns false if the tenant is denied, which
+// includes tenants mapped to an invalid tenant ID.
+func (m *tenantMapper) mapTenant(userID string) (string, bool) {
+	for _, r := range m.rules {
+		if !r.source.MatchString(userID) {
+			continue
+		}
+
+		target := r.source.ReplaceAllString(userID, r.target)
+		if validTenantID(target) != nil {
+			return "", false
+		}
+		if target != userID {
+			metricTenantsMapped.WithLabelValues(r.rule).Inc()
+		}
+		return target, true
+	}
+
+	return userID, !m.denyUnmapped
+}
+
+// validTenantID returns an error if id can't be used as tenant ID. Tenant IDs name folders in the wal and the
+// backend, so . and .. are rejected besides the rules of tenant.ValidTenantID.
+func validTenantID(id string) error {
+	if id == "" {
+		return fmt.Errorf("tenant ID is empty")
+	}
+	if id == "." || id == ".." {
+		return fmt.Errorf("tenant ID %s is not allowed", id)
+	}
+	return tenant.ValidTenantID(id)
+}
@@ -0,0 +1,90 @@
+package distributor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantMapper(t *testing.T) {
+	m, err := newTenantMapper(TenantMappingConfig{})
+	require.NoError(t, err)
+	require.Nil(t, m)
+
+	_, err = newTenantMapper(TenantMappingConfig{Rules: []TenantMappingRule{{Source: "(", Target: "a"}}})
+	require.Error(t, err)
+	_, err = newTenantMapper(TenantMappingConfig{Rules: []TenantMappingRule{{Source: "a"}}})
+	require.Error(t, err)
+	// targets must be valid tenant IDs
+	for _, target := range []string{"a|b", "..", "a b", strings.Repeat("a", 151)} {
+		_, err = newTenantMapper(TenantMappingConfig{Rules: []TenantMappingRule{{Source: "a", Target: target}}})
+		require.Error(t, err, target)
+	}
+
+	m, err = newTenantMapper(TenantMappingConfig{
+		Rules: []TenantMappingRule{
+			// merge several tenants into one
+			{Source: "team-a|team-b", Target: "org"},
+			// rewrite with capture groups
+			{Source: "legacy-(.*)", Target: "$1"},
+			// keep as is
+			{Source: "org", Target: "org"},
+			{Source: "empty-.*", Target: "$1"},
+			{Source: "invalid-(.*)", Target: "$1|x"},
+			{Source: "dot-(.*)", Target: "$1"},
+		},
+	})
+	require.NoError(t, err)
+
+	tcs := []struct {
+		tenant   string
+		expected string
+		ok       bool
+	}{
+		{tenant: "team-a", expected: "org", ok: true},
+		{tenant: "team-b", expected: "org", ok: true},
+		{tenant: "org", expected: "org", ok: true},
+		{tenant: "legacy-foo", expected: "foo", ok: true},
+		// the whole tenant has to match
+		{tenant: "team-abc", expected: "team-abc", ok: true},
+		{tenant: "unknown", expected: "unknown", ok: true},
+		// mapping to an empty or invalid tenant is denied
+		{tenant: "empty-foo", ok: false},
+		{tenant: "invalid-foo", ok: false},
+		{tenant: "dot-..", ok: false},
+	}
+	for _, tc := range tcs {
+		actual, ok := m.mapTenant(tc.tenant)
+		assert.Equal(t, tc.ok, ok, tc.tenant)
+		assert.Equal(t, tc.expected, actual, tc.tenant)
+	}
+
+	// unknown tenants are denied
+	m.denyUnmapped = true
+	_, ok := m.mapTenant("unknown")
+	assert.False(t, ok)
+	actual, ok := m.mapTenant("team-a")
+	assert.True(t, ok)
+	assert.Equal(t, "org", actual)
+}
+
+func TestTenantMapperMetricLabels(t *testing.T) {
+	m, err := newTenantMapper(TenantMappingConfig{
+		Rules: []TenantMappingRule{{Source: "legacy-(.*)", Target: "$1"}},
+	})
+	require.NoError(t, err)
+
+	before := testutil.ToFloat64(metricTenantsMapped.WithLabelValues("legacy-(.*)"))
+	series := testutil.CollectAndCount(metricTenantsMapped)
+	for _, tenant := range []string{"legacy-a", "legacy-b", "legacy-c"} {
+		_, ok := m.mapTenant(tenant)
+		require.True(t, ok)
+	}
+
+	// the series are per rule, not per tenant
+	assert.Equal(t, before+3, testutil.ToFloat64(metricTenantsMapped.WithLabelValues("legacy-(.*)")))
+	assert.Equal(t, series, testutil.CollectAndCount(metricTenantsMapped))
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/grafana/tempo/modules/frontend/transport"
 	v1 "github.com/grafana/tempo/modules/frontend/v1"
+	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/usagestats"
 )
 
@@ -19,11 +20,12 @@ var (
 )
 
 type Config struct {
-	Config               v1.Config    `yaml:",inline"`
-	MaxRetries           int          `yaml:"max_retries,omitempty"`
-	QueryShards          int          `yaml:"query_shards,omitempty"`
-	TolerateFailedBlocks int          `yaml:"tolerate_failed_blocks,omitempty"`
-	Search               SearchConfig `yaml:"search"`
+	Config               v1.Config          `yaml:",inline"`
+	MaxRetries           int                `yaml:"max_retries,omitempty"`
+	QueryShards          int                `yaml:"query_shards,omitempty"`
+	TolerateFailedBlocks int                `yaml:"tolerate_failed_blocks,omitempty"`
+	Search               SearchConfig       `yaml:"search"`
+	SavedQueries         SavedQueriesConfig `yaml:"saved_queries"`
 }
 
 type SearchConfig struct {
@@ -42,13 +44,23 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(string, *flag.FlagSet) {
 			DefaultLimit:          20,
 			MaxLimit:              0,
 			MaxDuration:           61 * time.Minute,
+			MaxSplitQueries:       24,
 			ConcurrentRequests:    defaultConcurrentRequests,
 			TargetBytesPerRequest: defaultTargetBytesPerRequest,
 		},
 	}
+	cfg.SavedQueries = SavedQueriesConfig{
+		Enabled:           false,
+		MaxSavedQueries:   1000,
+		MaxHistoryPerUser: 100,
+	}
 }
 
-type CortexNoQuerierLimits struct{}
+// CortexNoQuerierLimits disables shuffle sharding of the queriers. The requests of a tenant dispatched to queriers at
+// once are limited by its max concurrent queries.
+type CortexNoQuerierLimits struct {
+	*overrides.Overrides
+}
 
 var _ v1.Limits = (*CortexNoQuerierLimits)(nil)
 
@@ -0,0 +1,140 @@
+package frontend
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/grafana/tempo/pkg/api"
+)
+
+const (
+	eventProgress = "progress"
+	eventPartial  = "partial"
+	eventResult   = "result"
+	eventError    = "error"
+)
+
+var errStreamIncomplete = errors.New("event stream ended without a result")
+
+// writeEvent writes a single server-sent event. Multi line data is split into several data fields.
+func writeEvent(w io.Writer, event string, data []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "event: %s\n", event)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// isEventStream returns true if the response body is a stream of server-sent events.
+func isEventStream(resp *http.Response) bool {
+	return resp.Header != nil && resp.Header.Get(api.HeaderContentType) == api.HeaderAcceptEventStream
+}
+
+// serveEventStream copies the events of body to w and flushes after every read so the client receives them as
+// they are written. Without http.Flusher the events can't be streamed, the stream is read to its end and its
+// result is returned as a plain JSON response instead.
+func serveEventStream(w http.ResponseWriter, body io.ReadCloser) error {
+	defer body.Close()
+
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return serveEventStreamResult(w, body)
+	}
+
+	h := w.Header()
+	h.Set(api.HeaderContentType, api.HeaderAcceptEventStream)
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			f.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// serveEventStreamResult writes the data of the result event of body as a JSON response, or the message of an error
+// event as an internal server error.
+func serveEventStreamResult(w http.ResponseWriter, body io.Reader) error {
+	var (
+		event string
+		data  [][]byte
+	)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, math.MaxInt32)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0:
+			switch event {
+			case eventResult:
+				w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(bytes.Join(data, []byte("\n")))
+				return err
+			case eventError:
+				http.Error(w, string(bytes.Join(data, []byte("\n"))), http.StatusInternalServerError)
+				return nil
+			}
+			event, data = "", nil
+		case bytes.HasPrefix(line, []byte("event: ")):
+			event = string(line[len("event: "):])
+		case bytes.HasPrefix(line, []byte("data: ")):
+			data = append(data, append([]byte(nil), line[len("data: "):]...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	http.Error(w, errStreamIncomplete.Error(), http.StatusInternalServerError)
+	return errStreamIncomplete
+}
+
+// eventStreamResponse converts a successful search response into an event stream of a single result event.
+// It's used for searches that aren't sharded and don't report progress.
+func eventStreamResponse(resp *http.Response) (*http.Response, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeEvent(&buf, eventResult, body); err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			api.HeaderContentType: {api.HeaderAcceptEventStream},
+		},
+		Body:          io.NopCloser(&buf),
+		ContentLength: int64(buf.Len()),
+	}, nil
+}
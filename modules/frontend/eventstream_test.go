@@ -0,0 +1,61 @@
+package frontend
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/api"
+)
+
+func TestWriteEvent(t *testing.T) {
+	var sb strings.Builder
+	require.NoError(t, writeEvent(&sb, eventResult, []byte("a\nb")))
+	assert.Equal(t, "event: result\ndata: a\ndata: b\n\n", sb.String())
+}
+
+func TestServeEventStream(t *testing.T) {
+	events := "event: progress\ndata: {}\n\nevent: result\ndata: {}\n\n"
+
+	t.Run("flusher", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		require.NoError(t, serveEventStream(res, io.NopCloser(strings.NewReader(events))))
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.True(t, res.Flushed)
+		assert.Equal(t, api.HeaderAcceptEventStream, res.Header().Get(api.HeaderContentType))
+		assert.Equal(t, events, res.Body.String())
+	})
+
+	// the default http middlewares hide http.Flusher
+	t.Run("result without flusher", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		err := serveEventStream(struct{ http.ResponseWriter }{res}, io.NopCloser(strings.NewReader(events)))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, api.HeaderAcceptJSON, res.Header().Get(api.HeaderContentType))
+		assert.Equal(t, "{}", res.Body.String())
+	})
+
+	t.Run("error without flusher", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		err := serveEventStream(struct{ http.ResponseWriter }{res}, io.NopCloser(strings.NewReader("event: progress\ndata: {}\n\nevent: error\ndata: failed\n\n")))
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+		assert.Equal(t, "failed\n", res.Body.String())
+	})
+
+	t.Run("incomplete without flusher", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		err := serveEventStream(struct{ http.ResponseWriter }{res}, io.NopCloser(strings.NewReader("event: progress\ndata: {}\n\n")))
+		assert.Equal(t, errStreamIncomplete, err)
+		assert.Equal(t, http.StatusInternalServerError, res.Code)
+	})
+}
@@ -2,6 +2,7 @@ package frontend
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -23,19 +24,21 @@ import (
 	"github.com/grafana/tempo/modules/storage"
 	"github.com/grafana/tempo/pkg/api"
 	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/traceql"
 	"github.com/grafana/tempo/tempodb"
 )
 
 const (
 	traceByIDOp = "traces"
 	searchOp    = "search"
+	metricsOp   = "metrics"
 )
 
 type QueryFrontend struct {
-	TraceByID, Search http.Handler
-	logger            log.Logger
-	queriesPerTenant  *prometheus.CounterVec
-	store             storage.Store
+	TraceByID, Search, Metrics http.Handler
+	logger                     log.Logger
+	queriesPerTenant           *prometheus.CounterVec
+	store                      storage.Store
 }
 
 // New returns a new QueryFrontend
@@ -65,10 +68,12 @@ func New(cfg Config, next http.RoundTripper, o *overrides.Overrides, store stora
 	}, []string{"tenant", "op"})
 
 	retryWare := newRetryWare(cfg.MaxRetries, registerer)
+	queryLimitsWare := newQueryLimitsWare(o, logger, registerer)
 
 	// tracebyid middleware
-	traceByIDMiddleware := MergeMiddlewares(newTraceByIDMiddleware(cfg, logger), retryWare)
-	searchMiddleware := MergeMiddlewares(newSearchMiddleware(cfg, o, store, logger), retryWare)
+	traceByIDMiddleware := MergeMiddlewares(queryLimitsWare, newTraceByIDMiddleware(cfg, logger), retryWare)
+	searchMiddleware := MergeMiddlewares(queryLimitsWare, newQueryBlocklistWare(o, logger, registerer), newSearchMiddleware(cfg, o, store, logger), retryWare)
+	metricsMiddleware := MergeMiddlewares(queryLimitsWare, newQueryBlocklistWare(o, logger, registerer), newMetricsMiddleware(cfg, o), retryWare)
 
 	traceByIDCounter := queriesPerTenant.MustCurryWith(prometheus.Labels{
 		"op": traceByIDOp,
@@ -76,12 +81,17 @@ func New(cfg Config, next http.RoundTripper, o *overrides.Overrides, store stora
 	searchCounter := queriesPerTenant.MustCurryWith(prometheus.Labels{
 		"op": searchOp,
 	})
+	metricsCounter := queriesPerTenant.MustCurryWith(prometheus.Labels{
+		"op": metricsOp,
+	})
 
 	traces := traceByIDMiddleware.Wrap(next)
 	search := searchMiddleware.Wrap(next)
+	metrics := metricsMiddleware.Wrap(next)
 	return &QueryFrontend{
 		TraceByID:        newHandler(traces, traceByIDCounter, logger),
 		Search:           newHandler(search, searchCounter, logger),
+		Metrics:          newHandler(metrics, metricsCounter, logger),
 		logger:           logger,
 		queriesPerTenant: queriesPerTenant,
 		store:            store,
@@ -178,7 +188,7 @@ func newSearchMiddleware(cfg Config, o *overrides.Overrides, reader tempodb.Read
 		ingesterSearchRT := next
 		backendSearchRT := NewRoundTripper(next, newSearchSharder(reader, o, cfg.Search.Sharder, logger))
 
-		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		rt := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
 			// backend search queries require sharding so we pass through a special roundtripper
 			if api.IsBackendSearch(r) {
 				return backendSearchRT.RoundTrip(r)
@@ -192,9 +202,174 @@ func newSearchMiddleware(cfg Config, o *overrides.Overrides, reader tempodb.Read
 
 			return ingesterSearchRT.RoundTrip(r)
 		})
+
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.Header.Get(api.HeaderAccept) == api.HeaderAcceptEventStream {
+				return streamSearchRoundTrip(rt, r)
+			}
+
+			// only search results can be returned as protobuf. tags and tag values are always json
+			if !isSearchResults(r) || r.Header.Get(api.HeaderAccept) != api.HeaderAcceptProtobuf {
+				return rt.RoundTrip(r)
+			}
+
+			// queriers and the sharder always respond with json
+			r.Header.Set(api.HeaderAccept, api.HeaderAcceptJSON)
+
+			resp, err := rt.RoundTrip(r)
+			if err != nil || resp == nil || resp.StatusCode != http.StatusOK {
+				return resp, err
+			}
+
+			span := opentracing.SpanFromContext(r.Context())
+			if span != nil {
+				span.SetTag("contentType", api.HeaderAcceptProtobuf)
+			}
+
+			return searchResponseToProtobuf(resp)
+		})
+	})
+}
+
+// newMetricsMiddleware creates a new frontend middleware passing metrics queries on to a querier, which reads the
+// blocks in the range. The max look back and max duration of searches apply to metrics queries. Queries exceeding
+// the max duration are split into sub-queries if configured.
+func newMetricsMiddleware(cfg Config, o *overrides.Overrides) Middleware {
+	limits := &searchSharder{
+		overrides: o,
+		cfg:       cfg.Search.Sharder,
+	}
+
+	return MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			req, err := api.ParseMetricsRequest(r)
+			if err != nil {
+				return &http.Response{
+					StatusCode: http.StatusBadRequest,
+					Body:       io.NopCloser(strings.NewReader(err.Error())),
+					Header:     http.Header{},
+				}, nil
+			}
+
+			orgID, _ := user.ExtractOrgID(r.Context())
+			r.Header.Set(user.OrgIDHeaderName, orgID)
+
+			ranges, err := metricsRanges(limits, orgID, req)
+			if err != nil {
+				return &http.Response{
+					StatusCode: http.StatusBadRequest,
+					Body:       io.NopCloser(strings.NewReader(err.Error())),
+					Header:     http.Header{},
+				}, nil
+			}
+			if len(ranges) > 1 {
+				return splitMetricsRoundTrip(next, r, req, ranges)
+			}
+
+			// queries with thresholds are evaluated in two phases, the values of the thresholds are computed first
+			resp, err := resolveMetricsThresholds(next, r, req)
+			if err != nil || resp != nil {
+				return resp, err
+			}
+
+			// a relative time range has been translated to start and end
+			r = api.BuildMetricsRequest(r, req)
+			r.RequestURI = buildUpstreamRequestURI(r.URL.Path, r.URL.Query())
+
+			return next.RoundTrip(r)
+		})
 	})
 }
 
+// resolveMetricsThresholds requests the values of the metrics thresholds of the query from a querier and replaces
+// the thresholds of the query with them. The response of the querier is returned if the request failed.
+func resolveMetricsThresholds(next http.RoundTripper, r *http.Request, req *api.MetricsRequest) (*http.Response, error) {
+	expr, err := traceql.Parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	if len(expr.MetricsThresholds()) == 0 {
+		return nil, nil
+	}
+
+	thresholdsReq := api.BuildMetricsRequest(r.Clone(r.Context()), req)
+	thresholdsReq.URL.Path = strings.TrimSuffix(thresholdsReq.URL.Path, api.PathMetricsQueryRange) + api.PathMetricsThresholds
+	thresholdsReq.RequestURI = buildUpstreamRequestURI(thresholdsReq.URL.Path, thresholdsReq.URL.Query())
+
+	resp, err := next.RoundTrip(thresholdsReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	thresholds := &api.MetricsThresholdsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(thresholds); err != nil {
+		return nil, fmt.Errorf("error decoding the values of the metrics thresholds: %w", err)
+	}
+	resolved, err := expr.ResolveMetricsThresholds(thresholds.Values())
+	if err != nil {
+		return nil, err
+	}
+	req.Query = resolved.String()
+
+	return nil, nil
+}
+
+// streamSearchRoundTrip executes a search requested as server-sent events. Backend searches are streamed with
+// progress by the sharder, other searches respond with a single result event. Tags and tag values are always
+// json.
+func streamSearchRoundTrip(rt http.RoundTripper, r *http.Request) (*http.Response, error) {
+	if !isSearchResults(r) {
+		r.Header.Set(api.HeaderAccept, api.HeaderAcceptJSON)
+		return rt.RoundTrip(r)
+	}
+	if api.IsBackendSearch(r) {
+		return rt.RoundTrip(r)
+	}
+
+	r.Header.Set(api.HeaderAccept, api.HeaderAcceptJSON)
+	resp, err := rt.RoundTrip(r)
+	if err != nil || resp == nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	return eventStreamResponse(resp)
+}
+
+// isSearchResults returns true for the search paths responding with search results rather than tags or tag values
+func isSearchResults(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, api.PathSearch) || api.IsSearchRecent(r)
+}
+
+// searchResponseToProtobuf converts the json body of a search response to protobuf.
+func searchResponseToProtobuf(resp *http.Response) (*http.Response, error) {
+	defer resp.Body.Close()
+
+	responseObject := &tempopb.SearchResponse{}
+	err := jsonpb.Unmarshal(resp.Body, responseObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "error unmarshalling search response at query frontend")
+	}
+
+	body, err := proto.Marshal(responseObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	resp.Header.Set(api.HeaderContentType, api.HeaderAcceptProtobuf)
+	resp.Header.Del("Content-Length")
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+
+	return resp, nil
+}
+
 // buildUpstreamRequestURI returns a uri based on the passed parameters
 // we do this because weaveworks/common uses the RequestURI field to translate from http.Request to httpgrpc.Request
 // https://github.com/weaveworks/common/blob/47e357f4e1badb7da17ad74bae63e228bdd76e8f/httpgrpc/server/server.go#L48
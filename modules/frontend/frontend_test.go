@@ -2,15 +2,25 @@ package frontend
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/golang/protobuf/jsonpb" //nolint:all //deprecated
+	"github.com/golang/protobuf/proto"  //nolint:all //deprecated
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/traceql"
 )
 
 type mockNextTripperware struct{}
@@ -42,6 +52,292 @@ func TestFrontendRoundTripsSearch(t *testing.T) {
 	assert.Equal(t, res.Body.String(), "next")
 }
 
+func TestFrontendSearchContentNegotiation(t *testing.T) {
+	expected := &tempopb.SearchResponse{
+		Traces: []*tempopb.TraceSearchMetadata{
+			{TraceID: "1234", RootServiceName: "svc", RootTraceName: "op", DurationMs: 10},
+		},
+		Metrics: &tempopb.SearchMetrics{InspectedTraces: 1},
+	}
+	body, err := (&jsonpb.Marshaler{}).MarshalToString(expected)
+	require.NoError(t, err)
+
+	var upstreamAccept string
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		upstreamAccept = r.Header.Get(api.HeaderAccept)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{api.HeaderContentType: {api.HeaderAcceptJSON}},
+			Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+		}, nil
+	})
+
+	f, err := New(Config{QueryShards: minQueryShards,
+		Search: SearchConfig{
+			Sharder: SearchSharderConfig{
+				ConcurrentRequests:    defaultConcurrentRequests,
+				TargetBytesPerRequest: defaultTargetBytesPerRequest,
+			},
+		},
+	}, next, nil, nil, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name                string
+		path                string
+		accept              string
+		expectedContentType string
+	}{
+		{
+			name:                "default json",
+			path:                api.PathSearch,
+			expectedContentType: api.HeaderAcceptJSON,
+		},
+		{
+			name:                "protobuf",
+			path:                api.PathSearch,
+			accept:              api.HeaderAcceptProtobuf,
+			expectedContentType: api.HeaderAcceptProtobuf,
+		},
+		{
+			name:                "recent search protobuf",
+			path:                api.PathSearchRecent,
+			accept:              api.HeaderAcceptProtobuf,
+			expectedContentType: api.HeaderAcceptProtobuf,
+		},
+		{
+			name:                "tags are always json",
+			path:                api.PathSearchTags,
+			accept:              api.HeaderAcceptProtobuf,
+			expectedContentType: api.HeaderAcceptJSON,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set(api.HeaderAccept, tc.accept)
+			}
+
+			res := httptest.NewRecorder()
+			f.Search.ServeHTTP(res, req)
+			require.Equal(t, http.StatusOK, res.Code)
+			assert.Equal(t, tc.expectedContentType, res.Header().Get(api.HeaderContentType))
+
+			actual := &tempopb.SearchResponse{}
+			if tc.expectedContentType == api.HeaderAcceptProtobuf {
+				assert.Equal(t, api.HeaderAcceptJSON, upstreamAccept)
+				require.NoError(t, proto.Unmarshal(res.Body.Bytes(), actual))
+				assert.Equal(t, expected, actual)
+			} else {
+				assert.Equal(t, body, res.Body.String())
+			}
+		})
+	}
+}
+
+func TestFrontendSearchRecentIsNotSharded(t *testing.T) {
+	var upstreamURIs []string
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		upstreamURIs = append(upstreamURIs, r.RequestURI)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte("next"))),
+		}, nil
+	})
+
+	// without a store the sharder would fail to find the blocks to search
+	f, err := New(Config{QueryShards: minQueryShards,
+		Search: SearchConfig{
+			Sharder: SearchSharderConfig{
+				ConcurrentRequests:    defaultConcurrentRequests,
+				TargetBytesPerRequest: defaultTargetBytesPerRequest,
+			},
+		},
+	}, next, nil, nil, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", api.PathSearchRecent+"?tags=service.name%3Dfoo&start=1&end=2", nil)
+	res := httptest.NewRecorder()
+	f.Search.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "next", res.Body.String())
+	assert.Equal(t, []string{api.PathPrefixQuerier + api.PathSearchRecent + "?tags=service.name%3Dfoo&start=1&end=2"}, upstreamURIs)
+}
+
+func TestFrontendMetricsQueryRange(t *testing.T) {
+	var upstreamURIs []string
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		upstreamURIs = append(upstreamURIs, r.RequestURI)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte("next"))),
+		}, nil
+	})
+
+	o, err := overrides.NewOverrides(overrides.Limits{})
+	require.NoError(t, err)
+
+	f, err := New(Config{QueryShards: minQueryShards,
+		Search: SearchConfig{
+			Sharder: SearchSharderConfig{
+				ConcurrentRequests:    defaultConcurrentRequests,
+				TargetBytesPerRequest: defaultTargetBytesPerRequest,
+			},
+		},
+	}, next, o, nil, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", api.PathMetricsQueryRange+"?start=1000&end=2000&q=%7B+.a+%7D+%7C+histogram_over_time%28duration%29", nil)
+	res := httptest.NewRecorder()
+	f.Metrics.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "next", res.Body.String())
+	assert.Equal(t, []string{api.PathPrefixQuerier + api.PathMetricsQueryRange + "?end=2000&q=%7B+.a+%7D+%7C+histogram_over_time%28duration%29&start=1000&step=10s"}, upstreamURIs)
+
+	// invalid queries aren't passed on
+	req = httptest.NewRequest("GET", api.PathMetricsQueryRange+"?start=1000&end=2000&q=%7B+.a+%7D", nil)
+	res = httptest.NewRecorder()
+	f.Metrics.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Len(t, upstreamURIs, 1)
+}
+
+func TestFrontendMetricsThresholds(t *testing.T) {
+	var upstreamQueries []string
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		upstreamQueries = append(upstreamQueries, r.URL.Path+" "+r.URL.Query().Get("q"))
+
+		body := "next"
+		if strings.HasSuffix(r.URL.Path, api.PathMetricsThresholds) {
+			b, err := json.Marshal(api.NewMetricsThresholdsResponse([]traceql.MetricsThresholdValues{
+				{traceql.Static{Type: traceql.TypeNil}: 1.5},
+			}, 0))
+			require.NoError(t, err)
+			body = string(b)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	o, err := overrides.NewOverrides(overrides.Limits{})
+	require.NoError(t, err)
+
+	f, err := New(Config{QueryShards: minQueryShards,
+		Search: SearchConfig{
+			Sharder: SearchSharderConfig{
+				ConcurrentRequests:    defaultConcurrentRequests,
+				TargetBytesPerRequest: defaultTargetBytesPerRequest,
+			},
+		},
+	}, next, o, nil, log.NewNopLogger(), nil)
+	require.NoError(t, err)
+
+	query := url.QueryEscape("{ duration > quantile_over_time(duration, 0.9) } | histogram_over_time(duration)")
+	req := httptest.NewRequest("GET", api.PathMetricsQueryRange+"?start=1000&end=2000&q="+query, nil)
+	res := httptest.NewRecorder()
+	f.Metrics.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "next", res.Body.String())
+	assert.Equal(t, []string{
+		api.PathMetricsThresholds + " { duration > quantile_over_time(duration, 0.9) } | histogram_over_time(duration)",
+		api.PathMetricsQueryRange + " { duration > 1500ms }|histogram_over_time(duration)",
+	}, upstreamQueries)
+}
+
+func TestFrontendMetricsQueryRangeSplit(t *testing.T) {
+	var upstreamURIs []string
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		upstreamURIs = append(upstreamURIs, r.RequestURI)
+
+		req, err := api.ParseMetricsRequest(r)
+		require.NoError(t, err)
+		steps := int((time.Duration(req.End-req.Start)*time.Second + req.Step - 1) / req.Step)
+		counts := make([]uint64, steps)
+		for i := range counts {
+			counts[i] = uint64(req.Start)
+		}
+		b, err := json.Marshal(&api.MetricsQueryRangeResponse{
+			Start:         req.Start,
+			StepMs:        req.Step.Milliseconds(),
+			Series:        []api.MetricsSeries{{Bucket: 1, Counts: counts}},
+			SkippedBlocks: 1,
+		})
+		require.NoError(t, err)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader(b)),
+		}, nil
+	})
+
+	newFrontend := func(sharder SearchSharderConfig) *QueryFrontend {
+		o, err := overrides.NewOverrides(overrides.Limits{})
+		require.NoError(t, err)
+
+		sharder.ConcurrentRequests = defaultConcurrentRequests
+		sharder.TargetBytesPerRequest = defaultTargetBytesPerRequest
+		f, err := New(Config{QueryShards: minQueryShards, Search: SearchConfig{Sharder: sharder}}, next, o, nil, log.NewNopLogger(), nil)
+		require.NoError(t, err)
+		return f
+	}
+	query := func(f *QueryFrontend, params string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", api.PathMetricsQueryRange+"?"+params, nil)
+		res := httptest.NewRecorder()
+		f.Metrics.ServeHTTP(res, req)
+		return res
+	}
+	histogram := "q=" + url.QueryEscape("{ .a } | histogram_over_time(duration)")
+
+	// the range is split into sub-queries of whole steps, oldest first
+	f := newFrontend(SearchSharderConfig{MaxDuration: 120 * time.Second, SplitByMaxDuration: true})
+	res := query(f, histogram+"&start=1000&end=1250&step=50s")
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{
+		api.PathPrefixQuerier + api.PathMetricsQueryRange + "?end=1100&q=%7B+.a+%7D+%7C+histogram_over_time%28duration%29&start=1000&step=50s",
+		api.PathPrefixQuerier + api.PathMetricsQueryRange + "?end=1200&q=%7B+.a+%7D+%7C+histogram_over_time%28duration%29&start=1100&step=50s",
+		api.PathPrefixQuerier + api.PathMetricsQueryRange + "?end=1250&q=%7B+.a+%7D+%7C+histogram_over_time%28duration%29&start=1200&step=50s",
+	}, upstreamURIs)
+
+	actual := &api.MetricsQueryRangeResponse{}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), actual))
+	assert.Equal(t, &api.MetricsQueryRangeResponse{
+		Start:         1000,
+		StepMs:        50000,
+		Series:        []api.MetricsSeries{{Bucket: 1, Counts: []uint64{1000, 1000, 1100, 1100, 1200}}},
+		SkippedBlocks: 3,
+	}, actual)
+
+	// the number of sub-queries is capped
+	upstreamURIs = nil
+	f = newFrontend(SearchSharderConfig{MaxDuration: 120 * time.Second, SplitByMaxDuration: true, MaxSplitQueries: 2})
+	res = query(f, histogram+"&start=1000&end=1250&step=50s")
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Equal(t, "range specified by start and end would be split into 3 sub-queries, more than the max of 2. received start=1000 end=1250", res.Body.String())
+
+	// queries with thresholds aren't split
+	res = query(f, "q="+url.QueryEscape("{ duration > quantile_over_time(duration, 0.9) } | histogram_over_time(duration)")+"&start=1000&end=1250&step=50s")
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	// longer ranges are rejected without splitting
+	f = newFrontend(SearchSharderConfig{MaxDuration: 120 * time.Second})
+	res = query(f, histogram+"&start=1000&end=1250&step=50s")
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Equal(t, "range specified by start and end exceeds 2m0s. received start=1000 end=1250", res.Body.String())
+
+	// the max look back applies
+	f = newFrontend(SearchSharderConfig{MaxLookback: time.Hour})
+	res = query(f, histogram+"&start=1000&end=1250&step=50s")
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Empty(t, upstreamURIs)
+}
+
 func TestFrontendBadConfigFails(t *testing.T) {
 	f, err := New(Config{QueryShards: minQueryShards - 1,
 		Search: SearchConfig{
@@ -98,6 +98,24 @@ func (f *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// server-sent events are flushed as they are received, the response is logged once the stream ends
+	if isEventStream(resp) {
+		err = serveEventStream(w, resp.Body)
+		if err != nil {
+			level.Info(f.logger).Log("msg", "event stream ended", "err", err)
+		}
+		level.Info(f.logger).Log(
+			"tenant", orgID,
+			"method", r.Method,
+			"traceID", traceID,
+			"url", r.URL.RequestURI(),
+			"duration", time.Since(start).String(),
+			"status", resp.StatusCode,
+			"stream", true,
+		)
+		return
+	}
+
 	// write headers, status code and body
 	copyHeader(w.Header(), resp.Header)
 	w.WriteHeader(resp.StatusCode)
@@ -0,0 +1,171 @@
+package frontend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/traceql"
+)
+
+// metricsRanges enforces the max look back and max duration of the searches of a tenant on a metrics query. The
+// ranges of the sub-queries are returned if the query is split, otherwise the range of the query.
+func metricsRanges(limits *searchSharder, tenantID string, req *api.MetricsRequest) ([]timeRange, error) {
+	maxLookback := limits.maxLookback(tenantID)
+	if maxLookback != 0 && req.Start < uint32(time.Now().Add(-maxLookback).Unix()) {
+		return nil, fmt.Errorf("start is further in the past than the max look back of %s. received start=%d", maxLookback, req.Start)
+	}
+
+	maxDuration := limits.maxDuration(tenantID)
+	if maxDuration == 0 || time.Duration(req.End-req.Start)*time.Second <= maxDuration {
+		return []timeRange{{start: req.Start, end: req.End}}, nil
+	}
+	if !limits.cfg.SplitByMaxDuration {
+		return nil, fmt.Errorf("range specified by start and end exceeds %s. received start=%d end=%d", maxDuration, req.Start, req.End)
+	}
+
+	// the thresholds of a query are computed over its whole range
+	expr, err := traceql.Parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	if len(expr.MetricsThresholds()) > 0 {
+		return nil, fmt.Errorf("range specified by start and end exceeds %s and queries with thresholds can't be split. received start=%d end=%d", maxDuration, req.Start, req.End)
+	}
+
+	return splitMetricsRange(req, maxDuration, limits.maxSplitQueries(tenantID))
+}
+
+// splitMetricsRange splits the range of a metrics query into consecutive ranges of at most maxDuration, oldest first.
+// Every range but the last is a whole number of steps and seconds, so the steps of the ranges line up with the steps
+// of the query. An error is returned if there would be more than maxQueries ranges, 0 doesn't limit the number of
+// ranges.
+func splitMetricsRange(req *api.MetricsRequest, maxDuration time.Duration, maxQueries int) ([]timeRange, error) {
+	// the shortest range that is a whole number of steps and seconds is stepsPerUnit steps
+	stepsPerUnit := time.Second / gcd(req.Step, time.Second)
+	if req.Step > maxDuration/stepsPerUnit {
+		return nil, fmt.Errorf("range specified by start and end exceeds %s and can't be split into sub-queries of whole steps of %s", maxDuration, req.Step)
+	}
+	unit := req.Step * stepsPerUnit
+	size := uint64(maxDuration / unit * unit / time.Second)
+
+	count := (uint64(req.End-req.Start) + size - 1) / size
+	if maxQueries > 0 && count > uint64(maxQueries) {
+		return nil, fmt.Errorf("range specified by start and end would be split into %d sub-queries, more than the max of %d. received start=%d end=%d", count, maxQueries, req.Start, req.End)
+	}
+
+	ranges := make([]timeRange, 0, count)
+	for start := uint64(req.Start); start < uint64(req.End); start += size {
+		end := start + size
+		if end > uint64(req.End) {
+			end = uint64(req.End)
+		}
+		ranges = append(ranges, timeRange{start: uint32(start), end: uint32(end)})
+	}
+	return ranges, nil
+}
+
+func gcd(a, b time.Duration) time.Duration {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// splitMetricsRoundTrip executes the sub-queries of a metrics query one after another and merges their responses.
+// The response of the first failed sub-query is returned.
+func splitMetricsRoundTrip(next http.RoundTripper, r *http.Request, req *api.MetricsRequest, ranges []timeRange) (*http.Response, error) {
+	resps := make([]*api.MetricsQueryRangeResponse, 0, len(ranges))
+	for _, rng := range ranges {
+		rangeReq := *req
+		rangeReq.Start, rangeReq.End = rng.start, rng.end
+
+		subR := api.BuildMetricsRequest(r.Clone(r.Context()), &rangeReq)
+		subR.RequestURI = buildUpstreamRequestURI(subR.URL.Path, subR.URL.Query())
+
+		resp, err := next.RoundTrip(subR)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return resp, nil
+		}
+
+		sub := &api.MetricsQueryRangeResponse{}
+		err = json.NewDecoder(resp.Body).Decode(sub)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding the response of a metrics sub-query: %w", err)
+		}
+		resps = append(resps, sub)
+	}
+
+	merged, err := mergeMetricsResponses(req, resps)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			api.HeaderContentType: {api.HeaderAcceptJSON},
+		},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}, nil
+}
+
+// mergeMetricsResponses merges the responses of the sub-queries of a metrics query into the response of the query.
+// The counts of a sub-query are placed at the steps of its range, the exemplars of a bucket are concatenated.
+func mergeMetricsResponses(req *api.MetricsRequest, resps []*api.MetricsQueryRangeResponse) (*api.MetricsQueryRangeResponse, error) {
+	rng := time.Duration(req.End-req.Start) * time.Second
+	steps := int((rng + req.Step - 1) / req.Step)
+
+	merged := &api.MetricsQueryRangeResponse{
+		Start:  req.Start,
+		StepMs: req.Step.Milliseconds(),
+		Series: []api.MetricsSeries{},
+	}
+	buckets := map[float64]int{}
+	for _, resp := range resps {
+		if resp.Start < req.Start {
+			return nil, errors.New("metrics sub-query starts before the query")
+		}
+		offset := int(time.Duration(resp.Start-req.Start) * time.Second / req.Step)
+		merged.SkippedBlocks += resp.SkippedBlocks
+
+		for _, s := range resp.Series {
+			i, ok := buckets[s.Bucket]
+			if !ok {
+				i = len(merged.Series)
+				buckets[s.Bucket] = i
+				merged.Series = append(merged.Series, api.MetricsSeries{
+					Bucket: s.Bucket,
+					Counts: make([]uint64, steps),
+				})
+			}
+
+			series := &merged.Series[i]
+			for j, c := range s.Counts {
+				if offset+j < steps {
+					series.Counts[offset+j] += c
+				}
+			}
+			series.Exemplars = append(series.Exemplars, s.Exemplars...)
+		}
+	}
+
+	sort.Slice(merged.Series, func(i, j int) bool {
+		return merged.Series[i].Bucket < merged.Series[j].Bucket
+	})
+	return merged, nil
+}
@@ -0,0 +1,70 @@
+package frontend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/api"
+)
+
+func TestSplitMetricsRange(t *testing.T) {
+	req := &api.MetricsRequest{Start: 1000, End: 1350, Step: 30 * time.Second}
+
+	ranges, err := splitMetricsRange(req, 100*time.Second, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []timeRange{{start: 1000, end: 1090}, {start: 1090, end: 1180}, {start: 1180, end: 1270}, {start: 1270, end: 1350}}, ranges)
+
+	_, err = splitMetricsRange(req, 100*time.Second, 3)
+	assert.EqualError(t, err, "range specified by start and end would be split into 4 sub-queries, more than the max of 3. received start=1000 end=1350")
+
+	// ranges are whole seconds
+	req.Step = 1500 * time.Millisecond
+	ranges, err = splitMetricsRange(req, 100*time.Second, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []timeRange{{start: 1000, end: 1099}, {start: 1099, end: 1198}, {start: 1198, end: 1297}, {start: 1297, end: 1350}}, ranges)
+
+	// a step longer than the max duration can't be split
+	req.Step = 200 * time.Second
+	_, err = splitMetricsRange(req, 100*time.Second, 0)
+	assert.Error(t, err)
+}
+
+func TestMergeMetricsResponses(t *testing.T) {
+	req := &api.MetricsRequest{Start: 1000, End: 1050, Step: 10 * time.Second}
+
+	merged, err := mergeMetricsResponses(req, []*api.MetricsQueryRangeResponse{
+		{
+			Start:  1000,
+			StepMs: 10000,
+			Series: []api.MetricsSeries{
+				{Bucket: 1, Counts: []uint64{1, 2, 3}, Exemplars: []api.MetricsExemplar{{TraceID: "a", Value: 1, TimestampMs: 1000000}}},
+			},
+			SkippedBlocks: 1,
+		},
+		{
+			Start:  1030,
+			StepMs: 10000,
+			Series: []api.MetricsSeries{
+				{Bucket: 0.5, Counts: []uint64{1, 0}},
+				{Bucket: 1, Counts: []uint64{4, 5}, Exemplars: []api.MetricsExemplar{{TraceID: "b", Value: 1, TimestampMs: 1040000}}},
+			},
+			SkippedBlocks: 2,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &api.MetricsQueryRangeResponse{
+		Start:  1000,
+		StepMs: 10000,
+		Series: []api.MetricsSeries{
+			{Bucket: 0.5, Counts: []uint64{0, 0, 0, 1, 0}},
+			{Bucket: 1, Counts: []uint64{1, 2, 3, 4, 5}, Exemplars: []api.MetricsExemplar{
+				{TraceID: "a", Value: 1, TimestampMs: 1000000},
+				{TraceID: "b", Value: 1, TimestampMs: 1040000},
+			}},
+		},
+		SkippedBlocks: 3,
+	}, merged)
+}
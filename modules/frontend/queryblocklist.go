@@ -0,0 +1,186 @@
+package frontend
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/user"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/tempo/modules/overrides"
+)
+
+const (
+	blockedReasonDenied      = "denied"
+	blockedReasonRateLimited = "rate_limited"
+
+	// maxBlocklistCacheEntries bounds the compiled patterns and the limiters kept for the rules of all tenants, so
+	// rules that keep changing in the runtime config don't grow them forever
+	maxBlocklistCacheEntries = 1024
+)
+
+// newQueryBlocklistWare creates a middleware denying or rate limiting the searches of a tenant with a TraceQL query
+// matching the query blocklist of the tenant. The blocklist is read from the overrides for every search, so rules
+// added to the runtime config apply without restarting the frontend. Rate limits are enforced by every frontend on
+// its own, a tenant may run the matching searches at the rate limit times the number of frontends.
+func newQueryBlocklistWare(o *overrides.Overrides, logger log.Logger, registerer prometheus.Registerer) Middleware {
+	blockedQueries := promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "query_frontend_queries_blocked_total",
+		Help:      "Total searches blocked by the query blocklist per tenant.",
+	}, []string{"tenant", "reason"})
+
+	blocklist := &queryBlocklist{
+		overrides:      o,
+		logger:         logger,
+		blockedQueries: blockedQueries,
+		patterns:       map[string]*regexp.Regexp{},
+		limiters:       map[blocklistLimiterKey]*rate.Limiter{},
+	}
+
+	return MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if resp := blocklist.check(r); resp != nil {
+				return resp, nil
+			}
+			return next.RoundTrip(r)
+		})
+	})
+}
+
+type blocklistLimiterKey struct {
+	tenant  string
+	pattern string
+}
+
+type queryBlocklist struct {
+	overrides      *overrides.Overrides
+	logger         log.Logger
+	blockedQueries *prometheus.CounterVec
+
+	mtx      sync.Mutex
+	patterns map[string]*regexp.Regexp
+	limiters map[blocklistLimiterKey]*rate.Limiter
+}
+
+// check returns the response to a blocked search, nil if the search may run. Only the first matching rule applies.
+func (b *queryBlocklist) check(r *http.Request) *http.Response {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		return nil
+	}
+
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		return nil
+	}
+
+	for _, rule := range b.overrides.QueryBlocklist(tenantID) {
+		re := b.pattern(rule.Pattern)
+		if re == nil || !re.MatchString(query) {
+			continue
+		}
+
+		if rule.RateLimit > 0 && b.limiter(tenantID, rule).Allow() {
+			return nil
+		}
+
+		reason, statusCode, msg := blockedReasonDenied, http.StatusForbidden, "query denied by the query blocklist"
+		if rule.RateLimit > 0 {
+			reason, statusCode = blockedReasonRateLimited, http.StatusTooManyRequests
+			msg = fmt.Sprintf("query rate limited to %v per second by the query blocklist", rule.RateLimit)
+		}
+		if rule.Reason != "" {
+			msg += ": " + rule.Reason
+		}
+
+		b.blockedQueries.WithLabelValues(tenantID, reason).Inc()
+		level.Info(b.logger).Log("msg", "search blocked", "tenant", tenantID, "reason", reason, "pattern", rule.Pattern, "query", query)
+
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       io.NopCloser(strings.NewReader(msg)),
+			Header:     http.Header{},
+		}
+	}
+
+	return nil
+}
+
+// pattern returns the compiled pattern, nil if it doesn't compile. The runtime config rejects invalid patterns, they
+// are only skipped here for defaults set in the config file.
+func (b *queryBlocklist) pattern(p string) *regexp.Regexp {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	re, ok := b.patterns[p]
+	if !ok {
+		var err error
+		re, err = regexp.Compile(p)
+		if err != nil {
+			level.Warn(b.logger).Log("msg", "skipping invalid query blocklist pattern", "pattern", p, "err", err)
+		}
+		// the patterns in use are compiled again
+		if len(b.patterns) >= maxBlocklistCacheEntries {
+			b.patterns = map[string]*regexp.Regexp{}
+		}
+		b.patterns[p] = re
+	}
+	return re
+}
+
+// limiter returns the limiter of the matching searches of a tenant. Changes to the rate limit of a rule apply to
+// its existing limiter.
+func (b *queryBlocklist) limiter(tenantID string, rule overrides.QueryBlocklistRule) *rate.Limiter {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	burst := int(math.Max(1, math.Ceil(rule.RateLimit)))
+
+	key := blocklistLimiterKey{tenant: tenantID, pattern: rule.Pattern}
+	l, ok := b.limiters[key]
+	if !ok {
+		if len(b.limiters) >= maxBlocklistCacheEntries {
+			b.removeLimiters()
+		}
+		l = rate.NewLimiter(rate.Limit(rule.RateLimit), burst)
+		b.limiters[key] = l
+		return l
+	}
+	if l.Limit() != rate.Limit(rule.RateLimit) {
+		l.SetLimit(rate.Limit(rule.RateLimit))
+		l.SetBurst(burst)
+	}
+	return l
+}
+
+// removeLimiters removes the limiters of the rules that were removed from the blocklist of their tenant. All
+// limiters are removed if the rules in use alone exceed the max entries.
+func (b *queryBlocklist) removeLimiters() {
+	for key := range b.limiters {
+		if !hasBlocklistRule(b.overrides.QueryBlocklist(key.tenant), key.pattern) {
+			delete(b.limiters, key)
+		}
+	}
+	if len(b.limiters) >= maxBlocklistCacheEntries {
+		b.limiters = map[blocklistLimiterKey]*rate.Limiter{}
+	}
+}
+
+func hasBlocklistRule(rules []overrides.QueryBlocklistRule, pattern string) bool {
+	for _, rule := range rules {
+		if rule.Pattern == pattern && rule.RateLimit > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package frontend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/tempo/modules/overrides"
+)
+
+func TestQueryBlocklist(t *testing.T) {
+	o, err := overrides.NewOverrides(overrides.Limits{
+		QueryBlocklist: []overrides.QueryBlocklistRule{
+			{Pattern: `span\.foo`, Reason: "dashboard overloads the queriers"},
+			{Pattern: `span\.bar`, RateLimit: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	var calls int
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := newQueryBlocklistWare(o, log.NewNopLogger(), prometheus.NewRegistry()).Wrap(next)
+
+	search := func(q string) *http.Response {
+		req := httptest.NewRequest("GET", "/api/search?q="+url.QueryEscape(q), nil)
+		req = req.WithContext(user.InjectOrgID(context.Background(), "test"))
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// denied
+	resp := search(`{ span.foo = "a" }`)
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "dashboard overloads the queriers")
+	require.Equal(t, 0, calls)
+
+	// rate limited after the first search
+	require.Equal(t, http.StatusOK, search(`{ span.bar = "a" }`).StatusCode)
+	require.Equal(t, http.StatusTooManyRequests, search(`{ span.bar = "a" }`).StatusCode)
+	require.Equal(t, 1, calls)
+
+	// not matching
+	require.Equal(t, http.StatusOK, search(`{ span.baz = "a" }`).StatusCode)
+	require.Equal(t, 2, calls)
+
+	// searches without a query aren't blocked
+	req := httptest.NewRequest("GET", "/api/search?tags=foo%3Dbar", nil)
+	req = req.WithContext(user.InjectOrgID(context.Background(), "test"))
+	resp, err = rt.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 3, calls)
+}
+
+func TestQueryBlocklistCacheBounded(t *testing.T) {
+	o, err := overrides.NewOverrides(overrides.Limits{
+		QueryBlocklist: []overrides.QueryBlocklistRule{
+			{Pattern: `span\.bar`, RateLimit: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	b := &queryBlocklist{
+		overrides: o,
+		logger:    log.NewNopLogger(),
+		patterns:  map[string]*regexp.Regexp{},
+		limiters:  map[blocklistLimiterKey]*rate.Limiter{},
+	}
+
+	// rules that are no longer in the blocklist
+	for i := 0; i < 2*maxBlocklistCacheEntries; i++ {
+		rule := overrides.QueryBlocklistRule{Pattern: fmt.Sprintf(`span\.foo%d`, i), RateLimit: 1}
+		require.NotNil(t, b.pattern(rule.Pattern))
+		b.limiter("test", rule)
+	}
+	require.LessOrEqual(t, len(b.patterns), maxBlocklistCacheEntries)
+	require.LessOrEqual(t, len(b.limiters), maxBlocklistCacheEntries)
+
+	// the limiters of the rules in use are kept
+	rule := overrides.QueryBlocklistRule{Pattern: `span\.bar`, RateLimit: 1}
+	l := b.limiter("test", rule)
+	for i := 0; i < 2*maxBlocklistCacheEntries; i++ {
+		b.limiter("test", overrides.QueryBlocklistRule{Pattern: fmt.Sprintf(`span\.baz%d`, i), RateLimit: 1})
+	}
+	require.Same(t, l, b.limiter("test", rule))
+}
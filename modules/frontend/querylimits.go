@@ -0,0 +1,103 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/modules/overrides"
+)
+
+const (
+	limitedReasonTimeout = "query_timeout"
+)
+
+// newQueryLimitsWare creates a middleware enforcing the query timeout of a tenant, so the slow queries of one tenant
+// don't occupy all queriers. The timeout is read from the overrides for every query. Queries streaming their response
+// run until the stream is closed. The max concurrent queries of a tenant are enforced by the request queue.
+func newQueryLimitsWare(o *overrides.Overrides, logger log.Logger, registerer prometheus.Registerer) Middleware {
+	limitedQueries := promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "query_frontend_queries_limited_total",
+		Help:      "Total queries canceled by the query limits per tenant.",
+	}, []string{"tenant", "reason"})
+
+	limits := &queryLimits{
+		overrides:      o,
+		logger:         logger,
+		limitedQueries: limitedQueries,
+	}
+
+	return MiddlewareFunc(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return limits.roundTrip(next, r)
+		})
+	})
+}
+
+type queryLimits struct {
+	overrides      *overrides.Overrides
+	logger         log.Logger
+	limitedQueries *prometheus.CounterVec
+}
+
+func (l *queryLimits) roundTrip(next http.RoundTripper, r *http.Request) (*http.Response, error) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		return next.RoundTrip(r)
+	}
+
+	ctx, cancel := r.Context(), context.CancelFunc(func() {})
+	timeout := l.overrides.QueryTimeout(tenantID)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	resp, err := next.RoundTrip(r.WithContext(ctx))
+	if resp != nil && err == nil && isEventStream(resp) {
+		// the stream is written after the round trip, the query is done once it is closed
+		resp.Body = &doneBody{ReadCloser: resp.Body, done: cancel}
+		return resp, nil
+	}
+	cancel()
+
+	failed := err != nil || resp == nil || resp.StatusCode != http.StatusOK
+	if failed && timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		l.limitedQueries.WithLabelValues(tenantID, limitedReasonTimeout).Inc()
+		level.Info(l.logger).Log("msg", "query timed out", "tenant", tenantID, "timeout", timeout, "url", r.URL.RequestURI())
+
+		if resp != nil && resp.Body != nil {
+			_ = resp.Body.Close()
+		}
+		return &http.Response{
+			StatusCode: http.StatusGatewayTimeout,
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf("query timed out after %s", timeout))),
+			Header:     http.Header{},
+		}, nil
+	}
+
+	return resp, err
+}
+
+// doneBody calls done once when it's closed
+type doneBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *doneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,50 @@
+package frontend
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/modules/overrides"
+)
+
+func TestQueryLimitsTimeout(t *testing.T) {
+	o, err := overrides.NewOverrides(overrides.Limits{
+		QueryTimeout: model.Duration(50 * time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Query().Get("slow") == "" {
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		}
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+	rt := newQueryLimitsWare(o, log.NewNopLogger(), prometheus.NewRegistry()).Wrap(next)
+
+	query := func(url string) *http.Response {
+		req := httptest.NewRequest("GET", url, nil)
+		req = req.WithContext(user.InjectOrgID(context.Background(), "test"))
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	require.Equal(t, http.StatusOK, query("/api/search").StatusCode)
+
+	resp := query("/api/search?slow=true")
+	require.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "timed out after 50ms")
+}
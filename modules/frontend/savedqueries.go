@@ -0,0 +1,434 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/api"
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/traceql"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const (
+	// HeaderUser identifies the user a query history belongs to. Grafana sends it when `send_user_header` is enabled.
+	HeaderUser = "X-Grafana-User"
+
+	maxSavedQueryNameLength = 256
+	maxSavedQueriesBodySize = 10 << 20
+)
+
+type SavedQueriesConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	MaxSavedQueries   int  `yaml:"max_saved_queries"`
+	MaxHistoryPerUser int  `yaml:"max_history_per_user"`
+}
+
+// SavedQuery is a named TraceQL query shared by all users of a tenant
+type SavedQuery struct {
+	Name        string    `json:"name"`
+	Query       string    `json:"query"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   string    `json:"createdBy,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// QueryHistoryEntry is a single query previously run by a user
+type QueryHistoryEntry struct {
+	Query     string    `json:"query"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SavedQueriesExport is the document returned by the export endpoint and accepted by the import endpoint
+type SavedQueriesExport struct {
+	Queries []SavedQuery `json:"queries"`
+}
+
+// savedQueriesDocument is the per tenant object persisted in the backend
+type savedQueriesDocument struct {
+	Queries []SavedQuery                   `json:"queries"`
+	History map[string][]QueryHistoryEntry `json:"history"`
+}
+
+// SavedQueries stores named queries and a bounded per user query history for each tenant in the backend.
+// Every change is a read-modify-write of a single object per tenant. Updates are serialized within a
+// query frontend, concurrent writes from different query frontends are last write wins.
+type SavedQueries struct {
+	cfg    SavedQueriesConfig
+	r      backend.RawReader
+	w      backend.RawWriter
+	logger log.Logger
+
+	mtx sync.Mutex
+}
+
+// NewSavedQueries returns a new SavedQueries
+func NewSavedQueries(cfg SavedQueriesConfig, r backend.RawReader, w backend.RawWriter, logger log.Logger) *SavedQueries {
+	return &SavedQueries{
+		cfg:    cfg,
+		r:      r,
+		w:      w,
+		logger: logger,
+	}
+}
+
+// SavedQueriesHandler lists (GET) and creates or updates (POST) saved queries
+func (s *SavedQueries) SavedQueriesHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		doc, err := s.read(r.Context(), tenantID)
+		if err != nil {
+			s.writeError(w, tenantID, err)
+			return
+		}
+		writeJSON(w, doc.Queries)
+	case http.MethodPost:
+		var q SavedQuery
+		if err := decodeJSONBody(r, &q); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateSavedQuery(q); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		q.CreatedBy = r.Header.Get(HeaderUser)
+		q.UpdatedAt = time.Now()
+
+		err = s.update(r.Context(), tenantID, func(doc *savedQueriesDocument) error {
+			return s.upsert(doc, q)
+		})
+		if err != nil {
+			s.writeError(w, tenantID, err)
+			return
+		}
+		writeJSON(w, q)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SavedQueryHandler retrieves (GET) or deletes (DELETE) a single saved query by name
+func (s *SavedQueries) SavedQueryHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := mux.Vars(r)[api.URLParamQueryName]
+
+	switch r.Method {
+	case http.MethodGet:
+		doc, err := s.read(r.Context(), tenantID)
+		if err != nil {
+			s.writeError(w, tenantID, err)
+			return
+		}
+		i := findSavedQuery(doc.Queries, name)
+		if i < 0 {
+			http.Error(w, fmt.Sprintf("saved query %s not found", name), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, doc.Queries[i])
+	case http.MethodDelete:
+		found := false
+		err = s.update(r.Context(), tenantID, func(doc *savedQueriesDocument) error {
+			i := findSavedQuery(doc.Queries, name)
+			if i < 0 {
+				return nil
+			}
+			found = true
+			doc.Queries = append(doc.Queries[:i], doc.Queries[i+1:]...)
+			return nil
+		})
+		if err != nil {
+			s.writeError(w, tenantID, err)
+			return
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("saved query %s not found", name), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// QueryHistoryHandler lists (GET), appends to (POST) or clears (DELETE) the query history of the requesting user.
+// The most recent entries are returned first.
+func (s *SavedQueries) QueryHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userID := r.Header.Get(HeaderUser)
+	if userID == "" {
+		http.Error(w, fmt.Sprintf("please provide the %s header", HeaderUser), http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		doc, err := s.read(r.Context(), tenantID)
+		if err != nil {
+			s.writeError(w, tenantID, err)
+			return
+		}
+		history := doc.History[userID]
+		if history == nil {
+			history = []QueryHistoryEntry{}
+		}
+		writeJSON(w, history)
+	case http.MethodPost:
+		var e QueryHistoryEntry
+		if err := decodeJSONBody(r, &e); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if e.Query == "" {
+			http.Error(w, "query is required", http.StatusBadRequest)
+			return
+		}
+		if e.Timestamp.IsZero() {
+			e.Timestamp = time.Now()
+		}
+
+		err = s.update(r.Context(), tenantID, func(doc *savedQueriesDocument) error {
+			history := append([]QueryHistoryEntry{e}, doc.History[userID]...)
+			if s.cfg.MaxHistoryPerUser > 0 && len(history) > s.cfg.MaxHistoryPerUser {
+				history = history[:s.cfg.MaxHistoryPerUser]
+			}
+			doc.History[userID] = history
+			return nil
+		})
+		if err != nil {
+			s.writeError(w, tenantID, err)
+			return
+		}
+		writeJSON(w, e)
+	case http.MethodDelete:
+		err = s.update(r.Context(), tenantID, func(doc *savedQueriesDocument) error {
+			delete(doc.History, userID)
+			return nil
+		})
+		if err != nil {
+			s.writeError(w, tenantID, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ExportHandler returns all saved queries of a tenant as a SavedQueriesExport
+func (s *SavedQueries) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	doc, err := s.read(r.Context(), tenantID)
+	if err != nil {
+		s.writeError(w, tenantID, err)
+		return
+	}
+	writeJSON(w, SavedQueriesExport{Queries: doc.Queries})
+}
+
+// ImportHandler adds the queries in a SavedQueriesExport to the saved queries of a tenant. Queries with the
+// same name as an existing query replace it.
+func (s *SavedQueries) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var export SavedQueriesExport
+	if err := decodeJSONBody(r, &export); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	for i := range export.Queries {
+		if err := validateSavedQuery(export.Queries[i]); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if export.Queries[i].UpdatedAt.IsZero() {
+			export.Queries[i].UpdatedAt = now
+		}
+	}
+
+	err = s.update(r.Context(), tenantID, func(doc *savedQueriesDocument) error {
+		for _, q := range export.Queries {
+			if err := s.upsert(doc, q); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.writeError(w, tenantID, err)
+		return
+	}
+	writeJSON(w, SavedQueriesExport{Queries: export.Queries})
+}
+
+// upsert adds or replaces the given query in doc while respecting the configured limit
+func (s *SavedQueries) upsert(doc *savedQueriesDocument, q SavedQuery) error {
+	if i := findSavedQuery(doc.Queries, q.Name); i >= 0 {
+		doc.Queries[i] = q
+		return nil
+	}
+	if s.cfg.MaxSavedQueries > 0 && len(doc.Queries) >= s.cfg.MaxSavedQueries {
+		return errSavedQueriesLimit{limit: s.cfg.MaxSavedQueries}
+	}
+	doc.Queries = append(doc.Queries, q)
+	sort.Slice(doc.Queries, func(i, j int) bool {
+		return doc.Queries[i].Name < doc.Queries[j].Name
+	})
+	return nil
+}
+
+// update performs a read-modify-write of the saved queries document of a tenant
+func (s *SavedQueries) update(ctx context.Context, tenantID string, fn func(doc *savedQueriesDocument) error) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	doc, err := s.read(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	err = fn(doc)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return s.w.Write(ctx, backend.SavedQueriesName, backend.KeyPath{tenantID}, bytes.NewReader(b), int64(len(b)), false)
+}
+
+// read returns the saved queries document of a tenant or an empty document if the tenant has none
+func (s *SavedQueries) read(ctx context.Context, tenantID string) (*savedQueriesDocument, error) {
+	doc := &savedQueriesDocument{}
+
+	reader, size, err := s.r.Read(ctx, backend.SavedQueriesName, backend.KeyPath{tenantID}, false)
+	if errors.Is(err, backend.ErrDoesNotExist) {
+		doc.Queries = []SavedQuery{}
+		doc.History = map[string][]QueryHistoryEntry{}
+		return doc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	b, err := tempo_io.ReadAllWithEstimate(reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(b, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Queries == nil {
+		doc.Queries = []SavedQuery{}
+	}
+	if doc.History == nil {
+		doc.History = map[string][]QueryHistoryEntry{}
+	}
+	return doc, nil
+}
+
+func (s *SavedQueries) writeError(w http.ResponseWriter, tenantID string, err error) {
+	var limitErr errSavedQueriesLimit
+	if errors.As(err, &limitErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	level.Error(s.logger).Log("msg", "failed to access saved queries", "tenant", tenantID, "err", err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+type errSavedQueriesLimit struct {
+	limit int
+}
+
+func (e errSavedQueriesLimit) Error() string {
+	return fmt.Sprintf("saved queries limit of %d reached", e.limit)
+}
+
+func validateSavedQuery(q SavedQuery) error {
+	if q.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(q.Name) > maxSavedQueryNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxSavedQueryNameLength)
+	}
+	if strings.Contains(q.Name, "/") {
+		return errors.New("name must not contain '/'")
+	}
+	if _, err := traceql.Parse(q.Query); err != nil {
+		return fmt.Errorf("invalid query %s: %w", q.Name, err)
+	}
+	return nil
+}
+
+func findSavedQuery(queries []SavedQuery, name string) int {
+	for i, q := range queries {
+		if q.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func decodeJSONBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(io.LimitReader(r.Body, maxSavedQueriesBodySize)).Decode(v)
+	if err != nil {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,183 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/tempodb/backend/local"
+)
+
+func newTestSavedQueries(t *testing.T, cfg SavedQueriesConfig) http.Handler {
+	r, w, _, err := local.New(&local.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+
+	s := NewSavedQueries(cfg, r, w, log.NewNopLogger())
+
+	router := mux.NewRouter()
+	router.HandleFunc(api.PathSavedQueries, s.SavedQueriesHandler)
+	router.HandleFunc(api.PathSavedQuery, s.SavedQueryHandler)
+	router.HandleFunc(api.PathQueryHistory, s.QueryHistoryHandler)
+	router.HandleFunc(api.PathSavedQueriesExport, s.ExportHandler)
+	router.HandleFunc(api.PathSavedQueriesImport, s.ImportHandler)
+	return router
+}
+
+func doSavedQueriesRequest(t *testing.T, h http.Handler, tenant, userID, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req = req.WithContext(user.InjectOrgID(context.Background(), tenant))
+	if userID != "" {
+		req.Header.Set(HeaderUser, userID)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSavedQueries(t *testing.T) {
+	h := newTestSavedQueries(t, SavedQueriesConfig{MaxSavedQueries: 2})
+
+	// empty
+	rec := doSavedQueriesRequest(t, h, "test", "", http.MethodGet, api.PathSavedQueries, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	// create
+	rec = doSavedQueriesRequest(t, h, "test", "alice", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "errors", Query: `{ status = error }`})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doSavedQueriesRequest(t, h, "test", "bob", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "checkout", Query: `{ .service.name = "checkout" }`})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// invalid query
+	rec = doSavedQueriesRequest(t, h, "test", "", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "bad", Query: `{ .foo = `})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// limit reached
+	rec = doSavedQueriesRequest(t, h, "test", "", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "third", Query: `{ }`})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// update of an existing query is allowed at the limit
+	rec = doSavedQueriesRequest(t, h, "test", "bob", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "errors", Query: `{ status = error && duration > 1s }`})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// list is sorted by name
+	rec = doSavedQueriesRequest(t, h, "test", "", http.MethodGet, api.PathSavedQueries, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var queries []SavedQuery
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &queries))
+	require.Len(t, queries, 2)
+	assert.Equal(t, "checkout", queries[0].Name)
+	assert.Equal(t, "errors", queries[1].Name)
+	assert.Equal(t, `{ status = error && duration > 1s }`, queries[1].Query)
+	assert.Equal(t, "bob", queries[1].CreatedBy)
+
+	// get
+	rec = doSavedQueriesRequest(t, h, "test", "", http.MethodGet, "/api/queries/saved/checkout", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var q SavedQuery
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &q))
+	assert.Equal(t, `{ .service.name = "checkout" }`, q.Query)
+
+	// other tenants can't see the queries
+	rec = doSavedQueriesRequest(t, h, "other", "", http.MethodGet, "/api/queries/saved/checkout", nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// delete
+	rec = doSavedQueriesRequest(t, h, "test", "", http.MethodDelete, "/api/queries/saved/checkout", nil)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	rec = doSavedQueriesRequest(t, h, "test", "", http.MethodDelete, "/api/queries/saved/checkout", nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestQueryHistory(t *testing.T) {
+	h := newTestSavedQueries(t, SavedQueriesConfig{MaxHistoryPerUser: 2})
+
+	// user is required
+	rec := doSavedQueriesRequest(t, h, "test", "", http.MethodGet, api.PathQueryHistory, nil)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	for _, query := range []string{"{ .a = 1 }", "{ .b = 2 }", "{ .c = 3 }"} {
+		rec = doSavedQueriesRequest(t, h, "test", "alice", http.MethodPost, api.PathQueryHistory, QueryHistoryEntry{Query: query})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	}
+	rec = doSavedQueriesRequest(t, h, "test", "bob", http.MethodPost, api.PathQueryHistory, QueryHistoryEntry{Query: "{ .d = 4 }"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// history is bounded and most recent first
+	rec = doSavedQueriesRequest(t, h, "test", "alice", http.MethodGet, api.PathQueryHistory, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var history []QueryHistoryEntry
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	require.Len(t, history, 2)
+	assert.Equal(t, "{ .c = 3 }", history[0].Query)
+	assert.Equal(t, "{ .b = 2 }", history[1].Query)
+	assert.False(t, history[0].Timestamp.IsZero())
+
+	// clear only affects the requesting user
+	rec = doSavedQueriesRequest(t, h, "test", "alice", http.MethodDelete, api.PathQueryHistory, nil)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = doSavedQueriesRequest(t, h, "test", "alice", http.MethodGet, api.PathQueryHistory, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+
+	rec = doSavedQueriesRequest(t, h, "test", "bob", http.MethodGet, api.PathQueryHistory, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &history))
+	require.Len(t, history, 1)
+}
+
+func TestSavedQueriesImportExport(t *testing.T) {
+	h := newTestSavedQueries(t, SavedQueriesConfig{})
+
+	rec := doSavedQueriesRequest(t, h, "source", "", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "a", Query: `{ .a = 1 }`, Description: "first"})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doSavedQueriesRequest(t, h, "source", "", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "b", Query: `{ .b = 2 }`})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doSavedQueriesRequest(t, h, "source", "", http.MethodGet, api.PathSavedQueriesExport, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var export SavedQueriesExport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &export))
+	require.Len(t, export.Queries, 2)
+
+	// import merges by name
+	rec = doSavedQueriesRequest(t, h, "dest", "", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "a", Query: `{ .old = 1 }`})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = doSavedQueriesRequest(t, h, "dest", "", http.MethodPost, api.PathSavedQueries, SavedQuery{Name: "c", Query: `{ .c = 3 }`})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doSavedQueriesRequest(t, h, "dest", "", http.MethodPost, api.PathSavedQueriesImport, export)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = doSavedQueriesRequest(t, h, "dest", "", http.MethodGet, api.PathSavedQueriesExport, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var actual SavedQueriesExport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &actual))
+	require.Len(t, actual.Queries, 3)
+	assert.Equal(t, export.Queries[0], actual.Queries[0])
+	assert.Equal(t, export.Queries[1], actual.Queries[1])
+	assert.Equal(t, "c", actual.Queries[2].Name)
+
+	// invalid imports are rejected as a whole
+	rec = doSavedQueriesRequest(t, h, "dest", "", http.MethodPost, api.PathSavedQueriesImport, SavedQueriesExport{Queries: []SavedQuery{{Name: "d", Query: `{ .d = 4 }`}, {Name: "", Query: `{ }`}}})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,199 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/jsonpb" //nolint:all deprecated
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/grafana/tempo/pkg/api"
+)
+
+// searchProgressInterval is the minimum time between two progress events of a streamed search
+const searchProgressInterval = 500 * time.Millisecond
+
+// searchProgressEvent is the payload of the progress events of a streamed search.
+type searchProgressEvent struct {
+	CompletedJobs   int     `json:"completedJobs"`
+	TotalJobs       int     `json:"totalJobs"`
+	CompletedBlocks int     `json:"completedBlocks"`
+	TotalBlocks     int     `json:"totalBlocks"`
+	Percent         float64 `json:"percent"`
+	Traces          int     `json:"traces"`
+	InspectedTraces uint32  `json:"inspectedTraces"`
+	InspectedBytes  uint64  `json:"inspectedBytes"`
+}
+
+// searchProgress tracks the completed jobs of a streamed search and writes the events of the stream.
+type searchProgress struct {
+	mtx sync.Mutex
+
+	w         io.Writer
+	onErr     func(err error)
+	lastEvent time.Time
+
+	jobBlocks     map[*http.Request]string
+	pendingBlocks map[string]int
+	progress      searchProgressEvent
+}
+
+// newSearchProgress returns a tracker for the passed requests. Requests of the same block are counted as one
+// block once they have all completed, ingester requests are only counted as jobs.
+func newSearchProgress(w io.Writer, onErr func(err error), reqs []*http.Request, ingesterReqs []*http.Request) *searchProgress {
+	p := &searchProgress{
+		w:             w,
+		onErr:         onErr,
+		jobBlocks:     make(map[*http.Request]string, len(reqs)),
+		pendingBlocks: map[string]int{},
+	}
+	p.progress.TotalJobs = len(reqs)
+
+	isIngesterReq := make(map[*http.Request]bool, len(ingesterReqs))
+	for _, r := range ingesterReqs {
+		isIngesterReq[r] = true
+	}
+
+	for _, r := range reqs {
+		if isIngesterReq[r] {
+			continue
+		}
+		blockReq, err := api.ParseSearchBlockRequest(r)
+		if err != nil {
+			continue
+		}
+		p.jobBlocks[r] = blockReq.BlockID
+		p.pendingBlocks[blockReq.BlockID]++
+	}
+	p.progress.TotalBlocks = len(p.pendingBlocks)
+
+	return p
+}
+
+// jobDone records a completed request and writes a progress event if the last one is older than
+// searchProgressInterval.
+func (p *searchProgress) jobDone(r *http.Request, overallResponse *searchResponse) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.progress.CompletedJobs++
+	if blockID, ok := p.jobBlocks[r]; ok {
+		p.pendingBlocks[blockID]--
+		if p.pendingBlocks[blockID] == 0 {
+			p.progress.CompletedBlocks++
+		}
+	}
+
+	if time.Since(p.lastEvent) >= searchProgressInterval {
+		p.writeProgress(overallResponse)
+	}
+}
+
+// writeProgress writes a progress event. It must be called with the mutex held.
+func (p *searchProgress) writeProgress(overallResponse *searchResponse) {
+	p.lastEvent = time.Now()
+
+	overallResponse.mtx.Lock()
+	p.progress.Traces = len(overallResponse.resultsMap)
+	p.progress.InspectedTraces = overallResponse.resultsMetrics.InspectedTraces
+	p.progress.InspectedBytes = overallResponse.resultsMetrics.InspectedBytes
+	overallResponse.mtx.Unlock()
+
+	p.progress.Percent = 100
+	if p.progress.TotalJobs > 0 {
+		p.progress.Percent = 100 * float64(p.progress.CompletedJobs) / float64(p.progress.TotalJobs)
+	}
+
+	b, err := json.Marshal(p.progress)
+	if err == nil {
+		err = writeEvent(p.w, eventProgress, b)
+	}
+	if err != nil {
+		p.onErr(err)
+	}
+}
+
+// writeEvent writes any other event to the stream.
+func (p *searchProgress) writeEvent(event string, data []byte) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if err := writeEvent(p.w, event, data); err != nil {
+		p.onErr(err)
+	}
+}
+
+// streamSearch executes the sub-queries in the background and returns a response streaming server-sent events.
+// Progress events report the completed jobs and blocks and the number of traces found so far. A search split
+// into several sub-queries sends a partial event with the results so far after every sub-query but the last.
+// The stream ends with a result event holding the search response or an error event. Closing the stream cancels
+// the search. The requests and overallResponse must use a context canceled by cancel.
+func (s searchSharder) streamSearch(span opentracing.Span, cancel context.CancelFunc, queries [][]*http.Request, ingesterReqs []*http.Request, overallResponse *searchResponse) *http.Response {
+	var reqs []*http.Request
+	for _, q := range queries {
+		reqs = append(reqs, q...)
+	}
+
+	pr, pw := io.Pipe()
+	progress := newSearchProgress(pw, func(err error) {
+		// the client is gone, stop searching
+		level.Debug(s.logger).Log("msg", "search progress stream closed", "err", err)
+		cancel()
+	}, reqs, ingesterReqs)
+
+	go func() {
+		defer span.Finish()
+		defer cancel()
+		defer pw.Close()
+
+		m := &jsonpb.Marshaler{}
+		s.executeQueries(queries, overallResponse, func(r *http.Request) {
+			progress.jobDone(r, overallResponse)
+		}, func() {
+			if overallResponse.shouldQuit() {
+				return
+			}
+			bodyString, err := m.MarshalToString(overallResponse.result())
+			if err == nil {
+				progress.writeEvent(eventPartial, []byte(bodyString))
+			}
+		})
+		setSearchSpanTags(span, overallResponse)
+
+		progress.mtx.Lock()
+		progress.writeProgress(overallResponse)
+		progress.mtx.Unlock()
+
+		if overallResponse.err != nil {
+			progress.writeEvent(eventError, []byte(overallResponse.err.Error()))
+			return
+		}
+		if overallResponse.statusCode != http.StatusOK {
+			progress.writeEvent(eventError, []byte(overallResponse.statusMsg))
+			return
+		}
+		if err := overallResponse.ctx.Err(); err != nil {
+			return
+		}
+
+		bodyString, err := m.MarshalToString(overallResponse.result())
+		if err != nil {
+			progress.writeEvent(eventError, []byte(err.Error()))
+			return
+		}
+		progress.writeEvent(eventResult, []byte(bodyString))
+	}()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			api.HeaderContentType: {api.HeaderAcceptEventStream},
+		},
+		Body: pr,
+	}
+}
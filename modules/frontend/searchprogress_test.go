@@ -0,0 +1,259 @@
+package frontend
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/protobuf/jsonpb" //nolint:all deprecated
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/modules/overrides"
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+type testEvent struct {
+	event string
+	data  string
+}
+
+func readEvents(t *testing.T, r io.Reader) []testEvent {
+	var events []testEvent
+	var current testEvent
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, current)
+			current = testEvent{}
+		case strings.HasPrefix(line, "event: "):
+			current.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data += strings.TrimPrefix(line, "data: ")
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	return events
+}
+
+func TestSearchSharderRoundTripStream(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		expectedEvent string
+		expectedData  string
+	}{
+		{
+			name:          "result",
+			status:        http.StatusOK,
+			expectedEvent: eventResult,
+		},
+		{
+			name:          "upstream error",
+			status:        http.StatusInternalServerError,
+			expectedEvent: eventError,
+			expectedData:  "upstream: (500) ",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				// jobs are always executed with json responses
+				assert.Equal(t, api.HeaderAcceptJSON, r.Header.Get(api.HeaderAccept))
+
+				if tc.status != http.StatusOK {
+					return &http.Response{
+						Body:       io.NopCloser(strings.NewReader("")),
+						StatusCode: tc.status,
+					}, nil
+				}
+
+				resString, err := (&jsonpb.Marshaler{}).MarshalToString(&tempopb.SearchResponse{
+					Traces: []*tempopb.TraceSearchMetadata{
+						{
+							TraceID: r.URL.Query().Get("startPage"),
+						},
+					},
+					Metrics: &tempopb.SearchMetrics{
+						InspectedTraces: 1,
+						InspectedBytes:  10,
+					},
+				})
+				require.NoError(t, err)
+
+				return &http.Response{
+					Body:       io.NopCloser(strings.NewReader(resString)),
+					StatusCode: http.StatusOK,
+				}, nil
+			})
+
+			o, err := overrides.NewOverrides(overrides.Limits{})
+			require.NoError(t, err)
+
+			sharder := newSearchSharder(&mockReader{
+				metas: []*backend.BlockMeta{ // two blocks with 2 records that are each the target bytes per request
+					{
+						StartTime:    time.Unix(1100, 0),
+						EndTime:      time.Unix(1200, 0),
+						Size:         defaultTargetBytesPerRequest * 2,
+						TotalRecords: 2,
+						BlockID:      uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+						Version:      "vParquet",
+					},
+					{
+						StartTime:    time.Unix(1100, 0),
+						EndTime:      time.Unix(1200, 0),
+						Size:         defaultTargetBytesPerRequest * 2,
+						TotalRecords: 2,
+						BlockID:      uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+						Version:      "vParquet",
+					},
+				},
+			}, o, SearchSharderConfig{
+				ConcurrentRequests:    1,
+				TargetBytesPerRequest: defaultTargetBytesPerRequest,
+				DefaultLimit:          10,
+			}, log.NewNopLogger())
+			testRT := NewRoundTripper(next, sharder)
+
+			req := httptest.NewRequest("GET", "/?start=1000&end=1500", nil)
+			req.Header.Set(api.HeaderAccept, api.HeaderAcceptEventStream)
+			req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
+
+			resp, err := testRT.RoundTrip(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.True(t, isEventStream(resp))
+
+			events := readEvents(t, resp.Body)
+			require.GreaterOrEqual(t, len(events), 2)
+
+			// the last progress event precedes the result
+			last := events[len(events)-1]
+			assert.Equal(t, tc.expectedEvent, last.event)
+
+			progress := events[len(events)-2]
+			require.Equal(t, eventProgress, progress.event)
+			actualProgress := searchProgressEvent{}
+			require.NoError(t, json.Unmarshal([]byte(progress.data), &actualProgress))
+			assert.Equal(t, 4, actualProgress.TotalJobs)
+			assert.Equal(t, 2, actualProgress.TotalBlocks)
+
+			if tc.status != http.StatusOK {
+				assert.Equal(t, tc.expectedData, last.data)
+				return
+			}
+
+			assert.Equal(t, searchProgressEvent{
+				CompletedJobs:   4,
+				TotalJobs:       4,
+				CompletedBlocks: 2,
+				TotalBlocks:     2,
+				Percent:         100,
+				Traces:          2,
+				InspectedTraces: 4,
+				InspectedBytes:  40,
+			}, actualProgress)
+
+			result := &tempopb.SearchResponse{}
+			require.NoError(t, jsonpb.UnmarshalString(last.data, result))
+			assert.Len(t, result.Traces, 2)
+			assert.Equal(t, uint32(4), result.Metrics.InspectedTraces)
+		})
+	}
+}
+
+func TestSearchSharderRoundTripStreamCancel(t *testing.T) {
+	executed := make(chan struct{}, 10)
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		executed <- struct{}{}
+		return &http.Response{
+			Body:       io.NopCloser(strings.NewReader(`{"metrics":{}}`)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+
+	o, err := overrides.NewOverrides(overrides.Limits{})
+	require.NoError(t, err)
+
+	sharder := newSearchSharder(&mockReader{
+		metas: []*backend.BlockMeta{
+			{
+				StartTime:    time.Unix(1100, 0),
+				EndTime:      time.Unix(1200, 0),
+				Size:         defaultTargetBytesPerRequest * 10,
+				TotalRecords: 10,
+				BlockID:      uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+				Version:      "vParquet",
+			},
+		},
+	}, o, SearchSharderConfig{
+		ConcurrentRequests:    1,
+		TargetBytesPerRequest: defaultTargetBytesPerRequest,
+		DefaultLimit:          10,
+	}, log.NewNopLogger())
+	testRT := NewRoundTripper(next, sharder)
+
+	req := httptest.NewRequest("GET", "/?start=1000&end=1500", nil)
+	req.Header.Set(api.HeaderAccept, api.HeaderAcceptEventStream)
+	req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
+
+	resp, err := testRT.RoundTrip(req)
+	require.NoError(t, err)
+
+	// the first job blocks on writing its progress event until the stream is closed
+	<-executed
+	require.NoError(t, resp.Body.Close())
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Less(t, len(executed), 9)
+}
+
+func TestSearchSharderRoundTripStreamSplit(t *testing.T) {
+	var searched []string
+	testRT := newSplitTestRoundTripper(t, &searched)
+
+	req := httptest.NewRequest("GET", "/?start=1000&end=1300", nil)
+	req.Header.Set(api.HeaderAccept, api.HeaderAcceptEventStream)
+	req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
+
+	resp, err := testRT.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// a partial event follows every sub-query but the last. the middle sub-query is skipped, its only block is
+	// searched by the newest one
+	var partials []*tempopb.SearchResponse
+	var last testEvent
+	for _, e := range readEvents(t, resp.Body) {
+		last = e
+		if e.event != eventPartial {
+			continue
+		}
+		partial := &tempopb.SearchResponse{}
+		require.NoError(t, jsonpb.UnmarshalString(e.data, partial))
+		partials = append(partials, partial)
+	}
+	require.Len(t, partials, 1)
+	assert.Len(t, partials[0].Traces, 2)
+
+	require.Equal(t, eventResult, last.event)
+	result := &tempopb.SearchResponse{}
+	require.NoError(t, jsonpb.UnmarshalString(last.data, result))
+	assert.Len(t, result.Traces, 3)
+}
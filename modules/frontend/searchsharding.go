@@ -13,9 +13,11 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gogo/protobuf/jsonpb" //nolint:all deprecated
+	"github.com/google/uuid"
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/api"
 	"github.com/grafana/tempo/pkg/boundedwaitgroup"
+	"github.com/grafana/tempo/pkg/model/trace"
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/tempodb"
 	"github.com/grafana/tempo/tempodb/backend"
@@ -139,6 +141,9 @@ type SearchSharderConfig struct {
 	DefaultLimit          uint32        `yaml:"default_result_limit"`
 	MaxLimit              uint32        `yaml:"max_result_limit"`
 	MaxDuration           time.Duration `yaml:"max_duration"`
+	SplitByMaxDuration    bool          `yaml:"split_by_max_duration,omitempty"`
+	MaxSplitQueries       int           `yaml:"max_split_queries,omitempty"`
+	MaxLookback           time.Duration `yaml:"max_lookback,omitempty"`
 	QueryBackendAfter     time.Duration `yaml:"query_backend_after,omitempty"`
 	QueryIngestersUntil   time.Duration `yaml:"query_ingesters_until,omitempty"`
 }
@@ -169,6 +174,7 @@ func (s searchSharder) RoundTrip(r *http.Request) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusBadRequest,
 			Body:       io.NopCloser(strings.NewReader(err.Error())),
+			Header:     http.Header{},
 		}, nil
 	}
 
@@ -181,48 +187,116 @@ func (s searchSharder) RoundTrip(r *http.Request) (*http.Response, error) {
 		return &http.Response{
 			StatusCode: http.StatusBadRequest,
 			Body:       io.NopCloser(strings.NewReader(err.Error())),
+			Header:     http.Header{},
 		}, nil
 	}
 	span, ctx := opentracing.StartSpanFromContext(ctx, "frontend.ShardSearch")
-	defer span.Finish()
+	finishSpan := true
+	defer func() {
+		if finishSpan {
+			span.Finish()
+		}
+	}()
+
+	// progress is streamed as server-sent events, the jobs are always executed with json responses. the jobs
+	// of a stream are canceled once the stream is closed
+	streaming := r.Header.Get(api.HeaderAccept) == api.HeaderAcceptEventStream
+	cancel := context.CancelFunc(func() {})
+	if streaming {
+		r.Header.Set(api.HeaderAccept, api.HeaderAcceptJSON)
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer func() {
+		if finishSpan {
+			cancel()
+		}
+	}()
 
-	// calculate and enforce max search duration
-	maxDuration := s.maxDuration(tenantID)
-	if maxDuration != 0 && time.Duration(searchReq.End-searchReq.Start)*time.Second > maxDuration {
+	// enforce max search look back
+	maxLookback := s.maxLookback(tenantID)
+	if maxLookback != 0 && searchReq.Start != 0 && searchReq.Start < uint32(time.Now().Add(-maxLookback).Unix()) {
 		return &http.Response{
 			StatusCode: http.StatusBadRequest,
-			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf("range specified by start and end exceeds %s. received start=%d end=%d", maxDuration, searchReq.Start, searchReq.End))),
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf("start is further in the past than the max look back of %s. received start=%d", maxLookback, searchReq.Start))),
+			Header:     http.Header{},
 		}, nil
 	}
 
-	ingesterReq, err := s.ingesterRequest(ctx, tenantID, r, *searchReq)
-	if err != nil {
-		return nil, err
+	// calculate and enforce max search duration. longer ranges are split into sub-queries if configured
+	ranges := []timeRange{{start: searchReq.Start, end: searchReq.End}}
+	maxDuration := s.maxDuration(tenantID)
+	if maxDuration != 0 && time.Duration(searchReq.End-searchReq.Start)*time.Second > maxDuration {
+		if !s.cfg.SplitByMaxDuration {
+			return &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       io.NopCloser(strings.NewReader(fmt.Sprintf("range specified by start and end exceeds %s. received start=%d end=%d", maxDuration, searchReq.Start, searchReq.End))),
+				Header:     http.Header{},
+			}, nil
+		}
+		ranges, err = splitRange(searchReq.Start, searchReq.End, maxDuration, s.maxSplitQueries(tenantID))
+		if err != nil {
+			return &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       io.NopCloser(strings.NewReader(err.Error())),
+				Header:     http.Header{},
+			}, nil
+		}
 	}
 
-	start, end := s.backendRange(searchReq)
-
-	blocks := s.blockMetas(int64(start), int64(end), tenantID)
-	span.SetTag("block-count", len(blocks))
-
-	var reqs []*http.Request
-	// add backend requests if we need them
-	if start != end {
-		reqs, err = s.backendRequests(ctx, tenantID, r, blocks)
+	var (
+		queries      [][]*http.Request
+		ingesterReqs []*http.Request
+		blocks       []*backend.BlockMeta
+		requestCount int
+		seenBlocks   = map[uuid.UUID]struct{}{}
+	)
+	for _, rng := range ranges {
+		rangeReq := *searchReq
+		rangeReq.Start, rangeReq.End = rng.start, rng.end
+
+		ingesterReq, err := s.ingesterRequest(ctx, tenantID, r, rangeReq)
 		if err != nil {
 			return nil, err
 		}
+
+		start, end := s.backendRange(&rangeReq)
+
+		// blocks overlapping several sub-queries are only searched by the newest one
+		var rangeBlocks []*backend.BlockMeta
+		for _, m := range s.blockMetas(int64(start), int64(end), tenantID, searchReq.Tags[trace.ServiceNameTag]) {
+			if _, ok := seenBlocks[m.BlockID]; ok {
+				continue
+			}
+			seenBlocks[m.BlockID] = struct{}{}
+			rangeBlocks = append(rangeBlocks, m)
+		}
+		blocks = append(blocks, rangeBlocks...)
+
+		var reqs []*http.Request
+		// add backend requests if we need them
+		if start != end {
+			reqs, err = s.backendRequests(ctx, tenantID, r, rangeBlocks)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// add ingester request if we have one. it's important to add the ingeste request to
+		// the beginning of the slice so it is prioritized over the possibly enormous
+		// number of backend requests
+		if ingesterReq != nil {
+			reqs = append([]*http.Request{ingesterReq}, reqs...)
+			ingesterReqs = append(ingesterReqs, ingesterReq)
+		}
+
+		if len(reqs) > 0 {
+			queries = append(queries, reqs)
+			requestCount += len(reqs)
+		}
 	}
-	// add ingester request if we have one. it's important to add the ingeste request to
-	// the beginning of the slice so it is prioritized over the possibly enormous
-	// number of backend requests
-	if ingesterReq != nil {
-		reqs = append([]*http.Request{ingesterReq}, reqs...)
-	}
-	span.SetTag("request-count", len(reqs))
+	span.SetTag("block-count", len(blocks))
+	span.SetTag("request-count", requestCount)
+	span.SetTag("sub-query-count", len(queries))
 
-	// execute requests
-	wg := boundedwaitgroup.New(uint(s.cfg.ConcurrentRequests))
 	overallResponse := newSearchResponse(ctx, int(searchReq.Limit))
 	overallResponse.resultsMetrics.InspectedBlocks = uint32(len(blocks))
 
@@ -232,6 +306,79 @@ func (s searchSharder) RoundTrip(r *http.Request) (*http.Response, error) {
 	}
 	overallResponse.resultsMetrics.TotalBlockBytes = totalBlockBytes
 
+	// enforce max bytes scanned
+	if maxBytes := s.overrides.MaxBytesScannedPerQuery(tenantID); maxBytes > 0 && totalBlockBytes > uint64(maxBytes) {
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf("search would scan %d bytes of blocks, more than the max of %d. reduce the range specified by start and end", totalBlockBytes, maxBytes))),
+			Header:     http.Header{},
+		}, nil
+	}
+
+	if streaming {
+		// the span is finished once the stream is complete
+		finishSpan = false
+		return s.streamSearch(span, cancel, queries, ingesterReqs, overallResponse), nil
+	}
+
+	s.executeQueries(queries, overallResponse, nil, nil)
+
+	// all goroutines have finished, we can safely access searchResults fields directly now
+	setSearchSpanTags(span, overallResponse)
+
+	if overallResponse.err != nil {
+		return nil, overallResponse.err
+	}
+
+	if overallResponse.statusCode != http.StatusOK {
+		// translate all non-200s into 500s. if, for instance, we get a 400 back from an internal component
+		// it means that we created a bad request. 400 should not be propagated back to the user b/c
+		// the bad request was due to a bug on our side, so return 500 instead.
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(overallResponse.statusMsg)),
+		}, nil
+	}
+
+	m := &jsonpb.Marshaler{}
+	bodyString, err := m.MarshalToString(overallResponse.result())
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			api.HeaderContentType: {api.HeaderAcceptJSON},
+		},
+		Body:          io.NopCloser(strings.NewReader(bodyString)),
+		ContentLength: int64(len([]byte(bodyString))),
+	}, nil
+}
+
+// executeQueries executes the requests of the sub-queries of a search one sub-query after another until
+// overallResponse should quit. If set, jobDone is called after every executed request and queryDone after every
+// sub-query but the last.
+func (s searchSharder) executeQueries(queries [][]*http.Request, overallResponse *searchResponse, jobDone func(r *http.Request), queryDone func()) {
+	for i, reqs := range queries {
+		if overallResponse.shouldQuit() {
+			return
+		}
+
+		s.executeRequests(reqs, overallResponse, jobDone)
+
+		if queryDone != nil && i < len(queries)-1 {
+			queryDone()
+		}
+	}
+}
+
+// executeRequests executes the requests with up to ConcurrentRequests in flight and aggregates the results in
+// overallResponse until it should quit. If set, jobDone is called after every executed request.
+func (s searchSharder) executeRequests(reqs []*http.Request, overallResponse *searchResponse, jobDone func(r *http.Request)) {
+	wg := boundedwaitgroup.New(uint(s.cfg.ConcurrentRequests))
+
 	for _, req := range reqs {
 		if overallResponse.shouldQuit() {
 			break
@@ -245,6 +392,10 @@ func (s searchSharder) RoundTrip(r *http.Request) (*http.Response, error) {
 				return
 			}
 
+			if jobDone != nil {
+				defer jobDone(innerR)
+			}
+
 			resp, err := s.next.RoundTrip(innerR)
 			if err != nil {
 				_ = level.Error(s.logger).Log("msg", "error executing sharded query", "url", innerR.RequestURI, "err", err)
@@ -284,52 +435,25 @@ func (s searchSharder) RoundTrip(r *http.Request) (*http.Response, error) {
 		}(req)
 	}
 	wg.Wait()
+}
 
-	// all goroutines have finished, we can safely access searchResults fields directly now
+func setSearchSpanTags(span opentracing.Span, overallResponse *searchResponse) {
 	span.SetTag("inspectedBlocks", overallResponse.resultsMetrics.InspectedBlocks)
 	span.SetTag("inspectedBytes", overallResponse.resultsMetrics.InspectedBytes)
 	span.SetTag("inspectedTraces", overallResponse.resultsMetrics.InspectedTraces)
 	span.SetTag("totalBlockBytes", overallResponse.resultsMetrics.TotalBlockBytes)
-
-	if overallResponse.err != nil {
-		return nil, overallResponse.err
-	}
-
-	if overallResponse.statusCode != http.StatusOK {
-		// translate all non-200s into 500s. if, for instance, we get a 400 back from an internal component
-		// it means that we created a bad request. 400 should not be propagated back to the user b/c
-		// the bad request was due to a bug on our side, so return 500 instead.
-		return &http.Response{
-			StatusCode: http.StatusInternalServerError,
-			Header:     http.Header{},
-			Body:       io.NopCloser(strings.NewReader(overallResponse.statusMsg)),
-		}, nil
-	}
-
-	m := &jsonpb.Marshaler{}
-	bodyString, err := m.MarshalToString(overallResponse.result())
-	if err != nil {
-		return nil, err
-	}
-
-	return &http.Response{
-		StatusCode: http.StatusOK,
-		Header: http.Header{
-			api.HeaderContentType: {api.HeaderAcceptJSON},
-		},
-		Body:          io.NopCloser(strings.NewReader(bodyString)),
-		ContentLength: int64(len([]byte(bodyString))),
-	}, nil
 }
 
-// blockMetas returns all relevant blockMetas given a start/end
-func (s *searchSharder) blockMetas(start, end int64, tenantID string) []*backend.BlockMeta {
+// blockMetas returns all relevant blockMetas given a start/end. If serviceName is set blocks that are known not to
+// contain the service are skipped.
+func (s *searchSharder) blockMetas(start, end int64, tenantID string, serviceName string) []*backend.BlockMeta {
 	// reduce metas to those in the requested range
 	metas := []*backend.BlockMeta{}
 	allMetas := s.reader.BlockMetas(tenantID)
 	for _, m := range allMetas {
 		if m.StartTime.Unix() <= end &&
-			m.EndTime.Unix() >= start {
+			m.EndTime.Unix() >= start &&
+			(serviceName == "" || m.MayContainServiceName(serviceName)) {
 			metas = append(metas, m)
 		}
 	}
@@ -371,6 +495,7 @@ func (s *searchSharder) backendRequests(ctx context.Context, tenantID string, pa
 				Version:       m.Version,
 				Size_:         m.Size,
 				FooterSize:    m.FooterSize,
+				IndexVersion:  m.IndexVersion,
 			})
 
 			if err != nil {
@@ -468,3 +593,52 @@ func (s *searchSharder) maxDuration(tenantID string) time.Duration {
 
 	return s.cfg.MaxDuration
 }
+
+func (s *searchSharder) maxLookback(tenantID string) time.Duration {
+	// check overrides first, if no overrides then grab from our config
+	maxLookback := s.overrides.MaxSearchLookback(tenantID)
+	if maxLookback != 0 {
+		return maxLookback
+	}
+
+	return s.cfg.MaxLookback
+}
+
+func (s *searchSharder) maxSplitQueries(tenantID string) int {
+	// check overrides first, if no overrides then grab from our config
+	maxSplitQueries := s.overrides.MaxSearchSplitQueries(tenantID)
+	if maxSplitQueries != 0 {
+		return maxSplitQueries
+	}
+
+	return s.cfg.MaxSplitQueries
+}
+
+type timeRange struct {
+	start, end uint32
+}
+
+// splitRange splits start through end into consecutive ranges of at most maxDuration, newest first. An error is
+// returned if there would be more than maxQueries ranges, 0 doesn't limit the number of ranges.
+func splitRange(start, end uint32, maxDuration time.Duration, maxQueries int) ([]timeRange, error) {
+	step := uint32(maxDuration / time.Second)
+	if step == 0 {
+		step = 1
+	}
+
+	count := (uint64(end-start) + uint64(step) - 1) / uint64(step)
+	if maxQueries > 0 && count > uint64(maxQueries) {
+		return nil, fmt.Errorf("range specified by start and end would be split into %d sub-queries, more than the max of %d. received start=%d end=%d", count, maxQueries, start, end)
+	}
+
+	ranges := make([]timeRange, 0, count)
+	for end > start {
+		rangeStart := start
+		if end-start > step {
+			rangeStart = end - step
+		}
+		ranges = append(ranges, timeRange{start: rangeStart, end: end})
+		end = rangeStart
+	}
+	return ranges, nil
+}
@@ -5,10 +5,12 @@ import (
 	"context"
 	"errors"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/ddsketch"
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/blocklist"
@@ -42,8 +45,21 @@ func (m *mockReader) BlockMetas(tenantID string) []*backend.BlockMeta {
 func (m *mockReader) Search(ctx context.Context, meta *backend.BlockMeta, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error) {
 	return nil, nil
 }
+func (m *mockReader) ProjectSpans(ctx context.Context, meta *backend.BlockMeta, p common.Projection, cb func(*common.ProjectedSpan) error, opts common.SearchOptions) error {
+	return nil
+}
+func (m *mockReader) DurationSketches(ctx context.Context, metas []*backend.BlockMeta, service string, start, end time.Time) (*ddsketch.Sketch, []*backend.BlockMeta, error) {
+	return nil, metas, nil
+}
+
+func (m *mockReader) CountServiceSpans(ctx context.Context, metas []*backend.BlockMeta, service string, start, end time.Time, step time.Duration) ([]uint64, []*backend.BlockMeta, error) {
+	return nil, metas, nil
+}
 func (m *mockReader) EnablePolling(sharder blocklist.JobSharder) {}
-func (m *mockReader) Shutdown()                                  {}
+func (m *mockReader) ApplyBlocklistEvents(ctx context.Context, events []blocklist.BlockEvent) error {
+	return nil
+}
+func (m *mockReader) Shutdown() {}
 
 func TestSearchResponseShouldQuit(t *testing.T) {
 	ctx := context.Background()
@@ -254,6 +270,42 @@ func TestBackendRequests(t *testing.T) {
 	}
 }
 
+func TestBlockMetasSkipsServices(t *testing.T) {
+	o, err := overrides.NewOverrides(overrides.Limits{})
+	require.NoError(t, err)
+
+	metas := []*backend.BlockMeta{
+		{
+			BlockID:      uuid.MustParse("00000000-0000-0000-0000-000000000000"),
+			StartTime:    time.Unix(100, 0),
+			EndTime:      time.Unix(200, 0),
+			ServiceNames: []string{"frontend", "shop-backend"},
+		},
+		{
+			BlockID:      uuid.MustParse("00000000-0000-0000-0000-000000000001"),
+			StartTime:    time.Unix(100, 0),
+			EndTime:      time.Unix(200, 0),
+			ServiceNames: []string{"database"},
+		},
+		{
+			// service names weren't recorded
+			BlockID:   uuid.MustParse("00000000-0000-0000-0000-000000000002"),
+			StartTime: time.Unix(100, 0),
+			EndTime:   time.Unix(200, 0),
+		},
+	}
+	sharder := &searchSharder{
+		reader:    &mockReader{metas: metas},
+		overrides: o,
+		logger:    log.NewNopLogger(),
+	}
+
+	assert.Equal(t, metas, sharder.blockMetas(150, 160, "test", ""))
+	// services are matched by substring like the search itself
+	assert.Equal(t, []*backend.BlockMeta{metas[0], metas[2]}, sharder.blockMetas(150, 160, "test", "backend"))
+	assert.Equal(t, []*backend.BlockMeta{metas[2]}, sharder.blockMetas(150, 160, "test", "payments"))
+}
+
 func TestIngesterRequest(t *testing.T) {
 	now := int(time.Now().Unix())
 	tenMinutesAgo := int(time.Now().Add(-10 * time.Minute).Unix())
@@ -693,11 +745,187 @@ func TestSearchSharderRoundTripBadRequest(t *testing.T) {
 	req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
 	resp, err = testRT.RoundTrip(req)
 	testBadRequest(t, resp, err, "range specified by start and end exceeds 1m0s. received start=1000 end=1500")
+
+	// start before max look back
+	sharder = newSearchSharder(&mockReader{}, o, SearchSharderConfig{
+		ConcurrentRequests:    defaultConcurrentRequests,
+		TargetBytesPerRequest: defaultTargetBytesPerRequest,
+		MaxLookback:           time.Hour,
+	}, log.NewNopLogger())
+	testRT = NewRoundTripper(next, sharder)
+
+	req = httptest.NewRequest("GET", "/?start=1000&end=1010", nil)
+	req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
+	resp, err = testRT.RoundTrip(req)
+	testBadRequest(t, resp, err, "start is further in the past than the max look back of 1h0m0s. received start=1000")
+
+	// test max look back error with overrides
+	o, err = overrides.NewOverrides(overrides.Limits{
+		MaxSearchLookback: model.Duration(2 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	sharder = newSearchSharder(&mockReader{}, o, SearchSharderConfig{
+		ConcurrentRequests:    defaultConcurrentRequests,
+		TargetBytesPerRequest: defaultTargetBytesPerRequest,
+		MaxLookback:           time.Hour,
+	}, log.NewNopLogger())
+	testRT = NewRoundTripper(next, sharder)
+
+	req = httptest.NewRequest("GET", "/?start=1000&end=1010", nil)
+	req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
+	resp, err = testRT.RoundTrip(req)
+	testBadRequest(t, resp, err, "start is further in the past than the max look back of 2h0m0s. received start=1000")
+
+	// blocks larger than the max bytes scanned
+	o, err = overrides.NewOverrides(overrides.Limits{
+		MaxBytesScannedPerQuery: 1000,
+	})
+	require.NoError(t, err)
+
+	sharder = newSearchSharder(&mockReader{
+		metas: []*backend.BlockMeta{
+			{StartTime: time.Unix(1000, 0), EndTime: time.Unix(1010, 0), Size: 600, TotalRecords: 1, BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000000")},
+			{StartTime: time.Unix(1000, 0), EndTime: time.Unix(1010, 0), Size: 600, TotalRecords: 1, BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000001")},
+		},
+	}, o, SearchSharderConfig{
+		ConcurrentRequests:    defaultConcurrentRequests,
+		TargetBytesPerRequest: defaultTargetBytesPerRequest,
+	}, log.NewNopLogger())
+	testRT = NewRoundTripper(next, sharder)
+
+	req = httptest.NewRequest("GET", "/?start=1000&end=1010", nil)
+	req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
+	resp, err = testRT.RoundTrip(req)
+	testBadRequest(t, resp, err, "search would scan 1200 bytes of blocks, more than the max of 1000. reduce the range specified by start and end")
+}
+
+func TestSplitRange(t *testing.T) {
+	ranges, err := splitRange(1050, 1300, 100*time.Second, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []timeRange{{start: 1200, end: 1300}, {start: 1100, end: 1200}, {start: 1050, end: 1100}}, ranges)
+
+	ranges, err = splitRange(1000, 1100, 100*time.Second, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []timeRange{{start: 1000, end: 1100}}, ranges)
+
+	ranges, err = splitRange(1000, 1100, time.Hour, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []timeRange{{start: 1000, end: 1100}}, ranges)
+
+	// the number of sub-queries is capped
+	_, err = splitRange(1050, 1300, 100*time.Second, 2)
+	assert.EqualError(t, err, "range specified by start and end would be split into 3 sub-queries, more than the max of 2. received start=1050 end=1300")
+
+	// without allocating the ranges of huge splits
+	_, err = splitRange(0, math.MaxUint32, time.Second, 100)
+	assert.Error(t, err)
+}
+
+// newSplitTestRoundTripper returns a round tripper splitting searches into sub-queries of 100s. A block is
+// searched in each of the sub-queries of 1000 through 1300, the middle block overlaps the two newest. The ids of
+// the searched blocks are returned as trace ids and appended to searched.
+func newSplitTestRoundTripper(t *testing.T, searched *[]string) http.RoundTripper {
+	var mtx sync.Mutex
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		blockID := r.URL.Query().Get("blockID")
+		mtx.Lock()
+		*searched = append(*searched, blockID)
+		mtx.Unlock()
+
+		resString, err := (&jsonpb.Marshaler{}).MarshalToString(&tempopb.SearchResponse{
+			Traces:  []*tempopb.TraceSearchMetadata{{TraceID: blockID}},
+			Metrics: &tempopb.SearchMetrics{},
+		})
+		require.NoError(t, err)
+
+		return &http.Response{
+			Body:       io.NopCloser(strings.NewReader(resString)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+
+	o, err := overrides.NewOverrides(overrides.Limits{})
+	require.NoError(t, err)
+
+	meta := func(id string, start, end int64) *backend.BlockMeta {
+		return &backend.BlockMeta{
+			StartTime:    time.Unix(start, 0),
+			EndTime:      time.Unix(end, 0),
+			Size:         defaultTargetBytesPerRequest,
+			TotalRecords: 1,
+			BlockID:      uuid.MustParse(id),
+		}
+	}
+	sharder := newSearchSharder(&mockReader{
+		metas: []*backend.BlockMeta{
+			meta("00000000-0000-0000-0000-000000000000", 1010, 1050),
+			meta("00000000-0000-0000-0000-000000000001", 1150, 1220),
+			meta("00000000-0000-0000-0000-000000000002", 1250, 1290),
+		},
+	}, o, SearchSharderConfig{
+		ConcurrentRequests:    1,
+		TargetBytesPerRequest: defaultTargetBytesPerRequest,
+		DefaultLimit:          10,
+		MaxDuration:           100 * time.Second,
+		SplitByMaxDuration:    true,
+	}, log.NewNopLogger())
+
+	return NewRoundTripper(next, sharder)
+}
+
+func TestSearchSharderRoundTripSplit(t *testing.T) {
+	tests := []struct {
+		name             string
+		limit            int
+		expectedSearched []string
+	}{
+		{
+			name:  "all sub-queries",
+			limit: 10,
+			expectedSearched: []string{
+				"00000000-0000-0000-0000-000000000001",
+				"00000000-0000-0000-0000-000000000002",
+				"00000000-0000-0000-0000-000000000000",
+			},
+		},
+		{
+			name:  "limit reached in first sub-query",
+			limit: 1,
+			expectedSearched: []string{
+				"00000000-0000-0000-0000-000000000001",
+				"00000000-0000-0000-0000-000000000002",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var searched []string
+			testRT := newSplitTestRoundTripper(t, &searched)
+
+			req := httptest.NewRequest("GET", "/?start=1000&end=1300&limit="+strconv.Itoa(tc.limit), nil)
+			req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
+
+			resp, err := testRT.RoundTrip(req)
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			// newest sub-query first, blocks overlapping several sub-queries are searched once
+			assert.Equal(t, tc.expectedSearched, searched)
+
+			result := &tempopb.SearchResponse{}
+			require.NoError(t, jsonpb.Unmarshal(resp.Body, result))
+			assert.Len(t, result.Traces, len(tc.expectedSearched))
+			assert.Equal(t, uint32(3), result.Metrics.InspectedBlocks)
+		})
+	}
 }
 
 func testBadRequest(t *testing.T, resp *http.Response, err error, expectedBody string) {
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 	assert.Nil(t, err)
+	assert.NotNil(t, resp.Header)
 	buff, err := io.ReadAll(resp.Body)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedBody, string(buff))
@@ -736,3 +964,29 @@ func TestMaxDuration(t *testing.T) {
 	actual = sharder.maxDuration("test")
 	assert.Equal(t, 10*time.Minute, actual)
 }
+
+func TestMaxSplitQueries(t *testing.T) {
+	next := RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		t.Fatal("no sub-query is executed")
+		return nil, nil
+	})
+
+	// the override takes precedence over the config
+	o, err := overrides.NewOverrides(overrides.Limits{
+		MaxSearchSplitQueries: 2,
+	})
+	require.NoError(t, err)
+	sharder := newSearchSharder(&mockReader{}, o, SearchSharderConfig{
+		ConcurrentRequests:    1,
+		TargetBytesPerRequest: defaultTargetBytesPerRequest,
+		MaxDuration:           100 * time.Second,
+		SplitByMaxDuration:    true,
+		MaxSplitQueries:       10,
+	}, log.NewNopLogger())
+	testRT := NewRoundTripper(next, sharder)
+
+	req := httptest.NewRequest("GET", "/?start=1000&end=1300", nil)
+	req = req.WithContext(user.InjectOrgID(req.Context(), "blerg"))
+	resp, err := testRT.RoundTrip(req)
+	testBadRequest(t, resp, err, "range specified by start and end would be split into 3 sub-queries, more than the max of 2. received start=1000 end=1300")
+}
@@ -44,6 +44,9 @@ func (cfg *Config) RegisterFlags(f *flag.FlagSet) {
 type Limits interface {
 	// Returns max queriers to use per tenant, or 0 if shuffle sharding is disabled.
 	MaxQueriersPerUser(user string) int
+
+	// Returns max requests of the tenant dispatched to queriers at once, or 0 if unlimited.
+	MaxConcurrentQueries(user string) int
 }
 
 // Frontend queues HTTP requests, dispatches them to backends, and handles retries
@@ -209,7 +212,7 @@ func (f *Frontend) Process(server frontendv1pb.Frontend_ProcessServer) error {
 	lastUserIndex := queue.FirstUser()
 
 	for {
-		reqWrapper, idx, err := f.requestQueue.GetNextRequestForQuerier(server.Context(), lastUserIndex, querierID)
+		reqWrapper, idx, userID, err := f.requestQueue.GetNextRequestForQuerier(server.Context(), lastUserIndex, querierID)
 		if err != nil {
 			return err
 		}
@@ -232,6 +235,7 @@ func (f *Frontend) Process(server frontendv1pb.Frontend_ProcessServer) error {
 		  it's possible that it's own queue would perpetually contain only expired requests.
 		*/
 		if req.originalCtx.Err() != nil {
+			f.requestQueue.FinishRequest(userID)
 			lastUserIndex = lastUserIndex.ReuseLastUser()
 			continue
 		}
@@ -265,16 +269,19 @@ func (f *Frontend) Process(server frontendv1pb.Frontend_ProcessServer) error {
 		// downstream req.  Only way we can do that is to close the stream.
 		// The worker client is expecting this semantics.
 		case <-req.originalCtx.Done():
+			f.requestQueue.FinishRequest(userID)
 			return req.originalCtx.Err()
 
 		// Is there was an error handling this request due to network IO,
 		// then error out this upstream request _and_ stream.
 		case err := <-errs:
+			f.requestQueue.FinishRequest(userID)
 			req.err <- err
 			return err
 
 		// Happy path: merge the stats and propagate the response.
 		case resp := <-resps:
+			f.requestQueue.FinishRequest(userID)
 			if stats.ShouldTrackHTTPGRPCResponse(resp.HttpResponse) {
 				stats := stats.FromContext(req.originalCtx)
 				stats.Merge(resp.Stats) // Safe if stats is nil.
@@ -327,11 +334,12 @@ func (f *Frontend) queueRequest(ctx context.Context, req *request) error {
 
 	// aggregate the max queriers limit in the case of a multi tenant query
 	maxQueriers := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxQueriersPerUser)
+	maxInflight := validation.SmallestPositiveNonZeroIntPerTenant(tenantIDs, f.limits.MaxConcurrentQueries)
 
 	joinedTenantID := tenant.JoinTenantIDs(tenantIDs)
 	f.activeUsers.UpdateUserTimestamp(joinedTenantID, now)
 
-	err = f.requestQueue.EnqueueRequest(joinedTenantID, req, maxQueriers, nil)
+	err = f.requestQueue.EnqueueRequest(joinedTenantID, req, maxQueriers, maxInflight, nil)
 	if err == queue.ErrTooManyRequests {
 		return errTooManyRequest
 	}
@@ -7,6 +7,7 @@ import (
 	"github.com/grafana/tempo/modules/generator/processor/servicegraphs"
 	"github.com/grafana/tempo/modules/generator/processor/spanmetrics"
 	"github.com/grafana/tempo/modules/generator/registry"
+	"github.com/grafana/tempo/modules/generator/ruler"
 	"github.com/grafana/tempo/modules/generator/storage"
 )
 
@@ -24,6 +25,8 @@ type Config struct {
 	Processor ProcessorConfig `yaml:"processor"`
 	Registry  registry.Config `yaml:"registry"`
 	Storage   storage.Config  `yaml:"storage"`
+	Ruler     ruler.Config    `yaml:"ruler"`
+	Replay    ReplayConfig    `yaml:"replay"`
 	// MetricsIngestionSlack is the max amount of time passed since a span's start time
 	// for the span to be considered in metrics generation
 	MetricsIngestionSlack time.Duration `yaml:"metrics_ingestion_time_range_slack"`
@@ -35,10 +38,33 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	cfg.Processor.RegisterFlagsAndApplyDefaults(prefix, f)
 	cfg.Registry.RegisterFlagsAndApplyDefaults(prefix, f)
 	cfg.Storage.RegisterFlagsAndApplyDefaults(prefix, f)
+	cfg.Ruler.RegisterFlagsAndApplyDefaults(prefix, f)
+	cfg.Replay.RegisterFlagsAndApplyDefaults(prefix, f)
 	// setting default for max span age before discarding to 30s
 	cfg.MetricsIngestionSlack = 30 * time.Second
 }
 
+// ReplayConfig configures replaying recent spans from the ingesters when a tenant is created on this
+// generator or when the generators in the ring change.
+type ReplayConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Window is how far back spans are replayed
+	Window time.Duration `yaml:"window"`
+	// MaxBytes is the max size of the traces requested from each ingester
+	MaxBytes int `yaml:"max_bytes"`
+	// Timeout is the max duration of a replay
+	Timeout time.Duration `yaml:"timeout"`
+	// RingCheckPeriod is how often the generator ring is checked for changes
+	RingCheckPeriod time.Duration `yaml:"ring_check_period"`
+}
+
+func (cfg *ReplayConfig) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet) {
+	cfg.Window = 5 * time.Minute
+	cfg.MaxBytes = 50 * 1024 * 1024
+	cfg.Timeout = time.Minute
+	cfg.RingCheckPeriod = 10 * time.Second
+}
+
 type ProcessorConfig struct {
 	ServiceGraphs servicegraphs.Config `yaml:"service_graphs"`
 	SpanMetrics   spanmetrics.Config   `yaml:"span_metrics"`
@@ -65,6 +91,9 @@ func (cfg *ProcessorConfig) copyWithOverrides(o metricsGeneratorOverrides, userI
 	if dimensions := o.MetricsGeneratorProcessorSpanMetricsDimensions(userID); dimensions != nil {
 		copyCfg.SpanMetrics.Dimensions = dimensions
 	}
+	if bucketOverrides := o.MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides(userID); bucketOverrides != nil {
+		copyCfg.SpanMetrics.HistogramBucketOverrides = bucketOverrides
+	}
 
 	return copyCfg
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/grafana/tempo/modules/generator/processor/servicegraphs"
 	"github.com/grafana/tempo/modules/generator/processor/spanmetrics"
+	"github.com/grafana/tempo/modules/overrides"
 )
 
 func TestProcessorConfig_copyWithOverrides(t *testing.T) {
@@ -27,6 +28,7 @@ func TestProcessorConfig_copyWithOverrides(t *testing.T) {
 			serviceGraphsDimensions:       []string{"namespace"},
 			spanMetricsHistogramBuckets:   []float64{1, 2, 3},
 			spanMetricsDimensions:         []string{"cluster", "namespace"},
+			spanMetricsBucketOverrides:    []overrides.HistogramBucketOverride{{Values: []string{"batch"}, Buckets: []float64{60}}},
 		}
 
 		copied := original.copyWithOverrides(o, "tenant")
@@ -44,6 +46,7 @@ func TestProcessorConfig_copyWithOverrides(t *testing.T) {
 		assert.Equal(t, []string{"namespace"}, copied.ServiceGraphs.Dimensions)
 		assert.Equal(t, []float64{1, 2, 3}, copied.SpanMetrics.HistogramBuckets)
 		assert.Equal(t, []string{"cluster", "namespace"}, copied.SpanMetrics.Dimensions)
+		assert.Equal(t, []overrides.HistogramBucketOverride{{Values: []string{"batch"}, Buckets: []float64{60}}}, copied.SpanMetrics.HistogramBucketOverrides)
 	})
 
 	t.Run("empty overrides", func(t *testing.T) {
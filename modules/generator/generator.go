@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -18,6 +19,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/grafana/tempo/modules/generator/storage"
+	ingester_client "github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/pkg/tempopb"
 )
 
@@ -40,6 +42,11 @@ type Generator struct {
 	overrides metricsGeneratorOverrides
 
 	ringLifecycler *ring.BasicLifecycler
+	generatorRing  ring.ReadRing
+
+	// replayer is nil if replaying is disabled
+	replayer  *replayer
+	replaying atomic.Bool
 
 	instancesMtx sync.RWMutex
 	instances    map[string]*instance
@@ -55,11 +62,14 @@ type Generator struct {
 	logger log.Logger
 }
 
-// New makes a new Generator.
-func New(cfg *Config, overrides metricsGeneratorOverrides, reg prometheus.Registerer, logger log.Logger) (*Generator, error) {
+// New makes a new Generator. The ingester ring and client config are only used to replay recent spans.
+func New(cfg *Config, overrides metricsGeneratorOverrides, ingesterRing ring.ReadRing, generatorRing ring.ReadRing, ingesterClientCfg ingester_client.Config, reg prometheus.Registerer, logger log.Logger) (*Generator, error) {
 	if cfg.Storage.Path == "" {
 		return nil, errors.New("must configure metrics_generator.storage.path")
 	}
+	if err := cfg.Processor.SpanMetrics.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid span metrics config: %w", err)
+	}
 
 	err := os.MkdirAll(cfg.Storage.Path, os.ModePerm)
 	if err != nil {
@@ -70,6 +80,8 @@ func New(cfg *Config, overrides metricsGeneratorOverrides, reg prometheus.Regist
 		cfg:       cfg,
 		overrides: overrides,
 
+		generatorRing: generatorRing,
+
 		instances: map[string]*instance{},
 
 		reg:    reg,
@@ -103,6 +115,10 @@ func New(cfg *Config, overrides metricsGeneratorOverrides, reg prometheus.Regist
 		return nil, fmt.Errorf("create ring lifecycler: %w", err)
 	}
 
+	if cfg.Replay.Enabled {
+		g.replayer = newReplayer(cfg.Replay, overrides, ingesterRing, generatorRing, ingesterClientCfg, g.ringLifecycler.GetInstanceAddr, g.logger)
+	}
+
 	g.Service = services.NewBasicService(g.starting, g.running, g.stopping)
 	return g, nil
 }
@@ -121,7 +137,12 @@ func (g *Generator) starting(ctx context.Context) (err error) {
 		}
 	}()
 
-	g.subservices, err = services.NewManager(g.ringLifecycler)
+	subservices := []services.Service{g.ringLifecycler}
+	if g.replayer != nil {
+		subservices = append(subservices, g.replayer.pool)
+	}
+
+	g.subservices, err = services.NewManager(subservices...)
 	if err != nil {
 		return fmt.Errorf("unable to start metrics-generator dependencies: %w", err)
 	}
@@ -137,17 +158,72 @@ func (g *Generator) starting(ctx context.Context) (err error) {
 }
 
 func (g *Generator) running(ctx context.Context) error {
+	// the generator ring is only checked for changes when replaying is enabled
+	var ringCheck <-chan time.Time
+	var lastRingState ring.ReplicationSet
+	if g.replayer != nil {
+		ticker := time.NewTicker(g.cfg.Replay.RingCheckPeriod)
+		defer ticker.Stop()
+		ringCheck = ticker.C
+
+		// ignore the error, an empty replication set is compared with the next state
+		lastRingState, _ = g.generatorRing.GetAllHealthy(ring.Write) // nolint:errcheck
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 
+		case <-ringCheck:
+			ringState, _ := g.generatorRing.GetAllHealthy(ring.Write) // nolint:errcheck
+			if ring.HasReplicationSetChangedWithoutState(lastRingState, ringState) {
+				lastRingState = ringState
+				go g.replayAll(ctx)
+			}
+
 		case err := <-g.subservicesWatcher.Chan():
 			return fmt.Errorf("metrics-generator subservice failed %w", err)
 		}
 	}
 }
 
+// replayAll replays the recent spans of all tenants after the generators in the ring changed, the traces
+// sharded to this generator have changed.
+func (g *Generator) replayAll(ctx context.Context) {
+	// a replay of all tenants is already running, it uses the new ring as well
+	if !g.replaying.CAS(false, true) {
+		return
+	}
+	defer g.replaying.Store(false)
+
+	g.instancesMtx.RLock()
+	instances := make([]*instance, 0, len(g.instances))
+	for _, inst := range g.instances {
+		instances = append(instances, inst)
+	}
+	g.instancesMtx.RUnlock()
+
+	end := time.Now()
+	for _, inst := range instances {
+		g.replay(ctx, inst, replayTriggerRingChange, end)
+	}
+}
+
+// replay replays the recent spans of the tenant that ended before end
+func (g *Generator) replay(ctx context.Context, inst *instance, trigger string, end time.Time) {
+	if g.readOnly.Load() {
+		return
+	}
+
+	metricReplays.WithLabelValues(trigger).Inc()
+	err := g.replayer.replay(ctx, inst, end)
+	if err != nil {
+		metricReplaysFailed.WithLabelValues(inst.instanceID).Inc()
+		level.Error(g.logger).Log("msg", "replaying recent spans failed", "tenant", inst.instanceID, "trigger", trigger, "err", err)
+	}
+}
+
 func (g *Generator) stopping(_ error) error {
 	// Mark as read-only
 	g.stopIncomingRequests()
@@ -222,6 +298,12 @@ func (g *Generator) getOrCreateInstance(instanceID string) (*instance, error) {
 		return nil, err
 	}
 	g.instances[instanceID] = inst
+
+	// spans of the tenant pushed before the instance was created, e.g. before a restart, are replayed
+	if g.replayer != nil {
+		go g.replay(context.Background(), inst, replayTriggerNewTenant, time.Now())
+	}
+
 	return inst, nil
 }
 
@@ -12,11 +12,13 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	prometheus_storage "github.com/prometheus/prometheus/storage"
 
 	"github.com/grafana/tempo/modules/generator/processor"
 	"github.com/grafana/tempo/modules/generator/processor/servicegraphs"
 	"github.com/grafana/tempo/modules/generator/processor/spanmetrics"
 	"github.com/grafana/tempo/modules/generator/registry"
+	"github.com/grafana/tempo/modules/generator/ruler"
 	"github.com/grafana/tempo/modules/generator/storage"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
@@ -50,6 +52,11 @@ var (
 		Name:      "metrics_generator_spans_discarded_total",
 		Help:      "The total number of discarded spans received per tenant",
 	}, []string{"tenant", "reason"})
+	metricAlertingRulesUpdateFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "metrics_generator_alerting_rules_update_failed_total",
+		Help:      "The total number of times updating the alerting rules failed",
+	}, []string{"tenant"})
 )
 
 const reasonOutsideTimeRangeSlack = "outside_metrics_ingestion_slack"
@@ -69,6 +76,10 @@ type instance struct {
 	// active at any time
 	processors map[string]processor.Processor
 
+	// rulerMtx protects the ruler, it is created once the tenant has alerting rules
+	rulerMtx sync.RWMutex
+	ruler    *ruler.Ruler
+
 	shutdownCh chan struct{}
 
 	reg    prometheus.Registerer
@@ -83,8 +94,7 @@ func newInstance(cfg *Config, instanceID string, overrides metricsGeneratorOverr
 		instanceID: instanceID,
 		overrides:  overrides,
 
-		registry: registry.New(&cfg.Registry, overrides, instanceID, wal, logger),
-		wal:      wal,
+		wal: wal,
 
 		processors: make(map[string]processor.Processor),
 
@@ -94,10 +104,14 @@ func newInstance(cfg *Config, instanceID string, overrides metricsGeneratorOverr
 		logger: logger,
 	}
 
+	// samples are written to the WAL and, if the tenant has alerting rules, to the ruler
+	i.registry = registry.New(&cfg.Registry, overrides, instanceID, i, logger)
+
 	err := i.updateProcessors()
 	if err != nil {
 		return nil, fmt.Errorf("could not initialize processors: %w", err)
 	}
+	i.updateRulesAndLog()
 	go i.watchOverrides()
 
 	return i, nil
@@ -117,6 +131,7 @@ func (i *instance) watchOverrides() {
 				metricActiveProcessorsUpdateFailed.WithLabelValues(i.instanceID).Inc()
 				level.Error(i.logger).Log("msg", "updating the processors failed", "err", err)
 			}
+			i.updateRulesAndLog()
 
 		case <-i.shutdownCh:
 			return
@@ -165,6 +180,50 @@ func (i *instance) updateProcessors() error {
 	return nil
 }
 
+func (i *instance) updateRulesAndLog() {
+	err := i.updateRules()
+	if err != nil {
+		metricAlertingRulesUpdateFailed.WithLabelValues(i.instanceID).Inc()
+		level.Error(i.logger).Log("msg", "updating the alerting rules failed", "err", err)
+	}
+}
+
+// updateRules updates the alerting rules of the ruler. The ruler is created the first time the
+// tenant has alerting rules.
+func (i *instance) updateRules() error {
+	groups := i.overrides.MetricsGeneratorAlertingRules(i.instanceID)
+
+	i.rulerMtx.Lock()
+	defer i.rulerMtx.Unlock()
+
+	if i.ruler == nil {
+		if len(groups) == 0 {
+			return nil
+		}
+
+		var err error
+		i.ruler, err = ruler.New(&i.cfg.Ruler, i.instanceID, i.reg, i.logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	return i.ruler.UpdateRules(groups)
+}
+
+// Appender implements storage.Appendable
+func (i *instance) Appender(ctx context.Context) prometheus_storage.Appender {
+	appender := i.wal.Appender(ctx)
+
+	i.rulerMtx.RLock()
+	defer i.rulerMtx.RUnlock()
+
+	if i.ruler == nil {
+		return appender
+	}
+	return i.ruler.WrapAppender(ctx, appender)
+}
+
 // diffProcessors compares the existing processors with the desired processors and config.
 // Must be called under a read lock.
 func (i *instance) diffProcessors(desiredProcessors map[string]struct{}, desiredCfg ProcessorConfig) (toAdd, toRemove, toReplace []string, err error) {
@@ -265,6 +324,27 @@ func (i *instance) pushSpans(ctx context.Context, req *tempopb.PushSpansRequest)
 	}
 }
 
+// replaySpans sends spans replayed from the ingesters to the processors that support replaying, the other
+// processors would count the spans twice.
+func (i *instance) replaySpans(ctx context.Context, req *tempopb.PushSpansRequest) {
+	spanCount := 0
+	for _, b := range req.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			spanCount += len(ils.Spans)
+		}
+	}
+	metricSpansReplayed.WithLabelValues(i.instanceID).Add(float64(spanCount))
+
+	i.processorsMtx.RLock()
+	defer i.processorsMtx.RUnlock()
+
+	for _, p := range i.processors {
+		if replayer, ok := p.(processor.Replayer); ok {
+			replayer.ReplaySpans(ctx, req)
+		}
+	}
+}
+
 func (i *instance) preprocessSpans(req *tempopb.PushSpansRequest) {
 	size := 0
 	spanCount := 0
@@ -311,6 +391,12 @@ func (i *instance) shutdown() {
 
 	i.registry.Close()
 
+	i.rulerMtx.Lock()
+	if i.ruler != nil {
+		i.ruler.Stop()
+	}
+	i.rulerMtx.Unlock()
+
 	err := i.wal.Close()
 	if err != nil {
 		level.Error(i.logger).Log("msg", "closing wal failed", "tenant", i.instanceID, "err", err)
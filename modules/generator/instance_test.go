@@ -16,6 +16,7 @@ import (
 
 	"github.com/grafana/tempo/modules/generator/processor/servicegraphs"
 	"github.com/grafana/tempo/modules/generator/storage"
+	tempo_overrides "github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/util/test"
@@ -130,6 +131,32 @@ func Test_instance_updateProcessors(t *testing.T) {
 	})
 }
 
+func Test_instance_updateRules(t *testing.T) {
+	cfg := Config{}
+	cfg.RegisterFlagsAndApplyDefaults("", &flag.FlagSet{})
+	overrides := mockOverrides{}
+
+	instance, err := newInstance(&cfg, "test", &overrides, &noopStorage{}, prometheus.NewRegistry(), log.NewNopLogger())
+	assert.NoError(t, err)
+	defer instance.shutdown()
+
+	// no ruler without rules
+	assert.Nil(t, instance.ruler)
+	assert.IsType(t, &noopAppender{}, instance.Appender(context.Background()))
+
+	overrides.alertingRules = []tempo_overrides.AlertingRuleGroup{
+		{Name: "group", Rules: []tempo_overrides.AlertingRule{{Alert: "alert", Expr: "up == 0"}}},
+	}
+	assert.NoError(t, instance.updateRules())
+	assert.NotNil(t, instance.ruler)
+
+	// invalid rules are rejected
+	overrides.alertingRules = []tempo_overrides.AlertingRuleGroup{
+		{Name: "group", Rules: []tempo_overrides.AlertingRule{{Alert: "alert", Expr: "up =="}}},
+	}
+	assert.Error(t, instance.updateRules())
+}
+
 type noopStorage struct{}
 
 var _ storage.Storage = (*noopStorage)(nil)
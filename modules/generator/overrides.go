@@ -8,11 +8,14 @@ import (
 type metricsGeneratorOverrides interface {
 	registry.Overrides
 
+	MetricsGeneratorRingSize(userID string) int
 	MetricsGeneratorProcessors(userID string) map[string]struct{}
 	MetricsGeneratorProcessorServiceGraphsHistogramBuckets(userID string) []float64
 	MetricsGeneratorProcessorServiceGraphsDimensions(userID string) []string
 	MetricsGeneratorProcessorSpanMetricsHistogramBuckets(userID string) []float64
 	MetricsGeneratorProcessorSpanMetricsDimensions(userID string) []string
+	MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides(userID string) []overrides.HistogramBucketOverride
+	MetricsGeneratorAlertingRules(userID string) []overrides.AlertingRuleGroup
 }
 
 var _ metricsGeneratorOverrides = (*overrides.Overrides)(nil)
@@ -1,6 +1,10 @@
 package generator
 
-import "time"
+import (
+	"time"
+
+	"github.com/grafana/tempo/modules/overrides"
+)
 
 type mockOverrides struct {
 	processors                    map[string]struct{}
@@ -8,6 +12,8 @@ type mockOverrides struct {
 	serviceGraphsDimensions       []string
 	spanMetricsHistogramBuckets   []float64
 	spanMetricsDimensions         []string
+	spanMetricsBucketOverrides    []overrides.HistogramBucketOverride
+	alertingRules                 []overrides.AlertingRuleGroup
 }
 
 var _ metricsGeneratorOverrides = (*mockOverrides)(nil)
@@ -20,6 +26,10 @@ func (m *mockOverrides) MetricsGeneratorCollectionInterval(userID string) time.D
 	return 15 * time.Second
 }
 
+func (m *mockOverrides) MetricsGeneratorRingSize(userID string) int {
+	return 0
+}
+
 func (m *mockOverrides) MetricsGeneratorProcessors(userID string) map[string]struct{} {
 	return m.processors
 }
@@ -43,3 +53,11 @@ func (m *mockOverrides) MetricsGeneratorProcessorSpanMetricsHistogramBuckets(use
 func (m *mockOverrides) MetricsGeneratorProcessorSpanMetricsDimensions(userID string) []string {
 	return m.spanMetricsDimensions
 }
+
+func (m *mockOverrides) MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides(userID string) []overrides.HistogramBucketOverride {
+	return m.spanMetricsBucketOverrides
+}
+
+func (m *mockOverrides) MetricsGeneratorAlertingRules(userID string) []overrides.AlertingRuleGroup {
+	return m.alertingRules
+}
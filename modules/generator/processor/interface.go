@@ -17,3 +17,11 @@ type Processor interface {
 	// PushSpans should not be called anymore.
 	Shutdown(ctx context.Context)
 }
+
+// Replayer is implemented by processors that can process spans replayed from the ingesters after the
+// generator was restarted or the generators were scaled.
+type Replayer interface {
+	// ReplaySpans processes a batch of spans that may have been processed already by this or another
+	// generator.
+	ReplaySpans(ctx context.Context, req *tempopb.PushSpansRequest)
+}
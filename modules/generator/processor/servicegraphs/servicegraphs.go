@@ -75,6 +75,8 @@ type Processor struct {
 	logger             log.Logger
 }
 
+var _ gen.Replayer = (*Processor)(nil)
+
 func New(cfg Config, tenant string, registry registry.Registry, logger log.Logger) gen.Processor {
 	labels := []string{"client", "server", "connection_type"}
 	for _, d := range cfg.Dimensions {
@@ -126,7 +128,20 @@ func (p *Processor) PushSpans(ctx context.Context, req *tempopb.PushSpansRequest
 	span, _ := opentracing.StartSpanFromContext(ctx, "servicegraphs.PushSpans")
 	defer span.Finish()
 
-	if err := p.consume(req.Batches); err != nil {
+	p.consumeAndLog(req.Batches, false)
+}
+
+// ReplaySpans implements processor.Replayer. Replayed spans only complete edges that also have a span
+// that was pushed live, so requests recorded before the replay aren't counted twice.
+func (p *Processor) ReplaySpans(ctx context.Context, req *tempopb.PushSpansRequest) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "servicegraphs.ReplaySpans")
+	defer span.Finish()
+
+	p.consumeAndLog(req.Batches, true)
+}
+
+func (p *Processor) consumeAndLog(resourceSpans []*v1_trace.ResourceSpans, replay bool) {
+	if err := p.consume(resourceSpans, replay); err != nil {
 		if errors.As(err, &tooManySpansError{}) {
 			level.Warn(p.logger).Log("msg", "skipped processing of spans", "maxItems", p.Cfg.MaxItems, "err", err)
 		} else {
@@ -135,7 +150,7 @@ func (p *Processor) PushSpans(ctx context.Context, req *tempopb.PushSpansRequest
 	}
 }
 
-func (p *Processor) consume(resourceSpans []*v1_trace.ResourceSpans) (err error) {
+func (p *Processor) consume(resourceSpans []*v1_trace.ResourceSpans, replay bool) (err error) {
 	var (
 		isNew             bool
 		totalDroppedSpans int
@@ -164,6 +179,7 @@ func (p *Processor) consume(resourceSpans []*v1_trace.ResourceSpans) (err error)
 						e.ClientService = svcName
 						e.ClientLatencySec = spanDurationSec(span)
 						e.Failed = e.Failed || p.spanFailed(span)
+						e.Live = e.Live || !replay
 						p.upsertDimensions(e.Dimensions, rs.Resource.Attributes, span.Attributes)
 
 						// A database request will only have one span, we don't wait for the server
@@ -187,6 +203,7 @@ func (p *Processor) consume(resourceSpans []*v1_trace.ResourceSpans) (err error)
 						e.ServerService = svcName
 						e.ServerLatencySec = spanDurationSec(span)
 						e.Failed = e.Failed || p.spanFailed(span)
+						e.Live = e.Live || !replay
 						p.upsertDimensions(e.Dimensions, rs.Resource.Attributes, span.Attributes)
 					})
 				default:
@@ -205,7 +222,7 @@ func (p *Processor) consume(resourceSpans []*v1_trace.ResourceSpans) (err error)
 					return err
 				}
 
-				if isNew {
+				if isNew && !replay {
 					p.metricTotalEdges.Inc()
 				}
 			}
@@ -234,6 +251,10 @@ func (p *Processor) Shutdown(_ context.Context) {
 }
 
 func (p *Processor) onComplete(e *store.Edge) {
+	if !e.Live {
+		return
+	}
+
 	labelValues := make([]string, 0, 2+len(p.Cfg.Dimensions))
 	labelValues = append(labelValues, e.ClientService, e.ServerService, string(e.ConnectionType))
 
@@ -248,11 +269,14 @@ func (p *Processor) onComplete(e *store.Edge) {
 		p.serviceGraphRequestFailedTotal.Inc(registryLabelValues, 1)
 	}
 
-	p.serviceGraphRequestServerSecondsHistogram.ObserveWithExemplar(registryLabelValues, e.ServerLatencySec, e.TraceID)
-	p.serviceGraphRequestClientSecondsHistogram.ObserveWithExemplar(registryLabelValues, e.ClientLatencySec, e.TraceID)
+	p.serviceGraphRequestServerSecondsHistogram.ObserveWithExemplar(registryLabelValues, e.ServerLatencySec, e.TraceID, 1)
+	p.serviceGraphRequestClientSecondsHistogram.ObserveWithExemplar(registryLabelValues, e.ClientLatencySec, e.TraceID, 1)
 }
 
 func (p *Processor) onExpire(e *store.Edge) {
+	if !e.Live {
+		return
+	}
 	p.metricExpiredEdges.Inc()
 }
 
@@ -17,6 +17,7 @@ import (
 
 	"github.com/grafana/tempo/modules/generator/registry"
 	"github.com/grafana/tempo/pkg/tempopb"
+	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 )
 
 func TestServiceGraphs(t *testing.T) {
@@ -134,6 +135,82 @@ func TestServiceGraphs_failedRequests(t *testing.T) {
 	assert.Equal(t, 1.0, testRegistry.Query(`traces_service_graph_request_failed_total`, serverToDatabaseLabels))
 }
 
+func TestServiceGraphs_replay(t *testing.T) {
+	testRegistry := registry.NewTestRegistry()
+
+	cfg := Config{}
+	cfg.RegisterFlagsAndApplyDefaults("", nil)
+
+	p := New(cfg, "test", testRegistry, log.NewNopLogger())
+	defer p.Shutdown(context.Background())
+
+	request, err := loadTestData("testdata/trace-with-queue-database.json")
+	require.NoError(t, err)
+
+	// edges built only from replayed spans are not recorded
+	p.(*Processor).ReplaySpans(context.Background(), request)
+	assert.Equal(t, 0.0, testRegistry.Query(`traces_service_graph_request_total`, labels.FromMap(map[string]string{
+		"client":          "mythical-requester",
+		"server":          "mythical-server",
+		"connection_type": "",
+	})))
+
+	// replayed client spans complete the edges of live server spans
+	request, err = loadTestData("testdata/trace-with-queue-database.json")
+	require.NoError(t, err)
+	replayed, live := splitByKind(request, v1_trace.Span_SPAN_KIND_CLIENT, v1_trace.Span_SPAN_KIND_PRODUCER)
+
+	p.(*Processor).ReplaySpans(context.Background(), replayed)
+	p.PushSpans(context.Background(), live)
+
+	assert.Equal(t, 1.0, testRegistry.Query(`traces_service_graph_request_total`, labels.FromMap(map[string]string{
+		"client":          "mythical-requester",
+		"server":          "mythical-server",
+		"connection_type": "",
+	})))
+	assert.Equal(t, 1.0, testRegistry.Query(`traces_service_graph_request_total`, labels.FromMap(map[string]string{
+		"client":          "mythical-requester",
+		"server":          "mythical-recorder",
+		"connection_type": "messaging_system",
+	})))
+	// database edges only have a client span
+	assert.Equal(t, 0.0, testRegistry.Query(`traces_service_graph_request_total`, labels.FromMap(map[string]string{
+		"client":          "mythical-server",
+		"server":          "postgres",
+		"connection_type": "database",
+	})))
+}
+
+// splitByKind splits the spans of the request in the spans of the given kinds and the other spans
+func splitByKind(req *tempopb.PushSpansRequest, kinds ...v1_trace.Span_SpanKind) (matching, other *tempopb.PushSpansRequest) {
+	matching, other = &tempopb.PushSpansRequest{}, &tempopb.PushSpansRequest{}
+	for _, b := range req.Batches {
+		var matchingSpans, otherSpans []*v1_trace.Span
+		for _, ils := range b.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				isKind := false
+				for _, k := range kinds {
+					isKind = isKind || s.Kind == k
+				}
+				if isKind {
+					matchingSpans = append(matchingSpans, s)
+				} else {
+					otherSpans = append(otherSpans, s)
+				}
+			}
+		}
+		matching.Batches = append(matching.Batches, &v1_trace.ResourceSpans{
+			Resource:                    b.Resource,
+			InstrumentationLibrarySpans: []*v1_trace.InstrumentationLibrarySpans{{Spans: matchingSpans}},
+		})
+		other.Batches = append(other.Batches, &v1_trace.ResourceSpans{
+			Resource:                    b.Resource,
+			InstrumentationLibrarySpans: []*v1_trace.InstrumentationLibrarySpans{{Spans: otherSpans}},
+		})
+	}
+	return matching, other
+}
+
 func TestServiceGraphs_tooManySpansErr(t *testing.T) {
 	testRegistry := registry.TestRegistry{}
 
@@ -146,7 +223,7 @@ func TestServiceGraphs_tooManySpansErr(t *testing.T) {
 	request, err := loadTestData("testdata/trace-with-queue-database.json")
 	require.NoError(t, err)
 
-	err = p.(*Processor).consume(request.Batches)
+	err = p.(*Processor).consume(request.Batches, false)
 	assert.True(t, errors.As(err, &tooManySpansError{}))
 }
 
@@ -26,6 +26,10 @@ type Edge struct {
 	// Additional dimension to add to the metrics
 	Dimensions map[string]string
 
+	// Live is true if at least one of the spans of the Edge was pushed to the generator. Edges
+	// built only from spans replayed from the ingesters were already recorded before.
+	Live bool
+
 	// expiration is the time at which the Edge expires, expressed as Unix time
 	expiration int64
 }
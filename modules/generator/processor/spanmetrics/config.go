@@ -4,6 +4,8 @@ import (
 	"flag"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/tempo/modules/overrides"
 )
 
 const (
@@ -13,11 +15,36 @@ const (
 type Config struct {
 	// Buckets for latency histogram in seconds.
 	HistogramBuckets []float64 `yaml:"histogram_buckets"`
+	// HistogramBucketOverrides replace the HistogramBuckets for the spans of matching resources. The first
+	// matching override is used.
+	HistogramBucketOverrides []overrides.HistogramBucketOverride `yaml:"histogram_bucket_overrides"`
 	// Additional dimensions (labels) to be added to the metric,
 	// along with the default ones (service, span_name, span_kind and span_status).
 	Dimensions []string `yaml:"dimensions"`
+	// EnableTargetInfo emits a target_info metric per resource and adds job and instance
+	// labels to the span metrics, so resource attributes can be joined in PromQL instead
+	// of being added as dimensions to every series.
+	EnableTargetInfo bool `yaml:"enable_target_info"`
+	// Resource attributes to be added as labels to the target_info metric.
+	TargetInfoDimensions []string `yaml:"target_info_dimensions"`
+	// SpanMultiplierKeys are attributes holding the number of spans a sampled span represents. The first key
+	// found in the span or resource attributes scales the metrics of the span.
+	SpanMultiplierKeys []string `yaml:"span_multiplier_keys"`
+	// SamplingProbabilityKeys are attributes holding the probability a span was sampled with, the span counts as
+	// 1/probability spans. They are only looked up if no SpanMultiplierKeys is found.
+	SamplingProbabilityKeys []string `yaml:"sampling_probability_keys"`
 }
 
 func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet) {
 	cfg.HistogramBuckets = prometheus.ExponentialBuckets(0.002, 2, 14)
 }
+
+// Validate returns an error if a histogram bucket override is invalid
+func (cfg *Config) Validate() error {
+	for _, o := range cfg.HistogramBucketOverrides {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,14 +2,17 @@ package spanmetrics
 
 import (
 	"context"
+	"math"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/prometheus/util/strutil"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.5.0"
 
 	gen "github.com/grafana/tempo/modules/generator/processor"
 	processor_util "github.com/grafana/tempo/modules/generator/processor/util"
 	"github.com/grafana/tempo/modules/generator/registry"
+	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1 "github.com/grafana/tempo/pkg/tempopb/resource/v1"
 	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
@@ -20,6 +23,7 @@ const (
 	metricCallsTotal      = "traces_spanmetrics_calls_total"
 	metricDurationSeconds = "traces_spanmetrics_latency"
 	metricSizeTotal       = "traces_spanmetrics_size_total"
+	metricTargetInfo      = "traces_target_info"
 )
 
 type Processor struct {
@@ -28,24 +32,51 @@ type Processor struct {
 	spanMetricsCallsTotal      registry.Counter
 	spanMetricsDurationSeconds registry.Histogram
 	spanMetricsSizeTotal       registry.Counter
+	spanMetricsTargetInfo      registry.Gauge
+
+	bucketOverrides []bucketOverride
 
 	// for testing
 	now func() time.Time
 }
 
+// bucketOverride is an overrides.HistogramBucketOverride prepared for matching resources
+type bucketOverride struct {
+	attribute string
+	values    map[string]struct{}
+	buckets   *registry.Buckets
+}
+
 func New(cfg Config, registry registry.Registry) gen.Processor {
 	labels := []string{"service", "span_name", "span_kind", "status_code"}
 	for _, d := range cfg.Dimensions {
 		labels = append(labels, strutil.SanitizeLabelName(d))
 	}
+	if cfg.EnableTargetInfo {
+		labels = append(labels, "job", "instance")
+	}
 
-	return &Processor{
+	p := &Processor{
 		Cfg:                        cfg,
 		spanMetricsCallsTotal:      registry.NewCounter(metricCallsTotal, labels),
 		spanMetricsDurationSeconds: registry.NewHistogram(metricDurationSeconds, labels, cfg.HistogramBuckets),
 		spanMetricsSizeTotal:       registry.NewCounter(metricSizeTotal, labels),
 		now:                        time.Now,
 	}
+
+	if cfg.EnableTargetInfo {
+		targetInfoLabels := []string{"job", "instance"}
+		for _, d := range cfg.TargetInfoDimensions {
+			targetInfoLabels = append(targetInfoLabels, strutil.SanitizeLabelName(d))
+		}
+		p.spanMetricsTargetInfo = registry.NewGauge(metricTargetInfo, targetInfoLabels)
+	}
+
+	for _, o := range cfg.HistogramBucketOverrides {
+		p.bucketOverrides = append(p.bucketOverrides, newBucketOverride(o))
+	}
+
+	return p
 }
 
 func (p *Processor) Name() string {
@@ -67,18 +98,59 @@ func (p *Processor) aggregateMetrics(resourceSpans []*v1_trace.ResourceSpans) {
 		// already extract service name, so we only have to do it once per batch of spans
 		svcName, _ := processor_util.FindServiceName(rs.Resource.Attributes)
 
+		var job, instance string
+		if p.Cfg.EnableTargetInfo {
+			job, instance = p.aggregateTargetInfo(rs.Resource)
+		}
+
+		buckets := p.resourceBuckets(rs.Resource)
+
 		for _, ils := range rs.InstrumentationLibrarySpans {
 			for _, span := range ils.Spans {
-				p.aggregateMetricsForSpan(svcName, rs.Resource, span)
+				p.aggregateMetricsForSpan(svcName, job, instance, buckets, rs.Resource, span)
 			}
 		}
 	}
 }
 
-func (p *Processor) aggregateMetricsForSpan(svcName string, rs *v1.Resource, span *v1_trace.Span) {
+// aggregateTargetInfo records the target_info series for the given resource and returns the job
+// and instance labels identifying it.
+func (p *Processor) aggregateTargetInfo(rs *v1.Resource) (job, instance string) {
+	job, _ = processor_util.FindJob(rs.GetAttributes())
+	instance, _ = processor_util.FindInstanceID(rs.GetAttributes())
+
+	labelValues := make([]string, 0, 2+len(p.Cfg.TargetInfoDimensions))
+	labelValues = append(labelValues, job, instance)
+
+	for _, d := range p.Cfg.TargetInfoDimensions {
+		value, _ := processor_util.FindAttributeValue(d, rs.GetAttributes())
+		labelValues = append(labelValues, value)
+	}
+
+	p.spanMetricsTargetInfo.Set(registry.NewLabelValues(labelValues), 1)
+
+	return job, instance
+}
+
+// resourceBuckets returns the latency buckets of the first override matching the resource, or nil to use the
+// default buckets.
+func (p *Processor) resourceBuckets(rs *v1.Resource) *registry.Buckets {
+	for _, o := range p.bucketOverrides {
+		value, ok := processor_util.FindAttributeValue(o.attribute, rs.GetAttributes())
+		if !ok {
+			continue
+		}
+		if _, ok := o.values[value]; ok {
+			return o.buckets
+		}
+	}
+	return nil
+}
+
+func (p *Processor) aggregateMetricsForSpan(svcName, job, instance string, buckets *registry.Buckets, rs *v1.Resource, span *v1_trace.Span) {
 	latencySeconds := float64(span.GetEndTimeUnixNano()-span.GetStartTimeUnixNano()) / float64(time.Second.Nanoseconds())
 
-	labelValues := make([]string, 0, 4+len(p.Cfg.Dimensions))
+	labelValues := make([]string, 0, 6+len(p.Cfg.Dimensions))
 	labelValues = append(labelValues, svcName, span.GetName(), span.GetKind().String(), span.GetStatus().GetCode().String())
 
 	for _, d := range p.Cfg.Dimensions {
@@ -86,9 +158,48 @@ func (p *Processor) aggregateMetricsForSpan(svcName string, rs *v1.Resource, spa
 		labelValues = append(labelValues, value)
 	}
 
+	if p.Cfg.EnableTargetInfo {
+		labelValues = append(labelValues, job, instance)
+	}
+
 	registryLabelValues := registry.NewLabelValues(labelValues)
+	multiplier := p.spanMultiplier(rs, span)
+
+	p.spanMetricsCallsTotal.Inc(registryLabelValues, multiplier)
+	p.spanMetricsSizeTotal.Inc(registryLabelValues, float64(span.Size())*multiplier)
+	p.spanMetricsDurationSeconds.ObserveWithExemplarAndBuckets(registryLabelValues, latencySeconds, tempo_util.TraceIDToHexString(span.TraceId), multiplier, buckets)
+}
+
+func newBucketOverride(o overrides.HistogramBucketOverride) bucketOverride {
+	attribute := o.Attribute
+	if attribute == "" {
+		attribute = semconv.AttributeServiceName
+	}
+
+	values := make(map[string]struct{}, len(o.Values))
+	for _, v := range o.Values {
+		values[v] = struct{}{}
+	}
+
+	return bucketOverride{
+		attribute: attribute,
+		values:    values,
+		buckets:   registry.NewBuckets(o.Buckets),
+	}
+}
 
-	p.spanMetricsCallsTotal.Inc(registryLabelValues, 1)
-	p.spanMetricsSizeTotal.Inc(registryLabelValues, float64(span.Size()))
-	p.spanMetricsDurationSeconds.ObserveWithExemplar(registryLabelValues, latencySeconds, tempo_util.TraceIDToHexString(span.TraceId))
+// spanMultiplier returns the number of spans the span represents according to its sampling attributes. Spans
+// without valid sampling attributes count once.
+func (p *Processor) spanMultiplier(rs *v1.Resource, span *v1_trace.Span) float64 {
+	for _, key := range p.Cfg.SpanMultiplierKeys {
+		if m, ok := processor_util.FindNumericAttributeValue(key, span.Attributes, rs.Attributes); ok && m > 0 && !math.IsInf(m, 1) {
+			return m
+		}
+	}
+	for _, key := range p.Cfg.SamplingProbabilityKeys {
+		if prob, ok := processor_util.FindNumericAttributeValue(key, span.Attributes, rs.Attributes); ok && prob > 0 && prob <= 1 {
+			return 1 / prob
+		}
+	}
+	return 1
 }
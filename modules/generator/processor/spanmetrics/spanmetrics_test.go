@@ -9,8 +9,10 @@ import (
 
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/tempo/modules/generator/registry"
+	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/tempopb"
 	common_v1 "github.com/grafana/tempo/pkg/tempopb/common/v1"
 	trace_v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
@@ -101,6 +103,171 @@ func TestSpanMetrics_dimensions(t *testing.T) {
 	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_sum", lbls))
 }
 
+func TestSpanMetrics_targetInfo(t *testing.T) {
+	testRegistry := registry.NewTestRegistry()
+
+	cfg := Config{}
+	cfg.RegisterFlagsAndApplyDefaults("", nil)
+	cfg.HistogramBuckets = []float64{0.5, 1}
+	cfg.EnableTargetInfo = true
+	cfg.TargetInfoDimensions = []string{"k8s.cluster.name", "does-not-exist"}
+
+	p := New(cfg, testRegistry)
+	defer p.Shutdown(context.Background())
+
+	batch := test.MakeBatch(10, nil)
+	batch.Resource.Attributes = append(batch.Resource.Attributes,
+		&common_v1.KeyValue{
+			Key:   "service.namespace",
+			Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_StringValue{StringValue: "test-namespace"}},
+		},
+		&common_v1.KeyValue{
+			Key:   "service.instance.id",
+			Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_StringValue{StringValue: "test-instance"}},
+		},
+		&common_v1.KeyValue{
+			Key:   "k8s.cluster.name",
+			Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_StringValue{StringValue: "test-cluster"}},
+		},
+	)
+
+	p.PushSpans(context.Background(), &tempopb.PushSpansRequest{Batches: []*trace_v1.ResourceSpans{batch}})
+
+	fmt.Println(testRegistry)
+
+	// resource attributes are only added to target_info, span metrics only carry job and instance
+	targetInfoLbls := labels.FromMap(map[string]string{
+		"job":              "test-namespace/test-service",
+		"instance":         "test-instance",
+		"k8s_cluster_name": "test-cluster",
+		"does_not_exist":   "",
+	})
+	assert.Equal(t, 1.0, testRegistry.Query("traces_target_info", targetInfoLbls))
+
+	lbls := labels.FromMap(map[string]string{
+		"service":     "test-service",
+		"span_name":   "test",
+		"span_kind":   "SPAN_KIND_CLIENT",
+		"status_code": "STATUS_CODE_OK",
+		"job":         "test-namespace/test-service",
+		"instance":    "test-instance",
+	})
+
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_calls_total", lbls))
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_count", lbls))
+}
+
+func TestSpanMetrics_spanMultiplier(t *testing.T) {
+	testRegistry := registry.NewTestRegistry()
+
+	cfg := Config{}
+	cfg.RegisterFlagsAndApplyDefaults("", nil)
+	cfg.HistogramBuckets = []float64{0.5, 1}
+	cfg.SpanMultiplierKeys = []string{"sampling.multiplier"}
+	cfg.SamplingProbabilityKeys = []string{"sampling.probability"}
+
+	p := New(cfg, testRegistry)
+	defer p.Shutdown(context.Background())
+
+	batch := test.MakeBatch(4, nil)
+	var spans []*trace_v1.Span
+	for _, ils := range batch.InstrumentationLibrarySpans {
+		spans = append(spans, ils.Spans...)
+	}
+	require.Len(t, spans, 4)
+
+	// a multiplier, a probability, both with the multiplier taking precedence, an invalid probability counting once
+	spans[0].Attributes = append(spans[0].Attributes, &common_v1.KeyValue{
+		Key:   "sampling.multiplier",
+		Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_IntValue{IntValue: 10}},
+	})
+	spans[1].Attributes = append(spans[1].Attributes, &common_v1.KeyValue{
+		Key:   "sampling.probability",
+		Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_DoubleValue{DoubleValue: 0.25}},
+	})
+	spans[2].Attributes = append(spans[2].Attributes, &common_v1.KeyValue{
+		Key:   "sampling.probability",
+		Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_StringValue{StringValue: "0.5"}},
+	}, &common_v1.KeyValue{
+		Key:   "sampling.multiplier",
+		Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_DoubleValue{DoubleValue: 3}},
+	})
+	spans[3].Attributes = append(spans[3].Attributes, &common_v1.KeyValue{
+		Key:   "sampling.probability",
+		Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_DoubleValue{DoubleValue: 0}},
+	})
+
+	p.PushSpans(context.Background(), &tempopb.PushSpansRequest{Batches: []*trace_v1.ResourceSpans{batch}})
+
+	lbls := labels.FromMap(map[string]string{
+		"service":     "test-service",
+		"span_name":   "test",
+		"span_kind":   "SPAN_KIND_CLIENT",
+		"status_code": "STATUS_CODE_OK",
+	})
+
+	expected := 10.0 + 4 + 3 + 1
+	assert.Equal(t, expected, testRegistry.Query("traces_spanmetrics_calls_total", lbls))
+	assert.Equal(t, expected, testRegistry.Query("traces_spanmetrics_latency_count", lbls))
+	assert.Equal(t, expected, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls, math.Inf(1))))
+	assert.Equal(t, expected, testRegistry.Query("traces_spanmetrics_latency_sum", lbls))
+}
+
+func TestSpanMetrics_histogramBucketOverrides(t *testing.T) {
+	testRegistry := registry.NewTestRegistry()
+
+	cfg := Config{}
+	cfg.RegisterFlagsAndApplyDefaults("", nil)
+	cfg.HistogramBuckets = []float64{0.5, 1}
+	cfg.HistogramBucketOverrides = []overrides.HistogramBucketOverride{
+		{Attribute: "team", Values: []string{"batch"}, Buckets: []float64{10, 60}},
+		{Values: []string{"batch-service", "other-service"}, Buckets: []float64{30}},
+	}
+
+	p := New(cfg, testRegistry)
+	defer p.Shutdown(context.Background())
+
+	batch := test.MakeBatch(10, nil)
+	batchJob := test.MakeBatch(10, nil)
+	batchJob.Resource.Attributes = []*common_v1.KeyValue{
+		{Key: "service.name", Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_StringValue{StringValue: "batch-service"}}},
+		{Key: "team", Value: &common_v1.AnyValue{Value: &common_v1.AnyValue_StringValue{StringValue: "batch"}}},
+	}
+	otherJob := test.MakeBatch(10, nil)
+	otherJob.Resource.Attributes[0].Value = &common_v1.AnyValue{Value: &common_v1.AnyValue_StringValue{StringValue: "other-service"}}
+
+	p.PushSpans(context.Background(), &tempopb.PushSpansRequest{Batches: []*trace_v1.ResourceSpans{batch, batchJob, otherJob}})
+
+	fmt.Println(testRegistry)
+
+	lbls := func(svc string) labels.Labels {
+		return labels.FromMap(map[string]string{
+			"service":     svc,
+			"span_name":   "test",
+			"span_kind":   "SPAN_KIND_CLIENT",
+			"status_code": "STATUS_CODE_OK",
+		})
+	}
+
+	// the default buckets
+	assert.Equal(t, 0.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("test-service"), 0.5)))
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("test-service"), 1)))
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("test-service"), math.Inf(1))))
+
+	// the first matching override
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("batch-service"), 10)))
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("batch-service"), 60)))
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("batch-service"), math.Inf(1))))
+	assert.Equal(t, 0.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("batch-service"), 1)))
+	assert.Equal(t, 0.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("batch-service"), 30)))
+
+	// an override matching the service name
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("other-service"), 30)))
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("other-service"), math.Inf(1))))
+	assert.Equal(t, 0.0, testRegistry.Query("traces_spanmetrics_latency_bucket", withLe(lbls("other-service"), 1)))
+	assert.Equal(t, 10.0, testRegistry.Query("traces_spanmetrics_latency_count", lbls("other-service")))
+}
+
 func withLe(lbls labels.Labels, le float64) labels.Labels {
 	lb := labels.NewBuilder(lbls)
 	lb = lb.Set(labels.BucketLabel, strconv.FormatFloat(le, 'f', -1, 64))
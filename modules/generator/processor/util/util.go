@@ -1,6 +1,8 @@
 package util
 
 import (
+	"strconv"
+
 	semconv "go.opentelemetry.io/collector/model/semconv/v1.5.0"
 
 	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
@@ -21,3 +23,45 @@ func FindAttributeValue(key string, attributes ...[]*v1_common.KeyValue) (string
 	}
 	return "", false
 }
+
+// FindNumericAttributeValue returns the value of the first attribute with the key that is an int, a double or a
+// string holding a number.
+func FindNumericAttributeValue(key string, attributes ...[]*v1_common.KeyValue) (float64, bool) {
+	for _, attrs := range attributes {
+		for _, kv := range attrs {
+			if key != kv.Key || kv.Value == nil {
+				continue
+			}
+			switch v := kv.Value.Value.(type) {
+			case *v1_common.AnyValue_IntValue:
+				return float64(v.IntValue), true
+			case *v1_common.AnyValue_DoubleValue:
+				return v.DoubleValue, true
+			case *v1_common.AnyValue_StringValue:
+				f, err := strconv.ParseFloat(v.StringValue, 64)
+				if err == nil {
+					return f, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// FindJob returns the Prometheus job for a resource, i.e. "<service.namespace>/<service.name>", or
+// just the service name if the resource has no namespace.
+func FindJob(attributes []*v1_common.KeyValue) (string, bool) {
+	svcName, ok := FindServiceName(attributes)
+	if !ok {
+		return "", false
+	}
+	if namespace, ok := FindAttributeValue(semconv.AttributeServiceNamespace, attributes); ok && namespace != "" {
+		return namespace + "/" + svcName, true
+	}
+	return svcName, true
+}
+
+// FindInstanceID returns the service.instance.id of a resource.
+func FindInstanceID(attributes []*v1_common.KeyValue) (string, bool) {
+	return FindAttributeValue(semconv.AttributeServiceInstanceID, attributes)
+}
@@ -99,3 +99,52 @@ func TestFindServiceName(t *testing.T) {
 		})
 	}
 }
+
+func TestFindJob(t *testing.T) {
+	stringKV := func(k, v string) *v1_common.KeyValue {
+		return &v1_common.KeyValue{
+			Key:   k,
+			Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_StringValue{StringValue: v}},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		attributes  []*v1_common.KeyValue
+		expectedJob string
+		expectedOk  bool
+	}{
+		{
+			"empty attributes",
+			nil,
+			"",
+			false,
+		},
+		{
+			"service name only",
+			[]*v1_common.KeyValue{stringKV("service.name", "my-service")},
+			"my-service",
+			true,
+		},
+		{
+			"service name and namespace",
+			[]*v1_common.KeyValue{stringKV("service.namespace", "my-namespace"), stringKV("service.name", "my-service")},
+			"my-namespace/my-service",
+			true,
+		},
+		{
+			"namespace without service name",
+			[]*v1_common.KeyValue{stringKV("service.namespace", "my-namespace")},
+			"",
+			false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			job, ok := FindJob(tc.attributes)
+
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedJob, job)
+		})
+	}
+}
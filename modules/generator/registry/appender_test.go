@@ -6,6 +6,7 @@ import (
 
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
 	"github.com/prometheus/prometheus/storage"
 )
 
@@ -45,6 +46,8 @@ type sample struct {
 	l labels.Labels
 	t int64
 	v float64
+	// stale is true for staleness markers, their NaN value is not comparable
+	stale bool
 }
 
 type exemplarSample struct {
@@ -60,6 +63,27 @@ func newSample(lbls map[string]string, t int64, v float64) sample {
 	}
 }
 
+func newStaleSample(lbls map[string]string, t int64) sample {
+	return sample{
+		l:     labels.FromMap(lbls),
+		t:     t,
+		stale: true,
+	}
+}
+
+// initialZeroSamples returns the 0 samples written for new series one ms before their first samples
+func initialZeroSamples(samples []sample) []sample {
+	zeros := make([]sample, 0, len(samples))
+	for _, s := range samples {
+		zeros = append(zeros, sample{
+			l: s.l,
+			t: s.t - 1,
+			v: 0,
+		})
+	}
+	return zeros
+}
+
 func newExemplar(lbls map[string]string, e exemplar.Exemplar) exemplarSample {
 	return exemplarSample{
 		l: labels.FromMap(lbls),
@@ -68,6 +92,9 @@ func newExemplar(lbls map[string]string, e exemplar.Exemplar) exemplarSample {
 }
 
 func (s sample) String() string {
+	if s.stale {
+		return fmt.Sprintf("%s %d stale", s.l, s.t)
+	}
 	return fmt.Sprintf("%s %d %g", s.l, s.t, s.v)
 }
 
@@ -79,7 +106,11 @@ func (c *capturingAppender) Appender(ctx context.Context) storage.Appender {
 }
 
 func (c *capturingAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
-	c.samples = append(c.samples, sample{l, t, v})
+	if value.IsStaleNaN(v) {
+		c.samples = append(c.samples, sample{l: l, t: t, stale: true})
+		return ref, nil
+	}
+	c.samples = append(c.samples, sample{l: l, t: t, v: v})
 	return ref, nil
 }
 
@@ -17,6 +17,8 @@ type counter struct {
 	// seriesMtx is used to sync modifications to the map, not to the data in series
 	seriesMtx sync.RWMutex
 	series    map[uint64]*counterSeries
+	// stale are the series removed since the last collection
+	stale staleSeries
 
 	onAddSeries    func(count uint32) bool
 	onRemoveSeries func(count uint32)
@@ -27,6 +29,8 @@ type counterSeries struct {
 	labelValues []string
 	value       *atomic.Float64
 	lastUpdated *atomic.Int64
+	// collected is false until the series is collected for the first time
+	collected *atomic.Bool
 }
 
 var _ Counter = (*counter)(nil)
@@ -92,6 +96,7 @@ func (c *counter) newSeries(labelValues *LabelValues, value float64) *counterSer
 		labelValues: labelValues.getValuesCopy(),
 		value:       atomic.NewFloat64(value),
 		lastUpdated: atomic.NewInt64(time.Now().UnixMilli()),
+		collected:   atomic.NewBool(false),
 	}
 }
 
@@ -126,6 +131,14 @@ func (c *counter) collectMetrics(appender storage.Appender, timeMs int64, extern
 			lb.Set(name, s.labelValues[i])
 		}
 
+		// a new series starts with a 0 sample so the first increase isn't lost, e.g. after the generator restarted
+		if !s.collected.Swap(true) {
+			_, err = appender.Append(0, lb.Labels(), timeMs-1, 0)
+			if err != nil {
+				return
+			}
+		}
+
 		_, err = appender.Append(0, lb.Labels(), timeMs, s.value.Load())
 		if err != nil {
 			return
@@ -134,6 +147,21 @@ func (c *counter) collectMetrics(appender storage.Appender, timeMs int64, extern
 		// TODO support exemplars
 	}
 
+	for hash, labelValues := range c.stale.take() {
+		// the series was created again since it was removed
+		if _, ok := c.series[hash]; ok {
+			continue
+		}
+		for i, name := range c.labels {
+			lb.Set(name, labelValues[i])
+		}
+
+		_, err = appender.Append(0, lb.Labels(), timeMs, staleMarker)
+		if err != nil {
+			return
+		}
+	}
+
 	return
 }
 
@@ -144,6 +172,7 @@ func (c *counter) removeStaleSeries(staleTimeMs int64) {
 	for hash, s := range c.series {
 		if s.lastUpdated.Load() < staleTimeMs {
 			delete(c.series, hash)
+			c.stale.add(hash, s.labelValues)
 			c.onRemoveSeries(1)
 		}
 	}
@@ -29,6 +29,8 @@ func Test_counter(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-1"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-2"}, collectionTimeMs, 2),
 	}
+	// new series start with a 0 sample
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, c, collectionTimeMs, nil, 2, expectedSamples, nil)
 
 	c.Inc(NewLabelValues([]string{"value-2"}), 2.0)
@@ -41,6 +43,7 @@ func Test_counter(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-1"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-2"}, collectionTimeMs, 4),
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-3"}, collectionTimeMs, 3),
+		newSample(map[string]string{"__name__": "my_counter", "label": "value-3"}, collectionTimeMs-1, 0),
 	}
 	collectMetricAndAssert(t, c, collectionTimeMs, nil, 3, expectedSamples, nil)
 }
@@ -76,6 +79,8 @@ func Test_counter_cantAdd(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-1"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-2"}, collectionTimeMs, 2),
 	}
+	// new series start with a 0 sample
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, c, collectionTimeMs, nil, 2, expectedSamples, nil)
 
 	// block new series - existing series can still be updated
@@ -114,6 +119,8 @@ func Test_counter_removeStaleSeries(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-1"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-2"}, collectionTimeMs, 2),
 	}
+	// new series start with a 0 sample
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, c, collectionTimeMs, nil, 2, expectedSamples, nil)
 
 	time.Sleep(10 * time.Millisecond)
@@ -127,6 +134,13 @@ func Test_counter_removeStaleSeries(t *testing.T) {
 	assert.Equal(t, 1, removedSeries)
 
 	collectionTimeMs = time.Now().UnixMilli()
+	expectedSamples = []sample{
+		newSample(map[string]string{"__name__": "my_counter", "label": "value-2"}, collectionTimeMs, 4),
+		// the removed series is marked stale once
+		newStaleSample(map[string]string{"__name__": "my_counter", "label": "value-1"}, collectionTimeMs),
+	}
+	collectMetricAndAssert(t, c, collectionTimeMs, nil, 1, expectedSamples, nil)
+
 	expectedSamples = []sample{
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-2"}, collectionTimeMs, 4),
 	}
@@ -144,6 +158,8 @@ func Test_counter_externalLabels(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-1", "external_label": "external_value"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-2", "external_label": "external_value"}, collectionTimeMs, 2),
 	}
+	// new series start with a 0 sample
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, c, collectionTimeMs, map[string]string{"external_label": "external_value"}, 2, expectedSamples, nil)
 }
 
@@ -226,6 +242,8 @@ func Test_counter_concurrencyCorrectness(t *testing.T) {
 	expectedSamples := []sample{
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-1"}, collectionTimeMs, float64(totalCount.Load())),
 	}
+	// new series start with a 0 sample
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, c, collectionTimeMs, nil, 1, expectedSamples, nil)
 }
 
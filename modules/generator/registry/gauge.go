@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"go.uber.org/atomic"
+)
+
+type gauge struct {
+	metricName string
+	labels     []string
+
+	// seriesMtx is used to sync modifications to the map, not to the data in series
+	seriesMtx sync.RWMutex
+	series    map[uint64]*gaugeSeries
+	// stale are the series removed since the last collection
+	stale staleSeries
+
+	onAddSeries    func(count uint32) bool
+	onRemoveSeries func(count uint32)
+}
+
+type gaugeSeries struct {
+	// labelValues should not be modified after creation
+	labelValues []string
+	value       *atomic.Float64
+	lastUpdated *atomic.Int64
+}
+
+var _ Gauge = (*gauge)(nil)
+var _ metric = (*gauge)(nil)
+
+func newGauge(name string, labels []string, onAddSeries func(uint32) bool, onRemoveSeries func(count uint32)) *gauge {
+	if onAddSeries == nil {
+		onAddSeries = func(uint32) bool {
+			return true
+		}
+	}
+	if onRemoveSeries == nil {
+		onRemoveSeries = func(uint32) {}
+	}
+
+	return &gauge{
+		metricName:     name,
+		labels:         labels,
+		series:         make(map[uint64]*gaugeSeries),
+		onAddSeries:    onAddSeries,
+		onRemoveSeries: onRemoveSeries,
+	}
+}
+
+func (g *gauge) Set(labelValues *LabelValues, value float64) {
+	if len(g.labels) != len(labelValues.getValues()) {
+		panic(fmt.Sprintf("length of given label values does not match with labels, labels: %v, label values: %v", g.labels, labelValues))
+	}
+
+	hash := labelValues.getHash()
+
+	g.seriesMtx.RLock()
+	s, ok := g.series[hash]
+	g.seriesMtx.RUnlock()
+
+	if ok {
+		g.updateSeries(s, value)
+		return
+	}
+
+	if !g.onAddSeries(1) {
+		return
+	}
+
+	newSeries := g.newSeries(labelValues, value)
+
+	g.seriesMtx.Lock()
+	defer g.seriesMtx.Unlock()
+
+	s, ok = g.series[hash]
+	if ok {
+		g.updateSeries(s, value)
+		return
+	}
+	g.series[hash] = newSeries
+}
+
+func (g *gauge) newSeries(labelValues *LabelValues, value float64) *gaugeSeries {
+	return &gaugeSeries{
+		labelValues: labelValues.getValuesCopy(),
+		value:       atomic.NewFloat64(value),
+		lastUpdated: atomic.NewInt64(time.Now().UnixMilli()),
+	}
+}
+
+func (g *gauge) updateSeries(s *gaugeSeries, value float64) {
+	s.value.Store(value)
+	s.lastUpdated.Store(time.Now().UnixMilli())
+}
+
+func (g *gauge) name() string {
+	return g.metricName
+}
+
+func (g *gauge) collectMetrics(appender storage.Appender, timeMs int64, externalLabels map[string]string) (activeSeries int, err error) {
+	g.seriesMtx.RLock()
+	defer g.seriesMtx.RUnlock()
+
+	activeSeries = len(g.series)
+
+	lbls := make(labels.Labels, 1+len(externalLabels)+len(g.labels))
+	lb := labels.NewBuilder(lbls)
+
+	// set metric name
+	lb.Set(labels.MetricName, g.metricName)
+	// set external labels
+	for name, value := range externalLabels {
+		lb.Set(name, value)
+	}
+
+	for _, s := range g.series {
+		// set series-specific labels
+		for i, name := range g.labels {
+			lb.Set(name, s.labelValues[i])
+		}
+
+		_, err = appender.Append(0, lb.Labels(), timeMs, s.value.Load())
+		if err != nil {
+			return
+		}
+	}
+
+	for hash, labelValues := range g.stale.take() {
+		// the series was created again since it was removed
+		if _, ok := g.series[hash]; ok {
+			continue
+		}
+		for i, name := range g.labels {
+			lb.Set(name, labelValues[i])
+		}
+
+		_, err = appender.Append(0, lb.Labels(), timeMs, staleMarker)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (g *gauge) removeStaleSeries(staleTimeMs int64) {
+	g.seriesMtx.Lock()
+	defer g.seriesMtx.Unlock()
+
+	for hash, s := range g.series {
+		if s.lastUpdated.Load() < staleTimeMs {
+			delete(g.series, hash)
+			g.stale.add(hash, s.labelValues)
+			g.onRemoveSeries(1)
+		}
+	}
+}
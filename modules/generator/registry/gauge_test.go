@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_gauge(t *testing.T) {
+	var seriesAdded int
+	onAdd := func(count uint32) bool {
+		seriesAdded++
+		return true
+	}
+
+	g := newGauge("my_gauge", []string{"label"}, onAdd, nil)
+
+	g.Set(NewLabelValues([]string{"value-1"}), 1.0)
+	g.Set(NewLabelValues([]string{"value-2"}), 2.0)
+
+	assert.Equal(t, 2, seriesAdded)
+
+	collectionTimeMs := time.Now().UnixMilli()
+	expectedSamples := []sample{
+		newSample(map[string]string{"__name__": "my_gauge", "label": "value-1"}, collectionTimeMs, 1),
+		newSample(map[string]string{"__name__": "my_gauge", "label": "value-2"}, collectionTimeMs, 2),
+	}
+	collectMetricAndAssert(t, g, collectionTimeMs, nil, 2, expectedSamples, nil)
+
+	g.Set(NewLabelValues([]string{"value-2"}), 1.0)
+	g.Set(NewLabelValues([]string{"value-3"}), 3.0)
+
+	assert.Equal(t, 3, seriesAdded)
+
+	collectionTimeMs = time.Now().UnixMilli()
+	expectedSamples = []sample{
+		newSample(map[string]string{"__name__": "my_gauge", "label": "value-1"}, collectionTimeMs, 1),
+		newSample(map[string]string{"__name__": "my_gauge", "label": "value-2"}, collectionTimeMs, 1),
+		newSample(map[string]string{"__name__": "my_gauge", "label": "value-3"}, collectionTimeMs, 3),
+	}
+	collectMetricAndAssert(t, g, collectionTimeMs, nil, 3, expectedSamples, nil)
+}
+
+func Test_gauge_invalidLabelValues(t *testing.T) {
+	g := newGauge("my_gauge", []string{"label"}, nil, nil)
+
+	assert.Panics(t, func() {
+		g.Set(nil, 1.0)
+	})
+	assert.Panics(t, func() {
+		g.Set(NewLabelValues([]string{"value-1", "value-2"}), 1.0)
+	})
+}
+
+func Test_gauge_removeStaleSeries(t *testing.T) {
+	var removedSeries int
+	onRemove := func(count uint32) {
+		assert.Equal(t, uint32(1), count)
+		removedSeries++
+	}
+
+	g := newGauge("my_gauge", []string{"label"}, nil, onRemove)
+
+	g.Set(NewLabelValues([]string{"value-1"}), 1.0)
+	g.Set(NewLabelValues([]string{"value-2"}), 2.0)
+
+	time.Sleep(10 * time.Millisecond)
+	timeMs := time.Now().UnixMilli()
+
+	// update value-2 series
+	g.Set(NewLabelValues([]string{"value-2"}), 3.0)
+
+	g.removeStaleSeries(timeMs)
+
+	assert.Equal(t, 1, removedSeries)
+
+	collectionTimeMs := time.Now().UnixMilli()
+	expectedSamples := []sample{
+		newSample(map[string]string{"__name__": "my_gauge", "label": "value-2"}, collectionTimeMs, 3),
+		newStaleSample(map[string]string{"__name__": "my_gauge", "label": "value-1"}, collectionTimeMs),
+	}
+	collectMetricAndAssert(t, g, collectionTimeMs, nil, 1, expectedSamples, nil)
+}
@@ -15,17 +15,21 @@ import (
 )
 
 type histogram struct {
-	metricName   string
-	nameCount    string
-	nameSum      string
-	nameBucket   string
-	labels       []string
-	buckets      []float64
-	bucketLabels []string
+	metricName string
+	nameCount  string
+	nameSum    string
+	nameBucket string
+	labels     []string
+	buckets    *Buckets
 
 	// seriesMtx is used to sync modifications to the map, not to the data in series
 	seriesMtx sync.RWMutex
 	series    map[uint64]*histogramSeries
+	// stale are the series removed since the last collection
+	stale staleSeries
+	// staleBuckets are the layouts of stale series with buckets other than the histogram's
+	staleBucketsMtx sync.Mutex
+	staleBuckets    map[uint64]*Buckets
 
 	onAddSerie    func(count uint32) bool
 	onRemoveSerie func(count uint32)
@@ -36,12 +40,43 @@ type histogramSeries struct {
 	labelValues []string
 	count       *atomic.Float64
 	sum         *atomic.Float64
+	// bucketLayout is the layout of buckets, it is set when the series is created
+	bucketLayout *Buckets
 	// buckets includes the +Inf bucket
 	buckets []*atomic.Float64
 	// exemplar is stored as a single traceID
 	exemplars      []*atomic.String
 	exemplarValues []*atomic.Float64
 	lastUpdated    *atomic.Int64
+	// collected is false until the series is collected for the first time
+	collected *atomic.Bool
+}
+
+// Buckets is a bucket layout of histogram series, the +Inf bucket is added implicitly
+type Buckets struct {
+	bounds []float64
+	labels []string
+}
+
+// NewBuckets creates a bucket layout from the upper bounds of the buckets. Layouts should be created once and
+// reused for all observations.
+func NewBuckets(bounds []float64) *Buckets {
+	b := &Buckets{
+		bounds: make([]float64, 0, len(bounds)+1),
+		labels: make([]string, 0, len(bounds)+1),
+	}
+	b.bounds = append(b.bounds, bounds...)
+	b.bounds = append(b.bounds, math.Inf(1))
+	for _, bound := range b.bounds {
+		b.labels = append(b.labels, formatFloat(bound))
+	}
+	return b
+}
+
+// activeSeries is the number of active series of a histogram series with this layout
+func (b *Buckets) activeSeries() uint32 {
+	// sum + count + #buckets
+	return uint32(2 + len(b.bounds))
 }
 
 var _ Histogram = (*histogram)(nil)
@@ -57,29 +92,24 @@ func newHistogram(name string, labels []string, buckets []float64, onAddSeries f
 		onRemoveSeries = func(uint32) {}
 	}
 
-	// add +Inf bucket
-	buckets = append(buckets, math.Inf(1))
-
-	bucketLabels := make([]string, len(buckets))
-	for i, bucket := range buckets {
-		bucketLabels[i] = formatFloat(bucket)
-	}
-
 	return &histogram{
 		metricName:    name,
 		nameCount:     fmt.Sprintf("%s_count", name),
 		nameSum:       fmt.Sprintf("%s_sum", name),
 		nameBucket:    fmt.Sprintf("%s_bucket", name),
 		labels:        labels,
-		buckets:       buckets,
-		bucketLabels:  bucketLabels,
+		buckets:       NewBuckets(buckets),
 		series:        make(map[uint64]*histogramSeries),
 		onAddSerie:    onAddSeries,
 		onRemoveSerie: onRemoveSeries,
 	}
 }
 
-func (h *histogram) ObserveWithExemplar(labelValues *LabelValues, value float64, traceID string) {
+func (h *histogram) ObserveWithExemplar(labelValues *LabelValues, value float64, traceID string, multiplier float64) {
+	h.ObserveWithExemplarAndBuckets(labelValues, value, traceID, multiplier, nil)
+}
+
+func (h *histogram) ObserveWithExemplarAndBuckets(labelValues *LabelValues, value float64, traceID string, multiplier float64, buckets *Buckets) {
 	if len(h.labels) != len(labelValues.getValues()) {
 		panic(fmt.Sprintf("length of given label values does not match with labels, labels: %v, label values: %v", h.labels, labelValues))
 	}
@@ -91,58 +121,65 @@ func (h *histogram) ObserveWithExemplar(labelValues *LabelValues, value float64,
 	h.seriesMtx.RUnlock()
 
 	if ok {
-		h.updateSeries(s, value, traceID)
+		h.updateSeries(s, value, traceID, multiplier)
 		return
 	}
 
-	if !h.onAddSerie(h.activeSeriesPerHistogramSerie()) {
+	if buckets == nil {
+		buckets = h.buckets
+	}
+
+	if !h.onAddSerie(buckets.activeSeries()) {
 		return
 	}
 
-	newSeries := h.newSeries(labelValues, value, traceID)
+	newSeries := h.newSeries(labelValues, buckets, value, traceID, multiplier)
 
 	h.seriesMtx.Lock()
 	defer h.seriesMtx.Unlock()
 
 	s, ok = h.series[hash]
 	if ok {
-		h.updateSeries(s, value, traceID)
+		h.updateSeries(s, value, traceID, multiplier)
 		return
 	}
 	h.series[hash] = newSeries
 }
 
-func (h *histogram) newSeries(labelValues *LabelValues, value float64, traceID string) *histogramSeries {
+func (h *histogram) newSeries(labelValues *LabelValues, buckets *Buckets, value float64, traceID string, multiplier float64) *histogramSeries {
 	newSeries := &histogramSeries{
-		labelValues: labelValues.getValuesCopy(),
-		count:       atomic.NewFloat64(0),
-		sum:         atomic.NewFloat64(0),
-		buckets:     nil,
-		exemplars:   nil,
-		lastUpdated: atomic.NewInt64(0),
-	}
-	for i := 0; i < len(h.buckets); i++ {
+		labelValues:  labelValues.getValuesCopy(),
+		count:        atomic.NewFloat64(0),
+		sum:          atomic.NewFloat64(0),
+		bucketLayout: buckets,
+		buckets:      nil,
+		exemplars:    nil,
+		lastUpdated:  atomic.NewInt64(0),
+		collected:    atomic.NewBool(false),
+	}
+	for i := 0; i < len(buckets.bounds); i++ {
 		newSeries.buckets = append(newSeries.buckets, atomic.NewFloat64(0))
 		newSeries.exemplars = append(newSeries.exemplars, atomic.NewString(""))
 		newSeries.exemplarValues = append(newSeries.exemplarValues, atomic.NewFloat64(0))
 	}
 
-	h.updateSeries(newSeries, value, traceID)
+	h.updateSeries(newSeries, value, traceID, multiplier)
 
 	return newSeries
 }
 
-func (h *histogram) updateSeries(s *histogramSeries, value float64, traceID string) {
-	s.count.Add(1)
-	s.sum.Add(value)
+func (h *histogram) updateSeries(s *histogramSeries, value float64, traceID string, multiplier float64) {
+	s.count.Add(multiplier)
+	s.sum.Add(value * multiplier)
 
-	for i, bucket := range h.buckets {
+	bounds := s.bucketLayout.bounds
+	for i, bucket := range bounds {
 		if value <= bucket {
-			s.buckets[i].Add(1)
+			s.buckets[i].Add(multiplier)
 		}
 	}
 
-	bucket := sort.SearchFloat64s(h.buckets, value)
+	bucket := sort.SearchFloat64s(bounds, value)
 	s.exemplars[bucket].Store(traceID)
 	s.exemplarValues[bucket].Store(value)
 
@@ -157,7 +194,9 @@ func (h *histogram) collectMetrics(appender storage.Appender, timeMs int64, exte
 	h.seriesMtx.RLock()
 	defer h.seriesMtx.RUnlock()
 
-	activeSeries = len(h.series) * int(h.activeSeriesPerHistogramSerie())
+	for _, s := range h.series {
+		activeSeries += int(s.bucketLayout.activeSeries())
+	}
 
 	lbls := make(labels.Labels, 1+len(externalLabels)+len(h.labels)+1)
 	lb := labels.NewBuilder(lbls)
@@ -173,6 +212,14 @@ func (h *histogram) collectMetrics(appender storage.Appender, timeMs int64, exte
 			lb.Set(name, s.labelValues[i])
 		}
 
+		// a new series starts with 0 samples so the first observations aren't lost, e.g. after the generator restarted
+		if !s.collected.Swap(true) {
+			err = h.appendAll(appender, lb, s.bucketLayout, timeMs-1, 0)
+			if err != nil {
+				return
+			}
+		}
+
 		// sum
 		lb.Set(labels.MetricName, h.nameSum)
 		_, err = appender.Append(0, lb.Labels(), timeMs, s.sum.Load())
@@ -190,7 +237,7 @@ func (h *histogram) collectMetrics(appender storage.Appender, timeMs int64, exte
 		// bucket
 		lb.Set(labels.MetricName, h.nameBucket)
 
-		for i, bucketLabel := range h.bucketLabels {
+		for i, bucketLabel := range s.bucketLayout.labels {
 			lb.Set(labels.BucketLabel, bucketLabel)
 			ref, err := appender.Append(0, lb.Labels(), timeMs, s.buckets[i].Load())
 			if err != nil {
@@ -218,9 +265,61 @@ func (h *histogram) collectMetrics(appender storage.Appender, timeMs int64, exte
 		lb.Del(labels.BucketLabel)
 	}
 
+	h.staleBucketsMtx.Lock()
+	staleBuckets := h.staleBuckets
+	h.staleBuckets = nil
+	h.staleBucketsMtx.Unlock()
+
+	for hash, labelValues := range h.stale.take() {
+		// the series was created again since it was removed
+		if _, ok := h.series[hash]; ok {
+			continue
+		}
+		for i, name := range h.labels {
+			lb.Set(name, labelValues[i])
+		}
+
+		buckets, ok := staleBuckets[hash]
+		if !ok {
+			buckets = h.buckets
+		}
+		err = h.appendAll(appender, lb, buckets, timeMs, staleMarker)
+		if err != nil {
+			return
+		}
+	}
+
 	return
 }
 
+// appendAll appends the same value to the sum, count and bucket series of a histogram series. The series specific
+// labels must be set in lb.
+func (h *histogram) appendAll(appender storage.Appender, lb *labels.Builder, buckets *Buckets, timeMs int64, value float64) error {
+	lb.Set(labels.MetricName, h.nameSum)
+	_, err := appender.Append(0, lb.Labels(), timeMs, value)
+	if err != nil {
+		return err
+	}
+
+	lb.Set(labels.MetricName, h.nameCount)
+	_, err = appender.Append(0, lb.Labels(), timeMs, value)
+	if err != nil {
+		return err
+	}
+
+	lb.Set(labels.MetricName, h.nameBucket)
+	for _, bucketLabel := range buckets.labels {
+		lb.Set(labels.BucketLabel, bucketLabel)
+		_, err = appender.Append(0, lb.Labels(), timeMs, value)
+		if err != nil {
+			return err
+		}
+	}
+	lb.Del(labels.BucketLabel)
+
+	return nil
+}
+
 func (h *histogram) removeStaleSeries(staleTimeMs int64) {
 	h.seriesMtx.Lock()
 	defer h.seriesMtx.Unlock()
@@ -228,14 +327,24 @@ func (h *histogram) removeStaleSeries(staleTimeMs int64) {
 	for hash, s := range h.series {
 		if s.lastUpdated.Load() < staleTimeMs {
 			delete(h.series, hash)
-			h.onRemoveSerie(h.activeSeriesPerHistogramSerie())
+			h.stale.add(hash, s.labelValues)
+			if s.bucketLayout != h.buckets {
+				h.addStaleBuckets(hash, s.bucketLayout)
+			}
+			h.onRemoveSerie(s.bucketLayout.activeSeries())
 		}
 	}
 }
 
-func (h *histogram) activeSeriesPerHistogramSerie() uint32 {
-	// sum + count + #buckets
-	return uint32(2 + len(h.buckets))
+// addStaleBuckets records the layout of a removed series, so its staleness markers are appended for its buckets
+func (h *histogram) addStaleBuckets(hash uint64, buckets *Buckets) {
+	h.staleBucketsMtx.Lock()
+	defer h.staleBucketsMtx.Unlock()
+
+	if h.staleBuckets == nil {
+		h.staleBuckets = map[uint64]*Buckets{}
+	}
+	h.staleBuckets[hash] = buckets
 }
 
 func formatFloat(value float64) string {
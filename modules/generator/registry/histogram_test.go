@@ -21,8 +21,8 @@ func Test_histogram(t *testing.T) {
 
 	h := newHistogram("my_histogram", []string{"label"}, []float64{1.0, 2.0}, onAdd, nil)
 
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "trace-1")
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "trace-2")
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "trace-1", 1.0)
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "trace-2", 1.0)
 
 	assert.Equal(t, 2, seriesAdded)
 
@@ -51,10 +51,12 @@ func Test_histogram(t *testing.T) {
 			Ts:     collectionTimeMs,
 		}),
 	}
+	// new series start with 0 samples
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, h, collectionTimeMs, nil, 10, expectedSamples, expectedExemplars)
 
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 2.5, "trace-2.2")
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-3"}), 3.0, "trace-3")
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 2.5, "trace-2.2", 1.0)
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-3"}), 3.0, "trace-3", 1.0)
 
 	assert.Equal(t, 3, seriesAdded)
 
@@ -88,6 +90,8 @@ func Test_histogram(t *testing.T) {
 			Ts:     collectionTimeMs,
 		}),
 	}
+	// value-3 is a new series
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples[10:])...)
 	collectMetricAndAssert(t, h, collectionTimeMs, nil, 15, expectedSamples, expectedExemplars)
 }
 
@@ -95,10 +99,10 @@ func Test_histogram_invalidLabelValues(t *testing.T) {
 	h := newHistogram("my_histogram", []string{"label"}, []float64{1.0, 2.0}, nil, nil)
 
 	assert.Panics(t, func() {
-		h.ObserveWithExemplar(nil, 1.0, "")
+		h.ObserveWithExemplar(nil, 1.0, "", 1.0)
 	})
 	assert.Panics(t, func() {
-		h.ObserveWithExemplar(NewLabelValues([]string{"value-1", "value-2"}), 1.0, "")
+		h.ObserveWithExemplar(NewLabelValues([]string{"value-1", "value-2"}), 1.0, "", 1.0)
 	})
 }
 
@@ -114,8 +118,8 @@ func Test_histogram_cantAdd(t *testing.T) {
 	// allow adding new series
 	canAdd = true
 
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "")
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "")
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "", 1.0)
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "", 1.0)
 
 	collectionTimeMs := time.Now().UnixMilli()
 	expectedSamples := []sample{
@@ -130,13 +134,15 @@ func Test_histogram_cantAdd(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "2"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "+Inf"}, collectionTimeMs, 1),
 	}
+	// new series start with 0 samples
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, h, collectionTimeMs, nil, 10, expectedSamples, nil)
 
 	// block new series - existing series can still be updated
 	canAdd = false
 
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 2.5, "")
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-3"}), 3.0, "")
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 2.5, "", 1.0)
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-3"}), 3.0, "", 1.0)
 
 	collectionTimeMs = time.Now().UnixMilli()
 	expectedSamples = []sample{
@@ -164,8 +170,8 @@ func Test_histogram_removeStaleSeries(t *testing.T) {
 	h := newHistogram("my_histogram", []string{"label"}, []float64{1.0, 2.0}, nil, onRemove)
 
 	timeMs := time.Now().UnixMilli()
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "")
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "")
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "", 1.0)
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "", 1.0)
 
 	h.removeStaleSeries(timeMs)
 
@@ -184,13 +190,15 @@ func Test_histogram_removeStaleSeries(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "2"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "+Inf"}, collectionTimeMs, 1),
 	}
+	// new series start with 0 samples
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, h, collectionTimeMs, nil, 10, expectedSamples, nil)
 
 	time.Sleep(10 * time.Millisecond)
 	timeMs = time.Now().UnixMilli()
 
 	// update value-2 series
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 2.5, "")
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 2.5, "", 1.0)
 
 	h.removeStaleSeries(timeMs)
 
@@ -204,14 +212,79 @@ func Test_histogram_removeStaleSeries(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "2"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "+Inf"}, collectionTimeMs, 2),
 	}
+	// the removed series is marked stale
+	expectedSamples = append(expectedSamples,
+		newStaleSample(map[string]string{"__name__": "my_histogram_count", "label": "value-1"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_sum", "label": "value-1"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "1"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "2"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "+Inf"}, collectionTimeMs),
+	)
 	collectMetricAndAssert(t, h, collectionTimeMs, nil, 5, expectedSamples, nil)
 }
 
+func Test_histogram_buckets(t *testing.T) {
+	var addedSeries, removedSeries []uint32
+	onAdd := func(count uint32) bool {
+		addedSeries = append(addedSeries, count)
+		return true
+	}
+	onRemove := func(count uint32) {
+		removedSeries = append(removedSeries, count)
+	}
+
+	h := newHistogram("my_histogram", []string{"label"}, []float64{1.0, 2.0}, onAdd, onRemove)
+	buckets := NewBuckets([]float64{60})
+
+	timeMs := time.Now().UnixMilli()
+	h.ObserveWithExemplarAndBuckets(NewLabelValues([]string{"value-1"}), 1.5, "", 1.0, nil)
+	h.ObserveWithExemplarAndBuckets(NewLabelValues([]string{"value-2"}), 30, "", 1.0, buckets)
+	// existing series keep their buckets
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 90, "", 1.0)
+
+	assert.Equal(t, []uint32{5, 4}, addedSeries)
+
+	collectionTimeMs := time.Now().UnixMilli()
+	expectedSamples := []sample{
+		newSample(map[string]string{"__name__": "my_histogram_count", "label": "value-1"}, collectionTimeMs, 1),
+		newSample(map[string]string{"__name__": "my_histogram_sum", "label": "value-1"}, collectionTimeMs, 1.5),
+		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "1"}, collectionTimeMs, 0),
+		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "2"}, collectionTimeMs, 1),
+		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "+Inf"}, collectionTimeMs, 1),
+		newSample(map[string]string{"__name__": "my_histogram_count", "label": "value-2"}, collectionTimeMs, 2),
+		newSample(map[string]string{"__name__": "my_histogram_sum", "label": "value-2"}, collectionTimeMs, 120),
+		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "60"}, collectionTimeMs, 1),
+		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "+Inf"}, collectionTimeMs, 2),
+	}
+	// new series start with 0 samples
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
+	collectMetricAndAssert(t, h, collectionTimeMs, nil, 9, expectedSamples, nil)
+
+	h.removeStaleSeries(timeMs + 1000)
+
+	assert.ElementsMatch(t, []uint32{5, 4}, removedSeries)
+
+	// stale series are marked stale for the buckets they had
+	collectionTimeMs = time.Now().UnixMilli()
+	expectedSamples = []sample{
+		newStaleSample(map[string]string{"__name__": "my_histogram_count", "label": "value-1"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_sum", "label": "value-1"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "1"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "2"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "+Inf"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_count", "label": "value-2"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_sum", "label": "value-2"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "60"}, collectionTimeMs),
+		newStaleSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "+Inf"}, collectionTimeMs),
+	}
+	collectMetricAndAssert(t, h, collectionTimeMs, nil, 0, expectedSamples, nil)
+}
+
 func Test_histogram_externalLabels(t *testing.T) {
 	h := newHistogram("my_histogram", []string{"label"}, []float64{1.0, 2.0}, nil, nil)
 
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "")
-	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "")
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "", 1.0)
+	h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "", 1.0)
 
 	collectionTimeMs := time.Now().UnixMilli()
 	expectedSamples := []sample{
@@ -226,6 +299,8 @@ func Test_histogram_externalLabels(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "2", "external_label": "external_value"}, collectionTimeMs, 1),
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-2", "le": "+Inf", "external_label": "external_value"}, collectionTimeMs, 1),
 	}
+	// new series start with 0 samples
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, h, collectionTimeMs, map[string]string{"external_label": "external_value"}, 10, expectedSamples, nil)
 }
 
@@ -247,8 +322,8 @@ func Test_histogram_concurrencyDataRace(t *testing.T) {
 
 	for i := 0; i < 4; i++ {
 		go accessor(func() {
-			h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "")
-			h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "")
+			h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "", 1.0)
+			h.ObserveWithExemplar(NewLabelValues([]string{"value-2"}), 1.5, "", 1.0)
 		})
 	}
 
@@ -259,7 +334,7 @@ func Test_histogram_concurrencyDataRace(t *testing.T) {
 		for i := range s {
 			s[i] = letters[rand.Intn(len(letters))]
 		}
-		h.ObserveWithExemplar(NewLabelValues([]string{string(s)}), 1.0, "")
+		h.ObserveWithExemplar(NewLabelValues([]string{string(s)}), 1.0, "", 1.0)
 	})
 
 	go accessor(func() {
@@ -292,7 +367,7 @@ func Test_histogram_concurrencyCorrectness(t *testing.T) {
 				case <-end:
 					return
 				default:
-					h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 2.0, "")
+					h.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 2.0, "", 1.0)
 					totalCount.Inc()
 				}
 			}
@@ -312,5 +387,7 @@ func Test_histogram_concurrencyCorrectness(t *testing.T) {
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "2"}, collectionTimeMs, float64(totalCount.Load())),
 		newSample(map[string]string{"__name__": "my_histogram_bucket", "label": "value-1", "le": "+Inf"}, collectionTimeMs, float64(totalCount.Load())),
 	}
+	// new series start with 0 samples
+	expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
 	collectMetricAndAssert(t, h, collectionTimeMs, nil, 5, expectedSamples, nil)
 }
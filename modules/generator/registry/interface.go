@@ -4,6 +4,7 @@ package registry
 type Registry interface {
 	NewCounter(name string, labels []string) Counter
 	NewHistogram(name string, labels []string, buckets []float64) Histogram
+	NewGauge(name string, labels []string) Gauge
 }
 
 // Counter
@@ -12,11 +13,22 @@ type Counter interface {
 	Inc(values *LabelValues, value float64)
 }
 
+// Gauge
+// https://prometheus.io/docs/concepts/metric_types/#gauge
+type Gauge interface {
+	Set(values *LabelValues, value float64)
+}
+
 // Histogram
 // https://prometheus.io/docs/concepts/metric_types/#histogram
 type Histogram interface {
 	// ObserveWithExemplar observes a datapoint with the given values. traceID will be added as exemplar.
-	ObserveWithExemplar(values *LabelValues, value float64, traceID string)
+	// The datapoint is counted multiplier times, e.g. for a sampled span representing several spans.
+	ObserveWithExemplar(values *LabelValues, value float64, traceID string, multiplier float64)
+	// ObserveWithExemplarAndBuckets observes a datapoint like ObserveWithExemplar. New series use the given buckets
+	// instead of the buckets of the histogram, existing series keep the buckets they were created with. The buckets
+	// of the histogram are used if buckets is nil.
+	ObserveWithExemplarAndBuckets(values *LabelValues, value float64, traceID string, multiplier float64, buckets *Buckets)
 }
 
 // LabelValues is a wrapper around a slice of label values. It has the ability to cache the hash of
@@ -2,6 +2,7 @@ package registry
 
 import (
 	"context"
+	"math"
 	"os"
 	"sync"
 	"time"
@@ -137,6 +138,12 @@ func (r *ManagedRegistry) NewHistogram(name string, labels []string, buckets []f
 	return h
 }
 
+func (r *ManagedRegistry) NewGauge(name string, labels []string) Gauge {
+	g := newGauge(name, labels, r.onAddMetricSeries, r.onRemoveMetricSeries)
+	r.registerMetric(g)
+	return g
+}
+
 func (r *ManagedRegistry) registerMetric(m metric) {
 	r.metricsMtx.Lock()
 	defer r.metricsMtx.Unlock()
@@ -235,7 +242,17 @@ func (r *ManagedRegistry) removeStaleSeries(_ context.Context) {
 	level.Info(r.logger).Log("msg", "deleted stale series", "active_series", r.activeSeries.Load())
 }
 
+// Close stops the registry. All series are removed and a last collection writes staleness markers for them, so
+// the series of an inactive tenant end instead of extending their last value.
 func (r *ManagedRegistry) Close() {
 	level.Info(r.logger).Log("msg", "closing registry")
 	r.onShutdown()
+
+	r.metricsMtx.RLock()
+	for _, m := range r.metrics {
+		m.removeStaleSeries(math.MaxInt64)
+	}
+	r.metricsMtx.RUnlock()
+
+	r.collectMetrics(context.Background())
 }
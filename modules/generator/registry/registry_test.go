@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestManagedRegistry_concurrency(t *testing.T) {
@@ -66,7 +68,24 @@ func TestManagedRegistry_counter(t *testing.T) {
 	expectedSamples := []sample{
 		newSample(map[string]string{"__name__": "my_counter", "label": "value-1", "__metrics_gen_instance": mustGetHostname()}, 0, 1.0),
 	}
-	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples)
+	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples, true)
+}
+
+func TestManagedRegistry_gauge(t *testing.T) {
+	appender := &capturingAppender{}
+
+	registry := New(&Config{}, &mockOverrides{}, "test", appender, log.NewNopLogger())
+	defer registry.Close()
+
+	gauge := registry.NewGauge("my_gauge", []string{"label"})
+
+	gauge.Set(NewLabelValues([]string{"value-1"}), 2.0)
+	gauge.Set(NewLabelValues([]string{"value-1"}), 1.0)
+
+	expectedSamples := []sample{
+		newSample(map[string]string{"__name__": "my_gauge", "label": "value-1", "__metrics_gen_instance": mustGetHostname()}, 0, 1.0),
+	}
+	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples, false)
 }
 
 func TestManagedRegistry_histogram(t *testing.T) {
@@ -77,7 +96,7 @@ func TestManagedRegistry_histogram(t *testing.T) {
 
 	histogram := registry.NewHistogram("histogram", []string{"label"}, []float64{1.0, 2.0})
 
-	histogram.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "")
+	histogram.ObserveWithExemplar(NewLabelValues([]string{"value-1"}), 1.0, "", 1.0)
 
 	expectedSamples := []sample{
 		newSample(map[string]string{"__name__": "histogram_count", "label": "value-1", "__metrics_gen_instance": mustGetHostname()}, 0, 1.0),
@@ -86,7 +105,7 @@ func TestManagedRegistry_histogram(t *testing.T) {
 		newSample(map[string]string{"__name__": "histogram_bucket", "label": "value-1", "__metrics_gen_instance": mustGetHostname(), "le": "2"}, 0, 1.0),
 		newSample(map[string]string{"__name__": "histogram_bucket", "label": "value-1", "__metrics_gen_instance": mustGetHostname(), "le": "+Inf"}, 0, 1.0),
 	}
-	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples)
+	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples, true)
 }
 
 func TestManagedRegistry_removeStaleSeries(t *testing.T) {
@@ -110,7 +129,7 @@ func TestManagedRegistry_removeStaleSeries(t *testing.T) {
 		newSample(map[string]string{"__name__": "metric_1", "__metrics_gen_instance": mustGetHostname()}, 0, 1),
 		newSample(map[string]string{"__name__": "metric_2", "__metrics_gen_instance": mustGetHostname()}, 0, 2),
 	}
-	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples)
+	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples, true)
 
 	appender.samples = nil
 
@@ -122,8 +141,9 @@ func TestManagedRegistry_removeStaleSeries(t *testing.T) {
 
 	expectedSamples = []sample{
 		newSample(map[string]string{"__name__": "metric_2", "__metrics_gen_instance": mustGetHostname()}, 0, 4),
+		newStaleSample(map[string]string{"__name__": "metric_1", "__metrics_gen_instance": mustGetHostname()}, 0),
 	}
-	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples)
+	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples, false)
 }
 
 func TestManagedRegistry_externalLabels(t *testing.T) {
@@ -143,7 +163,7 @@ func TestManagedRegistry_externalLabels(t *testing.T) {
 	expectedSamples := []sample{
 		newSample(map[string]string{"__name__": "my_counter", "__metrics_gen_instance": mustGetHostname(), "foo": "bar"}, 0, 1),
 	}
-	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples)
+	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples, true)
 }
 
 func TestManagedRegistry_maxSeries(t *testing.T) {
@@ -167,7 +187,7 @@ func TestManagedRegistry_maxSeries(t *testing.T) {
 	expectedSamples := []sample{
 		newSample(map[string]string{"__name__": "metric_1", "label": "value-1", "__metrics_gen_instance": mustGetHostname()}, 0, 1),
 	}
-	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples)
+	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples, true)
 }
 
 func TestManagedRegistry_disableCollection(t *testing.T) {
@@ -190,8 +210,38 @@ func TestManagedRegistry_disableCollection(t *testing.T) {
 	assert.Empty(t, appender.exemplars)
 }
 
-func collectRegistryMetricsAndAssert(t *testing.T, r *ManagedRegistry, appender *capturingAppender, expectedSamples []sample) {
-	assert.Equal(t, uint32(len(expectedSamples)), r.activeSeries.Load())
+// collectRegistryMetricsAndAssert collects the metrics of the registry and compares them with expectedSamples. If
+// newSeries is true the series are collected for the first time and start with a 0 sample.
+func TestManagedRegistry_closeMarksSeriesStale(t *testing.T) {
+	appender := &capturingAppender{}
+
+	registry := New(&Config{}, &mockOverrides{}, "test", appender, log.NewNopLogger())
+
+	counter := registry.NewCounter("my_counter", []string{"label"})
+	counter.Inc(NewLabelValues([]string{"value-1"}), 1.0)
+
+	expectedSamples := []sample{
+		newSample(map[string]string{"__name__": "my_counter", "label": "value-1", "__metrics_gen_instance": mustGetHostname()}, 0, 1.0),
+	}
+	collectRegistryMetricsAndAssert(t, registry, appender, expectedSamples, true)
+
+	appender.samples = nil
+	registry.Close()
+
+	assert.Equal(t, uint32(0), registry.activeSeries.Load())
+	require.Len(t, appender.samples, 1)
+	assert.True(t, appender.samples[0].stale)
+	assert.Equal(t, labels.FromMap(map[string]string{"__name__": "my_counter", "label": "value-1", "__metrics_gen_instance": mustGetHostname()}), appender.samples[0].l)
+}
+
+func collectRegistryMetricsAndAssert(t *testing.T, r *ManagedRegistry, appender *capturingAppender, expectedSamples []sample, newSeries bool) {
+	activeSeries := 0
+	for _, s := range expectedSamples {
+		if !s.stale {
+			activeSeries++
+		}
+	}
+	assert.Equal(t, uint32(activeSeries), r.activeSeries.Load())
 
 	collectionTimeMs := time.Now().UnixMilli()
 	r.collectMetrics(context.Background())
@@ -199,6 +249,9 @@ func collectRegistryMetricsAndAssert(t *testing.T, r *ManagedRegistry, appender
 	for i := range expectedSamples {
 		expectedSamples[i].t = collectionTimeMs
 	}
+	if newSeries {
+		expectedSamples = append(expectedSamples, initialZeroSamples(expectedSamples)...)
+	}
 
 	assert.Equal(t, true, appender.isCommitted)
 	assert.Equal(t, false, appender.isRolledback)
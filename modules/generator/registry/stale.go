@@ -0,0 +1,39 @@
+package registry
+
+import (
+	"math"
+	"sync"
+
+	"github.com/prometheus/prometheus/model/value"
+)
+
+// staleMarker is the sample value that marks a series as stale in Prometheus. Queries stop returning the series
+// after the marker instead of extending its last value over the lookback window.
+var staleMarker = math.Float64frombits(value.StaleNaN)
+
+// staleSeries holds removed series until a staleness marker is collected for them
+type staleSeries struct {
+	mtx    sync.Mutex
+	series map[uint64][]string
+}
+
+// add records the label values of a removed series
+func (s *staleSeries) add(hash uint64, labelValues []string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.series == nil {
+		s.series = map[uint64][]string{}
+	}
+	s.series[hash] = labelValues
+}
+
+// take returns the series removed since the last call
+func (s *staleSeries) take() map[uint64][]string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	series := s.series
+	s.series = nil
+	return series
+}
@@ -2,7 +2,6 @@ package registry
 
 import (
 	"fmt"
-	"math"
 	"sort"
 	"strings"
 
@@ -42,6 +41,14 @@ func (t *TestRegistry) NewHistogram(name string, labels []string, buckets []floa
 	}
 }
 
+func (t *TestRegistry) NewGauge(name string, labels []string) Gauge {
+	return &testGauge{
+		name:     name,
+		labels:   labels,
+		registry: t,
+	}
+}
+
 func (t *TestRegistry) addToMetric(name string, lbls labels.Labels, value float64) {
 	if t == nil || t.metrics == nil {
 		return
@@ -49,6 +56,13 @@ func (t *TestRegistry) addToMetric(name string, lbls labels.Labels, value float6
 	t.metrics[name+lbls.String()] += value
 }
 
+func (t *TestRegistry) setMetric(name string, lbls labels.Labels, value float64) {
+	if t == nil || t.metrics == nil {
+		return
+	}
+	t.metrics[name+lbls.String()] = value
+}
+
 // Query returns the value of the given metric. Note this is a rather naive query engine, it's only
 // possible to query metrics by using the exact same labels as they were stored with.
 func (t *TestRegistry) Query(name string, lbls labels.Labels) float64 {
@@ -88,6 +102,24 @@ func (t testCounter) Inc(values *LabelValues, value float64) {
 	t.registry.addToMetric(t.name, lbls, value)
 }
 
+type testGauge struct {
+	name     string
+	labels   []string
+	registry *TestRegistry
+}
+
+var _ Gauge = (*testGauge)(nil)
+
+func (t testGauge) Set(values *LabelValues, value float64) {
+	lbls := make(labels.Labels, len(t.labels))
+	for i, label := range t.labels {
+		lbls[i] = labels.Label{Name: label, Value: values.values[i]}
+	}
+	sort.Sort(lbls)
+
+	t.registry.setMetric(t.name, lbls, value)
+}
+
 type testHistogram struct {
 	nameSum    string
 	nameCount  string
@@ -99,22 +131,29 @@ type testHistogram struct {
 
 var _ Histogram = (*testHistogram)(nil)
 
-func (t testHistogram) ObserveWithExemplar(values *LabelValues, value float64, traceID string) {
+func (t testHistogram) ObserveWithExemplar(values *LabelValues, value float64, traceID string, multiplier float64) {
+	t.ObserveWithExemplarAndBuckets(values, value, traceID, multiplier, nil)
+}
+
+func (t testHistogram) ObserveWithExemplarAndBuckets(values *LabelValues, value float64, traceID string, multiplier float64, buckets *Buckets) {
+	if buckets == nil {
+		buckets = NewBuckets(t.buckets)
+	}
+
 	lbls := make(labels.Labels, len(t.labels))
 	for i, label := range t.labels {
 		lbls[i] = labels.Label{Name: label, Value: values.values[i]}
 	}
 	sort.Sort(lbls)
 
-	t.registry.addToMetric(t.nameCount, lbls, 1)
-	t.registry.addToMetric(t.nameSum, lbls, value)
+	t.registry.addToMetric(t.nameCount, lbls, multiplier)
+	t.registry.addToMetric(t.nameSum, lbls, value*multiplier)
 
-	for _, bucket := range t.buckets {
+	for _, bucket := range buckets.bounds {
 		if value <= bucket {
-			t.registry.addToMetric(t.nameBucket, withLe(lbls, bucket), 1)
+			t.registry.addToMetric(t.nameBucket, withLe(lbls, bucket), multiplier)
 		}
 	}
-	t.registry.addToMetric(t.nameBucket, withLe(lbls, math.Inf(1)), 1)
 }
 
 func withLe(lbls labels.Labels, le float64) labels.Labels {
@@ -25,15 +25,31 @@ func TestTestRegistry_counter(t *testing.T) {
 	assert.Equal(t, 4.5, testRegistry.Query("counter", lbls))
 }
 
+func TestTestRegistry_gauge(t *testing.T) {
+	testRegistry := NewTestRegistry()
+
+	gauge := testRegistry.NewGauge("gauge", []string{"foo", "bar"})
+
+	labelValues := NewLabelValues([]string{"foo-value", "bar-value"})
+	gauge.Set(labelValues, 1.0)
+	gauge.Set(labelValues, 2.5)
+
+	lbls := labels.FromMap(map[string]string{
+		"foo": "foo-value",
+		"bar": "bar-value",
+	})
+	assert.Equal(t, 2.5, testRegistry.Query("gauge", lbls))
+}
+
 func TestTestRegistry_histogram(t *testing.T) {
 	testRegistry := NewTestRegistry()
 
 	histogram := testRegistry.NewHistogram("histogram", []string{"foo", "bar"}, []float64{1.0, 2.0})
 
 	labelValues := NewLabelValues([]string{"foo-value", "bar-value"})
-	histogram.ObserveWithExemplar(labelValues, 1.0, "")
-	histogram.ObserveWithExemplar(labelValues, 2.0, "")
-	histogram.ObserveWithExemplar(labelValues, 2.5, "")
+	histogram.ObserveWithExemplar(labelValues, 1.0, "", 1.0)
+	histogram.ObserveWithExemplar(labelValues, 2.0, "", 1.0)
+	histogram.ObserveWithExemplar(labelValues, 2.5, "", 1.0)
 
 	lbls := labels.FromMap(map[string]string{
 		"foo": "foo-value",
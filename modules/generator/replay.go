@@ -0,0 +1,237 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/ring"
+	ring_client "github.com/grafana/dskit/ring/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/weaveworks/common/user"
+
+	ingester_client "github.com/grafana/tempo/modules/ingester/client"
+	"github.com/grafana/tempo/pkg/model/trace"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util"
+)
+
+const (
+	replayTriggerNewTenant  = "new_tenant"
+	replayTriggerRingChange = "ring_change"
+)
+
+var (
+	metricReplays = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "metrics_generator_replays_total",
+		Help:      "The total number of replays of recent spans from the ingesters per trigger",
+	}, []string{"trigger"})
+	metricReplaysFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "metrics_generator_replays_failed_total",
+		Help:      "The total number of failed replays of recent spans from the ingesters per tenant",
+	}, []string{"tenant"})
+	metricSpansReplayed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "metrics_generator_spans_replayed_total",
+		Help:      "The total number of spans replayed from the ingesters per tenant",
+	}, []string{"tenant"})
+	metricReplayIngesterClients = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "metrics_generator_ingester_clients",
+		Help:      "The current number of ingester clients used to replay spans.",
+	})
+)
+
+// replayer fetches the recent traces of a tenant from the ingesters and replays the spans of the traces that
+// are sharded to this generator. This recovers the edges of service graphs spanning a restart or a change of
+// the generators in the ring.
+type replayer struct {
+	cfg           ReplayConfig
+	overrides     metricsGeneratorOverrides
+	ingesterRing  ring.ReadRing
+	generatorRing ring.ReadRing
+	pool          *ring_client.Pool
+
+	// addr returns the address of this generator in the generator ring
+	addr func() string
+
+	logger log.Logger
+}
+
+func newReplayer(cfg ReplayConfig, overrides metricsGeneratorOverrides, ingesterRing ring.ReadRing, generatorRing ring.ReadRing, clientCfg ingester_client.Config, addr func() string, logger log.Logger) *replayer {
+	factory := func(addr string) (ring_client.PoolClient, error) {
+		return ingester_client.New(addr, clientCfg)
+	}
+
+	return &replayer{
+		cfg:           cfg,
+		overrides:     overrides,
+		ingesterRing:  ingesterRing,
+		generatorRing: generatorRing,
+		pool: ring_client.NewPool("metrics_generator_pool",
+			clientCfg.PoolConfig,
+			ring_client.NewRingServiceDiscovery(ingesterRing),
+			factory,
+			metricReplayIngesterClients,
+			logger),
+		addr:   addr,
+		logger: logger,
+	}
+}
+
+// replay replays the spans of the tenant that ended in the replay window before end.
+func (r *replayer) replay(ctx context.Context, inst *instance, end time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+	ctx = user.InjectOrgID(ctx, inst.instanceID)
+
+	start := end.Add(-r.cfg.Window)
+
+	traces, err := r.recentTraces(ctx, inst.instanceID, start, end)
+	if err != nil {
+		return err
+	}
+
+	req := spansToReplay(traces, r.owns(inst.instanceID), start, end)
+	if len(req.Batches) == 0 {
+		return nil
+	}
+
+	inst.replaySpans(ctx, req)
+	return nil
+}
+
+// recentTraces requests the recent traces from all ingesters and combines the parts of a trace returned by
+// several ingesters.
+func (r *replayer) recentTraces(ctx context.Context, userID string, start, end time.Time) ([]*tempopb.RecentTrace, error) {
+	replicationSet, err := r.ingesterRing.GetReplicationSetForOperation(ring.Read)
+	if err != nil {
+		return nil, fmt.Errorf("error finding ingesters: %w", err)
+	}
+
+	req := &tempopb.RecentTracesRequest{
+		Start:    uint32(start.Unix()),
+		End:      uint32(end.Unix()),
+		MaxBytes: uint32(r.cfg.MaxBytes),
+	}
+
+	results, err := replicationSet.Do(ctx, 0, func(ctx context.Context, ingester *ring.InstanceDesc) (interface{}, error) {
+		client, err := r.pool.GetClientFor(ingester.Addr)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.(tempopb.QuerierClient).RecentTraces(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Truncated {
+			level.Warn(r.logger).Log("msg", "recent traces of ingester were truncated", "tenant", userID, "ingester", ingester.Addr, "maxBytes", r.cfg.MaxBytes)
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error requesting recent traces from ingesters: %w", err)
+	}
+
+	responses := make([]*tempopb.RecentTracesResponse, 0, len(results))
+	for _, result := range results {
+		responses = append(responses, result.(*tempopb.RecentTracesResponse))
+	}
+
+	return combineRecentTraces(responses), nil
+}
+
+// owns returns a func that checks if a trace of the tenant is sharded to this generator. It follows the
+// sharding of the distributor.
+func (r *replayer) owns(userID string) func(traceID []byte) bool {
+	subring := r.generatorRing.ShuffleShard(userID, r.overrides.MetricsGeneratorRingSize(userID))
+	addr := r.addr()
+
+	bufDescs, bufHosts, bufZones := ring.MakeBuffersForGet()
+	return func(traceID []byte) bool {
+		replicationSet, err := subring.Get(util.TokenFor(userID, traceID), ring.Write, bufDescs, bufHosts, bufZones)
+		if err != nil {
+			return false
+		}
+		for _, instance := range replicationSet.Instances {
+			if instance.Addr == addr {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func combineRecentTraces(responses []*tempopb.RecentTracesResponse) []*tempopb.RecentTrace {
+	var ids [][]byte
+	combiners := map[string]*trace.Combiner{}
+
+	for _, resp := range responses {
+		for _, t := range resp.Traces {
+			c, ok := combiners[string(t.TraceID)]
+			if !ok {
+				c = trace.NewCombiner()
+				combiners[string(t.TraceID)] = c
+				ids = append(ids, t.TraceID)
+			}
+			c.Consume(t.Trace)
+		}
+	}
+
+	traces := make([]*tempopb.RecentTrace, 0, len(ids))
+	for _, id := range ids {
+		tr, _ := combiners[string(id)].Result()
+		traces = append(traces, &tempopb.RecentTrace{
+			TraceID: id,
+			Trace:   tr,
+		})
+	}
+	return traces
+}
+
+// spansToReplay returns the spans of the owned traces that ended between start and end.
+func spansToReplay(traces []*tempopb.RecentTrace, owns func(traceID []byte) bool, start, end time.Time) *tempopb.PushSpansRequest {
+	req := &tempopb.PushSpansRequest{}
+
+	minEnd, maxEnd := uint64(start.UnixNano()), uint64(end.UnixNano())
+	for _, t := range traces {
+		if t.Trace == nil || !owns(t.TraceID) {
+			continue
+		}
+
+		for _, b := range t.Trace.Batches {
+			var ilss []*v1.InstrumentationLibrarySpans
+			for _, ils := range b.InstrumentationLibrarySpans {
+				var spans []*v1.Span
+				for _, span := range ils.Spans {
+					if span.EndTimeUnixNano >= minEnd && span.EndTimeUnixNano <= maxEnd {
+						spans = append(spans, span)
+					}
+				}
+				if len(spans) == 0 {
+					continue
+				}
+				ilss = append(ilss, &v1.InstrumentationLibrarySpans{
+					InstrumentationLibrary: ils.InstrumentationLibrary,
+					Spans:                  spans,
+				})
+			}
+			if len(ilss) == 0 {
+				continue
+			}
+			req.Batches = append(req.Batches, &v1.ResourceSpans{
+				Resource:                    b.Resource,
+				InstrumentationLibrarySpans: ilss,
+			})
+		}
+	}
+
+	return req
+}
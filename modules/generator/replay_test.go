@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util/test"
+)
+
+func TestCombineRecentTraces(t *testing.T) {
+	idA := test.ValidTraceID(nil)
+	idB := test.ValidTraceID(nil)
+	partA1 := test.MakeTrace(1, idA)
+	partA2 := test.MakeTrace(1, idA)
+	traceB := test.MakeTrace(2, idB)
+
+	// each ingester returns the part of the trace it received
+	traces := combineRecentTraces([]*tempopb.RecentTracesResponse{
+		{Traces: []*tempopb.RecentTrace{{TraceID: idA, Trace: partA1}, {TraceID: idB, Trace: traceB}}},
+		{Traces: []*tempopb.RecentTrace{{TraceID: idA, Trace: partA2}}},
+		{Traces: []*tempopb.RecentTrace{{TraceID: idB, Trace: traceB}}},
+	})
+
+	require.Len(t, traces, 2)
+	assert.Equal(t, idA, traces[0].TraceID)
+	assert.Len(t, traces[0].Trace.Batches, 2)
+	assert.Equal(t, idB, traces[1].TraceID)
+	assert.Len(t, traces[1].Trace.Batches, 2)
+}
+
+func TestSpansToReplay(t *testing.T) {
+	now := time.Now()
+	span := func(end time.Time) *v1.Span {
+		return &v1.Span{
+			StartTimeUnixNano: uint64(end.Add(-time.Second).UnixNano()),
+			EndTimeUnixNano:   uint64(end.UnixNano()),
+		}
+	}
+
+	owned := test.ValidTraceID(nil)
+	notOwned := test.ValidTraceID(nil)
+	recentTrace := func(id []byte, spans ...*v1.Span) *tempopb.RecentTrace {
+		return &tempopb.RecentTrace{
+			TraceID: id,
+			Trace: &tempopb.Trace{
+				Batches: []*v1.ResourceSpans{
+					{
+						InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: spans}},
+					},
+				},
+			},
+		}
+	}
+
+	inWindow := span(now.Add(-time.Minute))
+	req := spansToReplay([]*tempopb.RecentTrace{
+		recentTrace(owned, inWindow, span(now.Add(-time.Hour)), span(now.Add(time.Minute))),
+		recentTrace(owned, span(now.Add(-time.Hour))),
+		recentTrace(notOwned, span(now.Add(-time.Minute))),
+	}, func(traceID []byte) bool {
+		return bytes.Equal(traceID, owned)
+	}, now.Add(-5*time.Minute), now)
+
+	// only the spans of owned traces that ended in the window are replayed
+	require.Len(t, req.Batches, 1)
+	require.Len(t, req.Batches[0].InstrumentationLibrarySpans, 1)
+	assert.Equal(t, []*v1.Span{inWindow}, req.Batches[0].InstrumentationLibrarySpans[0].Spans)
+}
@@ -0,0 +1,35 @@
+package ruler
+
+import (
+	"flag"
+	"time"
+)
+
+type Config struct {
+	// EvaluationInterval is how often rule groups are evaluated, unless the group sets an interval.
+	EvaluationInterval time.Duration `yaml:"evaluation_interval"`
+
+	// Retention is how long samples are kept in memory for rule evaluation. Range selectors in rules
+	// can't look further back.
+	Retention time.Duration `yaml:"retention"`
+
+	// AlertmanagerURLs are the Alertmanagers alerts are sent to, e.g. http://alertmanager:9093. The
+	// tenant is sent in the X-Scope-OrgID header so multi-tenant Alertmanagers can be used.
+	AlertmanagerURLs []string `yaml:"alertmanager_urls"`
+
+	// NotificationTimeout is the timeout when sending alerts to an Alertmanager.
+	NotificationTimeout time.Duration `yaml:"notification_timeout"`
+
+	// ResendDelay is the minimum time to wait before resending a firing alert.
+	ResendDelay time.Duration `yaml:"resend_delay"`
+
+	// ExternalURL is used to build the generator URL of alerts.
+	ExternalURL string `yaml:"external_url"`
+}
+
+func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet) {
+	cfg.EvaluationInterval = time.Minute
+	cfg.Retention = 15 * time.Minute
+	cfg.NotificationTimeout = 10 * time.Second
+	cfg.ResendDelay = time.Minute
+}
@@ -0,0 +1,232 @@
+package ruler
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+)
+
+// memStore is a minimal in-memory storage holding the recent samples rules are evaluated on.
+type memStore struct {
+	mtx    sync.RWMutex
+	series map[uint64]*memSeries
+}
+
+var _ storage.Appendable = (*memStore)(nil)
+var _ storage.Queryable = (*memStore)(nil)
+
+type memSeries struct {
+	lbls    labels.Labels
+	samples []sample
+}
+
+type sample struct {
+	t int64
+	v float64
+}
+
+func (s sample) T() int64   { return s.t }
+func (s sample) V() float64 { return s.v }
+
+func newMemStore() *memStore {
+	return &memStore{
+		series: map[uint64]*memSeries{},
+	}
+}
+
+// Appender implements storage.Appendable
+func (s *memStore) Appender(_ context.Context) storage.Appender {
+	return &memAppender{s: s}
+}
+
+// Querier implements storage.Queryable
+func (s *memStore) Querier(_ context.Context, mint, maxt int64) (storage.Querier, error) {
+	return &memQuerier{s: s, mint: mint, maxt: maxt}, nil
+}
+
+// truncate removes all samples before mint and the series left empty.
+func (s *memStore) truncate(mint int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for hash, series := range s.series {
+		i := sort.Search(len(series.samples), func(i int) bool {
+			return series.samples[i].t >= mint
+		})
+		if i == len(series.samples) {
+			delete(s.series, hash)
+			continue
+		}
+		series.samples = append(series.samples[:0], series.samples[i:]...)
+	}
+}
+
+func (s *memStore) seriesCount() int {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	return len(s.series)
+}
+
+type pendingSample struct {
+	lbls labels.Labels
+	sample
+}
+
+type memAppender struct {
+	s       *memStore
+	pending []pendingSample
+}
+
+func (a *memAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	a.pending = append(a.pending, pendingSample{lbls: l, sample: sample{t: t, v: v}})
+	return ref, nil
+}
+
+func (a *memAppender) AppendExemplar(ref storage.SeriesRef, _ labels.Labels, _ exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (a *memAppender) Commit() error {
+	a.s.mtx.Lock()
+	defer a.s.mtx.Unlock()
+
+	for _, p := range a.pending {
+		hash := p.lbls.Hash()
+		series, ok := a.s.series[hash]
+		if !ok {
+			series = &memSeries{lbls: p.lbls.Copy()}
+			a.s.series[hash] = series
+		}
+
+		// out of order samples are dropped
+		if n := len(series.samples); n > 0 && series.samples[n-1].t >= p.t {
+			continue
+		}
+		series.samples = append(series.samples, p.sample)
+	}
+	a.pending = a.pending[:0]
+
+	return nil
+}
+
+func (a *memAppender) Rollback() error {
+	a.pending = a.pending[:0]
+	return nil
+}
+
+type memQuerier struct {
+	s          *memStore
+	mint, maxt int64
+}
+
+func (q *memQuerier) Select(_ bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	mint, maxt := q.mint, q.maxt
+	if hints != nil {
+		mint, maxt = hints.Start, hints.End
+	}
+
+	q.s.mtx.RLock()
+	defer q.s.mtx.RUnlock()
+
+	var set seriesSet
+	for _, series := range q.s.series {
+		if !matches(series.lbls, matchers) {
+			continue
+		}
+
+		var samples []tsdbutil.Sample
+		for _, s := range series.samples {
+			if s.t >= mint && s.t <= maxt {
+				samples = append(samples, s)
+			}
+		}
+		if len(samples) == 0 {
+			continue
+		}
+
+		set.series = append(set.series, storage.NewListSeries(series.lbls, samples))
+	}
+
+	// series are always sorted, the cost is negligible for the amount of series rules select
+	sort.Slice(set.series, func(i, j int) bool {
+		return labels.Compare(set.series[i].Labels(), set.series[j].Labels()) < 0
+	})
+
+	return &set
+}
+
+func (q *memQuerier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return q.labels(matchers, func(l labels.Label, add func(string)) {
+		if l.Name == name {
+			add(l.Value)
+		}
+	}), nil, nil
+}
+
+func (q *memQuerier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	return q.labels(matchers, func(l labels.Label, add func(string)) {
+		add(l.Name)
+	}), nil, nil
+}
+
+func (q *memQuerier) labels(matchers []*labels.Matcher, fn func(labels.Label, func(string))) []string {
+	q.s.mtx.RLock()
+	defer q.s.mtx.RUnlock()
+
+	values := map[string]struct{}{}
+	add := func(v string) {
+		values[v] = struct{}{}
+	}
+	for _, series := range q.s.series {
+		if !matches(series.lbls, matchers) {
+			continue
+		}
+		for _, l := range series.lbls {
+			fn(l, add)
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for v := range values {
+		result = append(result, v)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (q *memQuerier) Close() error {
+	return nil
+}
+
+func matches(lbls labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lbls.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+type seriesSet struct {
+	series []storage.Series
+	cur    storage.Series
+}
+
+func (s *seriesSet) Next() bool {
+	if len(s.series) == 0 {
+		return false
+	}
+	s.cur = s.series[0]
+	s.series = s.series[1:]
+	return true
+}
+
+func (s *seriesSet) At() storage.Series         { return s.cur }
+func (s *seriesSet) Err() error                 { return nil }
+func (s *seriesSet) Warnings() storage.Warnings { return nil }
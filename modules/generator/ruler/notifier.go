@@ -0,0 +1,109 @@
+package ruler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/util/strutil"
+	"github.com/weaveworks/common/user"
+)
+
+const alertmanagerAlertsPath = "/api/v2/alerts"
+
+// alert is the payload of the Alertmanager v2 API.
+type alert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt,omitempty"`
+	EndsAt       time.Time         `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// notifier sends alerts to the configured Alertmanagers.
+type notifier struct {
+	cfg    *Config
+	tenant string
+	client *http.Client
+	logger log.Logger
+}
+
+func newNotifier(cfg *Config, tenant string, logger log.Logger) *notifier {
+	return &notifier{
+		cfg:    cfg,
+		tenant: tenant,
+		client: &http.Client{Timeout: cfg.NotificationTimeout},
+		logger: logger,
+	}
+}
+
+// send implements rules.NotifyFunc. It is called by the rule groups after every evaluation with
+// the alerts that need to be (re)sent.
+func (n *notifier) send(ctx context.Context, expr string, alerts ...*rules.Alert) {
+	if len(alerts) == 0 || len(n.cfg.AlertmanagerURLs) == 0 {
+		return
+	}
+
+	payload := make([]alert, 0, len(alerts))
+	for _, a := range alerts {
+		p := alert{
+			Labels:      a.Labels.Map(),
+			Annotations: a.Annotations.Map(),
+			StartsAt:    a.FiredAt,
+		}
+		if !a.ResolvedAt.IsZero() {
+			p.EndsAt = a.ResolvedAt
+		} else {
+			p.EndsAt = a.ValidUntil
+		}
+		if n.cfg.ExternalURL != "" {
+			p.GeneratorURL = strings.TrimSuffix(n.cfg.ExternalURL, "/") + strutil.TableLinkForExpression(expr)
+		}
+		payload = append(payload, p)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		level.Error(n.logger).Log("msg", "failed to marshal alerts", "err", err)
+		return
+	}
+
+	for _, url := range n.cfg.AlertmanagerURLs {
+		err := n.post(ctx, strings.TrimSuffix(url, "/")+alertmanagerAlertsPath, body)
+		if err != nil {
+			metricNotificationsFailed.WithLabelValues(n.tenant).Add(float64(len(alerts)))
+			level.Error(n.logger).Log("msg", "failed to send alerts", "alertmanager", url, "err", err)
+			continue
+		}
+		metricNotificationsSent.WithLabelValues(n.tenant).Add(float64(len(alerts)))
+	}
+}
+
+func (n *notifier) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(user.OrgIDHeaderName, n.tenant)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package ruler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"gopkg.in/yaml.v3"
+
+	"github.com/grafana/tempo/modules/overrides"
+)
+
+var (
+	metricRulerSeries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "metrics_generator_ruler_series",
+		Help:      "The number of series held in memory for rule evaluation",
+	}, []string{"tenant"})
+	metricNotificationsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "metrics_generator_ruler_notifications_sent_total",
+		Help:      "The total number of alerts sent to Alertmanagers",
+	}, []string{"tenant"})
+	metricNotificationsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "metrics_generator_ruler_notifications_failed_total",
+		Help:      "The total number of alerts that failed to be sent to Alertmanagers",
+	}, []string{"tenant"})
+)
+
+const truncateInterval = time.Minute
+
+// Ruler evaluates the alerting rules of a tenant on the metrics generated by the metrics-generator
+// and forwards the alerts to Alertmanager. Samples are kept in memory for the retention period so
+// range selectors can be used.
+type Ruler struct {
+	cfg    *Config
+	tenant string
+
+	store    *memStore
+	loader   *groupLoader
+	manager  *rules.Manager
+	notifier *notifier
+
+	mtx    sync.Mutex
+	groups []overrides.AlertingRuleGroup
+	active bool
+
+	// reg holds the metrics of the rules manager, they are unregistered when the ruler stops
+	reg *unregisterer
+
+	cancel context.CancelFunc
+	doneCh chan struct{}
+
+	logger log.Logger
+}
+
+func New(cfg *Config, tenant string, reg prometheus.Registerer, logger log.Logger) (*Ruler, error) {
+	if cfg.EvaluationInterval <= 0 {
+		return nil, fmt.Errorf("evaluation interval must be greater than 0")
+	}
+
+	logger = log.With(logger, "component", "ruler")
+	unreg := &unregisterer{Registerer: prometheus.WrapRegistererWith(prometheus.Labels{"tenant": tenant}, reg)}
+
+	var externalURL *url.URL
+	if cfg.ExternalURL != "" {
+		var err error
+		externalURL, err = url.Parse(cfg.ExternalURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid external url: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &Ruler{
+		cfg:      cfg,
+		tenant:   tenant,
+		store:    newMemStore(),
+		loader:   &groupLoader{},
+		notifier: newNotifier(cfg, tenant, logger),
+		reg:      unreg,
+		cancel:   cancel,
+		doneCh:   make(chan struct{}),
+		logger:   logger,
+	}
+
+	engine := promql.NewEngine(promql.EngineOpts{
+		Logger:     log.With(logger, "component", "query engine"),
+		MaxSamples: 50_000_000,
+		Timeout:    cfg.EvaluationInterval,
+		NoStepSubqueryIntervalFn: func(int64) int64 {
+			return cfg.EvaluationInterval.Milliseconds()
+		},
+	})
+
+	r.manager = rules.NewManager(&rules.ManagerOptions{
+		ExternalURL:     externalURL,
+		QueryFunc:       rules.EngineQueryFunc(engine, r.store),
+		NotifyFunc:      r.notifier.send,
+		Context:         ctx,
+		Appendable:      r.store,
+		Queryable:       r.store,
+		Logger:          logger,
+		Registerer:      unreg,
+		OutageTolerance: time.Hour,
+		ForGracePeriod:  10 * time.Minute,
+		ResendDelay:     cfg.ResendDelay,
+		GroupLoader:     r.loader,
+	})
+
+	go r.manager.Run()
+	go r.truncateLoop(ctx)
+
+	return r, nil
+}
+
+// UpdateRules replaces the alerting rules evaluated. If the rules are invalid an error is returned and
+// the previous rules keep being evaluated.
+func (r *Ruler) UpdateRules(groups []overrides.AlertingRuleGroup) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if reflect.DeepEqual(groups, r.groups) {
+		return nil
+	}
+
+	ruleGroups, err := toRuleGroups(groups)
+	if err != nil {
+		return err
+	}
+	r.loader.set(ruleGroups)
+
+	var identifiers []string
+	if len(groups) > 0 {
+		identifiers = []string{r.tenant}
+	}
+	err = r.manager.Update(r.cfg.EvaluationInterval, identifiers, nil, r.cfg.ExternalURL)
+	if err != nil {
+		return err
+	}
+
+	level.Info(r.logger).Log("msg", "updated alerting rules", "groups", len(groups))
+
+	r.groups = groups
+	r.active = len(groups) > 0
+	return nil
+}
+
+// WrapAppender returns an appender that also writes the samples to the ruler if the tenant has
+// alerting rules.
+func (r *Ruler) WrapAppender(ctx context.Context, app storage.Appender) storage.Appender {
+	r.mtx.Lock()
+	active := r.active
+	r.mtx.Unlock()
+
+	if !active {
+		return app
+	}
+	return &fanoutAppender{primary: app, secondary: r.store.Appender(ctx)}
+}
+
+// Stop stops evaluating rules, releases the samples held in memory and removes the metrics of the tenant so
+// the ruler can be created again.
+func (r *Ruler) Stop() {
+	r.manager.Stop()
+	r.cancel()
+	<-r.doneCh
+
+	r.reg.unregisterAll()
+	metricRulerSeries.DeleteLabelValues(r.tenant)
+	metricNotificationsSent.DeleteLabelValues(r.tenant)
+	metricNotificationsFailed.DeleteLabelValues(r.tenant)
+}
+
+func (r *Ruler) truncateLoop(ctx context.Context) {
+	defer close(r.doneCh)
+
+	ticker := time.NewTicker(truncateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.store.truncate(time.Now().Add(-r.cfg.Retention).UnixMilli())
+			metricRulerSeries.WithLabelValues(r.tenant).Set(float64(r.store.seriesCount()))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// toRuleGroups converts the rule groups from the overrides to the Prometheus format and validates
+// them. Only alerting rules are supported.
+func toRuleGroups(groups []overrides.AlertingRuleGroup) (*rulefmt.RuleGroups, error) {
+	ruleGroups := &rulefmt.RuleGroups{}
+	names := map[string]struct{}{}
+
+	for _, g := range groups {
+		if g.Name == "" {
+			return nil, fmt.Errorf("rule group name must not be empty")
+		}
+		if _, ok := names[g.Name]; ok {
+			return nil, fmt.Errorf("rule group %s is defined more than once", g.Name)
+		}
+		names[g.Name] = struct{}{}
+
+		ruleGroup := rulefmt.RuleGroup{
+			Name:     g.Name,
+			Interval: g.Interval,
+		}
+		for _, rule := range g.Rules {
+			node := rulefmt.RuleNode{
+				Alert:       yaml.Node{Kind: yaml.ScalarNode, Value: rule.Alert},
+				Expr:        yaml.Node{Kind: yaml.ScalarNode, Value: rule.Expr},
+				For:         model.Duration(rule.For),
+				Labels:      rule.Labels,
+				Annotations: rule.Annotations,
+			}
+			if err := validateRule(rule); err != nil {
+				return nil, fmt.Errorf("rule group %s: %w", g.Name, err)
+			}
+			ruleGroup.Rules = append(ruleGroup.Rules, node)
+		}
+		ruleGroups.Groups = append(ruleGroups.Groups, ruleGroup)
+	}
+
+	return ruleGroups, nil
+}
+
+func validateRule(rule overrides.AlertingRule) error {
+	if rule.Alert == "" {
+		return fmt.Errorf("alert name must not be empty")
+	}
+	if rule.Expr == "" {
+		return fmt.Errorf("alert %s: expr must not be empty", rule.Alert)
+	}
+	if _, err := parser.ParseExpr(rule.Expr); err != nil {
+		return fmt.Errorf("alert %s: could not parse expression: %w", rule.Alert, err)
+	}
+	for name := range rule.Labels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("alert %s: invalid label name %s", rule.Alert, name)
+		}
+	}
+	for name := range rule.Annotations {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("alert %s: invalid annotation name %s", rule.Alert, name)
+		}
+	}
+	return nil
+}
+
+// unregisterer records the collectors registered through it so they can be unregistered
+type unregisterer struct {
+	prometheus.Registerer
+
+	mtx        sync.Mutex
+	collectors []prometheus.Collector
+}
+
+func (u *unregisterer) Register(c prometheus.Collector) error {
+	err := u.Registerer.Register(c)
+	if err != nil {
+		return err
+	}
+
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	u.collectors = append(u.collectors, c)
+	return nil
+}
+
+func (u *unregisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := u.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (u *unregisterer) Unregister(c prometheus.Collector) bool {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	for i, registered := range u.collectors {
+		if registered == c {
+			u.collectors = append(u.collectors[:i], u.collectors[i+1:]...)
+			break
+		}
+	}
+	return u.Registerer.Unregister(c)
+}
+
+func (u *unregisterer) unregisterAll() {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+
+	for _, c := range u.collectors {
+		u.Registerer.Unregister(c)
+	}
+	u.collectors = nil
+}
+
+// groupLoader implements rules.GroupLoader, it serves the rule groups of the tenant from memory.
+type groupLoader struct {
+	mtx    sync.Mutex
+	groups *rulefmt.RuleGroups
+}
+
+func (l *groupLoader) set(groups *rulefmt.RuleGroups) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.groups = groups
+}
+
+func (l *groupLoader) Load(string) (*rulefmt.RuleGroups, []error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.groups, nil
+}
+
+func (l *groupLoader) Parse(query string) (parser.Expr, error) {
+	return parser.ParseExpr(query)
+}
+
+// fanoutAppender writes samples to two appenders. Series references are only valid for the primary
+// appender.
+type fanoutAppender struct {
+	primary, secondary storage.Appender
+}
+
+func (a *fanoutAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	ref, err := a.primary.Append(ref, l, t, v)
+	if err != nil {
+		return ref, err
+	}
+	_, err = a.secondary.Append(0, l, t, v)
+	return ref, err
+}
+
+func (a *fanoutAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	return a.primary.AppendExemplar(ref, l, e)
+}
+
+func (a *fanoutAppender) Commit() error {
+	err := a.primary.Commit()
+	if err != nil {
+		_ = a.secondary.Rollback()
+		return err
+	}
+	return a.secondary.Commit()
+}
+
+func (a *fanoutAppender) Rollback() error {
+	err := a.primary.Rollback()
+	_ = a.secondary.Rollback()
+	return err
+}
@@ -0,0 +1,250 @@
+package ruler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/modules/overrides"
+)
+
+func TestRuler(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		received []alert
+		tenants  []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, alertmanagerAlertsPath, r.URL.Path)
+
+		var alerts []alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alerts))
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		received = append(received, alerts...)
+		tenants = append(tenants, r.Header.Get(user.OrgIDHeaderName))
+	}))
+	defer srv.Close()
+
+	cfg := &Config{
+		EvaluationInterval:  100 * time.Millisecond,
+		Retention:           time.Minute,
+		AlertmanagerURLs:    []string{srv.URL},
+		NotificationTimeout: time.Second,
+		ResendDelay:         time.Minute,
+	}
+
+	r, err := New(cfg, "test", prometheus.NewRegistry(), log.NewNopLogger())
+	require.NoError(t, err)
+	defer r.Stop()
+
+	// no rules, samples are not kept
+	appender := r.WrapAppender(context.Background(), &noopAppender{})
+	assert.IsType(t, &noopAppender{}, appender)
+
+	err = r.UpdateRules([]overrides.AlertingRuleGroup{
+		{
+			Name: "latency",
+			Rules: []overrides.AlertingRule{
+				{
+					Alert:       "HighLatency",
+					Expr:        `traces_spanmetrics_latency_sum / traces_spanmetrics_latency_count > 1`,
+					Labels:      map[string]string{"severity": "page"},
+					Annotations: map[string]string{"summary": "high latency"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	now := time.Now().UnixMilli()
+	appender = r.WrapAppender(context.Background(), &noopAppender{})
+	for _, service := range []string{"fast", "slow"} {
+		sum := 1.0
+		if service == "slow" {
+			sum = 20
+		}
+		_, err = appender.Append(0, labels.FromStrings("__name__", "traces_spanmetrics_latency_sum", "service", service), now, sum)
+		require.NoError(t, err)
+		_, err = appender.Append(0, labels.FromStrings("__name__", "traces_spanmetrics_latency_count", "service", service), now, 10)
+		require.NoError(t, err)
+	}
+	require.NoError(t, appender.Commit())
+
+	require.Eventually(t, func() bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(received) > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, map[string]string{"alertname": "HighLatency", "service": "slow", "severity": "page"}, received[0].Labels)
+	assert.Equal(t, map[string]string{"summary": "high latency"}, received[0].Annotations)
+	assert.Equal(t, "test", tenants[0])
+}
+
+func TestRulerStopUnregistersMetrics(t *testing.T) {
+	cfg := &Config{}
+	cfg.RegisterFlagsAndApplyDefaults("", nil)
+
+	reg := prometheus.NewRegistry()
+	for i := 0; i < 2; i++ {
+		r, err := New(cfg, "test", reg, log.NewNopLogger())
+		require.NoError(t, err)
+		require.NoError(t, r.UpdateRules([]overrides.AlertingRuleGroup{
+			{Name: "a", Rules: []overrides.AlertingRule{{Alert: "a", Expr: "up"}}},
+		}))
+
+		families, err := reg.Gather()
+		require.NoError(t, err)
+		require.NotEmpty(t, families)
+
+		// the ruler of the tenant can be created again once it's stopped
+		r.Stop()
+
+		families, err = reg.Gather()
+		require.NoError(t, err)
+		require.Empty(t, families)
+	}
+}
+
+func TestRulerInvalidRules(t *testing.T) {
+	cfg := &Config{}
+	cfg.RegisterFlagsAndApplyDefaults("", nil)
+
+	r, err := New(cfg, "test", prometheus.NewRegistry(), log.NewNopLogger())
+	require.NoError(t, err)
+	defer r.Stop()
+
+	tests := []struct {
+		name   string
+		groups []overrides.AlertingRuleGroup
+		err    string
+	}{
+		{
+			name:   "empty group name",
+			groups: []overrides.AlertingRuleGroup{{Rules: []overrides.AlertingRule{{Alert: "a", Expr: "up"}}}},
+			err:    "rule group name must not be empty",
+		},
+		{
+			name: "duplicate group",
+			groups: []overrides.AlertingRuleGroup{
+				{Name: "a", Rules: []overrides.AlertingRule{{Alert: "a", Expr: "up"}}},
+				{Name: "a", Rules: []overrides.AlertingRule{{Alert: "a", Expr: "up"}}},
+			},
+			err: "rule group a is defined more than once",
+		},
+		{
+			name:   "missing alert",
+			groups: []overrides.AlertingRuleGroup{{Name: "a", Rules: []overrides.AlertingRule{{Expr: "up"}}}},
+			err:    "rule group a: alert name must not be empty",
+		},
+		{
+			name:   "invalid expr",
+			groups: []overrides.AlertingRuleGroup{{Name: "a", Rules: []overrides.AlertingRule{{Alert: "a", Expr: "up{"}}}},
+			err:    "rule group a: alert a: could not parse expression",
+		},
+		{
+			name:   "invalid label",
+			groups: []overrides.AlertingRuleGroup{{Name: "a", Rules: []overrides.AlertingRule{{Alert: "a", Expr: "up", Labels: map[string]string{"a-b": "c"}}}}},
+			err:    "rule group a: alert a: invalid label name a-b",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := r.UpdateRules(tc.groups)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	s := newMemStore()
+
+	app := s.Appender(context.Background())
+	_, _ = app.Append(0, labels.FromStrings("__name__", "a", "job", "1"), 1000, 1)
+	_, _ = app.Append(0, labels.FromStrings("__name__", "a", "job", "1"), 2000, 2)
+	_, _ = app.Append(0, labels.FromStrings("__name__", "a", "job", "1"), 1500, 3) // out of order
+	_, _ = app.Append(0, labels.FromStrings("__name__", "a", "job", "2"), 1000, 4)
+	_, _ = app.Append(0, labels.FromStrings("__name__", "b", "job", "1"), 1000, 5)
+	require.NoError(t, app.Commit())
+
+	app = s.Appender(context.Background())
+	_, _ = app.Append(0, labels.FromStrings("__name__", "c"), 1000, 1)
+	require.NoError(t, app.Rollback())
+	assert.Equal(t, 3, s.seriesCount())
+
+	q, err := s.Querier(context.Background(), 0, 3000)
+	require.NoError(t, err)
+
+	set := q.Select(true, nil, labels.MustNewMatcher(labels.MatchEqual, "__name__", "a"))
+	assert.Equal(t, map[string][]sample{
+		`{__name__="a", job="1"}`: {{1000, 1}, {2000, 2}},
+		`{__name__="a", job="2"}`: {{1000, 4}},
+	}, collect(t, set))
+
+	values, _, err := q.LabelValues("job")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, values)
+
+	names, _, err := q.LabelNames(labels.MustNewMatcher(labels.MatchEqual, "__name__", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"__name__", "job"}, names)
+
+	s.truncate(1500)
+	assert.Equal(t, 1, s.seriesCount())
+
+	set = q.Select(true, nil)
+	assert.Equal(t, map[string][]sample{
+		`{__name__="a", job="1"}`: {{2000, 2}},
+	}, collect(t, set))
+}
+
+func collect(t *testing.T, set storage.SeriesSet) map[string][]sample {
+	result := map[string][]sample{}
+	for set.Next() {
+		var samples []sample
+		it := set.At().Iterator()
+		for it.Next() {
+			ts, v := it.At()
+			samples = append(samples, sample{ts, v})
+		}
+		require.NoError(t, it.Err())
+		result[set.At().Labels().String()] = samples
+	}
+	require.NoError(t, set.Err())
+	return result
+}
+
+type noopAppender struct{}
+
+var _ storage.Appender = (*noopAppender)(nil)
+
+func (n *noopAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	return 0, nil
+}
+
+func (n *noopAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	return 0, nil
+}
+
+func (n *noopAppender) Commit() error { return nil }
+
+func (n *noopAppender) Rollback() error { return nil }
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// durableAckKey is the grpc metadata key asking the ingester to sync the pushed traces to disk before it responds
+const durableAckKey = "x-tempo-durable-ack"
+
+// WithDurableAck returns a context whose pushes are only acknowledged by the ingester once they are synced to disk
+func WithDurableAck(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, durableAckKey, "true")
+}
+
+// DurableAckRequested returns true if the incoming push asks to be synced to disk before it is acknowledged
+func DurableAckRequested(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	values := md.Get(durableAckKey)
+	return len(values) > 0 && values[0] == "true"
+}
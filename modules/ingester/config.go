@@ -26,6 +26,19 @@ type Config struct {
 	CompleteBlockTimeout time.Duration `yaml:"complete_block_timeout"`
 	OverrideRingKey      string        `yaml:"override_ring_key"`
 	UseFlatbufferSearch  bool          `yaml:"use_flatbuffer_search"`
+	// ConcurrentBlockSearches is the number of block searches running at once, shared between tenants by the weight
+	// of their ingester_search_weight override. 0 runs all block searches immediately.
+	ConcurrentBlockSearches int `yaml:"concurrent_block_searches"`
+	// CutBatchBytes is the size of the cut traces written to the head block with a single write. 0 writes every
+	// trace separately.
+	CutBatchBytes int `yaml:"cut_batch_bytes"`
+
+	// ReplayTenants restricts the wal replay on startup to these tenants, or all tenants if empty, except
+	// ReplayExcludeTenants. The wal files of other tenants are kept until a replay that includes them. The wal
+	// files of ReplayPriorityTenants are replayed before those of other tenants, in the order they are listed.
+	ReplayTenants         []string `yaml:"replay_tenants"`
+	ReplayExcludeTenants  []string `yaml:"replay_exclude_tenants"`
+	ReplayPriorityTenants []string `yaml:"replay_priority_tenants"`
 }
 
 // RegisterFlagsAndApplyDefaults registers the flags.
@@ -45,6 +58,8 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	f.DurationVar(&cfg.MaxBlockDuration, prefix+".max-block-duration", time.Hour, "Maximum duration which the head block can be appended to before cutting it.")
 	f.Uint64Var(&cfg.MaxBlockBytes, prefix+".max-block-bytes", 1024*1024*1024, "Maximum size of the head block before cutting it.")
 	f.DurationVar(&cfg.CompleteBlockTimeout, prefix+".complete-block-timeout", 3*tempodb.DefaultBlocklistPoll, "Duration to keep blocks in the ingester after they have been flushed.")
+	f.IntVar(&cfg.ConcurrentBlockSearches, prefix+".concurrent-block-searches", 0, "Number of block searches running at once, scheduled fairly between tenants. 0 to disable.")
+	f.IntVar(&cfg.CutBatchBytes, prefix+".cut-batch-bytes", 0, "Size of the cut traces written to the head block with a single write. 0 to write every trace separately.")
 
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -2,6 +2,7 @@ package ingester
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -21,7 +22,9 @@ import (
 	"github.com/uber/jaeger-client-go"
 	"github.com/weaveworks/common/user"
 
+	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/pkg/util/log"
+	"github.com/grafana/tempo/tempodb/wal"
 )
 
 var (
@@ -121,6 +124,45 @@ func (i *Ingester) FlushHandler(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// DeleteTraceResponse is the response of the DeleteTraceHandler
+type DeleteTraceResponse struct {
+	// SkippedBlocks is the number of wal blocks the trace couldn't be deleted from
+	SkippedBlocks int `json:"skippedBlocks"`
+}
+
+// DeleteTraceHandler deletes a trace of the tenant that hasn't been flushed to the backend yet. Tombstones are
+// appended to the wal blocks of the tenant, blocks that don't support them are skipped and counted in the response.
+func (i *Ingester) DeleteTraceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	instanceID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	traceID, err := util.HexStringToTraceID(r.URL.Query().Get("traceID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := DeleteTraceResponse{}
+	if inst, ok := i.getInstanceByID(instanceID); ok && inst != nil {
+		resp.SkippedBlocks, err = inst.DeleteTrace(traceID)
+		if err != nil {
+			level.Error(log.Logger).Log("msg", "failed to delete trace", "tenant", instanceID, "traceID", util.TraceIDToHexString(traceID), "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
 type flushOp struct {
 	kind     int
 	at       time.Time // When to execute
@@ -152,7 +194,11 @@ func (i *Ingester) sweepAllInstances(immediate bool) {
 func (i *Ingester) sweepInstance(instance *instance, immediate bool) {
 	// cut traces internally
 	err := instance.CutCompleteTraces(i.cfg.MaxTraceIdle, immediate)
-	if err != nil {
+	if errors.Is(err, wal.ErrWALFull) {
+		// completing the head block early frees its wal file
+		level.Warn(log.WithUserID(instance.instanceID, log.Logger)).Log("msg", "wal full. cutting head block early", "err", err)
+		immediate = true
+	} else if err != nil {
 		level.Error(log.WithUserID(instance.instanceID, log.Logger)).Log("msg", "failed to cut traces", "err", err)
 		return
 	}
@@ -196,25 +242,42 @@ func (i *Ingester) flushLoop(j int) {
 		op := o.(*flushOp)
 		op.attempts++
 
-		var retry bool
-		var err error
-
 		if op.kind == opKindComplete {
-			retry, err = i.handleComplete(op)
+			// blocks are completed by the completion pool of the store if enabled, so the flush queue isn't held
+			// up by large blocks
+			if block := i.completingBlock(op); block != nil && i.store.SubmitCompletion(block, func() { i.finishOp(op, i.handleComplete) }) {
+				continue
+			}
+			i.finishOp(op, i.handleComplete)
 		} else {
-			retry, err = i.handleFlush(context.Background(), op.userID, op.blockID)
+			i.finishOp(op, func(op *flushOp) (bool, error) {
+				return i.handleFlush(context.Background(), op.userID, op.blockID)
+			})
 		}
+	}
+}
 
-		if err != nil {
-			handleFailedOp(op, err)
-		}
+// finishOp performs the op and requeues it or clears it from the flush queues
+func (i *Ingester) finishOp(op *flushOp, handle func(*flushOp) (bool, error)) {
+	retry, err := handle(op)
+	if err != nil {
+		handleFailedOp(op, err)
+	}
 
-		if retry {
-			i.requeue(op)
-		} else {
-			i.flushQueues.Clear(op)
-		}
+	if retry {
+		i.requeue(op)
+	} else {
+		i.flushQueues.Clear(op)
+	}
+}
+
+// completingBlock returns the completing block of a complete op or nil if it doesn't exist
+func (i *Ingester) completingBlock(op *flushOp) *wal.AppendBlock {
+	instance, ok := i.getInstanceByID(op.userID)
+	if !ok {
+		return nil
 	}
+	return instance.CompletingBlock(op.blockID)
 }
 
 func handleFailedOp(op *flushOp, err error) {
@@ -3,6 +3,9 @@ package ingester
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -33,17 +36,30 @@ import (
 	"github.com/grafana/tempo/pkg/validation"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/wal"
 )
 
 // ErrReadOnly is returned when the ingester is shutting down and a push was
 // attempted.
 var ErrReadOnly = errors.New("Ingester is shutting down")
 
-var metricFlushQueueLength = promauto.NewGauge(prometheus.GaugeOpts{
-	Namespace: "tempo",
-	Name:      "ingester_flush_queue_length",
-	Help:      "The total number of series pending in the flush queue.",
-})
+var (
+	metricFlushQueueLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "ingester_flush_queue_length",
+		Help:      "The total number of series pending in the flush queue.",
+	})
+	metricReplayFiles = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "ingester_replay_wal_files",
+		Help:      "The number of wal files to replay on startup.",
+	})
+	metricReplayedFiles = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "ingester_replayed_wal_files",
+		Help:      "The number of wal files replayed so far on startup.",
+	})
+)
 
 const (
 	ingesterRingKey = "ring"
@@ -64,10 +80,15 @@ type Ingester struct {
 	local        *local.Backend
 	replayJitter bool // this var exists so tests can remove jitter
 
+	replayMtx      sync.Mutex
+	replayProgress wal.ReplayProgress
+	replayDone     bool
+
 	flushQueues     *flushqueues.ExclusiveQueues
 	flushQueuesDone sync.WaitGroup
 
-	limiter *Limiter
+	limiter         *Limiter
+	searchScheduler *searchScheduler
 
 	subservicesWatcher *services.FailureWatcher
 }
@@ -98,6 +119,8 @@ func New(cfg Config, store storage.Store, limits *overrides.Overrides, reg prome
 	// Now that the lifecycler has been created, we can create the limiter
 	// which depends on it.
 	i.limiter = NewLimiter(limits, i.lifecycler, cfg.LifecyclerConfig.RingConfig.ReplicationFactor)
+	limits.RegisterUsage(overrides.MetricMaxLocalTracesPerUser, i.liveTracesUsage)
+	i.searchScheduler = newSearchScheduler(cfg.ConcurrentBlockSearches, limits.IngesterSearchWeight)
 
 	i.subservicesWatcher = services.NewFailureWatcher()
 	i.subservicesWatcher.WatchService(i.lifecycler)
@@ -117,6 +140,9 @@ func (i *Ingester) starting(ctx context.Context) error {
 		return fmt.Errorf("failed to rediscover local blocks: %w", err)
 	}
 
+	// start scrubbing once replay is done so the scrubber never reads files being replayed
+	go i.store.WAL().ScrubLoop(ctx, log.Logger)
+
 	// Now that user states have been created, we can start the lifecycler.
 	// Important: we want to keep lifecycler running until we ask it to stop, so we need to give it independent context
 	if err := i.lifecycler.StartAsync(context.Background()); err != nil {
@@ -154,8 +180,14 @@ func (i *Ingester) stopping(_ error) error {
 	if i.flushQueues != nil {
 		i.flushQueues.Stop()
 		i.flushQueuesDone.Wait()
+
+		// the flush loops hand off completions to the completion pool of the store
+		i.store.DrainCompletions()
 	}
 
+	// objects written to the wal are replicated asynchronously
+	i.store.WAL().DrainReplication()
+
 	return nil
 }
 
@@ -220,6 +252,16 @@ func (i *Ingester) PushBytesV2(ctx context.Context, req *tempopb.PushBytesReques
 		return nil, err
 	}
 
+	// the distributor rejects pushes of paused tenants, this covers overrides that are not reloaded yet
+	if err := i.limiter.AssertIngestionNotPaused(instanceID); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s %s", overrides.ErrorPrefixIngestionPaused, err.Error())
+	}
+
+	// shed load until completed blocks free up the wal
+	if i.store.WAL().Full() {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s %s", overrides.ErrorPrefixWALFull, wal.ErrWALFull.Error())
+	}
+
 	instance, err := i.getOrCreateInstance(instanceID)
 	if err != nil {
 		return nil, err
@@ -264,7 +306,35 @@ func (i *Ingester) FindTraceByID(ctx context.Context, req *tempopb.TraceByIDRequ
 	}, nil
 }
 
+// RecentTraces returns the traces held by the ingester that overlap the time range. It's used by the
+// metrics-generators to replay spans they missed while restarting or scaling.
+func (i *Ingester) RecentTraces(ctx context.Context, req *tempopb.RecentTracesRequest) (*tempopb.RecentTracesResponse, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "ingester.RecentTraces")
+	defer span.Finish()
+
+	instanceID, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inst, ok := i.getInstanceByID(instanceID)
+	if !ok || inst == nil {
+		return &tempopb.RecentTracesResponse{}, nil
+	}
+
+	resp, err := inst.RecentTraces(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	span.LogFields(ot_log.Int("traces", len(resp.Traces)), ot_log.Bool("truncated", resp.Truncated))
+
+	return resp, nil
+}
+
 func (i *Ingester) CheckReady(ctx context.Context) error {
+	if status := i.ReplayStatus(); status != "" {
+		return errors.New(status)
+	}
 	if err := i.lifecycler.CheckReady(ctx); err != nil {
 		return fmt.Errorf("ingester check ready failed %w", err)
 	}
@@ -272,6 +342,21 @@ func (i *Ingester) CheckReady(ctx context.Context) error {
 	return nil
 }
 
+// ReplayStatus describes the progress of the wal replay on startup. It returns an empty string once the wal has
+// been replayed.
+func (i *Ingester) ReplayStatus() string {
+	i.replayMtx.Lock()
+	defer i.replayMtx.Unlock()
+
+	if i.replayDone {
+		return ""
+	}
+	if i.replayProgress.Total == 0 {
+		return "replaying wal"
+	}
+	return fmt.Sprintf("replaying wal: %d/%d files", i.replayProgress.Replayed, i.replayProgress.Total)
+}
+
 func (i *Ingester) getOrCreateInstance(instanceID string) (*instance, error) {
 	inst, ok := i.getInstanceByID(instanceID)
 	if ok {
@@ -283,7 +368,7 @@ func (i *Ingester) getOrCreateInstance(instanceID string) (*instance, error) {
 	inst, ok = i.instances[instanceID]
 	if !ok {
 		var err error
-		inst, err = newInstance(instanceID, i.limiter, i.store, i.local, i.cfg.UseFlatbufferSearch)
+		inst, err = newInstance(instanceID, i.limiter, i.store, i.local, i.cfg.UseFlatbufferSearch, i.searchScheduler, i.cfg.CutBatchBytes)
 		if err != nil {
 			return nil, err
 		}
@@ -300,6 +385,15 @@ func (i *Ingester) getInstanceByID(id string) (*instance, bool) {
 	return inst, ok
 }
 
+// liveTracesUsage returns the number of live traces of each tenant.
+func (i *Ingester) liveTracesUsage() map[string]float64 {
+	usage := map[string]float64{}
+	for _, inst := range i.getInstances() {
+		usage[inst.instanceID] = float64(inst.traceCount.Load())
+	}
+	return usage
+}
+
 func (i *Ingester) getInstances() []*instance {
 	i.instancesMtx.RLock()
 	defer i.instancesMtx.RUnlock()
@@ -331,7 +425,8 @@ func (i *Ingester) replayWal() error {
 	// of the blocks correctly. as we are scanning traces in the blocks we read their start/end times
 	// and attempt to set start/end times appropriately. we use now - max_block_duration - ingestion_slack
 	// as the minimum acceptable start time for a replayed block.
-	blocks, err := i.store.WAL().RescanBlocks(func(b []byte, dataEncoding string) (uint32, uint32, error) {
+	filter := wal.NewReplayTenantFilter(i.cfg.ReplayTenants, i.cfg.ReplayExcludeTenants, i.cfg.ReplayPriorityTenants)
+	blocks, err := i.store.WAL().RescanBlocksForTenants(func(b []byte, dataEncoding string) (uint32, uint32, error) {
 		d, err := model.NewObjectDecoder(dataEncoding)
 		if err != nil {
 			return 0, 0, nil
@@ -346,12 +441,12 @@ func (i *Ingester) replayWal() error {
 			return 0, 0, err
 		}
 		return start, end, nil
-	}, i.cfg.MaxBlockDuration, log.Logger)
+	}, i.cfg.MaxBlockDuration, filter, i.replayed, log.Logger)
 	if err != nil {
 		return fmt.Errorf("fatal error replaying wal: %w", err)
 	}
 
-	searchBlocks, err := search.RescanBlocks(i.store.WAL().GetFilepath())
+	searchBlocks, err := search.RescanBlocksForTenants(i.store.WAL().GetFilepath(), filter)
 	if err != nil {
 		return fmt.Errorf("fatal error replaying search wal: %w", err)
 	}
@@ -410,11 +505,62 @@ func (i *Ingester) replayWal() error {
 		}, i.replayJitter)
 	}
 
+	err = i.replayJournals(filter)
+	if err != nil {
+		return fmt.Errorf("fatal error replaying push journals: %w", err)
+	}
+
+	i.replayMtx.Lock()
+	i.replayDone = true
+	i.replayMtx.Unlock()
+
 	level.Info(log.Logger).Log("msg", "wal replay complete")
 
 	return nil
 }
 
+// replayJournals replays the durable pushes of traces that weren't cut to the wal before the ingester stopped
+func (i *Ingester) replayJournals(filter *wal.ReplayTenantFilter) error {
+	entries, err := os.ReadDir(filepath.Join(i.store.WAL().GetFilepath(), journalDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return filter.Before(entries[i].Name(), entries[j].Name())
+	})
+	for _, e := range entries {
+		tenantID := e.Name()
+		if !e.IsDir() || !filter.Replays(tenantID) {
+			continue
+		}
+
+		instance, err := i.getOrCreateInstance(tenantID)
+		if err != nil {
+			return err
+		}
+		err = instance.replayJournal()
+		if err != nil {
+			return err
+		}
+		level.Info(log.Logger).Log("msg", "replayed push journal", "tenant", tenantID)
+	}
+
+	return nil
+}
+
+func (i *Ingester) replayed(p wal.ReplayProgress) {
+	i.replayMtx.Lock()
+	i.replayProgress = p
+	i.replayMtx.Unlock()
+
+	metricReplayFiles.Set(float64(p.Total))
+	metricReplayedFiles.Set(float64(p.Replayed))
+}
+
 func (i *Ingester) rediscoverLocalBlocks() error {
 	ctx := context.TODO()
 
@@ -299,6 +299,10 @@ func TestFlush(t *testing.T) {
 	// create new ingester.  this should replay wal!
 	ingester, _, _ = defaultIngester(t, tmpDir)
 
+	// the replay is complete and its progress reported
+	require.Empty(t, ingester.ReplayStatus())
+	require.Equal(t, ingester.replayProgress.Total, ingester.replayProgress.Replayed)
+
 	// should be able to find old traces that were replayed
 	for i, traceID := range traceIDs {
 		foundTrace, err := ingester.FindTraceByID(ctx, &tempopb.TraceByIDRequest{
@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"hash"
 	"hash/fnv"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log/level"
+	"github.com/gogo/googleapis/google/rpc"
 	"github.com/gogo/status"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -20,6 +23,7 @@ import (
 	"go.uber.org/atomic"
 	"google.golang.org/grpc/codes"
 
+	"github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/model/trace"
@@ -39,14 +43,16 @@ type traceTooLargeError struct {
 	traceID           common.ID
 	instanceID        string
 	maxBytes, reqSize int
+	discardedSpans    int
 }
 
-func newTraceTooLargeError(traceID common.ID, instanceID string, maxBytes, reqSize int) *traceTooLargeError {
+func newTraceTooLargeError(traceID common.ID, instanceID string, maxBytes, reqSize, discardedSpans int) *traceTooLargeError {
 	return &traceTooLargeError{
-		traceID:    traceID,
-		instanceID: instanceID,
-		maxBytes:   maxBytes,
-		reqSize:    reqSize,
+		traceID:        traceID,
+		instanceID:     instanceID,
+		maxBytes:       maxBytes,
+		reqSize:        reqSize,
+		discardedSpans: discardedSpans,
 	}
 }
 
@@ -56,6 +62,29 @@ func (e traceTooLargeError) Error() string {
 		overrides.ErrorPrefixTraceTooLarge, e.maxBytes, e.reqSize, hex.EncodeToString(e.traceID), e.instanceID)
 }
 
+// traceTruncatedError is returned when only the spans of a push that fit in the max size of the trace were accepted
+type traceTruncatedError struct {
+	traceID        common.ID
+	instanceID     string
+	maxBytes       int
+	discardedSpans int
+}
+
+func newTraceTruncatedError(traceID common.ID, instanceID string, maxBytes, discardedSpans int) *traceTruncatedError {
+	return &traceTruncatedError{
+		traceID:        traceID,
+		instanceID:     instanceID,
+		maxBytes:       maxBytes,
+		discardedSpans: discardedSpans,
+	}
+}
+
+func (e traceTruncatedError) Error() string {
+	return fmt.Sprintf(
+		"%s max size of trace (%d) reached, trace %s for tenant %s truncated and %d spans discarded",
+		overrides.ErrorPrefixTraceTruncated, e.maxBytes, hex.EncodeToString(e.traceID), e.instanceID, e.discardedSpans)
+}
+
 // Errors returned on Query.
 var (
 	ErrTraceMissing = errors.New("Trace missing")
@@ -117,11 +146,19 @@ type instance struct {
 	bytesReceivedTotal *prometheus.CounterVec
 	limiter            *Limiter
 	writer             tempodb.Writer
+	searchScheduler    *searchScheduler
+	cutBatchBytes      int
 
 	local       *local.Backend
 	localReader backend.Reader
 	localWriter backend.Writer
 
+	// journal holds durable pushes until their traces are cut, nil until the first durable push or replay.
+	// journalMtx is held for reading while a durable push is journaled and applied, and for writing while
+	// the journal is truncated.
+	journal    *pushJournal
+	journalMtx sync.RWMutex
+
 	hash hash.Hash32
 }
 
@@ -135,7 +172,7 @@ type searchLocalBlockEntry struct {
 	mtx sync.RWMutex
 }
 
-func newInstance(instanceID string, limiter *Limiter, writer tempodb.Writer, l *local.Backend, useFlatbufferSearch bool) (*instance, error) {
+func newInstance(instanceID string, limiter *Limiter, writer tempodb.Writer, l *local.Backend, useFlatbufferSearch bool, searchScheduler *searchScheduler, cutBatchBytes int) (*instance, error) {
 	i := &instance{
 		traces:               map[uint32]*liveTrace{},
 		largeTraces:          map[uint32]int{},
@@ -148,6 +185,8 @@ func newInstance(instanceID string, limiter *Limiter, writer tempodb.Writer, l *
 		bytesReceivedTotal: metricBytesReceivedTotal,
 		limiter:            limiter,
 		writer:             writer,
+		searchScheduler:    searchScheduler,
+		cutBatchBytes:      cutBatchBytes,
 
 		local:       l,
 		localReader: backend.NewReader(l),
@@ -162,7 +201,31 @@ func newInstance(instanceID string, limiter *Limiter, writer tempodb.Writer, l *
 	return i, nil
 }
 
+// PushBytesRequest pushes every trace in the request. Traces that exceed the max size of a trace do not fail the
+// rest of the request. Their discarded spans are reported in the details of the returned error.
 func (i *instance) PushBytesRequest(ctx context.Context, req *tempopb.PushBytesRequest) error {
+	var (
+		firstErr                      error
+		tooLargeSpans, truncatedSpans int
+		segment                       uint64
+	)
+
+	// durable pushes are only acknowledged once they are synced to the journal
+	if client.DurableAckRequested(ctx) {
+		err := i.openJournal()
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "failed to open push journal: %v", err)
+		}
+
+		i.journalMtx.RLock()
+		defer i.journalMtx.RUnlock()
+
+		segment, err = i.journal.append(req)
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "failed to sync push to journal: %v", err)
+		}
+	}
+
 	for j := range req.Traces {
 		// Search data is optional.
 		var searchData []byte
@@ -170,16 +233,60 @@ func (i *instance) PushBytesRequest(ctx context.Context, req *tempopb.PushBytesR
 			searchData = req.SearchData[j].Slice
 		}
 
-		err := i.PushBytes(ctx, req.Ids[j].Slice, req.Traces[j].Slice, searchData)
-		if err != nil {
+		err := i.pushBytes(ctx, req.Ids[j].Slice, req.Traces[j].Slice, searchData, segment)
+		switch e := err.(type) {
+		case nil:
+			continue
+		case *traceTooLargeError:
+			tooLargeSpans += e.discardedSpans
+		case *traceTruncatedError:
+			truncatedSpans += e.discardedSpans
+		default:
 			return err
 		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
 	}
-	return nil
+
+	if firstErr == nil {
+		return nil
+	}
+
+	return newDiscardedSpansStatus(firstErr.Error(), map[string]int{
+		overrides.ReasonTraceTooLarge:  tooLargeSpans,
+		overrides.ReasonTraceTruncated: truncatedSpans,
+	})
+}
+
+// newDiscardedSpansStatus returns a FailedPrecondition error with the number of discarded spans per reason attached
+// as an ErrorInfo. The distributor uses it to record the discarded spans instead of discarding the entire push.
+func newDiscardedSpansStatus(msg string, discarded map[string]int) error {
+	metadata := make(map[string]string, len(discarded))
+	for reason, count := range discarded {
+		if count > 0 {
+			metadata[reason] = strconv.Itoa(count)
+		}
+	}
+
+	st, err := status.New(codes.FailedPrecondition, msg).WithDetails(&rpc.ErrorInfo{
+		Reason:   overrides.ErrorInfoReasonDiscardedSpans,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, msg)
+	}
+	return st.Err()
 }
 
 // PushBytes is used to push an unmarshalled tempopb.Trace to the instance
 func (i *instance) PushBytes(ctx context.Context, id []byte, traceBytes []byte, searchData []byte) error {
+	return i.pushBytes(ctx, id, traceBytes, searchData, 0)
+}
+
+// pushBytes is PushBytes for a push appended to the given journal segment, 0 if it wasn't journaled
+func (i *instance) pushBytes(ctx context.Context, id []byte, traceBytes []byte, searchData []byte, segment uint64) error {
 	i.measureReceivedBytes(traceBytes, searchData)
 
 	if !validation.ValidTraceID(id) {
@@ -192,26 +299,27 @@ func (i *instance) PushBytes(ctx context.Context, id []byte, traceBytes []byte,
 		return status.Errorf(codes.FailedPrecondition, "%s max live traces exceeded for tenant %s: %v", overrides.ErrorPrefixLiveTracesExceeded, i.instanceID, err)
 	}
 
-	return i.push(ctx, id, traceBytes, searchData)
+	return i.push(ctx, id, traceBytes, searchData, segment)
 }
 
-func (i *instance) push(ctx context.Context, id, traceBytes, searchData []byte) error {
+func (i *instance) push(ctx context.Context, id, traceBytes, searchData []byte, segment uint64) error {
 	i.tracesMtx.Lock()
 	defer i.tracesMtx.Unlock()
 
 	tkn := i.tokenForTraceID(id)
 
 	if maxBytes, ok := i.largeTraces[tkn]; ok {
-		return status.Errorf(codes.FailedPrecondition, (newTraceTooLargeError(id, i.instanceID, maxBytes, len(traceBytes)).Error()))
+		return newTraceTooLargeError(id, i.instanceID, maxBytes, len(traceBytes), countSpans(traceBytes))
 	}
 
 	trace := i.getOrCreateTrace(id)
+	if trace.journalSegment == 0 {
+		trace.journalSegment = segment
+	}
 	err := trace.Push(ctx, i.instanceID, traceBytes, searchData)
-	if err != nil {
-		if e, ok := err.(*traceTooLargeError); ok {
-			i.largeTraces[tkn] = trace.maxBytes
-			return status.Errorf(codes.FailedPrecondition, e.Error())
-		}
+	switch err.(type) {
+	case *traceTooLargeError, *traceTruncatedError:
+		i.largeTraces[tkn] = trace.maxBytes
 	}
 
 	return err
@@ -230,28 +338,175 @@ func (i *instance) CutCompleteTraces(cutoff time.Duration, immediate bool) error
 	tracesToCut := i.tracesToCut(cutoff, immediate)
 	segmentDecoder := model.MustNewSegmentDecoder(model.CurrentEncoding)
 
-	for _, t := range tracesToCut {
-		// sort batches before cutting to reduce combinations during compaction
-		sortByteSlices(t.batches)
+	// vParquet head blocks are appended the unmarshalled traces instead of objects they would decode again
+	i.blocksMtx.RLock()
+	appendTraces := i.headBlock != nil && i.headBlock.SupportsTraces()
+	i.blocksMtx.RUnlock()
 
-		out, err := segmentDecoder.ToObject(t.batches)
-		if err != nil {
-			return err
+	// the traces are written to the head block in batches of about cutBatchBytes, or one by one if 0
+	batchStart := 0
+	batchBytes := 0
+	var objs [][]byte
+	var pbs []*tempopb.Trace
+	for idx, t := range tracesToCut {
+		if appendTraces {
+			pb, err := segmentDecoder.ToTrace(t.batches)
+			if err != nil {
+				return err
+			}
+
+			pbs = append(pbs, pb)
+			for _, b := range t.batches {
+				batchBytes += len(b)
+			}
+		} else {
+			// sort batches before cutting to reduce combinations during compaction
+			sortByteSlices(t.batches)
+
+			out, err := segmentDecoder.ToObject(t.batches)
+			if err != nil {
+				return err
+			}
+
+			objs = append(objs, out)
+			batchBytes += len(out)
+		}
+		if batchBytes < i.cutBatchBytes && idx < len(tracesToCut)-1 {
+			continue
 		}
 
-		err = i.writeTraceToHeadBlock(t.traceID, out, t.searchData, t.start, t.end)
+		err := i.writeTracesToHeadBlock(tracesToCut[batchStart:idx+1], objs, pbs)
+		if errors.Is(err, wal.ErrWALFull) {
+			// keep the traces that were not written so they are cut again once the wal has space
+			i.requeueTraces(tracesToCut[batchStart:])
+			return err
+		}
 		if err != nil {
 			return err
 		}
 
 		// return trace byte slices to be reused by proto marshalling
 		//  WARNING: can't reuse traceid's b/c the appender takes ownership of byte slices that are passed to it
-		tempopb.ReuseByteSlices(t.batches)
+		for _, written := range tracesToCut[batchStart : idx+1] {
+			tempopb.ReuseByteSlices(written.batches)
+		}
+
+		batchStart = idx + 1
+		batchBytes = 0
+		objs = objs[:0]
+		pbs = pbs[:0]
+	}
+
+	// vParquet wal blocks buffer the cut traces in memory until they are flushed. Journaled traces are synced
+	// before they are removed from the journal.
+	if len(tracesToCut) > 0 {
+		var err error
+		i.blocksMtx.Lock()
+		if i.headBlock != nil {
+			if i.hasJournal() {
+				err = i.headBlock.Sync()
+			} else {
+				err = i.headBlock.Flush()
+			}
+		}
+		i.blocksMtx.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	return i.truncateJournal()
+}
+
+func (i *instance) journalPath() string {
+	return filepath.Join(i.writer.WAL().GetFilepath(), journalDir, i.instanceID)
+}
+
+func (i *instance) hasJournal() bool {
+	i.journalMtx.RLock()
+	defer i.journalMtx.RUnlock()
+
+	return i.journal != nil
+}
+
+// openJournal opens the journal on the first durable push. Segments left by a previous run that weren't replayed
+// are kept.
+func (i *instance) openJournal() error {
+	i.journalMtx.Lock()
+	defer i.journalMtx.Unlock()
+
+	if i.journal != nil {
+		return nil
+	}
+
+	j, _, err := openPushJournal(i.journalPath())
+	if err != nil {
+		return err
+	}
+	j.retainBefore = j.segment
+	i.journal = j
+	return nil
+}
+
+// replayJournal pushes the journaled requests left by the previous run into the live traces. Their segments are
+// kept until the traces are cut. A segment may hold pushes of traces that were already cut to the wal, they are
+// pushed again and combined with the written trace when the block is completed or compacted.
+func (i *instance) replayJournal() error {
+	i.journalMtx.Lock()
+	defer i.journalMtx.Unlock()
+
+	j, segments, err := openPushJournal(i.journalPath())
+	if err != nil {
+		return err
+	}
+	i.journal = j
+
+	ctx := context.Background()
+	for _, segment := range segments {
+		err = j.replaySegment(segment, func(req *tempopb.PushBytesRequest) error {
+			for k := range req.Traces {
+				var searchData []byte
+				if len(req.SearchData) > k {
+					searchData = req.SearchData[k].Slice
+				}
+				// traces that exceed the limits are discarded as they were when first pushed
+				_ = i.pushBytes(ctx, req.Ids[k].Slice, req.Traces[k].Slice, searchData, segment)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// truncateJournal starts a new journal segment and removes the segments that no live trace was pushed into
+func (i *instance) truncateJournal() error {
+	i.journalMtx.Lock()
+	defer i.journalMtx.Unlock()
+
+	if i.journal == nil {
+		return nil
+	}
+
+	oldest, err := i.journal.rotate()
+	if err != nil {
+		return err
+	}
+
+	i.tracesMtx.Lock()
+	for _, t := range i.traces {
+		if t.journalSegment != 0 && t.journalSegment < oldest {
+			oldest = t.journalSegment
+		}
+	}
+	i.tracesMtx.Unlock()
+
+	return i.journal.removeBefore(oldest)
+}
+
 // CutBlockIfReady cuts a completingBlock from the HeadBlock if ready.
 // Returns the ID of a block if one was cut or a nil ID if one was not cut, along with the error (if any).
 func (i *instance) CutBlockIfReady(maxBlockLifetime time.Duration, maxBlockBytes uint64, immediate bool) (uuid.UUID, error) {
@@ -279,62 +534,77 @@ func (i *instance) CutBlockIfReady(maxBlockLifetime time.Duration, maxBlockBytes
 	return uuid.Nil, nil
 }
 
-// CompleteBlock moves a completingBlock to a completeBlock. The new completeBlock has the same ID.
-func (i *instance) CompleteBlock(blockID uuid.UUID) error {
+// CompletingBlock returns the completing block with the given ID or nil if it doesn't exist
+func (i *instance) CompletingBlock(blockID uuid.UUID) *wal.AppendBlock {
 	i.blocksMtx.Lock()
-	var completingBlock *wal.AppendBlock
+	defer i.blocksMtx.Unlock()
+
 	for _, iterBlock := range i.completingBlocks {
 		if iterBlock.BlockID() == blockID {
-			completingBlock = iterBlock
-			break
+			return iterBlock
 		}
 	}
-	i.blocksMtx.Unlock()
+	return nil
+}
 
+// CompleteBlock moves a completingBlock to a completeBlock. The new completeBlock has the same ID.
+func (i *instance) CompleteBlock(blockID uuid.UUID) error {
+	completingBlock := i.CompletingBlock(blockID)
 	if completingBlock == nil {
 		return fmt.Errorf("error finding completingBlock")
 	}
 
 	ctx := context.Background()
 
-	backendBlock, err := i.writer.CompleteBlockWithBackend(ctx, completingBlock, model.StaticCombiner, i.localReader, i.localWriter)
-	if err != nil {
-		return errors.Wrap(err, "error completing wal block with local backend")
-	}
+	// the block and its search data are completed aside and committed to the local backend at once, so a crash
+	// never leaves a partial block behind
+	w := i.writer.WAL()
+	completingReader := backend.NewReader(w.CompletingBackend())
+	completingWriter := backend.NewWriter(w.CompletingBackend())
 
-	ingesterBlock, err := wal.NewLocalBlock(ctx, backendBlock, i.local)
+	backendBlock, err := i.writer.CompleteBlockWithOverrides(ctx, completingBlock, model.StaticCombiner, i.blockVersion(), i.limiter.limits.BloomFilterFalsePositive(i.instanceID), completingReader, completingWriter)
 	if err != nil {
-		return errors.Wrap(err, "error creating ingester block")
+		return errors.Wrap(err, "error completing wal block with local backend")
 	}
+	meta := backendBlock.BlockMeta()
 
-	i.blocksMtx.Lock()
-	i.completeBlocks = append(i.completeBlocks, ingesterBlock)
-	i.blocksMtx.Unlock()
-
-	// only build flatbuffer search structures if we are configured to
-	if !i.useFlatbufferSearch {
-		return nil
+	// Search data (optional), only built if we are configured to use flatbuffer search
+	var oldSearch *searchStreamingBlockEntry
+	if i.useFlatbufferSearch {
+		i.blocksMtx.RLock()
+		oldSearch = i.searchAppendBlocks[completingBlock]
+		i.blocksMtx.RUnlock()
 	}
 
-	// Search data (optional)
-	i.blocksMtx.RLock()
-	oldSearch := i.searchAppendBlocks[completingBlock]
-	i.blocksMtx.RUnlock()
-
-	var newSearch *search.BackendSearchBlock
 	if oldSearch != nil {
-		newSearch, err = i.writer.CompleteSearchBlockWithBackend(oldSearch.b, backendBlock.BlockMeta().BlockID, backendBlock.BlockMeta().TenantID, i.localReader, i.localWriter)
+		_, err = i.writer.CompleteSearchBlockWithBackend(oldSearch.b, meta.BlockID, meta.TenantID, completingReader, completingWriter)
 		if err != nil {
 			return err
 		}
 	}
 
+	err = w.CommitBlock(meta.BlockID, meta.TenantID)
+	if err != nil {
+		return errors.Wrap(err, "error committing completed block")
+	}
+
+	backendBlock, err = encoding.OpenBlock(meta, i.localReader)
+	if err != nil {
+		return errors.Wrap(err, "error opening completed block")
+	}
+
+	ingesterBlock, err := wal.NewLocalBlock(ctx, backendBlock, i.local)
+	if err != nil {
+		return errors.Wrap(err, "error creating ingester block")
+	}
+
 	i.blocksMtx.Lock()
 	defer i.blocksMtx.Unlock()
 
-	if newSearch != nil {
+	i.completeBlocks = append(i.completeBlocks, ingesterBlock)
+	if oldSearch != nil {
 		i.searchCompleteBlocks[ingesterBlock] = &searchLocalBlockEntry{
-			b: newSearch,
+			b: search.OpenBackendSearchBlock(meta.BlockID, meta.TenantID, i.localReader),
 		}
 	}
 
@@ -509,6 +779,39 @@ func (i *instance) getOrCreateTrace(traceID []byte) *liveTrace {
 }
 
 // tokenForTraceID hash trace ID, should be called under lock
+// DeleteTrace removes a trace that hasn't been flushed to the backend. The live trace is dropped and a tombstone is
+// appended to the head block and the completing blocks, so the trace never reaches the backend. Blocks that don't
+// support tombstones, vParquet and replayed blocks, are counted and skipped.
+func (i *instance) DeleteTrace(id []byte) (skipped int, err error) {
+	i.tracesMtx.Lock()
+	tkn := i.tokenForTraceID(id)
+	if t, ok := i.traces[tkn]; ok && bytes.Equal(t.traceID, id) {
+		delete(i.traces, tkn)
+		i.traceCount.Store(int32(len(i.traces)))
+	}
+	i.tracesMtx.Unlock()
+
+	i.blocksMtx.Lock()
+	defer i.blocksMtx.Unlock()
+
+	blocks := append([]*wal.AppendBlock{i.headBlock}, i.completingBlocks...)
+	for _, b := range blocks {
+		if b == nil {
+			continue
+		}
+		err = b.Delete(id)
+		if errors.Is(err, common.ErrUnsupported) {
+			skipped++
+			continue
+		}
+		if err != nil {
+			return skipped, fmt.Errorf("error deleting trace from wal block %s: %w", b.BlockID(), err)
+		}
+	}
+
+	return skipped, nil
+}
+
 func (i *instance) tokenForTraceID(id []byte) uint32 {
 	i.hash.Reset()
 	_, _ = i.hash.Write(id)
@@ -525,7 +828,7 @@ func (i *instance) resetHeadBlock() error {
 
 	oldHeadBlock := i.headBlock
 	var err error
-	newHeadBlock, err := i.writer.WAL().NewBlock(uuid.New(), i.instanceID, model.CurrentEncoding)
+	newHeadBlock, err := i.newHeadBlock()
 	if err != nil {
 		return err
 	}
@@ -552,6 +855,70 @@ func (i *instance) resetHeadBlock() error {
 	return nil
 }
 
+// newHeadBlock creates a wal block with the wal block version and encoding of the tenant. The wal config of the
+// runtime config replaces the encoding and ingestion slack of the wal config, tenant overrides take precedence over
+// both. Invalid overrides fall back to the wal config.
+func (i *instance) newHeadBlock() (*wal.AppendBlock, error) {
+	w := i.writer.WAL()
+
+	version := i.limiter.limits.WALBlockVersion(i.instanceID)
+	if version != "" {
+		err := wal.ValidateVersion(version)
+		if err != nil {
+			level.Warn(log.Logger).Log("msg", "invalid wal block version override. using the configured version", "tenant", i.instanceID, "err", err)
+			version = ""
+		}
+	}
+	if version == "" {
+		version = w.Version()
+	}
+
+	encoding := w.Encoding()
+	runtimeCfg := i.limiter.limits.WALRuntimeConfig()
+	if runtimeCfg != nil && runtimeCfg.Encoding != "" {
+		// validated when the runtime config is loaded
+		if enc, err := backend.ParseEncoding(runtimeCfg.Encoding); err == nil {
+			encoding = enc
+		}
+	}
+	if override := i.limiter.limits.WALEncoding(i.instanceID); override != "" {
+		enc, err := backend.ParseEncoding(override)
+		if err != nil {
+			level.Warn(log.Logger).Log("msg", "invalid wal encoding override. using the configured encoding", "tenant", i.instanceID, "err", err)
+		} else {
+			encoding = enc
+		}
+	}
+
+	b, err := w.NewBlockWithVersionAndEncoding(uuid.New(), i.instanceID, model.CurrentEncoding, version, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	if runtimeCfg != nil && runtimeCfg.IngestionTimeRangeSlack > 0 {
+		slack := time.Duration(runtimeCfg.IngestionTimeRangeSlack)
+		b.SetIngestionSlack(slack, slack)
+	}
+	b.SetIngestionSlack(i.limiter.limits.IngestionTimeRangeSlack(i.instanceID))
+	return b, nil
+}
+
+// blockVersion is the version of the blocks completed for the tenant. Invalid overrides fall back to the storage
+// config.
+func (i *instance) blockVersion() string {
+	version := i.limiter.limits.BlockVersion(i.instanceID)
+	if version == "" {
+		return ""
+	}
+
+	_, err := encoding.FromVersion(version)
+	if err != nil {
+		level.Warn(log.Logger).Log("msg", "invalid block version override. using the configured version", "tenant", i.instanceID, "err", err)
+		return ""
+	}
+	return version
+}
+
 func (i *instance) tracesToCut(cutoff time.Duration, immediate bool) []*liveTrace {
 	i.tracesMtx.Lock()
 	defer i.tracesMtx.Unlock()
@@ -573,11 +940,74 @@ func (i *instance) tracesToCut(cutoff time.Duration, immediate bool) []*liveTrac
 	return tracesToCut
 }
 
-func (i *instance) writeTraceToHeadBlock(id common.ID, b []byte, searchData [][]byte, start, end uint32) error {
+// requeueTraces adds cut traces back to the live traces. Traces that were pushed again since they were cut are
+// merged with the live trace.
+func (i *instance) requeueTraces(traces []*liveTrace) {
+	i.tracesMtx.Lock()
+	defer i.tracesMtx.Unlock()
+
+	for _, t := range traces {
+		tkn := i.tokenForTraceID(t.traceID)
+		live, ok := i.traces[tkn]
+		if !ok {
+			i.traces[tkn] = t
+			continue
+		}
+
+		if t.journalSegment != 0 && (live.journalSegment == 0 || t.journalSegment < live.journalSegment) {
+			live.journalSegment = t.journalSegment
+		}
+		live.batches = append(t.batches, live.batches...)
+		live.searchData = append(t.searchData, live.searchData...)
+		live.currentBytes += t.currentBytes
+		live.currentSearchBytes += t.currentSearchBytes
+		if t.start < live.start {
+			live.start = t.start
+		}
+		if t.end > live.end {
+			live.end = t.end
+		}
+	}
+	i.traceCount.Store(int32(len(i.traces)))
+}
+
+// writeTracesToHeadBlock appends the cut traces with their objects, or their decoded traces if passed, to the head
+// block with a single write
+func (i *instance) writeTracesToHeadBlock(traces []*liveTrace, objs [][]byte, pbs []*tempopb.Trace) error {
+	ids := make([]common.ID, 0, len(traces))
+	starts := make([]uint32, 0, len(traces))
+	ends := make([]uint32, 0, len(traces))
+	for _, t := range traces {
+		ids = append(ids, t.traceID)
+		starts = append(starts, t.start)
+		ends = append(ends, t.end)
+	}
+
 	i.blocksMtx.Lock()
 	defer i.blocksMtx.Unlock()
 
-	err := i.headBlock.Append(id, b, start, end)
+	// the head block can be cut to a block without trace support after the traces were decoded, they are
+	// appended as objects then
+	if len(pbs) > 0 && !i.headBlock.SupportsTraces() {
+		segmentDecoder := model.MustNewSegmentDecoder(model.CurrentEncoding)
+		objs = make([][]byte, 0, len(traces))
+		for _, t := range traces {
+			sortByteSlices(t.batches)
+			out, err := segmentDecoder.ToObject(t.batches)
+			if err != nil {
+				return err
+			}
+			objs = append(objs, out)
+		}
+		pbs = nil
+	}
+
+	var err error
+	if len(pbs) > 0 {
+		err = i.headBlock.AppendTraces(ids, pbs, starts, ends)
+	} else {
+		err = i.headBlock.AppendBatch(ids, objs, starts, ends)
+	}
 	if err != nil {
 		return err
 	}
@@ -589,8 +1019,12 @@ func (i *instance) writeTraceToHeadBlock(id common.ID, b []byte, searchData [][]
 		// is reading it. This prevents stalling the write path
 		// while a search is happening. There are mutexes internally
 		// for the parts that aren't.
-		err := entry.b.Append(context.TODO(), id, searchData)
-		return err
+		for _, t := range traces {
+			err := entry.b.Append(context.TODO(), t.traceID, t.searchData)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
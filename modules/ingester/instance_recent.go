@@ -0,0 +1,209 @@
+package ingester
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/model/trace"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+// recentTraces collects the traces returned by RecentTraces and combines the parts of a trace found in the live
+// traces and in several blocks.
+type recentTraces struct {
+	maxBytes  int
+	bytes     int
+	truncated bool
+
+	ids       [][]byte
+	combiners map[string]*trace.Combiner
+}
+
+// add returns false once maxBytes is reached and no more traces should be added
+func (r *recentTraces) add(id []byte, size int, tr *tempopb.Trace) bool {
+	if r.maxBytes > 0 && r.bytes+size > r.maxBytes {
+		r.truncated = true
+		return false
+	}
+	r.bytes += size
+
+	c, ok := r.combiners[string(id)]
+	if !ok {
+		c = trace.NewCombiner()
+		r.combiners[string(id)] = c
+		r.ids = append(r.ids, id)
+	}
+	c.Consume(tr)
+
+	return true
+}
+
+func (r *recentTraces) response() *tempopb.RecentTracesResponse {
+	resp := &tempopb.RecentTracesResponse{
+		Traces:    make([]*tempopb.RecentTrace, 0, len(r.ids)),
+		Truncated: r.truncated,
+	}
+	for _, id := range r.ids {
+		tr, _ := r.combiners[string(id)].Result()
+		resp.Traces = append(resp.Traces, &tempopb.RecentTrace{
+			TraceID: id,
+			Trace:   tr,
+		})
+	}
+	return resp
+}
+
+// RecentTraces returns the live traces and the traces in the head and completing blocks that overlap the time
+// range of the request. Complete blocks are left out, they are already flushed to the backend. Once the size of
+// the returned traces reaches req.MaxBytes the remaining traces are left out and the response is truncated.
+func (i *instance) RecentTraces(ctx context.Context, req *tempopb.RecentTracesRequest) (*tempopb.RecentTracesResponse, error) {
+	overlaps := func(start, end uint32) bool {
+		return start <= req.End && end >= req.Start
+	}
+
+	r := &recentTraces{
+		maxBytes:  int(req.MaxBytes),
+		combiners: map[string]*trace.Combiner{},
+	}
+
+	// live traces, copy the segments to decode them outside of the lock
+	type liveSegments struct {
+		id       []byte
+		segments [][]byte
+		size     int
+	}
+	var live []liveSegments
+
+	i.tracesMtx.Lock()
+	for _, t := range i.traces {
+		if !overlaps(t.start, t.end) {
+			continue
+		}
+		size := 0
+		for _, b := range t.batches {
+			size += len(b)
+		}
+		live = append(live, liveSegments{
+			id:       t.traceID,
+			segments: append([][]byte(nil), t.batches...),
+			size:     size,
+		})
+	}
+	i.tracesMtx.Unlock()
+
+	segmentDecoder := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	for _, l := range live {
+		tr, err := segmentDecoder.PrepareForRead(l.segments)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unmarshal liveTrace: %w", err)
+		}
+		if !r.add(l.id, l.size, tr) {
+			return r.response(), nil
+		}
+	}
+
+	// block objects, copy them under the lock and decode them afterwards
+	objs, err := i.recentObjects(ctx, overlaps, r)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range objs {
+		tr, err := o.decoder.PrepareForRead(o.obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal trace in block %s: %w", o.blockID, err)
+		}
+		if !r.add(o.id, len(o.obj), tr) {
+			break
+		}
+	}
+
+	return r.response(), nil
+}
+
+// recentObject is a copy of an object of a wal block
+type recentObject struct {
+	blockID string
+	id      []byte
+	obj     []byte
+	decoder model.ObjectDecoder
+}
+
+// recentObjects copies the objects of the head and completing blocks that overlap the time range under the blocks
+// lock. Objects that don't fit in the remaining bytes of r are left out, r is truncated then.
+func (i *instance) recentObjects(ctx context.Context, overlaps func(start, end uint32) bool, r *recentTraces) ([]recentObject, error) {
+	i.blocksMtx.RLock()
+	defer i.blocksMtx.RUnlock()
+
+	var objs []recentObject
+	bytes := r.bytes
+	blocks := append([]*wal.AppendBlock{i.headBlock}, i.completingBlocks...)
+	for _, b := range blocks {
+		meta := b.Meta()
+		if meta.TotalObjects == 0 || !overlaps(uint32(meta.StartTime.Unix()), uint32(meta.EndTime.Unix())) {
+			continue
+		}
+
+		var full bool
+		var err error
+		objs, bytes, full, err = recentObjectsInBlock(ctx, b, overlaps, r, objs, bytes)
+		if err != nil {
+			return nil, err
+		}
+		if full {
+			break
+		}
+	}
+
+	return objs, nil
+}
+
+// recentObjectsInBlock appends copies of the objects of the block that overlap the time range to objs. It returns
+// true once no more objects fit.
+func recentObjectsInBlock(ctx context.Context, b *wal.AppendBlock, overlaps func(start, end uint32) bool, r *recentTraces, objs []recentObject, bytes int) ([]recentObject, int, bool, error) {
+	iter, err := b.ReadIterator(model.StaticCombiner)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to iterate block %s: %w", b.BlockID(), err)
+	}
+	defer iter.Close()
+
+	decoder, err := model.NewObjectDecoder(b.Meta().DataEncoding)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, false, err
+		}
+
+		id, obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			return objs, bytes, false, nil
+		}
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("failed to iterate block %s: %w", b.BlockID(), err)
+		}
+
+		// objects of encodings without a fast range are always included
+		if start, end, err := decoder.FastRange(obj); err == nil && !overlaps(start, end) {
+			continue
+		}
+
+		if r.maxBytes > 0 && bytes+len(obj) > r.maxBytes {
+			r.truncated = true
+			return objs, bytes, true, nil
+		}
+		bytes += len(obj)
+
+		// the iterator reuses its buffers
+		objs = append(objs, recentObject{
+			blockID: b.BlockID().String(),
+			id:      append([]byte(nil), id...),
+			obj:     append([]byte(nil), obj...),
+			decoder: decoder,
+		})
+	}
+}
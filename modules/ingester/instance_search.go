@@ -42,7 +42,7 @@ func (i *instance) Search(ctx context.Context, req *tempopb.SearchRequest) (*tem
 	// deadlocking with other activity (ingest, flushing), caused by releasing
 	// and then attempting to retake the lock.
 	i.blocksMtx.RLock()
-	i.searchWAL(ctx, p, sr)
+	i.searchWAL(ctx, req, p, sr)
 	i.searchLocalBlocks(ctx, req, p, sr)
 	i.blocksMtx.RUnlock()
 
@@ -133,8 +133,9 @@ func (i *instance) searchLiveTraces(ctx context.Context, p search.Pipeline, sr *
 	}()
 }
 
-// searchWAL starts a search task for every WAL block. Must be called under lock.
-func (i *instance) searchWAL(ctx context.Context, p search.Pipeline, sr *search.Results) {
+// searchWAL starts a search task for every WAL block. vParquet blocks are searched with the columnar readers
+// instead of their search data. Must be called under lock.
+func (i *instance) searchWAL(ctx context.Context, req *tempopb.SearchRequest, p search.Pipeline, sr *search.Results) {
 	searchFunc := func(e *searchStreamingBlockEntry) {
 		span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchWAL")
 		defer span.Finish()
@@ -153,14 +154,54 @@ func (i *instance) searchWAL(ctx context.Context, p search.Pipeline, sr *search.
 		}
 	}
 
+	searchParquetFunc := func(b *wal.AppendBlock) {
+		span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchWAL")
+		defer span.Finish()
+
+		defer sr.FinishWorker()
+
+		span.SetTag("blockID", b.BlockID().String())
+
+		resp, err := b.Search(ctx, req, common.SearchOptions{})
+		if err != nil {
+			level.Error(log.Logger).Log("msg", "error searching wal block", "blockID", b.BlockID().String(), "err", err)
+			return
+		}
+
+		for _, t := range resp.Traces {
+			sr.AddResult(ctx, t)
+		}
+		sr.AddBlockInspected()
+
+		sr.AddBytesInspected(resp.Metrics.InspectedBytes)
+		sr.AddTraceInspected(resp.Metrics.InspectedTraces)
+	}
+
 	// head block
 	sr.StartWorker()
-	go searchFunc(i.searchHeadBlock)
+	if hb := i.headBlock; hb != nil && hb.SupportsSearch() {
+		// the head block is captured under the lock, the task may run after it is cut
+		i.searchScheduler.Go(i.instanceID, func() { searchParquetFunc(hb) })
+	} else {
+		e := i.searchHeadBlock
+		i.searchScheduler.Go(i.instanceID, func() { searchFunc(e) })
+	}
 
 	// completing blocks
-	for _, e := range i.searchAppendBlocks {
+	for _, b := range i.completingBlocks {
+		if b.SupportsSearch() {
+			b := b
+			sr.StartWorker()
+			i.searchScheduler.Go(i.instanceID, func() { searchParquetFunc(b) })
+		}
+	}
+	for b, e := range i.searchAppendBlocks {
+		if b.SupportsSearch() {
+			continue
+		}
+		e := e
 		sr.StartWorker()
-		go searchFunc(e)
+		i.searchScheduler.Go(i.instanceID, func() { searchFunc(e) })
 	}
 }
 
@@ -168,8 +209,9 @@ func (i *instance) searchWAL(ctx context.Context, p search.Pipeline, sr *search.
 func (i *instance) searchLocalBlocks(ctx context.Context, req *tempopb.SearchRequest, p search.Pipeline, sr *search.Results) {
 	// first check the searchCompleteBlocks map. if there is an entry for a block here we want to search it first
 	for _, e := range i.searchCompleteBlocks {
+		e := e
 		sr.StartWorker()
-		go func(e *searchLocalBlockEntry) {
+		i.searchScheduler.Go(i.instanceID, func() {
 			span, ctx := opentracing.StartSpanFromContext(ctx, "instance.fb.searchLocalBlocks")
 			defer span.Finish()
 
@@ -185,7 +227,7 @@ func (i *instance) searchLocalBlocks(ctx context.Context, req *tempopb.SearchReq
 			if err != nil {
 				level.Error(log.Logger).Log("msg", "error searching local block", "blockID", e.b.BlockID().String(), "err", err)
 			}
-		}(e)
+		})
 	}
 
 	// next check all complete blocks to see if they were not searched, if they weren't then attempt to search them
@@ -202,8 +244,9 @@ func (i *instance) searchLocalBlocks(ctx context.Context, req *tempopb.SearchReq
 			continue
 		}
 
+		e := e
 		sr.StartWorker()
-		go func(e *wal.LocalBlock) {
+		i.searchScheduler.Go(i.instanceID, func() {
 			defer sr.FinishWorker()
 
 			span, ctx := opentracing.StartSpanFromContext(ctx, "instance.searchLocalBlocks")
@@ -227,7 +270,7 @@ func (i *instance) searchLocalBlocks(ctx context.Context, req *tempopb.SearchReq
 
 			sr.AddBytesInspected(resp.Metrics.InspectedBytes)
 			sr.AddTraceInspected(resp.Metrics.InspectedTraces)
-		}(e)
+		})
 	}
 }
 
@@ -306,34 +349,78 @@ func (i *instance) SearchTagValues(ctx context.Context, tagName string) (*tempop
 	limit := i.limiter.limits.MaxBytesPerTagValuesQuery(userID)
 	distinctValues := util.NewDistinctStringCollector(limit)
 
+	err = i.searchTagValues(ctx, tagName, func(_, v string) { distinctValues.Collect(v) }, distinctValues.Exceeded)
+	if err != nil {
+		return nil, err
+	}
+
+	if distinctValues.Exceeded() {
+		level.Warn(log.Logger).Log("msg", "size of tag values in instance exceeded limit, reduce cardinality or size of tags", "tag", tagName, "userID", userID, "limit", limit, "total", distinctValues.TotalDataSize())
+	}
+
+	return &tempopb.SearchTagValuesResponse{
+		TagValues: distinctValues.Strings(),
+	}, nil
+}
+
+// SearchTagValuesV2 returns the values of the tag with their type and number of occurrences. Values of blocks with
+// typed columns get the type of their column, the type of the other values is inferred. The search data of live
+// traces and the wal counts a value once per trace.
+func (i *instance) SearchTagValuesV2(ctx context.Context, tagName string) (*tempopb.SearchTagValuesV2Response, error) {
+	userID, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := i.limiter.limits.MaxBytesPerTagValuesQuery(userID)
+	distinctValues := util.NewDistinctValueCollector(limit)
+
+	err = i.searchTagValues(ctx, tagName, func(typ, v string) { distinctValues.Collect(typ, v, 1) }, distinctValues.Exceeded)
+	if err != nil {
+		return nil, err
+	}
+
+	if distinctValues.Exceeded() {
+		level.Warn(log.Logger).Log("msg", "size of tag values in instance exceeded limit, reduce cardinality or size of tags", "tag", tagName, "userID", userID, "limit", limit, "total", distinctValues.TotalDataSize())
+	}
+
+	return &tempopb.SearchTagValuesV2Response{
+		TagValues: distinctValues.Values(),
+	}, nil
+}
+
+// searchTagValues passes every value of the tag in the live traces, the wal and the local blocks to cb until
+// exceeded returns true.
+func (i *instance) searchTagValues(ctx context.Context, tagName string, cb common.TypedTagCallback, exceeded func() bool) error {
+	untyped := func(v string) { cb(common.InferTagValueType(v), v) }
+
 	// live traces
 	kv := &tempofb.KeyValues{}
 	tagNameBytes := []byte(tagName)
-	err = i.visitSearchEntriesLiveTraces(ctx, func(entry *tempofb.SearchEntry) {
+	err := i.visitSearchEntriesLiveTraces(ctx, func(entry *tempofb.SearchEntry) {
 		kv := tempofb.FindTag(entry, kv, tagNameBytes)
 		if kv != nil {
 			for i, ii := 0, kv.ValueLength(); i < ii; i++ {
-				key := string(kv.Value(i))
-				distinctValues.Collect(key)
+				untyped(string(kv.Value(i)))
 			}
 		}
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// wal + search blocks
-	if !distinctValues.Exceeded() {
+	if !exceeded() {
 		err = i.visitSearchableBlocks(ctx, func(block search.SearchableBlock) error {
-			return block.TagValues(ctx, tagName, distinctValues.Collect)
+			return block.TagValues(ctx, tagName, untyped)
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	// local blocks
-	if !distinctValues.Exceeded() {
+	if !exceeded() {
 		i.blocksMtx.RLock()
 		defer i.blocksMtx.RUnlock()
 		for _, b := range i.completeBlocks {
@@ -343,27 +430,25 @@ func (i *instance) SearchTagValues(ctx context.Context, tagName string) (*tempop
 				continue
 			}
 
-			err = b.SearchTagValues(ctx, tagName, distinctValues.Collect, common.SearchOptions{})
+			if typed, ok := b.BackendBlock.(common.TypedTagValuesSearcher); ok {
+				err = typed.SearchTypedTagValues(ctx, tagName, cb, common.SearchOptions{})
+			} else {
+				err = b.SearchTagValues(ctx, tagName, untyped, common.SearchOptions{})
+			}
 			if err == common.ErrUnsupported {
 				level.Warn(log.Logger).Log("msg", "block does not support tag value search", "blockID", b.BlockMeta().BlockID)
 				continue
 			}
 			if err != nil {
-				return nil, fmt.Errorf("unexpected error searching tag values (%s): %w", b.BlockMeta().BlockID, err)
+				return fmt.Errorf("unexpected error searching tag values (%s): %w", b.BlockMeta().BlockID, err)
 			}
-			if distinctValues.Exceeded() {
+			if exceeded() {
 				break
 			}
 		}
 	}
 
-	if distinctValues.Exceeded() {
-		level.Warn(log.Logger).Log("msg", "size of tag values in instance exceeded limit, reduce cardinality or size of tags", "tag", tagName, "userID", userID, "limit", limit, "total", distinctValues.TotalDataSize())
-	}
-
-	return &tempopb.SearchTagValuesResponse{
-		TagValues: distinctValues.Strings(),
-	}, nil
+	return nil
 }
 
 func (i *instance) visitSearchEntriesLiveTraces(ctx context.Context, visitFn func(entry *tempofb.SearchEntry)) error {
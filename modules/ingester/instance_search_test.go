@@ -23,6 +23,8 @@ import (
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 	"github.com/grafana/tempo/tempodb/search"
 )
 
@@ -107,6 +109,51 @@ func checkEqual(t *testing.T, ids [][]byte, sr *tempopb.SearchResponse) {
 	}
 }
 
+func TestInstanceSearchParquetWAL(t *testing.T) {
+	limits, err := overrides.NewOverrides(overrides.Limits{
+		WALBlockVersion: vparquet.VersionString,
+	})
+	require.NoError(t, err, "unexpected error creating limits")
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+
+	ingester, _, _ := defaultIngester(t, t.TempDir())
+	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false, nil, 0)
+	require.NoError(t, err, "unexpected error creating new instance")
+	require.Equal(t, vparquet.VersionString, i.headBlock.Meta().Version)
+
+	// search data isn't used for vParquet wal blocks, all traces match the service name
+	ids, _ := writeTracesWithSearchData(t, i, "foo", "bar", false)
+	err = i.CutCompleteTraces(0, true)
+	require.NoError(t, err)
+
+	// the cut traces are appended without objects
+	for _, id := range ids {
+		trace, err := i.FindTraceByID(context.Background(), id)
+		require.NoError(t, err)
+		require.NotNil(t, trace)
+	}
+
+	req := &tempopb.SearchRequest{
+		Tags:  map[string]string{"service.name": "test-service"},
+		Limit: 1000,
+	}
+	sr, err := i.Search(context.Background(), req)
+	require.NoError(t, err)
+	assert.Len(t, sr.Traces, 100)
+
+	// completing block
+	blockID, err := i.CutBlockIfReady(0, 0, true)
+	require.NoError(t, err)
+	assert.NotEqual(t, blockID, uuid.Nil)
+
+	sr, err = i.Search(context.Background(), req)
+	require.NoError(t, err)
+	assert.Len(t, sr.Traces, 100)
+
+	err = i.CompleteBlock(blockID)
+	require.NoError(t, err)
+}
+
 func TestInstanceSearchTags(t *testing.T) {
 	for _, b := range []bool{true, false} {
 		t.Run(fmt.Sprintf("flatbufferSearch:%t", b), func(t *testing.T) {
@@ -155,6 +202,17 @@ func testSearchTagsAndValues(t *testing.T, ctx context.Context, i *instance, tag
 	assert.Contains(t, sr.TagNames, tagName)
 	assert.Equal(t, tagName, sr.TagNames[0])
 	assert.Equal(t, expectedTagValues, srv.TagValues)
+
+	srv2, err := i.SearchTagValuesV2(ctx, tagName)
+	require.NoError(t, err)
+
+	values := make([]string, 0, len(srv2.TagValues))
+	for _, v := range srv2.TagValues {
+		assert.Equal(t, common.TagValueTypeString, v.Type)
+		assert.Greater(t, v.Count, uint64(0))
+		values = append(values, v.Value)
+	}
+	assert.Equal(t, expectedTagValues, values)
 }
 
 // TestInstanceSearchMaxBytesPerTagValuesQueryReturnsPartial confirms that SearchTagValues returns
@@ -171,7 +229,7 @@ func TestInstanceSearchMaxBytesPerTagValuesQueryReturnsPartial(t *testing.T) {
 			tempDir := t.TempDir()
 
 			ingester, _, _ := defaultIngester(t, tempDir)
-			i, err := newInstance("fake", limiter, ingester.store, ingester.local, b)
+			i, err := newInstance("fake", limiter, ingester.store, ingester.local, b, nil, 0)
 			assert.NoError(t, err, "unexpected error creating new instance")
 
 			var tagKey = "foo"
@@ -259,7 +317,7 @@ func TestInstanceSearchDoesNotRace(t *testing.T) {
 	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
 
 	ingester, _, _ := defaultIngester(t, t.TempDir())
-	i, err := newInstance("fake", limiter, ingester.store, ingester.local, false)
+	i, err := newInstance("fake", limiter, ingester.store, ingester.local, false, nil, 0)
 	require.NoError(t, err)
 
 	// This matches the encoding for live traces, since
@@ -4,19 +4,32 @@ import (
 	"context"
 	"encoding/binary"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gogo/googleapis/google/rpc"
+	"github.com/gogo/status"
 	"github.com/google/uuid"
+	"github.com/grafana/dskit/services"
+	prom_model "github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 
+	"github.com/grafana/tempo/modules/ingester/client"
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/model/trace"
 	"github.com/grafana/tempo/pkg/tempopb"
 	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 )
 
 const testTenantID = "fake"
@@ -135,6 +148,243 @@ func TestInstanceFind(t *testing.T) {
 	queryAll(t, i, ids, traces)
 }
 
+func TestInstanceWALEncodingOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		expected func(*Ingester) backend.Encoding
+	}{
+		{
+			name:     "no override",
+			expected: func(i *Ingester) backend.Encoding { return i.store.WAL().Encoding() },
+		},
+		{
+			name:     "override",
+			override: "snappy",
+			expected: func(*Ingester) backend.Encoding { return backend.EncSnappy },
+		},
+		{
+			name:     "invalid override",
+			override: "foo",
+			expected: func(i *Ingester) backend.Encoding { return i.store.WAL().Encoding() },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limits, err := overrides.NewOverrides(overrides.Limits{
+				WALEncoding: tc.override,
+			})
+			require.NoError(t, err, "unexpected error creating limits")
+			limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+
+			ingester, _, _ := defaultIngester(t, t.TempDir())
+			i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false, nil, 0)
+			require.NoError(t, err, "unexpected error creating new instance")
+			require.Equal(t, tc.expected(ingester), i.headBlock.Meta().Encoding)
+
+			id := make([]byte, 16)
+			rand.Read(id)
+			testTrace := test.MakeTrace(10, id)
+			trace.SortTrace(testTrace)
+			traceBytes, err := model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForWrite(testTrace, 0, 0)
+			require.NoError(t, err)
+			require.NoError(t, i.PushBytes(context.Background(), id, traceBytes, nil))
+			require.NoError(t, i.CutCompleteTraces(0, true))
+
+			queryAll(t, i, [][]byte{id}, []*tempopb.Trace{testTrace})
+		})
+	}
+}
+
+func TestInstanceWALRuntimeConfig(t *testing.T) {
+	overridesFile := filepath.Join(t.TempDir(), "overrides.yaml")
+	writeRuntimeConfig := func(cfg string) {
+		require.NoError(t, os.WriteFile(overridesFile, []byte(cfg), 0o600))
+	}
+	writeRuntimeConfig(`
+wal:
+  encoding: snappy
+  ingestion_time_range_slack: 10m
+overrides:
+  tenant-override:
+    wal_encoding: lz4
+`)
+
+	limits, err := overrides.NewOverrides(overrides.Limits{
+		PerTenantOverrideConfig: overridesFile,
+		PerTenantOverridePeriod: prom_model.Duration(50 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), limits))
+	defer services.StopAndAwaitTerminated(context.Background(), limits) //nolint:errcheck
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+
+	ingester, _, _ := defaultIngester(t, t.TempDir())
+	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, backend.EncSnappy, i.headBlock.Meta().Encoding)
+
+	// tenant overrides take precedence
+	overridden, err := newInstance("tenant-override", limiter, ingester.store, ingester.local, false, nil, 0)
+	require.NoError(t, err)
+	require.Equal(t, backend.EncLZ4_4M, overridden.headBlock.Meta().Encoding)
+
+	// an invalid runtime config is rejected and the previous one kept
+	writeRuntimeConfig(`
+wal:
+  encoding: foo
+`)
+	time.Sleep(200 * time.Millisecond)
+	require.Equal(t, "snappy", limits.WALRuntimeConfig().Encoding)
+
+	// the next head block picks up the reloaded config
+	writeRuntimeConfig(`
+wal:
+  encoding: zstd
+`)
+	require.Eventually(t, func() bool {
+		cfg := limits.WALRuntimeConfig()
+		return cfg != nil && cfg.Encoding == "zstd"
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, backend.EncSnappy, i.headBlock.Meta().Encoding)
+	require.NoError(t, i.resetHeadBlock())
+	require.Equal(t, backend.EncZstd, i.headBlock.Meta().Encoding)
+}
+
+func TestInstanceBlockVersionOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		override string
+		expected string
+	}{
+		{
+			name:     "no override",
+			expected: encoding.DefaultEncoding().Version(),
+		},
+		{
+			name:     "override",
+			override: v2.VersionString,
+			expected: v2.VersionString,
+		},
+		{
+			name:     "invalid override",
+			override: "foo",
+			expected: encoding.DefaultEncoding().Version(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			limits, err := overrides.NewOverrides(overrides.Limits{
+				BlockVersion: tc.override,
+			})
+			require.NoError(t, err, "unexpected error creating limits")
+			limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+
+			ingester, _, _ := defaultIngester(t, t.TempDir())
+			i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false, nil, 0)
+			require.NoError(t, err, "unexpected error creating new instance")
+
+			id := test.ValidTraceID(nil)
+			traceBytes, err := model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForWrite(test.MakeTrace(10, id), 0, 0)
+			require.NoError(t, err)
+			require.NoError(t, i.PushBytes(context.Background(), id, traceBytes, nil))
+			require.NoError(t, i.CutCompleteTraces(0, true))
+
+			blockID, err := i.CutBlockIfReady(0, 0, true)
+			require.NoError(t, err)
+			require.NoError(t, i.CompleteBlock(blockID))
+
+			require.Len(t, i.completeBlocks, 1)
+			assert.Equal(t, tc.expected, i.completeBlocks[0].BlockMeta().Version)
+		})
+	}
+}
+
+func TestInstanceIngestionSlackOverride(t *testing.T) {
+	limits, err := overrides.NewOverrides(overrides.Limits{
+		IngestionTimeRangeSlackPast:   prom_model.Duration(48 * time.Hour),
+		IngestionTimeRangeSlackFuture: prom_model.Duration(time.Hour),
+	})
+	require.NoError(t, err, "unexpected error creating limits")
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+
+	ingester, _, _ := defaultIngester(t, t.TempDir())
+	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false, nil, 0)
+	require.NoError(t, err, "unexpected error creating new instance")
+
+	// historical spans within the tenant's slack aren't clamped to now
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	end := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+	id := test.ValidTraceID(nil)
+	obj, err := model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForWrite(test.MakeTrace(1, id), uint32(start.Unix()), uint32(end.Unix()))
+	require.NoError(t, err)
+	require.NoError(t, i.headBlock.Append(id, obj, uint32(start.Unix()), uint32(end.Unix())))
+
+	assert.Equal(t, start.Unix(), i.headBlock.Meta().StartTime.Unix())
+	assert.Equal(t, end.Unix(), i.headBlock.Meta().EndTime.Unix())
+}
+
+func TestInstanceRecentTraces(t *testing.T) {
+	i, _ := defaultInstance(t)
+
+	now := uint32(time.Now().Unix())
+	ranges := [][2]uint32{
+		{now - 100, now - 95},
+		{now - 60, now - 55},
+		{now - 10, now - 5},
+	}
+
+	ids := [][]byte{}
+	traces := []*tempopb.Trace{}
+	push := func(j int) {
+		traceBytes, err := model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForWrite(traces[j], ranges[j][0], ranges[j][1])
+		require.NoError(t, err)
+		require.NoError(t, i.PushBytes(context.Background(), ids[j], traceBytes, nil))
+	}
+	for j := range ranges {
+		id := test.ValidTraceID(nil)
+		testTrace := test.MakeTrace(10, id)
+		trace.SortTrace(testTrace)
+
+		ids = append(ids, id)
+		traces = append(traces, testTrace)
+		push(j)
+	}
+
+	recent := func(req *tempopb.RecentTracesRequest) *tempopb.RecentTracesResponse {
+		resp, err := i.RecentTraces(context.Background(), req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// live traces
+	resp := recent(&tempopb.RecentTracesRequest{Start: now - 70, End: now - 50})
+	require.Len(t, resp.Traces, 1)
+	assert.Equal(t, ids[1], resp.Traces[0].TraceID)
+	assert.Equal(t, traces[1], resp.Traces[0].Trace)
+
+	// head block
+	require.NoError(t, i.CutCompleteTraces(0, true))
+	resp = recent(&tempopb.RecentTracesRequest{Start: now - 70, End: now})
+	require.Len(t, resp.Traces, 2)
+
+	// completing block and live trace are combined
+	_, err := i.CutBlockIfReady(0, 0, true)
+	require.NoError(t, err)
+	push(1)
+	resp = recent(&tempopb.RecentTracesRequest{Start: now - 70, End: now - 50})
+	require.Len(t, resp.Traces, 1)
+	assert.Equal(t, ids[1], resp.Traces[0].TraceID)
+	assert.Equal(t, traces[1], resp.Traces[0].Trace)
+
+	// max bytes
+	resp = recent(&tempopb.RecentTracesRequest{Start: now - 200, End: now, MaxBytes: 1})
+	assert.Empty(t, resp.Traces)
+	assert.True(t, resp.Truncated)
+}
+
 func queryAll(t *testing.T, i *instance, ids [][]byte, traces []*tempopb.Trace) {
 	for j, id := range ids {
 		trace, err := i.FindTraceByID(context.Background(), id)
@@ -282,7 +532,7 @@ func TestInstanceLimits(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false)
+			i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false, nil, 0)
 			require.NoError(t, err, "unexpected error creating new instance")
 
 			for j, push := range tt.pushes {
@@ -372,6 +622,139 @@ func TestInstanceCutCompleteTraces(t *testing.T) {
 	}
 }
 
+func TestInstanceRequeueTraces(t *testing.T) {
+	instance, _ := defaultInstance(t)
+
+	cutID := make([]byte, 16)
+	rand.Read(cutID)
+	pushedID := make([]byte, 16)
+	rand.Read(pushedID)
+
+	cut := &liveTrace{traceID: cutID, batches: [][]byte{{0x01}}, start: 10, end: 20}
+	cutAndPushed := &liveTrace{traceID: pushedID, batches: [][]byte{{0x02}}, start: 10, end: 20, currentBytes: 1}
+
+	// the second trace was pushed again after it was cut
+	pushed := &liveTrace{traceID: pushedID, batches: [][]byte{{0x03}}, start: 15, end: 30, currentBytes: 1}
+	instance.traces[instance.tokenForTraceID(pushedID)] = pushed
+
+	instance.requeueTraces([]*liveTrace{cut, cutAndPushed})
+
+	require.Len(t, instance.traces, 2)
+	require.Equal(t, int32(2), instance.traceCount.Load())
+	require.Equal(t, cut, instance.traces[instance.tokenForTraceID(cutID)])
+
+	merged := instance.traces[instance.tokenForTraceID(pushedID)]
+	require.Equal(t, [][]byte{{0x02}, {0x03}}, merged.batches)
+	require.Equal(t, uint32(10), merged.start)
+	require.Equal(t, uint32(30), merged.end)
+	require.Equal(t, 2, merged.currentBytes)
+}
+
+func TestInstanceWriteTracesToHeadBlockWithoutTraceSupport(t *testing.T) {
+	instance, _ := defaultInstance(t)
+	require.False(t, instance.headBlock.SupportsTraces())
+
+	// the traces were decoded for a head block that was cut to a block without trace support in the meantime
+	dec := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	traces := make([]*liveTrace, 0, 2)
+	pbs := make([]*tempopb.Trace, 0, 2)
+	for i := 0; i < 2; i++ {
+		id := test.ValidTraceID(nil)
+		segment, err := dec.PrepareForWrite(test.MakeTrace(2, id), 10, 20)
+		require.NoError(t, err)
+		pb, err := dec.ToTrace([][]byte{segment})
+		require.NoError(t, err)
+
+		traces = append(traces, &liveTrace{traceID: id, batches: [][]byte{segment}, start: 10, end: 20})
+		pbs = append(pbs, pb)
+	}
+
+	require.NoError(t, instance.writeTracesToHeadBlock(traces, nil, pbs))
+	for _, tr := range traces {
+		found, err := instance.FindTraceByID(context.Background(), tr.traceID)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+	}
+}
+
+func TestInstanceDeleteTrace(t *testing.T) {
+	i, _ := defaultInstance(t)
+
+	push := func() ([]byte, *tempopb.Trace) {
+		id := make([]byte, 16)
+		rand.Read(id)
+
+		testTrace := test.MakeTrace(10, id)
+		trace.SortTrace(testTrace)
+		traceBytes, err := model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForWrite(testTrace, 0, 0)
+		require.NoError(t, err)
+		require.NoError(t, i.PushBytes(context.Background(), id, traceBytes, nil))
+		return id, testTrace
+	}
+
+	// one trace in the head block, one in a completing block and one live trace
+	headID, _ := push()
+	completingID, _ := push()
+	require.NoError(t, i.CutCompleteTraces(0, true))
+	blockID, err := i.CutBlockIfReady(0, 0, true)
+	require.NoError(t, err)
+	require.NotEqual(t, uuid.Nil, blockID)
+
+	headID, _ = push()
+	require.NoError(t, i.CutCompleteTraces(0, true))
+	liveID, _ := push()
+	keptID, keptTrace := push()
+
+	for _, id := range [][]byte{headID, completingID, liveID} {
+		skipped, err := i.DeleteTrace(id)
+		require.NoError(t, err)
+		require.Equal(t, 0, skipped)
+
+		tr, err := i.FindTraceByID(context.Background(), id)
+		require.NoError(t, err)
+		require.Empty(t, tr.GetBatches())
+	}
+	require.Equal(t, int32(1), i.traceCount.Load())
+	queryAll(t, i, [][]byte{keptID}, []*tempopb.Trace{keptTrace})
+
+	// the deleted traces don't reach the completed block
+	require.NoError(t, i.CompleteBlock(blockID))
+	tr, err := i.FindTraceByID(context.Background(), completingID)
+	require.NoError(t, err)
+	require.Empty(t, tr.GetBatches())
+}
+
+func TestInstanceDurablePushJournal(t *testing.T) {
+	instance, ingester := defaultInstance(t)
+
+	md, _ := metadata.FromOutgoingContext(client.WithDurableAck(context.Background()))
+	durableCtx := metadata.NewIncomingContext(context.Background(), md)
+
+	durableID := test.ValidTraceID(nil)
+	err := instance.PushBytesRequest(durableCtx, makeRequest(durableID))
+	require.NoError(t, err)
+	err = instance.PushBytesRequest(context.Background(), makeRequest(test.ValidTraceID(nil)))
+	require.NoError(t, err)
+
+	segments, err := journalSegments(instance.journalPath())
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+
+	// a restarted instance replays the durable push only
+	restarted, err := newInstance(testTenantID, instance.limiter, ingester.store, ingester.local, false, nil, 0)
+	require.NoError(t, err)
+	require.NoError(t, restarted.replayJournal())
+	require.Len(t, restarted.traces, 1)
+	require.Contains(t, restarted.traces, restarted.tokenForTraceID(durableID))
+
+	// segments are removed once their traces are cut
+	require.NoError(t, restarted.CutCompleteTraces(0, true))
+	segments, err = journalSegments(instance.journalPath())
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	require.Equal(t, restarted.journal.segment, segments[0])
+}
+
 func TestInstanceCutBlockIfReady(t *testing.T) {
 	tt := []struct {
 		name               string
@@ -489,7 +872,7 @@ func TestInstanceFailsLargeTracesEvenAfterFlushing(t *testing.T) {
 	require.NoError(t, err)
 	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
 
-	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false)
+	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false, nil, 0)
 	require.NoError(t, err)
 
 	pushFn := func(byteCount int) error {
@@ -502,13 +885,13 @@ func TestInstanceFailsLargeTracesEvenAfterFlushing(t *testing.T) {
 
 	// Pushing again fails
 	err = pushFn(3)
-	require.Contains(t, err.Error(), (newTraceTooLargeError(id, i.instanceID, maxTraceBytes, 3)).Error())
+	require.Contains(t, err.Error(), (newTraceTooLargeError(id, i.instanceID, maxTraceBytes, 3, 0)).Error())
 
 	// Pushing still fails after flush
 	err = i.CutCompleteTraces(0, true)
 	require.NoError(t, err)
 	err = pushFn(5)
-	require.Contains(t, err.Error(), (newTraceTooLargeError(id, i.instanceID, maxTraceBytes, 5)).Error())
+	require.Contains(t, err.Error(), (newTraceTooLargeError(id, i.instanceID, maxTraceBytes, 5, 0)).Error())
 
 	// Cut block and then pushing works again
 	_, err = i.CutBlockIfReady(0, 0, true)
@@ -517,6 +900,48 @@ func TestInstanceFailsLargeTracesEvenAfterFlushing(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestInstancePartiallyAcceptsLargeTraces(t *testing.T) {
+	ingester, _, _ := defaultIngester(t, t.TempDir())
+
+	limits, err := overrides.NewOverrides(overrides.Limits{
+		MaxBytesPerTrace: 1000,
+	})
+	require.NoError(t, err)
+	limiter := NewLimiter(limits, &ringCountMock{count: 1}, 1)
+
+	i, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, false, nil, 0)
+	require.NoError(t, err)
+
+	large := makeRequestWithByteLimit(1500, []byte{0x01})
+	small := makeRequestWithByteLimit(300, []byte{0x02})
+	req := &tempopb.PushBytesRequest{
+		Ids:    append(large.Ids, small.Ids...),
+		Traces: append(large.Traces, small.Traces...),
+	}
+
+	// the large trace is truncated and the small trace is accepted
+	err = i.PushBytesRequest(context.Background(), req)
+	require.Error(t, err)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+	assert.True(t, strings.HasPrefix(st.Message(), overrides.ErrorPrefixTraceTruncated))
+	require.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*rpc.ErrorInfo)
+	assert.Equal(t, overrides.ErrorInfoReasonDiscardedSpans, info.Reason)
+	assert.NotEmpty(t, info.Metadata[overrides.ReasonTraceTruncated])
+	assert.Empty(t, info.Metadata[overrides.ReasonTraceTooLarge])
+
+	assert.Equal(t, 2, len(i.traces))
+
+	// further pushes to the truncated trace are rejected entirely
+	err = i.PushBytesRequest(context.Background(), makeRequestWithByteLimit(100, []byte{0x01}))
+	st = status.Convert(err)
+	assert.True(t, strings.HasPrefix(st.Message(), overrides.ErrorPrefixTraceTooLarge))
+	info = st.Details()[0].(*rpc.ErrorInfo)
+	assert.NotEmpty(t, info.Metadata[overrides.ReasonTraceTooLarge])
+}
+
 func TestSortByteSlices(t *testing.T) {
 	numTraces := 100
 
@@ -566,7 +991,7 @@ func defaultInstanceWithFlatBufferSearch(t testing.TB, fbSearch bool) (*instance
 	tmpDir := t.TempDir()
 
 	ingester, _, _ := defaultIngester(t, tmpDir)
-	instance, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, fbSearch)
+	instance, err := newInstance(testTenantID, limiter, ingester.store, ingester.local, fbSearch, nil, 0)
 	require.NoError(t, err, "unexpected error creating new instance")
 
 	return instance, ingester, tmpDir
@@ -0,0 +1,244 @@
+package ingester
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+// journalDir is the folder in the wal holding the push journals of the tenants
+const journalDir = "journal"
+
+const journalRecordHeaderSize = 8
+
+var (
+	metricJournalSyncDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempo",
+		Name:      "ingester_journal_sync_duration_seconds",
+		Help:      "Duration of appending and syncing durable pushes to the push journal.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 8),
+	})
+	metricJournalFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingester_journal_failures_total",
+		Help:      "The total number of durable pushes that failed to be synced to the push journal.",
+	})
+)
+
+// pushJournal holds the pushes of a tenant that asked for a durable ack until their traces are cut to the wal.
+// Live traces are only written to the wal once they are cut, so every durable push is appended and synced to the
+// journal before it is acknowledged. The journal is split into numbered segments, a new one is started whenever
+// traces are cut and segments are removed once no live trace was pushed into them.
+type pushJournal struct {
+	dir string
+
+	mtx     sync.Mutex
+	f       *os.File
+	segment uint64
+	written int64
+
+	// retainBefore keeps the segments before it, they were left by a previous run and not replayed
+	retainBefore uint64
+}
+
+// openPushJournal opens the journal in dir and returns the existing segments to replay. New pushes are appended to
+// a segment after them.
+func openPushJournal(dir string) (*pushJournal, []uint64, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = wal.SyncDir(filepath.Dir(dir))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	segments, err := journalSegments(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	j := &pushJournal{dir: dir}
+	if len(segments) > 0 {
+		j.segment = segments[len(segments)-1]
+	}
+	err = j.startSegment()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return j, segments, nil
+}
+
+// append appends the request to the current segment and syncs it. Returns the segment it was appended to.
+func (j *pushJournal) append(req *tempopb.PushBytesRequest) (uint64, error) {
+	start := time.Now()
+	defer func() { metricJournalSyncDuration.Observe(time.Since(start).Seconds()) }()
+
+	b, err := req.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	record := make([]byte, journalRecordHeaderSize+len(b))
+	binary.LittleEndian.PutUint32(record, uint32(len(b)))
+	binary.LittleEndian.PutUint32(record[4:], crc32.ChecksumIEEE(b))
+	copy(record[journalRecordHeaderSize:], b)
+
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	_, err = j.f.Write(record)
+	if err == nil {
+		err = j.f.Sync()
+	}
+	if err != nil {
+		metricJournalFailures.Inc()
+		return 0, err
+	}
+
+	j.written += int64(len(record))
+	return j.segment, nil
+}
+
+// rotate starts a new segment unless the current one is empty. Returns the current segment.
+func (j *pushJournal) rotate() (uint64, error) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	if j.written == 0 {
+		return j.segment, nil
+	}
+
+	err := j.f.Close()
+	if err != nil {
+		return 0, err
+	}
+	err = j.startSegment()
+	if err != nil {
+		return 0, err
+	}
+	return j.segment, nil
+}
+
+// startSegment must be called with the lock held
+func (j *pushJournal) startSegment() error {
+	j.segment++
+	f, err := os.OpenFile(j.segmentPath(j.segment), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	// syncing the records of the segment doesn't persist its directory entry
+	err = wal.SyncDir(j.dir)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	j.f = f
+	j.written = 0
+	return nil
+}
+
+// removeBefore removes the segments before the given one
+func (j *pushJournal) removeBefore(segment uint64) error {
+	segments, err := journalSegments(j.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range segments {
+		if s >= segment {
+			break
+		}
+		if s < j.retainBefore {
+			continue
+		}
+		err = os.Remove(j.segmentPath(s))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *pushJournal) segmentPath(segment uint64) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%020d", segment))
+}
+
+// replaySegment calls fn with every request of the segment. A truncated or corrupt record ends the segment, it was
+// not acknowledged.
+func (j *pushJournal) replaySegment(segment uint64, fn func(req *tempopb.PushBytesRequest) error) error {
+	f, err := os.Open(j.segmentPath(segment))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, journalRecordHeaderSize)
+	for {
+		_, err = io.ReadFull(r, header)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		b := make([]byte, binary.LittleEndian.Uint32(header))
+		_, err = io.ReadFull(r, b)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if crc32.ChecksumIEEE(b) != binary.LittleEndian.Uint32(header[4:]) {
+			return nil
+		}
+
+		req := &tempopb.PushBytesRequest{}
+		err = req.Unmarshal(b)
+		if err != nil {
+			return nil
+		}
+		err = fn(req)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// journalSegments returns the numbers of the segments in dir in ascending order
+func journalSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]uint64, 0, len(entries))
+	for _, e := range entries {
+		s, err := strconv.ParseUint(e.Name(), 10, 64)
+		if err != nil || e.IsDir() {
+			continue
+		}
+		segments = append(segments, s)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
@@ -9,6 +9,7 @@ import (
 
 const (
 	errMaxTracesPerUserLimitExceeded = "per-user traces limit (local: %d global: %d actual local: %d) exceeded"
+	errIngestionPaused               = "ingestion is paused for tenant %s"
 )
 
 // RingCount is the interface exposed by a ring implementation which allows
@@ -48,6 +49,14 @@ func (l *Limiter) AssertMaxTracesPerUser(userID string, traces int) error {
 	return fmt.Errorf(errMaxTracesPerUserLimitExceeded, localLimit, globalLimit, actualLimit)
 }
 
+// AssertIngestionNotPaused returns an error if ingestion is paused for the tenant.
+func (l *Limiter) AssertIngestionNotPaused(userID string) error {
+	if l.limits.IngestionPaused(userID) {
+		return fmt.Errorf(errIngestionPaused, userID)
+	}
+	return nil
+}
+
 func (l *Limiter) maxTracesPerUser(userID string) int {
 	localLimit := l.limits.MaxLocalTracesPerUser(userID)
 
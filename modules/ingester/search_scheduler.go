@@ -0,0 +1,149 @@
+package ingester
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricSearchBlockSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "ingester_search_block_seconds_total",
+		Help:      "The total time spent searching blocks per tenant.",
+	}, []string{"tenant"})
+	metricSearchQueueLength = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "ingester_search_queue_length",
+		Help:      "The number of block searches waiting to be scheduled per tenant.",
+	}, []string{"tenant"})
+)
+
+// searchScheduler bounds the number of block searches running at once and hands out free slots with weighted
+// fairness. Every tenant is charged the time its block searches run divided by its weight, and the next slot goes to
+// the waiting tenant charged the least. With the number of slots at or below the number of cores the run time of a
+// search approximates the CPU it used, so a tenant running expensive searches only delays its own searches.
+type searchScheduler struct {
+	slots  int
+	weight func(tenantID string) int
+	now    func() time.Time
+
+	mtx     sync.Mutex
+	running int
+	tenants map[string]*searchTenant
+	// vclock is the charge of the tenant that was last given a slot. Tenants that start searching again are charged
+	// at least this much so they can't use up credit collected while they were idle.
+	vclock float64
+}
+
+type searchTenant struct {
+	id      string
+	queue   []func()
+	charged float64
+	running int
+}
+
+// newSearchScheduler returns a scheduler running up to slots block searches at once. weight returns the weight of a
+// tenant, values below 1 are treated as 1. A nil scheduler runs every search immediately.
+func newSearchScheduler(slots int, weight func(tenantID string) int) *searchScheduler {
+	if slots <= 0 {
+		return nil
+	}
+
+	return &searchScheduler{
+		slots:   slots,
+		weight:  weight,
+		now:     time.Now,
+		tenants: map[string]*searchTenant{},
+	}
+}
+
+// Go runs fn once the tenant is given a slot. It never blocks, so it may be called with locks held. fn is run even
+// if the search was cancelled in the meantime, it is expected to return early.
+func (s *searchScheduler) Go(tenantID string, fn func()) {
+	if s == nil {
+		go fn()
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	t, ok := s.tenants[tenantID]
+	if !ok {
+		t = &searchTenant{id: tenantID}
+		s.tenants[tenantID] = t
+	}
+	if len(t.queue) == 0 && t.running == 0 && t.charged < s.vclock {
+		t.charged = s.vclock
+	}
+	t.queue = append(t.queue, fn)
+	metricSearchQueueLength.WithLabelValues(tenantID).Set(float64(len(t.queue)))
+
+	s.dispatchLocked()
+}
+
+// dispatchLocked starts queued searches while slots are free. Must be called with the lock held.
+func (s *searchScheduler) dispatchLocked() {
+	for s.running < s.slots {
+		t := s.nextLocked()
+		if t == nil {
+			return
+		}
+
+		fn := t.queue[0]
+		t.queue[0] = nil
+		t.queue = t.queue[1:]
+		metricSearchQueueLength.WithLabelValues(t.id).Set(float64(len(t.queue)))
+
+		t.running++
+		s.running++
+		if t.charged > s.vclock {
+			s.vclock = t.charged
+		}
+
+		go s.run(t, fn)
+	}
+}
+
+// nextLocked returns the waiting tenant charged the least. Ties go to the tenant with fewer running searches per
+// weight. Must be called with the lock held.
+func (s *searchScheduler) nextLocked() *searchTenant {
+	var next *searchTenant
+	for _, t := range s.tenants {
+		if len(t.queue) == 0 {
+			continue
+		}
+		if next == nil || t.charged < next.charged ||
+			(t.charged == next.charged && float64(t.running)/s.weightOf(t.id) < float64(next.running)/s.weightOf(next.id)) {
+			next = t
+		}
+	}
+	return next
+}
+
+func (s *searchScheduler) run(t *searchTenant, fn func()) {
+	start := s.now()
+	fn()
+	elapsed := s.now().Sub(start)
+	metricSearchBlockSeconds.WithLabelValues(t.id).Add(elapsed.Seconds())
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	t.charged += elapsed.Seconds() / s.weightOf(t.id)
+	t.running--
+	s.running--
+
+	s.dispatchLocked()
+}
+
+func (s *searchScheduler) weightOf(tenantID string) float64 {
+	w := s.weight(tenantID)
+	if w < 1 {
+		return 1
+	}
+	return float64(w)
+}
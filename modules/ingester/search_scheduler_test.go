@@ -0,0 +1,96 @@
+package ingester
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchSchedulerWeightedFairness(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		clock = time.Unix(0, 0)
+		order []string
+		wg    sync.WaitGroup
+	)
+
+	s := newSearchScheduler(1, func(tenantID string) int {
+		if tenantID == "b" {
+			return 2
+		}
+		return 1
+	})
+	s.now = func() time.Time {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return clock
+	}
+
+	task := func(name string, cost time.Duration, wait <-chan struct{}) func() {
+		wg.Add(1)
+		return func() {
+			defer wg.Done()
+			if wait != nil {
+				<-wait
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			clock = clock.Add(cost)
+			order = append(order, name)
+		}
+	}
+
+	// the first search of a holds the only slot until everything is queued
+	release := make(chan struct{})
+	s.Go("a", task("a1", 10*time.Second, release))
+	s.Go("a", task("a2", 4*time.Second, nil))
+	s.Go("a", task("a3", 4*time.Second, nil))
+	s.Go("b", task("b1", 4*time.Second, nil))
+	s.Go("b", task("b2", 4*time.Second, nil))
+	s.Go("b", task("b3", 4*time.Second, nil))
+	close(release)
+	wg.Wait()
+
+	// a is charged 10s for a1, b only 2s per search due to its weight
+	assert.Equal(t, []string{"a1", "b1", "b2", "b3", "a2", "a3"}, order)
+}
+
+func TestSearchSchedulerIdleTenantsDontCollectCredit(t *testing.T) {
+	s := newSearchScheduler(1, func(string) int { return 1 })
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s.Go("a", wg.Done)
+	wg.Wait()
+
+	s.mtx.Lock()
+	s.vclock = 100
+	s.mtx.Unlock()
+
+	// b starts searching after a while and starts with the charge of the other tenants
+	release := make(chan struct{})
+	wg.Add(1)
+	s.Go("b", func() {
+		<-release
+		wg.Done()
+	})
+
+	s.mtx.Lock()
+	assert.Equal(t, float64(100), s.tenants["b"].charged)
+	s.mtx.Unlock()
+
+	close(release)
+	wg.Wait()
+}
+
+func TestSearchSchedulerDisabled(t *testing.T) {
+	s := newSearchScheduler(0, nil)
+	assert.Nil(t, s)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	s.Go("a", wg.Done)
+	wg.Wait()
+}
@@ -10,9 +10,17 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
 	"github.com/grafana/tempo/pkg/util/log"
 )
 
+// TruncatedAttribute is added to the resources of the spans that were accepted when a push exceeded the max size of a
+// trace
+const TruncatedAttribute = "tempo.trace.truncated"
+
 var (
 	metricTraceSearchBytesDiscardedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: "tempo",
@@ -37,6 +45,9 @@ type liveTrace struct {
 	searchData         [][]byte
 	maxSearchBytes     int
 	currentSearchBytes int
+
+	// journalSegment is the oldest push journal segment holding a push of the trace, 0 if it wasn't journaled
+	journalSegment uint64
 }
 
 func newTrace(traceID []byte, maxBytes int, maxSearchBytes int) *liveTrace {
@@ -50,12 +61,24 @@ func newTrace(traceID []byte, maxBytes int, maxSearchBytes int) *liveTrace {
 	}
 }
 
+// Push appends the segment to the trace. If the segment doesn't fit in the max size of the trace the spans that fit
+// are accepted, the trace is marked as truncated and a *traceTruncatedError is returned. If no span fits a
+// *traceTooLargeError is returned and nothing is accepted.
 func (t *liveTrace) Push(_ context.Context, instanceID string, trace []byte, searchData []byte) error {
 	t.lastAppend = time.Now()
+
+	var truncatedErr error
 	if t.maxBytes != 0 {
 		reqSize := len(trace)
 		if t.currentBytes+reqSize > t.maxBytes {
-			return newTraceTooLargeError(t.traceID, instanceID, t.maxBytes, reqSize)
+			truncated, kept, total := t.truncate(trace, t.maxBytes-t.currentBytes)
+			if kept == 0 {
+				return newTraceTooLargeError(t.traceID, instanceID, t.maxBytes, reqSize, total)
+			}
+
+			trace = truncated
+			reqSize = len(trace)
+			truncatedErr = newTraceTruncatedError(t.traceID, instanceID, t.maxBytes, total-kept)
 		}
 
 		t.currentBytes += reqSize
@@ -85,5 +108,105 @@ func (t *liveTrace) Push(_ context.Context, instanceID string, trace []byte, sea
 		}
 	}
 
-	return nil
+	return truncatedErr
+}
+
+// truncate returns a copy of the segment that only contains the leading spans that fit in maxBytes, along with the
+// number of spans kept and the number of spans in the segment. The kept batches are marked with the truncated
+// attribute. If the segment can't be decoded nothing is kept.
+func (t *liveTrace) truncate(segment []byte, maxBytes int) ([]byte, int, int) {
+	start, end, err := t.decoder.FastRange(segment)
+	if err != nil {
+		return nil, 0, 0
+	}
+	tr, err := t.decoder.PrepareForRead([][]byte{segment})
+	if err != nil {
+		return nil, 0, 0
+	}
+
+	total := 0
+	kept := 0
+	size := 0
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				total++
+				size += s.Size()
+				if size <= maxBytes {
+					kept++
+				}
+			}
+		}
+	}
+
+	// the size of the spans is a lower bound of the size of the segment. drop spans from the end until the
+	// segment fits
+	for ; kept > 0; kept-- {
+		truncated, err := t.decoder.PrepareForWrite(truncateTrace(tr, kept), start, end)
+		if err != nil {
+			return nil, 0, total
+		}
+		if len(truncated) <= maxBytes {
+			return truncated, kept, total
+		}
+	}
+
+	return nil, 0, total
+}
+
+// truncateTrace returns a new trace that contains the first n spans of tr. The resources of the returned trace are
+// marked with the truncated attribute.
+func truncateTrace(tr *tempopb.Trace, n int) *tempopb.Trace {
+	out := &tempopb.Trace{}
+	for _, b := range tr.Batches {
+		if n == 0 {
+			break
+		}
+
+		resource := &v1_resource.Resource{}
+		if b.Resource != nil {
+			*resource = *b.Resource
+		}
+		resource.Attributes = append(append([]*v1_common.KeyValue{}, resource.Attributes...), &v1_common.KeyValue{
+			Key:   TruncatedAttribute,
+			Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_BoolValue{BoolValue: true}},
+		})
+
+		batch := &v1_trace.ResourceSpans{Resource: resource}
+		for _, ils := range b.InstrumentationLibrarySpans {
+			if n == 0 {
+				break
+			}
+
+			spans := ils.Spans
+			if len(spans) > n {
+				spans = spans[:n]
+			}
+			n -= len(spans)
+
+			batch.InstrumentationLibrarySpans = append(batch.InstrumentationLibrarySpans, &v1_trace.InstrumentationLibrarySpans{
+				InstrumentationLibrary: ils.InstrumentationLibrary,
+				Spans:                  spans,
+			})
+		}
+		out.Batches = append(out.Batches, batch)
+	}
+
+	return out
+}
+
+// countSpans returns the number of spans in the segment or 0 if it can't be decoded
+func countSpans(segment []byte) int {
+	tr, err := model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForRead([][]byte{segment})
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			count += len(ils.Spans)
+		}
+	}
+	return count
 }
@@ -6,6 +6,8 @@ import (
 
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/tempopb"
+	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util/test"
 	prom_dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -71,3 +73,54 @@ func TestTraceStartEndTime(t *testing.T) {
 	assert.Equal(t, uint32(5), tr.start)
 	assert.Equal(t, uint32(25), tr.end)
 }
+
+func TestTraceTruncate(t *testing.T) {
+	s := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	traceID := test.ValidTraceID(nil)
+
+	batch := test.MakeBatch(10, traceID)
+	buff, err := s.PrepareForWrite(&tempopb.Trace{Batches: []*v1_trace.ResourceSpans{batch}}, 10, 20)
+	require.NoError(t, err)
+
+	maxBytes := len(buff) / 2
+	tr := newTrace(traceID, maxBytes, 0)
+
+	// the spans that fit are accepted
+	err = tr.Push(context.Background(), "test", buff, nil)
+	truncatedErr, ok := err.(*traceTruncatedError)
+	require.True(t, ok, "expected traceTruncatedError, got %v", err)
+	require.Len(t, tr.batches, 1)
+	assert.LessOrEqual(t, tr.currentBytes, maxBytes)
+
+	actual, err := s.PrepareForRead(tr.batches)
+	require.NoError(t, err)
+	require.Len(t, actual.Batches, 1)
+
+	keptSpans := spansOf(actual.Batches[0])
+	assert.Greater(t, len(keptSpans), 0)
+	assert.Equal(t, 10, len(keptSpans)+truncatedErr.discardedSpans)
+	assert.Equal(t, spansOf(batch)[:len(keptSpans)], keptSpans)
+
+	attrs := actual.Batches[0].Resource.Attributes
+	assert.Equal(t, TruncatedAttribute, attrs[len(attrs)-1].Key)
+	assert.True(t, attrs[len(attrs)-1].Value.GetBoolValue())
+
+	// the original batch is not modified
+	assert.Len(t, spansOf(batch), 10)
+	assert.Len(t, batch.Resource.Attributes, len(attrs)-1)
+
+	// nothing fits once the trace is full
+	err = tr.Push(context.Background(), "test", buff, nil)
+	tooLargeErr, ok := err.(*traceTooLargeError)
+	require.True(t, ok, "expected traceTooLargeError, got %v", err)
+	assert.Equal(t, 10, tooLargeErr.discardedSpans)
+	assert.Len(t, tr.batches, 1)
+}
+
+func spansOf(b *v1_trace.ResourceSpans) []*v1_trace.Span {
+	var spans []*v1_trace.Span
+	for _, ils := range b.InstrumentationLibrarySpans {
+		spans = append(spans, ils.Spans...)
+	}
+	return spans
+}
@@ -0,0 +1,22 @@
+package overrides
+
+import (
+	"github.com/prometheus/common/model"
+)
+
+// AlertingRuleGroup is a group of alerting rules that are evaluated together by the metrics-generator.
+// It mirrors the Prometheus rule group format.
+type AlertingRuleGroup struct {
+	Name     string         `yaml:"name" json:"name"`
+	Interval model.Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	Rules    []AlertingRule `yaml:"rules" json:"rules"`
+}
+
+// AlertingRule is a Prometheus alerting rule evaluated on the metrics generated from traces.
+type AlertingRule struct {
+	Alert       string            `yaml:"alert" json:"alert"`
+	Expr        string            `yaml:"expr" json:"expr"`
+	For         model.Duration    `yaml:"for,omitempty" json:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
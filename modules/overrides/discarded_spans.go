@@ -7,6 +7,18 @@ import (
 
 const discardReasonLabel = "reason"
 
+const (
+	// ReasonTraceTooLarge indicates that a single trace has too many spans
+	ReasonTraceTooLarge = "trace_too_large"
+	// ReasonTraceTruncated indicates that the spans did not fit in a trace that reached the single trace limit.
+	// The rest of the push was accepted and the trace is marked as truncated.
+	ReasonTraceTruncated = "trace_truncated"
+
+	// ErrorInfoReasonDiscardedSpans flags the ErrorInfo attached by the ingester to errors of partially accepted
+	// pushes. Its metadata maps discard reasons to the number of spans discarded.
+	ErrorInfoReasonDiscardedSpans = "DISCARDED_SPANS"
+)
+
 var metricDiscardedSpans = promauto.NewCounterVec(prometheus.CounterOpts{
 	Namespace: "tempo",
 	Name:      "discarded_spans_total",
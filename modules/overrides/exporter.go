@@ -0,0 +1,96 @@
+package overrides
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricEffectiveLimitsDesc = prometheus.NewDesc(
+		"tempo_limits_effective",
+		"Effective resource limits of tenants after applying overrides to the defaults",
+		[]string{"limit_name", "user"},
+		nil,
+	)
+	metricLimitsUsageDesc = prometheus.NewDesc(
+		"tempo_limits_usage",
+		"Current usage of resource limits by tenants as measured by this process",
+		[]string{"limit_name", "user"},
+		nil,
+	)
+)
+
+// UsageFunc returns the current usage of a limit keyed by tenant.
+type UsageFunc func() map[string]float64
+
+// RegisterUsage registers fn as the source of the usage of the limit named limitName, one of the Metric*
+// constants. Components call this for the limits they enforce. Registering a limit again replaces fn.
+func (o *Overrides) RegisterUsage(limitName string, fn UsageFunc) {
+	o.usageMtx.Lock()
+	defer o.usageMtx.Unlock()
+
+	if o.usage == nil {
+		o.usage = map[string]UsageFunc{}
+	}
+	o.usage[limitName] = fn
+}
+
+// effectiveLimits returns the limits exported by the exporter keyed by limit name.
+func effectiveLimits(l *Limits) map[string]float64 {
+	return map[string]float64{
+		MetricMaxLocalTracesPerUser:     float64(l.MaxLocalTracesPerUser),
+		MetricMaxGlobalTracesPerUser:    float64(l.MaxGlobalTracesPerUser),
+		MetricMaxBytesPerTrace:          float64(l.MaxBytesPerTrace),
+		MetricMaxSearchBytesPerTrace:    float64(l.MaxSearchBytesPerTrace),
+		MetricMaxBytesPerTagValuesQuery: float64(l.MaxBytesPerTagValuesQuery),
+		MetricIngestionRateLimitBytes:   float64(l.IngestionRateLimitBytes),
+		MetricIngestionBurstSizeBytes:   float64(l.IngestionBurstSizeBytes),
+		MetricBlockRetention:            float64(l.BlockRetention),
+	}
+}
+
+// exporter exports the effective limits of every known tenant alongside their usage. Tenants are known
+// if they have per-tenant overrides or if a registered UsageFunc reports usage for them.
+type exporter struct {
+	o *Overrides
+}
+
+// NewExporter returns a collector exporting the effective limits and usage of all tenants.
+func NewExporter(o *Overrides) prometheus.Collector {
+	return &exporter{o: o}
+}
+
+func (e *exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metricEffectiveLimitsDesc
+	ch <- metricLimitsUsageDesc
+}
+
+func (e *exporter) Collect(ch chan<- prometheus.Metric) {
+	tenants := map[string]struct{}{}
+	if overrides := e.o.tenantOverrides(); overrides != nil {
+		for tenant := range overrides.TenantLimits {
+			if tenant != wildcardTenant {
+				tenants[tenant] = struct{}{}
+			}
+		}
+	}
+
+	e.o.usageMtx.RLock()
+	usageFns := make(map[string]UsageFunc, len(e.o.usage))
+	for name, fn := range e.o.usage {
+		usageFns[name] = fn
+	}
+	e.o.usageMtx.RUnlock()
+
+	for name, fn := range usageFns {
+		for tenant, usage := range fn() {
+			tenants[tenant] = struct{}{}
+			ch <- prometheus.MustNewConstMetric(metricLimitsUsageDesc, prometheus.GaugeValue, usage, name, tenant)
+		}
+	}
+
+	for tenant := range tenants {
+		for name, limit := range effectiveLimits(e.o.getOverridesForUser(tenant)) {
+			ch <- prometheus.MustNewConstMetric(metricEffectiveLimitsDesc, prometheus.GaugeValue, limit, name, tenant)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package overrides
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/dskit/services"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExporter(t *testing.T) {
+	overridesFile := filepath.Join(t.TempDir(), "overrides.yaml")
+	buff, err := yaml.Marshal(&perTenantOverrides{
+		TenantLimits: map[string]*Limits{
+			"user1": {
+				MaxLocalTracesPerUser:   10,
+				IngestionRateLimitBytes: 100,
+			},
+			wildcardTenant: {
+				MaxLocalTracesPerUser:   20,
+				IngestionRateLimitBytes: 200,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(overridesFile, buff, os.ModePerm))
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry() // have to overwrite the registry or test panics with multiple metric reg
+	o, err := NewOverrides(Limits{
+		MaxLocalTracesPerUser:   1,
+		IngestionRateLimitBytes: 2,
+		PerTenantOverrideConfig: overridesFile,
+		PerTenantOverridePeriod: model.Duration(time.Hour),
+	})
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.TODO(), o))
+	defer func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.TODO(), o))
+	}()
+
+	// user2 is only known from its usage and gets the wildcard limits
+	o.RegisterUsage(MetricMaxLocalTracesPerUser, func() map[string]float64 {
+		return map[string]float64{"user1": 5, "user2": 15}
+	})
+	o.RegisterUsage(MetricIngestionRateLimitBytes, func() map[string]float64 {
+		return map[string]float64{"user1": 50}
+	})
+
+	expected := `
+# HELP tempo_limits_effective Effective resource limits of tenants after applying overrides to the defaults
+# TYPE tempo_limits_effective gauge
+tempo_limits_effective{limit_name="ingestion_rate_limit_bytes",user="user1"} 100
+tempo_limits_effective{limit_name="ingestion_rate_limit_bytes",user="user2"} 200
+tempo_limits_effective{limit_name="max_local_traces_per_user",user="user1"} 10
+tempo_limits_effective{limit_name="max_local_traces_per_user",user="user2"} 20
+# HELP tempo_limits_usage Current usage of resource limits by tenants as measured by this process
+# TYPE tempo_limits_usage gauge
+tempo_limits_usage{limit_name="ingestion_rate_limit_bytes",user="user1"} 50
+tempo_limits_usage{limit_name="max_local_traces_per_user",user="user1"} 5
+tempo_limits_usage{limit_name="max_local_traces_per_user",user="user2"} 15
+`
+	// only compare the two limits set in this test, the others are all zero
+	filtered := &filteredCollector{
+		c:     NewExporter(o),
+		names: []string{MetricMaxLocalTracesPerUser, MetricIngestionRateLimitBytes},
+	}
+	require.NoError(t, testutil.CollectAndCompare(filtered, strings.NewReader(expected)))
+}
+
+type filteredCollector struct {
+	c     prometheus.Collector
+	names []string
+}
+
+func (f *filteredCollector) Describe(ch chan<- *prometheus.Desc) {
+	f.c.Describe(ch)
+}
+
+func (f *filteredCollector) Collect(ch chan<- prometheus.Metric) {
+	all := make(chan prometheus.Metric)
+	go func() {
+		f.c.Collect(all)
+		close(all)
+	}()
+
+	for m := range all {
+		for _, name := range f.names {
+			if metricHasLimitName(m, name) {
+				ch <- m
+				break
+			}
+		}
+	}
+}
+
+func metricHasLimitName(m prometheus.Metric, name string) bool {
+	pb := &dto.Metric{}
+	_ = m.Write(pb)
+	for _, l := range pb.Label {
+		if l.GetName() == "limit_name" && l.GetValue() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package overrides
+
+import (
+	"errors"
+	"fmt"
+)
+
+// HistogramBucketOverride are latency buckets for the spans of resources with one of the given values of a resource
+// attribute. Series keep the buckets of the first span they were created with, so the attribute should be the
+// service name or one of the dimensions.
+type HistogramBucketOverride struct {
+	// Resource attribute to match, service.name if empty.
+	Attribute string    `yaml:"attribute" json:"attribute"`
+	Values    []string  `yaml:"values" json:"values"`
+	Buckets   []float64 `yaml:"buckets" json:"buckets"`
+}
+
+// Validate returns an error if the override matches no values or its buckets aren't strictly ascending
+func (o HistogramBucketOverride) Validate() error {
+	if len(o.Values) == 0 {
+		return errors.New("histogram bucket override must have values")
+	}
+	if len(o.Buckets) == 0 {
+		return fmt.Errorf("histogram bucket override of %v must have buckets", o.Values)
+	}
+	for i := 1; i < len(o.Buckets); i++ {
+		if o.Buckets[i] <= o.Buckets[i-1] {
+			return fmt.Errorf("histogram bucket override of %v: buckets must be strictly ascending, %v follows %v", o.Values, o.Buckets[i], o.Buckets[i-1])
+		}
+	}
+	return nil
+}
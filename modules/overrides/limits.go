@@ -2,10 +2,13 @@ package overrides
 
 import (
 	"flag"
+	"fmt"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
 )
 
 const (
@@ -18,8 +21,25 @@ const (
 	ErrorPrefixLiveTracesExceeded = "LIVE_TRACES_EXCEEDED:"
 	// ErrorPrefixTraceTooLarge is used to flag batches from the ingester that were rejected b/c they exceeded the single trace limit
 	ErrorPrefixTraceTooLarge = "TRACE_TOO_LARGE:"
+	// ErrorPrefixTraceTruncated is used to flag batches from the ingester that were partially accepted b/c they exceeded the single trace limit
+	ErrorPrefixTraceTruncated = "TRACE_TRUNCATED:"
 	// ErrorPrefixRateLimited is used to flag batches that have exceeded the spans/second of the tenant
 	ErrorPrefixRateLimited = "RATE_LIMITED:"
+	// ErrorPrefixInflightBytesExceeded is used to flag batches that were rejected b/c the distributor is processing too many bytes
+	ErrorPrefixInflightBytesExceeded = "INFLIGHT_BYTES_EXCEEDED:"
+	// ErrorPrefixIngestionPaused is used to flag batches that were rejected b/c ingestion is paused for the tenant
+	ErrorPrefixIngestionPaused = "INGESTION_PAUSED:"
+	// ErrorPrefixTenantDenied is used to flag batches that were rejected b/c the tenant is denied by the tenant mapping
+	ErrorPrefixTenantDenied = "TENANT_DENIED:"
+	// ErrorPrefixWALFull is used to flag batches from the ingester that were rejected b/c its wal reached the max disk usage
+	ErrorPrefixWALFull = "WAL_FULL:"
+	// ErrorPrefixReceiverNotAllowed is used to flag batches that were rejected b/c the tenant may not use the receiver they were received with
+	ErrorPrefixReceiverNotAllowed = "RECEIVER_NOT_ALLOWED:"
+
+	// The values of the ingestion attribution override, each stamps one resource attribute
+	IngestionAttributionReceiver  = "receiver"
+	IngestionAttributionSourceIP  = "source_ip"
+	IngestionAttributionPrincipal = "principal"
 
 	// metrics
 	MetricMaxLocalTracesPerUser     = "max_local_traces_per_user"
@@ -49,11 +69,36 @@ type Limits struct {
 	IngestionRateLimitBytes int       `yaml:"ingestion_rate_limit_bytes" json:"ingestion_rate_limit_bytes"`
 	IngestionBurstSizeBytes int       `yaml:"ingestion_burst_size_bytes" json:"ingestion_burst_size_bytes"`
 	SearchTagsAllowList     ListToMap `yaml:"search_tags_allow_list" json:"search_tags_allow_list"`
+	IngestionPaused         bool      `yaml:"ingestion_paused" json:"ingestion_paused"`
+	// AllowedReceivers are the receivers the tenant may push with, e.g. otlp. Empty allows all receivers.
+	AllowedReceivers ListToMap `yaml:"allowed_receivers" json:"allowed_receivers"`
+	// IngestionDurableAck acknowledges pushes only once a quorum of ingesters synced them to disk
+	IngestionDurableAck bool `yaml:"ingestion_durable_ack" json:"ingestion_durable_ack"`
+	// IngestionServiceMetricsMaxServices is the number of services of the tenant with the most spans whose received
+	// spans and bytes are counted in their own series, other services are counted together. 0 disables the per
+	// service metrics.
+	IngestionServiceMetricsMaxServices int `yaml:"ingestion_service_metrics_max_services" json:"ingestion_service_metrics_max_services"`
+	// IngestionAttribution are the attributes stamped on the resources of the pushes of the tenant, any of receiver,
+	// source_ip and principal. Empty stamps none.
+	IngestionAttribution ListToMap `yaml:"ingestion_attribution" json:"ingestion_attribution"`
 
 	// Ingester enforced limits.
 	MaxLocalTracesPerUser  int `yaml:"max_traces_per_user" json:"max_traces_per_user"`
 	MaxGlobalTracesPerUser int `yaml:"max_global_traces_per_user" json:"max_global_traces_per_user"`
 	MaxSearchBytesPerTrace int `yaml:"max_search_bytes_per_trace" json:"max_search_bytes_per_trace"`
+	// WALBlockVersion is the version of the tenant's wal blocks. Empty uses the version of the wal config.
+	WALBlockVersion string `yaml:"wal_block_version" json:"wal_block_version"`
+	// WALEncoding is the encoding of the tenant's wal blocks. Empty uses the encoding of the wal config.
+	WALEncoding string `yaml:"wal_encoding" json:"wal_encoding"`
+	// IngestionTimeRangeSlackPast and IngestionTimeRangeSlackFuture are how far before and after now the start and
+	// end times of the tenant's traces are accepted for the time range of wal blocks. 0 uses the slack of the wal config.
+	IngestionTimeRangeSlackPast   model.Duration `yaml:"ingestion_time_range_slack_past" json:"ingestion_time_range_slack_past"`
+	IngestionTimeRangeSlackFuture model.Duration `yaml:"ingestion_time_range_slack_future" json:"ingestion_time_range_slack_future"`
+	// BlockVersion is the version of the blocks the ingester completes for the tenant. It can be any registered block
+	// encoding. Empty uses the block version of the storage config.
+	BlockVersion string `yaml:"block_version" json:"block_version"`
+	// IngesterSearchWeight is the share of the tenant's block searches when the ingester schedules them.
+	IngesterSearchWeight int `yaml:"ingester_search_weight" json:"ingester_search_weight"`
 
 	// Metrics-generator config
 	MetricsGeneratorRingSize                               int           `yaml:"metrics_generator_ring_size" json:"metrics_generator_ring_size"`
@@ -68,14 +113,35 @@ type Limits struct {
 	MetricsGeneratorProcessorSpanMetricsHistogramBuckets   []float64     `yaml:"metrics_generator_processor_span_metrics_histogram_buckets" json:"metrics_generator_processor_span_metrics_histogram_buckets"`
 	MetricsGeneratorProcessorSpanMetricsDimensions         []string      `yaml:"metrics_generator_processor_span_metrics_dimensions" json:"metrics_generator_processor_span_metrics_dimensions"`
 
+	MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides []HistogramBucketOverride `yaml:"metrics_generator_processor_span_metrics_histogram_bucket_overrides" json:"metrics_generator_processor_span_metrics_histogram_bucket_overrides"`
+
+	MetricsGeneratorAlertingRules []AlertingRuleGroup `yaml:"metrics_generator_alerting_rules" json:"metrics_generator_alerting_rules"`
+
 	// Compactor enforced limits.
-	BlockRetention model.Duration `yaml:"block_retention" json:"block_retention"`
+	BlockRetention           model.Duration `yaml:"block_retention" json:"block_retention"`
+	BloomFilterFalsePositive float64        `yaml:"bloom_filter_false_positive" json:"bloom_filter_false_positive"`
+	// CompactionCombineStrategy is how the parts of a trace are combined: merge-all, latest-wins or size-capped
+	CompactionCombineStrategy string `yaml:"compaction_combine_strategy" json:"compaction_combine_strategy"`
 
 	// Querier and Ingester enforced limits.
 	MaxBytesPerTagValuesQuery int `yaml:"max_bytes_per_tag_values_query" json:"max_bytes_per_tag_values_query"`
 
 	// QueryFrontend enforced limits
 	MaxSearchDuration model.Duration `yaml:"max_search_duration" json:"max_search_duration"`
+	MaxSearchLookback model.Duration `yaml:"max_search_lookback" json:"max_search_lookback"`
+	// MaxSearchSplitQueries is the number of sub-queries a search or metrics query of the tenant exceeding the max
+	// search duration may be split into. 0 falls back to the query frontend configuration.
+	MaxSearchSplitQueries int `yaml:"max_search_split_queries" json:"max_search_split_queries"`
+	// QueryBlocklist denies or rate limits the searches of the tenant with matching TraceQL queries
+	QueryBlocklist []QueryBlocklistRule `yaml:"query_blocklist" json:"query_blocklist"`
+	// QueryTimeout cancels the queries of the tenant running longer. 0 disables the timeout.
+	QueryTimeout model.Duration `yaml:"query_timeout" json:"query_timeout"`
+	// MaxConcurrentQueries is the number of requests of the tenant a frontend dispatches to queriers at once, further
+	// requests wait in the queue. 0 disables the limit.
+	MaxConcurrentQueries int `yaml:"max_concurrent_queries" json:"max_concurrent_queries"`
+	// MaxBytesScannedPerQuery rejects the searches of the tenant whose blocks are larger in total. 0 disables the
+	// limit.
+	MaxBytesScannedPerQuery int `yaml:"max_bytes_scanned_per_query" json:"max_bytes_scanned_per_query"`
 
 	// MaxBytesPerTrace is enforced in the Ingester, Compactor, Querier (Search) and Serverless (Search). It
 	//  is not used when doing a trace by id lookup.
@@ -98,6 +164,10 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.IntVar(&l.MaxGlobalTracesPerUser, "ingester.max-global-traces-per-user", 0, "Maximum number of active traces per user, across the cluster. 0 to disable.")
 	f.IntVar(&l.MaxBytesPerTrace, "ingester.max-bytes-per-trace", 50e5, "Maximum size of a trace in bytes.  0 to disable.")
 	f.IntVar(&l.MaxSearchBytesPerTrace, "ingester.max-search-bytes-per-trace", 5e3, "Maximum size of search data per trace in bytes.  0 to disable.")
+	f.IntVar(&l.IngesterSearchWeight, "ingester.search-weight", 1, "Weight of the tenant's block searches when the ingester schedules them.")
+
+	// Compactor limits
+	f.StringVar(&l.CompactionCombineStrategy, "compactor.combine-strategy", "size-capped", "How the parts of a trace are combined during compaction (merge-all, latest-wins or size-capped).")
 
 	// Querier limits
 	f.IntVar(&l.MaxBytesPerTagValuesQuery, "querier.max-bytes-per-tag-values-query", 50e5, "Maximum size of response for a tag-values query. Used mainly to limit large the number of values associated with a particular tag")
@@ -121,3 +191,33 @@ func (l *Limits) Collect(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.IngestionBurstSizeBytes), MetricIngestionBurstSizeBytes)
 	ch <- prometheus.MustNewConstMetric(metricLimitsDesc, prometheus.GaugeValue, float64(l.BlockRetention), MetricBlockRetention)
 }
+
+// Validate returns an error if a limit has an invalid value
+func (l *Limits) Validate() error {
+	if err := validateQueryBlocklist(l.QueryBlocklist); err != nil {
+		return err
+	}
+
+	for attribution := range l.IngestionAttribution {
+		switch attribution {
+		case IngestionAttributionReceiver, IngestionAttributionSourceIP, IngestionAttributionPrincipal:
+		default:
+			return fmt.Errorf("invalid ingestion attribution %q, must be one of %s, %s or %s", attribution,
+				IngestionAttributionReceiver, IngestionAttributionSourceIP, IngestionAttributionPrincipal)
+		}
+	}
+
+	switch common.CombineStrategy(l.CompactionCombineStrategy) {
+	case "", common.CombineStrategyMergeAll, common.CombineStrategyLatestWins, common.CombineStrategySizeCapped:
+	default:
+		return fmt.Errorf("invalid compaction combine strategy %q, must be one of %s, %s or %s", l.CompactionCombineStrategy,
+			common.CombineStrategyMergeAll, common.CombineStrategyLatestWins, common.CombineStrategySizeCapped)
+	}
+
+	for _, o := range l.MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -55,6 +55,10 @@ metrics_generator_send_queue_size: 10
 metrics_generator_send_workers: 1
 
 max_search_duration: 5m
+query_blocklist:
+- pattern: .*span.foo.*
+  rate_limit: 0.5
+  reason: dashboard overloads the queriers
 `
 	inputJSON := `
 {
@@ -77,7 +81,10 @@ max_search_duration: 5m
 	"metrics_generator_send_queue_size": 10,
 	"metrics_generator_send_workers": 1,
 
-	"max_search_duration": "5m"
+	"max_search_duration": "5m",
+	"query_blocklist": [
+	  {"pattern": ".*span.foo.*", "rate_limit": 0.5, "reason": "dashboard overloads the queriers"}
+	]
 }`
 
 	limitsYAML := Limits{}
@@ -90,3 +97,73 @@ max_search_duration: 5m
 
 	assert.Equal(t, limitsYAML, limitsJSON)
 }
+
+func TestLimitsValidate(t *testing.T) {
+	limits := Limits{
+		IngestionAttribution: ListToMap{IngestionAttributionReceiver: {}, IngestionAttributionPrincipal: {}},
+	}
+	require.NoError(t, limits.Validate())
+
+	limits.IngestionAttribution = ListToMap{"source-ip": {}}
+	require.Error(t, limits.Validate())
+
+	limits.IngestionAttribution = nil
+	limits.QueryBlocklist = []QueryBlocklistRule{{Pattern: "("}}
+	require.Error(t, limits.Validate())
+
+	limits.QueryBlocklist = nil
+	limits.CompactionCombineStrategy = "latest-wins"
+	require.NoError(t, limits.Validate())
+
+	limits.CompactionCombineStrategy = "latest"
+	require.Error(t, limits.Validate())
+
+	limits.CompactionCombineStrategy = ""
+	limits.MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides = []HistogramBucketOverride{{Values: []string{"batch"}, Buckets: []float64{1, 60}}}
+	require.NoError(t, limits.Validate())
+}
+
+func TestHistogramBucketOverrideValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		override HistogramBucketOverride
+		err      string
+	}{
+		{
+			name:     "valid",
+			override: HistogramBucketOverride{Attribute: "job", Values: []string{"batch"}, Buckets: []float64{0.1, 1, 60}},
+		},
+		{
+			name:     "no values",
+			override: HistogramBucketOverride{Buckets: []float64{1}},
+			err:      "histogram bucket override must have values",
+		},
+		{
+			name:     "no buckets",
+			override: HistogramBucketOverride{Values: []string{"batch"}},
+			err:      "histogram bucket override of [batch] must have buckets",
+		},
+		{
+			name:     "descending",
+			override: HistogramBucketOverride{Values: []string{"batch"}, Buckets: []float64{1, 0.1}},
+			err:      "buckets must be strictly ascending",
+		},
+		{
+			name:     "duplicate",
+			override: HistogramBucketOverride{Values: []string{"batch"}, Buckets: []float64{1, 1}},
+			err:      "buckets must be strictly ascending",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.override.Validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
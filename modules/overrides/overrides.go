@@ -5,15 +5,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/grafana/dskit/runtimeconfig"
 	"github.com/grafana/dskit/services"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
 
 	"github.com/grafana/tempo/pkg/util"
 	"github.com/grafana/tempo/pkg/util/log"
+	"github.com/grafana/tempo/tempodb/backend"
 )
 
 const wildcardTenant = "*"
@@ -30,6 +33,15 @@ var (
 // perTenantOverrides represents the overrides config file
 type perTenantOverrides struct {
 	TenantLimits map[string]*Limits `yaml:"overrides"`
+	// WAL overrides the wal config of the ingesters for all tenants
+	WAL *WALRuntimeConfig `yaml:"wal,omitempty"`
+}
+
+// WALRuntimeConfig are the fields of the wal config that can be changed in the runtime config. They apply to the wal
+// blocks created after the runtime config was reloaded. Zero values keep the wal config.
+type WALRuntimeConfig struct {
+	Encoding                string         `yaml:"encoding,omitempty" json:"encoding,omitempty"`
+	IngestionTimeRangeSlack model.Duration `yaml:"ingestion_time_range_slack,omitempty" json:"ingestion_time_range_slack,omitempty"`
 }
 
 // forUser returns limits for a given tenant, or nil if there are no tenant-specific limits.
@@ -51,6 +63,23 @@ func loadPerTenantOverrides(r io.Reader) (interface{}, error) {
 		return nil, err
 	}
 
+	// an invalid wal config fails the reload and keeps the previous runtime config
+	if overrides.WAL != nil && overrides.WAL.Encoding != "" {
+		if _, err := backend.ParseEncoding(overrides.WAL.Encoding); err != nil {
+			return nil, fmt.Errorf("invalid wal encoding in runtime config: %w", err)
+		}
+	}
+
+	// so are invalid limits of a tenant
+	for tenant, l := range overrides.TenantLimits {
+		if l == nil {
+			continue
+		}
+		if err := l.Validate(); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant, err)
+		}
+	}
+
 	return overrides, nil
 }
 
@@ -68,6 +97,9 @@ type Overrides struct {
 	defaultLimits    *Limits
 	runtimeConfigMgr *runtimeconfig.Manager
 
+	usageMtx sync.RWMutex
+	usage    map[string]UsageFunc
+
 	// Manager for subservices
 	subservices        *services.Manager
 	subservicesWatcher *services.FailureWatcher
@@ -78,6 +110,10 @@ type Overrides struct {
 // are defaulted to those values.  As such, the last call to NewOverrides will
 // become the new global defaults.
 func NewOverrides(defaults Limits) (*Overrides, error) {
+	if err := defaults.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid overrides: %w", err)
+	}
+
 	var manager *runtimeconfig.Manager
 	subservices := []services.Service(nil)
 
@@ -258,6 +294,33 @@ func (o *Overrides) IngestionBurstSizeBytes(userID string) int {
 	return o.getOverridesForUser(userID).IngestionBurstSizeBytes
 }
 
+// IngestionPaused returns true if all spans pushed for this tenant are rejected. Queries are still served.
+func (o *Overrides) IngestionPaused(userID string) bool {
+	return o.getOverridesForUser(userID).IngestionPaused
+}
+
+// IngestionDurableAck returns true if pushes of this tenant are only acknowledged once a quorum of ingesters synced
+// them to disk.
+func (o *Overrides) IngestionDurableAck(userID string) bool {
+	return o.getOverridesForUser(userID).IngestionDurableAck
+}
+
+// AllowedReceivers returns the receivers this tenant may push with. An empty map allows all receivers.
+func (o *Overrides) AllowedReceivers(userID string) map[string]struct{} {
+	return o.getOverridesForUser(userID).AllowedReceivers.GetMap()
+}
+
+// IngestionAttribution returns the attributes stamped on the resources of the pushes of this tenant.
+func (o *Overrides) IngestionAttribution(userID string) map[string]struct{} {
+	return o.getOverridesForUser(userID).IngestionAttribution.GetMap()
+}
+
+// IngestionServiceMetricsMaxServices is the number of services of this tenant with their own ingestion metrics, 0 if
+// the per service metrics are disabled.
+func (o *Overrides) IngestionServiceMetricsMaxServices(userID string) int {
+	return o.getOverridesForUser(userID).IngestionServiceMetricsMaxServices
+}
+
 // SearchTagsAllowList is the list of tags to be extracted for search, for this tenant.
 func (o *Overrides) SearchTagsAllowList(userID string) map[string]struct{} {
 	return o.getOverridesForUser(userID).SearchTagsAllowList.GetMap()
@@ -326,16 +389,105 @@ func (o *Overrides) MetricsGeneratorProcessorSpanMetricsDimensions(userID string
 	return o.getOverridesForUser(userID).MetricsGeneratorProcessorSpanMetricsDimensions
 }
 
+// MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides controls the histogram buckets of the spans of
+// matching resources in the span metrics processor.
+func (o *Overrides) MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides(userID string) []HistogramBucketOverride {
+	return o.getOverridesForUser(userID).MetricsGeneratorProcessorSpanMetricsHistogramBucketOverrides
+}
+
+// MetricsGeneratorAlertingRules returns the alerting rules the metrics-generator evaluates on the
+// metrics generated for this tenant.
+func (o *Overrides) MetricsGeneratorAlertingRules(userID string) []AlertingRuleGroup {
+	return o.getOverridesForUser(userID).MetricsGeneratorAlertingRules
+}
+
+// WALBlockVersion is the version of the wal blocks of this tenant. Empty uses the version of the wal config.
+func (o *Overrides) WALBlockVersion(userID string) string {
+	return o.getOverridesForUser(userID).WALBlockVersion
+}
+
+// WALEncoding is the encoding of the wal blocks of this tenant. Empty uses the encoding of the wal config.
+func (o *Overrides) WALEncoding(userID string) string {
+	return o.getOverridesForUser(userID).WALEncoding
+}
+
+// WALRuntimeConfig returns the wal config of the runtime config, nil if it has none
+func (o *Overrides) WALRuntimeConfig() *WALRuntimeConfig {
+	if tenantOverrides := o.tenantOverrides(); tenantOverrides != nil {
+		return tenantOverrides.WAL
+	}
+	return nil
+}
+
+// IngestionTimeRangeSlack is how far before and after now the times of the tenant's traces are accepted for the time
+// range of wal blocks. 0 uses the slack of the wal config.
+func (o *Overrides) IngestionTimeRangeSlack(userID string) (past time.Duration, future time.Duration) {
+	limits := o.getOverridesForUser(userID)
+	return time.Duration(limits.IngestionTimeRangeSlackPast), time.Duration(limits.IngestionTimeRangeSlackFuture)
+}
+
+// CompactionCombineStrategy is how the parts of a trace are combined during compaction for this tenant.
+func (o *Overrides) CompactionCombineStrategy(userID string) string {
+	return o.getOverridesForUser(userID).CompactionCombineStrategy
+}
+
 // BlockRetention is the duration of the block retention for this tenant.
 func (o *Overrides) BlockRetention(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).BlockRetention)
 }
 
+// BloomFilterFalsePositive is the target false positive rate of the bloom filters of a tenant's blocks. Existing
+// blocks are regenerated by the compactor if rebloom is enabled.
+func (o *Overrides) BloomFilterFalsePositive(userID string) float64 {
+	return o.getOverridesForUser(userID).BloomFilterFalsePositive
+}
+
+// BlockVersion is the version of the blocks completed for this tenant. Empty uses the version of the storage config.
+func (o *Overrides) BlockVersion(userID string) string {
+	return o.getOverridesForUser(userID).BlockVersion
+}
+
+// IngesterSearchWeight is the weight of the block searches of this tenant when the ingester schedules them.
+func (o *Overrides) IngesterSearchWeight(userID string) int {
+	return o.getOverridesForUser(userID).IngesterSearchWeight
+}
+
 // MaxSearchDuration is the duration of the max search duration for this tenant.
 func (o *Overrides) MaxSearchDuration(userID string) time.Duration {
 	return time.Duration(o.getOverridesForUser(userID).MaxSearchDuration)
 }
 
+// MaxSearchLookback is how far back in time searches of this tenant may start.
+func (o *Overrides) MaxSearchLookback(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).MaxSearchLookback)
+}
+
+// MaxSearchSplitQueries is the number of sub-queries a search of this tenant exceeding the max search duration may
+// be split into.
+func (o *Overrides) MaxSearchSplitQueries(userID string) int {
+	return o.getOverridesForUser(userID).MaxSearchSplitQueries
+}
+
+// QueryBlocklist returns the rules denying or rate limiting searches of this tenant with matching TraceQL queries.
+func (o *Overrides) QueryBlocklist(userID string) []QueryBlocklistRule {
+	return o.getOverridesForUser(userID).QueryBlocklist
+}
+
+// QueryTimeout is how long queries of this tenant may run, 0 if they don't time out.
+func (o *Overrides) QueryTimeout(userID string) time.Duration {
+	return time.Duration(o.getOverridesForUser(userID).QueryTimeout)
+}
+
+// MaxConcurrentQueries is the number of requests of this tenant a frontend dispatches to queriers at once, 0 if unlimited.
+func (o *Overrides) MaxConcurrentQueries(userID string) int {
+	return o.getOverridesForUser(userID).MaxConcurrentQueries
+}
+
+// MaxBytesScannedPerQuery is the total size of the blocks a search of this tenant may scan, 0 if unlimited.
+func (o *Overrides) MaxBytesScannedPerQuery(userID string) int {
+	return o.getOverridesForUser(userID).MaxBytesScannedPerQuery
+}
+
 func (o *Overrides) getOverridesForUser(userID string) *Limits {
 	if tenantOverrides := o.tenantOverrides(); tenantOverrides != nil {
 		l := tenantOverrides.forUser(userID)
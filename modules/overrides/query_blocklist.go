@@ -0,0 +1,31 @@
+package overrides
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// QueryBlocklistRule denies or rate limits the searches of a tenant with a TraceQL query matching Pattern. It is
+// meant to shed the load of a misbehaving dashboard until it is fixed.
+type QueryBlocklistRule struct {
+	// Pattern is a regular expression matched against the TraceQL query of a search
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// RateLimit is the number of matching searches per second the tenant may run through each query frontend. 0
+	// denies all of them.
+	RateLimit float64 `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+	// Reason is returned with the error of a blocked search
+	Reason string `yaml:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// validateQueryBlocklist returns an error if a pattern of the rules is not a valid regular expression
+func validateQueryBlocklist(rules []QueryBlocklistRule) error {
+	for _, r := range rules {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("invalid query blocklist pattern %q: %w", r.Pattern, err)
+		}
+		if r.RateLimit < 0 {
+			return fmt.Errorf("invalid query blocklist rate limit %v for pattern %q", r.RateLimit, r.Pattern)
+		}
+	}
+	return nil
+}
@@ -11,7 +11,8 @@ import (
 
 // Config for a querier.
 type Config struct {
-	Search SearchConfig `yaml:"search"`
+	Search  SearchConfig  `yaml:"search"`
+	Metrics MetricsConfig `yaml:"metrics"`
 
 	TraceLookupQueryTimeout time.Duration `yaml:"query_timeout"`
 	ExtraQueryDelay         time.Duration `yaml:"extra_query_delay,omitempty"`
@@ -21,11 +22,18 @@ type Config struct {
 }
 
 type SearchConfig struct {
-	QueryTimeout      time.Duration `yaml:"query_timeout"`
-	PreferSelf        int           `yaml:"prefer_self"`
-	ExternalEndpoints []string      `yaml:"external_endpoints"`
-	HedgeRequestsAt   time.Duration `yaml:"external_hedge_requests_at"`
-	HedgeRequestsUpTo int           `yaml:"external_hedge_requests_up_to"`
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+	// RecentQueryTimeout bounds searches of the recent data in the ingesters through /api/search/recent
+	RecentQueryTimeout time.Duration `yaml:"recent_query_timeout"`
+	PreferSelf         int           `yaml:"prefer_self"`
+	ExternalEndpoints  []string      `yaml:"external_endpoints"`
+	HedgeRequestsAt    time.Duration `yaml:"external_hedge_requests_at"`
+	HedgeRequestsUpTo  int           `yaml:"external_hedge_requests_up_to"`
+}
+
+// MetricsConfig configures metrics queries, which read the spans of the blocks in the backend by column.
+type MetricsConfig struct {
+	ConcurrentBlocks int `yaml:"concurrent_blocks"`
 }
 
 // RegisterFlagsAndApplyDefaults register flags.
@@ -38,6 +46,8 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	cfg.Search.HedgeRequestsAt = 8 * time.Second
 	cfg.Search.HedgeRequestsUpTo = 2
 	cfg.Search.QueryTimeout = 30 * time.Second
+	cfg.Search.RecentQueryTimeout = 5 * time.Second
+	cfg.Metrics.ConcurrentBlocks = 8
 	cfg.Worker = worker.Config{
 		MatchMaxConcurrency:   true,
 		MaxConcurrentRequests: cfg.MaxConcurrentQueries,
@@ -2,6 +2,8 @@ package querier
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -89,13 +91,13 @@ func (q *Querier) TraceByIDHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	span.SetTag("contentType", api.HeaderAcceptJSON)
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
 	marshaller := &jsonpb.Marshaler{}
 	err = marshaller.Marshal(w, resp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
 }
 
 func (q *Querier) SearchHandler(w http.ResponseWriter, r *http.Request) {
@@ -150,13 +152,54 @@ func (q *Querier) SearchHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
 	marshaller := &jsonpb.Marshaler{}
 	err := marshaller.Marshal(w, resp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+}
+
+// SearchRecentHandler searches only the recent data in the ingesters. The search fails once the recent query timeout
+// is exceeded, instead of waiting for slow ingesters.
+func (q *Querier) SearchRecentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithDeadline(r.Context(), time.Now().Add(q.cfg.Search.RecentQueryTimeout))
+	defer cancel()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Querier.SearchRecentHandler")
+	defer span.Finish()
+
+	req, err := api.ParseSearchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Query) > 0 {
+		http.Error(w, "traceQL queries are not yet supported", http.StatusNotImplemented)
+		return
+	}
+
+	span.SetTag("SearchRequest", req.String())
+
+	resp, err := q.SearchRecent(ctx, req)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		http.Error(w, fmt.Sprintf("recent search exceeded the timeout of %s", q.cfg.Search.RecentQueryTimeout), http.StatusGatewayTimeout)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+	marshaller := &jsonpb.Marshaler{}
+	err = marshaller.Marshal(w, resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
 
 func (q *Querier) SearchTagsHandler(w http.ResponseWriter, r *http.Request) {
@@ -175,13 +218,13 @@ func (q *Querier) SearchTagsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
 	marshaller := &jsonpb.Marshaler{}
 	err = marshaller.Marshal(w, resp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
 }
 
 func (q *Querier) SearchTagValuesHandler(w http.ResponseWriter, r *http.Request) {
@@ -208,11 +251,113 @@ func (q *Querier) SearchTagValuesHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
 	marshaller := &jsonpb.Marshaler{}
 	err = marshaller.Marshal(w, resp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+}
+
+func (q *Querier) SearchTagValuesV2Handler(w http.ResponseWriter, r *http.Request) {
+	// Enforce the query timeout while querying backends
+	ctx, cancel := context.WithDeadline(r.Context(), time.Now().Add(q.cfg.Search.QueryTimeout))
+	defer cancel()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Querier.SearchTagValuesV2Handler")
+	defer span.Finish()
+
+	vars := mux.Vars(r)
+	tagName, ok := vars["tagName"]
+	if !ok {
+		http.Error(w, "please provide a tagName", http.StatusBadRequest)
+		return
+	}
+	req := &tempopb.SearchTagValuesRequest{
+		TagName: tagName,
+	}
+
+	resp, err := q.SearchTagValuesV2(ctx, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+	marshaller := &jsonpb.Marshaler{}
+	err = marshaller.Marshal(w, resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// MetricsQueryRangeHandler computes the metrics function of a TraceQL query over the blocks in the backend
+func (q *Querier) MetricsQueryRangeHandler(w http.ResponseWriter, r *http.Request) {
+	// Enforce the query timeout while querying backends
+	ctx, cancel := context.WithDeadline(r.Context(), time.Now().Add(q.cfg.Search.QueryTimeout))
+	defer cancel()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Querier.MetricsQueryRangeHandler")
+	defer span.Finish()
+
+	req, err := api.ParseMetricsRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	span.SetTag("query", req.Query)
+
+	resp, err := q.QueryRange(ctx, req)
+	if errors.Is(err, errUnsupportedMetricsQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// MetricsThresholdsHandler computes the values of the metrics thresholds of a TraceQL query over the blocks in the
+// backend
+func (q *Querier) MetricsThresholdsHandler(w http.ResponseWriter, r *http.Request) {
+	// Enforce the query timeout while querying backends
+	ctx, cancel := context.WithDeadline(r.Context(), time.Now().Add(q.cfg.Search.QueryTimeout))
+	defer cancel()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Querier.MetricsThresholdsHandler")
+	defer span.Finish()
+
+	req, err := api.ParseMetricsRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	span.SetTag("query", req.Query)
+
+	resp, err := q.QueryThresholds(ctx, req)
+	if errors.Is(err, errUnsupportedMetricsQuery) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(api.HeaderContentType, api.HeaderAcceptJSON)
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 }
@@ -0,0 +1,436 @@
+package querier
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/boundedwaitgroup"
+	"github.com/grafana/tempo/pkg/ddsketch"
+	"github.com/grafana/tempo/pkg/traceql"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+const (
+	// the names the name and status intrinsics are projected as, see the label mappings of vparquet
+	projectedLabelName       = "name"
+	projectedLabelStatusCode = "status.code"
+
+	// serviceNameAttribute is the resource attribute the span duration sketches of blocks are kept per
+	serviceNameAttribute = "service.name"
+
+	// maxMetricsExemplars is the number of exemplars kept per bucket of a histogram
+	maxMetricsExemplars = 5
+)
+
+// errUnsupportedMetricsQuery is returned for metrics queries that can't be evaluated against projected spans
+var errUnsupportedMetricsQuery = errors.New("unsupported metrics query")
+
+// QueryRange computes the metrics function of a TraceQL query from the spans of the blocks in the backend. Only the
+// blocks are read, spans that are still in the ingesters aren't counted.
+func (q *Querier) QueryRange(ctx context.Context, req *api.MetricsRequest) (*api.MetricsQueryRangeResponse, error) {
+	tenantID, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error extracting org id in Querier.QueryRange")
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Querier.QueryRange")
+	defer span.Finish()
+
+	expr, err := traceql.Parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	agg, ok := expr.MetricsAggregate()
+	if !ok {
+		return nil, fmt.Errorf("%w: the query must end with a metrics function", errUnsupportedMetricsQuery)
+	}
+	match, err := expr.SpanMatcher()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedMetricsQuery, err.Error())
+	}
+	projection, err := projectionFor(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := time.Unix(int64(req.Start), 0), time.Unix(int64(req.End), 0)
+	histogram, err := traceql.NewHistogramOverTime(start, end, req.Step, maxMetricsExemplars)
+	if err != nil {
+		return nil, err
+	}
+
+	metas := q.blockMetas(tenantID, start, end)
+	if service, ok := serviceFilter(expr); ok {
+		switch {
+		case agg.CountsSpans():
+			// the spans of the blocks with statistics are counted without reading them
+			var counts []uint64
+			counts, metas, err = q.store.CountServiceSpans(ctx, metas, service, start, end, req.Step)
+			if err != nil {
+				return nil, err
+			}
+			histogram.AddCounts(0, counts)
+		case service != "":
+			// the blocks without spans of the service don't have to be read
+			metas = withServiceName(metas, service)
+		}
+	}
+
+	var mtx sync.Mutex
+	skipped, err := q.projectBlocks(ctx, metas, projection, func(s *common.ProjectedSpan) {
+		ps := projectedSpan{s}
+		if !match(ps) {
+			return
+		}
+		if agg.CountsSpans() {
+			mtx.Lock()
+			histogram.Observe(s.StartTimeUnixNano, 0, s.TraceID)
+			mtx.Unlock()
+			return
+		}
+		v, ok := ps.AttributeFor(agg.Field)
+		if !ok {
+			return
+		}
+		value, ok := traceql.NumericValue(v)
+		if !ok {
+			return
+		}
+
+		mtx.Lock()
+		histogram.Observe(s.StartTimeUnixNano, value, s.TraceID)
+		mtx.Unlock()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &api.MetricsQueryRangeResponse{
+		Start:         req.Start,
+		StepMs:        req.Step.Milliseconds(),
+		Series:        []api.MetricsSeries{},
+		SkippedBlocks: skipped,
+	}
+	for _, s := range histogram.Series() {
+		series := api.MetricsSeries{
+			Bucket: s.Bucket,
+			Counts: s.Counts,
+		}
+		for _, e := range s.Exemplars {
+			series.Exemplars = append(series.Exemplars, api.MetricsExemplar{
+				TraceID:     hex.EncodeToString(e.TraceID),
+				Value:       e.Value,
+				TimestampMs: e.TimestampMs,
+			})
+		}
+		resp.Series = append(resp.Series, series)
+	}
+
+	return resp, nil
+}
+
+// QueryThresholds computes the values of the metrics thresholds of a TraceQL query from the spans of the blocks in
+// the backend. It is the first phase of queries with thresholds, the values are computed from the spans matching the
+// query without the conditions referencing a threshold.
+func (q *Querier) QueryThresholds(ctx context.Context, req *api.MetricsRequest) (*api.MetricsThresholdsResponse, error) {
+	tenantID, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error extracting org id in Querier.QueryThresholds")
+	}
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "Querier.QueryThresholds")
+	defer span.Finish()
+
+	expr, err := traceql.Parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	thresholds := expr.MetricsThresholds()
+	if len(thresholds) == 0 {
+		return api.NewMetricsThresholdsResponse(nil, 0), nil
+	}
+	filter, err := expr.MetricsThresholdFilter()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedMetricsQuery, err.Error())
+	}
+	match, err := filter.SpanMatcher()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedMetricsQuery, err.Error())
+	}
+	projection, err := projectionFor(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	quantiles := make([]*traceql.Quantiles, 0, len(thresholds))
+	for _, t := range thresholds {
+		quantiles = append(quantiles, traceql.NewQuantiles(t))
+	}
+
+	start, end := time.Unix(int64(req.Start), 0), time.Unix(int64(req.End), 0)
+	metas := q.blockMetas(tenantID, start, end)
+
+	// the blocks with sketches of the span durations of the service don't have to be read
+	if service, ok := durationSketchService(filter, thresholds); ok {
+		var sketch *ddsketch.Sketch
+		sketch, metas, err = q.store.DurationSketches(ctx, metas, service, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for _, qs := range quantiles {
+			qs.Merge(traceql.Static{Type: traceql.TypeNil}, sketch)
+		}
+	}
+
+	var mtx sync.Mutex
+	skipped, err := q.projectBlocks(ctx, metas, projection, func(s *common.ProjectedSpan) {
+		ps := projectedSpan{s}
+		if !match(ps) {
+			return
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		for i, t := range thresholds {
+			observeThreshold(quantiles[i], t, ps)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]traceql.MetricsThresholdValues, 0, len(quantiles))
+	for _, qs := range quantiles {
+		values = append(values, qs.Values())
+	}
+	return api.NewMetricsThresholdsResponse(values, skipped), nil
+}
+
+// observeThreshold records the value of the field of the threshold for the span. Spans without the attribute the
+// threshold groups by are left out, they don't match any group in the second phase.
+func observeThreshold(q *traceql.Quantiles, t traceql.MetricsThreshold, s traceql.Span) {
+	v, ok := s.AttributeFor(t.Field)
+	if !ok {
+		return
+	}
+	value, ok := traceql.NumericValue(v)
+	if !ok {
+		return
+	}
+
+	group := traceql.Static{Type: traceql.TypeNil}
+	if t.By != nil {
+		if group, ok = s.AttributeFor(*t.By); !ok {
+			return
+		}
+	}
+	q.Observe(group, value)
+}
+
+// durationSketchService returns the service the spans of the first phase of a query are filtered by if the values of
+// all thresholds can be computed from the span duration sketches of the blocks. Sketches only hold the durations of
+// the spans of every service, so the thresholds must be quantiles of the duration of all spans of a single service.
+func durationSketchService(filter *traceql.RootExpr, thresholds []traceql.MetricsThreshold) (string, bool) {
+	for _, t := range thresholds {
+		if t.Field.Intrinsic != traceql.IntrinsicDuration || t.By != nil {
+			return "", false
+		}
+	}
+	if len(filter.Pipeline.Elements) != 1 {
+		return "", false
+	}
+	f, ok := filter.Pipeline.Elements[0].(traceql.SpansetFilter)
+	if !ok {
+		return "", false
+	}
+	return serviceCondition(f.Expression)
+}
+
+// serviceCondition returns the service of a condition only matching the spans of a service. Conditions referencing
+// a threshold have been replaced with true in the first phase. Only the resource attribute is the service, unscoped
+// attributes match span attributes first.
+func serviceCondition(e traceql.FieldExpression) (string, bool) {
+	o, ok := e.(traceql.BinaryOperation)
+	if !ok {
+		return "", false
+	}
+
+	switch o.Op {
+	case traceql.OpAnd:
+		if isStaticTrue(o.LHS) {
+			return serviceCondition(o.RHS)
+		}
+		if isStaticTrue(o.RHS) {
+			return serviceCondition(o.LHS)
+		}
+	case traceql.OpEqual:
+		a, ok := o.LHS.(traceql.Attribute)
+		if !ok || a.Name != serviceNameAttribute || a.Parent || a.Scope != traceql.AttributeScopeResource {
+			return "", false
+		}
+		if service, ok := o.RHS.(traceql.Static); ok && service.Type == traceql.TypeString {
+			return service.S, true
+		}
+	}
+	return "", false
+}
+
+// serviceFilter returns the service a query only matches the spans of, or an empty service if it matches all spans.
+// The spans of a service are counted in the statistics of blocks and their service names are recorded, so these
+// queries can be answered without reading the spans of every block.
+func serviceFilter(expr *traceql.RootExpr) (string, bool) {
+	if len(expr.Pipeline.Elements) != 2 {
+		return "", false
+	}
+	f, ok := expr.Pipeline.Elements[0].(traceql.SpansetFilter)
+	if !ok {
+		return "", false
+	}
+	if isStaticTrue(f.Expression) {
+		return "", true
+	}
+	return serviceCondition(f.Expression)
+}
+
+// withServiceName returns the blocks that may contain spans of the service
+func withServiceName(metas []*backend.BlockMeta, service string) []*backend.BlockMeta {
+	var filtered []*backend.BlockMeta
+	for _, m := range metas {
+		if m.MayContainServiceName(service) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+func isStaticTrue(e traceql.FieldExpression) bool {
+	s, ok := e.(traceql.Static)
+	return ok && s.Type == traceql.TypeBoolean && s.B
+}
+
+// blockMetas returns the blocks of the tenant overlapping the time range
+func (q *Querier) blockMetas(tenantID string, start, end time.Time) []*backend.BlockMeta {
+	var metas []*backend.BlockMeta
+	for _, m := range q.store.BlockMetas(tenantID) {
+		if m.StartTime.Before(end) && m.EndTime.After(start) {
+			metas = append(metas, m)
+		}
+	}
+	return metas
+}
+
+// projectBlocks reads the projected spans of the blocks. The callback is called concurrently for spans of different
+// blocks. The number of blocks that can't be read by column is returned.
+func (q *Querier) projectBlocks(ctx context.Context, metas []*backend.BlockMeta, p common.Projection, cb func(*common.ProjectedSpan)) (int, error) {
+	var (
+		wg       = boundedwaitgroup.New(uint(q.cfg.Metrics.ConcurrentBlocks))
+		mtx      sync.Mutex
+		firstErr error
+		skipped  int
+	)
+	for _, m := range metas {
+		wg.Add(1)
+		go func(m *backend.BlockMeta) {
+			defer wg.Done()
+
+			err := q.store.ProjectSpans(ctx, m, p, func(s *common.ProjectedSpan) error {
+				cb(s)
+				return ctx.Err()
+			}, common.SearchOptions{})
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			switch {
+			case errors.Is(err, common.ErrUnsupported):
+				skipped++
+			case err != nil && firstErr == nil:
+				firstErr = fmt.Errorf("error reading block %s: %w", m.BlockID, err)
+			}
+		}(m)
+	}
+	wg.Wait()
+
+	return skipped, firstErr
+}
+
+// projectionFor returns the projection of the fields the query references. Projected spans don't have the parent
+// and child count intrinsics, queries referencing them or the attributes of the parent are unsupported.
+func projectionFor(expr *traceql.RootExpr) (common.Projection, error) {
+	p := common.Projection{
+		TraceID:   true,
+		StartTime: true,
+	}
+
+	seen := map[string]struct{}{}
+	addAttribute := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			p.Attributes = append(p.Attributes, name)
+		}
+	}
+
+	for _, a := range expr.Attributes() {
+		if a.Parent {
+			return common.Projection{}, fmt.Errorf("%w: attributes of the parent can't be read by column: %s", errUnsupportedMetricsQuery, a.String())
+		}
+		switch a.Intrinsic {
+		case traceql.IntrinsicDuration:
+			p.Duration = true
+		case traceql.IntrinsicName:
+			addAttribute(projectedLabelName)
+		case traceql.IntrinsicStatus:
+			addAttribute(projectedLabelStatusCode)
+		case traceql.IntrinsicNone:
+			addAttribute(a.Name)
+		default:
+			return common.Projection{}, fmt.Errorf("%w: %s can't be read by column", errUnsupportedMetricsQuery, a.String())
+		}
+	}
+
+	return p, nil
+}
+
+// projectedSpan evaluates queries against a projected span. Projected attributes keep neither their type nor their
+// scope, values are strings and span attributes shadow resource attributes of the same name.
+type projectedSpan struct {
+	*common.ProjectedSpan
+}
+
+func (s projectedSpan) AttributeFor(a traceql.Attribute) (traceql.Static, bool) {
+	switch a.Intrinsic {
+	case traceql.IntrinsicDuration:
+		return traceql.Static{Type: traceql.TypeDuration, D: time.Duration(s.DurationNanos)}, true
+	case traceql.IntrinsicName:
+		name, ok := s.Attributes[projectedLabelName]
+		return traceql.Static{Type: traceql.TypeString, S: name}, ok
+	case traceql.IntrinsicStatus:
+		code, err := strconv.Atoi(s.Attributes[projectedLabelStatusCode])
+		if err != nil {
+			return traceql.Static{}, false
+		}
+		// otlp status codes
+		status := traceql.StatusUnset
+		switch code {
+		case 1:
+			status = traceql.StatusOk
+		case 2:
+			status = traceql.StatusError
+		}
+		return traceql.Static{Type: traceql.TypeStatus, Status: status}, true
+	case traceql.IntrinsicNone:
+		v, ok := s.Attributes[a.Name]
+		return traceql.Static{Type: traceql.TypeString, S: v}, ok
+	}
+
+	return traceql.Static{}, false
+}
@@ -0,0 +1,344 @@
+package querier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/modules/storage"
+	"github.com/grafana/tempo/pkg/api"
+	"github.com/grafana/tempo/pkg/ddsketch"
+	"github.com/grafana/tempo/pkg/traceql"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// projectingStore is a store of blocks that can only be projected or have span duration sketches
+type projectingStore struct {
+	storage.Store
+
+	metas    []*backend.BlockMeta
+	spans    map[uuid.UUID][]common.ProjectedSpan
+	sketches map[uuid.UUID]*ddsketch.Sketch
+	// spanCounts are the spans per step and service of blocks with statistics, all spans are counted as service ""
+	spanCounts map[uuid.UUID]map[string][]uint64
+}
+
+func (s *projectingStore) BlockMetas(string) []*backend.BlockMeta {
+	return s.metas
+}
+
+func (s *projectingStore) ProjectSpans(_ context.Context, meta *backend.BlockMeta, _ common.Projection, cb func(*common.ProjectedSpan) error, _ common.SearchOptions) error {
+	spans, ok := s.spans[meta.BlockID]
+	if !ok {
+		return common.ErrUnsupported
+	}
+	for i := range spans {
+		if err := cb(&spans[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *projectingStore) DurationSketches(_ context.Context, metas []*backend.BlockMeta, _ string, _, _ time.Time) (*ddsketch.Sketch, []*backend.BlockMeta, error) {
+	merged := ddsketch.New()
+	var remaining []*backend.BlockMeta
+	for _, m := range metas {
+		sketch, ok := s.sketches[m.BlockID]
+		if !ok {
+			remaining = append(remaining, m)
+			continue
+		}
+		merged.Merge(sketch)
+	}
+	return merged, remaining, nil
+}
+
+func (s *projectingStore) CountServiceSpans(_ context.Context, metas []*backend.BlockMeta, service string, start, end time.Time, step time.Duration) ([]uint64, []*backend.BlockMeta, error) {
+	total := make([]uint64, (end.Sub(start)+step-1)/step)
+	var remaining []*backend.BlockMeta
+	for _, m := range metas {
+		services, ok := s.spanCounts[m.BlockID]
+		if !ok {
+			remaining = append(remaining, m)
+			continue
+		}
+		for i, c := range services[service] {
+			total[i] += c
+		}
+	}
+	return total, remaining, nil
+}
+
+func TestQueryRange(t *testing.T) {
+	start := time.Unix(1000, 0)
+	span := func(offset, duration time.Duration, attrs map[string]string) common.ProjectedSpan {
+		return common.ProjectedSpan{
+			TraceID:           []byte{0x01},
+			StartTimeUnixNano: uint64(start.Add(offset).UnixNano()),
+			DurationNanos:     uint64(duration),
+			Attributes:        attrs,
+		}
+	}
+	meta := func(from, to time.Duration) *backend.BlockMeta {
+		return &backend.BlockMeta{BlockID: uuid.New(), StartTime: start.Add(from), EndTime: start.Add(to)}
+	}
+
+	inRange, unsupported, outOfRange := meta(0, time.Minute), meta(0, time.Minute), meta(time.Hour, 2*time.Hour)
+	store := &projectingStore{
+		metas: []*backend.BlockMeta{inRange, unsupported, outOfRange},
+		spans: map[uuid.UUID][]common.ProjectedSpan{
+			inRange.BlockID: {
+				span(0, 300*time.Millisecond, map[string]string{"http.status_code": "500", "status.code": "2"}),
+				span(30*time.Second, 3*time.Second, map[string]string{"http.status_code": "500", "status.code": "2"}),
+				span(30*time.Second, time.Second, map[string]string{"http.status_code": "200"}),
+			},
+			outOfRange.BlockID: {
+				span(time.Hour, time.Second, map[string]string{"http.status_code": "500", "status.code": "2"}),
+			},
+		},
+	}
+	q := &Querier{
+		cfg:   Config{Metrics: MetricsConfig{ConcurrentBlocks: 2}},
+		store: store,
+	}
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	resp, err := q.QueryRange(ctx, &api.MetricsRequest{
+		Query: "{ .http.status_code >= 500 && status = error } | histogram_over_time(duration)",
+		Start: uint32(start.Unix()),
+		End:   uint32(start.Add(time.Minute).Unix()),
+		Step:  30 * time.Second,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, &api.MetricsQueryRangeResponse{
+		Start:  uint32(start.Unix()),
+		StepMs: 30_000,
+		Series: []api.MetricsSeries{
+			{
+				Bucket:    0.5,
+				Counts:    []uint64{1, 0},
+				Exemplars: []api.MetricsExemplar{{TraceID: "01", Value: 0.3, TimestampMs: 1_000_000}},
+			},
+			{
+				Bucket:    4,
+				Counts:    []uint64{0, 1},
+				Exemplars: []api.MetricsExemplar{{TraceID: "01", Value: 3, TimestampMs: 1_030_000}},
+			},
+		},
+		SkippedBlocks: 1,
+	}, resp)
+
+	// the parent can't be read by column
+	_, err = q.QueryRange(ctx, &api.MetricsRequest{
+		Query: "{ parent.a = 1 } | histogram_over_time(duration)",
+		Start: uint32(start.Unix()),
+		End:   uint32(start.Add(time.Minute).Unix()),
+		Step:  30 * time.Second,
+	})
+	require.ErrorIs(t, err, errUnsupportedMetricsQuery)
+}
+
+func TestQueryRangeCountOverTime(t *testing.T) {
+	start := time.Unix(1000, 0)
+	span := func(offset time.Duration, service string) common.ProjectedSpan {
+		return common.ProjectedSpan{
+			TraceID:           []byte{0x01},
+			StartTimeUnixNano: uint64(start.Add(offset).UnixNano()),
+			Attributes:        map[string]string{"service.name": service},
+		}
+	}
+
+	counted := &backend.BlockMeta{BlockID: uuid.New(), StartTime: start, EndTime: start.Add(10 * time.Second)}
+	projected := &backend.BlockMeta{BlockID: uuid.New(), StartTime: start, EndTime: start.Add(time.Minute)}
+	otherService := &backend.BlockMeta{BlockID: uuid.New(), StartTime: start, EndTime: start.Add(time.Minute), ServiceNames: []string{"db"}}
+	store := &projectingStore{
+		metas: []*backend.BlockMeta{counted, projected, otherService},
+		spans: map[uuid.UUID][]common.ProjectedSpan{
+			counted.BlockID:      {span(0, "api"), span(0, "api")},
+			projected.BlockID:    {span(0, "api"), span(30*time.Second, "api"), span(30*time.Second, "db")},
+			otherService.BlockID: {span(0, "db")},
+		},
+		spanCounts: map[uuid.UUID]map[string][]uint64{counted.BlockID: {"": {5, 0}, "api": {5, 0}}},
+	}
+	q := &Querier{
+		cfg:   Config{Metrics: MetricsConfig{ConcurrentBlocks: 2}},
+		store: store,
+	}
+
+	tests := []struct {
+		query    string
+		expected []uint64
+	}{
+		// the counted block isn't read, the block without the service is skipped
+		{query: `{ resource.service.name = "api" } | count_over_time()`, expected: []uint64{6, 1}},
+		{query: `{ true } | count_over_time()`, expected: []uint64{7, 2}},
+		// the statistics can't be filtered by other conditions, all blocks are read
+		{query: `{ resource.service.name = "api" && duration > 1s } | count_over_time()`},
+		{query: `{ .service.name = "db" } | count_over_time()`, expected: []uint64{1, 1}},
+		// unscoped attributes match span attributes first, the spans of all blocks are read
+		{query: `{ .service.name = "api" } | count_over_time()`, expected: []uint64{3, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), "test")
+			resp, err := q.QueryRange(ctx, &api.MetricsRequest{
+				Query: tc.query,
+				Start: uint32(start.Unix()),
+				End:   uint32(start.Add(time.Minute).Unix()),
+				Step:  30 * time.Second,
+			})
+			require.NoError(t, err)
+
+			if tc.expected == nil {
+				assert.Empty(t, resp.Series)
+				return
+			}
+			require.Len(t, resp.Series, 1)
+			assert.Equal(t, 0.0, resp.Series[0].Bucket)
+			assert.Equal(t, tc.expected, resp.Series[0].Counts)
+		})
+	}
+}
+
+func TestQueryThresholds(t *testing.T) {
+	start := time.Unix(1000, 0)
+	span := func(duration time.Duration, name string) common.ProjectedSpan {
+		return common.ProjectedSpan{
+			TraceID:           []byte{0x01},
+			StartTimeUnixNano: uint64(start.UnixNano()),
+			DurationNanos:     uint64(duration),
+			Attributes:        map[string]string{"name": name, "service.name": "api"},
+		}
+	}
+
+	block := &backend.BlockMeta{BlockID: uuid.New(), StartTime: start, EndTime: start.Add(time.Minute)}
+	store := &projectingStore{
+		metas: []*backend.BlockMeta{block},
+		spans: map[uuid.UUID][]common.ProjectedSpan{
+			block.BlockID: {
+				span(time.Second, "a"),
+				span(3*time.Second, "a"),
+				span(2*time.Second, "b"),
+			},
+		},
+	}
+	q := &Querier{
+		cfg:   Config{Metrics: MetricsConfig{ConcurrentBlocks: 2}},
+		store: store,
+	}
+
+	ctx := user.InjectOrgID(context.Background(), "test")
+	resp, err := q.QueryThresholds(ctx, &api.MetricsRequest{
+		Query: `{ .service.name = "api" && duration > quantile_over_time(duration, 1) && duration >= quantile_over_time(duration, 0) by(name) } | histogram_over_time(duration)`,
+		Start: uint32(start.Unix()),
+		End:   uint32(start.Add(time.Minute).Unix()),
+		Step:  30 * time.Second,
+	})
+	require.NoError(t, err)
+
+	values := resp.Values()
+	require.Len(t, values, 2)
+	assert.InEpsilon(t, 3, values[0][traceql.Static{Type: traceql.TypeNil}], ddsketch.RelativeAccuracy)
+	assert.InEpsilon(t, 1, values[1][traceql.Static{Type: traceql.TypeString, S: "a"}], ddsketch.RelativeAccuracy)
+	assert.InEpsilon(t, 2, values[1][traceql.Static{Type: traceql.TypeString, S: "b"}], ddsketch.RelativeAccuracy)
+
+	// queries without thresholds have no values
+	resp, err = q.QueryThresholds(ctx, &api.MetricsRequest{
+		Query: "{ .a = 1 } | histogram_over_time(duration)",
+		Start: uint32(start.Unix()),
+		End:   uint32(start.Add(time.Minute).Unix()),
+		Step:  30 * time.Second,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Thresholds)
+}
+
+func TestQueryThresholdsDurationSketches(t *testing.T) {
+	start := time.Unix(1000, 0)
+	sketched := &backend.BlockMeta{BlockID: uuid.New(), StartTime: start, EndTime: start.Add(time.Minute)}
+	projected := &backend.BlockMeta{BlockID: uuid.New(), StartTime: start, EndTime: start.Add(time.Minute)}
+
+	sketch := ddsketch.New()
+	sketch.Add(float64(5 * time.Second))
+	store := &projectingStore{
+		metas: []*backend.BlockMeta{sketched, projected},
+		spans: map[uuid.UUID][]common.ProjectedSpan{
+			projected.BlockID: {{
+				TraceID:           []byte{0x01},
+				StartTimeUnixNano: uint64(start.UnixNano()),
+				DurationNanos:     uint64(time.Second),
+				Attributes:        map[string]string{"service.name": "api"},
+			}},
+		},
+		sketches: map[uuid.UUID]*ddsketch.Sketch{sketched.BlockID: sketch},
+	}
+	q := &Querier{
+		cfg:   Config{Metrics: MetricsConfig{ConcurrentBlocks: 2}},
+		store: store,
+	}
+
+	tests := []struct {
+		query    string
+		expected float64
+	}{
+		// the sketched block isn't read
+		{query: `{ resource.service.name = "api" && duration > quantile_over_time(duration, 1) }`, expected: 5},
+		// sketches can't be filtered by other conditions, only the projected block is read
+		{query: `{ .service.name = "api" && .a = "b" && duration > quantile_over_time(duration, 1) }`},
+		{query: `{ duration > quantile_over_time(duration, 1) }`, expected: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			ctx := user.InjectOrgID(context.Background(), "test")
+			resp, err := q.QueryThresholds(ctx, &api.MetricsRequest{
+				Query: tc.query + " | histogram_over_time(duration)",
+				Start: uint32(start.Unix()),
+				End:   uint32(start.Add(time.Minute).Unix()),
+				Step:  30 * time.Second,
+			})
+			require.NoError(t, err)
+
+			values := resp.Values()
+			require.Len(t, values, 1)
+			if tc.expected == 0 {
+				assert.Empty(t, values[0])
+				return
+			}
+			assert.InEpsilon(t, tc.expected, values[0][traceql.Static{Type: traceql.TypeNil}], ddsketch.RelativeAccuracy)
+		})
+	}
+}
+
+func TestProjectedSpan(t *testing.T) {
+	s := projectedSpan{&common.ProjectedSpan{
+		DurationNanos: uint64(time.Second),
+		Attributes:    map[string]string{"name": "GET", "status.code": "1", "a": "b"},
+	}}
+
+	v, ok := s.AttributeFor(traceql.Attribute{Intrinsic: traceql.IntrinsicDuration})
+	require.True(t, ok)
+	assert.Equal(t, traceql.Static{Type: traceql.TypeDuration, D: time.Second}, v)
+
+	v, ok = s.AttributeFor(traceql.Attribute{Intrinsic: traceql.IntrinsicName})
+	require.True(t, ok)
+	assert.Equal(t, traceql.Static{Type: traceql.TypeString, S: "GET"}, v)
+
+	v, ok = s.AttributeFor(traceql.Attribute{Intrinsic: traceql.IntrinsicStatus})
+	require.True(t, ok)
+	assert.Equal(t, traceql.Static{Type: traceql.TypeStatus, Status: traceql.StatusOk}, v)
+
+	v, ok = s.AttributeFor(traceql.Attribute{Scope: traceql.AttributeScopeResource, Name: "a"})
+	require.True(t, ok)
+	assert.Equal(t, traceql.Static{Type: traceql.TypeString, S: "b"}, v)
+
+	_, ok = s.AttributeFor(traceql.Attribute{Name: "c"})
+	require.False(t, ok)
+}
@@ -397,6 +397,46 @@ func (q *Querier) SearchTagValues(ctx context.Context, req *tempopb.SearchTagVal
 	return resp, nil
 }
 
+// SearchTagValuesV2 returns the values of a tag with their data type and number of occurrences, and a summary of the
+// numeric values. Every replica of the data is counted.
+func (q *Querier) SearchTagValuesV2(ctx context.Context, req *tempopb.SearchTagValuesRequest) (*tempopb.SearchTagValuesV2Response, error) {
+	userID, err := user.ExtractOrgID(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error extracting org id in Querier.SearchTagValuesV2")
+	}
+
+	limit := q.limits.MaxBytesPerTagValuesQuery(userID)
+	distinctValues := util.NewDistinctValueCollector(limit)
+
+	// Virtual tags values. Get these first, they don't occur in the data.
+	for _, v := range search.GetVirtualTagValues(req.TagName) {
+		distinctValues.Collect(common.InferTagValueType(v), v, 0)
+	}
+
+	// Get results from all ingesters
+	replicationSet, err := q.ring.GetReplicationSetForOperation(ring.Read)
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding ingesters in Querier.SearchTagValuesV2")
+	}
+	lookupResults, err := q.forGivenIngesters(ctx, replicationSet, func(client tempopb.QuerierClient) (interface{}, error) {
+		return client.SearchTagValuesV2(ctx, req)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error querying ingesters in Querier.SearchTagValuesV2")
+	}
+	for _, resp := range lookupResults {
+		for _, res := range resp.response.(*tempopb.SearchTagValuesV2Response).TagValues {
+			distinctValues.Collect(res.Type, res.Value, res.Count)
+		}
+	}
+
+	if distinctValues.Exceeded() {
+		level.Warn(log.Logger).Log("msg", "size of tag values in instance exceeded limit, reduce cardinality or size of tags", "tag", req.TagName, "userID", userID, "limit", limit, "total", distinctValues.TotalDataSize())
+	}
+
+	return typedTagValues(distinctValues.Values()), nil
+}
+
 // SearchBlock searches the specified subset of the block for the passed tags.
 func (q *Querier) SearchBlock(ctx context.Context, req *tempopb.SearchBlockRequest) (*tempopb.SearchResponse, error) {
 	// if we have no external configuration always search in the querier
@@ -447,6 +487,7 @@ func (q *Querier) internalSearchBlock(ctx context.Context, req *tempopb.SearchBl
 		BlockID:       blockID,
 		DataEncoding:  req.DataEncoding,
 		FooterSize:    req.FooterSize,
+		IndexVersion:  req.IndexVersion,
 	}
 
 	opts := common.SearchOptions{}
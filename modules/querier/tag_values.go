@@ -0,0 +1,83 @@
+package querier
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// tagValuesHistogramBuckets is the number of equal width buckets used to summarize numeric tag values
+const tagValuesHistogramBuckets = 10
+
+// typedTagValues returns the response for the typed tag values and summarizes the numeric ones weighted by their
+// number of occurrences.
+func typedTagValues(values []*tempopb.TagValue) *tempopb.SearchTagValuesV2Response {
+	return &tempopb.SearchTagValuesV2Response{
+		TagValues: values,
+		Numeric:   numericSummary(values, tagValuesHistogramBuckets),
+	}
+}
+
+type numericTagValue struct {
+	value float64
+	count uint64
+}
+
+// numericSummary computes the min, max and an equal width histogram of the occurrences of the int and double values.
+// It returns nil if there are none.
+func numericSummary(values []*tempopb.TagValue, buckets int) *tempopb.NumericTagValuesSummary {
+	var numbers []numericTagValue
+	for _, v := range values {
+		if v.Count == 0 || (v.Type != common.TagValueTypeInt && v.Type != common.TagValueTypeDouble) {
+			continue
+		}
+		f, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			continue
+		}
+		numbers = append(numbers, numericTagValue{value: f, count: v.Count})
+	}
+	if len(numbers) == 0 {
+		return nil
+	}
+
+	summary := &tempopb.NumericTagValuesSummary{
+		Min: numbers[0].value,
+		Max: numbers[0].value,
+	}
+	for _, n := range numbers {
+		summary.Min = math.Min(summary.Min, n.value)
+		summary.Max = math.Max(summary.Max, n.value)
+		summary.Count += n.count
+	}
+
+	// a single bucket is enough if all values are equal
+	if summary.Min == summary.Max {
+		buckets = 1
+	}
+
+	width := (summary.Max - summary.Min) / float64(buckets)
+	summary.Histogram = make([]*tempopb.TagValuesHistogramBucket, buckets)
+	for i := range summary.Histogram {
+		summary.Histogram[i] = &tempopb.TagValuesHistogramBucket{
+			LowerBound: summary.Min + float64(i)*width,
+			UpperBound: summary.Min + float64(i+1)*width,
+		}
+	}
+	summary.Histogram[buckets-1].UpperBound = summary.Max
+
+	for _, n := range numbers {
+		i := buckets - 1
+		if width > 0 {
+			i = int((n.value - summary.Min) / width)
+			if i >= buckets {
+				i = buckets - 1
+			}
+		}
+		summary.Histogram[i].Count += n.count
+	}
+
+	return summary
+}
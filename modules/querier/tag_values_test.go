@@ -0,0 +1,66 @@
+package querier
+
+import (
+	"testing"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedTagValues(t *testing.T) {
+	values := []*tempopb.TagValue{
+		{Type: common.TagValueTypeInt, Value: "-1", Count: 3},
+		{Type: common.TagValueTypeDouble, Value: "1.5", Count: 1},
+		{Type: common.TagValueTypeInt, Value: "200", Count: 5},
+		{Type: common.TagValueTypeString, Value: "200", Count: 7}, // a string attribute holding a number
+		{Type: common.TagValueTypeString, Value: "GET", Count: 2},
+		{Type: common.TagValueTypeBool, Value: "true", Count: 1},
+		{Type: common.TagValueTypeDouble, Value: "1e3", Count: 2},
+		{Type: common.TagValueTypeInt, Value: "5000"}, // a virtual value
+	}
+	resp := typedTagValues(values)
+
+	assert.Equal(t, values, resp.TagValues)
+
+	require.NotNil(t, resp.Numeric)
+	assert.Equal(t, -1.0, resp.Numeric.Min)
+	assert.Equal(t, 1000.0, resp.Numeric.Max)
+	assert.Equal(t, uint64(11), resp.Numeric.Count)
+	require.Len(t, resp.Numeric.Histogram, tagValuesHistogramBuckets)
+
+	var total uint64
+	for _, b := range resp.Numeric.Histogram {
+		total += b.Count
+	}
+	assert.Equal(t, uint64(11), total)
+	assert.Equal(t, uint64(4), resp.Numeric.Histogram[0].Count) // -1, 1.5
+	assert.Equal(t, uint64(5), resp.Numeric.Histogram[2].Count) // 200
+	assert.Equal(t, uint64(2), resp.Numeric.Histogram[9].Count) // 1000
+	assert.Equal(t, 1000.0, resp.Numeric.Histogram[9].UpperBound)
+}
+
+func TestTypedTagValuesNoNumbers(t *testing.T) {
+	resp := typedTagValues([]*tempopb.TagValue{
+		{Type: common.TagValueTypeString, Value: "1", Count: 1},
+		{Type: common.TagValueTypeBool, Value: "false", Count: 1},
+	})
+
+	assert.Nil(t, resp.Numeric)
+}
+
+func TestNumericSummarySingleValue(t *testing.T) {
+	summary := numericSummary([]*tempopb.TagValue{
+		{Type: common.TagValueTypeInt, Value: "3", Count: 2},
+	}, tagValuesHistogramBuckets)
+
+	assert.Equal(t, &tempopb.NumericTagValuesSummary{
+		Min:   3,
+		Max:   3,
+		Count: 2,
+		Histogram: []*tempopb.TagValuesHistogramBucket{
+			{LowerBound: 3, UpperBound: 3, Count: 2},
+		},
+	}, summary)
+}
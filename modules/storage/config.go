@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/tempo/tempodb/backend/s3"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 	"github.com/grafana/tempo/tempodb/pool"
 	"github.com/grafana/tempo/tempodb/wal"
 )
@@ -33,12 +34,19 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 
 	f.StringVar(&cfg.Trace.Backend, util.PrefixConfig(prefix, "trace.backend"), "", "Trace backend (s3, azure, gcs, local)")
 	f.DurationVar(&cfg.Trace.BlocklistPoll, util.PrefixConfig(prefix, "trace.blocklist_poll"), tempodb.DefaultBlocklistPoll, "Period at which to run the maintenance cycle.")
+	cfg.Trace.BlocklistNotifications.BlocklistPoll = tempodb.DefaultNotificationsBlocklistPoll
 
 	cfg.Trace.WAL = &wal.Config{}
 	f.StringVar(&cfg.Trace.WAL.Filepath, util.PrefixConfig(prefix, "trace.wal.path"), "/var/tempo/wal", "Path at which store WAL blocks.")
 	cfg.Trace.WAL.Encoding = backend.EncSnappy
 	cfg.Trace.WAL.SearchEncoding = backend.EncNone
 	cfg.Trace.WAL.IngestionSlack = 2 * time.Minute
+	cfg.Trace.WAL.FlushPolicy = wal.FlushPolicyNever
+	cfg.Trace.WAL.FlushInterval = time.Second
+	cfg.Trace.WAL.ReplayConcurrency = 1
+	cfg.Trace.WAL.Version = v2.VersionString
+	cfg.Trace.WAL.ZstdDictionary.SampleBytes = 256 * 1024
+	cfg.Trace.WAL.ZstdDictionary.MaxSizeBytes = 64 * 1024
 
 	cfg.Trace.Search = &tempodb.SearchConfig{}
 	cfg.Trace.Search.ChunkSizeBytes = tempodb.DefaultSearchChunkSizeBytes
@@ -46,6 +54,9 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	cfg.Trace.Search.ReadBufferCount = tempodb.DefaultReadBufferCount
 	cfg.Trace.Search.ReadBufferSizeBytes = tempodb.DefaultReadBufferSize
 
+	cfg.Trace.BloomPinning.MaxBlockAge = tempodb.DefaultBloomPinningMaxBlockAge
+	cfg.Trace.BloomPinning.MaxBytes = tempodb.DefaultBloomPinningMaxBytes
+
 	cfg.Trace.Block = &common.BlockConfig{}
 	f.Float64Var(&cfg.Trace.Block.BloomFP, util.PrefixConfig(prefix, "trace.block.bloom-filter-false-positive"), .01, "Bloom Filter False Positive.")
 	f.IntVar(&cfg.Trace.Block.BloomShardSizeBytes, util.PrefixConfig(prefix, "trace.block.bloom-filter-shard-size-bytes"), 100*1024, "Bloom Filter Shard Size in bytes.")
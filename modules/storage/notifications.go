@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/kv/memberlist"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/grafana/tempo/tempodb"
+	"github.com/grafana/tempo/tempodb/blocklist"
+)
+
+const (
+	maxNotificationBodySize = 1 << 20
+
+	blocklistEventsKey = "blocklist-notifications"
+)
+
+// BlocklistNotificationHandler shares the block events of bucket notifications POSTed by S3 (directly or through
+// an SNS subscription) or a GCS Pub/Sub push subscription with every replica through the kv store. Every replica
+// updates its blocklist with the events. SNS subscriptions are not confirmed automatically, the confirmation url
+// is logged.
+func (s *store) BlocklistNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := s.cfg.Trace.BlocklistNotifications.Token.String()
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	if s.blocklistEventsKV == nil {
+		http.Error(w, "blocklist notifications are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxNotificationBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n, err := blocklist.ParseNotification(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if n.SubscribeURL != "" {
+		level.Info(s.logger).Log("msg", "received SNS subscription confirmation for blocklist notifications", "subscribeURL", n.SubscribeURL)
+	}
+	if len(n.Events) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = s.shareBlocklistEvents(r.Context(), n.Events)
+	if err != nil {
+		// the blocklist catches up on the next poll
+		level.Error(s.logger).Log("msg", "failed to share blocklist notification", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// EnableBlocklistNotifications shares the block events of bucket notifications through the kv store. A bucket
+// notification is delivered to a single replica, the events are applied by every replica watching the kv store.
+func (s *store) EnableBlocklistNotifications(kvCfg kv.Config) error {
+	client, err := kv.NewClient(kvCfg, BlocklistEventsCodec, nil, s.logger)
+	if err != nil {
+		return fmt.Errorf("failed to create kv client for blocklist notifications: %w", err)
+	}
+	s.blocklistEventsKV = client
+	return nil
+}
+
+// shareBlocklistEvents adds the events to the kv store and removes the events received before the last poll
+func (s *store) shareBlocklistEvents(ctx context.Context, events []blocklist.BlockEvent) error {
+	now := time.Now()
+	expired := now.Add(-s.cfg.Trace.BlocklistPollInterval()).UnixNano()
+
+	return s.blocklistEventsKV.CAS(ctx, blocklistEventsKey, func(in interface{}) (out interface{}, retry bool, err error) {
+		shared, _ := in.(*BlocklistEvents)
+		if shared == nil {
+			shared = NewBlocklistEvents()
+		}
+
+		for key, e := range shared.Events {
+			if e.ReceivedAt < expired {
+				delete(shared.Events, key)
+			}
+		}
+		for _, e := range events {
+			shared.Events[blocklistEventKey(e)] = ReceivedBlockEvent{BlockEvent: e, ReceivedAt: now.UnixNano()}
+		}
+		return shared, true, nil
+	})
+}
+
+// watchBlocklistEvents applies the events shared by any replica until the context is done. An event is applied
+// once, unless it's received again.
+func (s *store) watchBlocklistEvents(ctx context.Context) {
+	applied := map[string]int64{}
+
+	s.blocklistEventsKV.WatchKey(ctx, blocklistEventsKey, func(in interface{}) bool {
+		shared, ok := in.(*BlocklistEvents)
+		if !ok || shared == nil {
+			return true
+		}
+
+		var events []blocklist.BlockEvent
+		for key, e := range shared.Events {
+			if applied[key] >= e.ReceivedAt {
+				continue
+			}
+			applied[key] = e.ReceivedAt
+			events = append(events, e.BlockEvent)
+		}
+		for key := range applied {
+			if _, ok := shared.Events[key]; !ok {
+				delete(applied, key)
+			}
+		}
+		if len(events) == 0 {
+			return true
+		}
+
+		err := s.Reader.ApplyBlocklistEvents(ctx, events)
+		if err != nil && !errors.Is(err, tempodb.ErrPollingDisabled) {
+			// the blocklist catches up on the next poll
+			level.Error(s.logger).Log("msg", "failed to apply blocklist notification", "err", err)
+		}
+		return true
+	})
+}
+
+func blocklistEventKey(e blocklist.BlockEvent) string {
+	return fmt.Sprintf("%s/%s/%t/%t", e.TenantID, e.BlockID, e.Compacted, e.Removed)
+}
+
+// ReceivedBlockEvent is a block event and the time it was received in unix nanoseconds
+type ReceivedBlockEvent struct {
+	blocklist.BlockEvent
+	ReceivedAt int64 `json:"receivedAt"`
+}
+
+// BlocklistEvents are the block events of the bucket notifications received by any replica since the last poll,
+// by event.
+type BlocklistEvents struct {
+	Events map[string]ReceivedBlockEvent `json:"events"`
+}
+
+func NewBlocklistEvents() *BlocklistEvents {
+	return &BlocklistEvents{Events: map[string]ReceivedBlockEvent{}}
+}
+
+// Merge implements the memberlist.Mergeable interface. The events received last are kept. Events missing from
+// the other events of a local CAS were removed.
+func (e *BlocklistEvents) Merge(mergeable memberlist.Mergeable, localCAS bool) (memberlist.Mergeable, error) {
+	if mergeable == nil {
+		return nil, nil
+	}
+	other, ok := mergeable.(*BlocklistEvents)
+	if !ok {
+		return nil, fmt.Errorf("expected *storage.BlocklistEvents, got %T", mergeable)
+	}
+	if other == nil {
+		return nil, nil
+	}
+	if e.Events == nil {
+		e.Events = map[string]ReceivedBlockEvent{}
+	}
+
+	change := NewBlocklistEvents()
+	for key, o := range other.Events {
+		if cur, ok := e.Events[key]; ok && cur.ReceivedAt >= o.ReceivedAt {
+			continue
+		}
+		e.Events[key] = o
+		change.Events[key] = o
+	}
+	if localCAS {
+		for key := range e.Events {
+			if _, ok := other.Events[key]; !ok {
+				delete(e.Events, key)
+			}
+		}
+	}
+
+	if len(change.Events) == 0 {
+		return nil, nil
+	}
+	return change, nil
+}
+
+// MergeContent implements the memberlist.Mergeable interface
+func (e *BlocklistEvents) MergeContent() []string {
+	keys := make([]string, 0, len(e.Events))
+	for key := range e.Events {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// RemoveTombstones removes the events received before the limit, they have been gossiped to every replica
+func (e *BlocklistEvents) RemoveTombstones(limit time.Time) (total, removed int) {
+	if limit.IsZero() {
+		return len(e.Events), 0
+	}
+	for key, ev := range e.Events {
+		if ev.ReceivedAt < limit.UnixNano() {
+			delete(e.Events, key)
+			removed++
+		}
+	}
+	return len(e.Events), removed
+}
+
+func (e *BlocklistEvents) Clone() memberlist.Mergeable {
+	clone := NewBlocklistEvents()
+	for key, ev := range e.Events {
+		clone.Events[key] = ev
+	}
+	return clone
+}
+
+// BlocklistEventsCodec encodes the shared blocklist events, it must be registered with the memberlist kv
+var BlocklistEventsCodec = blocklistEventsCodec{}
+
+type blocklistEventsCodec struct{}
+
+func (blocklistEventsCodec) Decode(data []byte) (interface{}, error) {
+	events := NewBlocklistEvents()
+	if err := jsoniter.ConfigFastest.Unmarshal(data, events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (blocklistEventsCodec) Encode(obj interface{}) ([]byte, error) {
+	return jsoniter.ConfigFastest.Marshal(obj)
+}
+
+func (blocklistEventsCodec) CodecID() string { return "storage.blocklistEventsCodec" }
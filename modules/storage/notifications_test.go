@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/dskit/kv"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb"
+	"github.com/grafana/tempo/tempodb/blocklist"
+)
+
+type eventsReader struct {
+	tempodb.Reader
+
+	mtx    sync.Mutex
+	events []blocklist.BlockEvent
+}
+
+func (r *eventsReader) ApplyBlocklistEvents(_ context.Context, events []blocklist.BlockEvent) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func (r *eventsReader) applied() []blocklist.BlockEvent {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return append([]blocklist.BlockEvent(nil), r.events...)
+}
+
+func (r *eventsReader) Shutdown() {}
+
+func newNotificationsStore(t *testing.T, reader tempodb.Reader) *store {
+	s := &store{
+		logger: log.NewNopLogger(),
+		Reader: reader,
+	}
+	s.cfg.Trace.BlocklistPoll = time.Minute
+	s.cfg.Trace.BlocklistNotifications = blocklist.NotificationsConfig{
+		Enabled: true,
+		Token:   flagext.SecretWithValue("secret"),
+	}
+	require.NoError(t, s.EnableBlocklistNotifications(kv.Config{Store: "inmemory"}))
+
+	s.Service = services.NewBasicService(s.starting, s.running, s.stopping)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), s))
+	t.Cleanup(func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), s))
+	})
+	return s
+}
+
+func TestBlocklistNotificationHandler(t *testing.T) {
+	// the notification is delivered to one replica and applied by all of them
+	receiving, other := &eventsReader{}, &eventsReader{}
+	s := newNotificationsStore(t, receiving)
+	newNotificationsStore(t, other)
+
+	body := `{"Records": [{"eventName": "ObjectCreated:Put", "s3": {"object": {"key": "single-tenant/00000000-0000-0000-0000-000000000001/meta.json"}}}]}`
+
+	for _, token := range []string{"", "wrong"} {
+		w := httptest.NewRecorder()
+		s.BlocklistNotificationHandler(w, httptest.NewRequest(http.MethodPost, "/api/blocklist/notifications?token="+token, strings.NewReader(body)))
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	s.BlocklistNotificationHandler(w, httptest.NewRequest(http.MethodPost, "/api/blocklist/notifications?token=secret", strings.NewReader(body)))
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	expected := []blocklist.BlockEvent{{TenantID: "single-tenant", BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000001")}}
+	for _, r := range []*eventsReader{receiving, other} {
+		require.Eventually(t, func() bool {
+			return len(r.applied()) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, expected, r.applied())
+	}
+}
+
+func TestBlocklistEventsMerge(t *testing.T) {
+	now := time.Now()
+	event := func(tenant string, receivedAt time.Time) ReceivedBlockEvent {
+		return ReceivedBlockEvent{BlockEvent: blocklist.BlockEvent{TenantID: tenant}, ReceivedAt: receivedAt.UnixNano()}
+	}
+
+	events := &BlocklistEvents{Events: map[string]ReceivedBlockEvent{
+		"a": event("a", now),
+		"b": event("b", now),
+	}}
+
+	// only newer events are a change
+	change, err := events.Merge(&BlocklistEvents{Events: map[string]ReceivedBlockEvent{
+		"a": event("a", now.Add(-time.Second)),
+		"b": event("b", now.Add(time.Second)),
+		"c": event("c", now),
+	}}, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"b", "c"}, change.MergeContent())
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, events.MergeContent())
+
+	change, err = events.Merge(events.Clone(), false)
+	require.NoError(t, err)
+	assert.Nil(t, change)
+
+	// a local CAS removes the events missing from the new value
+	_, err = events.Merge(&BlocklistEvents{Events: map[string]ReceivedBlockEvent{
+		"c": event("c", now),
+	}}, true)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"c"}, events.MergeContent())
+
+	total, removed := events.RemoveTombstones(now.Add(time.Second))
+	assert.Equal(t, 0, total)
+	assert.Equal(t, 1, removed)
+}
@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"net/http"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/dskit/kv"
 	"github.com/grafana/dskit/services"
 
 	"github.com/grafana/tempo/pkg/usagestats"
@@ -26,16 +28,23 @@ type Store interface {
 	tempodb.Reader
 	tempodb.Writer
 	tempodb.Compactor
+
+	EnableBlocklistNotifications(kvCfg kv.Config) error
+	BlocklistNotificationHandler(w http.ResponseWriter, r *http.Request)
 }
 
 type store struct {
 	services.Service
 
-	cfg Config
+	cfg    Config
+	logger log.Logger
 
 	tempodb.Reader
 	tempodb.Writer
 	tempodb.Compactor
+
+	// blocklistEventsKV shares the events of bucket notifications with every replica if they are enabled
+	blocklistEventsKV kv.Client
 }
 
 // NewStore creates a new Tempo Store using configuration supplied.
@@ -55,12 +64,13 @@ func NewStore(cfg Config, logger log.Logger) (Store, error) {
 
 	s := &store{
 		cfg:       cfg,
+		logger:    logger,
 		Reader:    r,
 		Writer:    w,
 		Compactor: c,
 	}
 
-	s.Service = services.NewIdleService(s.starting, s.stopping)
+	s.Service = services.NewBasicService(s.starting, s.running, s.stopping)
 	return s, nil
 }
 
@@ -68,6 +78,15 @@ func (s *store) starting(_ context.Context) error {
 	return nil
 }
 
+func (s *store) running(ctx context.Context) error {
+	if s.blocklistEventsKV != nil {
+		s.watchBlocklistEvents(ctx)
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
 func (s *store) stopping(_ error) error {
 	s.Reader.Shutdown()
 
@@ -21,7 +21,10 @@ import (
 )
 
 const (
-	URLParamTraceID = "traceID"
+	URLParamTraceID   = "traceID"
+	URLParamQueryName = "name"
+	// URLParamLegalHoldID is the id of a legal hold
+	URLParamLegalHoldID = "id"
 	// search
 	urlParamQuery       = "q"
 	urlParamTags        = "tags"
@@ -42,6 +45,7 @@ const (
 	urlParamVersion       = "version"
 	urlParamSize          = "size"
 	urlParamFooterSize    = "footerSize"
+	urlParamIndexVersion  = "indexVersion"
 
 	// maxBytes (serverless only)
 	urlParamMaxBytes = "maxBytes"
@@ -51,13 +55,29 @@ const (
 	HeaderAcceptProtobuf = "application/protobuf"
 	HeaderAcceptJSON     = "application/json"
 
+	// HeaderAcceptEventStream requests search progress as server-sent events
+	HeaderAcceptEventStream = "text/event-stream"
+
 	PathPrefixQuerier = "/querier"
 
-	PathTraces          = "/api/traces/{traceID}"
-	PathSearch          = "/api/search"
-	PathSearchTags      = "/api/search/tags"
-	PathSearchTagValues = "/api/search/tag/{tagName}/values"
-	PathEcho            = "/api/echo"
+	PathTraces            = "/api/traces/{traceID}"
+	PathSearch            = "/api/search"
+	PathSearchRecent      = "/api/search/recent"
+	PathSearchTags        = "/api/search/tags"
+	PathSearchTagValues   = "/api/search/tag/{tagName}/values"
+	PathSearchTagValuesV2 = "/api/v2/search/tag/{tagName}/values"
+	PathEcho              = "/api/echo"
+
+	PathSavedQueries       = "/api/queries/saved"
+	PathSavedQuery         = "/api/queries/saved/{name}"
+	PathQueryHistory       = "/api/queries/history"
+	PathSavedQueriesExport = "/api/queries/export"
+	PathSavedQueriesImport = "/api/queries/import"
+
+	PathLegalHolds = "/api/legal-holds"
+	PathLegalHold  = "/api/legal-holds/{id}"
+
+	PathBlocklistNotifications = "/api/blocklist/notifications"
 
 	QueryModeKey       = "mode"
 	QueryModeIngesters = "ingesters"
@@ -109,11 +129,25 @@ func ParseSearchRequest(r *http.Request) (*tempopb.SearchRequest, error) {
 
 	query, queryFound := extractQueryParam(r, urlParamQuery)
 	if queryFound {
-		_, err := traceql.Parse(query)
+		expr, err := traceql.Parse(query)
 		if err != nil {
 			return nil, fmt.Errorf("invalid query: %w", err)
 		}
 		req.Query = query
+
+		// a relative time range in the query is translated to start and end. it is dropped from the query so the
+		// request can be passed on with start and end.
+		if since := expr.Since; since > 0 {
+			if req.Start != 0 || req.End != 0 {
+				return nil, errors.New("invalid request: can't specify start or end and since in the query")
+			}
+			now := time.Now()
+			req.Start = uint32(now.Add(-since).Unix())
+			req.End = uint32(now.Unix())
+
+			expr.Since = 0
+			req.Query = expr.String()
+		}
 	}
 
 	encodedTags, tagsFound := extractQueryParam(r, urlParamTags)
@@ -300,6 +334,9 @@ func ParseSearchBlockRequest(r *http.Request) (*tempopb.SearchBlockRequest, erro
 	}
 	req.FooterSize = uint32(footerSize)
 
+	// index version is empty for most blocks
+	req.IndexVersion = r.URL.Query().Get(urlParamIndexVersion)
+
 	return req, nil
 }
 
@@ -377,6 +414,9 @@ func BuildSearchBlockRequest(req *http.Request, searchReq *tempopb.SearchBlockRe
 	q.Set(urlParamDataEncoding, searchReq.DataEncoding)
 	q.Set(urlParamVersion, searchReq.Version)
 	q.Set(urlParamFooterSize, strconv.FormatUint(uint64(searchReq.FooterSize), 10))
+	if searchReq.IndexVersion != "" {
+		q.Set(urlParamIndexVersion, searchReq.IndexVersion)
+	}
 
 	req.URL.RawQuery = q.Encode()
 
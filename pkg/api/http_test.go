@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -183,6 +184,34 @@ func TestQuerierParseSearchRequest(t *testing.T) {
 	}
 }
 
+func TestQuerierParseSearchRequestSince(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://tempo/api/search?q="+url.QueryEscape(`{ .foo = "bar" } since 2h`), nil)
+
+	before := time.Now()
+	searchRequest, err := ParseSearchRequest(r)
+	require.NoError(t, err)
+
+	assert.Equal(t, "{ .foo = `bar` }", searchRequest.Query)
+	assert.Equal(t, uint32(2*time.Hour/time.Second), searchRequest.End-searchRequest.Start)
+	assert.GreaterOrEqual(t, searchRequest.End, uint32(before.Unix()))
+
+	// the request can be passed on with start and end
+	r, err = BuildSearchRequest(nil, searchRequest)
+	require.NoError(t, err)
+	forwarded, err := ParseSearchRequest(r)
+	require.NoError(t, err)
+	assert.Equal(t, searchRequest, forwarded)
+
+	// since can't be combined with start and end
+	r = httptest.NewRequest("GET", "http://tempo/api/search?start=10&end=20&q="+url.QueryEscape(`{ .foo = "bar" } since 2h`), nil)
+	_, err = ParseSearchRequest(r)
+	assert.EqualError(t, err, "invalid request: can't specify start or end and since in the query")
+
+	r = httptest.NewRequest("GET", "http://tempo/api/search?q="+url.QueryEscape(`{ .foo = "bar" } since`), nil)
+	_, err = ParseSearchRequest(r)
+	assert.EqualError(t, err, "invalid query: parse error at line 1, col 23: syntax error: unexpected $end, expecting DURATION")
+}
+
 func TestQuerierParseSearchRequestTags(t *testing.T) {
 	type strMap map[string]string
 
@@ -312,6 +341,27 @@ func TestParseSearchBlockRequest(t *testing.T) {
 				FooterSize:    2000,
 			},
 		},
+		{
+			url: "/?start=10&end=20&startPage=0&pagesToSearch=10&blockID=b92ec614-3fd7-4299-b6db-f657e7025a9b&encoding=s2&footerSize=0&indexPageSize=10&totalRecords=11&dataEncoding=v1&version=v2&size=1000&indexVersion=v3",
+			expected: &tempopb.SearchBlockRequest{
+				SearchReq: &tempopb.SearchRequest{
+					Tags:  map[string]string{},
+					Start: 10,
+					End:   20,
+					Limit: defaultLimit,
+				},
+				StartPage:     0,
+				PagesToSearch: 10,
+				BlockID:       "b92ec614-3fd7-4299-b6db-f657e7025a9b",
+				Encoding:      "s2",
+				IndexPageSize: 10,
+				TotalRecords:  11,
+				DataEncoding:  "v1",
+				Version:       "v2",
+				Size_:         1000,
+				IndexVersion:  "v3",
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -389,6 +439,21 @@ func TestBuildSearchBlockRequest(t *testing.T) {
 			},
 			query: "?blockID=b92ec614-3fd7-4299-b6db-f657e7025a9b&dataEncoding=v1&encoding=s2&end=20&footerSize=2000&indexPageSize=10&limit=50&maxDuration=40ms&minDuration=30ms&pagesToSearch=10&size=1000&start=10&startPage=0&tags=foo%3Dbar&totalRecords=11&version=v2",
 		},
+		{
+			req: &tempopb.SearchBlockRequest{
+				StartPage:     0,
+				PagesToSearch: 10,
+				BlockID:       "b92ec614-3fd7-4299-b6db-f657e7025a9b",
+				Encoding:      "s2",
+				IndexPageSize: 10,
+				TotalRecords:  11,
+				DataEncoding:  "v1",
+				Version:       "v2",
+				Size_:         1000,
+				IndexVersion:  "v3",
+			},
+			query: "?blockID=b92ec614-3fd7-4299-b6db-f657e7025a9b&dataEncoding=v1&encoding=s2&footerSize=0&indexPageSize=10&indexVersion=v3&pagesToSearch=10&size=1000&startPage=0&totalRecords=11&version=v2",
+		},
 	}
 
 	for _, tc := range tests {
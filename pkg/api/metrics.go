@@ -0,0 +1,197 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/tempo/pkg/traceql"
+)
+
+const (
+	PathMetricsQueryRange = "/api/metrics/query_range"
+	PathMetricsThresholds = "/api/metrics/thresholds"
+
+	urlParamStep = "step"
+
+	// maxMetricsSteps bounds the number of values of every series of a metrics query
+	maxMetricsSteps = 1000
+	// defaultMetricsSteps is the number of steps a range is divided into if the request doesn't set the step
+	defaultMetricsSteps = 100
+)
+
+// MetricsRequest is a request for the metrics of the spans matching a TraceQL query over a time range. Start and end
+// are in unix epoch seconds.
+type MetricsRequest struct {
+	Query string
+	Start uint32
+	End   uint32
+	Step  time.Duration
+}
+
+// MetricsExemplar is a span counted in a bucket of a histogram
+type MetricsExemplar struct {
+	TraceID     string  `json:"traceID"`
+	Value       float64 `json:"value"`
+	TimestampMs int64   `json:"timestampMs"`
+}
+
+// MetricsSeries is the number of spans per step of a bucket of a histogram, Bucket is the inclusive upper bound of
+// the bucket.
+type MetricsSeries struct {
+	Bucket    float64           `json:"bucket"`
+	Counts    []uint64          `json:"counts"`
+	Exemplars []MetricsExemplar `json:"exemplars,omitempty"`
+}
+
+// MetricsQueryRangeResponse is the result of a metrics query. The first value of every series is the one of the step
+// starting at Start. SkippedBlocks is the number of blocks in the range that couldn't be read by column.
+type MetricsQueryRangeResponse struct {
+	Start         uint32          `json:"start"`
+	StepMs        int64           `json:"stepMs"`
+	Series        []MetricsSeries `json:"series"`
+	SkippedBlocks int             `json:"skippedBlocks,omitempty"`
+}
+
+// MetricsThresholdValue is the value of a threshold for a group of spans. The group is the value of the attribute the
+// threshold groups by, or nil if the threshold doesn't group spans.
+type MetricsThresholdValue struct {
+	Group traceql.Static `json:"group"`
+	Value float64        `json:"value"`
+}
+
+// MetricsThresholdsResponse is the result of the first phase of a metrics query with thresholds, the values of the
+// thresholds in the order they appear in the query. SkippedBlocks is the number of blocks in the range that couldn't
+// be read by column.
+type MetricsThresholdsResponse struct {
+	Thresholds    [][]MetricsThresholdValue `json:"thresholds"`
+	SkippedBlocks int                       `json:"skippedBlocks,omitempty"`
+}
+
+// NewMetricsThresholdsResponse returns the response for the values of the thresholds of a query
+func NewMetricsThresholdsResponse(values []traceql.MetricsThresholdValues, skippedBlocks int) *MetricsThresholdsResponse {
+	resp := &MetricsThresholdsResponse{
+		Thresholds:    make([][]MetricsThresholdValue, 0, len(values)),
+		SkippedBlocks: skippedBlocks,
+	}
+	for _, v := range values {
+		threshold := make([]MetricsThresholdValue, 0, len(v))
+		for group, value := range v {
+			threshold = append(threshold, MetricsThresholdValue{Group: group, Value: value})
+		}
+		sort.Slice(threshold, func(i, j int) bool {
+			return threshold[i].Group.String() < threshold[j].Group.String()
+		})
+		resp.Thresholds = append(resp.Thresholds, threshold)
+	}
+	return resp
+}
+
+// Values returns the values of the thresholds to resolve the query with
+func (r *MetricsThresholdsResponse) Values() []traceql.MetricsThresholdValues {
+	values := make([]traceql.MetricsThresholdValues, 0, len(r.Thresholds))
+	for _, threshold := range r.Thresholds {
+		v := make(traceql.MetricsThresholdValues, len(threshold))
+		for _, t := range threshold {
+			v[t.Group] = t.Value
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// ParseMetricsRequest decodes the query params of a metrics request. The query must end with a metrics function. A
+// relative time range in the query is translated to start and end, the step defaults to a hundredth of the range.
+func ParseMetricsRequest(r *http.Request) (*MetricsRequest, error) {
+	req := &MetricsRequest{}
+
+	query, ok := extractQueryParam(r, urlParamQuery)
+	if !ok {
+		return nil, errors.New("invalid request: q is required")
+	}
+	expr, err := traceql.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if _, ok := expr.MetricsAggregate(); !ok {
+		return nil, errors.New("invalid query: metrics queries must end with a metrics function")
+	}
+	req.Query = query
+
+	if s, ok := extractQueryParam(r, urlParamStart); ok {
+		start, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start: %w", err)
+		}
+		req.Start = uint32(start)
+	}
+	if s, ok := extractQueryParam(r, urlParamEnd); ok {
+		end, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end: %w", err)
+		}
+		req.End = uint32(end)
+	}
+
+	if since := expr.Since; since > 0 {
+		if req.Start != 0 || req.End != 0 {
+			return nil, errors.New("invalid request: can't specify start or end and since in the query")
+		}
+		now := time.Now()
+		req.Start = uint32(now.Add(-since).Unix())
+		req.End = uint32(now.Unix())
+
+		expr.Since = 0
+		req.Query = expr.String()
+	}
+	if req.Start == 0 || req.End == 0 {
+		return nil, errors.New("invalid request: start and end or since in the query are required")
+	}
+	if req.End <= req.Start {
+		return nil, errors.New("invalid request: end must be after start")
+	}
+
+	rng := time.Duration(req.End-req.Start) * time.Second
+	if s, ok := extractQueryParam(r, urlParamStep); ok {
+		req.Step, err = time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid step: %w", err)
+		}
+		if req.Step <= 0 {
+			return nil, errors.New("invalid step: must be greater than 0")
+		}
+	} else {
+		req.Step = rng / defaultMetricsSteps
+		if req.Step < time.Second {
+			req.Step = time.Second
+		}
+	}
+	if rng/req.Step > maxMetricsSteps {
+		return nil, fmt.Errorf("invalid request: the range can't be divided into more than %d steps", maxMetricsSteps)
+	}
+
+	return req, nil
+}
+
+// BuildMetricsRequest populates the http.Request with the params of the metrics request. If no http.Request is
+// provided a new one is created.
+func BuildMetricsRequest(req *http.Request, metricsReq *MetricsRequest) *http.Request {
+	if req == nil {
+		req = &http.Request{
+			URL: &url.URL{},
+		}
+	}
+
+	q := req.URL.Query()
+	q.Set(urlParamQuery, metricsReq.Query)
+	q.Set(urlParamStart, strconv.FormatUint(uint64(metricsReq.Start), 10))
+	q.Set(urlParamEnd, strconv.FormatUint(uint64(metricsReq.End), 10))
+	q.Set(urlParamStep, metricsReq.Step.String())
+	req.URL.RawQuery = q.Encode()
+
+	return req
+}
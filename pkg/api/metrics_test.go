@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/traceql"
+)
+
+func TestParseMetricsRequest(t *testing.T) {
+	query := url.QueryEscape("{ .a = 1 } | histogram_over_time(duration)")
+
+	tests := []struct {
+		url         string
+		expected    *MetricsRequest
+		expectedErr string
+	}{
+		{
+			url: "/api/metrics/query_range?start=1000&end=2000&step=10s&q=" + query,
+			expected: &MetricsRequest{
+				Query: "{ .a = 1 } | histogram_over_time(duration)",
+				Start: 1000,
+				End:   2000,
+				Step:  10 * time.Second,
+			},
+		},
+		{
+			url: "/api/metrics/query_range?start=1000&end=2000&q=" + query,
+			expected: &MetricsRequest{
+				Query: "{ .a = 1 } | histogram_over_time(duration)",
+				Start: 1000,
+				End:   2000,
+				Step:  10 * time.Second,
+			},
+		},
+		{
+			url:         "/api/metrics/query_range?start=1000&end=2000",
+			expectedErr: "invalid request: q is required",
+		},
+		{
+			url:         "/api/metrics/query_range?start=1000&end=2000&q=" + url.QueryEscape("{ .a = 1 }"),
+			expectedErr: "invalid query: metrics queries must end with a metrics function",
+		},
+		{
+			url:         "/api/metrics/query_range?q=" + query,
+			expectedErr: "invalid request: start and end or since in the query are required",
+		},
+		{
+			url:         "/api/metrics/query_range?start=2000&end=1000&q=" + query,
+			expectedErr: "invalid request: end must be after start",
+		},
+		{
+			url:         "/api/metrics/query_range?start=1000&end=2000&step=0s&q=" + query,
+			expectedErr: "invalid step: must be greater than 0",
+		},
+		{
+			url:         "/api/metrics/query_range?start=1000&end=200000&step=1s&q=" + query,
+			expectedErr: "invalid request: the range can't be divided into more than 1000 steps",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.url, nil)
+			actual, err := ParseMetricsRequest(r)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+
+			// the request is passed on unchanged
+			forwarded, err := ParseMetricsRequest(BuildMetricsRequest(nil, actual))
+			require.NoError(t, err)
+			assert.Equal(t, actual, forwarded)
+		})
+	}
+}
+
+func TestParseMetricsRequestSince(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/metrics/query_range?q="+url.QueryEscape("{ .a = 1 } | histogram_over_time(duration) since 1h"), nil)
+
+	req, err := ParseMetricsRequest(r)
+	require.NoError(t, err)
+	assert.Equal(t, "{ .a = 1 }|histogram_over_time(duration)", req.Query)
+	assert.Equal(t, uint32(3600), req.End-req.Start)
+	assert.Equal(t, 36*time.Second, req.Step)
+
+	forwarded, err := ParseMetricsRequest(BuildMetricsRequest(nil, req))
+	require.NoError(t, err)
+	assert.Equal(t, req, forwarded)
+}
+
+func TestMetricsThresholdsResponse(t *testing.T) {
+	values := []traceql.MetricsThresholdValues{
+		{traceql.Static{Type: traceql.TypeNil}: 1.5},
+		{
+			traceql.Static{Type: traceql.TypeString, S: "b"}: 2,
+			traceql.Static{Type: traceql.TypeString, S: "a"}: 0.5,
+		},
+	}
+
+	resp := NewMetricsThresholdsResponse(values, 1)
+	assert.Equal(t, []MetricsThresholdValue{
+		{Group: traceql.Static{Type: traceql.TypeString, S: "a"}, Value: 0.5},
+		{Group: traceql.Static{Type: traceql.TypeString, S: "b"}, Value: 2},
+	}, resp.Thresholds[1])
+
+	b, err := json.Marshal(resp)
+	require.NoError(t, err)
+
+	decoded := &MetricsThresholdsResponse{}
+	require.NoError(t, json.Unmarshal(b, decoded))
+	assert.Equal(t, 1, decoded.SkippedBlocks)
+	assert.Equal(t, values, decoded.Values())
+}
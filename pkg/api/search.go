@@ -2,14 +2,24 @@ package api
 
 import (
 	"net/http"
+	"strings"
 )
 
 // IsBackendSearch returns true if the request has a start, end and tags parameter and is the /api/search path
 func IsBackendSearch(r *http.Request) bool {
+	if IsSearchRecent(r) {
+		return false
+	}
 	q := r.URL.Query()
 	return q.Get(urlParamStart) != "" && q.Get(urlParamEnd) != ""
 }
 
+// IsSearchRecent returns true if the request is for the /api/search/recent path, which only searches the ingesters
+// regardless of the time range
+func IsSearchRecent(r *http.Request) bool {
+	return strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), PathSearchRecent)
+}
+
 // IsSearchBlock returns true if the request appears to be for backend blocks. It is not exhaustive
 // and only looks for blockID
 func IsSearchBlock(r *http.Request) bool {
@@ -16,6 +16,18 @@ func TestIsBackendSearch(t *testing.T) {
 	assert.True(t, IsBackendSearch(httptest.NewRequest("GET", "/api/search/?start=1&end=2&tags=test", nil)))
 	assert.True(t, IsBackendSearch(httptest.NewRequest("GET", "/querier/api/search?start=1&end=2&tags=test", nil)))
 	assert.True(t, IsBackendSearch(httptest.NewRequest("GET", "/querier/api/search/?start=1&end=2&tags=test", nil)))
+
+	assert.False(t, IsBackendSearch(httptest.NewRequest("GET", "/api/search/recent?start=1&end=2&tags=test", nil)))
+	assert.False(t, IsBackendSearch(httptest.NewRequest("GET", "/querier/api/search/recent?start=1&end=2&tags=test", nil)))
+}
+
+func TestIsSearchRecent(t *testing.T) {
+	assert.False(t, IsSearchRecent(httptest.NewRequest("GET", "/api/search", nil)))
+	assert.False(t, IsSearchRecent(httptest.NewRequest("GET", "/api/search/tags", nil)))
+
+	assert.True(t, IsSearchRecent(httptest.NewRequest("GET", "/api/search/recent", nil)))
+	assert.True(t, IsSearchRecent(httptest.NewRequest("GET", "/api/search/recent/?tags=test", nil)))
+	assert.True(t, IsSearchRecent(httptest.NewRequest("GET", "/querier/api/search/recent?start=1&end=2", nil)))
 }
 
 func TestIsSearchBlock(t *testing.T) {
@@ -2,7 +2,7 @@ package cache
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"strings"
@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-redis/redis/v8"
 
+	"github.com/grafana/dskit/crypto/tls"
 	"github.com/grafana/dskit/flagext"
 )
 
@@ -18,6 +19,7 @@ import (
 type RedisConfig struct {
 	Endpoint           string         `yaml:"endpoint"`
 	MasterName         string         `yaml:"master_name"`
+	ClusterMode        bool           `yaml:"cluster_mode"`
 	Timeout            time.Duration  `yaml:"timeout"`
 	Expiration         time.Duration  `yaml:"expiration"`
 	DB                 int            `yaml:"db"`
@@ -28,6 +30,10 @@ type RedisConfig struct {
 	SentinelPassword   flagext.Secret `yaml:"sentinel_password"`
 	EnableTLS          bool           `yaml:"tls_enabled"`
 	InsecureSkipVerify bool           `yaml:"tls_insecure_skip_verify"`
+	TLSCAPath          string         `yaml:"tls_ca_path"`
+	TLSCertPath        string         `yaml:"tls_cert_path"`
+	TLSKeyPath         string         `yaml:"tls_key_path"`
+	TLSServerName      string         `yaml:"tls_server_name"`
 	IdleTimeout        time.Duration  `yaml:"idle_timeout"`
 	MaxConnAge         time.Duration  `yaml:"max_connection_age"`
 }
@@ -36,6 +42,7 @@ type RedisConfig struct {
 func (cfg *RedisConfig) RegisterFlagsWithPrefix(prefix, description string, f *flag.FlagSet) {
 	f.StringVar(&cfg.Endpoint, prefix+"redis.endpoint", "", description+"Redis Server endpoint to use for caching. A comma-separated list of endpoints for Redis Cluster or Redis Sentinel. If empty, no redis will be used.")
 	f.StringVar(&cfg.MasterName, prefix+"redis.master-name", "", description+"Redis Sentinel master name. An empty string for Redis Server or Redis Cluster.")
+	f.BoolVar(&cfg.ClusterMode, prefix+"redis.cluster-mode", false, description+"Connect to a Redis Cluster even if a single endpoint is configured, e.g. the configuration endpoint of a managed cluster.")
 	f.DurationVar(&cfg.Timeout, prefix+"redis.timeout", 500*time.Millisecond, description+"Maximum time to wait before giving up on redis requests.")
 	f.DurationVar(&cfg.Expiration, prefix+"redis.expiration", 0, description+"How long keys stay in the redis.")
 	f.IntVar(&cfg.DB, prefix+"redis.db", 0, description+"Database index.")
@@ -46,6 +53,10 @@ func (cfg *RedisConfig) RegisterFlagsWithPrefix(prefix, description string, f *f
 	f.Var(&cfg.SentinelPassword, prefix+"redis.sentinel-password", description+"Password to use when connecting to redis sentinel.")
 	f.BoolVar(&cfg.EnableTLS, prefix+"redis.tls-enabled", false, description+"Enable connecting to redis with TLS.")
 	f.BoolVar(&cfg.InsecureSkipVerify, prefix+"redis.tls-insecure-skip-verify", false, description+"Skip validating server certificate.")
+	f.StringVar(&cfg.TLSCAPath, prefix+"redis.tls-ca-path", "", description+"Path to the CA certificates to validate the server certificate against. If empty, the system CAs are used.")
+	f.StringVar(&cfg.TLSCertPath, prefix+"redis.tls-cert-path", "", description+"Path to the client certificate to authenticate with. Also requires the key path to be configured.")
+	f.StringVar(&cfg.TLSKeyPath, prefix+"redis.tls-key-path", "", description+"Path to the key of the client certificate.")
+	f.StringVar(&cfg.TLSServerName, prefix+"redis.tls-server-name", "", description+"Override the expected name on the server certificate.")
 	f.DurationVar(&cfg.IdleTimeout, prefix+"redis.idle-timeout", 0, description+"Close connections after remaining idle for this duration. If the value is zero, then idle connections are not closed.")
 	f.DurationVar(&cfg.MaxConnAge, prefix+"redis.max-connection-age", 0, description+"Close connections older than this duration. If the value is zero, then the pool does not close connections based on age.")
 }
@@ -57,7 +68,14 @@ type RedisClient struct {
 }
 
 // NewRedisClient creates Redis client
-func NewRedisClient(cfg *RedisConfig) *RedisClient {
+func NewRedisClient(cfg *RedisConfig) (*RedisClient, error) {
+	if cfg.ClusterMode && cfg.MasterName != "" {
+		return nil, errors.New("redis cluster mode and sentinel master name are mutually exclusive")
+	}
+	if cfg.ClusterMode && cfg.DB != 0 {
+		return nil, errors.New("redis cluster mode only supports db 0")
+	}
+
 	opt := &redis.UniversalOptions{
 		Addrs:            strings.Split(cfg.Endpoint, ","),
 		MasterName:       cfg.MasterName,
@@ -71,13 +89,33 @@ func NewRedisClient(cfg *RedisConfig) *RedisClient {
 		MaxConnAge:       cfg.MaxConnAge,
 	}
 	if cfg.EnableTLS {
-		opt.TLSConfig = &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+		tlsCfg := &tls.ClientConfig{
+			CAPath:             cfg.TLSCAPath,
+			CertPath:           cfg.TLSCertPath,
+			KeyPath:            cfg.TLSKeyPath,
+			ServerName:         cfg.TLSServerName,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}
+		var err error
+		opt.TLSConfig, err = tlsCfg.GetTLSConfig()
+		if err != nil {
+			return nil, fmt.Errorf("redis tls config: %w", err)
+		}
 	}
+
+	var rdb redis.UniversalClient
+	if cfg.ClusterMode {
+		// NewUniversalClient only creates a cluster client for multiple endpoints
+		rdb = redis.NewClusterClient(opt.Cluster())
+	} else {
+		rdb = redis.NewUniversalClient(opt)
+	}
+
 	return &RedisClient{
 		expiration: cfg.Expiration,
 		timeout:    cfg.Timeout,
-		rdb:        redis.NewUniversalClient(opt),
-	}
+		rdb:        rdb,
+	}, nil
 }
 
 func (c *RedisClient) Ping(ctx context.Context) error {
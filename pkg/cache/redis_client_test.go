@@ -19,6 +19,10 @@ func TestRedisClient(t *testing.T) {
 	require.Nil(t, err)
 	defer cluster.Close()
 
+	clusterMode, err := mockRedisClientClusterMode()
+	require.Nil(t, err)
+	defer clusterMode.Close()
+
 	ctx := context.Background()
 
 	tests := []struct {
@@ -33,6 +37,10 @@ func TestRedisClient(t *testing.T) {
 			name:   "cluster redis client",
 			client: cluster,
 		},
+		{
+			name:   "cluster mode redis client",
+			client: clusterMode,
+		},
 	}
 
 	for _, tt := range tests {
@@ -78,7 +86,7 @@ func mockRedisClientSingle() (*RedisClient, error) {
 		}, ","),
 	}
 
-	return NewRedisClient(cfg), nil
+	return NewRedisClient(cfg)
 }
 
 func mockRedisClientCluster() (*RedisClient, error) {
@@ -101,5 +109,52 @@ func mockRedisClientCluster() (*RedisClient, error) {
 		}, ","),
 	}
 
-	return NewRedisClient(cfg), nil
+	return NewRedisClient(cfg)
+}
+
+func mockRedisClientClusterMode() (*RedisClient, error) {
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &RedisConfig{
+		Expiration:  time.Minute,
+		Timeout:     100 * time.Millisecond,
+		Endpoint:    redisServer.Addr(),
+		ClusterMode: true,
+	}
+
+	return NewRedisClient(cfg)
+}
+
+func TestNewRedisClientInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RedisConfig
+	}{
+		{
+			name: "cluster mode with sentinel",
+			cfg:  RedisConfig{Endpoint: "localhost:6379", ClusterMode: true, MasterName: "master"},
+		},
+		{
+			name: "cluster mode with db",
+			cfg:  RedisConfig{Endpoint: "localhost:6379", ClusterMode: true, DB: 1},
+		},
+		{
+			name: "tls cert without key",
+			cfg:  RedisConfig{Endpoint: "localhost:6379", EnableTLS: true, TLSCertPath: "cert.pem"},
+		},
+		{
+			name: "missing tls ca",
+			cfg:  RedisConfig{Endpoint: "localhost:6379", EnableTLS: true, TLSCAPath: "does-not-exist.pem"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRedisClient(&tt.cfg)
+			require.Error(t, err)
+		})
+	}
 }
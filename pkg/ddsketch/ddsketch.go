@@ -0,0 +1,152 @@
+package ddsketch
+
+import (
+	"math"
+)
+
+// RelativeAccuracy is the relative error of the quantiles of all sketches, i.e. a quantile q of the values added to a
+// sketch is within q*(1±RelativeAccuracy). It is the same for all sketches so that any sketches can be merged.
+const RelativeAccuracy = 0.02
+
+var (
+	gamma    = (1 + RelativeAccuracy) / (1 - RelativeAccuracy)
+	logGamma = math.Log(gamma)
+)
+
+// Sketch summarizes the distribution of non-negative values in logarithmically sized bins (DDSketch,
+// https://arxiv.org/abs/1908.10693). Values below 1 are counted as zeros, so values should be in a unit that makes
+// them integers, e.g. nanoseconds. Sketches are encoded as json and can be merged.
+type Sketch struct {
+	Count uint64  `json:"count"`
+	Zeros uint64  `json:"zeros,omitempty"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	// Offset is the index of the first bin
+	Offset int      `json:"offset"`
+	Bins   []uint64 `json:"bins"`
+}
+
+// New returns an empty sketch
+func New() *Sketch {
+	return &Sketch{}
+}
+
+// Add adds a value to the sketch. Negative values are counted as zeros.
+func (s *Sketch) Add(v float64) {
+	if v < 0 || math.IsNaN(v) {
+		v = 0
+	}
+
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count++
+	s.Sum += v
+
+	if v < 1 {
+		s.Zeros++
+		return
+	}
+	s.addToBin(index(v), 1)
+}
+
+// Merge adds the values of o to the sketch
+func (s *Sketch) Merge(o *Sketch) {
+	if o == nil || o.Count == 0 {
+		return
+	}
+
+	if s.Count == 0 || o.Min < s.Min {
+		s.Min = o.Min
+	}
+	if s.Count == 0 || o.Max > s.Max {
+		s.Max = o.Max
+	}
+	s.Count += o.Count
+	s.Zeros += o.Zeros
+	s.Sum += o.Sum
+
+	for i, c := range o.Bins {
+		if c > 0 {
+			s.addToBin(o.Offset+i, c)
+		}
+	}
+}
+
+// Quantile returns the estimated q-quantile, 0 <= q <= 1, of the values added to the sketch. 0 is returned for empty
+// sketches.
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.Min
+	}
+	if q >= 1 {
+		return s.Max
+	}
+
+	rank := uint64(q * float64(s.Count-1))
+	if rank < s.Zeros {
+		return s.Min
+	}
+
+	n := s.Zeros
+	for i, c := range s.Bins {
+		n += c
+		if n > rank {
+			return clamp(value(s.Offset+i), s.Min, s.Max)
+		}
+	}
+	return s.Max
+}
+
+// Mean returns the mean of the values added to the sketch, 0 for empty sketches
+func (s *Sketch) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// addToBin adds c to the count of the bin with the given index, growing the bins as needed
+func (s *Sketch) addToBin(idx int, c uint64) {
+	switch {
+	case len(s.Bins) == 0:
+		s.Offset = idx
+		s.Bins = append(s.Bins, 0)
+	case idx < s.Offset:
+		bins := make([]uint64, s.Offset-idx+len(s.Bins))
+		copy(bins[s.Offset-idx:], s.Bins)
+		s.Bins = bins
+		s.Offset = idx
+	case idx >= s.Offset+len(s.Bins):
+		s.Bins = append(s.Bins, make([]uint64, idx-s.Offset-len(s.Bins)+1)...)
+	}
+	s.Bins[idx-s.Offset] += c
+}
+
+// index returns the index of the bin of a value >= 1. Bin i holds the values in (gamma^(i-1), gamma^i].
+func index(v float64) int {
+	return int(math.Ceil(math.Log(v) / logGamma))
+}
+
+// value returns the value representing the bin with the given index, which is within the relative accuracy of all
+// values of the bin
+func value(idx int) float64 {
+	return 2 * math.Pow(gamma, float64(idx)) / (gamma + 1)
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,91 @@
+package ddsketch
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSketchQuantiles(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	// log normally distributed values spanning several orders of magnitude
+	values := make([]float64, 10_000)
+	s := New()
+	for i := range values {
+		values[i] = math.Round(math.Exp(r.NormFloat64()*2 + 12))
+		s.Add(values[i])
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0.01, 0.25, 0.5, 0.9, 0.99} {
+		expected := values[int(q*float64(len(values)-1))]
+		assert.InEpsilon(t, expected, s.Quantile(q), RelativeAccuracy, "quantile %v", q)
+	}
+	assert.Equal(t, values[0], s.Quantile(0))
+	assert.Equal(t, values[len(values)-1], s.Quantile(1))
+	assert.Equal(t, uint64(len(values)), s.Count)
+}
+
+func TestSketchZeros(t *testing.T) {
+	s := New()
+	require.Equal(t, 0.0, s.Quantile(0.5))
+	require.Equal(t, 0.0, s.Mean())
+
+	s.Add(0)
+	s.Add(-5)
+	s.Add(0.5)
+	s.Add(100)
+
+	require.Equal(t, uint64(4), s.Count)
+	require.Equal(t, uint64(3), s.Zeros)
+	require.Equal(t, 0.0, s.Quantile(0.5))
+	require.Equal(t, 100.0, s.Quantile(1))
+	// negative values count as zeros
+	require.Equal(t, 0.0, s.Min)
+}
+
+func TestSketchMerge(t *testing.T) {
+	a, b, all := New(), New(), New()
+	for i := 1; i <= 1000; i++ {
+		v := float64(i * 1000)
+		if i%2 == 0 {
+			a.Add(v)
+		} else {
+			b.Add(v)
+		}
+		all.Add(v)
+	}
+	// b has smaller and larger values than a, the bins of a grow in both directions
+	b.Add(1)
+	all.Add(1)
+
+	a.Merge(b)
+	a.Merge(nil)
+	a.Merge(New())
+	require.Equal(t, all, a)
+
+	merged := New()
+	merged.Merge(a)
+	require.Equal(t, all, merged)
+}
+
+func TestSketchJSON(t *testing.T) {
+	s := New()
+	for i := 1; i <= 100; i++ {
+		s.Add(float64(i * i))
+	}
+
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	actual := &Sketch{}
+	require.NoError(t, json.Unmarshal(b, actual))
+	require.Equal(t, s, actual)
+	require.Equal(t, s.Quantile(0.5), actual.Quantile(0.5))
+}
@@ -0,0 +1,391 @@
+package endpointslices
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/services"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	labelServiceName = "kubernetes.io/service-name"
+
+	eventAdded    = "ADDED"
+	eventModified = "MODIFIED"
+	eventDeleted  = "DELETED"
+	eventBookmark = "BOOKMARK"
+	eventError    = "ERROR"
+)
+
+// Config configures the EndpointSlices watched for a Kubernetes service.
+type Config struct {
+	// APIServer is the address of the Kubernetes API server. Defaults to the in-cluster address.
+	APIServer string `yaml:"api_server"`
+	// Namespace of the service. Defaults to the namespace of the pod.
+	Namespace string `yaml:"namespace"`
+	// Service whose EndpointSlices are watched.
+	Service string `yaml:"service"`
+	// PortName is the name of the port to connect to. If empty, or not found, the port of the original address is used.
+	PortName string `yaml:"port_name"`
+	// ResyncPeriod is the maximum duration of a watch before all EndpointSlices are listed again.
+	ResyncPeriod time.Duration `yaml:"resync_period"`
+
+	// service account credentials, overridden in tests
+	serviceAccountDir string
+}
+
+// ApplyDefaults applies the default values.
+func (cfg *Config) ApplyDefaults() {
+	cfg.PortName = "grpc"
+	cfg.ResyncPeriod = 5 * time.Minute
+}
+
+// Endpoint is a single endpoint of the service.
+type Endpoint struct {
+	// Name of the pod, or the hostname if the endpoint doesn't reference a pod.
+	Name  string
+	Addr  string
+	Port  int
+	Ready bool
+}
+
+// Watcher keeps track of the endpoints of a Kubernetes service by watching its EndpointSlices. Compared to
+// polling DNS, changes are picked up as soon as the API server knows about them, and the readiness of every
+// endpoint is known.
+type Watcher struct {
+	services.Service
+
+	cfg    Config
+	url    string
+	client *http.Client
+	token  func() (string, error)
+	logger log.Logger
+
+	mtx       sync.RWMutex
+	slices    map[string][]Endpoint // by slice name
+	byName    map[string]Endpoint
+	lastNames map[string]string // last name seen for an address, kept across pod replacements
+}
+
+// NewWatcher creates a new EndpointSlice watcher and returns a service that is wrapping it.
+func NewWatcher(cfg Config, logger log.Logger) (*Watcher, error) {
+	if cfg.Service == "" {
+		return nil, errors.New("service is required")
+	}
+
+	saDir := cfg.serviceAccountDir
+	if saDir == "" {
+		saDir = serviceAccountDir
+	}
+
+	if cfg.Namespace == "" {
+		ns, err := os.ReadFile(saDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("namespace not configured and failed to read the pod namespace: %w", err)
+		}
+		cfg.Namespace = strings.TrimSpace(string(ns))
+	}
+
+	apiServer := cfg.APIServer
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("api server not configured and not running in a Kubernetes cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if ca, err := os.ReadFile(saDir + "/ca.crt"); err == nil {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	w := &Watcher{
+		cfg:    cfg,
+		url:    fmt.Sprintf("%s/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices", strings.TrimSuffix(apiServer, "/"), url.PathEscape(cfg.Namespace)),
+		client: &http.Client{Transport: transport},
+		logger: log.With(logger, "service", cfg.Service, "namespace", cfg.Namespace),
+		// the token is rotated by the kubelet, read it on every request
+		token: func() (string, error) {
+			token, err := os.ReadFile(saDir + "/token")
+			if os.IsNotExist(err) {
+				return "", nil
+			}
+			return strings.TrimSpace(string(token)), err
+		},
+		slices:    map[string][]Endpoint{},
+		byName:    map[string]Endpoint{},
+		lastNames: map[string]string{},
+	}
+	w.Service = services.NewBasicService(nil, w.running, nil)
+	return w, nil
+}
+
+// Endpoint returns the current endpoint with the given name.
+func (w *Watcher) Endpoint(name string) (Endpoint, bool) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	e, ok := w.byName[name]
+	return e, ok
+}
+
+// EndpointForAddr returns the current endpoint of the pod that was last seen with the given address. If the pod
+// was replaced the endpoint has a different address.
+func (w *Watcher) EndpointForAddr(addr string) (Endpoint, bool) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	name, ok := w.lastNames[addr]
+	if !ok {
+		return Endpoint{}, false
+	}
+	e, ok := w.byName[name]
+	return e, ok
+}
+
+// Endpoints returns all current endpoints.
+func (w *Watcher) Endpoints() []Endpoint {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	endpoints := make([]Endpoint, 0, len(w.byName))
+	for _, e := range w.byName {
+		endpoints = append(endpoints, e)
+	}
+	return endpoints
+}
+
+func (w *Watcher) running(ctx context.Context) error {
+	b := backoff.New(ctx, backoff.Config{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+	})
+
+	for ctx.Err() == nil {
+		resourceVersion, err := w.list(ctx)
+		if err == nil {
+			b.Reset()
+			err = w.watch(ctx, resourceVersion)
+		}
+		if err != nil && ctx.Err() == nil {
+			level.Warn(w.logger).Log("msg", "failed to watch endpoint slices", "err", err)
+			b.Wait()
+		}
+	}
+	return nil
+}
+
+// list replaces all known EndpointSlices and returns the resource version to start watching from.
+func (w *Watcher) list(ctx context.Context) (string, error) {
+	var list endpointSliceList
+	err := w.get(ctx, url.Values{}, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&list)
+	})
+	if err != nil {
+		return "", fmt.Errorf("listing endpoint slices: %w", err)
+	}
+
+	slices := make(map[string][]Endpoint, len(list.Items))
+	for _, s := range list.Items {
+		slices[s.Metadata.Name] = s.endpoints(w.cfg.PortName)
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.slices = slices
+	w.rebuild(true)
+
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch applies changes to the EndpointSlices until the watch expires or fails.
+func (w *Watcher) watch(ctx context.Context, resourceVersion string) error {
+	params := url.Values{}
+	params.Set("watch", "true")
+	params.Set("allowWatchBookmarks", "true")
+	params.Set("resourceVersion", resourceVersion)
+	if w.cfg.ResyncPeriod > 0 {
+		params.Set("timeoutSeconds", strconv.Itoa(int(w.cfg.ResyncPeriod.Seconds())))
+	}
+
+	return w.get(ctx, params, func(body io.Reader) error {
+		dec := json.NewDecoder(body)
+		for {
+			var ev watchEvent
+			err := dec.Decode(&ev)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("decoding watch event: %w", err)
+			}
+
+			switch ev.Type {
+			case eventAdded, eventModified, eventDeleted:
+				var s endpointSlice
+				if err := json.Unmarshal(ev.Object, &s); err != nil {
+					return fmt.Errorf("decoding endpoint slice: %w", err)
+				}
+				w.apply(ev.Type, s)
+			case eventBookmark:
+			case eventError:
+				// usually the resource version is too old. list again
+				return fmt.Errorf("watch error: %s", string(ev.Object))
+			}
+		}
+	})
+}
+
+func (w *Watcher) apply(eventType string, s endpointSlice) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	if eventType == eventDeleted {
+		delete(w.slices, s.Metadata.Name)
+	} else {
+		w.slices[s.Metadata.Name] = s.endpoints(w.cfg.PortName)
+	}
+	w.rebuild(false)
+}
+
+// rebuild updates the endpoints by name. The last name of each address is only forgotten on a full list, and
+// only if no endpoint with that name exists anymore, so a pod that is replaced can still be found by its old
+// address.
+func (w *Watcher) rebuild(full bool) {
+	w.byName = map[string]Endpoint{}
+	for _, endpoints := range w.slices {
+		for _, e := range endpoints {
+			// an endpoint can be part of several slices while they are updated, prefer the ready one
+			if existing, ok := w.byName[e.Name]; ok && existing.Ready && !e.Ready {
+				continue
+			}
+			w.byName[e.Name] = e
+			w.lastNames[e.Addr] = e.Name
+		}
+	}
+
+	if full {
+		for addr, name := range w.lastNames {
+			if _, ok := w.byName[name]; !ok {
+				delete(w.lastNames, addr)
+			}
+		}
+	}
+}
+
+func (w *Watcher) get(ctx context.Context, params url.Values, fn func(io.Reader) error) error {
+	params.Set("labelSelector", labelServiceName+"="+w.cfg.Service)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	token, err := w.token()
+	if err != nil {
+		return fmt.Errorf("reading service account token: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return fn(resp.Body)
+}
+
+// endpointSliceList and endpointSlice are the subset of the discovery.k8s.io/v1 API used by the watcher.
+type endpointSliceList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []endpointSlice `json:"items"`
+}
+
+type endpointSlice struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Endpoints []struct {
+		Addresses  []string `json:"addresses"`
+		Conditions struct {
+			Ready *bool `json:"ready"`
+		} `json:"conditions"`
+		Hostname  string `json:"hostname"`
+		TargetRef *struct {
+			Name string `json:"name"`
+		} `json:"targetRef"`
+	} `json:"endpoints"`
+	Ports []struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	} `json:"ports"`
+}
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+func (s *endpointSlice) endpoints(portName string) []Endpoint {
+	port := 0
+	for _, p := range s.Ports {
+		if p.Name == portName {
+			port = p.Port
+		}
+	}
+
+	endpoints := make([]Endpoint, 0, len(s.Endpoints))
+	for _, e := range s.Endpoints {
+		if len(e.Addresses) == 0 {
+			continue
+		}
+
+		name := e.Hostname
+		if e.TargetRef != nil && e.TargetRef.Name != "" {
+			name = e.TargetRef.Name
+		}
+		if name == "" {
+			continue
+		}
+
+		endpoints = append(endpoints, Endpoint{
+			Name: name,
+			Addr: e.Addresses[0],
+			Port: port,
+			// a nil ready condition is interpreted as ready
+			Ready: e.Conditions.Ready == nil || *e.Conditions.Ready,
+		})
+	}
+	return endpoints
+}
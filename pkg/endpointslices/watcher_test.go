@@ -0,0 +1,110 @@
+package endpointslices
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func endpointSliceJSON(name, pod, addr string, ready bool) string {
+	return fmt.Sprintf(`{"metadata":{"name":"%s"},"addressType":"IPv4","endpoints":[{"addresses":["%s"],"conditions":{"ready":%t},"targetRef":{"kind":"Pod","name":"%s"}}],"ports":[{"name":"http","port":3200},{"name":"grpc","port":9095}]}`, name, addr, ready, pod)
+}
+
+func TestWatcher(t *testing.T) {
+	saDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(saDir, "namespace"), []byte("tempo\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(saDir, "token"), []byte("token\n"), 0600))
+
+	events := make(chan string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/apis/discovery.k8s.io/v1/namespaces/tempo/endpointslices", r.URL.Path)
+		assert.Equal(t, "kubernetes.io/service-name=ingester", r.URL.Query().Get("labelSelector"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		if r.URL.Query().Get("watch") != "true" {
+			_, _ = fmt.Fprintf(w, `{"metadata":{"resourceVersion":"1"},"items":[%s,%s]}`,
+				endpointSliceJSON("ingester-a", "ingester-0", "10.0.0.1", true),
+				endpointSliceJSON("ingester-b", "ingester-1", "10.0.0.2", true))
+			return
+		}
+
+		assert.Equal(t, "1", r.URL.Query().Get("resourceVersion"))
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case ev := <-events:
+				_, _ = fmt.Fprintln(w, ev)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	cfg := Config{}
+	cfg.ApplyDefaults()
+	cfg.APIServer = server.URL
+	cfg.Service = "ingester"
+	cfg.serviceAccountDir = saDir
+
+	w, err := NewWatcher(cfg, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, services.StartAndAwaitRunning(context.Background(), w))
+	defer func() {
+		require.NoError(t, services.StopAndAwaitTerminated(context.Background(), w))
+	}()
+
+	require.Eventually(t, func() bool { return len(w.Endpoints()) == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	e, ok := w.Endpoint("ingester-0")
+	require.True(t, ok)
+	assert.Equal(t, Endpoint{Name: "ingester-0", Addr: "10.0.0.1", Port: 9095, Ready: true}, e)
+
+	// the pod is replaced and gets a new address. it's not ready yet
+	events <- fmt.Sprintf(`{"type":"MODIFIED","object":%s}`, endpointSliceJSON("ingester-a", "ingester-0", "10.0.0.3", false))
+	require.Eventually(t, func() bool {
+		e, _ := w.Endpoint("ingester-0")
+		return e.Addr == "10.0.0.3"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the old address resolves to the new pod
+	e, ok = w.EndpointForAddr("10.0.0.1")
+	require.True(t, ok)
+	assert.Equal(t, Endpoint{Name: "ingester-0", Addr: "10.0.0.3", Port: 9095, Ready: false}, e)
+
+	// the pod becomes ready
+	events <- fmt.Sprintf(`{"type":"MODIFIED","object":%s}`, endpointSliceJSON("ingester-a", "ingester-0", "10.0.0.3", true))
+	require.Eventually(t, func() bool {
+		e, _ := w.EndpointForAddr("10.0.0.1")
+		return e.Ready
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// deleted
+	events <- fmt.Sprintf(`{"type":"DELETED","object":%s}`, endpointSliceJSON("ingester-b", "ingester-1", "10.0.0.2", true))
+	require.Eventually(t, func() bool { return len(w.Endpoints()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	_, ok = w.Endpoint("ingester-1")
+	require.False(t, ok)
+}
+
+func TestNewWatcherValidation(t *testing.T) {
+	_, err := NewWatcher(Config{}, log.NewNopLogger())
+	require.EqualError(t, err, "service is required")
+
+	_, err = NewWatcher(Config{Service: "ingester", serviceAccountDir: t.TempDir()}, log.NewNopLogger())
+	require.ErrorContains(t, err, "namespace not configured")
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, err = NewWatcher(Config{Service: "ingester", Namespace: "tempo", serviceAccountDir: t.TempDir()}, log.NewNopLogger())
+	require.EqualError(t, err, "api server not configured and not running in a Kubernetes cluster")
+}
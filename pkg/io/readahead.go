@@ -0,0 +1,225 @@
+package io
+
+import (
+	"io"
+	"sync"
+)
+
+// ReadaheadReaderAt implements io.ReaderAt for readers that consume several regions of the underlying reader
+// sequentially, like the column chunks of a parquet file. It tracks up to streamCount sequential streams of reads.
+// Each stream holds the window of windowSize bytes it is currently reading from and fetches the following window
+// in the background, so at most 2 * streamCount windows are held in memory regardless of the size of the regions.
+// Reads that don't continue a stream are passed to the underlying reader. A read starting where one of the last
+// streamCount passed reads ended starts a new stream, replacing the least-recently-used one, so random reads don't
+// fetch windows they don't use.
+type ReadaheadReaderAt struct {
+	mtx     sync.Mutex
+	ra      io.ReaderAt
+	rasz    int64
+	wsz     int
+	count   int64
+	streams []readaheadStream
+	// ends of the last reads passed to the underlying reader, -1 if unused
+	ends    []int64
+	nextEnd int
+}
+
+type readaheadStream struct {
+	cur   *readaheadWindow
+	next  *readaheadWindow
+	count int64
+}
+
+// readaheadWindow is a region of the underlying reader. buf is only read after done is closed and is not
+// modified afterwards.
+type readaheadWindow struct {
+	off  int64
+	buf  []byte
+	done chan struct{}
+	err  error
+}
+
+var _ io.ReaderAt = (*ReadaheadReaderAt)(nil)
+
+func NewReadaheadReaderAt(ra io.ReaderAt, readerSize int64, windowSize, streamCount int) *ReadaheadReaderAt {
+	ends := make([]int64, streamCount)
+	for i := range ends {
+		ends[i] = -1
+	}
+
+	return &ReadaheadReaderAt{
+		ra:      ra,
+		rasz:    readerSize,
+		wsz:     windowSize,
+		streams: make([]readaheadStream, streamCount),
+		ends:    ends,
+	}
+}
+
+func (w *readaheadWindow) end() int64 {
+	return w.off + int64(len(w.buf))
+}
+
+func (w *readaheadWindow) wait() error {
+	<-w.done
+	return w.err
+}
+
+// fetch returns a window starting at offset that is large enough for the read of the given length, which must end
+// within the reader. The window is populated in the background.
+func (r *ReadaheadReaderAt) fetch(offset, length int64) *readaheadWindow {
+	sz := length
+	if sz < int64(r.wsz) {
+		sz = int64(r.wsz)
+	}
+	if offset+sz > r.rasz {
+		sz = r.rasz - offset
+	}
+
+	w := &readaheadWindow{
+		off:  offset,
+		buf:  make([]byte, sz),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		n, err := r.ra.ReadAt(w.buf, w.off)
+		// readers may return io.EOF with a read ending at the end of the reader
+		if err == io.EOF && n == len(w.buf) {
+			err = nil
+		}
+		w.err = err
+	}()
+	return w
+}
+
+// readahead starts fetching the window following the current window of the stream
+func (r *ReadaheadReaderAt) readahead(s *readaheadStream) {
+	s.next = nil
+	if off := s.cur.end(); off < r.rasz {
+		s.next = r.fetch(off, 0)
+	}
+}
+
+// ReadAt reads len(b) bytes at offset. A read past the end of the reader returns the bytes up to the end and
+// io.EOF.
+func (r *ReadaheadReaderAt) ReadAt(b []byte, offset int64) (int, error) {
+	if len(r.streams) == 0 {
+		return r.ra.ReadAt(b, offset)
+	}
+	if offset >= r.rasz {
+		return 0, io.EOF
+	}
+
+	var eof error
+	if offset+int64(len(b)) > r.rasz {
+		b = b[:r.rasz-offset]
+		eof = io.EOF
+	}
+
+	r.mtx.Lock()
+
+	// Least-recently-used tracking
+	r.count++
+	end := offset + int64(len(b))
+
+	var lru *readaheadStream
+	var windows []*readaheadWindow
+	for i := range r.streams {
+		s := &r.streams[i]
+		if s.cur == nil {
+			if lru == nil || lru.cur != nil {
+				lru = s
+			}
+			continue
+		}
+
+		if offset >= s.cur.off && end <= s.cur.end() {
+			// Read from the current window
+			windows = []*readaheadWindow{s.cur}
+		} else if s.next != nil && offset >= s.cur.off && offset < s.next.end() && end <= s.next.end() {
+			// Read continues into the next window, which becomes the current window of the stream
+			if offset < s.cur.end() {
+				windows = []*readaheadWindow{s.cur, s.next}
+			} else {
+				windows = []*readaheadWindow{s.next}
+			}
+			s.cur = s.next
+			r.readahead(s)
+		}
+
+		if windows != nil {
+			s.count = r.count
+			break
+		}
+
+		if lru == nil || (lru.cur != nil && s.count < lru.count) {
+			lru = s
+		}
+	}
+
+	if windows == nil && !r.continuesRead(offset, end) {
+		// No stream satisfied the read and the read doesn't look sequential
+		r.mtx.Unlock()
+
+		n, err := r.ra.ReadAt(b, offset)
+		if err == io.EOF && n == len(b) {
+			err = nil
+		}
+		if err == nil {
+			err = eof
+		}
+		return n, err
+	}
+
+	if windows == nil {
+		// No stream satisfied the sequential read, replace the least-recently-used stream
+		lru.cur = r.fetch(offset, int64(len(b)))
+		lru.count = r.count
+		r.readahead(lru)
+		windows = []*readaheadWindow{lru.cur}
+	}
+
+	r.mtx.Unlock()
+
+	n := 0
+	for _, w := range windows {
+		if err := w.wait(); err != nil {
+			r.drop(w)
+			return 0, err
+		}
+		n += copy(b[n:], w.buf[offset+int64(n)-w.off:])
+	}
+
+	return n, eof
+}
+
+// continuesRead returns true if the read at offset starts where one of the last reads passed to the underlying
+// reader ended. Otherwise the end of the read is recorded, replacing the oldest one. It must be called with the lock
+// held.
+func (r *ReadaheadReaderAt) continuesRead(offset, end int64) bool {
+	for i, e := range r.ends {
+		if e == offset {
+			r.ends[i] = -1
+			return true
+		}
+	}
+
+	r.ends[r.nextEnd] = end
+	r.nextEnd = (r.nextEnd + 1) % len(r.ends)
+	return false
+}
+
+// drop removes the stream reading the failed window, so the next read retries it.
+func (r *ReadaheadReaderAt) drop(w *readaheadWindow) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for i := range r.streams {
+		s := &r.streams[i]
+		if s.cur == w || s.next == w {
+			s.cur = nil
+			s.next = nil
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package io
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingReaderAt struct {
+	mtx   sync.Mutex
+	r     *bytes.Reader
+	reads []int64
+	err   error
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.mtx.Lock()
+	c.reads = append(c.reads, off)
+	err := c.err
+	c.mtx.Unlock()
+
+	if err != nil {
+		return 0, err
+	}
+	return c.r.ReadAt(p, off)
+}
+
+func (c *countingReaderAt) readCount() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.reads)
+}
+
+func TestReadaheadReaderAtSequentialStreams(t *testing.T) {
+	input := make([]byte, 10_000)
+	_, err := rand.Read(input)
+	require.NoError(t, err)
+
+	ra := &countingReaderAt{r: bytes.NewReader(input)}
+	r := NewReadaheadReaderAt(ra, int64(len(input)), 1000, 2)
+
+	// two interleaved sequential streams with reads crossing the windows
+	offsets := []int64{0, 5000}
+	for i := 0; i < 20; i++ {
+		for s := range offsets {
+			b := make([]byte, 150)
+			n, err := r.ReadAt(b, offsets[s])
+			require.NoError(t, err)
+			require.Equal(t, len(b), n)
+			require.Equal(t, input[offsets[s]:offsets[s]+150], b)
+			offsets[s] += 150
+		}
+	}
+
+	// the first read of each stream is passed on, the stream starts with the second read. each stream read 2850
+	// bytes from its windows, which is 3 windows plus at most one window of readahead
+	assert.LessOrEqual(t, ra.readCount(), 10)
+}
+
+func TestReadaheadReaderAtBypassesRandomReads(t *testing.T) {
+	input := make([]byte, 10_000)
+	_, err := rand.Read(input)
+	require.NoError(t, err)
+
+	ra := &countingReaderAt{r: bytes.NewReader(input)}
+	r := NewReadaheadReaderAt(ra, int64(len(input)), 1000, 2)
+
+	// reads that don't continue each other don't fetch windows
+	for _, off := range []int64{9000, 100, 5000, 2000} {
+		b := make([]byte, 10)
+		n, err := r.ReadAt(b, off)
+		require.NoError(t, err)
+		require.Equal(t, 10, n)
+		require.Equal(t, input[off:off+10], b)
+	}
+	assert.Equal(t, []int64{9000, 100, 5000, 2000}, ra.reads)
+
+	// a read continuing one of the last reads starts a stream
+	b := make([]byte, 10)
+	_, err = r.ReadAt(b, 2010)
+	require.NoError(t, err)
+	require.Equal(t, input[2010:2020], b)
+	_, err = r.ReadAt(b, 2020)
+	require.NoError(t, err)
+	require.Equal(t, input[2020:2030], b)
+	require.Eventually(t, func() bool { return ra.readCount() == 6 }, time.Second, 10*time.Millisecond)
+	// the window of the read and the readahead window
+	ra.mtx.Lock()
+	assert.ElementsMatch(t, []int64{2010, 3010}, ra.reads[4:])
+	ra.mtx.Unlock()
+}
+
+func TestReadaheadReaderAtEOF(t *testing.T) {
+	input := make([]byte, 1000)
+	_, err := rand.Read(input)
+	require.NoError(t, err)
+
+	r := NewReadaheadReaderAt(&countingReaderAt{r: bytes.NewReader(input)}, int64(len(input)), 100, 1)
+
+	for _, sequential := range []bool{false, true} {
+		if sequential {
+			// start a stream ending at the end of the reader
+			_, err = r.ReadAt(make([]byte, 10), 880)
+			require.NoError(t, err)
+		}
+
+		b := make([]byte, 50)
+		n, err := r.ReadAt(b, 890)
+		require.Equal(t, 50, n)
+		require.NoError(t, err)
+
+		n, err = r.ReadAt(b, 980)
+		require.ErrorIs(t, err, io.EOF)
+		require.Equal(t, 20, n)
+		require.Equal(t, input[980:], b[:n])
+	}
+
+	n, err := r.ReadAt(make([]byte, 10), 1000)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 0, n)
+}
+
+func TestReadaheadReaderAtRandomReads(t *testing.T) {
+	input := make([]byte, 10_000)
+	_, err := rand.Read(input)
+	require.NoError(t, err)
+
+	r := NewReadaheadReaderAt(&countingReaderAt{r: bytes.NewReader(input)}, int64(len(input)), 500, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				off := rand.Int63n(int64(len(input)) - 1)
+				l := rand.Int63n(int64(len(input))-off) % 2000
+				b := make([]byte, l)
+				n, err := r.ReadAt(b, off)
+				require.NoError(t, err)
+				require.Equal(t, len(b), n)
+				require.Equal(t, input[off:off+l], b)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReadaheadReaderAtError(t *testing.T) {
+	input := make([]byte, 1000)
+	ra := &countingReaderAt{r: bytes.NewReader(input), err: errors.New("failed")}
+	r := NewReadaheadReaderAt(ra, int64(len(input)), 100, 1)
+
+	_, err := r.ReadAt(make([]byte, 10), 0)
+	require.Error(t, err)
+
+	// the failed window is not kept
+	ra.mtx.Lock()
+	ra.err = nil
+	ra.mtx.Unlock()
+	n, err := r.ReadAt(make([]byte, 10), 0)
+	require.NoError(t, err)
+	require.Equal(t, 10, n)
+}
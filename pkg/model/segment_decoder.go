@@ -15,7 +15,8 @@ import (
 //   - The trace id is queried. In this case it uses PrepareForRead to turn the segments into a tempopb.Trace for
 //     return on the query path.
 //   - It needs to push them into tempodb. For this it uses ToObject() to create a single byte slice from the
-//     segments that is then completely handled by an ObjectDecoder of the same version
+//     segments that is then completely handled by an ObjectDecoder of the same version. Blocks that store traces
+//     instead of objects are passed the result of ToTrace().
 type SegmentDecoder interface {
 	// PrepareForWrite takes a trace pointer and returns a record prepared for writing to an ingester
 	PrepareForWrite(trace *tempopb.Trace, start uint32, end uint32) ([]byte, error)
@@ -26,6 +27,9 @@ type SegmentDecoder interface {
 	//  The resultant byte slice can then be manipulated using the corresponding ObjectDecoder.
 	//  ToObject is on the write path and should do as little as possible.
 	ToObject(segments [][]byte) ([]byte, error)
+	// ToTrace converts a set of segments into a tempopb.Trace with the batches of all segments. Unlike PrepareForRead
+	//  the segments are not combined, ToTrace is on the write path of blocks that store traces instead of objects.
+	ToTrace(segments [][]byte) (*tempopb.Trace, error)
 	// FastRange returns the start and end unix epoch timestamp of the provided segment. If its not possible to efficiently get these
 	// values from the underlying encoding then it should return decoder.ErrUnsupported
 	FastRange(segment []byte) (uint32, uint32, error)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/grafana/tempo/pkg/model/decoder"
+	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/pkg/util/test"
 	"github.com/stretchr/testify/require"
 )
@@ -41,6 +42,31 @@ func TestSegmentDecoderToObjectDecoder(t *testing.T) {
 	}
 }
 
+func TestSegmentDecoderToTrace(t *testing.T) {
+	for _, e := range AllEncodings {
+		t.Run(e, func(t *testing.T) {
+			segmentDecoder, err := NewSegmentDecoder(e)
+			require.NoError(t, err)
+
+			// random traces
+			trace1 := test.MakeTrace(10, nil)
+			trace2 := test.MakeTrace(10, nil)
+
+			segment1, err := segmentDecoder.PrepareForWrite(trace1, 10, 20)
+			require.NoError(t, err)
+			segment2, err := segmentDecoder.PrepareForWrite(trace2, 30, 40)
+			require.NoError(t, err)
+
+			// the batches of all segments are appended
+			actual, err := segmentDecoder.ToTrace([][]byte{segment1, segment2})
+			require.NoError(t, err)
+
+			expected := &tempopb.Trace{Batches: append(trace1.Batches, trace2.Batches...)}
+			require.True(t, proto.Equal(expected, actual))
+		})
+	}
+}
+
 func TestSegmentDecoderToObjectDecoderRange(t *testing.T) {
 	for _, e := range AllEncodings {
 		t.Run(e, func(t *testing.T) {
@@ -0,0 +1,66 @@
+package trace
+
+import (
+	"sort"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+// TruncateOldestSpans drops the spans with the oldest start times until the size of the trace is at most
+// maxBytes. The newest span is always kept. It returns the number of dropped spans.
+func TruncateOldestSpans(tr *tempopb.Trace, maxBytes int) int {
+	size := tr.Size()
+	if size <= maxBytes {
+		return 0
+	}
+
+	var spans []*v1.Span
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			spans = append(spans, ils.Spans...)
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].StartTimeUnixNano < spans[j].StartTimeUnixNano
+	})
+
+	dropped := map[*v1.Span]struct{}{}
+	for i := 0; i < len(spans)-1 && size > maxBytes; i++ {
+		size -= encodedSize(spans[i].Size())
+		dropped[spans[i]] = struct{}{}
+	}
+
+	batches := tr.Batches[:0]
+	for _, b := range tr.Batches {
+		ilss := b.InstrumentationLibrarySpans[:0]
+		for _, ils := range b.InstrumentationLibrarySpans {
+			kept := ils.Spans[:0]
+			for _, s := range ils.Spans {
+				if _, ok := dropped[s]; !ok {
+					kept = append(kept, s)
+				}
+			}
+			ils.Spans = kept
+			if len(ils.Spans) > 0 {
+				ilss = append(ilss, ils)
+			}
+		}
+		b.InstrumentationLibrarySpans = ilss
+		if len(b.InstrumentationLibrarySpans) > 0 {
+			batches = append(batches, b)
+		}
+	}
+	tr.Batches = batches
+
+	return len(dropped)
+}
+
+// encodedSize returns the size of a repeated message field of the given size, including its tag and length
+func encodedSize(size int) int {
+	lengthBytes := 1
+	for l := uint64(size); l >= 0x80; l >>= 7 {
+		lengthBytes++
+	}
+	return 1 + lengthBytes + size
+}
@@ -0,0 +1,59 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util/test"
+)
+
+func TestTruncateOldestSpans(t *testing.T) {
+	tr := test.MakeTraceWithSpanCount(2, 5, nil)
+	start := uint64(0)
+	var newest *v1.Span
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				start++
+				s.StartTimeUnixNano = start
+				newest = s
+			}
+		}
+	}
+
+	// not truncated if the trace fits
+	assert.Equal(t, 0, TruncateOldestSpans(tr, tr.Size()))
+	assert.Equal(t, 10, countTestSpans(tr))
+
+	// the newest spans are kept
+	maxBytes := tr.Size() * 2 / 3
+	dropped := TruncateOldestSpans(tr, maxBytes)
+
+	assert.LessOrEqual(t, tr.Size(), maxBytes)
+	assert.Equal(t, 10, dropped+countTestSpans(tr))
+	ilss := tr.Batches[len(tr.Batches)-1].InstrumentationLibrarySpans
+	kept := ilss[len(ilss)-1].Spans
+	assert.Equal(t, newest, kept[len(kept)-1])
+
+	// the newest span is always kept
+	dropped = TruncateOldestSpans(tr, 1)
+	assert.Equal(t, 1, countTestSpans(tr))
+	require.Len(t, tr.Batches, 1)
+	require.Len(t, tr.Batches[0].InstrumentationLibrarySpans, 1)
+	assert.Equal(t, []*v1.Span{newest}, tr.Batches[0].InstrumentationLibrarySpans[0].Spans)
+	assert.Greater(t, dropped, 0)
+}
+
+func countTestSpans(tr *tempopb.Trace) int {
+	count := 0
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			count += len(ils.Spans)
+		}
+	}
+	return count
+}
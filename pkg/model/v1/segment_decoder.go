@@ -50,6 +50,18 @@ func (d *SegmentDecoder) ToObject(segments [][]byte) ([]byte, error) {
 	return proto.Marshal(wrapper)
 }
 
+func (d *SegmentDecoder) ToTrace(segments [][]byte) (*tempopb.Trace, error) {
+	// unmarshalling into the same trace appends the batches of each segment
+	t := &tempopb.Trace{}
+	for _, s := range segments {
+		err := t.Unmarshal(s)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling trace: %w", err)
+		}
+	}
+	return t, nil
+}
+
 func (d *SegmentDecoder) FastRange([]byte) (uint32, uint32, error) {
 	return 0, 0, decoder.ErrUnsupported
 }
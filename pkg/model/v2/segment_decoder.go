@@ -78,6 +78,24 @@ func (d *SegmentDecoder) ToObject(segments [][]byte) ([]byte, error) {
 	}, minStart, maxEnd)
 }
 
+// ToTrace unmarshals all segments into a single trace without combining them
+func (d *SegmentDecoder) ToTrace(segments [][]byte) (*tempopb.Trace, error) {
+	// unmarshalling into the same trace appends the batches of each segment
+	t := &tempopb.Trace{}
+	for _, s := range segments {
+		obj, _, _, err := stripStartEnd(s)
+		if err != nil {
+			return nil, fmt.Errorf("error stripping start/end: %w", err)
+		}
+
+		err = t.Unmarshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshaling trace: %w", err)
+		}
+	}
+	return t, nil
+}
+
 func (d *SegmentDecoder) FastRange(buff []byte) (uint32, uint32, error) {
 	_, start, end, err := stripStartEnd(buff)
 	return start, end, err
@@ -28,12 +28,12 @@ import (
 //	    D      0,  1,  0
 //	  E        0,  2, -1
 //
-// Currently supports 6 levels of nesting which should be enough for anybody. :)
-type RowNumber [6]int64
+// Currently supports 8 levels of nesting which should be enough for anybody. :)
+type RowNumber [8]int64
 
 // EmptyRowNumber creates an empty invalid row number.
 func EmptyRowNumber() RowNumber {
-	return RowNumber{-1, -1, -1, -1, -1, -1}
+	return RowNumber{-1, -1, -1, -1, -1, -1, -1, -1}
 }
 
 // MaxRowNumber is a helper that represents the maximum(-ish) representable value.
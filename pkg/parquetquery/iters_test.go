@@ -8,7 +8,7 @@ import (
 
 func TestRowNumber(t *testing.T) {
 	tr := EmptyRowNumber()
-	require.Equal(t, RowNumber{-1, -1, -1, -1, -1, -1}, tr)
+	require.Equal(t, RowNumber{-1, -1, -1, -1, -1, -1, -1, -1}, tr)
 
 	steps := []struct {
 		repetitionLevel int
@@ -16,11 +16,11 @@ func TestRowNumber(t *testing.T) {
 		expected        RowNumber
 	}{
 		// Name.Language.Country examples from the Dremel whitepaper
-		{0, 3, RowNumber{0, 0, 0, 0, -1, -1}},
-		{2, 2, RowNumber{0, 0, 1, -1, -1, -1}},
-		{1, 1, RowNumber{0, 1, -1, -1, -1, -1}},
-		{1, 3, RowNumber{0, 2, 0, 0, -1, -1}},
-		{0, 1, RowNumber{1, 0, -1, -1, -1, -1}},
+		{0, 3, RowNumber{0, 0, 0, 0, -1, -1, -1, -1}},
+		{2, 2, RowNumber{0, 0, 1, -1, -1, -1, -1, -1}},
+		{1, 1, RowNumber{0, 1, -1, -1, -1, -1, -1, -1}},
+		{1, 3, RowNumber{0, 2, 0, 0, -1, -1, -1, -1}},
+		{0, 1, RowNumber{1, 0, -1, -1, -1, -1, -1, -1}},
 	}
 
 	for _, step := range steps {
@@ -75,11 +75,12 @@ func NewRequestQueue(maxOutstandingPerTenant int, forgetDelay time.Duration, que
 }
 
 // EnqueueRequest puts the request into the queue. MaxQueries is user-specific value that specifies how many queriers can
-// this user use (zero or negative = all queriers). It is passed to each EnqueueRequest, because it can change
-// between calls.
+// this user use (zero or negative = all queriers). MaxInflight is user-specific value that specifies how many requests
+// of this user are dispatched to queriers at once (zero or negative = unlimited), further requests wait in the queue
+// until FinishRequest is called for one. Both are passed to each EnqueueRequest, because they can change between calls.
 //
 // If request is successfully enqueued, successFn is called with the lock held, before any querier can receive the request.
-func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers int, successFn func()) error {
+func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers, maxInflight int, successFn func()) error {
 	q.mtx.Lock()
 	defer q.mtx.Unlock()
 
@@ -87,7 +88,7 @@ func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers in
 		return ErrStopped
 	}
 
-	queue := q.queues.getOrAddQueue(userID, maxQueriers)
+	queue := q.queues.getOrAddQueue(userID, maxQueriers, maxInflight)
 	if queue == nil {
 		// This can only happen if userID is "".
 		return errors.New("no queue found")
@@ -111,7 +112,8 @@ func (q *RequestQueue) EnqueueRequest(userID string, req Request, maxQueriers in
 // GetNextRequestForQuerier find next user queue and takes the next request off of it. Will block if there are no requests.
 // By passing user index from previous call of this method, querier guarantees that it iterates over all users fairly.
 // If querier finds that request from the user is already expired, it can get a request for the same user by using UserIndex.ReuseLastUser.
-func (q *RequestQueue) GetNextRequestForQuerier(ctx context.Context, last UserIndex, querierID string) (Request, UserIndex, error) {
+// The returned request is in flight until FinishRequest is called with its user.
+func (q *RequestQueue) GetNextRequestForQuerier(ctx context.Context, last UserIndex, querierID string) (Request, UserIndex, string, error) {
 	q.mtx.Lock()
 	defer q.mtx.Unlock()
 
@@ -125,11 +127,11 @@ FindQueue:
 	}
 
 	if q.stopped {
-		return nil, last, ErrStopped
+		return nil, last, "", ErrStopped
 	}
 
 	if err := ctx.Err(); err != nil {
-		return nil, last, err
+		return nil, last, "", err
 	}
 
 	for {
@@ -147,11 +149,12 @@ FindQueue:
 			}
 
 			q.queueLength.WithLabelValues(userID).Dec()
+			q.queues.startRequest(userID)
 
 			// Tell close() we've processed a request.
 			q.cond.Broadcast()
 
-			return request, last, nil
+			return request, last, userID, nil
 		}
 	}
 
@@ -161,6 +164,16 @@ FindQueue:
 	goto FindQueue
 }
 
+// FinishRequest marks a request of the user returned by GetNextRequestForQuerier as no longer in flight, so queriers
+// can receive another one.
+func (q *RequestQueue) FinishRequest(userID string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.queues.finishRequest(userID)
+	q.cond.Broadcast()
+}
+
 func (q *RequestQueue) forgetDisconnectedQueriers(_ context.Context) error {
 	q.mtx.Lock()
 	defer q.mtx.Unlock()
@@ -0,0 +1,52 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestQueueMaxInflight(t *testing.T) {
+	q := NewRequestQueue(10, 0,
+		prometheus.NewGaugeVec(prometheus.GaugeOpts{}, []string{"user"}),
+		prometheus.NewCounterVec(prometheus.CounterOpts{}, []string{"user"}))
+	q.RegisterQuerierConnection("querier")
+
+	for _, req := range []string{"a1", "a2", "a3"} {
+		require.NoError(t, q.EnqueueRequest("a", req, 0, 2, nil))
+	}
+	require.NoError(t, q.EnqueueRequest("b", "b1", 0, 0, nil))
+
+	next := func(ctx context.Context) (Request, string, error) {
+		req, _, userID, err := q.GetNextRequestForQuerier(ctx, FirstUser(), "querier")
+		return req, userID, err
+	}
+
+	// a has 2 requests in flight, b has its own limit
+	seen := map[Request]string{}
+	for i := 0; i < 3; i++ {
+		req, userID, err := next(context.Background())
+		require.NoError(t, err)
+		seen[req] = userID
+	}
+	require.Equal(t, map[Request]string{"a1": "a", "a2": "a", "b1": "b"}, seen)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		q.QuerierDisconnecting()
+	}()
+	_, _, err := next(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// finishing a request of a dispatches the next one
+	q.FinishRequest("a")
+	req, userID, err := next(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "a3", req)
+	require.Equal(t, "a", userID)
+}
@@ -41,6 +41,9 @@ type queues struct {
 
 	// Sorted list of querier names, used when creating per-user shard.
 	sortedQueriers []string
+
+	// Number of requests per user dispatched to queriers and not finished yet. Kept when the user queue is deleted.
+	inflight map[string]int
 }
 
 type userQueue struct {
@@ -51,6 +54,9 @@ type userQueue struct {
 	queriers    map[string]struct{}
 	maxQueriers int
 
+	// If > 0, queriers don't receive more requests of the user while this many are in flight.
+	maxInflight int
+
 	// Seed for shuffle sharding of queriers. This seed is based on userID only and is therefore consistent
 	// between different frontends.
 	seed int64
@@ -67,6 +73,7 @@ func newUserQueues(maxUserQueueSize int, forgetDelay time.Duration) *queues {
 		forgetDelay:      forgetDelay,
 		queriers:         map[string]*querier{},
 		sortedQueriers:   nil,
+		inflight:         map[string]int{},
 	}
 }
 
@@ -93,7 +100,8 @@ func (q *queues) deleteQueue(userID string) {
 // MaxQueriers is used to compute which queriers should handle requests for this user.
 // If maxQueriers is <= 0, all queriers can handle this user's requests.
 // If maxQueriers has changed since the last call, queriers for this are recomputed.
-func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
+// MaxInflight limits the requests of the user dispatched at once, if it's <= 0 they are not limited.
+func (q *queues) getOrAddQueue(userID string, maxQueriers, maxInflight int) chan Request {
 	// Empty user is not allowed, as that would break our users list ("" is used for free spot).
 	if userID == "" {
 		return nil
@@ -129,6 +137,8 @@ func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
 		}
 	}
 
+	uq.maxInflight = maxInflight
+
 	if uq.maxQueriers != maxQueriers {
 		uq.maxQueriers = maxQueriers
 		uq.queriers = shuffleQueriersForUser(uq.seed, maxQueriers, q.sortedQueriers, nil)
@@ -142,6 +152,7 @@ func (q *queues) getOrAddQueue(userID string, maxQueriers int) chan Request {
 // last user index, use -1.
 func (q *queues) getNextQueueForQuerier(lastUserIndex int, querierID string) (chan Request, string, int) {
 	uid := lastUserIndex
+	inflight := q.inflight
 
 	for iters := 0; iters < len(q.users); iters++ {
 		uid = uid + 1
@@ -166,11 +177,29 @@ func (q *queues) getNextQueueForQuerier(lastUserIndex int, querierID string) (ch
 			}
 		}
 
+		if q.maxInflight > 0 && inflight[u] >= q.maxInflight {
+			// The user has too many requests in flight.
+			continue
+		}
+
 		return q.ch, u, uid
 	}
 	return nil, "", uid
 }
 
+// startRequest counts a request of the user as in flight.
+func (q *queues) startRequest(userID string) {
+	q.inflight[userID]++
+}
+
+// finishRequest counts a request of the user as no longer in flight.
+func (q *queues) finishRequest(userID string) {
+	q.inflight[userID]--
+	if q.inflight[userID] <= 0 {
+		delete(q.inflight, userID)
+	}
+}
+
 func (q *queues) addQuerierConnection(querierID string) {
 	info := q.queriers[querierID]
 	if info != nil {
@@ -5,6 +5,7 @@ package tempopb
 
 import (
 	context "context"
+	encoding_binary "encoding/binary"
 	fmt "fmt"
 	_ "github.com/gogo/protobuf/gogoproto"
 	proto "github.com/gogo/protobuf/proto"
@@ -193,6 +194,172 @@ func (m *TraceByIDMetrics) GetFailedBlocks() uint32 {
 	return 0
 }
 
+// RecentTracesRequest asks an ingester for the traces it holds in memory and in its wal that overlap the time range
+type RecentTracesRequest struct {
+	Start    uint32 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End      uint32 `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	MaxBytes uint32 `protobuf:"varint,3,opt,name=maxBytes,proto3" json:"maxBytes,omitempty"`
+}
+
+func (m *RecentTracesRequest) Reset()         { *m = RecentTracesRequest{} }
+func (m *RecentTracesRequest) String() string { return proto.CompactTextString(m) }
+func (*RecentTracesRequest) ProtoMessage()    {}
+func (*RecentTracesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{3}
+}
+func (m *RecentTracesRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RecentTracesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RecentTracesRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RecentTracesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecentTracesRequest.Merge(m, src)
+}
+func (m *RecentTracesRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *RecentTracesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecentTracesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecentTracesRequest proto.InternalMessageInfo
+
+func (m *RecentTracesRequest) GetStart() uint32 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *RecentTracesRequest) GetEnd() uint32 {
+	if m != nil {
+		return m.End
+	}
+	return 0
+}
+
+func (m *RecentTracesRequest) GetMaxBytes() uint32 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
+type RecentTracesResponse struct {
+	Traces []*RecentTrace `protobuf:"bytes,1,rep,name=traces,proto3" json:"traces,omitempty"`
+	// true if traces were left out because maxBytes was reached
+	Truncated bool `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"`
+}
+
+func (m *RecentTracesResponse) Reset()         { *m = RecentTracesResponse{} }
+func (m *RecentTracesResponse) String() string { return proto.CompactTextString(m) }
+func (*RecentTracesResponse) ProtoMessage()    {}
+func (*RecentTracesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{4}
+}
+func (m *RecentTracesResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RecentTracesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RecentTracesResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RecentTracesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecentTracesResponse.Merge(m, src)
+}
+func (m *RecentTracesResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *RecentTracesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecentTracesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecentTracesResponse proto.InternalMessageInfo
+
+func (m *RecentTracesResponse) GetTraces() []*RecentTrace {
+	if m != nil {
+		return m.Traces
+	}
+	return nil
+}
+
+func (m *RecentTracesResponse) GetTruncated() bool {
+	if m != nil {
+		return m.Truncated
+	}
+	return false
+}
+
+type RecentTrace struct {
+	TraceID []byte `protobuf:"bytes,1,opt,name=traceID,proto3" json:"traceID,omitempty"`
+	Trace   *Trace `protobuf:"bytes,2,opt,name=trace,proto3" json:"trace,omitempty"`
+}
+
+func (m *RecentTrace) Reset()         { *m = RecentTrace{} }
+func (m *RecentTrace) String() string { return proto.CompactTextString(m) }
+func (*RecentTrace) ProtoMessage()    {}
+func (*RecentTrace) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{5}
+}
+func (m *RecentTrace) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RecentTrace) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RecentTrace.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *RecentTrace) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecentTrace.Merge(m, src)
+}
+func (m *RecentTrace) XXX_Size() int {
+	return m.Size()
+}
+func (m *RecentTrace) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecentTrace.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecentTrace proto.InternalMessageInfo
+
+func (m *RecentTrace) GetTraceID() []byte {
+	if m != nil {
+		return m.TraceID
+	}
+	return nil
+}
+
+func (m *RecentTrace) GetTrace() *Trace {
+	if m != nil {
+		return m.Trace
+	}
+	return nil
+}
+
 // SearchRequest takes no block parameters and implies a "recent traces" search
 type SearchRequest struct {
 	// case insensitive partial match
@@ -210,7 +377,7 @@ func (m *SearchRequest) Reset()         { *m = SearchRequest{} }
 func (m *SearchRequest) String() string { return proto.CompactTextString(m) }
 func (*SearchRequest) ProtoMessage()    {}
 func (*SearchRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{3}
+	return fileDescriptor_f22805646f4f62b6, []int{6}
 }
 func (m *SearchRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -302,13 +469,14 @@ type SearchBlockRequest struct {
 	Version       string         `protobuf:"bytes,9,opt,name=version,proto3" json:"version,omitempty"`
 	Size_         uint64         `protobuf:"varint,10,opt,name=size,proto3" json:"size,omitempty"`
 	FooterSize    uint32         `protobuf:"varint,11,opt,name=footerSize,proto3" json:"footerSize,omitempty"`
+	IndexVersion  string         `protobuf:"bytes,12,opt,name=indexVersion,proto3" json:"indexVersion,omitempty"`
 }
 
 func (m *SearchBlockRequest) Reset()         { *m = SearchBlockRequest{} }
 func (m *SearchBlockRequest) String() string { return proto.CompactTextString(m) }
 func (*SearchBlockRequest) ProtoMessage()    {}
 func (*SearchBlockRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{4}
+	return fileDescriptor_f22805646f4f62b6, []int{7}
 }
 func (m *SearchBlockRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -414,6 +582,13 @@ func (m *SearchBlockRequest) GetFooterSize() uint32 {
 	return 0
 }
 
+func (m *SearchBlockRequest) GetIndexVersion() string {
+	if m != nil {
+		return m.IndexVersion
+	}
+	return ""
+}
+
 type SearchResponse struct {
 	Traces  []*TraceSearchMetadata `protobuf:"bytes,1,rep,name=traces,proto3" json:"traces,omitempty"`
 	Metrics *SearchMetrics         `protobuf:"bytes,2,opt,name=metrics,proto3" json:"metrics,omitempty"`
@@ -423,7 +598,7 @@ func (m *SearchResponse) Reset()         { *m = SearchResponse{} }
 func (m *SearchResponse) String() string { return proto.CompactTextString(m) }
 func (*SearchResponse) ProtoMessage()    {}
 func (*SearchResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{5}
+	return fileDescriptor_f22805646f4f62b6, []int{8}
 }
 func (m *SearchResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -478,7 +653,7 @@ func (m *TraceSearchMetadata) Reset()         { *m = TraceSearchMetadata{} }
 func (m *TraceSearchMetadata) String() string { return proto.CompactTextString(m) }
 func (*TraceSearchMetadata) ProtoMessage()    {}
 func (*TraceSearchMetadata) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{6}
+	return fileDescriptor_f22805646f4f62b6, []int{9}
 }
 func (m *TraceSearchMetadata) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -555,7 +730,7 @@ func (m *SearchMetrics) Reset()         { *m = SearchMetrics{} }
 func (m *SearchMetrics) String() string { return proto.CompactTextString(m) }
 func (*SearchMetrics) ProtoMessage()    {}
 func (*SearchMetrics) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{7}
+	return fileDescriptor_f22805646f4f62b6, []int{10}
 }
 func (m *SearchMetrics) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -633,7 +808,7 @@ func (m *SearchTagsRequest) Reset()         { *m = SearchTagsRequest{} }
 func (m *SearchTagsRequest) String() string { return proto.CompactTextString(m) }
 func (*SearchTagsRequest) ProtoMessage()    {}
 func (*SearchTagsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{8}
+	return fileDescriptor_f22805646f4f62b6, []int{11}
 }
 func (m *SearchTagsRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -670,7 +845,7 @@ func (m *SearchTagsResponse) Reset()         { *m = SearchTagsResponse{} }
 func (m *SearchTagsResponse) String() string { return proto.CompactTextString(m) }
 func (*SearchTagsResponse) ProtoMessage()    {}
 func (*SearchTagsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{9}
+	return fileDescriptor_f22805646f4f62b6, []int{12}
 }
 func (m *SearchTagsResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -714,7 +889,7 @@ func (m *SearchTagValuesRequest) Reset()         { *m = SearchTagValuesRequest{}
 func (m *SearchTagValuesRequest) String() string { return proto.CompactTextString(m) }
 func (*SearchTagValuesRequest) ProtoMessage()    {}
 func (*SearchTagValuesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{10}
+	return fileDescriptor_f22805646f4f62b6, []int{13}
 }
 func (m *SearchTagValuesRequest) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -758,7 +933,7 @@ func (m *SearchTagValuesResponse) Reset()         { *m = SearchTagValuesResponse
 func (m *SearchTagValuesResponse) String() string { return proto.CompactTextString(m) }
 func (*SearchTagValuesResponse) ProtoMessage()    {}
 func (*SearchTagValuesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{11}
+	return fileDescriptor_f22805646f4f62b6, []int{14}
 }
 func (m *SearchTagValuesResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -794,22 +969,26 @@ func (m *SearchTagValuesResponse) GetTagValues() []string {
 	return nil
 }
 
-type Trace struct {
-	Batches []*v1.ResourceSpans `protobuf:"bytes,1,rep,name=batches,proto3" json:"batches,omitempty"`
+// SearchTagValuesV2Response is the typed variant of SearchTagValuesResponse. Each value is annotated
+// with its data type and numeric values are summarized to drive range based widgets.
+type SearchTagValuesV2Response struct {
+	TagValues []*TagValue `protobuf:"bytes,1,rep,name=tagValues,proto3" json:"tagValues,omitempty"`
+	// numeric is only set when at least one value is of type int or double
+	Numeric *NumericTagValuesSummary `protobuf:"bytes,2,opt,name=numeric,proto3" json:"numeric,omitempty"`
 }
 
-func (m *Trace) Reset()         { *m = Trace{} }
-func (m *Trace) String() string { return proto.CompactTextString(m) }
-func (*Trace) ProtoMessage()    {}
-func (*Trace) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{12}
+func (m *SearchTagValuesV2Response) Reset()         { *m = SearchTagValuesV2Response{} }
+func (m *SearchTagValuesV2Response) String() string { return proto.CompactTextString(m) }
+func (*SearchTagValuesV2Response) ProtoMessage()    {}
+func (*SearchTagValuesV2Response) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{15}
 }
-func (m *Trace) XXX_Unmarshal(b []byte) error {
+func (m *SearchTagValuesV2Response) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
 }
-func (m *Trace) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+func (m *SearchTagValuesV2Response) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	if deterministic {
-		return xxx_messageInfo_Trace.Marshal(b, m, deterministic)
+		return xxx_messageInfo_SearchTagValuesV2Response.Marshal(b, m, deterministic)
 	} else {
 		b = b[:cap(b)]
 		n, err := m.MarshalToSizedBuffer(b)
@@ -819,41 +998,52 @@ func (m *Trace) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 		return b[:n], nil
 	}
 }
-func (m *Trace) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Trace.Merge(m, src)
+func (m *SearchTagValuesV2Response) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchTagValuesV2Response.Merge(m, src)
 }
-func (m *Trace) XXX_Size() int {
+func (m *SearchTagValuesV2Response) XXX_Size() int {
 	return m.Size()
 }
-func (m *Trace) XXX_DiscardUnknown() {
-	xxx_messageInfo_Trace.DiscardUnknown(m)
+func (m *SearchTagValuesV2Response) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchTagValuesV2Response.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Trace proto.InternalMessageInfo
+var xxx_messageInfo_SearchTagValuesV2Response proto.InternalMessageInfo
 
-func (m *Trace) GetBatches() []*v1.ResourceSpans {
+func (m *SearchTagValuesV2Response) GetTagValues() []*TagValue {
 	if m != nil {
-		return m.Batches
+		return m.TagValues
 	}
 	return nil
 }
 
-// Write
-type PushResponse struct {
+func (m *SearchTagValuesV2Response) GetNumeric() *NumericTagValuesSummary {
+	if m != nil {
+		return m.Numeric
+	}
+	return nil
 }
 
-func (m *PushResponse) Reset()         { *m = PushResponse{} }
-func (m *PushResponse) String() string { return proto.CompactTextString(m) }
-func (*PushResponse) ProtoMessage()    {}
-func (*PushResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{13}
+type TagValue struct {
+	// type is one of string, int, double or bool
+	Type  string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Value string `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// count is the number of occurrences of the value in the searched data
+	Count uint64 `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
 }
-func (m *PushResponse) XXX_Unmarshal(b []byte) error {
+
+func (m *TagValue) Reset()         { *m = TagValue{} }
+func (m *TagValue) String() string { return proto.CompactTextString(m) }
+func (*TagValue) ProtoMessage()    {}
+func (*TagValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{16}
+}
+func (m *TagValue) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
 }
-func (m *PushResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+func (m *TagValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	if deterministic {
-		return xxx_messageInfo_PushResponse.Marshal(b, m, deterministic)
+		return xxx_messageInfo_TagValue.Marshal(b, m, deterministic)
 	} else {
 		b = b[:cap(b)]
 		n, err := m.MarshalToSizedBuffer(b)
@@ -863,41 +1053,59 @@ func (m *PushResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error)
 		return b[:n], nil
 	}
 }
-func (m *PushResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_PushResponse.Merge(m, src)
+func (m *TagValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TagValue.Merge(m, src)
 }
-func (m *PushResponse) XXX_Size() int {
+func (m *TagValue) XXX_Size() int {
 	return m.Size()
 }
-func (m *PushResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_PushResponse.DiscardUnknown(m)
+func (m *TagValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_TagValue.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_PushResponse proto.InternalMessageInfo
+var xxx_messageInfo_TagValue proto.InternalMessageInfo
 
-// PushBytesRequest pushes slices of traces, ids and searchdata. Traces are encoded using the
-//  current BatchDecoder in ./pkg/model
-type PushBytesRequest struct {
-	// pre-marshalled Traces. length must match ids
-	Traces []PreallocBytes `protobuf:"bytes,2,rep,name=traces,proto3,customtype=PreallocBytes" json:"traces"`
-	// trace ids. length must match traces
-	Ids []PreallocBytes `protobuf:"bytes,3,rep,name=ids,proto3,customtype=PreallocBytes" json:"ids"`
-	// search data, length must match traces
-	SearchData []PreallocBytes `protobuf:"bytes,4,rep,name=searchData,proto3,customtype=PreallocBytes" json:"searchData"`
+func (m *TagValue) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
 }
 
-func (m *PushBytesRequest) Reset()         { *m = PushBytesRequest{} }
-func (m *PushBytesRequest) String() string { return proto.CompactTextString(m) }
-func (*PushBytesRequest) ProtoMessage()    {}
-func (*PushBytesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{14}
+func (m *TagValue) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
 }
-func (m *PushBytesRequest) XXX_Unmarshal(b []byte) error {
+
+func (m *TagValue) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+// NumericTagValuesSummary describes the occurrences of the int and double values of a tag.
+type NumericTagValuesSummary struct {
+	Min       float64                     `protobuf:"fixed64,1,opt,name=min,proto3" json:"min,omitempty"`
+	Max       float64                     `protobuf:"fixed64,2,opt,name=max,proto3" json:"max,omitempty"`
+	Count     uint64                      `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Histogram []*TagValuesHistogramBucket `protobuf:"bytes,4,rep,name=histogram,proto3" json:"histogram,omitempty"`
+}
+
+func (m *NumericTagValuesSummary) Reset()         { *m = NumericTagValuesSummary{} }
+func (m *NumericTagValuesSummary) String() string { return proto.CompactTextString(m) }
+func (*NumericTagValuesSummary) ProtoMessage()    {}
+func (*NumericTagValuesSummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{17}
+}
+func (m *NumericTagValuesSummary) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
 }
-func (m *PushBytesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+func (m *NumericTagValuesSummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	if deterministic {
-		return xxx_messageInfo_PushBytesRequest.Marshal(b, m, deterministic)
+		return xxx_messageInfo_NumericTagValuesSummary.Marshal(b, m, deterministic)
 	} else {
 		b = b[:cap(b)]
 		n, err := m.MarshalToSizedBuffer(b)
@@ -907,35 +1115,66 @@ func (m *PushBytesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, er
 		return b[:n], nil
 	}
 }
-func (m *PushBytesRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_PushBytesRequest.Merge(m, src)
+func (m *NumericTagValuesSummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NumericTagValuesSummary.Merge(m, src)
 }
-func (m *PushBytesRequest) XXX_Size() int {
+func (m *NumericTagValuesSummary) XXX_Size() int {
 	return m.Size()
 }
-func (m *PushBytesRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_PushBytesRequest.DiscardUnknown(m)
+func (m *NumericTagValuesSummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_NumericTagValuesSummary.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_PushBytesRequest proto.InternalMessageInfo
+var xxx_messageInfo_NumericTagValuesSummary proto.InternalMessageInfo
 
-type PushSpansRequest struct {
-	// just send entire OTel spans for now
-	Batches []*v1.ResourceSpans `protobuf:"bytes,1,rep,name=batches,proto3" json:"batches,omitempty"`
+func (m *NumericTagValuesSummary) GetMin() float64 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
 }
 
-func (m *PushSpansRequest) Reset()         { *m = PushSpansRequest{} }
-func (m *PushSpansRequest) String() string { return proto.CompactTextString(m) }
-func (*PushSpansRequest) ProtoMessage()    {}
-func (*PushSpansRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{15}
+func (m *NumericTagValuesSummary) GetMax() float64 {
+	if m != nil {
+		return m.Max
+	}
+	return 0
 }
-func (m *PushSpansRequest) XXX_Unmarshal(b []byte) error {
+
+func (m *NumericTagValuesSummary) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *NumericTagValuesSummary) GetHistogram() []*TagValuesHistogramBucket {
+	if m != nil {
+		return m.Histogram
+	}
+	return nil
+}
+
+// TagValuesHistogramBucket counts the occurrences of the values in [lowerBound, upperBound). The last
+// bucket is inclusive of upperBound.
+type TagValuesHistogramBucket struct {
+	LowerBound float64 `protobuf:"fixed64,1,opt,name=lowerBound,proto3" json:"lowerBound,omitempty"`
+	UpperBound float64 `protobuf:"fixed64,2,opt,name=upperBound,proto3" json:"upperBound,omitempty"`
+	Count      uint64  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (m *TagValuesHistogramBucket) Reset()         { *m = TagValuesHistogramBucket{} }
+func (m *TagValuesHistogramBucket) String() string { return proto.CompactTextString(m) }
+func (*TagValuesHistogramBucket) ProtoMessage()    {}
+func (*TagValuesHistogramBucket) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{18}
+}
+func (m *TagValuesHistogramBucket) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
 }
-func (m *PushSpansRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+func (m *TagValuesHistogramBucket) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	if deterministic {
-		return xxx_messageInfo_PushSpansRequest.Marshal(b, m, deterministic)
+		return xxx_messageInfo_TagValuesHistogramBucket.Marshal(b, m, deterministic)
 	} else {
 		b = b[:cap(b)]
 		n, err := m.MarshalToSizedBuffer(b)
@@ -945,35 +1184,219 @@ func (m *PushSpansRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, er
 		return b[:n], nil
 	}
 }
-func (m *PushSpansRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_PushSpansRequest.Merge(m, src)
+func (m *TagValuesHistogramBucket) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TagValuesHistogramBucket.Merge(m, src)
 }
-func (m *PushSpansRequest) XXX_Size() int {
+func (m *TagValuesHistogramBucket) XXX_Size() int {
 	return m.Size()
 }
-func (m *PushSpansRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_PushSpansRequest.DiscardUnknown(m)
+func (m *TagValuesHistogramBucket) XXX_DiscardUnknown() {
+	xxx_messageInfo_TagValuesHistogramBucket.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_PushSpansRequest proto.InternalMessageInfo
+var xxx_messageInfo_TagValuesHistogramBucket proto.InternalMessageInfo
 
-func (m *PushSpansRequest) GetBatches() []*v1.ResourceSpans {
+func (m *TagValuesHistogramBucket) GetLowerBound() float64 {
 	if m != nil {
-		return m.Batches
+		return m.LowerBound
 	}
-	return nil
+	return 0
 }
 
-type TraceBytes struct {
-	// pre-marshalled Traces
-	Traces [][]byte `protobuf:"bytes,1,rep,name=traces,proto3" json:"traces,omitempty"`
+func (m *TagValuesHistogramBucket) GetUpperBound() float64 {
+	if m != nil {
+		return m.UpperBound
+	}
+	return 0
 }
 
-func (m *TraceBytes) Reset()         { *m = TraceBytes{} }
+func (m *TagValuesHistogramBucket) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type Trace struct {
+	Batches []*v1.ResourceSpans `protobuf:"bytes,1,rep,name=batches,proto3" json:"batches,omitempty"`
+}
+
+func (m *Trace) Reset()         { *m = Trace{} }
+func (m *Trace) String() string { return proto.CompactTextString(m) }
+func (*Trace) ProtoMessage()    {}
+func (*Trace) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{19}
+}
+func (m *Trace) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Trace) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Trace.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Trace) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Trace.Merge(m, src)
+}
+func (m *Trace) XXX_Size() int {
+	return m.Size()
+}
+func (m *Trace) XXX_DiscardUnknown() {
+	xxx_messageInfo_Trace.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Trace proto.InternalMessageInfo
+
+func (m *Trace) GetBatches() []*v1.ResourceSpans {
+	if m != nil {
+		return m.Batches
+	}
+	return nil
+}
+
+// Write
+type PushResponse struct {
+}
+
+func (m *PushResponse) Reset()         { *m = PushResponse{} }
+func (m *PushResponse) String() string { return proto.CompactTextString(m) }
+func (*PushResponse) ProtoMessage()    {}
+func (*PushResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{20}
+}
+func (m *PushResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *PushResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_PushResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *PushResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PushResponse.Merge(m, src)
+}
+func (m *PushResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *PushResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PushResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PushResponse proto.InternalMessageInfo
+
+// PushBytesRequest pushes slices of traces, ids and searchdata. Traces are encoded using the
+//  current BatchDecoder in ./pkg/model
+type PushBytesRequest struct {
+	// pre-marshalled Traces. length must match ids
+	Traces []PreallocBytes `protobuf:"bytes,2,rep,name=traces,proto3,customtype=PreallocBytes" json:"traces"`
+	// trace ids. length must match traces
+	Ids []PreallocBytes `protobuf:"bytes,3,rep,name=ids,proto3,customtype=PreallocBytes" json:"ids"`
+	// search data, length must match traces
+	SearchData []PreallocBytes `protobuf:"bytes,4,rep,name=searchData,proto3,customtype=PreallocBytes" json:"searchData"`
+}
+
+func (m *PushBytesRequest) Reset()         { *m = PushBytesRequest{} }
+func (m *PushBytesRequest) String() string { return proto.CompactTextString(m) }
+func (*PushBytesRequest) ProtoMessage()    {}
+func (*PushBytesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{21}
+}
+func (m *PushBytesRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *PushBytesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_PushBytesRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *PushBytesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PushBytesRequest.Merge(m, src)
+}
+func (m *PushBytesRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *PushBytesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PushBytesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PushBytesRequest proto.InternalMessageInfo
+
+type PushSpansRequest struct {
+	// just send entire OTel spans for now
+	Batches []*v1.ResourceSpans `protobuf:"bytes,1,rep,name=batches,proto3" json:"batches,omitempty"`
+}
+
+func (m *PushSpansRequest) Reset()         { *m = PushSpansRequest{} }
+func (m *PushSpansRequest) String() string { return proto.CompactTextString(m) }
+func (*PushSpansRequest) ProtoMessage()    {}
+func (*PushSpansRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f22805646f4f62b6, []int{22}
+}
+func (m *PushSpansRequest) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *PushSpansRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_PushSpansRequest.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *PushSpansRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PushSpansRequest.Merge(m, src)
+}
+func (m *PushSpansRequest) XXX_Size() int {
+	return m.Size()
+}
+func (m *PushSpansRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PushSpansRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PushSpansRequest proto.InternalMessageInfo
+
+func (m *PushSpansRequest) GetBatches() []*v1.ResourceSpans {
+	if m != nil {
+		return m.Batches
+	}
+	return nil
+}
+
+type TraceBytes struct {
+	// pre-marshalled Traces
+	Traces [][]byte `protobuf:"bytes,1,rep,name=traces,proto3" json:"traces,omitempty"`
+}
+
+func (m *TraceBytes) Reset()         { *m = TraceBytes{} }
 func (m *TraceBytes) String() string { return proto.CompactTextString(m) }
 func (*TraceBytes) ProtoMessage()    {}
 func (*TraceBytes) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f22805646f4f62b6, []int{16}
+	return fileDescriptor_f22805646f4f62b6, []int{23}
 }
 func (m *TraceBytes) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -1013,6 +1436,9 @@ func init() {
 	proto.RegisterType((*TraceByIDRequest)(nil), "tempopb.TraceByIDRequest")
 	proto.RegisterType((*TraceByIDResponse)(nil), "tempopb.TraceByIDResponse")
 	proto.RegisterType((*TraceByIDMetrics)(nil), "tempopb.TraceByIDMetrics")
+	proto.RegisterType((*RecentTracesRequest)(nil), "tempopb.RecentTracesRequest")
+	proto.RegisterType((*RecentTracesResponse)(nil), "tempopb.RecentTracesResponse")
+	proto.RegisterType((*RecentTrace)(nil), "tempopb.RecentTrace")
 	proto.RegisterType((*SearchRequest)(nil), "tempopb.SearchRequest")
 	proto.RegisterMapType((map[string]string)(nil), "tempopb.SearchRequest.TagsEntry")
 	proto.RegisterType((*SearchBlockRequest)(nil), "tempopb.SearchBlockRequest")
@@ -1023,6 +1449,10 @@ func init() {
 	proto.RegisterType((*SearchTagsResponse)(nil), "tempopb.SearchTagsResponse")
 	proto.RegisterType((*SearchTagValuesRequest)(nil), "tempopb.SearchTagValuesRequest")
 	proto.RegisterType((*SearchTagValuesResponse)(nil), "tempopb.SearchTagValuesResponse")
+	proto.RegisterType((*SearchTagValuesV2Response)(nil), "tempopb.SearchTagValuesV2Response")
+	proto.RegisterType((*TagValue)(nil), "tempopb.TagValue")
+	proto.RegisterType((*NumericTagValuesSummary)(nil), "tempopb.NumericTagValuesSummary")
+	proto.RegisterType((*TagValuesHistogramBucket)(nil), "tempopb.TagValuesHistogramBucket")
 	proto.RegisterType((*Trace)(nil), "tempopb.Trace")
 	proto.RegisterType((*PushResponse)(nil), "tempopb.PushResponse")
 	proto.RegisterType((*PushBytesRequest)(nil), "tempopb.PushBytesRequest")
@@ -1033,78 +1463,96 @@ func init() {
 func init() { proto.RegisterFile("pkg/tempopb/tempo.proto", fileDescriptor_f22805646f4f62b6) }
 
 var fileDescriptor_f22805646f4f62b6 = []byte{
-	// 1131 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0x4d, 0x6f, 0xdb, 0x46,
-	0x13, 0x36, 0x2d, 0xc9, 0xb2, 0x46, 0x92, 0x3f, 0x36, 0x89, 0xcd, 0x97, 0x31, 0x64, 0x81, 0x30,
-	0xde, 0xea, 0xd0, 0xc8, 0x89, 0x92, 0x36, 0x6d, 0x2e, 0x45, 0x05, 0xbb, 0xa9, 0x81, 0x2a, 0x70,
-	0x29, 0xd7, 0xf7, 0x15, 0xb9, 0x96, 0x09, 0x4b, 0x5c, 0x86, 0x5c, 0x19, 0x76, 0x4f, 0xed, 0xa5,
-	0xa7, 0x1e, 0xfa, 0x17, 0x0a, 0xf4, 0xc7, 0xe4, 0x52, 0x20, 0xc7, 0xa2, 0x87, 0xa0, 0xb0, 0xff,
-	0x47, 0x51, 0xec, 0xa7, 0x48, 0xfa, 0xe3, 0xd0, 0x9e, 0xc4, 0x79, 0xf6, 0xd9, 0xd9, 0x99, 0x67,
-	0x67, 0x66, 0x05, 0x9b, 0xf1, 0xd9, 0x78, 0x97, 0x91, 0x69, 0x4c, 0xe3, 0x91, 0xfc, 0xed, 0xc6,
-	0x09, 0x65, 0x14, 0x55, 0x15, 0xe8, 0x3c, 0x64, 0x09, 0xf6, 0xc9, 0xee, 0xf9, 0xb3, 0x5d, 0xf1,
-	0x21, 0x97, 0x9d, 0x27, 0xe3, 0x90, 0x9d, 0xce, 0x46, 0x5d, 0x9f, 0x4e, 0x77, 0xc7, 0x74, 0x4c,
-	0x77, 0x05, 0x3c, 0x9a, 0x9d, 0x08, 0x4b, 0x18, 0xe2, 0x4b, 0xd2, 0xdd, 0x9f, 0x2c, 0x58, 0x3b,
-	0xe2, 0xdb, 0xfb, 0x97, 0x07, 0x7b, 0x1e, 0x79, 0x3b, 0x23, 0x29, 0x43, 0x36, 0x54, 0x85, 0xcb,
-	0x83, 0x3d, 0xdb, 0x6a, 0x5b, 0x9d, 0x86, 0xa7, 0x4d, 0xd4, 0x02, 0x18, 0x4d, 0xa8, 0x7f, 0x36,
-	0x64, 0x38, 0x61, 0xf6, 0x62, 0xdb, 0xea, 0xd4, 0xbc, 0x0c, 0x82, 0x1c, 0x58, 0x16, 0xd6, 0x7e,
-	0x14, 0xd8, 0x25, 0xb1, 0x6a, 0x6c, 0xb4, 0x05, 0xb5, 0xb7, 0x33, 0x92, 0x5c, 0x0e, 0x68, 0x40,
-	0xec, 0x8a, 0x58, 0x9c, 0x03, 0x6e, 0x04, 0xeb, 0x99, 0x38, 0xd2, 0x98, 0x46, 0x29, 0x41, 0x3b,
-	0x50, 0x11, 0x27, 0x8b, 0x30, 0xea, 0xbd, 0x95, 0xae, 0xca, 0xbd, 0x2b, 0xa8, 0x9e, 0x5c, 0x44,
-	0xcf, 0xa1, 0x3a, 0x25, 0x2c, 0x09, 0xfd, 0x54, 0x44, 0x54, 0xef, 0xfd, 0x2f, 0xcf, 0xe3, 0x2e,
-	0x07, 0x92, 0xe0, 0x69, 0xa6, 0xfb, 0x69, 0x26, 0x6f, 0xb5, 0x88, 0x5c, 0x68, 0x9c, 0xe0, 0x70,
-	0x42, 0x82, 0x3e, 0x8f, 0x39, 0x15, 0xa7, 0x36, 0xbd, 0x1c, 0xe6, 0xfe, 0xb6, 0x08, 0xcd, 0x21,
-	0xc1, 0x89, 0x7f, 0xaa, 0xd5, 0x7a, 0x05, 0xe5, 0x23, 0x3c, 0xe6, 0xec, 0x52, 0xa7, 0xde, 0x6b,
-	0x9b, 0xb3, 0x73, 0xac, 0x2e, 0xa7, 0xec, 0x47, 0x2c, 0xb9, 0xec, 0x97, 0xdf, 0x7d, 0xd8, 0x5e,
-	0xf0, 0xc4, 0x1e, 0xb4, 0x03, 0xcd, 0x41, 0x18, 0xed, 0xcd, 0x12, 0xcc, 0x42, 0x1a, 0x0d, 0x64,
-	0x02, 0x4d, 0x2f, 0x0f, 0x0a, 0x16, 0xbe, 0xc8, 0xb0, 0x4a, 0x8a, 0x95, 0x05, 0xd1, 0x43, 0xa8,
-	0x7c, 0x13, 0x4e, 0x43, 0x66, 0x97, 0xc5, 0xaa, 0x34, 0x38, 0x9a, 0x8a, 0xcb, 0xaa, 0x48, 0x54,
-	0x18, 0x68, 0x0d, 0x4a, 0x24, 0x0a, 0xec, 0x25, 0x81, 0xf1, 0x4f, 0xce, 0xfb, 0x96, 0x5f, 0x86,
-	0xbd, 0x2c, 0x6e, 0x46, 0x1a, 0xce, 0x4b, 0xa8, 0x99, 0xc0, 0xf9, 0xa6, 0x33, 0x72, 0x29, 0x54,
-	0xa9, 0x79, 0xfc, 0x93, 0x6f, 0x3a, 0xc7, 0x93, 0x19, 0x51, 0x95, 0x20, 0x8d, 0x57, 0x8b, 0x9f,
-	0x59, 0xee, 0x0f, 0x25, 0x40, 0x52, 0x00, 0xa1, 0x9b, 0xd6, 0xea, 0x05, 0xd4, 0x52, 0x2d, 0x8b,
-	0xba, 0xd4, 0x8d, 0xdb, 0x05, 0xf3, 0xe6, 0x44, 0x5e, 0x8f, 0xa2, 0x8a, 0x0e, 0xf6, 0xd4, 0x41,
-	0xda, 0xe4, 0x35, 0x25, 0x12, 0x3a, 0xc4, 0x63, 0xa2, 0x54, 0x99, 0x03, 0x5c, 0xb7, 0x18, 0x8f,
-	0x49, 0x7a, 0x44, 0xa5, 0x6b, 0xa5, 0x4c, 0x1e, 0xe4, 0x35, 0x4b, 0x22, 0x9f, 0x06, 0x61, 0x34,
-	0x56, 0x65, 0x69, 0x6c, 0xee, 0x21, 0x8c, 0x02, 0x72, 0xc1, 0xdd, 0x0d, 0xc3, 0xef, 0x89, 0x52,
-	0x2c, 0x0f, 0xf2, 0xba, 0x61, 0x94, 0xe1, 0x89, 0x47, 0x7c, 0x9a, 0x04, 0xa9, 0x5d, 0x95, 0x75,
-	0x93, 0xc5, 0x38, 0x27, 0xc0, 0x0c, 0xef, 0xeb, 0x93, 0xa4, 0xcc, 0x39, 0x8c, 0xe7, 0x79, 0x4e,
-	0x92, 0x34, 0xa4, 0x91, 0x5d, 0x93, 0x79, 0x2a, 0x13, 0x21, 0x28, 0xa7, 0xfc, 0x78, 0x68, 0x5b,
-	0x9d, 0xb2, 0x27, 0xbe, 0x79, 0x2f, 0x9e, 0x50, 0xca, 0x48, 0x22, 0x02, 0xab, 0x8b, 0x33, 0x33,
-	0x88, 0x7b, 0x01, 0x2b, 0x5a, 0x51, 0xd5, 0x4e, 0x2f, 0x60, 0x49, 0x74, 0x8c, 0xae, 0xd5, 0xad,
-	0x7c, 0x9f, 0x48, 0xf6, 0x80, 0x30, 0xcc, 0xa3, 0xf2, 0x14, 0x17, 0x3d, 0x2d, 0xb6, 0x57, 0xf1,
-	0xc6, 0x6e, 0xf4, 0xd6, 0xef, 0x16, 0x3c, 0xb8, 0xc5, 0x63, 0x71, 0xae, 0xd4, 0xe6, 0x73, 0xa5,
-	0x03, 0xab, 0x09, 0xa5, 0x6c, 0x48, 0x92, 0xf3, 0xd0, 0x27, 0x6f, 0xf0, 0x54, 0x97, 0x54, 0x11,
-	0xe6, 0x37, 0xc2, 0x21, 0xe1, 0x5e, 0xf0, 0xe4, 0x98, 0xc9, 0x83, 0xe8, 0x63, 0x58, 0x17, 0x65,
-	0x70, 0x14, 0x4e, 0xc9, 0x77, 0x51, 0x78, 0xf1, 0x06, 0x47, 0x54, 0xdc, 0x7e, 0xd9, 0xbb, 0xb9,
-	0xc0, 0x95, 0x0c, 0xe6, 0xcd, 0x25, 0x1b, 0x25, 0x83, 0xb8, 0x3f, 0x9a, 0x9e, 0xd7, 0x93, 0xa2,
-	0x03, 0xab, 0x61, 0x94, 0xc6, 0xc4, 0x67, 0x24, 0x38, 0xd2, 0x92, 0xf2, 0x6d, 0x45, 0x18, 0xfd,
-	0x1f, 0x56, 0x0c, 0xd4, 0xbf, 0x64, 0x44, 0x8a, 0x58, 0xf6, 0x0a, 0x68, 0xce, 0xa3, 0x1a, 0x3f,
-	0xa5, 0x82, 0x47, 0x09, 0x73, 0x05, 0xd2, 0xb3, 0x30, 0x8e, 0x0d, 0x4f, 0x55, 0x75, 0x0e, 0xcc,
-	0xb0, 0x54, 0x7c, 0x95, 0x1c, 0x4b, 0x45, 0xd7, 0x81, 0x55, 0x51, 0xa5, 0x62, 0x93, 0x0c, 0x6f,
-	0x49, 0x84, 0x57, 0x84, 0xdd, 0x07, 0xb0, 0x2e, 0x25, 0xe0, 0xf3, 0x40, 0xf5, 0xa8, 0xfb, 0x54,
-	0x37, 0xb9, 0x04, 0x55, 0x99, 0x39, 0xb0, 0xcc, 0xf0, 0x98, 0xdf, 0x83, 0x2c, 0xb4, 0x9a, 0x67,
-	0x6c, 0xb7, 0x07, 0x1b, 0x66, 0xc7, 0x31, 0x9f, 0x16, 0x69, 0xf6, 0xd1, 0x91, 0x2c, 0x53, 0x1c,
-	0xd2, 0x74, 0x5f, 0xc2, 0xe6, 0x8d, 0x3d, 0xea, 0xa8, 0x2d, 0xa8, 0x31, 0x0d, 0xaa, 0xb3, 0xe6,
-	0x80, 0xdb, 0x87, 0x8a, 0xc8, 0x13, 0x7d, 0x0e, 0xd5, 0x11, 0x66, 0xfe, 0xa9, 0xa9, 0xfc, 0x6d,
-	0x53, 0xc2, 0xf2, 0xed, 0x3c, 0x7f, 0xd6, 0xf5, 0x48, 0x4a, 0x67, 0x89, 0x4f, 0x86, 0x31, 0x8e,
-	0x52, 0x4f, 0xf3, 0xdd, 0x15, 0x68, 0x1c, 0xce, 0x52, 0xd3, 0x43, 0xee, 0xaf, 0x16, 0xac, 0x71,
-	0x40, 0xa8, 0xa2, 0x63, 0x7f, 0x62, 0x1a, 0x6b, 0xb1, 0x5d, 0xea, 0x34, 0xfa, 0x8f, 0xf8, 0x88,
-	0xff, 0xf3, 0xc3, 0x76, 0xf3, 0x30, 0x21, 0x78, 0x32, 0xa1, 0xbe, 0x64, 0xeb, 0x8e, 0xfa, 0x08,
-	0x4a, 0x61, 0xc0, 0xef, 0xf7, 0x1e, 0x2e, 0x67, 0xa0, 0x4f, 0x00, 0xe4, 0x14, 0xdc, 0xc3, 0x0c,
-	0xdb, 0xe5, 0xfb, 0xf8, 0x19, 0xa2, 0x3b, 0x90, 0x21, 0xca, 0x4c, 0x54, 0x88, 0xff, 0x41, 0x82,
-	0x1d, 0x00, 0xf5, 0x54, 0xf2, 0x42, 0xdd, 0xc8, 0x0d, 0x91, 0x86, 0x4e, 0xaa, 0xf7, 0xb3, 0x05,
-	0x4b, 0xfc, 0x54, 0x92, 0xa0, 0x2f, 0xa0, 0x66, 0x24, 0x42, 0xf3, 0xc7, 0xb8, 0x28, 0x9b, 0xf3,
-	0x28, 0xb7, 0x64, 0x24, 0x5e, 0x40, 0x5f, 0x42, 0xdd, 0x90, 0x8f, 0x7b, 0xff, 0xc6, 0x45, 0x6f,
-	0x08, 0x6b, 0xaa, 0x59, 0x5f, 0x93, 0x88, 0x24, 0x98, 0x51, 0x13, 0x97, 0x48, 0xaf, 0xe0, 0x34,
-	0xab, 0xd5, 0xdd, 0x4e, 0xff, 0x5e, 0x84, 0x2a, 0x7f, 0x18, 0x43, 0x92, 0xa0, 0xaf, 0xa1, 0xf9,
-	0x55, 0x18, 0x05, 0xe6, 0x4f, 0x04, 0xba, 0xe5, 0x5f, 0x87, 0x76, 0xe8, 0xdc, 0xb6, 0x94, 0xc9,
-	0xb6, 0xa1, 0x07, 0xb5, 0x4f, 0x22, 0x86, 0xee, 0x78, 0x11, 0x9d, 0xcd, 0x1b, 0xb8, 0x71, 0xb1,
-	0x0f, 0xf5, 0xcc, 0x6b, 0x8b, 0x1e, 0x17, 0x98, 0xd9, 0x37, 0xf8, 0x3e, 0x37, 0xaf, 0x01, 0xe6,
-	0xfd, 0x8c, 0x9c, 0x02, 0x31, 0xd3, 0xf9, 0xce, 0xe3, 0x5b, 0xd7, 0x8c, 0xa3, 0x63, 0x58, 0x2d,
-	0xb4, 0x2c, 0xda, 0xbe, 0xb9, 0x23, 0x37, 0x00, 0x9c, 0xf6, 0xdd, 0x04, 0xed, 0xb7, 0x6f, 0xbf,
-	0xbb, 0x6a, 0x59, 0xef, 0xaf, 0x5a, 0xd6, 0x5f, 0x57, 0x2d, 0xeb, 0x97, 0xeb, 0xd6, 0xc2, 0xfb,
-	0xeb, 0xd6, 0xc2, 0x1f, 0xd7, 0xad, 0x85, 0xd1, 0x92, 0xf8, 0x3f, 0xfb, 0xfc, 0x9f, 0x00, 0x00,
-	0x00, 0xff, 0xff, 0xa6, 0x95, 0x7f, 0x34, 0x38, 0x0b, 0x00, 0x00,
+	// 1413 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x57, 0x4b, 0x6f, 0xdb, 0xc6,
+	0x16, 0x36, 0xf5, 0xb0, 0xac, 0x23, 0xc9, 0x8f, 0x89, 0x13, 0x33, 0x4a, 0xae, 0xec, 0x4b, 0x04,
+	0xf7, 0x6a, 0x91, 0xd8, 0x89, 0x92, 0x7b, 0xd3, 0x66, 0x53, 0x54, 0xb0, 0x9b, 0xa4, 0xa8, 0x82,
+	0x94, 0x72, 0x0d, 0xb4, 0xbb, 0x11, 0x39, 0x91, 0x09, 0x4b, 0x1c, 0x86, 0x1c, 0xba, 0x52, 0x77,
+	0xdd, 0x14, 0x5d, 0x74, 0xd1, 0x45, 0xff, 0x40, 0x81, 0x6c, 0xfa, 0x4f, 0xb2, 0x29, 0x90, 0x65,
+	0xd1, 0x45, 0x50, 0x24, 0x7f, 0xa4, 0x98, 0x07, 0x87, 0x0f, 0x4b, 0x2e, 0xd0, 0xae, 0xc4, 0xf9,
+	0xe6, 0x3b, 0x67, 0xce, 0x7c, 0x73, 0xce, 0x99, 0x11, 0xec, 0x04, 0x67, 0xe3, 0x03, 0x46, 0xa6,
+	0x01, 0x0d, 0x46, 0xf2, 0x77, 0x3f, 0x08, 0x29, 0xa3, 0xa8, 0xa6, 0xc0, 0xf6, 0x36, 0x0b, 0xb1,
+	0x43, 0x0e, 0xce, 0xef, 0x1d, 0x88, 0x0f, 0x39, 0xdd, 0xbe, 0x33, 0xf6, 0xd8, 0x69, 0x3c, 0xda,
+	0x77, 0xe8, 0xf4, 0x60, 0x4c, 0xc7, 0xf4, 0x40, 0xc0, 0xa3, 0xf8, 0x85, 0x18, 0x89, 0x81, 0xf8,
+	0x92, 0x74, 0xeb, 0x3b, 0x03, 0x36, 0x8f, 0xb9, 0x79, 0x7f, 0xfe, 0xf4, 0xd0, 0x26, 0x2f, 0x63,
+	0x12, 0x31, 0x64, 0x42, 0x4d, 0xb8, 0x7c, 0x7a, 0x68, 0x1a, 0x7b, 0x46, 0xb7, 0x69, 0x27, 0x43,
+	0xd4, 0x01, 0x18, 0x4d, 0xa8, 0x73, 0x36, 0x64, 0x38, 0x64, 0x66, 0x69, 0xcf, 0xe8, 0xd6, 0xed,
+	0x0c, 0x82, 0xda, 0xb0, 0x26, 0x46, 0x47, 0xbe, 0x6b, 0x96, 0xc5, 0xac, 0x1e, 0xa3, 0x9b, 0x50,
+	0x7f, 0x19, 0x93, 0x70, 0x3e, 0xa0, 0x2e, 0x31, 0xab, 0x62, 0x32, 0x05, 0x2c, 0x1f, 0xb6, 0x32,
+	0x71, 0x44, 0x01, 0xf5, 0x23, 0x82, 0x6e, 0x41, 0x55, 0xac, 0x2c, 0xc2, 0x68, 0xf4, 0xd6, 0xf7,
+	0xd5, 0xde, 0xf7, 0x05, 0xd5, 0x96, 0x93, 0xe8, 0x3e, 0xd4, 0xa6, 0x84, 0x85, 0x9e, 0x13, 0x89,
+	0x88, 0x1a, 0xbd, 0xeb, 0x79, 0x1e, 0x77, 0x39, 0x90, 0x04, 0x3b, 0x61, 0x5a, 0xff, 0x87, 0xcd,
+	0xe2, 0x24, 0xb2, 0xa0, 0xf9, 0x02, 0x7b, 0x13, 0xe2, 0xf6, 0x79, 0xcc, 0x91, 0x58, 0xb5, 0x65,
+	0xe7, 0x30, 0xeb, 0x4b, 0xb8, 0x62, 0x13, 0x87, 0xf8, 0x4c, 0x58, 0x47, 0x89, 0x64, 0xdb, 0x50,
+	0x8d, 0x84, 0x26, 0xd2, 0x46, 0x0e, 0xd0, 0x26, 0x94, 0x89, 0xef, 0x8a, 0xa8, 0x5a, 0x36, 0xff,
+	0xe4, 0x02, 0x4d, 0xf1, 0xac, 0x3f, 0x67, 0x24, 0x12, 0x02, 0xb5, 0x6c, 0x3d, 0xb6, 0x46, 0xb0,
+	0x9d, 0x77, 0xad, 0x54, 0xb8, 0x0d, 0xab, 0x62, 0xa3, 0x3c, 0xa0, 0x72, 0xb7, 0xd1, 0xdb, 0xd6,
+	0xdb, 0xcb, 0xd0, 0x6d, 0xc5, 0xe1, 0x32, 0xb3, 0x30, 0xf6, 0x1d, 0xcc, 0x88, 0x5c, 0x79, 0xcd,
+	0x4e, 0x01, 0x6b, 0x00, 0x8d, 0x8c, 0xd1, 0x25, 0x27, 0xad, 0xa5, 0x2f, 0x5d, 0x22, 0xbd, 0xf5,
+	0xaa, 0x04, 0xad, 0x21, 0xc1, 0xa1, 0x73, 0x9a, 0x08, 0xf1, 0x08, 0x2a, 0xc7, 0x78, 0x9c, 0x84,
+	0xba, 0xa7, 0xcd, 0x72, 0xac, 0x7d, 0x4e, 0x39, 0xf2, 0x59, 0x38, 0xef, 0x57, 0x5e, 0xbf, 0xdd,
+	0x5d, 0xb1, 0x85, 0x0d, 0xba, 0x05, 0xad, 0x81, 0xe7, 0x1f, 0xc6, 0x21, 0x66, 0x1e, 0xf5, 0x07,
+	0x91, 0x12, 0x2e, 0x0f, 0x0a, 0x16, 0x9e, 0x65, 0x58, 0x65, 0xc5, 0xca, 0x82, 0xfc, 0x40, 0x3e,
+	0xf3, 0xa6, 0x1e, 0x33, 0x2b, 0xf2, 0x40, 0xc4, 0x20, 0x3d, 0xa6, 0xea, 0x82, 0x63, 0x5a, 0x4d,
+	0x8f, 0x69, 0x1b, 0xaa, 0x9f, 0xf3, 0xd4, 0x34, 0xd7, 0x44, 0x9e, 0xca, 0x41, 0xfb, 0x21, 0xd4,
+	0x75, 0xe0, 0xdc, 0xe8, 0x8c, 0xcc, 0x85, 0x6c, 0x75, 0x9b, 0x7f, 0x72, 0xa3, 0x73, 0x3c, 0x89,
+	0x89, 0xaa, 0x0b, 0x39, 0x78, 0x54, 0xfa, 0xc0, 0xb0, 0x5e, 0x95, 0x01, 0x49, 0x01, 0x44, 0x16,
+	0x25, 0x5a, 0x3d, 0x80, 0x7a, 0x94, 0xc8, 0xa2, 0x52, 0xfc, 0xda, 0x62, 0xc1, 0xec, 0x94, 0xc8,
+	0xcf, 0x4c, 0xd4, 0xd4, 0xd3, 0x43, 0xb5, 0x50, 0x32, 0xe4, 0x47, 0x2f, 0x36, 0xf4, 0x1c, 0x8f,
+	0x89, 0x52, 0x25, 0x05, 0xb8, 0x6e, 0x01, 0x1e, 0x93, 0xe8, 0x98, 0x4a, 0xd7, 0x4a, 0x99, 0x3c,
+	0xc8, 0x13, 0x94, 0xf8, 0x0e, 0x75, 0x3d, 0x7f, 0xac, 0x8a, 0x54, 0x8f, 0xb9, 0x07, 0xcf, 0x77,
+	0xc9, 0x8c, 0xbb, 0x1b, 0x7a, 0xdf, 0x10, 0xa5, 0x58, 0x1e, 0xe4, 0x55, 0xc4, 0x28, 0xc3, 0x13,
+	0x9b, 0x38, 0x34, 0x74, 0x23, 0xb3, 0x26, 0xab, 0x28, 0x8b, 0x71, 0x8e, 0x8b, 0x19, 0x3e, 0x4a,
+	0x56, 0x92, 0x32, 0xe7, 0x30, 0xbe, 0xcf, 0x73, 0x12, 0x46, 0x1e, 0xf5, 0xcd, 0xba, 0xdc, 0xa7,
+	0x1a, 0x22, 0x04, 0x95, 0x88, 0x2f, 0x0f, 0x7b, 0x46, 0xb7, 0x62, 0x8b, 0x6f, 0xde, 0x99, 0x5e,
+	0x50, 0xca, 0x48, 0x28, 0x02, 0x6b, 0x88, 0x35, 0x33, 0x08, 0x5f, 0x51, 0x84, 0x79, 0xa2, 0x5c,
+	0x36, 0xe5, 0x8a, 0x59, 0xcc, 0x9a, 0xc1, 0x7a, 0xa2, 0xba, 0x2a, 0xbd, 0x07, 0x85, 0xd2, 0xbb,
+	0x99, 0x2f, 0x03, 0xc9, 0x1e, 0x10, 0x86, 0x79, 0xe4, 0xba, 0x04, 0xef, 0x16, 0x1b, 0x52, 0xf1,
+	0x54, 0x2f, 0x74, 0xa3, 0x5f, 0x0d, 0xb8, 0xb2, 0xc0, 0x63, 0xb1, 0x3e, 0xeb, 0x69, 0x7d, 0x76,
+	0x61, 0x23, 0xa4, 0x94, 0x0d, 0x49, 0x78, 0xee, 0x39, 0xe4, 0x19, 0x9e, 0x26, 0x69, 0x57, 0x84,
+	0xf9, 0xa9, 0x71, 0x48, 0xb8, 0x17, 0x3c, 0xd9, 0x98, 0xf3, 0x20, 0xba, 0x0d, 0x5b, 0x22, 0x55,
+	0x8e, 0xbd, 0x29, 0xf9, 0xc2, 0xf7, 0x66, 0xcf, 0xb0, 0x4f, 0x45, 0x86, 0x54, 0xec, 0x8b, 0x13,
+	0x5c, 0x6d, 0x37, 0x2d, 0x40, 0x59, 0x4c, 0x19, 0xc4, 0xfa, 0x56, 0xf7, 0x85, 0xa4, 0xb7, 0x76,
+	0x61, 0xc3, 0xf3, 0xa3, 0x80, 0x38, 0x8c, 0xb8, 0xc7, 0x89, 0xa4, 0xdc, 0xac, 0x08, 0xa3, 0xff,
+	0xc0, 0xba, 0x86, 0x64, 0xa3, 0x2c, 0x89, 0x30, 0x0a, 0x68, 0xce, 0xa3, 0x6a, 0xd8, 0xe5, 0x82,
+	0x47, 0x09, 0x73, 0x05, 0xa2, 0x33, 0x2f, 0x08, 0x34, 0x4f, 0x65, 0x7e, 0x0e, 0xcc, 0xb0, 0x54,
+	0x7c, 0xd5, 0x1c, 0x4b, 0x45, 0xd7, 0x85, 0x0d, 0x91, 0xc9, 0xc2, 0x48, 0x86, 0xb7, 0x2a, 0xc2,
+	0x2b, 0xc2, 0xd6, 0x15, 0xd8, 0x92, 0x12, 0xf0, 0x9e, 0xa1, 0xea, 0xd8, 0xba, 0x0b, 0x28, 0x0b,
+	0xaa, 0x34, 0x6b, 0xc3, 0x1a, 0xc3, 0x63, 0x7e, 0x0e, 0x32, 0xd1, 0xea, 0xb6, 0x1e, 0x5b, 0x3d,
+	0xb8, 0xa6, 0x2d, 0x4e, 0x78, 0x47, 0x89, 0xb2, 0xd7, 0xb4, 0x64, 0xe9, 0xe4, 0x90, 0x43, 0xeb,
+	0x21, 0xec, 0x5c, 0xb0, 0x51, 0x4b, 0xf1, 0xeb, 0x21, 0x01, 0xd5, 0x5a, 0x29, 0x60, 0x7d, 0x6f,
+	0xc0, 0xf5, 0x82, 0xe5, 0x49, 0x4f, 0xdb, 0x1e, 0x14, 0x6d, 0x1b, 0xbd, 0xad, 0xb4, 0x20, 0xd4,
+	0x4c, 0xc6, 0x1d, 0x7a, 0x04, 0x35, 0x3f, 0x9e, 0x92, 0xd0, 0x73, 0x54, 0x21, 0xa4, 0xf7, 0xc1,
+	0x33, 0x89, 0xeb, 0x65, 0x86, 0xf1, 0x74, 0x8a, 0xc3, 0xb9, 0x9d, 0x18, 0x58, 0x9f, 0xc2, 0x5a,
+	0x32, 0xc9, 0x0b, 0x9e, 0xcd, 0x83, 0x64, 0x9b, 0xe2, 0x7b, 0x71, 0xb7, 0xe5, 0xa8, 0x43, 0x63,
+	0x9f, 0x89, 0x54, 0xa8, 0xd8, 0x72, 0x60, 0xfd, 0x64, 0xc0, 0xce, 0x92, 0x05, 0x79, 0x1f, 0x9f,
+	0x7a, 0xbe, 0x70, 0x6d, 0xd8, 0xfc, 0x53, 0x20, 0x78, 0x66, 0x96, 0x14, 0x82, 0x67, 0x8b, 0xbd,
+	0xa2, 0x8f, 0xa0, 0x7e, 0xea, 0x45, 0x8c, 0x8e, 0x43, 0x3c, 0x35, 0x2b, 0x42, 0x8e, 0x7f, 0x5f,
+	0x90, 0x23, 0x7a, 0x92, 0x50, 0xfa, 0xb1, 0x73, 0x46, 0x98, 0x9d, 0xda, 0x58, 0x01, 0x98, 0xcb,
+	0x68, 0xbc, 0xc2, 0x26, 0xf4, 0x6b, 0x12, 0xf6, 0x69, 0xec, 0xbb, 0x2a, 0xba, 0x0c, 0xc2, 0xe7,
+	0xe3, 0x20, 0x48, 0xe6, 0x65, 0xac, 0x19, 0x64, 0x89, 0x10, 0x7d, 0xa8, 0xca, 0x8b, 0xff, 0x43,
+	0xa8, 0x8d, 0x30, 0x73, 0x4e, 0xf5, 0x41, 0xee, 0xea, 0xc8, 0xe5, 0x6b, 0xf2, 0xfc, 0xde, 0xbe,
+	0x4d, 0x22, 0x1a, 0x87, 0x0e, 0x19, 0x06, 0xd8, 0x8f, 0xec, 0x84, 0x6f, 0xad, 0x43, 0xf3, 0x79,
+	0x1c, 0xe9, 0x1e, 0x69, 0xfd, 0x6c, 0xc0, 0x26, 0x07, 0x44, 0xd6, 0x27, 0xb9, 0x79, 0x47, 0x37,
+	0xce, 0xd2, 0x5e, 0xb9, 0xdb, 0xec, 0x5f, 0xe5, 0xd7, 0xfc, 0xef, 0x6f, 0x77, 0x5b, 0xcf, 0x43,
+	0x82, 0x27, 0x13, 0xea, 0x48, 0xb6, 0x22, 0xa1, 0xff, 0x42, 0xd9, 0x73, 0x79, 0xfd, 0x5e, 0xc2,
+	0xe5, 0x0c, 0xf4, 0x3f, 0x00, 0x79, 0x13, 0x1e, 0x62, 0x86, 0xcd, 0xca, 0x65, 0xfc, 0x0c, 0xd1,
+	0x1a, 0xc8, 0x10, 0xe5, 0x4e, 0x54, 0x88, 0xff, 0x40, 0x82, 0x5b, 0x00, 0xea, 0xf1, 0xc8, 0x48,
+	0x84, 0xae, 0xe5, 0x2e, 0x89, 0x66, 0xb2, 0xa9, 0xde, 0x0f, 0x06, 0xac, 0xf2, 0x55, 0x49, 0xc8,
+	0x53, 0x45, 0x4b, 0x84, 0xd2, 0xe7, 0x69, 0x51, 0xb6, 0xf6, 0xd5, 0xdc, 0x94, 0x96, 0x78, 0x05,
+	0x7d, 0x0c, 0x0d, 0x4d, 0x3e, 0xe9, 0xfd, 0x1d, 0x17, 0xbd, 0x21, 0x6c, 0xaa, 0x66, 0xfc, 0x98,
+	0xf8, 0x24, 0xc4, 0x8c, 0xea, 0xb8, 0xc4, 0xf6, 0x0a, 0x4e, 0xb3, 0x5a, 0x2d, 0x77, 0xfa, 0x4b,
+	0x05, 0x6a, 0xfc, 0x71, 0xe4, 0x91, 0x10, 0x3d, 0x81, 0xd6, 0x27, 0x9e, 0xef, 0xea, 0x67, 0x35,
+	0x5a, 0xf0, 0x0e, 0x4f, 0x1c, 0xb6, 0x17, 0x4d, 0x65, 0x76, 0xdb, 0x4c, 0x2e, 0x62, 0xfe, 0x56,
+	0x45, 0x4b, 0x5e, 0x45, 0xed, 0x9d, 0x0b, 0xb8, 0x76, 0x71, 0x04, 0x8d, 0xcc, 0x8b, 0x0b, 0xdd,
+	0x28, 0x30, 0xb3, 0xef, 0xb0, 0xcb, 0xdc, 0x3c, 0x06, 0x48, 0xfb, 0x35, 0x6a, 0x17, 0x88, 0x99,
+	0xce, 0xde, 0xbe, 0xb1, 0x70, 0x4e, 0x3b, 0x3a, 0x81, 0x8d, 0x42, 0x63, 0x45, 0xbb, 0x17, 0x2d,
+	0x72, 0x0d, 0xbe, 0xbd, 0xb7, 0x9c, 0xa0, 0xfd, 0x7e, 0x05, 0x5b, 0x85, 0xc9, 0x93, 0xde, 0x5f,
+	0x7b, 0xb6, 0x96, 0x11, 0xd2, 0x6e, 0x6f, 0xad, 0xa0, 0x01, 0x34, 0xb3, 0x7f, 0x48, 0xd0, 0xcd,
+	0x45, 0x7f, 0x3c, 0xb4, 0xcf, 0x7f, 0x2d, 0x99, 0x4d, 0xdc, 0xf5, 0xcd, 0xd7, 0xef, 0x3a, 0xc6,
+	0x9b, 0x77, 0x1d, 0xe3, 0x8f, 0x77, 0x1d, 0xe3, 0xc7, 0xf7, 0x9d, 0x95, 0x37, 0xef, 0x3b, 0x2b,
+	0xbf, 0xbd, 0xef, 0xac, 0x8c, 0x56, 0xc5, 0x9f, 0xd1, 0xfb, 0x7f, 0x0e, 0x00, 0x1e, 0xa9, 0xde,
+	0xf0, 0xf5, 0x0e, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1306,6 +1754,8 @@ type QuerierClient interface {
 	SearchBlock(ctx context.Context, in *SearchBlockRequest, opts ...grpc.CallOption) (*SearchResponse, error)
 	SearchTags(ctx context.Context, in *SearchTagsRequest, opts ...grpc.CallOption) (*SearchTagsResponse, error)
 	SearchTagValues(ctx context.Context, in *SearchTagValuesRequest, opts ...grpc.CallOption) (*SearchTagValuesResponse, error)
+	SearchTagValuesV2(ctx context.Context, in *SearchTagValuesRequest, opts ...grpc.CallOption) (*SearchTagValuesV2Response, error)
+	RecentTraces(ctx context.Context, in *RecentTracesRequest, opts ...grpc.CallOption) (*RecentTracesResponse, error)
 }
 
 type querierClient struct {
@@ -1361,6 +1811,24 @@ func (c *querierClient) SearchTagValues(ctx context.Context, in *SearchTagValues
 	return out, nil
 }
 
+func (c *querierClient) SearchTagValuesV2(ctx context.Context, in *SearchTagValuesRequest, opts ...grpc.CallOption) (*SearchTagValuesV2Response, error) {
+	out := new(SearchTagValuesV2Response)
+	err := c.cc.Invoke(ctx, "/tempopb.Querier/SearchTagValuesV2", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *querierClient) RecentTraces(ctx context.Context, in *RecentTracesRequest, opts ...grpc.CallOption) (*RecentTracesResponse, error) {
+	out := new(RecentTracesResponse)
+	err := c.cc.Invoke(ctx, "/tempopb.Querier/RecentTraces", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // QuerierServer is the server API for Querier service.
 type QuerierServer interface {
 	FindTraceByID(context.Context, *TraceByIDRequest) (*TraceByIDResponse, error)
@@ -1368,6 +1836,8 @@ type QuerierServer interface {
 	SearchBlock(context.Context, *SearchBlockRequest) (*SearchResponse, error)
 	SearchTags(context.Context, *SearchTagsRequest) (*SearchTagsResponse, error)
 	SearchTagValues(context.Context, *SearchTagValuesRequest) (*SearchTagValuesResponse, error)
+	SearchTagValuesV2(context.Context, *SearchTagValuesRequest) (*SearchTagValuesV2Response, error)
+	RecentTraces(context.Context, *RecentTracesRequest) (*RecentTracesResponse, error)
 }
 
 // UnimplementedQuerierServer can be embedded to have forward compatible implementations.
@@ -1389,6 +1859,12 @@ func (*UnimplementedQuerierServer) SearchTags(ctx context.Context, req *SearchTa
 func (*UnimplementedQuerierServer) SearchTagValues(ctx context.Context, req *SearchTagValuesRequest) (*SearchTagValuesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SearchTagValues not implemented")
 }
+func (*UnimplementedQuerierServer) SearchTagValuesV2(ctx context.Context, req *SearchTagValuesRequest) (*SearchTagValuesV2Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchTagValuesV2 not implemented")
+}
+func (*UnimplementedQuerierServer) RecentTraces(ctx context.Context, req *RecentTracesRequest) (*RecentTracesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RecentTraces not implemented")
+}
 
 func RegisterQuerierServer(s *grpc.Server, srv QuerierServer) {
 	s.RegisterService(&_Querier_serviceDesc, srv)
@@ -1484,6 +1960,42 @@ func _Querier_SearchTagValues_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Querier_SearchTagValuesV2_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchTagValuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuerierServer).SearchTagValuesV2(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tempopb.Querier/SearchTagValuesV2",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuerierServer).SearchTagValuesV2(ctx, req.(*SearchTagValuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Querier_RecentTraces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecentTracesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QuerierServer).RecentTraces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tempopb.Querier/RecentTraces",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QuerierServer).RecentTraces(ctx, req.(*RecentTracesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Querier_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "tempopb.Querier",
 	HandlerType: (*QuerierServer)(nil),
@@ -1508,6 +2020,14 @@ var _Querier_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SearchTagValues",
 			Handler:    _Querier_SearchTagValues_Handler,
 		},
+		{
+			MethodName: "SearchTagValuesV2",
+			Handler:    _Querier_SearchTagValuesV2_Handler,
+		},
+		{
+			MethodName: "RecentTraces",
+			Handler:    _Querier_RecentTraces_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pkg/tempopb/tempo.proto",
@@ -1639,7 +2159,7 @@ func (m *TraceByIDMetrics) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *SearchRequest) Marshal() (dAtA []byte, err error) {
+func (m *RecentTracesRequest) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -1649,46 +2169,173 @@ func (m *SearchRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *SearchRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *RecentTracesRequest) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *SearchRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *RecentTracesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if len(m.Query) > 0 {
-		i -= len(m.Query)
-		copy(dAtA[i:], m.Query)
-		i = encodeVarintTempo(dAtA, i, uint64(len(m.Query)))
+	if m.MaxBytes != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.MaxBytes))
 		i--
-		dAtA[i] = 0x42
+		dAtA[i] = 0x18
 	}
 	if m.End != 0 {
 		i = encodeVarintTempo(dAtA, i, uint64(m.End))
 		i--
-		dAtA[i] = 0x30
+		dAtA[i] = 0x10
 	}
 	if m.Start != 0 {
 		i = encodeVarintTempo(dAtA, i, uint64(m.Start))
 		i--
-		dAtA[i] = 0x28
-	}
-	if m.Limit != 0 {
-		i = encodeVarintTempo(dAtA, i, uint64(m.Limit))
-		i--
-		dAtA[i] = 0x20
+		dAtA[i] = 0x8
 	}
-	if m.MaxDurationMs != 0 {
-		i = encodeVarintTempo(dAtA, i, uint64(m.MaxDurationMs))
-		i--
-		dAtA[i] = 0x18
+	return len(dAtA) - i, nil
+}
+
+func (m *RecentTracesResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
 	}
-	if m.MinDurationMs != 0 {
-		i = encodeVarintTempo(dAtA, i, uint64(m.MinDurationMs))
-		i--
+	return dAtA[:n], nil
+}
+
+func (m *RecentTracesResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RecentTracesResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Truncated {
+		i--
+		if m.Truncated {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Traces) > 0 {
+		for iNdEx := len(m.Traces) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Traces[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTempo(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *RecentTrace) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RecentTrace) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RecentTrace) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Trace != nil {
+		{
+			size, err := m.Trace.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTempo(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.TraceID) > 0 {
+		i -= len(m.TraceID)
+		copy(dAtA[i:], m.TraceID)
+		i = encodeVarintTempo(dAtA, i, uint64(len(m.TraceID)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *SearchRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SearchRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *SearchRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Query) > 0 {
+		i -= len(m.Query)
+		copy(dAtA[i:], m.Query)
+		i = encodeVarintTempo(dAtA, i, uint64(len(m.Query)))
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.End != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.End))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Start != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.Start))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Limit != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.Limit))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.MaxDurationMs != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.MaxDurationMs))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.MinDurationMs != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.MinDurationMs))
+		i--
 		dAtA[i] = 0x10
 	}
 	if len(m.Tags) > 0 {
@@ -1733,6 +2380,13 @@ func (m *SearchBlockRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.IndexVersion) > 0 {
+		i -= len(m.IndexVersion)
+		copy(dAtA[i:], m.IndexVersion)
+		i = encodeVarintTempo(dAtA, i, uint64(len(m.IndexVersion)))
+		i--
+		dAtA[i] = 0x62
+	}
 	if m.FooterSize != 0 {
 		i = encodeVarintTempo(dAtA, i, uint64(m.FooterSize))
 		i--
@@ -2079,7 +2733,7 @@ func (m *SearchTagValuesResponse) MarshalToSizedBuffer(dAtA []byte) (int, error)
 	return len(dAtA) - i, nil
 }
 
-func (m *Trace) Marshal() (dAtA []byte, err error) {
+func (m *SearchTagValuesV2Response) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -2089,20 +2743,32 @@ func (m *Trace) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Trace) MarshalTo(dAtA []byte) (int, error) {
+func (m *SearchTagValuesV2Response) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *Trace) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *SearchTagValuesV2Response) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if len(m.Batches) > 0 {
-		for iNdEx := len(m.Batches) - 1; iNdEx >= 0; iNdEx-- {
+	if m.Numeric != nil {
+		{
+			size, err := m.Numeric.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTempo(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.TagValues) > 0 {
+		for iNdEx := len(m.TagValues) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size, err := m.Batches[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				size, err := m.TagValues[iNdEx].MarshalToSizedBuffer(dAtA[:i])
 				if err != nil {
 					return 0, err
 				}
@@ -2116,7 +2782,7 @@ func (m *Trace) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *PushResponse) Marshal() (dAtA []byte, err error) {
+func (m *TagValue) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -2126,20 +2792,39 @@ func (m *PushResponse) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *PushResponse) MarshalTo(dAtA []byte) (int, error) {
+func (m *TagValue) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *PushResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *TagValue) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
+	if m.Count != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.Count))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Value) > 0 {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintTempo(dAtA, i, uint64(len(m.Value)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Type) > 0 {
+		i -= len(m.Type)
+		copy(dAtA[i:], m.Type)
+		i = encodeVarintTempo(dAtA, i, uint64(len(m.Type)))
+		i--
+		dAtA[i] = 0xa
+	}
 	return len(dAtA) - i, nil
 }
 
-func (m *PushBytesRequest) Marshal() (dAtA []byte, err error) {
+func (m *NumericTagValuesSummary) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -2149,62 +2834,51 @@ func (m *PushBytesRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *PushBytesRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *NumericTagValuesSummary) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *PushBytesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *NumericTagValuesSummary) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if len(m.SearchData) > 0 {
-		for iNdEx := len(m.SearchData) - 1; iNdEx >= 0; iNdEx-- {
+	if len(m.Histogram) > 0 {
+		for iNdEx := len(m.Histogram) - 1; iNdEx >= 0; iNdEx-- {
 			{
-				size := m.SearchData[iNdEx].Size()
-				i -= size
-				if _, err := m.SearchData[iNdEx].MarshalTo(dAtA[i:]); err != nil {
+				size, err := m.Histogram[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
 					return 0, err
 				}
+				i -= size
 				i = encodeVarintTempo(dAtA, i, uint64(size))
 			}
 			i--
 			dAtA[i] = 0x22
 		}
 	}
-	if len(m.Ids) > 0 {
-		for iNdEx := len(m.Ids) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size := m.Ids[iNdEx].Size()
-				i -= size
-				if _, err := m.Ids[iNdEx].MarshalTo(dAtA[i:]); err != nil {
-					return 0, err
-				}
-				i = encodeVarintTempo(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x1a
-		}
+	if m.Count != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.Count))
+		i--
+		dAtA[i] = 0x18
 	}
-	if len(m.Traces) > 0 {
-		for iNdEx := len(m.Traces) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size := m.Traces[iNdEx].Size()
-				i -= size
-				if _, err := m.Traces[iNdEx].MarshalTo(dAtA[i:]); err != nil {
-					return 0, err
-				}
-				i = encodeVarintTempo(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0x12
-		}
+	if m.Max != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Max))))
+		i--
+		dAtA[i] = 0x11
+	}
+	if m.Min != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.Min))))
+		i--
+		dAtA[i] = 0x9
 	}
 	return len(dAtA) - i, nil
 }
 
-func (m *PushSpansRequest) Marshal() (dAtA []byte, err error) {
+func (m *TagValuesHistogramBucket) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -2214,12 +2888,52 @@ func (m *PushSpansRequest) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *PushSpansRequest) MarshalTo(dAtA []byte) (int, error) {
+func (m *TagValuesHistogramBucket) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *PushSpansRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *TagValuesHistogramBucket) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Count != 0 {
+		i = encodeVarintTempo(dAtA, i, uint64(m.Count))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.UpperBound != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.UpperBound))))
+		i--
+		dAtA[i] = 0x11
+	}
+	if m.LowerBound != 0 {
+		i -= 8
+		encoding_binary.LittleEndian.PutUint64(dAtA[i:], uint64(math.Float64bits(float64(m.LowerBound))))
+		i--
+		dAtA[i] = 0x9
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Trace) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Trace) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Trace) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
@@ -2241,7 +2955,7 @@ func (m *PushSpansRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
-func (m *TraceBytes) Marshal() (dAtA []byte, err error) {
+func (m *PushResponse) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalToSizedBuffer(dAtA[:size])
@@ -2251,35 +2965,160 @@ func (m *TraceBytes) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *TraceBytes) MarshalTo(dAtA []byte) (int, error) {
+func (m *PushResponse) MarshalTo(dAtA []byte) (int, error) {
 	size := m.Size()
 	return m.MarshalToSizedBuffer(dAtA[:size])
 }
 
-func (m *TraceBytes) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+func (m *PushResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	i := len(dAtA)
 	_ = i
 	var l int
 	_ = l
-	if len(m.Traces) > 0 {
-		for iNdEx := len(m.Traces) - 1; iNdEx >= 0; iNdEx-- {
-			i -= len(m.Traces[iNdEx])
-			copy(dAtA[i:], m.Traces[iNdEx])
-			i = encodeVarintTempo(dAtA, i, uint64(len(m.Traces[iNdEx])))
-			i--
-			dAtA[i] = 0xa
-		}
-	}
 	return len(dAtA) - i, nil
 }
 
-func encodeVarintTempo(dAtA []byte, offset int, v uint64) int {
-	offset -= sovTempo(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *PushBytesRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *PushBytesRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PushBytesRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.SearchData) > 0 {
+		for iNdEx := len(m.SearchData) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size := m.SearchData[iNdEx].Size()
+				i -= size
+				if _, err := m.SearchData[iNdEx].MarshalTo(dAtA[i:]); err != nil {
+					return 0, err
+				}
+				i = encodeVarintTempo(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x22
+		}
+	}
+	if len(m.Ids) > 0 {
+		for iNdEx := len(m.Ids) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size := m.Ids[iNdEx].Size()
+				i -= size
+				if _, err := m.Ids[iNdEx].MarshalTo(dAtA[i:]); err != nil {
+					return 0, err
+				}
+				i = encodeVarintTempo(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if len(m.Traces) > 0 {
+		for iNdEx := len(m.Traces) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size := m.Traces[iNdEx].Size()
+				i -= size
+				if _, err := m.Traces[iNdEx].MarshalTo(dAtA[i:]); err != nil {
+					return 0, err
+				}
+				i = encodeVarintTempo(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *PushSpansRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *PushSpansRequest) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PushSpansRequest) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Batches) > 0 {
+		for iNdEx := len(m.Batches) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Batches[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTempo(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *TraceBytes) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TraceBytes) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TraceBytes) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Traces) > 0 {
+		for iNdEx := len(m.Traces) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Traces[iNdEx])
+			copy(dAtA[i:], m.Traces[iNdEx])
+			i = encodeVarintTempo(dAtA, i, uint64(len(m.Traces[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintTempo(dAtA []byte, offset int, v uint64) int {
+	offset -= sovTempo(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
 	}
 	dAtA[offset] = uint8(v)
 	return base
@@ -2338,6 +3177,59 @@ func (m *TraceByIDMetrics) Size() (n int) {
 	return n
 }
 
+func (m *RecentTracesRequest) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Start != 0 {
+		n += 1 + sovTempo(uint64(m.Start))
+	}
+	if m.End != 0 {
+		n += 1 + sovTempo(uint64(m.End))
+	}
+	if m.MaxBytes != 0 {
+		n += 1 + sovTempo(uint64(m.MaxBytes))
+	}
+	return n
+}
+
+func (m *RecentTracesResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Traces) > 0 {
+		for _, e := range m.Traces {
+			l = e.Size()
+			n += 1 + l + sovTempo(uint64(l))
+		}
+	}
+	if m.Truncated {
+		n += 2
+	}
+	return n
+}
+
+func (m *RecentTrace) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.TraceID)
+	if l > 0 {
+		n += 1 + l + sovTempo(uint64(l))
+	}
+	if m.Trace != nil {
+		l = m.Trace.Size()
+		n += 1 + l + sovTempo(uint64(l))
+	}
+	return n
+}
+
 func (m *SearchRequest) Size() (n int) {
 	if m == nil {
 		return 0
@@ -2418,6 +3310,10 @@ func (m *SearchBlockRequest) Size() (n int) {
 	if m.FooterSize != 0 {
 		n += 1 + sovTempo(uint64(m.FooterSize))
 	}
+	l = len(m.IndexVersion)
+	if l > 0 {
+		n += 1 + l + sovTempo(uint64(l))
+	}
 	return n
 }
 
@@ -2546,6 +3442,87 @@ func (m *SearchTagValuesResponse) Size() (n int) {
 	return n
 }
 
+func (m *SearchTagValuesV2Response) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.TagValues) > 0 {
+		for _, e := range m.TagValues {
+			l = e.Size()
+			n += 1 + l + sovTempo(uint64(l))
+		}
+	}
+	if m.Numeric != nil {
+		l = m.Numeric.Size()
+		n += 1 + l + sovTempo(uint64(l))
+	}
+	return n
+}
+
+func (m *TagValue) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Type)
+	if l > 0 {
+		n += 1 + l + sovTempo(uint64(l))
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovTempo(uint64(l))
+	}
+	if m.Count != 0 {
+		n += 1 + sovTempo(uint64(m.Count))
+	}
+	return n
+}
+
+func (m *NumericTagValuesSummary) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Min != 0 {
+		n += 9
+	}
+	if m.Max != 0 {
+		n += 9
+	}
+	if m.Count != 0 {
+		n += 1 + sovTempo(uint64(m.Count))
+	}
+	if len(m.Histogram) > 0 {
+		for _, e := range m.Histogram {
+			l = e.Size()
+			n += 1 + l + sovTempo(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *TagValuesHistogramBucket) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.LowerBound != 0 {
+		n += 9
+	}
+	if m.UpperBound != 0 {
+		n += 9
+	}
+	if m.Count != 0 {
+		n += 1 + sovTempo(uint64(m.Count))
+	}
+	return n
+}
+
 func (m *Trace) Size() (n int) {
 	if m == nil {
 		return 0
@@ -3004,7 +3981,7 @@ func (m *TraceByIDMetrics) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SearchRequest) Unmarshal(dAtA []byte) error {
+func (m *RecentTracesRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3027,17 +4004,17 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SearchRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: RecentTracesRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SearchRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: RecentTracesRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Tags", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Start", wireType)
 			}
-			var msglen int
+			m.Start = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3047,13 +4024,648 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				m.Start |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthTempo
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field End", wireType)
+			}
+			m.End = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.End |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBytes", wireType)
+			}
+			m.MaxBytes = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxBytes |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTempo(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RecentTracesResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTempo
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RecentTracesResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RecentTracesResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Traces", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Traces = append(m.Traces, &RecentTrace{})
+			if err := m.Traces[len(m.Traces)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Truncated", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Truncated = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTempo(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RecentTrace) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTempo
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RecentTrace: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RecentTrace: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TraceID", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TraceID = append(m.TraceID[:0], dAtA[iNdEx:postIndex]...)
+			if m.TraceID == nil {
+				m.TraceID = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Trace", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Trace == nil {
+				m.Trace = &Trace{}
+			}
+			if err := m.Trace.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTempo(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SearchRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTempo
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SearchRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SearchRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tags", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Tags == nil {
+				m.Tags = make(map[string]string)
+			}
+			var mapkey string
+			var mapvalue string
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowTempo
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					var stringLenmapkey uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowTempo
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapkey |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapkey := int(stringLenmapkey)
+					if intStringLenmapkey < 0 {
+						return ErrInvalidLengthTempo
+					}
+					postStringIndexmapkey := iNdEx + intStringLenmapkey
+					if postStringIndexmapkey < 0 {
+						return ErrInvalidLengthTempo
+					}
+					if postStringIndexmapkey > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
+					iNdEx = postStringIndexmapkey
+				} else if fieldNum == 2 {
+					var stringLenmapvalue uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowTempo
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						stringLenmapvalue |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					intStringLenmapvalue := int(stringLenmapvalue)
+					if intStringLenmapvalue < 0 {
+						return ErrInvalidLengthTempo
+					}
+					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
+					if postStringIndexmapvalue < 0 {
+						return ErrInvalidLengthTempo
+					}
+					if postStringIndexmapvalue > l {
+						return io.ErrUnexpectedEOF
+					}
+					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
+					iNdEx = postStringIndexmapvalue
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipTempo(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if (skippy < 0) || (iNdEx+skippy) < 0 {
+						return ErrInvalidLengthTempo
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.Tags[mapkey] = mapvalue
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MinDurationMs", wireType)
+			}
+			m.MinDurationMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MinDurationMs |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxDurationMs", wireType)
+			}
+			m.MaxDurationMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxDurationMs |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+			}
+			m.Limit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Limit |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Start", wireType)
+			}
+			m.Start = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Start |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field End", wireType)
+			}
+			m.End = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.End |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Query", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Query = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTempo(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTempo
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SearchBlockRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SearchBlockRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SearchReq", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTempo
 			}
 			postIndex := iNdEx + msglen
 			if postIndex < 0 {
@@ -3062,109 +4674,190 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Tags == nil {
-				m.Tags = make(map[string]string)
-			}
-			var mapkey string
-			var mapvalue string
-			for iNdEx < postIndex {
-				entryPreIndex := iNdEx
-				var wire uint64
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowTempo
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					wire |= uint64(b&0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				fieldNum := int32(wire >> 3)
-				if fieldNum == 1 {
-					var stringLenmapkey uint64
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowTempo
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						stringLenmapkey |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					intStringLenmapkey := int(stringLenmapkey)
-					if intStringLenmapkey < 0 {
-						return ErrInvalidLengthTempo
-					}
-					postStringIndexmapkey := iNdEx + intStringLenmapkey
-					if postStringIndexmapkey < 0 {
-						return ErrInvalidLengthTempo
-					}
-					if postStringIndexmapkey > l {
-						return io.ErrUnexpectedEOF
-					}
-					mapkey = string(dAtA[iNdEx:postStringIndexmapkey])
-					iNdEx = postStringIndexmapkey
-				} else if fieldNum == 2 {
-					var stringLenmapvalue uint64
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowTempo
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						stringLenmapvalue |= uint64(b&0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					intStringLenmapvalue := int(stringLenmapvalue)
-					if intStringLenmapvalue < 0 {
-						return ErrInvalidLengthTempo
-					}
-					postStringIndexmapvalue := iNdEx + intStringLenmapvalue
-					if postStringIndexmapvalue < 0 {
-						return ErrInvalidLengthTempo
-					}
-					if postStringIndexmapvalue > l {
-						return io.ErrUnexpectedEOF
-					}
-					mapvalue = string(dAtA[iNdEx:postStringIndexmapvalue])
-					iNdEx = postStringIndexmapvalue
-				} else {
-					iNdEx = entryPreIndex
-					skippy, err := skipTempo(dAtA[iNdEx:])
-					if err != nil {
-						return err
-					}
-					if (skippy < 0) || (iNdEx+skippy) < 0 {
-						return ErrInvalidLengthTempo
-					}
-					if (iNdEx + skippy) > postIndex {
-						return io.ErrUnexpectedEOF
-					}
-					iNdEx += skippy
-				}
-			}
-			m.Tags[mapkey] = mapvalue
+			if m.SearchReq == nil {
+				m.SearchReq = &SearchRequest{}
+			}
+			if err := m.SearchReq.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockID", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BlockID = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StartPage", wireType)
+			}
+			m.StartPage = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StartPage |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PagesToSearch", wireType)
+			}
+			m.PagesToSearch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PagesToSearch |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Encoding", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Encoding = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IndexPageSize", wireType)
+			}
+			m.IndexPageSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.IndexPageSize |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TotalRecords", wireType)
+			}
+			m.TotalRecords = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.TotalRecords |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DataEncoding", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DataEncoding = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MinDurationMs", wireType)
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
 			}
-			m.MinDurationMs = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3174,16 +4867,29 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MinDurationMs |= uint32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Version = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 10:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field MaxDurationMs", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
 			}
-			m.MaxDurationMs = 0
+			m.Size_ = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3193,16 +4899,16 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.MaxDurationMs |= uint32(b&0x7F) << shift
+				m.Size_ |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 4:
+		case 11:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Limit", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field FooterSize", wireType)
 			}
-			m.Limit = 0
+			m.FooterSize = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3212,16 +4918,16 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Limit |= uint32(b&0x7F) << shift
+				m.FooterSize |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Start", wireType)
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field IndexVersion", wireType)
 			}
-			m.Start = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3231,16 +4937,79 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Start |= uint32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field End", wireType)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
 			}
-			m.End = 0
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.IndexVersion = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTempo(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SearchResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTempo
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SearchResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SearchResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Traces", wireType)
+			}
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3250,16 +5019,31 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.End |= uint32(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 8:
+			if msglen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Traces = append(m.Traces, &TraceSearchMetadata{})
+			if err := m.Traces[len(m.Traces)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Query", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Metrics", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3269,23 +5053,27 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthTempo
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthTempo
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Query = string(dAtA[iNdEx:postIndex])
+			if m.Metrics == nil {
+				m.Metrics = &SearchMetrics{}
+			}
+			if err := m.Metrics.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -3308,7 +5096,7 @@ func (m *SearchRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
+func (m *TraceSearchMetadata) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3331,17 +5119,17 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SearchBlockRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: TraceSearchMetadata: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SearchBlockRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: TraceSearchMetadata: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SearchReq", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TraceID", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3351,31 +5139,27 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthTempo
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthTempo
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.SearchReq == nil {
-				m.SearchReq = &SearchRequest{}
-			}
-			if err := m.SearchReq.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.TraceID = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field BlockID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field RootServiceName", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -3403,13 +5187,13 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.BlockID = string(dAtA[iNdEx:postIndex])
+			m.RootServiceName = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field StartPage", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RootTraceName", wireType)
 			}
-			m.StartPage = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3419,16 +5203,29 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.StartPage |= uint32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RootTraceName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 4:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PagesToSearch", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field StartTimeUnixNano", wireType)
 			}
-			m.PagesToSearch = 0
+			m.StartTimeUnixNano = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3438,16 +5235,16 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.PagesToSearch |= uint32(b&0x7F) << shift
+				m.StartTimeUnixNano |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Encoding", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DurationMs", wireType)
 			}
-			var stringLen uint64
+			m.DurationMs = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3457,29 +5254,66 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.DurationMs |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthTempo
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTempo(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthTempo
 			}
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Encoding = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 6:
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SearchMetrics) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTempo
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SearchMetrics: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SearchMetrics: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field IndexPageSize", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field InspectedTraces", wireType)
 			}
-			m.IndexPageSize = 0
+			m.InspectedTraces = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3489,16 +5323,16 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.IndexPageSize |= uint32(b&0x7F) << shift
+				m.InspectedTraces |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 7:
+		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TotalRecords", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field InspectedBytes", wireType)
 			}
-			m.TotalRecords = 0
+			m.InspectedBytes = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3508,16 +5342,16 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.TotalRecords |= uint32(b&0x7F) << shift
+				m.InspectedBytes |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 8:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DataEncoding", wireType)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InspectedBlocks", wireType)
 			}
-			var stringLen uint64
+			m.InspectedBlocks = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3527,29 +5361,16 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.InspectedBlocks |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthTempo
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthTempo
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.DataEncoding = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 9:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SkippedBlocks", wireType)
 			}
-			var stringLen uint64
+			m.SkippedBlocks = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3559,29 +5380,16 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.SkippedBlocks |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthTempo
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthTempo
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Version = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 10:
+		case 5:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field SkippedTraces", wireType)
 			}
-			m.Size_ = 0
+			m.SkippedTraces = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3591,16 +5399,16 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Size_ |= uint64(b&0x7F) << shift
+				m.SkippedTraces |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 11:
+		case 6:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field FooterSize", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TotalBlockBytes", wireType)
 			}
-			m.FooterSize = 0
+			m.TotalBlockBytes = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3610,7 +5418,7 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.FooterSize |= uint32(b&0x7F) << shift
+				m.TotalBlockBytes |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3636,7 +5444,57 @@ func (m *SearchBlockRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SearchResponse) Unmarshal(dAtA []byte) error {
+func (m *SearchTagsRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTempo
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SearchTagsRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SearchTagsRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTempo(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SearchTagsResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3659,51 +5517,17 @@ func (m *SearchResponse) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SearchResponse: wiretype end group for non-group")
+			return fmt.Errorf("proto: SearchTagsResponse: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SearchResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: SearchTagsResponse: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Traces", wireType)
-			}
-			var msglen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				msglen |= int(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			if msglen < 0 {
-				return ErrInvalidLengthTempo
-			}
-			postIndex := iNdEx + msglen
-			if postIndex < 0 {
-				return ErrInvalidLengthTempo
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Traces = append(m.Traces, &TraceSearchMetadata{})
-			if err := m.Traces[len(m.Traces)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Metrics", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TagNames", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -3713,27 +5537,23 @@ func (m *SearchResponse) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthTempo
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthTempo
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Metrics == nil {
-				m.Metrics = &SearchMetrics{}
-			}
-			if err := m.Metrics.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.TagNames = append(m.TagNames, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -3756,7 +5576,7 @@ func (m *SearchResponse) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *TraceSearchMetadata) Unmarshal(dAtA []byte) error {
+func (m *SearchTagValuesRequest) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3779,79 +5599,15 @@ func (m *TraceSearchMetadata) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: TraceSearchMetadata: wiretype end group for non-group")
+			return fmt.Errorf("proto: SearchTagValuesRequest: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: TraceSearchMetadata: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: SearchTagValuesRequest: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TraceID", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthTempo
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthTempo
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.TraceID = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field RootServiceName", wireType)
-			}
-			var stringLen uint64
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthTempo
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthTempo
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.RootServiceName = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field RootTraceName", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field TagName", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -3879,46 +5635,8 @@ func (m *TraceSearchMetadata) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.RootTraceName = string(dAtA[iNdEx:postIndex])
+			m.TagName = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field StartTimeUnixNano", wireType)
-			}
-			m.StartTimeUnixNano = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.StartTimeUnixNano |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DurationMs", wireType)
-			}
-			m.DurationMs = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.DurationMs |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTempo(dAtA[iNdEx:])
@@ -3940,7 +5658,7 @@ func (m *TraceSearchMetadata) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SearchMetrics) Unmarshal(dAtA []byte) error {
+func (m *SearchTagValuesResponse) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3959,78 +5677,21 @@ func (m *SearchMetrics) Unmarshal(dAtA []byte) error {
 			if b < 0x80 {
 				break
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: SearchMetrics: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SearchMetrics: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InspectedTraces", wireType)
-			}
-			m.InspectedTraces = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.InspectedTraces |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InspectedBytes", wireType)
-			}
-			m.InspectedBytes = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.InspectedBytes |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InspectedBlocks", wireType)
-			}
-			m.InspectedBlocks = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.InspectedBlocks |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SkippedBlocks", wireType)
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SearchTagValuesResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SearchTagValuesResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TagValues", wireType)
 			}
-			m.SkippedBlocks = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -4040,49 +5701,24 @@ func (m *SearchMetrics) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.SkippedBlocks |= uint32(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 5:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SkippedTraces", wireType)
-			}
-			m.SkippedTraces = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.SkippedTraces |= uint32(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
 			}
-		case 6:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TotalBlockBytes", wireType)
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
 			}
-			m.TotalBlockBytes = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowTempo
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.TotalBlockBytes |= uint64(b&0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
 			}
+			m.TagValues = append(m.TagValues, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTempo(dAtA[iNdEx:])
@@ -4104,7 +5740,7 @@ func (m *SearchMetrics) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SearchTagsRequest) Unmarshal(dAtA []byte) error {
+func (m *SearchTagValuesV2Response) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4127,12 +5763,82 @@ func (m *SearchTagsRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SearchTagsRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: SearchTagValuesV2Response: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SearchTagsRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: SearchTagValuesV2Response: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TagValues", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TagValues = append(m.TagValues, &TagValue{})
+			if err := m.TagValues[len(m.TagValues)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Numeric", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Numeric == nil {
+				m.Numeric = &NumericTagValuesSummary{}
+			}
+			if err := m.Numeric.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTempo(dAtA[iNdEx:])
@@ -4154,7 +5860,7 @@ func (m *SearchTagsRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SearchTagsResponse) Unmarshal(dAtA []byte) error {
+func (m *TagValue) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4177,15 +5883,15 @@ func (m *SearchTagsResponse) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SearchTagsResponse: wiretype end group for non-group")
+			return fmt.Errorf("proto: TagValue: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SearchTagsResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: TagValue: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TagNames", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -4213,8 +5919,59 @@ func (m *SearchTagsResponse) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.TagNames = append(m.TagNames, string(dAtA[iNdEx:postIndex]))
+			m.Type = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTempo
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTempo
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTempo(dAtA[iNdEx:])
@@ -4236,7 +5993,7 @@ func (m *SearchTagsResponse) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SearchTagValuesRequest) Unmarshal(dAtA []byte) error {
+func (m *NumericTagValuesSummary) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4259,17 +6016,58 @@ func (m *SearchTagValuesRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SearchTagValuesRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: NumericTagValuesSummary: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SearchTagValuesRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: NumericTagValuesSummary: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Min", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Min = float64(math.Float64frombits(v))
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Max", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.Max = float64(math.Float64frombits(v))
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTempo
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TagName", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Histogram", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -4279,23 +6077,25 @@ func (m *SearchTagValuesRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthTempo
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthTempo
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.TagName = string(dAtA[iNdEx:postIndex])
+			m.Histogram = append(m.Histogram, &TagValuesHistogramBucket{})
+			if err := m.Histogram[len(m.Histogram)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -4318,7 +6118,7 @@ func (m *SearchTagValuesRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *SearchTagValuesResponse) Unmarshal(dAtA []byte) error {
+func (m *TagValuesHistogramBucket) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -4341,17 +6141,39 @@ func (m *SearchTagValuesResponse) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: SearchTagValuesResponse: wiretype end group for non-group")
+			return fmt.Errorf("proto: TagValuesHistogramBucket: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: SearchTagValuesResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: TagValuesHistogramBucket: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field TagValues", wireType)
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LowerBound", wireType)
 			}
-			var stringLen uint64
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.LowerBound = float64(math.Float64frombits(v))
+		case 2:
+			if wireType != 1 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UpperBound", wireType)
+			}
+			var v uint64
+			if (iNdEx + 8) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint64(encoding_binary.LittleEndian.Uint64(dAtA[iNdEx:]))
+			iNdEx += 8
+			m.UpperBound = float64(math.Float64frombits(v))
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
+			}
+			m.Count = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTempo
@@ -4361,24 +6183,11 @@ func (m *SearchTagValuesResponse) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				m.Count |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
-				return ErrInvalidLengthTempo
-			}
-			postIndex := iNdEx + intStringLen
-			if postIndex < 0 {
-				return ErrInvalidLengthTempo
-			}
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.TagValues = append(m.TagValues, string(dAtA[iNdEx:postIndex]))
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTempo(dAtA[iNdEx:])
@@ -16,6 +16,9 @@ type typedExpression interface {
 
 type RootExpr struct {
 	Pipeline Pipeline
+	// Since is the relative time range of the query, i.e. `{ .a } since 2h`, or 0. Callers translate it to the
+	// start and end of the request.
+	Since time.Duration
 }
 
 func newRootExpr(e Element) *RootExpr {
@@ -276,6 +279,51 @@ func (o UnaryOperation) referencesSpan() bool {
 	return o.Expression.referencesSpan()
 }
 
+// CoalesceExpression resolves to the first of its expressions that has a value, e.g. to fall back to other
+// attributes when services name the same attribute differently. It is not the same as the coalesce() pipeline
+// operation.
+type CoalesceExpression struct {
+	Expressions []FieldExpression
+}
+
+func newCoalesceExpression(e []FieldExpression) CoalesceExpression {
+	return CoalesceExpression{
+		Expressions: e,
+	}
+}
+
+// newDefaultExpression creates the coalesce expression for lhs default rhs. Chained defaults are flattened into
+// a single coalesce expression.
+func newDefaultExpression(lhs FieldExpression, rhs FieldExpression) CoalesceExpression {
+	if c, ok := lhs.(CoalesceExpression); ok {
+		return newCoalesceExpression(append(c.Expressions, rhs))
+	}
+	return newCoalesceExpression([]FieldExpression{lhs, rhs})
+}
+
+// nolint: revive
+func (CoalesceExpression) __fieldExpression() {}
+
+func (c CoalesceExpression) impliedType() StaticType {
+	// the type of the first expression whose type is known
+	for _, e := range c.Expressions {
+		if t := e.impliedType(); t != TypeAttribute && t != TypeNil {
+			return t
+		}
+	}
+
+	return TypeAttribute
+}
+
+func (c CoalesceExpression) referencesSpan() bool {
+	for _, e := range c.Expressions {
+		if e.referencesSpan() {
+			return true
+		}
+	}
+	return false
+}
+
 // **********************
 // Statics
 // **********************
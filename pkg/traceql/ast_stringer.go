@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func (r RootExpr) String() string {
+	if r.Since > 0 {
+		return r.Pipeline.String() + " since " + durationString(r.Since)
+	}
 	return r.Pipeline.String()
 }
 
@@ -58,6 +62,14 @@ func (o UnaryOperation) String() string {
 	return unaryOp(o.Op, o.Expression)
 }
 
+func (c CoalesceExpression) String() string {
+	s := make([]string, 0, len(c.Expressions))
+	for _, e := range c.Expressions {
+		s = append(s, e.String())
+	}
+	return "coalesce(" + strings.Join(s, ", ") + ")"
+}
+
 func (n Static) String() string {
 	switch n.Type {
 	case TypeInt:
@@ -71,7 +83,7 @@ func (n Static) String() string {
 	case TypeNil:
 		return "nil"
 	case TypeDuration:
-		return n.D.String()
+		return durationString(n.D)
 	case TypeStatus:
 		return n.Status.String()
 	}
@@ -79,6 +91,22 @@ func (n Static) String() string {
 	return fmt.Sprintf("static(%d)", n.Type)
 }
 
+// durationString formats the duration so it can be parsed again, the lexer doesn't accept fractions of durations
+func durationString(d time.Duration) string {
+	s := d.String()
+	if !strings.Contains(s, ".") {
+		return s
+	}
+
+	switch {
+	case d%time.Millisecond == 0:
+		return strconv.FormatInt(int64(d/time.Millisecond), 10) + "ms"
+	case d%time.Microsecond == 0:
+		return strconv.FormatInt(int64(d/time.Microsecond), 10) + "us"
+	}
+	return strconv.FormatInt(int64(d), 10) + "ns"
+}
+
 func (a Attribute) String() string {
 	scopes := []string{}
 	if a.Parent {
@@ -125,5 +153,13 @@ func wrapElement(e Element) string {
 	if ok {
 		return att.String()
 	}
+	c, ok := e.(CoalesceExpression)
+	if ok {
+		return c.String()
+	}
+	t, ok := e.(MetricsThreshold)
+	if ok {
+		return t.String()
+	}
 	return "(" + e.String() + ")"
 }
@@ -150,6 +150,27 @@ func (o UnaryOperation) validate() error {
 	return nil
 }
 
+func (c CoalesceExpression) validate() error {
+	if len(c.Expressions) < 2 {
+		return fmt.Errorf("coalesce requires at least two field expressions: %s", c.String())
+	}
+
+	t := c.impliedType()
+	for _, e := range c.Expressions {
+		if err := e.validate(); err != nil {
+			return err
+		}
+
+		// nil is allowed as a fallback of any type
+		et := e.impliedType()
+		if et != TypeNil && !t.isMatchingOperand(et) {
+			return fmt.Errorf("coalesce field expressions must resolve to the same type: %s", c.String())
+		}
+	}
+
+	return nil
+}
+
 func (n Static) validate() error {
 	return nil
 }
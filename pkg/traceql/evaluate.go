@@ -0,0 +1,389 @@
+package traceql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Span is a span a query is evaluated against.
+type Span interface {
+	// AttributeFor returns the value of the attribute or intrinsic for the span, false if the span doesn't have it.
+	// Values of attributes may be returned as strings whatever their type, they are converted to the type of the
+	// static they are compared with.
+	AttributeFor(Attribute) (Static, bool)
+}
+
+// SpanMatcher returns a function reporting whether a span matches the query. Queries are evaluated span by span,
+// so only queries made of spanset filters, optionally followed by a metrics aggregate, are supported. A span matches
+// if it matches all filters. Metrics thresholds have to be resolved first.
+func (r *RootExpr) SpanMatcher() (func(Span) bool, error) {
+	if len(r.MetricsThresholds()) > 0 {
+		return nil, errors.New("metrics thresholds must be resolved before spans are matched")
+	}
+
+	var filters []FieldExpression
+	for _, e := range r.Pipeline.Elements {
+		switch o := e.(type) {
+		case SpansetFilter:
+			filters = append(filters, o.Expression)
+		case MetricsAggregate:
+		default:
+			return nil, fmt.Errorf("only spanset filters can be evaluated span by span: %s", e.String())
+		}
+	}
+
+	ev := &evaluator{regexps: map[string]*regexp.Regexp{}}
+	for _, f := range filters {
+		if err := ev.compileRegexps(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(s Span) bool {
+		for _, f := range filters {
+			if v := ev.evaluate(f, s); v.Type != TypeBoolean || !v.B {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// MetricsAggregate returns the metrics stage of the query, false if the query has none.
+func (r *RootExpr) MetricsAggregate() (MetricsAggregate, bool) {
+	if len(r.Pipeline.Elements) == 0 {
+		return MetricsAggregate{}, false
+	}
+	agg, ok := r.Pipeline.Elements[len(r.Pipeline.Elements)-1].(MetricsAggregate)
+	return agg, ok
+}
+
+// Attributes returns the attributes and intrinsics referenced by the query, including the ones of its thresholds
+// and metrics stage, in the order they appear.
+func (r *RootExpr) Attributes() []Attribute {
+	var (
+		seen       = map[Attribute]struct{}{}
+		attributes []Attribute
+	)
+	add := func(a Attribute) {
+		if _, ok := seen[a]; !ok {
+			seen[a] = struct{}{}
+			attributes = append(attributes, a)
+		}
+	}
+
+	_, _ = r.Pipeline.mapFieldExpressions(func(e FieldExpression) (FieldExpression, error) {
+		return transformFieldExpression(e, func(e FieldExpression) FieldExpression {
+			switch o := e.(type) {
+			case Attribute:
+				add(o)
+			case MetricsThreshold:
+				add(o.Field)
+				if o.By != nil {
+					add(*o.By)
+				}
+			}
+			return e
+		}), nil
+	})
+	if agg, ok := r.MetricsAggregate(); ok && !agg.CountsSpans() {
+		add(agg.Field)
+	}
+
+	return attributes
+}
+
+// NumericValue returns the value of a static as a number, durations are in seconds. Strings are parsed, false is
+// returned for statics that aren't numbers.
+func NumericValue(s Static) (float64, bool) {
+	switch s.Type {
+	case TypeInt:
+		return float64(s.N), true
+	case TypeFloat:
+		return s.F, true
+	case TypeDuration:
+		return s.D.Seconds(), true
+	case TypeString:
+		f, err := strconv.ParseFloat(s.S, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// evaluator evaluates field expressions against spans. It is safe for concurrent use once the regular expressions
+// are compiled.
+type evaluator struct {
+	regexps map[string]*regexp.Regexp
+}
+
+// compileRegexps compiles the static patterns of the regular expression operations of the expression
+func (ev *evaluator) compileRegexps(e FieldExpression) error {
+	var err error
+	transformFieldExpression(e, func(e FieldExpression) FieldExpression {
+		o, ok := e.(BinaryOperation)
+		if !ok || (o.Op != OpRegex && o.Op != OpNotRegex) {
+			return e
+		}
+		pattern, ok := o.RHS.(Static)
+		if !ok || pattern.Type != TypeString {
+			return e
+		}
+		if _, ok := ev.regexps[pattern.S]; ok {
+			return e
+		}
+		re, compileErr := regexp.Compile(pattern.S)
+		if compileErr != nil && err == nil {
+			err = fmt.Errorf("invalid regular expression %s: %w", pattern.S, compileErr)
+		}
+		ev.regexps[pattern.S] = re
+		return e
+	})
+	return err
+}
+
+func (ev *evaluator) evaluate(e FieldExpression, s Span) Static {
+	switch o := e.(type) {
+	case Static:
+		return o
+	case Attribute:
+		if v, ok := s.AttributeFor(o); ok {
+			return v
+		}
+	case CoalesceExpression:
+		for _, c := range o.Expressions {
+			if v := ev.evaluate(c, s); v.Type != TypeNil {
+				return v
+			}
+		}
+	case UnaryOperation:
+		return ev.unary(o, s)
+	case BinaryOperation:
+		return ev.binary(o, s)
+	}
+
+	return newStaticNil()
+}
+
+func (ev *evaluator) unary(o UnaryOperation, s Span) Static {
+	v := ev.evaluate(o.Expression, s)
+	switch {
+	case o.Op == OpNot && v.Type == TypeBoolean:
+		return newStaticBool(!v.B)
+	case o.Op == OpSub && v.Type == TypeInt:
+		return newStaticInt(-v.N)
+	case o.Op == OpSub && v.Type == TypeDuration:
+		return newStaticDuration(-v.D)
+	case o.Op == OpSub:
+		if f, ok := NumericValue(v); ok {
+			return newStaticFloat(-f)
+		}
+	}
+	return newStaticNil()
+}
+
+func (ev *evaluator) binary(o BinaryOperation, s Span) Static {
+	// boolean operators short circuit
+	switch o.Op {
+	case OpAnd:
+		return newStaticBool(isTrue(ev.evaluate(o.LHS, s)) && isTrue(ev.evaluate(o.RHS, s)))
+	case OpOr:
+		return newStaticBool(isTrue(ev.evaluate(o.LHS, s)) || isTrue(ev.evaluate(o.RHS, s)))
+	}
+
+	lhs, rhs := ev.evaluate(o.LHS, s), ev.evaluate(o.RHS, s)
+	switch o.Op {
+	case OpRegex, OpNotRegex:
+		if lhs.Type != TypeString || rhs.Type != TypeString {
+			return newStaticBool(false)
+		}
+		re, ok := ev.regexps[rhs.S]
+		if !ok {
+			var err error
+			if re, err = regexp.Compile(rhs.S); err != nil {
+				return newStaticBool(false)
+			}
+		}
+		if re == nil {
+			return newStaticBool(false)
+		}
+		return newStaticBool(re.MatchString(lhs.S) == (o.Op == OpRegex))
+	case OpEqual, OpNotEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+		// spans without the attribute don't match any comparison
+		c, ok := compareStatics(lhs, rhs)
+		if !ok {
+			return newStaticBool(false)
+		}
+		switch o.Op {
+		case OpEqual:
+			return newStaticBool(c == 0)
+		case OpNotEqual:
+			return newStaticBool(c != 0)
+		case OpGreater:
+			return newStaticBool(c > 0)
+		case OpGreaterEqual:
+			return newStaticBool(c >= 0)
+		case OpLess:
+			return newStaticBool(c < 0)
+		default:
+			return newStaticBool(c <= 0)
+		}
+	}
+
+	return arithmetic(o.Op, lhs, rhs)
+}
+
+func isTrue(s Static) bool {
+	return s.Type == TypeBoolean && s.B
+}
+
+// compareStatics compares the statics, false is returned if they can't be compared. Strings are converted to the
+// type of the other static. Booleans and statuses are only equal or not.
+func compareStatics(lhs, rhs Static) (int, bool) {
+	lhs, rhs = coerce(lhs, rhs.Type), coerce(rhs, lhs.Type)
+
+	switch {
+	case lhs.Type == TypeDuration && rhs.Type == TypeDuration:
+		return compareFloats(float64(lhs.D), float64(rhs.D)), true
+	case lhs.Type == TypeString && rhs.Type == TypeString:
+		return strings.Compare(lhs.S, rhs.S), true
+	case lhs.Type == TypeBoolean && rhs.Type == TypeBoolean:
+		return unequal(lhs.B != rhs.B), true
+	case lhs.Type == TypeStatus && rhs.Type == TypeStatus:
+		return unequal(lhs.Status != rhs.Status), true
+	case lhs.Type == TypeNil && rhs.Type == TypeNil:
+		return 0, true
+	case lhs.Type == TypeDuration || rhs.Type == TypeDuration:
+		return 0, false
+	}
+
+	l, lok := NumericValue(lhs)
+	r, rok := NumericValue(rhs)
+	if !lok || !rok || lhs.Type == TypeString || rhs.Type == TypeString {
+		return 0, false
+	}
+	return compareFloats(l, r), true
+}
+
+// coerce converts a string to the given type, other statics and strings that can't be converted are returned
+// unchanged.
+func coerce(s Static, t StaticType) Static {
+	if s.Type != TypeString {
+		return s
+	}
+
+	switch t {
+	case TypeInt:
+		if n, err := strconv.Atoi(s.S); err == nil {
+			return newStaticInt(n)
+		}
+		if f, err := strconv.ParseFloat(s.S, 64); err == nil {
+			return newStaticFloat(f)
+		}
+	case TypeFloat:
+		if f, err := strconv.ParseFloat(s.S, 64); err == nil {
+			return newStaticFloat(f)
+		}
+	case TypeBoolean:
+		if b, err := strconv.ParseBool(s.S); err == nil {
+			return newStaticBool(b)
+		}
+	case TypeDuration:
+		if d, err := parseDuration(s.S); err == nil {
+			return newStaticDuration(d)
+		}
+	}
+	return s
+}
+
+func compareFloats(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+func unequal(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// arithmetic applies the arithmetic operator, the result is nil if the statics aren't numbers. Durations can be
+// added to and subtracted from each other and scaled by numbers, all other operations on numbers except the ones on
+// integers result in a float.
+func arithmetic(op Operator, lhs, rhs Static) Static {
+	lhs, rhs = coerce(lhs, TypeFloat), coerce(rhs, TypeFloat)
+
+	switch {
+	case lhs.Type == TypeInt && rhs.Type == TypeInt && op != OpDiv && op != OpPower:
+		switch op {
+		case OpAdd:
+			return newStaticInt(lhs.N + rhs.N)
+		case OpSub:
+			return newStaticInt(lhs.N - rhs.N)
+		case OpMult:
+			return newStaticInt(lhs.N * rhs.N)
+		case OpMod:
+			if rhs.N == 0 {
+				return newStaticNil()
+			}
+			return newStaticInt(lhs.N % rhs.N)
+		}
+	case lhs.Type == TypeDuration && rhs.Type == TypeDuration && (op == OpAdd || op == OpSub):
+		if op == OpAdd {
+			return newStaticDuration(lhs.D + rhs.D)
+		}
+		return newStaticDuration(lhs.D - rhs.D)
+	case lhs.Type == TypeDuration && rhs.Type != TypeDuration && (op == OpMult || op == OpDiv):
+		f, ok := NumericValue(rhs)
+		if !ok || (op == OpDiv && f == 0) {
+			return newStaticNil()
+		}
+		if op == OpDiv {
+			f = 1 / f
+		}
+		return newStaticDuration(time.Duration(float64(lhs.D) * f))
+	case rhs.Type == TypeDuration && lhs.Type != TypeDuration && op == OpMult:
+		return arithmetic(op, rhs, lhs)
+	}
+
+	if (lhs.Type == TypeDuration) != (rhs.Type == TypeDuration) {
+		return newStaticNil()
+	}
+	l, lok := NumericValue(lhs)
+	r, rok := NumericValue(rhs)
+	if !lok || !rok {
+		return newStaticNil()
+	}
+
+	switch op {
+	case OpAdd:
+		return newStaticFloat(l + r)
+	case OpSub:
+		return newStaticFloat(l - r)
+	case OpMult:
+		return newStaticFloat(l * r)
+	case OpDiv:
+		if r == 0 {
+			return newStaticNil()
+		}
+		return newStaticFloat(l / r)
+	case OpMod:
+		if r == 0 {
+			return newStaticNil()
+		}
+		return newStaticFloat(math.Mod(l, r))
+	case OpPower:
+		return newStaticFloat(math.Pow(l, r))
+	}
+	return newStaticNil()
+}
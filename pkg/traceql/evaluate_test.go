@@ -0,0 +1,92 @@
+package traceql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapSpan map[Attribute]Static
+
+func (s mapSpan) AttributeFor(a Attribute) (Static, bool) {
+	v, ok := s[a]
+	return v, ok
+}
+
+func TestSpanMatcher(t *testing.T) {
+	span := mapSpan{
+		newIntrinsic(IntrinsicDuration):  newStaticDuration(1500 * time.Millisecond),
+		newIntrinsic(IntrinsicName):      newStaticString("GET /api"),
+		newIntrinsic(IntrinsicStatus):    newStaticStatus(StatusError),
+		newAttribute("http.status_code"): newStaticString("500"),
+		newAttribute("ratio"):            newStaticString("0.25"),
+		newAttribute("cached"):           newStaticString("true"),
+	}
+
+	tests := []struct {
+		query   string
+		matches bool
+	}{
+		{query: "{ duration > 1s }", matches: true},
+		{query: "{ duration > 2s }", matches: false},
+		{query: "{ duration * 2 = 3s }", matches: true},
+		{query: `{ name = "GET /api" && status = error }`, matches: true},
+		{query: `{ name =~ "GET .*" }`, matches: true},
+		{query: `{ name !~ "GET .*" }`, matches: false},
+		{query: "{ .http.status_code >= 500 }", matches: true},
+		{query: "{ .http.status_code + 1 = 501 }", matches: true},
+		{query: `{ .http.status_code = "500" }`, matches: true},
+		{query: "{ .ratio < 0.5 }", matches: true},
+		{query: "{ .cached = true }", matches: true},
+		{query: "{ .missing = 1 }", matches: false},
+		{query: "{ .missing != 1 }", matches: false},
+		{query: "{ .missing default .http.status_code = 500 }", matches: true},
+		{query: "{ .http.status_code = 500 || .missing = 1 }", matches: true},
+		{query: "{ !(.http.status_code = 500) }", matches: false},
+		{query: "{ .http.status_code = 500 } | { duration < 1s }", matches: false},
+		{query: "{ .http.status_code = 500 } | histogram_over_time(duration)", matches: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			expr, err := Parse(tc.query)
+			require.NoError(t, err)
+
+			match, err := expr.SpanMatcher()
+			require.NoError(t, err)
+			assert.Equal(t, tc.matches, match(span))
+		})
+	}
+}
+
+func TestSpanMatcherUnsupported(t *testing.T) {
+	for _, query := range []string{
+		"{ .a } && { .b }",
+		"{ .a } | count() > 1",
+		"{ .a } | by(.b)",
+		"{ duration > quantile_over_time(duration, 0.9) }",
+		`{ .a =~ "(" }`,
+	} {
+		t.Run(query, func(t *testing.T) {
+			expr, err := Parse(query)
+			require.NoError(t, err)
+
+			_, err = expr.SpanMatcher()
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRootExprAttributes(t *testing.T) {
+	expr, err := Parse("{ .a = 1 && duration > quantile_over_time(duration, 0.9) by(resource.b) } | { .a } | histogram_over_time(span.c)")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Attribute{
+		newAttribute("a"),
+		newIntrinsic(IntrinsicDuration),
+		newScopedAttribute(AttributeScopeResource, false, "b"),
+		newScopedAttribute(AttributeScopeSpan, false, "c"),
+	}, expr.Attributes())
+}
@@ -27,8 +27,11 @@ import (
     wrappedScalarPipeline Pipeline
     scalarPipeline Pipeline
     aggregate Aggregate
+    metricsAggregate MetricsAggregate
+    metricsThreshold MetricsThreshold
 
     fieldExpression FieldExpression
+    fieldExpressions []FieldExpression
     static Static
     intrinsicField Attribute
     attributeField Attribute
@@ -58,28 +61,36 @@ import (
 %type <wrappedScalarPipeline> wrappedScalarPipeline
 %type <scalarPipeline> scalarPipeline
 %type <aggregate> aggregate 
+%type <metricsAggregate> metricsAggregate
+%type <metricsThreshold> metricsThreshold
+%type <staticFloat> quantile
 
 %type <fieldExpression> fieldExpression
+%type <fieldExpressions> fieldExpressions
 %type <static> static
 %type <intrinsicField> intrinsicField
 %type <attributeField> attributeField
+%type <attributeField> metricsField
 
 %token <staticStr>      IDENTIFIER STRING
 %token <staticInt>      INTEGER
 %token <staticFloat>    FLOAT
 %token <staticDuration> DURATION
-%token <val>            DOT OPEN_BRACE CLOSE_BRACE OPEN_PARENS CLOSE_PARENS
+%token <val>            DOT OPEN_BRACE CLOSE_BRACE OPEN_PARENS CLOSE_PARENS COMMA
                         NIL TRUE FALSE STATUS_ERROR STATUS_OK STATUS_UNSET
                         IDURATION CHILDCOUNT NAME STATUS PARENT
                         PARENT_DOT RESOURCE_DOT SPAN_DOT
                         COUNT AVG MAX MIN SUM
                         BY COALESCE
+                        HISTOGRAM_OVER_TIME COUNT_OVER_TIME QUANTILE_OVER_TIME
+                        SINCE
                         END_ATTRIBUTE
 
 // Operators are listed with increasing precedence.
 %left <binOp> PIPE
 %left <binOp> AND OR
 %left <binOp> EQ NEQ LT LTE GT GTE NRE RE DESC TILDE
+%left <binOp> DEFAULT
 %left <binOp> ADD SUB
 %left <binOp> NOT
 %left <binOp> MUL DIV MOD
@@ -90,9 +101,15 @@ import (
 // Pipeline
 // **********************
 root:
+    query                                       {}
+  | query SINCE DURATION                        { yylex.(*lexer).setSince($3) }
+  ;
+
+query:
     spansetPipeline                             { yylex.(*lexer).expr = newRootExpr($1) }
   | spansetPipelineExpression                   { yylex.(*lexer).expr = newRootExpr($1) }
   | scalarPipelineExpressionFilter              { yylex.(*lexer).expr = newRootExpr($1) }
+  | spansetPipeline PIPE metricsAggregate       { yylex.(*lexer).expr = newRootExpr($1.addItem($3)) }
   ;
 
 // **********************
@@ -203,6 +220,31 @@ aggregate:
   | SUM OPEN_PARENS fieldExpression CLOSE_PARENS  { $$ = newAggregate(aggregateSum, $3) }
   ;
 
+// **********************
+// Metrics
+// **********************
+metricsAggregate:
+    HISTOGRAM_OVER_TIME OPEN_PARENS metricsField CLOSE_PARENS  { $$ = newMetricsAggregate(metricsAggregateHistogramOverTime, $3) }
+  | COUNT_OVER_TIME OPEN_PARENS CLOSE_PARENS                   { $$ = newMetricsAggregate(metricsAggregateCountOverTime, Attribute{}) }
+  ;
+
+metricsThreshold:
+    QUANTILE_OVER_TIME OPEN_PARENS metricsField COMMA quantile CLOSE_PARENS
+      { $$ = yylex.(*lexer).newMetricsThreshold($3, $5, nil) }
+  | QUANTILE_OVER_TIME OPEN_PARENS metricsField COMMA quantile CLOSE_PARENS BY OPEN_PARENS metricsField CLOSE_PARENS
+      { by := $9; $$ = yylex.(*lexer).newMetricsThreshold($3, $5, &by) }
+  ;
+
+quantile:
+    FLOAT                                    { $$ = $1 }
+  | INTEGER                                  { $$ = float64($1) }
+  ;
+
+metricsField:
+    intrinsicField                           { $$ = $1 }
+  | attributeField                           { $$ = $1 }
+  ;
+
 // **********************
 // FieldExpressions
 // **********************
@@ -224,13 +266,21 @@ fieldExpression:
   | fieldExpression POW fieldExpression      { $$ = newBinaryOperation(OpPower, $1, $3) }
   | fieldExpression AND fieldExpression      { $$ = newBinaryOperation(OpAnd, $1, $3) }
   | fieldExpression OR fieldExpression       { $$ = newBinaryOperation(OpOr, $1, $3) }
+  | fieldExpression DEFAULT fieldExpression  { $$ = newDefaultExpression($1, $3) }
   | SUB fieldExpression                      { $$ = newUnaryOperation(OpSub, $2) }
   | NOT fieldExpression                      { $$ = newUnaryOperation(OpNot, $2) }
+  | COALESCE OPEN_PARENS fieldExpressions CLOSE_PARENS { $$ = newCoalesceExpression($3) }
+  | metricsThreshold                         { $$ = $1 }
   | static                                   { $$ = $1 }
   | intrinsicField                           { $$ = $1 }
   | attributeField                           { $$ = $1 }
   ;
 
+fieldExpressions:
+    fieldExpression                          { $$ = []FieldExpression{$1} }
+  | fieldExpressions COMMA fieldExpression   { $$ = append($1, $3) }
+  ;
+
 // **********************
 // Statics
 // **********************
@@ -32,11 +32,14 @@ type yySymType struct {
 	wrappedScalarPipeline          Pipeline
 	scalarPipeline                 Pipeline
 	aggregate                      Aggregate
+	metricsAggregate               MetricsAggregate
+	metricsThreshold               MetricsThreshold
 
-	fieldExpression FieldExpression
-	static          Static
-	intrinsicField  Attribute
-	attributeField  Attribute
+	fieldExpression  FieldExpression
+	fieldExpressions []FieldExpression
+	static           Static
+	intrinsicField   Attribute
+	attributeField   Attribute
 
 	binOp          Operator
 	staticInt      int
@@ -55,48 +58,54 @@ const OPEN_BRACE = 57352
 const CLOSE_BRACE = 57353
 const OPEN_PARENS = 57354
 const CLOSE_PARENS = 57355
-const NIL = 57356
-const TRUE = 57357
-const FALSE = 57358
-const STATUS_ERROR = 57359
-const STATUS_OK = 57360
-const STATUS_UNSET = 57361
-const IDURATION = 57362
-const CHILDCOUNT = 57363
-const NAME = 57364
-const STATUS = 57365
-const PARENT = 57366
-const PARENT_DOT = 57367
-const RESOURCE_DOT = 57368
-const SPAN_DOT = 57369
-const COUNT = 57370
-const AVG = 57371
-const MAX = 57372
-const MIN = 57373
-const SUM = 57374
-const BY = 57375
-const COALESCE = 57376
-const END_ATTRIBUTE = 57377
-const PIPE = 57378
-const AND = 57379
-const OR = 57380
-const EQ = 57381
-const NEQ = 57382
-const LT = 57383
-const LTE = 57384
-const GT = 57385
-const GTE = 57386
-const NRE = 57387
-const RE = 57388
-const DESC = 57389
-const TILDE = 57390
-const ADD = 57391
-const SUB = 57392
-const NOT = 57393
-const MUL = 57394
-const DIV = 57395
-const MOD = 57396
-const POW = 57397
+const COMMA = 57356
+const NIL = 57357
+const TRUE = 57358
+const FALSE = 57359
+const STATUS_ERROR = 57360
+const STATUS_OK = 57361
+const STATUS_UNSET = 57362
+const IDURATION = 57363
+const CHILDCOUNT = 57364
+const NAME = 57365
+const STATUS = 57366
+const PARENT = 57367
+const PARENT_DOT = 57368
+const RESOURCE_DOT = 57369
+const SPAN_DOT = 57370
+const COUNT = 57371
+const AVG = 57372
+const MAX = 57373
+const MIN = 57374
+const SUM = 57375
+const BY = 57376
+const COALESCE = 57377
+const HISTOGRAM_OVER_TIME = 57378
+const COUNT_OVER_TIME = 57379
+const QUANTILE_OVER_TIME = 57380
+const SINCE = 57381
+const END_ATTRIBUTE = 57382
+const PIPE = 57383
+const AND = 57384
+const OR = 57385
+const EQ = 57386
+const NEQ = 57387
+const LT = 57388
+const LTE = 57389
+const GT = 57390
+const GTE = 57391
+const NRE = 57392
+const RE = 57393
+const DESC = 57394
+const TILDE = 57395
+const DEFAULT = 57396
+const ADD = 57397
+const SUB = 57398
+const NOT = 57399
+const MUL = 57400
+const DIV = 57401
+const MOD = 57402
+const POW = 57403
 
 var yyToknames = [...]string{
 	"$end",
@@ -112,6 +121,7 @@ var yyToknames = [...]string{
 	"CLOSE_BRACE",
 	"OPEN_PARENS",
 	"CLOSE_PARENS",
+	"COMMA",
 	"NIL",
 	"TRUE",
 	"FALSE",
@@ -133,6 +143,10 @@ var yyToknames = [...]string{
 	"SUM",
 	"BY",
 	"COALESCE",
+	"HISTOGRAM_OVER_TIME",
+	"COUNT_OVER_TIME",
+	"QUANTILE_OVER_TIME",
+	"SINCE",
 	"END_ATTRIBUTE",
 	"PIPE",
 	"AND",
@@ -147,6 +161,7 @@ var yyToknames = [...]string{
 	"RE",
 	"DESC",
 	"TILDE",
+	"DEFAULT",
 	"ADD",
 	"SUB",
 	"NOT",
@@ -155,6 +170,7 @@ var yyToknames = [...]string{
 	"MOD",
 	"POW",
 }
+
 var yyStatenames = [...]string{}
 
 const yyEofCode = 1
@@ -162,7 +178,7 @@ const yyErrCode = 2
 const yyInitialStackSize = 16
 
 //line yacctab:1
-var yyExca = [...]int{
+var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
@@ -170,195 +186,222 @@ var yyExca = [...]int{
 
 const yyPrivate = 57344
 
-const yyLast = 637
-
-var yyAct = [...]int{
-
-	75, 17, 6, 7, 5, 169, 2, 149, 12, 17,
-	69, 56, 111, 46, 45, 112, 33, 49, 116, 136,
-	137, 204, 138, 139, 140, 149, 64, 65, 203, 66,
-	67, 68, 69, 195, 17, 33, 92, 94, 93, 138,
-	139, 140, 149, 194, 104, 106, 107, 108, 109, 193,
-	192, 118, 202, 168, 64, 65, 71, 66, 67, 68,
-	69, 15, 161, 105, 17, 17, 17, 17, 17, 17,
-	17, 115, 126, 128, 129, 130, 131, 132, 133, 141,
-	142, 143, 144, 145, 146, 148, 147, 47, 10, 136,
-	137, 119, 138, 139, 140, 149, 99, 17, 197, 111,
-	17, 166, 91, 51, 52, 167, 53, 54, 55, 56,
-	166, 90, 89, 17, 40, 92, 94, 93, 41, 43,
-	17, 171, 112, 88, 87, 173, 201, 134, 17, 152,
-	153, 154, 66, 67, 68, 69, 167, 70, 117, 120,
-	121, 122, 123, 124, 125, 162, 163, 164, 165, 114,
-	150, 151, 141, 142, 143, 144, 145, 146, 148, 147,
-	158, 63, 136, 137, 196, 138, 139, 140, 149, 157,
-	156, 17, 50, 17, 155, 46, 77, 46, 173, 49,
-	76, 49, 159, 160, 16, 51, 52, 48, 53, 54,
-	55, 56, 14, 175, 176, 177, 178, 179, 180, 181,
-	182, 183, 184, 185, 186, 187, 188, 189, 190, 23,
-	24, 25, 29, 83, 4, 11, 72, 9, 28, 26,
-	27, 31, 30, 32, 78, 79, 80, 81, 82, 86,
-	84, 85, 200, 57, 58, 59, 60, 61, 62, 53,
-	54, 55, 56, 64, 65, 95, 66, 67, 68, 69,
-	1, 199, 0, 0, 73, 74, 150, 151, 141, 142,
-	143, 144, 145, 146, 148, 147, 0, 0, 136, 137,
-	198, 138, 139, 140, 149, 150, 151, 141, 142, 143,
-	144, 145, 146, 148, 147, 0, 0, 136, 137, 191,
-	138, 139, 140, 149, 150, 151, 141, 142, 143, 144,
-	145, 146, 148, 147, 0, 0, 136, 137, 174, 138,
-	139, 140, 149, 150, 151, 141, 142, 143, 144, 145,
-	146, 148, 147, 0, 0, 136, 137, 135, 138, 139,
-	140, 149, 150, 151, 141, 142, 143, 144, 145, 146,
-	148, 147, 0, 0, 136, 137, 116, 138, 139, 140,
-	149, 0, 0, 150, 151, 141, 142, 143, 144, 145,
-	146, 148, 147, 0, 0, 136, 137, 0, 138, 139,
-	140, 149, 57, 58, 59, 60, 61, 62, 0, 0,
-	0, 0, 64, 65, 113, 66, 67, 68, 69, 57,
-	58, 59, 60, 61, 62, 0, 110, 0, 0, 51,
-	52, 0, 53, 54, 55, 56, 39, 42, 39, 42,
-	0, 0, 40, 0, 40, 0, 41, 43, 41, 43,
-	34, 37, 0, 35, 0, 0, 35, 36, 38, 0,
-	36, 38, 23, 24, 25, 29, 0, 15, 0, 96,
-	0, 28, 26, 27, 31, 30, 32, 0, 0, 0,
-	0, 0, 0, 0, 0, 18, 21, 19, 20, 22,
-	13, 97, 34, 37, 0, 0, 0, 0, 35, 0,
-	0, 0, 36, 38, 23, 24, 25, 29, 0, 15,
-	0, 172, 0, 28, 26, 27, 31, 30, 32, 0,
-	0, 0, 0, 0, 0, 0, 0, 18, 21, 19,
-	20, 22, 13, 23, 24, 25, 29, 0, 15, 0,
-	170, 0, 28, 26, 27, 31, 30, 32, 0, 0,
-	0, 0, 0, 0, 0, 0, 18, 21, 19, 20,
-	22, 13, 23, 24, 25, 29, 0, 15, 0, 8,
-	0, 28, 26, 27, 31, 30, 32, 0, 0, 0,
-	0, 0, 0, 0, 0, 18, 21, 19, 20, 22,
-	13, 23, 24, 25, 29, 0, 15, 0, 96, 0,
-	28, 26, 27, 31, 30, 32, 0, 0, 0, 0,
-	0, 0, 44, 3, 18, 21, 19, 20, 22, 23,
-	24, 25, 29, 0, 0, 0, 127, 0, 28, 26,
-	27, 31, 30, 32, 0, 0, 0, 0, 0, 0,
-	0, 0, 18, 21, 19, 20, 22, 98, 100, 101,
-	102, 103, 23, 24, 25, 29, 0, 0, 0, 119,
-	0, 28, 26, 27, 31, 30, 32,
+const yyLast = 744
+
+var yyAct = [...]uint8{
+	73, 209, 81, 80, 7, 6, 183, 3, 145, 146,
+	158, 147, 148, 149, 158, 48, 47, 8, 159, 160,
+	150, 151, 152, 153, 154, 155, 157, 156, 71, 58,
+	161, 145, 146, 121, 147, 148, 149, 158, 35, 120,
+	99, 100, 147, 148, 149, 158, 120, 113, 115, 116,
+	117, 118, 230, 101, 150, 151, 152, 153, 154, 155,
+	157, 156, 79, 18, 161, 145, 146, 225, 147, 148,
+	149, 158, 18, 143, 121, 162, 163, 164, 161, 145,
+	146, 229, 147, 148, 149, 158, 215, 214, 59, 60,
+	61, 62, 63, 64, 174, 175, 176, 177, 18, 66,
+	67, 213, 68, 69, 70, 71, 212, 13, 42, 34,
+	237, 180, 43, 45, 228, 127, 240, 51, 66, 67,
+	180, 68, 69, 70, 71, 236, 99, 100, 18, 18,
+	18, 18, 18, 18, 18, 187, 231, 53, 54, 101,
+	55, 56, 57, 58, 226, 227, 189, 190, 191, 192,
+	193, 194, 195, 196, 197, 198, 199, 200, 201, 202,
+	203, 204, 205, 97, 224, 223, 208, 173, 18, 211,
+	210, 18, 124, 135, 137, 138, 139, 140, 141, 142,
+	222, 211, 210, 238, 18, 68, 69, 70, 71, 182,
+	48, 18, 48, 187, 55, 56, 57, 58, 37, 18,
+	179, 178, 38, 40, 24, 25, 26, 30, 88, 166,
+	165, 74, 170, 181, 29, 27, 28, 32, 31, 33,
+	83, 84, 85, 86, 87, 91, 89, 90, 232, 185,
+	99, 100, 221, 128, 77, 171, 172, 82, 217, 125,
+	239, 211, 210, 101, 181, 108, 16, 18, 114, 18,
+	96, 95, 94, 93, 92, 75, 76, 72, 46, 4,
+	65, 159, 160, 150, 151, 152, 153, 154, 155, 157,
+	156, 220, 52, 161, 145, 146, 216, 147, 148, 149,
+	158, 66, 67, 169, 68, 69, 70, 71, 18, 235,
+	234, 219, 51, 168, 51, 107, 109, 110, 111, 112,
+	159, 160, 150, 151, 152, 153, 154, 155, 157, 156,
+	167, 218, 161, 145, 146, 2, 147, 148, 149, 158,
+	159, 160, 150, 151, 152, 153, 154, 155, 157, 156,
+	207, 206, 161, 145, 146, 233, 147, 148, 149, 158,
+	159, 160, 150, 151, 152, 153, 154, 155, 157, 156,
+	78, 188, 161, 145, 146, 98, 147, 148, 149, 158,
+	159, 160, 150, 151, 152, 153, 154, 155, 157, 156,
+	144, 17, 161, 145, 146, 50, 147, 148, 149, 158,
+	159, 160, 150, 151, 152, 153, 154, 155, 157, 156,
+	125, 15, 161, 145, 146, 5, 147, 148, 149, 158,
+	12, 159, 160, 150, 151, 152, 153, 154, 155, 157,
+	156, 10, 102, 161, 145, 146, 123, 147, 148, 149,
+	158, 59, 60, 61, 62, 63, 64, 49, 11, 1,
+	0, 0, 66, 67, 0, 68, 69, 70, 71, 59,
+	60, 61, 62, 63, 64, 0, 0, 0, 0, 0,
+	53, 54, 0, 55, 56, 57, 58, 0, 53, 54,
+	122, 55, 56, 57, 58, 41, 44, 119, 36, 39,
+	0, 42, 0, 0, 37, 43, 45, 0, 38, 40,
+	126, 129, 130, 131, 132, 133, 134, 0, 0, 41,
+	44, 0, 0, 0, 0, 42, 36, 39, 0, 43,
+	45, 0, 37, 0, 0, 0, 38, 40, 24, 25,
+	26, 30, 0, 16, 0, 105, 0, 0, 29, 27,
+	28, 32, 31, 33, 0, 0, 0, 0, 0, 0,
+	0, 0, 19, 22, 20, 21, 23, 14, 106, 103,
+	104, 24, 25, 26, 30, 0, 16, 0, 105, 0,
+	0, 29, 27, 28, 32, 31, 33, 0, 0, 0,
+	0, 0, 0, 0, 0, 19, 22, 20, 21, 23,
+	14, 106, 24, 25, 26, 30, 0, 16, 0, 186,
+	0, 0, 29, 27, 28, 32, 31, 33, 0, 0,
+	0, 0, 0, 0, 0, 0, 19, 22, 20, 21,
+	23, 14, 24, 25, 26, 30, 0, 16, 0, 184,
+	0, 0, 29, 27, 28, 32, 31, 33, 0, 0,
+	0, 0, 0, 0, 0, 0, 19, 22, 20, 21,
+	23, 14, 24, 25, 26, 30, 0, 16, 0, 9,
+	0, 0, 29, 27, 28, 32, 31, 33, 0, 0,
+	0, 0, 0, 0, 0, 0, 19, 22, 20, 21,
+	23, 14, 24, 25, 26, 30, 0, 16, 0, 105,
+	0, 0, 29, 27, 28, 32, 31, 33, 0, 0,
+	0, 0, 0, 0, 0, 0, 19, 22, 20, 21,
+	23, 24, 25, 26, 30, 0, 0, 0, 136, 0,
+	0, 29, 27, 28, 32, 31, 33, 0, 88, 0,
+	0, 0, 0, 0, 0, 19, 22, 20, 21, 23,
+	83, 84, 85, 86, 87, 91, 89, 90, 24, 25,
+	26, 30, 0, 0, 0, 128, 0, 0, 29, 27,
+	28, 32, 31, 33,
 }
-var yyPact = [...]int{
-
-	527, -1000, -20, 425, -1000, 369, -1000, -1000, 527, -1000,
-	350, -1000, 194, 125, -1000, 204, -1000, -1000, 112, 111,
-	100, 99, 90, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, 427, 84, 84, 84, 84, 84, 51,
-	51, 51, 51, 51, 383, 86, 371, 136, 58, 333,
-	617, 79, 79, 79, 79, 79, 79, -1000, -1000, -1000,
-	-1000, -1000, -1000, 584, 584, 584, 584, 584, 584, 584,
-	204, 316, 204, 204, 204, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, 170, 166, 165, 156, 49, 204, 204,
-	204, 204, -1000, 369, -1000, -1000, 556, 41, 380, 498,
-	-1000, -1000, 380, -1000, 71, 51, -1000, -1000, 71, -1000,
-	-1000, -1000, 427, -1000, -1000, -1000, -1000, 54, -1000, 469,
-	187, 187, -44, -44, -44, -44, -23, 584, 80, 80,
-	-45, -45, -45, -45, 295, -1000, 204, 204, 204, 204,
-	204, 204, 204, 204, 204, 204, 204, 204, 204, 204,
-	204, 204, 276, -13, -13, 15, 14, 8, -2, 160,
-	94, -1000, 257, 238, 219, 113, 371, 5, 39, -1,
-	498, 194, 469, -21, -1000, -13, -13, -48, -48, -48,
-	-30, -30, -30, -30, -30, -30, -30, -30, -48, 40,
-	40, -1000, -1000, -1000, -1000, -1000, -7, -14, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000,
+
+var yyPact = [...]int16{
+	627, -1000, 70, -3, 426, -1000, 423, -1000, -1000, 627,
+	-1000, 395, -1000, 44, 245, -1000, 199, -1000, -1000, 242,
+	241, 240, 239, 238, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, 155, 503, 233, 233, 233, 233,
+	233, 236, 236, 236, 236, 236, 454, 33, 447, 403,
+	159, 377, 723, 221, 221, 221, 221, 221, 221, -1000,
+	-1000, -1000, -1000, -1000, -1000, 686, 686, 686, 686, 686,
+	686, 686, 199, 359, 199, 199, 199, 198, -1000, -1000,
+	-1000, -1000, 197, -1000, -1000, -1000, -1000, -1000, 306, 289,
+	279, 208, 154, 199, 199, 199, 199, -1000, -1000, -1000,
+	423, -1000, -1000, 189, 188, 657, 177, 150, 597, -1000,
+	-1000, 150, -1000, 60, 236, -1000, -1000, 60, -1000, -1000,
+	-1000, 536, -1000, -1000, -1000, -1000, 82, -1000, 567, 136,
+	136, -32, -32, -32, -32, 63, 686, 127, 127, -33,
+	-33, -33, -33, 338, -1000, 199, 199, 199, 199, 199,
+	199, 199, 199, 199, 199, 199, 199, 199, 199, 199,
+	199, 199, 318, -16, -16, 199, 699, 66, 61, 47,
+	46, 272, 234, -1000, 298, 278, 258, 219, 699, 152,
+	447, 226, 151, 26, 597, 44, 567, -8, -1000, -16,
+	-16, -51, -51, -51, 24, 24, 24, 24, 24, 24,
+	24, 24, -51, 10, 10, -47, -1000, 131, -24, 100,
+	-1000, -1000, -1000, -1000, -1000, -1000, 41, 12, -1000, -1000,
+	-1000, -1000, 123, -1000, -1000, 536, -1000, 199, 283, -1000,
+	-1000, -1000, -24, 112, -1000, -1000, 76, 171, 699, 103,
+	-1000,
 }
-var yyPgo = [...]int{
 
-	0, 250, 3, 245, 4, 582, 217, 5, 215, 2,
-	161, 214, 87, 8, 192, 187, 184, 56, 0, 180,
-	176,
+var yyPgo = [...]int16{
+	0, 429, 17, 412, 5, 258, 411, 6, 400, 4,
+	260, 395, 427, 107, 391, 375, 371, 355, 350, 335,
+	0, 330, 62, 3, 2, 1, 315,
 }
-var yyR1 = [...]int{
-
-	0, 1, 1, 1, 5, 5, 5, 5, 5, 5,
-	5, 6, 7, 7, 7, 7, 7, 7, 7, 2,
-	3, 4, 4, 4, 4, 4, 4, 4, 8, 9,
-	10, 10, 10, 10, 10, 10, 11, 11, 12, 12,
-	12, 12, 12, 12, 12, 12, 14, 15, 13, 13,
-	13, 13, 13, 13, 13, 13, 13, 16, 16, 16,
-	16, 16, 17, 17, 17, 17, 17, 17, 17, 17,
-	17, 17, 17, 17, 17, 17, 17, 17, 17, 17,
-	17, 17, 17, 17, 18, 18, 18, 18, 18, 18,
-	18, 18, 18, 18, 19, 19, 19, 19, 19, 20,
-	20, 20, 20, 20, 20,
+
+var yyR1 = [...]int8{
+	0, 1, 1, 26, 26, 26, 26, 5, 5, 5,
+	5, 5, 5, 5, 6, 7, 7, 7, 7, 7,
+	7, 7, 2, 3, 4, 4, 4, 4, 4, 4,
+	4, 8, 9, 10, 10, 10, 10, 10, 10, 11,
+	11, 12, 12, 12, 12, 12, 12, 12, 12, 14,
+	15, 13, 13, 13, 13, 13, 13, 13, 13, 13,
+	16, 16, 16, 16, 16, 17, 17, 18, 18, 19,
+	19, 25, 25, 20, 20, 20, 20, 20, 20, 20,
+	20, 20, 20, 20, 20, 20, 20, 20, 20, 20,
+	20, 20, 20, 20, 20, 20, 20, 20, 21, 21,
+	22, 22, 22, 22, 22, 22, 22, 22, 22, 22,
+	23, 23, 23, 23, 23, 24, 24, 24, 24, 24,
+	24,
 }
-var yyR2 = [...]int{
-
-	0, 1, 1, 1, 3, 3, 3, 3, 3, 3,
-	1, 3, 1, 1, 1, 3, 3, 3, 3, 4,
-	3, 3, 3, 3, 3, 3, 3, 1, 3, 3,
-	1, 1, 1, 1, 1, 1, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 1, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 1, 1, 3, 4, 4,
-	4, 4, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 2,
-	2, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 1, 1, 1, 1, 3,
-	3, 3, 3, 4, 4,
+
+var yyR2 = [...]int8{
+	0, 1, 3, 1, 1, 1, 3, 3, 3, 3,
+	3, 3, 3, 1, 3, 1, 1, 1, 3, 3,
+	3, 3, 4, 3, 3, 3, 3, 3, 3, 3,
+	1, 3, 3, 1, 1, 1, 1, 1, 1, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 1, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 1, 1,
+	3, 4, 4, 4, 4, 4, 3, 6, 10, 1,
+	1, 1, 1, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 2, 2, 4, 1, 1, 1, 1, 1, 3,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 3, 3, 3, 3, 4,
+	4,
 }
-var yyChk = [...]int{
-
-	-1000, -1, -7, -5, -11, -4, -9, -2, 12, -6,
-	-12, -8, -13, 33, -14, 10, -16, -18, 28, 30,
-	31, 29, 32, 5, 6, 7, 15, 16, 14, 8,
-	18, 17, 19, 36, 37, 43, 47, 38, 48, 37,
-	43, 47, 38, 48, -5, -7, -4, -12, -15, -13,
-	-10, 49, 50, 52, 53, 54, 55, 39, 40, 41,
-	42, 43, 44, -10, 49, 50, 52, 53, 54, 55,
-	12, -17, 12, 50, 51, -18, -19, -20, 20, 21,
-	22, 23, 24, 9, 26, 27, 25, 12, 12, 12,
-	12, 12, -9, -4, -2, -3, 12, 34, -5, 12,
-	-5, -5, -5, -5, -4, 12, -4, -4, -4, -4,
-	13, 13, 36, 13, 13, 13, 13, -12, -18, 12,
-	-12, -12, -12, -12, -12, -12, -13, 12, -13, -13,
-	-13, -13, -13, -13, -17, 11, 49, 50, 52, 53,
-	54, 39, 40, 41, 42, 43, 44, 46, 45, 55,
-	37, 38, -17, -17, -17, 4, 4, 4, 4, 26,
-	27, 13, -17, -17, -17, -17, -4, -13, 12, -7,
-	12, -13, 12, -7, 13, -17, -17, -17, -17, -17,
-	-17, -17, -17, -17, -17, -17, -17, -17, -17, -17,
-	-17, 13, 35, 35, 35, 35, 4, 4, 13, 13,
-	13, 13, 13, 35, 35,
+
+var yyChk = [...]int16{
+	-1000, -1, -26, -7, -5, -11, -4, -9, -2, 12,
+	-6, -12, -8, -13, 34, -14, 10, -16, -22, 29,
+	31, 32, 30, 33, 5, 6, 7, 16, 17, 15,
+	8, 19, 18, 20, 39, 41, 42, 48, 52, 43,
+	53, 42, 48, 52, 43, 53, -5, -7, -4, -12,
+	-15, -13, -10, 55, 56, 58, 59, 60, 61, 44,
+	45, 46, 47, 48, 49, -10, 55, 56, 58, 59,
+	60, 61, 12, -20, 12, 56, 57, 35, -18, -22,
+	-23, -24, 38, 21, 22, 23, 24, 25, 9, 27,
+	28, 26, 12, 12, 12, 12, 12, 8, -17, -9,
+	-4, -2, -3, 36, 37, 12, 35, -5, 12, -5,
+	-5, -5, -5, -4, 12, -4, -4, -4, -4, 13,
+	13, 41, 13, 13, 13, 13, -12, -22, 12, -12,
+	-12, -12, -12, -12, -12, -13, 12, -13, -13, -13,
+	-13, -13, -13, -20, 11, 55, 56, 58, 59, 60,
+	44, 45, 46, 47, 48, 49, 51, 50, 61, 42,
+	43, 54, -20, -20, -20, 12, 12, 4, 4, 4,
+	4, 27, 28, 13, -20, -20, -20, -20, 12, 12,
+	-4, -13, 12, -7, 12, -13, 12, -7, 13, -20,
+	-20, -20, -20, -20, -20, -20, -20, -20, -20, -20,
+	-20, -20, -20, -20, -20, -20, 13, -21, -20, -25,
+	-23, -24, 40, 40, 40, 40, 4, 4, 13, 13,
+	13, 13, -25, 13, 13, 41, 13, 14, 14, 40,
+	40, 13, -20, -19, 7, 6, 13, 34, 12, -25,
+	13,
 }
-var yyDef = [...]int{
-
-	0, -2, 1, 2, 3, 12, 13, 14, 0, 10,
-	0, 27, 0, 0, 45, 0, 55, 56, 0, 0,
-	0, 0, 0, 84, 85, 86, 87, 88, 89, 90,
-	91, 92, 93, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 12, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 0, 30, 31, 32,
-	33, 34, 35, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 81, 82, 83, 94, 95,
-	96, 97, 98, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 15, 16, 17, 18, 0, 0, 5, 0,
-	6, 7, 8, 9, 22, 0, 23, 24, 25, 26,
-	4, 11, 0, 21, 38, 46, 48, 36, 37, 0,
-	39, 40, 41, 42, 43, 44, 29, 0, 49, 50,
-	51, 52, 53, 54, 0, 28, 0, 0, 0, 0,
+
+var yyDef = [...]int8{
+	0, -2, 1, 3, 4, 5, 15, 16, 17, 0,
+	13, 0, 30, 0, 0, 48, 0, 58, 59, 0,
+	0, 0, 0, 0, 100, 101, 102, 103, 104, 105,
+	106, 107, 108, 109, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 15, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 33,
+	34, 35, 36, 37, 38, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 94, 95,
+	96, 97, 0, 110, 111, 112, 113, 114, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 2, 6, 18,
+	19, 20, 21, 0, 0, 0, 0, 8, 0, 9,
+	10, 11, 12, 25, 0, 26, 27, 28, 29, 7,
+	14, 0, 24, 41, 49, 51, 39, 40, 0, 42,
+	43, 44, 45, 46, 47, 32, 0, 52, 53, 54,
+	55, 56, 57, 0, 31, 0, 0, 0, 0, 0,
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 0, 0, 79, 80, 0, 0, 0, 0, 0,
-	0, 57, 0, 0, 0, 0, 0, 0, 0, 0,
-	0, 47, 0, 0, 19, 63, 64, 65, 66, 67,
-	68, 69, 70, 71, 72, 73, 74, 75, 76, 77,
-	78, 62, 99, 100, 101, 102, 0, 0, 58, 59,
-	60, 61, 20, 103, 104,
+	0, 0, 0, 91, 92, 0, 0, 0, 0, 0,
+	0, 0, 0, 60, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 50, 0, 0, 22, 74,
+	75, 76, 77, 78, 79, 80, 81, 82, 83, 84,
+	85, 86, 87, 88, 89, 90, 73, 0, 98, 0,
+	71, 72, 115, 116, 117, 118, 0, 0, 61, 62,
+	63, 64, 0, 66, 23, 0, 93, 0, 0, 119,
+	120, 65, 99, 0, 69, 70, 67, 0, 0, 0,
+	68,
 }
-var yyTok1 = [...]int{
 
+var yyTok1 = [...]int8{
 	1,
 }
-var yyTok2 = [...]int{
 
+var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
 	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
 	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
 	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
-	52, 53, 54, 55,
+	52, 53, 54, 55, 56, 57, 58, 59, 60, 61,
 }
-var yyTok3 = [...]int{
+
+var yyTok3 = [...]int8{
 	0,
 }
 
@@ -440,9 +483,9 @@ func yyErrorMessage(state, lookAhead int) string {
 	expected := make([]int, 0, 4)
 
 	// Look for shiftable tokens.
-	base := yyPact[state]
+	base := int(yyPact[state])
 	for tok := TOKSTART; tok-1 < len(yyToknames); tok++ {
-		if n := base + tok; n >= 0 && n < yyLast && yyChk[yyAct[n]] == tok {
+		if n := base + tok; n >= 0 && n < yyLast && int(yyChk[int(yyAct[n])]) == tok {
 			if len(expected) == cap(expected) {
 				return res
 			}
@@ -452,13 +495,13 @@ func yyErrorMessage(state, lookAhead int) string {
 
 	if yyDef[state] == -2 {
 		i := 0
-		for yyExca[i] != -1 || yyExca[i+1] != state {
+		for yyExca[i] != -1 || int(yyExca[i+1]) != state {
 			i += 2
 		}
 
 		// Look for tokens that we accept or reduce.
 		for i += 2; yyExca[i] >= 0; i += 2 {
-			tok := yyExca[i]
+			tok := int(yyExca[i])
 			if tok < TOKSTART || yyExca[i+1] == 0 {
 				continue
 			}
@@ -489,30 +532,30 @@ func yylex1(lex yyLexer, lval *yySymType) (char, token int) {
 	token = 0
 	char = lex.Lex(lval)
 	if char <= 0 {
-		token = yyTok1[0]
+		token = int(yyTok1[0])
 		goto out
 	}
 	if char < len(yyTok1) {
-		token = yyTok1[char]
+		token = int(yyTok1[char])
 		goto out
 	}
 	if char >= yyPrivate {
 		if char < yyPrivate+len(yyTok2) {
-			token = yyTok2[char-yyPrivate]
+			token = int(yyTok2[char-yyPrivate])
 			goto out
 		}
 	}
 	for i := 0; i < len(yyTok3); i += 2 {
-		token = yyTok3[i+0]
+		token = int(yyTok3[i+0])
 		if token == char {
-			token = yyTok3[i+1]
+			token = int(yyTok3[i+1])
 			goto out
 		}
 	}
 
 out:
 	if token == 0 {
-		token = yyTok2[1] /* unknown char */
+		token = int(yyTok2[1]) /* unknown char */
 	}
 	if yyDebug >= 3 {
 		__yyfmt__.Printf("lex %s(%d)\n", yyTokname(token), uint(char))
@@ -567,7 +610,7 @@ yystack:
 	yyS[yyp].yys = yystate
 
 yynewstate:
-	yyn = yyPact[yystate]
+	yyn = int(yyPact[yystate])
 	if yyn <= yyFlag {
 		goto yydefault /* simple state */
 	}
@@ -578,8 +621,8 @@ yynewstate:
 	if yyn < 0 || yyn >= yyLast {
 		goto yydefault
 	}
-	yyn = yyAct[yyn]
-	if yyChk[yyn] == yytoken { /* valid shift */
+	yyn = int(yyAct[yyn])
+	if int(yyChk[yyn]) == yytoken { /* valid shift */
 		yyrcvr.char = -1
 		yytoken = -1
 		yyVAL = yyrcvr.lval
@@ -592,7 +635,7 @@ yynewstate:
 
 yydefault:
 	/* default state action */
-	yyn = yyDef[yystate]
+	yyn = int(yyDef[yystate])
 	if yyn == -2 {
 		if yyrcvr.char < 0 {
 			yyrcvr.char, yytoken = yylex1(yylex, &yyrcvr.lval)
@@ -601,18 +644,18 @@ yydefault:
 		/* look through exception table */
 		xi := 0
 		for {
-			if yyExca[xi+0] == -1 && yyExca[xi+1] == yystate {
+			if yyExca[xi+0] == -1 && int(yyExca[xi+1]) == yystate {
 				break
 			}
 			xi += 2
 		}
 		for xi += 2; ; xi += 2 {
-			yyn = yyExca[xi+0]
+			yyn = int(yyExca[xi+0])
 			if yyn < 0 || yyn == yytoken {
 				break
 			}
 		}
-		yyn = yyExca[xi+1]
+		yyn = int(yyExca[xi+1])
 		if yyn < 0 {
 			goto ret0
 		}
@@ -634,10 +677,10 @@ yydefault:
 
 			/* find a state where "error" is a legal shift action */
 			for yyp >= 0 {
-				yyn = yyPact[yyS[yyp].yys] + yyErrCode
+				yyn = int(yyPact[yyS[yyp].yys]) + yyErrCode
 				if yyn >= 0 && yyn < yyLast {
-					yystate = yyAct[yyn] /* simulate a shift of "error" */
-					if yyChk[yystate] == yyErrCode {
+					yystate = int(yyAct[yyn]) /* simulate a shift of "error" */
+					if int(yyChk[yystate]) == yyErrCode {
 						goto yystack
 					}
 				}
@@ -673,7 +716,7 @@ yydefault:
 	yypt := yyp
 	_ = yypt // guard against "declared and not used"
 
-	yyp -= yyR2[yyn]
+	yyp -= int(yyR2[yyn])
 	// yyp is now the index of $0. Perform the default action. Iff the
 	// reduced production is ε, $1 is possibly out of range.
 	if yyp+1 >= len(yyS) {
@@ -684,16 +727,16 @@ yydefault:
 	yyVAL = yyS[yyp+1]
 
 	/* consult goto table to find next state */
-	yyn = yyR1[yyn]
-	yyg := yyPgo[yyn]
+	yyn = int(yyR1[yyn])
+	yyg := int(yyPgo[yyn])
 	yyj := yyg + yyS[yyp].yys + 1
 
 	if yyj >= yyLast {
-		yystate = yyAct[yyg]
+		yystate = int(yyAct[yyg])
 	} else {
-		yystate = yyAct[yyj]
-		if yyChk[yystate] != -yyn {
-			yystate = yyAct[yyg]
+		yystate = int(yyAct[yyj])
+		if int(yyChk[yystate]) != -yyn {
+			yystate = int(yyAct[yyg])
 		}
 	}
 	// dummy call; replaced with literal code
@@ -701,625 +744,721 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:93
+//line pkg/traceql/expr.y:104
 		{
-			yylex.(*lexer).expr = newRootExpr(yyDollar[1].spansetPipeline)
 		}
 	case 2:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:105
+		{
+			yylex.(*lexer).setSince(yyDollar[3].staticDuration)
+		}
+	case 3:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:109
+		{
+			yylex.(*lexer).expr = newRootExpr(yyDollar[1].spansetPipeline)
+		}
+	case 4:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:94
+//line pkg/traceql/expr.y:110
 		{
 			yylex.(*lexer).expr = newRootExpr(yyDollar[1].spansetPipelineExpression)
 		}
-	case 3:
+	case 5:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:95
+//line pkg/traceql/expr.y:111
 		{
 			yylex.(*lexer).expr = newRootExpr(yyDollar[1].scalarPipelineExpressionFilter)
 		}
-	case 4:
+	case 6:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:102
+//line pkg/traceql/expr.y:112
+		{
+			yylex.(*lexer).expr = newRootExpr(yyDollar[1].spansetPipeline.addItem(yyDollar[3].metricsAggregate))
+		}
+	case 7:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:119
 		{
 			yyVAL.spansetPipelineExpression = yyDollar[2].spansetPipelineExpression
 		}
-	case 5:
+	case 8:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:103
+//line pkg/traceql/expr.y:120
 		{
 			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetAnd, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
-	case 6:
+	case 9:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:104
+//line pkg/traceql/expr.y:121
 		{
 			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetChild, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
-	case 7:
+	case 10:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:105
+//line pkg/traceql/expr.y:122
 		{
 			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetDescendant, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
-	case 8:
+	case 11:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:106
+//line pkg/traceql/expr.y:123
 		{
 			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetUnion, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
-	case 9:
+	case 12:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:107
+//line pkg/traceql/expr.y:124
 		{
 			yyVAL.spansetPipelineExpression = newSpansetOperation(OpSpansetSibling, yyDollar[1].spansetPipelineExpression, yyDollar[3].spansetPipelineExpression)
 		}
-	case 10:
+	case 13:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:108
+//line pkg/traceql/expr.y:125
 		{
 			yyVAL.spansetPipelineExpression = yyDollar[1].wrappedSpansetPipeline
 		}
-	case 11:
+	case 14:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:112
+//line pkg/traceql/expr.y:129
 		{
 			yyVAL.wrappedSpansetPipeline = yyDollar[2].spansetPipeline
 		}
-	case 12:
+	case 15:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:115
+//line pkg/traceql/expr.y:132
 		{
 			yyVAL.spansetPipeline = newPipeline(yyDollar[1].spansetExpression)
 		}
-	case 13:
+	case 16:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:116
+//line pkg/traceql/expr.y:133
 		{
 			yyVAL.spansetPipeline = newPipeline(yyDollar[1].scalarFilter)
 		}
-	case 14:
+	case 17:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:117
+//line pkg/traceql/expr.y:134
 		{
 			yyVAL.spansetPipeline = newPipeline(yyDollar[1].groupOperation)
 		}
-	case 15:
+	case 18:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:118
+//line pkg/traceql/expr.y:135
 		{
 			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].scalarFilter)
 		}
-	case 16:
+	case 19:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:119
+//line pkg/traceql/expr.y:136
 		{
 			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].spansetExpression)
 		}
-	case 17:
+	case 20:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:120
+//line pkg/traceql/expr.y:137
 		{
 			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].groupOperation)
 		}
-	case 18:
+	case 21:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:121
+//line pkg/traceql/expr.y:138
 		{
 			yyVAL.spansetPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].coalesceOperation)
 		}
-	case 19:
+	case 22:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:125
+//line pkg/traceql/expr.y:142
 		{
 			yyVAL.groupOperation = newGroupOperation(yyDollar[3].fieldExpression)
 		}
-	case 20:
+	case 23:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:129
+//line pkg/traceql/expr.y:146
 		{
 			yyVAL.coalesceOperation = newCoalesceOperation()
 		}
-	case 21:
+	case 24:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:133
+//line pkg/traceql/expr.y:150
 		{
 			yyVAL.spansetExpression = yyDollar[2].spansetExpression
 		}
-	case 22:
+	case 25:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:134
+//line pkg/traceql/expr.y:151
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetAnd, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 23:
+	case 26:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:135
+//line pkg/traceql/expr.y:152
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetChild, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 24:
+	case 27:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:136
+//line pkg/traceql/expr.y:153
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetDescendant, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 25:
+	case 28:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:137
+//line pkg/traceql/expr.y:154
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetUnion, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 26:
+	case 29:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:138
+//line pkg/traceql/expr.y:155
 		{
 			yyVAL.spansetExpression = newSpansetOperation(OpSpansetSibling, yyDollar[1].spansetExpression, yyDollar[3].spansetExpression)
 		}
-	case 27:
+	case 30:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:139
+//line pkg/traceql/expr.y:156
 		{
 			yyVAL.spansetExpression = yyDollar[1].spansetFilter
 		}
-	case 28:
+	case 31:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:143
+//line pkg/traceql/expr.y:160
 		{
 			yyVAL.spansetFilter = newSpansetFilter(yyDollar[2].fieldExpression)
 		}
-	case 29:
+	case 32:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:147
+//line pkg/traceql/expr.y:164
 		{
 			yyVAL.scalarFilter = newScalarFilter(yyDollar[2].scalarFilterOperation, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 30:
+	case 33:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:151
+//line pkg/traceql/expr.y:168
 		{
 			yyVAL.scalarFilterOperation = OpEqual
 		}
-	case 31:
+	case 34:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:152
+//line pkg/traceql/expr.y:169
 		{
 			yyVAL.scalarFilterOperation = OpNotEqual
 		}
-	case 32:
+	case 35:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:153
+//line pkg/traceql/expr.y:170
 		{
 			yyVAL.scalarFilterOperation = OpLess
 		}
-	case 33:
+	case 36:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:154
+//line pkg/traceql/expr.y:171
 		{
 			yyVAL.scalarFilterOperation = OpLessEqual
 		}
-	case 34:
+	case 37:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:155
+//line pkg/traceql/expr.y:172
 		{
 			yyVAL.scalarFilterOperation = OpGreater
 		}
-	case 35:
+	case 38:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:156
+//line pkg/traceql/expr.y:173
 		{
 			yyVAL.scalarFilterOperation = OpGreaterEqual
 		}
-	case 36:
+	case 39:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:163
+//line pkg/traceql/expr.y:180
 		{
 			yyVAL.scalarPipelineExpressionFilter = newScalarFilter(yyDollar[2].scalarFilterOperation, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 37:
+	case 40:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:164
+//line pkg/traceql/expr.y:181
 		{
 			yyVAL.scalarPipelineExpressionFilter = newScalarFilter(yyDollar[2].scalarFilterOperation, yyDollar[1].scalarPipelineExpression, yyDollar[3].static)
 		}
-	case 38:
+	case 41:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:168
+//line pkg/traceql/expr.y:185
 		{
 			yyVAL.scalarPipelineExpression = yyDollar[2].scalarPipelineExpression
 		}
-	case 39:
+	case 42:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:169
+//line pkg/traceql/expr.y:186
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpAdd, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 40:
+	case 43:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:170
+//line pkg/traceql/expr.y:187
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpSub, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 41:
+	case 44:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:171
+//line pkg/traceql/expr.y:188
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpMult, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 42:
+	case 45:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:172
+//line pkg/traceql/expr.y:189
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpDiv, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 43:
+	case 46:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:173
+//line pkg/traceql/expr.y:190
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpMod, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 44:
+	case 47:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:174
+//line pkg/traceql/expr.y:191
 		{
 			yyVAL.scalarPipelineExpression = newScalarOperation(OpPower, yyDollar[1].scalarPipelineExpression, yyDollar[3].scalarPipelineExpression)
 		}
-	case 45:
+	case 48:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:175
+//line pkg/traceql/expr.y:192
 		{
 			yyVAL.scalarPipelineExpression = yyDollar[1].wrappedScalarPipeline
 		}
-	case 46:
+	case 49:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:179
+//line pkg/traceql/expr.y:196
 		{
 			yyVAL.wrappedScalarPipeline = yyDollar[2].scalarPipeline
 		}
-	case 47:
+	case 50:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:183
+//line pkg/traceql/expr.y:200
 		{
 			yyVAL.scalarPipeline = yyDollar[1].spansetPipeline.addItem(yyDollar[3].scalarExpression)
 		}
-	case 48:
+	case 51:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:187
+//line pkg/traceql/expr.y:204
 		{
 			yyVAL.scalarExpression = yyDollar[2].scalarExpression
 		}
-	case 49:
+	case 52:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:188
+//line pkg/traceql/expr.y:205
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpAdd, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 50:
+	case 53:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:189
+//line pkg/traceql/expr.y:206
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpSub, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 51:
+	case 54:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:190
+//line pkg/traceql/expr.y:207
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpMult, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 52:
+	case 55:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:191
+//line pkg/traceql/expr.y:208
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpDiv, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 53:
+	case 56:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:192
+//line pkg/traceql/expr.y:209
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpMod, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 54:
+	case 57:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:193
+//line pkg/traceql/expr.y:210
 		{
 			yyVAL.scalarExpression = newScalarOperation(OpPower, yyDollar[1].scalarExpression, yyDollar[3].scalarExpression)
 		}
-	case 55:
+	case 58:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:194
+//line pkg/traceql/expr.y:211
 		{
 			yyVAL.scalarExpression = yyDollar[1].aggregate
 		}
-	case 56:
+	case 59:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:195
+//line pkg/traceql/expr.y:212
 		{
 			yyVAL.scalarExpression = yyDollar[1].static
 		}
-	case 57:
+	case 60:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:199
+//line pkg/traceql/expr.y:216
 		{
 			yyVAL.aggregate = newAggregate(aggregateCount, nil)
 		}
-	case 58:
+	case 61:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:200
+//line pkg/traceql/expr.y:217
 		{
 			yyVAL.aggregate = newAggregate(aggregateMax, yyDollar[3].fieldExpression)
 		}
-	case 59:
+	case 62:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:201
+//line pkg/traceql/expr.y:218
 		{
 			yyVAL.aggregate = newAggregate(aggregateMin, yyDollar[3].fieldExpression)
 		}
-	case 60:
+	case 63:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:202
+//line pkg/traceql/expr.y:219
 		{
 			yyVAL.aggregate = newAggregate(aggregateAvg, yyDollar[3].fieldExpression)
 		}
-	case 61:
+	case 64:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:203
+//line pkg/traceql/expr.y:220
 		{
 			yyVAL.aggregate = newAggregate(aggregateSum, yyDollar[3].fieldExpression)
 		}
-	case 62:
+	case 65:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line pkg/traceql/expr.y:227
+		{
+			yyVAL.metricsAggregate = newMetricsAggregate(metricsAggregateHistogramOverTime, yyDollar[3].attributeField)
+		}
+	case 66:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:210
+//line pkg/traceql/expr.y:228
+		{
+			yyVAL.metricsAggregate = newMetricsAggregate(metricsAggregateCountOverTime, Attribute{})
+		}
+	case 67:
+		yyDollar = yyS[yypt-6 : yypt+1]
+//line pkg/traceql/expr.y:233
+		{
+			yyVAL.metricsThreshold = yylex.(*lexer).newMetricsThreshold(yyDollar[3].attributeField, yyDollar[5].staticFloat, nil)
+		}
+	case 68:
+		yyDollar = yyS[yypt-10 : yypt+1]
+//line pkg/traceql/expr.y:235
+		{
+			by := yyDollar[9].attributeField
+			yyVAL.metricsThreshold = yylex.(*lexer).newMetricsThreshold(yyDollar[3].attributeField, yyDollar[5].staticFloat, &by)
+		}
+	case 69:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:239
+		{
+			yyVAL.staticFloat = yyDollar[1].staticFloat
+		}
+	case 70:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:240
+		{
+			yyVAL.staticFloat = float64(yyDollar[1].staticInt)
+		}
+	case 71:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:244
+		{
+			yyVAL.attributeField = yyDollar[1].intrinsicField
+		}
+	case 72:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:245
+		{
+			yyVAL.attributeField = yyDollar[1].attributeField
+		}
+	case 73:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:252
 		{
 			yyVAL.fieldExpression = yyDollar[2].fieldExpression
 		}
-	case 63:
+	case 74:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:211
+//line pkg/traceql/expr.y:253
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpAdd, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 64:
+	case 75:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:212
+//line pkg/traceql/expr.y:254
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpSub, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 65:
+	case 76:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:213
+//line pkg/traceql/expr.y:255
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpMult, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 66:
+	case 77:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:214
+//line pkg/traceql/expr.y:256
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpDiv, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 67:
+	case 78:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:215
+//line pkg/traceql/expr.y:257
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpMod, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 68:
+	case 79:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:216
+//line pkg/traceql/expr.y:258
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpEqual, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 69:
+	case 80:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:217
+//line pkg/traceql/expr.y:259
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpNotEqual, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 70:
+	case 81:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:218
+//line pkg/traceql/expr.y:260
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpLess, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 71:
+	case 82:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:219
+//line pkg/traceql/expr.y:261
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpLessEqual, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 72:
+	case 83:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:220
+//line pkg/traceql/expr.y:262
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpGreater, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 73:
+	case 84:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:221
+//line pkg/traceql/expr.y:263
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpGreaterEqual, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 74:
+	case 85:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:222
+//line pkg/traceql/expr.y:264
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpRegex, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 75:
+	case 86:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:223
+//line pkg/traceql/expr.y:265
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpNotRegex, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 76:
+	case 87:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:224
+//line pkg/traceql/expr.y:266
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpPower, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 77:
+	case 88:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:225
+//line pkg/traceql/expr.y:267
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpAnd, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 78:
+	case 89:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:226
+//line pkg/traceql/expr.y:268
 		{
 			yyVAL.fieldExpression = newBinaryOperation(OpOr, yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
 		}
-	case 79:
+	case 90:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:269
+		{
+			yyVAL.fieldExpression = newDefaultExpression(yyDollar[1].fieldExpression, yyDollar[3].fieldExpression)
+		}
+	case 91:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/traceql/expr.y:227
+//line pkg/traceql/expr.y:270
 		{
 			yyVAL.fieldExpression = newUnaryOperation(OpSub, yyDollar[2].fieldExpression)
 		}
-	case 80:
+	case 92:
 		yyDollar = yyS[yypt-2 : yypt+1]
-//line pkg/traceql/expr.y:228
+//line pkg/traceql/expr.y:271
 		{
 			yyVAL.fieldExpression = newUnaryOperation(OpNot, yyDollar[2].fieldExpression)
 		}
-	case 81:
+	case 93:
+		yyDollar = yyS[yypt-4 : yypt+1]
+//line pkg/traceql/expr.y:272
+		{
+			yyVAL.fieldExpression = newCoalesceExpression(yyDollar[3].fieldExpressions)
+		}
+	case 94:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:273
+		{
+			yyVAL.fieldExpression = yyDollar[1].metricsThreshold
+		}
+	case 95:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:229
+//line pkg/traceql/expr.y:274
 		{
 			yyVAL.fieldExpression = yyDollar[1].static
 		}
-	case 82:
+	case 96:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:230
+//line pkg/traceql/expr.y:275
 		{
 			yyVAL.fieldExpression = yyDollar[1].intrinsicField
 		}
-	case 83:
+	case 97:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:231
+//line pkg/traceql/expr.y:276
 		{
 			yyVAL.fieldExpression = yyDollar[1].attributeField
 		}
-	case 84:
+	case 98:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:238
+//line pkg/traceql/expr.y:280
+		{
+			yyVAL.fieldExpressions = []FieldExpression{yyDollar[1].fieldExpression}
+		}
+	case 99:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line pkg/traceql/expr.y:281
+		{
+			yyVAL.fieldExpressions = append(yyDollar[1].fieldExpressions, yyDollar[3].fieldExpression)
+		}
+	case 100:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line pkg/traceql/expr.y:288
 		{
 			yyVAL.static = newStaticString(yyDollar[1].staticStr)
 		}
-	case 85:
+	case 101:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:239
+//line pkg/traceql/expr.y:289
 		{
 			yyVAL.static = newStaticInt(yyDollar[1].staticInt)
 		}
-	case 86:
+	case 102:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:240
+//line pkg/traceql/expr.y:290
 		{
 			yyVAL.static = newStaticFloat(yyDollar[1].staticFloat)
 		}
-	case 87:
+	case 103:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:241
+//line pkg/traceql/expr.y:291
 		{
 			yyVAL.static = newStaticBool(true)
 		}
-	case 88:
+	case 104:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:242
+//line pkg/traceql/expr.y:292
 		{
 			yyVAL.static = newStaticBool(false)
 		}
-	case 89:
+	case 105:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:243
+//line pkg/traceql/expr.y:293
 		{
 			yyVAL.static = newStaticNil()
 		}
-	case 90:
+	case 106:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:244
+//line pkg/traceql/expr.y:294
 		{
 			yyVAL.static = newStaticDuration(yyDollar[1].staticDuration)
 		}
-	case 91:
+	case 107:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:245
+//line pkg/traceql/expr.y:295
 		{
 			yyVAL.static = newStaticStatus(StatusOk)
 		}
-	case 92:
+	case 108:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:246
+//line pkg/traceql/expr.y:296
 		{
 			yyVAL.static = newStaticStatus(StatusError)
 		}
-	case 93:
+	case 109:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:247
+//line pkg/traceql/expr.y:297
 		{
 			yyVAL.static = newStaticStatus(StatusUnset)
 		}
-	case 94:
+	case 110:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:251
+//line pkg/traceql/expr.y:301
 		{
 			yyVAL.intrinsicField = newIntrinsic(IntrinsicDuration)
 		}
-	case 95:
+	case 111:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:252
+//line pkg/traceql/expr.y:302
 		{
 			yyVAL.intrinsicField = newIntrinsic(IntrinsicChildCount)
 		}
-	case 96:
+	case 112:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:253
+//line pkg/traceql/expr.y:303
 		{
 			yyVAL.intrinsicField = newIntrinsic(IntrinsicName)
 		}
-	case 97:
+	case 113:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:254
+//line pkg/traceql/expr.y:304
 		{
 			yyVAL.intrinsicField = newIntrinsic(IntrinsicStatus)
 		}
-	case 98:
+	case 114:
 		yyDollar = yyS[yypt-1 : yypt+1]
-//line pkg/traceql/expr.y:255
+//line pkg/traceql/expr.y:305
 		{
 			yyVAL.intrinsicField = newIntrinsic(IntrinsicParent)
 		}
-	case 99:
+	case 115:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:259
+//line pkg/traceql/expr.y:309
 		{
 			yyVAL.attributeField = newAttribute(yyDollar[2].staticStr)
 		}
-	case 100:
+	case 116:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:260
+//line pkg/traceql/expr.y:310
 		{
 			yyVAL.attributeField = newScopedAttribute(AttributeScopeResource, false, yyDollar[2].staticStr)
 		}
-	case 101:
+	case 117:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:261
+//line pkg/traceql/expr.y:311
 		{
 			yyVAL.attributeField = newScopedAttribute(AttributeScopeSpan, false, yyDollar[2].staticStr)
 		}
-	case 102:
+	case 118:
 		yyDollar = yyS[yypt-3 : yypt+1]
-//line pkg/traceql/expr.y:262
+//line pkg/traceql/expr.y:312
 		{
 			yyVAL.attributeField = newScopedAttribute(AttributeScopeNone, true, yyDollar[2].staticStr)
 		}
-	case 103:
+	case 119:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:263
+//line pkg/traceql/expr.y:313
 		{
 			yyVAL.attributeField = newScopedAttribute(AttributeScopeResource, true, yyDollar[3].staticStr)
 		}
-	case 104:
+	case 120:
 		yyDollar = yyS[yypt-4 : yypt+1]
-//line pkg/traceql/expr.y:264
+//line pkg/traceql/expr.y:314
 		{
 			yyVAL.attributeField = newScopedAttribute(AttributeScopeSpan, true, yyDollar[3].staticStr)
 		}
@@ -16,6 +16,7 @@ var tokens = map[string]int{
 	"}":          CLOSE_BRACE,
 	"(":          OPEN_PARENS,
 	")":          CLOSE_PARENS,
+	",":          COMMA,
 	"=":          EQ,
 	"!=":         NEQ,
 	"=~":         RE,
@@ -57,6 +58,12 @@ var tokens = map[string]int{
 	"sum":        SUM,
 	"by":         BY,
 	"coalesce":   COALESCE,
+	"default":    DEFAULT,
+
+	"histogram_over_time": HISTOGRAM_OVER_TIME,
+	"count_over_time":     COUNT_OVER_TIME,
+	"quantile_over_time":  QUANTILE_OVER_TIME,
+	"since":               SINCE,
 }
 
 type lexer struct {
@@ -66,6 +73,8 @@ type lexer struct {
 	errs   []ParseError
 
 	parsingAttribute bool
+	// thresholds is the number of metrics thresholds parsed so far
+	thresholds int
 }
 
 func (l *lexer) Lex(lval *yySymType) int {
@@ -160,6 +169,28 @@ func (l *lexer) Lex(lval *yySymType) int {
 	return IDENTIFIER
 }
 
+// newMetricsThreshold creates the next threshold of the query, thresholds are numbered in the order they appear.
+func (l *lexer) newMetricsThreshold(field Attribute, quantile float64, by *Attribute) MetricsThreshold {
+	t := MetricsThreshold{
+		Field:    field,
+		Quantile: quantile,
+		By:       by,
+		index:    l.thresholds,
+	}
+	l.thresholds++
+	return t
+}
+
+// setSince sets the relative time range of the query, it must be positive.
+func (l *lexer) setSince(d time.Duration) {
+	if d <= 0 {
+		// the query has been read to the end, the position of the scanner is meaningless
+		l.errs = append(l.errs, newParseError("syntax error: since requires a positive duration", 0, 0))
+		return
+	}
+	l.expr.Since = d
+}
+
 func (l *lexer) Error(msg string) {
 	l.errs = append(l.errs, newParseError(msg, l.Line, l.Column))
 }
@@ -229,7 +260,8 @@ func isAttributeRune(r rune) bool {
 		r != '(' &&
 		r != ')' &&
 		r != '}' &&
-		r != '{'
+		r != '{' &&
+		r != ','
 }
 
 func startsAttribute(tok int) bool {
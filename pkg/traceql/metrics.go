@@ -0,0 +1,187 @@
+package traceql
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// MetricsAggregateOp is a function computing metrics from the spans matched by the rest of the query.
+type MetricsAggregateOp int
+
+const (
+	metricsAggregateHistogramOverTime MetricsAggregateOp = iota
+	metricsAggregateCountOverTime
+)
+
+func (a MetricsAggregateOp) String() string {
+	switch a {
+	case metricsAggregateHistogramOverTime:
+		return "histogram_over_time"
+	case metricsAggregateCountOverTime:
+		return "count_over_time"
+	}
+
+	return fmt.Sprintf("metricsAggregate(%d)", a)
+}
+
+// MetricsAggregate is the final element of the pipeline of a metrics query, e.g.
+// { status = error } | histogram_over_time(duration). count_over_time() has no field.
+type MetricsAggregate struct {
+	Op    MetricsAggregateOp
+	Field Attribute
+}
+
+func newMetricsAggregate(op MetricsAggregateOp, field Attribute) MetricsAggregate {
+	return MetricsAggregate{
+		Op:    op,
+		Field: field,
+	}
+}
+
+func (a MetricsAggregate) String() string {
+	if a.CountsSpans() {
+		return a.Op.String() + "()"
+	}
+	return a.Op.String() + "(" + a.Field.String() + ")"
+}
+
+// CountsSpans returns true if the function counts the matching spans instead of aggregating a field. The counts are
+// a histogram with the single bucket 0.
+func (a MetricsAggregate) CountsSpans() bool {
+	return a.Op == metricsAggregateCountOverTime
+}
+
+func (a MetricsAggregate) validate() error {
+	if a.CountsSpans() {
+		return nil
+	}
+
+	if err := a.Field.validate(); err != nil {
+		return err
+	}
+
+	t := a.Field.impliedType()
+	if t != TypeAttribute && !t.isNumeric() {
+		return fmt.Errorf("metrics field expressions must resolve to a number type: %s", a.String())
+	}
+
+	return nil
+}
+
+// Exemplar is a span that contributed to a bucket of a histogram.
+type Exemplar struct {
+	TraceID     []byte
+	Value       float64
+	TimestampMs int64
+}
+
+// HistogramSeries is the number of spans per step for a single bucket of a histogram. Bucket is the inclusive
+// upper bound of the bucket.
+type HistogramSeries struct {
+	Bucket    float64
+	Counts    []uint64
+	Exemplars []Exemplar
+}
+
+// HistogramOverTime accumulates the result of histogram_over_time. Values are grouped in exponential buckets
+// with a power of 2 as upper bound, which can be rendered as a heatmap without further processing. Every bucket
+// keeps up to maxExemplars exemplars so the spans behind a cell of the heatmap can be looked up.
+type HistogramOverTime struct {
+	start        int64
+	step         int64
+	steps        int
+	maxExemplars int
+
+	buckets map[float64]*HistogramSeries
+}
+
+// NewHistogramOverTime creates a histogram covering [start, end) in intervals of step.
+func NewHistogramOverTime(start, end time.Time, step time.Duration, maxExemplars int) (*HistogramOverTime, error) {
+	if step <= 0 {
+		return nil, fmt.Errorf("step must be greater than 0")
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	return &HistogramOverTime{
+		start:        start.UnixNano(),
+		step:         int64(step),
+		steps:        int((end.UnixNano() - start.UnixNano() + int64(step) - 1) / int64(step)),
+		maxExemplars: maxExemplars,
+		buckets:      map[float64]*HistogramSeries{},
+	}, nil
+}
+
+// Observe records a span with the given start time in unix nanoseconds. Durations are expected in seconds.
+// Spans outside of the time range are ignored. The trace id is copied if the span is kept as exemplar.
+func (h *HistogramOverTime) Observe(tsNanos uint64, value float64, traceID []byte) {
+	ts := int64(tsNanos)
+	if ts < h.start {
+		return
+	}
+	i := int((ts - h.start) / h.step)
+	if i >= h.steps {
+		return
+	}
+
+	bucket := histogramBucket(value)
+	s, ok := h.buckets[bucket]
+	if !ok {
+		s = &HistogramSeries{
+			Bucket: bucket,
+			Counts: make([]uint64, h.steps),
+		}
+		h.buckets[bucket] = s
+	}
+
+	s.Counts[i]++
+	if len(s.Exemplars) < h.maxExemplars {
+		s.Exemplars = append(s.Exemplars, Exemplar{
+			TraceID:     append([]byte(nil), traceID...), // the id may be reused by the caller
+			Value:       value,
+			TimestampMs: ts / int64(time.Millisecond),
+		})
+	}
+}
+
+// AddCounts adds spans counted without their values to the bucket of value, e.g. from the statistics of a block.
+// counts are the number of spans per step, they are not kept as exemplars.
+func (h *HistogramOverTime) AddCounts(value float64, counts []uint64) {
+	bucket := histogramBucket(value)
+	s, ok := h.buckets[bucket]
+	if !ok {
+		s = &HistogramSeries{
+			Bucket: bucket,
+			Counts: make([]uint64, h.steps),
+		}
+		h.buckets[bucket] = s
+	}
+
+	for i := 0; i < len(counts) && i < h.steps; i++ {
+		s.Counts[i] += counts[i]
+	}
+}
+
+// Series returns the series of all buckets that have been observed, ordered by bucket.
+func (h *HistogramOverTime) Series() []HistogramSeries {
+	series := make([]HistogramSeries, 0, len(h.buckets))
+	for _, s := range h.buckets {
+		series = append(series, *s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Bucket < series[j].Bucket
+	})
+	return series
+}
+
+// histogramBucket returns the smallest power of 2 greater than or equal to the value. Values less than or
+// equal to 0 fall into bucket 0.
+func histogramBucket(value float64) float64 {
+	if value <= 0 || math.IsNaN(value) {
+		return 0
+	}
+	return math.Pow(2, math.Ceil(math.Log2(value)))
+}
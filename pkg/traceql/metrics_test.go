@@ -0,0 +1,130 @@
+package traceql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetricsAggregate(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected *RootExpr
+	}{
+		{
+			in: "{ .a } | histogram_over_time(duration)",
+			expected: &RootExpr{
+				Pipeline: newPipeline(
+					newSpansetFilter(newAttribute("a")),
+					newMetricsAggregate(metricsAggregateHistogramOverTime, newIntrinsic(IntrinsicDuration)),
+				),
+			},
+		},
+		{
+			in: `{ .a = "|" } | by(.b) | histogram_over_time(resource.size)`,
+			expected: &RootExpr{
+				Pipeline: newPipeline(
+					newSpansetFilter(newBinaryOperation(OpEqual, newAttribute("a"), newStaticString("|"))),
+					newGroupOperation(newAttribute("b")),
+					newMetricsAggregate(metricsAggregateHistogramOverTime, newScopedAttribute(AttributeScopeResource, false, "size")),
+				),
+			},
+		},
+		{
+			in: "{ .a = `|` } | histogram_over_time(duration)",
+			expected: &RootExpr{
+				Pipeline: newPipeline(
+					newSpansetFilter(newBinaryOperation(OpEqual, newAttribute("a"), newStaticString("|"))),
+					newMetricsAggregate(metricsAggregateHistogramOverTime, newIntrinsic(IntrinsicDuration)),
+				),
+			},
+		},
+		{
+			in: "{ .a || .b } | histogram_over_time(.c)",
+			expected: &RootExpr{
+				Pipeline: newPipeline(
+					newSpansetFilter(newBinaryOperation(OpOr, newAttribute("a"), newAttribute("b"))),
+					newMetricsAggregate(metricsAggregateHistogramOverTime, newAttribute("c")),
+				),
+			},
+		},
+		{
+			in: "{ .a } | count_over_time()",
+			expected: &RootExpr{
+				Pipeline: newPipeline(
+					newSpansetFilter(newAttribute("a")),
+					newMetricsAggregate(metricsAggregateCountOverTime, Attribute{}),
+				),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			actual, err := Parse(tc.in)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestHistogramOverTime(t *testing.T) {
+	start := time.Unix(100, 0)
+	h, err := NewHistogramOverTime(start, start.Add(30*time.Second), 10*time.Second, 1)
+	require.NoError(t, err)
+
+	ts := func(d time.Duration) uint64 {
+		return uint64(start.Add(d).UnixNano())
+	}
+
+	h.Observe(ts(0), 0.3, []byte{0x01})
+	h.Observe(ts(time.Second), 0.5, []byte{0x02})
+	h.Observe(ts(15*time.Second), 3, []byte{0x03})
+	h.Observe(ts(25*time.Second), 0.4, []byte{0x04})
+	h.Observe(ts(-time.Second), 1, []byte{0x05})   // before start
+	h.Observe(ts(30*time.Second), 1, []byte{0x06}) // after end
+
+	expected := []HistogramSeries{
+		{
+			Bucket:    0.5,
+			Counts:    []uint64{2, 0, 1},
+			Exemplars: []Exemplar{{TraceID: []byte{0x01}, Value: 0.3, TimestampMs: 100_000}},
+		},
+		{
+			Bucket:    4,
+			Counts:    []uint64{0, 1, 0},
+			Exemplars: []Exemplar{{TraceID: []byte{0x03}, Value: 3, TimestampMs: 115_000}},
+		},
+	}
+	assert.Equal(t, expected, h.Series())
+}
+
+func TestHistogramOverTimeAddCounts(t *testing.T) {
+	start := time.Unix(100, 0)
+	h, err := NewHistogramOverTime(start, start.Add(30*time.Second), 10*time.Second, 1)
+	require.NoError(t, err)
+
+	h.Observe(uint64(start.UnixNano()), 0, []byte{0x01})
+	h.AddCounts(0, []uint64{0, 2, 3, 4}) // more steps than the histogram
+
+	expected := []HistogramSeries{
+		{
+			Bucket:    0,
+			Counts:    []uint64{1, 2, 3},
+			Exemplars: []Exemplar{{TraceID: []byte{0x01}, Value: 0, TimestampMs: 100_000}},
+		},
+	}
+	assert.Equal(t, expected, h.Series())
+}
+
+func TestNewHistogramOverTimeValidation(t *testing.T) {
+	start := time.Unix(100, 0)
+
+	_, err := NewHistogramOverTime(start, start.Add(time.Minute), 0, 1)
+	require.EqualError(t, err, "step must be greater than 0")
+
+	_, err = NewHistogramOverTime(start, start, time.Second, 1)
+	require.EqualError(t, err, "end must be after start")
+}
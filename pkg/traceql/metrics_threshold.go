@@ -0,0 +1,396 @@
+package traceql
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/tempo/pkg/ddsketch"
+)
+
+const (
+	keywordQuantileOverTime = "quantile_over_time"
+	keywordBy               = "by"
+
+	// quantileScale scales the observed values before they are added to a sketch, sketches count values below 1 as
+	// zeros. Durations are observed in seconds and are kept with nanosecond precision.
+	quantileScale = float64(time.Second)
+)
+
+// MetricsThreshold is a metrics aggregate referenced by a spanset filter, e.g. the p99 of the duration of the spans
+// of the same operation in { duration > quantile_over_time(duration, 0.99) by(name) }. Queries with thresholds are
+// evaluated in two phases. The values of the thresholds are computed from the spans matched by
+// RootExpr.MetricsThresholdFilter, then RootExpr.ResolveMetricsThresholds replaces the thresholds with the values.
+type MetricsThreshold struct {
+	Field    Attribute
+	Quantile float64
+	// By is the attribute the spans are grouped by, a span is compared with the value of its group. nil if the
+	// value is computed over all spans.
+	By *Attribute
+
+	// index is the position of the threshold in the query, it identifies the threshold in the ast
+	index int
+}
+
+// nolint: revive
+func (MetricsThreshold) __fieldExpression() {}
+
+func (t MetricsThreshold) impliedType() StaticType {
+	if ft := t.Field.impliedType(); ft == TypeDuration {
+		return ft
+	}
+	return TypeFloat
+}
+
+func (t MetricsThreshold) referencesSpan() bool {
+	return t.By != nil
+}
+
+func (t MetricsThreshold) String() string {
+	s := keywordQuantileOverTime + "(" + t.Field.String() + ", " + strconv.FormatFloat(t.Quantile, 'f', -1, 64) + ")"
+	if t.By != nil {
+		s += " " + keywordBy + "(" + t.By.String() + ")"
+	}
+	return s
+}
+
+func (t MetricsThreshold) validate() error {
+	if err := t.Field.validate(); err != nil {
+		return err
+	}
+
+	ft := t.Field.impliedType()
+	if ft != TypeAttribute && !ft.isNumeric() {
+		return fmt.Errorf("metrics field expressions must resolve to a number type: %s", t.String())
+	}
+	if t.Quantile < 0 || t.Quantile > 1 || math.IsNaN(t.Quantile) {
+		return fmt.Errorf("quantile must be between 0 and 1: %s", t.String())
+	}
+
+	return nil
+}
+
+// MetricsThresholdValues are the values of a threshold computed in the first phase of a query. Values of a threshold
+// with By are keyed by the value of the attribute, the value of a threshold without By is keyed by the nil static.
+// Durations are in seconds.
+type MetricsThresholdValues map[Static]float64
+
+// MetricsThresholds returns the thresholds referenced by the query in the order they appear in the query.
+func (r *RootExpr) MetricsThresholds() []MetricsThreshold {
+	var thresholds []MetricsThreshold
+	_, _ = r.Pipeline.mapFieldExpressions(func(e FieldExpression) (FieldExpression, error) {
+		return transformFieldExpression(e, func(e FieldExpression) FieldExpression {
+			if t, ok := e.(MetricsThreshold); ok {
+				thresholds = append(thresholds, t)
+			}
+			return e
+		}), nil
+	})
+
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i].index < thresholds[j].index
+	})
+	return thresholds
+}
+
+// MetricsThresholdFilter returns the query the values of the thresholds are computed from in the first phase. It
+// matches the spans of the query without the conditions referencing a threshold, e.g. the spans of
+// { .service.name = "api" && duration > quantile_over_time(duration, 0.99) } are { .service.name = "api" && true }.
+// Metrics functions of the query are dropped.
+func (r *RootExpr) MetricsThresholdFilter() (*RootExpr, error) {
+	p, err := r.Pipeline.mapFieldExpressions(func(e FieldExpression) (FieldExpression, error) {
+		return resolveMetricsThreshold(e, func(t MetricsThreshold, condition BinaryOperation) FieldExpression {
+			return newStaticBool(true)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]Element, 0, len(p.Elements))
+	for _, e := range p.Elements {
+		if _, ok := e.(MetricsAggregate); !ok {
+			elements = append(elements, e)
+		}
+	}
+
+	return &RootExpr{Pipeline: newPipeline(elements...)}, nil
+}
+
+// ResolveMetricsThresholds returns the query of the second phase, values are the values of the thresholds in the
+// order returned by MetricsThresholds. The conditions referencing a threshold compare with its value. Conditions of
+// thresholds with By are expanded to a condition per group, e.g. duration > quantile_over_time(duration, 0.99) by(name)
+// becomes (name = "a" && duration > 1s) || (name = "b" && duration > 2s). Spans of groups without a value don't match.
+func (r *RootExpr) ResolveMetricsThresholds(values []MetricsThresholdValues) (*RootExpr, error) {
+	thresholds := r.MetricsThresholds()
+	if len(values) != len(thresholds) {
+		return nil, fmt.Errorf("query has %d thresholds, got values of %d", len(thresholds), len(values))
+	}
+
+	p, err := r.Pipeline.mapFieldExpressions(func(e FieldExpression) (FieldExpression, error) {
+		return resolveMetricsThreshold(e, func(t MetricsThreshold, condition BinaryOperation) FieldExpression {
+			return expandMetricsThreshold(t, condition, values[t.index])
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RootExpr{Pipeline: p}, nil
+}
+
+// resolveMetricsThreshold replaces the innermost conditions referencing a threshold with the result of resolve.
+// Conditions referencing several thresholds are resolved once per threshold.
+func resolveMetricsThreshold(e FieldExpression, resolve func(t MetricsThreshold, condition BinaryOperation) FieldExpression) (FieldExpression, error) {
+	var err error
+	e = transformFieldExpression(e, func(e FieldExpression) FieldExpression {
+		condition, ok := e.(BinaryOperation)
+		if !ok || !condition.Op.isBoolean() {
+			return e
+		}
+
+		for {
+			t, ok := findMetricsThreshold(condition)
+			if !ok {
+				return condition
+			}
+
+			resolved := resolve(t, condition)
+			if condition, ok = resolved.(BinaryOperation); !ok {
+				return resolved
+			}
+		}
+	})
+
+	if t, ok := findMetricsThreshold(e); ok {
+		err = fmt.Errorf("metrics thresholds must be compared: %s", t.String())
+	}
+	return e, err
+}
+
+// expandMetricsThreshold returns the condition comparing with the values of the threshold.
+func expandMetricsThreshold(t MetricsThreshold, condition BinaryOperation, values MetricsThresholdValues) FieldExpression {
+	withValue := func(v float64) FieldExpression {
+		static := newStaticFloat(v)
+		if t.impliedType() == TypeDuration {
+			static = newStaticDuration(time.Duration(v * float64(time.Second)))
+		}
+		return transformFieldExpression(condition, func(e FieldExpression) FieldExpression {
+			if other, ok := e.(MetricsThreshold); ok && other.index == t.index {
+				return static
+			}
+			return e
+		})
+	}
+
+	if t.By == nil {
+		v, ok := values[newStaticNil()]
+		if !ok {
+			return newStaticBool(false)
+		}
+		return withValue(v)
+	}
+
+	groups := make([]Static, 0, len(values))
+	for g := range values {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].String() < groups[j].String()
+	})
+
+	var expanded FieldExpression = newStaticBool(false)
+	for i, g := range groups {
+		group := newBinaryOperation(OpAnd, newBinaryOperation(OpEqual, *t.By, g), withValue(values[g]))
+		if i == 0 {
+			expanded = group
+			continue
+		}
+		expanded = newBinaryOperation(OpOr, expanded, group)
+	}
+	return expanded
+}
+
+func findMetricsThreshold(e FieldExpression) (MetricsThreshold, bool) {
+	var (
+		found MetricsThreshold
+		ok    bool
+	)
+	transformFieldExpression(e, func(e FieldExpression) FieldExpression {
+		if t, isThreshold := e.(MetricsThreshold); isThreshold && !ok {
+			found, ok = t, true
+		}
+		return e
+	})
+	return found, ok
+}
+
+// transformFieldExpression rebuilds the field expression bottom up, f is called with every node after its children
+// have been transformed.
+func transformFieldExpression(e FieldExpression, f func(FieldExpression) FieldExpression) FieldExpression {
+	switch o := e.(type) {
+	case BinaryOperation:
+		e = newBinaryOperation(o.Op, transformFieldExpression(o.LHS, f), transformFieldExpression(o.RHS, f))
+	case UnaryOperation:
+		e = newUnaryOperation(o.Op, transformFieldExpression(o.Expression, f))
+	case CoalesceExpression:
+		expressions := make([]FieldExpression, 0, len(o.Expressions))
+		for _, c := range o.Expressions {
+			expressions = append(expressions, transformFieldExpression(c, f))
+		}
+		e = newCoalesceExpression(expressions)
+	}
+	return f(e)
+}
+
+// mapFieldExpressions returns the pipeline with the field expressions of its elements replaced by f.
+func (p Pipeline) mapFieldExpressions(f func(FieldExpression) (FieldExpression, error)) (Pipeline, error) {
+	elements := make([]Element, 0, len(p.Elements))
+	for _, e := range p.Elements {
+		mapped, err := mapElementFieldExpressions(e, f)
+		if err != nil {
+			return Pipeline{}, err
+		}
+		elements = append(elements, mapped)
+	}
+	return newPipeline(elements...), nil
+}
+
+func mapElementFieldExpressions(e Element, f func(FieldExpression) (FieldExpression, error)) (Element, error) {
+	var err error
+	switch o := e.(type) {
+	case Pipeline:
+		return o.mapFieldExpressions(f)
+	case SpansetFilter:
+		o.Expression, err = f(o.Expression)
+		return o, err
+	case SpansetOperation:
+		lhs, err := mapElementFieldExpressions(o.LHS, f)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := mapElementFieldExpressions(o.RHS, f)
+		if err != nil {
+			return nil, err
+		}
+		return newSpansetOperation(o.Op, lhs.(SpansetExpression), rhs.(SpansetExpression)), nil
+	case ScalarFilter:
+		lhs, err := mapElementFieldExpressions(o.lhs, f)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := mapElementFieldExpressions(o.rhs, f)
+		if err != nil {
+			return nil, err
+		}
+		return newScalarFilter(o.op, lhs.(ScalarExpression), rhs.(ScalarExpression)), nil
+	case ScalarOperation:
+		lhs, err := mapElementFieldExpressions(o.LHS, f)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := mapElementFieldExpressions(o.RHS, f)
+		if err != nil {
+			return nil, err
+		}
+		return newScalarOperation(o.Op, lhs.(ScalarExpression), rhs.(ScalarExpression)), nil
+	case GroupOperation:
+		o.Expression, err = f(o.Expression)
+		return o, err
+	case Aggregate:
+		if o.e != nil {
+			o.e, err = f(o.e)
+		}
+		return o, err
+	}
+	return e, nil
+}
+
+// Quantiles computes the values of a threshold from the spans of the first phase of a query. The values of every
+// group are summarized in sketches, the quantiles are within the relative accuracy of the sketches.
+type Quantiles struct {
+	quantile float64
+	groups   map[Static]*quantileSketches
+}
+
+// quantileSketches summarizes the values of a group, sketches only hold non-negative values so the magnitudes of
+// negative values are kept apart.
+type quantileSketches struct {
+	positive *ddsketch.Sketch
+	negative *ddsketch.Sketch
+}
+
+// NewQuantiles creates an accumulator of the quantile of a threshold.
+func NewQuantiles(t MetricsThreshold) *Quantiles {
+	return &Quantiles{
+		quantile: t.Quantile,
+		groups:   map[Static]*quantileSketches{},
+	}
+}
+
+// Observe records the value of a span of the group. The group is the value of the By attribute of the threshold
+// for the span, or nil for thresholds without By. Durations are expected in seconds.
+func (q *Quantiles) Observe(group Static, value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+
+	s := q.group(group)
+	if value < 0 {
+		s.negative.Add(-value * quantileScale)
+		return
+	}
+	s.positive.Add(value * quantileScale)
+}
+
+// Merge records the values summarized in a sketch for the group. Values in the sketch are expected in nanoseconds
+// if they are durations and in billionths otherwise, sketches of span durations can be merged unchanged.
+func (q *Quantiles) Merge(group Static, sketch *ddsketch.Sketch) {
+	if sketch == nil || sketch.Count == 0 {
+		return
+	}
+
+	q.group(group).positive.Merge(sketch)
+}
+
+// Values returns the quantile of every group that has been observed.
+func (q *Quantiles) Values() MetricsThresholdValues {
+	values := make(MetricsThresholdValues, len(q.groups))
+	for g, s := range q.groups {
+		values[g] = s.quantile(q.quantile) / quantileScale
+	}
+	return values
+}
+
+func (q *Quantiles) group(group Static) *quantileSketches {
+	s, ok := q.groups[group]
+	if !ok {
+		s = &quantileSketches{
+			positive: ddsketch.New(),
+			negative: ddsketch.New(),
+		}
+		q.groups[group] = s
+	}
+	return s
+}
+
+func (s *quantileSketches) quantile(q float64) float64 {
+	negative := s.negative.Count
+	rank := uint64(q * float64(negative+s.positive.Count-1))
+	if rank < negative {
+		// the negative values in ascending order are their magnitudes in descending order
+		return -s.negative.Quantile(sketchQuantile(negative-1-rank, negative))
+	}
+	return s.positive.Quantile(sketchQuantile(rank-negative, s.positive.Count))
+}
+
+// sketchQuantile returns the quantile of the value with the given rank in a sketch of count values.
+func sketchQuantile(rank, count uint64) float64 {
+	if count <= 1 {
+		return 0
+	}
+	// the sketch rounds the rank down, round up here so the rank is kept despite floating point errors
+	return math.Nextafter(float64(rank)/float64(count-1), 1)
+}
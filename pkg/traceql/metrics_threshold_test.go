@@ -0,0 +1,203 @@
+package traceql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/ddsketch"
+)
+
+func TestParseMetricsThreshold(t *testing.T) {
+	name := newIntrinsic(IntrinsicName)
+
+	tests := []struct {
+		in       string
+		expected *RootExpr
+	}{
+		{
+			in: "{ duration > quantile_over_time(duration, 0.99) }",
+			expected: &RootExpr{
+				Pipeline: newPipeline(
+					newSpansetFilter(newBinaryOperation(OpGreater, newIntrinsic(IntrinsicDuration),
+						MetricsThreshold{Field: newIntrinsic(IntrinsicDuration), Quantile: 0.99})),
+				),
+			},
+		},
+		{
+			in: `{ .a = "quantile_over_time(" && duration > quantile_over_time(duration, .9) by (name) } | histogram_over_time(duration)`,
+			expected: &RootExpr{
+				Pipeline: newPipeline(
+					newSpansetFilter(newBinaryOperation(OpAnd,
+						newBinaryOperation(OpEqual, newAttribute("a"), newStaticString("quantile_over_time(")),
+						newBinaryOperation(OpGreater, newIntrinsic(IntrinsicDuration),
+							MetricsThreshold{Field: newIntrinsic(IntrinsicDuration), Quantile: 0.9, By: &name}))),
+					newMetricsAggregate(metricsAggregateHistogramOverTime, newIntrinsic(IntrinsicDuration)),
+				),
+			},
+		},
+		{
+			in: "{ .a > quantile_over_time(.a, 0.5) } && { .b < quantile_over_time(.b, 0.5) }",
+			expected: &RootExpr{
+				Pipeline: newPipeline(
+					newSpansetOperation(OpSpansetAnd,
+						newSpansetFilter(newBinaryOperation(OpGreater, newAttribute("a"),
+							MetricsThreshold{Field: newAttribute("a"), Quantile: 0.5})),
+						newSpansetFilter(newBinaryOperation(OpLess, newAttribute("b"),
+							MetricsThreshold{Field: newAttribute("b"), Quantile: 0.5, index: 1})),
+					),
+				),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			actual, err := Parse(tc.in)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+
+			// the query can be parsed from its string
+			reparsed, err := Parse(actual.String())
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, reparsed)
+		})
+	}
+}
+
+func TestMetricsThresholdFilter(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{
+			in:       "{ duration > quantile_over_time(duration, 0.99) }",
+			expected: "{ true }",
+		},
+		{
+			in:       `{ .service.name = "api" && duration > quantile_over_time(duration, 0.99) by(name) } | histogram_over_time(duration)`,
+			expected: "{ (.service.name = `api`) && true }",
+		},
+		{
+			in:       "{ .a } && { !(.b * 2 > quantile_over_time(.b, 0.5)) }",
+			expected: "({ .a }) && ({ !true })",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			expr, err := Parse(tc.in)
+			require.NoError(t, err)
+
+			filter, err := expr.MetricsThresholdFilter()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, filter.String())
+		})
+	}
+}
+
+func TestResolveMetricsThresholds(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		values   []MetricsThresholdValues
+		expected string
+	}{
+		{
+			name:     "without group",
+			in:       "{ duration > quantile_over_time(duration, 0.99) }",
+			values:   []MetricsThresholdValues{{newStaticNil(): 1.5}},
+			expected: "{ duration > 1500ms }",
+		},
+		{
+			name:     "without value",
+			in:       "{ .a && duration > quantile_over_time(duration, 0.99) }",
+			values:   []MetricsThresholdValues{{}},
+			expected: "{ .a && false }",
+		},
+		{
+			name: "by group",
+			in:   "{ duration > quantile_over_time(duration, 0.99) by(name) } | histogram_over_time(duration)",
+			values: []MetricsThresholdValues{{
+				newStaticString("POST"): 0.25,
+				newStaticString("GET"):  2,
+			}},
+			expected: "{ ((name = `GET`) && (duration > 2s)) || ((name = `POST`) && (duration > 250ms)) }|histogram_over_time(duration)",
+		},
+		{
+			name: "several thresholds",
+			in:   "{ .a > quantile_over_time(.a, 0.5) && .b < quantile_over_time(.b, 0.5) }",
+			values: []MetricsThresholdValues{
+				{newStaticNil(): 1},
+				{newStaticNil(): 2},
+			},
+			expected: "{ (.a > 1.00000) && (.b < 2.00000) }",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := Parse(tc.in)
+			require.NoError(t, err)
+
+			resolved, err := expr.ResolveMetricsThresholds(tc.values)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, resolved.String())
+			assert.Empty(t, resolved.MetricsThresholds())
+
+			_, err = Parse(resolved.String())
+			require.NoError(t, err)
+		})
+	}
+
+	expr, err := Parse("{ duration > quantile_over_time(duration, 0.99) }")
+	require.NoError(t, err)
+	_, err = expr.ResolveMetricsThresholds(nil)
+	require.Error(t, err)
+}
+
+func TestQuantiles(t *testing.T) {
+	name := newIntrinsic(IntrinsicName)
+	q := NewQuantiles(MetricsThreshold{Field: newIntrinsic(IntrinsicDuration), Quantile: 0.9, By: &name})
+
+	get, post, put := newStaticString("GET"), newStaticString("POST"), newStaticString("PUT")
+	for i := 10; i > 0; i-- {
+		q.Observe(get, (time.Duration(i) * 100 * time.Millisecond).Seconds())
+	}
+	q.Observe(post, 3)
+	// negative values are ordered before positive ones
+	for _, v := range []float64{-10, -20, -30, -40, 5} {
+		q.Observe(put, v)
+	}
+
+	values := q.Values()
+	require.Len(t, values, 3)
+	assert.InEpsilon(t, 0.9, values[get], 0.02)
+	assert.InEpsilon(t, 3, values[post], 0.02)
+	assert.InEpsilon(t, -10, values[put], 0.02)
+
+	q = NewQuantiles(MetricsThreshold{Field: newAttribute("a"), Quantile: 0.25})
+	for _, v := range []float64{-10, -20, -30, -40, 5} {
+		q.Observe(newStaticNil(), v)
+	}
+	assert.InEpsilon(t, -30, q.Values()[newStaticNil()], 0.02)
+}
+
+func TestQuantilesMerge(t *testing.T) {
+	q := NewQuantiles(MetricsThreshold{Field: newIntrinsic(IntrinsicDuration), Quantile: 0.5})
+
+	// sketches of span durations are in nanoseconds
+	sketch := ddsketch.New()
+	for _, d := range []time.Duration{time.Second, 2 * time.Second} {
+		sketch.Add(float64(d))
+	}
+	q.Merge(newStaticNil(), sketch)
+	q.Merge(newStaticNil(), nil)
+	q.Observe(newStaticNil(), 3)
+
+	values := q.Values()
+	require.Len(t, values, 1)
+	assert.InEpsilon(t, 2, values[newStaticNil()], ddsketch.RelativeAccuracy)
+}
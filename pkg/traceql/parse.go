@@ -29,6 +29,7 @@ func Parse(s string) (expr *RootExpr, err error) {
 			}
 		}
 	}()
+
 	l := lexer{
 		parser: yyNewParser().(*yyParserImpl),
 	}
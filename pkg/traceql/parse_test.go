@@ -77,7 +77,7 @@ func TestPipelineOperatorPrecedence(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(tc.expected)}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(tc.expected)}, actual)
 		})
 	}
 }
@@ -133,7 +133,7 @@ func TestPipelineSpansetOperators(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(tc.expected)}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(tc.expected)}, actual)
 		})
 	}
 }
@@ -215,7 +215,7 @@ func TestPipelineScalarOperators(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(tc.expected)}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(tc.expected)}, actual)
 		})
 	}
 }
@@ -255,7 +255,7 @@ func TestPipelines(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{tc.expected}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: tc.expected}, actual)
 		})
 	}
 }
@@ -293,7 +293,72 @@ func TestGroupCoalesceOperation(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{tc.expected}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: tc.expected}, actual)
+		})
+	}
+}
+
+func TestCoalesceExpression(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected FieldExpression
+	}{
+		{
+			in: `{ coalesce(span.http.route, span.http.target, "unknown") = "/api" }`,
+			expected: newBinaryOperation(OpEqual,
+				newCoalesceExpression([]FieldExpression{
+					newScopedAttribute(AttributeScopeSpan, false, "http.route"),
+					newScopedAttribute(AttributeScopeSpan, false, "http.target"),
+					newStaticString("unknown"),
+				}),
+				newStaticString("/api"),
+			),
+		},
+		{
+			in: `{ .a default .b default "c" = "d" }`,
+			expected: newBinaryOperation(OpEqual,
+				newCoalesceExpression([]FieldExpression{
+					newAttribute("a"),
+					newAttribute("b"),
+					newStaticString("c"),
+				}),
+				newStaticString("d"),
+			),
+		},
+		{
+			in: `{ .a default 1 + 2 > 3 }`,
+			expected: newBinaryOperation(OpGreater,
+				newCoalesceExpression([]FieldExpression{
+					newAttribute("a"),
+					newBinaryOperation(OpAdd, newStaticInt(1), newStaticInt(2)),
+				}),
+				newStaticInt(3),
+			),
+		},
+		{
+			in: `{ coalesce(.a,.b) && coalesce(.c, .d default .e) }`,
+			expected: newBinaryOperation(OpAnd,
+				newCoalesceExpression([]FieldExpression{
+					newAttribute("a"),
+					newAttribute("b"),
+				}),
+				newCoalesceExpression([]FieldExpression{
+					newAttribute("c"),
+					newCoalesceExpression([]FieldExpression{
+						newAttribute("d"),
+						newAttribute("e"),
+					}),
+				}),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			actual, err := Parse(tc.in)
+
+			assert.NoError(t, err)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(newSpansetFilter(tc.expected))}, actual)
 		})
 	}
 }
@@ -362,7 +427,7 @@ func TestSpansetExpressionPrecedence(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(tc.expected)}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(tc.expected)}, actual)
 		})
 	}
 }
@@ -386,7 +451,7 @@ func TestSpansetExpressionOperators(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(tc.expected)}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(tc.expected)}, actual)
 		})
 	}
 }
@@ -443,7 +508,7 @@ func TestScalarExpressionPrecedence(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(tc.expected)}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(tc.expected)}, actual)
 		})
 	}
 }
@@ -465,7 +530,7 @@ func TestScalarExpressionOperators(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(tc.expected)}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(tc.expected)}, actual)
 		})
 	}
 }
@@ -604,7 +669,7 @@ func TestSpansetFilterOperatorPrecedence(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(newSpansetFilter(tc.expected))}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(newSpansetFilter(tc.expected))}, actual)
 		})
 	}
 }
@@ -639,7 +704,7 @@ func TestSpansetFilterStatics(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(newSpansetFilter(tc.expected))}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(newSpansetFilter(tc.expected))}, actual)
 		})
 	}
 }
@@ -675,7 +740,7 @@ func TestSpansetFilterOperators(t *testing.T) {
 			actual, err := Parse(tc.in)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(newSpansetFilter(tc.expected))}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(newSpansetFilter(tc.expected))}, actual)
 		})
 	}
 }
@@ -734,25 +799,25 @@ func TestAttributes(t *testing.T) {
 			actual, err := Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(newSpansetFilter(tc.expected))}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(newSpansetFilter(tc.expected))}, actual)
 
 			s = "{" + tc.in + "}"
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(newSpansetFilter(tc.expected))}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(newSpansetFilter(tc.expected))}, actual)
 
 			s = "{ (" + tc.in + ") }"
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(newSpansetFilter(tc.expected))}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(newSpansetFilter(tc.expected))}, actual)
 
 			s = "{ " + tc.in + " + " + tc.in + " }"
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(newSpansetFilter(newBinaryOperation(OpAdd, tc.expected, tc.expected)))}, actual)
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(newSpansetFilter(newBinaryOperation(OpAdd, tc.expected, tc.expected)))}, actual)
 		})
 	}
 }
@@ -776,7 +841,7 @@ func TestIntrinsics(t *testing.T) {
 			actual, err := Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(
 				newSpansetFilter(Attribute{
 					Scope:     AttributeScopeNone,
 					Parent:    false,
@@ -789,7 +854,7 @@ func TestIntrinsics(t *testing.T) {
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(
 				newSpansetFilter(Attribute{
 					Scope:     AttributeScopeNone,
 					Parent:    false,
@@ -802,7 +867,7 @@ func TestIntrinsics(t *testing.T) {
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(
 				newSpansetFilter(Attribute{
 					Scope:     AttributeScopeSpan,
 					Parent:    false,
@@ -815,7 +880,7 @@ func TestIntrinsics(t *testing.T) {
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(
 				newSpansetFilter(Attribute{
 					Scope:     AttributeScopeResource,
 					Parent:    false,
@@ -828,7 +893,7 @@ func TestIntrinsics(t *testing.T) {
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(
 				newSpansetFilter(Attribute{
 					Scope:     AttributeScopeNone,
 					Parent:    true,
@@ -842,7 +907,7 @@ func TestIntrinsics(t *testing.T) {
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(
 				newSpansetFilter(Attribute{
 					Scope:     AttributeScopeNone,
 					Parent:    true,
@@ -855,7 +920,7 @@ func TestIntrinsics(t *testing.T) {
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(
 				newSpansetFilter(Attribute{
 					Scope:     AttributeScopeResource,
 					Parent:    true,
@@ -868,7 +933,7 @@ func TestIntrinsics(t *testing.T) {
 			actual, err = Parse(s)
 
 			assert.NoError(t, err)
-			assert.Equal(t, &RootExpr{newPipeline(
+			assert.Equal(t, &RootExpr{Pipeline: newPipeline(
 				newSpansetFilter(Attribute{
 					Scope:     AttributeScopeSpan,
 					Parent:    true,
@@ -0,0 +1,73 @@
+package traceql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		in            string
+		expectedSince time.Duration
+		expectedErr   error
+	}{
+		{
+			in: "{ .a }",
+		},
+		{
+			in:            "{ .a } since 2h",
+			expectedSince: 2 * time.Hour,
+		},
+		{
+			in:            "{ .a } | count() > 1 since 1d",
+			expectedSince: 24 * time.Hour,
+		},
+		{
+			in:            "{ .a }since 1h30m",
+			expectedSince: 90 * time.Minute,
+		},
+		{
+			in: `{ .since = "since 2h" } | by(.since)`,
+		},
+		{
+			in:            "{ .a } | by(.b) | histogram_over_time(duration) since 15m",
+			expectedSince: 15 * time.Minute,
+		},
+		{
+			in:          "{ .a } since",
+			expectedErr: newParseError("syntax error: unexpected $end, expecting DURATION", 1, 13),
+		},
+		{
+			in:          "{ .a } since foo",
+			expectedErr: newParseError("syntax error: unexpected IDENTIFIER, expecting DURATION", 1, 14),
+		},
+		{
+			in:          "{ .a } since 0s",
+			expectedErr: newParseError("syntax error: since requires a positive duration", 0, 0),
+		},
+		{
+			in:          "{ .a } since 2h | { .b }",
+			expectedErr: newParseError("syntax error: unexpected |", 1, 17),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			actual, err := Parse(tc.in)
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSince, actual.Since)
+
+			// the range is kept when the query is written
+			reparsed, err := Parse(actual.String())
+			require.NoError(t, err)
+			assert.Equal(t, actual, reparsed)
+		})
+	}
+}
@@ -102,6 +102,16 @@ func (c *Client) SearchTagValues(key string) (*tempopb.SearchTagValuesResponse,
 	return m, nil
 }
 
+func (c *Client) SearchTagValuesV2(key string) (*tempopb.SearchTagValuesV2Response, error) {
+	m := &tempopb.SearchTagValuesV2Response{}
+	_, err := c.getFor(c.BaseURL+"/api/v2/search/tag/"+key+"/values", m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
 // Search Tempo. tags must be in logfmt format, that is "key1=value1 key2=value2"
 func (c *Client) Search(tags string) (*tempopb.SearchResponse, error) {
 	m := &tempopb.SearchResponse{}
@@ -125,6 +135,18 @@ func (c *Client) SearchWithRange(tags string, start int64, end int64) (*tempopb.
 	return m, nil
 }
 
+// SearchTraceQLWithRange calls the /api/search endpoint with a TraceQL query. start/end are unix epoch timestamps in
+// seconds.
+func (c *Client) SearchTraceQLWithRange(query string, start int64, end int64) (*tempopb.SearchResponse, error) {
+	m := &tempopb.SearchResponse{}
+	_, err := c.getFor(c.BaseURL+"/api/search?q="+url.QueryEscape(query)+"&start="+strconv.FormatInt(start, 10)+"&end="+strconv.FormatInt(end, 10), m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
 func (c *Client) QueryTrace(id string) (*tempopb.Trace, error) {
 	m := &tempopb.Trace{}
 	resp, err := c.getFor(c.BaseURL+QueryTraceEndpoint+"/"+id, m)
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"sort"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+)
+
+type typedValue struct {
+	typ   string
+	value string
+}
+
+// DistinctValueCollector counts the occurrences of distinct typed tag values. Like the DistinctStringCollector the
+// values are limited by their total length, the occurrences of values that were collected are always counted.
+type DistinctValueCollector struct {
+	values   map[typedValue]uint64
+	maxLen   int
+	currLen  int
+	totalLen int
+}
+
+// NewDistinctValueCollector with the given maximum data size. This is calculated as the total length of the
+// recorded values. maximum=0 is interpreted as unlimited.
+func NewDistinctValueCollector(maxDataSize int) *DistinctValueCollector {
+	return &DistinctValueCollector{
+		values: make(map[typedValue]uint64),
+		maxLen: maxDataSize,
+	}
+}
+
+// Collect adds count occurrences of the value of the type
+func (d *DistinctValueCollector) Collect(typ, value string, count uint64) {
+	k := typedValue{typ: typ, value: value}
+	if _, ok := d.values[k]; ok {
+		d.values[k] += count
+		return
+	}
+
+	d.totalLen += len(value)
+	if d.maxLen > 0 && d.currLen+len(value) > d.maxLen {
+		return
+	}
+
+	d.values[k] = count
+	d.currLen += len(value)
+}
+
+// Values returns the collected values sorted by value and type
+func (d *DistinctValueCollector) Values() []*tempopb.TagValue {
+	values := make([]*tempopb.TagValue, 0, len(d.values))
+	for k, count := range d.values {
+		values = append(values, &tempopb.TagValue{Type: k.typ, Value: k.value, Count: count})
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Value != values[j].Value {
+			return values[i].Value < values[j].Value
+		}
+		return values[i].Type < values[j].Type
+	})
+	return values
+}
+
+// Exceeded indicates if some values were lost because the maximum size limit was met.
+func (d *DistinctValueCollector) Exceeded() bool {
+	return d.totalLen > d.currLen
+}
+
+// TotalDataSize is the total size of all distinct values encountered.
+func (d *DistinctValueCollector) TotalDataSize() int {
+	return d.totalLen
+}
@@ -0,0 +1,26 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+)
+
+func TestDistinctValueCollector(t *testing.T) {
+	d := NewDistinctValueCollector(10)
+
+	d.Collect("int", "123", 1)
+	d.Collect("string", "4567", 2)
+	d.Collect("string", "123", 1)
+	d.Collect("int", "123", 3)
+	d.Collect("string", "11", 1)
+
+	require.True(t, d.Exceeded())
+	require.Equal(t, []*tempopb.TagValue{
+		{Type: "int", Value: "123", Count: 4},
+		{Type: "string", Value: "123", Count: 1},
+		{Type: "string", Value: "4567", Count: 2},
+	}, d.Values())
+}
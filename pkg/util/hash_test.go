@@ -0,0 +1,31 @@
+package util
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// BenchmarkTokenForTraceID measures the hash of the trace ids of pushes and bloom filter lookups
+func BenchmarkTokenForTraceID(b *testing.B) {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = TokenForTraceID(id)
+	}
+}
+
+// BenchmarkCompareTraceIDs measures the comparison of the trace ids of lookups and merges
+func BenchmarkCompareTraceIDs(b *testing.B) {
+	id1 := make([]byte, 16)
+	rand.Read(id1)
+	id2 := append([]byte{}, id1...)
+	id2[15]++
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = bytes.Compare(id1, id2)
+	}
+}
@@ -24,6 +24,9 @@ func InitLogger(cfg *server.Config) {
 		logger = kitlog.NewJSONLogger(kitlog.NewSyncWriter(os.Stderr))
 	}
 
+	// keep the recent errors for the status dashboard
+	logger = newRecentErrorsLogger(logger, errorLog)
+
 	// add support for level based logging
 	logger = level.NewFilter(logger, LevelFilter(cfg.LogLevel.String()))
 
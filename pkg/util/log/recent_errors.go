@@ -0,0 +1,83 @@
+package log
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+const recentErrorsCapacity = 20
+
+// RecentError is an error level log line
+type RecentError struct {
+	Time time.Time
+	Line string
+}
+
+type recentErrors struct {
+	mtx     sync.Mutex
+	entries []RecentError
+	next    int
+}
+
+var errorLog = &recentErrors{}
+
+// RecentErrors returns the most recent error level log lines of the global logger, newest first.
+func RecentErrors() []RecentError {
+	return errorLog.list()
+}
+
+func (r *recentErrors) add(e RecentError) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if len(r.entries) < recentErrorsCapacity {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % recentErrorsCapacity
+}
+
+func (r *recentErrors) list() []RecentError {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	list := make([]RecentError, 0, len(r.entries))
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		list = append(list, r.entries[(r.next+i)%len(r.entries)])
+	}
+	return list
+}
+
+// recentErrorsLogger records the error level log lines passing through it in recentErrors
+type recentErrorsLogger struct {
+	next   kitlog.Logger
+	errors *recentErrors
+}
+
+func newRecentErrorsLogger(next kitlog.Logger, errors *recentErrors) kitlog.Logger {
+	return &recentErrorsLogger{
+		next:   next,
+		errors: errors,
+	}
+}
+
+func (l *recentErrorsLogger) Log(keyvals ...interface{}) error {
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if keyvals[i] == level.Key() && keyvals[i+1] == level.ErrorValue() {
+			buf := &bytes.Buffer{}
+			_ = kitlog.NewLogfmtLogger(buf).Log(keyvals...)
+			l.errors.add(RecentError{
+				Time: time.Now(),
+				Line: string(bytes.TrimSpace(buf.Bytes())),
+			})
+			break
+		}
+	}
+
+	return l.next.Log(keyvals...)
+}
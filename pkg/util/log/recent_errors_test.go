@@ -0,0 +1,32 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentErrorsLogger(t *testing.T) {
+	errors := &recentErrors{}
+	logger := newRecentErrorsLogger(kitlog.NewNopLogger(), errors)
+
+	level.Info(logger).Log("msg", "info")
+	require.Len(t, errors.list(), 0)
+
+	for i := 0; i < recentErrorsCapacity+5; i++ {
+		level.Error(logger).Log("msg", "failed", "i", i)
+	}
+
+	// the newest errors are kept, newest first
+	list := errors.list()
+	require.Len(t, list, recentErrorsCapacity)
+	for i, e := range list {
+		assert.True(t, strings.HasSuffix(e.Line, fmt.Sprintf("i=%d", recentErrorsCapacity+4-i)), e.Line)
+		assert.True(t, strings.HasPrefix(e.Line, "level=error msg=failed"), e.Line)
+	}
+}
@@ -7,9 +7,12 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/grafana/tempo/tempodb/backend/credentials"
 	"github.com/grafana/tempo/tempodb/backend/instrumentation"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 
 	"github.com/Azure/azure-pipeline-go/pipeline"
 	blob "github.com/Azure/azure-storage-blob-go/azblob"
@@ -20,6 +23,13 @@ import (
 
 const (
 	maxRetries = 1
+
+	// well known account and key of the Azurite emulator, https://github.com/Azure/Azurite#default-storage-account
+	azuriteAccountName = "devstoreaccount1"
+	azuriteAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+	defaultAuthorityHost = "https://login.microsoftonline.com/"
+	clientAssertionType  = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
 )
 
 func GetContainerURL(ctx context.Context, cfg *Config, hedge bool) (blob.ContainerURL, error) {
@@ -44,6 +54,11 @@ func GetContainerURL(ctx context.Context, cfg *Config, hedge bool) (blob.Contain
 	transport := instrumentation.NewTransport(customTransport)
 	var stats *hedgedhttp.Stats
 
+	// adapt the concurrency of reads to throttling, hedged requests count against the limit
+	if hedge {
+		transport = throttle.NewTransport(cfg.AdaptiveConcurrency, transport)
+	}
+
 	// hedge if desired (0 means disabled)
 	if hedge && cfg.HedgeRequestsAt != 0 {
 		transport, stats, err = hedgedhttp.NewRoundTripperAndStats(cfg.HedgeRequestsAt, cfg.HedgeRequestsUpTo, transport)
@@ -71,7 +86,14 @@ func GetContainerURL(ctx context.Context, cfg *Config, hedge bool) (blob.Contain
 		HTTPSender: httpSender,
 	}
 
-	if !cfg.UseManagedIdentity && cfg.UserAssignedID == "" {
+	if cfg.CredentialsPlugin.Enabled() {
+		credential, err := getPluginToken(ctx, cfg)
+		if err != nil {
+			return blob.ContainerURL{}, err
+		}
+
+		p = blob.NewPipeline(*credential, opts)
+	} else if !useOAuth(cfg) {
 		credential, err := blob.NewSharedKeyCredential(getStorageAccountName(cfg), getStorageAccountKey(cfg))
 		if err != nil {
 			return blob.ContainerURL{}, err
@@ -90,9 +112,8 @@ func GetContainerURL(ctx context.Context, cfg *Config, hedge bool) (blob.Contain
 	accountName := getStorageAccountName(cfg)
 	u, err := url.Parse(fmt.Sprintf("https://%s.%s", accountName, cfg.Endpoint))
 
-	// If the endpoint doesn't start with blob.core we can assume Azurite is being used
-	// So the endpoint should follow Azurite URL style
-	if !strings.HasPrefix(cfg.Endpoint, "blob.core") {
+	// Azurite uses path style URLs
+	if isAzurite(cfg) {
 		u, err = url.Parse(fmt.Sprintf("http://%s/%s", cfg.Endpoint, accountName))
 	}
 
@@ -133,11 +154,25 @@ func CreateContainer(ctx context.Context, conf *Config) (blob.ContainerURL, erro
 	return c, err
 }
 
+// isAzurite returns true if the endpoint doesn't start with blob.core, in which case we assume the Azurite
+// emulator is being used
+func isAzurite(cfg *Config) bool {
+	return cfg.Endpoint != "" && !strings.HasPrefix(cfg.Endpoint, "blob.core")
+}
+
+// useOAuth returns true if any of the Azure AD credentials is configured instead of a shared key
+func useOAuth(cfg *Config) bool {
+	return cfg.UseManagedIdentity || cfg.UseFederatedToken || cfg.UserAssignedID != ""
+}
+
 func getStorageAccountName(cfg *Config) string {
 	accountName := cfg.StorageAccountName
 	if accountName == "" {
 		accountName = os.Getenv("AZURE_STORAGE_ACCOUNT")
 	}
+	if accountName == "" && isAzurite(cfg) {
+		accountName = azuriteAccountName
+	}
 
 	return accountName
 }
@@ -147,18 +182,54 @@ func getStorageAccountKey(cfg *Config) string {
 	if accountKey == "" {
 		accountKey = os.Getenv("AZURE_STORAGE_KEY")
 	}
+	if accountKey == "" && isAzurite(cfg) && getStorageAccountName(cfg) == azuriteAccountName {
+		accountKey = azuriteAccountKey
+	}
 
 	return accountKey
 }
 
-func getOAuthToken(cfg *Config) (*blob.TokenCredential, error) {
-	spt, err := getServicePrincipalToken(cfg)
-	if err != nil {
-		return nil, err
+func getClientID(cfg *Config) string {
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = os.Getenv("AZURE_CLIENT_ID")
+	}
+
+	return clientID
+}
+
+func getTenantID(cfg *Config) string {
+	tenantID := cfg.TenantID
+	if tenantID == "" {
+		tenantID = os.Getenv("AZURE_TENANT_ID")
 	}
 
-	// Refresh obtains a fresh token
-	err = spt.Refresh()
+	return tenantID
+}
+
+func getFederatedTokenFile(cfg *Config) string {
+	tokenFile := cfg.FederatedTokenFile
+	if tokenFile == "" {
+		tokenFile = os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+	}
+
+	return tokenFile
+}
+
+func getAuthorityHost(cfg *Config) string {
+	authorityHost := cfg.AuthorityHost
+	if authorityHost == "" {
+		authorityHost = os.Getenv("AZURE_AUTHORITY_HOST")
+	}
+	if authorityHost == "" {
+		authorityHost = defaultAuthorityHost
+	}
+
+	return authorityHost
+}
+
+func getOAuthToken(cfg *Config) (*blob.TokenCredential, error) {
+	spt, err := getServicePrincipalToken(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -180,18 +251,184 @@ func getOAuthToken(cfg *Config) (*blob.TokenCredential, error) {
 	return &tc, nil
 }
 
+var (
+	pluginTokensMtx sync.Mutex
+	// pluginTokens are shared by the containers of configs with the same plugin so every container doesn't run the
+	// plugin and refresh its own token. Configs are copied, so they are keyed by the plugin they run.
+	pluginTokens = map[pluginTokenKey]*blob.TokenCredential{}
+)
+
+// pluginTokenKey identifies the plugin of a credentials config
+type pluginTokenKey struct {
+	command       string
+	args          string
+	timeout       time.Duration
+	refreshBefore time.Duration
+}
+
+func newPluginTokenKey(cfg credentials.Config) pluginTokenKey {
+	return pluginTokenKey{
+		command:       cfg.Command,
+		args:          strings.Join(cfg.Args, "\x00"),
+		timeout:       cfg.Timeout,
+		refreshBefore: cfg.RefreshBefore,
+	}
+}
+
+// getPluginToken returns a token credential that is refreshed by running the credentials plugin
+func getPluginToken(ctx context.Context, cfg *Config) (*blob.TokenCredential, error) {
+	pluginTokensMtx.Lock()
+	defer pluginTokensMtx.Unlock()
+
+	key := newPluginTokenKey(cfg.CredentialsPlugin)
+	if tc, ok := pluginTokens[key]; ok {
+		return tc, nil
+	}
+
+	plugin := credentials.NewPlugin(cfg.CredentialsPlugin)
+	creds, err := plugin.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if creds.Token == "" {
+		return nil, fmt.Errorf("credentials plugin returned no token")
+	}
+
+	tc := blob.NewTokenCredential(creds.Token, func(tc blob.TokenCredential) time.Duration {
+		creds, err := plugin.Credentials(context.Background())
+		if err != nil || creds.Token == "" {
+			// keep the current token and try again shortly
+			return 10 * time.Second
+		}
+
+		// set the new token value
+		tc.SetToken(creds.Token)
+
+		refreshIn, expires := plugin.RefreshIn()
+		if !expires {
+			// the token never expires, prevent the refresher from being triggered again
+			return 0
+		}
+		// a zero duration would stop the refresher
+		if refreshIn < time.Second {
+			refreshIn = time.Second
+		}
+		return refreshIn
+	})
+
+	pluginTokens[key] = &tc
+	return &tc, nil
+}
+
+// tokenSource creates a service principal token for the given resource
+type tokenSource struct {
+	name     string
+	newToken func(resource string) (*adal.ServicePrincipalToken, error)
+}
+
+// getServicePrincipalToken returns a token of the first configured credential that succeeds to obtain one.
+// Credentials are tried in the order: federated workload identity, user-assigned managed identity,
+// system-assigned managed identity.
 func getServicePrincipalToken(cfg *Config) (*adal.ServicePrincipalToken, error) {
-	endpoint := cfg.Endpoint
+	resource := fmt.Sprintf("https://%s.%s", getStorageAccountName(cfg), cfg.Endpoint)
+
+	sources := getTokenSources(cfg)
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no azure credentials configured")
+	}
+
+	errs := make([]string, 0, len(sources))
+	for _, source := range sources {
+		spt, err := source.newToken(resource)
+		if err == nil {
+			// Refresh obtains a fresh token
+			err = spt.Refresh()
+		}
+		if err == nil {
+			return spt, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", source.name, err))
+	}
+
+	return nil, fmt.Errorf("failed to obtain an azure token: %s", strings.Join(errs, "; "))
+}
 
-	resource := fmt.Sprintf("https://%s.%s", cfg.StorageAccountName, endpoint)
+func getTokenSources(cfg *Config) []tokenSource {
+	var sources []tokenSource
 
-	msiConfig := auth.MSIConfig{
-		Resource: resource,
+	if cfg.UseFederatedToken {
+		sources = append(sources, tokenSource{
+			name: "federated token",
+			newToken: func(resource string) (*adal.ServicePrincipalToken, error) {
+				return getFederatedToken(cfg, resource)
+			},
+		})
 	}
 
 	if cfg.UserAssignedID != "" {
-		msiConfig.ClientID = cfg.UserAssignedID
+		sources = append(sources, tokenSource{
+			name: "user-assigned managed identity",
+			newToken: func(resource string) (*adal.ServicePrincipalToken, error) {
+				msiConfig := auth.MSIConfig{
+					Resource: resource,
+					ClientID: cfg.UserAssignedID,
+				}
+				return msiConfig.ServicePrincipalToken()
+			},
+		})
+	}
+
+	if cfg.UseManagedIdentity {
+		sources = append(sources, tokenSource{
+			name: "system-assigned managed identity",
+			newToken: func(resource string) (*adal.ServicePrincipalToken, error) {
+				msiConfig := auth.MSIConfig{
+					Resource: resource,
+				}
+				return msiConfig.ServicePrincipalToken()
+			},
+		})
+	}
+
+	return sources
+}
+
+// getFederatedToken exchanges the federated token projected by Azure AD workload identity for an Azure AD token
+func getFederatedToken(cfg *Config, resource string) (*adal.ServicePrincipalToken, error) {
+	clientID := getClientID(cfg)
+	if clientID == "" {
+		return nil, fmt.Errorf("client id is required")
+	}
+	tenantID := getTenantID(cfg)
+	if tenantID == "" {
+		return nil, fmt.Errorf("tenant id is required")
+	}
+	tokenFile := getFederatedTokenFile(cfg)
+	if tokenFile == "" {
+		return nil, fmt.Errorf("federated token file is required")
+	}
+
+	oauthConfig, err := adal.NewOAuthConfig(getAuthorityHost(cfg), tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return adal.NewServicePrincipalTokenWithSecret(*oauthConfig, clientID, resource, &federatedTokenSecret{tokenFile: tokenFile})
+}
+
+// federatedTokenSecret authenticates with the federated token as client assertion. The file is read on every
+// refresh since the token is rotated.
+type federatedTokenSecret struct {
+	tokenFile string
+}
+
+func (s *federatedTokenSecret) SetAuthenticationValues(_ *adal.ServicePrincipalToken, v *url.Values) error {
+	token, err := os.ReadFile(s.tokenFile)
+	if err != nil {
+		return fmt.Errorf("reading federated token: %w", err)
 	}
 
-	return msiConfig.ServicePrincipalToken()
+	v.Set("client_assertion", strings.TrimSpace(string(token)))
+	v.Set("client_assertion_type", clientAssertionType)
+	return nil
 }
@@ -1,11 +1,21 @@
 package azure
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/grafana/dskit/flagext"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend/credentials"
 )
 
 const (
@@ -63,3 +73,127 @@ func TestGetStorageAccountKeyNotSet(t *testing.T) {
 	actual := getStorageAccountKey(&cfg)
 	assert.Equal(t, "", actual)
 }
+
+func TestGetStorageAccountAzuriteDefaults(t *testing.T) {
+	cfg := Config{Endpoint: "azurite:10000"}
+
+	assert.Equal(t, azuriteAccountName, getStorageAccountName(&cfg))
+	assert.Equal(t, azuriteAccountKey, getStorageAccountKey(&cfg))
+
+	// the well known key is only used for the well known account
+	cfg.StorageAccountName = TestStorageAccountName
+	assert.Equal(t, TestStorageAccountName, getStorageAccountName(&cfg))
+	assert.Equal(t, "", getStorageAccountKey(&cfg))
+
+	// no defaults for azure
+	cfg = Config{Endpoint: "blob.core.windows.net"}
+	assert.Equal(t, "", getStorageAccountName(&cfg))
+	assert.Equal(t, "", getStorageAccountKey(&cfg))
+}
+
+func TestGetTokenSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected []string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "system-assigned",
+			cfg:      Config{UseManagedIdentity: true},
+			expected: []string{"system-assigned managed identity"},
+		},
+		{
+			name:     "user-assigned",
+			cfg:      Config{UserAssignedID: "id"},
+			expected: []string{"user-assigned managed identity"},
+		},
+		{
+			name:     "chain",
+			cfg:      Config{UseFederatedToken: true, UserAssignedID: "id", UseManagedIdentity: true},
+			expected: []string{"federated token", "user-assigned managed identity", "system-assigned managed identity"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual []string
+			for _, s := range getTokenSources(&tc.cfg) {
+				actual = append(actual, s.name)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGetServicePrincipalTokenFederated(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("federated-token\n"), 0600))
+
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/tenant/oauth2/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"access-token","expires_in":"3600","expires_on":"%d","not_before":"%d","resource":"%s","token_type":"Bearer"}`,
+			time.Now().Add(time.Hour).Unix(), time.Now().Unix(), r.PostForm.Get("resource"))
+	}))
+	defer server.Close()
+
+	cfg := Config{
+		StorageAccountName: TestStorageAccountName,
+		Endpoint:           "blob.core.windows.net",
+		UseFederatedToken:  true,
+		ClientID:           "client",
+		TenantID:           "tenant",
+		FederatedTokenFile: tokenFile,
+		AuthorityHost:      server.URL,
+	}
+
+	spt, err := getServicePrincipalToken(&cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "access-token", spt.Token().AccessToken)
+
+	assert.Equal(t, "client", form.Get("client_id"))
+	assert.Equal(t, "federated-token", form.Get("client_assertion"))
+	assert.Equal(t, clientAssertionType, form.Get("client_assertion_type"))
+	assert.Equal(t, "https://foobar.blob.core.windows.net", form.Get("resource"))
+}
+
+func TestGetServicePrincipalTokenFailures(t *testing.T) {
+	_, err := getServicePrincipalToken(&Config{})
+	assert.EqualError(t, err, "no azure credentials configured")
+
+	// every credential of the chain is tried and reported
+	_, err = getServicePrincipalToken(&Config{UseFederatedToken: true})
+	assert.EqualError(t, err, "failed to obtain an azure token: federated token: client id is required")
+}
+
+func TestGetPluginTokenShared(t *testing.T) {
+	plugin := credentials.Config{
+		Command: "sh",
+		Args:    []string{"-c", `echo '{"Token": "token"}'`},
+	}
+
+	// copies of a config share the token of their plugin
+	cfg := Config{CredentialsPlugin: plugin}
+	tc, err := getPluginToken(context.Background(), &cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "token", (*tc).Token())
+
+	cfgCopy := cfg
+	other, err := getPluginToken(context.Background(), &cfgCopy)
+	require.NoError(t, err)
+	assert.Same(t, tc, other)
+
+	// other plugins have their own token
+	plugin.Args = []string{"-c", `echo '{"Token": "other"}'`}
+	other, err = getPluginToken(context.Background(), &Config{CredentialsPlugin: plugin})
+	require.NoError(t, err)
+	assert.NotSame(t, tc, other)
+	assert.Equal(t, "other", (*other).Token())
+}
@@ -18,8 +18,23 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
+func TestConfigKeys(t *testing.T) {
+	// the keys of the azure config are hyphenated
+	cfg := Config{}
+	err := yaml.UnmarshalStrict([]byte(`
+adaptive-concurrency:
+  max_concurrency: 10
+credentials-plugin:
+  command: token-helper
+`), &cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.AdaptiveConcurrency.MaxConcurrency)
+	assert.Equal(t, "token-helper", cfg.CredentialsPlugin.Command)
+}
+
 func TestCredentials(t *testing.T) {
 	_, _, _, err := New(&Config{})
 	require.Error(t, err)
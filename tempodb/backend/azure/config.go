@@ -4,17 +4,29 @@ import (
 	"time"
 
 	"github.com/grafana/dskit/flagext"
+
+	"github.com/grafana/tempo/tempodb/backend/credentials"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 )
 
 type Config struct {
 	StorageAccountName string         `yaml:"storage-account-name"`
 	StorageAccountKey  flagext.Secret `yaml:"storage-account-key"`
 	UseManagedIdentity bool           `yaml:"use-managed-identity"`
+	UseFederatedToken  bool           `yaml:"use-federated-token"`
 	UserAssignedID     string         `yaml:"user-assigned-id"`
+	ClientID           string         `yaml:"client-id"`
+	TenantID           string         `yaml:"tenant-id"`
+	FederatedTokenFile string         `yaml:"federated-token-file"`
+	AuthorityHost      string         `yaml:"authority-host"`
 	ContainerName      string         `yaml:"container-name"`
 	Endpoint           string         `yaml:"endpoint-suffix"`
 	MaxBuffers         int            `yaml:"max-buffers"`
 	BufferSize         int            `yaml:"buffer-size"`
 	HedgeRequestsAt    time.Duration  `yaml:"hedge-requests-at"`
 	HedgeRequestsUpTo  int            `yaml:"hedge-requests-up-to"`
+	// AdaptiveConcurrency limits concurrent reads and backs off when the backend throttles them
+	AdaptiveConcurrency throttle.Config `yaml:"adaptive-concurrency"`
+	// CredentialsPlugin sources the Azure AD token from an external process instead of the configured credentials
+	CredentialsPlugin credentials.Config `yaml:"credentials-plugin"`
 }
@@ -32,6 +32,8 @@ type Writer interface {
 	CloseAppend(ctx context.Context, tracker AppendTracker) error
 	// WriteTenantIndex writes the two meta slices as a tenant index
 	WriteTenantIndex(ctx context.Context, tenantID string, meta []*BlockMeta, compactedMeta []*CompactedBlockMeta) error
+	// WriteLegalHolds replaces the legal holds of a tenant
+	WriteLegalHolds(ctx context.Context, tenantID string, holds *LegalHolds) error
 }
 
 // Reader is a collection of methods to read data from tempodb backends
@@ -51,6 +53,8 @@ type Reader interface {
 	BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*BlockMeta, error)
 	// TenantIndex returns lists of all metas given a tenant
 	TenantIndex(ctx context.Context, tenantID string) (*TenantIndex, error)
+	// LegalHolds returns the legal holds of a tenant, including lifted holds. A tenant without holds has none.
+	LegalHolds(ctx context.Context, tenantID string) (*LegalHolds, error)
 	// Shutdown shuts...down?
 	Shutdown()
 }
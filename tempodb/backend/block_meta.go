@@ -2,6 +2,7 @@ package backend
 
 import (
 	"bytes"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,7 +30,23 @@ type BlockMeta struct {
 	TotalRecords    uint32    `json:"totalRecords"`    // Total Records stored in the index file
 	DataEncoding    string    `json:"dataEncoding"`    // DataEncoding is a string provided externally, but tracked by tempodb that indicates the way the bytes are encoded
 	BloomShardCount uint16    `json:"bloomShards"`     // Number of bloom filter shards
+	BloomFP         float64   `json:"bloomFP"`         // Target false positive rate of the bloom filter
 	FooterSize      uint32    `json:"footerSize"`      // Size of data file footer (parquet)
+
+	Corrupt bool `json:"corrupt,omitempty"` // Block was flagged by scrubbing as partially corrupt, it is salvaged by the compactor
+
+	ServiceNames []string `json:"serviceNames,omitempty"` // Sorted service names of the spans in the block. Not recorded if the block has too many services
+
+	HotSize       uint64 `json:"hotSize,omitempty"`       // Size of the hot file of parquet blocks with split columns. Size is then the size of both files and FooterSize the footer size of the cold file
+	HotFooterSize uint32 `json:"hotFooterSize,omitempty"` // Size of the hot file footer of parquet blocks with split columns
+
+	ServiceIndex bool `json:"serviceIndex,omitempty"` // Block has a service index mapping service names to the rows of their spans (parquet)
+	ZoneMaps     bool `json:"zoneMaps,omitempty"`     // Block has zone maps with the min and max values of numeric columns per row group (parquet)
+	Stats        bool `json:"stats,omitempty"`        // Block has statistics of its services, attributes and columns (parquet)
+
+	DurationSketches bool `json:"durationSketches,omitempty"` // Block has sketches of the span durations by service (parquet). Not recorded if the block has too many services
+
+	IndexVersion string `json:"indexVersion,omitempty"` // Version of the index file (v2). Empty for an index of fixed size records, v3 for a delta encoded index
 }
 
 func NewBlockMeta(tenantID string, blockID uuid.UUID, version string, encoding Encoding, dataEncoding string) *BlockMeta {
@@ -73,3 +90,19 @@ func (b *BlockMeta) ObjectAdded(id []byte, start uint32, end uint32) {
 
 	b.TotalObjects++
 }
+
+// MayContainServiceName returns false if the block is known not to contain spans of a service whose name contains
+// name, matching how searches filter by service name. Blocks that didn't record their service names may contain
+// any service.
+func (b *BlockMeta) MayContainServiceName(name string) bool {
+	if len(b.ServiceNames) == 0 {
+		return true
+	}
+
+	for _, s := range b.ServiceNames {
+		if strings.Contains(s, name) {
+			return true
+		}
+	}
+	return false
+}
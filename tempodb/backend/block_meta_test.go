@@ -94,6 +94,16 @@ func TestBlockMetaObjectAdded(t *testing.T) {
 	}
 }
 
+func TestBlockMetaMayContainServiceName(t *testing.T) {
+	b := &BlockMeta{}
+	assert.True(t, b.MayContainServiceName("frontend"))
+
+	b.ServiceNames = []string{"frontend", "shop-backend"}
+	assert.True(t, b.MayContainServiceName("frontend"))
+	assert.True(t, b.MayContainServiceName("backend"))
+	assert.False(t, b.MayContainServiceName("database"))
+}
+
 func TestBlockMetaParsing(t *testing.T) {
 	inputJSON := `
 {
@@ -88,14 +88,17 @@ func (r *readerWriter) Shutdown() {
 
 // Write implements backend.Writer
 func (r *readerWriter) Write(ctx context.Context, name string, keypath backend.KeyPath, data io.Reader, size int64, shouldCache bool) error {
+	// pass the reader through unbuffered, e.g. so the local backend can clone files
+	if !shouldCache {
+		return r.nextWriter.Write(ctx, name, keypath, data, size, false)
+	}
+
 	b, err := tempo_io.ReadAllWithEstimate(data, size)
 	if err != nil {
 		return err
 	}
 
-	if shouldCache {
-		r.cache.Store(ctx, []string{key(keypath, name)}, [][]byte{b})
-	}
+	r.cache.Store(ctx, []string{key(keypath, name)}, [][]byte{b})
 	return r.nextWriter.Write(ctx, name, keypath, bytes.NewReader(b), int64(len(b)), false)
 }
 
@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// RawComposer is implemented by RawWriters that can assemble an object on the server side from objects
+// previously written to the same keypath.
+type RawComposer interface {
+	RawWriter
+	// Compose writes name as the concatenation of parts, in order, and removes the parts.
+	Compose(ctx context.Context, name string, keypath KeyPath, parts []string) error
+	// DeleteParts removes the parts of name and any intermediate objects Compose wrote for them after uploading or
+	// composing the parts failed. Parts that don't exist are skipped.
+	DeleteParts(ctx context.Context, name string, keypath KeyPath, parts []string) error
+}
+
+// composeTracker tracks the parts of an append job that are being uploaded in parallel.
+type composeTracker struct {
+	name    string
+	keypath KeyPath
+	parts   []string
+	// pending holds the appended data that is not uploaded yet because it is smaller than the minimum part size
+	pending []byte
+
+	wg  sync.WaitGroup
+	sem chan struct{}
+
+	errMtx sync.Mutex
+	err    error
+}
+
+func (t *composeTracker) setErr(err error) {
+	t.errMtx.Lock()
+	defer t.errMtx.Unlock()
+
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+func (t *composeTracker) getErr() error {
+	t.errMtx.Lock()
+	defer t.errMtx.Unlock()
+
+	return t.err
+}
+
+type composeWriter struct {
+	RawComposer
+	concurrency int
+	minPartSize int
+}
+
+// NewComposeWriter returns a RawWriter that uploads the appended buffers as separate part objects with
+// up to concurrency uploads in flight. Appended buffers are coalesced until they are at least minPartSize
+// bytes, so every part but the last meets the minimum part size of the backend. CloseAppend uploads the rest,
+// waits for the uploads and assembles the final object with Compose. If an upload or Compose fails the uploaded parts
+// are deleted. This trades the sequential upload of
+// large objects for parallel uploads of their parts. If concurrency is less than 2 w is returned unchanged.
+func NewComposeWriter(w RawComposer, concurrency int, minPartSize int) RawWriter {
+	if concurrency < 2 {
+		return w
+	}
+
+	return &composeWriter{
+		RawComposer: w,
+		concurrency: concurrency,
+		minPartSize: minPartSize,
+	}
+}
+
+// PartName returns the name of the n-th part object of name.
+func PartName(name string, n int) string {
+	return fmt.Sprintf("%s.part-%06d", name, n)
+}
+
+// Append implements RawWriter
+func (c *composeWriter) Append(ctx context.Context, name string, keypath KeyPath, tracker AppendTracker, buffer []byte) (AppendTracker, error) {
+	var t *composeTracker
+	if tracker == nil {
+		t = &composeTracker{
+			name:    name,
+			keypath: keypath,
+			sem:     make(chan struct{}, c.concurrency),
+		}
+	} else {
+		t = tracker.(*composeTracker)
+	}
+
+	if err := t.getErr(); err != nil {
+		return t, err
+	}
+
+	// callers are free to reuse buffer after Append returns
+	t.pending = append(t.pending, buffer...)
+	if len(t.pending) >= c.minPartSize {
+		c.upload(ctx, t)
+	}
+
+	return t, nil
+}
+
+// upload starts the upload of the pending data as the next part
+func (c *composeWriter) upload(ctx context.Context, t *composeTracker) {
+	part := PartName(t.name, len(t.parts))
+	data := t.pending
+	t.pending = nil
+	t.parts = append(t.parts, part)
+
+	t.sem <- struct{}{}
+	t.wg.Add(1)
+	go func() {
+		defer func() {
+			<-t.sem
+			t.wg.Done()
+		}()
+
+		err := c.RawComposer.Write(ctx, part, t.keypath, bytes.NewReader(data), int64(len(data)), false)
+		if err != nil {
+			t.setErr(fmt.Errorf("error uploading part %s: %w", part, err))
+		}
+	}()
+}
+
+// CloseAppend implements RawWriter
+func (c *composeWriter) CloseAppend(ctx context.Context, tracker AppendTracker) error {
+	if tracker == nil {
+		return nil
+	}
+
+	t := tracker.(*composeTracker)
+	if len(t.pending) > 0 && t.getErr() == nil {
+		c.upload(ctx, t)
+	}
+	t.wg.Wait()
+
+	err := t.getErr()
+	if err == nil {
+		err = c.RawComposer.Compose(ctx, t.name, t.keypath, t.parts)
+	}
+	if err != nil {
+		if deleteErr := c.RawComposer.DeleteParts(ctx, t.name, t.keypath, t.parts); deleteErr != nil {
+			return fmt.Errorf("%w (error deleting parts: %v)", err, deleteErr)
+		}
+		return err
+	}
+
+	return nil
+}
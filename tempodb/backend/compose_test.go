@@ -0,0 +1,221 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+type fakeComposer struct {
+	mtx     sync.Mutex
+	objects map[string][]byte
+
+	inflight    atomic.Int32
+	maxInflight atomic.Int32
+	writeErr    error
+}
+
+func newFakeComposer() *fakeComposer {
+	return &fakeComposer{objects: map[string][]byte{}}
+}
+
+func (f *fakeComposer) Write(_ context.Context, name string, keypath KeyPath, data io.Reader, _ int64, _ bool) error {
+	n := f.inflight.Inc()
+	defer f.inflight.Dec()
+	for {
+		max := f.maxInflight.Load()
+		if n <= max || f.maxInflight.CAS(max, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if f.writeErr != nil {
+		return f.writeErr
+	}
+
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.objects[ObjectFileName(keypath, name)] = b
+	return nil
+}
+
+func (f *fakeComposer) Append(context.Context, string, KeyPath, AppendTracker, []byte) (AppendTracker, error) {
+	return nil, errors.New("unexpected append")
+}
+
+func (f *fakeComposer) CloseAppend(context.Context, AppendTracker) error {
+	return errors.New("unexpected close append")
+}
+
+func (f *fakeComposer) Compose(_ context.Context, name string, keypath KeyPath, parts []string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	buf := &bytes.Buffer{}
+	for _, p := range parts {
+		key := ObjectFileName(keypath, p)
+		b, ok := f.objects[key]
+		if !ok {
+			return ErrDoesNotExist
+		}
+		buf.Write(b)
+		delete(f.objects, key)
+	}
+	f.objects[ObjectFileName(keypath, name)] = buf.Bytes()
+	return nil
+}
+
+func (f *fakeComposer) DeleteParts(_ context.Context, name string, keypath KeyPath, parts []string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	for _, p := range parts {
+		delete(f.objects, ObjectFileName(keypath, p))
+	}
+	return nil
+}
+
+func TestComposeWriter(t *testing.T) {
+	ctx := context.Background()
+	keypath := KeyPath{"tenant", "block"}
+
+	f := newFakeComposer()
+	w := NewComposeWriter(f, 3, 0)
+
+	var tracker AppendTracker
+	var err error
+	expected := &bytes.Buffer{}
+	buffer := make([]byte, 0, 16)
+	for i := 0; i < 10; i++ {
+		// reuse the same buffer like the buffered writers do
+		buffer = append(buffer[:0], byte(i), byte(i), byte(i))
+		expected.Write(buffer)
+
+		tracker, err = w.Append(ctx, "data", keypath, tracker, buffer)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.CloseAppend(ctx, tracker))
+
+	assert.Equal(t, map[string][]byte{
+		ObjectFileName(keypath, "data"): expected.Bytes(),
+	}, f.objects)
+	assert.LessOrEqual(t, f.maxInflight.Load(), int32(3))
+	assert.Greater(t, f.maxInflight.Load(), int32(1))
+}
+
+func TestComposeWriterMinPartSize(t *testing.T) {
+	ctx := context.Background()
+	keypath := KeyPath{"tenant", "block"}
+
+	f := newFakeComposer()
+	var composedParts []string
+	c := &recordingComposer{fakeComposer: f, parts: &composedParts}
+	w := NewComposeWriter(c, 2, 10)
+
+	var tracker AppendTracker
+	var err error
+	expected := &bytes.Buffer{}
+	for i := 0; i < 12; i++ {
+		buffer := []byte{byte(i), byte(i), byte(i)}
+		expected.Write(buffer)
+
+		tracker, err = w.Append(ctx, "data", keypath, tracker, buffer)
+		require.NoError(t, err)
+	}
+
+	// 36 bytes in parts of at least 10 bytes, the last part is the rest
+	ct := tracker.(*composeTracker)
+	ct.wg.Wait()
+	sizes := make([]int, 0, len(ct.parts))
+	for _, p := range ct.parts {
+		sizes = append(sizes, len(f.objects[ObjectFileName(keypath, p)]))
+	}
+	assert.Equal(t, []int{12, 12, 12}, sizes)
+
+	require.NoError(t, w.CloseAppend(ctx, tracker))
+	assert.Len(t, composedParts, 3)
+	assert.Equal(t, map[string][]byte{
+		ObjectFileName(keypath, "data"): expected.Bytes(),
+	}, f.objects)
+
+	// data smaller than the minimum part size is uploaded as a single part on close
+	composedParts = nil
+	tracker, err = w.Append(ctx, "small", keypath, nil, []byte{0x01, 0x02})
+	require.NoError(t, err)
+	require.NoError(t, w.CloseAppend(ctx, tracker))
+	assert.Len(t, composedParts, 1)
+	assert.Equal(t, []byte{0x01, 0x02}, f.objects[ObjectFileName(keypath, "small")])
+}
+
+type recordingComposer struct {
+	*fakeComposer
+	parts *[]string
+}
+
+func (r *recordingComposer) Compose(ctx context.Context, name string, keypath KeyPath, parts []string) error {
+	*r.parts = append(*r.parts, parts...)
+	return r.fakeComposer.Compose(ctx, name, keypath, parts)
+}
+
+func TestComposeWriterError(t *testing.T) {
+	ctx := context.Background()
+
+	f := newFakeComposer()
+	f.writeErr = errors.New("upload failed")
+	w := NewComposeWriter(f, 2, 0)
+
+	tracker, err := w.Append(ctx, "data", KeyPath{"tenant"}, nil, []byte{0x01})
+	require.NoError(t, err)
+	err = w.CloseAppend(ctx, tracker)
+	require.ErrorIs(t, err, f.writeErr)
+	assert.Empty(t, f.objects)
+}
+
+func TestComposeWriterDeletesPartsOnError(t *testing.T) {
+	ctx := context.Background()
+	keypath := KeyPath{"tenant"}
+
+	f := newFakeComposer()
+	c := &failingComposer{fakeComposer: f, err: errors.New("compose failed")}
+	w := NewComposeWriter(c, 2, 0)
+
+	var tracker AppendTracker
+	var err error
+	for i := 0; i < 3; i++ {
+		tracker, err = w.Append(ctx, "data", keypath, tracker, []byte{byte(i)})
+		require.NoError(t, err)
+	}
+	err = w.CloseAppend(ctx, tracker)
+	require.ErrorIs(t, err, c.err)
+	assert.Empty(t, f.objects)
+}
+
+type failingComposer struct {
+	*fakeComposer
+	err error
+}
+
+func (f *failingComposer) Compose(context.Context, string, KeyPath, []string) error {
+	return f.err
+}
+
+func TestComposeWriterDisabled(t *testing.T) {
+	f := newFakeComposer()
+
+	assert.Same(t, f, NewComposeWriter(f, 0, 0))
+	assert.Same(t, f, NewComposeWriter(f, 1, 0))
+}
@@ -0,0 +1,166 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	defaultTimeout       = 30 * time.Second
+	defaultRefreshBefore = time.Minute
+)
+
+var metricPluginRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempodb",
+	Name:      "backend_credentials_plugin_runs_total",
+	Help:      "The total number of runs of the backend credentials plugin by status.",
+}, []string{"status"})
+
+// Config configures an exec plugin that sources backend credentials from an external process, e.g. a Vault agent
+// or a credential broker
+type Config struct {
+	// Command is the plugin executable. Empty disables the plugin.
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// Timeout bounds a single run of the plugin. Defaults to 30s.
+	Timeout time.Duration `yaml:"timeout"`
+	// RefreshBefore runs the plugin again this long before the credentials expire. Defaults to 1m.
+	RefreshBefore time.Duration `yaml:"refresh_before"`
+}
+
+// Enabled returns true if a plugin is configured
+func (c Config) Enabled() bool {
+	return c.Command != ""
+}
+
+// Credentials are written by the plugin to stdout as JSON. The format is a superset of the output of AWS
+// credential_process plugins. S3 uses the access key, GCS and Azure the token.
+type Credentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken"`
+	// Token is a bearer token
+	Token string `json:"Token"`
+	// Expiration is when the credentials expire. The zero value never expires.
+	Expiration time.Time `json:"Expiration"`
+}
+
+// Plugin runs the configured command to obtain credentials and caches them until they are about to expire
+type Plugin struct {
+	cfg Config
+	run func(ctx context.Context) ([]byte, error)
+	now func() time.Time
+
+	mtx   sync.Mutex
+	creds *Credentials
+}
+
+func NewPlugin(cfg Config) *Plugin {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.RefreshBefore <= 0 {
+		cfg.RefreshBefore = defaultRefreshBefore
+	}
+
+	p := &Plugin{
+		cfg: cfg,
+		now: time.Now,
+	}
+	p.run = p.exec
+	return p
+}
+
+// Credentials returns the cached credentials or runs the plugin if they expired
+func (p *Plugin) Credentials(ctx context.Context) (Credentials, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if !p.expiredLocked() {
+		return *p.creds, nil
+	}
+
+	out, err := p.run(ctx)
+	if err != nil {
+		metricPluginRuns.WithLabelValues("error").Inc()
+		return Credentials{}, err
+	}
+
+	creds := &Credentials{}
+	err = json.Unmarshal(out, creds)
+	if err != nil {
+		metricPluginRuns.WithLabelValues("error").Inc()
+		return Credentials{}, fmt.Errorf("parsing output of credentials plugin %s: %w", p.cfg.Command, err)
+	}
+	if creds.AccessKeyID == "" && creds.Token == "" {
+		metricPluginRuns.WithLabelValues("error").Inc()
+		return Credentials{}, fmt.Errorf("credentials plugin %s returned neither an access key nor a token", p.cfg.Command)
+	}
+
+	metricPluginRuns.WithLabelValues("success").Inc()
+	p.creds = creds
+	return *creds, nil
+}
+
+// Expired returns true if the plugin has to be run for the next credentials
+func (p *Plugin) Expired() bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	return p.expiredLocked()
+}
+
+// RefreshIn returns the time until the credentials have to be refreshed, or false if they never expire
+func (p *Plugin) RefreshIn() (time.Duration, bool) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.creds == nil {
+		return 0, true
+	}
+	if p.creds.Expiration.IsZero() {
+		return 0, false
+	}
+
+	d := p.creds.Expiration.Add(-p.cfg.RefreshBefore).Sub(p.now())
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// expiredLocked must be called with the lock held
+func (p *Plugin) expiredLocked() bool {
+	if p.creds == nil {
+		return true
+	}
+	if p.creds.Expiration.IsZero() {
+		return false
+	}
+	return !p.now().Before(p.creds.Expiration.Add(-p.cfg.RefreshBefore))
+}
+
+func (p *Plugin) exec(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.cfg.Command, p.cfg.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("running credentials plugin %s: %w: %s", p.cfg.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginCachesUntilExpiry(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs := 0
+
+	p := NewPlugin(Config{Command: "plugin", RefreshBefore: time.Minute})
+	p.now = func() time.Time { return now }
+	p.run = func(context.Context) ([]byte, error) {
+		runs++
+		return []byte(`{"Version": 1, "AccessKeyId": "key", "SecretAccessKey": "secret", "SessionToken": "session", "Expiration": "2022-01-01T00:10:00Z"}`), nil
+	}
+
+	assert.True(t, p.Expired())
+	creds, err := p.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Expiration:      time.Date(2022, 1, 1, 0, 10, 0, 0, time.UTC),
+	}, creds)
+	assert.Equal(t, 1, runs)
+
+	refreshIn, expires := p.RefreshIn()
+	assert.True(t, expires)
+	assert.Equal(t, 9*time.Minute, refreshIn)
+
+	// cached until refresh_before the expiration
+	now = now.Add(8 * time.Minute)
+	assert.False(t, p.Expired())
+	_, err = p.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, runs)
+
+	now = now.Add(time.Minute)
+	assert.True(t, p.Expired())
+	_, err = p.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs)
+}
+
+func TestPluginWithoutExpiration(t *testing.T) {
+	p := NewPlugin(Config{Command: "plugin"})
+	p.run = func(context.Context) ([]byte, error) {
+		return []byte(`{"Token": "token"}`), nil
+	}
+
+	creds, err := p.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token", creds.Token)
+	assert.False(t, p.Expired())
+
+	_, expires := p.RefreshIn()
+	assert.False(t, expires)
+}
+
+func TestPluginErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		err  error
+	}{
+		{name: "run fails", err: errors.New("failed")},
+		{name: "invalid json", out: "not json"},
+		{name: "no credentials", out: `{"Expiration": "2022-01-01T00:10:00Z"}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPlugin(Config{Command: "plugin"})
+			p.run = func(context.Context) ([]byte, error) {
+				return []byte(tc.out), tc.err
+			}
+
+			_, err := p.Credentials(context.Background())
+			assert.Error(t, err)
+			assert.True(t, p.Expired())
+		})
+	}
+}
+
+func TestPluginExec(t *testing.T) {
+	p := NewPlugin(Config{
+		Command: "sh",
+		Args:    []string{"-c", `echo '{"Token": "token"}'`},
+	})
+
+	creds, err := p.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token", creds.Token)
+
+	// stderr is part of the error
+	p = NewPlugin(Config{
+		Command: "sh",
+		Args:    []string{"-c", "echo denied >&2; exit 1"},
+	})
+	_, err = p.Credentials(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "denied")
+
+	// runs are bounded by the timeout
+	p = NewPlugin(Config{
+		Command: "sleep",
+		Args:    []string{"10"},
+		Timeout: 100 * time.Millisecond,
+	})
+	start := time.Now()
+	_, err = p.Credentials(context.Background())
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -2,6 +2,9 @@ package gcs
 
 import (
 	"time"
+
+	"github.com/grafana/tempo/tempodb/backend/credentials"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 )
 
 type Config struct {
@@ -13,4 +16,9 @@ type Config struct {
 	Insecure           bool              `yaml:"insecure"`
 	ObjectCacheControl string            `yaml:"object_cache_control"`
 	ObjectMetadata     map[string]string `yaml:"object_metadata"`
+	ParallelUploads    int               `yaml:"parallel_uploads"`
+	// AdaptiveConcurrency limits concurrent reads and backs off when the backend throttles them
+	AdaptiveConcurrency throttle.Config `yaml:"adaptive_concurrency"`
+	// CredentialsPlugin sources the oauth2 token from an external process instead of the default credentials
+	CredentialsPlugin credentials.Config `yaml:"credentials_plugin"`
 }
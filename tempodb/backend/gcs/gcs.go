@@ -4,18 +4,22 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"fmt"
 	"io"
 	"net/http"
 	"path"
 	"strings"
 	"time"
 
+	"github.com/grafana/tempo/tempodb/backend/credentials"
 	"github.com/grafana/tempo/tempodb/backend/instrumentation"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 
 	"cloud.google.com/go/storage"
 	"github.com/cristalhq/hedgedhttp"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	google_http "google.golang.org/api/transport/http"
@@ -24,6 +28,15 @@ import (
 	"github.com/grafana/tempo/tempodb/backend"
 )
 
+const (
+	// maxComposeSources is the maximum number of source objects of a single GCS compose request
+	maxComposeSources = 32
+	// maxComposeComponents is the maximum number of components of a composite object
+	maxComposeComponents = 1024
+	// minComposePartSize keeps objects of up to 16GiB within the components of a composite object
+	minComposePartSize = 16 * 1024 * 1024
+)
+
 type readerWriter struct {
 	cfg          *Config
 	bucket       *storage.BucketHandle
@@ -66,7 +79,7 @@ func internalNew(cfg *Config, confirm bool) (backend.RawReader, backend.RawWrite
 		hedgedBucket: hedgedBucket,
 	}
 
-	return rw, rw, rw, nil
+	return rw, backend.NewComposeWriter(rw, cfg.ParallelUploads, minComposePartSize), rw, nil
 }
 
 // Write implements backend.Writer
@@ -114,6 +127,90 @@ func (rw *readerWriter) CloseAppend(_ context.Context, tracker backend.AppendTra
 	return w.Close()
 }
 
+// Compose implements backend.RawComposer. GCS composes at most 32 objects at once so larger sets of parts
+// are composed into intermediate objects first. A composite object has at most 1024 components, so at most
+// 1024 parts can be composed.
+func (rw *readerWriter) Compose(ctx context.Context, name string, keypath backend.KeyPath, parts []string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "gcs.Compose", opentracing.Tags{
+		"parts": len(parts),
+	})
+	defer span.Finish()
+
+	if len(parts) > maxComposeComponents {
+		return fmt.Errorf("composing %d parts, at most %d parts can be composed", len(parts), maxComposeComponents)
+	}
+
+	objectName := backend.ObjectFileName(keypath, name)
+	srcs := make([]string, 0, len(parts))
+	for _, p := range parts {
+		srcs = append(srcs, backend.ObjectFileName(keypath, p))
+	}
+	toDelete := srcs
+
+	for round := 0; len(srcs) > maxComposeSources; round++ {
+		var next []string
+		for i := 0; i < len(srcs); i += maxComposeSources {
+			end := i + maxComposeSources
+			if end > len(srcs) {
+				end = len(srcs)
+			}
+
+			dst := fmt.Sprintf("%s.compose-%d-%d", objectName, round, len(next))
+			if err := rw.compose(ctx, dst, srcs[i:end]); err != nil {
+				return err
+			}
+			next = append(next, dst)
+		}
+		toDelete = append(toDelete, next...)
+		srcs = next
+	}
+
+	if err := rw.compose(ctx, objectName, srcs); err != nil {
+		return err
+	}
+
+	for _, o := range toDelete {
+		if err := rw.bucket.Object(o).Delete(ctx); err != nil {
+			return errors.Wrapf(err, "error deleting composed part %s", o)
+		}
+	}
+
+	return nil
+}
+
+// DeleteParts implements backend.RawComposer. The intermediate objects of Compose are found by their prefix.
+func (rw *readerWriter) DeleteParts(ctx context.Context, name string, keypath backend.KeyPath, parts []string) error {
+	objectName := backend.ObjectFileName(keypath, name)
+	objects := make([]string, 0, len(parts))
+	for _, p := range parts {
+		objects = append(objects, backend.ObjectFileName(keypath, p))
+	}
+
+	iter := rw.bucket.Objects(ctx, &storage.Query{
+		Prefix:   objectName + ".compose-",
+		Versions: false,
+	})
+	for {
+		attrs, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "iterating intermediate objects")
+		}
+		objects = append(objects, attrs.Name)
+	}
+
+	for _, o := range objects {
+		err := rw.bucket.Object(o).Delete(ctx)
+		if err != nil && err != storage.ErrObjectNotExist {
+			return errors.Wrapf(err, "error deleting part %s", o)
+		}
+	}
+
+	return nil
+}
+
 // List implements backend.Reader
 func (rw *readerWriter) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
 	prefix := path.Join(keypath...)
@@ -192,6 +289,28 @@ func (rw *readerWriter) writer(ctx context.Context, name string) *storage.Writer
 	return w
 }
 
+func (rw *readerWriter) compose(ctx context.Context, dst string, srcs []string) error {
+	handles := make([]*storage.ObjectHandle, 0, len(srcs))
+	for _, src := range srcs {
+		handles = append(handles, rw.bucket.Object(src))
+	}
+
+	c := rw.bucket.Object(dst).ComposerFrom(handles...)
+	if rw.cfg.ObjectMetadata != nil {
+		c.Metadata = rw.cfg.ObjectMetadata
+	}
+	if rw.cfg.ObjectCacheControl != "" {
+		c.CacheControl = rw.cfg.ObjectCacheControl
+	}
+
+	_, err := c.Run(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "error composing object %s", dst)
+	}
+
+	return nil
+}
+
 func (rw *readerWriter) readAll(ctx context.Context, name string) ([]byte, error) {
 	r, err := rw.hedgedBucket.Object(name).NewReader(ctx)
 	if err != nil {
@@ -251,6 +370,10 @@ func createBucket(ctx context.Context, cfg *Config, hedge bool) (*storage.Bucket
 	if cfg.Insecure {
 		transportOptions = append(transportOptions, option.WithoutAuthentication())
 		customTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	} else if cfg.CredentialsPlugin.Enabled() {
+		transportOptions = append(transportOptions, option.WithTokenSource(&pluginTokenSource{
+			plugin: credentials.NewPlugin(cfg.CredentialsPlugin),
+		}))
 	}
 	transport, err := google_http.NewTransport(ctx, customTransport, transportOptions...)
 	if err != nil {
@@ -261,6 +384,11 @@ func createBucket(ctx context.Context, cfg *Config, hedge bool) (*storage.Bucket
 	transport = instrumentation.NewTransport(transport)
 	var stats *hedgedhttp.Stats
 
+	// adapt the concurrency of reads to throttling, hedged requests count against the limit
+	if hedge {
+		transport = throttle.NewTransport(cfg.AdaptiveConcurrency, transport)
+	}
+
 	// hedge if desired (0 means disabled)
 	if hedge && cfg.HedgeRequestsAt != 0 {
 		transport, stats, err = hedgedhttp.NewRoundTripperAndStats(cfg.HedgeRequestsAt, cfg.HedgeRequestsUpTo, transport)
@@ -289,6 +417,27 @@ func createBucket(ctx context.Context, cfg *Config, hedge bool) (*storage.Bucket
 	return client.Bucket(cfg.BucketName), nil
 }
 
+// pluginTokenSource retrieves oauth2 tokens from a credentials plugin
+type pluginTokenSource struct {
+	plugin *credentials.Plugin
+}
+
+func (s *pluginTokenSource) Token() (*oauth2.Token, error) {
+	creds, err := s.plugin.Credentials(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if creds.Token == "" {
+		return nil, fmt.Errorf("credentials plugin returned no token")
+	}
+
+	return &oauth2.Token{
+		AccessToken: creds.Token,
+		TokenType:   "Bearer",
+		Expiry:      creds.Expiration,
+	}, nil
+}
+
 func readError(err error) error {
 	if err == storage.ErrObjectNotExist {
 		return backend.ErrDoesNotExist
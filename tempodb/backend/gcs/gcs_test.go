@@ -190,3 +190,78 @@ func fakeServerWithObjectAttributes(t *testing.T, o *raw.Object) *httptest.Serve
 
 	return server
 }
+
+func TestCompose(t *testing.T) {
+	var composed []string
+	var sources [][]string
+	var deleted []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/compose"):
+			req := &raw.ComposeRequest{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+
+			var srcs []string
+			for _, src := range req.SourceObjects {
+				srcs = append(srcs, src.Name)
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/b/blerg/o/"), "/compose")
+			composed = append(composed, name)
+			sources = append(sources, srcs)
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/b/blerg/o/"))
+		case r.Method == http.MethodGet && r.URL.Path == "/b/blerg/o":
+			require.Equal(t, "tenant/data.compose-", r.URL.Query().Get("prefix"))
+			_, _ = w.Write([]byte(`{"items":[{"name":"tenant/data.compose-0-0"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	_, w, _, err := NewNoConfirm(&Config{
+		BucketName:      "blerg",
+		Insecure:        true,
+		Endpoint:        server.URL,
+		ParallelUploads: 2,
+	})
+	require.NoError(t, err)
+
+	// 70 parts need 3 intermediate objects
+	var parts []string
+	for i := 0; i < 70; i++ {
+		parts = append(parts, backend.PartName("data", i))
+	}
+	err = w.(backend.RawComposer).Compose(context.Background(), "data", backend.KeyPath{"tenant"}, parts)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"tenant/data.compose-0-0",
+		"tenant/data.compose-0-1",
+		"tenant/data.compose-0-2",
+		"tenant/data",
+	}, composed)
+	require.Len(t, sources[0], 32)
+	require.Equal(t, "tenant/data.part-000000", sources[0][0])
+	require.Len(t, sources[1], 32)
+	require.Len(t, sources[2], 6)
+	require.Equal(t, "tenant/data.part-000069", sources[2][5])
+	require.Equal(t, []string{"tenant/data.compose-0-0", "tenant/data.compose-0-1", "tenant/data.compose-0-2"}, sources[3])
+	require.Len(t, deleted, 73)
+
+	// the parts and intermediate objects of a failed compose are deleted
+	deleted = nil
+	err = w.(backend.RawComposer).DeleteParts(context.Background(), "data", backend.KeyPath{"tenant"}, parts[:2])
+	require.NoError(t, err)
+	require.Equal(t, []string{"tenant/data.part-000000", "tenant/data.part-000001", "tenant/data.compose-0-0"}, deleted)
+
+	// a composite object has at most 1024 components
+	composed = nil
+	for i := len(parts); i <= maxComposeComponents; i++ {
+		parts = append(parts, backend.PartName("data", i))
+	}
+	err = w.(backend.RawComposer).Compose(context.Background(), "data", backend.KeyPath{"tenant"}, parts)
+	require.Error(t, err)
+	require.Empty(t, composed)
+}
@@ -0,0 +1,128 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LegalHold keeps data from being deleted by retention or trace deletion until it is lifted. A hold covers a single
+// trace or all traces of a tenant in a time range. Lifted holds are kept for audit.
+type LegalHold struct {
+	ID string `json:"id"`
+	// TraceID is the hex encoded id of the held trace
+	TraceID string `json:"traceID,omitempty"`
+	// Start and End are the held time range
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// LiftedAt is when the hold was lifted, zero while it is active
+	LiftedAt time.Time `json:"liftedAt"`
+}
+
+// Validate returns an error unless the hold covers either a trace or a time range
+func (h *LegalHold) Validate() error {
+	hasRange := !h.Start.IsZero() || !h.End.IsZero()
+	switch {
+	case h.TraceID != "" && hasRange:
+		return errors.New("a legal hold covers either a trace id or a time range")
+	case h.TraceID != "":
+		if _, err := hex.DecodeString(h.TraceID); err != nil {
+			return fmt.Errorf("invalid trace id %s: %w", h.TraceID, err)
+		}
+	case hasRange:
+		if h.Start.IsZero() || h.End.IsZero() || !h.Start.Before(h.End) {
+			return errors.New("a legal hold time range requires a start before its end")
+		}
+	default:
+		return errors.New("a legal hold requires a trace id or a time range")
+	}
+	return nil
+}
+
+// Active returns true if the hold hasn't been lifted
+func (h *LegalHold) Active() bool {
+	return h.LiftedAt.IsZero()
+}
+
+// holdsTimeRange returns true if the hold is active and its time range overlaps the block
+func (h *LegalHold) holdsTimeRange(meta *BlockMeta) bool {
+	if !h.Active() || h.TraceID != "" {
+		return false
+	}
+	return !meta.StartTime.After(h.End) && !meta.EndTime.Before(h.Start)
+}
+
+// holdsTrace returns true if the hold is active and holds the trace id
+func (h *LegalHold) holdsTrace(id []byte) bool {
+	if !h.Active() || h.TraceID == "" {
+		return false
+	}
+	held, err := hex.DecodeString(h.TraceID)
+	return err == nil && bytes.Equal(held, id)
+}
+
+// heldTrace returns the id of the held trace if the hold is active and the id is in the id range of the block
+func (h *LegalHold) heldTrace(meta *BlockMeta) []byte {
+	if !h.Active() || h.TraceID == "" {
+		return nil
+	}
+	held, err := hex.DecodeString(h.TraceID)
+	if err != nil {
+		return nil
+	}
+	if bytes.Compare(held, meta.MinID) < 0 || bytes.Compare(held, meta.MaxID) > 0 {
+		return nil
+	}
+	return held
+}
+
+// LegalHolds are the legal holds of a tenant
+type LegalHolds struct {
+	Holds []LegalHold `json:"holds"`
+}
+
+// HoldsTimeRange returns true if any active hold covers the time range of the block
+func (l *LegalHolds) HoldsTimeRange(meta *BlockMeta) bool {
+	for i := range l.Holds {
+		if l.Holds[i].holdsTimeRange(meta) {
+			return true
+		}
+	}
+	return false
+}
+
+// HeldTraces returns the ids of the held traces that are in the id range of the block. The block only contains
+// the traces that its bloom filter and a find confirm.
+func (l *LegalHolds) HeldTraces(meta *BlockMeta) [][]byte {
+	var ids [][]byte
+	for i := range l.Holds {
+		if id := l.Holds[i].heldTrace(meta); id != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// HoldsTrace returns true if any active hold covers the trace stored in the block
+func (l *LegalHolds) HoldsTrace(id []byte, meta *BlockMeta) bool {
+	for i := range l.Holds {
+		if l.Holds[i].holdsTrace(id) || l.Holds[i].holdsTimeRange(meta) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the index of the hold with the id or -1
+func (l *LegalHolds) Find(id string) int {
+	for i := range l.Holds {
+		if l.Holds[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLegalHoldValidate(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		hold  LegalHold
+		valid bool
+	}{
+		{name: "trace", hold: LegalHold{TraceID: "0102"}, valid: true},
+		{name: "range", hold: LegalHold{Start: now, End: now.Add(time.Hour)}, valid: true},
+		{name: "empty", hold: LegalHold{}},
+		{name: "invalid trace id", hold: LegalHold{TraceID: "xyz"}},
+		{name: "trace and range", hold: LegalHold{TraceID: "0102", Start: now, End: now.Add(time.Hour)}},
+		{name: "open range", hold: LegalHold{Start: now}},
+		{name: "reversed range", hold: LegalHold{Start: now, End: now.Add(-time.Hour)}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.hold.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestLegalHoldsMatch(t *testing.T) {
+	now := time.Now()
+	meta := &BlockMeta{
+		MinID:     []byte{0x01, 0x00},
+		MaxID:     []byte{0x01, 0xff},
+		StartTime: now.Add(-2 * time.Hour),
+		EndTime:   now.Add(-time.Hour),
+	}
+
+	tests := []struct {
+		name        string
+		hold        LegalHold
+		holdsRange  bool
+		heldTraces  [][]byte
+		holdsTrace  bool
+		holdsOthers bool
+	}{
+		{name: "trace", hold: LegalHold{TraceID: "0110"}, heldTraces: [][]byte{{0x01, 0x10}}, holdsTrace: true},
+		{name: "trace outside id range", hold: LegalHold{TraceID: "0210"}},
+		{name: "overlapping range", hold: LegalHold{Start: now.Add(-90 * time.Minute), End: now}, holdsRange: true, holdsTrace: true, holdsOthers: true},
+		{name: "range after block", hold: LegalHold{Start: now.Add(-30 * time.Minute), End: now}},
+		{name: "lifted trace", hold: LegalHold{TraceID: "0110", LiftedAt: now}},
+		{name: "lifted range", hold: LegalHold{Start: now.Add(-90 * time.Minute), End: now, LiftedAt: now}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			holds := &LegalHolds{Holds: []LegalHold{tc.hold}}
+			assert.Equal(t, tc.holdsRange, holds.HoldsTimeRange(meta))
+			assert.Equal(t, tc.heldTraces, holds.HeldTraces(meta))
+			assert.Equal(t, tc.holdsTrace, holds.HoldsTrace([]byte{0x01, 0x10}, meta))
+			assert.Equal(t, tc.holdsOthers, holds.HoldsTrace([]byte{0x01, 0x20}, meta))
+		})
+	}
+}
+
+func TestLegalHoldsReadWrite(t *testing.T) {
+	mockR := &MockRawReader{
+		ReadFn: func(ctx context.Context, name string, keypath KeyPath, shouldCache bool) (io.ReadCloser, int64, error) {
+			return nil, 0, ErrDoesNotExist
+		},
+	}
+	mockW := &MockRawWriter{}
+	r := NewReader(mockR)
+	w := NewWriter(mockW)
+	ctx := context.Background()
+
+	holds, err := r.LegalHolds(ctx, "test")
+	require.NoError(t, err)
+	assert.Empty(t, holds.Holds)
+
+	expected := &LegalHolds{Holds: []LegalHold{{ID: uuid.New().String(), TraceID: "0102", CreatedAt: time.Now().UTC()}}}
+	require.NoError(t, w.WriteLegalHolds(ctx, "test", expected))
+
+	mockR.ReadFn = nil
+	mockR.R = mockW.writeBuffer
+	holds, err = r.LegalHolds(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, expected, holds)
+}
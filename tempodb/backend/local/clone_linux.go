@@ -0,0 +1,20 @@
+package local
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile clones the data of src into dst with the FICLONE ioctl, both files then share their extents until
+// either is changed. errReflinkUnsupported is returned if the filesystems of the files don't support reflinks.
+func cloneFile(dst, src *os.File) error {
+	err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+	for _, unsupported := range []error{unix.EOPNOTSUPP, unix.EXDEV, unix.EINVAL, unix.ENOTTY, unix.ENOSYS} {
+		if errors.Is(err, unsupported) {
+			return errReflinkUnsupported
+		}
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package local
+
+import "os"
+
+// cloneFile is only supported on linux
+func cloneFile(*os.File, *os.File) error {
+	return errReflinkUnsupported
+}
@@ -15,6 +15,9 @@ func (rw *Backend) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error
 	metaFilename := rw.metaFileName(blockID, tenantID)
 	compactedMetaFilename := rw.compactedMetaFileName(blockID, tenantID)
 
+	if rw.cfg.SharedFilesystem {
+		return renameRetrySafe(metaFilename, compactedMetaFilename)
+	}
 	return os.Rename(metaFilename, compactedMetaFilename)
 }
 
@@ -2,4 +2,8 @@ package local
 
 type Config struct {
 	Path string `yaml:"path"`
+	// SharedFilesystem protects the backend from concurrent writers on a filesystem shared by several hosts, e.g.
+	// NFS. Objects are written to temporary files and renamed into place, tenant index writes are fenced and renames
+	// are retry-safe.
+	SharedFilesystem bool `yaml:"shared_filesystem"`
 }
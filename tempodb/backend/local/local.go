@@ -2,6 +2,7 @@ package local
 
 import (
 	"context"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
@@ -9,10 +10,16 @@ import (
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/opentracing/opentracing-go"
+	"go.uber.org/atomic"
 )
 
+var errReflinkUnsupported = errors.New("reflinks are not supported")
+
 type Backend struct {
 	cfg *Config
+
+	// noReflink is set once cloning a file failed because reflinks aren't supported
+	noReflink atomic.Bool
 }
 
 var _ backend.RawReader = (*Backend)(nil)
@@ -37,8 +44,13 @@ func New(cfg *Config) (backend.RawReader, backend.RawWriter, backend.Compactor,
 	return l, l, l, err
 }
 
-// Write implements backend.Writer
+// Write implements backend.Writer. Files are copied with copyFile, e.g. the data of completed blocks flushed from the
+// local blocks of the ingester to a local backend.
 func (rw *Backend) Write(ctx context.Context, name string, keypath backend.KeyPath, data io.Reader, _ int64, _ bool) error {
+	if rw.cfg.SharedFilesystem {
+		return rw.writeShared(name, keypath, data)
+	}
+
 	blockFolder := rw.rootPath(keypath)
 	err := os.MkdirAll(blockFolder, os.ModePerm)
 	if err != nil {
@@ -52,6 +64,10 @@ func (rw *Backend) Write(ctx context.Context, name string, keypath backend.KeyPa
 	}
 	defer dst.Close()
 
+	if src, ok := data.(*os.File); ok {
+		return rw.copyFile(dst, src)
+	}
+
 	_, err = io.Copy(dst, data)
 	if err != nil {
 		return err
@@ -59,6 +75,30 @@ func (rw *Backend) Write(ctx context.Context, name string, keypath backend.KeyPa
 	return err
 }
 
+// copyFile clones src into dst if the filesystem supports reflinks, e.g. btrfs or xfs, so unchanged data isn't
+// rewritten. Otherwise the data is copied, on linux with copy_file_range. Reflinks are detected by the first clone
+// and not tried again if they aren't supported. Files that were read from are copied from their offset.
+func (rw *Backend) copyFile(dst, src *os.File) error {
+	if !rw.noReflink.Load() {
+		if off, err := src.Seek(0, io.SeekCurrent); err == nil && off == 0 {
+			err = cloneFile(dst, src)
+			if err == nil {
+				return nil
+			}
+			if errors.Is(err, errReflinkUnsupported) {
+				rw.noReflink.Store(true)
+			}
+			// the clone failed, start over
+			if err := dst.Truncate(0); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := io.Copy(dst, src)
+	return err
+}
+
 // Append implements backend.Writer
 func (rw *Backend) Append(ctx context.Context, name string, keypath backend.KeyPath, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
 	span, _ := opentracing.StartSpanFromContext(ctx, "local.Append", opentracing.Tags{
@@ -104,7 +144,12 @@ func (rw *Backend) CloseAppend(ctx context.Context, tracker backend.AppendTracke
 // List implements backend.Reader
 func (rw *Backend) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
 	path := rw.rootPath(keypath)
-	folders, err := os.ReadDir(path)
+	var folders []os.DirEntry
+	err := retryStale(func() error {
+		var err error
+		folders, err = os.ReadDir(path)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +169,7 @@ func (rw *Backend) List(ctx context.Context, keypath backend.KeyPath) ([]string,
 func (rw *Backend) Read(ctx context.Context, name string, keypath backend.KeyPath, _ bool) (io.ReadCloser, int64, error) {
 	filename := rw.objectFileName(keypath, name)
 
-	f, err := os.OpenFile(filename, os.O_RDONLY, 0644)
+	f, err := openRetryStale(filename, os.O_RDONLY, 0644)
 	if err != nil {
 		return nil, -1, readError(err)
 	}
@@ -147,18 +192,17 @@ func (rw *Backend) ReadRange(ctx context.Context, name string, keypath backend.K
 
 	filename := rw.objectFileName(keypath, name)
 
-	f, err := os.OpenFile(filename, os.O_RDONLY, 0644)
-	if err != nil {
-		return readError(err)
-	}
-	defer f.Close()
+	// the file is opened again if it was replaced while it was read
+	return retryStale(func() error {
+		f, err := os.OpenFile(filename, os.O_RDONLY, 0644)
+		if err != nil {
+			return readError(err)
+		}
+		defer f.Close()
 
-	_, err = f.ReadAt(buffer, int64(offset))
-	if err != nil {
+		_, err = f.ReadAt(buffer, int64(offset))
 		return err
-	}
-
-	return nil
+	})
 }
 
 // Shutdown implements backend.Reader
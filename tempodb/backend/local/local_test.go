@@ -67,3 +67,117 @@ func TestReadWrite(t *testing.T) {
 	assert.Len(t, list, 1)
 	assert.Equal(t, blockID.String(), list[0])
 }
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	rw, err := NewBackend(&Config{Path: dir})
+	assert.NoError(t, err)
+
+	object := make([]byte, 100_000)
+	_, err = rand.Read(object)
+	assert.NoError(t, err)
+
+	srcName := dir + "/src"
+	assert.NoError(t, os.WriteFile(srcName, object, 0644))
+
+	ctx := context.Background()
+	keypath := backend.KeyPathForBlock(uuid.New(), "fake")
+	read := func() []byte {
+		r, size, err := rw.Read(ctx, objectName, keypath, false)
+		assert.NoError(t, err)
+		defer r.Close()
+		b, err := io.ReadAllWithEstimate(r, size)
+		assert.NoError(t, err)
+		return b
+	}
+
+	// files are cloned or copied whole, whether or not the filesystem supports reflinks
+	for i := 0; i < 2; i++ {
+		src, err := os.Open(srcName)
+		assert.NoError(t, err)
+		assert.NoError(t, rw.Write(ctx, objectName, keypath, src, int64(len(object)), false))
+		assert.NoError(t, src.Close())
+		assert.Equal(t, object, read())
+	}
+
+	// files that were read from are copied from their offset
+	src, err := os.Open(srcName)
+	assert.NoError(t, err)
+	defer src.Close()
+	_, err = src.Read(make([]byte, 10))
+	assert.NoError(t, err)
+	assert.NoError(t, rw.Write(ctx, objectName, keypath, src, int64(len(object)-10), false))
+	assert.Equal(t, object[10:], read())
+}
+
+func TestSharedFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	rw, err := NewBackend(&Config{Path: dir, SharedFilesystem: true})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	blockID := uuid.New()
+	keypath := backend.KeyPathForBlock(blockID, "fake")
+	object := []byte("object")
+
+	// objects are renamed into place, no temporary files are left behind
+	assert.NoError(t, rw.Write(ctx, objectName, keypath, bytes.NewReader(object), int64(len(object)), false))
+	r, size, err := rw.Read(ctx, objectName, keypath, false)
+	assert.NoError(t, err)
+	actual, err := io.ReadAllWithEstimate(r, size)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, object, actual)
+
+	entries, err := os.ReadDir(rw.rootPath(keypath))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// every write of the tenant index takes a new fencing token
+	tenantPath := backend.KeyPath{"fake"}
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, rw.Write(ctx, backend.TenantIndexName, tenantPath, bytes.NewReader(object), int64(len(object)), false))
+	}
+	fenceFile := rw.objectFileName(tenantPath, backend.TenantIndexName+fenceSuffix)
+	token, err := os.ReadFile(fenceFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", string(token))
+
+	// blocks are still listed
+	list, err := rw.List(ctx, tenantPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{blockID.String()}, list)
+
+	// a retried rename of the meta succeeds
+	assert.NoError(t, rw.Write(ctx, backend.MetaName, keypath, bytes.NewReader(object), int64(len(object)), false))
+	assert.NoError(t, rw.MarkBlockCompacted(blockID, "fake"))
+	assert.NoError(t, rw.MarkBlockCompacted(blockID, "fake"))
+	assert.NoError(t, rw.ClearBlock(blockID, "fake"))
+	assert.Error(t, rw.MarkBlockCompacted(blockID, "fake"))
+}
+
+func TestFencing(t *testing.T) {
+	dir := t.TempDir()
+	fenceFile := dir + "/" + backend.TenantIndexName + fenceSuffix
+	dst := dir + "/" + backend.TenantIndexName
+
+	stale, err := nextFencingToken(fenceFile)
+	assert.NoError(t, err)
+	latest, err := nextFencingToken(fenceFile)
+	assert.NoError(t, err)
+	assert.Greater(t, latest, stale)
+
+	write := func(token uint64, content string) error {
+		tmp := dir + "/tmp-" + content
+		assert.NoError(t, os.WriteFile(tmp, []byte(content), 0644))
+		return renameFenced(fenceFile, token, tmp, dst)
+	}
+
+	// the writer with the latest token replaces the object, the older writer is fenced
+	assert.NoError(t, write(latest, "latest"))
+	assert.ErrorIs(t, write(stale, "stale"), errFenced)
+
+	b, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "latest", string(b))
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package local
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes an exclusive advisory lock of the whole file, waiting for other holders. fcntl locks are used over
+// flock since NFS clients only forward them to the server. They are held per process, callers must exclude other
+// goroutines.
+func lockFile(f *os.File) error {
+	return unix.FcntlFlock(f.Fd(), unix.F_SETLKW, &unix.Flock_t{
+		Type:   unix.F_WRLCK,
+		Whence: io.SeekStart,
+	})
+}
+
+func unlockFile(f *os.File) error {
+	return unix.FcntlFlock(f.Fd(), unix.F_SETLK, &unix.Flock_t{
+		Type:   unix.F_UNLCK,
+		Whence: io.SeekStart,
+	})
+}
@@ -0,0 +1,194 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const (
+	// fenceSuffix is appended to the tenant index to name the file holding the fencing token of the last writer
+	fenceSuffix = ".fence"
+	// staleRetries is how often an operation is retried after a stale file handle
+	staleRetries = 3
+)
+
+var errFenced = errors.New("tenant index was fenced by a newer writer")
+
+// fenceMtx excludes the goroutines of the process from the fence files, advisory locks only exclude other processes
+var fenceMtx sync.Mutex
+
+var metricStaleFileHandles = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "tempodb",
+	Name:      "backend_local_stale_file_handles_total",
+	Help:      "The total number of operations of the local backend retried after a stale file handle.",
+})
+
+// writeShared writes the object to a temporary file that is synced and renamed into place, so readers on other hosts
+// never see a partially written object. Writes of the tenant index are fenced.
+func (rw *Backend) writeShared(name string, keypath backend.KeyPath, data io.Reader) error {
+	dir := rw.rootPath(keypath)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	var token uint64
+	fenced := name == backend.TenantIndexName
+	if fenced {
+		token, err = nextFencingToken(filepath.Join(dir, name+fenceSuffix))
+		if err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+	defer tmp.Close()
+
+	if src, ok := data.(*os.File); ok {
+		err = rw.copyFile(tmp, src)
+	} else {
+		_, err = io.Copy(tmp, data)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	dst := filepath.Join(dir, name)
+	if !fenced {
+		return renameRetrySafe(tmp.Name(), dst)
+	}
+	return renameFenced(filepath.Join(dir, name+fenceSuffix), token, tmp.Name(), dst)
+}
+
+// nextFencingToken increments the fencing token in the fence file and returns it. A writer may only replace the
+// object if the token is still the latest once its data is written.
+func nextFencingToken(fenceFile string) (uint64, error) {
+	var token uint64
+	err := withFence(fenceFile, func(f *os.File, current uint64) error {
+		token = current + 1
+		return writeFencingToken(f, token)
+	})
+	return token, err
+}
+
+// renameFenced renames from to to if token is still the latest token of the fence file. errFenced is returned if a
+// newer writer took a token in the meantime, its object must not be overwritten by an older one.
+func renameFenced(fenceFile string, token uint64, from, to string) error {
+	return withFence(fenceFile, func(_ *os.File, current uint64) error {
+		if current != token {
+			return fmt.Errorf("%w: token %d, latest %d", errFenced, token, current)
+		}
+		return renameRetrySafe(from, to)
+	})
+}
+
+// withFence calls f with the fence file locked and the token it holds. The advisory lock is released when f returns.
+func withFence(fenceFile string, f func(*os.File, uint64) error) error {
+	fenceMtx.Lock()
+	defer fenceMtx.Unlock()
+
+	file, err := openRetryStale(fenceFile, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := lockFile(file); err != nil {
+		return fmt.Errorf("error locking %s: %w", fenceFile, err)
+	}
+	defer unlockFile(file) // nolint: errcheck
+
+	b, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	var current uint64
+	if s := strings.TrimSpace(string(b)); s != "" {
+		current, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid fencing token in %s: %w", fenceFile, err)
+		}
+	}
+
+	return f(file, current)
+}
+
+func writeFencingToken(f *os.File, token uint64) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := f.WriteAt([]byte(strconv.FormatUint(token, 10)), 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// renameRetrySafe renames from to to. NFS clients retry renames whose reply was lost, the retry fails because the
+// source is already gone. A rename is considered done if the source doesn't exist but the target does.
+func renameRetrySafe(from, to string) error {
+	err := retryStale(func() error {
+		return os.Rename(from, to)
+	})
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if _, srcErr := os.Stat(from); !errors.Is(srcErr, fs.ErrNotExist) {
+		return err
+	}
+	if _, dstErr := os.Stat(to); dstErr != nil {
+		return err
+	}
+	return nil
+}
+
+// openRetryStale opens the file, retrying if the handle of the file or its directory is stale. NFS clients return
+// stale file handles when another host replaced or removed a file, reopening looks the path up again.
+func openRetryStale(name string, flag int, perm os.FileMode) (*os.File, error) {
+	var f *os.File
+	err := retryStale(func() error {
+		var err error
+		f, err = os.OpenFile(name, flag, perm)
+		return err
+	})
+	return f, err
+}
+
+func retryStale(f func() error) error {
+	var err error
+	for i := 0; i < staleRetries; i++ {
+		err = f()
+		if !isStale(err) {
+			return err
+		}
+		metricStaleFileHandles.Inc()
+	}
+	return err
+}
+
+func isStale(err error) bool {
+	return errors.Is(err, syscall.ESTALE)
+}
@@ -92,6 +92,7 @@ type MockReader struct {
 	M             *BlockMeta // meta
 	BlockMetaFn   func(ctx context.Context, blockID uuid.UUID, tenantID string) (*BlockMeta, error)
 	TenantIndexFn func(ctx context.Context, tenantID string) (*TenantIndex, error)
+	Holds         *LegalHolds
 	R             []byte // read
 	Range         []byte // ReadRange
 	ReadFn        func(name string, blockID uuid.UUID, tenantID string) ([]byte, error)
@@ -140,12 +141,21 @@ func (m *MockReader) TenantIndex(ctx context.Context, tenantID string) (*TenantI
 	return &TenantIndex{}, nil
 }
 
+func (m *MockReader) LegalHolds(ctx context.Context, tenantID string) (*LegalHolds, error) {
+	if m.Holds != nil {
+		return m.Holds, nil
+	}
+
+	return &LegalHolds{}, nil
+}
+
 func (m *MockReader) Shutdown() {}
 
 // MockWriter
 type MockWriter struct {
 	IndexMeta          map[string][]*BlockMeta
 	IndexCompactedMeta map[string][]*CompactedBlockMeta
+	Holds              map[string]*LegalHolds
 }
 
 func (m *MockWriter) Write(ctx context.Context, name string, blockID uuid.UUID, tenantID string, buffer []byte, shouldCache bool) error {
@@ -174,3 +184,10 @@ func (m *MockWriter) WriteTenantIndex(ctx context.Context, tenantID string, meta
 	m.IndexCompactedMeta[tenantID] = compactedMeta
 	return nil
 }
+func (m *MockWriter) WriteLegalHolds(ctx context.Context, tenantID string, holds *LegalHolds) error {
+	if m.Holds == nil {
+		m.Holds = make(map[string]*LegalHolds)
+	}
+	m.Holds[tenantID] = holds
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"path"
@@ -17,6 +18,8 @@ const (
 	MetaName          = "meta.json"
 	CompactedMetaName = "meta.compacted.json"
 	TenantIndexName   = "index.json.gz"
+	SavedQueriesName  = "saved_queries.json"
+	LegalHoldsName    = "legal_holds.json"
 	// File name for the cluster seed file.
 	ClusterSeedFileName = "tempo_cluster_seed.json"
 )
@@ -102,6 +105,15 @@ func (w *writer) WriteTenantIndex(ctx context.Context, tenantID string, meta []*
 	return nil
 }
 
+func (w *writer) WriteLegalHolds(ctx context.Context, tenantID string, holds *LegalHolds) error {
+	b, err := json.Marshal(holds)
+	if err != nil {
+		return err
+	}
+
+	return w.w.Write(ctx, LegalHoldsName, KeyPath([]string{tenantID}), bytes.NewReader(b), int64(len(b)), false)
+}
+
 type reader struct {
 	r RawReader
 }
@@ -155,7 +167,7 @@ func (r *reader) Blocks(ctx context.Context, tenantID string) ([]uuid.UUID, erro
 	for _, id := range objects {
 		// TODO: this line exists due to behavior differences in backends: https://github.com/grafana/tempo/issues/880
 		// revisit once #880 is resolved.
-		if id == TenantIndexName || id == "" {
+		if id == TenantIndexName || id == SavedQueriesName || id == LegalHoldsName || id == "" {
 			continue
 		}
 		uuid, err := uuid.Parse(id)
@@ -209,6 +221,30 @@ func (r *reader) TenantIndex(ctx context.Context, tenantID string) (*TenantIndex
 	return i, nil
 }
 
+func (r *reader) LegalHolds(ctx context.Context, tenantID string) (*LegalHolds, error) {
+	reader, size, err := r.r.Read(ctx, LegalHoldsName, KeyPath([]string{tenantID}), false)
+	if errors.Is(err, ErrDoesNotExist) {
+		return &LegalHolds{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bytes, err := tempo_io.ReadAllWithEstimate(reader, size)
+	if err != nil {
+		return nil, err
+	}
+
+	holds := &LegalHolds{}
+	err = json.Unmarshal(bytes, holds)
+	if err != nil {
+		return nil, err
+	}
+
+	return holds, nil
+}
+
 func (r *reader) Shutdown() {
 	r.r.Shutdown()
 }
@@ -4,6 +4,9 @@ import (
 	"time"
 
 	"github.com/grafana/dskit/flagext"
+
+	"github.com/grafana/tempo/tempodb/backend/credentials"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 )
 
 type Config struct {
@@ -15,12 +18,17 @@ type Config struct {
 	Insecure           bool           `yaml:"insecure"`
 	InsecureSkipVerify bool           `yaml:"insecure_skip_verify"`
 	PartSize           uint64         `yaml:"part_size"`
+	ParallelUploads    int            `yaml:"parallel_uploads"`
 	HedgeRequestsAt    time.Duration  `yaml:"hedge_requests_at"`
 	HedgeRequestsUpTo  int            `yaml:"hedge_requests_up_to"`
+	// AdaptiveConcurrency limits concurrent reads and backs off when the backend throttles them
+	AdaptiveConcurrency throttle.Config `yaml:"adaptive_concurrency"`
 	// SignatureV2 configures the object storage to use V2 signing instead of V4
 	SignatureV2    bool              `yaml:"signature_v2"`
 	ForcePathStyle bool              `yaml:"forcepathstyle"`
 	Tags           map[string]string `yaml:"tags"`
 	StorageClass   string            `yaml:"storage_class"`
 	Metadata       map[string]string `yaml:"metadata"`
+	// CredentialsPlugin sources the credentials from an external process instead of the default chain
+	CredentialsPlugin credentials.Config `yaml:"credentials_plugin"`
 }
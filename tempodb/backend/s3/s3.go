@@ -9,7 +9,9 @@ import (
 	"path"
 	"strings"
 
+	tempo_credentials "github.com/grafana/tempo/tempodb/backend/credentials"
 	"github.com/grafana/tempo/tempodb/backend/instrumentation"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/cristalhq/hedgedhttp"
@@ -63,6 +65,32 @@ func (s *overrideSignatureVersion) IsExpired() bool {
 	return s.upstream.IsExpired()
 }
 
+// pluginProvider retrieves credentials from a credentials plugin
+type pluginProvider struct {
+	plugin *tempo_credentials.Plugin
+}
+
+func (p *pluginProvider) Retrieve() (credentials.Value, error) {
+	creds, err := p.plugin.Credentials(context.Background())
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	if creds.AccessKeyID == "" {
+		return credentials.Value{}, fmt.Errorf("credentials plugin returned no access key")
+	}
+
+	return credentials.Value{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+func (p *pluginProvider) IsExpired() bool {
+	return p.plugin.Expired()
+}
+
 // NewNoConfirm gets the S3 backend without testing it
 func NewNoConfirm(cfg *Config) (backend.RawReader, backend.RawWriter, backend.Compactor, error) {
 	return internalNew(cfg, false)
@@ -100,7 +128,19 @@ func internalNew(cfg *Config, confirm bool) (backend.RawReader, backend.RawWrite
 		core:       core,
 		hedgedCore: hedgedCore,
 	}
-	return rw, rw, rw, nil
+	return rw, backend.NewComposeWriter(rw, cfg.ParallelUploads, composePartSize(cfg)), rw, nil
+}
+
+// minComposePartSize is the minimum size of every part of a multipart copy but the last
+const minComposePartSize = 5 * 1024 * 1024
+
+// composePartSize is the size of the parts uploaded in parallel, the configured part size or the minimum
+// size of a multipart copy part if larger
+func composePartSize(cfg *Config) int {
+	if cfg.PartSize > minComposePartSize {
+		return int(cfg.PartSize)
+	}
+	return minComposePartSize
 }
 
 func getPutObjectOptions(rw *readerWriter) minio.PutObjectOptions {
@@ -214,6 +254,56 @@ func (rw *readerWriter) CloseAppend(ctx context.Context, tracker backend.AppendT
 	return nil
 }
 
+// Compose implements backend.RawComposer. The parts are assembled with a multipart copy so every part but
+// the last must be at least 5MiB.
+func (rw *readerWriter) Compose(ctx context.Context, name string, keypath backend.KeyPath, parts []string) error {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "s3.Compose", opentracing.Tags{
+		"parts": len(parts),
+	})
+	defer span.Finish()
+
+	objectName := backend.ObjectFileName(keypath, name)
+	uploadID, err := rw.core.NewMultipartUpload(ctx, rw.cfg.Bucket, objectName, getPutObjectOptions(rw))
+	if err != nil {
+		return err
+	}
+
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for i, p := range parts {
+		part, err := rw.core.CopyObjectPart(ctx, rw.cfg.Bucket, backend.ObjectFileName(keypath, p), rw.cfg.Bucket, objectName, uploadID, i+1, 0, -1, nil)
+		if err != nil {
+			_ = rw.core.AbortMultipartUpload(ctx, rw.cfg.Bucket, objectName, uploadID)
+			return errors.Wrapf(err, "error copying part %s", p)
+		}
+		completeParts = append(completeParts, part)
+	}
+
+	etag, err := rw.core.CompleteMultipartUpload(ctx, rw.cfg.Bucket, objectName, uploadID, completeParts, minio.PutObjectOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "error completing multipart copy, object: %s, obj etag: %s", objectName, etag)
+	}
+
+	for _, p := range parts {
+		err = rw.core.RemoveObject(ctx, rw.cfg.Bucket, backend.ObjectFileName(keypath, p), minio.RemoveObjectOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "error deleting composed part %s", p)
+		}
+	}
+
+	return nil
+}
+
+// DeleteParts implements backend.RawComposer. A failed Compose aborts its multipart copy, only the parts are left.
+func (rw *readerWriter) DeleteParts(ctx context.Context, _ string, keypath backend.KeyPath, parts []string) error {
+	for _, p := range parts {
+		err := rw.core.RemoveObject(ctx, rw.cfg.Bucket, backend.ObjectFileName(keypath, p), minio.RemoveObjectOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "error deleting part %s", p)
+		}
+	}
+	return nil
+}
+
 // List implements backend.Reader
 func (rw *readerWriter) List(ctx context.Context, keypath backend.KeyPath) ([]string, error) {
 	prefix := path.Join(keypath...)
@@ -337,23 +427,31 @@ func createCore(cfg *Config, hedge bool) (*minio.Core, error) {
 		return p
 	}
 
-	creds := credentials.NewChainCredentials([]credentials.Provider{
-		wrapCredentialsProvider(&credentials.EnvAWS{}),
-		wrapCredentialsProvider(&credentials.Static{
-			Value: credentials.Value{
-				AccessKeyID:     cfg.AccessKey,
-				SecretAccessKey: cfg.SecretKey.String(),
-			},
-		}),
-		wrapCredentialsProvider(&credentials.EnvMinio{}),
-		wrapCredentialsProvider(&credentials.FileAWSCredentials{}),
-		wrapCredentialsProvider(&credentials.FileMinioClient{}),
-		wrapCredentialsProvider(&credentials.IAM{
-			Client: &http.Client{
-				Transport: http.DefaultTransport,
-			},
-		}),
-	})
+	var creds *credentials.Credentials
+	if cfg.CredentialsPlugin.Enabled() {
+		// a configured plugin is the only source of credentials so its errors aren't masked by the chain
+		creds = credentials.New(wrapCredentialsProvider(&pluginProvider{
+			plugin: tempo_credentials.NewPlugin(cfg.CredentialsPlugin),
+		}))
+	} else {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			wrapCredentialsProvider(&credentials.EnvAWS{}),
+			wrapCredentialsProvider(&credentials.Static{
+				Value: credentials.Value{
+					AccessKeyID:     cfg.AccessKey,
+					SecretAccessKey: cfg.SecretKey.String(),
+				},
+			}),
+			wrapCredentialsProvider(&credentials.EnvMinio{}),
+			wrapCredentialsProvider(&credentials.FileAWSCredentials{}),
+			wrapCredentialsProvider(&credentials.FileMinioClient{}),
+			wrapCredentialsProvider(&credentials.IAM{
+				Client: &http.Client{
+					Transport: http.DefaultTransport,
+				},
+			}),
+		})
+	}
 
 	customTransport, err := minio.DefaultTransport(!cfg.Insecure)
 	if err != nil {
@@ -368,6 +466,11 @@ func createCore(cfg *Config, hedge bool) (*minio.Core, error) {
 	transport := instrumentation.NewTransport(customTransport)
 	var stats *hedgedhttp.Stats
 
+	// adapt the concurrency of reads to throttling, hedged requests count against the limit
+	if hedge {
+		transport = throttle.NewTransport(cfg.AdaptiveConcurrency, transport)
+	}
+
 	if hedge && cfg.HedgeRequestsAt != 0 {
 		transport, stats, err = hedgedhttp.NewRoundTripperAndStats(cfg.HedgeRequestsAt, cfg.HedgeRequestsUpTo, transport)
 		if err != nil {
@@ -224,3 +224,9 @@ func TestObjectStorageClass(t *testing.T) {
 		})
 	}
 }
+
+func TestComposePartSize(t *testing.T) {
+	assert.Equal(t, minComposePartSize, composePartSize(&Config{}))
+	assert.Equal(t, minComposePartSize, composePartSize(&Config{PartSize: 1024}))
+	assert.Equal(t, 16*1024*1024, composePartSize(&Config{PartSize: 16 * 1024 * 1024}))
+}
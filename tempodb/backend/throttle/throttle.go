@@ -0,0 +1,244 @@
+package throttle
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricConcurrencyLimit = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "backend_concurrency_limit",
+		Help:      "The current limit of concurrent backend reads per host.",
+	}, []string{"host"})
+	metricThrottledRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_throttled_requests_total",
+		Help:      "The total number of backend reads throttled by the backend per host.",
+	}, []string{"host"})
+)
+
+// Config controls the adaptive concurrency of backend reads
+type Config struct {
+	// MaxConcurrency is the max number of concurrent reads per host and the limit reads start with. 0 disables the
+	// limit.
+	MaxConcurrency int `yaml:"max_concurrency"`
+	// MinConcurrency is the lowest the limit is reduced to. Defaults to 1.
+	MinConcurrency int `yaml:"min_concurrency"`
+	// IdleTimeout releases the slot of a read whose body isn't read for this long, in case the body is never
+	// closed. Defaults to 1m.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+const defaultIdleTimeout = time.Minute
+
+// NewTransport limits the concurrent requests per host of next. The limit is halved when the backend throttles a
+// request and raised by one after a limit's worth of requests succeed. If MaxConcurrency is 0 next is returned.
+func NewTransport(cfg Config, next http.RoundTripper) http.RoundTripper {
+	if cfg.MaxConcurrency <= 0 {
+		return next
+	}
+	if cfg.MinConcurrency <= 0 {
+		cfg.MinConcurrency = 1
+	}
+	if cfg.MinConcurrency > cfg.MaxConcurrency {
+		cfg.MinConcurrency = cfg.MaxConcurrency
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = defaultIdleTimeout
+	}
+
+	return &transport{
+		cfg:      cfg,
+		next:     next,
+		limiters: map[string]*limiter{},
+	}
+}
+
+type transport struct {
+	cfg  Config
+	next http.RoundTripper
+
+	mtx      sync.Mutex
+	limiters map[string]*limiter
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := t.limiter(req.URL.Host)
+
+	start, err := l.acquire(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+
+	l.observe(start, isThrottled(resp.StatusCode))
+	// the read lasts until the body is read to the end, closed or left idle
+	resp.Body = newReleasingBody(resp.Body, t.cfg.IdleTimeout, l.release)
+	return resp, nil
+}
+
+func (t *transport) limiter(host string) *limiter {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	l, ok := t.limiters[host]
+	if !ok {
+		l = newLimiter(host, t.cfg)
+		t.limiters[host] = l
+	}
+	return l
+}
+
+// releasingBody releases the slot of a read once, when its body returns an error or io.EOF, is closed or isn't read
+// for the idle timeout. A body that is never closed doesn't hold its slot forever.
+type releasingBody struct {
+	io.ReadCloser
+	idleTimeout time.Duration
+	idle        *time.Timer
+	once        sync.Once
+	release     func()
+}
+
+func newReleasingBody(body io.ReadCloser, idleTimeout time.Duration, release func()) *releasingBody {
+	b := &releasingBody{
+		ReadCloser:  body,
+		idleTimeout: idleTimeout,
+		release:     release,
+	}
+	b.idle = time.AfterFunc(idleTimeout, b.done)
+	return b
+}
+
+func (b *releasingBody) Read(p []byte) (int, error) {
+	b.idle.Reset(b.idleTimeout)
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.idle.Stop()
+		b.done()
+	}
+	return n, err
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.idle.Stop()
+	b.done()
+	return err
+}
+
+// done releases the slot once. It's also called by the idle timer, so it must not access the timer.
+func (b *releasingBody) done() {
+	b.once.Do(b.release)
+}
+
+// isThrottled returns true for the status codes backends respond with when they throttle requests. S3 responds to
+// SlowDown with a 503.
+func isThrottled(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// limiter is an additive increase, multiplicative decrease limit of concurrent requests to a single host
+type limiter struct {
+	min, max int
+	gauge    prometheus.Gauge
+	counter  prometheus.Counter
+
+	mtx      sync.Mutex
+	limit    int
+	inflight int
+	// successes is the number of requests that succeeded since the limit last changed
+	successes int
+	// decreasedAt is when the limit was last decreased. Throttled requests started before are ignored so a burst of
+	// throttled responses to requests sent under the old limit only decreases it once.
+	decreasedAt time.Time
+	// released is closed and replaced whenever a request finishes or the limit is raised
+	released chan struct{}
+}
+
+func newLimiter(host string, cfg Config) *limiter {
+	l := &limiter{
+		min:      cfg.MinConcurrency,
+		max:      cfg.MaxConcurrency,
+		gauge:    metricConcurrencyLimit.WithLabelValues(host),
+		counter:  metricThrottledRequests.WithLabelValues(host),
+		limit:    cfg.MaxConcurrency,
+		released: make(chan struct{}),
+	}
+	l.gauge.Set(float64(l.limit))
+	return l
+}
+
+// acquire waits until the request fits in the limit or its context is done. It returns when the request started.
+func (l *limiter) acquire(req *http.Request) (time.Time, error) {
+	for {
+		l.mtx.Lock()
+		if l.inflight < l.limit {
+			l.inflight++
+			l.mtx.Unlock()
+			return time.Now(), nil
+		}
+		released := l.released
+		l.mtx.Unlock()
+
+		select {
+		case <-released:
+		case <-req.Context().Done():
+			return time.Time{}, req.Context().Err()
+		}
+	}
+}
+
+func (l *limiter) release() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.inflight--
+	l.notify()
+}
+
+// observe adjusts the limit with the outcome of a request started at start
+func (l *limiter) observe(start time.Time, throttled bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if throttled {
+		l.counter.Inc()
+		if start.Before(l.decreasedAt) {
+			return
+		}
+
+		l.limit /= 2
+		if l.limit < l.min {
+			l.limit = l.min
+		}
+		l.successes = 0
+		l.decreasedAt = time.Now()
+		l.gauge.Set(float64(l.limit))
+		return
+	}
+
+	l.successes++
+	if l.successes >= l.limit && l.limit < l.max {
+		l.limit++
+		l.successes = 0
+		l.gauge.Set(float64(l.limit))
+		l.notify()
+	}
+}
+
+// notify wakes up the requests waiting in acquire. It must be called with the lock held.
+func (l *limiter) notify() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
@@ -0,0 +1,187 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterAdapts(t *testing.T) {
+	l := newLimiter("test", Config{MinConcurrency: 2, MaxConcurrency: 8})
+	assert.Equal(t, 8, l.limit)
+
+	before := time.Now()
+	l.observe(time.Now(), true)
+	assert.Equal(t, 4, l.limit)
+
+	// requests sent under the old limit don't decrease it again
+	l.observe(before, true)
+	assert.Equal(t, 4, l.limit)
+
+	// the limit is never reduced below the min
+	l.observe(time.Now(), true)
+	l.observe(time.Now(), true)
+	assert.Equal(t, 2, l.limit)
+
+	// a limit's worth of successes raises it by one
+	l.observe(time.Now(), false)
+	assert.Equal(t, 2, l.limit)
+	l.observe(time.Now(), false)
+	assert.Equal(t, 3, l.limit)
+
+	for i := 0; i < 100; i++ {
+		l.observe(time.Now(), false)
+	}
+	assert.Equal(t, 8, l.limit)
+}
+
+func TestTransportLimitsConcurrency(t *testing.T) {
+	next := &mockRoundTripper{
+		statusCode: http.StatusServiceUnavailable,
+	}
+	tr := NewTransport(Config{MaxConcurrency: 4}, next)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, err := http.NewRequest(http.MethodGet, "http://bucket.host/object", nil)
+			require.NoError(t, err)
+			resp, err := tr.RoundTrip(req)
+			require.NoError(t, err)
+
+			// the request holds its slot until the body is closed
+			time.Sleep(10 * time.Millisecond)
+			require.NoError(t, resp.Body.Close())
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, next.maxInflight, 4)
+	assert.Equal(t, 20, next.requests)
+
+	l := tr.(*transport).limiters["bucket.host"]
+	require.NotNil(t, l)
+	assert.Less(t, l.limit, 4)
+	assert.Equal(t, 0, l.inflight)
+}
+
+func TestTransportWaitRespectsContext(t *testing.T) {
+	next := &mockRoundTripper{
+		statusCode: http.StatusOK,
+	}
+	tr := NewTransport(Config{MaxConcurrency: 1}, next)
+
+	req, err := http.NewRequest(http.MethodGet, "http://bucket.host/object", nil)
+	require.NoError(t, err)
+	resp, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = tr.RoundTrip(req.WithContext(ctx))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// other hosts have their own limit
+	req, err = http.NewRequest(http.MethodGet, "http://other.host/object", nil)
+	require.NoError(t, err)
+	other, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+
+	require.NoError(t, resp.Body.Close())
+	require.NoError(t, other.Body.Close())
+}
+
+func TestTransportReleasesUnclosedBodies(t *testing.T) {
+	next := &mockRoundTripper{
+		statusCode: http.StatusOK,
+	}
+	tr := NewTransport(Config{MaxConcurrency: 1, IdleTimeout: 50 * time.Millisecond}, next)
+
+	roundTrip := func() *http.Response {
+		req, err := http.NewRequest(http.MethodGet, "http://bucket.host/object", nil)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		resp, err := tr.RoundTrip(req.WithContext(ctx))
+		require.NoError(t, err)
+		return resp
+	}
+
+	// a body read to the end releases its slot without being closed
+	resp := roundTrip()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "object", string(b))
+
+	// an idle body releases its slot after the idle timeout
+	start := time.Now()
+	resp = roundTrip()
+	roundTrip()
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// closing the body doesn't release the slot again
+	require.NoError(t, resp.Body.Close())
+	l := tr.(*transport).limiters["bucket.host"]
+	require.Eventually(t, func() bool {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+		return l.inflight == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTransportDisabled(t *testing.T) {
+	next := &mockRoundTripper{}
+	assert.Equal(t, http.RoundTripper(next), NewTransport(Config{}, next))
+}
+
+type mockRoundTripper struct {
+	statusCode int
+
+	mtx         sync.Mutex
+	inflight    int
+	maxInflight int
+	requests    int
+}
+
+func (m *mockRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.requests++
+	m.inflight++
+	if m.inflight > m.maxInflight {
+		m.maxInflight = m.inflight
+	}
+
+	return &http.Response{
+		StatusCode: m.statusCode,
+		Body: &mockBody{
+			Reader: strings.NewReader("object"),
+			close: func() {
+				m.mtx.Lock()
+				m.inflight--
+				m.mtx.Unlock()
+			},
+		},
+	}, nil
+}
+
+type mockBody struct {
+	io.Reader
+	close func()
+}
+
+func (b *mockBody) Close() error {
+	b.close()
+	return nil
+}
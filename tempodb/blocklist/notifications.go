@@ -0,0 +1,196 @@
+package blocklist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var metricNotificationEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempodb",
+	Name:      "blocklist_notification_events_total",
+	Help:      "Total number of block meta changes received as bucket notifications by status.",
+}, []string{"status"})
+
+// NotificationsConfig configures updating the blocklist from bucket notifications in between polls
+type NotificationsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Token must be passed as the token query parameter of notification requests
+	Token flagext.Secret `yaml:"token"`
+	// BlocklistPoll replaces the poll period of the blocklist, polls only pick up missed notifications
+	BlocklistPoll time.Duration `yaml:"blocklist_poll"`
+}
+
+// BlockEvent is a change of the meta of a block reported by a bucket notification
+type BlockEvent struct {
+	TenantID string
+	BlockID  uuid.UUID
+	// Compacted is set if the compacted meta of the block changed
+	Compacted bool
+	// Removed is set if the meta was removed, otherwise it was written
+	Removed bool
+}
+
+// Notification are the block events of a bucket notification. SubscribeURL is set if the notification asks to
+// confirm an SNS subscription.
+type Notification struct {
+	Events       []BlockEvent
+	SubscribeURL string
+}
+
+type s3Notification struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+type snsNotification struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+type pubSubPush struct {
+	Message struct {
+		Attributes map[string]string `json:"attributes"`
+	} `json:"message"`
+}
+
+// ParseNotification parses S3 event notifications, delivered directly or wrapped in an SNS message, and GCS
+// Pub/Sub push messages. Changes of objects other than block metas are ignored.
+func ParseNotification(body []byte) (*Notification, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("invalid bucket notification: %w", err)
+	}
+
+	n := &Notification{}
+	switch {
+	case fields["Records"] != nil:
+		s3 := s3Notification{}
+		if err := json.Unmarshal(body, &s3); err != nil {
+			return nil, fmt.Errorf("invalid S3 event notification: %w", err)
+		}
+		for _, r := range s3.Records {
+			// keys of S3 events are url encoded
+			key, err := url.QueryUnescape(r.S3.Object.Key)
+			if err != nil {
+				continue
+			}
+			n.addEvent(key, strings.HasPrefix(r.EventName, "ObjectRemoved:"))
+		}
+	case fields["Type"] != nil:
+		sns := snsNotification{}
+		if err := json.Unmarshal(body, &sns); err != nil {
+			return nil, fmt.Errorf("invalid SNS message: %w", err)
+		}
+		switch sns.Type {
+		case "SubscriptionConfirmation":
+			n.SubscribeURL = sns.SubscribeURL
+		case "Notification":
+			return ParseNotification([]byte(sns.Message))
+		}
+	case fields["message"] != nil:
+		push := pubSubPush{}
+		if err := json.Unmarshal(body, &push); err != nil {
+			return nil, fmt.Errorf("invalid Pub/Sub push message: %w", err)
+		}
+		attributes := push.Message.Attributes
+		switch attributes["eventType"] {
+		case "OBJECT_FINALIZE":
+			n.addEvent(attributes["objectId"], false)
+		case "OBJECT_DELETE", "OBJECT_ARCHIVE":
+			n.addEvent(attributes["objectId"], true)
+		}
+	default:
+		return nil, errors.New("unknown bucket notification")
+	}
+
+	return n, nil
+}
+
+// addEvent adds an event if the key is the meta or compacted meta of a block. Keys may have a prefix, the
+// last three parts are <tenant>/<block id>/<meta>.
+func (n *Notification) addEvent(key string, removed bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) < 3 {
+		return
+	}
+	parts = parts[len(parts)-3:]
+
+	if parts[2] != backend.MetaName && parts[2] != backend.CompactedMetaName {
+		return
+	}
+	blockID, err := uuid.Parse(parts[1])
+	if err != nil || parts[0] == "" {
+		return
+	}
+
+	n.Events = append(n.Events, BlockEvent{
+		TenantID:  parts[0],
+		BlockID:   blockID,
+		Compacted: parts[2] == backend.CompactedMetaName,
+		Removed:   removed,
+	})
+}
+
+// ApplyEvents updates the list with the changed blocks of the events. Written metas are read from the backend,
+// a block found to be compacted is moved to the compacted blocks. Like all updates of the list they are kept
+// until the next poll.
+func (p *Poller) ApplyEvents(ctx context.Context, l *List, events []BlockEvent) error {
+	var errs []error
+	for _, e := range events {
+		var (
+			add             []*backend.BlockMeta
+			remove          []*backend.BlockMeta
+			compactedAdd    []*backend.CompactedBlockMeta
+			compactedRemove []*backend.CompactedBlockMeta
+		)
+
+		switch {
+		case e.Removed && e.Compacted:
+			compactedRemove = append(compactedRemove, &backend.CompactedBlockMeta{BlockMeta: backend.BlockMeta{BlockID: e.BlockID}})
+		case e.Removed:
+			remove = append(remove, &backend.BlockMeta{BlockID: e.BlockID})
+		default:
+			m, cm, err := p.pollBlock(ctx, e.TenantID, e.BlockID)
+			if err != nil {
+				metricNotificationEvents.WithLabelValues("error").Inc()
+				metricBlocklistErrors.WithLabelValues(e.TenantID).Inc()
+				errs = append(errs, err)
+				continue
+			}
+			if m != nil {
+				add = append(add, m)
+			}
+			if cm != nil {
+				remove = append(remove, &cm.BlockMeta)
+				compactedAdd = append(compactedAdd, cm)
+			}
+		}
+
+		metricNotificationEvents.WithLabelValues("applied").Inc()
+		l.Update(e.TenantID, add, remove, compactedAdd, compactedRemove)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to read %d of %d changed block metas, first error: %w", len(errs), len(events), errs[0])
+	}
+	return nil
+}
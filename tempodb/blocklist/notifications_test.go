@@ -0,0 +1,132 @@
+package blocklist
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+func TestParseNotification(t *testing.T) {
+	blockID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+	tests := []struct {
+		name         string
+		body         string
+		expected     *Notification
+		expectsError bool
+	}{
+		{
+			name: "s3",
+			body: `{"Records": [
+				{"eventName": "ObjectCreated:Put", "s3": {"object": {"key": "single-tenant/00000000-0000-0000-0000-000000000001/meta.json"}}},
+				{"eventName": "ObjectRemoved:Delete", "s3": {"object": {"key": "prefix/single-tenant/00000000-0000-0000-0000-000000000001/meta.compacted.json"}}},
+				{"eventName": "ObjectCreated:Put", "s3": {"object": {"key": "single-tenant/00000000-0000-0000-0000-000000000001/data"}}},
+				{"eventName": "ObjectCreated:Put", "s3": {"object": {"key": "single-tenant/index.json.gz"}}}
+			]}`,
+			expected: &Notification{
+				Events: []BlockEvent{
+					{TenantID: "single-tenant", BlockID: blockID},
+					{TenantID: "single-tenant", BlockID: blockID, Compacted: true, Removed: true},
+				},
+			},
+		},
+		{
+			name: "sns",
+			body: `{"Type": "Notification", "Message": "{\"Records\": [{\"eventName\": \"ObjectCreated:Put\", \"s3\": {\"object\": {\"key\": \"tenant%3D1/00000000-0000-0000-0000-000000000001/meta.compacted.json\"}}}]}"}`,
+			expected: &Notification{
+				Events: []BlockEvent{
+					{TenantID: "tenant=1", BlockID: blockID, Compacted: true},
+				},
+			},
+		},
+		{
+			name: "sns subscription confirmation",
+			body: `{"Type": "SubscriptionConfirmation", "SubscribeURL": "https://sns.example.com/confirm"}`,
+			expected: &Notification{
+				SubscribeURL: "https://sns.example.com/confirm",
+			},
+		},
+		{
+			name: "pubsub",
+			body: `{"message": {"attributes": {"eventType": "OBJECT_DELETE", "objectId": "single-tenant/00000000-0000-0000-0000-000000000001/meta.json"}}, "subscription": "sub"}`,
+			expected: &Notification{
+				Events: []BlockEvent{
+					{TenantID: "single-tenant", BlockID: blockID, Removed: true},
+				},
+			},
+		},
+		{
+			name:     "pubsub metadata update",
+			body:     `{"message": {"attributes": {"eventType": "OBJECT_METADATA_UPDATE", "objectId": "single-tenant/00000000-0000-0000-0000-000000000001/meta.json"}}}`,
+			expected: &Notification{},
+		},
+		{
+			name:         "unknown",
+			body:         `{"foo": "bar"}`,
+			expectsError: true,
+		},
+		{
+			name:         "invalid",
+			body:         `not json`,
+			expectsError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ParseNotification([]byte(tc.body))
+			if tc.expectsError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestApplyEvents(t *testing.T) {
+	live := &backend.BlockMeta{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), TenantID: "test"}
+	compacted := &backend.CompactedBlockMeta{BlockMeta: backend.BlockMeta{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), TenantID: "test"}}
+	removed := &backend.BlockMeta{BlockID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), TenantID: "test"}
+
+	r := newMockReader(PerTenant{"test": {live}}, nil, false)
+	c := newMockCompactor(PerTenantCompacted{"test": {compacted}}, false)
+	poller := NewPoller(&PollerConfig{
+		PollConcurrency:     testPollConcurrency,
+		PollFallback:        testPollFallback,
+		TenantIndexBuilders: testBuilders,
+	}, &mockJobSharder{}, r, c, &backend.MockWriter{}, log.NewNopLogger())
+
+	l := New()
+	l.ApplyPollResults(PerTenant{"test": {removed, &compacted.BlockMeta}}, PerTenantCompacted{})
+
+	err := poller.ApplyEvents(context.Background(), l, []BlockEvent{
+		{TenantID: "test", BlockID: live.BlockID},
+		{TenantID: "test", BlockID: compacted.BlockID, Compacted: true},
+		{TenantID: "test", BlockID: removed.BlockID, Removed: true},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []*backend.BlockMeta{live}, l.Metas("test"))
+	assert.Equal(t, []*backend.CompactedBlockMeta{compacted}, l.CompactedMetas("test"))
+
+	// the changes are kept for the next poll
+	l.ApplyPollResults(PerTenant{}, PerTenantCompacted{})
+	assert.Equal(t, []*backend.BlockMeta{live}, l.Metas("test"))
+
+	// errors reading a meta are returned after applying the other events
+	poller = NewPoller(&PollerConfig{}, &mockJobSharder{}, newMockReader(nil, nil, true), c, &backend.MockWriter{}, log.NewNopLogger())
+	err = poller.ApplyEvents(context.Background(), l, []BlockEvent{
+		{TenantID: "test", BlockID: live.BlockID},
+		{TenantID: "test", BlockID: live.BlockID, Removed: true},
+	})
+	assert.Error(t, err)
+	assert.Empty(t, l.Metas("test"))
+}
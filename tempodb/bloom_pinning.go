@@ -0,0 +1,194 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gkLog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/atomic"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/blocklist"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var (
+	metricPinnedBloomBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "bloom_pinned_bytes",
+		Help:      "Total size of the bloom filters pinned in memory.",
+	})
+	metricPinnedBlooms = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "bloom_pinned_objects",
+		Help:      "Number of bloom filter shards pinned in memory.",
+	})
+	metricPinnedBloomHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "bloom_pinned_hits_total",
+		Help:      "Total number of bloom filter reads served from memory.",
+	})
+	metricPinnedBloomFetchFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "bloom_pinned_fetch_failures_total",
+		Help:      "Total number of bloom filters that failed to be fetched for pinning.",
+	})
+)
+
+// bloomPinner keeps the bloom filters of the most recent blocks of the configured tenants in memory.
+type bloomPinner struct {
+	cfg     BloomPinningConfig
+	tenants map[string]struct{}
+	all     bool
+	r       backend.Reader
+	logger  gkLog.Logger
+
+	mtx    sync.RWMutex
+	pinned map[string][]byte
+
+	updating atomic.Bool
+}
+
+func newBloomPinner(cfg BloomPinningConfig, r backend.Reader, logger gkLog.Logger) *bloomPinner {
+	p := &bloomPinner{
+		cfg:     cfg,
+		tenants: map[string]struct{}{},
+		r:       r,
+		logger:  logger,
+		pinned:  map[string][]byte{},
+	}
+	for _, t := range cfg.Tenants {
+		if t == "*" {
+			p.all = true
+		}
+		p.tenants[t] = struct{}{}
+	}
+	return p
+}
+
+// wrap returns a reader that serves pinned blooms from memory and passes all other reads to next.
+func (p *bloomPinner) wrap(next backend.RawReader) backend.RawReader {
+	return &pinnedReader{RawReader: next, p: p}
+}
+
+func (p *bloomPinner) get(keypath backend.KeyPath, name string) ([]byte, bool) {
+	if !strings.HasPrefix(name, common.NameBloomPrefix) {
+		return nil, false
+	}
+
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	b, ok := p.pinned[pinnedKey(keypath, name)]
+	return b, ok
+}
+
+func (p *bloomPinner) pinsTenant(tenantID string) bool {
+	if p.all {
+		return true
+	}
+	_, ok := p.tenants[tenantID]
+	return ok
+}
+
+// updateAsync updates the pinned blooms in the background. Polls are skipped while a previous update is still
+// fetching blooms.
+func (p *bloomPinner) updateAsync(ctx context.Context, blocklist blocklist.PerTenant) {
+	if !p.updating.CAS(false, true) {
+		level.Info(p.logger).Log("msg", "bloom pinning still in progress, skipping update")
+		return
+	}
+
+	go func() {
+		defer p.updating.Store(false)
+		p.update(ctx, blocklist, time.Now())
+	}()
+}
+
+// update pins the blooms of the blocks in the blocklist, newest first, until the memory budget is reached.
+// Blooms already pinned are kept, the blooms of all other blocks are released.
+func (p *bloomPinner) update(ctx context.Context, blocklist blocklist.PerTenant, now time.Time) {
+	var metas []*backend.BlockMeta
+	for tenantID, tenantMetas := range blocklist {
+		if !p.pinsTenant(tenantID) {
+			continue
+		}
+		for _, m := range tenantMetas {
+			if p.cfg.MaxBlockAge > 0 && now.Sub(m.EndTime) > p.cfg.MaxBlockAge {
+				continue
+			}
+			metas = append(metas, m)
+		}
+	}
+
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].EndTime.After(metas[j].EndTime)
+	})
+
+	p.mtx.RLock()
+	current := p.pinned
+	p.mtx.RUnlock()
+
+	pinned := make(map[string][]byte, len(current))
+	var size uint64
+
+outer:
+	for _, m := range metas {
+		keypath := backend.KeyPathForBlock(m.BlockID, m.TenantID)
+		for i := 0; i < common.ValidateShardCount(int(m.BloomShardCount)); i++ {
+			name := common.BloomName(i)
+			k := pinnedKey(keypath, name)
+
+			b, ok := current[k]
+			if !ok {
+				var err error
+				b, err = p.r.Read(ctx, name, m.BlockID, m.TenantID, false)
+				if err != nil {
+					metricPinnedBloomFetchFailures.Inc()
+					level.Warn(p.logger).Log("msg", "failed to fetch bloom for pinning", "tenant", m.TenantID, "block", m.BlockID, "name", name, "err", err)
+					continue
+				}
+			}
+
+			if size+uint64(len(b)) > p.cfg.MaxBytes {
+				break outer
+			}
+			size += uint64(len(b))
+			pinned[k] = b
+		}
+	}
+
+	p.mtx.Lock()
+	p.pinned = pinned
+	p.mtx.Unlock()
+
+	metricPinnedBloomBytes.Set(float64(size))
+	metricPinnedBlooms.Set(float64(len(pinned)))
+}
+
+func pinnedKey(keypath backend.KeyPath, name string) string {
+	return strings.Join(keypath, ":") + ":" + name
+}
+
+type pinnedReader struct {
+	backend.RawReader
+	p *bloomPinner
+}
+
+// Read implements backend.RawReader
+func (r *pinnedReader) Read(ctx context.Context, name string, keypath backend.KeyPath, shouldCache bool) (io.ReadCloser, int64, error) {
+	if b, ok := r.p.get(keypath, name); ok {
+		metricPinnedBloomHits.Inc()
+		return io.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+	}
+
+	return r.RawReader.Read(ctx, name, keypath, shouldCache)
+}
@@ -0,0 +1,104 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/blocklist"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestBloomPinner(t *testing.T) {
+	now := time.Now()
+
+	newMeta := func(tenantID string, age time.Duration, shards uint16) *backend.BlockMeta {
+		m := backend.NewBlockMeta(tenantID, uuid.New(), "v2", backend.EncNone, "")
+		m.EndTime = now.Add(-age)
+		m.BloomShardCount = shards
+		return m
+	}
+
+	var (
+		mtx   sync.Mutex
+		reads = map[string]int{}
+	)
+	raw := &backend.MockRawReader{
+		ReadFn: func(ctx context.Context, name string, keypath backend.KeyPath, shouldCache bool) (io.ReadCloser, int64, error) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			reads[pinnedKey(keypath, name)]++
+
+			b := bytes.Repeat([]byte{0x01}, 10)
+			return io.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+		},
+	}
+
+	recent := newMeta("pinned", time.Hour, 2)
+	older := newMeta("pinned", 2*time.Hour, 2)
+	oldest := newMeta("pinned", 3*time.Hour, 1)
+	tooOld := newMeta("pinned", 48*time.Hour, 1)
+	notPinned := newMeta("other", time.Hour, 1)
+
+	p := newBloomPinner(BloomPinningConfig{
+		Tenants:     []string{"pinned"},
+		MaxBlockAge: 24 * time.Hour,
+		MaxBytes:    40, // 4 shards
+	}, backend.NewReader(raw), log.NewNopLogger())
+
+	p.update(context.Background(), blocklist.PerTenant{
+		"pinned": {oldest, tooOld, recent, older},
+		"other":  {notPinned},
+	}, now)
+
+	isPinned := func(m *backend.BlockMeta, shard int) bool {
+		_, ok := p.get(backend.KeyPathForBlock(m.BlockID, m.TenantID), common.BloomName(shard))
+		return ok
+	}
+
+	// the newest blocks fit into the budget
+	assert.True(t, isPinned(recent, 0))
+	assert.True(t, isPinned(recent, 1))
+	assert.True(t, isPinned(older, 0))
+	assert.True(t, isPinned(older, 1))
+	assert.False(t, isPinned(oldest, 0))
+	assert.False(t, isPinned(tooOld, 0))
+	assert.False(t, isPinned(notPinned, 0))
+
+	// pinned blooms are served from memory
+	r := p.wrap(raw)
+	keypath := backend.KeyPathForBlock(recent.BlockID, recent.TenantID)
+	rc, size, err := r.Read(context.Background(), common.BloomName(0), keypath, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), size)
+	_ = rc.Close()
+	assert.Equal(t, 1, reads[pinnedKey(keypath, common.BloomName(0))])
+
+	// a block is removed, the next block is pinned without fetching the already pinned blooms again
+	p.update(context.Background(), blocklist.PerTenant{
+		"pinned": {oldest, older},
+	}, now)
+
+	assert.False(t, isPinned(recent, 0))
+	assert.True(t, isPinned(older, 0))
+	assert.True(t, isPinned(oldest, 0))
+	assert.Equal(t, 1, reads[pinnedKey(backend.KeyPathForBlock(older.BlockID, older.TenantID), common.BloomName(0))])
+}
+
+func TestBloomPinnerAllTenants(t *testing.T) {
+	p := newBloomPinner(BloomPinningConfig{Tenants: []string{"*"}}, nil, log.NewNopLogger())
+	assert.True(t, p.pinsTenant("foo"))
+
+	p = newBloomPinner(BloomPinningConfig{Tenants: []string{"foo"}}, nil, log.NewNopLogger())
+	assert.True(t, p.pinsTenant("foo"))
+	assert.False(t, p.pinsTenant("bar"))
+}
@@ -87,7 +87,14 @@ func (rw *readerWriter) doCompaction() {
 	rw.compactorTenantOffset = (rw.compactorTenantOffset + 1) % uint(len(tenants))
 
 	tenantID := tenants[rw.compactorTenantOffset]
-	blocklist := rw.blocklist.Metas(tenantID)
+
+	// blocks flagged corrupt are only rewritten by the salvage loop
+	var blocklist []*backend.BlockMeta
+	for _, m := range rw.blocklist.Metas(tenantID) {
+		if !m.Corrupt {
+			blocklist = append(blocklist, m)
+		}
+	}
 
 	blockSelector := newTimeWindowBlockSelector(blocklist,
 		rw.compactorCfg.MaxCompactionRange,
@@ -99,6 +106,7 @@ func (rw *readerWriter) doCompaction() {
 	start := time.Now()
 
 	level.Info(rw.logger).Log("msg", "starting compaction cycle", "tenantID", tenantID, "offset", rw.compactorTenantOffset)
+	selectStart := start
 	for {
 		toBeCompacted, hashString := blockSelector.BlocksToCompact()
 		if len(toBeCompacted) == 0 {
@@ -112,7 +120,7 @@ func (rw *readerWriter) doCompaction() {
 			continue
 		}
 		level.Info(rw.logger).Log("msg", "Compacting hash", "hashString", hashString)
-		err := rw.compact(toBeCompacted, tenantID)
+		err := rw.compactSelected(toBeCompacted, tenantID, selectStart)
 
 		if err == backend.ErrDoesNotExist {
 			level.Warn(rw.logger).Log("msg", "unable to find meta during compaction.  trying again on this block list", "err", err)
@@ -128,16 +136,39 @@ func (rw *readerWriter) doCompaction() {
 			level.Info(rw.logger).Log("msg", "compacted blocks for a maintenance cycle, bailing out", "tenantID", tenantID)
 			break
 		}
+
+		selectStart = time.Now()
 	}
 }
 
 func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string) error {
+	return rw.compactSelected(blockMetas, tenantID, time.Time{})
+}
+
+// compactSelected compacts the blocks chosen by the compaction loop. selectStart is when the loop started to select
+// them, it is zero for blocks that were not selected by the loop.
+func (rw *readerWriter) compactSelected(blockMetas []*backend.BlockMeta, tenantID string, selectStart time.Time) error {
 	level.Debug(rw.logger).Log("msg", "beginning compaction", "num blocks compacting", len(blockMetas))
 
+	tracer := rw.compactionTracer()
+	jobStart := time.Now()
+	spanStart := jobStart
+	if !selectStart.IsZero() {
+		spanStart = selectStart
+	}
+
 	// todo - add timeout?
-	ctx := context.Background()
-	span, ctx := opentracing.StartSpanFromContext(ctx, "rw.compact")
+	span := tracer.StartSpan("rw.compact", opentracing.StartTime(spanStart), opentracing.Tags{
+		"tenant": tenantID,
+		"blocks": len(blockMetas),
+	})
 	defer span.Finish()
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+
+	if !selectStart.IsZero() {
+		selectSpan := tracer.StartSpan("rw.compact.selectBlocks", opentracing.ChildOf(span.Context()), opentracing.StartTime(selectStart))
+		selectSpan.FinishWithOptions(opentracing.FinishOptions{FinishTime: jobStart})
+	}
 
 	traceID, _ := util.ExtractTraceID(ctx)
 	if traceID != "" {
@@ -152,17 +183,39 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 	startTime := time.Now()
 
 	var totalRecords int
+	currentMetas := make([]*backend.BlockMeta, 0, len(blockMetas))
 	for _, blockMeta := range blockMetas {
 		level.Info(rw.logger).Log("msg", "compacting block", "block", fmt.Sprintf("%+v", blockMeta))
 		totalRecords += blockMeta.TotalObjects
 
 		// Make sure block still exists
-		_, err = rw.r.BlockMeta(ctx, blockMeta.BlockID, tenantID)
+		currentMeta, err := rw.r.BlockMeta(ctx, blockMeta.BlockID, tenantID)
 		if err != nil {
 			return err
 		}
+		currentMetas = append(currentMetas, currentMeta)
+	}
+
+	holds, err := rw.r.LegalHolds(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("error reading legal holds: %w", err)
 	}
 
+	tombstones, err := readTombstoneIDs(ctx, rw.r, currentMetas)
+	if err != nil {
+		return err
+	}
+	dropObject, err := tombstoneFilter(holds, currentMetas, tombstones)
+	if err != nil {
+		return err
+	}
+
+	// the parts of a trace are combined in the order of the blocks, oldest first
+	blockMetas = append([]*backend.BlockMeta(nil), blockMetas...)
+	sort.SliceStable(blockMetas, func(i, j int) bool {
+		return blockMetas[i].EndTime.Before(blockMetas[j].EndTime)
+	})
+
 	enc, err := encoding.FromVersion(blockMetas[0].Version)
 	if err != nil {
 		return err
@@ -170,6 +223,7 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 
 	compactionLevel := compactionLevelForBlocks(blockMetas)
 	compactionLevelLabel := strconv.Itoa(int(compactionLevel))
+	span.SetTag("level", compactionLevel)
 
 	combiner := instrumentedObjectCombiner{
 		tenant:               tenantID,
@@ -177,13 +231,17 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 		compactionLevelLabel: compactionLevelLabel,
 	}
 
+	blockCfg := rw.compactorCfg.BlockConfigForLevel(*rw.cfg.Block, compactionLevel+1) // settings of the output blocks
+	blockCfg.BloomFP = rw.bloomFPForTenant(tenantID)
+
 	opts := common.CompactionOptions{
-		BlockConfig:        *rw.cfg.Block,
+		BlockConfig:        blockCfg,
 		ChunkSizeBytes:     rw.compactorCfg.ChunkSizeBytes,
 		FlushSizeBytes:     rw.compactorCfg.FlushSizeBytes,
 		IteratorBufferSize: rw.compactorCfg.IteratorBufferSize,
 		OutputBlocks:       outputBlocks,
 		Combiner:           combiner,
+		CombineStrategy:    rw.compactorOverrides.CombineStrategyForTenant(tenantID),
 		MaxBytesPerTrace:   rw.compactorOverrides.MaxBytesPerTraceForTenant(tenantID),
 		BytesWritten: func(compactionLevel, bytes int) {
 			metricCompactionBytesWritten.WithLabelValues(strconv.Itoa(compactionLevel)).Add(float64(bytes))
@@ -197,18 +255,31 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 		SpansDiscarded: func(spans int) {
 			rw.compactorSharder.RecordDiscardedSpans(spans, tenantID)
 		},
+		TraceTruncated: func() {
+			rw.compactorSharder.RecordTruncatedTrace(tenantID)
+		},
+		DropObject: dropObject,
+		Tracer:     tracer,
 	}
 
 	compactor := enc.NewCompactor(opts)
 
 	newCompactedBlocks, err := compactor.Compact(ctx, rw.logger, rw.r, rw.getWriterForBlock, blockMetas)
 	if err != nil {
+		span.SetTag("error", true)
 		return err
 	}
 
 	// mark old blocks compacted so they don't show up in polling
 	markCompacted(rw, tenantID, blockMetas, newCompactedBlocks)
 
+	// tombstones written during the compaction are kept with the new blocks
+	err = carryOverTombstones(ctx, rw.r, rw.w, tombstones, blockMetas, newCompactedBlocks)
+	if err != nil {
+		level.Error(rw.logger).Log("msg", "unable to carry over tombstones to the compacted blocks", "tenantID", tenantID, "err", err)
+		metricCompactionErrors.Inc()
+	}
+
 	metricCompactionBlocks.WithLabelValues(compactionLevelLabel).Add(float64(len(blockMetas)))
 
 	logArgs := []interface{}{
@@ -225,6 +296,14 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 	return nil
 }
 
+// compactionTracer returns the tracer that records the compaction jobs
+func (rw *readerWriter) compactionTracer() opentracing.Tracer {
+	if rw.compactorCfg != nil && rw.compactorCfg.Tracer != nil {
+		return rw.compactorCfg.Tracer
+	}
+	return opentracing.GlobalTracer()
+}
+
 func markCompacted(rw *readerWriter, tenantID string, oldBlocks []*backend.BlockMeta, newBlocks []*backend.BlockMeta) {
 	for _, meta := range oldBlocks {
 		// Mark in the backend
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"math/rand"
 	"path"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,6 +15,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	ot_bridge "go.opentelemetry.io/otel/bridge/opentracing"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/model/trace"
@@ -27,6 +30,7 @@ import (
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
 	"github.com/grafana/tempo/tempodb/pool"
 	"github.com/grafana/tempo/tempodb/wal"
 )
@@ -44,6 +48,8 @@ func (m *mockSharder) Combine(dataEncoding string, tenantID string, objs ...[]by
 
 func (m *mockSharder) RecordDiscardedSpans(count int, tenantID string) {}
 
+func (m *mockSharder) RecordTruncatedTrace(tenantID string) {}
+
 type mockCombiner struct {
 }
 
@@ -58,6 +64,8 @@ func (m *mockJobSharder) Owns(_ string) bool { return true }
 type mockOverrides struct {
 	blockRetention   time.Duration
 	maxBytesPerTrace int
+	combineStrategy  common.CombineStrategy
+	bloomFP          float64
 }
 
 func (m *mockOverrides) BlockRetentionForTenant(_ string) time.Duration {
@@ -68,8 +76,16 @@ func (m *mockOverrides) MaxBytesPerTraceForTenant(_ string) int {
 	return m.maxBytesPerTrace
 }
 
+func (m *mockOverrides) CombineStrategyForTenant(_ string) common.CombineStrategy {
+	return m.combineStrategy
+}
+
+func (m *mockOverrides) BloomFPForTenant(_ string) float64 {
+	return m.bloomFP
+}
+
 func TestCompactionRoundtrip(t *testing.T) {
-	testEncodings := []string{v2.VersionString, vparquet.VersionString}
+	testEncodings := []string{v2.VersionString, vparquet.VersionString, vparquet2.VersionString}
 	for _, enc := range testEncodings {
 		t.Run(enc, func(t *testing.T) {
 			testCompactionRoundtrip(t, enc)
@@ -211,7 +227,7 @@ func testCompactionRoundtrip(t *testing.T, targetBlockVersion string) {
 }
 
 func TestSameIDCompaction(t *testing.T) {
-	testEncodings := []string{v2.VersionString, vparquet.VersionString}
+	testEncodings := []string{v2.VersionString, vparquet.VersionString, vparquet2.VersionString}
 	for _, enc := range testEncodings {
 		t.Run(enc, func(t *testing.T) {
 			testSameIDCompaction(t, enc)
@@ -432,6 +448,157 @@ func TestCompactionUpdatesBlocklist(t *testing.T) {
 	}
 }
 
+func TestCompactionLevelBlockConfig(t *testing.T) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			BloomShardSizeBytes:  100_000,
+			Version:              v2.VersionString,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	zstd := backend.EncZstd
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      24 * time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: 0,
+		Levels: []CompactionLevelConfig{
+			{Level: 2, Encoding: &zstd},
+		},
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+
+	rw := r.(*readerWriter)
+	compactAll := func() *backend.BlockMeta {
+		rw.pollBlocklist()
+		err = rw.compact(rw.blocklist.Metas(testTenantID), testTenantID)
+		require.NoError(t, err)
+		rw.pollBlocklist()
+
+		blocks := rw.blocklist.Metas(testTenantID)
+		require.Len(t, blocks, 1)
+		return blocks[0]
+	}
+
+	cutTestBlocks(t, w, testTenantID, 2, 10)
+
+	// level 1 uses the block config
+	meta := compactAll()
+	require.Equal(t, uint8(1), meta.CompactionLevel)
+	require.Equal(t, backend.EncNone, meta.Encoding)
+
+	// level 2 uses the override
+	cutTestBlocks(t, w, testTenantID, 1, 10)
+	meta = compactAll()
+	require.Equal(t, uint8(2), meta.CompactionLevel)
+	require.Equal(t, backend.EncZstd, meta.Encoding)
+}
+
+type recordingSpanExporter struct {
+	mtx   sync.Mutex
+	spans []tracesdk.ReadOnlySpan
+}
+
+func (e *recordingSpanExporter) ExportSpans(_ context.Context, spans []tracesdk.ReadOnlySpan) error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingSpanExporter) Shutdown(context.Context) error { return nil }
+
+func TestCompactionJobTracing(t *testing.T) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			BloomShardSizeBytes:  100_000,
+			Version:              v2.VersionString,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	exporter := &recordingSpanExporter{}
+	tp := tracesdk.NewTracerProvider(tracesdk.WithSyncer(exporter))
+	tracer, _ := ot_bridge.NewTracerPair(tp.Tracer("test"))
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      24 * time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: 0,
+		Tracer:                  tracer,
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+	rw := r.(*readerWriter)
+
+	cutTestBlocks(t, w, testTenantID, 2, 10)
+	rw.pollBlocklist()
+
+	selectStart := time.Now()
+	err = rw.compactSelected(rw.blocklist.Metas(testTenantID), testTenantID, selectStart)
+	require.NoError(t, err)
+
+	spans := map[string]int{}
+	for _, s := range exporter.spans {
+		spans[s.Name()]++
+		require.Equal(t, exporter.spans[0].SpanContext().TraceID(), s.SpanContext().TraceID())
+	}
+	// no flush size, every object is flushed separately
+	require.Equal(t, map[string]int{
+		"rw.compact":               1,
+		"rw.compact.selectBlocks":  1,
+		"v2.compactor.download":    2,
+		"v2.compactor.merge":       21,
+		"v2.compactor.appendBlock": 20,
+		"v2.compactor.finishBlock": 1,
+	}, spans)
+
+	for _, s := range exporter.spans {
+		if s.Name() == "rw.compact" {
+			require.Equal(t, selectStart.UnixNano(), s.StartTime().UnixNano())
+		}
+	}
+}
+
 func TestCompactionMetrics(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -566,7 +733,7 @@ func TestCompactionIteratesThroughTenants(t *testing.T) {
 
 func TestCompactionHonorsBlockStartEndTimes(t *testing.T) {
 
-	testEncodings := []string{v2.VersionString, vparquet.VersionString}
+	testEncodings := []string{v2.VersionString, vparquet.VersionString, vparquet2.VersionString}
 	for _, enc := range testEncodings {
 		t.Run(enc, func(t *testing.T) {
 			testCompactionHonorsBlockStartEndTimes(t, enc)
@@ -695,7 +862,7 @@ func makeTraceID(i int, j int) []byte {
 }
 
 func BenchmarkCompaction(b *testing.B) {
-	testEncodings := []string{v2.VersionString, vparquet.VersionString}
+	testEncodings := []string{v2.VersionString, vparquet.VersionString, vparquet2.VersionString}
 	for _, enc := range testEncodings {
 		b.Run(enc, func(b *testing.B) {
 			benchmarkCompaction(b, enc)
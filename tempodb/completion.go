@@ -0,0 +1,175 @@
+package tempodb
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+var (
+	metricCompletionQueueLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "completion_queue_length",
+		Help:      "Number of wal blocks waiting to be completed by the completion pool.",
+	})
+	metricCompletionsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "completion_in_flight",
+		Help:      "Number of wal blocks being completed by the completion pool.",
+	})
+	metricCompletionReservedBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "completion_reserved_bytes",
+		Help:      "Bytes of the memory budget of the completion pool reserved by the blocks being completed.",
+	})
+)
+
+// CompletionConfig configures the pool completing wal blocks concurrently
+type CompletionConfig struct {
+	// Workers is the max number of blocks completed at once. 0 disables the pool, blocks are completed by the
+	// caller.
+	Workers int `yaml:"workers"`
+	// MemoryBudgetBytes limits the summed wal data size of the blocks completed at once. A block larger than the
+	// budget is completed alone. 0 is unlimited.
+	MemoryBudgetBytes uint64 `yaml:"memory_budget_bytes"`
+}
+
+type completionJob struct {
+	size uint64
+	fn   func()
+}
+
+// completionPool runs jobs with a fixed number of workers in the order they are submitted. The next job is only
+// started once the running jobs leave room for its size in the memory budget.
+type completionPool struct {
+	budget uint64
+
+	mtx      sync.Mutex
+	cond     *sync.Cond
+	queue    []completionJob
+	reserved uint64
+	running  int
+	stopped  bool
+	workers  sync.WaitGroup
+}
+
+func newCompletionPool(cfg CompletionConfig) *completionPool {
+	if cfg.Workers <= 0 {
+		return nil
+	}
+
+	p := &completionPool{
+		budget: cfg.MemoryBudgetBytes,
+	}
+	p.cond = sync.NewCond(&p.mtx)
+
+	p.workers.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go p.worker()
+	}
+
+	return p
+}
+
+// submit queues the job. Returns false if the pool is stopped.
+func (p *completionPool) submit(size uint64, fn func()) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.stopped {
+		return false
+	}
+
+	p.queue = append(p.queue, completionJob{size: size, fn: fn})
+	metricCompletionQueueLength.Inc()
+	p.cond.Signal()
+	return true
+}
+
+// admissible returns true if the job at the head of the queue fits the memory budget. Must be called with the
+// lock held.
+func (p *completionPool) admissible() bool {
+	if len(p.queue) == 0 {
+		return false
+	}
+	return p.budget == 0 || p.running == 0 || p.reserved+p.queue[0].size <= p.budget
+}
+
+// worker runs the queued jobs until the pool is stopped and the queue is empty
+func (p *completionPool) worker() {
+	defer p.workers.Done()
+
+	for {
+		p.mtx.Lock()
+		for !p.admissible() && !(p.stopped && len(p.queue) == 0) {
+			p.cond.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mtx.Unlock()
+			return
+		}
+
+		job := p.queue[0]
+		p.queue = p.queue[1:]
+		p.reserved += job.size
+		p.running++
+		metricCompletionQueueLength.Dec()
+		metricCompletionsInFlight.Inc()
+		metricCompletionReservedBytes.Add(float64(job.size))
+		p.mtx.Unlock()
+
+		job.fn()
+
+		p.mtx.Lock()
+		p.reserved -= job.size
+		p.running--
+		metricCompletionsInFlight.Dec()
+		metricCompletionReservedBytes.Sub(float64(job.size))
+		p.mtx.Unlock()
+
+		// freed memory may admit the next job for any waiting worker, and drain waits for the jobs to be done
+		p.cond.Broadcast()
+	}
+}
+
+// drain waits until the submitted jobs are done, including the queued ones
+func (p *completionPool) drain() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for len(p.queue) > 0 || p.running > 0 {
+		p.cond.Wait()
+	}
+}
+
+// stop rejects new jobs and waits until the workers have run the queued jobs
+func (p *completionPool) stop() {
+	p.mtx.Lock()
+	p.stopped = true
+	p.cond.Broadcast()
+	p.mtx.Unlock()
+
+	p.workers.Wait()
+}
+
+// SubmitCompletion queues fn on the completion pool. The memory fn needs is estimated by the wal data size of the
+// block. Returns false if the pool is disabled or stopped, the caller completes the block itself.
+func (rw *readerWriter) SubmitCompletion(block *wal.AppendBlock, fn func()) bool {
+	if rw.completions == nil {
+		return false
+	}
+
+	return rw.completions.submit(block.DataLength(), fn)
+}
+
+// DrainCompletions waits until the completions submitted to the completion pool are done, including the queued ones
+func (rw *readerWriter) DrainCompletions() {
+	if rw.completions == nil {
+		return
+	}
+
+	rw.completions.drain()
+}
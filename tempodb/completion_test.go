@@ -0,0 +1,154 @@
+package tempodb
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletionPoolDisabled(t *testing.T) {
+	assert.Nil(t, newCompletionPool(CompletionConfig{}))
+
+	rw := &readerWriter{}
+	assert.False(t, rw.SubmitCompletion(nil, func() {}))
+}
+
+func TestCompletionPool(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         CompletionConfig
+		sizes       []uint64
+		maxRunning  int
+		maxReserved uint64
+	}{
+		{
+			name:       "workers",
+			cfg:        CompletionConfig{Workers: 2},
+			sizes:      []uint64{10, 10, 10, 10, 10},
+			maxRunning: 2,
+		},
+		{
+			name:        "memory budget",
+			cfg:         CompletionConfig{Workers: 4, MemoryBudgetBytes: 25},
+			sizes:       []uint64{10, 10, 10, 10, 10},
+			maxRunning:  2,
+			maxReserved: 20,
+		},
+		{
+			name:        "block larger than budget runs alone",
+			cfg:         CompletionConfig{Workers: 4, MemoryBudgetBytes: 25},
+			sizes:       []uint64{10, 100, 10},
+			maxRunning:  1,
+			maxReserved: 100,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newCompletionPool(tc.cfg)
+			defer p.stop()
+
+			var (
+				mtx         sync.Mutex
+				running     int
+				reserved    uint64
+				maxRunning  int
+				maxReserved uint64
+				wg          sync.WaitGroup
+			)
+
+			for _, size := range tc.sizes {
+				size := size
+				wg.Add(1)
+				p.submit(size, func() {
+					defer wg.Done()
+
+					mtx.Lock()
+					running++
+					reserved += size
+					if running > maxRunning {
+						maxRunning = running
+					}
+					if reserved > maxReserved {
+						maxReserved = reserved
+					}
+					mtx.Unlock()
+
+					time.Sleep(20 * time.Millisecond)
+
+					mtx.Lock()
+					running--
+					reserved -= size
+					mtx.Unlock()
+				})
+			}
+
+			wg.Wait()
+			require.LessOrEqual(t, maxRunning, tc.maxRunning)
+			if tc.maxReserved > 0 {
+				require.LessOrEqual(t, maxReserved, tc.maxReserved)
+			}
+		})
+	}
+}
+
+func TestCompletionPoolStop(t *testing.T) {
+	p := newCompletionPool(CompletionConfig{Workers: 1})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.True(t, p.submit(1, func() {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	ran := false
+	require.True(t, p.submit(1, func() { ran = true }))
+
+	stopped := make(chan struct{})
+	go func() {
+		p.stop()
+		close(stopped)
+	}()
+
+	// stop waits for the running job
+	select {
+	case <-stopped:
+		t.Fatal("stopped before the running job was done")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+
+	// the queued job ran and new jobs are rejected
+	assert.True(t, ran)
+	assert.False(t, p.submit(1, func() {}))
+}
+
+func TestCompletionPoolDrain(t *testing.T) {
+	p := newCompletionPool(CompletionConfig{Workers: 2, MemoryBudgetBytes: 10})
+	defer p.stop()
+
+	var mtx sync.Mutex
+	done := 0
+	for i := 0; i < 5; i++ {
+		require.True(t, p.submit(10, func() {
+			time.Sleep(5 * time.Millisecond)
+			mtx.Lock()
+			done++
+			mtx.Unlock()
+		}))
+	}
+
+	p.drain()
+	mtx.Lock()
+	defer mtx.Unlock()
+	assert.Equal(t, 5, done)
+
+	// the pool still runs jobs after draining
+	require.True(t, p.submit(1, func() {}))
+}
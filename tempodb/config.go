@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
+
 	"github.com/grafana/tempo/pkg/cache"
+	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/azure"
 	"github.com/grafana/tempo/tempodb/backend/cache/memcached"
 	"github.com/grafana/tempo/tempodb/backend/cache/redis"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"github.com/grafana/tempo/tempodb/backend/s3"
+	"github.com/grafana/tempo/tempodb/blocklist"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	"github.com/grafana/tempo/tempodb/pool"
@@ -25,10 +29,16 @@ const (
 	DefaultRetentionConcurrency     = uint(10)
 	DefaultTenantIndexBuilders      = 2
 
+	// DefaultNotificationsBlocklistPoll is the poll period of the blocklist if it's updated from bucket notifications
+	DefaultNotificationsBlocklistPoll = 30 * time.Minute
+
 	DefaultPrefetchTraceCount   = 1000
 	DefaultSearchChunkSizeBytes = 1_000_000
 	DefaultReadBufferCount      = 8
 	DefaultReadBufferSize       = 4 * 1024 * 1024
+
+	DefaultBloomPinningMaxBlockAge = 24 * time.Hour
+	DefaultBloomPinningMaxBytes    = 256 * 1024 * 1024
 )
 
 // Config holds the entirety of tempodb configuration
@@ -39,6 +49,9 @@ type Config struct {
 	Block  *common.BlockConfig `yaml:"block"`
 	Search *SearchConfig       `yaml:"search"`
 
+	// Completion completes wal blocks concurrently with a shared memory budget
+	Completion CompletionConfig `yaml:"completion"`
+
 	BlocklistPoll                    time.Duration `yaml:"blocklist_poll"`
 	BlocklistPollConcurrency         uint          `yaml:"blocklist_poll_concurrency"`
 	BlocklistPollFallback            bool          `yaml:"blocklist_poll_fallback"`
@@ -46,6 +59,9 @@ type Config struct {
 	BlocklistPollStaleTenantIndex    time.Duration `yaml:"blocklist_poll_stale_tenant_index"`
 	BlocklistPollJitterMs            int           `yaml:"blocklist_poll_jitter_ms"`
 
+	// BlocklistNotifications updates the blocklist from bucket notifications in between polls
+	BlocklistNotifications blocklist.NotificationsConfig `yaml:"blocklist_notifications"`
+
 	// backends
 	Backend string        `yaml:"backend"`
 	Local   *local.Config `yaml:"local"`
@@ -60,6 +76,8 @@ type Config struct {
 	BackgroundCache         *cache.BackgroundConfig `yaml:"background_cache"`
 	Memcached               *memcached.Config       `yaml:"memcached"`
 	Redis                   *redis.Config           `yaml:"redis"`
+
+	BloomPinning BloomPinningConfig `yaml:"bloom_pinning"`
 }
 
 type SearchConfig struct {
@@ -77,6 +95,18 @@ type SearchConfig struct {
 	} `yaml:"cache_control"`
 }
 
+// BloomPinningConfig configures the bloom filters of the configured tenants kept in memory. The blooms are
+// fetched when the blocklist is polled, so trace by id lookups don't have to fetch them from the cache or the
+// backend.
+type BloomPinningConfig struct {
+	// Tenants whose blooms are pinned, "*" pins the blooms of all tenants
+	Tenants []string `yaml:"tenants"`
+	// MaxBlockAge limits the pinned blooms to blocks that ended within the duration. 0 pins the blooms of all blocks.
+	MaxBlockAge time.Duration `yaml:"max_block_age"`
+	// MaxBytes is the memory budget of all pinned blooms. The blooms of the most recent blocks are pinned first.
+	MaxBytes uint64 `yaml:"max_bytes"`
+}
+
 func (c SearchConfig) ApplyToOptions(o *common.SearchOptions) {
 	o.ChunkSizeBytes = c.ChunkSizeBytes
 	o.PrefetchTraceCount = c.PrefetchTraceCount
@@ -114,6 +144,86 @@ type CompactorConfig struct {
 	IteratorBufferSize      int           `yaml:"iterator_buffer_size"`
 	MaxTimePerTenant        time.Duration `yaml:"max_time_per_tenant"`
 	CompactionCycle         time.Duration `yaml:"compaction_cycle"`
+	RebloomCycle            time.Duration `yaml:"rebloom_cycle"`
+
+	// SalvageCycle is the time between salvage cycles. Each cycle rewrites the blocks flagged corrupt.
+	SalvageCycle time.Duration `yaml:"salvage_cycle"`
+	// SalvageMaxLostRatio is the max ratio of pages of a block that may be lost for it to be retired.
+	SalvageMaxLostRatio float64 `yaml:"salvage_max_lost_ratio"`
+
+	// TombstoneCycle is the time between tombstone cycles. Each cycle rewrites the blocks with tombstones.
+	TombstoneCycle time.Duration `yaml:"tombstone_cycle"`
+
+	// Levels overrides the block settings of compacted blocks by compaction level
+	Levels []CompactionLevelConfig `yaml:"levels"`
+
+	// Tracer records a trace of each compaction job. Set by the compactor, the global tracer is used if nil.
+	Tracer opentracing.Tracer `yaml:"-"`
+}
+
+// CompactionLevelConfig overrides the block settings of blocks compacted into the given level or any higher
+// level. Unset fields keep the value of the block config.
+type CompactionLevelConfig struct {
+	Level                    uint8             `yaml:"level"`
+	Encoding                 *backend.Encoding `yaml:"encoding"`
+	ParquetCompression       string            `yaml:"parquet_compression"`
+	ParquetDisableDictionary *bool             `yaml:"parquet_disable_dictionary"`
+	ParquetPageSizeBytes     int               `yaml:"parquet_page_size_bytes"`
+}
+
+// Validate returns an error if the per level block settings are invalid for the given block config
+func (cfg *CompactorConfig) Validate(block *common.BlockConfig) error {
+	levels := map[uint8]struct{}{}
+	for _, l := range cfg.Levels {
+		if _, ok := levels[l.Level]; ok {
+			return fmt.Errorf("compaction level %d configured more than once", l.Level)
+		}
+		levels[l.Level] = struct{}{}
+
+		b := cfg.BlockConfigForLevel(*block, l.Level)
+		if err := common.ValidateConfig(&b); err != nil {
+			return fmt.Errorf("compaction level %d: %w", l.Level, err)
+		}
+	}
+	return nil
+}
+
+// BlockConfigForLevel returns the block config for blocks of the given compaction level. The overrides of the
+// highest configured level that is less than or equal to the given level are applied.
+func (cfg *CompactorConfig) BlockConfigForLevel(block common.BlockConfig, level uint8) common.BlockConfig {
+	var match *CompactionLevelConfig
+	for i := range cfg.Levels {
+		l := &cfg.Levels[i]
+		if l.Level <= level && (match == nil || l.Level > match.Level) {
+			match = l
+		}
+	}
+	if match == nil {
+		return block
+	}
+
+	if match.Encoding != nil {
+		block.Encoding = *match.Encoding
+	}
+	if match.ParquetCompression != "" {
+		block.ParquetCompression = match.ParquetCompression
+	}
+	if match.ParquetDisableDictionary != nil {
+		block.ParquetDisableDictionary = *match.ParquetDisableDictionary
+	}
+	if match.ParquetPageSizeBytes != 0 {
+		block.ParquetPageSizeBytes = match.ParquetPageSizeBytes
+	}
+	return block
+}
+
+// BlocklistPollInterval is the period the blocklist is polled at. The blocklist is polled less often if it's
+// updated from bucket notifications in between.
+func (cfg *Config) BlocklistPollInterval() time.Duration {
+	if cfg.BlocklistNotifications.Enabled && cfg.BlocklistNotifications.BlocklistPoll > 0 {
+		return cfg.BlocklistNotifications.BlocklistPoll
+	}
+	return cfg.BlocklistPoll
 }
 
 func validateConfig(cfg *Config) error {
@@ -135,5 +245,13 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("block version validation failed: %w", err)
 	}
 
+	if cfg.BlocklistNotifications.Enabled && cfg.BlocklistNotifications.Token.String() == "" {
+		return errors.New("blocklist notifications require a token")
+	}
+
+	if len(cfg.BloomPinning.Tenants) > 0 && cfg.BloomPinning.MaxBytes == 0 {
+		return errors.New("bloom pinning max bytes must be greater than 0")
+	}
+
 	return nil
 }
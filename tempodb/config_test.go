@@ -3,6 +3,7 @@ package tempodb
 import (
 	"testing"
 
+	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	"github.com/stretchr/testify/require"
 )
@@ -38,3 +39,54 @@ func TestApplyToOptions(t *testing.T) {
 	require.Equal(t, cfg.ReadBufferCount, 6)
 	require.Equal(t, cfg.ReadBufferSizeBytes, 7)
 }
+
+func TestCompactorConfigBlockConfigForLevel(t *testing.T) {
+	zstd := backend.EncZstd
+	disabled := true
+	cfg := CompactorConfig{
+		Levels: []CompactionLevelConfig{
+			{Level: 3, Encoding: &zstd, ParquetCompression: "zstd", ParquetDisableDictionary: &disabled},
+			{Level: 1, ParquetCompression: "snappy", ParquetPageSizeBytes: 1024},
+		},
+	}
+	block := common.BlockConfig{
+		Encoding:           backend.EncLZ4_64k,
+		ParquetCompression: "none",
+	}
+
+	// no override below the lowest level
+	require.Equal(t, block, cfg.BlockConfigForLevel(block, 0))
+
+	actual := cfg.BlockConfigForLevel(block, 1)
+	require.Equal(t, backend.EncLZ4_64k, actual.Encoding)
+	require.Equal(t, "snappy", actual.ParquetCompression)
+	require.Equal(t, 1024, actual.ParquetPageSizeBytes)
+	require.False(t, actual.ParquetDisableDictionary)
+
+	require.Equal(t, actual, cfg.BlockConfigForLevel(block, 2))
+
+	// only the highest matching level applies
+	actual = cfg.BlockConfigForLevel(block, 5)
+	require.Equal(t, backend.EncZstd, actual.Encoding)
+	require.Equal(t, "zstd", actual.ParquetCompression)
+	require.Equal(t, 0, actual.ParquetPageSizeBytes)
+	require.True(t, actual.ParquetDisableDictionary)
+}
+
+func TestCompactorConfigValidate(t *testing.T) {
+	block := &common.BlockConfig{
+		IndexDownsampleBytes: 1,
+		IndexPageSizeBytes:   1,
+		BloomFP:              0.01,
+		BloomShardSizeBytes:  1,
+	}
+
+	cfg := CompactorConfig{Levels: []CompactionLevelConfig{{Level: 1, ParquetCompression: "zstd"}}}
+	require.NoError(t, cfg.Validate(block))
+
+	cfg = CompactorConfig{Levels: []CompactionLevelConfig{{Level: 1, ParquetCompression: "foo"}}}
+	require.EqualError(t, cfg.Validate(block), "compaction level 1: unsupported parquet compression foo, supported values are [none snappy gzip zstd lz4 brotli]")
+
+	cfg = CompactorConfig{Levels: []CompactionLevelConfig{{Level: 1}, {Level: 1}}}
+	require.EqualError(t, cfg.Validate(block), "compaction level 1 configured more than once")
+}
@@ -0,0 +1,63 @@
+package tempodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/tempo/pkg/ddsketch"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// MergeDurationSketches merges the span duration sketches of a service, or of all services if service is empty, of
+// the blocks within start and end. The sketch of a block covers all its spans, so only blocks completely within the
+// range are merged. The blocks overlapping the range that are only partially within it or have no sketches are
+// returned, their spans have to be read to answer a query exactly.
+func MergeDurationSketches(ctx context.Context, r backend.Reader, metas []*backend.BlockMeta, service string, start, end time.Time) (*ddsketch.Sketch, []*backend.BlockMeta, error) {
+	merged := ddsketch.New()
+	var remaining []*backend.BlockMeta
+
+	for _, m := range metas {
+		if !m.StartTime.Before(end) || !m.EndTime.After(start) {
+			continue
+		}
+		if !m.DurationSketches || m.StartTime.Before(start) || m.EndTime.After(end) {
+			remaining = append(remaining, m)
+			continue
+		}
+
+		sketches, err := readDurationSketches(ctx, r, m)
+		if errors.Is(err, common.ErrUnsupported) {
+			remaining = append(remaining, m)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if service != "" {
+			merged.Merge(sketches[service])
+			continue
+		}
+		for _, s := range sketches {
+			merged.Merge(s)
+		}
+	}
+
+	return merged, remaining, nil
+}
+
+func readDurationSketches(ctx context.Context, r backend.Reader, meta *backend.BlockMeta) (map[string]*ddsketch.Sketch, error) {
+	block, err := encoding.OpenBlock(meta, r)
+	if err != nil {
+		return nil, fmt.Errorf("error opening block %s: %w", meta.BlockID, err)
+	}
+	sketched, ok := block.(common.DurationSketched)
+	if !ok {
+		return nil, common.ErrUnsupported
+	}
+	return sketched.DurationSketches(ctx)
+}
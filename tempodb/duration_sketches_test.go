@@ -0,0 +1,79 @@
+package tempodb
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/ddsketch"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
+)
+
+func TestMergeDurationSketches(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	ctx := context.Background()
+
+	rawR, rawW, _, err := local.New(&local.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+
+	sketch := func(values ...float64) *ddsketch.Sketch {
+		s := ddsketch.New()
+		for _, v := range values {
+			s.Add(v)
+		}
+		return s
+	}
+	meta := func(start, end time.Duration, sketches map[string]*ddsketch.Sketch) *backend.BlockMeta {
+		m := backend.NewBlockMeta("test", uuid.New(), vparquet2.VersionString, backend.EncNone, "")
+		m.StartTime = now.Add(start)
+		m.EndTime = now.Add(end)
+		if sketches != nil {
+			buf, err := json.Marshal(sketches)
+			require.NoError(t, err)
+			require.NoError(t, w.Write(ctx, vparquet2.DurationSketchesFileName, m.BlockID, m.TenantID, buf, false))
+			m.DurationSketches = true
+		}
+		return m
+	}
+
+	inRange := meta(time.Minute, 2*time.Minute, map[string]*ddsketch.Sketch{
+		"a": sketch(1000, 2000),
+		"b": sketch(5000),
+	})
+	inRange2 := meta(3*time.Minute, 4*time.Minute, map[string]*ddsketch.Sketch{
+		"a": sketch(3000),
+	})
+	withoutSketches := meta(time.Minute, 2*time.Minute, nil)
+	partial := meta(-time.Minute, time.Minute, map[string]*ddsketch.Sketch{
+		"a": sketch(4000),
+	})
+	outside := meta(time.Hour, 2*time.Hour, map[string]*ddsketch.Sketch{
+		"a": sketch(6000),
+	})
+	metas := []*backend.BlockMeta{inRange, inRange2, withoutSketches, partial, outside}
+
+	s, remaining, err := MergeDurationSketches(ctx, r, metas, "a", now, now.Add(10*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), s.Count)
+	require.Equal(t, 1000.0, s.Min)
+	require.Equal(t, 3000.0, s.Max)
+	require.Equal(t, []*backend.BlockMeta{withoutSketches, partial}, remaining)
+
+	s, _, err = MergeDurationSketches(ctx, r, metas, "", now, now.Add(10*time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), s.Count)
+	require.Equal(t, 5000.0, s.Max)
+
+	s, remaining, err = MergeDurationSketches(ctx, r, metas, "c", now, now.Add(10*time.Minute))
+	require.NoError(t, err)
+	require.Zero(t, s.Count)
+	require.Len(t, remaining, 2)
+}
@@ -12,11 +12,11 @@ const (
 	NameObjects = "data"
 	// NameIndex names the backend index object
 	NameIndex = "index"
-	// nameBloomPrefix is the prefix used to build the bloom shards
-	nameBloomPrefix = "bloom-"
+	// NameBloomPrefix is the prefix used to build the bloom shards
+	NameBloomPrefix = "bloom-"
 )
 
 // bloomName returns the backend bloom name for the given shard
 func BloomName(shard int) string {
-	return nameBloomPrefix + strconv.Itoa(shard)
+	return NameBloomPrefix + strconv.Itoa(shard)
 }
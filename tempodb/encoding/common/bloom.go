@@ -51,6 +51,56 @@ func NewBloom(fp float64, shardSize, estimatedObjects uint) *ShardedBloomFilter
 	return b
 }
 
+// NewBloomWithShardCount creates a ShardedBloomFilter with a fixed number of shards. The size of the shards
+// is chosen to reach the requested false positive rate. This is used to rebuild the bloom of an existing
+// block whose shard count is recorded in its meta.
+func NewBloomWithShardCount(fp float64, shardCount, estimatedObjects uint) *ShardedBloomFilter {
+	if shardCount < minShardCount {
+		shardCount = minShardCount
+	}
+
+	m, k := bloom.EstimateParameters(estimatedObjects, fp)
+	shardBits := uint(math.Ceil(float64(m) / float64(shardCount)))
+
+	b := &ShardedBloomFilter{
+		blooms: make([]*bloom.BloomFilter, shardCount),
+	}
+
+	for i := 0; i < int(shardCount); i++ {
+		b.blooms[i] = bloom.New(shardBits, k)
+	}
+
+	return b
+}
+
+// NewAdaptiveBloom creates a ShardedBloomFilter whose shard count and size are chosen for the estimated number of
+// objects, e.g. the total objects of the input blocks or the length of the wal block. Small blocks get a single shard
+// no larger than needed, so finding a trace fetches fewer bytes. Blocks needing more than the max number of shards of
+// shardSize bytes get larger shards instead, so the false positive rate is kept.
+func NewAdaptiveBloom(fp float64, shardSize, estimatedObjects uint) *ShardedBloomFilter {
+	if estimatedObjects == 0 {
+		estimatedObjects = 1
+	}
+	return NewBloomWithShardCount(fp, AdaptiveShardCount(fp, shardSize, estimatedObjects), estimatedObjects)
+}
+
+// AdaptiveShardCount returns the shard count of an adaptive filter of the given number of objects
+func AdaptiveShardCount(fp float64, shardSize, objects uint) uint {
+	if objects == 0 {
+		objects = 1
+	}
+	m, _ := bloom.EstimateParameters(objects, fp)
+	shardCount := uint(math.Ceil(float64(m) / (float64(shardSize) * 8.0)))
+
+	if shardCount < minShardCount {
+		shardCount = minShardCount
+	}
+	if shardCount > maxShardCount {
+		shardCount = maxShardCount
+	}
+	return shardCount
+}
+
 func (b *ShardedBloomFilter) Add(traceID []byte) {
 	shardKey := ShardKeyForTraceID(traceID, len(b.blooms))
 	b.blooms[shardKey].Add(traceID)
@@ -70,6 +120,7 @@ func (b *ShardedBloomFilter) Marshal() ([][]byte, error) {
 	return bloomBytes, nil
 }
 
+// GetShardCount returns the shard count of the filter
 func (b *ShardedBloomFilter) GetShardCount() int {
 	return len(b.blooms)
 }
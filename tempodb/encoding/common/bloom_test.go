@@ -152,3 +152,130 @@ func TestBloomShardCount(t *testing.T) {
 	}
 
 }
+
+func TestBloomWithShardCount(t *testing.T) {
+	tests := []struct {
+		name             string
+		bloomFP          float64
+		shardCount       uint
+		estimatedObjects uint
+		expectedShards   int
+	}{
+		{
+			name:             "regular",
+			bloomFP:          0.01,
+			shardCount:       5,
+			estimatedObjects: 10000,
+			expectedShards:   5,
+		},
+		{
+			name:             "lower fp",
+			bloomFP:          0.001,
+			shardCount:       5,
+			estimatedObjects: 10000,
+			expectedShards:   5,
+		},
+		{
+			name:             "no shards",
+			bloomFP:          0.01,
+			shardCount:       0,
+			estimatedObjects: 10000,
+			expectedShards:   minShardCount,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable, needed for running test cases in parallel
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewBloomWithShardCount(tt.bloomFP, tt.shardCount, tt.estimatedObjects)
+			assert.Equal(t, tt.expectedShards, b.GetShardCount())
+
+			bloomBytes, err := b.Marshal()
+			assert.NoError(t, err)
+
+			for _, singleBloom := range bloomBytes {
+				filter := &willf_bloom.BloomFilter{}
+				_, err = filter.ReadFrom(bytes.NewReader(singleBloom))
+				assert.NoError(t, err)
+				assert.LessOrEqual(t, filter.EstimateFalsePositiveRate(tt.estimatedObjects/uint(b.GetShardCount())), tt.bloomFP*1.5)
+			}
+		})
+	}
+}
+
+func TestAdaptiveBloom(t *testing.T) {
+	tests := []struct {
+		name           string
+		bloomFP        float64
+		shardSize      uint
+		objects        int
+		expectedShards int
+	}{
+		{
+			name:           "small block",
+			bloomFP:        0.01,
+			shardSize:      100 * 1024,
+			objects:        100,
+			expectedShards: 1,
+		},
+		{
+			name:           "regular",
+			bloomFP:        0.01,
+			shardSize:      1024,
+			objects:        10000,
+			expectedShards: 12,
+		},
+		{
+			name:           "too many shards",
+			bloomFP:        0.01,
+			shardSize:      1,
+			objects:        100000,
+			expectedShards: maxShardCount,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // capture range variable, needed for running test cases in parallel
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewAdaptiveBloom(tt.bloomFP, tt.shardSize, uint(tt.objects))
+			traceIDs := make([][]byte, 0, tt.objects)
+			for i := 0; i < tt.objects; i++ {
+				id := make([]byte, 16)
+				_, err := rand.Read(id)
+				assert.NoError(t, err)
+				traceIDs = append(traceIDs, id)
+				b.Add(id)
+			}
+
+			assert.Equal(t, tt.expectedShards, b.GetShardCount())
+			for _, id := range traceIDs {
+				assert.True(t, b.Test(id))
+			}
+
+			bloomBytes, err := b.Marshal()
+			assert.NoError(t, err)
+			assert.Len(t, bloomBytes, tt.expectedShards)
+
+			for _, singleBloom := range bloomBytes {
+				filter := &willf_bloom.BloomFilter{}
+				_, err = filter.ReadFrom(bytes.NewReader(singleBloom))
+				assert.NoError(t, err)
+
+				// shards are no larger than needed, unless the max shard count was reached
+				if tt.expectedShards < maxShardCount {
+					assert.LessOrEqual(t, filter.Cap(), tt.shardSize*8)
+				}
+			}
+
+			// the false positive rate is kept, the estimate is empirical and tiny shards are rounded
+			filter := &willf_bloom.BloomFilter{}
+			_, err = filter.ReadFrom(bytes.NewReader(bloomBytes[0]))
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, filter.EstimateFalsePositiveRate(uint(tt.objects/tt.expectedShards)), tt.bloomFP*1.5)
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 type BlockConfig struct {
 	IndexDownsampleBytes int              `yaml:"index_downsample_bytes"`
 	IndexPageSizeBytes   int              `yaml:"index_page_size_bytes"`
+	IndexVersion         string           `yaml:"index_version"` // index of v2 blocks, empty or v2 writes fixed size records, v3 delta encodes them
 	BloomFP              float64          `yaml:"bloom_filter_false_positive"`
 	BloomShardSizeBytes  int              `yaml:"bloom_filter_shard_size_bytes"`
 	Version              string           `yaml:"version"`
@@ -18,9 +19,28 @@ type BlockConfig struct {
 	SearchPageSizeBytes  int              `yaml:"search_page_size_bytes"`
 
 	// parquet fields
-	RowGroupSizeBytes int `yaml:"row_group_size_bytes"`
+	RowGroupSizeBytes        int    `yaml:"row_group_size_bytes"`
+	ParquetCompression       string `yaml:"parquet_compression"`        // overrides the codec of every column, empty uses the codecs of the schema
+	ParquetDisableDictionary bool   `yaml:"parquet_disable_dictionary"` // disables dictionary encoding of all columns
+	ParquetPageSizeBytes     int    `yaml:"parquet_page_size_bytes"`    // target size of data pages, 0 uses the parquet default
+	EncodeConcurrency        int    `yaml:"encode_concurrency"`         // goroutines encoding traces when a block is created, i.e. completed in the ingester
+	MaxServiceNames          int    `yaml:"max_service_names"`          // service names recorded in the meta of a block so searches for other services skip it, 0 disables
+	ParquetSplitHotColumns   bool   `yaml:"parquet_split_hot_columns"`  // stores the columns read by most searches in a small hot file and the others in a cold file
+	ParquetServiceIndex      bool   `yaml:"parquet_service_index"`      // writes an index of the row ranges of each service so service searches skip row groups (vParquet2)
+	ParquetZoneMaps          bool   `yaml:"parquet_zone_maps"`          // writes the min and max durations and status codes of each row group so range searches skip row groups (vParquet2)
+	ParquetStats             bool   `yaml:"parquet_stats"`              // writes the span counts per service, attribute cardinalities and column sizes of each block (vParquet2)
+	ParquetWriteBudgetBytes  int    `yaml:"parquet_write_budget_bytes"` // approximate memory used to write a block, row groups are spilled to temporary files (vParquet2), 0 buffers row groups in memory
+	ParquetSpillPath         string `yaml:"parquet_spill_path"`         // directory of the temporary files of blocks written with a write budget, empty uses the default temp directory
+	ParquetDurationSketches  bool   `yaml:"parquet_duration_sketches"`  // records sketches of the span durations of each service in the meta of a block (vParquet2)
 }
 
+// IndexVersionV3 is the index version of v2 blocks with a delta encoded index of variable size pages. Blocks
+// without an index version have an index of fixed size records.
+const IndexVersionV3 = "v3"
+
+// ParquetCompressionCodecs are the supported values of BlockConfig.ParquetCompression
+var ParquetCompressionCodecs = []string{"none", "snappy", "gzip", "zstd", "lz4", "brotli"}
+
 // ValidateConfig returns true if the config is valid
 func ValidateConfig(b *BlockConfig) error {
 	if b.IndexDownsampleBytes <= 0 {
@@ -31,6 +51,10 @@ func ValidateConfig(b *BlockConfig) error {
 		return fmt.Errorf("positive index page size required")
 	}
 
+	if b.IndexVersion != "" && b.IndexVersion != "v2" && b.IndexVersion != IndexVersionV3 {
+		return fmt.Errorf("unsupported index version %s, supported values are v2 and %s", b.IndexVersion, IndexVersionV3)
+	}
+
 	if b.BloomFP <= 0.0 {
 		return fmt.Errorf("invalid bloom filter fp rate %v", b.BloomFP)
 	}
@@ -39,5 +63,34 @@ func ValidateConfig(b *BlockConfig) error {
 		return fmt.Errorf("positive value required for bloom-filter shard size")
 	}
 
+	if b.ParquetCompression != "" && !isParquetCompressionCodec(b.ParquetCompression) {
+		return fmt.Errorf("unsupported parquet compression %s, supported values are %v", b.ParquetCompression, ParquetCompressionCodecs)
+	}
+
+	if b.EncodeConcurrency < 0 {
+		return fmt.Errorf("encode concurrency must not be negative")
+	}
+
+	if b.ParquetPageSizeBytes < 0 {
+		return fmt.Errorf("parquet page size must not be negative")
+	}
+
+	if b.ParquetWriteBudgetBytes < 0 {
+		return fmt.Errorf("parquet write budget must not be negative")
+	}
+
+	if b.ParquetWriteBudgetBytes > 0 && b.ParquetSplitHotColumns {
+		return fmt.Errorf("parquet write budget is not supported with split hot columns")
+	}
+
 	return nil
 }
+
+func isParquetCompressionCodec(codec string) bool {
+	for _, c := range ParquetCompressionCodecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,9 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/grafana/tempo/pkg/ddsketch"
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/tempodb/backend"
@@ -22,6 +25,14 @@ type Searcher interface {
 	SearchTagValues(ctx context.Context, tag string, cb TagCallback, opts SearchOptions) error
 }
 
+// TypedTagCallback is called for every occurrence of a tag value with its type, one of the TagValueType constants
+type TypedTagCallback func(typ, value string)
+
+// TypedTagValuesSearcher is implemented by blocks that store attribute values in typed columns
+type TypedTagValuesSearcher interface {
+	SearchTypedTagValues(ctx context.Context, tag string, cb TypedTagCallback, opts SearchOptions) error
+}
+
 type CacheControl struct {
 	Footer      bool
 	ColumnIndex bool
@@ -51,11 +62,29 @@ type CompactionOptions struct {
 	OutputBlocks       uint8
 	BlockConfig        BlockConfig
 	Combiner           model.ObjectCombiner
+	CombineStrategy    CombineStrategy
 
 	ObjectsCombined func(compactionLevel, objects int)
 	ObjectsWritten  func(compactionLevel, objects int)
 	BytesWritten    func(compactionLevel, bytes int)
 	SpansDiscarded  func(spans int)
+	TraceTruncated  func()
+
+	// DropObject is called with the id of every object before it is written. Objects it returns true for are
+	// dropped from the compacted blocks.
+	DropObject func(id ID) bool
+
+	// Tracer records the download, merge, encode and upload spans of the compaction. The global tracer is used if nil.
+	Tracer opentracing.Tracer
+}
+
+// StartSpan starts a span of the compaction with the tracer of the options
+func (o CompactionOptions) StartSpan(ctx context.Context, operationName string, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	tracer := o.Tracer
+	if tracer == nil {
+		tracer = opentracing.GlobalTracer()
+	}
+	return opentracing.StartSpanFromContextWithTracer(ctx, tracer, operationName, opts...)
 }
 
 type Iterator interface {
@@ -65,6 +94,103 @@ type Iterator interface {
 	Close()
 }
 
+// IDIterable is implemented by backend blocks that can enumerate the ids of all objects they contain
+// without reading the objects themselves. The id passed to the callback is only valid for the duration
+// of the call.
+type IDIterable interface {
+	IterateIDs(ctx context.Context, cb func(id ID) error) error
+}
+
+// ObjectIterable is implemented by backend blocks that can iterate over all their objects in id order. The
+// objects are returned in the data encoding returned with the iterator, which may differ from the data encoding
+// of the block meta.
+type ObjectIterable interface {
+	ObjectIterator(ctx context.Context) (Iterator, string, error)
+}
+
+// LostRange is a range of ids that could not be read from a corrupt block. Objects with ids in
+// (After, Through] may have been lost. Records is the number of index records (pages) in the range.
+type LostRange struct {
+	After   ID
+	Through ID
+	Records int
+}
+
+// SalvageIterator iterates over the readable objects of a corrupt block.
+type SalvageIterator interface {
+	Iterator
+
+	// Lost returns the ranges of ids skipped so far.
+	Lost() []LostRange
+}
+
+// Salvageable is implemented by backend blocks that can be read around corrupt regions.
+type Salvageable interface {
+	SalvageIterator() (SalvageIterator, error)
+}
+
+// VerifyProblem is an inconsistency found by verifying a block. Fatal problems are objects that can't be read,
+// the others can be repaired by regenerating the blooms, index or meta of the block.
+type VerifyProblem struct {
+	Fatal bool
+	Msg   string
+}
+
+// Verifiable is implemented by backend blocks that can check the files specific to their encoding against their
+// meta. Errors are returned if the block couldn't be checked, the inconsistencies found are returned as problems.
+type Verifiable interface {
+	Verify(ctx context.Context) ([]VerifyProblem, error)
+}
+
+// Projection names the fields of spans read by ProjectSpans. Attributes are span or resource attributes, including
+// the ones stored in dedicated columns.
+type Projection struct {
+	TraceID    bool
+	StartTime  bool
+	Duration   bool
+	Attributes []string
+}
+
+// ProjectedSpan holds the fields of a span requested by a projection. Attributes neither the span nor its resource
+// have are missing from the map, span attributes shadow resource attributes of the same name.
+type ProjectedSpan struct {
+	TraceID           ID
+	StartTimeUnixNano uint64
+	DurationNanos     uint64
+	Attributes        map[string]string
+}
+
+// Projectable is implemented by backend blocks that can read a subset of the fields of their spans without decoding
+// the spans. Only the columns of the projected fields are read. The span passed to the callback is only valid for the
+// duration of the call.
+type Projectable interface {
+	ProjectSpans(ctx context.Context, p Projection, cb func(*ProjectedSpan) error, opts SearchOptions) error
+}
+
+// BlockStats are statistics of the contents of a block, recorded when the block is written. The cardinalities of
+// attributes are estimates, column sizes are compressed bytes. The span start times are those of the spans, unlike
+// the times of the block meta they are not adjusted for the ingestion slack.
+type BlockStats struct {
+	Spans                uint64            `json:"spans"`
+	MinSpanStartUnixNano uint64            `json:"minSpanStartUnixNano,omitempty"`
+	MaxSpanStartUnixNano uint64            `json:"maxSpanStartUnixNano,omitempty"`
+	ServiceSpans         map[string]uint64 `json:"serviceSpans"`
+	AttributeCardinality map[string]uint64 `json:"attributeCardinality"`
+	ColumnSizes          map[string]uint64 `json:"columnSizes"`
+}
+
+// Measurable is implemented by backend blocks that can record statistics of their contents. ErrUnsupported is
+// returned for blocks written without statistics.
+type Measurable interface {
+	Stats(ctx context.Context) (*BlockStats, error)
+}
+
+// DurationSketched is implemented by backend blocks that can record sketches of the span durations of their
+// services. ErrUnsupported is returned for blocks written without sketches.
+type DurationSketched interface {
+	DurationSketches(ctx context.Context) (map[string]*ddsketch.Sketch, error)
+}
+
 type BackendBlock interface {
 	Finder
 	Searcher
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"math"
+	"strconv"
+)
+
+// The types of tag values
+const (
+	TagValueTypeString = "string"
+	TagValueTypeInt    = "int"
+	TagValueTypeDouble = "double"
+	TagValueTypeBool   = "bool"
+)
+
+// InferTagValueType returns the type of a tag value read from untyped search data, e.g. the flatbuffer search data
+// of live traces and v2 blocks, which stores int, double and bool attributes formatted as strings.
+func InferTagValueType(v string) string {
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return TagValueTypeInt
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return TagValueTypeDouble
+	}
+	if v == "true" || v == "false" {
+		return TagValueTypeBool
+	}
+	return TagValueTypeString
+}
@@ -11,6 +11,19 @@ import (
 // ID in TempoDB
 type ID []byte
 
+// CombineStrategy is how the parts of a trace found in several blocks are combined during compaction
+type CombineStrategy string
+
+const (
+	// CombineStrategyMergeAll merges all parts of the trace regardless of its size
+	CombineStrategyMergeAll CombineStrategy = "merge-all"
+	// CombineStrategyLatestWins keeps the part of the trace in the block with the latest end time
+	CombineStrategyLatestWins CombineStrategy = "latest-wins"
+	// CombineStrategySizeCapped merges all parts of the trace and drops the oldest spans beyond the max
+	// bytes per trace
+	CombineStrategySizeCapped CombineStrategy = "size-capped"
+)
+
 // Record represents the location of an ID in an object file
 type Record struct {
 	ID     ID
@@ -31,6 +44,8 @@ type DataReader interface {
 	//  NextPage takes a reusable buffer to read the page into and returns it in case it needs to resize
 	//  NextPage returns the uncompressed page buffer ready for object iteration and the length of the
 	//    original page from the page header. len(page) might not equal page len!
+	//  If the page fails its checksum the length is returned with the error so the caller can skip the page.
+	//  Uncompressed pages of memory mapped files are returned without copying them and must not be modified.
 	NextPage([]byte) ([]byte, uint32, error)
 }
 
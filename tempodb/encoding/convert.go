@@ -0,0 +1,66 @@
+package encoding
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// ConvertBlock writes the objects of a block to a new block of another version, e.g. so blocks written by a newer
+// release can still be read after rolling back to a release that doesn't support their version. The new block keeps
+// the time range, object count and compaction level of the block and is written with the block config in the
+// passed version. The block itself is left as is, callers mark it compacted once the new block is written.
+func ConvertBlock(ctx context.Context, cfg *common.BlockConfig, meta *backend.BlockMeta, version string, r backend.Reader, w backend.Writer) (*backend.BlockMeta, error) {
+	to, err := FromVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := OpenBlock(meta, r)
+	if err != nil {
+		return nil, fmt.Errorf("error opening block: %w", err)
+	}
+
+	iterable, ok := block.(common.ObjectIterable)
+	if !ok {
+		return nil, fmt.Errorf("block version %s: %w", meta.Version, common.ErrUnsupported)
+	}
+
+	iter, dataEncoding, err := iterable.ObjectIterator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := model.NewObjectDecoder(dataEncoding)
+	if err != nil {
+		iter.Close()
+		return nil, err
+	}
+
+	newMeta := backend.NewBlockMeta(meta.TenantID, uuid.New(), version, cfg.Encoding, dataEncoding)
+	newMeta.StartTime = meta.StartTime
+	newMeta.EndTime = meta.EndTime
+	newMeta.TotalObjects = meta.TotalObjects
+
+	blockCfg := *cfg
+	blockCfg.Version = version
+
+	newMeta, err = to.CreateBlock(ctx, &blockCfg, newMeta, iter, dec, r, w)
+	if err != nil {
+		return nil, err
+	}
+
+	// blocks are created at level 0, the meta is rewritten so the compactor doesn't select the block with new blocks
+	if meta.CompactionLevel != newMeta.CompactionLevel {
+		newMeta.CompactionLevel = meta.CompactionLevel
+		if err := w.WriteBlockMeta(ctx, newMeta); err != nil {
+			return nil, err
+		}
+	}
+
+	return newMeta, nil
+}
@@ -0,0 +1,90 @@
+package encoding
+
+import (
+	"context"
+	"encoding/binary"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
+)
+
+func TestConvertBlock(t *testing.T) {
+	encodings := []VersionedEncoding{v2.Encoding{}, vparquet.Encoding{}, vparquet2.Encoding{}}
+
+	cfg := &common.BlockConfig{
+		IndexDownsampleBytes: 1000,
+		IndexPageSizeBytes:   1000,
+		BloomFP:              0.01,
+		BloomShardSizeBytes:  100_000,
+		Encoding:             backend.EncNone,
+		RowGroupSizeBytes:    1_000_000,
+	}
+
+	for _, from := range encodings {
+		for _, to := range encodings {
+			t.Run(from.Version()+" to "+to.Version(), func(t *testing.T) {
+				ctx := context.Background()
+				rawR, rawW, _, err := local.New(&local.Config{Path: t.TempDir()})
+				require.NoError(t, err)
+				r, w := backend.NewReader(rawR), backend.NewWriter(rawW)
+
+				meta := createVerifyTestBlock(t, from, r, w)
+				meta.CompactionLevel = 2
+
+				converted, err := ConvertBlock(ctx, cfg, meta, to.Version(), r, w)
+				require.NoError(t, err)
+				require.Equal(t, to.Version(), converted.Version)
+				require.NotEqual(t, meta.BlockID, converted.BlockID)
+				require.Equal(t, meta.TotalObjects, converted.TotalObjects)
+				require.Equal(t, meta.CompactionLevel, converted.CompactionLevel)
+
+				// the meta is written and the traces of the block are found in the new block
+				converted, err = r.BlockMeta(ctx, converted.BlockID, converted.TenantID)
+				require.NoError(t, err)
+				require.Equal(t, meta.CompactionLevel, converted.CompactionLevel)
+
+				source, err := OpenBlock(meta, r)
+				require.NoError(t, err)
+				block, err := OpenBlock(converted, r)
+				require.NoError(t, err)
+
+				for i := 0; i < meta.TotalObjects; i++ {
+					id := make([]byte, 16)
+					binary.BigEndian.PutUint64(id[8:], uint64(i))
+
+					expected, err := source.FindTraceByID(ctx, id, common.SearchOptions{})
+					require.NoError(t, err)
+					tr, err := block.FindTraceByID(ctx, id, common.SearchOptions{})
+					require.NoError(t, err)
+					require.NotNil(t, tr)
+					require.Equal(t, spanIDs(expected), spanIDs(tr))
+				}
+			})
+		}
+	}
+
+	_, err := ConvertBlock(context.Background(), cfg, &backend.BlockMeta{Version: v2.VersionString}, "unknown", nil, nil)
+	require.Error(t, err)
+}
+
+func spanIDs(tr *tempopb.Trace) []string {
+	var ids []string
+	for _, b := range tr.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				ids = append(ids, string(s.SpanId))
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,73 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
+)
+
+var (
+	registryMtx sync.RWMutex
+	registry    = map[string]VersionedEncoding{}
+)
+
+func init() {
+	MustRegister(v2.Encoding{})
+	MustRegister(vparquet.Encoding{})
+	MustRegister(vparquet2.Encoding{})
+}
+
+// Register makes a block encoding available by its version, e.g. for the block version of the storage config or
+// of a tenant. Encodings developed outside of this package register themselves in the init function of their
+// package, which is then imported for its side effects by the binary that uses them. Versions are unique.
+func Register(e VersionedEncoding) error {
+	if e == nil {
+		return errors.New("encoding is nil")
+	}
+	v := e.Version()
+	if v == "" {
+		return errors.New("encoding version is empty")
+	}
+
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+
+	if _, ok := registry[v]; ok {
+		return fmt.Errorf("encoding %s is already registered", v)
+	}
+	registry[v] = e
+	return nil
+}
+
+// MustRegister is Register panicking on errors
+func MustRegister(e VersionedEncoding) {
+	if err := Register(e); err != nil {
+		panic(err)
+	}
+}
+
+// Versions returns the versions of all registered encodings in order
+func Versions() []string {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+
+	versions := make([]string, 0, len(registry))
+	for v := range registry {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+func registered(v string) (VersionedEncoding, bool) {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+
+	e, ok := registry[v]
+	return e, ok
+}
@@ -18,6 +18,12 @@ type Appender interface {
 	DataLength() uint64
 }
 
+// BatchAppender is an Appender that can write multiple objects as a single page
+type BatchAppender interface {
+	Appender
+	AppendBatch([]common.ID, [][]byte) error
+}
+
 type appender struct {
 	dataWriter    common.DataWriter
 	records       map[uint64][]common.Record
@@ -28,7 +34,7 @@ type appender struct {
 
 // NewAppender returns an appender.  This appender simply appends new objects
 // to the provided dataWriter.
-func NewAppender(dataWriter common.DataWriter) Appender {
+func NewAppender(dataWriter common.DataWriter) BatchAppender {
 	return &appender{
 		dataWriter: dataWriter,
 		records:    map[uint64][]common.Record{},
@@ -59,6 +65,37 @@ func (a *appender) Append(id common.ID, b []byte) error {
 	return nil
 }
 
+// AppendBatch appends the ids/objects to the writer as a single page. Every distinct id gets a record that references
+// the whole page. Like Append the caller gives up ownership of the byte arrays.
+func (a *appender) AppendBatch(ids []common.ID, objs [][]byte) error {
+	for i := range ids {
+		_, err := a.dataWriter.Write(ids[i], objs[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	bytesWritten, err := a.dataWriter.CutPage()
+	if err != nil {
+		return err
+	}
+
+	added := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := added[string(id)]; ok {
+			continue
+		}
+		added[string(id)] = struct{}{}
+
+		a.hash.Reset()
+		_, _ = a.hash.Write(id)
+		a.addRecord(a.hash.Sum64(), id, bytesWritten)
+	}
+	a.currentOffset += uint64(bytesWritten)
+
+	return nil
+}
+
 func (a *appender) addRecord(hash uint64, id common.ID, bytesWritten int) {
 	new := common.Record{
 		ID:     id,
@@ -1,10 +1,15 @@
 package v2
 
 import (
+	"bytes"
+	"context"
+	"io"
 	"math/rand"
 	"testing"
 
+	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -14,6 +19,58 @@ func (n noopDataWriter) Write(common.ID, []byte) (int, error) { return 10, nil }
 func (n noopDataWriter) CutPage() (int, error)                { return 100, nil }
 func (n noopDataWriter) Complete() error                      { return nil }
 
+// countingDataReader counts the pages read
+type countingDataReader struct {
+	common.DataReader
+	reads int
+}
+
+func (r *countingDataReader) Read(ctx context.Context, records []common.Record, pages [][]byte, buffer []byte) ([][]byte, []byte, error) {
+	r.reads += len(records)
+	return r.DataReader.Read(ctx, records, pages, buffer)
+}
+
+func TestAppendBatch(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	dataWriter, err := NewDataWriter(buffer, backend.EncNone)
+	require.NoError(t, err)
+	appender := NewAppender(dataWriter)
+
+	require.NoError(t, appender.AppendBatch(
+		[]common.ID{{0x03}, {0x01}, {0x03}},
+		[][]byte{{0x01}, {0x02}, {0x03}},
+	))
+	require.NoError(t, appender.Append(common.ID{0x02}, []byte{0x04}))
+
+	// one record per distinct id, the ids of the batch share a page
+	records := appender.Records()
+	require.Len(t, records, 3)
+	assert.Equal(t, records[0].Start, records[2].Start)
+	assert.Equal(t, uint64(buffer.Len()), appender.DataLength())
+
+	dataReader, err := NewDataReader(backend.NewContextReaderWithAllReader(bytes.NewReader(buffer.Bytes())), backend.EncNone)
+	require.NoError(t, err)
+	counting := &countingDataReader{DataReader: dataReader}
+	iter := NewRecordObjectIterator(records, counting, NewObjectReaderWriter())
+	defer iter.Close()
+
+	var ids []common.ID
+	var objs [][]byte
+	for {
+		id, obj, err := iter.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, id)
+		objs = append(objs, append([]byte(nil), obj...))
+	}
+	assert.Equal(t, []common.ID{{0x01}, {0x02}, {0x03}, {0x03}}, ids)
+	assert.Equal(t, [][]byte{{0x02}, {0x04}, {0x01}, {0x03}}, objs)
+	// the page of the batch is read once for both of its records
+	assert.Equal(t, 2, counting.reads)
+}
+
 func BenchmarkAppender100(b *testing.B) {
 	benchmarkAppender(b, 100)
 }
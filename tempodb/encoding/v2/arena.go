@@ -0,0 +1,78 @@
+package v2
+
+// defaultArenaChunkSize is the size of the chunks allocated by an arena. Slices larger than a quarter of the
+// chunk size are allocated on their own so a chunk isn't wasted on a single large object.
+const defaultArenaChunkSize = 1024 * 1024
+
+// arena hands out byte slices from large chunks, replacing many small allocations with a few large ones.
+//
+// A reusing arena keeps its chunks. Slices returned by it stay valid until reset is called, after that the
+// memory is reused and the caller must not access the slices anymore. A non reusing arena forgets full chunks,
+// they are garbage collected once all slices referencing them are gone. Use it if the lifetime of the slices
+// isn't known.
+type arena struct {
+	chunkSize int
+	reuse     bool
+
+	chunks [][]byte // full chunks, reused after reset
+	free   [][]byte // chunks available after reset
+	cur    []byte
+}
+
+func newArena(chunkSize int, reuse bool) *arena {
+	if chunkSize <= 0 {
+		chunkSize = defaultArenaChunkSize
+	}
+	return &arena{
+		chunkSize: chunkSize,
+		reuse:     reuse,
+	}
+}
+
+// copy returns a copy of b allocated in the arena.
+func (a *arena) copy(b []byte) []byte {
+	if len(b) == 0 {
+		// preserve nil vs empty
+		return b[:0:0]
+	}
+	dst := a.alloc(len(b))
+	copy(dst, b)
+	return dst
+}
+
+// alloc returns a slice of length n allocated in the arena. The contents of the slice are undefined.
+func (a *arena) alloc(n int) []byte {
+	if n > a.chunkSize/4 {
+		return make([]byte, n)
+	}
+
+	if cap(a.cur)-len(a.cur) < n {
+		if a.cur != nil && a.reuse {
+			a.chunks = append(a.chunks, a.cur)
+		}
+		if len(a.free) > 0 {
+			a.cur = a.free[len(a.free)-1][:0]
+			a.free = a.free[:len(a.free)-1]
+		} else {
+			a.cur = make([]byte, 0, a.chunkSize)
+		}
+	}
+
+	start := len(a.cur)
+	a.cur = a.cur[:start+n]
+	// cap the slice so appending to it can't overwrite the next allocation
+	return a.cur[start : start+n : start+n]
+}
+
+// reset makes all memory of a reusing arena available again. All slices previously returned are invalidated.
+func (a *arena) reset() {
+	if !a.reuse {
+		return
+	}
+	if a.cur != nil {
+		a.chunks = append(a.chunks, a.cur)
+		a.cur = nil
+	}
+	a.free = append(a.free, a.chunks...)
+	a.chunks = a.chunks[:0]
+}
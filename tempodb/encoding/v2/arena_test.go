@@ -0,0 +1,55 @@
+package v2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArena(t *testing.T) {
+	a := newArena(16, true)
+
+	b1 := a.copy([]byte{0x01, 0x02})
+	b2 := a.copy([]byte{0x03})
+	assert.Equal(t, []byte{0x01, 0x02}, b1)
+	assert.Equal(t, []byte{0x03}, b2)
+	assert.Nil(t, a.copy(nil))
+
+	// appending to a slice must not overwrite the next allocation
+	_ = append(b1, 0xFF)
+	assert.Equal(t, []byte{0x03}, b2)
+
+	// large slices are allocated on their own
+	large := a.alloc(5)
+	assert.Len(t, large, 5)
+	assert.Equal(t, 3, len(a.cur))
+
+	// a new chunk is started when the current one is full
+	for i := 0; i < 4; i++ {
+		a.alloc(4)
+	}
+	assert.Len(t, a.chunks, 1)
+	assert.Equal(t, 4, len(a.cur))
+
+	// chunks are reused after reset
+	a.reset()
+	assert.Len(t, a.free, 2)
+	b3 := a.copy([]byte{0x04})
+	assert.Len(t, a.free, 1)
+	assert.Equal(t, []byte{0x04}, b3)
+}
+
+func TestArenaNoReuse(t *testing.T) {
+	a := newArena(16, false)
+
+	b1 := a.copy([]byte{0x01, 0x02, 0x03, 0x04})
+	for i := 0; i < 4; i++ {
+		a.alloc(4)
+	}
+	a.reset()
+	a.copy([]byte{0x05, 0x06, 0x07, 0x08})
+
+	assert.Empty(t, a.chunks)
+	assert.Empty(t, a.free)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, b1)
+}
@@ -23,6 +23,12 @@ type BackendBlock struct {
 
 var _ common.Finder = (*BackendBlock)(nil)
 var _ common.Searcher = (*BackendBlock)(nil)
+var _ common.IDIterable = (*BackendBlock)(nil)
+var _ common.Salvageable = (*BackendBlock)(nil)
+var _ common.ObjectIterable = (*BackendBlock)(nil)
+
+// iterateIDsChunkSizeBytes is the buffer size used to read the data object when iterating ids or objects
+const iterateIDsChunkSizeBytes = 1_000_000
 
 // NewBackendBlock returns a BackendBlock for the given backend.BlockMeta
 func NewBackendBlock(meta *backend.BlockMeta, r backend.Reader) (*BackendBlock, error) {
@@ -66,10 +72,9 @@ func (b *BackendBlock) find(ctx context.Context, id common.ID) ([]byte, error) {
 		return nil, nil
 	}
 
-	indexReaderAt := backend.NewContextReader(b.meta, common.NameIndex, b.reader, false)
-	indexReader, err := NewIndexReader(indexReaderAt, int(b.meta.IndexPageSize), int(b.meta.TotalRecords))
+	indexReader, err := b.NewIndexReader()
 	if err != nil {
-		return nil, fmt.Errorf("error building index reader (%s, %s): %w", b.meta.TenantID, b.meta.BlockID, err)
+		return nil, err
 	}
 
 	ra := backend.NewContextReader(b.meta, common.NameObjects, b.reader, false)
@@ -124,9 +129,38 @@ func (b *BackendBlock) partialIterator(chunkSizeBytes uint32, startPage int, tot
 	return newPartialPagedIterator(chunkSizeBytes, reader, dataReader, NewObjectReaderWriter(), startPage, totalPages), nil
 }
 
+// SalvageIterator returns an iterator over the objects of the block that skips the pages that can't be read.
+func (b *BackendBlock) SalvageIterator() (common.SalvageIterator, error) {
+	ra := backend.NewContextReader(b.meta, common.NameObjects, b.reader, false)
+	dataReader, err := NewDataReader(ra, b.meta.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dataReader (%s, %s): %w", b.meta.TenantID, b.meta.BlockID, err)
+	}
+
+	reader, err := b.NewIndexReader()
+	if err != nil {
+		return nil, err
+	}
+
+	return newSalvageIterator(reader, dataReader, NewObjectReaderWriter(), b.meta.MaxID, int(b.meta.TotalRecords)), nil
+}
+
+// NewIndexReader returns a reader of the index of the block in the index version of its meta
 func (b *BackendBlock) NewIndexReader() (common.IndexReader, error) {
 	indexReaderAt := backend.NewContextReader(b.meta, common.NameIndex, b.reader, false)
-	reader, err := NewIndexReader(indexReaderAt, int(b.meta.IndexPageSize), int(b.meta.TotalRecords))
+
+	var (
+		reader common.IndexReader
+		err    error
+	)
+	switch b.meta.IndexVersion {
+	case "":
+		reader, err = NewIndexReader(indexReaderAt, int(b.meta.IndexPageSize), int(b.meta.TotalRecords))
+	case common.IndexVersionV3:
+		reader, err = NewIndexReaderV3(indexReaderAt, int(b.meta.TotalRecords))
+	default:
+		err = fmt.Errorf("unknown index version %s", b.meta.IndexVersion)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create index reader (%s, %s): %w", b.meta.TenantID, b.meta.BlockID, err)
 	}
@@ -206,6 +240,41 @@ func (b *BackendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, o
 	return resp, nil
 }
 
+// ObjectIterator returns the objects of the block in the data encoding of its meta
+func (b *BackendBlock) ObjectIterator(_ context.Context) (common.Iterator, string, error) {
+	iter, err := b.Iterator(iterateIDsChunkSizeBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return iter, b.meta.DataEncoding, nil
+}
+
+// IterateIDs calls cb with the id of every object in the block. v2 blocks don't store all ids in the index
+// so the entire data object is read.
+func (b *BackendBlock) IterateIDs(ctx context.Context, cb func(id common.ID) error) error {
+	iter, err := b.Iterator(iterateIDsChunkSizeBytes)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for {
+		id, _, err := iter.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error iterating %s, %w", b.meta.BlockID, err)
+		}
+
+		err = cb(id)
+		if err != nil {
+			return err
+		}
+	}
+}
+
 func (b *BackendBlock) SearchTags(ctx context.Context, cb common.TagCallback, opts common.SearchOptions) error {
 	return common.ErrUnsupported
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 )
 
@@ -49,13 +50,18 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 			compactionLevel = blockMeta.CompactionLevel
 		}
 
+		// the download opens the block, its objects are read while merging
+		span, _ := c.opts.StartSpan(ctx, "v2.compactor.download", opentracing.Tag{Key: "block", Value: blockMeta.BlockID.String()})
+
 		// Open iterator
 		block, err := NewBackendBlock(blockMeta, r)
 		if err != nil {
+			span.Finish()
 			return nil, err
 		}
 
 		iter, err := block.Iterator(c.opts.ChunkSizeBytes)
+		span.Finish()
 		if err != nil {
 			return nil, err
 		}
@@ -78,7 +84,20 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 	iter := NewMultiblockIterator(ctx, iters, c.opts.IteratorBufferSize, combiner, dataEncoding, l)
 	defer iter.Close()
 
+	// merge spans cover the reading and combining of the objects between two flushes of the output block
+	var mergeSpan opentracing.Span
+	finishMerge := func() {
+		if mergeSpan != nil {
+			mergeSpan.Finish()
+			mergeSpan = nil
+		}
+	}
+	defer finishMerge()
+
 	for {
+		if mergeSpan == nil {
+			mergeSpan, _ = c.opts.StartSpan(ctx, "v2.compactor.merge")
+		}
 
 		id, body, err := iter.Next(ctx)
 		if err == io.EOF {
@@ -89,6 +108,10 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 			return nil, errors.Wrap(err, "error iterating input blocks")
 		}
 
+		if c.opts.DropObject != nil && c.opts.DropObject(id) {
+			continue
+		}
+
 		// make a new block if necessary
 		if currentBlock == nil {
 			currentBlock, err = NewStreamingBlock(&c.opts.BlockConfig, uuid.New(), tenantID, inputs, recordsPerBlock)
@@ -107,6 +130,7 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 		// write partial block
 		if currentBlock.CurrentBufferLength() >= int(c.opts.FlushSizeBytes) {
 			runtime.GC()
+			finishMerge()
 			tracker, err = c.appendBlock(ctx, writerCallback, tracker, currentBlock)
 			if err != nil {
 				return nil, errors.Wrap(err, "error writing partial block")
@@ -115,6 +139,7 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 
 		// ship block to backend if done
 		if currentBlock.Length() >= recordsPerBlock {
+			finishMerge()
 			err = c.finishBlock(ctx, writerCallback, tracker, currentBlock, l)
 			if err != nil {
 				return nil, errors.Wrap(err, "error shipping block to backend")
@@ -125,6 +150,7 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 	}
 
 	// ship final block to backend
+	finishMerge()
 	if currentBlock != nil {
 		err = c.finishBlock(ctx, writerCallback, tracker, currentBlock, l)
 		if err != nil {
@@ -136,6 +162,9 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 }
 
 func (c *Compactor) appendBlock(ctx context.Context, writerCallback func(*backend.BlockMeta, time.Time) backend.Writer, tracker backend.AppendTracker, block *StreamingBlock) (backend.AppendTracker, error) {
+	span, ctx := c.opts.StartSpan(ctx, "v2.compactor.appendBlock", opentracing.Tag{Key: "phase", Value: "encode"})
+	defer span.Finish()
+
 	compactionLevel := int(block.BlockMeta().CompactionLevel - 1)
 
 	if c.opts.ObjectsWritten != nil {
@@ -155,6 +184,9 @@ func (c *Compactor) appendBlock(ctx context.Context, writerCallback func(*backen
 }
 
 func (c *Compactor) finishBlock(ctx context.Context, writerCallback func(*backend.BlockMeta, time.Time) backend.Writer, tracker backend.AppendTracker, block *StreamingBlock, l log.Logger) error {
+	span, ctx := c.opts.StartSpan(ctx, "v2.compactor.finishBlock", opentracing.Tag{Key: "phase", Value: "upload"})
+	defer span.Finish()
+
 	level.Info(l).Log("msg", "writing compacted block", "block", fmt.Sprintf("%+v", block.BlockMeta()))
 
 	bytesFlushed, err := block.Complete(ctx, tracker, writerCallback(block.BlockMeta(), time.Now()))
@@ -16,6 +16,7 @@ type dataReader struct {
 	contextReader backend.ContextReader
 
 	pageBuffer []byte
+	header     checksumDataHeader
 
 	encoding         backend.Encoding
 	pool             ReaderPool
@@ -41,6 +42,15 @@ func NewDataReader(r backend.ContextReader, encoding backend.Encoding) (common.D
 	}, nil
 }
 
+// NewDataReaderWithPool is NewDataReader decompressing pages with the readers of pool, e.g. a ZstdDictPool.
+func NewDataReaderWithPool(r backend.ContextReader, pool ReaderPool) common.DataReader {
+	return &dataReader{
+		encoding:      pool.Encoding(),
+		contextReader: r,
+		pool:          pool,
+	}
+}
+
 // Read implements common.DataReader
 func (r *dataReader) Read(ctx context.Context, records []common.Record, pagesBuffer [][]byte, buffer []byte) ([][]byte, []byte, error) {
 	if len(records) == 0 {
@@ -84,7 +94,11 @@ func (r *dataReader) Read(ctx context.Context, records []common.Record, pagesBuf
 	// read and strip page data
 	compressedPages := make([][]byte, 0, len(compressedPagesBuffer))
 	for _, v0Page := range compressedPagesBuffer {
-		page, err := unmarshalPageFromBytes(v0Page, constDataHeader)
+		page, err := unmarshalPageFromBytes(v0Page, &r.header)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = r.header.verify(page.data)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -137,11 +151,25 @@ func (r *dataReader) NextPage(buffer []byte) ([]byte, uint32, error) {
 		return nil, 0, err
 	}
 
-	page, err := unmarshalPageFromReader(reader, constDataHeader, r.pageBuffer)
+	page, err := unmarshalPageFromReader(reader, &r.header, r.pageBuffer)
 	if err != nil {
 		return nil, 0, err
 	}
-	r.pageBuffer = page.data
+	if !page.mapped {
+		r.pageBuffer = page.data
+	}
+
+	// the page was consumed, return its length so the caller can skip it
+	err = r.header.verify(page.data)
+	if err != nil {
+		return nil, page.totalLength, err
+	}
+
+	// uncompressed pages of memory mapped files are returned without copying them. buffer is never written to by
+	// these readers, so the mapped page passed back as the buffer of the next call isn't modified either.
+	if page.mapped && r.encoding == backend.EncNone {
+		return page.data, page.totalLength, nil
+	}
 
 	compressedReader, err := r.getCompressedReader(page.data)
 	if err != nil {
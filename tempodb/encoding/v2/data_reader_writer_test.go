@@ -18,7 +18,7 @@ func TestReaderNextPage(t *testing.T) {
 	objsPerPage := 100
 	enc := backend.EncZstd
 
-	ids, objs, buffer, _ := createTestData(t, totalObjects, objsPerPage, enc)
+	ids, objs, buffer, _ := createTestData(t, totalObjects, objsPerPage, enc, false)
 	testNextPage(t, totalObjects, enc, ids, objs, buffer)
 }
 
@@ -27,16 +27,46 @@ func TestReaderRead(t *testing.T) {
 	objsPerPage := 100
 	enc := backend.EncZstd
 
-	ids, objs, buffer, recs := createTestData(t, totalObjects, objsPerPage, enc)
+	ids, objs, buffer, recs := createTestData(t, totalObjects, objsPerPage, enc, false)
 	testRead(t, totalObjects, enc, ids, objs, buffer, recs)
 }
 
+func TestReaderChecksum(t *testing.T) {
+	totalObjects := 1000
+	objsPerPage := 100
+	enc := backend.EncSnappy
+
+	ids, objs, buffer, recs := createTestData(t, totalObjects, objsPerPage, enc, true)
+	testNextPage(t, totalObjects, enc, ids, objs, buffer)
+	testRead(t, totalObjects, enc, ids, objs, buffer, recs)
+
+	// corrupt the data of the second page
+	buffer[recs[1].Start+uint64(recs[1].Length)-1] ^= 0xFF
+
+	r, err := NewDataReader(backend.NewContextReaderWithAllReader(bytes.NewReader(buffer)), enc)
+	require.NoError(t, err)
+	defer r.Close()
+
+	_, _, err = r.Read(context.Background(), recs[1:2], nil, nil)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	_, _, err = r.NextPage(nil)
+	require.NoError(t, err)
+	_, pageLen, err := r.NextPage(nil)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	require.Equal(t, recs[1].Length, pageLen)
+
+	// the corrupt page is skipped
+	_, _, err = r.NextPage(nil)
+	require.NoError(t, err)
+}
+
 func BenchmarkReaderRead(b *testing.B) {
 	totalObjects := 10000
 	objsPerPage := 100
 	enc := backend.EncZstd
 
-	ids, objs, buffer, recs := createTestData(b, totalObjects, objsPerPage, enc)
+	ids, objs, buffer, recs := createTestData(b, totalObjects, objsPerPage, enc, false)
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -49,7 +79,7 @@ func BenchmarkReaderNextPage(b *testing.B) {
 	objsPerPage := 100
 	enc := backend.EncZstd
 
-	ids, objs, buffer, _ := createTestData(b, totalObjects, objsPerPage, enc)
+	ids, objs, buffer, _ := createTestData(b, totalObjects, objsPerPage, enc, false)
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -63,7 +93,7 @@ func BenchmarkWriter(b *testing.B) {
 	enc := backend.EncZstd
 
 	for i := 0; i < b.N; i++ {
-		_, _, _, _ = createTestData(b, totalObjects, objsPerPage, enc)
+		_, _, _, _ = createTestData(b, totalObjects, objsPerPage, enc, false)
 	}
 }
 
@@ -137,10 +167,14 @@ func testRead(t require.TestingT, totalObjects int, enc backend.Encoding, ids []
 }
 
 // nolint:unparam
-func createTestData(t require.TestingT, totalObjects int, objsPerPage int, enc backend.Encoding) ([][]byte, [][]byte, []byte, common.Records) {
+func createTestData(t require.TestingT, totalObjects int, objsPerPage int, enc backend.Encoding, checksum bool) ([][]byte, [][]byte, []byte, common.Records) {
 	buffer := &bytes.Buffer{}
 
-	w, err := NewDataWriter(buffer, enc)
+	newDataWriter := NewDataWriter
+	if checksum {
+		newDataWriter = NewChecksumDataWriter
+	}
+	w, err := newDataWriter(buffer, enc)
 	require.NoError(t, err)
 
 	bytesWritten := 0
@@ -19,15 +19,42 @@ type dataWriter struct {
 
 	objectRW     common.ObjectReaderWriter
 	objectBuffer *bytes.Buffer
+
+	checksum bool
 }
 
 // NewDataWriter creates a paged page writer
 func NewDataWriter(writer io.Writer, encoding backend.Encoding) (common.DataWriter, error) {
+	return newDataWriter(writer, encoding, false)
+}
+
+// NewChecksumDataWriter creates a paged page writer that writes a crc32c checksum of every page in the
+// page header. The checksum is verified by the DataReader.
+func NewChecksumDataWriter(writer io.Writer, encoding backend.Encoding) (common.DataWriter, error) {
+	return newDataWriter(writer, encoding, true)
+}
+
+// NewChecksumDataWriterWithPool is NewChecksumDataWriter compressing pages with the writers of pool, e.g. a
+// ZstdDictPool.
+func NewChecksumDataWriterWithPool(writer io.Writer, pool WriterPool) (common.DataWriter, error) {
+	return newDataWriterWithPool(writer, pool, true)
+}
+
+// NewDataWriterWithPool is NewDataWriter compressing pages with the writers of pool, e.g. a ZstdDictPool.
+func NewDataWriterWithPool(writer io.Writer, pool WriterPool) (common.DataWriter, error) {
+	return newDataWriterWithPool(writer, pool, false)
+}
+
+func newDataWriter(writer io.Writer, encoding backend.Encoding, checksum bool) (common.DataWriter, error) {
 	pool, err := GetWriterPool(encoding)
 	if err != nil {
 		return nil, err
 	}
 
+	return newDataWriterWithPool(writer, pool, checksum)
+}
+
+func newDataWriterWithPool(writer io.Writer, pool WriterPool, checksum bool) (common.DataWriter, error) {
 	compressedBuffer := &bytes.Buffer{}
 	compressionWriter, err := pool.GetWriter(compressedBuffer)
 	if err != nil {
@@ -41,6 +68,7 @@ func NewDataWriter(writer io.Writer, encoding backend.Encoding) (common.DataWrit
 		compressedBuffer:  compressedBuffer,
 		objectRW:          NewObjectReaderWriter(),
 		objectBuffer:      &bytes.Buffer{},
+		checksum:          checksum,
 	}, nil
 }
 
@@ -62,7 +90,11 @@ func (p *dataWriter) CutPage() (int, error) {
 	p.compressionWriter.Close()
 
 	// now marshal the buffer as a page to the output
-	bytesWritten, marshalErr := marshalPageToWriter(p.compressedBuffer.Bytes(), p.outputWriter, constDataHeader)
+	var header pageHeader = constDataHeader
+	if p.checksum {
+		header = newChecksumDataHeader(p.compressedBuffer.Bytes())
+	}
+	bytesWritten, marshalErr := marshalPageToWriter(p.compressedBuffer.Bytes(), p.outputWriter, header)
 
 	// reset buffers for the next write
 	p.objectBuffer.Reset()
@@ -85,8 +85,9 @@ func (f *PagedFinder) findOne(ctx context.Context, id common.ID, record common.R
 		return nil, errors.New("unexpected 0 length pages in findOne")
 	}
 
-	// dataReader is expected to return pages in the v0 format.  so this works
-	iter := NewIterator(bytes.NewReader(pages[0]), f.objectRW)
+	// dataReader is expected to return pages in the v0 format.  so this works. the pages are
+	// not reused so the object found can reference them
+	iter := NewBufferIterator(pages[0], f.objectRW)
 	if f.combiner != nil {
 		iter, err = NewDedupingIterator(iter, f.combiner, f.dataEncoding)
 	}
@@ -66,6 +66,199 @@ func TestIndexHeaderChecksum(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestIndexV3WriterReader(t *testing.T) {
+	numRecords := 100000
+	pageSize := 210
+
+	randomRecords := randomOrderedRecords(t, numRecords)
+	indexBytes, err := NewIndexWriterV3(pageSize).Write(randomRecords)
+	require.NoError(t, err)
+
+	indexReader, err := NewIndexReaderV3(backend.NewContextReaderWithAllReader(bytes.NewReader(indexBytes)), numRecords)
+	require.NoError(t, err)
+
+	i := 0
+	for i = 0; i < numRecords; i++ {
+		expectedRecord := randomRecords[i]
+
+		actualRecord, err := indexReader.At(context.Background(), i)
+		assert.NoError(t, err)
+		assert.Equal(t, &expectedRecord, actualRecord)
+
+		actualRecord, actualIdx, err := indexReader.Find(context.Background(), expectedRecord.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, i, actualIdx)
+		assert.Equal(t, &expectedRecord, actualRecord)
+	}
+
+	actualRecord, err := indexReader.At(context.Background(), i)
+	assert.NoError(t, err)
+	assert.Nil(t, actualRecord)
+
+	actualRecord, err = indexReader.At(context.Background(), -1)
+	assert.NoError(t, err)
+	assert.Nil(t, actualRecord)
+}
+
+func TestIndexV3FindMatchesV2(t *testing.T) {
+	numRecords := 10000
+	pageSize := 1000
+
+	records := randomOrderedRecords(t, numRecords)
+	v2Bytes, err := NewIndexWriter(pageSize).Write(records)
+	require.NoError(t, err)
+	v3Bytes, err := NewIndexWriterV3(pageSize).Write(records)
+	require.NoError(t, err)
+
+	v2Reader, err := NewIndexReader(backend.NewContextReaderWithAllReader(bytes.NewReader(v2Bytes)), pageSize, numRecords)
+	require.NoError(t, err)
+	v3Reader, err := NewIndexReaderV3(backend.NewContextReaderWithAllReader(bytes.NewReader(v3Bytes)), numRecords)
+	require.NoError(t, err)
+
+	// ids between the records and beyond the last record
+	for i := 0; i < 10000; i++ {
+		id := make([]byte, 16)
+		_, err := rand.Read(id)
+		require.NoError(t, err)
+
+		expectedRecord, expectedIdx, err := v2Reader.Find(context.Background(), id)
+		require.NoError(t, err)
+		actualRecord, actualIdx, err := v3Reader.Find(context.Background(), id)
+		require.NoError(t, err)
+
+		assert.Equal(t, expectedIdx, actualIdx)
+		assert.Equal(t, expectedRecord, actualRecord)
+	}
+}
+
+func TestIndexV3Size(t *testing.T) {
+	numRecords := 10000
+	pageSize := 250 * 1024
+
+	tests := []struct {
+		name     string
+		idBytes  int
+		maxRatio float64
+	}{
+		{
+			name:     "64 bit ids",
+			idBytes:  8,
+			maxRatio: 0.45,
+		},
+		{
+			name:     "128 bit ids",
+			idBytes:  16,
+			maxRatio: 0.75,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// records of contiguous data pages of ~1MiB like the ones written by the streaming block
+			records := make([]common.Record, 0, numRecords)
+			for i := 0; i < numRecords; i++ {
+				id := make([]byte, 16)
+				_, err := rand.Read(id[16-tc.idBytes:])
+				require.NoError(t, err)
+
+				records = append(records, common.Record{
+					ID:     id,
+					Length: uint32(1024*1024 - rand.Intn(64*1024)),
+				})
+			}
+			common.SortRecords(records)
+			for i := 1; i < len(records); i++ {
+				records[i].Start = records[i-1].Start + uint64(records[i-1].Length)
+			}
+
+			v2Bytes, err := NewIndexWriter(pageSize).Write(records)
+			require.NoError(t, err)
+			v3Bytes, err := NewIndexWriterV3(pageSize).Write(records)
+			require.NoError(t, err)
+
+			// the last v2 page is padded, compare to the size of the fixed size records
+			recordBytes := numRecords * NewRecordReaderWriter().RecordLength()
+			ratio := float64(len(v3Bytes)) / float64(recordBytes)
+			t.Logf("v2 index %d bytes (%d bytes of records), v3 index %d bytes, ratio %.2f", len(v2Bytes), recordBytes, len(v3Bytes), ratio)
+			assert.Less(t, ratio, tc.maxRatio)
+		})
+	}
+}
+
+func TestIndexV3HeaderChecksum(t *testing.T) {
+	numRecords := 100
+	pageSize := 210
+
+	randomRecords := randomOrderedRecords(t, numRecords)
+	indexBytes, err := NewIndexWriterV3(pageSize).Write(randomRecords)
+	require.NoError(t, err)
+
+	indexBytes[len(indexBytes)-1]++
+
+	r, err := NewIndexReaderV3(backend.NewContextReaderWithAllReader(bytes.NewReader(indexBytes)), numRecords)
+	require.NoError(t, err)
+	_, err = r.At(context.Background(), 0)
+	assert.NoError(t, err)
+	_, err = r.At(context.Background(), numRecords-1)
+	assert.Error(t, err)
+
+	// directory
+	indexBytes[indexV3HeaderLength]++
+
+	r, err = NewIndexReaderV3(backend.NewContextReaderWithAllReader(bytes.NewReader(indexBytes)), numRecords)
+	require.NoError(t, err)
+	_, err = r.At(context.Background(), 0)
+	assert.Error(t, err)
+}
+
+func BenchmarkIndexFind(b *testing.B) {
+	numRecords := 100000
+	pageSize := 250 * 1024
+
+	records := make([]common.Record, 0, numRecords)
+	for i := 0; i < numRecords; i++ {
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		records = append(records, common.Record{ID: id, Length: 1024 * 1024})
+	}
+	common.SortRecords(records)
+
+	v2Bytes, err := NewIndexWriter(pageSize).Write(records)
+	require.NoError(b, err)
+	v3Bytes, err := NewIndexWriterV3(pageSize).Write(records)
+	require.NoError(b, err)
+
+	// a reader per find, like the reader of a find by id of a backend block. every read is a request to the backend
+	b.Run("v2", func(b *testing.B) {
+		cr := &countingReader{Reader: bytes.NewReader(v2Bytes)}
+		for i := 0; i < b.N; i++ {
+			r, _ := NewIndexReader(backend.NewContextReaderWithAllReader(cr), pageSize, numRecords)
+			_, _, err := r.Find(context.Background(), records[i%numRecords].ID)
+			require.NoError(b, err)
+		}
+		b.ReportMetric(float64(cr.reads)/float64(b.N), "reads/op")
+	})
+	b.Run("v3", func(b *testing.B) {
+		cr := &countingReader{Reader: bytes.NewReader(v3Bytes)}
+		for i := 0; i < b.N; i++ {
+			r, _ := NewIndexReaderV3(backend.NewContextReaderWithAllReader(cr), numRecords)
+			_, _, err := r.Find(context.Background(), records[i%numRecords].ID)
+			require.NoError(b, err)
+		}
+		b.ReportMetric(float64(cr.reads)/float64(b.N), "reads/op")
+	})
+}
+
+type countingReader struct {
+	*bytes.Reader
+	reads int
+}
+
+func (r *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	r.reads++
+	return r.Reader.ReadAt(p, off)
+}
+
 func randomOrderedRecords(t *testing.T, num int) []common.Record {
 	randomRecords := []common.Record{}
 
@@ -0,0 +1,365 @@
+package v2
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/cespare/xxhash"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+/*
+v3 index
+
+| header | directory | pages |
+
+header:    | magic uint32 | page count uint32 | directory checksum uint64 (xxhash) |
+directory: page count entries of | first id [16]byte | first record uint32 | offset uint64 | length uint32 |
+page:      an index page of variable length, its data are the records of the page. ids of a record only store the
+           suffix not shared with the id of the previous record, the start is omitted if the record starts at the
+           end of the previous one.
+
+record:    | flags byte (shared prefix length, 0x80 if contiguous) | id suffix | start uvarint (if not contiguous) | length uvarint |
+
+The directory is read once per reader and binary searched in memory, Find and At then read a single page.
+*/
+
+const (
+	indexV3Magic           = uint32(0x33584449) // IDX3
+	indexV3HeaderLength    = uint32Size + uint32Size + uint64Size
+	indexV3DirectoryLength = recordIDLength + uint32Size + uint64Size + uint32Size
+
+	recordIDLength       = 16
+	recordContiguousFlag = 0x80
+	maxRecordV3Length    = 1 + recordIDLength + binary.MaxVarintLen64 + binary.MaxVarintLen32
+)
+
+type indexWriterV3 struct {
+	pageSizeBytes int
+}
+
+// NewIndexWriterV3 returns an index writer of the v3 index. Pages are filled with records up to pageSizeBytes.
+func NewIndexWriterV3(pageSizeBytes int) common.IndexWriter {
+	return &indexWriterV3{
+		pageSizeBytes: pageSizeBytes,
+	}
+}
+
+// Write implements common.IndexWriter
+func (w *indexWriterV3) Write(records []common.Record) ([]byte, error) {
+	maxDataLength := w.pageSizeBytes - int(baseHeaderSize) - IndexHeaderLength
+	if maxDataLength < maxRecordV3Length {
+		return nil, fmt.Errorf("pageSize %d too small for one record", w.pageSizeBytes)
+	}
+
+	var (
+		pages     [][]byte
+		directory []byte
+		data      []byte
+		prev      *common.Record
+	)
+	for i := range records {
+		if len(records[i].ID) > recordIDLength {
+			return nil, fmt.Errorf("record %d has an id of %d bytes", i, len(records[i].ID))
+		}
+		if prev != nil && len(data)+maxRecordV3Length > maxDataLength {
+			pages = append(pages, data)
+			data, prev = nil, nil
+		}
+		if prev == nil {
+			directory = appendIndexV3DirectoryEntry(directory, records[i], i)
+		}
+
+		data = appendRecordV3(data, prev, records[i])
+		prev = &records[i]
+	}
+	if len(data) > 0 {
+		pages = append(pages, data)
+	}
+
+	index := make([]byte, indexV3HeaderLength, int(indexV3HeaderLength)+len(directory)+len(pages)*w.pageSizeBytes)
+	binary.LittleEndian.PutUint32(index, indexV3Magic)
+	binary.LittleEndian.PutUint32(index[uint32Size:], uint32(len(pages)))
+	index = append(index, directory...)
+
+	for i, data := range pages {
+		page := make([]byte, int(baseHeaderSize)+IndexHeaderLength+len(data))
+		copy(page[int(baseHeaderSize)+IndexHeaderLength:], data)
+		if _, err := marshalHeaderToPage(page, &indexHeader{checksum: xxhash.Sum64(data)}); err != nil {
+			return nil, err
+		}
+
+		entry := index[int(indexV3HeaderLength)+i*indexV3DirectoryLength+recordIDLength+uint32Size:]
+		binary.LittleEndian.PutUint64(entry, uint64(len(index)))
+		binary.LittleEndian.PutUint32(entry[uint64Size:], uint32(len(page)))
+		index = append(index, page...)
+	}
+	binary.LittleEndian.PutUint64(index[2*uint32Size:], xxhash.Sum64(index[indexV3HeaderLength:int(indexV3HeaderLength)+len(directory)]))
+
+	return index, nil
+}
+
+func appendIndexV3DirectoryEntry(directory []byte, first common.Record, firstRecord int) []byte {
+	entry := make([]byte, indexV3DirectoryLength)
+	copy(entry, first.ID)
+	binary.LittleEndian.PutUint32(entry[recordIDLength:], uint32(firstRecord))
+	// offset and length are set once the page is marshalled
+	return append(directory, entry...)
+}
+
+func appendRecordV3(b []byte, prev *common.Record, r common.Record) []byte {
+	var id, prevID [recordIDLength]byte
+	copy(id[:], r.ID)
+
+	contiguous := false
+	if prev != nil {
+		copy(prevID[:], prev.ID)
+		contiguous = r.Start == prev.Start+uint64(prev.Length)
+	}
+
+	shared := 0
+	for shared < recordIDLength && id[shared] == prevID[shared] {
+		shared++
+	}
+
+	flags := byte(shared)
+	if contiguous {
+		flags |= recordContiguousFlag
+	}
+	b = append(b, flags)
+	b = append(b, id[shared:]...)
+	if !contiguous {
+		b = binary.AppendUvarint(b, r.Start)
+	}
+	return binary.AppendUvarint(b, uint64(r.Length))
+}
+
+type indexPageV3 struct {
+	firstID     common.ID
+	firstRecord int
+	offset      uint64
+	length      uint32
+}
+
+type indexReaderV3 struct {
+	r            backend.ContextReader
+	totalRecords int
+
+	directory []indexPageV3
+	pageCache map[int][]common.Record // indexReaderV3 is not concurrency safe, like indexReader it is used within one request
+}
+
+// NewIndexReaderV3 returns an index reader of a v3 index with totalRecords records.
+func NewIndexReaderV3(r backend.ContextReader, totalRecords int) (common.IndexReader, error) {
+	return &indexReaderV3{
+		r:            r,
+		totalRecords: totalRecords,
+		pageCache:    map[int][]common.Record{},
+	}, nil
+}
+
+// At implements common.IndexReader
+func (r *indexReaderV3) At(ctx context.Context, i int) (*common.Record, error) {
+	if i < 0 || i >= r.totalRecords {
+		return nil, nil
+	}
+
+	directory, err := r.getDirectory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pageIdx := sort.Search(len(directory), func(p int) bool {
+		return directory[p].firstRecord > i
+	}) - 1
+	if pageIdx < 0 {
+		return nil, fmt.Errorf("record %d not in directory", i)
+	}
+
+	records, err := r.getPage(ctx, pageIdx)
+	if err != nil {
+		return nil, err
+	}
+
+	record := records[i-directory[pageIdx].firstRecord]
+	return &record, nil
+}
+
+// Find implements common.IndexReader
+func (r *indexReaderV3) Find(ctx context.Context, id common.ID) (*common.Record, int, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "indexReaderV3.Find")
+	defer span.Finish()
+
+	directory, err := r.getDirectory(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	if len(directory) == 0 {
+		return nil, -1, nil
+	}
+
+	// the first record >= id is in the last page starting with a smaller id or it is the first record of the next page
+	pageIdx := sort.Search(len(directory), func(p int) bool {
+		return bytes.Compare(directory[p].firstID, id) >= 0
+	}) - 1
+	if pageIdx < 0 {
+		pageIdx = 0
+	}
+
+	for ; pageIdx < len(directory); pageIdx++ {
+		records, err := r.getPage(ctx, pageIdx)
+		if err != nil {
+			return nil, -1, err
+		}
+
+		i := sort.Search(len(records), func(i int) bool {
+			return bytes.Compare(records[i].ID, id) >= 0
+		})
+		if i < len(records) {
+			record := records[i]
+			return &record, directory[pageIdx].firstRecord + i, nil
+		}
+	}
+
+	return nil, -1, nil
+}
+
+func (r *indexReaderV3) getDirectory(ctx context.Context) ([]indexPageV3, error) {
+	if r.directory != nil {
+		return r.directory, nil
+	}
+
+	header := make([]byte, indexV3HeaderLength)
+	if _, err := r.r.ReadAt(ctx, header, 0); err != nil {
+		return nil, err
+	}
+	if magic := binary.LittleEndian.Uint32(header); magic != indexV3Magic {
+		return nil, fmt.Errorf("unexpected magic %x of v3 index", magic)
+	}
+	pageCount := int(binary.LittleEndian.Uint32(header[uint32Size:]))
+
+	entries := make([]byte, pageCount*indexV3DirectoryLength)
+	if _, err := r.r.ReadAt(ctx, entries, int64(indexV3HeaderLength)); err != nil {
+		return nil, err
+	}
+	if xxhash.Sum64(entries) != binary.LittleEndian.Uint64(header[2*uint32Size:]) {
+		return nil, fmt.Errorf("mismatched checksum: directory")
+	}
+
+	directory := make([]indexPageV3, 0, pageCount)
+	for len(entries) > 0 {
+		directory = append(directory, indexPageV3{
+			firstID:     append(common.ID(nil), entries[:recordIDLength]...),
+			firstRecord: int(binary.LittleEndian.Uint32(entries[recordIDLength:])),
+			offset:      binary.LittleEndian.Uint64(entries[recordIDLength+uint32Size:]),
+			length:      binary.LittleEndian.Uint32(entries[recordIDLength+uint32Size+uint64Size:]),
+		})
+		entries = entries[indexV3DirectoryLength:]
+	}
+
+	r.directory = directory
+	return directory, nil
+}
+
+func (r *indexReaderV3) getPage(ctx context.Context, pageIdx int) ([]common.Record, error) {
+	records, ok := r.pageCache[pageIdx]
+	if ok {
+		return records, nil
+	}
+
+	entry := r.directory[pageIdx]
+	pageBuffer := make([]byte, entry.length)
+	if _, err := r.r.ReadAt(ctx, pageBuffer, int64(entry.offset)); err != nil {
+		return nil, err
+	}
+
+	page, err := unmarshalPageFromBytes(pageBuffer, &indexHeader{})
+	if err != nil {
+		return nil, err
+	}
+	if page.header.(*indexHeader).checksum != xxhash.Sum64(page.data) {
+		return nil, fmt.Errorf("mismatched checksum: %d", pageIdx)
+	}
+
+	expected := r.totalRecords - entry.firstRecord
+	if pageIdx+1 < len(r.directory) {
+		expected = r.directory[pageIdx+1].firstRecord - entry.firstRecord
+	}
+
+	records, err = unmarshalRecordsV3(page.data, expected)
+	if err != nil {
+		return nil, fmt.Errorf("error reading page %d: %w", pageIdx, err)
+	}
+
+	r.pageCache[pageIdx] = records
+	return records, nil
+}
+
+func unmarshalRecordsV3(b []byte, expected int) ([]common.Record, error) {
+	if expected <= 0 {
+		return nil, fmt.Errorf("unexpected %d records", expected)
+	}
+
+	records := make([]common.Record, 0, expected)
+	ids := make([]byte, expected*recordIDLength)
+
+	// the id of the first record shares its prefix with an id of zeros
+	prevID := make([]byte, recordIDLength)
+	for len(b) > 0 {
+		if len(records) == expected {
+			return nil, fmt.Errorf("more than %d records", expected)
+		}
+
+		flags := b[0]
+		b = b[1:]
+
+		shared := int(flags &^ recordContiguousFlag)
+		if shared > recordIDLength || len(b) < recordIDLength-shared {
+			return nil, fmt.Errorf("invalid id of record %d", len(records))
+		}
+		id := ids[len(records)*recordIDLength : (len(records)+1)*recordIDLength]
+		copy(id, prevID[:shared])
+		copy(id[shared:], b[:recordIDLength-shared])
+		b = b[recordIDLength-shared:]
+
+		var start uint64
+		if flags&recordContiguousFlag != 0 {
+			if len(records) == 0 {
+				return nil, fmt.Errorf("first record is contiguous")
+			}
+			prev := records[len(records)-1]
+			start = prev.Start + uint64(prev.Length)
+		} else {
+			var n int
+			start, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid start of record %d", len(records))
+			}
+			b = b[n:]
+		}
+
+		length, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid length of record %d", len(records))
+		}
+		b = b[n:]
+
+		records = append(records, common.Record{
+			ID:     id,
+			Start:  start,
+			Length: uint32(length),
+		})
+		prevID = id
+	}
+
+	if len(records) != expected {
+		return nil, fmt.Errorf("%d records, expected %d", len(records), expected)
+	}
+	return records, nil
+}
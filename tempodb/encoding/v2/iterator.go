@@ -27,3 +27,29 @@ func (i *iterator) Next(_ context.Context) (common.ID, []byte, error) {
 
 func (i *iterator) Close() {
 }
+
+type bufferIterator struct {
+	buffer []byte
+	o      common.ObjectReaderWriter
+}
+
+// NewBufferIterator returns an iterator over the objects in a buffer. The ids and objects are not copied, they
+// reference the buffer and are only valid as long as the buffer isn't modified.
+func NewBufferIterator(buffer []byte, o common.ObjectReaderWriter) common.Iterator {
+	return &bufferIterator{
+		buffer: buffer,
+		o:      o,
+	}
+}
+
+func (i *bufferIterator) Next(_ context.Context) (common.ID, []byte, error) {
+	var id common.ID
+	var obj []byte
+	var err error
+
+	i.buffer, id, obj, err = i.o.UnmarshalAndAdvanceBuffer(i.buffer)
+	return id, obj, err
+}
+
+func (i *bufferIterator) Close() {
+}
@@ -16,22 +16,30 @@ type dedupingIterator struct {
 	currentID     []byte
 	currentObject []byte
 	dataEncoding  string
+
+	// the objects of the wrapped iterator are copied into two arenas that take turns: one holds the
+	// objects of the id returned by the current call to Next, the other the object read ahead.
+	arenas [2]*arena
 }
 
 // NewDedupingIterator returns a dedupingIterator.  This iterator is used to wrap another
 // iterator.  It will dedupe consecutive objects with the same id using the ObjectCombiner.
+// The wrapped iterator is allowed to reuse its buffers between calls to Next. The object
+// returned by the dedupingIterator is only valid until the next call to Next, the id is not
+// reused.
 func NewDedupingIterator(iter common.Iterator, combiner model.ObjectCombiner, dataEncoding string) (common.Iterator, error) {
 	i := &dedupingIterator{
 		iter:         iter,
 		combiner:     combiner,
 		dataEncoding: dataEncoding,
+		arenas:       [2]*arena{newArena(defaultArenaChunkSize, true), newArena(defaultArenaChunkSize, true)},
 	}
 
-	var err error
-	i.currentID, i.currentObject, err = i.iter.Next(context.Background())
+	id, obj, err := i.iter.Next(context.Background())
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
+	i.setCurrent(id, obj)
 
 	return i, nil
 }
@@ -42,6 +50,12 @@ func (i *dedupingIterator) Next(ctx context.Context) (common.ID, []byte, error)
 		return nil, nil, io.EOF
 	}
 
+	// arenas[0] holds the object read ahead, it is returned by this call. the object returned by the
+	// previous call is in arenas[1] and can be released.
+	objArena := i.arenas[0]
+	i.arenas[0], i.arenas[1] = i.arenas[1], i.arenas[0]
+	i.arenas[0].reset()
+
 	var dedupedID []byte
 	currentObjects := [][]byte{i.currentObject}
 
@@ -53,14 +67,11 @@ func (i *dedupingIterator) Next(ctx context.Context) (common.ID, []byte, error)
 
 		if !bytes.Equal(i.currentID, id) {
 			dedupedID = i.currentID
-
-			i.currentID = id
-			i.currentObject = obj
+			i.setCurrent(id, obj)
 			break
 		}
 
-		i.currentID = id
-		currentObjects = append(currentObjects, obj)
+		currentObjects = append(currentObjects, objArena.copy(obj))
 	}
 
 	if len(currentObjects) == 1 {
@@ -75,6 +86,18 @@ func (i *dedupingIterator) Next(ctx context.Context) (common.ID, []byte, error)
 	return dedupedID, dedupedObject, nil
 }
 
+// setCurrent copies the object read ahead so the wrapped iterator can reuse its buffers.
+func (i *dedupingIterator) setCurrent(id common.ID, obj []byte) {
+	if id == nil {
+		i.currentID = nil
+		i.currentObject = nil
+		return
+	}
+
+	i.currentID = append([]byte(nil), id...)
+	i.currentObject = i.arenas[0].copy(obj)
+}
+
 // Close implements Iterator
 func (i *dedupingIterator) Close() {
 	i.iter.Close()
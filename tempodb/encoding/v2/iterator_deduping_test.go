@@ -117,10 +117,60 @@ func TestDedupingIterator(t *testing.T) {
 			}
 			assert.NoError(t, err)
 			actualIDs = append(actualIDs, id)
-			actualObjs = append(actualObjs, obj)
+			// the object is only valid until the next call to Next
+			actualObjs = append(actualObjs, append([]byte(nil), obj...))
 		}
 
 		assert.Equal(t, tc.expectedIDs, actualIDs)
 		assert.Equal(t, tc.expectedObjs, actualObjs)
 	}
 }
+
+// reusingIterator returns ids and objects that are overwritten by the next call to Next
+type reusingIterator struct {
+	mockIterator
+	id  []byte
+	obj []byte
+}
+
+func (i *reusingIterator) Next(ctx context.Context) (common.ID, []byte, error) {
+	id, obj, err := i.mockIterator.Next(ctx)
+	if err != nil {
+		// scribble over the previous results
+		for j := range i.obj {
+			i.obj[j] = 0xFF
+		}
+		return nil, nil, err
+	}
+
+	i.id = append(i.id[:0], id...)
+	i.obj = append(i.obj[:0], obj...)
+	return i.id, i.obj, nil
+}
+
+func TestDedupingIteratorReusedBuffers(t *testing.T) {
+	iter, err := NewDedupingIterator(&reusingIterator{
+		mockIterator: mockIterator{
+			ids:  []common.ID{{0x01}, {0x02}, {0x02}, {0x03}},
+			objs: [][]byte{{0x01}, {0x02}, {0x03}, {0x04}},
+		},
+	}, &mockCombiner{}, "")
+	require.NoError(t, err)
+
+	expectedIDs := []common.ID{{0x01}, {0x02}, {0x03}}
+	expectedObjs := [][]byte{{0x01}, {0x02, 0x03}, {0x04}}
+
+	var actualIDs []common.ID
+	for i := 0; ; i++ {
+		id, obj, err := iter.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, expectedObjs[i], obj)
+		actualIDs = append(actualIDs, id)
+	}
+
+	// ids are not reused
+	assert.Equal(t, expectedIDs, actualIDs)
+}
@@ -24,6 +24,9 @@ type multiblockIterator struct {
 	quitCh       chan struct{}
 	err          atomic.Error
 	logger       log.Logger
+
+	// objects are copied into a non reusing arena, they are owned by the caller of Next
+	arena *arena
 }
 
 var _ common.Iterator = (*multiblockIterator)(nil)
@@ -42,6 +45,7 @@ func NewMultiblockIterator(ctx context.Context, inputs []common.Iterator, buffer
 		resultsCh:    make(chan iteratorResult, bufferSize),
 		quitCh:       make(chan struct{}, 1),
 		logger:       logger,
+		arena:        newArena(defaultArenaChunkSize, false),
 	}
 
 	for _, iter := range inputs {
@@ -153,7 +157,7 @@ func (i *multiblockIterator) iterate(ctx context.Context) {
 		// Copy slices allows data to escape the iterators
 		res := iteratorResult{
 			id:     append([]byte(nil), lowestID...),
-			object: append([]byte(nil), lowestObject...),
+			object: i.arena.copy(lowestObject),
 		}
 
 		select {
@@ -1,7 +1,6 @@
 package v2
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"io"
@@ -17,11 +16,14 @@ type recordIterator struct {
 
 	currentIterator common.Iterator
 
+	pages  [][]byte
 	buffer []byte
 }
 
 // NewRecordIterator returns a recordIterator.  This iterator is used for iterating through
-// a series of objects by reading them one at a time from Records.
+// a series of objects by reading them one at a time from Records. The pages read are reused
+// and the objects are decoded in place: the object returned is only valid until the next call
+// to Next. IDs are copied, they are commonly retained by appenders.
 func NewRecordIterator(r []common.Record, dataR common.DataReader, objectRW common.ObjectReaderWriter) common.Iterator {
 	return &recordIterator{
 		records:  r,
@@ -37,27 +39,29 @@ func (i *recordIterator) Next(ctx context.Context) (common.ID, []byte, error) {
 			return nil, nil, err
 		}
 		if id != nil {
-			return id, object, nil
+			return append([]byte(nil), id...), object, nil
 		}
 	}
 
 	// read the next record and create an iterator
 	if len(i.records) > 0 {
-		var pages [][]byte
 		var err error
-		pages, i.buffer, err = i.dataR.Read(ctx, i.records[:1], pages, i.buffer)
+		i.pages, i.buffer, err = i.dataR.Read(ctx, i.records[:1], i.pages, i.buffer)
 		if err != nil {
 			return nil, nil, err
 		}
-		if len(pages) == 0 {
+		if len(i.pages) == 0 {
 			return nil, nil, errors.New("unexpected 0 length pages from dataReader")
 		}
 
-		buff := pages[0]
-		i.currentIterator = NewIterator(bytes.NewReader(buff), i.objectRW)
+		i.currentIterator = NewBufferIterator(i.pages[0], i.objectRW)
 		i.records = i.records[1:]
 
-		return i.currentIterator.Next(ctx)
+		id, object, err := i.currentIterator.Next(ctx)
+		if id != nil {
+			id = append([]byte(nil), id...)
+		}
+		return id, object, err
 	}
 
 	// done
@@ -67,3 +71,142 @@ func (i *recordIterator) Next(ctx context.Context) (common.ID, []byte, error) {
 func (i *recordIterator) Close() {
 	i.dataR.Close()
 }
+
+// maxCachedPagesBytes is the size of the decoded pages a recordObjectIterator keeps for the records still to be
+// returned. Pages read once the limit is reached are read again for each of their records.
+const maxCachedPagesBytes = 32 * 1024 * 1024
+
+type recordObjectIterator struct {
+	records []common.Record
+
+	objectRW common.ObjectReaderWriter
+	dataR    common.DataReader
+
+	// remaining is the number of records not yet returned by the start of their page
+	remaining map[uint64]int
+	// cached are the decoded pages of the records not yet returned by their start
+	cached      map[uint64]*objectPage
+	cachedBytes int
+
+	currentID      common.ID
+	currentStart   uint64
+	currentObjects [][]byte
+
+	pages  [][]byte
+	buffer []byte
+}
+
+// objectPage are the objects of a page by id
+type objectPage struct {
+	objects map[string][][]byte
+	size    int
+}
+
+// NewRecordObjectIterator returns an iterator that, unlike NewRecordIterator, only returns the objects of a page with
+// the ID of the record it was read for. It is used for pages shared by the records of several IDs, as written by
+// AppendBatch, so that the objects are returned once each and in the order of the records. Every page is read and
+// decoded once and kept until the objects of all its records were returned, up to maxCachedPagesBytes of pages.
+func NewRecordObjectIterator(r []common.Record, dataR common.DataReader, objectRW common.ObjectReaderWriter) common.Iterator {
+	remaining := make(map[uint64]int, len(r))
+	for _, rec := range r {
+		remaining[rec.Start]++
+	}
+
+	return &recordObjectIterator{
+		records:   r,
+		objectRW:  objectRW,
+		dataR:     dataR,
+		remaining: remaining,
+		cached:    map[uint64]*objectPage{},
+	}
+}
+
+func (i *recordObjectIterator) Next(ctx context.Context) (common.ID, []byte, error) {
+	for len(i.currentObjects) == 0 {
+		if i.currentID != nil {
+			i.release()
+			i.currentID = nil
+		}
+		if len(i.records) == 0 {
+			return nil, nil, io.EOF
+		}
+
+		rec := i.records[0]
+		page, ok := i.cached[rec.Start]
+		if !ok {
+			var err error
+			page, err = i.readPage(ctx, rec)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		i.currentID = rec.ID
+		i.currentStart = rec.Start
+		i.currentObjects = page.objects[string(rec.ID)]
+		i.records = i.records[1:]
+	}
+
+	obj := i.currentObjects[0]
+	i.currentObjects = i.currentObjects[1:]
+	return append([]byte(nil), i.currentID...), obj, nil
+}
+
+// readPage reads and decodes the page of a record. Pages shared with records still to be returned are cached.
+func (i *recordObjectIterator) readPage(ctx context.Context, rec common.Record) (*objectPage, error) {
+	var err error
+	i.pages, i.buffer, err = i.dataR.Read(ctx, []common.Record{rec}, i.pages, i.buffer)
+	if err != nil {
+		return nil, err
+	}
+	if len(i.pages) == 0 {
+		return nil, errors.New("unexpected 0 length pages from dataReader")
+	}
+
+	buf := i.pages[0]
+	cache := i.remaining[rec.Start] > 1 && i.cachedBytes+len(buf) <= maxCachedPagesBytes
+	if cache {
+		// the buffer is reused by the next read
+		buf = append([]byte(nil), buf...)
+	}
+
+	page := &objectPage{
+		objects: map[string][][]byte{},
+		size:    len(buf),
+	}
+	for {
+		var id common.ID
+		var obj []byte
+		buf, id, obj, err = i.objectRW.UnmarshalAndAdvanceBuffer(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		page.objects[string(id)] = append(page.objects[string(id)], obj)
+	}
+
+	if cache {
+		i.cached[rec.Start] = page
+		i.cachedBytes += page.size
+	}
+	return page, nil
+}
+
+// release drops the page of the current record once the objects of all its records were returned
+func (i *recordObjectIterator) release() {
+	i.remaining[i.currentStart]--
+	if i.remaining[i.currentStart] > 0 {
+		return
+	}
+	delete(i.remaining, i.currentStart)
+	if page, ok := i.cached[i.currentStart]; ok {
+		delete(i.cached, i.currentStart)
+		i.cachedBytes -= page.size
+	}
+}
+
+func (i *recordObjectIterator) Close() {
+	i.dataR.Close()
+}
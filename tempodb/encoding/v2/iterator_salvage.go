@@ -0,0 +1,114 @@
+package v2
+
+import (
+	"context"
+	"io"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+type salvageIterator struct {
+	indexReader  common.IndexReader
+	dataReader   common.DataReader
+	objectRW     common.ObjectReaderWriter
+	maxID        common.ID
+	totalRecords int
+
+	nextRecord int
+	record     *common.Record
+	page       common.Iterator
+	lastID     common.ID
+	done       bool
+
+	pages  [][]byte
+	buffer []byte
+
+	lost []common.LostRange
+}
+
+var _ common.SalvageIterator = (*salvageIterator)(nil)
+
+// newSalvageIterator returns an iterator that reads the records of the index one at a time and skips the
+// pages that can't be read or decoded. An unreadable index ends the iteration, the remaining ids up to
+// maxID are reported lost. The object returned is only valid until the next call to Next.
+func newSalvageIterator(indexReader common.IndexReader, dataReader common.DataReader, objectRW common.ObjectReaderWriter, maxID common.ID, totalRecords int) *salvageIterator {
+	return &salvageIterator{
+		indexReader:  indexReader,
+		dataReader:   dataReader,
+		objectRW:     objectRW,
+		maxID:        maxID,
+		totalRecords: totalRecords,
+	}
+}
+
+func (i *salvageIterator) Next(ctx context.Context) (common.ID, []byte, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		if i.page != nil {
+			id, obj, err := i.page.Next(ctx)
+			if err == nil && id != nil {
+				i.lastID = append([]byte(nil), id...)
+				return i.lastID, obj, nil
+			}
+			if err != nil && err != io.EOF {
+				// the rest of the page can't be decoded
+				i.lose(i.record.ID, 1)
+			}
+			i.lastID = i.record.ID
+			i.page = nil
+		}
+
+		if i.done {
+			return nil, nil, io.EOF
+		}
+
+		record, err := i.indexReader.At(ctx, i.nextRecord)
+		if err != nil {
+			i.lose(i.maxID, i.totalRecords-i.nextRecord)
+			i.done = true
+			continue
+		}
+		if record == nil {
+			i.done = true
+			continue
+		}
+		i.nextRecord++
+
+		i.record = &common.Record{
+			ID:     append([]byte(nil), record.ID...),
+			Start:  record.Start,
+			Length: record.Length,
+		}
+
+		i.pages, i.buffer, err = i.dataReader.Read(ctx, []common.Record{*i.record}, i.pages, i.buffer)
+		if err != nil || len(i.pages) == 0 {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, nil, ctxErr
+			}
+			i.lose(i.record.ID, 1)
+			i.lastID = i.record.ID
+			continue
+		}
+
+		i.page = NewBufferIterator(i.pages[0], i.objectRW)
+	}
+}
+
+func (i *salvageIterator) lose(through common.ID, records int) {
+	i.lost = append(i.lost, common.LostRange{
+		After:   i.lastID,
+		Through: through,
+		Records: records,
+	})
+}
+
+func (i *salvageIterator) Lost() []common.LostRange {
+	return i.lost
+}
+
+func (i *salvageIterator) Close() {
+	i.dataReader.Close()
+}
@@ -0,0 +1,109 @@
+package v2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestSalvageIterator(t *testing.T) {
+	const totalObjects = 100
+	const corruptPage = 37
+
+	// a plain appender creates a record for every object
+	buff := &bytes.Buffer{}
+	writer, err := NewDataWriter(buff, backend.EncNone)
+	require.NoError(t, err)
+
+	appender := NewAppender(writer)
+	ids := make([]common.ID, 0, totalObjects)
+	objs := make([][]byte, 0, totalObjects)
+	for i := 0; i < totalObjects; i++ {
+		obj := make([]byte, 100)
+		_, err = rand.Read(obj)
+		require.NoError(t, err)
+		id := []byte(fmt.Sprintf("%4d", i))
+
+		ids = append(ids, id)
+		objs = append(objs, obj)
+
+		err = appender.Append(id, obj)
+		require.NoError(t, err)
+	}
+	require.NoError(t, appender.Complete())
+
+	records := common.Records(appender.Records())
+
+	// overwrite the header of one page
+	data := buff.Bytes()
+	for i := 0; i < 4; i++ {
+		data[int(records[corruptPage].Start)+i] = 0xFF
+	}
+
+	reader, err := NewDataReader(backend.NewContextReaderWithAllReader(bytes.NewReader(data)), backend.EncNone)
+	require.NoError(t, err)
+
+	iter := newSalvageIterator(records, reader, NewObjectReaderWriter(), ids[totalObjects-1], totalObjects)
+	expectedIDs := append(append([]common.ID{}, ids[:corruptPage]...), ids[corruptPage+1:]...)
+	expectedObjs := append(append([][]byte{}, objs[:corruptPage]...), objs[corruptPage+1:]...)
+	assertIterator(t, iter, expectedIDs, expectedObjs)
+
+	assert.Equal(t, []common.LostRange{
+		{After: ids[corruptPage-1], Through: ids[corruptPage], Records: 1},
+	}, iter.Lost())
+}
+
+func TestSalvageIteratorIndexError(t *testing.T) {
+	buff := &bytes.Buffer{}
+	writer, err := NewDataWriter(buff, backend.EncNone)
+	require.NoError(t, err)
+
+	appender := NewAppender(writer)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, appender.Append([]byte(fmt.Sprintf("%4d", i)), []byte{0x01}))
+	}
+	require.NoError(t, appender.Complete())
+
+	reader, err := NewDataReader(backend.NewContextReaderWithAllReader(bytes.NewReader(buff.Bytes())), backend.EncNone)
+	require.NoError(t, err)
+
+	maxID := []byte("   9")
+	iter := newSalvageIterator(&failingIndexReader{Records: common.Records(appender.Records()), failAt: 4}, reader, NewObjectReaderWriter(), maxID, 10)
+
+	count := 0
+	for {
+		id, _, err := iter.Next(context.Background())
+		require.True(t, err == nil || err == io.EOF)
+		if id == nil {
+			break
+		}
+		count++
+	}
+
+	assert.Equal(t, 4, count)
+	assert.Equal(t, []common.LostRange{
+		{After: []byte("   3"), Through: maxID, Records: 6},
+	}, iter.Lost())
+}
+
+type failingIndexReader struct {
+	common.Records
+	failAt int
+}
+
+func (r *failingIndexReader) At(ctx context.Context, i int) (*common.Record, error) {
+	if i >= r.failAt {
+		return nil, errors.New("corrupt index")
+	}
+	return r.Records.At(ctx, i)
+}
@@ -17,6 +17,13 @@ type page struct {
 	data        []byte
 	totalLength uint32
 	header      pageHeader
+	// mapped is set if data is a slice of a memory mapped file that must not be modified
+	mapped bool
+}
+
+// sliceReader is implemented by readers of memory mapped files. Next returns the next n bytes without copying them.
+type sliceReader interface {
+	Next(n int) ([]byte, error)
 }
 
 /*
@@ -26,8 +33,7 @@ type page struct {
 | totalLength | header len | header fields      | page bytes |
 */
 func unmarshalPageFromBytes(b []byte, header pageHeader) (*page, error) {
-	totalHeaderSize := baseHeaderSize + header.headerLength()
-	if len(b) < totalHeaderSize {
+	if len(b) < baseHeaderSize {
 		return nil, fmt.Errorf("page of size %d too small", len(b))
 	}
 
@@ -44,7 +50,7 @@ func unmarshalPageFromBytes(b []byte, header pageHeader) (*page, error) {
 	}
 	b = b[headerLength:]
 
-	dataLength := int(totalLength) - totalHeaderSize
+	dataLength := int(totalLength) - baseHeaderSize - int(headerLength)
 	if len(b) != dataLength {
 		return nil, fmt.Errorf("expected data len %d does not match actual %d", dataLength, len(b))
 	}
@@ -57,8 +63,6 @@ func unmarshalPageFromBytes(b []byte, header pageHeader) (*page, error) {
 }
 
 func unmarshalPageFromReader(r io.Reader, header pageHeader, buffer []byte) (*page, error) {
-	totalHeaderSize := baseHeaderSize + header.headerLength()
-
 	var totalLength uint32
 	var headerLength uint16
 
@@ -79,12 +83,28 @@ func unmarshalPageFromReader(r io.Reader, header pageHeader, buffer []byte) (*pa
 	if err != nil {
 		return nil, err
 	}
-	dataLength := int(totalLength) - totalHeaderSize
+	dataLength := int(totalLength) - baseHeaderSize - int(headerLength)
 
 	if dataLength < 0 {
 		return nil, fmt.Errorf("unexpected negative dataLength unmarshalling page: %d", dataLength)
 	}
 
+	if s, ok := r.(sliceReader); ok {
+		data, err := s.Next(dataLength)
+		if len(data) != dataLength {
+			return nil, fmt.Errorf("unexpected incomplete page read: expected:%d read:%d", dataLength, len(data))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &page{
+			data:        data,
+			totalLength: totalLength,
+			header:      header,
+			mapped:      true,
+		}, nil
+	}
+
 	if cap(buffer) < dataLength {
 		buffer = make([]byte, dataLength)
 	} else {
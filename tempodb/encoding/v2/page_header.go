@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/crc32"
 )
 
 type pageHeader interface {
@@ -15,6 +16,9 @@ type pageHeader interface {
 // DataHeaderLength is the length in bytes for the data header
 const DataHeaderLength = 0
 
+// ChecksumDataHeaderLength is the length in bytes for the data header of checksummed pages
+const ChecksumDataHeaderLength = int(uint32Size) // 32bit checksum (crc32c)
+
 // IndexHeaderLength is the length in bytes for the record header
 const IndexHeaderLength = int(uint64Size) // 64bit checksum (xxhash)
 
@@ -38,6 +42,64 @@ func (h *dataHeader) marshalHeader(b []byte) error {
 	return nil
 }
 
+// ErrChecksumMismatch is returned when the page bytes don't match the checksum in the page header
+var ErrChecksumMismatch = errors.New("page checksum mismatch")
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumDataHeader implements a pageHeader for data pages with a checksum of the page bytes. It also reads
+// data pages without a checksum, in which case the page bytes are not verified.
+// checksum - 32 bit crc32c
+type checksumDataHeader struct {
+	checksum    uint32
+	hasChecksum bool
+}
+
+func newChecksumDataHeader(b []byte) *checksumDataHeader {
+	return &checksumDataHeader{
+		checksum:    crc32.Checksum(b, crc32cTable),
+		hasChecksum: true,
+	}
+}
+
+func (h *checksumDataHeader) unmarshalHeader(b []byte) error {
+	switch len(b) {
+	case DataHeaderLength:
+		h.checksum = 0
+		h.hasChecksum = false
+	case ChecksumDataHeaderLength:
+		h.checksum = binary.LittleEndian.Uint32(b[:uint32Size])
+		h.hasChecksum = true
+	default:
+		return fmt.Errorf("unexpected data header len of %d", len(b))
+	}
+
+	return nil
+}
+
+func (h *checksumDataHeader) headerLength() int {
+	return ChecksumDataHeaderLength
+}
+
+func (h *checksumDataHeader) marshalHeader(b []byte) error {
+	if len(b) != ChecksumDataHeaderLength {
+		return fmt.Errorf("unexpected data header len of %d", len(b))
+	}
+
+	binary.LittleEndian.PutUint32(b, h.checksum)
+
+	return nil
+}
+
+// verify checks the page bytes against the checksum, if present
+func (h *checksumDataHeader) verify(b []byte) error {
+	if h.hasChecksum && crc32.Checksum(b, crc32cTable) != h.checksum {
+		return ErrChecksumMismatch
+	}
+
+	return nil
+}
+
 // indexHeader implements a pageHeader that has index fields
 // checksum - 64 bit xxhash
 type indexHeader struct {
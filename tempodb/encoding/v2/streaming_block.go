@@ -50,7 +50,7 @@ func NewStreamingBlock(cfg *common.BlockConfig, id uuid.UUID, tenantID string, m
 
 	c := &StreamingBlock{
 		meta:  newMeta,
-		bloom: common.NewBloom(cfg.BloomFP, uint(cfg.BloomShardSizeBytes), uint(estimatedObjects)),
+		bloom: common.NewAdaptiveBloom(cfg.BloomFP, uint(cfg.BloomShardSizeBytes), uint(estimatedObjects)),
 		cfg:   cfg,
 	}
 
@@ -133,6 +133,10 @@ func (c *StreamingBlock) Complete(ctx context.Context, tracker backend.AppendTra
 	meta := c.BlockMeta()
 
 	indexWriter := NewIndexWriter(c.cfg.IndexPageSizeBytes)
+	if c.cfg.IndexVersion == common.IndexVersionV3 {
+		indexWriter = NewIndexWriterV3(c.cfg.IndexPageSizeBytes)
+		meta.IndexVersion = common.IndexVersionV3
+	}
 	indexBytes, err := indexWriter.Write(records)
 	if err != nil {
 		return 0, err
@@ -141,6 +145,7 @@ func (c *StreamingBlock) Complete(ctx context.Context, tracker backend.AppendTra
 	meta.TotalRecords = uint32(len(records)) // casting
 	meta.IndexPageSize = uint32(c.cfg.IndexPageSizeBytes)
 	meta.BloomShardCount = uint16(c.bloom.GetShardCount())
+	meta.BloomFP = c.cfg.BloomFP
 
 	return bytesFlushed, writeBlockMeta(ctx, w, meta, indexBytes, c.bloom)
 }
@@ -136,17 +136,20 @@ func TestStreamingBlockAll(t *testing.T) {
 		indexPageSize := rand.Intn(5000) + 1000
 
 		for _, enc := range backend.SupportedEncoding {
-			t.Run(enc.String(), func(t *testing.T) {
-				testStreamingBlockToBackendBlock(t,
-					&common.BlockConfig{
-						IndexDownsampleBytes: indexDownsampleBytes,
-						BloomFP:              bloomFP,
-						BloomShardSizeBytes:  bloomShardSize,
-						Encoding:             enc,
-						IndexPageSizeBytes:   indexPageSize,
-					},
-				)
-			})
+			for _, indexVersion := range []string{"", common.IndexVersionV3} {
+				t.Run(enc.String()+indexVersion, func(t *testing.T) {
+					testStreamingBlockToBackendBlock(t,
+						&common.BlockConfig{
+							IndexDownsampleBytes: indexDownsampleBytes,
+							BloomFP:              bloomFP,
+							BloomShardSizeBytes:  bloomShardSize,
+							Encoding:             enc,
+							IndexPageSizeBytes:   indexPageSize,
+							IndexVersion:         indexVersion,
+						},
+					)
+				})
+			}
 		}
 	}
 }
@@ -256,8 +259,6 @@ func streamingBlock(t *testing.T, cfg *common.BlockConfig, w backend.Writer) (*S
 	block, err := NewStreamingBlock(cfg, originatingMeta.BlockID, originatingMeta.TenantID, []*backend.BlockMeta{originatingMeta}, originatingMeta.TotalObjects)
 	require.NoError(t, err, "unexpected error completing block")
 
-	expectedBloomShards := block.bloom.GetShardCount()
-
 	ctx := context.Background()
 	for {
 		id, data, err := iter.Next(ctx)
@@ -272,6 +273,8 @@ func streamingBlock(t *testing.T, cfg *common.BlockConfig, w backend.Writer) (*S
 		err = block.AddObject(id, data)
 		require.NoError(t, err)
 	}
+	expectedBloomShards := int(common.AdaptiveShardCount(cfg.BloomFP, uint(cfg.BloomShardSizeBytes), uint(numMsgs)))
+
 	var tracker backend.AppendTracker
 	tracker, _, err = block.FlushBuffer(ctx, tracker, w)
 	require.NoError(t, err)
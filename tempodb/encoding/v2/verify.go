@@ -0,0 +1,117 @@
+package v2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var _ common.Verifiable = (*BackendBlock)(nil)
+
+// Verify reads the pages of the data file in order and checks the index against them. The index can be
+// regenerated from the data file, so only unreadable pages are fatal.
+func (b *BackendBlock) Verify(ctx context.Context) ([]common.VerifyProblem, error) {
+	var problems []common.VerifyProblem
+
+	records, err := b.replayRecords(ctx)
+	if err != nil {
+		problems = append(problems, common.VerifyProblem{
+			Fatal: true,
+			Msg:   fmt.Sprintf("%s: error reading page %d: %v", common.NameObjects, len(records), err),
+		})
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err == nil && len(records) != int(b.meta.TotalRecords) {
+		problems = append(problems, common.VerifyProblem{
+			Msg: fmt.Sprintf("%s has %d pages, %d records in the meta", common.NameObjects, len(records), b.meta.TotalRecords),
+		})
+	}
+
+	indexReader, err := b.NewIndexReader()
+	if err != nil {
+		return append(problems, common.VerifyProblem{Msg: err.Error()}), nil
+	}
+	for i := 0; i < int(b.meta.TotalRecords); i++ {
+		record, err := indexReader.At(ctx, i)
+		if err != nil {
+			return append(problems, common.VerifyProblem{Msg: fmt.Sprintf("%s: error reading record %d: %v", common.NameIndex, i, err)}), ctx.Err()
+		}
+		if record == nil {
+			return append(problems, common.VerifyProblem{Msg: fmt.Sprintf("%s has %d records, %d in the meta", common.NameIndex, i, b.meta.TotalRecords)}), nil
+		}
+		if i < len(records) && (!bytes.Equal(record.ID, records[i].ID) || record.Start != records[i].Start || record.Length != records[i].Length) {
+			return append(problems, common.VerifyProblem{Msg: fmt.Sprintf("%s: record %d doesn't match page %d of %s", common.NameIndex, i, i, common.NameObjects)}), nil
+		}
+	}
+
+	return problems, nil
+}
+
+// replayRecords returns the records of the pages of the data file. The records of the pages read before an error
+// are returned with it.
+func (b *BackendBlock) replayRecords(ctx context.Context) ([]common.Record, error) {
+	rc, _, err := b.reader.StreamReader(ctx, common.NameObjects, b.meta.BlockID, b.meta.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	dataReader, err := NewDataReader(backend.NewContextReaderWithAllReader(sequentialReader{rc}), b.meta.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer dataReader.Close()
+
+	var (
+		buffer   []byte
+		pageLen  uint32
+		records  []common.Record
+		offset   uint64
+		objectRW = NewObjectReaderWriter()
+	)
+	for {
+		buffer, pageLen, err = dataReader.NextPage(buffer)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+
+		var lastID common.ID
+		iter := NewIterator(bytes.NewReader(buffer), objectRW)
+		for {
+			id, _, err := iter.Next(ctx)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return records, err
+			}
+			lastID = id
+		}
+
+		records = append(records, common.Record{
+			ID:     append([]byte(nil), lastID...),
+			Start:  offset,
+			Length: pageLen,
+		})
+		offset += uint64(pageLen)
+	}
+}
+
+// sequentialReader is a backend.AllReader of a stream, it can only be read in order
+type sequentialReader struct {
+	io.Reader
+}
+
+func (sequentialReader) ReadAt([]byte, int64) (int, error) {
+	return 0, common.ErrUnsupported
+}
@@ -0,0 +1,432 @@
+package v2
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/huff0"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var zstdDictMagic = []byte{0x37, 0xa4, 0x30, 0xec}
+
+const (
+	// minZstdDictContent is the smallest dictionary content, the repeat offsets of the dictionary point into it
+	minZstdDictContent = 8
+
+	// zstdDictDmerSize is the length of the byte sequences counted when training a dictionary
+	zstdDictDmerSize = 8
+	// zstdDictSegmentSize is the length of the segments of the samples a dictionary is trained from
+	zstdDictSegmentSize = 256
+)
+
+// default distributions of the zstd format, https://github.com/facebook/zstd/blob/dev/doc/zstd_compression_format.md#default-distributions
+var (
+	zstdDefaultOffsetNorm = []int16{
+		1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1}
+	zstdDefaultMatchLengthNorm = []int16{
+		1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1}
+	zstdDefaultLiteralLengthNorm = []int16{
+		4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1}
+)
+
+// BuildZstdDictionary builds a zstd dictionary from samples of the objects it will compress. The content of the
+// dictionary is trained from the samples, see trainZstdDictContent. The literals of the content are used for the
+// huffman table of the dictionary, the sequence tables are the defaults of the format. The id of the dictionary is
+// derived from its content.
+func BuildZstdDictionary(samples [][]byte, maxSize int) ([]byte, error) {
+	content := trainZstdDictContent(samples, maxSize)
+	if len(content) < minZstdDictContent {
+		return nil, fmt.Errorf("zstd dictionary content of %d bytes is too small", len(content))
+	}
+
+	literals, err := zstdDictLiteralTable(content)
+	if err != nil {
+		return nil, err
+	}
+
+	dict := make([]byte, 0, 8+len(literals)+128+12+len(content))
+	dict = append(dict, zstdDictMagic...)
+	dict = binary.LittleEndian.AppendUint32(dict, zstdDictContentID(content))
+	dict = append(dict, literals...)
+	for _, t := range []struct {
+		norm     []int16
+		tableLog uint8
+	}{
+		{norm: zstdDefaultOffsetNorm, tableLog: 5},
+		{norm: zstdDefaultMatchLengthNorm, tableLog: 6},
+		{norm: zstdDefaultLiteralLengthNorm, tableLog: 6},
+	} {
+		dict, err = appendNCount(dict, t.norm, t.tableLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the default repeat offsets
+	for _, offset := range []uint32{1, 4, 8} {
+		dict = binary.LittleEndian.AppendUint32(dict, offset)
+	}
+	dict = append(dict, content...)
+
+	// make sure the dictionary can be loaded
+	_, err = NewZstdDictPool(dict)
+	if err != nil {
+		return nil, fmt.Errorf("building zstd dictionary: %w", err)
+	}
+	return dict, nil
+}
+
+// zstdDictContentID returns the id of a dictionary with the content. Ids below 32768 are reserved for registered
+// dictionaries and ids of 2^31 and above are reserved as well, the crc of the content is masked into the range in
+// between.
+func zstdDictContentID(content []byte) uint32 {
+	return crc32.ChecksumIEEE(content)&(1<<31-1) | 1<<15
+}
+
+// trainZstdDictContent selects the segments of the samples that occur in the most samples, similar to the cover
+// algorithm of zstd. A segment is scored by the number of samples containing each of its distinct dmers, the dmers of
+// a selected segment don't score again. The samples are divided into epochs and the best segment of every epoch is
+// selected in turn, so the dictionary covers all samples, until the dictionary is full or no segment scores. The
+// best segments are placed at the end of the content, where their offsets are the smallest. Samples that fit
+// into the dictionary are used as is.
+func trainZstdDictContent(samples [][]byte, maxSize int) []byte {
+	total := 0
+	for _, s := range samples {
+		total += len(s)
+	}
+	if total <= maxSize {
+		content := make([]byte, 0, total)
+		for _, s := range samples {
+			content = append(content, s...)
+		}
+		return content
+	}
+
+	// the dmers starting at every position of the concatenated samples, dmers crossing samples are not valid
+	all := make([]byte, 0, total)
+	dmers := make([]uint64, total)
+	valid := make([]bool, total)
+	freqs := map[uint64]int{}
+	seen := map[uint64]struct{}{}
+	for _, s := range samples {
+		start := len(all)
+		all = append(all, s...)
+		for k := range seen {
+			delete(seen, k)
+		}
+		for i := 0; i+zstdDictDmerSize <= len(s); i++ {
+			dmer := binary.LittleEndian.Uint64(s[i:])
+			dmers[start+i] = dmer
+			valid[start+i] = true
+			if _, ok := seen[dmer]; !ok {
+				seen[dmer] = struct{}{}
+				freqs[dmer]++
+			}
+		}
+	}
+
+	segmentSize := zstdDictSegmentSize
+	if segmentSize > maxSize {
+		segmentSize = maxSize
+	}
+	epochs := maxSize / segmentSize
+	epochSize := total / epochs
+	if epochSize < segmentSize {
+		epochSize = segmentSize
+		epochs = (total + epochSize - 1) / epochSize
+	}
+
+	var segments [][]byte
+	size := 0
+	active := map[uint64]int{}
+	for exhausted := 0; exhausted < epochs && size < maxSize; {
+		exhausted = 0
+		for epoch := 0; epoch < epochs && size < maxSize; epoch++ {
+			begin := epoch * epochSize
+			end := begin + epochSize
+			if end > total || epoch == epochs-1 {
+				end = total
+			}
+
+			// slide a window of the segment size over the epoch
+			for k := range active {
+				delete(active, k)
+			}
+			bestStart, bestScore, score := 0, 0, 0
+			for i := begin; i < end; i++ {
+				if valid[i] {
+					if active[dmers[i]] == 0 {
+						score += freqs[dmers[i]]
+					}
+					active[dmers[i]]++
+				}
+				windowStart := i - segmentSize + 1
+				if windowStart > begin && valid[windowStart-1] {
+					dmer := dmers[windowStart-1]
+					active[dmer]--
+					if active[dmer] == 0 {
+						score -= freqs[dmer]
+						delete(active, dmer)
+					}
+				}
+				if score > bestScore {
+					bestStart, bestScore = windowStart, score
+				}
+			}
+			if bestScore == 0 {
+				exhausted++
+				continue
+			}
+			if bestStart < begin {
+				bestStart = begin
+			}
+
+			segmentEnd := bestStart + segmentSize
+			if segmentEnd > end {
+				segmentEnd = end
+			}
+			for i := bestStart; i < segmentEnd; i++ {
+				if valid[i] {
+					freqs[dmers[i]] = 0
+				}
+			}
+			segments = append(segments, all[bestStart:segmentEnd])
+			size += segmentEnd - bestStart
+		}
+	}
+
+	content := make([]byte, 0, size)
+	for i := len(segments) - 1; i >= 0; i-- {
+		content = append(content, segments[i]...)
+	}
+	if len(content) > maxSize {
+		content = content[len(content)-maxSize:]
+	}
+	return content
+}
+
+// ZstdDictionaryID returns the id of a zstd dictionary. Frames compressed with a dictionary carry its id.
+func ZstdDictionaryID(dict []byte) (uint32, error) {
+	if len(dict) < 8 || string(dict[:4]) != string(zstdDictMagic) {
+		return 0, errors.New("not a zstd dictionary")
+	}
+	return binary.LittleEndian.Uint32(dict[4:8]), nil
+}
+
+// zstdDictLiteralTable returns the huffman table of the literals in content. Every byte value is included so the
+// table can be reused for any block. Content that doesn't compress gets a table that favours no literal.
+func zstdDictLiteralTable(content []byte) ([]byte, error) {
+	if len(content) > huff0.BlockSizeMax-256 {
+		content = content[len(content)-(huff0.BlockSizeMax-256):]
+	}
+
+	in := make([]byte, 0, len(content)+256)
+	in = append(in, content...)
+	for b := 0; b < 256; b++ {
+		in = append(in, byte(b))
+	}
+
+	var s huff0.Scratch
+	_, _, err := huff0.Compress1X(in, &s)
+	if err == nil {
+		return s.OutTable, nil
+	}
+
+	// every byte once and a run of zeros keeps the table compressible
+	in = in[:0]
+	for b := 0; b < 256; b++ {
+		in = append(in, byte(b))
+	}
+	in = append(in, make([]byte, 256)...)
+	s = huff0.Scratch{}
+	_, _, err = huff0.Compress1X(in, &s)
+	if err != nil {
+		return nil, fmt.Errorf("building zstd dictionary literal table: %w", err)
+	}
+	return s.OutTable, nil
+}
+
+// appendNCount appends the normalized counts of a fse table as described in
+// https://github.com/facebook/zstd/blob/dev/doc/zstd_compression_format.md#fse-table-description
+func appendNCount(dst []byte, norm []int16, tableLog uint8) ([]byte, error) {
+	const minTableLog = 5
+
+	var (
+		tableSize = 1 << tableLog
+		previous0 bool
+		charnum   int
+
+		bitStream = uint32(tableLog - minTableLog)
+		bitCount  = uint(4)
+		remaining = int16(tableSize + 1) // +1 for extra accuracy
+		threshold = int16(tableSize)
+		nbBits    = uint(tableLog + 1)
+	)
+
+	flush16 := func() {
+		dst = append(dst, byte(bitStream), byte(bitStream>>8))
+		bitStream >>= 16
+	}
+
+	for remaining > 1 {
+		if charnum >= len(norm) {
+			return nil, errors.New("normalized counts don't add up to the table size")
+		}
+		if previous0 {
+			start := charnum
+			for norm[charnum] == 0 {
+				charnum++
+			}
+			for charnum >= start+24 {
+				start += 24
+				bitStream += uint32(0xFFFF) << bitCount
+				flush16()
+			}
+			for charnum >= start+3 {
+				start += 3
+				bitStream += 3 << bitCount
+				bitCount += 2
+			}
+			bitStream += uint32(charnum-start) << bitCount
+			bitCount += 2
+			if bitCount > 16 {
+				flush16()
+				bitCount -= 16
+			}
+		}
+
+		count := norm[charnum]
+		charnum++
+		max := (2*threshold - 1) - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		count++ // +1 for extra accuracy
+		if count >= threshold {
+			count += max
+		}
+		bitStream += uint32(count) << bitCount
+		bitCount += nbBits
+		if count < max {
+			bitCount--
+		}
+
+		previous0 = count == 1
+		if remaining < 1 {
+			return nil, errors.New("normalized counts exceed the table size")
+		}
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+
+		if bitCount > 16 {
+			flush16()
+			bitCount -= 16
+		}
+	}
+
+	n := len(dst) + int((bitCount+7)/8)
+	dst = append(dst, byte(bitStream), byte(bitStream>>8))
+	return dst[:n], nil
+}
+
+// ZstdDictPool is a zstd compression pool that compresses with a dictionary. Pages compressed without a
+// dictionary can be read as well. Encoders and decoders don't run concurrently, so they hold no goroutines and
+// are pooled.
+type ZstdDictPool struct {
+	dict []byte
+
+	readers sync.Pool
+	writers sync.Pool
+}
+
+// NewZstdDictPool returns a pool compressing with the dictionary. The dictionary is validated.
+func NewZstdDictPool(dict []byte) (*ZstdDictPool, error) {
+	pool := &ZstdDictPool{dict: dict}
+
+	d, err := pool.newReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	pool.readers.Put(d)
+
+	return pool, nil
+}
+
+func (pool *ZstdDictPool) newReader(src io.Reader) (*zstd.Decoder, error) {
+	return zstd.NewReader(src, zstd.WithDecoderDicts(pool.dict), zstd.WithDecoderConcurrency(1))
+}
+
+// Encoding implements WriterPool and ReaderPool
+func (pool *ZstdDictPool) Encoding() backend.Encoding {
+	return backend.EncZstd
+}
+
+// GetReader gets or creates a new CompressionReader and reset it to read from src
+func (pool *ZstdDictPool) GetReader(src io.Reader) (io.Reader, error) {
+	if r := pool.readers.Get(); r != nil {
+		return pool.ResetReader(src, r.(*zstd.Decoder))
+	}
+	return pool.newReader(src)
+}
+
+// PutReader places back in the pool a CompressionReader
+func (pool *ZstdDictPool) PutReader(reader io.Reader) {
+	r := reader.(*zstd.Decoder)
+	// releases the source
+	_ = r.Reset(nil)
+	pool.readers.Put(r)
+}
+
+// ResetReader implements ReaderPool
+func (pool *ZstdDictPool) ResetReader(src io.Reader, resetReader io.Reader) (io.Reader, error) {
+	reader := resetReader.(*zstd.Decoder)
+	err := reader.Reset(src)
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+// GetWriter gets or creates a new CompressionWriter and reset it to write to dst
+func (pool *ZstdDictPool) GetWriter(dst io.Writer) (io.WriteCloser, error) {
+	if w := pool.writers.Get(); w != nil {
+		return pool.ResetWriter(dst, w.(*zstd.Encoder))
+	}
+	w, err := zstd.NewWriter(dst, zstd.WithEncoderDict(pool.dict), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// PutWriter places back in the pool a CompressionWriter
+func (pool *ZstdDictPool) PutWriter(writer io.WriteCloser) {
+	w := writer.(*zstd.Encoder)
+	_ = w.Close()
+	// releases the destination
+	w.Reset(nil)
+	pool.writers.Put(w)
+}
+
+// ResetWriter implements WriterPool
+func (pool *ZstdDictPool) ResetWriter(dst io.Writer, resetWriter io.WriteCloser) (io.WriteCloser, error) {
+	writer := resetWriter.(*zstd.Encoder)
+	writer.Reset(dst)
+	return writer, nil
+}
@@ -0,0 +1,138 @@
+package v2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestZstdDictionary(t *testing.T) {
+	// small objects sharing most of their bytes compress poorly on their own
+	newObj := func(i int) []byte {
+		return []byte(fmt.Sprintf(`{"service.name":"frontend","http.method":"GET","http.url":"/api/v1/items/%d","http.status_code":200,"span.kind":"server"}`, i))
+	}
+	samples := make([][]byte, 0, 100)
+	for i := 0; i < 100; i++ {
+		samples = append(samples, newObj(i))
+	}
+
+	dict, err := BuildZstdDictionary(samples, 4096)
+	require.NoError(t, err)
+	id, err := ZstdDictionaryID(dict)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, id, uint32(1<<15))
+	require.Less(t, id, uint32(1<<31))
+
+	// the dictionary is deterministic
+	again, err := BuildZstdDictionary(samples, 4096)
+	require.NoError(t, err)
+	require.Equal(t, dict, again)
+
+	pool, err := NewZstdDictPool(dict)
+	require.NoError(t, err)
+
+	write := func(w common.DataWriter, buffer *bytes.Buffer) ([]common.ID, [][]byte, []common.Record) {
+		var ids []common.ID
+		var objs [][]byte
+		var recs []common.Record
+		for i := 0; i < 10; i++ {
+			id := make([]byte, 16)
+			_, err := rand.Read(id)
+			require.NoError(t, err)
+			obj := newObj(1000 + i)
+			_, err = w.Write(id, obj)
+			require.NoError(t, err)
+			start := uint64(buffer.Len())
+			length, err := w.CutPage()
+			require.NoError(t, err)
+			ids = append(ids, id)
+			objs = append(objs, obj)
+			recs = append(recs, common.Record{ID: id, Start: start, Length: uint32(length)})
+		}
+		require.NoError(t, w.Complete())
+		return ids, objs, recs
+	}
+
+	plain := &bytes.Buffer{}
+	w, err := NewChecksumDataWriter(plain, backend.EncZstd)
+	require.NoError(t, err)
+	write(w, plain)
+
+	compressed := &bytes.Buffer{}
+	w, err = NewChecksumDataWriterWithPool(compressed, pool)
+	require.NoError(t, err)
+	ids, objs, recs := write(w, compressed)
+	require.Less(t, compressed.Len(), plain.Len())
+
+	// pages are read back with the dictionary
+	r := NewDataReaderWithPool(backend.NewContextReaderWithAllReader(bytes.NewReader(compressed.Bytes())), pool)
+	defer r.Close()
+	o := NewObjectReaderWriter()
+	for i := range ids {
+		page, _, err := r.NextPage(nil)
+		require.NoError(t, err)
+		_, id, obj, err := o.UnmarshalAndAdvanceBuffer(page)
+		require.NoError(t, err)
+		require.Equal(t, ids[i], common.ID(id))
+		require.Equal(t, objs[i], obj)
+	}
+	_, _, err = r.NextPage(nil)
+	require.Equal(t, io.EOF, err)
+
+	pages, _, err := r.Read(context.Background(), recs[2:4], nil, nil)
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+
+	// pages compressed without a dictionary are read too
+	r = NewDataReaderWithPool(backend.NewContextReaderWithAllReader(bytes.NewReader(plain.Bytes())), pool)
+	defer r.Close()
+	_, _, err = r.NextPage(nil)
+	require.NoError(t, err)
+
+	// but pages compressed with a dictionary are not read without it
+	plainReader, err := NewDataReader(backend.NewContextReaderWithAllReader(bytes.NewReader(compressed.Bytes())), backend.EncZstd)
+	require.NoError(t, err)
+	defer plainReader.Close()
+	_, _, err = plainReader.NextPage(nil)
+	require.Error(t, err)
+
+	_, err = BuildZstdDictionary([][]byte{[]byte("tiny")}, 4096)
+	require.Error(t, err)
+	_, err = NewZstdDictPool([]byte("not a dictionary"))
+	require.Error(t, err)
+}
+
+func TestTrainZstdDictContent(t *testing.T) {
+	// the attributes shared by every sample are selected, the unique ids are not
+	samples := make([][]byte, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(`{"id":"%016x","service.name":"checkout","http.method":"POST","http.route":"/api/v1/cart"}`, rand.Uint64())))
+	}
+
+	content := trainZstdDictContent(samples, 1024)
+	require.LessOrEqual(t, len(content), 1024)
+	require.Contains(t, string(content), `"service.name":"checkout","http.method":"POST"`)
+
+	// samples that fit are used as is
+	require.Equal(t, []byte("abcdef"), trainZstdDictContent([][]byte{[]byte("abc"), []byte("def")}, 1024))
+}
+
+func TestZstdDictContentID(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		content := make([]byte, 16)
+		_, err := rand.Read(content)
+		require.NoError(t, err)
+
+		id := zstdDictContentID(content)
+		require.GreaterOrEqual(t, id, uint32(1<<15))
+		require.Less(t, id, uint32(1<<31))
+	}
+}
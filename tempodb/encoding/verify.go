@@ -0,0 +1,129 @@
+package encoding
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/willf/bloom"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// VerifyReport are the problems found by verifying a block
+type VerifyReport struct {
+	// Objects is the number of ids read from the block
+	Objects  int
+	Problems []common.VerifyProblem
+}
+
+// Fatal returns true if objects of the block can't be read
+func (r *VerifyReport) Fatal() bool {
+	for _, p := range r.Problems {
+		if p.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyBlock re-reads a backend block and checks its files, bloom filters and ids against its meta. The files
+// are checked by the encoding of the block if it implements common.Verifiable. The ids are read with these files,
+// so they are only checked if no problems were found in them. Errors are returned if the block couldn't be
+// verified, e.g. because its version is unknown.
+func VerifyBlock(ctx context.Context, meta *backend.BlockMeta, r backend.Reader) (*VerifyReport, error) {
+	block, err := OpenBlock(meta, r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{}
+	if v, ok := block.(common.Verifiable); ok {
+		problems, err := v.Verify(ctx)
+		if err != nil {
+			return nil, err
+		}
+		report.Problems = append(report.Problems, problems...)
+	}
+	filesOK := len(report.Problems) == 0
+
+	blooms := verifyBlooms(ctx, meta, r, report)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	iterable, ok := block.(common.IDIterable)
+	if !ok || !filesOK {
+		return report, nil
+	}
+
+	var (
+		prev                      common.ID
+		unsorted, outside, missed int
+		checkRange                = len(meta.MinID) > 0 && len(meta.MaxID) > 0
+	)
+	err = iterable.IterateIDs(ctx, func(id common.ID) error {
+		if prev != nil && bytes.Compare(prev, id) >= 0 {
+			unsorted++
+		}
+		if checkRange && (bytes.Compare(id, meta.MinID) < 0 || bytes.Compare(id, meta.MaxID) > 0) {
+			outside++
+		}
+		if len(blooms) > 0 {
+			if f := blooms[common.ShardKeyForTraceID(id, len(blooms))]; f != nil && !f.Test(id) {
+				missed++
+			}
+		}
+		prev = append(prev[:0], id...)
+		report.Objects++
+		return nil
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if err != nil {
+		report.Problems = append(report.Problems, common.VerifyProblem{Fatal: true, Msg: fmt.Sprintf("error reading ids after %d objects: %v", report.Objects, err)})
+	} else if report.Objects != meta.TotalObjects {
+		report.Problems = append(report.Problems, common.VerifyProblem{Msg: fmt.Sprintf("block has %d objects, %d in the meta", report.Objects, meta.TotalObjects)})
+	}
+	if unsorted > 0 {
+		report.Problems = append(report.Problems, common.VerifyProblem{Fatal: true, Msg: fmt.Sprintf("%d ids are out of order, lookups by id miss them", unsorted)})
+	}
+	if outside > 0 {
+		report.Problems = append(report.Problems, common.VerifyProblem{Msg: fmt.Sprintf("%d ids are outside of the min and max id of the meta", outside)})
+	}
+	if missed > 0 {
+		report.Problems = append(report.Problems, common.VerifyProblem{Msg: fmt.Sprintf("%d ids are missing from the bloom filters", missed)})
+	}
+
+	return report, nil
+}
+
+// verifyBlooms reads the bloom filter shards of the block. Shards that can't be read are nil and reported as
+// problems, they can be regenerated.
+func verifyBlooms(ctx context.Context, meta *backend.BlockMeta, r backend.Reader, report *VerifyReport) []*bloom.BloomFilter {
+	if meta.BloomShardCount == 0 {
+		report.Problems = append(report.Problems, common.VerifyProblem{Msg: "meta has no bloom filter shards"})
+		return nil
+	}
+
+	blooms := make([]*bloom.BloomFilter, meta.BloomShardCount)
+	for i := range blooms {
+		name := common.BloomName(i)
+		b, err := r.Read(ctx, name, meta.BlockID, meta.TenantID, false)
+		if err != nil {
+			report.Problems = append(report.Problems, common.VerifyProblem{Msg: fmt.Sprintf("%s: error reading bloom filter: %v", name, err)})
+			continue
+		}
+
+		f := &bloom.BloomFilter{}
+		if _, err := f.ReadFrom(bytes.NewReader(b)); err != nil {
+			report.Problems = append(report.Problems, common.VerifyProblem{Msg: fmt.Sprintf("%s: error parsing bloom filter: %v", name, err)})
+			continue
+		}
+		blooms[i] = f
+	}
+	return blooms
+}
@@ -0,0 +1,124 @@
+package encoding
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
+)
+
+func TestVerifyBlock(t *testing.T) {
+	// data files of the encodings
+	encodings := map[VersionedEncoding]string{
+		v2.Encoding{}:        common.NameObjects,
+		vparquet.Encoding{}:  vparquet.DataFileName,
+		vparquet2.Encoding{}: vparquet2.DataFileName,
+	}
+
+	for v, dataFileName := range encodings {
+		t.Run(v.Version(), func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			rawR, rawW, _, err := local.New(&local.Config{Path: dir})
+			require.NoError(t, err)
+			r, w := backend.NewReader(rawR), backend.NewWriter(rawW)
+
+			meta := createVerifyTestBlock(t, v, r, w)
+			blockDir := filepath.Join(dir, meta.TenantID, meta.BlockID.String())
+
+			report, err := VerifyBlock(ctx, meta, r)
+			require.NoError(t, err)
+			require.Empty(t, report.Problems)
+			require.Equal(t, 10, report.Objects)
+
+			// a wrong object count in the meta is reparable
+			wrongMeta := *meta
+			wrongMeta.TotalObjects = 11
+			report, err = VerifyBlock(ctx, &wrongMeta, r)
+			require.NoError(t, err)
+			require.Len(t, report.Problems, 1)
+			require.False(t, report.Fatal())
+
+			// missing blooms are reparable
+			require.NoError(t, os.Remove(filepath.Join(blockDir, common.BloomName(0))))
+			report, err = VerifyBlock(ctx, meta, r)
+			require.NoError(t, err)
+			require.NotEmpty(t, report.Problems)
+			require.False(t, report.Fatal())
+			require.Equal(t, 10, report.Objects)
+
+			// truncated data is fatal
+			dataFile := filepath.Join(blockDir, dataFileName)
+			info, err := os.Stat(dataFile)
+			require.NoError(t, err)
+			require.NoError(t, os.Truncate(dataFile, info.Size()-10))
+			report, err = VerifyBlock(ctx, meta, r)
+			require.NoError(t, err)
+			require.True(t, report.Fatal())
+		})
+	}
+}
+
+func createVerifyTestBlock(t *testing.T, v VersionedEncoding, r backend.Reader, w backend.Writer) *backend.BlockMeta {
+	dec, err := model.NewObjectDecoder(model.CurrentEncoding)
+	require.NoError(t, err)
+
+	iter := &verifyTestIterator{}
+	for i := 0; i < 10; i++ {
+		id := make([]byte, 16)
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+
+		seg, err := model.MustNewSegmentDecoder(model.CurrentEncoding).PrepareForWrite(test.MakeTrace(2, id), 0, 0)
+		require.NoError(t, err)
+		obj, err := model.MustNewSegmentDecoder(model.CurrentEncoding).ToObject([][]byte{seg})
+		require.NoError(t, err)
+
+		iter.ids = append(iter.ids, id)
+		iter.objs = append(iter.objs, obj)
+	}
+
+	cfg := &common.BlockConfig{
+		IndexDownsampleBytes: 1000,
+		IndexPageSizeBytes:   1000,
+		BloomFP:              0.01,
+		BloomShardSizeBytes:  100_000,
+		Encoding:             backend.EncNone,
+		RowGroupSizeBytes:    1_000_000,
+	}
+	meta := backend.NewBlockMeta("test", uuid.New(), v.Version(), backend.EncNone, model.CurrentEncoding)
+	meta.TotalObjects = 10
+
+	meta, err = v.CreateBlock(context.Background(), cfg, meta, iter, dec, r, w)
+	require.NoError(t, err)
+	return meta
+}
+
+type verifyTestIterator struct {
+	ids  []common.ID
+	objs [][]byte
+}
+
+func (i *verifyTestIterator) Next(context.Context) (common.ID, []byte, error) {
+	if len(i.ids) == 0 {
+		return nil, nil, io.EOF
+	}
+	id, obj := i.ids[0], i.objs[0]
+	i.ids, i.objs = i.ids[1:], i.objs[1:]
+	return id, obj, nil
+}
+
+func (i *verifyTestIterator) Close() {}
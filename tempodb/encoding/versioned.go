@@ -7,7 +7,6 @@ import (
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
-	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 )
 
@@ -38,13 +37,10 @@ type VersionedEncoding interface {
 	CopyBlock(ctx context.Context, meta *backend.BlockMeta, from backend.Reader, to backend.Writer) error
 }
 
-// FromVersion returns a versioned encoding for the provided string
+// FromVersion returns the registered encoding for the provided string
 func FromVersion(v string) (VersionedEncoding, error) {
-	switch v {
-	case v2.VersionString:
-		return v2.Encoding{}, nil
-	case vparquet.VersionString:
-		return vparquet.Encoding{}, nil
+	if e, ok := registered(v); ok {
+		return e, nil
 	}
 
 	return nil, fmt.Errorf("%s is not a valid block version", v)
@@ -55,12 +51,15 @@ func DefaultEncoding() VersionedEncoding {
 	return vparquet.Encoding{}
 }
 
-// allEncodings returns all encodings
+// allEncodings returns all registered encodings
 func allEncodings() []VersionedEncoding {
-	return []VersionedEncoding{
-		v2.Encoding{},
-		vparquet.Encoding{},
+	versions := Versions()
+	encodings := make([]VersionedEncoding, 0, len(versions))
+	for _, v := range versions {
+		e, _ := registered(v)
+		encodings = append(encodings, e)
 	}
+	return encodings
 }
 
 // OpenBlock for reading in the backend. It automatically chooes the encoding for the given block.
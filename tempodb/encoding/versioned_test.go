@@ -5,6 +5,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 )
 
 func TestFromVersionErrors(t *testing.T) {
@@ -21,3 +23,26 @@ func TestAllVersions(t *testing.T) {
 		require.NoError(t, err)
 	}
 }
+
+type testEncoding struct {
+	v2.Encoding
+}
+
+func (testEncoding) Version() string {
+	return "test"
+}
+
+func TestRegister(t *testing.T) {
+	require.NoError(t, Register(testEncoding{}))
+
+	encoding, err := FromVersion("test")
+	require.NoError(t, err)
+	assert.Equal(t, testEncoding{}, encoding)
+	assert.Contains(t, Versions(), "test")
+
+	// versions are unique
+	assert.Error(t, Register(testEncoding{}))
+	assert.Error(t, Register(v2.Encoding{}))
+
+	assert.Error(t, Register(nil))
+}
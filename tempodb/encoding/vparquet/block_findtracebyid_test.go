@@ -74,7 +74,8 @@ func TestBackendBlockFindTraceByID(t *testing.T) {
 
 	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
 	meta.TotalObjects = len(traces)
-	s := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
 
 	// Write test data, occasionally flushing (cutting new row group)
 	rowGroupSize := 5
@@ -98,6 +99,12 @@ func TestBackendBlockFindTraceByID(t *testing.T) {
 		require.NoError(t, err)
 
 		require.Equal(t, wantProto, gotProto)
+
+		// streamed with readahead
+		gotProto, err = b.FindTraceByID(ctx, tr.TraceID, streamingSearchOptions())
+		require.NoError(t, err)
+
+		require.Equal(t, wantProto, gotProto)
 	}
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/segmentio/parquet-go"
 
 	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/parquetquery"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 )
@@ -37,6 +38,18 @@ func (b *backendBlock) Iterator(ctx context.Context) (Iterator, error) {
 	return &blockIterator{blockID: b.meta.BlockID.String(), r: r}, nil
 }
 
+var _ common.ObjectIterable = (*backendBlock)(nil)
+
+// ObjectIterator returns the traces of the block as objects of the current data encoding
+func (b *backendBlock) ObjectIterator(ctx context.Context) (common.Iterator, string, error) {
+	iter, err := b.Iterator(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &walObjectIterator{iter: iter, dec: model.MustNewSegmentDecoder(model.CurrentEncoding)}, model.CurrentEncoding, nil
+}
+
 func (b *backendBlock) RawIterator(ctx context.Context, pool *rowPool) (*rawIterator, error) {
 	pf, r, err := b.open(ctx)
 	if err != nil {
@@ -51,6 +64,41 @@ func (b *backendBlock) RawIterator(ctx context.Context, pool *rowPool) (*rawIter
 	return &rawIterator{b.meta.BlockID.String(), r, traceIDIndex, pool}, nil
 }
 
+var _ common.IDIterable = (*backendBlock)(nil)
+
+// IterateIDs calls cb with the id of every trace in the block. Only the trace id column is read.
+func (b *backendBlock) IterateIDs(ctx context.Context, cb func(id common.ID) error) error {
+	pf, _, err := b.openForSearch(ctx, common.SearchOptions{})
+	if err != nil {
+		return err
+	}
+
+	traceIDIndex, _ := parquetquery.GetColumnIndexByPath(pf, TraceIDColumnName)
+	if traceIDIndex < 0 {
+		return fmt.Errorf("cannot find trace ID column in '%s' in block '%s'", TraceIDColumnName, b.meta.BlockID.String())
+	}
+
+	iter := parquetquery.NewColumnIterator(ctx, pf.RowGroups(), traceIDIndex, TraceIDColumnName, 1000, nil, TraceIDColumnName)
+	defer iter.Close()
+
+	for {
+		res, err := iter.Next()
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("error iterating ids in block %s", b.meta.BlockID.String()))
+		}
+		if res == nil {
+			return nil
+		}
+
+		for _, v := range res.ToMap()[TraceIDColumnName] {
+			err = cb(v.ByteArray())
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
 type blockIterator struct {
 	blockID string
 	r       *parquet.Reader //nolint:all //deprecated
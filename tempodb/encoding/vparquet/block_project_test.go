@@ -0,0 +1,134 @@
+package vparquet
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestBackendBlockProjectSpans(t *testing.T) {
+	intVal := int64(123)
+	strVal := "foo"
+	shadowed := "span"
+	method := "GET"
+
+	tr := &Trace{
+		TraceID: []byte{0x01},
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				ServiceName: "svc",
+				Attrs: []Attribute{
+					{Key: "res.int", ValueInt: &intVal},
+					{Key: "shadowed", Value: &strVal},
+				},
+			},
+			InstrumentationLibrarySpans: []ILS{{
+				Spans: []Span{
+					{
+						ID:             []byte{0x01},
+						Name:           "a",
+						StartUnixNanos: 100,
+						EndUnixNanos:   150,
+						HttpMethod:     &method,
+						Attrs: []Attribute{
+							{Key: "shadowed", Value: &shadowed},
+						},
+					},
+					{
+						ID:             []byte{0x02},
+						Name:           "b",
+						StartUnixNanos: 200,
+						EndUnixNanos:   300,
+					},
+				},
+			}},
+		}},
+	}
+	tr2 := &Trace{
+		TraceID: []byte{0x02},
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				ServiceName: "svc2",
+				Attrs: []Attribute{
+					{Key: "other", ValueInt: &intVal},
+				},
+			},
+			InstrumentationLibrarySpans: []ILS{{
+				Spans: []Span{{
+					ID:             []byte{0x03},
+					Name:           "c",
+					StartUnixNanos: 400,
+					EndUnixNanos:   410,
+					Attrs: []Attribute{
+						{Key: "res.int", Value: &strVal},
+					},
+				}},
+			}},
+		}},
+	}
+	block := makeBackendBlockWithTraces(t, []*Trace{tr, tr2})
+
+	p := common.Projection{
+		TraceID:    true,
+		Duration:   true,
+		Attributes: []string{LabelServiceName, LabelName, LabelHTTPMethod, "res.int", "shadowed"},
+	}
+
+	var actual []common.ProjectedSpan
+	err := block.ProjectSpans(context.Background(), p, func(s *common.ProjectedSpan) error {
+		actual = append(actual, common.ProjectedSpan{
+			TraceID:       append(common.ID(nil), s.TraceID...),
+			DurationNanos: s.DurationNanos,
+			Attributes:    s.Attributes,
+		})
+		return nil
+	}, defaultSearchOptions())
+	require.NoError(t, err)
+
+	require.Equal(t, []common.ProjectedSpan{
+		{
+			TraceID:       common.ID{0x01},
+			DurationNanos: 50,
+			Attributes: map[string]string{
+				LabelServiceName: "svc",
+				LabelName:        "a",
+				LabelHTTPMethod:  "GET",
+				"res.int":        "123",
+				"shadowed":       "span",
+			},
+		},
+		{
+			TraceID:       common.ID{0x01},
+			DurationNanos: 100,
+			Attributes: map[string]string{
+				LabelServiceName: "svc",
+				LabelName:        "b",
+				"res.int":        "123",
+				"shadowed":       "foo",
+			},
+		},
+		{
+			TraceID:       common.ID{0x02},
+			DurationNanos: 10,
+			Attributes: map[string]string{
+				LabelServiceName: "svc2",
+				LabelName:        "c",
+				"res.int":        "foo",
+			},
+		},
+	}, actual)
+
+	// only the start times are read without fields
+	var starts []uint64
+	err = block.ProjectSpans(context.Background(), common.Projection{StartTime: true}, func(s *common.ProjectedSpan) error {
+		require.Empty(t, s.TraceID)
+		require.Empty(t, s.Attributes)
+		starts = append(starts, s.StartTimeUnixNano)
+		return nil
+	}, defaultSearchOptions())
+	require.NoError(t, err)
+	require.Equal(t, []uint64{100, 200, 400}, starts)
+}
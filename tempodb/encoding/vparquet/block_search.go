@@ -33,6 +33,9 @@ var StatusCodeMapping = map[string]int{
 	StatusCodeError: int(v1.Status_STATUS_CODE_ERROR),
 }
 
+// pageReadBufferSize is the size of the reads of the parquet sdk when a block is streamed with readahead
+const pageReadBufferSize = 64 * 1024
+
 // openForSearch consolidates all the logic regarding opening a parquet file in object storage
 func (b *backendBlock) openForSearch(ctx context.Context, opts common.SearchOptions, o ...parquet.FileOption) (*parquet.File, *BackendReaderAt, error) {
 	backendReaderAt := NewBackendReaderAt(ctx, b.r, DataFileName, b.meta.BlockID, b.meta.TenantID)
@@ -42,12 +45,14 @@ func (b *backendBlock) openForSearch(ctx context.Context, opts common.SearchOpti
 
 	// buffering
 	if opts.ReadBufferSize > 0 {
-		//   only use buffered reader at if the block is small, otherwise it's far more effective to use larger
-		//   buffers in the parquet sdk
+		//   small blocks fit in the buffers of the buffered reader at. larger blocks are streamed: the column chunks
+		//   are read in small pages by the parquet sdk and served by the readahead reader at, which bounds the
+		//   memory to the read buffers instead of buffering every column chunk of a row group
 		if opts.ReadBufferCount*opts.ReadBufferSize > int(b.meta.Size) {
 			readerAt = tempo_io.NewBufferedReaderAt(readerAt, int64(b.meta.Size), opts.ReadBufferSize, opts.ReadBufferCount)
 		} else {
-			o = append(o, parquet.ReadBufferSize(opts.ReadBufferSize))
+			readerAt = tempo_io.NewReadaheadReaderAt(readerAt, int64(b.meta.Size), opts.ReadBufferSize, opts.ReadBufferCount)
+			o = append(o, parquet.ReadBufferSize(pageReadBufferSize))
 		}
 	}
 
@@ -216,7 +221,7 @@ func (b *backendBlock) SearchTagValues(ctx context.Context, tag string, cb commo
 	// column
 	column := labelMappings[tag]
 	if column == "" {
-		err = searchStandardTagValues(ctx, tag, pf, cb)
+		err = searchStandardTagValues(ctx, tag, pf, func(_, v string) { cb(v) })
 		if err != nil {
 			return fmt.Errorf("unexpected error searching standard tags: %w", err)
 		}
@@ -230,6 +235,39 @@ func (b *backendBlock) SearchTagValues(ctx context.Context, tag string, cb commo
 	return nil
 }
 
+// SearchTypedTagValues implements common.TypedTagValuesSearcher. Every occurrence of a value is reported with the
+// type of the column it is stored in.
+func (b *backendBlock) SearchTypedTagValues(ctx context.Context, tag string, cb common.TypedTagCallback, opts common.SearchOptions) error {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.SearchTypedTagValues",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+		})
+	defer span.Finish()
+
+	pf, rr, err := b.openForSearch(derivedCtx, opts)
+	if err != nil {
+		return fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+	defer func() { span.SetTag("inspectedBytes", rr.TotalBytesRead.Load()) }()
+
+	column := labelMappings[tag]
+	if column == "" {
+		err = searchStandardTagValues(ctx, tag, pf, cb)
+		if err != nil {
+			return fmt.Errorf("unexpected error searching standard tags: %w", err)
+		}
+		return nil
+	}
+
+	err = searchSpecialTypedTagValues(ctx, column, pf, cb)
+	if err != nil {
+		return fmt.Errorf("unexpected error searching special tags: %w", err)
+	}
+	return nil
+}
+
 func makePipelineWithRowGroups(ctx context.Context, req *tempopb.SearchRequest, pf *parquet.File, rgs []parquet.RowGroup) pq.Iterator {
 	makeIter := makeIterFunc(ctx, rgs, pf)
 
@@ -455,52 +493,75 @@ func rawToResults(ctx context.Context, pf *parquet.File, rgs []parquet.RowGroup,
 }
 
 // searchStandardTagValues searches a parquet file for "standard" tags. i.e. tags that don't have unique
-// columns and are contained in labelMappings
-func searchStandardTagValues(ctx context.Context, tag string, pf *parquet.File, cb common.TagCallback) error {
-	rgs := pf.RowGroups()
-	makeIter := makeIterFunc(ctx, rgs, pf)
+// columns and are contained in labelMappings. Int, double and bool values are reported with their type formatted
+// as strings the same way they are stored in flatbuffer search data.
+func searchStandardTagValues(ctx context.Context, tag string, pf *parquet.File, cb common.TypedTagCallback) error {
+	makeIter := makeIterFunc(ctx, pf.RowGroups(), pf)
 
 	keyPred := pq.NewStringInPredicate([]string{tag})
 
-	iter := pq.NewJoinIterator(DefinitionLevelResourceAttrs, []pq.Iterator{
-		makeIter(FieldResourceAttrKey, keyPred, "keys"),
-		makeIter(FieldResourceAttrVal, nil, "values"),
-	}, nil)
+	iter := newAttrValuesIter(makeIter, DefinitionLevelResourceAttrs, FieldResourceAttrKey, resourceAttrValueColumns, resourceAttrMemberColumns, keyPred, nil)
+	err := reportTagValues(iter, cb)
+	iter.Close()
+	if err != nil {
+		return errors.Wrap(err, "iter.Next on failed on resource lookup")
+	}
+
+	iter = newAttrValuesIter(makeIter, DefinitionLevelResourceSpansILSSpanAttrs, FieldSpanAttrKey, spanAttrValueColumns, spanAttrMemberColumns, keyPred, nil)
+	err = reportTagValues(iter, cb)
+	iter.Close()
+	if err != nil {
+		return errors.Wrap(err, "iter.Next on failed on span lookup")
+	}
+
+	return nil
+}
+
+// reportTagValues drains an attribute iterator built by newAttrValuesIter and passes every non-null value and
+// array member to cb.
+func reportTagValues(iter pq.Iterator, cb common.TypedTagCallback) error {
+	var buffer [][]parquet.Value
 	for {
 		match, err := iter.Next()
 		if err != nil {
-			return errors.Wrap(err, "iter.Next on failed on resource lookup")
+			return err
 		}
 		if match == nil {
-			break
+			return nil
 		}
-		m := match.ToMap()
-		for _, s := range m["values"] {
-			cb(s.String())
+		buffer = match.Columns(buffer, "values", "members")
+		for _, vs := range buffer {
+			for _, v := range vs {
+				if !v.IsNull() {
+					cb(projectedType(v), projectedValue(v))
+				}
+			}
 		}
 	}
-	iter.Close()
+}
 
-	iter = pq.NewJoinIterator(DefinitionLevelResourceSpansILSSpan, []pq.Iterator{
-		makeIter(FieldSpanAttrKey, keyPred, "keys"),
-		makeIter(FieldSpanAttrVal, nil, "values"),
-	}, nil)
+// searchSpecialTypedTagValues reports every value of the provided column with its type. Unlike
+// searchSpecialTagValues it reads all values instead of the dictionaries, so every occurrence is reported.
+func searchSpecialTypedTagValues(ctx context.Context, column string, pf *parquet.File, cb common.TypedTagCallback) error {
+	iter := makeIterFunc(ctx, pf.RowGroups(), pf)(column, nil, "values")
+	defer iter.Close()
+
+	var buffer [][]parquet.Value
 	for {
 		match, err := iter.Next()
 		if err != nil {
-			return errors.Wrap(err, "iter.Next on failed on span lookup")
+			return errors.Wrap(err, "iter.Next failed")
 		}
 		if match == nil {
-			break
+			return nil
 		}
-		m := match.ToMap()
-		for _, s := range m["values"] {
-			cb(s.String())
+		buffer = match.Columns(buffer, "values")
+		for _, v := range buffer[0] {
+			if !v.IsNull() {
+				cb(projectedType(v), projectedValue(v))
+			}
 		}
 	}
-	iter.Close()
-
-	return nil
 }
 
 // searchSpecialTagValues searches a parquet file for all values for the provided column. It first attempts
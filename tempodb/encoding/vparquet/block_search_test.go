@@ -173,13 +173,15 @@ func TestBackendBlockSearch(t *testing.T) {
 		return nil
 	}
 
-	for _, req := range searchesThatMatch {
-		res, err := b.Search(ctx, req, defaultSearchOptions())
-		require.NoError(t, err)
+	for _, opts := range []common.SearchOptions{defaultSearchOptions(), streamingSearchOptions()} {
+		for _, req := range searchesThatMatch {
+			res, err := b.Search(ctx, req, opts)
+			require.NoError(t, err)
 
-		meta := findInResults(expected.TraceID, res.Traces)
-		require.NotNil(t, meta, "search request:", req)
-		require.Equal(t, expected, meta, "search request:", req)
+			meta := findInResults(expected.TraceID, res.Traces)
+			require.NotNil(t, meta, "search request:", req)
+			require.Equal(t, expected, meta, "search request:", req)
+		}
 	}
 
 	// Excludes
@@ -211,11 +213,13 @@ func TestBackendBlockSearch(t *testing.T) {
 		// Span attributes
 		makeReq("foo", "baz"),
 	}
-	for _, req := range searchesThatDontMatch {
-		res, err := b.Search(ctx, req, defaultSearchOptions())
-		require.NoError(t, err)
-		meta := findInResults(expected.TraceID, res.Traces)
-		require.Nil(t, meta, req)
+	for _, opts := range []common.SearchOptions{defaultSearchOptions(), streamingSearchOptions()} {
+		for _, req := range searchesThatDontMatch {
+			res, err := b.Search(ctx, req, opts)
+			require.NoError(t, err)
+			meta := findInResults(expected.TraceID, res.Traces)
+			require.Nil(t, meta, req)
+		}
 	}
 }
 
@@ -276,7 +280,8 @@ func makeBackendBlockWithTraces(t *testing.T, trs []*Trace) *backendBlock {
 	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
 	meta.TotalObjects = 1
 
-	s := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
 
 	for i, tr := range trs {
 		s.Add(tr, 0, 0)
@@ -302,6 +307,15 @@ func defaultSearchOptions() common.SearchOptions {
 	}
 }
 
+// streamingSearchOptions uses read buffers smaller than the test blocks, so the blocks are streamed with readahead
+func streamingSearchOptions() common.SearchOptions {
+	return common.SearchOptions{
+		ChunkSizeBytes:  1_000_000,
+		ReadBufferCount: 2,
+		ReadBufferSize:  4 * 1024,
+	}
+}
+
 func makeTraces() ([]*Trace, map[string]string) {
 	traces := []*Trace{}
 	attrVals := make(map[string]string)
@@ -391,3 +405,84 @@ func makeTraces() ([]*Trace, map[string]string) {
 
 	return traces, attrVals
 }
+
+func TestBackendBlockSearchTagValuesTyped(t *testing.T) {
+	intVal := int64(123)
+	doubleVal := 1.5
+	boolVal := true
+	strVal := "foo"
+
+	tr := &Trace{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				ServiceName: "svc",
+				Attrs: []Attribute{
+					{Key: "res.int", ValueInt: &intVal},
+					{Key: "res.str", Value: &strVal},
+				},
+			},
+			InstrumentationLibrarySpans: []ILS{{
+				Spans: []Span{{
+					ID: make([]byte, 8),
+					Attrs: []Attribute{
+						{Key: "span.double", ValueDouble: &doubleVal},
+						{Key: "span.bool", ValueBool: &boolVal},
+						{Key: "span.mixed", ValueInt: &intVal},
+						{Key: "span.str", Value: &strVal},
+					},
+				}},
+			}},
+		}},
+	}
+	tr2 := &Trace{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				ServiceName: "svc",
+				Attrs: []Attribute{
+					{Key: "res.int", ValueInt: &intVal},
+				},
+			},
+			InstrumentationLibrarySpans: []ILS{{
+				Spans: []Span{{
+					ID: make([]byte, 8),
+					Attrs: []Attribute{
+						{Key: "span.mixed", Value: &strVal},
+					},
+				}},
+			}},
+		}},
+	}
+	block := makeBackendBlockWithTraces(t, []*Trace{tr, tr2})
+
+	tcs := map[string][]string{
+		"res.int":     {"123", "123"},
+		"res.str":     {"foo"},
+		"span.double": {"1.5"},
+		"span.bool":   {"true"},
+		"span.mixed":  {"123", "foo"},
+		"span.str":    {"foo"},
+	}
+	for tag, expected := range tcs {
+		var actual []string
+		err := block.SearchTagValues(context.Background(), tag, func(s string) { actual = append(actual, s) }, defaultSearchOptions())
+		require.NoError(t, err)
+		require.ElementsMatch(t, expected, actual, tag)
+	}
+
+	// typed values have the type of their column and are reported once per occurrence
+	typedTcs := map[string][]string{
+		"res.int":      {"int 123", "int 123"},
+		"res.str":      {"string foo"},
+		"span.double":  {"double 1.5"},
+		"span.bool":    {"bool true"},
+		"span.mixed":   {"int 123", "string foo"},
+		"span.str":     {"string foo"},
+		"service.name": {"string svc", "string svc"},
+	}
+	for tag, expected := range typedTcs {
+		var actual []string
+		err := block.SearchTypedTagValues(context.Background(), tag, func(typ, v string) { actual = append(actual, typ+" "+v) }, defaultSearchOptions())
+		require.NoError(t, err)
+		require.ElementsMatch(t, expected, actual, tag)
+	}
+}
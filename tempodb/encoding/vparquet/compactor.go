@@ -16,6 +16,7 @@ import (
 	"github.com/segmentio/parquet-go"
 
 	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/model/trace"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 )
@@ -56,10 +57,13 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 
 		block := newBackendBlock(blockMeta, r)
 
-		span, derivedCtx := opentracing.StartSpanFromContext(ctx, "vparquet.compactor.iterator")
+		span, derivedCtx := c.opts.StartSpan(ctx, "vparquet.compactor.iterator")
 		defer span.Finish()
 
+		// the download opens the block, its rows are read while merging
+		downloadSpan, _ := c.opts.StartSpan(derivedCtx, "vparquet.compactor.download", opentracing.Tag{Key: "block", Value: blockMeta.BlockID.String()})
 		iter, err := block.RawIterator(derivedCtx, pool)
+		downloadSpan.Finish()
 		if err != nil {
 			return nil, err
 		}
@@ -93,20 +97,12 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 			return rows[0], nil
 		}
 
-		// Total
-		if c.opts.MaxBytesPerTrace > 0 {
-			sum := 0
-			for _, row := range rows {
-				sum += estimateProtoSize(row)
-			}
-			if sum > c.opts.MaxBytesPerTrace {
-				// Trace too large to compact
-				for i := 1; i < len(rows); i++ {
-					c.opts.SpansDiscarded(countSpans(sch, rows[i]))
-					pool.Put(rows[i])
-				}
-				return rows[0], nil
+		// The rows are in the order of the blocks, the last one is from the latest block
+		if c.opts.CombineStrategy == common.CombineStrategyLatestWins {
+			for i := 0; i < len(rows)-1; i++ {
+				pool.Put(rows[i])
 			}
+			return rows[len(rows)-1], nil
 		}
 
 		// Time to combine.
@@ -123,7 +119,26 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 		tr, _ := cmb.Result()
 
 		c.opts.ObjectsCombined(int(compactionLevel), 1)
-		return sch.Deconstruct(pool.Get(), tr), nil
+		row := sch.Deconstruct(pool.Get(), tr)
+
+		if c.opts.CombineStrategy == common.CombineStrategyMergeAll || c.opts.MaxBytesPerTrace == 0 || estimateProtoSize(row) <= c.opts.MaxBytesPerTrace {
+			return row, nil
+		}
+
+		// Trace too large, drop the oldest spans
+		protoTrace := parquetTraceToTempopbTrace(tr)
+		dropped := trace.TruncateOldestSpans(protoTrace, c.opts.MaxBytesPerTrace)
+		if dropped == 0 {
+			return row, nil
+		}
+		c.opts.SpansDiscarded(dropped)
+		if c.opts.TraceTruncated != nil {
+			c.opts.TraceTruncated()
+		}
+
+		truncated := traceToParquet(tr.TraceID, protoTrace)
+		pool.Put(row)
+		return sch.Deconstruct(pool.Get(), &truncated), nil
 	}
 
 	var (
@@ -133,7 +148,21 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 	)
 	defer m.Close()
 
+	// merge spans cover the reading and combining of the traces between two flushes of the output block
+	var mergeSpan opentracing.Span
+	finishMerge := func() {
+		if mergeSpan != nil {
+			mergeSpan.Finish()
+			mergeSpan = nil
+		}
+	}
+	defer finishMerge()
+
 	for {
+		if mergeSpan == nil {
+			mergeSpan, _ = c.opts.StartSpan(ctx, "vparquet.compactor.merge")
+		}
+
 		lowestID, lowestObject, err := m.Next(ctx)
 		if err == io.EOF {
 			break
@@ -143,6 +172,11 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 			return nil, errors.Wrap(err, "error iterating input blocks")
 		}
 
+		if c.opts.DropObject != nil && c.opts.DropObject(lowestID) {
+			pool.Put(lowestObject)
+			continue
+		}
+
 		// make a new block if necessary
 		if currentBlock == nil {
 			// Start with a copy and then customize
@@ -154,7 +188,10 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 			}
 			w := writerCallback(newMeta, time.Now())
 
-			currentBlock = newStreamingBlock(ctx, &c.opts.BlockConfig, newMeta, r, w, tempo_io.NewBufferedWriter)
+			currentBlock, err = newStreamingBlock(ctx, &c.opts.BlockConfig, newMeta, r, w, tempo_io.NewBufferedWriter)
+			if err != nil {
+				return nil, err
+			}
 			currentBlock.meta.CompactionLevel = nextCompactionLevel
 			newCompactedBlocks = append(newCompactedBlocks, currentBlock.meta)
 		}
@@ -162,6 +199,7 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 		// Flush existing block data if the next trace can't fit
 		if currentBlock.EstimatedBufferedBytes() > 0 && currentBlock.EstimatedBufferedBytes()+estimateProtoSize(lowestObject) > c.opts.BlockConfig.RowGroupSizeBytes {
 			runtime.GC()
+			finishMerge()
 			err = c.appendBlock(ctx, currentBlock, l)
 			if err != nil {
 				return nil, errors.Wrap(err, "error writing partial block")
@@ -179,6 +217,7 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 		// Flush again if block is already full.
 		if currentBlock.EstimatedBufferedBytes() > c.opts.BlockConfig.RowGroupSizeBytes {
 			runtime.GC()
+			finishMerge()
 			err = c.appendBlock(ctx, currentBlock, l)
 			if err != nil {
 				return nil, errors.Wrap(err, "error writing partial block")
@@ -192,6 +231,7 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 			currentBlockPtrCopy := currentBlock
 			currentBlockPtrCopy.meta.StartTime = minBlockStart
 			currentBlockPtrCopy.meta.EndTime = maxBlockEnd
+			finishMerge()
 			err := c.finishBlock(ctx, currentBlockPtrCopy, l)
 			if err != nil {
 				return nil, errors.Wrap(err, fmt.Sprintf("error shipping block to backend, blockID %s", currentBlockPtrCopy.meta.BlockID.String()))
@@ -201,6 +241,7 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 	}
 
 	// ship final block to backend
+	finishMerge()
 	if currentBlock != nil {
 		currentBlock.meta.StartTime = minBlockStart
 		currentBlock.meta.EndTime = maxBlockEnd
@@ -214,7 +255,7 @@ func (c *Compactor) Compact(ctx context.Context, l log.Logger, r backend.Reader,
 }
 
 func (c *Compactor) appendBlock(ctx context.Context, block *streamingBlock, l log.Logger) error {
-	span, _ := opentracing.StartSpanFromContext(ctx, "vparquet.compactor.appendBlock")
+	span, _ := c.opts.StartSpan(ctx, "vparquet.compactor.appendBlock", opentracing.Tag{Key: "phase", Value: "encode"})
 	defer span.Finish()
 
 	var (
@@ -242,7 +283,7 @@ func (c *Compactor) appendBlock(ctx context.Context, block *streamingBlock, l lo
 }
 
 func (c *Compactor) finishBlock(ctx context.Context, block *streamingBlock, l log.Logger) error {
-	span, _ := opentracing.StartSpanFromContext(ctx, "vparquet.compactor.finishBlock")
+	span, _ := c.opts.StartSpan(ctx, "vparquet.compactor.finishBlock", opentracing.Tag{Key: "phase", Value: "upload"})
 	defer span.Finish()
 
 	bytesFlushed, err := block.Complete()
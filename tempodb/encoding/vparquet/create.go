@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"sort"
+	"sync"
 
 	"github.com/google/uuid"
 	tempo_io "github.com/grafana/tempo/pkg/io"
@@ -35,28 +37,87 @@ func (b *backendWriter) Close() error {
 	return b.w.CloseAppend(b.ctx, b.tracker)
 }
 
+// encodeBatchSize is the number of objects read from the iterator and encoded at once if encoding is concurrent.
+const encodeBatchSize = 1000
+
+// encodeItem is an object of a batch and the trace it is encoded to.
+type encodeItem struct {
+	id  common.ID
+	obj []byte
+	tr  Trace
+}
+
 func CreateBlock(ctx context.Context, cfg *common.BlockConfig, meta *backend.BlockMeta, i common.Iterator, dec model.ObjectDecoder, r backend.Reader, to backend.Writer) (*backend.BlockMeta, error) {
-	s := newStreamingBlock(ctx, cfg, meta, r, to, tempo_io.NewBufferedWriter)
+	s, err := newStreamingBlock(ctx, cfg, meta, r, to, tempo_io.NewBufferedWriter)
+	if err != nil {
+		return nil, err
+	}
+
+	// wal blocks in the parquet format are completed without decoding the objects again
+	if wi, ok := i.(*walObjectIterator); ok {
+		return createBlockFromTraces(ctx, cfg, s, wi.iter)
+	}
+
+	// objects are decoded and converted to parquet in batches, the batch is added to the block in order.
+	concurrency := cfg.EncodeConcurrency
+	batchSize := encodeBatchSize
+	if concurrency <= 1 {
+		concurrency = 1
+		batchSize = 1
+	}
+	batch := make([]encodeItem, 0, batchSize)
 
 	for {
-		id, obj, err := i.Next(ctx)
-		if err == io.EOF {
+		batch, err = readBatch(ctx, i, batch[:0], batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
 			break
 		}
 
-		tr, err := dec.PrepareForRead(obj)
+		err = encodeBatch(batch, dec, concurrency)
 		if err != nil {
 			return nil, err
 		}
 
-		// Copy ID to allow it to escape the iterator.
-		id = append([]byte(nil), id...)
+		for j := range batch {
+			s.Add(&batch[j].tr, 0, 0) // start and end time of the wal meta are used.
+
+			// Here we repurpose RowGroupSizeBytes as number of raw column values.
+			// This is a fairly close approximation.
+			if s.EstimatedBufferedBytes() > cfg.RowGroupSizeBytes {
+				_, err = s.Flush()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// the traces are referenced by the block until they are flushed
+		batch = make([]encodeItem, 0, batchSize)
+	}
+
+	_, err = s.Complete()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.meta, nil
+}
 
-		trp := traceToParquet(id, tr)
-		s.Add(&trp, 0, 0) // start and end time of the wal meta are used.
+func createBlockFromTraces(ctx context.Context, cfg *common.BlockConfig, s *streamingBlock, i Iterator) (*backend.BlockMeta, error) {
+	for {
+		tr, err := i.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if tr == nil {
+			break
+		}
+
+		s.Add(tr, 0, 0) // start and end time of the wal meta are used.
 
-		// Here we repurpose RowGroupSizeBytes as number of raw column values.
-		// This is a fairly close approximation.
 		if s.EstimatedBufferedBytes() > cfg.RowGroupSizeBytes {
 			_, err = s.Flush()
 			if err != nil {
@@ -73,6 +134,75 @@ func CreateBlock(ctx context.Context, cfg *common.BlockConfig, meta *backend.Blo
 	return s.meta, nil
 }
 
+// readBatch reads up to size objects from the iterator. Objects are copied if more than one is read, the
+// iterator may reuse their buffers.
+func readBatch(ctx context.Context, i common.Iterator, batch []encodeItem, size int) ([]encodeItem, error) {
+	for len(batch) < size {
+		id, obj, err := i.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if size > 1 {
+			obj = append([]byte(nil), obj...)
+		}
+
+		// Copy ID to allow it to escape the iterator.
+		batch = append(batch, encodeItem{
+			id:  append([]byte(nil), id...),
+			obj: obj,
+		})
+	}
+	return batch, nil
+}
+
+// encodeBatch decodes the objects of the batch and converts them to parquet traces using up to concurrency
+// goroutines.
+func encodeBatch(batch []encodeItem, dec model.ObjectDecoder, concurrency int) error {
+	encode := func(items []encodeItem) error {
+		for j := range items {
+			tr, err := dec.PrepareForRead(items[j].obj)
+			if err != nil {
+				return err
+			}
+			items[j].tr = traceToParquet(items[j].id, tr)
+			items[j].obj = nil
+		}
+		return nil
+	}
+
+	if concurrency == 1 || len(batch) == 1 {
+		return encode(batch)
+	}
+
+	chunk := (len(batch) + concurrency - 1) / concurrency
+	errs := make([]error, concurrency)
+	wg := sync.WaitGroup{}
+	for c := 0; c < concurrency && c*chunk < len(batch); c++ {
+		end := (c + 1) * chunk
+		if end > len(batch) {
+			end = len(batch)
+		}
+
+		wg.Add(1)
+		go func(c int, items []encodeItem) {
+			defer wg.Done()
+			errs[c] = encode(items)
+		}(c, batch[c*chunk:end])
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type streamingBlock struct {
 	ctx   context.Context
 	bloom *common.ShardedBloomFilter
@@ -86,32 +216,55 @@ type streamingBlock struct {
 	bufferedTraces        []*Trace
 	currentBufferedTraces int
 	currentBufferedBytes  int
+
+	// serviceNames are the services of the traces added to the block. nil if they aren't recorded or the block
+	// has more than the max service names.
+	serviceNames      map[string]struct{}
+	maxServiceNames   int
+	serviceNameColumn int
 }
 
-func newStreamingBlock(ctx context.Context, cfg *common.BlockConfig, meta *backend.BlockMeta, r backend.Reader, to backend.Writer, createBufferedWriter func(w io.Writer) tempo_io.BufferedWriteFlusher) *streamingBlock {
+func newStreamingBlock(ctx context.Context, cfg *common.BlockConfig, meta *backend.BlockMeta, r backend.Reader, to backend.Writer, createBufferedWriter func(w io.Writer) tempo_io.BufferedWriteFlusher) (*streamingBlock, error) {
 	newMeta := backend.NewBlockMeta(meta.TenantID, meta.BlockID, VersionString, backend.EncNone, "")
 	newMeta.StartTime = meta.StartTime
 	newMeta.EndTime = meta.EndTime
+	newMeta.BloomFP = cfg.BloomFP
 
 	// TotalObjects is used here an an estimated count for the bloom filter.
 	// The real number of objects is tracked below.
-	bloom := common.NewBloom(cfg.BloomFP, uint(cfg.BloomShardSizeBytes), uint(meta.TotalObjects))
+	bloom := common.NewAdaptiveBloom(cfg.BloomFP, uint(cfg.BloomShardSizeBytes), uint(meta.TotalObjects))
+
+	opts, err := writerOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	w := &backendWriter{ctx, to, DataFileName, meta.BlockID, meta.TenantID, nil}
 	bw := createBufferedWriter(w)
-	pw := parquet.NewGenericWriter[*Trace](bw)
-
-	return &streamingBlock{
-		ctx:            ctx,
-		meta:           newMeta,
-		bloom:          bloom,
-		bw:             bw,
-		pw:             pw,
-		w:              w,
-		r:              r,
-		to:             to,
-		bufferedTraces: make([]*Trace, 0, 1000),
+	pw := parquet.NewGenericWriter[*Trace](bw, opts...)
+
+	s := &streamingBlock{
+		ctx:               ctx,
+		meta:              newMeta,
+		bloom:             bloom,
+		bw:                bw,
+		pw:                pw,
+		w:                 w,
+		r:                 r,
+		to:                to,
+		bufferedTraces:    make([]*Trace, 0, 1000),
+		maxServiceNames:   cfg.MaxServiceNames,
+		serviceNameColumn: -1,
+	}
+
+	if cfg.MaxServiceNames > 0 {
+		s.serviceNames = map[string]struct{}{}
+		if col, found := pw.Schema().Lookup("rs", "Resource", "ServiceName"); found {
+			s.serviceNameColumn = col.ColumnIndex
+		}
 	}
+
+	return s, nil
 }
 
 func (b *streamingBlock) Add(tr *Trace, start, end uint32) {
@@ -122,6 +275,12 @@ func (b *streamingBlock) Add(tr *Trace, start, end uint32) {
 	b.meta.ObjectAdded(id, start, end)
 	b.currentBufferedTraces++
 	b.currentBufferedBytes += estimateTraceSize(tr)
+
+	if b.serviceNames != nil {
+		for _, rs := range tr.ResourceSpans {
+			b.addServiceName(rs.Resource.ServiceName)
+		}
+	}
 }
 
 func (b *streamingBlock) AddRaw(id []byte, row parquet.Row, start, end uint32) error {
@@ -135,9 +294,30 @@ func (b *streamingBlock) AddRaw(id []byte, row parquet.Row, start, end uint32) e
 	b.currentBufferedTraces++
 	b.currentBufferedBytes += estimateProtoSize(row)
 
+	if b.serviceNames != nil && b.serviceNameColumn >= 0 {
+		for _, v := range row {
+			if v.Column() == b.serviceNameColumn && !v.IsNull() {
+				b.addServiceName(string(v.ByteArray()))
+			}
+		}
+	}
+
 	return nil
 }
 
+// addServiceName records a service of the block. Once the block has more than the max service names they are no
+// longer recorded.
+func (b *streamingBlock) addServiceName(name string) {
+	if b.serviceNames == nil || name == "" {
+		return
+	}
+
+	b.serviceNames[name] = struct{}{}
+	if len(b.serviceNames) > b.maxServiceNames {
+		b.serviceNames = nil
+	}
+}
+
 func (b *streamingBlock) EstimatedBufferedBytes() int {
 	return b.currentBufferedBytes
 }
@@ -218,6 +398,14 @@ func (b *streamingBlock) Complete() (int, error) {
 
 	b.meta.BloomShardCount = uint16(b.bloom.GetShardCount())
 
+	if len(b.serviceNames) > 0 {
+		b.meta.ServiceNames = make([]string, 0, len(b.serviceNames))
+		for name := range b.serviceNames {
+			b.meta.ServiceNames = append(b.meta.ServiceNames, name)
+		}
+		sort.Strings(b.meta.ServiceNames)
+	}
+
 	return n, writeBlockMeta(b.ctx, b.to, b.meta, b.bloom)
 }
 
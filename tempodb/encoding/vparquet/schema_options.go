@@ -0,0 +1,131 @@
+package vparquet
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/parquet-go"
+	"github.com/segmentio/parquet-go/compress"
+	"github.com/segmentio/parquet-go/encoding"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var (
+	compressionCodecs = map[string]compress.Codec{
+		"none":   &parquet.Uncompressed,
+		"snappy": &parquet.Snappy,
+		"gzip":   &parquet.Gzip,
+		"zstd":   &parquet.Zstd,
+		"lz4":    &parquet.Lz4Raw,
+		"brotli": &parquet.Brotli,
+	}
+
+	// schemas caches the trace schema for every combination of options
+	schemas sync.Map // map[schemaOptions]*parquet.Schema
+)
+
+type schemaOptions struct {
+	compression       string
+	disableDictionary bool
+}
+
+// writerOptions returns the parquet writer options for the given block config. The codecs and encodings of the
+// columns are part of the schema so any override requires a modified schema.
+func writerOptions(cfg *common.BlockConfig) ([]parquet.WriterOption, error) {
+	var opts []parquet.WriterOption
+
+	if cfg.ParquetCompression != "" || cfg.ParquetDisableDictionary {
+		sch, err := schemaWithOptions(schemaOptions{
+			compression:       cfg.ParquetCompression,
+			disableDictionary: cfg.ParquetDisableDictionary,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sch)
+	}
+
+	if cfg.ParquetPageSizeBytes > 0 {
+		opts = append(opts, parquet.PageBufferSize(cfg.ParquetPageSizeBytes))
+	}
+
+	return opts, nil
+}
+
+func schemaWithOptions(o schemaOptions) (*parquet.Schema, error) {
+	if sch, ok := schemas.Load(o); ok {
+		return sch.(*parquet.Schema), nil
+	}
+
+	var codec compress.Codec
+	if o.compression != "" {
+		var ok bool
+		codec, ok = compressionCodecs[o.compression]
+		if !ok {
+			return nil, fmt.Errorf("unsupported parquet compression %s", o.compression)
+		}
+	}
+
+	base := parquet.SchemaOf(new(Trace))
+	opts := &nodeOptions{codec: codec, disableDictionary: o.disableDictionary}
+	sch := parquet.NewSchema(base.Name(), optionsNode{Node: base, opts: opts})
+
+	actual, _ := schemas.LoadOrStore(o, sch)
+	return actual.(*parquet.Schema), nil
+}
+
+// nodeOptions overrides the codec and encoding of the leaf columns of a schema. The structure, and therefore the
+// go types and column paths, of the schema is unchanged.
+type nodeOptions struct {
+	codec             compress.Codec
+	disableDictionary bool
+}
+
+func (o *nodeOptions) compression(n parquet.Node) compress.Codec {
+	if n.Leaf() && o.codec != nil {
+		return o.codec
+	}
+	return n.Compression()
+}
+
+func (o *nodeOptions) encoding(n parquet.Node) encoding.Encoding {
+	enc := n.Encoding()
+	if n.Leaf() && o.disableDictionary && enc != nil && isDictionaryEncoding(enc) {
+		// nil falls back to the default encoding of the column type
+		return nil
+	}
+	return enc
+}
+
+func (o *nodeOptions) fields(n parquet.Node) []parquet.Field {
+	fields := n.Fields()
+	wrapped := make([]parquet.Field, 0, len(fields))
+	for _, f := range fields {
+		wrapped = append(wrapped, optionsField{Field: f, opts: o})
+	}
+	return wrapped
+}
+
+func isDictionaryEncoding(enc encoding.Encoding) bool {
+	e := enc.Encoding()
+	return e == parquet.PlainDictionary.Encoding() || e == parquet.RLEDictionary.Encoding()
+}
+
+type optionsNode struct {
+	parquet.Node
+	opts *nodeOptions
+}
+
+func (n optionsNode) Compression() compress.Codec { return n.opts.compression(n.Node) }
+func (n optionsNode) Encoding() encoding.Encoding { return n.opts.encoding(n.Node) }
+func (n optionsNode) Fields() []parquet.Field     { return n.opts.fields(n.Node) }
+
+type optionsField struct {
+	parquet.Field
+	opts *nodeOptions
+}
+
+func (f optionsField) Compression() compress.Codec { return f.opts.compression(f.Field) }
+func (f optionsField) Encoding() encoding.Encoding { return f.opts.encoding(f.Field) }
+func (f optionsField) Fields() []parquet.Field     { return f.opts.fields(f.Field) }
@@ -0,0 +1,72 @@
+package vparquet
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/segmentio/parquet-go"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var _ common.Verifiable = (*backendBlock)(nil)
+
+// Verify reads every page of the parquet file of the block and checks its footer against the meta.
+func (b *backendBlock) Verify(ctx context.Context) ([]common.VerifyProblem, error) {
+	var problems []common.VerifyProblem
+	fatal := func(format string, args ...interface{}) ([]common.VerifyProblem, error) {
+		return append(problems, common.VerifyProblem{Fatal: true, Msg: DataFileName + ": " + fmt.Sprintf(format, args...)}), ctx.Err()
+	}
+
+	if b.meta.Size < 8 {
+		return fatal("size %d of the meta is too small for a parquet file", b.meta.Size)
+	}
+
+	trailer := make([]byte, 8)
+	if _, err := NewBackendReaderAt(ctx, b.r, DataFileName, b.meta.BlockID, b.meta.TenantID).ReadAt(trailer, int64(b.meta.Size)-8); err != nil {
+		return fatal("error reading footer: %v", err)
+	}
+	if string(trailer[4:]) != "PAR1" {
+		return fatal("no parquet footer at the size %d of the meta", b.meta.Size)
+	}
+
+	// blocks written before the footer size was added to the meta have none
+	if actual := binary.LittleEndian.Uint32(trailer); b.meta.FooterSize > 0 && actual != b.meta.FooterSize {
+		problems = append(problems, common.VerifyProblem{Msg: fmt.Sprintf("%s: footer size is %d, %d in the meta", DataFileName, actual, b.meta.FooterSize)})
+	}
+
+	pf, _, err := b.open(ctx)
+	if err != nil {
+		return fatal("error opening parquet file: %v", err)
+	}
+
+	columns := pf.Schema().Columns()
+	for i, rg := range pf.RowGroups() {
+		for j, chunk := range rg.ColumnChunks() {
+			if err := readPages(chunk); err != nil {
+				return fatal("row group %d column %s: error reading page: %v", i, strings.Join(columns[j], "."), err)
+			}
+		}
+	}
+
+	return problems, ctx.Err()
+}
+
+func readPages(chunk parquet.ColumnChunk) error {
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	for {
+		_, err := pages.ReadPage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package vparquet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/model"
+	v2 "github.com/grafana/tempo/pkg/model/v2"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestWALBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "block")
+	b, err := CreateWALBlock(path, v2.Encoding, false)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(v2.Encoding)
+	objDec := model.MustNewObjectDecoder(v2.Encoding)
+
+	// every trace is appended in two parts with a flush in between for some of them
+	traces := map[string]*tempopb.Trace{}
+	var ids []common.ID
+	for i := 0; i < 50; i++ {
+		id := test.ValidTraceID(nil)
+		ids = append(ids, id)
+
+		first := test.MakeTrace(2, id)
+		second := test.MakeTrace(1, id)
+		traces[string(id)] = &tempopb.Trace{Batches: append(append([]*v1_trace.ResourceSpans{}, first.Batches...), second.Batches...)}
+
+		require.NoError(t, b.Append(id, makeWALObject(t, dec, first)))
+		if i%2 == 0 {
+			require.NoError(t, b.Flush())
+		}
+		require.NoError(t, b.Append(id, makeWALObject(t, dec, second)))
+	}
+	require.Greater(t, b.DataLength(), uint64(0))
+	flushes := len(b.flushed)
+
+	// find
+	for _, id := range ids {
+		obj, err := b.Find(context.Background(), id)
+		require.NoError(t, err)
+		requireWALTrace(t, objDec, id, traces[string(id)], obj)
+	}
+	obj, err := b.Find(context.Background(), test.ValidTraceID(nil))
+	require.NoError(t, err)
+	require.Nil(t, obj)
+
+	// objects are iterated in order of their ids
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i], ids[j]) == -1
+	})
+	iter, err := b.ObjectIterator(context.Background())
+	require.NoError(t, err)
+	for _, expectedID := range ids {
+		id, obj, err := iter.Next(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expectedID, id)
+		requireWALTrace(t, objDec, id, traces[string(id)], obj)
+	}
+	_, _, err = iter.Next(context.Background())
+	require.Equal(t, io.EOF, err)
+	iter.Close()
+
+	// search
+	resp, err := b.Search(context.Background(), &tempopb.SearchRequest{
+		Tags: map[string]string{LabelServiceName: "test-service"},
+	}, common.SearchOptions{})
+	require.NoError(t, err)
+	found := map[string]struct{}{}
+	for _, tr := range resp.Traces {
+		found[tr.TraceID] = struct{}{}
+	}
+	require.Len(t, found, len(ids))
+	require.Len(t, resp.Traces, len(ids))
+
+	// reads don't flush the buffer
+	require.Len(t, b.flushed, flushes)
+	require.NotEmpty(t, b.buffer)
+
+	require.NoError(t, b.Clear())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestWALBlockSearchCombinesParts(t *testing.T) {
+	b, err := CreateWALBlock(filepath.Join(t.TempDir(), "block"), v2.Encoding, false)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(v2.Encoding)
+
+	// the trace is split across two files and the buffer, only the combined trace matches the search
+	id := test.ValidTraceID(nil)
+	makePart := func(service, name string) []byte {
+		tr := test.MakeTrace(1, id)
+		tr.Batches[0].Resource.Attributes[0].Value.Value = &v1_common.AnyValue_StringValue{StringValue: service}
+		for _, ils := range tr.Batches[0].InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				s.Name = name
+			}
+		}
+		return makeWALObject(t, dec, tr)
+	}
+	require.NoError(t, b.Append(id, makePart("svc-a", "first")))
+	require.NoError(t, b.Flush())
+	require.NoError(t, b.Append(id, makePart("svc-b", "second")))
+	require.NoError(t, b.Flush())
+	require.NoError(t, b.Append(id, makePart("svc-c", "third")))
+
+	other := test.ValidTraceID(nil)
+	require.NoError(t, b.Append(other, makeWALObject(t, dec, test.MakeTrace(1, other))))
+
+	for _, tags := range []map[string]string{
+		{LabelServiceName: "svc-a", LabelName: "third"},
+		{LabelServiceName: "svc-c", LabelName: "second"},
+	} {
+		resp, err := b.Search(context.Background(), &tempopb.SearchRequest{Tags: tags}, common.SearchOptions{})
+		require.NoError(t, err)
+		require.Len(t, resp.Traces, 1)
+		require.Equal(t, util.TraceIDToHexString(id), resp.Traces[0].TraceID)
+		require.Equal(t, uint32(2), resp.Metrics.InspectedTraces)
+	}
+
+	resp, err := b.Search(context.Background(), &tempopb.SearchRequest{
+		Tags: map[string]string{LabelServiceName: "svc-a", LabelName: "fourth"},
+	}, common.SearchOptions{})
+	require.NoError(t, err)
+	require.Empty(t, resp.Traces)
+
+	// the buffered parts are unchanged by the reads
+	require.Len(t, b.flushed, 2)
+	require.Len(t, b.buffer, 2)
+}
+
+func TestWALBlockAppendBatch(t *testing.T) {
+	b, err := CreateWALBlock(filepath.Join(t.TempDir(), "block"), v2.Encoding, false)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(v2.Encoding)
+	objDec := model.MustNewObjectDecoder(v2.Encoding)
+
+	ids := []common.ID{test.ValidTraceID(nil), test.ValidTraceID(nil)}
+	traces := []*tempopb.Trace{test.MakeTrace(2, ids[0]), test.MakeTrace(2, ids[1])}
+	objs := [][]byte{makeWALObject(t, dec, traces[0]), makeWALObject(t, dec, traces[1])}
+
+	// a batch with an invalid object appends none of its objects
+	err = b.AppendBatch(append(ids, test.ValidTraceID(nil)), append(objs, []byte{0x01, 0x02}))
+	require.Error(t, err)
+	require.Equal(t, 0, b.Length())
+
+	require.NoError(t, b.AppendBatch(ids, objs))
+	require.Equal(t, len(ids), b.Length())
+	for i, id := range ids {
+		obj, err := b.Find(context.Background(), id)
+		require.NoError(t, err)
+		requireWALTrace(t, objDec, id, traces[i], obj)
+	}
+}
+
+func TestWALBlockAppendTraceBatch(t *testing.T) {
+	b, err := CreateWALBlock(filepath.Join(t.TempDir(), "block"), v2.Encoding, false)
+	require.NoError(t, err)
+
+	objDec := model.MustNewObjectDecoder(v2.Encoding)
+
+	ids := []common.ID{test.ValidTraceID(nil), test.ValidTraceID(nil)}
+	traces := []*tempopb.Trace{test.MakeTrace(2, ids[0]), test.MakeTrace(2, ids[1])}
+
+	err = b.AppendTraceBatch(ids, traces[:1])
+	require.Error(t, err)
+	require.Equal(t, 0, b.Length())
+
+	// the traces are found like appended objects
+	require.NoError(t, b.AppendTraceBatch(ids, traces))
+	require.Equal(t, len(ids), b.Length())
+	for i, id := range ids {
+		obj, err := b.Find(context.Background(), id)
+		require.NoError(t, err)
+		requireWALTrace(t, objDec, id, traces[i], obj)
+	}
+}
+
+func TestWALBlockSync(t *testing.T) {
+	// the block doesn't sync its flushes, Sync syncs them anyway
+	b, err := CreateWALBlock(filepath.Join(t.TempDir(), "block"), v2.Encoding, false)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(v2.Encoding)
+	id := test.ValidTraceID(nil)
+	require.NoError(t, b.Append(id, makeWALObject(t, dec, test.MakeTrace(2, id))))
+	require.NoError(t, b.Flush())
+	require.NoError(t, b.Append(id, makeWALObject(t, dec, test.MakeTrace(1, id))))
+
+	require.NoError(t, b.Sync())
+	require.Len(t, b.flushed, 2)
+	for _, fl := range b.flushed {
+		require.True(t, fl.synced)
+	}
+	require.False(t, b.unsyncedDir)
+	require.Empty(t, b.buffer)
+}
+
+func TestOpenWALBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "block")
+	b, err := CreateWALBlock(path, v2.Encoding, true)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(v2.Encoding)
+	objDec := model.MustNewObjectDecoder(v2.Encoding)
+
+	traces := map[string]*tempopb.Trace{}
+	for i := 0; i < 10; i++ {
+		id := test.ValidTraceID(nil)
+		tr := test.MakeTrace(2, id)
+		traces[string(id)] = tr
+
+		require.NoError(t, b.Append(id, makeWALObject(t, dec, tr)))
+		require.NoError(t, b.Flush())
+	}
+
+	// a partially flushed file is removed on replay
+	partial := filepath.Join(path, "9999999999")
+	require.NoError(t, os.WriteFile(partial, []byte{0x01, 0x02, 0x03}, 0644))
+
+	replayed := 0
+	b, warning, err := OpenWALBlock(path, v2.Encoding, true, func(id common.ID, start, end uint32) {
+		require.Contains(t, traces, string(id))
+		require.NotZero(t, start)
+		require.GreaterOrEqual(t, end, start)
+		replayed++
+	})
+	require.NoError(t, err)
+	require.Error(t, warning)
+	require.Equal(t, len(traces), replayed)
+	require.Equal(t, len(traces), b.Length())
+	_, err = os.Stat(partial)
+	require.True(t, os.IsNotExist(err))
+
+	for id, tr := range traces {
+		obj, err := b.Find(context.Background(), []byte(id))
+		require.NoError(t, err)
+		requireWALTrace(t, objDec, []byte(id), tr, obj)
+	}
+
+	// the replayed block can be appended to
+	id := test.ValidTraceID(nil)
+	require.NoError(t, b.Append(id, makeWALObject(t, dec, test.MakeTrace(1, id))))
+	require.NoError(t, b.Flush())
+	require.Equal(t, len(traces)+1, b.Length())
+}
+
+func makeWALObject(t *testing.T, dec model.SegmentDecoder, tr *tempopb.Trace) []byte {
+	segment, err := dec.PrepareForWrite(tr, 0, 0)
+	require.NoError(t, err)
+	obj, err := dec.ToObject([][]byte{segment})
+	require.NoError(t, err)
+	return obj
+}
+
+// requireWALTrace compares the traces in the parquet schema, the proto trace isn't preserved exactly
+func requireWALTrace(t *testing.T, dec model.ObjectDecoder, id common.ID, expected *tempopb.Trace, obj []byte) {
+	actual, err := dec.PrepareForRead(obj)
+	require.NoError(t, err)
+
+	expectedTrace := traceToParquet(id, expected)
+	actualTrace := traceToParquet(id, actual)
+	SortTrace(&expectedTrace)
+	SortTrace(&actualTrace)
+	require.Equal(t, expectedTrace, actualTrace)
+}
@@ -0,0 +1,34 @@
+package vparquet2
+
+import (
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+const (
+	DataFileName = "data.parquet"
+
+	// HotFileName and ColdFileName are the files of blocks with split columns, see BlockConfig.ParquetSplitHotColumns
+	HotFileName  = "hot.parquet"
+	ColdFileName = "cold.parquet"
+)
+
+type backendBlock struct {
+	meta *backend.BlockMeta
+	r    backend.Reader
+}
+
+var _ common.BackendBlock = (*backendBlock)(nil)
+
+func newBackendBlock(meta *backend.BlockMeta, r backend.Reader) *backendBlock {
+	return &backendBlock{meta, r}
+}
+
+func (b *backendBlock) BlockMeta() *backend.BlockMeta {
+	return b.meta
+}
+
+// split returns true if the columns of the block are split in a hot and a cold file
+func (b *backendBlock) split() bool {
+	return b.meta.HotSize > 0
+}
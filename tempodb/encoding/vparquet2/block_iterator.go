@@ -0,0 +1,187 @@
+package vparquet2
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/parquet-go"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// rowReader reads the rows of a block in the trace schema
+type rowReader interface {
+	parquet.RowReader
+	Read(row interface{}) error
+	SeekToRow(rowIndex int64) error
+	Schema() *parquet.Schema
+	Close() error
+}
+
+var (
+	_ rowReader = (*parquet.Reader)(nil) //nolint:all //deprecated
+	_ rowReader = (*splitReader)(nil)
+)
+
+func (b *backendBlock) open(ctx context.Context) (*parquet.File, rowReader, error) {
+	if b.split() {
+		return b.openSplit(ctx)
+	}
+
+	pf, err := b.openBuffered(ctx, DataFileName, b.meta.Size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := parquet.NewReader(pf, parquet.SchemaOf(&Trace{}))
+	return pf, r, nil
+}
+
+func (b *backendBlock) openBuffered(ctx context.Context, name string, size uint64) (*parquet.File, error) {
+	rr := NewBackendReaderAt(ctx, b.r, name, b.meta.BlockID, b.meta.TenantID)
+
+	// 128 MB memory buffering
+	br := tempo_io.NewBufferedReaderAt(rr, int64(size), 2*1024*1024, 64)
+
+	return parquet.OpenFile(br, int64(size))
+}
+
+func (b *backendBlock) Iterator(ctx context.Context) (Iterator, error) {
+	_, r, err := b.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blockIterator{blockID: b.meta.BlockID.String(), r: r}, nil
+}
+
+var _ common.ObjectIterable = (*backendBlock)(nil)
+
+// ObjectIterator returns the traces of the block as objects of the current data encoding
+func (b *backendBlock) ObjectIterator(ctx context.Context) (common.Iterator, string, error) {
+	iter, err := b.Iterator(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &walObjectIterator{iter: iter, dec: model.MustNewSegmentDecoder(model.CurrentEncoding)}, model.CurrentEncoding, nil
+}
+
+func (b *backendBlock) RawIterator(ctx context.Context, pool *rowPool) (*rawIterator, error) {
+	_, r, err := b.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	traceIDColumn, found := r.Schema().Lookup(TraceIDColumnName)
+	if !found {
+		return nil, fmt.Errorf("cannot find trace ID column in '%s' in block '%s'", TraceIDColumnName, b.meta.BlockID.String())
+	}
+
+	return &rawIterator{
+		blockID:      b.meta.BlockID.String(),
+		r:            r,
+		traceIDIndex: traceIDColumn.ColumnIndex,
+		pool:         pool,
+	}, nil
+}
+
+var _ common.IDIterable = (*backendBlock)(nil)
+
+// IterateIDs calls cb with the id of every trace in the block. Only the trace id column is read.
+func (b *backendBlock) IterateIDs(ctx context.Context, cb func(id common.ID) error) error {
+	pf, err := b.openFile(ctx, common.SearchOptions{})
+	if err != nil {
+		return err
+	}
+
+	rgs, traceIDIndex, err := pf.column(TraceIDColumnName)
+	if err != nil {
+		return err
+	}
+	if traceIDIndex < 0 {
+		return fmt.Errorf("cannot find trace ID column in '%s' in block '%s'", TraceIDColumnName, b.meta.BlockID.String())
+	}
+
+	iter := parquetquery.NewColumnIterator(ctx, rgs, traceIDIndex, TraceIDColumnName, 1000, nil, TraceIDColumnName)
+	defer iter.Close()
+
+	for {
+		res, err := iter.Next()
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("error iterating ids in block %s", b.meta.BlockID.String()))
+		}
+		if res == nil {
+			return nil
+		}
+
+		for _, v := range res.ToMap()[TraceIDColumnName] {
+			err = cb(v.ByteArray())
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+type blockIterator struct {
+	blockID string
+	r       rowReader
+}
+
+func (i *blockIterator) Next(context.Context) (*Trace, error) {
+	t := &Trace{}
+	switch err := i.r.Read(t); err {
+	case nil:
+		return t, nil
+	case io.EOF:
+		return nil, nil
+	default:
+		return nil, errors.Wrap(err, fmt.Sprintf("error iterating through block %s", i.blockID))
+	}
+}
+
+func (i *blockIterator) Close() {
+	// parquet reader is shared, lets not close it here
+}
+
+type rawIterator struct {
+	blockID      string
+	r            rowReader
+	traceIDIndex int
+	pool         *rowPool
+}
+
+var _ RawIterator = (*rawIterator)(nil)
+
+func (i *rawIterator) getTraceID(r parquet.Row) common.ID {
+	for _, v := range r {
+		if v.Column() == i.traceIDIndex {
+			return v.ByteArray()
+		}
+	}
+	return nil
+}
+
+func (i *rawIterator) Next(context.Context) (common.ID, parquet.Row, error) {
+	rows := []parquet.Row{i.pool.Get()}
+	n, err := i.r.ReadRows(rows)
+	if n > 0 {
+		return i.getTraceID(rows[0]), rows[0], nil
+	}
+
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+
+	return nil, nil, errors.Wrap(err, fmt.Sprintf("error iterating through block %s", i.blockID))
+}
+
+func (i *rawIterator) Close() {
+	i.r.Close()
+}
@@ -0,0 +1,89 @@
+package vparquet2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/parquet-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+)
+
+func TestIteratorReadsAllRows(t *testing.T) {
+	rawR, _, _, err := local.New(&local.Config{
+		Path: "./test-data",
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	ctx := context.Background()
+
+	blocks, err := r.Blocks(ctx, "single-tenant")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+
+	meta, err := r.BlockMeta(ctx, blocks[0], "single-tenant")
+	require.NoError(t, err)
+
+	b := newBackendBlock(meta, r)
+
+	iter, err := b.Iterator(context.Background())
+	require.NoError(t, err)
+	defer iter.Close()
+
+	actualCount := 0
+	for {
+		tr, err := iter.Next(context.Background())
+		if tr == nil {
+			break
+		}
+		actualCount++
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, meta.TotalObjects, actualCount)
+}
+
+func TestRawIteratorReadsSummary(t *testing.T) {
+	rawR, _, _, err := local.New(&local.Config{
+		Path: "./test-data",
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	ctx := context.Background()
+
+	blocks, err := r.Blocks(ctx, "single-tenant")
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+
+	meta, err := r.BlockMeta(ctx, blocks[0], "single-tenant")
+	require.NoError(t, err)
+
+	b := newBackendBlock(meta, r)
+
+	iter, err := b.RawIterator(ctx, newRowPool(1_000_000))
+	require.NoError(t, err)
+
+	sch := parquet.SchemaOf(new(Trace))
+	for {
+		id, row, err := iter.Next(ctx)
+		require.NoError(t, err)
+		if row == nil {
+			break
+		}
+
+		tr := new(Trace)
+		require.NoError(t, sch.Reconstruct(tr, row))
+		require.Equal(t, tr.TraceID, []byte(id))
+
+		expected := *tr
+		assignTraceSummary(&expected)
+		require.NotZero(t, tr.SpanCount)
+		require.Equal(t, expected.ServiceNames, tr.ServiceNames)
+		require.Equal(t, expected.SpanCount, tr.SpanCount)
+		require.Equal(t, expected.ErrorCount, tr.ErrorCount)
+	}
+}
@@ -0,0 +1,324 @@
+package vparquet2
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/segmentio/parquet-go"
+
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+const (
+	columnSpanStart = "rs.ils.Spans.StartUnixNanos"
+	columnSpanEnd   = "rs.ils.Spans.EndUnixNanos"
+
+	// projectedAttrPrefix is prepended to the names attributes are selected as, they can't collide with the
+	// other selected columns
+	projectedAttrPrefix = "attr."
+)
+
+var _ common.Projectable = (*backendBlock)(nil)
+
+// ProjectSpans reads the projected fields of all spans in the block. Only the columns of the fields are read, spans are
+// driven by their start time column and the trace and resource columns are read alongside.
+func (b *backendBlock) ProjectSpans(ctx context.Context, p common.Projection, cb func(*common.ProjectedSpan) error, opts common.SearchOptions) error {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.ProjectSpans",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+		})
+	defer span.Finish()
+
+	pf, err := b.openFile(derivedCtx, opts)
+	if err != nil {
+		return fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+	defer func() { span.SetTag("inspectedBytes", pf.bytesRead()) }()
+
+	return projectSpans(derivedCtx, pf, p, cb)
+}
+
+func projectSpans(ctx context.Context, pf *blockFile, p common.Projection, cb func(*common.ProjectedSpan) error) error {
+	makeIter := makeIterFunc(ctx, pf)
+
+	var (
+		traceIters    []pq.Iterator
+		resourceIters []pq.Iterator
+		spanIters     = []pq.Iterator{makeIter(columnSpanStart, nil, columnSpanStart)}
+		genericAttrs  []string
+	)
+	if p.TraceID {
+		traceIters = append(traceIters, makeIter(TraceIDColumnName, nil, TraceIDColumnName))
+	}
+	if p.Duration {
+		spanIters = append(spanIters, makeIter(columnSpanEnd, nil, columnSpanEnd))
+	}
+
+	for _, name := range p.Attributes {
+		column := labelMappings[name]
+		switch {
+		case column == "":
+			genericAttrs = append(genericAttrs, name)
+		case strings.HasPrefix(column, "rs.ils.Spans."):
+			spanIters = append(spanIters, makeIter(column, nil, projectedAttrPrefix+name))
+		case strings.HasPrefix(column, "rs.Resource."):
+			resourceIters = append(resourceIters, makeIter(column, nil, projectedAttrPrefix+name))
+		default:
+			traceIters = append(traceIters, makeIter(column, nil, projectedAttrPrefix+name))
+		}
+	}
+
+	if len(genericAttrs) > 0 {
+		keyPred := pq.NewStringInPredicate(genericAttrs)
+		resourceIters = append(resourceIters, newAttrValuesIter(makeIter, DefinitionLevelResourceAttrs, FieldResourceAttrKey,
+			resourceAttrValueColumns, resourceAttrMemberColumns, keyPred, &projectedAttrPredicate{}))
+		spanIters = append(spanIters, newAttrValuesIter(makeIter, DefinitionLevelResourceSpansILSSpanAttrs, FieldSpanAttrKey,
+			spanAttrValueColumns, spanAttrMemberColumns, keyPred, &projectedAttrPredicate{}))
+	}
+
+	spans := pq.NewUnionIterator(DefinitionLevelResourceSpansILSSpan, spanIters, nil)
+	defer spans.Close()
+	traces := newProjectionFollower(DefinitionLevelTrace, traceIters)
+	defer traces.close()
+	resources := newProjectionFollower(DefinitionLevelResourceSpans, resourceIters)
+	defer resources.close()
+
+	s := &common.ProjectedSpan{}
+	for {
+		res, err := spans.Next()
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			return nil
+		}
+
+		var start, end uint64
+		s.TraceID = s.TraceID[:0]
+		s.Attributes = map[string]string{}
+
+		traceRes, err := traces.at(res.RowNumber)
+		if err != nil {
+			return err
+		}
+		resourceRes, err := resources.at(res.RowNumber)
+		if err != nil {
+			return err
+		}
+
+		// span attributes are collected last to shadow resource attributes
+		hasStart := false
+		for _, r := range []*pq.IteratorResult{traceRes, resourceRes, res} {
+			if r == nil {
+				continue
+			}
+			for k, vs := range r.ToMap() {
+				v, ok := firstNonNull(vs)
+				if !ok {
+					continue
+				}
+				switch {
+				case k == columnSpanStart:
+					start, hasStart = v.Uint64(), true
+				case k == columnSpanEnd:
+					end = v.Uint64()
+				case k == TraceIDColumnName:
+					s.TraceID = append(s.TraceID, v.ByteArray()...)
+				case strings.HasPrefix(k, projectedAttrPrefix):
+					s.Attributes[strings.TrimPrefix(k, projectedAttrPrefix)] = projectedValue(v)
+				}
+			}
+		}
+
+		// resource spans without spans have a null start time
+		if !hasStart {
+			continue
+		}
+
+		s.StartTimeUnixNano, s.DurationNanos = 0, 0
+		if p.StartTime {
+			s.StartTimeUnixNano = start
+		}
+		if p.Duration && end > start {
+			s.DurationNanos = end - start
+		}
+
+		err = cb(s)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// projectionFollower reads the trace or resource level columns of the spans returned by the span iterator. The
+// spans are in row order, the follower only seeks forward.
+type projectionFollower struct {
+	definitionLevel int
+	iter            pq.Iterator
+	peek            *pq.IteratorResult
+	done            bool
+}
+
+func newProjectionFollower(definitionLevel int, iters []pq.Iterator) *projectionFollower {
+	f := &projectionFollower{definitionLevel: definitionLevel}
+	if len(iters) > 0 {
+		f.iter = pq.NewUnionIterator(definitionLevel, iters, nil)
+	}
+	return f
+}
+
+// at returns the result of the trace or resource of the span with the row number, nil if the columns have no values
+// for it.
+func (f *projectionFollower) at(rowNumber pq.RowNumber) (*pq.IteratorResult, error) {
+	if f.iter == nil {
+		return nil, nil
+	}
+
+	if !f.done && (f.peek == nil || pq.CompareRowNumbers(f.definitionLevel, f.peek.RowNumber, rowNumber) < 0) {
+		var err error
+		f.peek, err = f.iter.SeekTo(rowNumber, f.definitionLevel)
+		if err != nil {
+			return nil, err
+		}
+		f.done = f.peek == nil
+	}
+
+	if f.peek == nil || pq.CompareRowNumbers(f.definitionLevel, f.peek.RowNumber, rowNumber) != 0 {
+		return nil, nil
+	}
+	return f.peek, nil
+}
+
+func (f *projectionFollower) close() {
+	if f.iter != nil {
+		f.iter.Close()
+	}
+}
+
+var (
+	resourceAttrValueColumns = []string{FieldResourceAttrVal, FieldResourceAttrValInt, FieldResourceAttrValDouble, FieldResourceAttrValBool}
+	spanAttrValueColumns     = []string{FieldSpanAttrVal, FieldSpanAttrValInt, FieldSpanAttrValDouble, FieldSpanAttrValBool}
+	eventAttrValueColumns    = []string{FieldEventAttrVal, FieldEventAttrValInt, FieldEventAttrValDouble, FieldEventAttrValBool}
+
+	// the list columns of arrays of values of a single type
+	resourceAttrMemberColumns = []string{FieldResourceAttrValArray, "rs.Resource.Attrs.ValueArrayInt", "rs.Resource.Attrs.ValueArrayDouble", "rs.Resource.Attrs.ValueArrayBool"}
+	spanAttrMemberColumns     = []string{FieldSpanAttrValArray, "rs.ils.Spans.Attrs.ValueArrayInt", "rs.ils.Spans.Attrs.ValueArrayDouble", "rs.ils.Spans.Attrs.ValueArrayBool"}
+	eventAttrMemberColumns    = []string{FieldEventAttrValArray, "rs.ils.Spans.Events.Attrs.ValueArrayInt", "rs.ils.Spans.Events.Attrs.ValueArrayDouble", "rs.ils.Spans.Events.Attrs.ValueArrayBool"}
+)
+
+// newAttrValuesIter joins the keys of the generic attributes matching the key predicate with their values. The keys
+// are selected as "keys", the values as "values" and the members of arrays as "members". Only one of the value
+// columns is set for each attribute.
+func newAttrValuesIter(makeIter func(string, pq.Predicate, string) pq.Iterator, definitionLevel int, keyColumn string,
+	valueColumns, memberColumns []string, keyPred pq.Predicate, groupPred pq.GroupPredicate) pq.Iterator {
+	valueIters := make([]pq.Iterator, 0, len(valueColumns)+len(memberColumns))
+	for _, c := range valueColumns {
+		valueIters = append(valueIters, makeIter(c, nil, "values"))
+	}
+	for _, c := range memberColumns {
+		valueIters = append(valueIters, makeIter(c, nil, "members"))
+	}
+
+	return pq.NewJoinIterator(definitionLevel, []pq.Iterator{
+		makeIter(keyColumn, keyPred, "keys"),
+		pq.NewUnionIterator(definitionLevel, valueIters, nil),
+	}, groupPred)
+}
+
+// projectedAttrPredicate replaces the key and value of a generic attribute with its value selected as the name of the
+// attribute. Arrays are projected as json arrays of their members.
+type projectedAttrPredicate struct {
+	buffer [][]parquet.Value
+}
+
+var _ pq.GroupPredicate = (*projectedAttrPredicate)(nil)
+
+func (p *projectedAttrPredicate) KeepGroup(group *pq.IteratorResult) bool {
+	p.buffer = group.Columns(p.buffer, "keys", "values", "members")
+	keys, vals, members := p.buffer[0], p.buffer[1], p.buffer[2]
+	if len(keys) != 1 {
+		return false
+	}
+
+	v, ok := firstNonNull(vals)
+	if array, isArray := projectedArray(members); isArray {
+		v, ok = parquet.ValueOf(array), true
+	}
+	if !ok {
+		return false
+	}
+	group.Reset()
+	group.AppendValue(projectedAttrPrefix+keys[0].String(), v)
+	return true
+}
+
+// projectedArray formats the members of an array as a json array, false if there are none
+func projectedArray(members []parquet.Value) (string, bool) {
+	var sb strings.Builder
+	for _, m := range members {
+		if m.IsNull() {
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteByte('[')
+		} else {
+			sb.WriteByte(',')
+		}
+		if m.Kind() == parquet.ByteArray {
+			sb.WriteString(strconv.Quote(m.String()))
+		} else {
+			sb.WriteString(projectedValue(m))
+		}
+	}
+	if sb.Len() == 0 {
+		return "", false
+	}
+	sb.WriteByte(']')
+	return sb.String(), true
+}
+
+// firstNonNull returns the first value that isn't null. Only one of the value columns of an attribute is set.
+func firstNonNull(vs []parquet.Value) (parquet.Value, bool) {
+	for _, v := range vs {
+		if !v.IsNull() {
+			return v, true
+		}
+	}
+	return parquet.Value{}, false
+}
+
+// projectedType returns the tag value type of a value, see common.TypedTagCallback
+func projectedType(v parquet.Value) string {
+	switch v.Kind() {
+	case parquet.Int32, parquet.Int64:
+		return common.TagValueTypeInt
+	case parquet.Float, parquet.Double:
+		return common.TagValueTypeDouble
+	case parquet.Boolean:
+		return common.TagValueTypeBool
+	default:
+		return common.TagValueTypeString
+	}
+}
+
+// projectedValue formats a value the same way SearchTagValues reports it
+func projectedValue(v parquet.Value) string {
+	switch v.Kind() {
+	case parquet.Int32:
+		return strconv.FormatInt(int64(v.Int32()), 10)
+	case parquet.Int64:
+		return strconv.FormatInt(v.Int64(), 10)
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'g', -1, 64)
+	case parquet.Boolean:
+		return strconv.FormatBool(v.Boolean())
+	default:
+		return v.String()
+	}
+}
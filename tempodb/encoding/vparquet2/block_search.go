@@ -0,0 +1,814 @@
+package vparquet2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/segmentio/parquet-go"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// These are reserved search parameters
+const (
+	StatusCodeUnset = "unset"
+	StatusCodeOK    = "ok"
+	StatusCodeError = "error"
+)
+
+var StatusCodeMapping = map[string]int{
+	StatusCodeUnset: int(v1.Status_STATUS_CODE_UNSET),
+	StatusCodeOK:    int(v1.Status_STATUS_CODE_OK),
+	StatusCodeError: int(v1.Status_STATUS_CODE_ERROR),
+}
+
+// Trace-level summary columns
+const (
+	columnServiceNames = "ServiceNames"
+	columnErrorCount   = "ErrorCount"
+)
+
+// pageReadBufferSize is the size of the reads of the parquet sdk when a block is streamed with readahead
+const pageReadBufferSize = 64 * 1024
+
+// openFile opens the parquet data of the block for searches. The cold file of blocks with split columns is opened
+// when one of its columns is read.
+func (b *backendBlock) openFile(ctx context.Context, opts common.SearchOptions, o ...parquet.FileOption) (*blockFile, error) {
+	if !b.split() {
+		pf, rr, err := b.openForSearch(ctx, opts, o...)
+		if err != nil {
+			return nil, err
+		}
+		f := newBlockFile(pf)
+		f.readers = append(f.readers, rr)
+		return f, nil
+	}
+
+	hot, rr, err := b.openParquetFile(ctx, HotFileName, b.meta.HotSize, b.meta.HotFooterSize, opts, o...)
+	if err != nil {
+		return nil, err
+	}
+	f := newBlockFile(hot)
+	f.readers = append(f.readers, rr)
+	f.openCold = func() (*parquet.File, error) {
+		cold, rr, err := b.openParquetFile(ctx, ColdFileName, b.meta.Size-b.meta.HotSize, b.meta.FooterSize, opts, o...)
+		if err != nil {
+			return nil, err
+		}
+		f.readers = append(f.readers, rr)
+		return cold, nil
+	}
+	return f, nil
+}
+
+// openForSearch consolidates all the logic regarding opening a parquet file in object storage
+func (b *backendBlock) openForSearch(ctx context.Context, opts common.SearchOptions, o ...parquet.FileOption) (*parquet.File, *BackendReaderAt, error) {
+	return b.openParquetFile(ctx, DataFileName, b.meta.Size, b.meta.FooterSize, opts, o...)
+}
+
+func (b *backendBlock) openParquetFile(ctx context.Context, name string, size uint64, footerSize uint32, opts common.SearchOptions, o ...parquet.FileOption) (*parquet.File, *BackendReaderAt, error) {
+	backendReaderAt := NewBackendReaderAt(ctx, b.r, name, b.meta.BlockID, b.meta.TenantID)
+
+	// backend reader
+	readerAt := io.ReaderAt(backendReaderAt)
+
+	// buffering
+	if opts.ReadBufferSize > 0 {
+		//   small blocks fit in the buffers of the buffered reader at. larger blocks are streamed: the column chunks
+		//   are read in small pages by the parquet sdk and served by the readahead reader at, which bounds the
+		//   memory to the read buffers instead of buffering every column chunk of a row group
+		if opts.ReadBufferCount*opts.ReadBufferSize > int(size) {
+			readerAt = tempo_io.NewBufferedReaderAt(readerAt, int64(size), opts.ReadBufferSize, opts.ReadBufferCount)
+		} else {
+			readerAt = tempo_io.NewReadaheadReaderAt(readerAt, int64(size), opts.ReadBufferSize, opts.ReadBufferCount)
+			o = append(o, parquet.ReadBufferSize(pageReadBufferSize))
+		}
+	}
+
+	// optimized reader
+	readerAt = newParquetOptimizedReaderAt(readerAt, int64(size), footerSize)
+
+	// cached reader
+	if opts.CacheControl.ColumnIndex || opts.CacheControl.Footer || opts.CacheControl.OffsetIndex {
+		readerAt = newCachedReaderAt(readerAt, backendReaderAt, opts.CacheControl)
+	}
+
+	span, _ := opentracing.StartSpanFromContext(ctx, "parquet.OpenFile")
+	defer span.Finish()
+	pf, err := parquet.OpenFile(readerAt, int64(size), o...)
+
+	return pf, backendReaderAt, err
+}
+
+func (b *backendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, opts common.SearchOptions) (_ *tempopb.SearchResponse, err error) {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.Search",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+		})
+	defer span.Finish()
+
+	pf, err := b.openFile(derivedCtx, opts, parquet.SkipPageIndex(true))
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+	defer func() { span.SetTag("inspectedBytes", pf.bytesRead()) }()
+
+	// Get list of row groups to inspect. Ideally we use predicate pushdown
+	// here to keep only row groups that can potentially satisfy the request
+	// conditions, but don't have it figured out yet.
+	if opts.TotalPages > 0 {
+		// Read UP TO TotalPages.  The sharding calculations
+		// are just estimates, so it may not line up with the
+		// actual number of pages in this file.
+		rgs := pf.RowGroups()
+		if opts.StartPage+opts.TotalPages > len(rgs) {
+			opts.TotalPages = len(rgs) - opts.StartPage
+		}
+		pf.limitRowGroups(opts.StartPage, opts.TotalPages)
+	}
+
+	// skip the row groups without spans of the searched service
+	if name, ok := req.Tags[LabelServiceName]; ok && b.meta.ServiceIndex {
+		idx, err := readServiceIndex(derivedCtx, b.meta, b.r)
+		if err != nil {
+			return nil, err
+		}
+		pf.keepRowGroups(idx.rowGroups(name, pf.pf.RowGroups()))
+	}
+
+	// skip the row groups without values in the searched ranges
+	if ranges := zoneMapRanges(req); len(ranges) > 0 && b.meta.ZoneMaps {
+		z, err := readZoneMaps(derivedCtx, b.meta, b.r)
+		if err != nil {
+			return nil, err
+		}
+		pf.keepRowGroups(z.rowGroups(ranges, pf.pf.RowGroups()))
+	}
+
+	if len(pf.RowGroups()) == 0 {
+		return &tempopb.SearchResponse{Metrics: &tempopb.SearchMetrics{InspectedBlocks: 1}}, nil
+	}
+
+	results, err := searchParquetFile(derivedCtx, pf, req)
+	if err != nil {
+		return nil, err
+	}
+	results.Metrics.InspectedBlocks++
+	results.Metrics.InspectedBytes += pf.bytesRead()
+	results.Metrics.InspectedTraces += uint32(b.meta.TotalObjects)
+
+	return results, nil
+}
+
+func (b *backendBlock) SearchTags(ctx context.Context, cb common.TagCallback, opts common.SearchOptions) error {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.SearchTags",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+		})
+	defer span.Finish()
+
+	pf, err := b.openFile(derivedCtx, opts)
+	if err != nil {
+		return fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+	defer func() { span.SetTag("inspectedBytes", pf.bytesRead()) }()
+
+	return searchTags(pf, cb)
+}
+
+// searchTags reports the special attributes with values and the keys of the generic attributes of a parquet file
+func searchTags(pf *blockFile, cb common.TagCallback) error {
+	// find chunks of all special columns
+	for lbl, col := range labelMappings {
+		chunks, err := pf.columnChunks(col)
+		if err != nil {
+			return err
+		}
+
+	chunks:
+		for _, cc := range chunks {
+			pgs := cc.Pages()
+			for {
+				pg, err := pgs.ReadPage()
+				if err == io.EOF || pg == nil {
+					break
+				}
+				if err != nil {
+					return err
+				}
+
+				// if a special attribute has any non-null values, include it
+				if pg.NumNulls() < pg.NumValues() {
+					cb(lbl)
+					break chunks // no need to search the attribute again
+				}
+			}
+		}
+	}
+
+	// find chunks of generic attribute columns
+	resourceKeys, err := pf.columnChunks(FieldResourceAttrKey)
+	if err != nil {
+		return err
+	}
+	spanKeys, err := pf.columnChunks(FieldSpanAttrKey)
+	if err != nil {
+		return err
+	}
+	if resourceKeys == nil || spanKeys == nil {
+		return fmt.Errorf("resource or span attributes col not found (%s, %s)", FieldResourceAttrKey, FieldSpanAttrKey)
+	}
+	eventKeys, err := pf.columnChunks(FieldEventAttrKey)
+	if err != nil {
+		return err
+	}
+
+	// search other attributes
+	for _, chunks := range [][]parquet.ColumnChunk{resourceKeys, spanKeys, eventKeys} {
+		for _, cc := range chunks {
+			pgs := cc.Pages()
+			for {
+				pg, err := pgs.ReadPage()
+				if err == io.EOF || pg == nil {
+					break
+				}
+				if err != nil {
+					return err
+				}
+
+				dict := pg.Dictionary()
+				if dict == nil {
+					continue
+				}
+
+				for i := 0; i < dict.Len(); i++ {
+					s := string(dict.Index(int32(i)).ByteArray())
+					cb(s)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *backendBlock) SearchTagValues(ctx context.Context, tag string, cb common.TagCallback, opts common.SearchOptions) error {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.SearchTagValues",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+		})
+	defer span.Finish()
+
+	pf, err := b.openFile(derivedCtx, opts)
+	if err != nil {
+		return fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+	defer func() { span.SetTag("inspectedBytes", pf.bytesRead()) }()
+
+	// labelMappings will indicate whether this is a search for a special or standard
+	// column
+	column := labelMappings[tag]
+	if column == "" {
+		err = searchStandardTagValues(ctx, tag, pf, func(_, v string) { cb(v) })
+		if err != nil {
+			return fmt.Errorf("unexpected error searching standard tags: %w", err)
+		}
+		return nil
+	}
+
+	err = searchSpecialTagValues(ctx, column, pf, cb)
+	if err != nil {
+		return fmt.Errorf("unexpected error searching special tags: %w", err)
+	}
+	return nil
+}
+
+// SearchTypedTagValues implements common.TypedTagValuesSearcher. Every occurrence of a value is reported with the
+// type of the column it is stored in.
+func (b *backendBlock) SearchTypedTagValues(ctx context.Context, tag string, cb common.TypedTagCallback, opts common.SearchOptions) error {
+	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "parquet.backendBlock.SearchTypedTagValues",
+		opentracing.Tags{
+			"blockID":   b.meta.BlockID,
+			"tenantID":  b.meta.TenantID,
+			"blockSize": b.meta.Size,
+		})
+	defer span.Finish()
+
+	pf, err := b.openFile(derivedCtx, opts)
+	if err != nil {
+		return fmt.Errorf("unexpected error opening parquet file: %w", err)
+	}
+	defer func() { span.SetTag("inspectedBytes", pf.bytesRead()) }()
+
+	column := labelMappings[tag]
+	if column == "" {
+		err = searchStandardTagValues(ctx, tag, pf, cb)
+		if err != nil {
+			return fmt.Errorf("unexpected error searching standard tags: %w", err)
+		}
+		return nil
+	}
+
+	err = searchSpecialTypedTagValues(ctx, column, pf, cb)
+	if err != nil {
+		return fmt.Errorf("unexpected error searching special tags: %w", err)
+	}
+	return nil
+}
+
+func makePipelineWithRowGroups(ctx context.Context, req *tempopb.SearchRequest, pf *blockFile) pq.Iterator {
+	makeIter := makeIterFunc(ctx, pf)
+
+	// Wire up iterators
+	var resourceIters []pq.Iterator
+	var traceIters []pq.Iterator
+
+	otherAttrConditions := map[string]string{}
+
+	for k, v := range req.Tags {
+		column := labelMappings[k]
+
+		// answer the most common searches from the trace-level summary
+		if k == LabelServiceName {
+			traceIters = append(traceIters, makeIter(columnServiceNames, pq.NewSubstringPredicate(v), ""))
+			continue
+		}
+		if k == LabelStatusCode && v == StatusCodeError {
+			traceIters = append(traceIters, makeIter(columnErrorCount, pq.NewIntBetweenPredicate(1, math.MaxInt64), ""))
+			continue
+		}
+
+		// if we don't have a column mapping then pass it forward to otherAttribute handling
+		if column == "" {
+			otherAttrConditions[k] = v
+			continue
+		}
+
+		// most columns are just a substring predicate over the column, but we have
+		// special handling for http status code and span status
+		if k == LabelHTTPStatusCode {
+			if i, err := strconv.Atoi(v); err == nil {
+				resourceIters = append(resourceIters, makeIter(column, pq.NewIntBetweenPredicate(int64(i), int64(i)), ""))
+				break
+			}
+			// Non-numeric string field
+			otherAttrConditions[k] = v
+			continue
+		}
+		if k == LabelStatusCode {
+			code := StatusCodeMapping[v]
+			resourceIters = append(resourceIters, makeIter(column, pq.NewIntBetweenPredicate(int64(code), int64(code)), ""))
+			continue
+		}
+
+		if k == LabelRootServiceName || k == LabelRootSpanName {
+			traceIters = append(traceIters, makeIter(column, pq.NewSubstringPredicate(v), ""))
+		} else {
+			resourceIters = append(resourceIters, makeIter(column, pq.NewSubstringPredicate(v), ""))
+		}
+	}
+
+	// Generic attribute conditions?
+	if len(otherAttrConditions) > 0 {
+		// We are looking for one or more foo=bar attributes that aren't
+		// projected to their own columns, they are in the generic Key/Value
+		// columns at the resource or span levels.  We want to search
+		// both locations. But we also only want to read the columns once.
+
+		keys := make([]string, 0, len(otherAttrConditions))
+		vals := make([]string, 0, len(otherAttrConditions))
+		for k, v := range otherAttrConditions {
+			keys = append(keys, k)
+			vals = append(vals, v)
+		}
+
+		keyPred := pq.NewStringInPredicate(keys)
+		valPred := pq.NewStringInPredicate(vals)
+
+		// This iterator combines the results from the resource, span
+		// and event searches, and checks if all conditions were satisfied
+		// on each ResourceSpans.  This is a single-pass over the attribute columns.
+		// Values match the values of attributes and the members of arrays of strings.
+		j := pq.NewUnionIterator(DefinitionLevelResourceSpans, []pq.Iterator{
+			// This iterator finds all keys/values at the resource level
+			pq.NewJoinIterator(DefinitionLevelResourceAttrs, []pq.Iterator{
+				makeIter(FieldResourceAttrKey, keyPred, "keys"),
+				pq.NewUnionIterator(DefinitionLevelResourceAttrs, []pq.Iterator{
+					makeIter(FieldResourceAttrVal, valPred, "values"),
+					makeIter(FieldResourceAttrValArray, valPred, "values"),
+				}, nil),
+			}, &attrValuesGroupPredicate{}),
+			// This iterator finds all keys/values at the span level
+			pq.NewJoinIterator(DefinitionLevelResourceSpansILSSpanAttrs, []pq.Iterator{
+				makeIter(FieldSpanAttrKey, keyPred, "keys"),
+				pq.NewUnionIterator(DefinitionLevelResourceSpansILSSpanAttrs, []pq.Iterator{
+					makeIter(FieldSpanAttrVal, valPred, "values"),
+					makeIter(FieldSpanAttrValArray, valPred, "values"),
+				}, nil),
+			}, &attrValuesGroupPredicate{}),
+			// This iterator finds all keys/values at the event level
+			pq.NewJoinIterator(DefinitionLevelResourceSpansILSSpanEventAttrs, []pq.Iterator{
+				makeIter(FieldEventAttrKey, keyPred, "keys"),
+				pq.NewUnionIterator(DefinitionLevelResourceSpansILSSpanEventAttrs, []pq.Iterator{
+					makeIter(FieldEventAttrVal, valPred, "values"),
+					makeIter(FieldEventAttrValArray, valPred, "values"),
+				}, nil),
+			}, &attrValuesGroupPredicate{}),
+		}, pq.NewKeyValueGroupPredicate(keys, vals))
+
+		resourceIters = append(resourceIters, j)
+	}
+
+	// Multiple resource-level filters get joined and wrapped
+	// up to trace-level. A single filter can be used as-is
+	if len(resourceIters) == 1 {
+		traceIters = append(traceIters, resourceIters[0])
+	}
+	if len(resourceIters) > 1 {
+		traceIters = append(traceIters, pq.NewJoinIterator(DefinitionLevelTrace, resourceIters, nil))
+	}
+
+	// Duration filtering?
+	if req.MinDurationMs > 0 || req.MaxDurationMs > 0 {
+		min, max := durationRange(req)
+		durFilter := pq.NewIntBetweenPredicate(min, max)
+		traceIters = append(traceIters, makeIter("DurationNanos", durFilter, "Duration"))
+	}
+
+	// Time range filtering?
+	if req.Start > 0 && req.End > 0 {
+		// Here's how we detect the trace overlaps the time window:
+
+		// Trace start <= req.End
+		startFilter := pq.NewIntBetweenPredicate(0, time.Unix(int64(req.End), 0).UnixNano())
+		traceIters = append(traceIters, makeIter("StartTimeUnixNano", startFilter, "StartTime"))
+
+		// Trace end >= req.Start, only if column exists
+		if pf.hasColumn("EndTimeUnixNano") {
+			endFilter := pq.NewIntBetweenPredicate(time.Unix(int64(req.Start), 0).UnixNano(), math.MaxInt64)
+			traceIters = append(traceIters, makeIter("EndTimeUnixNano", endFilter, ""))
+		}
+	}
+
+	switch len(traceIters) {
+
+	case 0:
+		// Empty request, in this case every trace matches so we can
+		// simply iterate any column.
+		return makeIter("TraceID", nil, "")
+
+	case 1:
+		// There is only 1 iterator already, no need to wrap it up
+		return traceIters[0]
+
+	default:
+		// Join all conditions
+		return pq.NewJoinIterator(DefinitionLevelTrace, traceIters, nil)
+	}
+}
+
+// durationRange returns the range of the trace durations in nanoseconds matching the request
+func durationRange(req *tempopb.SearchRequest) (int64, int64) {
+	min := int64(0)
+	if req.MinDurationMs > 0 {
+		min = (time.Millisecond * time.Duration(req.MinDurationMs)).Nanoseconds()
+	}
+	max := int64(math.MaxInt64)
+	if req.MaxDurationMs > 0 {
+		max = (time.Millisecond * time.Duration(req.MaxDurationMs)).Nanoseconds()
+	}
+	return min, max
+}
+
+// zoneMapRanges returns the ranges of the zone map columns the values of the matching traces are within
+func zoneMapRanges(req *tempopb.SearchRequest) map[string]minMax {
+	ranges := map[string]minMax{}
+
+	if req.MinDurationMs > 0 || req.MaxDurationMs > 0 {
+		min, max := durationRange(req)
+		ranges[columnDurationNanos] = minMax{Min: min, Max: max}
+	}
+	if v, ok := req.Tags[LabelHTTPStatusCode]; ok {
+		if i, err := strconv.Atoi(v); err == nil {
+			ranges[columnHTTPStatusCode] = minMax{Min: int64(i), Max: int64(i)}
+		}
+	}
+	if v, ok := req.Tags[LabelStatusCode]; ok {
+		code := int64(StatusCodeMapping[v])
+		ranges[columnStatusCode] = minMax{Min: code, Max: code}
+	}
+
+	return ranges
+}
+
+func searchParquetFile(ctx context.Context, pf *blockFile, req *tempopb.SearchRequest) (*tempopb.SearchResponse, error) {
+
+	// Search happens in 2 phases for an optimization.
+	// Phase 1 is iterate all columns involved in the request.
+	// Only if there are any matches do we enter phase 2, which
+	// is to load the display-related columns.
+
+	// Find matches
+	matchingRows, err := searchRaw(ctx, pf, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(matchingRows) == 0 {
+		return &tempopb.SearchResponse{Metrics: &tempopb.SearchMetrics{}}, nil
+	}
+
+	// We have some results, now load the display columns
+	results, err := rawToResults(ctx, pf, matchingRows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tempopb.SearchResponse{
+		Traces:  results,
+		Metrics: &tempopb.SearchMetrics{},
+	}, nil
+}
+
+func searchRaw(ctx context.Context, pf *blockFile, req *tempopb.SearchRequest) ([]pq.RowNumber, error) {
+	iter := makePipelineWithRowGroups(ctx, req, pf)
+	if iter == nil {
+		return nil, errors.New("make pipeline returned a nil iterator")
+	}
+	defer iter.Close()
+
+	// Collect matches, row numbers only.
+	var matchingRows []pq.RowNumber
+	for {
+		match, err := iter.Next()
+		if err != nil {
+			return nil, errors.Wrap(err, "searchRaw next failed")
+		}
+		if match == nil {
+			break
+		}
+		matchingRows = append(matchingRows, match.RowNumber)
+		if req.Limit > 0 && len(matchingRows) >= int(req.Limit) {
+			break
+		}
+	}
+
+	return matchingRows, nil
+}
+
+func rawToResults(ctx context.Context, pf *blockFile, rowNumbers []pq.RowNumber) ([]*tempopb.TraceSearchMetadata, error) {
+	makeIter := makeIterFunc(ctx, pf)
+
+	results := []*tempopb.TraceSearchMetadata{}
+	iter2 := pq.NewJoinIterator(DefinitionLevelTrace, []pq.Iterator{
+		&rowNumberIterator{rowNumbers: rowNumbers},
+		makeIter("TraceID", nil, "TraceID"),
+		makeIter("RootServiceName", nil, "RootServiceName"),
+		makeIter("RootSpanName", nil, "RootSpanName"),
+		makeIter("StartTimeUnixNano", nil, "StartTimeUnixNano"),
+		makeIter("DurationNanos", nil, "DurationNanos"),
+	}, nil)
+	defer iter2.Close()
+
+	for {
+		match, err := iter2.Next()
+		if err != nil {
+			return nil, errors.Wrap(err, "rawToResults next failed")
+		}
+		if match == nil {
+			break
+		}
+
+		matchMap := match.ToMap()
+		result := &tempopb.TraceSearchMetadata{
+			TraceID:           util.TraceIDToHexString(matchMap["TraceID"][0].Bytes()),
+			RootServiceName:   matchMap["RootServiceName"][0].String(),
+			RootTraceName:     matchMap["RootSpanName"][0].String(),
+			StartTimeUnixNano: matchMap["StartTimeUnixNano"][0].Uint64(),
+			DurationMs:        uint32(matchMap["DurationNanos"][0].Int64() / int64(time.Millisecond)),
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// searchStandardTagValues searches a parquet file for "standard" tags. i.e. tags that don't have unique
+// columns and are contained in labelMappings. Int, double and bool values are reported with their type formatted
+// as strings the same way they are stored in flatbuffer search data.
+func searchStandardTagValues(ctx context.Context, tag string, pf *blockFile, cb common.TypedTagCallback) error {
+	makeIter := makeIterFunc(ctx, pf)
+
+	keyPred := pq.NewStringInPredicate([]string{tag})
+
+	iter := newAttrValuesIter(makeIter, DefinitionLevelResourceAttrs, FieldResourceAttrKey, resourceAttrValueColumns, resourceAttrMemberColumns, keyPred, nil)
+	err := reportTagValues(iter, cb)
+	iter.Close()
+	if err != nil {
+		return errors.Wrap(err, "iter.Next on failed on resource lookup")
+	}
+
+	iter = newAttrValuesIter(makeIter, DefinitionLevelResourceSpansILSSpanAttrs, FieldSpanAttrKey, spanAttrValueColumns, spanAttrMemberColumns, keyPred, nil)
+	err = reportTagValues(iter, cb)
+	iter.Close()
+	if err != nil {
+		return errors.Wrap(err, "iter.Next on failed on span lookup")
+	}
+
+	iter = newAttrValuesIter(makeIter, DefinitionLevelResourceSpansILSSpanEventAttrs, FieldEventAttrKey, eventAttrValueColumns, eventAttrMemberColumns, keyPred, nil)
+	err = reportTagValues(iter, cb)
+	iter.Close()
+	if err != nil {
+		return errors.Wrap(err, "iter.Next on failed on event lookup")
+	}
+
+	return nil
+}
+
+// reportTagValues drains an attribute iterator built by newAttrValuesIter and passes every non-null value and
+// array member to cb.
+func reportTagValues(iter pq.Iterator, cb common.TypedTagCallback) error {
+	var buffer [][]parquet.Value
+	for {
+		match, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		if match == nil {
+			return nil
+		}
+		buffer = match.Columns(buffer, "values", "members")
+		for _, vs := range buffer {
+			for _, v := range vs {
+				if !v.IsNull() {
+					cb(projectedType(v), projectedValue(v))
+				}
+			}
+		}
+	}
+}
+
+// attrValuesGroupPredicate pairs the key of an attribute with each of its matching values. Arrays have a value for
+// every matching member, the pairs let KeyValueGroupPredicate match any of them.
+type attrValuesGroupPredicate struct {
+	buffer [][]parquet.Value
+}
+
+var _ pq.GroupPredicate = (*attrValuesGroupPredicate)(nil)
+
+func (p *attrValuesGroupPredicate) KeepGroup(group *pq.IteratorResult) bool {
+	p.buffer = group.Columns(p.buffer, "keys", "values")
+	keys, vals := p.buffer[0], p.buffer[1]
+	if len(keys) != 1 || len(vals) == 0 {
+		return false
+	}
+
+	if len(vals) > 1 {
+		group.Reset()
+		for _, v := range vals {
+			group.AppendValue("keys", keys[0])
+			group.AppendValue("values", v)
+		}
+	}
+	return true
+}
+
+// searchSpecialTypedTagValues reports every value of the provided column with its type. Unlike
+// searchSpecialTagValues it reads all values instead of the dictionaries, so every occurrence is reported.
+func searchSpecialTypedTagValues(ctx context.Context, column string, pf *blockFile, cb common.TypedTagCallback) error {
+	iter := makeIterFunc(ctx, pf)(column, nil, "values")
+	defer iter.Close()
+
+	var buffer [][]parquet.Value
+	for {
+		match, err := iter.Next()
+		if err != nil {
+			return errors.Wrap(err, "iter.Next failed")
+		}
+		if match == nil {
+			return nil
+		}
+		buffer = match.Columns(buffer, "values")
+		for _, v := range buffer[0] {
+			if !v.IsNull() {
+				cb(projectedType(v), projectedValue(v))
+			}
+		}
+	}
+}
+
+// searchSpecialTagValues searches a parquet file for all values for the provided column. It first attempts
+// to only pull all values from the column's dictionary. If this fails it falls back to scanning the entire path.
+func searchSpecialTagValues(ctx context.Context, column string, pf *blockFile, cb common.TagCallback) error {
+	pred := newReportValuesPredicate(cb)
+
+	iter := makeIterFunc(ctx, pf)(column, pred, "")
+	defer iter.Close()
+	for {
+		match, err := iter.Next()
+		if err != nil {
+			return errors.Wrap(err, "iter.Next failed")
+		}
+		if match == nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+func makeIterFunc(ctx context.Context, pf *blockFile) func(name string, predicate pq.Predicate, selectAs string) pq.Iterator {
+	return func(name string, predicate pq.Predicate, selectAs string) pq.Iterator {
+		rgs, index, err := pf.column(name)
+		if err != nil {
+			return &errIterator{err: err}
+		}
+		if index == -1 {
+			// TODO - don't panic, error instead
+			panic("column not found in parquet file:" + name)
+		}
+		return pq.NewColumnIterator(ctx, rgs, index, name, 1000, predicate, selectAs)
+	}
+}
+
+type rowNumberIterator struct {
+	rowNumbers []pq.RowNumber
+}
+
+var _ pq.Iterator = (*rowNumberIterator)(nil)
+
+func (r *rowNumberIterator) Next() (*pq.IteratorResult, error) {
+	if len(r.rowNumbers) == 0 {
+		return nil, nil
+	}
+
+	res := &pq.IteratorResult{RowNumber: r.rowNumbers[0]}
+	r.rowNumbers = r.rowNumbers[1:]
+	return res, nil
+}
+
+func (r *rowNumberIterator) SeekTo(to pq.RowNumber, definitionLevel int) (*pq.IteratorResult, error) {
+	var at *pq.IteratorResult
+
+	for at, _ = r.Next(); r != nil && pq.CompareRowNumbers(definitionLevel, at.RowNumber, to) < 0; {
+		at, _ = r.Next()
+	}
+
+	return at, nil
+}
+
+func (r *rowNumberIterator) Close() {}
+
+// reportValuesPredicate is a "fake" predicate that uses existing iterator logic to find all values in a given column
+type reportValuesPredicate struct {
+	cb common.TagCallback
+}
+
+func newReportValuesPredicate(cb common.TagCallback) *reportValuesPredicate {
+	return &reportValuesPredicate{cb: cb}
+}
+
+// KeepColumnChunk always returns true b/c we always have to dig deeper to find all values
+func (r *reportValuesPredicate) KeepColumnChunk(cc parquet.ColumnChunk) bool {
+	return true
+}
+
+// KeepPage checks to see if the page has a dictionary. if it does then we can report the values contained in it
+// and return false b/c we don't have to go to the actual columns to retrieve values. if there is no dict we return
+// true so the iterator will call KeepValue on all values in the column
+func (r *reportValuesPredicate) KeepPage(pg parquet.Page) bool {
+	if dict := pg.Dictionary(); dict != nil {
+		for i := 0; i < dict.Len(); i++ {
+			s := string(dict.Index(int32(i)).ByteArray())
+			r.cb(s)
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// KeepValue is only called if this column does not have a dictionary. Just report everything to r.cb and
+// return false so the iterator do any extra work.
+func (r *reportValuesPredicate) KeepValue(v parquet.Value) bool {
+	r.cb(v.String())
+
+	return false
+}
@@ -0,0 +1,664 @@
+package vparquet2
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendBlockSearch(t *testing.T) {
+
+	// Helper functions to make pointers
+	strPtr := func(s string) *string { return &s }
+	intPtr := func(i int64) *int64 { return &i }
+
+	// Trace
+	// This is a fully-populated trace that we search for every condition
+	wantTr := &Trace{
+		TraceID:           test.ValidTraceID(nil),
+		StartTimeUnixNano: uint64(1000 * time.Second),
+		EndTimeUnixNano:   uint64(2000 * time.Second),
+		DurationNanos:     uint64((100 * time.Millisecond).Nanoseconds()),
+		RootServiceName:   "RootService",
+		RootSpanName:      "RootSpan",
+		ServiceNames:      []string{"myservice"},
+		SpanCount:         1,
+		ErrorCount:        1,
+		ResourceSpans: []ResourceSpans{
+			{
+				Resource: Resource{
+					ServiceName:      "myservice",
+					Cluster:          strPtr("cluster"),
+					Namespace:        strPtr("namespace"),
+					Pod:              strPtr("pod"),
+					Container:        strPtr("container"),
+					K8sClusterName:   strPtr("k8scluster"),
+					K8sNamespaceName: strPtr("k8snamespace"),
+					K8sPodName:       strPtr("k8spod"),
+					K8sContainerName: strPtr("k8scontainer"),
+					Attrs: []Attribute{
+						{Key: "bat", Value: strPtr("baz")},
+					},
+				},
+				InstrumentationLibrarySpans: []ILS{
+					{
+						Spans: []Span{
+							{
+								Name:           "hello",
+								HttpMethod:     strPtr("get"),
+								HttpUrl:        strPtr("url/hello/world"),
+								HttpStatusCode: intPtr(500),
+								ID:             []byte{},
+								ParentSpanID:   []byte{},
+								StatusCode:     int(v1.Status_STATUS_CODE_ERROR),
+								Attrs: []Attribute{
+									{Key: "foo", Value: strPtr("bar")},
+								},
+								Events: []Event{
+									{
+										Name:             "exception",
+										ExceptionType:    strPtr("java.lang.NullPointerException"),
+										ExceptionMessage: strPtr("name is null"),
+										Attrs: []Attribute{
+											{Key: "exception.escaped", Value: strPtr("yes")},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// make a bunch of traces and include our wantTr above
+	total := 1000
+	insertAt := rand.Intn(total)
+	allTraces := make([]*Trace, 0, total)
+	for i := 0; i < total; i++ {
+		if i == insertAt {
+			allTraces = append(allTraces, wantTr)
+			continue
+		}
+
+		id := test.ValidTraceID(nil)
+		pbTrace := test.MakeTrace(10, id)
+		pqTrace := traceToParquet(id, pbTrace)
+		allTraces = append(allTraces, &pqTrace)
+	}
+
+	b := makeBackendBlockWithTraces(t, allTraces)
+	ctx := context.TODO()
+
+	// Helper function to make a tag search
+	makeReq := func(k, v string) *tempopb.SearchRequest {
+		return &tempopb.SearchRequest{
+			Tags: map[string]string{
+				k: v,
+			},
+		}
+	}
+
+	// Matches
+	searchesThatMatch := []*tempopb.SearchRequest{
+		{
+			// Empty request
+		},
+		{
+			MinDurationMs: 99,
+			MaxDurationMs: 101,
+		},
+		{
+			Start: 1000,
+			End:   2000,
+		},
+		{
+			// Overlaps start
+			Start: 999,
+			End:   1001,
+		},
+		{
+			// Overlaps end
+			Start: 1999,
+			End:   2001,
+		},
+
+		// Well-known resource attributes
+		makeReq(LabelServiceName, "service"),
+		makeReq(LabelCluster, "cluster"),
+		makeReq(LabelNamespace, "namespace"),
+		makeReq(LabelPod, "pod"),
+		makeReq(LabelContainer, "container"),
+		makeReq(LabelK8sClusterName, "k8scluster"),
+		makeReq(LabelK8sNamespaceName, "k8snamespace"),
+		makeReq(LabelK8sPodName, "k8spod"),
+		makeReq(LabelK8sContainerName, "k8scontainer"),
+
+		// Well-known span attributes
+		makeReq(LabelName, "ell"),
+		makeReq(LabelHTTPMethod, "get"),
+		makeReq(LabelHTTPUrl, "hello"),
+		makeReq(LabelHTTPStatusCode, "500"),
+		makeReq(LabelStatusCode, StatusCodeError),
+
+		// Well-known event attributes
+		makeReq(LabelExceptionType, "NullPointer"),
+		makeReq(LabelExceptionMessage, "is null"),
+
+		// Span attributes
+		makeReq("foo", "bar"),
+		// Resource attributes
+		makeReq("bat", "baz"),
+		// Event attributes
+		makeReq("exception.escaped", "yes"),
+
+		// Multiple
+		{
+			Tags: map[string]string{
+				"service.name": "service",
+				"http.method":  "get",
+				"foo":          "bar",
+			},
+		},
+	}
+	expected := &tempopb.TraceSearchMetadata{
+		TraceID:           util.TraceIDToHexString(wantTr.TraceID),
+		StartTimeUnixNano: wantTr.StartTimeUnixNano,
+		DurationMs:        uint32(wantTr.DurationNanos / uint64(time.Millisecond)),
+		RootServiceName:   wantTr.RootServiceName,
+		RootTraceName:     wantTr.RootSpanName,
+	}
+
+	findInResults := func(id string, res []*tempopb.TraceSearchMetadata) *tempopb.TraceSearchMetadata {
+		for _, r := range res {
+			if r.TraceID == id {
+				return r
+			}
+		}
+		return nil
+	}
+
+	for _, opts := range []common.SearchOptions{defaultSearchOptions(), streamingSearchOptions()} {
+		for _, req := range searchesThatMatch {
+			res, err := b.Search(ctx, req, opts)
+			require.NoError(t, err)
+
+			meta := findInResults(expected.TraceID, res.Traces)
+			require.NotNil(t, meta, "search request:", req)
+			require.Equal(t, expected, meta, "search request:", req)
+		}
+	}
+
+	// Excludes
+	searchesThatDontMatch := []*tempopb.SearchRequest{
+		{
+			MinDurationMs: 101,
+		},
+		{
+			MaxDurationMs: 99,
+		},
+		{
+			Start: 100,
+			End:   200,
+		},
+
+		// Well-known resource attributes
+		makeReq(LabelServiceName, "foo"),
+		makeReq(LabelCluster, "foo"),
+		makeReq(LabelNamespace, "foo"),
+		makeReq(LabelPod, "foo"),
+		makeReq(LabelContainer, "foo"),
+
+		// Well-known span attributes
+		makeReq(LabelHTTPMethod, "post"),
+		makeReq(LabelHTTPUrl, "asdf"),
+		makeReq(LabelHTTPStatusCode, "200"),
+		makeReq(LabelStatusCode, StatusCodeOK),
+
+		// Well-known event attributes
+		makeReq(LabelExceptionType, "IllegalState"),
+		makeReq(LabelExceptionMessage, "timeout"),
+
+		// Span attributes
+		makeReq("foo", "baz"),
+		// Event attributes
+		makeReq("exception.escaped", "no"),
+	}
+	for _, opts := range []common.SearchOptions{defaultSearchOptions(), streamingSearchOptions()} {
+		for _, req := range searchesThatDontMatch {
+			res, err := b.Search(ctx, req, opts)
+			require.NoError(t, err)
+			meta := findInResults(expected.TraceID, res.Traces)
+			require.Nil(t, meta, req)
+		}
+	}
+}
+
+func TestBackendBlockSearchTraceSummary(t *testing.T) {
+	makeTrace := func(services map[string]int) *Trace {
+		tr := &Trace{
+			TraceID: test.ValidTraceID(nil),
+		}
+		for svc, statusCode := range services {
+			tr.ResourceSpans = append(tr.ResourceSpans, ResourceSpans{
+				Resource: Resource{
+					ServiceName: svc,
+				},
+				InstrumentationLibrarySpans: []ILS{
+					{
+						Spans: []Span{
+							{ID: []byte{}, StatusCode: statusCode},
+						},
+					},
+				},
+			})
+		}
+		assignTraceSummary(tr)
+		return tr
+	}
+
+	withError := makeTrace(map[string]int{
+		"frontend": int(v1.Status_STATUS_CODE_OK),
+		"backend":  int(v1.Status_STATUS_CODE_ERROR),
+	})
+	withoutError := makeTrace(map[string]int{
+		"frontend": int(v1.Status_STATUS_CODE_OK),
+	})
+	require.Equal(t, []string{"backend", "frontend"}, withError.ServiceNames)
+	require.Equal(t, int64(2), withError.SpanCount)
+	require.Equal(t, int64(1), withError.ErrorCount)
+
+	b := makeBackendBlockWithTraces(t, []*Trace{withError, withoutError})
+
+	tcs := []struct {
+		tags     map[string]string
+		expected []*Trace
+	}{
+		{
+			tags:     map[string]string{LabelServiceName: "frontend"},
+			expected: []*Trace{withError, withoutError},
+		},
+		{
+			// matches both services of a trace
+			tags:     map[string]string{LabelServiceName: "end"},
+			expected: []*Trace{withError, withoutError},
+		},
+		{
+			tags:     map[string]string{LabelServiceName: "back"},
+			expected: []*Trace{withError},
+		},
+		{
+			tags:     map[string]string{LabelStatusCode: StatusCodeError},
+			expected: []*Trace{withError},
+		},
+		{
+			tags:     map[string]string{LabelServiceName: "frontend", LabelStatusCode: StatusCodeError},
+			expected: []*Trace{withError},
+		},
+		{
+			tags: map[string]string{LabelServiceName: "database"},
+		},
+	}
+
+	for _, tc := range tcs {
+		res, err := b.Search(context.Background(), &tempopb.SearchRequest{Tags: tc.tags}, defaultSearchOptions())
+		require.NoError(t, err)
+
+		var expected, actual []string
+		for _, tr := range tc.expected {
+			expected = append(expected, util.TraceIDToHexString(tr.TraceID))
+		}
+		for _, tr := range res.Traces {
+			actual = append(actual, tr.TraceID)
+		}
+		assert.ElementsMatch(t, expected, actual, tc.tags)
+	}
+}
+
+func TestBackendBlockSearchTags(t *testing.T) {
+	traces, attrs := makeTraces()
+	block := makeBackendBlockWithTraces(t, traces)
+
+	foundAttrs := map[string]struct{}{}
+
+	cb := func(s string) {
+		foundAttrs[s] = struct{}{}
+	}
+
+	ctx := context.Background()
+	err := block.SearchTags(ctx, cb, defaultSearchOptions())
+	require.NoError(t, err)
+
+	// test that all attrs are in found attrs
+	for k := range attrs {
+		_, ok := foundAttrs[k]
+		require.True(t, ok)
+	}
+}
+
+func TestBackendBlockSearchTagValues(t *testing.T) {
+	traces, attrs := makeTraces()
+	block := makeBackendBlockWithTraces(t, traces)
+
+	ctx := context.Background()
+	for tag, val := range attrs {
+		wasCalled := false
+		cb := func(s string) {
+			wasCalled = true
+			assert.Equal(t, val, s, tag)
+		}
+
+		err := block.SearchTagValues(ctx, tag, cb, defaultSearchOptions())
+		require.NoError(t, err)
+		require.True(t, wasCalled, tag)
+	}
+}
+
+func makeBackendBlockWithTraces(t *testing.T, trs []*Trace) *backendBlock {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+	}
+
+	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = 1
+
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+
+	for i, tr := range trs {
+		s.Add(tr, 0, 0)
+		if i%100 == 0 {
+			_, err := s.Flush()
+			require.NoError(t, err)
+		}
+	}
+
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	b := newBackendBlock(s.meta, r)
+
+	return b
+}
+
+func defaultSearchOptions() common.SearchOptions {
+	return common.SearchOptions{
+		ChunkSizeBytes:  1_000_000,
+		ReadBufferCount: 8,
+		ReadBufferSize:  4 * 1024 * 1024,
+	}
+}
+
+// streamingSearchOptions uses read buffers smaller than the test blocks, so the blocks are streamed with readahead
+func streamingSearchOptions() common.SearchOptions {
+	return common.SearchOptions{
+		ChunkSizeBytes:  1_000_000,
+		ReadBufferCount: 2,
+		ReadBufferSize:  4 * 1024,
+	}
+}
+
+func makeTraces() ([]*Trace, map[string]string) {
+	traces := []*Trace{}
+	attrVals := make(map[string]string)
+
+	ptr := func(s string) *string { return &s }
+
+	attrVals[LabelCluster] = "cluster"
+	attrVals[LabelServiceName] = "servicename"
+	attrVals[LabelRootServiceName] = "rootsvc"
+	attrVals[LabelNamespace] = "ns"
+	attrVals[LabelPod] = "pod"
+	attrVals[LabelContainer] = "con"
+	attrVals[LabelK8sClusterName] = "kclust"
+	attrVals[LabelK8sNamespaceName] = "kns"
+	attrVals[LabelK8sPodName] = "kpod"
+	attrVals[LabelK8sContainerName] = "k8scon"
+
+	attrVals[LabelName] = "span"
+	attrVals[LabelRootSpanName] = "rootspan"
+	attrVals[LabelHTTPMethod] = "method"
+	attrVals[LabelHTTPUrl] = "url"
+	attrVals[LabelHTTPStatusCode] = "404"
+	attrVals[LabelStatusCode] = "2"
+	attrVals[LabelExceptionType] = "error"
+	attrVals[LabelExceptionMessage] = "message"
+
+	for i := 0; i < 10; i++ {
+		tr := &Trace{
+			RootServiceName: "rootsvc",
+			RootSpanName:    "rootspan",
+		}
+
+		for j := 0; j < 3; j++ {
+			key := test.RandomString()
+			val := test.RandomString()
+			attrVals[key] = val
+
+			rs := ResourceSpans{
+				Resource: Resource{
+					ServiceName:      "servicename",
+					Cluster:          ptr("cluster"),
+					Namespace:        ptr("ns"),
+					Pod:              ptr("pod"),
+					Container:        ptr("con"),
+					K8sClusterName:   ptr("kclust"),
+					K8sNamespaceName: ptr("kns"),
+					K8sPodName:       ptr("kpod"),
+					K8sContainerName: ptr("k8scon"),
+					Attrs: []Attribute{
+						{
+							Key:   key,
+							Value: &val,
+						},
+					},
+				},
+				InstrumentationLibrarySpans: []ILS{
+					{},
+				},
+			}
+			tr.ResourceSpans = append(tr.ResourceSpans, rs)
+
+			for k := 0; k < 10; k++ {
+				key := test.RandomString()
+				val := test.RandomString()
+				attrVals[key] = val
+
+				eventKey := test.RandomString()
+				eventVal := test.RandomString()
+				attrVals[eventKey] = eventVal
+
+				sts := int64(404)
+				span := Span{
+					Name:           "span",
+					HttpMethod:     ptr("method"),
+					HttpUrl:        ptr("url"),
+					HttpStatusCode: &sts,
+					StatusCode:     2,
+					Attrs: []Attribute{
+						{
+							Key:   key,
+							Value: &val,
+						},
+					},
+					Events: []Event{
+						{
+							ExceptionType:    ptr("error"),
+							ExceptionMessage: ptr("message"),
+							Attrs: []Attribute{
+								{
+									Key:   eventKey,
+									Value: &eventVal,
+								},
+							},
+						},
+					},
+				}
+
+				rs.InstrumentationLibrarySpans[0].Spans = append(rs.InstrumentationLibrarySpans[0].Spans, span)
+			}
+
+		}
+
+		traces = append(traces, tr)
+	}
+
+	return traces, attrVals
+}
+
+func TestBackendBlockSearchTagValuesTyped(t *testing.T) {
+	intVal := int64(123)
+	doubleVal := 1.5
+	boolVal := true
+	strVal := "foo"
+
+	tr := &Trace{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				ServiceName: "svc",
+				Attrs: []Attribute{
+					{Key: "res.int", ValueInt: &intVal},
+					{Key: "res.str", Value: &strVal},
+				},
+			},
+			InstrumentationLibrarySpans: []ILS{{
+				Spans: []Span{{
+					ID: make([]byte, 8),
+					Attrs: []Attribute{
+						{Key: "span.double", ValueDouble: &doubleVal},
+						{Key: "span.bool", ValueBool: &boolVal},
+						{Key: "span.mixed", ValueInt: &intVal},
+						{Key: "span.str", Value: &strVal},
+					},
+				}},
+			}},
+		}},
+	}
+	tr2 := &Trace{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				ServiceName: "svc",
+				Attrs: []Attribute{
+					{Key: "res.int", ValueInt: &intVal},
+				},
+			},
+			InstrumentationLibrarySpans: []ILS{{
+				Spans: []Span{{
+					ID: make([]byte, 8),
+					Attrs: []Attribute{
+						{Key: "span.mixed", Value: &strVal},
+					},
+				}},
+			}},
+		}},
+	}
+	block := makeBackendBlockWithTraces(t, []*Trace{tr, tr2})
+
+	tcs := map[string][]string{
+		"res.int":     {"123", "123"},
+		"res.str":     {"foo"},
+		"span.double": {"1.5"},
+		"span.bool":   {"true"},
+		"span.mixed":  {"123", "foo"},
+		"span.str":    {"foo"},
+	}
+	for tag, expected := range tcs {
+		var actual []string
+		err := block.SearchTagValues(context.Background(), tag, func(s string) { actual = append(actual, s) }, defaultSearchOptions())
+		require.NoError(t, err)
+		require.ElementsMatch(t, expected, actual, tag)
+	}
+
+	// typed values have the type of their column and are reported once per occurrence
+	typedTcs := map[string][]string{
+		"res.int":      {"int 123", "int 123"},
+		"res.str":      {"string foo"},
+		"span.double":  {"double 1.5"},
+		"span.bool":    {"bool true"},
+		"span.mixed":   {"int 123", "string foo"},
+		"span.str":     {"string foo"},
+		"service.name": {"string svc", "string svc"},
+	}
+	for tag, expected := range typedTcs {
+		var actual []string
+		err := block.SearchTypedTagValues(context.Background(), tag, func(typ, v string) { actual = append(actual, typ+" "+v) }, defaultSearchOptions())
+		require.NoError(t, err)
+		require.ElementsMatch(t, expected, actual, tag)
+	}
+}
+
+func TestBackendBlockSearchArrayAttributes(t *testing.T) {
+	tr := &Trace{
+		ResourceSpans: []ResourceSpans{{
+			Resource: Resource{
+				ServiceName: "svc",
+				Attrs: []Attribute{
+					{Key: "res.array", ValueArrayString: []string{"a", "b"}},
+				},
+			},
+			InstrumentationLibrarySpans: []ILS{{
+				Spans: []Span{{
+					ID: make([]byte, 8),
+					Attrs: []Attribute{
+						{Key: "span.array", ValueArrayString: []string{"x", "y"}},
+						{Key: "span.ints", ValueArrayInt: []int64{1, 2}},
+					},
+				}},
+			}},
+		}},
+	}
+	block := makeBackendBlockWithTraces(t, []*Trace{tr})
+
+	// searches match any member of the arrays
+	for _, tags := range []map[string]string{
+		{"res.array": "b"},
+		{"span.array": "x"},
+		{"span.array": "y"},
+		{"res.array": "a", "span.array": "y"},
+	} {
+		res, err := block.Search(context.Background(), &tempopb.SearchRequest{Tags: tags}, defaultSearchOptions())
+		require.NoError(t, err)
+		require.Len(t, res.Traces, 1, tags)
+	}
+
+	res, err := block.Search(context.Background(), &tempopb.SearchRequest{Tags: map[string]string{"span.array": "z"}}, defaultSearchOptions())
+	require.NoError(t, err)
+	require.Len(t, res.Traces, 0)
+
+	// tag values are the members of the arrays
+	tcs := map[string][]string{
+		"res.array":  {"a", "b"},
+		"span.array": {"x", "y"},
+		"span.ints":  {"1", "2"},
+	}
+	for tag, expected := range tcs {
+		var actual []string
+		err := block.SearchTagValues(context.Background(), tag, func(s string) { actual = append(actual, s) }, defaultSearchOptions())
+		require.NoError(t, err)
+		require.ElementsMatch(t, expected, actual, tag)
+	}
+}
@@ -0,0 +1,257 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/segmentio/parquet-go"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkCompactor(b *testing.B) {
+	b.Run("Small", func(b *testing.B) {
+		benchmarkCompactor(b, 1000, 100, 100) // 10M spans
+	})
+	b.Run("Medium", func(b *testing.B) {
+		benchmarkCompactor(b, 100, 100, 1000) // 10M spans
+	})
+	b.Run("Large", func(b *testing.B) {
+		benchmarkCompactor(b, 10, 1000, 1000) // 10M spans
+	})
+}
+
+func benchmarkCompactor(b *testing.B, traceCount, batchCount, spanCount int) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: b.TempDir(),
+	})
+	require.NoError(b, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+	l := log.NewNopLogger()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+		RowGroupSizeBytes:   20_000_000,
+	}
+
+	meta := createTestBlock(b, ctx, cfg, r, w, traceCount, batchCount, spanCount)
+
+	inputs := []*backend.BlockMeta{meta}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		fmt.Println(b.N)
+		c := NewCompactor(common.CompactionOptions{
+			BlockConfig:      *cfg,
+			OutputBlocks:     1,
+			FlushSizeBytes:   30_000_000,
+			MaxBytesPerTrace: 50_000_000,
+		})
+
+		_, err = c.Compact(ctx, l, r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, inputs)
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkCompactorDupes(b *testing.B) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: b.TempDir(),
+	})
+	require.NoError(b, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+	l := log.NewNopLogger()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+		RowGroupSizeBytes:   20_000_000,
+	}
+
+	// 1M span traces
+	meta := createTestBlock(b, ctx, cfg, r, w, 10, 1000, 1000)
+	inputs := []*backend.BlockMeta{meta, meta}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		c := NewCompactor(common.CompactionOptions{
+			BlockConfig:      *cfg,
+			OutputBlocks:     1,
+			FlushSizeBytes:   30_000_000,
+			MaxBytesPerTrace: 50_000_000,
+			ObjectsCombined:  func(compactionLevel, objects int) {},
+			SpansDiscarded:   func(spans int) {},
+		})
+
+		_, err = c.Compact(ctx, l, r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, inputs)
+		require.NoError(b, err)
+	}
+}
+
+// createTestBlock with the number of given traces and the needed sizes.
+// Trace IDs are guaranteed to be monotonically increasing so that
+// the block will be iterated in order.
+// nolint: revive
+func createTestBlock(t testing.TB, ctx context.Context, cfg *common.BlockConfig, r backend.Reader, w backend.Writer, traceCount, batchCount, spanCount int) *backend.BlockMeta {
+	inMeta := &backend.BlockMeta{
+		TenantID:     tenantID,
+		BlockID:      uuid.New(),
+		TotalObjects: traceCount,
+	}
+
+	sb, err := newStreamingBlock(ctx, cfg, inMeta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+
+	for i := 0; i < traceCount; i++ {
+		id := make([]byte, 16)
+		binary.LittleEndian.PutUint64(id, uint64(i))
+
+		tr := test.MakeTraceWithSpanCount(batchCount, spanCount, id)
+		trp := traceToParquet(id, tr)
+
+		sb.Add(&trp, 0, 0)
+		if sb.EstimatedBufferedBytes() > 20_000_000 {
+			_, err := sb.Flush()
+			require.NoError(t, err)
+		}
+	}
+
+	_, err = sb.Complete()
+	require.NoError(t, err)
+
+	return sb.meta
+}
+
+func TestCompactorCombineStrategies(t *testing.T) {
+	tests := []struct {
+		strategy         common.CombineStrategy
+		maxBytesPerTrace int
+		expectedSpans    func(spans int) bool
+	}{
+		{common.CombineStrategyMergeAll, 1, func(spans int) bool { return spans == 40 }},
+		{common.CombineStrategyLatestWins, 0, func(spans int) bool { return spans == 20 }},
+		{common.CombineStrategySizeCapped, 0, func(spans int) bool { return spans == 40 }},
+		{common.CombineStrategySizeCapped, 2_000, func(spans int) bool { return spans > 0 && spans < 40 }},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tc.strategy, tc.maxBytesPerTrace), func(t *testing.T) {
+			rawR, rawW, _, err := local.New(&local.Config{
+				Path: t.TempDir(),
+			})
+			require.NoError(t, err)
+
+			r := backend.NewReader(rawR)
+			w := backend.NewWriter(rawW)
+			ctx := context.Background()
+
+			cfg := &common.BlockConfig{
+				BloomFP:             0.01,
+				BloomShardSizeBytes: 100 * 1024,
+				RowGroupSizeBytes:   20_000_000,
+			}
+
+			// both blocks contain different spans of the same trace
+			inputs := []*backend.BlockMeta{
+				createTestBlock(t, ctx, cfg, r, w, 1, 2, 10),
+				createTestBlock(t, ctx, cfg, r, w, 1, 2, 10),
+			}
+
+			discarded, truncated := 0, 0
+			c := NewCompactor(common.CompactionOptions{
+				BlockConfig:      *cfg,
+				OutputBlocks:     1,
+				FlushSizeBytes:   30_000_000,
+				MaxBytesPerTrace: tc.maxBytesPerTrace,
+				CombineStrategy:  tc.strategy,
+				ObjectsCombined:  func(compactionLevel, objects int) {},
+				SpansDiscarded:   func(spans int) { discarded += spans },
+				TraceTruncated:   func() { truncated++ },
+			})
+
+			metas, err := c.Compact(ctx, log.NewNopLogger(), r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, inputs)
+			require.NoError(t, err)
+			require.Len(t, metas, 1)
+
+			tr, err := newBackendBlock(metas[0], r).FindTraceByID(ctx, make([]byte, 16), common.SearchOptions{})
+			require.NoError(t, err)
+			require.NotNil(t, tr)
+
+			spans := 0
+			for _, b := range tr.Batches {
+				for _, ils := range b.InstrumentationLibrarySpans {
+					spans += len(ils.Spans)
+				}
+			}
+			require.True(t, tc.expectedSpans(spans), "unexpected span count %d", spans)
+
+			// truncation is recorded
+			if spans < 40 && tc.strategy == common.CombineStrategySizeCapped {
+				require.Equal(t, 40, spans+discarded)
+				require.Equal(t, 1, truncated)
+			} else {
+				require.Equal(t, 0, truncated)
+			}
+		})
+	}
+}
+
+func TestCompactorServiceNames(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+		RowGroupSizeBytes:   20_000_000,
+		MaxServiceNames:     10,
+	}
+
+	inputs := []*backend.BlockMeta{
+		createTestBlock(t, ctx, cfg, r, w, 10, 2, 10),
+		createTestBlock(t, ctx, cfg, r, w, 10, 2, 10),
+	}
+	require.Equal(t, []string{"test-service"}, inputs[0].ServiceNames)
+
+	c := NewCompactor(common.CompactionOptions{
+		BlockConfig:     *cfg,
+		OutputBlocks:    1,
+		FlushSizeBytes:  30_000_000,
+		ObjectsCombined: func(compactionLevel, objects int) {},
+	})
+
+	// the service names are recorded from the raw rows of the input blocks
+	metas, err := c.Compact(ctx, log.NewNopLogger(), r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, inputs)
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.Equal(t, []string{"test-service"}, metas[0].ServiceNames)
+}
+
+func TestValueAlloc(t *testing.T) {
+	_ = make([]parquet.Value, 1_000_000)
+}
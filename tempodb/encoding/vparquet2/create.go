@@ -0,0 +1,807 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/pkg/errors"
+	"github.com/segmentio/parquet-go"
+)
+
+type backendWriter struct {
+	ctx      context.Context
+	w        backend.Writer
+	name     string
+	blockID  uuid.UUID
+	tenantID string
+	tracker  backend.AppendTracker
+}
+
+var _ io.WriteCloser = (*backendWriter)(nil)
+
+func (b *backendWriter) Write(p []byte) (n int, err error) {
+	b.tracker, err = b.w.Append(b.ctx, b.name, b.blockID, b.tenantID, b.tracker, p)
+	return len(p), err
+}
+
+func (b *backendWriter) Close() error {
+	return b.w.CloseAppend(b.ctx, b.tracker)
+}
+
+// encodeBatchSize is the number of objects read from the iterator and encoded at once if encoding is concurrent.
+const encodeBatchSize = 1000
+
+// encodeItem is an object of a batch and the trace it is encoded to.
+type encodeItem struct {
+	id  common.ID
+	obj []byte
+	tr  Trace
+}
+
+func CreateBlock(ctx context.Context, cfg *common.BlockConfig, meta *backend.BlockMeta, i common.Iterator, dec model.ObjectDecoder, r backend.Reader, to backend.Writer) (*backend.BlockMeta, error) {
+	s, err := newStreamingBlock(ctx, cfg, meta, r, to, tempo_io.NewBufferedWriter)
+	if err != nil {
+		return nil, err
+	}
+
+	// wal blocks in the parquet format are completed without decoding the objects again
+	if wi, ok := i.(*walObjectIterator); ok {
+		return createBlockFromTraces(ctx, cfg, s, wi.iter)
+	}
+
+	// objects are decoded and converted to parquet in batches, the batch is added to the block in order.
+	concurrency := cfg.EncodeConcurrency
+	batchSize := encodeBatchSize
+	if concurrency <= 1 {
+		concurrency = 1
+		batchSize = 1
+	}
+	batch := make([]encodeItem, 0, batchSize)
+
+	for {
+		batch, err = readBatch(ctx, i, batch[:0], batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		err = encodeBatch(batch, dec, concurrency)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := range batch {
+			s.Add(&batch[j].tr, 0, 0) // start and end time of the wal meta are used.
+
+			// Here we repurpose RowGroupSizeBytes as number of raw column values.
+			// This is a fairly close approximation.
+			if s.EstimatedBufferedBytes() > cfg.RowGroupSizeBytes {
+				_, err = s.Flush()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// the traces are referenced by the block until they are flushed
+		batch = make([]encodeItem, 0, batchSize)
+	}
+
+	_, err = s.Complete()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.meta, nil
+}
+
+func createBlockFromTraces(ctx context.Context, cfg *common.BlockConfig, s *streamingBlock, i Iterator) (*backend.BlockMeta, error) {
+	for {
+		tr, err := i.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if tr == nil {
+			break
+		}
+
+		s.Add(tr, 0, 0) // start and end time of the wal meta are used.
+
+		if s.EstimatedBufferedBytes() > cfg.RowGroupSizeBytes {
+			_, err = s.Flush()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	_, err := s.Complete()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.meta, nil
+}
+
+// readBatch reads up to size objects from the iterator. Objects are copied if more than one is read, the
+// iterator may reuse their buffers.
+func readBatch(ctx context.Context, i common.Iterator, batch []encodeItem, size int) ([]encodeItem, error) {
+	for len(batch) < size {
+		id, obj, err := i.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if size > 1 {
+			obj = append([]byte(nil), obj...)
+		}
+
+		// Copy ID to allow it to escape the iterator.
+		batch = append(batch, encodeItem{
+			id:  append([]byte(nil), id...),
+			obj: obj,
+		})
+	}
+	return batch, nil
+}
+
+// encodeBatch decodes the objects of the batch and converts them to parquet traces using up to concurrency
+// goroutines.
+func encodeBatch(batch []encodeItem, dec model.ObjectDecoder, concurrency int) error {
+	encode := func(items []encodeItem) error {
+		for j := range items {
+			tr, err := dec.PrepareForRead(items[j].obj)
+			if err != nil {
+				return err
+			}
+			items[j].tr = traceToParquet(items[j].id, tr)
+			items[j].obj = nil
+		}
+		return nil
+	}
+
+	if concurrency == 1 || len(batch) == 1 {
+		return encode(batch)
+	}
+
+	chunk := (len(batch) + concurrency - 1) / concurrency
+	errs := make([]error, concurrency)
+	wg := sync.WaitGroup{}
+	for c := 0; c < concurrency && c*chunk < len(batch); c++ {
+		end := (c + 1) * chunk
+		if end > len(batch) {
+			end = len(batch)
+		}
+
+		wg.Add(1)
+		go func(c int, items []encodeItem) {
+			defer wg.Done()
+			errs[c] = encode(items)
+		}(c, batch[c*chunk:end])
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type streamingBlock struct {
+	ctx   context.Context
+	bloom *common.ShardedBloomFilter
+	meta  *backend.BlockMeta
+	bw    tempo_io.BufferedWriteFlusher
+	pw    *parquet.GenericWriter[*Trace]
+	w     *backendWriter
+	r     backend.Reader
+	to    backend.Writer
+
+	// split writes the hot and cold files of blocks with split columns, bw, pw and w are nil then
+	split *splitWriter
+	// spill writes the data file of blocks with a write budget to a temporary file, bw and w are nil then
+	spill *spillWriter
+	// err is the first error writing the buffered traces of blocks with a write budget in Add
+	err error
+
+	bufferedTraces        []*Trace
+	bufferedTracesBytes   int
+	maxBufferedBytes      int
+	currentBufferedTraces int
+	currentBufferedBytes  int
+
+	// serviceNames are the services of the traces added to the block. nil if they aren't recorded or the block
+	// has more than the max service names.
+	serviceNames      map[string]struct{}
+	maxServiceNames   int
+	serviceNameColumn int
+
+	// serviceIndex builds the service index of the block, nil if it isn't written
+	serviceIndex *serviceIndexWriter
+	// zoneMaps builds the zone maps of the block, nil if they aren't written
+	zoneMaps *zoneMapsWriter
+	// stats builds the statistics of the block, nil if they aren't written
+	stats *statsWriter
+	// durationSketches builds the span duration sketches of the services of the block, nil if they aren't recorded
+	durationSketches *durationSketchesWriter
+}
+
+func newStreamingBlock(ctx context.Context, cfg *common.BlockConfig, meta *backend.BlockMeta, r backend.Reader, to backend.Writer, createBufferedWriter func(w io.Writer) tempo_io.BufferedWriteFlusher) (*streamingBlock, error) {
+	newMeta := backend.NewBlockMeta(meta.TenantID, meta.BlockID, VersionString, backend.EncNone, "")
+	newMeta.StartTime = meta.StartTime
+	newMeta.EndTime = meta.EndTime
+	newMeta.BloomFP = cfg.BloomFP
+
+	// TotalObjects is used here an an estimated count for the bloom filter.
+	// The real number of objects is tracked below.
+	bloom := common.NewAdaptiveBloom(cfg.BloomFP, uint(cfg.BloomShardSizeBytes), uint(meta.TotalObjects))
+
+	opts, err := writerOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &streamingBlock{
+		ctx:               ctx,
+		meta:              newMeta,
+		bloom:             bloom,
+		r:                 r,
+		to:                to,
+		bufferedTraces:    make([]*Trace, 0, 1000),
+		maxServiceNames:   cfg.MaxServiceNames,
+		serviceNameColumn: -1,
+	}
+
+	sch := parquet.SchemaOf(new(Trace))
+	if cfg.ParquetSplitHotColumns {
+		sch, err = writerSchema(cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.split = newSplitWriter(ctx, to, meta, sch, opts, createBufferedWriter)
+	} else if cfg.ParquetWriteBudgetBytes > 0 {
+		s.spill, err = newSpillWriter(cfg.ParquetSpillPath)
+		if err != nil {
+			return nil, err
+		}
+		s.maxBufferedBytes = cfg.ParquetWriteBudgetBytes / 2
+		opts = append(opts, parquet.ColumnPageBuffers(s.spill.pool), parquet.PageBufferSize(spillPageSize(cfg, sch)))
+		s.pw = parquet.NewGenericWriter[*Trace](s.spill, opts...)
+	} else {
+		s.w = &backendWriter{ctx, to, DataFileName, meta.BlockID, meta.TenantID, nil}
+		s.bw = createBufferedWriter(s.w)
+		s.pw = parquet.NewGenericWriter[*Trace](s.bw, opts...)
+	}
+
+	if cfg.MaxServiceNames > 0 {
+		s.serviceNames = map[string]struct{}{}
+	}
+	if cfg.ParquetServiceIndex {
+		s.serviceIndex = newServiceIndexWriter()
+	}
+	if cfg.ParquetZoneMaps {
+		s.zoneMaps = newZoneMapsWriter(sch)
+	}
+	if cfg.ParquetStats {
+		s.stats = newStatsWriter()
+	}
+	if cfg.ParquetDurationSketches {
+		s.durationSketches = newDurationSketchesWriter(cfg.MaxServiceNames)
+	}
+	if s.serviceNames != nil || s.serviceIndex != nil {
+		if col, found := sch.Lookup("rs", "Resource", "ServiceName"); found {
+			s.serviceNameColumn = col.ColumnIndex
+		}
+	}
+
+	return s, nil
+}
+
+func (b *streamingBlock) Add(tr *Trace, start, end uint32) {
+	b.bufferedTraces = append(b.bufferedTraces, tr)
+	id := tr.TraceID
+
+	b.bloom.Add(id)
+	b.meta.ObjectAdded(id, start, end)
+	size := estimateTraceSize(tr)
+	b.currentBufferedTraces++
+	b.currentBufferedBytes += size
+	b.bufferedTracesBytes += size
+
+	// blocks with a write budget write the traces to the pages of the row group early, the pages are spilled
+	if b.maxBufferedBytes > 0 && b.bufferedTracesBytes > b.maxBufferedBytes && b.err == nil {
+		b.err = b.flushBufferedTraces()
+	}
+
+	if b.serviceNames != nil || b.serviceIndex != nil {
+		for _, rs := range tr.ResourceSpans {
+			b.addServiceName(rs.Resource.ServiceName)
+		}
+	}
+	if b.serviceIndex != nil {
+		b.serviceIndex.addRow()
+	}
+	if b.zoneMaps != nil {
+		b.zoneMaps.addTrace(tr)
+	}
+	if b.stats != nil {
+		b.stats.addTrace(tr)
+	}
+	if b.durationSketches != nil {
+		b.durationSketches.addTrace(tr)
+	}
+}
+
+func (b *streamingBlock) AddRaw(id []byte, row parquet.Row, start, end uint32) error {
+	var err error
+	if b.split != nil {
+		err = b.split.writeRow(row)
+	} else {
+		_, err = b.pw.WriteRows([]parquet.Row{row})
+	}
+	if err != nil {
+		return err
+	}
+
+	b.bloom.Add(id)
+	b.meta.ObjectAdded(id, start, end)
+	b.currentBufferedTraces++
+	b.currentBufferedBytes += estimateProtoSize(row)
+
+	if (b.serviceNames != nil || b.serviceIndex != nil) && b.serviceNameColumn >= 0 {
+		for _, v := range row {
+			if v.Column() == b.serviceNameColumn && !v.IsNull() {
+				b.addServiceName(string(v.ByteArray()))
+			}
+		}
+	}
+	if b.serviceIndex != nil {
+		b.serviceIndex.addRow()
+	}
+	if b.zoneMaps != nil {
+		b.zoneMaps.addRow(row)
+	}
+	if b.stats != nil {
+		b.stats.addRow(row)
+	}
+	if b.durationSketches != nil {
+		b.durationSketches.addRow(row)
+	}
+
+	return nil
+}
+
+// addServiceName records a service of the block. Once the block has more than the max service names they are no
+// longer recorded in the meta, the service index records all of them.
+func (b *streamingBlock) addServiceName(name string) {
+	if name == "" {
+		return
+	}
+
+	if b.serviceIndex != nil {
+		b.serviceIndex.addService(name)
+	}
+	if b.serviceNames == nil {
+		return
+	}
+
+	b.serviceNames[name] = struct{}{}
+	if len(b.serviceNames) > b.maxServiceNames {
+		b.serviceNames = nil
+	}
+}
+
+func (b *streamingBlock) EstimatedBufferedBytes() int {
+	return b.currentBufferedBytes
+}
+
+func (b *streamingBlock) CurrentBufferedObjects() int {
+	return b.currentBufferedTraces
+}
+
+func (b *streamingBlock) Flush() (int, error) {
+	if b.err != nil {
+		return 0, fmt.Errorf("writing buffered traces: %w", b.err)
+	}
+
+	// batch write traces
+	if err := b.flushBufferedTraces(); err != nil {
+		return 0, fmt.Errorf("flushing buffered traces: %w", err)
+	}
+
+	if b.serviceIndex != nil {
+		b.serviceIndex.flushRowGroup()
+	}
+	if b.zoneMaps != nil {
+		b.zoneMaps.flushRowGroup()
+	}
+
+	if b.split != nil {
+		n, err := b.split.flush()
+		b.meta.Size += uint64(n)
+		b.meta.TotalRecords++
+		b.currentBufferedTraces = 0
+		b.currentBufferedBytes = 0
+		return n, err
+	}
+
+	// Flush row group
+	err := b.pw.Flush()
+	if err != nil {
+		return 0, err
+	}
+
+	if b.spill != nil {
+		n := b.spill.flush()
+		b.meta.Size += uint64(n)
+		b.meta.TotalRecords++
+		b.currentBufferedTraces = 0
+		b.currentBufferedBytes = 0
+		return n, nil
+	}
+
+	n := b.bw.Len()
+	b.meta.Size += uint64(n)
+	b.meta.TotalRecords++
+	b.currentBufferedTraces = 0
+	b.currentBufferedBytes = 0
+
+	// Flush to underlying writer
+	return n, b.bw.Flush()
+}
+
+func (b *streamingBlock) Complete() (int, error) {
+	if b.spill != nil {
+		defer b.spill.close()
+	}
+	if b.err != nil {
+		return 0, fmt.Errorf("writing buffered traces: %w", b.err)
+	}
+
+	// batch write traces
+	if err := b.flushBufferedTraces(); err != nil {
+		return 0, fmt.Errorf("flushing buffered traces: %w", err)
+	}
+
+	// Flush final row group
+	b.meta.TotalRecords++
+
+	if b.split != nil {
+		return b.completeSplit()
+	}
+	if b.spill != nil {
+		return b.completeSpill()
+	}
+
+	err := b.pw.Flush()
+	if err != nil {
+		return 0, err
+	}
+
+	// Close parquet file. This writes the footer and metadata.
+	err = b.pw.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	// Now Flush and close out in-memory buffer
+	n := b.bw.Len()
+	b.meta.Size += uint64(n)
+	err = b.bw.Flush()
+	if err != nil {
+		return 0, err
+	}
+
+	err = b.bw.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	err = b.w.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	b.meta.FooterSize, err = b.readFooterSize(DataFileName, b.meta.Size)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, b.writeMeta()
+}
+
+// completeSplit closes the hot and cold files of a block with split columns and writes the meta
+func (b *streamingBlock) completeSplit() (int, error) {
+	n, err := b.split.complete()
+	if err != nil {
+		return 0, err
+	}
+	b.meta.Size += uint64(n)
+	b.meta.HotSize = b.split.hot.size
+
+	b.meta.HotFooterSize, err = b.readFooterSize(HotFileName, b.split.hot.size)
+	if err != nil {
+		return 0, err
+	}
+	b.meta.FooterSize, err = b.readFooterSize(ColdFileName, b.split.cold.size)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, b.writeMeta()
+}
+
+// completeSpill closes the data file of a block with a write budget, uploads it and writes the meta
+func (b *streamingBlock) completeSpill() (int, error) {
+	err := b.pw.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	n := b.spill.flush()
+	b.meta.Size = uint64(b.spill.size)
+
+	err = b.spill.upload(b.ctx, b.to, b.meta.BlockID, b.meta.TenantID)
+	if err != nil {
+		return 0, fmt.Errorf("error uploading spilled data file: %w", err)
+	}
+
+	b.meta.FooterSize, err = b.readFooterSize(DataFileName, b.meta.Size)
+	if err != nil {
+		return 0, err
+	}
+
+	return n, b.writeMeta()
+}
+
+// readFooterSize reads the footer size out of the parquet footer of a file of the block
+func (b *streamingBlock) readFooterSize(name string, size uint64) (uint32, error) {
+	buf := make([]byte, 8)
+	err := b.r.ReadRange(b.ctx, name, b.meta.BlockID, b.meta.TenantID, size-8, buf, false)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading parquet file footer")
+	}
+	if string(buf[4:8]) != "PAR1" {
+		return 0, errors.New("Failed to confirm magic footer while writing a new parquet block")
+	}
+	return binary.LittleEndian.Uint32(buf[0:4]), nil
+}
+
+func (b *streamingBlock) writeMeta() error {
+	b.meta.BloomShardCount = uint16(b.bloom.GetShardCount())
+
+	if len(b.serviceNames) > 0 {
+		b.meta.ServiceNames = make([]string, 0, len(b.serviceNames))
+		for name := range b.serviceNames {
+			b.meta.ServiceNames = append(b.meta.ServiceNames, name)
+		}
+		sort.Strings(b.meta.ServiceNames)
+	}
+
+	if b.serviceIndex != nil {
+		idx, err := b.serviceIndex.marshal()
+		if err != nil {
+			return err
+		}
+		err = b.to.Write(b.ctx, ServiceIndexFileName, b.meta.BlockID, b.meta.TenantID, idx, true)
+		if err != nil {
+			return fmt.Errorf("unexpected error writing service index %w", err)
+		}
+		b.meta.ServiceIndex = true
+	}
+
+	if b.zoneMaps != nil {
+		z, err := b.zoneMaps.marshal()
+		if err != nil {
+			return err
+		}
+		err = b.to.Write(b.ctx, ZoneMapsFileName, b.meta.BlockID, b.meta.TenantID, z, true)
+		if err != nil {
+			return fmt.Errorf("unexpected error writing zone maps %w", err)
+		}
+		b.meta.ZoneMaps = true
+	}
+
+	if b.stats != nil {
+		err := b.writeStats()
+		if err != nil {
+			return fmt.Errorf("unexpected error writing stats %w", err)
+		}
+		b.meta.Stats = true
+	}
+
+	if b.durationSketches != nil {
+		sketches, err := b.durationSketches.marshal()
+		if err != nil {
+			return err
+		}
+		if sketches != nil {
+			err = b.to.Write(b.ctx, DurationSketchesFileName, b.meta.BlockID, b.meta.TenantID, sketches, true)
+			if err != nil {
+				return fmt.Errorf("unexpected error writing duration sketches %w", err)
+			}
+			b.meta.DurationSketches = true
+		}
+	}
+
+	return writeBlockMeta(b.ctx, b.to, b.meta, b.bloom)
+}
+
+// writeStats writes the statistics of the block with the column sizes read from the footers of its files
+func (b *streamingBlock) writeStats() error {
+	type file struct {
+		name       string
+		size       uint64
+		footerSize uint32
+	}
+	files := []file{{DataFileName, b.meta.Size, b.meta.FooterSize}}
+	if b.split != nil {
+		files = []file{
+			{HotFileName, b.meta.HotSize, b.meta.HotFooterSize},
+			{ColdFileName, b.meta.Size - b.meta.HotSize, b.meta.FooterSize},
+		}
+	}
+
+	sizes := map[string]uint64{}
+	block := newBackendBlock(b.meta, b.r)
+	for _, f := range files {
+		pf, _, err := block.openParquetFile(b.ctx, f.name, f.size, f.footerSize, common.SearchOptions{}, parquet.SkipPageIndex(true), parquet.SkipBloomFilters(true))
+		if err != nil {
+			return err
+		}
+		columnSizes(pf, sizes)
+	}
+
+	stats, err := b.stats.marshal(sizes)
+	if err != nil {
+		return err
+	}
+	return b.to.Write(b.ctx, StatsFileName, b.meta.BlockID, b.meta.TenantID, stats, true)
+}
+
+func (b *streamingBlock) flushBufferedTraces() error {
+	// batch write traces
+	if len(b.bufferedTraces) > 0 {
+		var err error
+		if b.split != nil {
+			err = b.split.writeTraces(b.bufferedTraces)
+		} else {
+			_, err = b.pw.Write(b.bufferedTraces)
+		}
+		if err != nil {
+			return err
+		}
+		// zero out traces to allow the GC to collect
+		for i := range b.bufferedTraces {
+			b.bufferedTraces[i] = nil
+		}
+		b.bufferedTraces = b.bufferedTraces[:0]
+		b.bufferedTracesBytes = 0
+	}
+
+	return nil
+}
+
+// estimateTraceSize attempts to estimate the size of trace in bytes. This is used to make choose
+// when to cut a row group during block creation.
+// TODO: This function regularly estimates lower values then estimateProtoSize() and the size
+// of the actual proto. It's also quite inefficient. Perhaps just using static values per span or attribute
+// would be a better choice?
+func estimateTraceSize(tr *Trace) (size int) {
+	size += len(tr.TraceID)
+	size += len(tr.TraceIDText)
+	size += len(tr.RootServiceName)
+	size += len(tr.RootSpanName)
+	size += 8 + 8 + 8 // start/end/duration
+	size += 7
+	for _, name := range tr.ServiceNames {
+		size += len(name)
+	}
+	size += 8 + 8 // span/error count
+
+	for _, rs := range tr.ResourceSpans {
+		size += estimateAttrSize(rs.Resource.Attrs)
+		size += len(rs.Resource.ServiceName)
+		size += strLen(rs.Resource.Namespace)
+		size += strLen(rs.Resource.Cluster)
+		size += strLen(rs.Resource.Pod)
+		size += strLen(rs.Resource.Container)
+		size += strLen(rs.Resource.K8sClusterName)
+		size += strLen(rs.Resource.K8sContainerName)
+		size += strLen(rs.Resource.K8sNamespaceName)
+		size += strLen(rs.Resource.K8sPodName)
+		size += 9
+
+		for _, ils := range rs.InstrumentationLibrarySpans {
+			size += len(ils.InstrumentationLibrary.Name)
+			size += len(ils.InstrumentationLibrary.Version)
+			size += 2
+			for _, s := range ils.Spans {
+				size += 8 + 8 + 8 + 8 + 4 + 4 // start/end/kind/statuscode/dropped events/dropped attrs
+				size += len(s.ID)
+				size += len(s.ParentSpanID)
+				size += len(s.Name)
+				size += strLen(s.HttpMethod)
+				size += strLen(s.HttpUrl)
+				size += len(s.StatusMessage)
+				size += len(s.TraceState)
+				if s.HttpStatusCode != nil {
+					size += 8
+				}
+				size += estimateAttrSize(s.Attrs)
+				size += estimateEventsSize(s.Events)
+				size += estimateLinksSize(s.Links)
+				size += 14
+			}
+		}
+	}
+	return
+}
+
+func estimateAttrSize(attrs []Attribute) (size int) {
+	for _, a := range attrs {
+		size += len(a.Key)
+		size += strLen(a.Value)
+		size += len(a.ValueArray)
+		for _, v := range a.ValueArrayString {
+			size += len(v)
+		}
+		size += 8*len(a.ValueArrayInt) + 8*len(a.ValueArrayDouble) + len(a.ValueArrayBool)
+		size += len(a.ValueKVList)
+		if a.ValueBool != nil {
+			size++
+		}
+		if a.ValueDouble != nil {
+			size += 8
+		}
+		if a.ValueInt != nil {
+			size += 8
+		}
+	}
+	return
+}
+
+func estimateEventsSize(events []Event) (size int) {
+	for _, e := range events {
+		size += 8 + 4 // time/dropped attributes
+		size += len(e.Name)
+		size += strLen(e.ExceptionType)
+		size += strLen(e.ExceptionMessage)
+		size += estimateAttrSize(e.Attrs)
+	}
+	return
+}
+
+func estimateLinksSize(links []Link) (size int) {
+	for _, l := range links {
+		size += 4 // dropped attributes
+		size += len(l.TraceID)
+		size += len(l.SpanID)
+		size += len(l.TraceState)
+		size += estimateAttrSize(l.Attrs)
+	}
+	return
+}
+
+func strLen(s *string) (size int) {
+	if s == nil {
+		return 0
+	}
+	return len(*s)
+}
@@ -0,0 +1,241 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/grafana/tempo/pkg/model"
+	v2 "github.com/grafana/tempo/pkg/model/v2"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBlockHonorsTraceStartEndTimesFromWalMeta(t *testing.T) {
+	ctx := context.Background()
+
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+
+	iter := newTestIterator()
+
+	iter.Add(test.MakeTrace(10, nil), 100, 401)
+	iter.Add(test.MakeTrace(10, nil), 101, 402)
+	iter.Add(test.MakeTrace(10, nil), 102, 403)
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+	}
+
+	meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = 1
+	meta.StartTime = time.Unix(300, 0)
+	meta.EndTime = time.Unix(305, 0)
+
+	outMeta, err := CreateBlock(ctx, cfg, meta, iter, iter.decoder, r, w)
+	require.NoError(t, err)
+	require.Equal(t, 300, int(outMeta.StartTime.Unix()))
+	require.Equal(t, 305, int(outMeta.EndTime.Unix()))
+}
+
+func TestCreateBlockServiceNames(t *testing.T) {
+	tests := []struct {
+		maxServiceNames int
+		expected        []string
+	}{
+		{0, nil},
+		{2, nil},
+		{3, []string{"svc-0", "svc-1", "svc-2"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%d", tc.maxServiceNames), func(t *testing.T) {
+			ctx := context.Background()
+
+			rawR, rawW, _, err := local.New(&local.Config{
+				Path: t.TempDir(),
+			})
+			require.NoError(t, err)
+
+			r := backend.NewReader(rawR)
+			w := backend.NewWriter(rawW)
+
+			iter := newTestIterator()
+			for i := 2; i >= 0; i-- {
+				tr := test.MakeTrace(2, nil)
+				for _, b := range tr.Batches {
+					b.Resource.Attributes[0].Value.Value = &v1.AnyValue_StringValue{StringValue: fmt.Sprintf("svc-%d", i)}
+				}
+				iter.Add(tr, 100, 101)
+			}
+
+			cfg := &common.BlockConfig{
+				BloomFP:             0.01,
+				BloomShardSizeBytes: 100 * 1024,
+				MaxServiceNames:     tc.maxServiceNames,
+			}
+
+			meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+			meta.TotalObjects = 3
+
+			outMeta, err := CreateBlock(ctx, cfg, meta, iter, iter.decoder, r, w)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, outMeta.ServiceNames)
+		})
+	}
+}
+
+func TestCreateBlockEncodeConcurrency(t *testing.T) {
+	ctx := context.Background()
+
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+
+	ids := make([]common.ID, 0, 2500)
+	traces := make([]*tempopb.Trace, 0, cap(ids))
+	for i := 0; i < cap(ids); i++ {
+		id := make([]byte, 16)
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+		ids = append(ids, id)
+		traces = append(traces, test.MakeTrace(2, id))
+	}
+
+	// the same block is created sequentially and concurrently
+	var data [][]byte
+	for _, concurrency := range []int{0, 4} {
+		iter := newTestIterator()
+		for i, id := range ids {
+			iter.AddWithID(id, traces[i], 100, 101)
+		}
+
+		cfg := &common.BlockConfig{
+			BloomFP:             0.01,
+			BloomShardSizeBytes: 100 * 1024,
+			RowGroupSizeBytes:   100_000,
+			EncodeConcurrency:   concurrency,
+		}
+
+		meta := backend.NewBlockMeta("fake", uuid.New(), VersionString, backend.EncNone, "")
+		meta.TotalObjects = len(ids)
+
+		outMeta, err := CreateBlock(ctx, cfg, meta, iter, iter.decoder, r, w)
+		require.NoError(t, err)
+		require.Equal(t, len(ids), outMeta.TotalObjects)
+		require.Greater(t, int(outMeta.TotalRecords), 1)
+
+		b, err := r.Read(ctx, DataFileName, outMeta.BlockID, outMeta.TenantID, false)
+		require.NoError(t, err)
+		data = append(data, b)
+
+		block := newBackendBlock(outMeta, r)
+		for _, id := range []common.ID{ids[0], ids[1234], ids[len(ids)-1]} {
+			tr, err := block.FindTraceByID(ctx, id, common.SearchOptions{})
+			require.NoError(t, err)
+			require.NotNil(t, tr)
+		}
+	}
+
+	require.Equal(t, data[0], data[1])
+}
+
+// func TestEstimateTraceSize(t *testing.T) {
+// 	f := "<put data.parquet file here>"
+// 	file, err := os.OpenFile(f, os.O_RDONLY, 0644)
+// 	require.NoError(t, err)
+
+// 	count := 10000
+
+// 	totalProtoSz := 0
+// 	totalParqSz := 0
+
+// 	r := parquet.NewGenericReader[*Trace](file)
+// 	tr := make([]*Trace, 1)
+// 	for {
+// 		count--
+// 		if count == 0 {
+// 			break
+// 		}
+
+// 		_, err := r.Read(tr)
+// 		require.NoError(t, err)
+
+// 		if tr[0] == nil {
+// 			break
+// 		}
+// 		protoTr, err := parquetTraceToTempopbTrace(tr[0])
+// 		require.NoError(t, err)
+
+// 		protoSz := protoTr.Size()
+// 		parqSz := estimateTraceSize(tr[0])
+
+// 		totalProtoSz += protoSz
+// 		totalParqSz += parqSz
+
+// 		if float64(parqSz)/float64(protoSz) < .7 ||
+// 			float64(parqSz)/float64(protoSz) > 1.3 {
+// 			fmt.Println(protoTr)
+// 			break
+// 		}
+// 	}
+// 	fmt.Println(totalParqSz, totalProtoSz)
+// }
+
+type testIterator struct {
+	ids     []common.ID
+	traces  [][]byte
+	decoder model.ObjectDecoder
+	segment model.SegmentDecoder
+}
+
+var _ common.Iterator = (*testIterator)(nil)
+
+func newTestIterator() *testIterator {
+	return &testIterator{
+		decoder: v2.NewObjectDecoder(),
+		segment: v2.NewSegmentDecoder(),
+	}
+}
+
+func (i *testIterator) Add(tr *tempopb.Trace, start, end uint32) {
+	b, _ := i.segment.PrepareForWrite(tr, start, end)
+	b2, _ := i.segment.ToObject([][]byte{b})
+	i.ids = append(i.ids, nil)
+	i.traces = append(i.traces, b2)
+}
+
+func (i *testIterator) AddWithID(id common.ID, tr *tempopb.Trace, start, end uint32) {
+	i.Add(tr, start, end)
+	i.ids[len(i.ids)-1] = id
+}
+
+func (i *testIterator) Next(ctx context.Context) (common.ID, []byte, error) {
+	if len(i.traces) == 0 {
+		return nil, nil, io.EOF
+	}
+	id, tr := i.ids[0], i.traces[0]
+	i.ids, i.traces = i.ids[1:], i.traces[1:]
+	return id, tr, nil
+}
+
+func (i *testIterator) Close() {
+}
@@ -0,0 +1,173 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/parquet-go"
+
+	"github.com/grafana/tempo/pkg/ddsketch"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// DurationSketchesFileName are the span duration sketches by service of blocks written with
+// BlockConfig.ParquetDurationSketches
+const DurationSketchesFileName = "duration_sketches.json"
+
+// defaultMaxDurationSketches is the max number of services with duration sketches if the max service names aren't
+// configured. Every sketch adds up to a few KB to the block.
+const defaultMaxDurationSketches = 100
+
+var _ common.DurationSketched = (*backendBlock)(nil)
+
+// DurationSketches returns the span duration sketches by service recorded when the block was written
+func (b *backendBlock) DurationSketches(ctx context.Context) (map[string]*ddsketch.Sketch, error) {
+	if !b.meta.DurationSketches {
+		return nil, common.ErrUnsupported
+	}
+
+	buf, err := b.r.Read(ctx, DurationSketchesFileName, b.meta.BlockID, b.meta.TenantID, true)
+	if err != nil {
+		return nil, fmt.Errorf("error reading duration sketches: %w", err)
+	}
+
+	sketches := map[string]*ddsketch.Sketch{}
+	if err := json.Unmarshal(buf, &sketches); err != nil {
+		return nil, fmt.Errorf("error unmarshalling duration sketches: %w", err)
+	}
+	return sketches, nil
+}
+
+// durationSketchesWriter builds sketches of the span durations of each service of a block while its rows are added.
+// The sketches are dropped once the block has more than the max services, a partial set of sketches would silently
+// answer queries wrong.
+type durationSketchesWriter struct {
+	sketches map[string]*ddsketch.Sketch
+	max      int
+
+	serviceName    int
+	spanStart      int
+	spanEnd        int
+	spanStartLevel int
+
+	// columns are the values of the current row of the columns above
+	columns map[int][]parquet.Value
+}
+
+func newDurationSketchesWriter(max int) *durationSketchesWriter {
+	if max <= 0 {
+		max = defaultMaxDurationSketches
+	}
+
+	sch := parquet.SchemaOf(new(Trace))
+	w := &durationSketchesWriter{
+		sketches: map[string]*ddsketch.Sketch{},
+		max:      max,
+		columns:  map[int][]parquet.Value{},
+	}
+
+	lookup := func(path string) int {
+		col, found := sch.Lookup(strings.Split(path, ".")...)
+		if !found {
+			return -1
+		}
+		w.columns[col.ColumnIndex] = nil
+		return col.ColumnIndex
+	}
+
+	w.serviceName = lookup(labelMappings[LabelServiceName])
+	w.spanStart = lookup(columnSpanStart)
+	w.spanEnd = lookup(columnSpanEnd)
+	if col, found := sch.Lookup(strings.Split(columnSpanStart, ".")...); found {
+		w.spanStartLevel = col.MaxDefinitionLevel
+	}
+	return w
+}
+
+// addTrace adds the span durations of the next trace
+func (w *durationSketchesWriter) addTrace(tr *Trace) {
+	if w.sketches == nil {
+		return
+	}
+	for _, rs := range tr.ResourceSpans {
+		for _, ils := range rs.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				w.add(rs.Resource.ServiceName, s.StartUnixNanos, s.EndUnixNanos)
+			}
+		}
+	}
+}
+
+// addRow adds the span durations of the next row. The start and end columns have the same levels, their values at
+// the same position belong to the same span.
+func (w *durationSketchesWriter) addRow(row parquet.Row) {
+	if w.sketches == nil {
+		return
+	}
+
+	for col := range w.columns {
+		w.columns[col] = w.columns[col][:0]
+	}
+	for _, v := range row {
+		if vs, ok := w.columns[v.Column()]; ok {
+			w.columns[v.Column()] = append(vs, v)
+		}
+	}
+
+	// a repetition level of 0 or 1 starts the spans of the next resource
+	services := w.columns[w.serviceName]
+	ends := w.columns[w.spanEnd]
+	resource := -1
+	for i, v := range w.columns[w.spanStart] {
+		if v.RepetitionLevel() <= 1 {
+			resource++
+		}
+		if v.DefinitionLevel() != w.spanStartLevel || i >= len(ends) {
+			continue
+		}
+		if resource < len(services) && !services[resource].IsNull() {
+			w.add(services[resource].String(), v.Uint64(), ends[i].Uint64())
+		}
+	}
+}
+
+func (w *durationSketchesWriter) add(service string, start, end uint64) {
+	if service == "" || w.sketches == nil {
+		return
+	}
+
+	s, ok := w.sketches[service]
+	if !ok {
+		if len(w.sketches) == w.max {
+			w.sketches = nil
+			return
+		}
+		s = ddsketch.New()
+		w.sketches[service] = s
+	}
+
+	var duration uint64
+	if end > start {
+		duration = end - start
+	}
+	s.Add(float64(duration))
+}
+
+// result returns the sketches by service, nil if the block has too many services
+func (w *durationSketchesWriter) result() map[string]*ddsketch.Sketch {
+	if len(w.sketches) == 0 {
+		return nil
+	}
+	return w.sketches
+}
+
+// marshal encodes the sketches, nil if they aren't recorded
+func (w *durationSketchesWriter) marshal() ([]byte, error) {
+	sketches := w.result()
+	if sketches == nil {
+		return nil, nil
+	}
+	return json.Marshal(sketches)
+}
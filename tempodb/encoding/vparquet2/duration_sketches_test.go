@@ -0,0 +1,139 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestDurationSketches(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:                 0.01,
+		BloomShardSizeBytes:     100 * 1024,
+		RowGroupSizeBytes:       20_000_000,
+		ParquetDurationSketches: true,
+	}
+
+	meta := backend.NewBlockMeta(tenantID, uuid.New(), VersionString, backend.EncNone, "")
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+
+	// 10 traces with a resource of service a with spans of i and 100*i ms and a resource of service b with a span
+	// ending before it starts
+	for i := 1; i <= 10; i++ {
+		id := make([]byte, 16)
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+		ms := uint64(time.Millisecond)
+
+		tr := &Trace{
+			TraceID: id,
+			ResourceSpans: []ResourceSpans{
+				{
+					Resource: Resource{ServiceName: "a"},
+					InstrumentationLibrarySpans: []ILS{{
+						Spans: []Span{
+							{ID: []byte{1}, StartUnixNanos: 1000, EndUnixNanos: 1000 + uint64(i)*ms},
+							{ID: []byte{2}, StartUnixNanos: 1000, EndUnixNanos: 1000 + uint64(i)*100*ms},
+						},
+					}},
+				},
+				{
+					Resource: Resource{ServiceName: "b"},
+					InstrumentationLibrarySpans: []ILS{{
+						Spans: []Span{{ID: []byte{3}, StartUnixNanos: 1000, EndUnixNanos: 0}},
+					}},
+				},
+			},
+		}
+		s.Add(tr, 0, 0)
+		if i == 5 {
+			_, err := s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	check := func(meta *backend.BlockMeta) {
+		require.True(t, meta.DurationSketches)
+		sketches, err := newBackendBlock(meta, r).DurationSketches(ctx)
+		require.NoError(t, err)
+		require.Len(t, sketches, 2)
+
+		a := sketches["a"]
+		require.Equal(t, uint64(20), a.Count)
+		require.Equal(t, float64(time.Millisecond), a.Min)
+		require.Equal(t, float64(time.Second), a.Max)
+		require.InEpsilon(t, float64(10*time.Millisecond), a.Quantile(0.5), 0.02)
+
+		b := sketches["b"]
+		require.Equal(t, uint64(10), b.Count)
+		require.Equal(t, uint64(10), b.Zeros)
+	}
+	check(s.meta)
+
+	// the meta written to the backend has the flag
+	written, err := r.BlockMeta(ctx, s.meta.BlockID, tenantID)
+	require.NoError(t, err)
+	check(written)
+
+	// compacted blocks have the same sketches
+	c := NewCompactor(common.CompactionOptions{
+		BlockConfig:     *cfg,
+		OutputBlocks:    1,
+		FlushSizeBytes:  30_000_000,
+		ObjectsCombined: func(compactionLevel, objects int) {},
+	})
+	metas, err := c.Compact(ctx, log.NewNopLogger(), r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, []*backend.BlockMeta{s.meta})
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	check(metas[0])
+}
+
+func TestDurationSketchesMaxServices(t *testing.T) {
+	w := newDurationSketchesWriter(2)
+
+	add := func(services ...string) {
+		tr := &Trace{}
+		for _, service := range services {
+			tr.ResourceSpans = append(tr.ResourceSpans, ResourceSpans{
+				Resource:                    Resource{ServiceName: service},
+				InstrumentationLibrarySpans: []ILS{{Spans: []Span{{StartUnixNanos: 1, EndUnixNanos: 2}}}},
+			})
+		}
+		w.addTrace(tr)
+	}
+
+	add("", "a", "b")
+	require.Len(t, w.result(), 2)
+
+	// the sketches are dropped once there are too many services
+	add("c")
+	require.Nil(t, w.result())
+	add("a")
+	require.Nil(t, w.result())
+	buf, err := w.marshal()
+	require.NoError(t, err)
+	require.Nil(t, buf)
+
+	require.Equal(t, defaultMaxDurationSketches, newDurationSketchesWriter(0).max)
+}
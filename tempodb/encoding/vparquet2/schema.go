@@ -0,0 +1,791 @@
+package vparquet2
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/golang/protobuf/jsonpb" //nolint:all //deprecated
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// Label names for conversion b/n Proto <> Parquet
+const (
+	LabelRootSpanName    = "root.name"
+	LabelRootServiceName = "root.service.name"
+
+	LabelServiceName = "service.name"
+	LabelCluster     = "cluster"
+	LabelNamespace   = "namespace"
+	LabelPod         = "pod"
+	LabelContainer   = "container"
+
+	LabelK8sClusterName   = "k8s.cluster.name"
+	LabelK8sNamespaceName = "k8s.namespace.name"
+	LabelK8sPodName       = "k8s.pod.name"
+	LabelK8sContainerName = "k8s.container.name"
+
+	LabelName           = "name"
+	LabelHTTPMethod     = "http.method"
+	LabelHTTPUrl        = "http.url"
+	LabelHTTPStatusCode = "http.status_code"
+	LabelStatusCode     = "status.code"
+
+	LabelExceptionType    = "exception.type"
+	LabelExceptionMessage = "exception.message"
+)
+
+// These definition levels match the schema below
+const (
+	DefinitionLevelTrace                          = 0
+	DefinitionLevelResourceSpans                  = 1
+	DefinitionLevelResourceAttrs                  = 2
+	DefinitionLevelResourceSpansILSSpan           = 3
+	DefinitionLevelResourceSpansILSSpanAttrs      = 4
+	DefinitionLevelResourceSpansILSSpanEvent      = 4
+	DefinitionLevelResourceSpansILSSpanEventAttrs = 5
+
+	FieldResourceAttrKey       = "rs.Resource.Attrs.Key"
+	FieldResourceAttrVal       = "rs.Resource.Attrs.Value"
+	FieldResourceAttrValInt    = "rs.Resource.Attrs.ValueInt"
+	FieldResourceAttrValDouble = "rs.Resource.Attrs.ValueDouble"
+	FieldResourceAttrValBool   = "rs.Resource.Attrs.ValueBool"
+	FieldResourceAttrValArray  = "rs.Resource.Attrs.ValueArrayString"
+	FieldSpanAttrKey           = "rs.ils.Spans.Attrs.Key"
+	FieldSpanAttrVal           = "rs.ils.Spans.Attrs.Value"
+	FieldSpanAttrValInt        = "rs.ils.Spans.Attrs.ValueInt"
+	FieldSpanAttrValDouble     = "rs.ils.Spans.Attrs.ValueDouble"
+	FieldSpanAttrValBool       = "rs.ils.Spans.Attrs.ValueBool"
+	FieldSpanAttrValArray      = "rs.ils.Spans.Attrs.ValueArrayString"
+
+	FieldEventAttrKey       = "rs.ils.Spans.Events.Attrs.Key"
+	FieldEventAttrVal       = "rs.ils.Spans.Events.Attrs.Value"
+	FieldEventAttrValInt    = "rs.ils.Spans.Events.Attrs.ValueInt"
+	FieldEventAttrValDouble = "rs.ils.Spans.Events.Attrs.ValueDouble"
+	FieldEventAttrValBool   = "rs.ils.Spans.Events.Attrs.ValueBool"
+	FieldEventAttrValArray  = "rs.ils.Spans.Events.Attrs.ValueArrayString"
+)
+
+var (
+	jsonMarshaler = new(jsonpb.Marshaler)
+
+	labelMappings = map[string]string{
+		LabelRootSpanName:     "RootSpanName",
+		LabelRootServiceName:  "RootServiceName",
+		LabelServiceName:      "rs.Resource.ServiceName",
+		LabelCluster:          "rs.Resource.Cluster",
+		LabelNamespace:        "rs.Resource.Namespace",
+		LabelPod:              "rs.Resource.Pod",
+		LabelContainer:        "rs.Resource.Container",
+		LabelK8sClusterName:   "rs.Resource.K8sClusterName",
+		LabelK8sNamespaceName: "rs.Resource.K8sNamespaceName",
+		LabelK8sPodName:       "rs.Resource.K8sPodName",
+		LabelK8sContainerName: "rs.Resource.K8sContainerName",
+		LabelName:             "rs.ils.Spans.Name",
+		LabelHTTPMethod:       "rs.ils.Spans.HttpMethod",
+		LabelHTTPUrl:          "rs.ils.Spans.HttpUrl",
+		LabelHTTPStatusCode:   "rs.ils.Spans.HttpStatusCode",
+		LabelStatusCode:       "rs.ils.Spans.StatusCode",
+		LabelExceptionType:    "rs.ils.Spans.Events.ExceptionType",
+		LabelExceptionMessage: "rs.ils.Spans.Events.ExceptionMessage",
+	}
+)
+
+type Attribute struct {
+	Key string `parquet:",snappy,dict"`
+
+	// This is a bad design that leads to millions of null values. How can we fix this?
+	Value       *string  `parquet:",dict,snappy,optional"`
+	ValueInt    *int64   `parquet:",snappy,optional"`
+	ValueDouble *float64 `parquet:",snappy,optional"`
+	ValueBool   *bool    `parquet:",snappy,optional"`
+	ValueKVList string   `parquet:",snappy,optional"`
+	// ValueArray holds empty arrays and arrays of mixed or nested values as json
+	ValueArray string `parquet:",snappy,optional"`
+
+	// Arrays of values of a single type are stored in list columns, so searches match their members
+	ValueArrayString []string  `parquet:",snappy,dict"`
+	ValueArrayInt    []int64   `parquet:",snappy"`
+	ValueArrayDouble []float64 `parquet:",snappy"`
+	ValueArrayBool   []bool    `parquet:",snappy"`
+}
+
+// Event attributes are stored in the same typed columns as span attributes, so they can be filtered
+// without decoding them.
+type Event struct {
+	TimeUnixNano           uint64      `parquet:",delta"`
+	Name                   string      `parquet:",snappy,dict"`
+	Attrs                  []Attribute `parquet:""`
+	DroppedAttributesCount int32       `parquet:",snappy,delta"`
+
+	// Known attributes
+	ExceptionType    *string `parquet:",snappy,optional,dict"`
+	ExceptionMessage *string `parquet:",snappy,optional"`
+}
+
+type Link struct {
+	TraceID                []byte      `parquet:","`
+	SpanID                 []byte      `parquet:","`
+	TraceState             string      `parquet:",snappy"`
+	Attrs                  []Attribute `parquet:""`
+	DroppedAttributesCount int32       `parquet:",snappy,delta"`
+}
+
+// nolint:revive
+// Ignore field naming warnings
+type Span struct {
+	// ID is []byte to save space. It doesn't need to be user
+	// friendly like trace ID, and []byte is half the size of string.
+	ID                     []byte      `parquet:","`
+	Name                   string      `parquet:",snappy,dict"`
+	Kind                   int         `parquet:",delta"`
+	ParentSpanID           []byte      `parquet:","`
+	TraceState             string      `parquet:",snappy"`
+	StartUnixNanos         uint64      `parquet:",delta"`
+	EndUnixNanos           uint64      `parquet:",delta"`
+	StatusCode             int         `parquet:",delta"`
+	StatusMessage          string      `parquet:",snappy"`
+	Attrs                  []Attribute `parquet:""`
+	DroppedAttributesCount int32       `parquet:",snappy"`
+	Events                 []Event     `parquet:""`
+	DroppedEventsCount     int32       `parquet:",snappy"`
+	Links                  []Link      `parquet:""`
+	DroppedLinksCount      int32       `parquet:",snappy"`
+
+	// Known attributes
+	HttpMethod     *string `parquet:",snappy,optional,dict"`
+	HttpUrl        *string `parquet:",snappy,optional,dict"`
+	HttpStatusCode *int64  `parquet:",snappy,optional"`
+}
+
+type IL struct {
+	Name    string `parquet:",snappy,dict"`
+	Version string `parquet:",snappy,dict"`
+}
+
+type ILS struct {
+	InstrumentationLibrary IL     `parquet:"il"`
+	Spans                  []Span `parquet:""`
+}
+
+type Resource struct {
+	Attrs []Attribute
+
+	// Known attributes
+	ServiceName      string  `parquet:",snappy,dict"`
+	Cluster          *string `parquet:",snappy,optional,dict"`
+	Namespace        *string `parquet:",snappy,optional,dict"`
+	Pod              *string `parquet:",snappy,optional,dict"`
+	Container        *string `parquet:",snappy,optional,dict"`
+	K8sClusterName   *string `parquet:",snappy,optional,dict"`
+	K8sNamespaceName *string `parquet:",snappy,optional,dict"`
+	K8sPodName       *string `parquet:",snappy,optional,dict"`
+	K8sContainerName *string `parquet:",snappy,optional,dict"`
+
+	Test string `parquet:",snappy,dict,optional"` // Always empty for testing
+}
+
+type ResourceSpans struct {
+	Resource                    Resource `parquet:""`
+	InstrumentationLibrarySpans []ILS    `parquet:"ils"`
+}
+
+type Trace struct {
+	// TraceID is a byte slice as it helps maintain the sort order of traces within a parquet file
+	TraceID       []byte          `parquet:""`
+	ResourceSpans []ResourceSpans `parquet:"rs"`
+
+	// TraceIDText is for better useability on downstream systems i.e: something other than Tempo is reading these files.
+	// It will not be used as the primary traceID field within Tempo and is only helpful for debugging purposes.
+	TraceIDText string `parquet:",snappy"`
+
+	// Trace-level attributes for searching
+	StartTimeUnixNano uint64 `parquet:",delta"`
+	EndTimeUnixNano   uint64 `parquet:",delta"`
+	DurationNanos     uint64 `parquet:",delta"`
+	RootServiceName   string `parquet:",dict"`
+	RootSpanName      string `parquet:",dict"`
+
+	// Trace-level summary of the spans. Answers the most common searches without reading span-level columns.
+	ServiceNames []string `parquet:",snappy,dict"`
+	SpanCount    int64    `parquet:",delta"`
+	ErrorCount   int64    `parquet:",delta"`
+}
+
+func attrToParquet(a *v1.KeyValue) Attribute {
+	p := Attribute{
+		Key: a.Key,
+	}
+	switch v := a.GetValue().Value.(type) {
+	case *v1.AnyValue_StringValue:
+		p.Value = &v.StringValue
+	case *v1.AnyValue_IntValue:
+		p.ValueInt = &v.IntValue
+	case *v1.AnyValue_DoubleValue:
+		p.ValueDouble = &v.DoubleValue
+	case *v1.AnyValue_BoolValue:
+		p.ValueBool = &v.BoolValue
+	case *v1.AnyValue_ArrayValue:
+		if arrayToParquet(v.ArrayValue, &p) {
+			break
+		}
+		jsonBytes := &bytes.Buffer{}
+		_ = jsonMarshaler.Marshal(jsonBytes, a.Value) // deliberately marshalling a.Value because of AnyValue logic
+		p.ValueArray = jsonBytes.String()
+	case *v1.AnyValue_KvlistValue:
+		jsonBytes := &bytes.Buffer{}
+		_ = jsonMarshaler.Marshal(jsonBytes, a.Value) // deliberately marshalling a.Value because of AnyValue logic
+		p.ValueKVList = jsonBytes.String()
+	}
+	return p
+}
+
+// arrayToParquet sets the list column of the type of the values of a non empty array. It returns false if the
+// values have different types or are arrays or kvlists themselves.
+func arrayToParquet(a *v1.ArrayValue, p *Attribute) bool {
+	if a == nil || len(a.Values) == 0 {
+		return false
+	}
+
+	switch a.Values[0].GetValue().(type) {
+	case *v1.AnyValue_StringValue:
+		values := make([]string, 0, len(a.Values))
+		for _, v := range a.Values {
+			sv, ok := v.GetValue().(*v1.AnyValue_StringValue)
+			if !ok {
+				return false
+			}
+			values = append(values, sv.StringValue)
+		}
+		p.ValueArrayString = values
+	case *v1.AnyValue_IntValue:
+		values := make([]int64, 0, len(a.Values))
+		for _, v := range a.Values {
+			iv, ok := v.GetValue().(*v1.AnyValue_IntValue)
+			if !ok {
+				return false
+			}
+			values = append(values, iv.IntValue)
+		}
+		p.ValueArrayInt = values
+	case *v1.AnyValue_DoubleValue:
+		values := make([]float64, 0, len(a.Values))
+		for _, v := range a.Values {
+			dv, ok := v.GetValue().(*v1.AnyValue_DoubleValue)
+			if !ok {
+				return false
+			}
+			values = append(values, dv.DoubleValue)
+		}
+		p.ValueArrayDouble = values
+	case *v1.AnyValue_BoolValue:
+		values := make([]bool, 0, len(a.Values))
+		for _, v := range a.Values {
+			bv, ok := v.GetValue().(*v1.AnyValue_BoolValue)
+			if !ok {
+				return false
+			}
+			values = append(values, bv.BoolValue)
+		}
+		p.ValueArrayBool = values
+	default:
+		return false
+	}
+	return true
+}
+
+func traceToParquet(id common.ID, tr *tempopb.Trace) Trace {
+
+	ot := Trace{
+		TraceIDText: util.TraceIDToHexString(id),
+		TraceID:     util.PadTraceIDTo16Bytes(id),
+	}
+
+	// Trace-level items
+	traceStart := uint64(0)
+	traceEnd := uint64(0)
+	var rootSpan *v1_trace.Span
+	var rootBatch *v1_trace.ResourceSpans
+
+	ot.ResourceSpans = make([]ResourceSpans, 0, len(tr.Batches))
+	for _, b := range tr.Batches {
+		ob := ResourceSpans{
+			Resource: Resource{},
+		}
+
+		if b.Resource != nil {
+			ob.Resource.Attrs = make([]Attribute, 0, len(b.Resource.Attributes))
+			for _, a := range b.Resource.Attributes {
+				switch a.Key {
+				case LabelServiceName:
+					ob.Resource.ServiceName = a.Value.GetStringValue()
+				case LabelCluster:
+					c := a.Value.GetStringValue()
+					ob.Resource.Cluster = &c
+				case LabelNamespace:
+					n := a.Value.GetStringValue()
+					ob.Resource.Namespace = &n
+				case LabelPod:
+					p := a.Value.GetStringValue()
+					ob.Resource.Pod = &p
+				case LabelContainer:
+					c := a.Value.GetStringValue()
+					ob.Resource.Container = &c
+
+				case LabelK8sClusterName:
+					c := a.Value.GetStringValue()
+					ob.Resource.K8sClusterName = &c
+				case LabelK8sNamespaceName:
+					n := a.Value.GetStringValue()
+					ob.Resource.K8sNamespaceName = &n
+				case LabelK8sPodName:
+					p := a.Value.GetStringValue()
+					ob.Resource.K8sPodName = &p
+				case LabelK8sContainerName:
+					c := a.Value.GetStringValue()
+					ob.Resource.K8sContainerName = &c
+
+				default:
+					// Other attributes put in generic columns
+					ob.Resource.Attrs = append(ob.Resource.Attrs, attrToParquet(a))
+				}
+			}
+		}
+
+		ob.InstrumentationLibrarySpans = make([]ILS, 0, len(b.InstrumentationLibrarySpans))
+		for _, ils := range b.InstrumentationLibrarySpans {
+			oils := ILS{}
+			if ils.InstrumentationLibrary != nil {
+				oils.InstrumentationLibrary = IL{
+					Name:    ils.InstrumentationLibrary.Name,
+					Version: ils.InstrumentationLibrary.Version,
+				}
+			}
+
+			oils.Spans = make([]Span, 0, len(ils.Spans))
+			for _, s := range ils.Spans {
+
+				if traceStart == 0 || s.StartTimeUnixNano < traceStart {
+					traceStart = s.StartTimeUnixNano
+				}
+				if s.EndTimeUnixNano > traceEnd {
+					traceEnd = s.EndTimeUnixNano
+				}
+				if len(s.ParentSpanId) == 0 {
+					rootSpan = s
+					rootBatch = b
+				}
+
+				events := make([]Event, 0, len(s.Events))
+				for _, e := range s.Events {
+					events = append(events, eventToParquet(e))
+				}
+				links := make([]Link, 0, len(s.Links))
+				for _, l := range s.Links {
+					links = append(links, linkToParquet(l))
+				}
+
+				ss := Span{
+					ID:                     s.SpanId,
+					ParentSpanID:           s.ParentSpanId,
+					Name:                   s.Name,
+					Kind:                   int(s.Kind),
+					StatusCode:             int(s.Status.Code),
+					StatusMessage:          s.Status.Message,
+					StartUnixNanos:         s.StartTimeUnixNano,
+					EndUnixNanos:           s.EndTimeUnixNano,
+					Attrs:                  make([]Attribute, 0, len(s.Attributes)),
+					DroppedAttributesCount: int32(s.DroppedAttributesCount),
+					Events:                 events,
+					DroppedEventsCount:     int32(s.DroppedEventsCount),
+					Links:                  links,
+					DroppedLinksCount:      int32(s.DroppedLinksCount),
+				}
+
+				for _, a := range s.Attributes {
+					switch a.Key {
+
+					case LabelHTTPMethod:
+						m := a.Value.GetStringValue()
+						ss.HttpMethod = &m
+					case LabelHTTPUrl:
+						m := a.Value.GetStringValue()
+						ss.HttpUrl = &m
+					case LabelHTTPStatusCode:
+						m := a.Value.GetIntValue()
+						ss.HttpStatusCode = &m
+					default:
+						// Other attributes put in generic columns
+						ss.Attrs = append(ss.Attrs, attrToParquet(a))
+					}
+				}
+
+				oils.Spans = append(oils.Spans, ss)
+			}
+
+			ob.InstrumentationLibrarySpans = append(ob.InstrumentationLibrarySpans, oils)
+		}
+
+		ot.ResourceSpans = append(ot.ResourceSpans, ob)
+	}
+
+	ot.StartTimeUnixNano = traceStart
+	ot.EndTimeUnixNano = traceEnd
+	ot.DurationNanos = traceEnd - traceStart
+	assignTraceSummary(&ot)
+
+	if rootSpan != nil && rootBatch != nil && rootBatch.Resource != nil {
+		ot.RootSpanName = rootSpan.Name
+
+		for _, a := range rootBatch.Resource.Attributes {
+			if a.Key == LabelServiceName {
+				ot.RootServiceName = a.Value.GetStringValue()
+				break
+			}
+		}
+	}
+
+	return ot
+}
+
+// assignTraceSummary sets the trace-level summary columns from the spans of the trace
+func assignTraceSummary(t *Trace) {
+	t.ServiceNames = t.ServiceNames[:0]
+	t.SpanCount = 0
+	t.ErrorCount = 0
+
+	for _, rs := range t.ResourceSpans {
+		if name := rs.Resource.ServiceName; name != "" && !containsString(t.ServiceNames, name) {
+			t.ServiceNames = append(t.ServiceNames, name)
+		}
+		for _, ils := range rs.InstrumentationLibrarySpans {
+			t.SpanCount += int64(len(ils.Spans))
+			for _, s := range ils.Spans {
+				if s.StatusCode == int(v1_trace.Status_STATUS_CODE_ERROR) {
+					t.ErrorCount++
+				}
+			}
+		}
+	}
+
+	sort.Strings(t.ServiceNames)
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func eventToParquet(e *v1_trace.Span_Event) Event {
+	ee := Event{
+		Name:                   e.Name,
+		TimeUnixNano:           e.TimeUnixNano,
+		DroppedAttributesCount: int32(e.DroppedAttributesCount),
+	}
+
+	for _, a := range e.Attributes {
+		// Exceptions of other types than strings are put in generic columns to keep their value
+		_, isString := a.Value.GetValue().(*v1.AnyValue_StringValue)
+
+		switch {
+		case a.Key == LabelExceptionType && isString:
+			t := a.Value.GetStringValue()
+			ee.ExceptionType = &t
+		case a.Key == LabelExceptionMessage && isString:
+			m := a.Value.GetStringValue()
+			ee.ExceptionMessage = &m
+		default:
+			// Other attributes put in generic columns
+			ee.Attrs = append(ee.Attrs, attrToParquet(a))
+		}
+	}
+
+	return ee
+}
+
+func linkToParquet(l *v1_trace.Span_Link) Link {
+	ll := Link{
+		TraceID:                l.TraceId,
+		SpanID:                 l.SpanId,
+		TraceState:             l.TraceState,
+		Attrs:                  make([]Attribute, 0, len(l.Attributes)),
+		DroppedAttributesCount: int32(l.DroppedAttributesCount),
+	}
+
+	for _, a := range l.Attributes {
+		ll.Attrs = append(ll.Attrs, attrToParquet(a))
+	}
+
+	return ll
+}
+
+func parquetToProtoAttrs(parquetAttrs []Attribute) []*v1.KeyValue {
+	var protoAttrs []*v1.KeyValue
+
+	for _, attr := range parquetAttrs {
+		protoVal := &v1.AnyValue{}
+
+		if attr.Value != nil {
+			protoVal.Value = &v1.AnyValue_StringValue{
+				StringValue: *attr.Value,
+			}
+		} else if attr.ValueInt != nil {
+			protoVal.Value = &v1.AnyValue_IntValue{
+				IntValue: *attr.ValueInt,
+			}
+		} else if attr.ValueDouble != nil {
+			protoVal.Value = &v1.AnyValue_DoubleValue{
+				DoubleValue: *attr.ValueDouble,
+			}
+		} else if attr.ValueBool != nil {
+			protoVal.Value = &v1.AnyValue_BoolValue{
+				BoolValue: *attr.ValueBool,
+			}
+		} else if arr := parquetToProtoArray(attr); arr != nil {
+			protoVal.Value = &v1.AnyValue_ArrayValue{
+				ArrayValue: arr,
+			}
+		} else if attr.ValueArray != "" {
+			_ = jsonpb.Unmarshal(bytes.NewBufferString(attr.ValueArray), protoVal)
+		} else if attr.ValueKVList != "" {
+			_ = jsonpb.Unmarshal(bytes.NewBufferString(attr.ValueKVList), protoVal)
+		}
+
+		protoAttrs = append(protoAttrs, &v1.KeyValue{
+			Key:   attr.Key,
+			Value: protoVal,
+		})
+	}
+
+	return protoAttrs
+}
+
+// parquetToProtoArray returns the array stored in the list columns of the attribute, nil if they are empty
+func parquetToProtoArray(attr Attribute) *v1.ArrayValue {
+	var values []*v1.AnyValue
+
+	switch {
+	case len(attr.ValueArrayString) > 0:
+		values = make([]*v1.AnyValue, 0, len(attr.ValueArrayString))
+		for _, v := range attr.ValueArrayString {
+			values = append(values, &v1.AnyValue{Value: &v1.AnyValue_StringValue{StringValue: v}})
+		}
+	case len(attr.ValueArrayInt) > 0:
+		values = make([]*v1.AnyValue, 0, len(attr.ValueArrayInt))
+		for _, v := range attr.ValueArrayInt {
+			values = append(values, &v1.AnyValue{Value: &v1.AnyValue_IntValue{IntValue: v}})
+		}
+	case len(attr.ValueArrayDouble) > 0:
+		values = make([]*v1.AnyValue, 0, len(attr.ValueArrayDouble))
+		for _, v := range attr.ValueArrayDouble {
+			values = append(values, &v1.AnyValue{Value: &v1.AnyValue_DoubleValue{DoubleValue: v}})
+		}
+	case len(attr.ValueArrayBool) > 0:
+		values = make([]*v1.AnyValue, 0, len(attr.ValueArrayBool))
+		for _, v := range attr.ValueArrayBool {
+			values = append(values, &v1.AnyValue{Value: &v1.AnyValue_BoolValue{BoolValue: v}})
+		}
+	default:
+		return nil
+	}
+
+	return &v1.ArrayValue{Values: values}
+}
+
+func parquetToProtoEvents(parquetEvents []Event) []*v1_trace.Span_Event {
+	var protoEvents []*v1_trace.Span_Event
+
+	if len(parquetEvents) > 0 {
+		protoEvents = make([]*v1_trace.Span_Event, 0, len(parquetEvents))
+
+		for _, e := range parquetEvents {
+
+			protoEvent := &v1_trace.Span_Event{
+				TimeUnixNano:           e.TimeUnixNano,
+				Name:                   e.Name,
+				Attributes:             nil,
+				DroppedAttributesCount: uint32(e.DroppedAttributesCount),
+			}
+
+			if len(e.Attrs) > 0 {
+				protoEvent.Attributes = parquetToProtoAttrs(e.Attrs)
+			}
+
+			// known event attributes
+			for _, attr := range []struct {
+				Key   string
+				Value *string
+			}{
+				{Key: LabelExceptionType, Value: e.ExceptionType},
+				{Key: LabelExceptionMessage, Value: e.ExceptionMessage},
+			} {
+				if attr.Value != nil {
+					protoEvent.Attributes = append(protoEvent.Attributes, &v1.KeyValue{
+						Key: attr.Key,
+						Value: &v1.AnyValue{
+							Value: &v1.AnyValue_StringValue{
+								StringValue: *attr.Value,
+							},
+						},
+					})
+				}
+			}
+
+			protoEvents = append(protoEvents, protoEvent)
+		}
+	}
+
+	return protoEvents
+}
+
+func parquetToProtoLinks(parquetLinks []Link) []*v1_trace.Span_Link {
+	var protoLinks []*v1_trace.Span_Link
+
+	if len(parquetLinks) > 0 {
+		protoLinks = make([]*v1_trace.Span_Link, 0, len(parquetLinks))
+
+		for _, l := range parquetLinks {
+			protoLinks = append(protoLinks, &v1_trace.Span_Link{
+				TraceId:                l.TraceID,
+				SpanId:                 l.SpanID,
+				TraceState:             l.TraceState,
+				Attributes:             parquetToProtoAttrs(l.Attrs),
+				DroppedAttributesCount: uint32(l.DroppedAttributesCount),
+			})
+		}
+	}
+
+	return protoLinks
+}
+
+func parquetTraceToTempopbTrace(parquetTrace *Trace) *tempopb.Trace {
+
+	protoTrace := &tempopb.Trace{}
+	protoTrace.Batches = make([]*v1_trace.ResourceSpans, 0, len(parquetTrace.ResourceSpans))
+
+	for _, rs := range parquetTrace.ResourceSpans {
+		protoBatch := &v1_trace.ResourceSpans{}
+		protoBatch.Resource = &v1_resource.Resource{
+			Attributes: parquetToProtoAttrs(rs.Resource.Attrs),
+		}
+
+		// known resource attributes
+		if rs.Resource.ServiceName != "" {
+			protoBatch.Resource.Attributes = append(protoBatch.Resource.Attributes, &v1.KeyValue{
+				Key: LabelServiceName,
+				Value: &v1.AnyValue{
+					Value: &v1.AnyValue_StringValue{
+						StringValue: rs.Resource.ServiceName,
+					},
+				},
+			})
+		}
+		for _, attr := range []struct {
+			Key   string
+			Value *string
+		}{
+			{Key: LabelCluster, Value: rs.Resource.Cluster},
+			{Key: LabelNamespace, Value: rs.Resource.Namespace},
+			{Key: LabelPod, Value: rs.Resource.Pod},
+			{Key: LabelContainer, Value: rs.Resource.Container},
+			{Key: LabelK8sClusterName, Value: rs.Resource.K8sClusterName},
+			{Key: LabelK8sNamespaceName, Value: rs.Resource.K8sNamespaceName},
+			{Key: LabelK8sPodName, Value: rs.Resource.K8sPodName},
+			{Key: LabelK8sContainerName, Value: rs.Resource.K8sContainerName},
+		} {
+			if attr.Value != nil {
+				protoBatch.Resource.Attributes = append(protoBatch.Resource.Attributes, &v1.KeyValue{
+					Key: attr.Key,
+					Value: &v1.AnyValue{
+						Value: &v1.AnyValue_StringValue{
+							StringValue: *attr.Value,
+						},
+					},
+				})
+			}
+		}
+
+		protoBatch.InstrumentationLibrarySpans = make([]*v1_trace.InstrumentationLibrarySpans, 0, len(rs.InstrumentationLibrarySpans))
+
+		for _, ils := range rs.InstrumentationLibrarySpans {
+			protoILS := &v1_trace.InstrumentationLibrarySpans{
+				InstrumentationLibrary: &v1.InstrumentationLibrary{
+					Name:    ils.InstrumentationLibrary.Name,
+					Version: ils.InstrumentationLibrary.Version,
+				},
+			}
+
+			protoILS.Spans = make([]*v1_trace.Span, 0, len(ils.Spans))
+			for _, span := range ils.Spans {
+
+				protoSpan := &v1_trace.Span{
+					TraceId:           parquetTrace.TraceID,
+					SpanId:            span.ID,
+					TraceState:        span.TraceState,
+					Name:              span.Name,
+					Kind:              v1_trace.Span_SpanKind(span.Kind),
+					ParentSpanId:      span.ParentSpanID,
+					StartTimeUnixNano: span.StartUnixNanos,
+					EndTimeUnixNano:   span.EndUnixNanos,
+					Status: &v1_trace.Status{
+						Message: span.StatusMessage,
+						Code:    v1_trace.Status_StatusCode(span.StatusCode),
+					},
+					DroppedAttributesCount: uint32(span.DroppedAttributesCount),
+					DroppedEventsCount:     uint32(span.DroppedEventsCount),
+					Attributes:             parquetToProtoAttrs(span.Attrs),
+					Events:                 parquetToProtoEvents(span.Events),
+					DroppedLinksCount:      uint32(span.DroppedLinksCount),
+					Links:                  parquetToProtoLinks(span.Links),
+				}
+
+				// known span attributes
+				if span.HttpMethod != nil {
+					protoSpan.Attributes = append(protoSpan.Attributes, &v1.KeyValue{
+						Key: LabelHTTPMethod,
+						Value: &v1.AnyValue{
+							Value: &v1.AnyValue_StringValue{
+								StringValue: *span.HttpMethod,
+							},
+						},
+					})
+				}
+				if span.HttpUrl != nil {
+					protoSpan.Attributes = append(protoSpan.Attributes, &v1.KeyValue{
+						Key: LabelHTTPUrl,
+						Value: &v1.AnyValue{
+							Value: &v1.AnyValue_StringValue{
+								StringValue: *span.HttpUrl,
+							},
+						},
+					})
+				}
+				if span.HttpStatusCode != nil {
+					protoSpan.Attributes = append(protoSpan.Attributes, &v1.KeyValue{
+						Key: LabelHTTPStatusCode,
+						Value: &v1.AnyValue{
+							Value: &v1.AnyValue_IntValue{
+								IntValue: *span.HttpStatusCode,
+							},
+						},
+					})
+				}
+
+				protoILS.Spans = append(protoILS.Spans, protoSpan)
+			}
+
+			protoBatch.InstrumentationLibrarySpans = append(protoBatch.InstrumentationLibrarySpans, protoILS)
+		}
+		protoTrace.Batches = append(protoTrace.Batches, protoBatch)
+	}
+
+	return protoTrace
+}
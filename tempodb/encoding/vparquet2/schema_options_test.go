@@ -0,0 +1,129 @@
+package vparquet2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/segmentio/parquet-go/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestWriterOptions(t *testing.T) {
+	tests := []struct {
+		name               string
+		cfg                common.BlockConfig
+		expectedCodec      format.CompressionCodec // codec of every column, unchecked if uncompressed
+		expectedDictionary bool
+	}{
+		{
+			name:               "defaults",
+			expectedDictionary: true,
+		},
+		{
+			name:               "zstd",
+			cfg:                common.BlockConfig{ParquetCompression: "zstd"},
+			expectedCodec:      format.Zstd,
+			expectedDictionary: true,
+		},
+		{
+			name: "no dictionary",
+			cfg:  common.BlockConfig{ParquetDisableDictionary: true, ParquetPageSizeBytes: 1024},
+		},
+		{
+			name:          "gzip and no dictionary",
+			cfg:           common.BlockConfig{ParquetCompression: "gzip", ParquetDisableDictionary: true},
+			expectedCodec: format.Gzip,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rawR, rawW, _, err := local.New(&local.Config{
+				Path: t.TempDir(),
+			})
+			require.NoError(t, err)
+
+			r := backend.NewReader(rawR)
+			w := backend.NewWriter(rawW)
+			ctx := context.Background()
+
+			cfg := tc.cfg
+			cfg.BloomFP = 0.01
+			cfg.BloomShardSizeBytes = 100 * 1024
+
+			meta := createTestBlock(t, ctx, &cfg, r, w, 10, 2, 5)
+			b := newBackendBlock(meta, r)
+
+			pf, _, err := b.openForSearch(ctx, common.SearchOptions{})
+			require.NoError(t, err)
+
+			dictionary := false
+			for _, rg := range pf.Metadata().RowGroups {
+				for _, c := range rg.Columns {
+					if tc.expectedCodec != format.Uncompressed {
+						assert.Equal(t, tc.expectedCodec, c.MetaData.Codec, c.MetaData.PathInSchema)
+					}
+					if c.MetaData.DictionaryPageOffset != 0 {
+						dictionary = true
+					}
+				}
+			}
+			assert.Equal(t, tc.expectedDictionary, dictionary)
+
+			// the block is readable
+			count := 0
+			err = b.IterateIDs(ctx, func(id common.ID) error {
+				count++
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 10, count)
+		})
+	}
+}
+
+func TestWriterOptionsRoundTrip(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:                  0.01,
+		BloomShardSizeBytes:      100 * 1024,
+		ParquetCompression:       "zstd",
+		ParquetDisableDictionary: true,
+	}
+
+	id := test.ValidTraceID(nil)
+	tr := traceToParquet(id, test.MakeTrace(5, id))
+
+	meta := backend.NewBlockMeta(tenantID, [16]byte{1}, VersionString, backend.EncNone, "")
+	meta.TotalObjects = 1
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+	s.Add(&tr, 0, 0)
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	actual, err := newBackendBlock(s.meta, r).FindTraceByID(ctx, id, common.SearchOptions{})
+	require.NoError(t, err)
+
+	// compare the encoded traces, empty and nil ids are equivalent
+	expectedBytes, err := parquetTraceToTempopbTrace(&tr).Marshal()
+	require.NoError(t, err)
+	actualBytes, err := actual.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, expectedBytes, actualBytes)
+}
@@ -0,0 +1,118 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/parquet-go"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// ServiceIndexFileName is the service index of blocks written with BlockConfig.ParquetServiceIndex
+const ServiceIndexFileName = "service_index.json"
+
+// serviceIndex maps the services of a block to the ranges of rows holding their spans. The ranges are aligned to
+// the row groups written by the block, so searches for a service skip the row groups without its spans without
+// reading any column of the block.
+type serviceIndex struct {
+	Services map[string][]rowRange `json:"services"`
+}
+
+// rowRange are the rows from Start to End, End excluded
+type rowRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// serviceIndexWriter builds the service index of a block while its rows are added
+type serviceIndexWriter struct {
+	index serviceIndex
+
+	rows          int64
+	rowGroupStart int64
+	// rowGroupServices are the services of the rows added since the last row group was flushed
+	rowGroupServices map[string]struct{}
+}
+
+func newServiceIndexWriter() *serviceIndexWriter {
+	return &serviceIndexWriter{
+		index:            serviceIndex{Services: map[string][]rowRange{}},
+		rowGroupServices: map[string]struct{}{},
+	}
+}
+
+func (w *serviceIndexWriter) addService(name string) {
+	w.rowGroupServices[name] = struct{}{}
+}
+
+// addRow completes the row whose services were added
+func (w *serviceIndexWriter) addRow() {
+	w.rows++
+}
+
+// flushRowGroup adds the rows of the current row group to the ranges of its services. The range of a service in
+// the previous row group is extended.
+func (w *serviceIndexWriter) flushRowGroup() {
+	if w.rows == w.rowGroupStart {
+		return
+	}
+
+	for name := range w.rowGroupServices {
+		ranges := w.index.Services[name]
+		if n := len(ranges); n > 0 && ranges[n-1].End == w.rowGroupStart {
+			ranges[n-1].End = w.rows
+		} else {
+			ranges = append(ranges, rowRange{Start: w.rowGroupStart, End: w.rows})
+		}
+		w.index.Services[name] = ranges
+		delete(w.rowGroupServices, name)
+	}
+	w.rowGroupStart = w.rows
+}
+
+func (w *serviceIndexWriter) marshal() ([]byte, error) {
+	w.flushRowGroup()
+	return json.Marshal(w.index)
+}
+
+func readServiceIndex(ctx context.Context, meta *backend.BlockMeta, r backend.Reader) (*serviceIndex, error) {
+	b, err := r.Read(ctx, ServiceIndexFileName, meta.BlockID, meta.TenantID, true)
+	if err != nil {
+		return nil, fmt.Errorf("error reading service index: %w", err)
+	}
+
+	idx := &serviceIndex{}
+	if err := json.Unmarshal(b, idx); err != nil {
+		return nil, fmt.Errorf("error unmarshalling service index: %w", err)
+	}
+	return idx, nil
+}
+
+// rowGroups returns for every row group whether it holds spans of a service containing name. Service name searches
+// match substrings of the service names.
+func (idx *serviceIndex) rowGroups(name string, rgs []parquet.RowGroup) []bool {
+	keep := make([]bool, len(rgs))
+
+	for service, ranges := range idx.Services {
+		if !strings.Contains(service, name) {
+			continue
+		}
+
+		start := int64(0)
+		for i, rg := range rgs {
+			end := start + rg.NumRows()
+			for _, r := range ranges {
+				if r.Start < end && start < r.End {
+					keep[i] = true
+					break
+				}
+			}
+			start = end
+		}
+	}
+
+	return keep
+}
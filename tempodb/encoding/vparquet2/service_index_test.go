@@ -0,0 +1,125 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestServiceIndex(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+		RowGroupSizeBytes:   20_000_000,
+		ParquetServiceIndex: true,
+	}
+
+	meta := backend.NewBlockMeta(tenantID, uuid.New(), VersionString, backend.EncNone, "")
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+
+	// 4 row groups of 25 traces with their own service. trace 60 has spans of every service.
+	ids := make([]common.ID, 0, 100)
+	for i := 0; i < 100; i++ {
+		id := make([]byte, 16)
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+		tr := test.MakeTrace(4, id)
+		for j, batch := range tr.Batches {
+			service := fmt.Sprintf("service-%d", i/25)
+			if i == 60 {
+				service = fmt.Sprintf("service-%d", j)
+			}
+			batch.Resource.Attributes = []*v1_common.KeyValue{
+				{Key: "service.name", Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_StringValue{StringValue: service}}},
+			}
+		}
+		ids = append(ids, id)
+
+		pqTr := traceToParquet(id, tr)
+		s.Add(&pqTr, 0, 0)
+		if i%25 == 24 {
+			_, err := s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+	require.True(t, s.meta.ServiceIndex)
+
+	idx, err := readServiceIndex(ctx, s.meta, r)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]rowRange{
+		"service-0": {{Start: 0, End: 25}, {Start: 50, End: 75}},
+		"service-1": {{Start: 25, End: 75}},
+		"service-2": {{Start: 50, End: 75}},
+		"service-3": {{Start: 50, End: 100}},
+	}, idx.Services)
+
+	// searches aren't buffered, so the inspected bytes are the bytes of the row groups read
+	search := func(meta *backend.BlockMeta, service string) *tempopb.SearchResponse {
+		res, err := newBackendBlock(meta, r).Search(ctx, &tempopb.SearchRequest{Tags: map[string]string{LabelServiceName: service}}, common.SearchOptions{})
+		require.NoError(t, err)
+		return res
+	}
+	traceIDs := func(res *tempopb.SearchResponse) []string {
+		ids := make([]string, 0, len(res.Traces))
+		for _, tr := range res.Traces {
+			ids = append(ids, tr.TraceID)
+		}
+		return ids
+	}
+
+	all := search(s.meta, "service")
+	require.Len(t, all.Traces, 100)
+
+	// searches only read the row groups of the service
+	res := search(s.meta, "service-1")
+	require.Len(t, res.Traces, 26)
+	require.Contains(t, traceIDs(res), util.TraceIDToHexString(ids[60]))
+	require.Less(t, res.Metrics.InspectedBytes, all.Metrics.InspectedBytes)
+
+	// searches of services that aren't in the block read nothing
+	res = search(s.meta, "other")
+	require.Len(t, res.Traces, 0)
+	require.Zero(t, res.Metrics.InspectedBytes)
+
+	// compacted blocks have a service index
+	c := NewCompactor(common.CompactionOptions{
+		BlockConfig:     *cfg,
+		OutputBlocks:    1,
+		FlushSizeBytes:  30_000_000,
+		ObjectsCombined: func(compactionLevel, objects int) {},
+	})
+	metas, err := c.Compact(ctx, log.NewNopLogger(), r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, []*backend.BlockMeta{s.meta})
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.True(t, metas[0].ServiceIndex)
+
+	res = search(metas[0], "service-3")
+	require.Len(t, res.Traces, 26)
+	require.Contains(t, traceIDs(res), util.TraceIDToHexString(ids[60]))
+}
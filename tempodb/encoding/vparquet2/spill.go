@@ -0,0 +1,212 @@
+package vparquet2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/segmentio/parquet-go"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// minSpillPageSizeBytes is the smallest page buffer size of blocks written with a write budget. Smaller pages would
+// blow up the size of the page headers and indexes.
+const minSpillPageSizeBytes = 4 * 1024
+
+// spillWriter writes the data file of a block written with BlockConfig.ParquetWriteBudgetBytes. The pages of the open
+// row group are kept in a temporary file instead of memory and the row groups are written to a second temporary file
+// instead of being buffered for the backend. The data file is uploaded when the block is completed.
+type spillWriter struct {
+	file *os.File
+	pool *spillPool
+
+	// size is the number of bytes written to the file, flushed the size at the last flushed row group
+	size    int64
+	flushed int64
+}
+
+var _ io.Writer = (*spillWriter)(nil)
+
+func newSpillWriter(dir string) (*spillWriter, error) {
+	file, err := createSpillFile(dir, "tempo-block-*.parquet")
+	if err != nil {
+		return nil, err
+	}
+	pages, err := createSpillFile(dir, "tempo-pages-*")
+	if err != nil {
+		closeSpillFile(file)
+		return nil, err
+	}
+
+	return &spillWriter{
+		file: file,
+		pool: &spillPool{file: pages},
+	}, nil
+}
+
+func (w *spillWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// flush returns the number of bytes written since the last flush
+func (w *spillWriter) flush() int {
+	n := w.size - w.flushed
+	w.flushed = w.size
+	return int(n)
+}
+
+// upload writes the file to the backend as the data file of the block. The file itself is passed to the backend,
+// so the local backend can clone or copy it in the kernel.
+func (w *spillWriter) upload(ctx context.Context, to backend.Writer, blockID uuid.UUID, tenantID string) error {
+	_, err := w.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	return to.StreamWriter(ctx, DataFileName, blockID, tenantID, w.file, w.size)
+}
+
+// close removes the temporary files
+func (w *spillWriter) close() {
+	closeSpillFile(w.file)
+	closeSpillFile(w.pool.file)
+}
+
+// createSpillFile creates a temporary file and removes it right away, so its space is released once it is closed
+// or the process exits, even if the block is never completed.
+func createSpillFile(dir, pattern string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error creating spill file: %w", err)
+	}
+	_ = os.Remove(f.Name())
+	return f, nil
+}
+
+func closeSpillFile(f *os.File) {
+	_ = f.Close()
+	// the file is already removed unless the platform doesn't remove open files
+	_ = os.Remove(f.Name())
+}
+
+// spillPageSize returns the size of the page buffers of a block written with a write budget. Half of the budget is
+// shared by the page buffers of all columns, the other half holds the traces not yet written to the pages.
+func spillPageSize(cfg *common.BlockConfig, sch *parquet.Schema) int {
+	size := cfg.ParquetWriteBudgetBytes / 2 / len(sch.Columns())
+	if cfg.ParquetPageSizeBytes > 0 && cfg.ParquetPageSizeBytes < size {
+		size = cfg.ParquetPageSizeBytes
+	}
+	if size < minSpillPageSizeBytes {
+		size = minSpillPageSizeBytes
+	}
+	return size
+}
+
+// spillPool is a parquet.PageBufferPool storing the pages of the open row groups of a parquet writer in a file. Pages
+// are appended to the file, it is truncated once all pages were released.
+type spillPool struct {
+	mtx  sync.Mutex
+	file *os.File
+	size int64
+	open int
+}
+
+var _ parquet.PageBufferPool = (*spillPool)(nil)
+
+func (p *spillPool) GetPageBuffer() io.ReadWriter {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.open++
+	return &spillBuffer{pool: p}
+}
+
+func (p *spillPool) PutPageBuffer(buf io.ReadWriter) {
+	if _, ok := buf.(*spillBuffer); !ok {
+		return
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.open--
+	if p.open == 0 && p.size > 0 {
+		// an error leaves the file as is, the space is reused once it can be truncated
+		if err := p.file.Truncate(0); err == nil {
+			p.size = 0
+		}
+	}
+}
+
+// write appends p to the file and returns its offset
+func (p *spillPool) write(b []byte) (int64, int, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	off := p.size
+	n, err := p.file.WriteAt(b, off)
+	p.size += int64(n)
+	return off, n, err
+}
+
+// spillExtent is a range of the file of a spillPool
+type spillExtent struct {
+	off, len int64
+}
+
+// spillBuffer is a page buffer of a spillPool. The writes to a buffer are usually contiguous in the file, but other
+// buffers may be written in between.
+type spillBuffer struct {
+	pool    *spillPool
+	extents []spillExtent
+
+	// read is the extent and offset in the extent of the next read
+	read    int
+	readOff int64
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	off, n, err := b.pool.write(p)
+	if n > 0 {
+		if last := len(b.extents) - 1; last >= 0 && b.extents[last].off+b.extents[last].len == off {
+			b.extents[last].len += int64(n)
+		} else {
+			b.extents = append(b.extents, spillExtent{off: off, len: int64(n)})
+		}
+	}
+	return n, err
+}
+
+func (b *spillBuffer) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && b.read < len(b.extents) {
+		e := b.extents[b.read]
+		chunk := p[n:]
+		if remaining := e.len - b.readOff; int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+
+		m, err := b.pool.file.ReadAt(chunk, e.off+b.readOff)
+		n += m
+		b.readOff += int64(m)
+		if b.readOff == e.len {
+			b.read++
+			b.readOff = 0
+		}
+		if err != nil && !(errors.Is(err, io.EOF) && m == len(chunk)) {
+			return n, err
+		}
+	}
+
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,174 @@
+package vparquet2
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto" //nolint:all //deprecated
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestSpillBlock(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+	spillPath := t.TempDir()
+
+	cfg := &common.BlockConfig{
+		BloomFP:                 0.01,
+		BloomShardSizeBytes:     100 * 1024,
+		RowGroupSizeBytes:       20_000_000,
+		ParquetWriteBudgetBytes: 64 * 1024,
+		ParquetSpillPath:        spillPath,
+		ParquetStats:            true,
+	}
+
+	meta := backend.NewBlockMeta(tenantID, uuid.New(), VersionString, backend.EncNone, "")
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+	require.NotNil(t, s.spill)
+
+	ids := make([]common.ID, 0, 200)
+	traces := make([]*tempopb.Trace, 0, 200)
+	for i := 0; i < 200; i++ {
+		id := make([]byte, 16)
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+		tr := test.MakeTrace(5, id)
+		ids = append(ids, id)
+		traces = append(traces, tr)
+
+		pqTr := traceToParquet(id, tr)
+		s.Add(&pqTr, 0, 0)
+		// the traces are written to the pages long before the row group is flushed
+		require.LessOrEqual(t, s.bufferedTracesBytes, s.maxBufferedBytes)
+
+		if i%70 == 0 {
+			_, err := s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), s.meta.TotalRecords)
+	require.NotZero(t, s.meta.FooterSize)
+
+	// the temporary files are removed
+	files, err := os.ReadDir(spillPath)
+	require.NoError(t, err)
+	require.Empty(t, files)
+
+	b := newBackendBlock(s.meta, r)
+	for i, id := range ids {
+		tr, err := b.FindTraceByID(ctx, id, defaultSearchOptions())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(traces[i], tr))
+	}
+
+	pf, _, err := b.openForSearch(ctx, defaultSearchOptions())
+	require.NoError(t, err)
+	require.Len(t, pf.RowGroups(), 4)
+
+	var spans uint64
+	for _, tr := range traces {
+		for _, batch := range tr.Batches {
+			for _, ils := range batch.InstrumentationLibrarySpans {
+				spans += uint64(len(ils.Spans))
+			}
+		}
+	}
+	stats, err := b.Stats(ctx)
+	require.NoError(t, err)
+	require.Equal(t, spans, stats.Spans)
+
+	// the budget isn't supported with split columns
+	cfg.ParquetSplitHotColumns = true
+	cfg.IndexDownsampleBytes = 1
+	cfg.IndexPageSizeBytes = 1
+	require.Error(t, common.ValidateConfig(cfg))
+}
+
+type fileWriter struct {
+	backend.MockWriter
+	data []byte
+}
+
+func (w *fileWriter) StreamWriter(_ context.Context, _ string, _ uuid.UUID, _ string, data io.Reader, _ int64) error {
+	if _, ok := data.(*os.File); !ok {
+		return fmt.Errorf("expected *os.File, got %T", data)
+	}
+	var err error
+	w.data, err = io.ReadAll(data)
+	return err
+}
+
+func TestSpillWriterUpload(t *testing.T) {
+	// the file is passed to the backend, so the local backend can clone it
+	sw, err := newSpillWriter(t.TempDir())
+	require.NoError(t, err)
+	defer sw.close()
+
+	_, err = sw.Write([]byte("parquet"))
+	require.NoError(t, err)
+
+	w := &fileWriter{}
+	require.NoError(t, sw.upload(context.Background(), w, uuid.New(), tenantID))
+	require.Equal(t, []byte("parquet"), w.data)
+}
+
+func TestSpillPool(t *testing.T) {
+	f, err := createSpillFile(t.TempDir(), "pages-*")
+	require.NoError(t, err)
+	defer closeSpillFile(f)
+
+	p := &spillPool{file: f}
+
+	// writes to two buffers are interleaved
+	a := p.GetPageBuffer()
+	b := p.GetPageBuffer()
+	_, err = a.Write([]byte("aaa"))
+	require.NoError(t, err)
+	_, err = a.Write([]byte("AA"))
+	require.NoError(t, err)
+	_, err = b.Write([]byte("bb"))
+	require.NoError(t, err)
+	_, err = a.Write([]byte("a"))
+	require.NoError(t, err)
+	require.Len(t, a.(*spillBuffer).extents, 2)
+
+	readAll := func(buf io.Reader) []byte {
+		var out bytes.Buffer
+		// small reads cross the extents
+		_, err := io.CopyBuffer(struct{ io.Writer }{&out}, struct{ io.Reader }{buf}, make([]byte, 4))
+		require.NoError(t, err)
+		return out.Bytes()
+	}
+	require.Equal(t, []byte("aaaAAa"), readAll(a))
+	require.Equal(t, []byte("bb"), readAll(b))
+
+	// the file is truncated once all buffers are released
+	p.PutPageBuffer(a)
+	require.Equal(t, int64(8), p.size)
+	p.PutPageBuffer(b)
+	require.Equal(t, int64(0), p.size)
+	info, err := f.Stat()
+	require.NoError(t, err)
+	require.Zero(t, info.Size())
+}
@@ -0,0 +1,499 @@
+package vparquet2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/segmentio/parquet-go"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	pq "github.com/grafana/tempo/pkg/parquetquery"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// hotColumns are the columns stored in the hot file of blocks with split columns: the ids, times, durations,
+// services, names, statuses and dedicated attributes most searches read. All other columns are stored in the cold
+// file so these searches never read the bulk of the bytes of a block.
+var hotColumns = map[string]struct{}{
+	"TraceID":                      {},
+	"StartTimeUnixNano":            {},
+	"EndTimeUnixNano":              {},
+	"DurationNanos":                {},
+	"RootServiceName":              {},
+	"RootSpanName":                 {},
+	"ServiceNames":                 {},
+	"SpanCount":                    {},
+	"ErrorCount":                   {},
+	"rs.Resource.ServiceName":      {},
+	"rs.Resource.Cluster":          {},
+	"rs.Resource.Namespace":        {},
+	"rs.Resource.Pod":              {},
+	"rs.Resource.Container":        {},
+	"rs.Resource.K8sClusterName":   {},
+	"rs.Resource.K8sNamespaceName": {},
+	"rs.Resource.K8sPodName":       {},
+	"rs.Resource.K8sContainerName": {},
+	"rs.ils.Spans.ID":              {},
+	"rs.ils.Spans.Name":            {},
+	"rs.ils.Spans.StartUnixNanos":  {},
+	"rs.ils.Spans.EndUnixNanos":    {},
+	"rs.ils.Spans.StatusCode":      {},
+	"rs.ils.Spans.HttpMethod":      {},
+	"rs.ils.Spans.HttpUrl":         {},
+	"rs.ils.Spans.HttpStatusCode":  {},
+}
+
+func isHotColumn(path string) bool {
+	_, ok := hotColumns[path]
+	return ok
+}
+
+// splitSchema splits the columns of a trace schema in a hot and a cold schema. Both schemas keep the nesting and
+// the order of the columns of the trace schema, the values of a row keep their repetition and definition levels
+// and the row numbers of the values of both files match.
+type splitSchema struct {
+	hot, cold *parquet.Schema
+	// columns are the hot or cold column of every column of the trace schema
+	columns []splitColumn
+}
+
+type splitColumn struct {
+	hot   bool
+	index int
+}
+
+// splitSchemas caches the split schema of every trace schema
+var splitSchemas sync.Map // map[*parquet.Schema]*splitSchema
+
+func splitSchemaOf(sch *parquet.Schema) *splitSchema {
+	if s, ok := splitSchemas.Load(sch); ok {
+		return s.(*splitSchema)
+	}
+
+	s := &splitSchema{
+		hot:     parquet.NewSchema(sch.Name(), splitNode{Node: sch, hot: true}),
+		cold:    parquet.NewSchema(sch.Name(), splitNode{Node: sch, hot: false}),
+		columns: make([]splitColumn, len(sch.Columns())),
+	}
+	for i, path := range sch.Columns() {
+		hot := isHotColumn(strings.Join(path, "."))
+		sub := s.cold
+		if hot {
+			sub = s.hot
+		}
+		col, _ := sub.Lookup(path...)
+		s.columns[i] = splitColumn{hot: hot, index: col.ColumnIndex}
+	}
+
+	actual, _ := splitSchemas.LoadOrStore(sch, s)
+	return actual.(*splitSchema)
+}
+
+// split appends the values of the row to the hot and cold rows
+func (s *splitSchema) split(row, hot, cold parquet.Row) (parquet.Row, parquet.Row) {
+	for _, v := range row {
+		c := s.columns[v.Column()]
+		v = v.Level(v.RepetitionLevel(), v.DefinitionLevel(), c.index)
+		if c.hot {
+			hot = append(hot, v)
+		} else {
+			cold = append(cold, v)
+		}
+	}
+	return hot, cold
+}
+
+// splitNode keeps the fields of a node with hot or cold columns. The nesting of the fields is unchanged.
+type splitNode struct {
+	parquet.Node
+	path string
+	hot  bool
+}
+
+func (n splitNode) Fields() []parquet.Field {
+	fields := n.Node.Fields()
+	kept := make([]parquet.Field, 0, len(fields))
+	for _, f := range fields {
+		path := f.Name()
+		if n.path != "" {
+			path = n.path + "." + path
+		}
+		if hasSplitColumns(f, path, n.hot) {
+			kept = append(kept, splitField{Field: f, path: path, hot: n.hot})
+		}
+	}
+	return kept
+}
+
+type splitField struct {
+	parquet.Field
+	path string
+	hot  bool
+}
+
+func (f splitField) Fields() []parquet.Field {
+	return splitNode{Node: f.Field, path: f.path, hot: f.hot}.Fields()
+}
+
+func hasSplitColumns(n parquet.Node, path string, hot bool) bool {
+	if n.Leaf() {
+		return isHotColumn(path) == hot
+	}
+	for _, f := range n.Fields() {
+		if hasSplitColumns(f, path+"."+f.Name(), hot) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitReader reads the traces of a block with split columns. The hot and cold rows of a trace are reconstructed
+// into the same trace: the fields of each schema are set, repeated fields of the cold schema reuse the elements of
+// the hot schema.
+type splitReader struct {
+	hot, cold *parquet.Reader //nolint:all //deprecated
+	schema    *parquet.Schema
+	split     *splitSchema
+
+	hotRows, coldRows []parquet.Row
+}
+
+func newSplitReader(hot, cold *parquet.File) *splitReader {
+	sch := parquet.SchemaOf(new(Trace))
+	split := splitSchemaOf(sch)
+
+	return &splitReader{
+		hot:      parquet.NewReader(hot, split.hot),
+		cold:     parquet.NewReader(cold, split.cold),
+		schema:   sch,
+		split:    split,
+		hotRows:  []parquet.Row{nil},
+		coldRows: []parquet.Row{nil},
+	}
+}
+
+func (r *splitReader) Schema() *parquet.Schema {
+	return r.schema
+}
+
+// ReadRows reads the next traces as rows of the trace schema
+func (r *splitReader) ReadRows(rows []parquet.Row) (int, error) {
+	for i := range rows {
+		tr := new(Trace)
+		if err := r.Read(tr); err != nil {
+			return i, err
+		}
+		rows[i] = r.schema.Deconstruct(rows[i][:0], tr)
+	}
+	return len(rows), nil
+}
+
+// Read reads the next trace
+func (r *splitReader) Read(row interface{}) error {
+	r.hotRows[0] = r.hotRows[0][:0]
+	r.coldRows[0] = r.coldRows[0][:0]
+
+	n, err := r.hot.ReadRows(r.hotRows)
+	if n == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return err
+	}
+	n, err = r.cold.ReadRows(r.coldRows)
+	if n == 0 {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("reading cold file: %w", err)
+	}
+
+	if err := r.split.hot.Reconstruct(row, r.hotRows[0]); err != nil {
+		return err
+	}
+	return r.split.cold.Reconstruct(row, r.coldRows[0])
+}
+
+func (r *splitReader) SeekToRow(rowIndex int64) error {
+	if err := r.hot.SeekToRow(rowIndex); err != nil {
+		return err
+	}
+	return r.cold.SeekToRow(rowIndex)
+}
+
+func (r *splitReader) Close() error {
+	err := r.hot.Close()
+	if coldErr := r.cold.Close(); err == nil {
+		err = coldErr
+	}
+	return err
+}
+
+// blockFile is the parquet data of a block read by the search iterators. The columns of blocks with split columns
+// are read from the hot file, the cold file is only opened the first time one of its columns is read.
+type blockFile struct {
+	// pf is the data file, or the hot file of blocks with split columns
+	pf *parquet.File
+
+	openCold func() (*parquet.File, error)
+	coldOnce sync.Once
+	cold     *parquet.File
+	coldErr  error
+
+	// rgStart and rgCount are the searched row groups
+	rgStart, rgCount int
+	// rgKeep are the row groups of the file that are searched, nil searches all of them
+	rgKeep []bool
+
+	readers []*BackendReaderAt
+}
+
+func newBlockFile(pf *parquet.File) *blockFile {
+	return &blockFile{pf: pf, rgCount: len(pf.RowGroups())}
+}
+
+func (f *blockFile) split() bool {
+	return f.openCold != nil
+}
+
+// limitRowGroups searches count row groups starting at start. Both files of a block with split columns have the
+// same row groups.
+func (f *blockFile) limitRowGroups(start, count int) {
+	f.rgStart = start
+	f.rgCount = count
+}
+
+// keepRowGroups skips the row groups of the file that aren't kept. Row groups skipped before stay skipped.
+func (f *blockFile) keepRowGroups(keep []bool) {
+	if f.rgKeep != nil {
+		for i := range keep {
+			keep[i] = keep[i] && f.rgKeep[i]
+		}
+	}
+	f.rgKeep = keep
+}
+
+// RowGroups returns the searched row groups of the data or hot file
+func (f *blockFile) RowGroups() []parquet.RowGroup {
+	return f.searched(f.pf)
+}
+
+// searched returns the searched row groups of a file of the block
+func (f *blockFile) searched(pf *parquet.File) []parquet.RowGroup {
+	rgs := pf.RowGroups()
+	if f.rgKeep == nil {
+		return rgs[f.rgStart : f.rgStart+f.rgCount]
+	}
+
+	kept := make([]parquet.RowGroup, 0, f.rgCount)
+	for i := f.rgStart; i < f.rgStart+f.rgCount; i++ {
+		if f.rgKeep[i] {
+			kept = append(kept, rgs[i])
+		}
+	}
+	return kept
+}
+
+// coldFile opens the cold file once
+func (f *blockFile) coldFile() (*parquet.File, error) {
+	f.coldOnce.Do(func() {
+		f.cold, f.coldErr = f.openCold()
+		if f.coldErr != nil {
+			f.coldErr = fmt.Errorf("opening cold file: %w", f.coldErr)
+		}
+	})
+	return f.cold, f.coldErr
+}
+
+// column returns the searched row groups holding the column and its index, -1 if the column doesn't exist
+func (f *blockFile) column(name string) ([]parquet.RowGroup, int, error) {
+	if !f.split() || isHotColumn(name) {
+		index, _ := pq.GetColumnIndexByPath(f.pf, name)
+		return f.RowGroups(), index, nil
+	}
+
+	cold, err := f.coldFile()
+	if err != nil {
+		return nil, -1, err
+	}
+	index, _ := pq.GetColumnIndexByPath(cold, name)
+	return f.searched(cold), index, nil
+}
+
+// hasColumn returns true if the block has the column. Blocks with split columns always have the columns of the
+// current schema.
+func (f *blockFile) hasColumn(name string) bool {
+	if f.split() && !isHotColumn(name) {
+		_, ok := parquet.SchemaOf(new(Trace)).Lookup(strings.Split(name, ".")...)
+		return ok
+	}
+	return pq.HasColumn(f.pf, name)
+}
+
+// columnChunks returns the chunks of the column in the searched row groups, nil if the column doesn't exist
+func (f *blockFile) columnChunks(name string) ([]parquet.ColumnChunk, error) {
+	rgs, index, err := f.column(name)
+	if err != nil || index == -1 {
+		return nil, err
+	}
+	chunks := make([]parquet.ColumnChunk, 0, len(rgs))
+	for _, rg := range rgs {
+		chunks = append(chunks, rg.ColumnChunks()[index])
+	}
+	return chunks, nil
+}
+
+// rows returns a reader of the rows of the block
+func (f *blockFile) rows() (rowReader, error) {
+	if !f.split() {
+		return parquet.NewReader(f.pf), nil
+	}
+	cold, err := f.coldFile()
+	if err != nil {
+		return nil, err
+	}
+	return newSplitReader(f.pf, cold), nil
+}
+
+// bytesRead returns the bytes read from the backend by the files of the block
+func (f *blockFile) bytesRead() uint64 {
+	var n uint64
+	for _, r := range f.readers {
+		n += r.TotalBytesRead.Load()
+	}
+	return n
+}
+
+// errIterator is returned by the iterators of columns that can't be read
+type errIterator struct {
+	err error
+}
+
+var _ pq.Iterator = (*errIterator)(nil)
+
+func (i *errIterator) Next() (*pq.IteratorResult, error) { return nil, i.err }
+func (i *errIterator) SeekTo(pq.RowNumber, int) (*pq.IteratorResult, error) {
+	return nil, i.err
+}
+func (i *errIterator) Close() {}
+
+// splitFileNames are the files of a block with split columns
+var splitFileNames = []string{HotFileName, ColdFileName}
+
+// openSplit opens the hot and cold files of a block with split columns for full reads
+func (b *backendBlock) openSplit(ctx context.Context) (*parquet.File, rowReader, error) {
+	hot, err := b.openBuffered(ctx, HotFileName, b.meta.HotSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	cold, err := b.openBuffered(ctx, ColdFileName, b.meta.Size-b.meta.HotSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hot, newSplitReader(hot, cold), nil
+}
+
+// splitWriter writes the hot and cold files of a block with split columns. Every row is written to both files and
+// the row groups of both files are flushed at the same rows.
+type splitWriter struct {
+	schema    *parquet.Schema
+	split     *splitSchema
+	hot, cold *splitFileWriter
+
+	row, hotRow, coldRow parquet.Row
+}
+
+// splitFileWriter writes a file of a block with split columns to the backend
+type splitFileWriter struct {
+	w    *backendWriter
+	bw   tempo_io.BufferedWriteFlusher
+	pw   *parquet.GenericWriter[any]
+	size uint64
+}
+
+func newSplitWriter(ctx context.Context, to backend.Writer, meta *backend.BlockMeta, sch *parquet.Schema, opts []parquet.WriterOption, createBufferedWriter func(w io.Writer) tempo_io.BufferedWriteFlusher) *splitWriter {
+	split := splitSchemaOf(sch)
+
+	newFile := func(name string, fileSchema *parquet.Schema) *splitFileWriter {
+		w := &backendWriter{ctx, to, name, meta.BlockID, meta.TenantID, nil}
+		bw := createBufferedWriter(w)
+		// the schema of the file replaces the schema of the options
+		pw := parquet.NewGenericWriter[any](bw, append(opts[:len(opts):len(opts)], fileSchema)...)
+		return &splitFileWriter{w: w, bw: bw, pw: pw}
+	}
+
+	return &splitWriter{
+		schema: sch,
+		split:  split,
+		hot:    newFile(HotFileName, split.hot),
+		cold:   newFile(ColdFileName, split.cold),
+	}
+}
+
+func (w *splitWriter) writeTraces(trs []*Trace) error {
+	for _, tr := range trs {
+		w.row = w.schema.Deconstruct(w.row[:0], tr)
+		if err := w.writeRow(w.row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRow writes a row of the trace schema to the hot and cold files
+func (w *splitWriter) writeRow(row parquet.Row) error {
+	w.hotRow, w.coldRow = w.split.split(row, w.hotRow[:0], w.coldRow[:0])
+	if _, err := w.hot.pw.WriteRows([]parquet.Row{w.hotRow}); err != nil {
+		return err
+	}
+	_, err := w.cold.pw.WriteRows([]parquet.Row{w.coldRow})
+	return err
+}
+
+// flush flushes the row groups of both files and returns the bytes written
+func (w *splitWriter) flush() (int, error) {
+	n := 0
+	for _, f := range []*splitFileWriter{w.hot, w.cold} {
+		if err := f.pw.Flush(); err != nil {
+			return 0, err
+		}
+		fn := f.bw.Len()
+		f.size += uint64(fn)
+		n += fn
+		if err := f.bw.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// complete flushes the final row groups, writes the footers and closes both files. It returns the bytes written.
+func (w *splitWriter) complete() (int, error) {
+	n := 0
+	for _, f := range []*splitFileWriter{w.hot, w.cold} {
+		if err := f.pw.Flush(); err != nil {
+			return 0, err
+		}
+		// Close parquet file. This writes the footer and metadata.
+		if err := f.pw.Close(); err != nil {
+			return 0, err
+		}
+
+		fn := f.bw.Len()
+		f.size += uint64(fn)
+		n += fn
+		if err := f.bw.Flush(); err != nil {
+			return 0, err
+		}
+		if err := f.bw.Close(); err != nil {
+			return 0, err
+		}
+		if err := f.w.Close(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,143 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/protobuf/proto" //nolint:all //deprecated
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestSplitBlock(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:                0.01,
+		BloomShardSizeBytes:    100 * 1024,
+		RowGroupSizeBytes:      20_000_000,
+		ParquetSplitHotColumns: true,
+	}
+
+	meta := backend.NewBlockMeta(tenantID, uuid.New(), VersionString, backend.EncNone, "")
+	meta.TotalObjects = 100
+
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+
+	ids := make([]common.ID, 0, 100)
+	traces := make([]*tempopb.Trace, 0, 100)
+	for i := 0; i < 100; i++ {
+		id := make([]byte, 16)
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+		tr := test.MakeTrace(5, id)
+		foo := "baz"
+		if i == 7 {
+			foo = "bar"
+		}
+		for _, batch := range tr.Batches {
+			for _, ils := range batch.InstrumentationLibrarySpans {
+				for _, s := range ils.Spans {
+					s.Attributes = []*v1_common.KeyValue{
+						{Key: "foo", Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_StringValue{StringValue: foo}}},
+					}
+				}
+			}
+		}
+		ids = append(ids, id)
+		traces = append(traces, tr)
+
+		pqTr := traceToParquet(id, tr)
+		s.Add(&pqTr, 0, 0)
+		if i%30 == 0 {
+			_, err := s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+
+	require.NotZero(t, s.meta.HotSize)
+	require.NotZero(t, s.meta.HotFooterSize)
+	require.Less(t, s.meta.HotSize, s.meta.Size)
+	b := newBackendBlock(s.meta, r)
+
+	// traces are read from both files
+	for i, id := range ids {
+		tr, err := b.FindTraceByID(ctx, id, defaultSearchOptions())
+		require.NoError(t, err)
+		require.True(t, proto.Equal(traces[i], tr))
+	}
+
+	iter, err := b.Iterator(ctx)
+	require.NoError(t, err)
+	for i := range ids {
+		tr, err := iter.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []byte(ids[i]), tr.TraceID)
+		require.True(t, proto.Equal(traces[i], parquetTraceToTempopbTrace(tr)))
+	}
+	tr, err := iter.Next(ctx)
+	require.NoError(t, err)
+	require.Nil(t, tr)
+
+	// searches of hot columns only read the hot file
+	res, err := b.Search(ctx, &tempopb.SearchRequest{Tags: map[string]string{LabelServiceName: "test-service"}}, defaultSearchOptions())
+	require.NoError(t, err)
+	require.Len(t, res.Traces, 100)
+	require.LessOrEqual(t, res.Metrics.InspectedBytes, s.meta.HotSize)
+
+	// searches of generic attributes read the cold file
+	res, err = b.Search(ctx, &tempopb.SearchRequest{Tags: map[string]string{"foo": "bar"}}, defaultSearchOptions())
+	require.NoError(t, err)
+	require.Len(t, res.Traces, 1)
+	require.Equal(t, util.TraceIDToHexString(ids[7]), res.Traces[0].TraceID)
+	require.Greater(t, res.Metrics.InspectedBytes, s.meta.HotSize)
+
+	tags := map[string]struct{}{}
+	err = b.SearchTags(ctx, func(tag string) { tags[tag] = struct{}{} }, defaultSearchOptions())
+	require.NoError(t, err)
+	require.Contains(t, tags, LabelServiceName)
+	require.Contains(t, tags, "foo")
+
+	values := map[string]struct{}{}
+	err = b.SearchTagValues(ctx, "foo", func(v string) { values[v] = struct{}{} }, defaultSearchOptions())
+	require.NoError(t, err)
+	require.Equal(t, map[string]struct{}{"bar": {}, "baz": {}}, values)
+
+	// compacted blocks keep all traces
+	c := NewCompactor(common.CompactionOptions{
+		BlockConfig:     *cfg,
+		OutputBlocks:    1,
+		FlushSizeBytes:  30_000_000,
+		ObjectsCombined: func(compactionLevel, objects int) {},
+	})
+	metas, err := c.Compact(ctx, log.NewNopLogger(), r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, []*backend.BlockMeta{s.meta})
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.NotZero(t, metas[0].HotSize)
+	require.Equal(t, 100, metas[0].TotalObjects)
+
+	found, err := newBackendBlock(metas[0], r).FindTraceByID(ctx, ids[42], defaultSearchOptions())
+	require.NoError(t, err)
+	require.True(t, proto.Equal(traces[42], found))
+}
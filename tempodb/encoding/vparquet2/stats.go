@@ -0,0 +1,257 @@
+package vparquet2
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/segmentio/parquet-go"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// StatsFileName are the statistics of blocks written with BlockConfig.ParquetStats
+const StatsFileName = "stats.json"
+
+// sketchSize is the number of hashes kept by the cardinality sketches, the standard error of their estimates is
+// about 1/sqrt(sketchSize)
+const sketchSize = 256
+
+var _ common.Measurable = (*backendBlock)(nil)
+
+// Stats returns the statistics recorded when the block was written
+func (b *backendBlock) Stats(ctx context.Context) (*common.BlockStats, error) {
+	if !b.meta.Stats {
+		return nil, common.ErrUnsupported
+	}
+	return readStats(ctx, b.meta, b.r)
+}
+
+func readStats(ctx context.Context, meta *backend.BlockMeta, r backend.Reader) (*common.BlockStats, error) {
+	b, err := r.Read(ctx, StatsFileName, meta.BlockID, meta.TenantID, true)
+	if err != nil {
+		return nil, fmt.Errorf("error reading stats: %w", err)
+	}
+
+	s := &common.BlockStats{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("error unmarshalling stats: %w", err)
+	}
+	return s, nil
+}
+
+// attrColumns are the indexes of the key and scalar value columns of resource or span attributes
+type attrColumns struct {
+	key    int
+	values []int
+}
+
+// statsWriter builds the statistics of a block while its rows are added. Traces are deconstructed to rows, so both
+// are counted the same way.
+type statsWriter struct {
+	stats    common.BlockStats
+	sketches map[string]*sketch
+
+	sch *parquet.Schema
+	row parquet.Row
+
+	serviceName    int
+	spanStart      int
+	spanStartLevel int
+	resourceAttrs  attrColumns
+	spanAttrs      attrColumns
+	// dedicated are the names of the attributes stored in dedicated columns by column index
+	dedicated map[int]string
+
+	// columns are the values of the current row of the columns above
+	columns map[int][]parquet.Value
+}
+
+func newStatsWriter() *statsWriter {
+	sch := parquet.SchemaOf(new(Trace))
+	w := &statsWriter{
+		stats: common.BlockStats{
+			ServiceSpans:         map[string]uint64{},
+			AttributeCardinality: map[string]uint64{},
+		},
+		sketches:  map[string]*sketch{},
+		sch:       sch,
+		dedicated: map[int]string{},
+		columns:   map[int][]parquet.Value{},
+	}
+
+	lookup := func(path string) int {
+		col, found := sch.Lookup(strings.Split(path, ".")...)
+		if !found {
+			return -1
+		}
+		w.columns[col.ColumnIndex] = nil
+		return col.ColumnIndex
+	}
+
+	w.serviceName = lookup(labelMappings[LabelServiceName])
+	w.spanStart = lookup(columnSpanStart)
+	if col, found := sch.Lookup(strings.Split(columnSpanStart, ".")...); found {
+		w.spanStartLevel = col.MaxDefinitionLevel
+	}
+	w.resourceAttrs = attrColumns{
+		key:    lookup(FieldResourceAttrKey),
+		values: []int{lookup(FieldResourceAttrVal), lookup(FieldResourceAttrValInt), lookup(FieldResourceAttrValDouble), lookup(FieldResourceAttrValBool)},
+	}
+	w.spanAttrs = attrColumns{
+		key:    lookup(FieldSpanAttrKey),
+		values: []int{lookup(FieldSpanAttrVal), lookup(FieldSpanAttrValInt), lookup(FieldSpanAttrValDouble), lookup(FieldSpanAttrValBool)},
+	}
+	for name, path := range labelMappings {
+		if col := lookup(path); col >= 0 {
+			w.dedicated[col] = name
+		}
+	}
+	return w
+}
+
+// addTrace adds the statistics of the next trace
+func (w *statsWriter) addTrace(tr *Trace) {
+	w.row = w.sch.Deconstruct(w.row[:0], tr)
+	w.addRow(w.row)
+}
+
+// addRow adds the statistics of the next row
+func (w *statsWriter) addRow(row parquet.Row) {
+	for col := range w.columns {
+		w.columns[col] = w.columns[col][:0]
+	}
+	for _, v := range row {
+		if vs, ok := w.columns[v.Column()]; ok {
+			w.columns[v.Column()] = append(vs, v)
+		}
+	}
+
+	// a repetition level of 0 or 1 starts the spans of the next resource, services are counted in the same order
+	services := w.columns[w.serviceName]
+	resource := -1
+	for _, v := range w.columns[w.spanStart] {
+		if v.RepetitionLevel() <= 1 {
+			resource++
+		}
+		if v.DefinitionLevel() != w.spanStartLevel {
+			continue
+		}
+		w.stats.Spans++
+		if start := v.Uint64(); w.stats.Spans == 1 || start < w.stats.MinSpanStartUnixNano {
+			w.stats.MinSpanStartUnixNano = start
+		}
+		if start := v.Uint64(); start > w.stats.MaxSpanStartUnixNano {
+			w.stats.MaxSpanStartUnixNano = start
+		}
+		if resource < len(services) && !services[resource].IsNull() {
+			w.stats.ServiceSpans[services[resource].String()]++
+		}
+	}
+
+	for col, name := range w.dedicated {
+		for _, v := range w.columns[col] {
+			if !v.IsNull() {
+				w.addValue(name, projectedValue(v))
+			}
+		}
+	}
+	w.addAttrs(w.resourceAttrs)
+	w.addAttrs(w.spanAttrs)
+}
+
+// addAttrs adds the values of generic attributes. Every attribute has an entry in the key and all value columns,
+// the entries at the same position belong to the same attribute.
+func (w *statsWriter) addAttrs(cols attrColumns) {
+	keys := w.columns[cols.key]
+	for i, k := range keys {
+		if k.IsNull() {
+			continue
+		}
+		for _, col := range cols.values {
+			vs := w.columns[col]
+			if i < len(vs) && !vs[i].IsNull() {
+				w.addValue(k.String(), projectedValue(vs[i]))
+				break
+			}
+		}
+	}
+}
+
+func (w *statsWriter) addValue(name, value string) {
+	s, ok := w.sketches[name]
+	if !ok {
+		s = newSketch()
+		w.sketches[name] = s
+	}
+	s.add(xxhash.Sum64String(value))
+}
+
+func (w *statsWriter) marshal(columnSizes map[string]uint64) ([]byte, error) {
+	for name, s := range w.sketches {
+		w.stats.AttributeCardinality[name] = s.estimate()
+	}
+	w.stats.ColumnSizes = columnSizes
+	return json.Marshal(w.stats)
+}
+
+// columnSizes adds the compressed sizes of the column chunks of a parquet file of the block to sizes
+func columnSizes(pf *parquet.File, sizes map[string]uint64) {
+	for _, rg := range pf.Metadata().RowGroups {
+		for _, cc := range rg.Columns {
+			sizes[strings.Join(cc.MetaData.PathInSchema, ".")] += uint64(cc.MetaData.TotalCompressedSize)
+		}
+	}
+}
+
+// sketch estimates the number of distinct values from the smallest hashes of the values (k minimum values). The
+// count is exact until more than sketchSize distinct values were added.
+type sketch struct {
+	hashes maxHeap
+	seen   map[uint64]struct{}
+}
+
+func newSketch() *sketch {
+	return &sketch{seen: map[uint64]struct{}{}}
+}
+
+func (s *sketch) add(h uint64) {
+	if _, ok := s.seen[h]; ok {
+		return
+	}
+	if len(s.hashes) == sketchSize {
+		if h >= s.hashes[0] {
+			return
+		}
+		delete(s.seen, heap.Pop(&s.hashes).(uint64))
+	}
+	heap.Push(&s.hashes, h)
+	s.seen[h] = struct{}{}
+}
+
+func (s *sketch) estimate() uint64 {
+	if len(s.hashes) < sketchSize {
+		return uint64(len(s.hashes))
+	}
+	// the largest kept hash is expected at (k-1)/n of the hash space
+	return uint64(math.Round(float64(sketchSize-1) / (float64(s.hashes[0]) / math.MaxUint64)))
+}
+
+type maxHeap []uint64
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,133 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestStats(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+		RowGroupSizeBytes:   20_000_000,
+		ParquetStats:        true,
+	}
+
+	meta := backend.NewBlockMeta(tenantID, uuid.New(), VersionString, backend.EncNone, "")
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+
+	// 10 traces with a resource of service a with 2 spans and a resource of service b with 1 span. the spans of
+	// service a have 5 distinct span ids, b has a resource attribute with a value per trace
+	// spans start from 100 to 129 unix nanos
+	method := "GET"
+	for i := 0; i < 10; i++ {
+		spanStart := uint64(100 + 3*i)
+		id := make([]byte, 16)
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+		userID := strconv.Itoa(i % 5)
+		pod := "pod-" + strconv.Itoa(i)
+
+		tr := &Trace{
+			TraceID: id,
+			ResourceSpans: []ResourceSpans{
+				{
+					Resource: Resource{ServiceName: "a"},
+					InstrumentationLibrarySpans: []ILS{{
+						Spans: []Span{
+							{ID: []byte{1}, Name: "x", StartUnixNanos: spanStart, HttpMethod: &method, Attrs: []Attribute{{Key: "user.id", Value: &userID}}},
+							{ID: []byte{2}, Name: "y", StartUnixNanos: spanStart + 1},
+						},
+					}},
+				},
+				{
+					Resource: Resource{ServiceName: "b", Attrs: []Attribute{{Key: "pod.name", Value: &pod}}},
+					InstrumentationLibrarySpans: []ILS{{
+						Spans: []Span{{ID: []byte{3}, Name: "z", StartUnixNanos: spanStart + 2}},
+					}},
+				},
+			},
+		}
+		s.Add(tr, 0, 0)
+		if i == 4 {
+			_, err := s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+	require.True(t, s.meta.Stats)
+
+	check := func(meta *backend.BlockMeta) {
+		stats, err := newBackendBlock(meta, r).Stats(ctx)
+		require.NoError(t, err)
+
+		require.Equal(t, uint64(30), stats.Spans)
+		require.Equal(t, uint64(100), stats.MinSpanStartUnixNano)
+		require.Equal(t, uint64(129), stats.MaxSpanStartUnixNano)
+		require.Equal(t, map[string]uint64{"a": 20, "b": 10}, stats.ServiceSpans)
+		require.Equal(t, uint64(2), stats.AttributeCardinality[LabelServiceName])
+		require.Equal(t, uint64(3), stats.AttributeCardinality[LabelName])
+		require.Equal(t, uint64(1), stats.AttributeCardinality[LabelHTTPMethod])
+		require.Equal(t, uint64(5), stats.AttributeCardinality["user.id"])
+		require.Equal(t, uint64(10), stats.AttributeCardinality["pod.name"])
+		require.NotZero(t, stats.ColumnSizes[TraceIDColumnName])
+		require.NotZero(t, stats.ColumnSizes[columnSpanStart])
+	}
+	check(s.meta)
+
+	// compacted blocks have the same stats
+	c := NewCompactor(common.CompactionOptions{
+		BlockConfig:     *cfg,
+		OutputBlocks:    1,
+		FlushSizeBytes:  30_000_000,
+		ObjectsCombined: func(compactionLevel, objects int) {},
+	})
+	metas, err := c.Compact(ctx, log.NewNopLogger(), r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, []*backend.BlockMeta{s.meta})
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.True(t, metas[0].Stats)
+	check(metas[0])
+
+	// blocks without stats
+	block := makeBackendBlockWithTraces(t, []*Trace{{TraceID: []byte{1}}})
+	_, err = block.Stats(ctx)
+	require.ErrorIs(t, err, common.ErrUnsupported)
+}
+
+func TestSketch(t *testing.T) {
+	s := newSketch()
+	for i := 0; i < 100; i++ {
+		s.add(uint64(i))
+		s.add(uint64(i))
+	}
+	require.Equal(t, uint64(100), s.estimate())
+
+	s = newSketch()
+	for i := 0; i < 100_000; i++ {
+		s.add(xxhash.Sum64String(strconv.Itoa(i % 50_000)))
+	}
+	require.InDelta(t, 50_000, float64(s.estimate()), 50_000*0.2)
+}
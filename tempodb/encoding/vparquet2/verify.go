@@ -0,0 +1,115 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/segmentio/parquet-go"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var _ common.Verifiable = (*backendBlock)(nil)
+
+// Verify reads every page of the parquet files of the block and checks their footers against the meta. The
+// optional indexes flagged in the meta must be readable.
+func (b *backendBlock) Verify(ctx context.Context) ([]common.VerifyProblem, error) {
+	var problems []common.VerifyProblem
+
+	if b.split() {
+		hot, hotProblems := b.verifyFile(ctx, HotFileName, b.meta.HotSize, b.meta.HotFooterSize)
+		cold, coldProblems := b.verifyFile(ctx, ColdFileName, b.meta.Size-b.meta.HotSize, b.meta.FooterSize)
+		problems = append(problems, hotProblems...)
+		problems = append(problems, coldProblems...)
+
+		if hot != nil && cold != nil && hot.NumRows() != cold.NumRows() {
+			problems = append(problems, common.VerifyProblem{
+				Fatal: true,
+				Msg:   fmt.Sprintf("%s has %d rows, %s has %d rows", HotFileName, hot.NumRows(), ColdFileName, cold.NumRows()),
+			})
+		}
+	} else {
+		_, fileProblems := b.verifyFile(ctx, DataFileName, b.meta.Size, b.meta.FooterSize)
+		problems = append(problems, fileProblems...)
+	}
+
+	// the indexes only skip row groups, blocks are searched without them once their flag is cleared
+	if b.meta.ServiceIndex {
+		if _, err := readServiceIndex(ctx, b.meta, b.r); err != nil {
+			problems = append(problems, common.VerifyProblem{Msg: fmt.Sprintf("%v, the service index flag of the meta must be cleared", err)})
+		}
+	}
+	if b.meta.ZoneMaps {
+		if _, err := readZoneMaps(ctx, b.meta, b.r); err != nil {
+			problems = append(problems, common.VerifyProblem{Msg: fmt.Sprintf("%v, the zone maps flag of the meta must be cleared", err)})
+		}
+	}
+	if b.meta.Stats {
+		if _, err := readStats(ctx, b.meta, b.r); err != nil {
+			problems = append(problems, common.VerifyProblem{Msg: fmt.Sprintf("%v, the stats flag of the meta must be cleared", err)})
+		}
+	}
+
+	return problems, ctx.Err()
+}
+
+// verifyFile checks the footer of a parquet file of the block and reads all its pages. The file is returned if
+// it could be opened.
+func (b *backendBlock) verifyFile(ctx context.Context, name string, size uint64, footerSize uint32) (*parquet.File, []common.VerifyProblem) {
+	fatal := func(format string, args ...interface{}) []common.VerifyProblem {
+		return []common.VerifyProblem{{Fatal: true, Msg: name + ": " + fmt.Sprintf(format, args...)}}
+	}
+
+	if size < 8 {
+		return nil, fatal("size %d of the meta is too small for a parquet file", size)
+	}
+
+	trailer := make([]byte, 8)
+	if _, err := NewBackendReaderAt(ctx, b.r, name, b.meta.BlockID, b.meta.TenantID).ReadAt(trailer, int64(size)-8); err != nil {
+		return nil, fatal("error reading footer: %v", err)
+	}
+	if string(trailer[4:]) != "PAR1" {
+		return nil, fatal("no parquet footer at the size %d of the meta", size)
+	}
+
+	var problems []common.VerifyProblem
+	// blocks written before the footer size was added to the meta have none
+	if actual := binary.LittleEndian.Uint32(trailer); footerSize > 0 && actual != footerSize {
+		problems = append(problems, common.VerifyProblem{Msg: fmt.Sprintf("%s: footer size is %d, %d in the meta", name, actual, footerSize)})
+	}
+
+	pf, err := b.openBuffered(ctx, name, size)
+	if err != nil {
+		return nil, append(problems, fatal("error opening parquet file: %v", err)...)
+	}
+
+	columns := pf.Schema().Columns()
+	for i, rg := range pf.RowGroups() {
+		for j, chunk := range rg.ColumnChunks() {
+			if err := readPages(chunk); err != nil {
+				return pf, append(problems, fatal("row group %d column %s: error reading page: %v", i, strings.Join(columns[j], "."), err)...)
+			}
+		}
+	}
+
+	return pf, problems
+}
+
+func readPages(chunk parquet.ColumnChunk) error {
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	for {
+		_, err := pages.ReadPage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,616 @@
+package vparquet2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/parquet-go"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// walFlushFilenameFormat names the parquet files of a wal block in the order they were flushed
+const walFlushFilenameFormat = "%010d"
+
+// WALBlock stores the traces appended to a wal block in the parquet schema. Appended traces are buffered in
+// memory and flushed to a new parquet file in the folder of the block, sorted by trace id. Reads only use the
+// flushed files, so the buffer is flushed before a block is iterated, searched or a trace is found.
+type WALBlock struct {
+	path         string
+	dataEncoding string
+	decoder      model.ObjectDecoder
+	sync         bool
+
+	mtx           sync.Mutex
+	buffer        []*Trace
+	bufferIDs     map[string]int
+	bufferedBytes int
+	flushed       []*walFlush
+	nextFlush     int
+}
+
+// walFlush is a parquet file of a wal block and the sorted ids of the traces it contains
+type walFlush struct {
+	filename string
+	size     int64
+	ids      []common.ID
+}
+
+// CreateWALBlock creates a wal block in the given folder. Appended objects are decoded with dataEncoding. If sync
+// is set every flushed file is synced to disk.
+func CreateWALBlock(path string, dataEncoding string, sync bool) (*WALBlock, error) {
+	b, err := newWALBlock(path, dataEncoding, sync)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(path, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// OpenWALBlock opens the wal block in the given folder for replay. fn is called with the id and time range of every
+// trace in the block. Files that can't be read, such as a file that was partially flushed, are removed and
+// returned as a warning.
+func OpenWALBlock(path string, dataEncoding string, sync bool, fn func(id common.ID, start, end uint32)) (*WALBlock, error, error) {
+	b, err := newWALBlock(path, dataEncoding, sync)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warning error
+	for _, e := range entries {
+		n, err := strconv.Atoi(e.Name())
+		if e.IsDir() || err != nil {
+			continue
+		}
+
+		filename := filepath.Join(path, e.Name())
+		fl, err := replayWALFlush(filename, fn)
+		if err != nil {
+			warning = fmt.Errorf("replaying wal file %s: %w", filename, err)
+			err = os.Remove(filename)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		b.flushed = append(b.flushed, fl)
+		if n >= b.nextFlush {
+			b.nextFlush = n + 1
+		}
+	}
+
+	return b, warning, nil
+}
+
+func newWALBlock(path string, dataEncoding string, sync bool) (*WALBlock, error) {
+	dec, err := model.NewObjectDecoder(dataEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WALBlock{
+		path:         path,
+		dataEncoding: dataEncoding,
+		decoder:      dec,
+		sync:         sync,
+		bufferIDs:    map[string]int{},
+		nextFlush:    1,
+	}, nil
+}
+
+// replayWALFlush reads all traces of a flushed file. Traces of partial files aren't passed to fn.
+func replayWALFlush(filename string, fn func(id common.ID, start, end uint32)) (*walFlush, error) {
+	fl := &walFlush{filename: filename}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	fl.size = info.Size()
+
+	iter, err := fl.iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var traces []*Trace
+	for {
+		tr, err := iter.Next(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		if tr == nil {
+			break
+		}
+		traces = append(traces, tr)
+	}
+
+	for _, tr := range traces {
+		fl.ids = append(fl.ids, tr.TraceID)
+		fn(tr.TraceID, uint32(tr.StartTimeUnixNano/1e9), uint32(tr.EndTimeUnixNano/1e9))
+	}
+	return fl, nil
+}
+
+// Append decodes the object and adds it to the buffer. Objects with the same id are combined.
+func (b *WALBlock) Append(id common.ID, obj []byte) error {
+	trace, err := b.decoder.PrepareForRead(obj)
+	if err != nil {
+		return err
+	}
+	tr := traceToParquet(id, trace)
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if i, ok := b.bufferIDs[string(tr.TraceID)]; ok {
+		b.buffer[i] = combineWALTraces(b.buffer[i], &tr)
+	} else {
+		b.bufferIDs[string(tr.TraceID)] = len(b.buffer)
+		b.buffer = append(b.buffer, &tr)
+	}
+	b.bufferedBytes += estimateTraceSize(&tr)
+
+	return nil
+}
+
+// combineWALTraces combines two parts of a trace and extends the time range of the result to include both
+func combineWALTraces(a, b *Trace) *Trace {
+	start, end := a.StartTimeUnixNano, a.EndTimeUnixNano
+	if b.StartTimeUnixNano < start {
+		start = b.StartTimeUnixNano
+	}
+	if b.EndTimeUnixNano > end {
+		end = b.EndTimeUnixNano
+	}
+	rootService, rootSpan := a.RootServiceName, a.RootSpanName
+	if rootService == "" && rootSpan == "" {
+		rootService, rootSpan = b.RootServiceName, b.RootSpanName
+	}
+
+	tr := CombineTraces(a, b)
+	tr.StartTimeUnixNano = start
+	tr.EndTimeUnixNano = end
+	tr.DurationNanos = end - start
+	tr.RootServiceName = rootService
+	tr.RootSpanName = rootSpan
+	return tr
+}
+
+// Flush writes the buffered traces to a new parquet file
+func (b *WALBlock) Flush() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.flush()
+}
+
+func (b *WALBlock) flush() error {
+	if len(b.buffer) == 0 {
+		return nil
+	}
+
+	sort.Slice(b.buffer, func(i, j int) bool {
+		return bytes.Compare(b.buffer[i].TraceID, b.buffer[j].TraceID) == -1
+	})
+
+	filename := filepath.Join(b.path, fmt.Sprintf(walFlushFilenameFormat, b.nextFlush))
+	size, err := b.writeFlush(filename)
+	if err != nil {
+		// ignore error, a partial file is removed on replay anyway
+		_ = os.Remove(filename)
+		return err
+	}
+
+	ids := make([]common.ID, 0, len(b.buffer))
+	for _, tr := range b.buffer {
+		ids = append(ids, tr.TraceID)
+	}
+	b.flushed = append(b.flushed, &walFlush{
+		filename: filename,
+		size:     size,
+		ids:      ids,
+	})
+	b.nextFlush++
+
+	b.buffer = b.buffer[:0]
+	b.bufferIDs = map[string]int{}
+	b.bufferedBytes = 0
+	return nil
+}
+
+func (b *WALBlock) writeFlush(filename string) (int64, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	w := parquet.NewGenericWriter[*Trace](f)
+	_, err = w.Write(b.buffer)
+	if err != nil {
+		return 0, err
+	}
+	err = w.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	if b.sync {
+		err = f.Sync()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// DataLength is the size of the flushed files and the estimated size of the buffered traces
+func (b *WALBlock) DataLength() uint64 {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	length := uint64(b.bufferedBytes)
+	for _, fl := range b.flushed {
+		length += uint64(fl.size)
+	}
+	return length
+}
+
+// Length is the number of traces in the flushed files and the buffer. Traces flushed more than once are counted
+// once per file.
+func (b *WALBlock) Length() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	length := len(b.buffer)
+	for _, fl := range b.flushed {
+		length += len(fl.ids)
+	}
+	return length
+}
+
+// flushedFiles flushes the buffer and returns the flushed files
+func (b *WALBlock) flushedFiles() ([]*walFlush, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	err := b.flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]*walFlush(nil), b.flushed...), nil
+}
+
+// Iterator returns the traces of the block in order of their id. The parts of a trace flushed to different files
+// are combined.
+func (b *WALBlock) Iterator(ctx context.Context) (Iterator, error) {
+	flushed, err := b.flushedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	iters := make([]Iterator, 0, len(flushed))
+	for _, fl := range flushed {
+		iter, err := fl.iterator()
+		if err != nil {
+			for _, i := range iters {
+				i.Close()
+			}
+			return nil, err
+		}
+		iters = append(iters, iter)
+	}
+
+	return &walIterator{
+		iters:  iters,
+		peeks:  make([]*Trace, len(iters)),
+		closed: make([]bool, len(iters)),
+	}, nil
+}
+
+// ObjectIterator returns the traces of the block in order of their id as objects in the data encoding of the
+// block. CreateBlock uses the parquet traces directly if it is passed this iterator.
+func (b *WALBlock) ObjectIterator(ctx context.Context) (common.Iterator, error) {
+	iter, err := b.Iterator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := model.NewSegmentDecoder(b.dataEncoding)
+	if err != nil {
+		iter.Close()
+		return nil, err
+	}
+
+	return &walObjectIterator{iter: iter, dec: dec}, nil
+}
+
+// Find returns the trace with the given id as an object in the data encoding of the block or nil if the block
+// doesn't contain it.
+func (b *WALBlock) Find(ctx context.Context, id common.ID) ([]byte, error) {
+	flushed, err := b.flushedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	id = util.PadTraceIDTo16Bytes(id)
+
+	var traces []*Trace
+	for _, fl := range flushed {
+		tr, err := fl.find(id)
+		if err != nil {
+			return nil, err
+		}
+		if tr != nil {
+			traces = append(traces, tr)
+		}
+	}
+
+	if len(traces) == 0 {
+		return nil, nil
+	}
+
+	dec, err := model.NewSegmentDecoder(b.dataEncoding)
+	if err != nil {
+		return nil, err
+	}
+	return marshalWALTrace(dec, combineWALTraceParts(traces))
+}
+
+// Search searches the flushed files of the block with the columnar readers.
+func (b *WALBlock) Search(ctx context.Context, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error) {
+	flushed, err := b.flushedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &tempopb.SearchResponse{
+		Metrics: &tempopb.SearchMetrics{
+			InspectedBlocks: 1,
+		},
+	}
+
+	for _, fl := range flushed {
+		results, err := fl.search(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Traces = append(resp.Traces, results.Traces...)
+		resp.Metrics.InspectedBytes += uint64(fl.size)
+		resp.Metrics.InspectedTraces += uint32(len(fl.ids))
+	}
+
+	return resp, nil
+}
+
+// Clear removes the folder of the block
+func (b *WALBlock) Clear() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.buffer = nil
+	b.bufferIDs = map[string]int{}
+	b.bufferedBytes = 0
+
+	return os.RemoveAll(b.path)
+}
+
+func (fl *walFlush) open() (*os.File, *parquet.File, error) {
+	f, err := os.Open(fl.filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pf, err := parquet.OpenFile(f, fl.size)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+
+	return f, pf, nil
+}
+
+func (fl *walFlush) iterator() (Iterator, error) {
+	f, pf, err := fl.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return &walFlushIterator{
+		f: f,
+		blockIterator: blockIterator{
+			blockID: fl.filename,
+			r:       parquet.NewReader(pf, parquet.SchemaOf(&Trace{})),
+		},
+	}, nil
+}
+
+// find reads the trace with the given id. The rows of a file are sorted by id and an id is in a file at most once
+// so the position of the id is the row number of the trace.
+func (fl *walFlush) find(id common.ID) (*Trace, error) {
+	row := sort.Search(len(fl.ids), func(i int) bool {
+		return bytes.Compare(fl.ids[i], id) >= 0
+	})
+	if row == len(fl.ids) || !bytes.Equal(fl.ids[row], id) {
+		return nil, nil
+	}
+
+	f, pf, err := fl.open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := parquet.NewReader(pf, parquet.SchemaOf(&Trace{}))
+	defer r.Close()
+
+	err = r.SeekToRow(int64(row))
+	if err != nil {
+		return nil, errors.Wrap(err, "seek to row")
+	}
+
+	tr := new(Trace)
+	err = r.Read(tr)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("error reading row from wal file %s", fl.filename))
+	}
+	return tr, nil
+}
+
+func (fl *walFlush) search(ctx context.Context, req *tempopb.SearchRequest) (*tempopb.SearchResponse, error) {
+	f, pf, err := fl.open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return searchParquetFile(ctx, newBlockFile(pf), req)
+}
+
+// walFlushIterator iterates the traces of a flushed file and closes the file with the iterator
+type walFlushIterator struct {
+	blockIterator
+	f *os.File
+}
+
+func (i *walFlushIterator) Close() {
+	_ = i.r.Close()
+	_ = i.f.Close()
+}
+
+// walIterator merges the traces of the flushed files of a block by id
+type walIterator struct {
+	iters  []Iterator
+	peeks  []*Trace
+	closed []bool
+}
+
+var _ Iterator = (*walIterator)(nil)
+
+func (i *walIterator) Next(ctx context.Context) (*Trace, error) {
+	var lowest []int
+	for j, iter := range i.iters {
+		if i.peeks[j] == nil && !i.closed[j] {
+			tr, err := iter.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if tr == nil {
+				iter.Close()
+				i.closed[j] = true
+				continue
+			}
+			i.peeks[j] = tr
+		}
+		if i.peeks[j] == nil {
+			continue
+		}
+
+		if len(lowest) == 0 {
+			lowest = append(lowest, j)
+			continue
+		}
+		switch bytes.Compare(i.peeks[j].TraceID, i.peeks[lowest[0]].TraceID) {
+		case 0:
+			lowest = append(lowest, j)
+		case -1:
+			lowest = append(lowest[:0], j)
+		}
+	}
+
+	if len(lowest) == 0 {
+		return nil, nil
+	}
+
+	traces := make([]*Trace, 0, len(lowest))
+	for _, j := range lowest {
+		traces = append(traces, i.peeks[j])
+		i.peeks[j] = nil
+	}
+	return combineWALTraceParts(traces), nil
+}
+
+func (i *walIterator) Close() {
+	for j, iter := range i.iters {
+		if !i.closed[j] {
+			iter.Close()
+			i.closed[j] = true
+		}
+	}
+}
+
+// walObjectIterator returns the traces of a wal or backend block as objects
+type walObjectIterator struct {
+	iter Iterator
+	dec  model.SegmentDecoder
+}
+
+var _ common.Iterator = (*walObjectIterator)(nil)
+
+func (i *walObjectIterator) Next(ctx context.Context) (common.ID, []byte, error) {
+	tr, err := i.iter.Next(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tr == nil {
+		return nil, nil, io.EOF
+	}
+
+	obj, err := marshalWALTrace(i.dec, tr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tr.TraceID, obj, nil
+}
+
+func (i *walObjectIterator) Close() {
+	i.iter.Close()
+}
+
+func combineWALTraceParts(traces []*Trace) *Trace {
+	tr := traces[0]
+	for _, part := range traces[1:] {
+		tr = combineWALTraces(tr, part)
+	}
+	return tr
+}
+
+// marshalWALTrace converts a parquet trace to an object in the data encoding of the decoder
+func marshalWALTrace(dec model.SegmentDecoder, tr *Trace) ([]byte, error) {
+	segment, err := dec.PrepareForWrite(parquetTraceToTempopbTrace(tr), uint32(tr.StartTimeUnixNano/1e9), uint32(tr.EndTimeUnixNano/1e9))
+	if err != nil {
+		return nil, err
+	}
+	return dec.ToObject([][]byte{segment})
+}
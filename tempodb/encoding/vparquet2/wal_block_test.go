@@ -0,0 +1,158 @@
+package vparquet2
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/model"
+	v2 "github.com/grafana/tempo/pkg/model/v2"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1_trace "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestWALBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "block")
+	b, err := CreateWALBlock(path, v2.Encoding, false)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(v2.Encoding)
+	objDec := model.MustNewObjectDecoder(v2.Encoding)
+
+	// every trace is appended in two parts with a flush in between for some of them
+	traces := map[string]*tempopb.Trace{}
+	var ids []common.ID
+	for i := 0; i < 50; i++ {
+		id := test.ValidTraceID(nil)
+		ids = append(ids, id)
+
+		first := test.MakeTrace(2, id)
+		second := test.MakeTrace(1, id)
+		traces[string(id)] = &tempopb.Trace{Batches: append(append([]*v1_trace.ResourceSpans{}, first.Batches...), second.Batches...)}
+
+		require.NoError(t, b.Append(id, makeWALObject(t, dec, first)))
+		if i%2 == 0 {
+			require.NoError(t, b.Flush())
+		}
+		require.NoError(t, b.Append(id, makeWALObject(t, dec, second)))
+	}
+	require.Greater(t, b.DataLength(), uint64(0))
+
+	// find
+	for _, id := range ids {
+		obj, err := b.Find(context.Background(), id)
+		require.NoError(t, err)
+		requireWALTrace(t, objDec, id, traces[string(id)], obj)
+	}
+	obj, err := b.Find(context.Background(), test.ValidTraceID(nil))
+	require.NoError(t, err)
+	require.Nil(t, obj)
+
+	// objects are iterated in order of their ids
+	sort.Slice(ids, func(i, j int) bool {
+		return bytes.Compare(ids[i], ids[j]) == -1
+	})
+	iter, err := b.ObjectIterator(context.Background())
+	require.NoError(t, err)
+	for _, expectedID := range ids {
+		id, obj, err := iter.Next(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, expectedID, id)
+		requireWALTrace(t, objDec, id, traces[string(id)], obj)
+	}
+	_, _, err = iter.Next(context.Background())
+	require.Equal(t, io.EOF, err)
+	iter.Close()
+
+	// search
+	resp, err := b.Search(context.Background(), &tempopb.SearchRequest{
+		Tags: map[string]string{LabelServiceName: "test-service"},
+	}, common.SearchOptions{})
+	require.NoError(t, err)
+	found := map[string]struct{}{}
+	for _, tr := range resp.Traces {
+		found[tr.TraceID] = struct{}{}
+	}
+	require.Len(t, found, len(ids))
+
+	require.NoError(t, b.Clear())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestOpenWALBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "block")
+	b, err := CreateWALBlock(path, v2.Encoding, true)
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(v2.Encoding)
+	objDec := model.MustNewObjectDecoder(v2.Encoding)
+
+	traces := map[string]*tempopb.Trace{}
+	for i := 0; i < 10; i++ {
+		id := test.ValidTraceID(nil)
+		tr := test.MakeTrace(2, id)
+		traces[string(id)] = tr
+
+		require.NoError(t, b.Append(id, makeWALObject(t, dec, tr)))
+		require.NoError(t, b.Flush())
+	}
+
+	// a partially flushed file is removed on replay
+	partial := filepath.Join(path, "9999999999")
+	require.NoError(t, os.WriteFile(partial, []byte{0x01, 0x02, 0x03}, 0644))
+
+	replayed := 0
+	b, warning, err := OpenWALBlock(path, v2.Encoding, true, func(id common.ID, start, end uint32) {
+		require.Contains(t, traces, string(id))
+		require.NotZero(t, start)
+		require.GreaterOrEqual(t, end, start)
+		replayed++
+	})
+	require.NoError(t, err)
+	require.Error(t, warning)
+	require.Equal(t, len(traces), replayed)
+	require.Equal(t, len(traces), b.Length())
+	_, err = os.Stat(partial)
+	require.True(t, os.IsNotExist(err))
+
+	for id, tr := range traces {
+		obj, err := b.Find(context.Background(), []byte(id))
+		require.NoError(t, err)
+		requireWALTrace(t, objDec, []byte(id), tr, obj)
+	}
+
+	// the replayed block can be appended to
+	id := test.ValidTraceID(nil)
+	require.NoError(t, b.Append(id, makeWALObject(t, dec, test.MakeTrace(1, id))))
+	require.NoError(t, b.Flush())
+	require.Equal(t, len(traces)+1, b.Length())
+}
+
+func makeWALObject(t *testing.T, dec model.SegmentDecoder, tr *tempopb.Trace) []byte {
+	segment, err := dec.PrepareForWrite(tr, 0, 0)
+	require.NoError(t, err)
+	obj, err := dec.ToObject([][]byte{segment})
+	require.NoError(t, err)
+	return obj
+}
+
+// requireWALTrace compares the traces in the parquet schema, the proto trace isn't preserved exactly
+func requireWALTrace(t *testing.T, dec model.ObjectDecoder, id common.ID, expected *tempopb.Trace, obj []byte) {
+	actual, err := dec.PrepareForRead(obj)
+	require.NoError(t, err)
+
+	expectedTrace := traceToParquet(id, expected)
+	actualTrace := traceToParquet(id, actual)
+	SortTrace(&expectedTrace)
+	SortTrace(&actualTrace)
+	require.Equal(t, expectedTrace, actualTrace)
+}
@@ -0,0 +1,166 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/parquet-go"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// ZoneMapsFileName are the zone maps of blocks written with BlockConfig.ParquetZoneMaps
+const ZoneMapsFileName = "zone_maps.json"
+
+// Columns with zone maps
+const (
+	columnDurationNanos  = "DurationNanos"
+	columnStatusCode     = "rs.ils.Spans.StatusCode"
+	columnHTTPStatusCode = "rs.ils.Spans.HttpStatusCode"
+)
+
+// zoneMapColumns are the numeric columns with zone maps
+var zoneMapColumns = []string{columnDurationNanos, columnStatusCode, columnHTTPStatusCode}
+
+// zoneMaps are the min and max values of numeric columns in the ranges of rows of a block. The ranges are the row
+// groups written by the block, so searches with range predicates on these columns skip the row groups without
+// matching values without reading their pages.
+type zoneMaps struct {
+	Zones []zone `json:"zones"`
+}
+
+// zone are the min and max values of the columns in the rows from Start to End, End excluded. Columns without
+// values in the rows are missing.
+type zone struct {
+	Start   int64             `json:"start"`
+	End     int64             `json:"end"`
+	Columns map[string]minMax `json:"columns"`
+}
+
+type minMax struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// zoneMapsWriter builds the zone maps of a block while its rows are added
+type zoneMapsWriter struct {
+	maps    zoneMaps
+	rows    int64
+	current zone
+	// columns are the indexes of the zone map columns in the rows added to the block
+	columns map[int]string
+}
+
+func newZoneMapsWriter(sch *parquet.Schema) *zoneMapsWriter {
+	w := &zoneMapsWriter{
+		current: zone{Columns: map[string]minMax{}},
+		columns: map[int]string{},
+	}
+	for _, name := range zoneMapColumns {
+		if col, found := sch.Lookup(strings.Split(name, ".")...); found {
+			w.columns[col.ColumnIndex] = name
+		}
+	}
+	return w
+}
+
+func (w *zoneMapsWriter) addValue(column string, v int64) {
+	mm, ok := w.current.Columns[column]
+	if !ok {
+		w.current.Columns[column] = minMax{Min: v, Max: v}
+		return
+	}
+	if v < mm.Min {
+		mm.Min = v
+	}
+	if v > mm.Max {
+		mm.Max = v
+	}
+	w.current.Columns[column] = mm
+}
+
+// addTrace adds the values of the next row
+func (w *zoneMapsWriter) addTrace(tr *Trace) {
+	w.addValue(columnDurationNanos, int64(tr.DurationNanos))
+	for _, rs := range tr.ResourceSpans {
+		for _, ils := range rs.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				w.addValue(columnStatusCode, int64(s.StatusCode))
+				if s.HttpStatusCode != nil {
+					w.addValue(columnHTTPStatusCode, *s.HttpStatusCode)
+				}
+			}
+		}
+	}
+	w.rows++
+}
+
+// addRow adds the values of the next row
+func (w *zoneMapsWriter) addRow(row parquet.Row) {
+	for _, v := range row {
+		if name, ok := w.columns[v.Column()]; ok && !v.IsNull() {
+			w.addValue(name, v.Int64())
+		}
+	}
+	w.rows++
+}
+
+// flushRowGroup completes the zone of the rows added since the last row group
+func (w *zoneMapsWriter) flushRowGroup() {
+	if w.rows == w.current.Start {
+		return
+	}
+
+	w.current.End = w.rows
+	w.maps.Zones = append(w.maps.Zones, w.current)
+	w.current = zone{Start: w.rows, Columns: map[string]minMax{}}
+}
+
+func (w *zoneMapsWriter) marshal() ([]byte, error) {
+	w.flushRowGroup()
+	return json.Marshal(w.maps)
+}
+
+func readZoneMaps(ctx context.Context, meta *backend.BlockMeta, r backend.Reader) (*zoneMaps, error) {
+	b, err := r.Read(ctx, ZoneMapsFileName, meta.BlockID, meta.TenantID, true)
+	if err != nil {
+		return nil, fmt.Errorf("error reading zone maps: %w", err)
+	}
+
+	z := &zoneMaps{}
+	if err := json.Unmarshal(b, z); err != nil {
+		return nil, fmt.Errorf("error unmarshalling zone maps: %w", err)
+	}
+	return z, nil
+}
+
+// rowGroups returns for every row group whether one of its zones may have values within the ranges of all columns
+func (z *zoneMaps) rowGroups(ranges map[string]minMax, rgs []parquet.RowGroup) []bool {
+	keep := make([]bool, len(rgs))
+
+	start := int64(0)
+	for i, rg := range rgs {
+		end := start + rg.NumRows()
+		for _, zn := range z.Zones {
+			if zn.Start < end && start < zn.End && zn.matches(ranges) {
+				keep[i] = true
+				break
+			}
+		}
+		start = end
+	}
+
+	return keep
+}
+
+func (zn *zone) matches(ranges map[string]minMax) bool {
+	for column, r := range ranges {
+		mm, ok := zn.Columns[column]
+		if !ok || r.Max < mm.Min || r.Min > mm.Max {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package vparquet2
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	tempo_io "github.com/grafana/tempo/pkg/io"
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+func TestZoneMaps(t *testing.T) {
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+	ctx := context.Background()
+
+	cfg := &common.BlockConfig{
+		BloomFP:             0.01,
+		BloomShardSizeBytes: 100 * 1024,
+		RowGroupSizeBytes:   20_000_000,
+		ParquetZoneMaps:     true,
+	}
+
+	meta := backend.NewBlockMeta(tenantID, uuid.New(), VersionString, backend.EncNone, "")
+	s, err := newStreamingBlock(ctx, cfg, meta, r, w, tempo_io.NewBufferedWriter)
+	require.NoError(t, err)
+
+	// 4 row groups of 25 traces. the traces of row group n last n+1 seconds and the spans of the first 3 row groups
+	// have the http status codes 200, 404 and 500.
+	start := uint64(time.Now().UnixNano())
+	httpStatusCodes := []int64{200, 404, 500}
+	for i := 0; i < 100; i++ {
+		id := make([]byte, 16)
+		binary.BigEndian.PutUint64(id[8:], uint64(i))
+		rg := i / 25
+
+		tr := test.MakeTrace(2, id)
+		for _, batch := range tr.Batches {
+			for _, ils := range batch.InstrumentationLibrarySpans {
+				for _, s := range ils.Spans {
+					s.StartTimeUnixNano = start
+					s.EndTimeUnixNano = start + uint64(rg+1)*uint64(time.Second)
+					if rg < len(httpStatusCodes) {
+						s.Attributes = []*v1_common.KeyValue{
+							{Key: LabelHTTPStatusCode, Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_IntValue{IntValue: httpStatusCodes[rg]}}},
+						}
+					}
+				}
+			}
+		}
+
+		pqTr := traceToParquet(id, tr)
+		s.Add(&pqTr, 0, 0)
+		if i%25 == 24 {
+			_, err := s.Flush()
+			require.NoError(t, err)
+		}
+	}
+	_, err = s.Complete()
+	require.NoError(t, err)
+	require.True(t, s.meta.ZoneMaps)
+
+	z, err := readZoneMaps(ctx, s.meta, r)
+	require.NoError(t, err)
+	require.Len(t, z.Zones, 4)
+	require.Equal(t, zone{
+		Start: 25,
+		End:   50,
+		Columns: map[string]minMax{
+			columnDurationNanos:  {Min: int64(2 * time.Second), Max: int64(2 * time.Second)},
+			columnStatusCode:     {Min: 1, Max: 1},
+			columnHTTPStatusCode: {Min: 404, Max: 404},
+		},
+	}, z.Zones[1])
+	require.NotContains(t, z.Zones[3].Columns, columnHTTPStatusCode)
+
+	// searches aren't buffered, so the inspected bytes are the bytes of the row groups read
+	search := func(meta *backend.BlockMeta, req *tempopb.SearchRequest) *tempopb.SearchResponse {
+		res, err := newBackendBlock(meta, r).Search(ctx, req, common.SearchOptions{})
+		require.NoError(t, err)
+		return res
+	}
+
+	all := search(s.meta, &tempopb.SearchRequest{})
+	require.Len(t, all.Traces, 100)
+
+	res := search(s.meta, &tempopb.SearchRequest{MinDurationMs: 3500})
+	require.Len(t, res.Traces, 25)
+	require.Less(t, res.Metrics.InspectedBytes, all.Metrics.InspectedBytes)
+
+	res = search(s.meta, &tempopb.SearchRequest{MinDurationMs: 1500, MaxDurationMs: 2500})
+	require.Len(t, res.Traces, 25)
+
+	res = search(s.meta, &tempopb.SearchRequest{Tags: map[string]string{LabelHTTPStatusCode: "404"}})
+	require.Len(t, res.Traces, 25)
+
+	// searches without values in the ranges read nothing
+	res = search(s.meta, &tempopb.SearchRequest{Tags: map[string]string{LabelHTTPStatusCode: "503"}})
+	require.Len(t, res.Traces, 0)
+	require.Zero(t, res.Metrics.InspectedBytes)
+
+	res = search(s.meta, &tempopb.SearchRequest{Tags: map[string]string{LabelStatusCode: StatusCodeUnset}})
+	require.Len(t, res.Traces, 0)
+	require.Zero(t, res.Metrics.InspectedBytes)
+
+	// compacted blocks have zone maps
+	c := NewCompactor(common.CompactionOptions{
+		BlockConfig:     *cfg,
+		OutputBlocks:    1,
+		FlushSizeBytes:  30_000_000,
+		ObjectsCombined: func(compactionLevel, objects int) {},
+	})
+	metas, err := c.Compact(ctx, log.NewNopLogger(), r, func(*backend.BlockMeta, time.Time) backend.Writer { return w }, []*backend.BlockMeta{s.meta})
+	require.NoError(t, err)
+	require.Len(t, metas, 1)
+	require.True(t, metas[0].ZoneMaps)
+
+	z, err = readZoneMaps(ctx, metas[0], r)
+	require.NoError(t, err)
+	require.Equal(t, minMax{Min: 200, Max: 500}, z.Zones[0].Columns[columnHTTPStatusCode])
+
+	res = search(metas[0], &tempopb.SearchRequest{MinDurationMs: 3500})
+	require.Len(t, res.Traces, 25)
+}
@@ -0,0 +1,194 @@
+package tempodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var (
+	metricRebloomDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempodb",
+		Name:      "rebloom_duration_seconds",
+		Help:      "Records the amount of time to regenerate the bloom filters of a tenant.",
+		Buckets:   prometheus.ExponentialBuckets(.25, 2, 10),
+	})
+	metricRebloomBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "rebloom_blocks_total",
+		Help:      "Total number of blocks whose bloom filters were regenerated.",
+	})
+	metricRebloomErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "rebloom_errors_total",
+		Help:      "Total number of times an error occurred while regenerating bloom filters.",
+	})
+)
+
+// todo: pass a context/chan in to cancel this cleanly
+// once a rebloom cycle regenerate the bloom filters of any blocks built with a different false positive rate
+func (rw *readerWriter) rebloomLoop() {
+	ticker := time.NewTicker(rw.compactorCfg.RebloomCycle)
+	for range ticker.C {
+		rw.doRebloom()
+	}
+}
+
+func (rw *readerWriter) doRebloom() {
+	// tenants are processed one at a time. regenerating a bloom requires reading the trace ids of the entire
+	// block and this job should stay in the background
+	for _, tenantID := range rw.blocklist.Tenants() {
+		rw.rebloomTenant(context.Background(), tenantID)
+	}
+}
+
+func (rw *readerWriter) rebloomTenant(ctx context.Context, tenantID string) {
+	start := time.Now()
+	defer func() { metricRebloomDuration.Observe(time.Since(start).Seconds()) }()
+
+	fp := rw.bloomFPForTenant(tenantID)
+	level.Debug(rw.logger).Log("msg", "performing rebloom", "tenantID", tenantID, "bloomFP", fp)
+
+	for _, b := range rw.blocklist.Metas(tenantID) {
+		if b.BloomFP == fp || !rw.compactorSharder.Owns(b.BlockID.String()) {
+			continue
+		}
+
+		// the polled blocklist is only updated on the next poll. check the current meta to avoid rebuilding the
+		// same bloom twice
+		meta, err := rw.r.BlockMeta(ctx, b.BlockID, tenantID)
+		if err != nil {
+			level.Error(rw.logger).Log("msg", "failed to read block meta for rebloom", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricRebloomErrors.Inc()
+			continue
+		}
+		if meta.BloomFP == fp {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "regenerating bloom filter", "blockID", b.BlockID, "tenantID", tenantID, "oldBloomFP", meta.BloomFP, "bloomFP", fp)
+		err = rw.rebloomBlock(ctx, meta, fp)
+		if errors.Is(err, errRebloomedBlockCompacted) {
+			level.Info(rw.logger).Log("msg", "block was compacted while its bloom filter was regenerated", "blockID", b.BlockID, "tenantID", tenantID)
+			continue
+		}
+		if err != nil {
+			level.Error(rw.logger).Log("msg", "failed to regenerate bloom filter", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricRebloomErrors.Inc()
+			continue
+		}
+		metricRebloomBlocks.Inc()
+	}
+}
+
+// rebloomBlock rebuilds the bloom filter of the given block at the given false positive rate. The shard count
+// of the block is kept so that readers with a stale meta still look up the correct shard, only the size of the
+// shards changes. The meta is never modified, a copy with the new rate is written if the block was not compacted
+// in the meantime.
+func (rw *readerWriter) rebloomBlock(ctx context.Context, meta *backend.BlockMeta, fp float64) error {
+	block, err := encoding.OpenBlock(meta, rw.uncachedReader)
+	if err != nil {
+		return fmt.Errorf("error opening block: %w", err)
+	}
+
+	iterable, ok := block.(common.IDIterable)
+	if !ok {
+		return fmt.Errorf("block version %s: %w", meta.Version, common.ErrUnsupported)
+	}
+
+	shardCount := common.ValidateShardCount(int(meta.BloomShardCount))
+	bloom := common.NewBloomWithShardCount(fp, uint(shardCount), uint(meta.TotalObjects))
+
+	err = iterable.IterateIDs(ctx, func(id common.ID) error {
+		bloom.Add(id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	blooms, err := bloom.Marshal()
+	if err != nil {
+		return err
+	}
+
+	w := rw.getWriterForBlock(meta, time.Now())
+	for i, bloom := range blooms {
+		err = w.Write(ctx, common.BloomName(i), meta.BlockID, meta.TenantID, bloom, true)
+		if err != nil {
+			return fmt.Errorf("unexpected error writing bloom-%d %w", i, err)
+		}
+	}
+
+	// compaction renames meta.json while the bloom is rebuilt. reread the meta right before writing it, writing it
+	// after the rename would revive the compacted block
+	current, err := rw.r.BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	if errors.Is(err, backend.ErrDoesNotExist) {
+		return errRebloomedBlockCompacted
+	}
+	if err != nil {
+		return fmt.Errorf("error rereading block meta: %w", err)
+	}
+	compacted, err := rw.compactedBlockExists(current)
+	if err != nil {
+		return err
+	}
+	if compacted {
+		return errRebloomedBlockCompacted
+	}
+
+	newMeta := *current
+	newMeta.BloomFP = fp
+	err = w.WriteBlockMeta(ctx, &newMeta)
+	if err != nil {
+		return err
+	}
+
+	// the block can still be compacted between the check and the write. mark it compacted again so the written
+	// meta doesn't revive it
+	compacted, err = rw.compactedBlockExists(current)
+	if err != nil {
+		return err
+	}
+	if compacted {
+		if err := rw.c.MarkBlockCompacted(current.BlockID, current.TenantID); err != nil {
+			return fmt.Errorf("error marking block compacted again: %w", err)
+		}
+		return errRebloomedBlockCompacted
+	}
+	return nil
+}
+
+// errRebloomedBlockCompacted is returned when a block was compacted while its bloom filter was regenerated
+var errRebloomedBlockCompacted = errors.New("block was compacted while its bloom filter was regenerated")
+
+func (rw *readerWriter) compactedBlockExists(meta *backend.BlockMeta) (bool, error) {
+	_, err := rw.c.CompactedBlockMeta(meta.BlockID, meta.TenantID)
+	if errors.Is(err, backend.ErrDoesNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading compacted block meta: %w", err)
+	}
+	return true, nil
+}
+
+// bloomFPForTenant is the false positive rate of the bloom filters written for the tenant's blocks, the override
+// of the tenant or the configured rate
+func (rw *readerWriter) bloomFPForTenant(tenantID string) float64 {
+	if rw.compactorOverrides != nil {
+		if o := rw.compactorOverrides.BloomFPForTenant(tenantID); o > 0 {
+			return o
+		}
+	}
+	return rw.cfg.Block.BloomFP
+}
@@ -0,0 +1,188 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	willf_bloom "github.com/willf/bloom"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestRebloom(t *testing.T) {
+	for _, enc := range []string{v2.VersionString, vparquet.VersionString} {
+		t.Run(enc, func(t *testing.T) {
+			testRebloom(t, enc)
+		})
+	}
+}
+
+func testRebloom(t *testing.T, targetBlockVersion string) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              0.05,
+			BloomShardSizeBytes:  100,
+			Version:              targetBlockVersion,
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	overrides := &mockOverrides{}
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: 0,
+	}, &mockSharder{}, overrides)
+
+	r.EnablePolling(&mockJobSharder{})
+
+	data := make([]testData, 0, 100)
+	for i := 0; i < 100; i++ {
+		id := test.ValidTraceID(nil)
+		data = append(data, testData{id: id, t: test.MakeTrace(1, id)})
+	}
+	block := cutTestBlockWithTraces(t, w, testTenantID, data)
+	meta := block.BlockMeta()
+	assert.Equal(t, 0.05, meta.BloomFP)
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+
+	// rebloom is a noop if the fp rate matches
+	rw.doRebloom()
+	bloomBytes := readBlooms(t, rw, meta)
+
+	// lower the fp rate for the tenant
+	overrides.bloomFP = 0.001
+	rw.doRebloom()
+
+	// the new meta is picked up on the next poll
+	rw.pollBlocklist()
+	metas := rw.blocklist.Metas(testTenantID)
+	require.Len(t, metas, 1)
+	assert.Equal(t, 0.001, metas[0].BloomFP)
+	assert.Equal(t, meta.BloomShardCount, metas[0].BloomShardCount)
+
+	// bloom shards are larger and contain every trace
+	newBloomBytes := readBlooms(t, rw, meta)
+	require.Len(t, newBloomBytes, len(bloomBytes))
+	for i := range bloomBytes {
+		assert.Greater(t, len(newBloomBytes[i]), len(bloomBytes[i]))
+	}
+
+	shards := make([]*willf_bloom.BloomFilter, len(newBloomBytes))
+	for i, b := range newBloomBytes {
+		shards[i] = &willf_bloom.BloomFilter{}
+		_, err = shards[i].ReadFrom(bytes.NewReader(b))
+		require.NoError(t, err)
+	}
+	for _, d := range data {
+		assert.True(t, shards[common.ShardKeyForTraceID(d.id, len(shards))].Test(d.id))
+
+		trs, failedBlocks, err := r.Find(context.Background(), testTenantID, d.id, BlockIDMin, BlockIDMax, 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, failedBlocks)
+		require.Len(t, trs, 1)
+	}
+
+	// compaction writes the blocks with the rate of the tenant
+	compacted := cutTestBlockWithTraces(t, w, testTenantID, []testData{{id: test.ValidTraceID(nil), t: test.MakeTrace(1, nil)}})
+	require.Equal(t, 0.05, compacted.BlockMeta().BloomFP)
+	rw.pollBlocklist()
+	require.NoError(t, rw.compact(rw.blocklist.Metas(testTenantID), testTenantID))
+	rw.pollBlocklist()
+	metas = rw.blocklist.Metas(testTenantID)
+	require.Len(t, metas, 1)
+	assert.Equal(t, 0.001, metas[0].BloomFP)
+
+	// the meta of a block compacted during the rebloom isn't written again and the passed meta is unchanged
+	stale := metas[0]
+	require.NoError(t, c.(*readerWriter).c.MarkBlockCompacted(stale.BlockID, testTenantID))
+	err = rw.rebloomBlock(context.Background(), stale, 0.01)
+	require.ErrorIs(t, err, errRebloomedBlockCompacted)
+	assert.Equal(t, 0.001, stale.BloomFP)
+	_, err = rw.r.BlockMeta(context.Background(), stale.BlockID, testTenantID)
+	require.ErrorIs(t, err, backend.ErrDoesNotExist)
+}
+
+func TestCompleteBlockWithOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+
+	_, w, _, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              0.05,
+			BloomShardSizeBytes:  100,
+			Version:              v2.VersionString,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(model.CurrentEncoding)
+	for _, bloomFP := range []float64{0, 0.001} {
+		head, err := w.WAL().NewBlock(uuid.New(), testTenantID, model.CurrentEncoding)
+		require.NoError(t, err)
+		id := test.ValidTraceID(nil)
+		writeTraceToWal(t, head, dec, id, test.MakeTrace(1, id), 0, 0)
+
+		rw := w.(*readerWriter)
+		b, err := w.CompleteBlockWithOverrides(context.Background(), head, &mockCombiner{}, "", bloomFP, rw.r, rw.w)
+		require.NoError(t, err)
+
+		expected := bloomFP
+		if expected == 0 {
+			expected = 0.05
+		}
+		assert.Equal(t, expected, b.BlockMeta().BloomFP)
+	}
+	// the config is unchanged
+	assert.Equal(t, 0.05, w.(*readerWriter).cfg.Block.BloomFP)
+}
+
+func readBlooms(t *testing.T, rw *readerWriter, meta *backend.BlockMeta) [][]byte {
+	blooms := make([][]byte, 0, meta.BloomShardCount)
+	for i := 0; i < int(meta.BloomShardCount); i++ {
+		b, err := rw.r.Read(context.Background(), common.BloomName(i), meta.BlockID, meta.TenantID, false)
+		require.NoError(t, err)
+		blooms = append(blooms, b)
+	}
+	return blooms
+}
@@ -0,0 +1,226 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+)
+
+// Conflict is a block that is different in the destination than in the source. Conflicting blocks are never
+// overwritten, the destination keeps its version.
+type Conflict struct {
+	BlockID uuid.UUID
+	Reason  string
+}
+
+// Result of replicating a tenant.
+type Result struct {
+	// Copied is the number of blocks copied to the destination
+	Copied int
+	// Compacted is the number of blocks marked compacted in the destination
+	Compacted int
+	Conflicts []Conflict
+}
+
+// Replicator copies the blocks in the tenant indexes of a source backend to a destination backend and writes
+// the tenant indexes of the destination. Blocks compacted in the source are marked compacted in the destination.
+// The destination is queryable as soon as its tenant indexes are written, without waiting for its own
+// blocklist poll.
+type Replicator struct {
+	src     backend.Reader
+	dst     backend.Reader
+	dstW    backend.Writer
+	dstC    backend.Compactor
+	logger  log.Logger
+	tenants []string
+}
+
+// New creates a replicator from src to dst. If tenants is empty all tenants of the source are replicated.
+func New(src backend.Reader, dst backend.Reader, dstW backend.Writer, dstC backend.Compactor, tenants []string, logger log.Logger) *Replicator {
+	return &Replicator{
+		src:     src,
+		dst:     dst,
+		dstW:    dstW,
+		dstC:    dstC,
+		logger:  logger,
+		tenants: tenants,
+	}
+}
+
+// Run replicates once every interval until the context is cancelled.
+func (r *Replicator) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.ReplicateAll(ctx); err != nil {
+			level.Error(r.logger).Log("msg", "failed to replicate", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReplicateAll replicates every tenant. A failing tenant doesn't stop the others, the last error is returned.
+func (r *Replicator) ReplicateAll(ctx context.Context) error {
+	tenants := r.tenants
+	if len(tenants) == 0 {
+		var err error
+		tenants, err = r.src.Tenants(ctx)
+		if err != nil {
+			return fmt.Errorf("error listing source tenants: %w", err)
+		}
+	}
+
+	var lastErr error
+	for _, tenantID := range tenants {
+		start := time.Now()
+		res, err := r.ReplicateTenant(ctx, tenantID)
+		if err != nil {
+			level.Error(r.logger).Log("msg", "failed to replicate tenant", "tenantID", tenantID, "err", err)
+			lastErr = err
+			continue
+		}
+
+		for _, c := range res.Conflicts {
+			level.Warn(r.logger).Log("msg", "replication conflict", "tenantID", tenantID, "blockID", c.BlockID, "reason", c.Reason)
+		}
+		level.Info(r.logger).Log("msg", "replicated tenant", "tenantID", tenantID, "copied", res.Copied,
+			"compacted", res.Compacted, "conflicts", len(res.Conflicts), "duration", time.Since(start))
+	}
+
+	return lastErr
+}
+
+// ReplicateTenant copies the blocks of the source tenant index missing in the destination, marks the blocks
+// compacted in the source compacted in the destination and writes the destination tenant index.
+func (r *Replicator) ReplicateTenant(ctx context.Context, tenantID string) (*Result, error) {
+	srcIndex, err := r.src.TenantIndex(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading source tenant index: %w", err)
+	}
+
+	dstLive := map[uuid.UUID]*backend.BlockMeta{}
+	dstCompacted := map[uuid.UUID]*backend.CompactedBlockMeta{}
+	dstIndex, err := r.dst.TenantIndex(ctx, tenantID)
+	switch {
+	case errors.Is(err, backend.ErrDoesNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("error reading destination tenant index: %w", err)
+	default:
+		for _, m := range dstIndex.Meta {
+			dstLive[m.BlockID] = m
+		}
+		for _, m := range dstIndex.CompactedMeta {
+			dstCompacted[m.BlockID] = m
+		}
+	}
+
+	res := &Result{}
+	conflict := func(id uuid.UUID, reason string) {
+		res.Conflicts = append(res.Conflicts, Conflict{BlockID: id, Reason: reason})
+	}
+
+	metas := make([]*backend.BlockMeta, 0, len(srcIndex.Meta))
+	compacted := make([]*backend.CompactedBlockMeta, 0, len(srcIndex.CompactedMeta))
+	srcBlocks := map[uuid.UUID]struct{}{}
+
+	for _, m := range srcIndex.Meta {
+		srcBlocks[m.BlockID] = struct{}{}
+
+		if c, ok := dstCompacted[m.BlockID]; ok {
+			conflict(m.BlockID, "compacted in destination")
+			compacted = append(compacted, c)
+			continue
+		}
+
+		d, ok := dstLive[m.BlockID]
+		if !ok {
+			// the block may have been copied after the destination tenant index was written
+			d, err = r.dst.BlockMeta(ctx, m.BlockID, tenantID)
+			switch {
+			case errors.Is(err, backend.ErrDoesNotExist):
+				// the meta is written last, a partially copied block is copied again
+				if err := encoding.CopyBlock(ctx, m, r.src, r.dstW); err != nil {
+					return nil, fmt.Errorf("error copying block %s: %w", m.BlockID, err)
+				}
+				res.Copied++
+				metas = append(metas, m)
+				continue
+			case err != nil:
+				return nil, fmt.Errorf("error reading destination block meta %s: %w", m.BlockID, err)
+			}
+		}
+
+		if !sameBlock(m, d) {
+			conflict(m.BlockID, "block meta differs")
+		}
+		metas = append(metas, d)
+	}
+
+	for _, c := range srcIndex.CompactedMeta {
+		srcBlocks[c.BlockID] = struct{}{}
+
+		if d, ok := dstCompacted[c.BlockID]; ok {
+			compacted = append(compacted, d)
+			continue
+		}
+
+		// blocks compacted before they were replicated are not copied
+		if _, ok := dstLive[c.BlockID]; !ok {
+			continue
+		}
+
+		if err := r.dstC.MarkBlockCompacted(c.BlockID, tenantID); err != nil {
+			return nil, fmt.Errorf("error marking block %s compacted: %w", c.BlockID, err)
+		}
+		res.Compacted++
+		compacted = append(compacted, c)
+	}
+
+	// blocks only in the destination were not written by replication, keep them
+	for id, d := range dstLive {
+		if _, ok := srcBlocks[id]; !ok {
+			conflict(id, "only in destination")
+			metas = append(metas, d)
+		}
+	}
+	for id, d := range dstCompacted {
+		if _, ok := srcBlocks[id]; !ok {
+			compacted = append(compacted, d)
+		}
+	}
+
+	if err := r.dstW.WriteTenantIndex(ctx, tenantID, metas, compacted); err != nil {
+		return nil, fmt.Errorf("error writing destination tenant index: %w", err)
+	}
+
+	return res, nil
+}
+
+// sameBlock returns true if both metas describe the same block contents.
+func sameBlock(a, b *backend.BlockMeta) bool {
+	return a.Version == b.Version &&
+		a.Encoding == b.Encoding &&
+		a.DataEncoding == b.DataEncoding &&
+		a.Size == b.Size &&
+		a.TotalObjects == b.TotalObjects &&
+		a.TotalRecords == b.TotalRecords &&
+		a.BloomShardCount == b.BloomShardCount &&
+		a.StartTime.Equal(b.StartTime) &&
+		a.EndTime.Equal(b.EndTime) &&
+		string(a.MinID) == string(b.MinID) &&
+		string(a.MaxID) == string(b.MaxID)
+}
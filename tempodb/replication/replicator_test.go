@@ -0,0 +1,162 @@
+package replication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+)
+
+const testTenantID = "fake"
+
+type testBackend struct {
+	r backend.Reader
+	w backend.Writer
+	c backend.Compactor
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	r, w, c, err := local.New(&local.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+	return &testBackend{r: backend.NewReader(r), w: backend.NewWriter(w), c: c}
+}
+
+func (b *testBackend) writeBlock(t *testing.T, totalObjects int) *backend.BlockMeta {
+	ctx := context.Background()
+	meta := backend.NewBlockMeta(testTenantID, uuid.New(), v2.VersionString, backend.EncNone, "")
+	meta.TotalObjects = totalObjects
+	meta.BloomShardCount = 1
+
+	require.NoError(t, b.w.Write(ctx, common.NameObjects, meta.BlockID, testTenantID, []byte("data"), false))
+	require.NoError(t, b.w.Write(ctx, common.NameIndex, meta.BlockID, testTenantID, []byte("index"), false))
+	require.NoError(t, b.w.Write(ctx, common.BloomName(0), meta.BlockID, testTenantID, []byte("bloom"), false))
+	require.NoError(t, b.w.WriteBlockMeta(ctx, meta))
+	return meta
+}
+
+func (b *testBackend) tenantIndex(t *testing.T) *backend.TenantIndex {
+	i, err := b.r.TenantIndex(context.Background(), testTenantID)
+	require.NoError(t, err)
+	return i
+}
+
+func blockIDs(metas []*backend.BlockMeta) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(metas))
+	for _, m := range metas {
+		ids = append(ids, m.BlockID)
+	}
+	return ids
+}
+
+func compactedIDs(metas []*backend.CompactedBlockMeta) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(metas))
+	for _, m := range metas {
+		ids = append(ids, m.BlockID)
+	}
+	return ids
+}
+
+func TestReplicateTenant(t *testing.T) {
+	ctx := context.Background()
+	src := newTestBackend(t)
+	dst := newTestBackend(t)
+
+	a := src.writeBlock(t, 10)
+	b := src.writeBlock(t, 20)
+	require.NoError(t, src.w.WriteTenantIndex(ctx, testTenantID, []*backend.BlockMeta{a, b}, nil))
+
+	r := New(src.r, dst.r, dst.w, dst.c, nil, log.NewNopLogger())
+
+	res, err := r.ReplicateTenant(ctx, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Copied: 2}, res)
+
+	assert.ElementsMatch(t, []uuid.UUID{a.BlockID, b.BlockID}, blockIDs(dst.tenantIndex(t).Meta))
+	data, err := dst.r.Read(ctx, common.NameObjects, a.BlockID, testTenantID, false)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), data)
+
+	// replicating again copies nothing
+	res, err = r.ReplicateTenant(ctx, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{}, res)
+
+	// a and b are compacted to c in the source
+	c := src.writeBlock(t, 30)
+	require.NoError(t, src.c.MarkBlockCompacted(a.BlockID, testTenantID))
+	require.NoError(t, src.c.MarkBlockCompacted(b.BlockID, testTenantID))
+	compactedA, err := src.c.CompactedBlockMeta(a.BlockID, testTenantID)
+	require.NoError(t, err)
+	compactedB, err := src.c.CompactedBlockMeta(b.BlockID, testTenantID)
+	require.NoError(t, err)
+	require.NoError(t, src.w.WriteTenantIndex(ctx, testTenantID, []*backend.BlockMeta{c}, []*backend.CompactedBlockMeta{compactedA, compactedB}))
+
+	res, err = r.ReplicateTenant(ctx, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Copied: 1, Compacted: 2}, res)
+
+	index := dst.tenantIndex(t)
+	assert.Equal(t, []uuid.UUID{c.BlockID}, blockIDs(index.Meta))
+	assert.ElementsMatch(t, []uuid.UUID{a.BlockID, b.BlockID}, compactedIDs(index.CompactedMeta))
+	_, err = dst.c.CompactedBlockMeta(a.BlockID, testTenantID)
+	require.NoError(t, err)
+}
+
+func TestReplicateTenantConflicts(t *testing.T) {
+	ctx := context.Background()
+	src := newTestBackend(t)
+	dst := newTestBackend(t)
+
+	a := src.writeBlock(t, 10)
+	require.NoError(t, src.w.WriteTenantIndex(ctx, testTenantID, []*backend.BlockMeta{a}, nil))
+
+	// the destination has a different block with the same id and a block of its own
+	differentA := *a
+	differentA.TotalObjects = 11
+	require.NoError(t, dst.w.WriteBlockMeta(ctx, &differentA))
+	own := dst.writeBlock(t, 5)
+	require.NoError(t, dst.w.WriteTenantIndex(ctx, testTenantID, []*backend.BlockMeta{own}, nil))
+
+	r := New(src.r, dst.r, dst.w, dst.c, nil, log.NewNopLogger())
+	res, err := r.ReplicateTenant(ctx, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.Copied)
+	assert.ElementsMatch(t, []Conflict{
+		{BlockID: a.BlockID, Reason: "block meta differs"},
+		{BlockID: own.BlockID, Reason: "only in destination"},
+	}, res.Conflicts)
+
+	// the destination keeps its blocks
+	index := dst.tenantIndex(t)
+	require.Len(t, index.Meta, 2)
+	for _, m := range index.Meta {
+		if m.BlockID == a.BlockID {
+			assert.Equal(t, 11, m.TotalObjects)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	src := newTestBackend(t)
+	dst := newTestBackend(t)
+
+	a := src.writeBlock(t, 10)
+	require.NoError(t, src.w.WriteTenantIndex(context.Background(), testTenantID, []*backend.BlockMeta{a}, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	r := New(src.r, dst.r, dst.w, dst.c, nil, log.NewNopLogger())
+	require.NoError(t, r.Run(ctx, time.Hour))
+
+	assert.Equal(t, []uuid.UUID{a.BlockID}, blockIDs(dst.tenantIndex(t).Meta))
+}
@@ -1,12 +1,15 @@
 package tempodb
 
 import (
+	"context"
 	"time"
 
 	"github.com/go-kit/log/level"
 
 	"github.com/grafana/tempo/pkg/boundedwaitgroup"
 	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
 )
 
 // todo: pass a context/chan in to cancel this cleanly
@@ -45,11 +48,23 @@ func (rw *readerWriter) retainTenant(tenantID string) {
 	}
 	level.Debug(rw.logger).Log("msg", "Performing block retention", "tenantID", tenantID, "retention", retention)
 
+	// blocks that may contain held data are kept until the hold is lifted
+	holds, err := rw.r.LegalHolds(context.Background(), tenantID)
+	if err != nil {
+		level.Error(rw.logger).Log("msg", "failed to read legal holds during retention", "tenantID", tenantID, "err", err)
+		metricRetentionErrors.Inc()
+		return
+	}
+
 	// iterate through block list.  make compacted anything that is past retention.
 	cutoff := time.Now().Add(-retention)
 	blocklist := rw.blocklist.Metas(tenantID)
 	for _, b := range blocklist {
 		if b.EndTime.Before(cutoff) && rw.compactorSharder.Owns(b.BlockID.String()) {
+			if rw.blockHeld(tenantID, holds, b) {
+				level.Debug(rw.logger).Log("msg", "block past retention is under legal hold", "blockID", b.BlockID, "tenantID", tenantID)
+				continue
+			}
 			level.Info(rw.logger).Log("msg", "marking block for deletion", "blockID", b.BlockID, "tenantID", tenantID)
 			err := rw.c.MarkBlockCompacted(b.BlockID, tenantID)
 			if err != nil {
@@ -73,6 +88,10 @@ func (rw *readerWriter) retainTenant(tenantID string) {
 	compactedBlocklist := rw.blocklist.CompactedMetas(tenantID)
 	for _, b := range compactedBlocklist {
 		if b.CompactedTime.Before(cutoff) && rw.compactorSharder.Owns(b.BlockID.String()) {
+			if rw.blockHeld(tenantID, holds, &b.BlockMeta) {
+				level.Debug(rw.logger).Log("msg", "compacted block is under legal hold", "blockID", b.BlockID, "tenantID", tenantID)
+				continue
+			}
 			level.Info(rw.logger).Log("msg", "deleting block", "blockID", b.BlockID, "tenantID", tenantID)
 			err := rw.c.ClearBlock(b.BlockID, tenantID)
 			if err != nil {
@@ -86,3 +105,39 @@ func (rw *readerWriter) retainTenant(tenantID string) {
 		}
 	}
 }
+
+// blockHeld returns true if the block contains data under a legal hold. Held traces in the id range of the block
+// are looked up in the block, which tests its bloom filter before finding the trace. Blocks that can't be checked
+// are considered held.
+func (rw *readerWriter) blockHeld(tenantID string, holds *backend.LegalHolds, meta *backend.BlockMeta) bool {
+	if holds.HoldsTimeRange(meta) {
+		return true
+	}
+
+	ids := holds.HeldTraces(meta)
+	if len(ids) == 0 {
+		return false
+	}
+
+	opts := common.SearchOptions{}
+	if rw.cfg.Search != nil {
+		rw.cfg.Search.ApplyToOptions(&opts)
+	}
+
+	block, err := encoding.OpenBlock(meta, rw.r)
+	if err != nil {
+		level.Error(rw.logger).Log("msg", "failed to open block to check legal holds", "blockID", meta.BlockID, "tenantID", tenantID, "err", err)
+		return true
+	}
+	for _, id := range ids {
+		tr, err := block.FindTraceByID(context.Background(), id, opts)
+		if err != nil {
+			level.Error(rw.logger).Log("msg", "failed to find held trace in block", "blockID", meta.BlockID, "tenantID", tenantID, "err", err)
+			return true
+		}
+		if tr != nil {
+			return true
+		}
+	}
+	return false
+}
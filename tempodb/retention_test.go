@@ -1,6 +1,8 @@
 package tempodb
 
 import (
+	"context"
+	"encoding/hex"
 	"path"
 	"testing"
 	"time"
@@ -11,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/util/test"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"github.com/grafana/tempo/tempodb/encoding"
@@ -75,6 +78,142 @@ func TestRetention(t *testing.T) {
 	checkBlocklists(t, blockID, 0, 0, rw)
 }
 
+func TestRetentionSkipsLegalHolds(t *testing.T) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              0.01,
+			BloomShardSizeBytes:  100_000,
+			Version:              encoding.DefaultEncoding().Version(),
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: time.Hour,
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID, model.CurrentEncoding)
+	require.NoError(t, err)
+	complete, err := w.CompleteBlock(head, &mockCombiner{})
+	require.NoError(t, err)
+	meta := complete.BlockMeta()
+
+	rw := r.(*readerWriter)
+	ctx := context.Background()
+	checkBlocklists(t, meta.BlockID, 1, 0, rw)
+
+	holds := &backend.LegalHolds{Holds: []backend.LegalHold{
+		{ID: "range", Start: meta.StartTime.Add(-time.Hour), End: meta.EndTime.Add(time.Hour)},
+	}}
+	require.NoError(t, rw.w.WriteLegalHolds(ctx, testTenantID, holds))
+
+	// the held block is past retention but kept
+	rw.doRetention()
+	checkBlocklists(t, meta.BlockID, 1, 0, rw)
+
+	// once the hold is lifted retention marks it compacted
+	holds.Holds[0].LiftedAt = time.Now()
+	require.NoError(t, rw.w.WriteLegalHolds(ctx, testTenantID, holds))
+	rw.doRetention()
+	checkBlocklists(t, meta.BlockID, 0, 1, rw)
+
+	// a compacted block under a hold isn't cleared
+	rw.compactorCfg.CompactedBlockRetention = 0
+	holds.Holds = append(holds.Holds, backend.LegalHold{ID: "again", Start: meta.StartTime.Add(-time.Hour), End: meta.EndTime.Add(time.Hour)})
+	require.NoError(t, rw.w.WriteLegalHolds(ctx, testTenantID, holds))
+	rw.doRetention()
+	checkBlocklists(t, meta.BlockID, 0, 1, rw)
+
+	holds.Holds[1].LiftedAt = time.Now()
+	require.NoError(t, rw.w.WriteLegalHolds(ctx, testTenantID, holds))
+	rw.doRetention()
+	checkBlocklists(t, meta.BlockID, 0, 0, rw)
+}
+
+func TestRetentionLegalHoldOnTrace(t *testing.T) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              0.01,
+			BloomShardSizeBytes:  100_000,
+			Version:              encoding.DefaultEncoding().Version(),
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: time.Hour,
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+
+	first := make([]byte, 16)
+	first[15] = 0x01
+	last := make([]byte, 16)
+	last[15] = 0xff
+	data := []testData{
+		{id: first, t: test.MakeTrace(1, first)},
+		{id: last, t: test.MakeTrace(1, last)},
+	}
+	meta := cutTestBlockWithTraces(t, w, testTenantID, data).BlockMeta()
+
+	rw := r.(*readerWriter)
+	ctx := context.Background()
+	rw.pollBlocklist()
+
+	// a trace in the id range of the block that isn't in the block doesn't hold it
+	missing := make([]byte, 16)
+	missing[15] = 0x80
+	holds := &backend.LegalHolds{Holds: []backend.LegalHold{
+		{ID: "missing", TraceID: hex.EncodeToString(missing)},
+	}}
+	require.False(t, rw.blockHeld(testTenantID, holds, meta))
+
+	// a trace in the block holds it
+	holds.Holds = append(holds.Holds, backend.LegalHold{ID: "held", TraceID: hex.EncodeToString(last)})
+	require.NoError(t, rw.w.WriteLegalHolds(ctx, testTenantID, holds))
+	rw.doRetention()
+	checkBlocklists(t, meta.BlockID, 1, 0, rw)
+
+	holds.Holds[1].LiftedAt = time.Now()
+	require.NoError(t, rw.w.WriteLegalHolds(ctx, testTenantID, holds))
+	rw.doRetention()
+	checkBlocklists(t, meta.BlockID, 0, 1, rw)
+}
+
 func TestRetentionUpdatesBlocklistImmediately(t *testing.T) {
 	// Test that retention updates the in-memory blocklist
 	// immediately to reflect affected blocks and doesn't
@@ -0,0 +1,241 @@
+package tempodb
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// NameSalvageReport is the name of the report written next to a block created by salvaging a corrupt block.
+const NameSalvageReport = "salvage.json"
+
+var errTooManyLost = errors.New("too many pages lost")
+
+var (
+	metricSalvageQueueLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "salvage_queue_length",
+		Help:      "Number of corrupt blocks waiting to be salvaged.",
+	})
+	metricSalvageBlocks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "salvage_blocks_total",
+		Help:      "Total number of corrupt blocks processed by the salvage loop.",
+	}, []string{"result"})
+	metricSalvageLostPages = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "salvage_lost_pages_total",
+		Help:      "Total number of pages that could not be read while salvaging corrupt blocks.",
+	})
+)
+
+// SalvageReport lists the traces lost when a corrupt block was rewritten.
+type SalvageReport struct {
+	SourceBlockID   uuid.UUID   `json:"sourceBlockID"`
+	SalvagedObjects int         `json:"salvagedObjects"`
+	InvalidObjects  int         `json:"invalidObjects"`
+	LostPages       int         `json:"lostPages"`
+	LostRanges      []LostRange `json:"lostRanges"`
+}
+
+// LostRange is a range of trace ids that may have been lost, the first id is exclusive, the last inclusive.
+type LostRange struct {
+	After   string `json:"after"`
+	Through string `json:"through"`
+}
+
+// todo: pass a context/chan in to cancel this cleanly
+// once a salvage cycle rewrite the blocks flagged corrupt that are owned by this compactor
+func (rw *readerWriter) salvageLoop() {
+	ticker := time.NewTicker(rw.compactorCfg.SalvageCycle)
+	for range ticker.C {
+		rw.doSalvage(context.Background())
+	}
+}
+
+func (rw *readerWriter) doSalvage(ctx context.Context) {
+	queue := rw.salvageQueue()
+	metricSalvageQueueLength.Set(float64(len(queue)))
+
+	for i, meta := range queue {
+		level.Info(rw.logger).Log("msg", "salvaging corrupt block", "blockID", meta.BlockID, "tenantID", meta.TenantID)
+
+		report, err := rw.salvageBlock(ctx, meta)
+		switch {
+		case errors.Is(err, errTooManyLost):
+			level.Error(rw.logger).Log("msg", "corrupt block not retired, too many pages lost", "blockID", meta.BlockID, "tenantID", meta.TenantID, "err", err)
+			metricSalvageBlocks.WithLabelValues("rejected").Inc()
+		case err != nil:
+			level.Error(rw.logger).Log("msg", "failed to salvage corrupt block", "blockID", meta.BlockID, "tenantID", meta.TenantID, "err", err)
+			metricSalvageBlocks.WithLabelValues("failed").Inc()
+		default:
+			level.Info(rw.logger).Log("msg", "salvaged corrupt block", "blockID", meta.BlockID, "tenantID", meta.TenantID,
+				"salvagedObjects", report.SalvagedObjects, "invalidObjects", report.InvalidObjects, "lostPages", report.LostPages, "lostRanges", fmt.Sprintf("%v", report.LostRanges))
+			metricSalvageBlocks.WithLabelValues("salvaged").Inc()
+		}
+
+		metricSalvageQueueLength.Set(float64(len(queue) - i - 1))
+	}
+}
+
+// salvageQueue returns the corrupt blocks owned by this compactor, oldest first.
+func (rw *readerWriter) salvageQueue() []*backend.BlockMeta {
+	var queue []*backend.BlockMeta
+	for _, tenantID := range rw.blocklist.Tenants() {
+		for _, m := range rw.blocklist.Metas(tenantID) {
+			if m.Corrupt && rw.compactorSharder.Owns(m.BlockID.String()) {
+				queue = append(queue, m)
+			}
+		}
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].EndTime.Before(queue[j].EndTime)
+	})
+	return queue
+}
+
+// salvageBlock writes the readable objects of a corrupt block to a new block and marks the corrupt block
+// compacted. A report of the lost ids is written next to the new block. If more pages are lost than allowed
+// by the policy the new block is cleared and the corrupt block is kept.
+func (rw *readerWriter) salvageBlock(ctx context.Context, meta *backend.BlockMeta) (*SalvageReport, error) {
+	// the block may have been salvaged by a previous cycle, the polled blocklist is only updated on the next poll
+	if _, err := rw.r.BlockMeta(ctx, meta.BlockID, meta.TenantID); err != nil {
+		return nil, err
+	}
+
+	block, err := encoding.OpenBlock(meta, rw.uncachedReader)
+	if err != nil {
+		return nil, fmt.Errorf("error opening block: %w", err)
+	}
+
+	salvageable, ok := block.(common.Salvageable)
+	if !ok {
+		return nil, fmt.Errorf("block version %s: %w", meta.Version, common.ErrUnsupported)
+	}
+
+	iter, err := salvageable.SalvageIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	enc, err := encoding.FromVersion(meta.Version)
+	if err != nil {
+		iter.Close()
+		return nil, err
+	}
+	dec, err := model.NewObjectDecoder(meta.DataEncoding)
+	if err != nil {
+		iter.Close()
+		return nil, err
+	}
+
+	policy := &salvagePolicyIterator{
+		SalvageIterator: iter,
+		dec:             dec,
+		maxLost:         int(rw.compactorCfg.SalvageMaxLostRatio * float64(meta.TotalRecords)),
+	}
+	if rw.compactorCfg.SalvageMaxLostRatio <= 0 {
+		policy.maxLost = -1
+	}
+
+	newMeta := backend.NewBlockMeta(meta.TenantID, uuid.New(), meta.Version, meta.Encoding, meta.DataEncoding)
+	newMeta.StartTime = meta.StartTime
+	newMeta.EndTime = meta.EndTime
+	newMeta.TotalObjects = meta.TotalObjects
+	newMeta.CompactionLevel = meta.CompactionLevel
+
+	newBlockID := newMeta.BlockID
+	w := rw.getWriterForBlock(newMeta, time.Now())
+	newMeta, err = enc.CreateBlock(ctx, rw.cfg.Block, newMeta, policy, dec, rw.r, w)
+	if err != nil {
+		// the new block has no meta yet and is never polled, remove what was written of it
+		if clearErr := rw.c.ClearBlock(newBlockID, meta.TenantID); clearErr != nil {
+			level.Warn(rw.logger).Log("msg", "failed to clear partially salvaged block", "blockID", newBlockID, "err", clearErr)
+		}
+		return nil, err
+	}
+
+	report := &SalvageReport{
+		SourceBlockID:   meta.BlockID,
+		SalvagedObjects: policy.objects,
+		InvalidObjects:  len(policy.invalid),
+	}
+	for _, l := range append(iter.Lost(), policy.invalid...) {
+		report.LostPages += l.Records
+		report.LostRanges = append(report.LostRanges, LostRange{
+			After:   hex.EncodeToString(l.After),
+			Through: hex.EncodeToString(l.Through),
+		})
+	}
+	metricSalvageLostPages.Add(float64(report.LostPages))
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Write(ctx, NameSalvageReport, newMeta.BlockID, newMeta.TenantID, b, false)
+	if err != nil {
+		return nil, fmt.Errorf("error writing salvage report: %w", err)
+	}
+
+	markCompacted(rw, meta.TenantID, []*backend.BlockMeta{meta}, []*backend.BlockMeta{newMeta})
+
+	return report, nil
+}
+
+// salvagePolicyIterator drops the objects that can't be decoded and aborts once more pages are lost than
+// allowed.
+type salvagePolicyIterator struct {
+	common.SalvageIterator
+	dec model.ObjectDecoder
+
+	maxLost int
+	objects int
+	lastID  common.ID
+	invalid []common.LostRange
+}
+
+func (i *salvagePolicyIterator) Next(ctx context.Context) (common.ID, []byte, error) {
+	for {
+		id, obj, err := i.SalvageIterator.Next(ctx)
+
+		if i.maxLost >= 0 {
+			lost := 0
+			for _, l := range i.SalvageIterator.Lost() {
+				lost += l.Records
+			}
+			if lost > i.maxLost {
+				return nil, nil, fmt.Errorf("%w: %d, allowed %d", errTooManyLost, lost, i.maxLost)
+			}
+		}
+
+		if err != nil || id == nil {
+			return id, obj, err
+		}
+
+		lastID := i.lastID
+		i.lastID = id
+		if _, err := i.dec.PrepareForRead(obj); err != nil {
+			i.invalid = append(i.invalid, common.LostRange{After: lastID, Through: id})
+			continue
+		}
+
+		i.objects++
+		return id, obj, nil
+	}
+}
@@ -0,0 +1,156 @@
+package tempodb
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestSalvage(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxLostRatio float64
+		expectRetire bool
+	}{
+		{
+			name:         "no limit",
+			expectRetire: true,
+		},
+		{
+			name:         "within limit",
+			maxLostRatio: 0.5,
+			expectRetire: true,
+		},
+		{
+			name:         "over limit",
+			maxLostRatio: 0.001,
+			expectRetire: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			testSalvage(t, tc.maxLostRatio, tc.expectRetire)
+		})
+	}
+}
+
+func testSalvage(t *testing.T, maxLostRatio float64, expectRetire bool) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              0.01,
+			BloomShardSizeBytes:  100,
+			Version:              v2.VersionString,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: time.Hour,
+		SalvageMaxLostRatio:     maxLostRatio,
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+
+	data := make([]testData, 0, 100)
+	for i := 0; i < 100; i++ {
+		id := test.ValidTraceID(nil)
+		data = append(data, testData{id: id, t: test.MakeTrace(1, id)})
+	}
+	block := cutTestBlockWithTraces(t, w, testTenantID, data)
+	meta := block.BlockMeta()
+
+	// overwrite a tenth of the data file, this spans several pages
+	dataPath := path.Join(tempDir, "traces", testTenantID, meta.BlockID.String(), "data")
+	b, err := os.ReadFile(dataPath)
+	require.NoError(t, err)
+	for i := len(b) / 2; i < len(b)/2+len(b)/10; i++ {
+		b[i] = 0xFF
+	}
+	require.NoError(t, os.WriteFile(dataPath, b, 0o644))
+
+	rw := r.(*readerWriter)
+
+	// blocks not flagged corrupt are left alone
+	rw.pollBlocklist()
+	assert.Empty(t, rw.salvageQueue())
+
+	meta.Corrupt = true
+	require.NoError(t, rw.w.WriteBlockMeta(context.Background(), meta))
+	rw.pollBlocklist()
+	require.Len(t, rw.salvageQueue(), 1)
+
+	rw.doSalvage(context.Background())
+	rw.pollBlocklist()
+
+	metas := rw.blocklist.Metas(testTenantID)
+	require.Len(t, metas, 1)
+
+	if !expectRetire {
+		assert.Equal(t, meta.BlockID, metas[0].BlockID)
+		assert.True(t, metas[0].Corrupt)
+		assert.Empty(t, rw.blocklist.CompactedMetas(testTenantID))
+		return
+	}
+
+	newMeta := metas[0]
+	assert.NotEqual(t, meta.BlockID, newMeta.BlockID)
+	assert.False(t, newMeta.Corrupt)
+	assert.Equal(t, meta.CompactionLevel, newMeta.CompactionLevel)
+
+	compacted := rw.blocklist.CompactedMetas(testTenantID)
+	require.Len(t, compacted, 1)
+	assert.Equal(t, meta.BlockID, compacted[0].BlockID)
+
+	reportBytes, err := rw.r.Read(context.Background(), NameSalvageReport, newMeta.BlockID, testTenantID, false)
+	require.NoError(t, err)
+	report := &SalvageReport{}
+	require.NoError(t, json.Unmarshal(reportBytes, report))
+	assert.Equal(t, meta.BlockID, report.SourceBlockID)
+	assert.NotEmpty(t, report.LostRanges)
+	assert.Greater(t, report.LostPages, 0)
+	assert.Equal(t, report.SalvagedObjects, newMeta.TotalObjects)
+	assert.Less(t, report.SalvagedObjects, len(data))
+
+	// every trace salvaged is found in the new block
+	found := 0
+	for _, d := range data {
+		trs, failedBlocks, err := r.Find(context.Background(), testTenantID, d.id, newMeta.BlockID.String(), newMeta.BlockID.String(), 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, failedBlocks)
+		if len(trs) == 1 && trs[0] != nil {
+			found++
+		}
+	}
+	assert.Equal(t, report.SalvagedObjects, found)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/go-kit/log/level"
@@ -16,6 +17,13 @@ import (
 // RescanBlocks scans through the search directory in the WAL folder and replays files
 // todo: copied from wal.RescanBlocks(), see if we can reduce duplication?
 func RescanBlocks(walPath string) ([]*StreamingSearchBlock, error) {
+	return RescanBlocksForTenants(walPath, nil)
+}
+
+// RescanBlocksForTenants is RescanBlocks restricted to the tenants accepted by filter, the files of the priority
+// tenants of the filter are replayed first. Files of other tenants are skipped and not removed. A nil filter replays
+// all tenants.
+func RescanBlocksForTenants(walPath string, filter *wal.ReplayTenantFilter) ([]*StreamingSearchBlock, error) {
 	searchFilepath := filepath.Join(walPath, "search")
 	files, err := os.ReadDir(searchFilepath)
 	if err != nil {
@@ -24,6 +32,16 @@ func RescanBlocks(walPath string) ([]*StreamingSearchBlock, error) {
 		return nil, nil
 	}
 
+	if filter != nil {
+		tenant := func(name string) string {
+			_, tenantID, _, _, _, _ := wal.ParseFilename(name)
+			return tenantID
+		}
+		sort.SliceStable(files, func(i, j int) bool {
+			return filter.Before(tenant(files[i].Name()), tenant(files[j].Name()))
+		})
+	}
+
 	blocks := make([]*StreamingSearchBlock, 0, len(files))
 	for _, f := range files {
 		info, err := f.Info()
@@ -35,6 +53,12 @@ func RescanBlocks(walPath string) ([]*StreamingSearchBlock, error) {
 		if info.IsDir() {
 			continue
 		}
+		if filter != nil {
+			if _, tenantID, _, _, _, err := wal.ParseFilename(info.Name()); err != nil || !filter.Replays(tenantID) {
+				level.Info(log.Logger).Log("msg", "skipping replay of wal file", "file", info.Name())
+				continue
+			}
+		}
 		start := time.Now()
 		level.Info(log.Logger).Log("msg", "beginning replay", "file", info.Name(), "size", info.Size())
 
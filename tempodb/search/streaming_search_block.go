@@ -1,7 +1,6 @@
 package search
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -185,6 +184,7 @@ type streamingSearchBlockIterator struct {
 	objectRW     common.ObjectReaderWriter
 
 	pagesBuffer [][]byte
+	buffer      []byte
 }
 
 var _ common.Iterator = (*streamingSearchBlockIterator)(nil)
@@ -196,23 +196,22 @@ func (s *streamingSearchBlockIterator) Next(ctx context.Context) (common.ID, []b
 
 	currentRecord := s.records[s.currentIndex]
 
-	// Use unique buffer that can be returned to the caller.
-	// This is primarily for DedupingIterator which uses 2 buffers at once.
-	var buffer []byte
-	s.pagesBuffer[0] = make([]byte, currentRecord.Length)
-	pagesBuffer, _, err := s.dataReader.Read(ctx, []common.Record{currentRecord}, s.pagesBuffer, buffer)
+	// The page buffer is reused and the object references it. This is safe because
+	// the DedupingIterator copies the objects it holds onto.
+	var err error
+	s.pagesBuffer, s.buffer, err = s.dataReader.Read(ctx, []common.Record{currentRecord}, s.pagesBuffer, s.buffer)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	_, pagesBuffer[0], err = s.objectRW.UnmarshalObjectFromReader(bytes.NewReader(pagesBuffer[0]))
+	_, _, obj, err := s.objectRW.UnmarshalAndAdvanceBuffer(s.pagesBuffer[0])
 	if err != nil {
 		return nil, nil, err
 	}
 
 	s.currentIndex++
 
-	return currentRecord.ID, pagesBuffer[0], nil
+	return currentRecord.ID, obj, nil
 }
 
 func (*streamingSearchBlockIterator) Close() {
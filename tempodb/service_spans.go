@@ -0,0 +1,83 @@
+package tempodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// CountServiceSpans counts the spans of a service, or all spans if service is empty, of the blocks within start and
+// end per step from the statistics of the blocks. The statistics of a block cover all its spans, so only blocks
+// whose recorded span start times all fall within the same step are counted. Blocks whose recorded service names don't include the
+// service have no spans of it and are left out. The other blocks overlapping the range are returned, their spans
+// have to be read to answer a query exactly.
+func CountServiceSpans(ctx context.Context, r backend.Reader, metas []*backend.BlockMeta, service string, start, end time.Time, step time.Duration) ([]uint64, []*backend.BlockMeta, error) {
+	if step <= 0 {
+		return nil, nil, fmt.Errorf("step must be greater than 0")
+	}
+
+	counts := make([]uint64, (end.Sub(start)+step-1)/step)
+	var remaining []*backend.BlockMeta
+
+	for _, m := range metas {
+		if !m.StartTime.Before(end) || !m.EndTime.After(start) {
+			continue
+		}
+		if service != "" && !m.MayContainServiceName(service) {
+			continue
+		}
+
+		if !m.Stats {
+			remaining = append(remaining, m)
+			continue
+		}
+
+		stats, err := readStats(ctx, r, m)
+		if errors.Is(err, common.ErrUnsupported) {
+			remaining = append(remaining, m)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the block times are clamped to the ingestion slack, only the recorded start times of the spans tell
+		// which steps they fall into
+		if stats.MaxSpanStartUnixNano == 0 {
+			remaining = append(remaining, m)
+			continue
+		}
+		minStart := time.Unix(0, int64(stats.MinSpanStartUnixNano))
+		maxStart := time.Unix(0, int64(stats.MaxSpanStartUnixNano))
+		if minStart.Before(start) || !maxStart.Before(end) || minStart.Sub(start)/step != maxStart.Sub(start)/step {
+			remaining = append(remaining, m)
+			continue
+		}
+		first := minStart.Sub(start) / step
+
+		if service != "" {
+			counts[first] += stats.ServiceSpans[service]
+			continue
+		}
+		counts[first] += stats.Spans
+	}
+
+	return counts, remaining, nil
+}
+
+func readStats(ctx context.Context, r backend.Reader, meta *backend.BlockMeta) (*common.BlockStats, error) {
+	block, err := encoding.OpenBlock(meta, r)
+	if err != nil {
+		return nil, fmt.Errorf("error opening block %s: %w", meta.BlockID, err)
+	}
+	measurable, ok := block.(common.Measurable)
+	if !ok {
+		return nil, common.ErrUnsupported
+	}
+	return measurable.Stats(ctx)
+}
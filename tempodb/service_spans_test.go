@@ -0,0 +1,79 @@
+package tempodb
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
+)
+
+func TestCountServiceSpans(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	ctx := context.Background()
+
+	rawR, rawW, _, err := local.New(&local.Config{Path: t.TempDir()})
+	require.NoError(t, err)
+	r := backend.NewReader(rawR)
+	w := backend.NewWriter(rawW)
+
+	// spans of blocks with stats start from start to end, the block times are those of the spans unless given
+	meta := func(start, end time.Duration, serviceSpans map[string]uint64, serviceNames ...string) *backend.BlockMeta {
+		m := backend.NewBlockMeta("test", uuid.New(), vparquet2.VersionString, backend.EncNone, "")
+		m.StartTime = now.Add(start)
+		m.EndTime = now.Add(end)
+		m.ServiceNames = serviceNames
+		if serviceSpans != nil {
+			stats := common.BlockStats{
+				ServiceSpans:         serviceSpans,
+				MinSpanStartUnixNano: uint64(now.Add(start).UnixNano()),
+				MaxSpanStartUnixNano: uint64(now.Add(end).UnixNano()),
+			}
+			for _, n := range serviceSpans {
+				stats.Spans += n
+			}
+			buf, err := json.Marshal(stats)
+			require.NoError(t, err)
+			require.NoError(t, w.Write(ctx, vparquet2.StatsFileName, m.BlockID, m.TenantID, buf, false))
+			m.Stats = true
+		}
+		return m
+	}
+
+	firstStep := meta(0, 50*time.Second, map[string]uint64{"a": 2, "b": 3})
+	secondStep := meta(time.Minute, 90*time.Second, map[string]uint64{"a": 4})
+	withoutStats := meta(0, 50*time.Second, nil)
+	twoSteps := meta(30*time.Second, 90*time.Second, map[string]uint64{"a": 1})
+	withoutA := meta(0, 50*time.Second, nil, "b")
+	outside := meta(time.Hour, 2*time.Hour, map[string]uint64{"a": 1})
+	// the block times were clamped to the ingestion slack, the spans start in the second step
+	clamped := meta(time.Minute, 70*time.Second, map[string]uint64{"a": 8})
+	clamped.StartTime, clamped.EndTime = now, now.Add(50*time.Second)
+	// stats written without the start times of the spans
+	withoutRange := meta(0, 50*time.Second, map[string]uint64{"a": 16})
+	stats := common.BlockStats{Spans: 16, ServiceSpans: map[string]uint64{"a": 16}}
+	buf, err := json.Marshal(stats)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(ctx, vparquet2.StatsFileName, withoutRange.BlockID, withoutRange.TenantID, buf, false))
+	metas := []*backend.BlockMeta{firstStep, secondStep, withoutStats, twoSteps, withoutA, outside, clamped, withoutRange}
+
+	counts, remaining, err := CountServiceSpans(ctx, r, metas, "a", now, now.Add(2*time.Minute), time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 12}, counts)
+	require.Equal(t, []*backend.BlockMeta{withoutStats, twoSteps, withoutRange}, remaining)
+
+	counts, remaining, err = CountServiceSpans(ctx, r, metas, "", now, now.Add(2*time.Minute), time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{5, 12}, counts)
+	require.Equal(t, []*backend.BlockMeta{withoutStats, twoSteps, withoutA, withoutRange}, remaining)
+
+	_, _, err = CountServiceSpans(ctx, r, metas, "", now, now.Add(2*time.Minute), 0)
+	require.Error(t, err)
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	pkg_cache "github.com/grafana/tempo/pkg/cache"
+	"github.com/grafana/tempo/pkg/ddsketch"
 	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/pkg/util/log"
@@ -42,6 +43,9 @@ const (
 	BlockIDMax = "FFFFFFFF-FFFF-FFFF-FFFF-FFFFFFFFFFFF"
 )
 
+// ErrPollingDisabled is returned when updating the blocklist of a component that doesn't poll it
+var ErrPollingDisabled = errors.New("blocklist polling is not enabled")
+
 var (
 	metricRetentionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
 		Namespace: "tempodb",
@@ -70,7 +74,10 @@ type Writer interface {
 	WriteBlock(ctx context.Context, block WriteableBlock) error
 	CompleteBlock(block *wal.AppendBlock, combiner model.ObjectCombiner) (common.BackendBlock, error)
 	CompleteBlockWithBackend(ctx context.Context, block *wal.AppendBlock, combiner model.ObjectCombiner, r backend.Reader, w backend.Writer) (common.BackendBlock, error)
+	CompleteBlockWithOverrides(ctx context.Context, block *wal.AppendBlock, combiner model.ObjectCombiner, version string, bloomFP float64, r backend.Reader, w backend.Writer) (common.BackendBlock, error)
 	CompleteSearchBlockWithBackend(block *search.StreamingSearchBlock, blockID uuid.UUID, tenantID string, r backend.Reader, w backend.Writer) (*search.BackendSearchBlock, error)
+	SubmitCompletion(block *wal.AppendBlock, fn func()) bool
+	DrainCompletions()
 	WAL() *wal.WAL
 }
 
@@ -79,8 +86,12 @@ type IterateObjectCallback func(id common.ID, obj []byte) bool
 type Reader interface {
 	Find(ctx context.Context, tenantID string, id common.ID, blockStart string, blockEnd string, timeStart int64, timeEnd int64) ([]*tempopb.Trace, []error, error)
 	Search(ctx context.Context, meta *backend.BlockMeta, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error)
+	ProjectSpans(ctx context.Context, meta *backend.BlockMeta, p common.Projection, cb func(*common.ProjectedSpan) error, opts common.SearchOptions) error
+	DurationSketches(ctx context.Context, metas []*backend.BlockMeta, service string, start, end time.Time) (*ddsketch.Sketch, []*backend.BlockMeta, error)
+	CountServiceSpans(ctx context.Context, metas []*backend.BlockMeta, service string, start, end time.Time, step time.Duration) ([]uint64, []*backend.BlockMeta, error)
 	BlockMetas(tenantID string) []*backend.BlockMeta
 	EnablePolling(sharder blocklist.JobSharder)
+	ApplyBlocklistEvents(ctx context.Context, events []blocklist.BlockEvent) error
 
 	Shutdown()
 }
@@ -93,11 +104,14 @@ type CompactorSharder interface {
 	Combine(dataEncoding string, tenantID string, objs ...[]byte) ([]byte, bool, error)
 	Owns(hash string) bool
 	RecordDiscardedSpans(count int, tenantID string)
+	RecordTruncatedTrace(tenantID string)
 }
 
 type CompactorOverrides interface {
 	BlockRetentionForTenant(tenantID string) time.Duration
 	MaxBytesPerTraceForTenant(tenantID string) int
+	CombineStrategyForTenant(tenantID string) common.CombineStrategy
+	BloomFPForTenant(tenantID string) float64
 }
 
 type WriteableBlock interface {
@@ -113,14 +127,16 @@ type readerWriter struct {
 	uncachedReader backend.Reader
 	uncachedWriter backend.Writer
 
-	wal  *wal.WAL
-	pool *pool.Pool
+	wal         *wal.WAL
+	pool        *pool.Pool
+	completions *completionPool
 
 	logger gkLog.Logger
 	cfg    *Config
 
 	blocklistPoller *blocklist.Poller
 	blocklist       *blocklist.List
+	bloomPinner     *bloomPinner
 
 	compactorCfg          *CompactorConfig
 	compactorSharder      CompactorSharder
@@ -159,11 +175,21 @@ func New(cfg *Config, logger gkLog.Logger) (Reader, Writer, Compactor, error) {
 	uncachedReader := backend.NewReader(rawR)
 	uncachedWriter := backend.NewWriter(rawW)
 
+	// pinned blooms are fetched from the backend and served by both the cached and uncached readers
+	var pinner *bloomPinner
+	if len(cfg.BloomPinning.Tenants) > 0 {
+		pinner = newBloomPinner(cfg.BloomPinning, uncachedReader, logger)
+		uncachedReader = backend.NewReader(pinner.wrap(rawR))
+	}
+
 	var cacheBackend pkg_cache.Cache
 
 	switch cfg.Cache {
 	case "redis":
-		cacheBackend = redis.NewClient(cfg.Redis, cfg.BackgroundCache, logger)
+		cacheBackend, err = redis.NewClient(cfg.Redis, cfg.BackgroundCache, logger)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 	case "memcached":
 		cacheBackend = memcached.NewClient(cfg.Memcached, cfg.BackgroundCache, logger)
 	}
@@ -175,6 +201,10 @@ func New(cfg *Config, logger gkLog.Logger) (Reader, Writer, Compactor, error) {
 		}
 	}
 
+	if pinner != nil {
+		rawR = pinner.wrap(rawR)
+	}
+
 	r := backend.NewReader(rawR)
 	w := backend.NewWriter(rawW)
 	rw := &readerWriter{
@@ -186,7 +216,9 @@ func New(cfg *Config, logger gkLog.Logger) (Reader, Writer, Compactor, error) {
 		cfg:            cfg,
 		logger:         logger,
 		pool:           pool.NewPool(cfg.Pool),
+		completions:    newCompletionPool(cfg.Completion),
 		blocklist:      blocklist.New(),
+		bloomPinner:    pinner,
 	}
 
 	rw.wal, err = wal.New(rw.cfg.WAL)
@@ -210,9 +242,19 @@ func (rw *readerWriter) CompleteBlock(block *wal.AppendBlock, combiner model.Obj
 // CompleteBlock iterates the given WAL block but flushes it to the given backend instead of the default TempoDB backend. The
 // new block will have the same ID as the input block.
 func (rw *readerWriter) CompleteBlockWithBackend(ctx context.Context, block *wal.AppendBlock, combiner model.ObjectCombiner, r backend.Reader, w backend.Writer) (common.BackendBlock, error) {
+	return rw.CompleteBlockWithOverrides(ctx, block, combiner, rw.cfg.Block.Version, 0, r, w)
+}
+
+// CompleteBlockWithOverrides is CompleteBlockWithBackend writing a block with the given version and bloom filter
+// false positive rate instead of the ones of the config, e.g. the overrides of the tenant. The version must be a
+// registered encoding, empty uses the config. A rate of 0 uses the config.
+func (rw *readerWriter) CompleteBlockWithOverrides(ctx context.Context, block *wal.AppendBlock, combiner model.ObjectCombiner, version string, bloomFP float64, r backend.Reader, w backend.Writer) (common.BackendBlock, error) {
+	if version == "" {
+		version = rw.cfg.Block.Version
+	}
 
 	// The destination block format:
-	vers, err := encoding.FromVersion(rw.cfg.Block.Version)
+	vers, err := encoding.FromVersion(version)
 	if err != nil {
 		return nil, err
 	}
@@ -239,7 +281,14 @@ func (rw *readerWriter) CompleteBlockWithBackend(ctx context.Context, block *wal
 		Encoding: rw.cfg.Block.Encoding,
 	}
 
-	newMeta, err := vers.CreateBlock(ctx, rw.cfg.Block, inMeta, iter, dec, r, w)
+	blockCfg := rw.cfg.Block
+	if bloomFP > 0 {
+		cfg := *rw.cfg.Block
+		cfg.BloomFP = bloomFP
+		blockCfg = &cfg
+	}
+
+	newMeta, err := vers.CreateBlock(ctx, blockCfg, inMeta, iter, dec, r, w)
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating block")
 	}
@@ -365,9 +414,39 @@ func (rw *readerWriter) Search(ctx context.Context, meta *backend.BlockMeta, req
 	return block.Search(ctx, req, opts)
 }
 
+// ProjectSpans reads the projected fields of all spans of the given block. common.ErrUnsupported is returned for
+// blocks that don't store their spans by column.
+func (rw *readerWriter) ProjectSpans(ctx context.Context, meta *backend.BlockMeta, p common.Projection, cb func(*common.ProjectedSpan) error, opts common.SearchOptions) error {
+	block, err := encoding.OpenBlock(meta, rw.r)
+	if err != nil {
+		return err
+	}
+
+	projectable, ok := block.(common.Projectable)
+	if !ok {
+		return common.ErrUnsupported
+	}
+
+	rw.cfg.Search.ApplyToOptions(&opts)
+	return projectable.ProjectSpans(ctx, p, cb, opts)
+}
+
+// DurationSketches merges the span duration sketches of the blocks, see MergeDurationSketches
+func (rw *readerWriter) DurationSketches(ctx context.Context, metas []*backend.BlockMeta, service string, start, end time.Time) (*ddsketch.Sketch, []*backend.BlockMeta, error) {
+	return MergeDurationSketches(ctx, rw.r, metas, service, start, end)
+}
+
+// CountServiceSpans counts the spans of the blocks per step from their statistics, see CountServiceSpans
+func (rw *readerWriter) CountServiceSpans(ctx context.Context, metas []*backend.BlockMeta, service string, start, end time.Time, step time.Duration) ([]uint64, []*backend.BlockMeta, error) {
+	return CountServiceSpans(ctx, rw.r, metas, service, start, end, step)
+}
+
 func (rw *readerWriter) Shutdown() {
 	// todo: stop blocklist poll
 	rw.pool.Shutdown()
+	if rw.completions != nil {
+		rw.completions.stop()
+	}
 	rw.r.Shutdown()
 }
 
@@ -391,6 +470,21 @@ func (rw *readerWriter) EnableCompaction(cfg *CompactorConfig, c CompactorSharde
 		level.Info(rw.logger).Log("msg", "compaction and retention enabled.")
 		go rw.compactionLoop()
 		go rw.retentionLoop()
+
+		if cfg.RebloomCycle > 0 {
+			level.Info(rw.logger).Log("msg", "rebloom enabled.", "cycle", cfg.RebloomCycle)
+			go rw.rebloomLoop()
+		}
+
+		if cfg.SalvageCycle > 0 {
+			level.Info(rw.logger).Log("msg", "salvaging corrupt blocks enabled.", "cycle", cfg.SalvageCycle)
+			go rw.salvageLoop()
+		}
+
+		if cfg.TombstoneCycle > 0 {
+			level.Info(rw.logger).Log("msg", "rewriting blocks with tombstones enabled.", "cycle", cfg.TombstoneCycle)
+			go rw.tombstoneLoop()
+		}
 	}
 }
 
@@ -414,7 +508,7 @@ func (rw *readerWriter) EnablePolling(sharder blocklist.JobSharder) {
 		rw.cfg.BlocklistPollTenantIndexBuilders = DefaultTenantIndexBuilders
 	}
 
-	level.Info(rw.logger).Log("msg", "polling enabled", "interval", rw.cfg.BlocklistPoll, "concurrency", rw.cfg.BlocklistPollConcurrency)
+	level.Info(rw.logger).Log("msg", "polling enabled", "interval", rw.cfg.BlocklistPollInterval(), "concurrency", rw.cfg.BlocklistPollConcurrency)
 
 	blocklistPoller := blocklist.NewPoller(&blocklist.PollerConfig{
 		PollConcurrency:     rw.cfg.BlocklistPollConcurrency,
@@ -434,7 +528,7 @@ func (rw *readerWriter) EnablePolling(sharder blocklist.JobSharder) {
 }
 
 func (rw *readerWriter) pollingLoop() {
-	ticker := time.NewTicker(rw.cfg.BlocklistPoll)
+	ticker := time.NewTicker(rw.cfg.BlocklistPollInterval())
 	for range ticker.C {
 		rw.pollBlocklist()
 	}
@@ -449,6 +543,19 @@ func (rw *readerWriter) pollBlocklist() {
 	}
 
 	rw.blocklist.ApplyPollResults(blocklist, compactedBlocklist)
+
+	if rw.bloomPinner != nil {
+		rw.bloomPinner.updateAsync(context.Background(), blocklist)
+	}
+}
+
+// ApplyBlocklistEvents updates the polled blocklist with the blocks changed by bucket notifications
+func (rw *readerWriter) ApplyBlocklistEvents(ctx context.Context, events []blocklist.BlockEvent) error {
+	if rw.blocklistPoller == nil {
+		return ErrPollingDisabled
+	}
+
+	return rw.blocklistPoller.ApplyEvents(ctx, rw.blocklist, events)
 }
 
 func (rw *readerWriter) shouldCache(meta *backend.BlockMeta, curTime time.Time) bool {
@@ -24,6 +24,7 @@ import (
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet2"
 	"github.com/grafana/tempo/tempodb/wal"
 )
 
@@ -598,8 +599,76 @@ func TestCompleteBlock(t *testing.T) {
 	}
 }
 
+func TestCompleteParquetWALBlock(t *testing.T) {
+	testEncodings := []string{v2.VersionString, vparquet.VersionString, vparquet2.VersionString}
+	for _, enc := range testEncodings {
+		t.Run(enc, func(t *testing.T) {
+			testCompleteParquetWALBlock(t, enc)
+		})
+	}
+}
+
+func testCompleteParquetWALBlock(t *testing.T, targetBlockVersion string) {
+	tempDir := t.TempDir()
+
+	_, w, _, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			BloomShardSizeBytes:  100_000,
+			Version:              targetBlockVersion,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			IngestionSlack: time.Minute,
+			Filepath:       path.Join(tempDir, "wal"),
+			Version:        vparquet.VersionString,
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	block, err := w.WAL().NewBlock(uuid.New(), testTenantID, model.CurrentEncoding)
+	require.NoError(t, err, "unexpected error creating block")
+	require.Equal(t, vparquet.VersionString, block.Meta().Version)
+
+	dec := model.MustNewSegmentDecoder(model.CurrentEncoding)
+
+	numMsgs := 100
+	reqs := make([]*tempopb.Trace, 0, numMsgs)
+	ids := make([][]byte, 0, numMsgs)
+	for i := 0; i < numMsgs; i++ {
+		id := test.ValidTraceID(nil)
+		req := test.MakeTrace(rand.Int()%10+1, id)
+		writeTraceToWal(t, block, dec, id, req, 0, 0)
+		reqs = append(reqs, req)
+		ids = append(ids, id)
+
+		// flush the wal block regularly like the ingester does after cutting traces
+		if i%10 == 0 {
+			require.NoError(t, block.Flush())
+		}
+	}
+
+	complete, err := w.CompleteBlock(block, &mockCombiner{})
+	require.NoError(t, err, "unexpected error completing block")
+	require.Equal(t, targetBlockVersion, complete.BlockMeta().Version)
+	require.Equal(t, numMsgs, complete.BlockMeta().TotalObjects)
+
+	for i, id := range ids {
+		found, err := complete.FindTraceByID(context.TODO(), id, common.SearchOptions{})
+		require.NoError(t, err)
+		require.True(t, proto.Equal(found, reqs[i]))
+	}
+}
+
 func TestCompleteBlockHonorsStartStopTimes(t *testing.T) {
-	testEncodings := []string{v2.VersionString, vparquet.VersionString}
+	testEncodings := []string{v2.VersionString, vparquet.VersionString, vparquet2.VersionString}
 	for _, enc := range testEncodings {
 		t.Run(enc, func(t *testing.T) {
 			testCompleteBlockHonorsStartStopTimes(t, enc)
@@ -0,0 +1,247 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// NameTombstones is the name of the object listing the traces of a block that are dropped on compaction.
+const NameTombstones = "tombstones.json"
+
+var (
+	metricTombstoneQueueLength = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "tombstone_queue_length",
+		Help:      "Number of blocks with tombstones waiting to be rewritten.",
+	})
+	metricTombstonedObjects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "compaction_objects_tombstoned_total",
+		Help:      "Total number of objects dropped during compaction because they were tombstoned.",
+	})
+)
+
+// Tombstones lists the traces of a block that are deleted when the block is compacted.
+type Tombstones struct {
+	TraceIDs []string `json:"traceIDs"`
+}
+
+// ReadTombstones returns the tombstones of a block. A block without tombstones returns empty tombstones.
+func ReadTombstones(ctx context.Context, r backend.Reader, meta *backend.BlockMeta) (*Tombstones, error) {
+	b, err := r.Read(ctx, NameTombstones, meta.BlockID, meta.TenantID, false)
+	if errors.Is(err, backend.ErrDoesNotExist) {
+		return &Tombstones{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading tombstones: %w", err)
+	}
+
+	t := &Tombstones{}
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, fmt.Errorf("error unmarshalling tombstones: %w", err)
+	}
+	return t, nil
+}
+
+// WriteTombstones adds the passed trace ids to the tombstones of a block, the traces are dropped the next time the
+// block is compacted. The tombstones are a separate object, the meta of the block is never rewritten. If the block
+// was compacted concurrently ErrTombstonedBlockCompacted is returned, the traces have to be deleted from the
+// compacted blocks again.
+func WriteTombstones(ctx context.Context, r backend.Reader, w backend.Writer, meta *backend.BlockMeta, ids []common.ID) error {
+	t, err := ReadTombstones(ctx, r, meta)
+	if err != nil {
+		return err
+	}
+
+	set := make(map[string]struct{}, len(t.TraceIDs)+len(ids))
+	for _, id := range t.TraceIDs {
+		set[id] = struct{}{}
+	}
+	for _, id := range ids {
+		set[hex.EncodeToString(id)] = struct{}{}
+	}
+
+	if err := writeTombstones(ctx, w, meta, set); err != nil {
+		return err
+	}
+
+	// the compactor rereads the tombstones after marking the blocks compacted and carries new ones over to the
+	// compacted blocks. A block that is still live now can't miss them.
+	_, err = r.BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	if errors.Is(err, backend.ErrDoesNotExist) {
+		return ErrTombstonedBlockCompacted
+	}
+	return err
+}
+
+// ErrTombstonedBlockCompacted is returned when a block was compacted while tombstones were written to it
+var ErrTombstonedBlockCompacted = errors.New("block was compacted while its tombstones were written")
+
+func writeTombstones(ctx context.Context, w backend.Writer, meta *backend.BlockMeta, ids map[string]struct{}) error {
+	t := &Tombstones{TraceIDs: make([]string, 0, len(ids))}
+	for id := range ids {
+		t.TraceIDs = append(t.TraceIDs, id)
+	}
+	sort.Strings(t.TraceIDs)
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	err = w.Write(ctx, NameTombstones, meta.BlockID, meta.TenantID, b, false)
+	if err != nil {
+		return fmt.Errorf("error writing tombstones: %w", err)
+	}
+	return nil
+}
+
+// readTombstoneIDs returns the tombstoned trace ids of the passed blocks
+func readTombstoneIDs(ctx context.Context, r backend.Reader, metas []*backend.BlockMeta) (map[string]struct{}, error) {
+	ids := map[string]struct{}{}
+	for _, m := range metas {
+		t, err := ReadTombstones(ctx, r, m)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range t.TraceIDs {
+			ids[id] = struct{}{}
+		}
+	}
+	return ids, nil
+}
+
+// carryOverTombstones writes the tombstones of the compacted blocks that were added after the compaction read
+// them to the new blocks, they are applied the next time the new blocks are compacted.
+func carryOverTombstones(ctx context.Context, r backend.Reader, w backend.Writer, applied map[string]struct{}, compacted []*backend.BlockMeta, newBlocks []*backend.BlockMeta) error {
+	current, err := readTombstoneIDs(ctx, r, compacted)
+	if err != nil {
+		return err
+	}
+
+	added := map[string]struct{}{}
+	for id := range current {
+		if _, ok := applied[id]; !ok {
+			added[id] = struct{}{}
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	for _, m := range newBlocks {
+		if err := writeTombstones(ctx, w, m, added); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tombstoneFilter returns a func that reports whether an object was tombstoned in the passed tombstones, or nil if
+// there are none. Tombstoned traces under a legal hold of any of the blocks are kept. Their tombstones are discarded
+// with the rewritten block, the trace has to be deleted again once the hold is lifted.
+func tombstoneFilter(holds *backend.LegalHolds, metas []*backend.BlockMeta, tombstones map[string]struct{}) (func(id common.ID) bool, error) {
+	ids := make([][]byte, 0, len(tombstones))
+	for s := range tombstones {
+		id, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tombstone %s: %w", s, err)
+		}
+		if heldInAny(holds, id, metas) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i], ids[j]) < 0 })
+	return func(id common.ID) bool {
+		i := sort.Search(len(ids), func(i int) bool { return bytes.Compare(ids[i], id) >= 0 })
+		if i < len(ids) && bytes.Equal(ids[i], id) {
+			metricTombstonedObjects.Inc()
+			return true
+		}
+		return false
+	}, nil
+}
+
+func heldInAny(holds *backend.LegalHolds, id []byte, metas []*backend.BlockMeta) bool {
+	for _, m := range metas {
+		if holds.HoldsTrace(id, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// todo: pass a context/chan in to cancel this cleanly
+// once a tombstone cycle rewrite the blocks with tombstones that are owned by this compactor
+func (rw *readerWriter) tombstoneLoop() {
+	ticker := time.NewTicker(rw.compactorCfg.TombstoneCycle)
+	for range ticker.C {
+		rw.doTombstones()
+	}
+}
+
+func (rw *readerWriter) doTombstones() {
+	queue := rw.tombstoneQueue(context.Background())
+	metricTombstoneQueueLength.Set(float64(len(queue)))
+
+	for i, meta := range queue {
+		level.Info(rw.logger).Log("msg", "rewriting block with tombstones", "blockID", meta.BlockID, "tenantID", meta.TenantID)
+
+		// the tombstones are applied by compacting the block on its own
+		err := rw.compact([]*backend.BlockMeta{meta}, meta.TenantID)
+		if errors.Is(err, backend.ErrDoesNotExist) {
+			level.Info(rw.logger).Log("msg", "block with tombstones was already compacted", "blockID", meta.BlockID, "tenantID", meta.TenantID)
+		} else if err != nil {
+			level.Error(rw.logger).Log("msg", "error rewriting block with tombstones", "blockID", meta.BlockID, "tenantID", meta.TenantID, "err", err)
+			metricCompactionErrors.Inc()
+		}
+
+		metricTombstoneQueueLength.Set(float64(len(queue) - i - 1))
+	}
+}
+
+// tombstoneQueue returns the blocks with tombstones owned by this compactor, oldest first. The tombstones of every
+// owned block are read, the meta of a block doesn't tell if it has tombstones. Blocks flagged corrupt are left to
+// the salvage loop.
+func (rw *readerWriter) tombstoneQueue(ctx context.Context) []*backend.BlockMeta {
+	var queue []*backend.BlockMeta
+	for _, tenantID := range rw.blocklist.Tenants() {
+		for _, m := range rw.blocklist.Metas(tenantID) {
+			if m.Corrupt || !rw.compactorSharder.Owns(m.BlockID.String()) {
+				continue
+			}
+
+			t, err := ReadTombstones(ctx, rw.r, m)
+			if err != nil {
+				level.Error(rw.logger).Log("msg", "error reading tombstones", "blockID", m.BlockID, "tenantID", tenantID, "err", err)
+				continue
+			}
+			if len(t.TraceIDs) > 0 {
+				queue = append(queue, m)
+			}
+		}
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].EndTime.Before(queue[j].EndTime)
+	})
+	return queue
+}
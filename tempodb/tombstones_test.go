@@ -0,0 +1,171 @@
+package tempodb
+
+import (
+	"context"
+	"encoding/hex"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestTombstones(t *testing.T) {
+	testEncodings := []string{v2.VersionString, vparquet.VersionString}
+	for _, enc := range testEncodings {
+		t.Run(enc, func(t *testing.T) {
+			testTombstones(t, enc)
+		})
+	}
+}
+
+func testTombstones(t *testing.T, targetBlockVersion string) {
+	tempDir := t.TempDir()
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			BloomShardSizeBytes:  100_000,
+			Version:              targetBlockVersion,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+			RowGroupSizeBytes:    30_000_000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10_000_000,
+		FlushSizeBytes:          10_000_000,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: time.Hour,
+	}, &mockSharder{}, &mockOverrides{})
+
+	r.EnablePolling(&mockJobSharder{})
+
+	data := make([]testData, 0, 10)
+	for i := 0; i < 10; i++ {
+		id := test.ValidTraceID(nil)
+		data = append(data, testData{id: id, t: test.MakeTrace(1, id)})
+	}
+	block := cutTestBlockWithTraces(t, w, testTenantID, data)
+	meta := block.BlockMeta()
+
+	rw := r.(*readerWriter)
+
+	// blocks without tombstones are left alone
+	rw.pollBlocklist()
+	assert.Empty(t, rw.tombstoneQueue(context.Background()))
+
+	deleted := []common.ID{data[0].id, data[5].id}
+	require.NoError(t, WriteTombstones(context.Background(), rw.r, rw.w, meta, deleted[:1]))
+	require.NoError(t, WriteTombstones(context.Background(), rw.r, rw.w, meta, deleted))
+
+	tombstones, err := ReadTombstones(context.Background(), rw.r, meta)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{hex.EncodeToString(data[0].id), hex.EncodeToString(data[5].id)}, tombstones.TraceIDs)
+
+	rw.pollBlocklist()
+	require.Len(t, rw.tombstoneQueue(context.Background()), 1)
+
+	rw.doTombstones()
+	rw.pollBlocklist()
+
+	metas := rw.blocklist.Metas(testTenantID)
+	require.Len(t, metas, 1)
+	newMeta := metas[0]
+	assert.NotEqual(t, meta.BlockID, newMeta.BlockID)
+	tombstones, err = ReadTombstones(context.Background(), rw.r, newMeta)
+	require.NoError(t, err)
+	assert.Empty(t, tombstones.TraceIDs)
+	assert.Equal(t, len(data)-len(deleted), newMeta.TotalObjects)
+	assert.Empty(t, rw.tombstoneQueue(context.Background()))
+
+	for i, d := range data {
+		trs, failedBlocks, err := r.Find(context.Background(), testTenantID, d.id, newMeta.BlockID.String(), newMeta.BlockID.String(), 0, 0)
+		require.NoError(t, err)
+		require.Nil(t, failedBlocks)
+
+		// the block isn't searched for a deleted id outside of its new id range
+		if i == 0 || i == 5 {
+			for _, tr := range trs {
+				assert.Nil(t, tr)
+			}
+			continue
+		}
+		require.Len(t, trs, 1)
+		assert.NotNil(t, trs[0])
+	}
+
+	// the block is gone, tombstones can't be added to it anymore
+	err = WriteTombstones(context.Background(), rw.r, rw.w, meta, deleted)
+	assert.ErrorIs(t, err, ErrTombstonedBlockCompacted)
+}
+
+func TestCarryOverTombstones(t *testing.T) {
+	r, _, _, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(t.TempDir(), "traces"),
+		},
+		Block: &common.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			BloomShardSizeBytes:  100_000,
+			Version:              v2.VersionString,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(t.TempDir(), "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+	rw := r.(*readerWriter)
+
+	ctx := context.Background()
+	compacted := backend.NewBlockMeta(testTenantID, uuid.New(), v2.VersionString, backend.EncNone, "")
+	newBlock := backend.NewBlockMeta(testTenantID, uuid.New(), v2.VersionString, backend.EncNone, "")
+	require.NoError(t, rw.w.WriteBlockMeta(ctx, compacted))
+
+	applied := []common.ID{test.ValidTraceID(nil)}
+	require.NoError(t, WriteTombstones(ctx, rw.r, rw.w, compacted, applied))
+	tombstones, err := readTombstoneIDs(ctx, rw.r, []*backend.BlockMeta{compacted})
+	require.NoError(t, err)
+
+	// nothing to carry over if no tombstones were added
+	require.NoError(t, carryOverTombstones(ctx, rw.r, rw.w, tombstones, []*backend.BlockMeta{compacted}, []*backend.BlockMeta{newBlock}))
+	newTombstones, err := ReadTombstones(ctx, rw.r, newBlock)
+	require.NoError(t, err)
+	assert.Empty(t, newTombstones.TraceIDs)
+
+	// tombstones added during the compaction are kept with the new blocks
+	added := test.ValidTraceID(nil)
+	require.NoError(t, WriteTombstones(ctx, rw.r, rw.w, compacted, []common.ID{added}))
+	require.NoError(t, carryOverTombstones(ctx, rw.r, rw.w, tombstones, []*backend.BlockMeta{compacted}, []*backend.BlockMeta{newBlock}))
+	newTombstones, err = ReadTombstones(ctx, rw.r, newBlock)
+	require.NoError(t, err)
+	assert.Equal(t, []string{hex.EncodeToString(added)}, newTombstones.TraceIDs)
+}
@@ -15,168 +15,582 @@ import (
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 )
 
 const maxDataEncodingLength = 32
 
 // AppendBlock is a block that is actively used to append new objects to.  It stores all data in the appendFile
-// in the order it was received and an in memory sorted index.
+// in the order it was received and an in memory sorted index. Once the appendFile reaches the segment size the
+// block is rotated into a new segment file.
 type AppendBlock struct {
 	meta           *backend.BlockMeta
 	ingestionSlack time.Duration
-
-	appendFile *os.File
-	appender   v2.Appender
-
+	// ingestionFutureSlack is the slack for times after now, 0 uses ingestionSlack
+	ingestionFutureSlack time.Duration
+
+	appendFile  File
+	appender    v2.Appender
+	syncer      *fileSyncer
+	writer      *segmentWriter
+	flush       flushPolicy
+	segmentSize uint64
+	// writeBatch writes objects as a single record, nil if the block can't be appended to
+	writeBatch func([]common.ID, [][]byte) error
+	// batch buffers appended objects to write them as a single record, nil if objects are written immediately
+	batch *recordBatch
+	// batched is set if a record of the block can hold several objects. The filename of the block carries the
+	// batchedMarker then.
+	batched bool
+
+	fs       FileSystem
 	filepath string
-	readFile *os.File
-	once     sync.Once
+	data     *segmentReader
+
+	// parquet stores the objects of blocks with the vParquet version instead of the appender
+	parquet *vparquet.WALBlock
+
+	// quota limits the disk usage of the wal folder, nil if unlimited
+	quota *diskQuota
+	// parquetLength is the data length of the vParquet block last counted in the quota
+	parquetLength uint64
+	// replication receives every written object, nil if the wal isn't replicated
+	replication *replicationQueue
+	// ranges are the time ranges of the appended objects
+	ranges objectRanges
+	// outstanding is set while the block is counted in the outstanding wal blocks
+	outstanding bool
+	// tombstones are the deleted ids of v2 blocks
+	tombstones tombstones
+	// dictionary is the zstd dictionary the block is compressed with, nil if it has none
+	dictionary *zstdDictionary
+	// sample receives the appended objects to train the zstd dictionary of the tenant, nil if it isn't trained
+	sample func(obj []byte)
 }
 
-func newAppendBlock(id uuid.UUID, tenantID string, filepath string, e backend.Encoding, dataEncoding string, ingestionSlack time.Duration) (*AppendBlock, error) {
+func newAppendBlock(fs FileSystem, id uuid.UUID, tenantID string, filepath string, e backend.Encoding, dataEncoding string, ingestionSlack time.Duration, checksum bool, flush flushPolicy, segmentSize uint64, batch batchPolicy, dict *zstdDictionary) (*AppendBlock, error) {
 	if strings.ContainsRune(dataEncoding, ':') ||
+		strings.Contains(dataEncoding, segmentSeparator) ||
 		len([]rune(dataEncoding)) > maxDataEncodingLength {
 		return nil, fmt.Errorf("dataEncoding %s is invalid", dataEncoding)
 	}
 
 	h := &AppendBlock{
 		meta:           backend.NewBlockMeta(tenantID, id, v2.VersionString, e, dataEncoding),
+		fs:             fs,
 		filepath:       filepath,
 		ingestionSlack: ingestionSlack,
+		flush:          flush,
+		segmentSize:    segmentSize,
+		batched:        batch.enabled(),
+		dictionary:     dict,
 	}
 
 	name := h.fullFilename()
 
-	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	f, err := fs.Create(name)
 	if err != nil {
 		return nil, err
 	}
 	h.appendFile = f
-
-	dataWriter, err := v2.NewDataWriter(f, e)
+	h.syncer = newFileSyncer(f, flush)
+	h.writer = &segmentWriter{f: f}
+	// the file is appended to so it isn't mapped
+	h.data = newSegmentReader(fs, name, []walSegment{{index: 0, start: 0}}, false)
+
+	// pages are written whole, so the data writer continues writing into the next segment after a rotation
+	var dataWriter common.DataWriter
+	switch {
+	case dict != nil && checksum:
+		dataWriter, err = v2.NewChecksumDataWriterWithPool(h.writer, dict.pool)
+	case dict != nil:
+		dataWriter, err = v2.NewDataWriterWithPool(h.writer, dict.pool)
+	case checksum:
+		dataWriter, err = v2.NewChecksumDataWriter(h.writer, e)
+	default:
+		dataWriter, err = v2.NewDataWriter(h.writer, e)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	h.appender = v2.NewAppender(dataWriter)
+	appender := v2.NewAppender(dataWriter)
+	h.appender = appender
+	h.writeBatch = func(ids []common.ID, objs [][]byte) error {
+		var err error
+		if h.batched {
+			err = appender.AppendBatch(ids, objs)
+		} else {
+			// blocks without batched records have a record per object
+			for i := range ids {
+				err = appender.Append(ids[i], objs[i])
+				if err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		err = h.appended()
+		if err != nil {
+			return err
+		}
+		h.replicate(ids, objs)
+		return nil
+	}
+	if batch.enabled() {
+		h.batch = newRecordBatch(batch, h.writeBatch)
+	}
 
 	return h, nil
 }
 
 // newAppendBlockFromFile returns an AppendBlock that can not be appended to, but can
-// be completed. It can return a warning or a fatal error
-func newAppendBlockFromFile(filename string, path string, ingestionSlack time.Duration, additionalStartSlack time.Duration, fn RangeFunc) (*AppendBlock, error, error) {
+// be completed. The segments of the file are replayed concurrently. It can return a warning or a fatal error.
+// If fn is nil the time range of the objects isn't read and must be set by the caller. If mapped is set the segment
+// files are memory mapped for replay and reads. dict is the zstd dictionary named by the filename.
+func newAppendBlockFromFile(fs FileSystem, filename string, segments []int, path string, ingestionSlack time.Duration, additionalStartSlack time.Duration, concurrency int, mapped bool, dict *zstdDictionary, fn RangeFunc) (*AppendBlock, error, error) {
+	b, err := newReplayedAppendBlock(fs, filename, path, ingestionSlack, dict)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replayed := make([]replayedSegment, len(segments))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(segments) {
+		concurrency = len(segments)
+	}
+	for j := 0; j < concurrency; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				replayed[i] = b.replaySegment(segments[i], additionalStartSlack, mapped, fn)
+			}
+		}()
+	}
+	for i := range segments {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
 	var warning error
+	var records []common.Record
+	blockStart := uint32(math.MaxUint32)
+	blockEnd := uint32(0)
+	walSegments := make([]walSegment, 0, len(segments))
+	start := uint64(0)
+	for i, r := range replayed {
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+		if warning == nil {
+			warning = r.warning
+		}
+
+		// shift the offsets of the records from the start of the segment to the start of the block
+		for _, rec := range r.records {
+			rec.Start += start
+			records = append(records, rec)
+		}
+		if r.start < blockStart {
+			blockStart = r.start
+		}
+		if r.end > blockEnd {
+			blockEnd = r.end
+		}
+		b.ranges.merge(r.ranges)
+		b.tombstones.merge(r.tombstones)
+
+		walSegments = append(walSegments, walSegment{index: segments[i], start: start})
+		start += r.size
+	}
+	common.SortRecords(records)
+
+	b.data = newSegmentReader(fs, b.fullFilename(), walSegments, mapped)
+	b.appender = v2.NewRecordAppender(records)
+	b.meta.TotalObjects = b.appender.Length()
+	b.meta.StartTime = time.Unix(int64(blockStart), 0)
+	b.meta.EndTime = time.Unix(int64(blockEnd), 0)
+
+	return b, warning, nil
+}
+
+// newReplayedAppendBlock returns an AppendBlock for the wal file with the given name without its data
+func newReplayedAppendBlock(fs FileSystem, filename string, path string, ingestionSlack time.Duration, dict *zstdDictionary) (*AppendBlock, error) {
 	blockID, tenantID, version, e, dataEncoding, err := ParseFilename(filename)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parsing wal filename: %w", err)
+		return nil, fmt.Errorf("parsing wal filename: %w", err)
+	}
+	dictID, err := parseDictionaryID(filename)
+	if err != nil {
+		return nil, fmt.Errorf("parsing wal filename: %w", err)
+	}
+	if dictID != dict.ID() {
+		return nil, fmt.Errorf("wal file %s is compressed with zstd dictionary %08x, got %08x", filename, dictID, dict.ID())
 	}
 
-	b := &AppendBlock{
+	return &AppendBlock{
 		meta:           backend.NewBlockMeta(tenantID, blockID, version, e, dataEncoding),
+		fs:             fs,
 		filepath:       path,
 		ingestionSlack: ingestionSlack,
-	}
+		batched:        parseBatched(filename),
+		dictionary:     dict,
+	}, nil
+}
+
+type replayedSegment struct {
+	records    []common.Record
+	ranges     map[string]objectRange
+	tombstones []common.ID
+	size       uint64
+	start, end uint32
+	warning    error
+	err        error
+}
 
-	// replay file to extract records
-	f, err := b.file()
+// replaySegment extracts the records of a segment file with offsets relative to the start of the segment
+func (a *AppendBlock) replaySegment(index int, additionalStartSlack time.Duration, mapped bool, fn RangeFunc) replayedSegment {
+	r := replayedSegment{start: math.MaxUint32}
+
+	f, err := openFile(a.fs, segmentFilename(a.fullFilename(), index), mapped)
 	if err != nil {
-		return nil, nil, fmt.Errorf("accessing file: %w", err)
+		r.err = fmt.Errorf("accessing file: %w", err)
+		return r
 	}
+	defer f.Close()
 
-	blockStart := uint32(math.MaxUint32)
-	blockEnd := uint32(0)
+	info, err := f.Stat()
+	if err != nil {
+		r.err = fmt.Errorf("accessing file: %w", err)
+		return r
+	}
+	r.size = uint64(info.Size())
 
-	records, warning, err := ReplayWALAndGetRecords(f, e, func(bytes []byte) error {
-		start, end, err := fn(bytes, dataEncoding)
+	r.records, r.warning, r.err = replayWALRecords(f, a.meta.Encoding, a.dictionary, a.batched, func(id []byte, bytes []byte) error {
+		if IsTombstone(bytes) {
+			r.tombstones = append(r.tombstones, append(common.ID(nil), id...))
+			return nil
+		}
+		if fn == nil {
+			return nil
+		}
+		start, end, err := fn(bytes, a.meta.DataEncoding)
 		if err != nil {
 			return err
 		}
-		start, end = b.adjustTimeRangeForSlack(start, end, additionalStartSlack)
-		if start < blockStart {
-			blockStart = start
+		start, end = a.adjustTimeRangeForSlack(start, end, additionalStartSlack)
+		if r.ranges == nil {
+			r.ranges = map[string]objectRange{}
 		}
-		if end > blockEnd {
-			blockEnd = end
+		objRange, ok := r.ranges[string(id)]
+		if !ok {
+			objRange = objectRange{start: start, end: end}
+		}
+		r.ranges[string(id)] = objRange.add(start, end)
+		if start < r.start {
+			r.start = start
+		}
+		if end > r.end {
+			r.end = end
 		}
 		return nil
 	})
-	if err != nil {
-		return nil, nil, err
-	}
-
-	b.appender = v2.NewRecordAppender(records)
-	b.meta.TotalObjects = b.appender.Length()
-	b.meta.StartTime = time.Unix(int64(blockStart), 0)
-	b.meta.EndTime = time.Unix(int64(blockEnd), 0)
 
-	return b, warning, nil
+	return r
 }
 
 // Append adds an id and object to this wal block. start/end should indicate the time range
-// associated with the past object. They are unix epoch seconds.
+// associated with the past object. They are unix epoch seconds. ErrWALFull is returned if the
+// wal folder reached its max disk usage, nothing is appended then.
 func (a *AppendBlock) Append(id common.ID, b []byte, start, end uint32) error {
-	err := a.appender.Append(id, b)
+	defer observeAppend(time.Now())
+
+	err := a.quota.check()
+	if err != nil {
+		return err
+	}
+
+	if a.parquet != nil {
+		return a.appendParquet(id, b, start, end)
+	}
+
+	if a.sample != nil {
+		a.sample(b)
+	}
+	if a.batch != nil {
+		// the object is replicated once the batch is written
+		err = a.batch.add(id, b)
+	} else {
+		err = a.appender.Append(id, b)
+		if err == nil {
+			err = a.appended()
+		}
+		if err == nil {
+			a.replicate([]common.ID{id}, [][]byte{b})
+		}
+	}
 	if err != nil {
 		return err
 	}
 	start, end = a.adjustTimeRangeForSlack(start, end, 0)
 	a.meta.ObjectAdded(id, start, end)
+	a.ranges.add(id, start, end)
+	metricAppendedBytes.WithLabelValues(a.meta.TenantID).Add(float64(len(id) + len(b)))
+
+	return nil
+}
+
+// AppendBatch adds multiple ids and objects to this wal block with a single write. starts/ends are the time ranges
+// of the objects like in Append. Either all objects are appended or none, ErrWALFull is returned if the wal folder
+// reached its max disk usage.
+func (a *AppendBlock) AppendBatch(ids []common.ID, objs [][]byte, starts, ends []uint32) error {
+	if len(ids) != len(objs) || len(ids) != len(starts) || len(ids) != len(ends) {
+		return fmt.Errorf("mismatched batch lengths: %d ids, %d objects, %d starts, %d ends", len(ids), len(objs), len(starts), len(ends))
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	defer observeAppend(time.Now())
+
+	err := a.quota.check()
+	if err != nil {
+		return err
+	}
+
+	if a.parquet != nil {
+		return a.appendParquetBatch(ids, objs, starts, ends)
+	}
+
+	if a.sample != nil {
+		for _, obj := range objs {
+			a.sample(obj)
+		}
+	}
+
+	switch {
+	case a.writeBatch == nil:
+		err = common.ErrUnsupported
+	case a.batch != nil:
+		// keep the order of the objects already batched
+		err = a.batch.writeAll(ids, objs)
+	default:
+		err = a.writeBatch(ids, objs)
+	}
+	if err != nil {
+		return err
+	}
+
+	size := 0
+	for i := range ids {
+		start, end := a.adjustTimeRangeForSlack(starts[i], ends[i], 0)
+		a.meta.ObjectAdded(ids[i], start, end)
+		a.ranges.add(ids[i], start, end)
+		size += len(ids[i]) + len(objs[i])
+	}
+	metricAppendedBytes.WithLabelValues(a.meta.TenantID).Add(float64(size))
+	return nil
+}
+
+// appended syncs the wal file and rotates it if the segment is full. It is called after every record written.
+func (a *AppendBlock) appended() error {
+	err := a.syncer.appended()
+	if err != nil {
+		return err
+	}
+
+	if a.segmentSize > 0 && a.writer.written >= a.segmentSize {
+		return a.rotate()
+	}
 	return nil
 }
 
+// rotate continues appending to a new segment file. The new segment is created before the current one is closed,
+// so the block keeps appending to the current segment if it can't be created. The current segment is synced unless
+// the flush policy leaves syncing to the OS.
+func (a *AppendBlock) rotate() error {
+	last := a.data.lastSegment()
+	next := walSegment{
+		index: last.index + 1,
+		start: last.start + a.writer.written,
+	}
+
+	name := segmentFilename(a.fullFilename(), next.index)
+	f, err := a.fs.Create(name)
+	if err != nil {
+		return err
+	}
+
+	err = a.writer.truncate()
+	if err != nil {
+		_ = f.Close()
+		_ = a.fs.Remove(name)
+		return err
+	}
+
+	// nothing is appended to the current segment anymore, failing to sync or close it doesn't stop the rotation
+	err = a.syncer.syncAndClose()
+	if closeErr := a.appendFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	a.data.addSegment(next)
+	a.appendFile = f
+	a.syncer = newFileSyncer(f, a.flush)
+	a.writer.reset(f)
+
+	metricSegmentsRotated.Inc()
+	return err
+}
+
 func (a *AppendBlock) BlockID() uuid.UUID {
 	return a.meta.BlockID
 }
 
 func (a *AppendBlock) DataLength() uint64 {
+	if a.parquet != nil {
+		return a.parquet.DataLength()
+	}
+	if a.batch != nil {
+		var length uint64
+		a.batch.locked(func(bufferedBytes int) {
+			length = a.appender.DataLength() + uint64(bufferedBytes)
+		})
+		return length
+	}
 	return a.appender.DataLength()
 }
 
+// Flush writes objects buffered by the block to disk. Objects appended to v2 blocks are written immediately unless
+// they are batched. The meta of v2 blocks is persisted in their sidecar.
+func (a *AppendBlock) Flush() error {
+	if a.parquet != nil {
+		return a.parquet.Flush()
+	}
+	if a.writeBatch == nil {
+		// replayed blocks aren't appended to
+		return nil
+	}
+	if a.batch != nil {
+		err := a.batch.flush()
+		if err != nil {
+			return err
+		}
+	}
+	return a.writeSidecar()
+}
+
+// Sync flushes the block and syncs its records to disk regardless of the flush policy, e.g. before another copy
+// of the appended objects is dropped.
+func (a *AppendBlock) Sync() error {
+	if a.parquet != nil {
+		return a.parquet.Sync()
+	}
+	err := a.Flush()
+	if err != nil {
+		return err
+	}
+	if a.writeBatch == nil {
+		return nil
+	}
+	return a.syncer.syncNow()
+}
+
+// length is the number of objects in the block
+func (a *AppendBlock) length() int {
+	if a.parquet != nil {
+		return a.parquet.Length()
+	}
+	return a.appender.Length()
+}
+
 func (a *AppendBlock) Meta() *backend.BlockMeta {
 	return a.meta
 }
 
 func (a *AppendBlock) Iterator(combiner model.ObjectCombiner) (common.Iterator, error) {
+	if a.parquet != nil {
+		return a.parquet.ObjectIterator(context.Background())
+	}
+
+	if a.batch != nil {
+		err := a.batch.flush()
+		if err != nil {
+			return nil, err
+		}
+		a.batch.close()
+	}
+
 	if a.appendFile != nil {
-		err := a.appendFile.Close()
+		err := a.writer.truncate()
+		if err != nil {
+			return nil, err
+		}
+		a.syncer.close()
+		err = a.appendFile.Close()
 		if err != nil {
 			return nil, err
 		}
 		a.appendFile = nil
-	}
 
-	records := a.appender.Records()
-	readFile, err := a.file()
-	if err != nil {
-		return nil, err
+		err = a.writeSidecar()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(readFile), a.meta.Encoding)
-	if err != nil {
-		return nil, err
+	return a.iterator(combiner)
+}
+
+// ReadIterator returns an iterator over the objects appended so far. Unlike Iterator the block can still be
+// appended to, objects appended after the call are not returned.
+func (a *AppendBlock) ReadIterator(combiner model.ObjectCombiner) (common.Iterator, error) {
+	if a.parquet == nil && a.batch != nil {
+		err := a.batch.flush()
+		if err != nil {
+			return nil, err
+		}
 	}
+	return a.iterator(combiner)
+}
 
-	iterator := v2.NewRecordIterator(records, dataReader, v2.NewObjectReaderWriter())
-	iterator, err = v2.NewDedupingIterator(iterator, combiner, a.meta.DataEncoding)
-	if err != nil {
-		return nil, err
+func (a *AppendBlock) iterator(combiner model.ObjectCombiner) (common.Iterator, error) {
+	if a.parquet != nil {
+		return a.parquet.ObjectIterator(context.Background())
 	}
 
-	return iterator, nil
+	return a.recordIterator(a.appender.Records(), combiner)
 }
 
 func (a *AppendBlock) Find(id common.ID, combiner model.ObjectCombiner) ([]byte, error) {
-	records := a.appender.RecordsForID(id)
-	file, err := a.file()
-	if err != nil {
-		return nil, err
+	if a.parquet != nil {
+		return a.parquet.Find(context.Background(), id)
+	}
+
+	if a.batch != nil {
+		err := a.batch.flush()
+		if err != nil {
+			return nil, err
+		}
 	}
+
+	if a.tombstones.contains(id) {
+		return nil, nil
+	}
+
+	records := a.appender.RecordsForID(id)
 	if len(records) == 0 {
 		return nil, nil
 	}
 
-	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(file), a.meta.Encoding)
+	dataReader, err := newDataReader(a.data, a.meta.Encoding, a.dictionary)
 	if err != nil {
 		return nil, err
 	}
@@ -187,12 +601,24 @@ func (a *AppendBlock) Find(id common.ID, combiner model.ObjectCombiner) ([]byte,
 }
 
 func (a *AppendBlock) Clear() error {
-	if a.readFile != nil {
-		_ = a.readFile.Close()
-		a.readFile = nil
+	a.untrackOutstanding()
+
+	if a.parquet != nil {
+		return a.parquet.Clear()
+	}
+
+	_ = a.data.Close()
+
+	a.batch.close()
+
+	// the sidecar is removed first so it never outlives the data
+	err := a.removeSidecar()
+	if err != nil {
+		return err
 	}
 
 	if a.appendFile != nil {
+		a.syncer.close()
 		_ = a.appendFile.Close()
 		a.appendFile = nil
 	}
@@ -200,8 +626,14 @@ func (a *AppendBlock) Clear() error {
 	// ignore error, it's important to remove the file above all else
 	_ = a.appender.Complete()
 
-	name := a.fullFilename()
-	return os.Remove(name)
+	segments := a.data.allSegments()
+	for _, seg := range segments[1:] {
+		err = a.fs.Remove(segmentFilename(a.fullFilename(), seg.index))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return a.fs.Remove(segmentFilename(a.fullFilename(), segments[0].index))
 }
 
 func (a *AppendBlock) fullFilename() string {
@@ -209,33 +641,47 @@ func (a *AppendBlock) fullFilename() string {
 		return filepath.Join(a.filepath, fmt.Sprintf("%v.%v", a.meta.BlockID, a.meta.TenantID))
 	}
 
+	encoding := a.meta.Encoding.String()
+	if a.dictionary != nil {
+		encoding += dictionarySeparator + dictionaryFilename(a.dictionary.id)
+	}
+	if a.batched {
+		encoding += dictionarySeparator + batchedMarker
+	}
+
 	var filename string
 	if a.meta.DataEncoding == "" {
-		filename = fmt.Sprintf("%v.%v.%v.%v", a.meta.BlockID, a.meta.TenantID, a.meta.Version, a.meta.Encoding)
+		filename = fmt.Sprintf("%v.%v.%v.%v", a.meta.BlockID, a.meta.TenantID, a.meta.Version, encoding)
 	} else {
-		filename = fmt.Sprintf("%v.%v.%v.%v.%v", a.meta.BlockID, a.meta.TenantID, a.meta.Version, a.meta.Encoding, a.meta.DataEncoding)
+		filename = fmt.Sprintf("%v.%v.%v.%v.%v", a.meta.BlockID, a.meta.TenantID, a.meta.Version, encoding, a.meta.DataEncoding)
 	}
 
 	return filepath.Join(a.filepath, filename)
 }
 
-func (a *AppendBlock) file() (*os.File, error) {
-	var err error
-	a.once.Do(func() {
-		if a.readFile == nil {
-			name := a.fullFilename()
-
-			a.readFile, err = os.OpenFile(name, os.O_RDONLY, 0644)
-		}
-	})
+// SetIngestionSlack overrides the slack of the start and end times of objects appended to the block. past is how far
+// before now and future how far after now times are accepted. A 0 slack keeps the slack of the wal config.
+func (a *AppendBlock) SetIngestionSlack(past, future time.Duration) {
+	if future <= 0 {
+		future = a.futureSlack()
+	}
+	if past > 0 {
+		a.ingestionSlack = past
+	}
+	a.ingestionFutureSlack = future
+}
 
-	return a.readFile, err
+func (a *AppendBlock) futureSlack() time.Duration {
+	if a.ingestionFutureSlack > 0 {
+		return a.ingestionFutureSlack
+	}
+	return a.ingestionSlack
 }
 
 func (a *AppendBlock) adjustTimeRangeForSlack(start uint32, end uint32, additionalStartSlack time.Duration) (uint32, uint32) {
 	now := time.Now()
 	startOfRange := uint32(now.Add(-a.ingestionSlack).Add(-additionalStartSlack).Unix())
-	endOfRange := uint32(now.Add(a.ingestionSlack).Unix())
+	endOfRange := uint32(now.Add(a.futureSlack()).Unix())
 
 	warn := false
 	if start < startOfRange {
@@ -0,0 +1,198 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+)
+
+// newParquetAppendBlock creates an append block that stores its objects in the vParquet format. The block is a
+// folder in the wal with the parquet files the appended objects are flushed to.
+func newParquetAppendBlock(id uuid.UUID, tenantID string, filepath string, e backend.Encoding, dataEncoding string, ingestionSlack time.Duration, flush flushPolicy) (*AppendBlock, error) {
+	if strings.ContainsRune(dataEncoding, ':') ||
+		strings.Contains(dataEncoding, segmentSeparator) ||
+		len([]rune(dataEncoding)) > maxDataEncodingLength {
+		return nil, fmt.Errorf("dataEncoding %s is invalid", dataEncoding)
+	}
+
+	h := &AppendBlock{
+		meta:           backend.NewBlockMeta(tenantID, id, vparquet.VersionString, e, dataEncoding),
+		filepath:       filepath,
+		ingestionSlack: ingestionSlack,
+		flush:          flush,
+	}
+
+	b, err := vparquet.CreateWALBlock(h.fullFilename(), dataEncoding, flush.policy != FlushPolicyNever)
+	if err != nil {
+		return nil, err
+	}
+	h.parquet = b
+
+	return h, nil
+}
+
+// newParquetAppendBlockFromFolder returns an AppendBlock for the wal folder of a vParquet block. Unlike v2 blocks
+// the replayed block can still be appended to. It can return a warning or a fatal error
+func newParquetAppendBlockFromFolder(name string, path string, ingestionSlack time.Duration, additionalStartSlack time.Duration, flush flushPolicy) (*AppendBlock, error, error) {
+	blockID, tenantID, version, e, dataEncoding, err := ParseFilename(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing wal filename: %w", err)
+	}
+
+	a := &AppendBlock{
+		meta:           backend.NewBlockMeta(tenantID, blockID, version, e, dataEncoding),
+		filepath:       path,
+		ingestionSlack: ingestionSlack,
+		flush:          flush,
+	}
+
+	blockStart := uint32(math.MaxUint32)
+	blockEnd := uint32(0)
+	b, warning, err := vparquet.OpenWALBlock(a.fullFilename(), dataEncoding, flush.policy != FlushPolicyNever, func(id common.ID, start, end uint32) {
+		start, end = a.adjustTimeRangeForSlack(start, end, additionalStartSlack)
+		if start < blockStart {
+			blockStart = start
+		}
+		if end > blockEnd {
+			blockEnd = end
+		}
+		a.meta.TotalObjects++
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a.parquet = b
+	a.meta.StartTime = time.Unix(int64(blockStart), 0)
+	a.meta.EndTime = time.Unix(int64(blockEnd), 0)
+
+	return a, warning, nil
+}
+
+func (a *AppendBlock) appendParquet(id common.ID, b []byte, start, end uint32) error {
+	err := a.parquet.Append(id, b)
+	if err != nil {
+		return err
+	}
+
+	a.parquetAppended(id, b, start, end)
+	a.countParquetLength()
+	return nil
+}
+
+// appendParquetBatch appends all objects of the batch or none of them
+func (a *AppendBlock) appendParquetBatch(ids []common.ID, objs [][]byte, starts, ends []uint32) error {
+	err := a.parquet.AppendBatch(ids, objs)
+	if err != nil {
+		return err
+	}
+
+	for i := range ids {
+		a.parquetAppended(ids[i], objs[i], starts[i], ends[i])
+	}
+	a.countParquetLength()
+	return nil
+}
+
+// AppendTraces adds multiple traces to a vParquet block without marshalling them into objects first, starts/ends
+// are the time ranges of the traces like in AppendBatch. The traces are only marshalled for the replicator.
+// Either all traces are appended or none, ErrWALFull is returned if the wal folder reached its max disk usage.
+// common.ErrUnsupported is returned for v2 blocks, see SupportsTraces.
+func (a *AppendBlock) AppendTraces(ids []common.ID, traces []*tempopb.Trace, starts, ends []uint32) error {
+	if a.parquet == nil {
+		return common.ErrUnsupported
+	}
+	if len(ids) != len(traces) || len(ids) != len(starts) || len(ids) != len(ends) {
+		return fmt.Errorf("mismatched batch lengths: %d ids, %d traces, %d starts, %d ends", len(ids), len(traces), len(starts), len(ends))
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	defer observeAppend(time.Now())
+
+	err := a.quota.check()
+	if err != nil {
+		return err
+	}
+
+	err = a.parquet.AppendTraceBatch(ids, traces)
+	if err != nil {
+		return err
+	}
+
+	size := 0
+	for i := range ids {
+		start, end := a.adjustTimeRangeForSlack(starts[i], ends[i], 0)
+		a.meta.ObjectAdded(ids[i], start, end)
+		a.ranges.add(ids[i], start, end)
+		size += len(ids[i]) + traces[i].Size()
+	}
+	a.replicateTraces(ids, traces, starts, ends)
+	metricAppendedBytes.WithLabelValues(a.meta.TenantID).Add(float64(size))
+	a.countParquetLength()
+	return nil
+}
+
+// SupportsTraces returns true if traces can be appended with AppendTraces
+func (a *AppendBlock) SupportsTraces() bool {
+	return a.parquet != nil
+}
+
+// traceToObject marshals a trace into an object of the data encoding for the replicator
+func traceToObject(dataEncoding string, trace *tempopb.Trace, start, end uint32) ([]byte, error) {
+	dec, err := model.NewSegmentDecoder(dataEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	segment, err := dec.PrepareForWrite(trace, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return dec.ToObject([][]byte{segment})
+}
+
+// countParquetLength counts the growth of the data length of the vParquet block in the quota. Buffered traces are
+// counted with their estimated size until they are flushed.
+func (a *AppendBlock) countParquetLength() {
+	if a.quota == nil {
+		return
+	}
+
+	length := a.parquet.DataLength()
+	if length > a.parquetLength {
+		a.quota.add(int(length - a.parquetLength))
+	}
+	a.parquetLength = length
+}
+
+func (a *AppendBlock) parquetAppended(id common.ID, b []byte, start, end uint32) {
+	start, end = a.adjustTimeRangeForSlack(start, end, 0)
+	a.meta.ObjectAdded(id, start, end)
+	a.ranges.add(id, start, end)
+	a.replicate([]common.ID{id}, [][]byte{b})
+	metricAppendedBytes.WithLabelValues(a.meta.TenantID).Add(float64(len(id) + len(b)))
+}
+
+// Search searches the objects of the block with the columnar readers. Only blocks with the vParquet version can
+// be searched, the search data of v2 blocks is kept in a separate streaming search block.
+func (a *AppendBlock) Search(ctx context.Context, req *tempopb.SearchRequest, opts common.SearchOptions) (*tempopb.SearchResponse, error) {
+	if a.parquet == nil {
+		return nil, common.ErrUnsupported
+	}
+	return a.parquet.Search(ctx, req, opts)
+}
+
+// SupportsSearch returns true if the block can be searched with Search
+func (a *AppendBlock) SupportsSearch() bool {
+	return a.parquet != nil
+}
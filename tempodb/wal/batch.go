@@ -0,0 +1,184 @@
+package wal
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// batchedMarker follows the encoding of the filename of v2 wal files with batched records, e.g.
+// <blockID>.<tenant>.v2.snappy+batch.v2. The pages of these files can hold several objects, so they are never replayed
+// as files with one object per page, e.g. by a release without batched records.
+const batchedMarker = "batch"
+
+type batchPolicy struct {
+	maxObjects    int
+	maxBytes      int
+	flushInterval time.Duration
+}
+
+// enabled returns true if appended objects are batched
+func (p batchPolicy) enabled() bool {
+	return p.maxObjects > 1 || p.maxBytes > 0
+}
+
+// parseBatched returns true if a wal filename has the batched marker
+func parseBatched(filename string) bool {
+	for _, flag := range encodingFlags(filename) {
+		if flag == batchedMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// encodingFlags returns the flags following the encoding of a wal filename, i.e. the id of the zstd dictionary and
+// the batched marker
+func encodingFlags(filename string) []string {
+	splits := strings.Split(filepath.Base(filename), ".")
+	if len(splits) < 4 {
+		return nil
+	}
+	encoding := splits[3]
+	// segment suffixes follow the data encoding
+	if len(splits) == 4 {
+		encoding, _, _ = strings.Cut(encoding, segmentSeparator)
+	}
+	return strings.Split(encoding, dictionarySeparator)[1:]
+}
+
+// recordBatch buffers the objects appended to a wal block and writes them as a single record once the batch holds
+// maxObjects objects or maxBytes bytes, or flushInterval after the first object was buffered.
+type recordBatch struct {
+	policy batchPolicy
+	write  func([]common.ID, [][]byte) error
+
+	mtx     sync.Mutex
+	ids     []common.ID
+	objs    [][]byte
+	size    int
+	pending *time.Timer
+	closed  bool
+	// err is the error of the last write on the timer, returned by the next call
+	err error
+}
+
+func newRecordBatch(policy batchPolicy, write func([]common.ID, [][]byte) error) *recordBatch {
+	return &recordBatch{
+		policy: policy,
+		write:  write,
+	}
+}
+
+// add buffers an object and writes the batch if it is full
+func (b *recordBatch) add(id common.ID, obj []byte) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+
+	b.ids = append(b.ids, id)
+	b.objs = append(b.objs, obj)
+	b.size += len(id) + len(obj)
+
+	if (b.policy.maxObjects > 0 && len(b.ids) >= b.policy.maxObjects) ||
+		(b.policy.maxBytes > 0 && b.size >= b.policy.maxBytes) {
+		return b.flushLocked()
+	}
+
+	// the first object of a batch schedules its write
+	if b.pending == nil && b.policy.flushInterval > 0 {
+		b.pending = time.AfterFunc(b.policy.flushInterval, b.flushPending)
+	}
+	return nil
+}
+
+// flush writes the buffered objects
+func (b *recordBatch) flush() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	return b.flushLocked()
+}
+
+// writeAll writes the buffered objects and then ids and objs as a single record
+func (b *recordBatch) writeAll(ids []common.ID, objs [][]byte) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	err := b.flushLocked()
+	if err != nil {
+		return err
+	}
+	return b.write(ids, objs)
+}
+
+func (b *recordBatch) flushPending() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.pending = nil
+	if b.closed {
+		return
+	}
+	b.err = b.flushLocked()
+}
+
+// flushLocked must be called with the lock held
+func (b *recordBatch) flushLocked() error {
+	if b.pending != nil {
+		b.pending.Stop()
+		b.pending = nil
+	}
+	if len(b.ids) == 0 {
+		return nil
+	}
+
+	ids, objs := b.ids, b.objs
+	b.ids, b.objs, b.size = nil, nil, 0
+
+	return b.write(ids, objs)
+}
+
+// takeErr must be called with the lock held
+func (b *recordBatch) takeErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
+
+// locked calls f with the size of the buffered objects. The batch isn't written while f runs.
+func (b *recordBatch) locked(f func(bufferedBytes int)) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	f(b.size)
+}
+
+// close stops writing the batch. Buffered objects are dropped.
+func (b *recordBatch) close() {
+	if b == nil {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.pending != nil {
+		b.pending.Stop()
+		b.pending = nil
+	}
+	b.ids, b.objs, b.size = nil, nil, 0
+	b.closed = true
+}
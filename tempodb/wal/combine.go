@@ -0,0 +1,139 @@
+package wal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/pkg/util/log"
+)
+
+var metricReplayCombinedObjects = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "wal_replay_combined_objects_total",
+	Help:      "The total number of duplicate objects combined while replaying the wal.",
+})
+
+// replayCombiner returns the combiner duplicate objects are combined with on replay, nil if they aren't
+func (c *Config) replayCombiner() model.ObjectCombiner {
+	if !c.ReplayCombineDuplicates {
+		return nil
+	}
+	if c.ReplayCombiner != nil {
+		return c.ReplayCombiner
+	}
+	return model.StaticCombiner
+}
+
+// duplicates returns the number of records of a replayed v2 block that share their id with the previous record
+func (a *AppendBlock) duplicates() int {
+	records := a.appender.Records()
+	duplicates := 0
+	for i := 1; i < len(records); i++ {
+		if bytes.Equal(records[i-1].ID, records[i].ID) {
+			duplicates++
+		}
+	}
+	return duplicates
+}
+
+// combineFile rewrites a replayed v2 block with the objects of every id combined and returns the rewritten block.
+// The new file is written and replayed in a separate folder and then moved over the first segment of the original,
+// so a failure before the move leaves the original intact. The time ranges are kept from the replayed block.
+func (w *WAL) combineFile(b *AppendBlock, combiner model.ObjectCombiner) (*AppendBlock, error) {
+	dir := filepath.Join(w.c.Filepath, transformDir)
+	err := w.c.FileSystem.MkdirAll(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	iter, err := b.iterator(combiner)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	combined, err := newAppendBlock(w.c.FileSystem, b.meta.BlockID, b.meta.TenantID, dir, b.meta.Encoding, b.meta.DataEncoding, w.c.IngestionSlack, w.c.Checksum, w.c.flushPolicy(), 0, batchPolicy{}, b.dictionary)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// clean up the partially written file on failure
+		if err != nil {
+			_ = combined.Clear()
+		}
+	}()
+
+	for {
+		var id, obj []byte
+		id, obj, err = iter.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("iterating replayed block: %w", err)
+		}
+
+		err = combined.appender.Append(append([]byte(nil), id...), obj)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = combined.appender.Complete()
+	if err != nil {
+		return nil, err
+	}
+	err = combined.appendFile.Close()
+	if err != nil {
+		return nil, err
+	}
+	combined.appendFile = nil
+
+	filename := filepath.Base(b.fullFilename())
+	replayed, _, err := newAppendBlockFromFile(w.c.FileSystem, filepath.Base(combined.fullFilename()), []int{0}, dir, w.c.IngestionSlack, 0, 1, w.c.MmapReads, b.dictionary, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sidecar doesn't match the combined objects
+	err = w.c.FileSystem.Remove(filepath.Join(w.c.Filepath, sidecarFilename(filename)))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// the original segments are closed before they are replaced
+	_ = b.data.Close()
+	segments := b.data.allSegments()
+	err = w.c.FileSystem.Rename(combined.fullFilename(), b.fullFilename())
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range segments[1:] {
+		removeErr := w.c.FileSystem.Remove(segmentFilename(b.fullFilename(), seg.index))
+		if removeErr != nil && !os.IsNotExist(removeErr) {
+			// the left over segments are replayed with the combined file and combined again on the next restart
+			level.Warn(log.Logger).Log("msg", "failed to remove wal segment after combining", "file", filename, "segment", seg.index, "err", removeErr)
+		}
+	}
+
+	// the combined file has a record per object, which is valid in files with batched records as well
+	replayed.filepath = w.c.Filepath
+	replayed.batched = b.batched
+	replayed.data = newSegmentReader(w.c.FileSystem, replayed.fullFilename(), []walSegment{{index: 0, start: 0}}, w.c.MmapReads)
+	replayed.meta.StartTime = b.meta.StartTime
+	replayed.meta.EndTime = b.meta.EndTime
+	b.ranges.mtx.RLock()
+	replayed.ranges.merge(b.ranges.ranges)
+	b.ranges.mtx.RUnlock()
+
+	return replayed, nil
+}
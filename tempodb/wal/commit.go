@@ -0,0 +1,118 @@
+package wal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb/backend/local"
+)
+
+// completingDir holds the blocks that are being completed. A block is only moved to the local backend once all of
+// its files are written and synced, so the blocks dir never holds a half-completed block.
+const completingDir = "completing"
+
+// CompletingBackend returns the local backend wal blocks are completed in. CommitBlock moves a completed block to
+// the local backend.
+func (w *WAL) CompletingBackend() *local.Backend {
+	return w.completing
+}
+
+// CommitBlock syncs a block completed in the completing backend and atomically renames it into the local backend.
+// A block with the same id already in the local backend, e.g. one recreated from a replayed wal, is replaced.
+func (w *WAL) CommitBlock(blockID uuid.UUID, tenantID string) error {
+	src := filepath.Join(w.c.Filepath, completingDir, tenantID, blockID.String())
+	dst := filepath.Join(w.c.BlocksFilepath, tenantID, blockID.String())
+
+	err := syncTree(src)
+	if err != nil {
+		return fmt.Errorf("syncing completed block %s: %w", blockID, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(dst), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(dst)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(src, dst)
+	if err != nil {
+		return fmt.Errorf("committing completed block %s: %w", blockID, err)
+	}
+
+	// the rename is only durable once both parents are synced
+	err = SyncDir(filepath.Dir(dst))
+	if err != nil {
+		return err
+	}
+	return SyncDir(filepath.Dir(src))
+}
+
+// recoverCompleting removes the blocks that were being completed when the process stopped. Their wal files are only
+// cleared after the block was committed, so they are replayed and completed again.
+func recoverCompleting(path string) error {
+	err := os.RemoveAll(path)
+	if err != nil {
+		return fmt.Errorf("removing half-completed blocks: %w", err)
+	}
+	return os.MkdirAll(path, os.ModePerm)
+}
+
+// syncTree syncs all files and folders below and including root
+func syncTree(root string) error {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs = append(dirs, path)
+			return nil
+		}
+		return syncFile(path)
+	})
+	if err != nil {
+		return err
+	}
+
+	// folders are synced after the files in them
+	for i := len(dirs) - 1; i >= 0; i-- {
+		err = SyncDir(dirs[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncFile(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	err = f.Sync()
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// SyncDir syncs a directory so the creation, rename or removal of its entries is durable
+func SyncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	closeErr := d.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
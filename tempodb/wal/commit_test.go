@@ -0,0 +1,91 @@
+package wal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+func TestCommitBlock(t *testing.T) {
+	tempDir := t.TempDir()
+
+	wal, err := New(&Config{
+		Filepath: tempDir,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	blockID := uuid.New()
+	w := backend.NewWriter(wal.CompletingBackend())
+
+	// a stale block with the same id, e.g. committed before the wal was cleared, is replaced
+	err = backend.NewWriter(wal.LocalBackend()).Write(ctx, "stale", blockID, testTenantID, []byte("stale"), false)
+	require.NoError(t, err)
+
+	err = w.Write(ctx, "data", blockID, testTenantID, []byte("data"), false)
+	require.NoError(t, err)
+	err = w.WriteBlockMeta(ctx, backend.NewBlockMeta(testTenantID, blockID, "v2", backend.EncNone, ""))
+	require.NoError(t, err)
+
+	// nothing is visible in the local backend until the block is committed
+	_, err = backend.NewReader(wal.LocalBackend()).BlockMeta(ctx, blockID, testTenantID)
+	require.Equal(t, backend.ErrDoesNotExist, err)
+
+	err = wal.CommitBlock(blockID, testTenantID)
+	require.NoError(t, err)
+
+	r := backend.NewReader(wal.LocalBackend())
+	meta, err := r.BlockMeta(ctx, blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, blockID, meta.BlockID)
+
+	data, err := r.Read(ctx, "data", blockID, testTenantID, false)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal([]byte("data"), data))
+
+	_, err = r.Read(ctx, "stale", blockID, testTenantID, false)
+	assert.Equal(t, backend.ErrDoesNotExist, err)
+
+	_, err = os.Stat(filepath.Join(tempDir, completingDir, testTenantID, blockID.String()))
+	assert.True(t, os.IsNotExist(err))
+
+	// committing a block that wasn't completed fails
+	assert.Error(t, wal.CommitBlock(uuid.New(), testTenantID))
+}
+
+func TestHalfCompletedBlocksAreRemoved(t *testing.T) {
+	tempDir := t.TempDir()
+
+	wal, err := New(&Config{
+		Filepath: tempDir,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	blockID := uuid.New()
+	err = backend.NewWriter(wal.CompletingBackend()).Write(ctx, "data", blockID, testTenantID, []byte("data"), false)
+	require.NoError(t, err)
+
+	// the block was never committed, restarting the wal removes it
+	_, err = New(&Config{
+		Filepath: tempDir,
+	})
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(tempDir, completingDir, testTenantID))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(tempDir, completingDir))
+	assert.NoError(t, err)
+
+	blocks, err := os.ReadDir(filepath.Join(tempDir, blocksDir))
+	require.NoError(t, err)
+	assert.Empty(t, blocks)
+}
@@ -0,0 +1,321 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/util/log"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+)
+
+const (
+	// dictionariesDir is the folder in the wal holding the zstd dictionaries of the tenants
+	dictionariesDir = "dictionaries"
+	// dictionarySeparator separates the encoding of a wal filename from the id of its zstd dictionary and the
+	// batchedMarker, e.g. <blockID>.<tenant>.v2.zstd+8000a1b2.v2
+	dictionarySeparator = "+"
+)
+
+var metricDictionariesTrained = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "wal_zstd_dictionaries_trained_total",
+	Help:      "The total number of zstd dictionaries trained for wal blocks per tenant.",
+}, []string{"tenant"})
+
+// ZstdDictionaryConfig trains a zstd dictionary per tenant from the first objects appended to its zstd wal blocks.
+// Blocks created after the dictionary is trained are compressed with it, which improves the compression of the
+// small pages written for every append. Dictionaries are stored in the wal folder and loaded to replay the blocks
+// compressed with them.
+type ZstdDictionaryConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// SampleBytes is how many bytes of appended objects are sampled to train the dictionary of a tenant
+	SampleBytes int `yaml:"sample_bytes"`
+	// MaxSizeBytes is the max size of the content of a dictionary
+	MaxSizeBytes int `yaml:"max_size_bytes"`
+}
+
+// zstdDictionary is a trained dictionary the wal blocks of a tenant are compressed with
+type zstdDictionary struct {
+	id   uint32
+	pool *v2.ZstdDictPool
+}
+
+// ID returns the id of the dictionary, 0 if d is nil
+func (d *zstdDictionary) ID() uint32 {
+	if d == nil {
+		return 0
+	}
+	return d.id
+}
+
+// dictionaryStore trains, persists and loads the zstd dictionaries of the tenants
+type dictionaryStore struct {
+	fs  FileSystem
+	dir string
+	cfg ZstdDictionaryConfig
+
+	mtx     sync.Mutex
+	current map[string]*zstdDictionary
+	loaded  map[string]map[uint32]*zstdDictionary
+	// samples are the objects sampled per tenant until its dictionary is trained
+	samples     map[string][][]byte
+	sampleBytes map[string]int
+}
+
+func newDictionaryStore(fs FileSystem, walPath string, cfg ZstdDictionaryConfig) *dictionaryStore {
+	return &dictionaryStore{
+		fs:          fs,
+		dir:         filepath.Join(walPath, dictionariesDir),
+		cfg:         cfg,
+		current:     map[string]*zstdDictionary{},
+		loaded:      map[string]map[uint32]*zstdDictionary{},
+		samples:     map[string][][]byte{},
+		sampleBytes: map[string]int{},
+	}
+}
+
+// forNewBlock returns the dictionary a new block of the tenant is compressed with, nil if the block is compressed
+// without one. If the dictionary of the tenant isn't trained yet, sample is set and must be called with the objects
+// appended to the block.
+func (s *dictionaryStore) forNewBlock(tenantID string, e backend.Encoding) (dict *zstdDictionary, sample func(obj []byte)) {
+	if s == nil || !s.cfg.Enabled || e != backend.EncZstd {
+		return nil, nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if d, ok := s.current[tenantID]; ok {
+		return d, nil
+	}
+	return nil, func(obj []byte) { s.sample(tenantID, obj) }
+}
+
+// sample keeps a copy of the object and trains the dictionary of the tenant once enough objects were sampled
+func (s *dictionaryStore) sample(tenantID string, obj []byte) {
+	if IsTombstone(obj) {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.current[tenantID]; ok {
+		return
+	}
+
+	s.samples[tenantID] = append(s.samples[tenantID], append([]byte(nil), obj...))
+	s.sampleBytes[tenantID] += len(obj)
+	if s.sampleBytes[tenantID] < s.cfg.SampleBytes {
+		return
+	}
+
+	samples := s.samples[tenantID]
+	delete(s.samples, tenantID)
+	delete(s.sampleBytes, tenantID)
+
+	d, err := s.train(tenantID, samples)
+	if err != nil {
+		// objects are sampled again for the next attempt
+		level.Warn(log.Logger).Log("msg", "failed to train wal zstd dictionary", "tenant", tenantID, "err", err)
+		return
+	}
+	s.current[tenantID] = d
+	metricDictionariesTrained.WithLabelValues(tenantID).Inc()
+}
+
+// train builds a dictionary from the samples and persists it. It must be called with the lock held.
+func (s *dictionaryStore) train(tenantID string, samples [][]byte) (*zstdDictionary, error) {
+	dict, err := v2.BuildZstdDictionary(samples, s.cfg.MaxSizeBytes)
+	if err != nil {
+		return nil, err
+	}
+	id, err := v2.ZstdDictionaryID(dict)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := v2.NewZstdDictPool(dict)
+	if err != nil {
+		return nil, err
+	}
+
+	// the dictionary is synced before any block references it
+	dir := filepath.Join(s.dir, tenantID)
+	err = s.fs.MkdirAll(dir)
+	if err != nil {
+		return nil, err
+	}
+	tmp := filepath.Join(dir, dictionaryFilename(id)+".tmp")
+	f, err := s.fs.Create(tmp)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write(dict)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = s.fs.Rename(tmp, filepath.Join(dir, dictionaryFilename(id)))
+	}
+	if err != nil {
+		_ = s.fs.Remove(tmp)
+		return nil, err
+	}
+
+	d := &zstdDictionary{id: id, pool: pool}
+	s.addLoaded(tenantID, d)
+	return d, nil
+}
+
+// load returns the dictionary of the tenant with the id, reading it from the wal folder if needed
+func (s *dictionaryStore) load(tenantID string, id uint32) (*zstdDictionary, error) {
+	if s == nil {
+		return nil, fmt.Errorf("zstd dictionary %08x of tenant %s not found", id, tenantID)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if d, ok := s.loaded[tenantID][id]; ok {
+		return d, nil
+	}
+
+	f, err := s.fs.Open(filepath.Join(s.dir, tenantID, dictionaryFilename(id)))
+	if err != nil {
+		return nil, fmt.Errorf("opening zstd dictionary %08x of tenant %s: %w", id, tenantID, err)
+	}
+	defer f.Close()
+	dict, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	dictID, err := v2.ZstdDictionaryID(dict)
+	if err != nil {
+		return nil, err
+	}
+	if dictID != id {
+		return nil, fmt.Errorf("zstd dictionary file %08x of tenant %s has id %08x", id, tenantID, dictID)
+	}
+	pool, err := v2.NewZstdDictPool(dict)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &zstdDictionary{id: id, pool: pool}
+	s.addLoaded(tenantID, d)
+	return d, nil
+}
+
+// forFilename returns the dictionary the wal file is compressed with, nil if it was compressed without one
+func (s *dictionaryStore) forFilename(filename string) (*zstdDictionary, error) {
+	id, err := parseDictionaryID(filename)
+	if err != nil || id == 0 {
+		return nil, err
+	}
+	_, tenantID, _, _, _, err := ParseFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+	return s.load(tenantID, id)
+}
+
+func (s *dictionaryStore) addLoaded(tenantID string, d *zstdDictionary) {
+	if s.loaded[tenantID] == nil {
+		s.loaded[tenantID] = map[uint32]*zstdDictionary{}
+	}
+	s.loaded[tenantID][d.id] = d
+}
+
+// removeUnused removes the dictionaries no wal file in names is compressed with, except the current ones
+func (s *dictionaryStore) removeUnused(names []string) error {
+	used := map[string]struct{}{}
+	for _, name := range names {
+		id, err := parseDictionaryID(name)
+		if err != nil || id == 0 {
+			continue
+		}
+		if _, tenantID, _, _, _, err := ParseFilename(name); err == nil {
+			used[filepath.Join(tenantID, dictionaryFilename(id))] = struct{}{}
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for tenantID, d := range s.current {
+		used[filepath.Join(tenantID, dictionaryFilename(d.id))] = struct{}{}
+	}
+
+	tenants, err := s.fs.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		if !tenant.IsDir() {
+			continue
+		}
+		files, err := s.fs.ReadDir(filepath.Join(s.dir, tenant.Name()))
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			name := filepath.Join(tenant.Name(), f.Name())
+			if _, ok := used[name]; ok {
+				continue
+			}
+			err = s.fs.Remove(filepath.Join(s.dir, name))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if id, err := strconv.ParseUint(f.Name(), 16, 32); err == nil {
+				delete(s.loaded[tenant.Name()], uint32(id))
+			}
+		}
+	}
+	return nil
+}
+
+func dictionaryFilename(id uint32) string {
+	return fmt.Sprintf("%08x", id)
+}
+
+// parseDictionaryID returns the id of the zstd dictionary in a wal filename, 0 if there is none
+func parseDictionaryID(filename string) (uint32, error) {
+	for _, flag := range encodingFlags(filename) {
+		if flag == batchedMarker {
+			continue
+		}
+		parsed, err := strconv.ParseUint(flag, 16, 32)
+		if err != nil || parsed == 0 {
+			return 0, fmt.Errorf("unable to parse %s. error parsing zstd dictionary id", filename)
+		}
+		return uint32(parsed), nil
+	}
+	return 0, nil
+}
+
+// newDataReader returns a reader of the pages of a v2 wal file, decompressed with the dictionary if it isn't nil
+func newDataReader(r backend.AllReader, e backend.Encoding, dict *zstdDictionary) (common.DataReader, error) {
+	if dict != nil {
+		return v2.NewDataReaderWithPool(backend.NewContextReaderWithAllReader(r), dict.pool), nil
+	}
+	return v2.NewDataReader(backend.NewContextReaderWithAllReader(r), e)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package wal
+
+import (
+	"errors"
+	"os"
+)
+
+// fallocate is only supported on linux
+func fallocate(*os.File, int64, int64) error {
+	return errors.New("preallocating wal files is only supported on linux")
+}
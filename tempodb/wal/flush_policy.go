@@ -0,0 +1,130 @@
+package wal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricSyncDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempo",
+		Name:      "wal_fsync_duration_seconds",
+		Help:      "Duration of syncing wal files to disk.",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 4, 8),
+	})
+	metricSyncFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "wal_fsync_failures_total",
+		Help:      "The total number of failed syncs of wal files to disk.",
+	})
+)
+
+type flushPolicy struct {
+	policy   string
+	interval time.Duration
+}
+
+// fileSyncer syncs the appended records of a wal file to disk following the flush policy.
+type fileSyncer struct {
+	policy flushPolicy
+
+	mtx     sync.Mutex
+	f       File
+	pending *time.Timer
+}
+
+func newFileSyncer(f File, policy flushPolicy) *fileSyncer {
+	return &fileSyncer{
+		policy: policy,
+		f:      f,
+	}
+}
+
+// appended is called after records are appended to the file
+func (s *fileSyncer) appended() error {
+	switch s.policy.policy {
+	case FlushPolicyAlways:
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		return s.sync()
+	case FlushPolicyInterval:
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		// the first append after a sync schedules the next one
+		if s.pending == nil && s.f != nil {
+			s.pending = time.AfterFunc(s.policy.interval, s.syncPending)
+		}
+	}
+	return nil
+}
+
+func (s *fileSyncer) syncPending() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.pending = nil
+	// errors are counted in sync, the next append schedules another attempt
+	_ = s.sync()
+}
+
+// syncNow syncs the appended records regardless of the flush policy
+func (s *fileSyncer) syncNow() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.pending != nil {
+		s.pending.Stop()
+		s.pending = nil
+	}
+	return s.sync()
+}
+
+// sync must be called with the lock held
+func (s *fileSyncer) sync() error {
+	if s.f == nil {
+		return nil
+	}
+
+	start := time.Now()
+	err := s.f.Sync()
+	metricSyncDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metricSyncFailures.Inc()
+	}
+	return err
+}
+
+// syncAndClose syncs the records appended since the last sync with the interval policy and stops syncing. It is
+// called when no more records are appended to the file.
+func (s *fileSyncer) syncAndClose() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var err error
+	if s.pending != nil {
+		s.pending.Stop()
+		s.pending = nil
+		err = s.sync()
+	}
+	s.f = nil
+	return err
+}
+
+// close stops syncing before the file is closed
+func (s *fileSyncer) close() {
+	if s == nil {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.pending != nil {
+		s.pending.Stop()
+		s.pending = nil
+	}
+	s.f = nil
+}
@@ -0,0 +1,71 @@
+package wal
+
+import (
+	"io"
+	"os"
+)
+
+// File is a wal file opened through a FileSystem
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.Closer
+
+	// Sync commits the data written to the file to stable storage
+	Sync() error
+	Stat() (os.FileInfo, error)
+}
+
+// FileSystem is the storage the wal files of v2 blocks are written to and replayed from. Names are paths as used by
+// the os package. Implementations allow alternative wal targets, e.g. replicated tmpfs or object storage. vParquet
+// wal blocks, search data and completed blocks are always stored on the local disk.
+type FileSystem interface {
+	// Create creates or truncates the named file and opens it for appending
+	Create(name string) (File, error)
+	// Open opens the named file for reading
+	Open(name string) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	Remove(name string) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	MkdirAll(path string) error
+	ReadDir(name string) ([]os.DirEntry, error)
+}
+
+// OSFileSystem is the default FileSystem. It stores wal files on the local disk.
+type OSFileSystem struct{}
+
+var _ FileSystem = OSFileSystem{}
+
+func (OSFileSystem) Create(name string) (File, error) {
+	return os.OpenFile(name, os.O_APPEND|os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+func (OSFileSystem) Open(name string) (File, error) {
+	return os.OpenFile(name, os.O_RDONLY, 0644)
+}
+
+func (OSFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (OSFileSystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (OSFileSystem) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (OSFileSystem) MkdirAll(path string) error {
+	return os.MkdirAll(path, os.ModePerm)
+}
+
+func (OSFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
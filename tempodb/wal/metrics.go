@@ -0,0 +1,54 @@
+package wal
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	metricAppendedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "wal_appended_bytes_total",
+		Help:      "The total number of bytes of ids and objects appended to the wal per tenant.",
+	}, []string{"tenant"})
+	metricAppendDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempo",
+		Name:      "wal_append_duration_seconds",
+		Help:      "Duration of appending objects to a wal block, including syncing and rotating segments.",
+		Buckets:   prometheus.ExponentialBuckets(0.00005, 4, 9),
+	})
+	metricReplayDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "tempo",
+		Name:      "wal_replay_duration_seconds",
+		Help:      "Duration of replaying a wal block.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+	})
+	metricBlocksOutstanding = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "wal_blocks_outstanding",
+		Help:      "The number of wal blocks created or replayed and not cleared yet per tenant.",
+	}, []string{"tenant"})
+)
+
+func observeAppend(began time.Time) {
+	metricAppendDuration.Observe(time.Since(began).Seconds())
+}
+
+// trackOutstanding counts the block as outstanding until it is cleared
+func (a *AppendBlock) trackOutstanding() {
+	if a.outstanding {
+		return
+	}
+	a.outstanding = true
+	metricBlocksOutstanding.WithLabelValues(a.meta.TenantID).Inc()
+}
+
+func (a *AppendBlock) untrackOutstanding() {
+	if !a.outstanding {
+		return
+	}
+	a.outstanding = false
+	metricBlocksOutstanding.WithLabelValues(a.meta.TenantID).Dec()
+}
@@ -0,0 +1,130 @@
+package wal
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/edsrzf/mmap-go"
+)
+
+var errMmapReadOnly = errors.New("memory mapped wal files are read only")
+
+// mmapFile is a wal file that is memory mapped for reading. Pages read sequentially, e.g. on replay, are slices of
+// the mapping, see Next. ReadAt copies from the page cache like any io.ReaderAt, but without a read syscall.
+type mmapFile struct {
+	f      *os.File
+	data   mmap.MMap
+	offset int64
+}
+
+var _ File = (*mmapFile)(nil)
+
+// mapFile memory maps a wal file that isn't appended to anymore. Files that aren't on the local disk and empty files
+// can't be mapped and are returned as is.
+func mapFile(f File) (File, error) {
+	osFile, ok := f.(*os.File)
+	if !ok {
+		return f, nil
+	}
+
+	info, err := osFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return f, nil
+	}
+
+	data, err := mmap.Map(osFile, mmap.RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &mmapFile{
+		f:    osFile,
+		data: data,
+	}, nil
+}
+
+// openFile opens a wal file for reading and memory maps it if mapped is set
+func openFile(fs FileSystem, name string, mapped bool) (File, error) {
+	f, err := fs.Open(name)
+	if err != nil || !mapped {
+		return f, err
+	}
+
+	m, err := mapFile(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Read implements io.Reader
+func (m *mmapFile) Read(p []byte) (int, error) {
+	n, err := m.ReadAt(p, m.offset)
+	m.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Next returns the next n bytes of the file and advances the offset like Read. The bytes aren't copied, they are a
+// slice of the read only mapping that is valid until the file is closed.
+func (m *mmapFile) Next(n int) ([]byte, error) {
+	if m.offset >= int64(len(m.data)) {
+		return nil, io.EOF
+	}
+
+	end := m.offset + int64(n)
+	if end > int64(len(m.data)) {
+		end = int64(len(m.data))
+	}
+	// appending to the slice must not write into the mapping
+	b := m.data[m.offset:end:end]
+	m.offset = end
+	if len(b) < n {
+		return b, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
+
+// ReadAt implements io.ReaderAt
+func (m *mmapFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *mmapFile) Write([]byte) (int, error) {
+	return 0, errMmapReadOnly
+}
+
+func (m *mmapFile) Sync() error {
+	return nil
+}
+
+func (m *mmapFile) Stat() (os.FileInfo, error) {
+	return m.f.Stat()
+}
+
+// Close unmaps and closes the file
+func (m *mmapFile) Close() error {
+	err := m.data.Unmap()
+	closeErr := m.f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package wal
+
+import (
+	"os"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/util/log"
+)
+
+var metricPreallocationFailures = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "wal_preallocation_failures_total",
+	Help:      "The total number of wal files that could not be preallocated.",
+})
+
+// preallocate reserves disk space for the next n bytes written to the segment in chunks of preallocateBytes. The
+// space is reserved past the end of the file, so the size of the file is the length of its data for replays and
+// the disk quota. Failing to preallocate doesn't fail the write, the rest of the segment isn't preallocated then.
+// Only files on the local disk are preallocated.
+func (w *segmentWriter) preallocate(n int) {
+	if w.preallocateBytes == 0 || w.preallocateFailed || w.written+uint64(n) <= w.allocated {
+		return
+	}
+
+	f, ok := w.f.(*os.File)
+	if !ok {
+		w.preallocateFailed = true
+		return
+	}
+
+	end := (((w.written + uint64(n)) / w.preallocateBytes) + 1) * w.preallocateBytes
+	err := fallocate(f, int64(w.allocated), int64(end-w.allocated))
+	if err != nil {
+		level.Warn(log.Logger).Log("msg", "failed to preallocate wal file", "file", f.Name(), "err", err)
+		metricPreallocationFailures.Inc()
+		w.preallocateFailed = true
+		return
+	}
+	w.allocated = end
+}
+
+// truncate releases the space preallocated past the data of the segment
+func (w *segmentWriter) truncate() error {
+	if w.allocated == 0 {
+		return nil
+	}
+
+	f, ok := w.f.(*os.File)
+	if !ok {
+		return nil
+	}
+
+	err := f.Truncate(int64(w.written))
+	if err != nil {
+		return err
+	}
+	w.allocated = 0
+	return nil
+}
+
+// reset continues preallocating in a new segment file
+func (w *segmentWriter) reset(f File) {
+	w.f = f
+	w.written = 0
+	w.allocated = 0
+	w.preallocateFailed = false
+}
@@ -0,0 +1,62 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/grafana/tempo/pkg/model"
+)
+
+func TestPreallocate(t *testing.T) {
+	const preallocate = 1024 * 1024
+
+	dir := t.TempDir()
+	f, err := os.Create(dir + "/probe")
+	require.NoError(t, err)
+	err = fallocate(f, 0, preallocate)
+	_ = f.Close()
+	if errors.Is(err, unix.EOPNOTSUPP) {
+		t.Skip("the filesystem of the temp dir doesn't support fallocate")
+	}
+	require.NoError(t, err)
+
+	wal, err := New(&Config{
+		Filepath:         dir,
+		PreallocateBytes: preallocate,
+	})
+	require.NoError(t, err)
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, model.CurrentEncoding)
+	require.NoError(t, err)
+
+	obj := make([]byte, 10*1024)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, block.Append([]byte{byte(i)}, obj, 0, 0))
+	}
+
+	// the file is as large as its data but holds the preallocated space
+	size, allocated := fileSpace(t, block.fullFilename())
+	assert.Equal(t, int64(block.writer.written), size)
+	assert.GreaterOrEqual(t, allocated, int64(preallocate))
+
+	// completing the block releases the space left over
+	_, err = block.Iterator(&mockCombiner{})
+	require.NoError(t, err)
+
+	size, allocated = fileSpace(t, block.fullFilename())
+	assert.Less(t, allocated, int64(preallocate))
+	assert.Less(t, size, int64(preallocate))
+}
+
+func fileSpace(t *testing.T, name string) (size int64, allocated int64) {
+	info, err := os.Stat(name)
+	require.NoError(t, err)
+	return info.Size(), info.Sys().(*syscall.Stat_t).Blocks * 512
+}
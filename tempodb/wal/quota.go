@@ -0,0 +1,148 @@
+package wal
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrWALFull is returned by Append when the wal folder uses more than the max disk usage
+var ErrWALFull = errors.New("wal disk usage limit reached")
+
+// diskUsageRefresh is how often the size of the wal folder is measured. Bytes written in between are added to the
+// last measurement, space freed in between is noticed with the next measurement.
+const diskUsageRefresh = 5 * time.Second
+
+var (
+	metricDiskUsage = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "wal_disk_usage_bytes",
+		Help:      "The measured size of the wal folder.",
+	})
+	metricAppendsRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "wal_appends_rejected_total",
+		Help:      "The total number of appends rejected because the wal reached its max disk usage.",
+	})
+)
+
+// diskQuota limits the disk usage of the wal folder. The bytes written to the wal files are tracked on write and the
+// folder is measured in the background, appends never wait for a measurement. A nil quota is unlimited.
+type diskQuota struct {
+	path string
+	max  uint64
+
+	mtx        sync.Mutex
+	measured   uint64
+	measuredAt time.Time
+	measuring  bool
+	// written is the number of bytes written since the last measurement started
+	written uint64
+	// rejected is set if an append was rejected since the last measurement
+	rejected bool
+}
+
+func newDiskQuota(path string, max uint64) *diskQuota {
+	if max == 0 {
+		return nil
+	}
+	q := &diskQuota{
+		path: path,
+		max:  max,
+	}
+	q.measure()
+	return q
+}
+
+// check returns ErrWALFull if the wal folder reached its max disk usage. The bytes of an accepted append are
+// counted once they are written, an append can exceed the max by its own size.
+func (q *diskQuota) check() error {
+	if q == nil {
+		return nil
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.refresh()
+	if q.measured+q.written >= q.max {
+		q.rejected = true
+		metricAppendsRejected.Inc()
+		return ErrWALFull
+	}
+	return nil
+}
+
+// add counts n bytes written to the wal folder
+func (q *diskQuota) add(n int) {
+	if q == nil || n <= 0 {
+		return
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.written += uint64(n)
+}
+
+// full returns true if the wal folder uses the max disk usage or appends were rejected since the last measurement
+func (q *diskQuota) full() bool {
+	if q == nil {
+		return false
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.refresh()
+	return q.rejected || q.measured+q.written >= q.max
+}
+
+// refresh measures the wal folder in the background if the last measurement is outdated. It must be called with
+// the lock held.
+func (q *diskQuota) refresh() {
+	if q.measuring || time.Since(q.measuredAt) < diskUsageRefresh {
+		return
+	}
+
+	q.measuring = true
+	go q.measure()
+}
+
+// measure walks the wal folder without holding the lock. Bytes written during the walk may be counted twice until
+// the next measurement, the usage is never underestimated.
+func (q *diskQuota) measure() {
+	q.mtx.Lock()
+	q.measuring = true
+	writtenBefore := q.written
+	q.mtx.Unlock()
+
+	var size uint64
+	// files can be removed while walking, errors are ignored and the file is not counted
+	_ = filepath.WalkDir(q.path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		size += uint64(info.Size())
+		return nil
+	})
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	q.measured = size
+	q.measuredAt = time.Now()
+	q.measuring = false
+	q.written -= writtenBefore
+	q.rejected = false
+	metricDiskUsage.Set(float64(size))
+}
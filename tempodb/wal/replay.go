@@ -1,71 +1,147 @@
 package wal
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 )
 
-// ReplayWALAndGetRecords replays a WAL file that could contain either traces or searchdata
-func ReplayWALAndGetRecords(file *os.File, enc backend.Encoding, handleObj func([]byte) error) ([]common.Record, error, error) {
-	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(file), enc)
+var metricCorruptRecords = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "wal_corrupt_records_total",
+	Help:      "The total number of records skipped while replaying the wal because they failed their checksum.",
+})
+
+// ReplayWALAndGetRecords replays a WAL file that could contain either traces or searchdata. The objects are
+// decoded in place, the slice passed to handleObj is only valid for the duration of the call.
+// Records written with a checksum that fail verification are skipped and returned as a warning.
+func ReplayWALAndGetRecords(file backend.AllReader, enc backend.Encoding, handleObj func([]byte) error) ([]common.Record, error, error) {
+	return replayWALRecords(file, enc, nil, false, func(_ []byte, obj []byte) error {
+		return handleObj(obj)
+	})
+}
+
+// replayWALRecords is ReplayWALAndGetRecords passing the id of every object to handleObj. The id is only valid for
+// the duration of the call. Files compressed with a zstd dictionary are read with dict. batched is set for files with
+// batched records, see walkPage.
+func replayWALRecords(file backend.AllReader, enc backend.Encoding, dict *zstdDictionary, batched bool, handleObj func(id []byte, obj []byte) error) ([]common.Record, error, error) {
+	var records []common.Record
+	// ids of the current page, a page written by a batch has a single record per distinct id
+	pageStart := uint64(0)
+	pageIDs := map[string]struct{}{}
+	corrupt, warning, err := walkRecords(file, enc, dict, batched, func(id []byte, obj []byte, start uint64, length uint32) error {
+		// handleObj is primarily used by search replay to record search data in block header
+		err := handleObj(id, obj)
+		if err != nil {
+			return fmt.Errorf("custom obj handler while replaying wal: %w", err)
+		}
+
+		if start != pageStart || len(records) == 0 {
+			pageStart = start
+			pageIDs = map[string]struct{}{}
+		}
+		if _, ok := pageIDs[string(id)]; ok {
+			return nil
+		}
+		pageIDs[string(id)] = struct{}{}
+
+		// make a copy so we don't hold onto the page buffer
+		recordID := append([]byte(nil), id...)
+		records = append(records, common.Record{
+			ID:     recordID,
+			Start:  start,
+			Length: length,
+		})
+		return nil
+	})
 	if err != nil {
 		return nil, nil, err
 	}
 
+	metricCorruptRecords.Add(float64(corrupt))
+	if corrupt > 0 && warning == nil {
+		warning = fmt.Errorf("skipped %d records with checksum mismatch while replaying wal", corrupt)
+	}
+
+	common.SortRecords(records)
+
+	return records, warning, nil
+}
+
+// walkRecords reads the records of a WAL file in order and validates their framing and checksums. handleRecord is
+// called with every object of a valid record, id and obj are only valid for the duration of the call. The objects of
+// a record written by a batch are passed with the same start and length, files without batched records must have a
+// single object per record. Records that fail their checksum are skipped and counted. A framing error, or an error
+// returned by handleRecord, stops the walk and is returned as a warning.
+func walkRecords(file backend.AllReader, enc backend.Encoding, dict *zstdDictionary, batched bool, handleRecord func(id []byte, obj []byte, start uint64, length uint32) error) (int, error, error) {
+	dataReader, err := newDataReader(file, enc, dict)
+	if err != nil {
+		return 0, nil, err
+	}
+
 	var buffer []byte
-	var records []common.Record
 	var warning error
 	var pageLen uint32
-	var id []byte
 	objectReader := v2.NewObjectReaderWriter()
 	currentOffset := uint64(0)
+	corrupt := 0
 	for {
 		buffer, pageLen, err = dataReader.NextPage(buffer)
 		if err == io.EOF {
 			break
 		}
+		if errors.Is(err, v2.ErrChecksumMismatch) {
+			corrupt++
+			currentOffset += uint64(pageLen)
+			continue
+		}
 		if err != nil {
 			warning = fmt.Errorf("accessing NextPage while replaying wal: %w", err)
 			break
 		}
 
-		reader := bytes.NewReader(buffer)
-		id, buffer, err = objectReader.UnmarshalObjectFromReader(reader)
-		if err != nil {
-			warning = fmt.Errorf("unmarshalling object while replaying wal: %w", err)
-			break
-		}
-		// wal should only ever have one object per page, test that here
-		_, _, err = objectReader.UnmarshalObjectFromReader(reader)
-		if err != io.EOF {
-			warning = fmt.Errorf("expected EOF while replaying wal: %w", err)
+		warning = walkPage(buffer, objectReader, batched, func(id []byte, obj []byte) error {
+			return handleRecord(id, obj, currentOffset, pageLen)
+		})
+		if warning != nil {
 			break
 		}
+		currentOffset += uint64(pageLen)
+	}
 
-		// handleObj is primarily used by search replay to record search data in block header
-		err = handleObj(buffer)
-		if err != nil {
-			warning = fmt.Errorf("custom obj handler while replaying wal: %w", err)
-			break
-		}
+	return corrupt, warning, nil
+}
 
-		// make a copy so we don't hold onto the iterator buffer
-		recordID := append([]byte(nil), id...)
-		records = append(records, common.Record{
-			ID:     recordID,
-			Start:  currentOffset,
-			Length: pageLen,
-		})
-		currentOffset += uint64(pageLen)
+// walkPage calls handleObj with every object of a page. A page holds at least one object, only pages of files
+// written with batched records can hold more than one.
+func walkPage(page []byte, objectReader common.ObjectReaderWriter, batched bool, handleObj func(id []byte, obj []byte) error) error {
+	rest, id, obj, err := objectReader.UnmarshalAndAdvanceBuffer(page)
+	if err != nil {
+		return fmt.Errorf("unmarshalling object while replaying wal: %w", err)
 	}
 
-	common.SortRecords(records)
+	for {
+		err = handleObj(id, obj)
+		if err != nil {
+			return err
+		}
 
-	return records, warning, nil
+		rest, id, obj, err = objectReader.UnmarshalAndAdvanceBuffer(rest)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unmarshalling object while replaying wal: %w", err)
+		}
+		if !batched {
+			return errors.New("unexpected object after the first object of a page in a wal file without batched records")
+		}
+	}
 }
@@ -0,0 +1,141 @@
+package wal
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var metricReplicationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "wal_replication_failures_total",
+	Help:      "The total number of objects appended to the wal that failed to replicate.",
+}, []string{"tenant"})
+
+// Replicator receives every object appended to the wal, e.g. to stream wal writes to a secondary site or a Kafka
+// topic for disaster recovery of traces that haven't been flushed yet. Objects are replicated asynchronously by a
+// single goroutine, in the order they were written to the wal. Objects buffered by a batch are replicated once the
+// batch is written. Replicate must not modify the id and object. A failed replication is counted but doesn't fail
+// the append, the object is already in the wal. Tombstones are replicated as the deleted id with an object
+// IsTombstone returns true for.
+type Replicator interface {
+	Replicate(tenantID string, id common.ID, obj []byte) error
+}
+
+// ReplicatorFunc adapts a func to a Replicator
+type ReplicatorFunc func(tenantID string, id common.ID, obj []byte) error
+
+// Replicate implements Replicator
+func (f ReplicatorFunc) Replicate(tenantID string, id common.ID, obj []byte) error {
+	return f(tenantID, id, obj)
+}
+
+// replicationQueueSize is the number of written objects waiting for replication. Writes wait for the replicator
+// once the queue is full.
+const replicationQueueSize = 1024
+
+// replicatedObject is an object or a trace of a vParquet block waiting for replication. Traces are marshalled into
+// objects of the data encoding before they are replicated.
+type replicatedObject struct {
+	tenantID string
+	id       common.ID
+	obj      []byte
+
+	trace        *tempopb.Trace
+	dataEncoding string
+	start, end   uint32
+}
+
+// replicationQueue passes the objects written to the wal to the replicator in order
+type replicationQueue struct {
+	replicator Replicator
+	objects    chan replicatedObject
+
+	mtx     sync.Mutex
+	cond    *sync.Cond
+	pending int
+}
+
+func newReplicationQueue(r Replicator) *replicationQueue {
+	q := &replicationQueue{
+		replicator: r,
+		objects:    make(chan replicatedObject, replicationQueueSize),
+	}
+	q.cond = sync.NewCond(&q.mtx)
+	go q.run()
+	return q
+}
+
+func (q *replicationQueue) run() {
+	for o := range q.objects {
+		var err error
+		if o.trace != nil {
+			o.obj, err = traceToObject(o.dataEncoding, o.trace, o.start, o.end)
+		}
+		if err == nil {
+			err = q.replicator.Replicate(o.tenantID, o.id, o.obj)
+		}
+		if err != nil {
+			metricReplicationFailures.WithLabelValues(o.tenantID).Inc()
+		}
+
+		q.mtx.Lock()
+		q.pending--
+		if q.pending == 0 {
+			q.cond.Broadcast()
+		}
+		q.mtx.Unlock()
+	}
+}
+
+// add queues an object for replication
+func (q *replicationQueue) add(o replicatedObject) {
+	q.mtx.Lock()
+	q.pending++
+	q.mtx.Unlock()
+
+	q.objects <- o
+}
+
+// drain waits until every queued object was replicated
+func (q *replicationQueue) drain() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	for q.pending > 0 {
+		q.cond.Wait()
+	}
+}
+
+// replicate queues written objects for the replicator of the block if it has one
+func (a *AppendBlock) replicate(ids []common.ID, objs [][]byte) {
+	if a.replication == nil {
+		return
+	}
+
+	for i := range ids {
+		a.replication.add(replicatedObject{tenantID: a.meta.TenantID, id: ids[i], obj: objs[i]})
+	}
+}
+
+// replicateTraces queues traces appended to a vParquet block for the replicator of the block if it has one
+func (a *AppendBlock) replicateTraces(ids []common.ID, traces []*tempopb.Trace, starts, ends []uint32) {
+	if a.replication == nil {
+		return
+	}
+
+	for i := range ids {
+		a.replication.add(replicatedObject{
+			tenantID:     a.meta.TenantID,
+			id:           ids[i],
+			trace:        traces[i],
+			dataEncoding: a.meta.DataEncoding,
+			start:        starts[i],
+			end:          ends[i],
+		})
+	}
+}
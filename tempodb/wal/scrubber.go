@@ -0,0 +1,146 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var (
+	metricScrubbedFiles = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "wal_scrub_files_total",
+		Help:      "The total number of wal files validated by the scrubber.",
+	})
+	metricScrubCorruptRecords = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "wal_scrub_corrupt_records_total",
+		Help:      "The total number of records found by the scrubber that failed their checksum.",
+	})
+	metricScrubCorruptBlocks = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "tempo",
+		Name:      "wal_scrub_corrupt_blocks",
+		Help:      "The number of wal blocks with corrupt records or framing found by the last scrub.",
+	})
+)
+
+// ScrubResult is the outcome of a scrub of the wal folder
+type ScrubResult struct {
+	// Files is the number of wal files validated
+	Files int
+	// CorruptRecords is the number of records that failed their checksum
+	CorruptRecords int
+	// CorruptBlocks are the names of the wal blocks with corrupt records or framing
+	CorruptBlocks []string
+}
+
+// ScrubLoop scrubs the wal folder every ScrubInterval until ctx is done. It returns immediately if ScrubInterval is 0.
+func (w *WAL) ScrubLoop(ctx context.Context, logger log.Logger) {
+	if w.c.ScrubInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.c.ScrubInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_, err := w.Scrub(w.c.ScrubInterval, logger)
+			if err != nil {
+				level.Error(logger).Log("msg", "failed to scrub wal", "err", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Scrub re-reads the v2 wal files that haven't been written to for at least minAge and validates the framing and
+// checksums of their records. Files still being appended to are skipped. vParquet blocks are not scrubbed.
+func (w *WAL) Scrub(minAge time.Duration, logger log.Logger) (ScrubResult, error) {
+	files, err := w.c.FileSystem.ReadDir(w.c.Filepath)
+	if err != nil {
+		return ScrubResult{}, err
+	}
+
+	corrupt := map[string]struct{}{}
+	result := ScrubResult{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			// removed since listing the folder
+			continue
+		}
+		if time.Since(info.ModTime()) < minAge {
+			continue
+		}
+
+		// files that aren't wal blocks are left to replay
+		name, _, err := parseSegmentFilename(f.Name())
+		if err != nil {
+			continue
+		}
+		_, _, _, enc, _, err := ParseFilename(name)
+		if err != nil {
+			continue
+		}
+
+		dict, err := w.dictionaries.forFilename(name)
+		if err != nil {
+			// the file can't be read and is removed on replay
+			continue
+		}
+
+		records, warning, err := w.scrubFile(f.Name(), enc, dict)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			warning = err
+		}
+
+		result.Files++
+		result.CorruptRecords += records
+		metricScrubbedFiles.Inc()
+		metricScrubCorruptRecords.Add(float64(records))
+
+		if records > 0 || warning != nil {
+			level.Warn(logger).Log("msg", "found corrupt wal file", "file", f.Name(), "corruptRecords", records, "err", warning)
+			if _, ok := corrupt[name]; !ok {
+				corrupt[name] = struct{}{}
+				result.CorruptBlocks = append(result.CorruptBlocks, name)
+			}
+		}
+	}
+
+	metricScrubCorruptBlocks.Set(float64(len(result.CorruptBlocks)))
+
+	return result, nil
+}
+
+// scrubFile validates a single wal file or segment. It returns the number of records that failed their checksum and
+// a warning if the framing of the file is invalid.
+func (w *WAL) scrubFile(filename string, enc backend.Encoding, dict *zstdDictionary) (int, error, error) {
+	f, err := w.c.FileSystem.Open(filepath.Join(w.c.Filepath, filename))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	return walkRecords(f, enc, dict, parseBatched(filename), func([]byte, []byte, uint64, uint32) error {
+		return nil
+	})
+}
@@ -0,0 +1,248 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var metricSegmentsRotated = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "wal_segments_rotated_total",
+	Help:      "The total number of wal blocks rotated into a new segment file.",
+})
+
+// segmentSeparator separates the segment index from the filename of the block. Data encodings can't contain it.
+const segmentSeparator = "~"
+
+// walSegment is one of the files the data of a wal block is split over. The offsets of the records of a block
+// address its segments as if they were concatenated, start is the offset of the first byte of the segment.
+type walSegment struct {
+	index int
+	start uint64
+}
+
+// segmentFilename returns the name of the segment file with the given index. The first segment uses the filename
+// of the block so blocks that were never rotated are unchanged.
+func segmentFilename(filename string, index int) string {
+	if index == 0 {
+		return filename
+	}
+	return filename + segmentSeparator + strconv.Itoa(index)
+}
+
+// parseSegmentFilename returns the filename of the block and the segment index of a segment file.
+func parseSegmentFilename(name string) (string, int, error) {
+	i := strings.LastIndex(name, segmentSeparator)
+	if i == -1 {
+		return name, 0, nil
+	}
+
+	index, err := strconv.Atoi(name[i+1:])
+	if err != nil || index <= 0 {
+		return "", 0, fmt.Errorf("unable to parse %s. invalid segment index", name)
+	}
+	return name[:i], index, nil
+}
+
+// walFile is the wal file of a block with the indexes of its segments in order. Blocks with the vParquet version
+// are folders without segments.
+type walFile struct {
+	name     string
+	segments []int
+	folder   bool
+}
+
+// groupSegmentFiles groups the segment files in the wal folder by block. Names that can't be parsed are returned
+// as blocks without segments so replaying them fails as before.
+func groupSegmentFiles(names []string) []walFile {
+	var files []walFile
+	byName := map[string]int{}
+	for _, name := range names {
+		filename, index, err := parseSegmentFilename(name)
+		if err != nil {
+			filename, index = name, 0
+		}
+
+		i, ok := byName[filename]
+		if !ok {
+			i = len(files)
+			byName[filename] = i
+			files = append(files, walFile{name: filename})
+		}
+		files[i].segments = append(files[i].segments, index)
+	}
+
+	for _, f := range files {
+		sort.Ints(f.segments)
+	}
+	return files
+}
+
+// segmentReader reads the data of a wal block split over segment files as if the segments were concatenated.
+// Segment files are opened on first access and memory mapped if mapped is set.
+type segmentReader struct {
+	fs       FileSystem
+	filename string
+	mapped   bool
+
+	mtx      sync.Mutex
+	segments []walSegment
+	files    []File
+	offset   int64
+}
+
+func newSegmentReader(fs FileSystem, filename string, segments []walSegment, mapped bool) *segmentReader {
+	return &segmentReader{
+		fs:       fs,
+		filename: filename,
+		mapped:   mapped,
+		segments: segments,
+		files:    make([]File, len(segments)),
+	}
+}
+
+// addSegment adds a segment that starts after the existing ones
+func (r *segmentReader) addSegment(s walSegment) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.segments = append(r.segments, s)
+	r.files = append(r.files, nil)
+}
+
+func (r *segmentReader) lastSegment() walSegment {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.segments[len(r.segments)-1]
+}
+
+func (r *segmentReader) allSegments() []walSegment {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return append([]walSegment(nil), r.segments...)
+}
+
+// ReadAt implements io.ReaderAt
+func (r *segmentReader) ReadAt(p []byte, off int64) (int, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return r.readAt(p, off)
+}
+
+// Read implements io.Reader
+func (r *segmentReader) Read(p []byte) (int, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	n, err := r.readAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *segmentReader) readAt(p []byte, off int64) (int, error) {
+	// the last segment that starts at or before off
+	i := sort.Search(len(r.segments), func(i int) bool {
+		return int64(r.segments[i].start) > off
+	}) - 1
+	if i < 0 {
+		return 0, fmt.Errorf("offset %d before first segment of %s", off, r.filename)
+	}
+
+	read := 0
+	for ; i < len(r.segments); i++ {
+		f, err := r.file(i)
+		if err != nil {
+			return read, err
+		}
+
+		// don't read past the end of a segment into the bytes of the next one
+		buffer := p[read:]
+		if i+1 < len(r.segments) {
+			end := int64(r.segments[i+1].start)
+			if int64(len(buffer)) > end-off {
+				buffer = buffer[:end-off]
+			}
+		}
+
+		n, err := f.ReadAt(buffer, off-int64(r.segments[i].start))
+		read += n
+		off += int64(n)
+		if err != nil && err != io.EOF {
+			return read, err
+		}
+		if read == len(p) {
+			return read, nil
+		}
+		if n < len(buffer) && i+1 < len(r.segments) {
+			// the segment is shorter than its successor's start, skip the gap
+			off = int64(r.segments[i+1].start)
+		}
+	}
+
+	return read, io.EOF
+}
+
+func (r *segmentReader) file(i int) (File, error) {
+	if r.files[i] != nil {
+		return r.files[i], nil
+	}
+
+	f, err := openFile(r.fs, segmentFilename(r.filename, r.segments[i].index), r.mapped)
+	if err != nil {
+		return nil, err
+	}
+	r.files[i] = f
+	return f, nil
+}
+
+// Close closes the opened segment files
+func (r *segmentReader) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var lastErr error
+	for i, f := range r.files {
+		if f == nil {
+			continue
+		}
+		if err := f.Close(); err != nil {
+			lastErr = err
+		}
+		r.files[i] = nil
+	}
+	return lastErr
+}
+
+// segmentWriter writes to the current segment file of a wal block and counts the bytes written to it.
+type segmentWriter struct {
+	f       File
+	written uint64
+	// quota counts the written bytes, nil if the wal disk usage is unlimited
+	quota *diskQuota
+
+	// preallocateBytes is the size of the chunks disk space is reserved in, 0 disables preallocation
+	preallocateBytes  uint64
+	allocated         uint64
+	preallocateFailed bool
+}
+
+func (w *segmentWriter) Write(p []byte) (int, error) {
+	w.preallocate(len(p))
+	n, err := w.f.Write(p)
+	w.written += uint64(n)
+	w.quota.add(n)
+	return n, err
+}
@@ -0,0 +1,196 @@
+package wal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+)
+
+// sidecarSuffix is appended to the filename of a v2 wal block to name its meta sidecar file
+const sidecarSuffix = segmentSeparator + "meta"
+
+// blockSidecar is the meta and the records of a v2 wal block persisted next to its data. It lets replay recover the
+// block without reading any object if the data hasn't changed since the sidecar was written.
+type blockSidecar struct {
+	StartTime    time.Time `json:"startTime"`
+	EndTime      time.Time `json:"endTime"`
+	TotalObjects int       `json:"totalObjects"`
+	MinID        []byte    `json:"minID"`
+	MaxID        []byte    `json:"maxID"`
+	// DataLength is the length of the data of all segments when the sidecar was written
+	DataLength uint64 `json:"dataLength"`
+	// Records are the marshalled records of the block with offsets from the start of the block
+	Records []byte `json:"records"`
+	// Tombstones are the ids deleted by tombstones
+	Tombstones []common.ID `json:"tombstones,omitempty"`
+}
+
+func sidecarFilename(filename string) string {
+	return filename + sidecarSuffix
+}
+
+// isSidecarFilename returns true for sidecar files and sidecar files that are still being written
+func isSidecarFilename(name string) bool {
+	return strings.Contains(name, sidecarSuffix)
+}
+
+// records returns the records of the sidecar. They must be within the data of the block.
+func (s *blockSidecar) records() ([]common.Record, error) {
+	rw := v2.NewRecordReaderWriter()
+	if len(s.Records)%rw.RecordLength() != 0 {
+		return nil, fmt.Errorf("sidecar records have length %d", len(s.Records))
+	}
+
+	records := make([]common.Record, 0, rw.RecordCount(s.Records))
+	for i := 0; i < len(s.Records); i += rw.RecordLength() {
+		r := rw.UnmarshalRecord(s.Records[i : i+rw.RecordLength()])
+		if r.Start+uint64(r.Length) > s.DataLength {
+			return nil, fmt.Errorf("sidecar record at %d exceeds the data length %d", r.Start, s.DataLength)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// newAppendBlockFromSidecar returns a replayed AppendBlock like newAppendBlockFromFile. The records, tombstones and
+// meta are recovered from the sidecar, the segment files with the given sizes aren't read. The time range of the
+// block is adjusted for slack like the time ranges of replayed objects.
+func newAppendBlockFromSidecar(fs FileSystem, filename string, segments []int, sizes []uint64, path string, ingestionSlack time.Duration, additionalStartSlack time.Duration, mapped bool, dict *zstdDictionary, s *blockSidecar) (*AppendBlock, error) {
+	b, err := newReplayedAppendBlock(fs, filename, path, ingestionSlack, dict)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.records()
+	if err != nil {
+		return nil, err
+	}
+	common.SortRecords(records)
+
+	walSegments := make([]walSegment, 0, len(segments))
+	start := uint64(0)
+	for i, index := range segments {
+		walSegments = append(walSegments, walSegment{index: index, start: start})
+		start += sizes[i]
+	}
+
+	b.data = newSegmentReader(fs, b.fullFilename(), walSegments, mapped)
+	b.appender = v2.NewRecordAppender(records)
+	b.tombstones.merge(s.Tombstones)
+
+	blockStart, blockEnd := b.adjustTimeRangeForSlack(uint32(s.StartTime.Unix()), uint32(s.EndTime.Unix()), additionalStartSlack)
+	b.meta.StartTime = time.Unix(int64(blockStart), 0)
+	b.meta.EndTime = time.Unix(int64(blockEnd), 0)
+	b.meta.TotalObjects = s.TotalObjects
+	b.meta.MinID = s.MinID
+	b.meta.MaxID = s.MaxID
+
+	return b, nil
+}
+
+// writeSidecar persists the meta of a v2 block. The sidecar is written to a temporary file and renamed so replay
+// never sees a partial sidecar. Nothing is written while appended objects are still buffered.
+func (a *AppendBlock) writeSidecar() error {
+	var dataLength uint64
+	var records []common.Record
+	buffered := false
+	if a.batch != nil {
+		a.batch.locked(func(bufferedBytes int) {
+			dataLength = a.appender.DataLength()
+			records = a.appender.Records()
+			buffered = bufferedBytes > 0
+		})
+	} else {
+		// records are added before the data length grows, records appended since are dropped below
+		dataLength = a.appender.DataLength()
+		records = a.appender.Records()
+	}
+	if buffered {
+		return nil
+	}
+
+	written := records[:0]
+	for _, r := range records {
+		if r.Start < dataLength {
+			written = append(written, r)
+		}
+	}
+	marshalled, err := v2.NewRecordReaderWriter().MarshalRecords(written)
+	if err != nil {
+		// records of ids that aren't 128 bit can't be persisted, the block is replayed in full
+		return nil
+	}
+
+	buf, err := json.Marshal(&blockSidecar{
+		StartTime:    a.meta.StartTime,
+		EndTime:      a.meta.EndTime,
+		TotalObjects: a.meta.TotalObjects,
+		MinID:        a.meta.MinID,
+		MaxID:        a.meta.MaxID,
+		DataLength:   dataLength,
+		Records:      marshalled,
+		Tombstones:   a.tombstones.list(),
+	})
+	if err != nil {
+		return err
+	}
+
+	name := sidecarFilename(a.fullFilename())
+	tmp := name + ".tmp"
+	f, err := a.fs.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = a.fs.Remove(tmp)
+		return err
+	}
+	return a.fs.Rename(tmp, name)
+}
+
+// removeSidecar removes the sidecar of the block if it has one
+func (a *AppendBlock) removeSidecar() error {
+	err := a.fs.Remove(sidecarFilename(a.fullFilename()))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readSidecar returns the sidecar of the wal file with the given name or nil if it has none
+func readSidecar(fs FileSystem, filename string) (*blockSidecar, error) {
+	f, err := fs.Open(sidecarFilename(filename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &blockSidecar{}
+	err = json.Unmarshal(buf, s)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling sidecar: %w", err)
+	}
+	return s, nil
+}
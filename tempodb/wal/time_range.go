@@ -0,0 +1,148 @@
+package wal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/tempo/pkg/model"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+)
+
+// objectRange is the time range of the objects appended for an id in unix epoch seconds
+type objectRange struct {
+	start, end uint32
+}
+
+func (r objectRange) add(start, end uint32) objectRange {
+	if start < r.start {
+		r.start = start
+	}
+	if end > r.end {
+		r.end = end
+	}
+	return r
+}
+
+// objectRanges are the time ranges of the objects of a block by id. Ids without a range, e.g. objects of replayed
+// vParquet blocks, are in every range.
+type objectRanges struct {
+	mtx    sync.RWMutex
+	ranges map[string]objectRange
+}
+
+func (o *objectRanges) add(id common.ID, start, end uint32) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if o.ranges == nil {
+		o.ranges = map[string]objectRange{}
+	}
+	r, ok := o.ranges[string(id)]
+	if !ok {
+		r = objectRange{start: start, end: end}
+	}
+	o.ranges[string(id)] = r.add(start, end)
+}
+
+func (o *objectRanges) merge(ranges map[string]objectRange) {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+
+	if o.ranges == nil {
+		o.ranges = make(map[string]objectRange, len(ranges))
+	}
+	for id, r := range ranges {
+		existing, ok := o.ranges[id]
+		if ok {
+			r = existing.add(r.start, r.end)
+		}
+		o.ranges[id] = r
+	}
+}
+
+// overlaps returns true if the objects of the id may have times within start and end
+func (o *objectRanges) overlaps(id common.ID, start, end uint32) bool {
+	o.mtx.RLock()
+	defer o.mtx.RUnlock()
+
+	r, ok := o.ranges[string(id)]
+	return !ok || (r.start <= end && r.end >= start)
+}
+
+// TimeRangeIterator is ReadIterator restricted to the objects with times within start and end, in unix epoch
+// seconds. The time ranges the objects were appended with are kept per id, so the records of v2 blocks outside the
+// range are skipped without reading them. vParquet blocks are still read, only their objects are skipped.
+func (a *AppendBlock) TimeRangeIterator(combiner model.ObjectCombiner, start, end uint32) (common.Iterator, error) {
+	if a.parquet != nil {
+		iter, err := a.parquet.ObjectIterator(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return &timeRangeIterator{iter: iter, ranges: &a.ranges, start: start, end: end}, nil
+	}
+
+	if a.batch != nil {
+		err := a.batch.flush()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	records := a.appender.Records()
+	filtered := records[:0]
+	for _, r := range records {
+		if a.ranges.overlaps(r.ID, start, end) {
+			filtered = append(filtered, r)
+		}
+	}
+
+	return a.recordIterator(filtered, combiner)
+}
+
+// recordIterator returns the deduplicated objects of the records of a v2 block
+func (a *AppendBlock) recordIterator(records []common.Record, combiner model.ObjectCombiner) (common.Iterator, error) {
+	records = a.tombstones.filter(records)
+
+	dataReader, err := newDataReader(a.data, a.meta.Encoding, a.dictionary)
+	if err != nil {
+		return nil, err
+	}
+
+	// records written by a batch share a page
+	var iterator common.Iterator
+	if a.batched {
+		iterator = v2.NewRecordObjectIterator(records, dataReader, v2.NewObjectReaderWriter())
+	} else {
+		iterator = v2.NewRecordIterator(records, dataReader, v2.NewObjectReaderWriter())
+	}
+	iterator, err = v2.NewDedupingIterator(iterator, combiner, a.meta.DataEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return iterator, nil
+}
+
+// timeRangeIterator skips the objects of an iterator outside a time range
+type timeRangeIterator struct {
+	iter       common.Iterator
+	ranges     *objectRanges
+	start, end uint32
+}
+
+func (i *timeRangeIterator) Next(ctx context.Context) (common.ID, []byte, error) {
+	for {
+		id, obj, err := i.iter.Next(ctx)
+		if err != nil || id == nil {
+			return id, obj, err
+		}
+		if i.ranges.overlaps(id, i.start, i.end) {
+			return id, obj, nil
+		}
+	}
+}
+
+func (i *timeRangeIterator) Close() {
+	i.iter.Close()
+}
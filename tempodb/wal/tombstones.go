@@ -0,0 +1,130 @@
+package wal
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
+)
+
+var metricTombstones = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempo",
+	Name:      "wal_tombstones_total",
+	Help:      "The total number of trace tombstones appended to the wal per tenant.",
+}, []string{"tenant"})
+
+// tombstoneObject is the object of a tombstone record. It starts with a zero byte, which is never the start of a
+// marshalled proto, and can't be mistaken for a trace. All objects of a tombstoned id are dropped from the block,
+// whether they were appended before or after the tombstone.
+var tombstoneObject = []byte("\x00tempo:wal:tombstone")
+
+// IsTombstone returns true if obj is the object of a tombstone record
+func IsTombstone(obj []byte) bool {
+	return bytes.Equal(obj, tombstoneObject)
+}
+
+// tombstones are the ids of a block deleted by tombstones
+type tombstones struct {
+	mtx sync.RWMutex
+	ids map[string]struct{}
+}
+
+func (t *tombstones) add(id common.ID) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.ids == nil {
+		t.ids = map[string]struct{}{}
+	}
+	t.ids[string(id)] = struct{}{}
+}
+
+func (t *tombstones) merge(ids []common.ID) {
+	for _, id := range ids {
+		t.add(id)
+	}
+}
+
+func (t *tombstones) contains(id common.ID) bool {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	_, ok := t.ids[string(id)]
+	return ok
+}
+
+// list returns the tombstoned ids
+func (t *tombstones) list() []common.ID {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	ids := make([]common.ID, 0, len(t.ids))
+	for id := range t.ids {
+		ids = append(ids, common.ID(id))
+	}
+	return ids
+}
+
+// filter returns the records whose ids aren't tombstoned
+func (t *tombstones) filter(records []common.Record) []common.Record {
+	t.mtx.RLock()
+	defer t.mtx.RUnlock()
+
+	if len(t.ids) == 0 {
+		return records
+	}
+
+	filtered := make([]common.Record, 0, len(records))
+	for _, r := range records {
+		if _, ok := t.ids[string(r.ID)]; !ok {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
+
+// Delete appends a tombstone for the id to this wal block. The objects of the id are no longer returned by Find and
+// the iterators, so they never reach the completed block. The tombstone is synced following the flush policy and
+// replayed with the block. Blocks with the vParquet version and replayed blocks return common.ErrUnsupported.
+// Ingesters call it for the traces deleted through their delete endpoint.
+func (a *AppendBlock) Delete(id common.ID) error {
+	if a.parquet != nil {
+		return fmt.Errorf("deleting from wal version %s: %w", vparquet.VersionString, common.ErrUnsupported)
+	}
+	if a.writeBatch == nil {
+		return fmt.Errorf("deleting from a replayed wal block: %w", common.ErrUnsupported)
+	}
+	defer observeAppend(time.Now())
+
+	err := a.quota.check()
+	if err != nil {
+		return err
+	}
+
+	// the appender keeps the id in its records
+	id = append(common.ID(nil), id...)
+	if a.batch != nil {
+		err = a.batch.add(id, tombstoneObject)
+	} else {
+		err = a.appender.Append(id, tombstoneObject)
+		if err == nil {
+			err = a.appended()
+		}
+		if err == nil {
+			a.replicate([]common.ID{id}, [][]byte{tombstoneObject})
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	a.tombstones.add(id)
+	metricTombstones.WithLabelValues(a.meta.TenantID).Inc()
+	return nil
+}
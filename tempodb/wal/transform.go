@@ -0,0 +1,180 @@
+package wal
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+)
+
+const transformDir = "transform"
+
+// ReplayTransformFunc is applied to every object replayed by RescanBlocks. It returns the object that replaces obj
+// and its data encoding. Returning a nil object drops it from the block. All objects of a block must be returned
+// with the same data encoding.
+//
+// Transforms allow migrating the data in the WAL, e.g. upgrading data encodings or stripping attributes removed by
+// new redaction rules, without dropping it on restart.
+type ReplayTransformFunc func(id common.ID, obj []byte, dataEncoding string) ([]byte, string, error)
+
+// transformFile rewrites the objects of the given wal file and its segments through the transforms and returns the
+// name of the rewritten file, which has no further segments. The new file is written to a separate folder and then
+// moved over the original so any failure leaves the original intact. Like a replay, only the readable records of
+// each segment are transformed, the error of the first unreadable record is returned as a warning. An empty name is
+// returned if every object was dropped, in which case the original is removed.
+func (w *WAL) transformFile(filename string, segments []int, transforms []ReplayTransformFunc) (string, error, error) {
+	blockID, tenantID, version, e, dataEncoding, err := ParseFilename(filename)
+	if err != nil {
+		return "", nil, fmt.Errorf("parsing wal filename: %w", err)
+	}
+	if version != v2.VersionString {
+		return "", nil, fmt.Errorf("transforming wal version %s: %w", version, common.ErrUnsupported)
+	}
+
+	dir := filepath.Join(w.c.Filepath, transformDir)
+	err = w.c.FileSystem.MkdirAll(dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the transformed file is compressed with the dictionary of the original
+	dict, err := w.dictionaries.forFilename(filename)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var transformed *AppendBlock
+	defer func() {
+		// clean up the partially written file on failure
+		if err != nil && transformed != nil {
+			_ = transformed.Clear()
+		}
+	}()
+
+	// pages written by a batch hold several objects, they are transformed and appended one by one
+	var transformErr error
+	transformObj := func(id []byte, obj []byte) error {
+		objDataEncoding := dataEncoding
+		// tombstones are kept as they are
+		for _, t := range transforms {
+			if IsTombstone(obj) {
+				break
+			}
+			var err error
+			obj, objDataEncoding, err = t(id, obj, objDataEncoding)
+			if err != nil {
+				return fmt.Errorf("transforming object %x: %w", id, err)
+			}
+			if obj == nil {
+				break
+			}
+		}
+		if obj == nil {
+			return nil
+		}
+
+		if transformed == nil {
+			var err error
+			transformed, err = newAppendBlock(w.c.FileSystem, blockID, tenantID, dir, e, objDataEncoding, w.c.IngestionSlack, w.c.Checksum, w.c.flushPolicy(), 0, batchPolicy{}, dict)
+			if err != nil {
+				return err
+			}
+		}
+		if objDataEncoding != transformed.meta.DataEncoding {
+			return fmt.Errorf("transformed object %x has data encoding %s, expected %s", id, objDataEncoding, transformed.meta.DataEncoding)
+		}
+
+		// the id and object point into the page buffer which is reused. the object is written immediately
+		// but the appender keeps the id in its records so it has to be copied
+		return transformed.appender.Append(append([]byte(nil), id...), obj)
+	}
+
+	batched := parseBatched(filename)
+	var warning error
+	for _, index := range segments {
+		var segmentWarning error
+		segmentWarning, err = w.transformSegment(segmentFilename(filename, index), e, dict, batched, func(id []byte, obj []byte) error {
+			transformErr = transformObj(id, obj)
+			return transformErr
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		// walking the records stops at the first error, one returned by a transform fails the whole file
+		if transformErr != nil {
+			err = transformErr
+			return "", nil, err
+		}
+		if warning == nil {
+			warning = segmentWarning
+		}
+	}
+
+	// every object was dropped
+	if transformed == nil {
+		return "", warning, w.removeSegments(filename, segments)
+	}
+
+	err = transformed.appender.Complete()
+	if err != nil {
+		return "", nil, err
+	}
+	err = transformed.appendFile.Close()
+	if err != nil {
+		return "", nil, err
+	}
+	transformed.appendFile = nil
+
+	// move the transformed file over the original. the name changes if the data encoding changed
+	newFilename := filepath.Base(transformed.fullFilename())
+	err = w.c.FileSystem.Rename(transformed.fullFilename(), filepath.Join(w.c.Filepath, newFilename))
+	if err != nil {
+		return "", nil, err
+	}
+	removed := segments
+	if newFilename == filename {
+		removed = removed[1:]
+	}
+	err = w.removeSegments(filename, removed)
+	if err != nil {
+		// the transformed file is already in place and will be replayed along with the original on the next
+		// restart. objects are combined by id so this only costs duplicated work
+		return "", nil, fmt.Errorf("removing original wal file after transform: %w", err)
+	}
+
+	return newFilename, warning, nil
+}
+
+// transformSegment calls handleObj with every readable object of a segment file. Records that fail their checksum
+// are skipped, the first unreadable record stops the walk and is returned as a warning.
+func (w *WAL) transformSegment(name string, e backend.Encoding, dict *zstdDictionary, batched bool, handleObj func(id []byte, obj []byte) error) (error, error) {
+	f, err := w.c.FileSystem.Open(filepath.Join(w.c.Filepath, name))
+	if err != nil {
+		return nil, fmt.Errorf("accessing file: %w", err)
+	}
+	defer f.Close()
+
+	corrupt, warning, err := walkRecords(f, e, dict, batched, func(id []byte, obj []byte, _ uint64, _ uint32) error {
+		return handleObj(id, obj)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if corrupt > 0 && warning == nil {
+		warning = fmt.Errorf("skipped %d records with checksum mismatch while transforming wal", corrupt)
+	}
+	return warning, nil
+}
+
+// removeSegments removes the given segments of a wal file
+func (w *WAL) removeSegments(filename string, segments []int) error {
+	for _, index := range segments {
+		err := w.c.FileSystem.Remove(filepath.Join(w.c.Filepath, segmentFilename(filename, index)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
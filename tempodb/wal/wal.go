@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
@@ -13,9 +15,13 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
+	"github.com/grafana/tempo/pkg/model"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/local"
 	versioned_encoding "github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 )
 
 const reasonOutsideIngestionSlack = "outside_ingestion_time_slack"
@@ -38,8 +44,15 @@ const (
 )
 
 type WAL struct {
-	c *Config
-	l *local.Backend
+	c          *Config
+	l          *local.Backend
+	completing *local.Backend
+	quota      *diskQuota
+	// dictionaries trains and loads the zstd dictionaries of the tenants. Dictionaries are loaded to replay the blocks
+	// compressed with them even if training is disabled.
+	dictionaries *dictionaryStore
+	// replication replicates the written objects if the wal has a replicator
+	replication *replicationQueue
 }
 
 type Config struct {
@@ -49,15 +62,94 @@ type Config struct {
 	Encoding          backend.Encoding `yaml:"encoding"`
 	SearchEncoding    backend.Encoding `yaml:"search_encoding"`
 	IngestionSlack    time.Duration    `yaml:"ingestion_time_range_slack"`
+	// Checksum writes a checksum with every record so corrupted records are skipped on replay
+	Checksum bool `yaml:"checksum"`
+	// FlushPolicy controls when appended records are synced to disk
+	FlushPolicy   string        `yaml:"flush_policy"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// ReplayConcurrency is the number of wal files, and segments of a file, replayed concurrently
+	ReplayConcurrency int `yaml:"replay_concurrency"`
+	// SegmentSizeBytes rotates a wal block into a new segment file once the current one reaches this size. 0
+	// disables rotation.
+	SegmentSizeBytes uint64 `yaml:"segment_size_bytes"`
+	// Version is the block version new wal blocks are written with, v2 or vParquet. Tenants can override it.
+	Version string `yaml:"version"`
+	// MaxDiskUsageBytes is the max size of the wal folder. Appends fail with ErrWALFull once it's reached. 0 disables
+	// the limit.
+	MaxDiskUsageBytes uint64 `yaml:"max_disk_usage_bytes"`
+	// FileSystem stores the wal files of v2 blocks. Defaults to the local disk.
+	FileSystem FileSystem `yaml:"-"`
+	// ScrubInterval is how often wal files that haven't been written to for the interval are re-read and validated.
+	// 0 disables scrubbing.
+	ScrubInterval time.Duration `yaml:"scrub_interval"`
+	// BatchMaxObjects and BatchMaxBytes batch the objects appended to v2 blocks into a single record that is written
+	// once the batch holds as many objects or bytes, or BatchFlushInterval after the first object was batched. Both
+	// 0 disable batching.
+	BatchMaxObjects    int           `yaml:"batch_max_objects"`
+	BatchMaxBytes      int           `yaml:"batch_max_bytes"`
+	BatchFlushInterval time.Duration `yaml:"batch_flush_interval"`
+	// MmapReads memory maps v2 wal files for replay and for reads from replayed blocks. Files on a FileSystem other
+	// than the local disk are read as usual.
+	MmapReads bool `yaml:"mmap_reads"`
+	// PreallocateBytes reserves the disk space of v2 wal files in chunks of this size to reduce fragmentation and
+	// running out of space mid-append. The space left over is released once the block is completed. Only supported
+	// on linux. 0 disables preallocation.
+	PreallocateBytes uint64 `yaml:"preallocate_bytes"`
+	// ReplayCombineDuplicates combines the objects of v2 blocks that share an id on replay, so replayed blocks don't
+	// carry duplicate objects into completed blocks. The replayed file is rewritten with the combined objects.
+	ReplayCombineDuplicates bool `yaml:"replay_combine_duplicates"`
+	// ReplayCombiner combines duplicate objects on replay. Defaults to the combiner of the object model.
+	ReplayCombiner model.ObjectCombiner `yaml:"-"`
+	// Replicator receives every object appended to the wal, e.g. to stream it to a secondary site. Objects replayed
+	// on startup aren't replicated again. nil disables replication.
+	Replicator Replicator `yaml:"-"`
+	// ZstdDictionary compresses the zstd blocks of a tenant with a dictionary trained from its objects
+	ZstdDictionary ZstdDictionaryConfig `yaml:"zstd_dictionary"`
 }
 
+const (
+	// FlushPolicyAlways syncs the wal file after every append
+	FlushPolicyAlways = "always"
+	// FlushPolicyInterval syncs the wal file at most FlushInterval after an append
+	FlushPolicyInterval = "interval"
+	// FlushPolicyNever leaves syncing the wal file to the OS
+	FlushPolicyNever = "never"
+)
+
 func New(c *Config) (*WAL, error) {
 	if c.Filepath == "" {
 		return nil, fmt.Errorf("please provide a path for the WAL")
 	}
 
+	switch c.FlushPolicy {
+	case "", FlushPolicyNever, FlushPolicyAlways:
+	case FlushPolicyInterval:
+		if c.FlushInterval <= 0 {
+			return nil, fmt.Errorf("flush interval must be positive with flush policy %s", FlushPolicyInterval)
+		}
+	default:
+		return nil, fmt.Errorf("unknown wal flush policy %s", c.FlushPolicy)
+	}
+
+	if c.batchPolicy().enabled() && c.BatchFlushInterval <= 0 {
+		return nil, fmt.Errorf("batch flush interval must be positive when batching wal records")
+	}
+
+	if c.ZstdDictionary.Enabled && (c.ZstdDictionary.SampleBytes <= 0 || c.ZstdDictionary.MaxSizeBytes <= 0) {
+		return nil, fmt.Errorf("zstd dictionary sample bytes and max size must be positive when zstd dictionaries are enabled")
+	}
+
+	err := ValidateVersion(c.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.FileSystem == nil {
+		c.FileSystem = OSFileSystem{}
+	}
+
 	// make folder
-	err := os.MkdirAll(c.Filepath, os.ModePerm)
+	err = c.FileSystem.MkdirAll(c.Filepath)
 	if err != nil {
 		return nil, err
 	}
@@ -90,68 +182,510 @@ func New(c *Config) (*WAL, error) {
 		return nil, err
 	}
 
-	return &WAL{
-		c: c,
-		l: l,
-	}, nil
+	// Setup the local backend blocks are completed in in /completing/
+	p = filepath.Join(c.Filepath, completingDir)
+	err = recoverCompleting(p)
+	if err != nil {
+		return nil, err
+	}
+
+	completing, err := local.NewBackend(&local.Config{
+		Path: p,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WAL{
+		c:            c,
+		l:            l,
+		completing:   completing,
+		quota:        newDiskQuota(c.Filepath, c.MaxDiskUsageBytes),
+		dictionaries: newDictionaryStore(c.FileSystem, c.Filepath, c.ZstdDictionary),
+	}
+	if c.Replicator != nil {
+		w.replication = newReplicationQueue(c.Replicator)
+	}
+	return w, nil
+}
+
+// ReplayProgress reports the progress of a wal replay after each file is replayed.
+type ReplayProgress struct {
+	// File is the name of the wal file that was just replayed
+	File string
+	// Replayed is the number of files replayed so far out of Total
+	Replayed int
+	Total    int
+}
+
+// ReplayProgressFunc is called by RescanBlocksWithProgress after each file is replayed. Calls are serialized.
+type ReplayProgressFunc func(ReplayProgress)
+
+// ReplayTenantFilter selects the tenants whose wal files are replayed and the order they are replayed in. Files of
+// tenants that aren't replayed are left in the wal folder for a later replay. A nil filter replays all tenants in
+// file order.
+type ReplayTenantFilter struct {
+	included map[string]struct{}
+	excluded map[string]struct{}
+	priority map[string]int
+}
+
+// NewReplayTenantFilter replays the tenants in include, or all tenants if include is empty, except those in exclude.
+// The tenants in priority are replayed first, in the order they are listed. It returns nil if all are empty.
+func NewReplayTenantFilter(include, exclude, priority []string) *ReplayTenantFilter {
+	if len(include) == 0 && len(exclude) == 0 && len(priority) == 0 {
+		return nil
+	}
+
+	toMap := func(tenants []string) map[string]struct{} {
+		m := make(map[string]struct{}, len(tenants))
+		for _, t := range tenants {
+			m[t] = struct{}{}
+		}
+		return m
+	}
+	f := &ReplayTenantFilter{
+		included: toMap(include),
+		excluded: toMap(exclude),
+		priority: make(map[string]int, len(priority)),
+	}
+	for i, t := range priority {
+		if _, ok := f.priority[t]; !ok {
+			f.priority[t] = i
+		}
+	}
+	return f
 }
 
-// RescanBlocks returns a slice of append blocks from the wal folder
-func (w *WAL) RescanBlocks(fn RangeFunc, additionalStartSlack time.Duration, log log.Logger) ([]*AppendBlock, error) {
-	files, err := os.ReadDir(w.c.Filepath)
+// Replays returns true if the wal files of the tenant are replayed
+func (f *ReplayTenantFilter) Replays(tenantID string) bool {
+	if f == nil {
+		return true
+	}
+	if _, ok := f.excluded[tenantID]; ok {
+		return false
+	}
+	if len(f.included) == 0 {
+		return true
+	}
+	_, ok := f.included[tenantID]
+	return ok
+}
+
+// Before returns true if the wal files of tenant a are replayed before the files of tenant b
+func (f *ReplayTenantFilter) Before(a, b string) bool {
+	return f.rank(a) < f.rank(b)
+}
+
+// rank is the position of a tenant in the priority list, tenants that aren't listed are ranked last
+func (f *ReplayTenantFilter) rank(tenantID string) int {
+	if f == nil {
+		return 0
+	}
+	if r, ok := f.priority[tenantID]; ok {
+		return r
+	}
+	return len(f.priority)
+}
+
+// RescanBlocks returns a slice of append blocks from the wal folder. If transforms are passed every object is
+// rewritten through them, in order, before the block is replayed. A block that fails to transform is removed, vParquet
+// blocks can't be transformed and are removed as well.
+func (w *WAL) RescanBlocks(fn RangeFunc, additionalStartSlack time.Duration, log log.Logger, transforms ...ReplayTransformFunc) ([]*AppendBlock, error) {
+	return w.RescanBlocksWithProgress(fn, additionalStartSlack, nil, log, transforms...)
+}
+
+// RescanBlocksWithProgress replays the wal files with up to ReplayConcurrency files replayed concurrently and
+// calls progress after each file. fn must be safe for concurrent use. The blocks are returned in replay order.
+func (w *WAL) RescanBlocksWithProgress(fn RangeFunc, additionalStartSlack time.Duration, progress ReplayProgressFunc, log log.Logger, transforms ...ReplayTransformFunc) ([]*AppendBlock, error) {
+	return w.RescanBlocksForTenants(fn, additionalStartSlack, nil, progress, log, transforms...)
+}
+
+// RescanBlocksForTenants is RescanBlocksWithProgress restricted to the tenants accepted by filter, the files of
+// the priority tenants of the filter are replayed first. A nil filter replays all tenants in file order. Files that
+// don't belong to an accepted tenant, including files that aren't named like wal blocks, are skipped and not
+// removed.
+func (w *WAL) RescanBlocksForTenants(fn RangeFunc, additionalStartSlack time.Duration, filter *ReplayTenantFilter, progress ReplayProgressFunc, log log.Logger, transforms ...ReplayTransformFunc) ([]*AppendBlock, error) {
+	// clear any files left over by a transform that was interrupted
+	err := w.c.FileSystem.RemoveAll(filepath.Join(w.c.Filepath, transformDir))
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := w.c.FileSystem.ReadDir(w.c.Filepath)
 	if err != nil {
 		return nil, err
 	}
 
-	blocks := make([]*AppendBlock, 0, len(files))
+	names := make([]string, 0, len(files))
+	var sidecars []string
+	var parquetFolders []walFile
 	for _, f := range files {
 		if f.IsDir() {
+			// vParquet blocks are folders, other folders hold search data and local blocks
+			if _, _, version, _, _, err := ParseFilename(f.Name()); err == nil && version == vparquet.VersionString {
+				parquetFolders = append(parquetFolders, walFile{name: f.Name(), folder: true})
+			}
+			continue
+		}
+		if isSidecarFilename(f.Name()) {
+			sidecars = append(sidecars, f.Name())
 			continue
 		}
+		names = append(names, f.Name())
+	}
+	walFiles := append(groupSegmentFiles(names), parquetFolders...)
 
-		start := time.Now()
-		fileInfo, err := f.Info()
-		if err != nil {
+	// remove sidecars left behind by removed blocks or interrupted writes
+	exists := make(map[string]struct{}, len(walFiles))
+	for _, f := range walFiles {
+		exists[sidecarFilename(f.name)] = struct{}{}
+	}
+	for _, name := range sidecars {
+		if _, ok := exists[name]; ok {
+			continue
+		}
+		err = w.c.FileSystem.Remove(filepath.Join(w.c.Filepath, name))
+		if err != nil && !os.IsNotExist(err) {
 			return nil, err
 		}
+	}
+
+	if filter != nil {
+		filtered := walFiles[:0]
+		tenants := make(map[string]string, len(walFiles))
+		for _, f := range walFiles {
+			if _, tenantID, _, _, _, err := ParseFilename(f.name); err == nil && filter.Replays(tenantID) {
+				filtered = append(filtered, f)
+				tenants[f.name] = tenantID
+				continue
+			}
+			level.Info(log).Log("msg", "skipping replay of wal file", "file", f.name)
+		}
+		walFiles = filtered
+
+		// the replay jobs are dispatched in order, the files of priority tenants are replayed first
+		sort.SliceStable(walFiles, func(i, j int) bool {
+			return filter.Before(tenants[walFiles[i].name], tenants[walFiles[j].name])
+		})
+	}
+
+	concurrency := w.c.ReplayConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(walFiles) {
+		concurrency = len(walFiles)
+	}
+
+	var (
+		replayed = make([]*AppendBlock, len(walFiles))
+		jobs     = make(chan int)
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		count    int
+		firstErr error
+	)
+	for j := 0; j < concurrency; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				b, err := w.replayFile(walFiles[idx], fn, additionalStartSlack, log, transforms)
+
+				mtx.Lock()
+				replayed[idx] = b
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				count++
+				if progress != nil {
+					progress(ReplayProgress{
+						File:     walFiles[idx].name,
+						Replayed: count,
+						Total:    len(walFiles),
+					})
+				}
+				mtx.Unlock()
+			}
+		}()
+	}
+
+	for idx := range walFiles {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	// dictionaries of blocks that were cleared are removed, those of files left to replay are kept
+	err = w.dictionaries.removeUnused(names)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*AppendBlock, 0, len(replayed))
+	for _, b := range replayed {
+		if b != nil {
+			b.trackOutstanding()
+			blocks = append(blocks, b)
+		}
+	}
 
-		level.Info(log).Log("msg", "beginning replay", "file", f.Name(), "size", fileInfo.Size())
-		b, warning, err := newAppendBlockFromFile(f.Name(), w.c.Filepath, w.c.IngestionSlack, additionalStartSlack, fn)
+	return blocks, nil
+}
 
-		remove := false
+// replayFile replays a single wal file and its segments. It returns a nil block if the file was removed.
+func (w *WAL) replayFile(file walFile, fn RangeFunc, additionalStartSlack time.Duration, log log.Logger, transforms []ReplayTransformFunc) (*AppendBlock, error) {
+	if file.folder {
+		// the objects of vParquet blocks are written as rows and can't be transformed. replaying them would keep the
+		// data the transforms remove
+		if len(transforms) > 0 {
+			level.Warn(log).Log("msg", "failed to transform block. removing.", "folder", file.name, "err", common.ErrUnsupported)
+			return nil, w.c.FileSystem.RemoveAll(filepath.Join(w.c.Filepath, file.name))
+		}
+		return w.replayFolder(file.name, additionalStartSlack, log)
+	}
+
+	start := time.Now()
+	size := int64(0)
+	sizes := make([]uint64, 0, len(file.segments))
+	for _, index := range file.segments {
+		fileInfo, err := w.c.FileSystem.Stat(filepath.Join(w.c.Filepath, segmentFilename(file.name, index)))
 		if err != nil {
-			// wal replay failed, clear and warn
-			level.Warn(log).Log("msg", "failed to replay block. removing.", "file", f.Name(), "err", err)
-			remove = true
+			return nil, err
 		}
+		size += fileInfo.Size()
+		sizes = append(sizes, uint64(fileInfo.Size()))
+	}
+
+	name := file.name
+	segments := file.segments
+	level.Info(log).Log("msg", "beginning replay", "file", name, "size", size, "segments", len(segments))
 
-		if b != nil && b.appender.Length() == 0 {
-			level.Warn(log).Log("msg", "empty wal file. ignoring.", "file", f.Name(), "err", err)
-			remove = true
+	if len(transforms) > 0 {
+		// the sidecar doesn't match the transformed objects
+		err := w.c.FileSystem.Remove(filepath.Join(w.c.Filepath, sidecarFilename(name)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
 		}
 
+		transformed, warning, err := w.transformFile(name, segments, transforms)
+		if err != nil {
+			// the original objects are never replayed, they hold the data the transforms remove
+			level.Warn(log).Log("msg", "failed to transform block. removing.", "file", name, "err", err)
+			return nil, w.removeSegments(name, segments)
+		}
 		if warning != nil {
-			level.Warn(log).Log("msg", "received warning while replaying block. partial replay likely.", "file", f.Name(), "warning", warning, "records", b.appender.Length())
+			level.Warn(log).Log("msg", "received warning while transforming block. partial replay likely.", "file", name, "warning", warning)
+		}
+		if transformed == "" {
+			level.Info(log).Log("msg", "all objects dropped by transform. removed.", "file", name)
+			return nil, nil
+		}
+		name = transformed
+		segments = []int{0}
+	}
+
+	// the sidecar holds the records and meta of the block if it wasn't appended to since it was written
+	sidecar, err := readSidecar(w.c.FileSystem, filepath.Join(w.c.Filepath, name))
+	if err != nil {
+		level.Warn(log).Log("msg", "failed to read wal sidecar. replaying all objects.", "file", name, "err", err)
+		sidecar = nil
+	}
+	if sidecar != nil && sidecar.DataLength != uint64(size) {
+		sidecar = nil
+	}
+
+	var b *AppendBlock
+	var warning error
+	// blocks compressed with a zstd dictionary can't be replayed without it
+	dict, err := w.dictionaries.forFilename(name)
+	if err == nil && sidecar != nil {
+		b, err = newAppendBlockFromSidecar(w.c.FileSystem, name, segments, sizes, w.c.Filepath, w.c.IngestionSlack, additionalStartSlack, w.c.MmapReads, dict, sidecar)
+		if err == nil {
+			level.Info(log).Log("msg", "recovered block from sidecar", "file", name)
+		} else {
+			level.Warn(log).Log("msg", "failed to recover block from sidecar. replaying all objects.", "file", name, "err", err)
+			b, err, sidecar = nil, nil, nil
 		}
+	}
+	if err == nil && sidecar == nil {
+		b, warning, err = newAppendBlockFromFile(w.c.FileSystem, name, segments, w.c.Filepath, w.c.IngestionSlack, additionalStartSlack, w.c.ReplayConcurrency, w.c.MmapReads, dict, fn)
+	}
+
+	remove := false
+	if err != nil {
+		// wal replay failed, clear and warn
+		level.Warn(log).Log("msg", "failed to replay block. removing.", "file", name, "err", err)
+		remove = true
+	}
+
+	if b != nil && b.length() == 0 {
+		level.Warn(log).Log("msg", "empty wal file. ignoring.", "file", name, "err", err)
+		remove = true
+	}
+
+	if warning != nil {
+		level.Warn(log).Log("msg", "received warning while replaying block. partial replay likely.", "file", name, "warning", warning, "records", b.length())
+	}
 
-		if remove {
-			err = os.Remove(filepath.Join(w.c.Filepath, f.Name()))
+	if remove {
+		err = w.c.FileSystem.Remove(filepath.Join(w.c.Filepath, sidecarFilename(name)))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, index := range segments {
+			err = w.c.FileSystem.Remove(filepath.Join(w.c.Filepath, segmentFilename(name, index)))
 			if err != nil {
 				return nil, err
 			}
-			continue
 		}
+		return nil, nil
+	}
 
-		level.Info(log).Log("msg", "replay complete", "file", f.Name(), "duration", time.Since(start))
+	if combiner := w.c.replayCombiner(); combiner != nil {
+		if duplicates := b.duplicates(); duplicates > 0 {
+			combined, err := w.combineFile(b, combiner)
+			if err != nil {
+				level.Warn(log).Log("msg", "failed to combine duplicate objects. replaying original.", "file", name, "err", err)
+			} else {
+				level.Info(log).Log("msg", "combined duplicate objects", "file", name, "duplicates", duplicates)
+				metricReplayCombinedObjects.Add(float64(duplicates))
+				b = combined
+			}
+		}
+	}
+
+	level.Info(log).Log("msg", "replay complete", "file", name, "duration", time.Since(start))
+	metricReplayDuration.Observe(time.Since(start).Seconds())
+
+	return b, nil
+}
 
-		blocks = append(blocks, b)
+// replayFolder replays the wal folder of a vParquet block. It returns a nil block if the folder was removed.
+func (w *WAL) replayFolder(name string, additionalStartSlack time.Duration, log log.Logger) (*AppendBlock, error) {
+	start := time.Now()
+	level.Info(log).Log("msg", "beginning replay", "folder", name)
+
+	b, warning, err := newParquetAppendBlockFromFolder(name, w.c.Filepath, w.c.IngestionSlack, additionalStartSlack, w.c.flushPolicy())
+	if b != nil {
+		// replayed vParquet blocks can still be appended to, their files were measured already
+		b.quota = w.quota
+		b.parquetLength = b.parquet.DataLength()
+		b.replication = w.replication
 	}
 
-	return blocks, nil
+	remove := false
+	if err != nil {
+		// wal replay failed, clear and warn
+		level.Warn(log).Log("msg", "failed to replay block. removing.", "folder", name, "err", err)
+		remove = true
+	}
+
+	if b != nil && b.length() == 0 {
+		level.Warn(log).Log("msg", "empty wal folder. ignoring.", "folder", name)
+		remove = true
+	}
+
+	if warning != nil {
+		level.Warn(log).Log("msg", "received warning while replaying block. partial replay likely.", "folder", name, "warning", warning, "records", b.length())
+	}
+
+	if remove {
+		err = os.RemoveAll(filepath.Join(w.c.Filepath, name))
+		if err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	level.Info(log).Log("msg", "replay complete", "folder", name, "duration", time.Since(start))
+	metricReplayDuration.Observe(time.Since(start).Seconds())
+
+	return b, nil
+}
+
+func (c *Config) flushPolicy() flushPolicy {
+	return flushPolicy{
+		policy:   c.FlushPolicy,
+		interval: c.FlushInterval,
+	}
 }
 
+func (c *Config) batchPolicy() batchPolicy {
+	return batchPolicy{
+		maxObjects:    c.BatchMaxObjects,
+		maxBytes:      c.BatchMaxBytes,
+		flushInterval: c.BatchFlushInterval,
+	}
+}
+
+// NewBlock creates a wal block with the configured version
 func (w *WAL) NewBlock(id uuid.UUID, tenantID string, dataEncoding string) (*AppendBlock, error) {
-	return newAppendBlock(id, tenantID, w.c.Filepath, w.c.Encoding, dataEncoding, w.c.IngestionSlack)
+	return w.NewBlockWithVersion(id, tenantID, dataEncoding, w.c.Version)
+}
+
+// NewBlockWithVersion creates a wal block with the given version. An empty version uses v2.
+func (w *WAL) NewBlockWithVersion(id uuid.UUID, tenantID string, dataEncoding string, version string) (*AppendBlock, error) {
+	return w.NewBlockWithVersionAndEncoding(id, tenantID, dataEncoding, version, w.c.Encoding)
+}
+
+// NewBlockWithVersionAndEncoding creates a wal block with the given version that is compressed with enc instead of
+// the encoding of the wal config. An empty version uses v2.
+func (w *WAL) NewBlockWithVersionAndEncoding(id uuid.UUID, tenantID string, dataEncoding string, version string, enc backend.Encoding) (*AppendBlock, error) {
+	var b *AppendBlock
+	var err error
+	switch version {
+	case "", v2.VersionString:
+		dict, sample := w.dictionaries.forNewBlock(tenantID, enc)
+		b, err = newAppendBlock(w.c.FileSystem, id, tenantID, w.c.Filepath, enc, dataEncoding, w.c.IngestionSlack, w.c.Checksum, w.c.flushPolicy(), w.c.SegmentSizeBytes, w.c.batchPolicy(), dict)
+		if err == nil {
+			b.sample = sample
+		}
+	case vparquet.VersionString:
+		b, err = newParquetAppendBlock(id, tenantID, w.c.Filepath, enc, dataEncoding, w.c.IngestionSlack, w.c.flushPolicy())
+	default:
+		return nil, fmt.Errorf("unsupported wal block version %s", version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.quota = w.quota
+	b.replication = w.replication
+	if b.writer != nil {
+		b.writer.preallocateBytes = w.c.PreallocateBytes
+		b.writer.quota = w.quota
+	}
+	b.trackOutstanding()
+	return b, nil
+}
+
+// DrainReplication waits until the objects written to the wal so far were passed to the replicator, e.g. on
+// shutdown. It returns immediately if the wal isn't replicated.
+func (w *WAL) DrainReplication() {
+	if w.replication != nil {
+		w.replication.drain()
+	}
+}
+
+// Full returns true if the wal folder reached the max disk usage. Appends fail with ErrWALFull until blocks are
+// completed and cleared.
+func (w *WAL) Full() bool {
+	return w.quota.full()
+}
+
+// ValidateVersion returns an error if wal blocks can't be written with the version. An empty version uses v2.
+func ValidateVersion(version string) error {
+	switch version {
+	case "", v2.VersionString, vparquet.VersionString:
+		return nil
+	}
+	return fmt.Errorf("unsupported wal block version %s, supported versions are %s and %s", version, v2.VersionString, vparquet.VersionString)
 }
 
 func (w *WAL) NewFile(blockid uuid.UUID, tenantid string, dir string) (*os.File, backend.Encoding, error) {
@@ -202,8 +736,8 @@ func ParseFilename(filename string) (uuid.UUID, string, string, backend.Encoding
 		return uuid.UUID{}, "", "", backend.EncNone, "", fmt.Errorf("unable to parse %s. error parsing version: %w", filename, err)
 	}
 
-	// fourth is encoding
-	encodingString := splits[3]
+	// fourth is encoding, followed by the id of the zstd dictionary of the file if it has one
+	encodingString, _, _ := strings.Cut(splits[3], dictionarySeparator)
 	encoding, err := backend.ParseEncoding(encodingString)
 	if err != nil {
 		return uuid.UUID{}, "", "", backend.EncNone, "", fmt.Errorf("unable to parse %s. error parsing encoding: %w", filename, err)
@@ -222,6 +756,16 @@ func (w *WAL) GetFilepath() string {
 	return w.c.Filepath
 }
 
+// Version is the block version new wal blocks are written with
+func (w *WAL) Version() string {
+	return w.c.Version
+}
+
+// Encoding is the encoding new wal blocks are written with
+func (w *WAL) Encoding() backend.Encoding {
+	return w.c.Encoding
+}
+
 func (w *WAL) ClearFolder(dir string) error {
 	p := filepath.Join(w.c.Filepath, dir)
 	return os.RemoveAll(p)
@@ -3,24 +3,37 @@ package wal
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
 	"math/rand"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/go-kit/log"
 	"github.com/golang/protobuf/proto" //nolint:all
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
 
+	"github.com/grafana/tempo/pkg/model"
+	model_v2 "github.com/grafana/tempo/pkg/model/v2"
 	"github.com/grafana/tempo/pkg/tempopb"
 	"github.com/grafana/tempo/pkg/util/test"
 	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
 	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
+	"github.com/grafana/tempo/tempodb/encoding/vparquet"
 )
 
 const (
@@ -65,10 +78,8 @@ func TestAppend(t *testing.T) {
 	}
 
 	records := block.appender.Records()
-	file, err := block.file()
-	require.NoError(t, err)
 
-	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(file), backend.EncNone)
+	dataReader, err := v2.NewDataReader(backend.NewContextReaderWithAllReader(block.data), backend.EncNone)
 	require.NoError(t, err)
 	iterator := v2.NewRecordIterator(records, dataReader, v2.NewObjectReaderWriter())
 	defer iterator.Close()
@@ -91,6 +102,42 @@ func TestAppend(t *testing.T) {
 	require.Equal(t, numMsgs, i)
 }
 
+func TestAppendBlockReadIterator(t *testing.T) {
+	wal, err := New(&Config{
+		Filepath: t.TempDir(),
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	countObjects := func() int {
+		iterator, err := block.ReadIterator(&mockCombiner{})
+		require.NoError(t, err)
+		defer iterator.Close()
+
+		count := 0
+		for {
+			_, _, err := iterator.Next(context.Background())
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			count++
+		}
+		return count
+	}
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, block.Append(test.ValidTraceID(nil), []byte{0x01, byte(i)}, 0, 0))
+	}
+	require.Equal(t, 10, countObjects())
+
+	// the block can still be appended to
+	require.NoError(t, block.Append(test.ValidTraceID(nil), []byte{0x02}, 0, 0))
+	require.Equal(t, 11, countObjects())
+}
+
 func TestCompletedDirIsRemoved(t *testing.T) {
 	// Create /completed/testfile and verify it is removed.
 
@@ -165,258 +212,990 @@ func TestErrorConditions(t *testing.T) {
 	require.NoFileExists(t, filepath.Join(tempDir, "fe0b83eb-a86b-4b6c-9a74-dc272cd5700e:blerg:v2:gzip"))
 }
 
-func TestAppendBlockStartEnd(t *testing.T) {
+func TestRescanBlocksWithTransforms(t *testing.T) {
+	tempDir := t.TempDir()
 	wal, err := New(&Config{
-		Filepath:       t.TempDir(),
-		Encoding:       backend.EncNone,
-		IngestionSlack: 2 * time.Minute,
+		Filepath: tempDir,
+		Encoding: backend.EncGZIP,
 	})
 	require.NoError(t, err, "unexpected error creating temp wal")
 
 	blockID := uuid.New()
-	block, err := wal.NewBlock(blockID, testTenantID, "")
+	block, err := wal.NewBlock(blockID, testTenantID, "v1")
 	require.NoError(t, err, "unexpected error creating block")
 
-	// create a new block and confirm start/end times are correct
-	blockStart := uint32(time.Now().Unix())
-	blockEnd := uint32(time.Now().Add(time.Minute).Unix())
-
+	ids := make([][]byte, 0, 10)
 	for i := 0; i < 10; i++ {
-		bytes := make([]byte, 16)
-		rand.Read(bytes)
+		id := make([]byte, 16)
+		rand.Read(id)
+		id[0] = byte(i)
+		ids = append(ids, id)
 
-		err = block.Append(bytes, bytes, blockStart, blockEnd)
+		err = block.Append(id, id, 0, 0)
 		require.NoError(t, err, "unexpected error writing req")
 	}
+	originalFilename := block.fullFilename()
 
-	require.Equal(t, blockStart, uint32(block.meta.StartTime.Unix()))
-	require.Equal(t, blockEnd, uint32(block.meta.EndTime.Unix()))
+	// the records after an unreadable one are lost, the readable ones are transformed
+	appendFile, err := os.OpenFile(originalFilename, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = appendFile.Write([]byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01})
+	require.NoError(t, err)
+	require.NoError(t, appendFile.Close())
 
-	// rescan the block and make sure that start/end times are correct
-	blockStart = uint32(time.Now().Add(-time.Hour).Unix())
-	blockEnd = uint32(time.Now().Unix())
+	rangeFn := func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}
 
-	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
-		return blockStart, blockEnd, nil
-	}, time.Hour, log.NewNopLogger())
-	require.NoError(t, err, "unexpected error getting blocks")
+	// drop the even objects, and upgrade the data encoding of the odd ones
+	dropEven := func(id common.ID, obj []byte, dataEncoding string) ([]byte, string, error) {
+		if id[0]%2 == 0 {
+			return nil, dataEncoding, nil
+		}
+		return obj, dataEncoding, nil
+	}
+	upgrade := func(id common.ID, obj []byte, dataEncoding string) ([]byte, string, error) {
+		require.Equal(t, "v1", dataEncoding)
+		return append(obj, 0xff), "v2", nil
+	}
+	blocks, err := wal.RescanBlocks(rangeFn, 0, log.NewNopLogger(), dropEven, upgrade)
+	require.NoError(t, err)
 	require.Len(t, blocks, 1)
 
-	require.Equal(t, blockStart, uint32(blocks[0].meta.StartTime.Unix()))
-	require.Equal(t, blockEnd, uint32(blocks[0].meta.EndTime.Unix()))
-}
+	b := blocks[0]
+	assert.Equal(t, blockID, b.BlockID())
+	assert.Equal(t, "v2", b.Meta().DataEncoding)
+	assert.Equal(t, 5, b.appender.Length())
+	for i, id := range ids {
+		obj, err := b.Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		if i%2 == 0 {
+			assert.Nil(t, obj)
+			continue
+		}
+		assert.Equal(t, append(append([]byte(nil), id...), 0xff), obj)
+	}
+	require.NoFileExists(t, originalFilename)
 
-func TestAdjustTimeRangeForSlack(t *testing.T) {
-	a := &AppendBlock{
-		meta: &backend.BlockMeta{
-			TenantID: "test",
-		},
-		ingestionSlack: 2 * time.Minute,
+	// a failing transform removes the block, the original objects are never replayed
+	blocks, err = wal.RescanBlocks(rangeFn, 0, log.NewNopLogger(), func(id common.ID, obj []byte, dataEncoding string) ([]byte, string, error) {
+		return nil, "", errors.New("failed")
+	})
+	require.NoError(t, err)
+	require.Len(t, blocks, 0)
+	require.NoFileExists(t, b.fullFilename())
+
+	// dropping every object removes the block
+	block, err = wal.NewBlock(uuid.New(), testTenantID, "v1")
+	require.NoError(t, err, "unexpected error creating block")
+	require.NoError(t, block.Append(ids[0], ids[0], 0, 0))
+	blocks, err = wal.RescanBlocks(rangeFn, 0, log.NewNopLogger(), func(id common.ID, obj []byte, dataEncoding string) ([]byte, string, error) {
+		return nil, dataEncoding, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, blocks, 0)
+
+	files, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	for _, f := range files {
+		assert.True(t, f.IsDir(), "unexpected file %s", f.Name())
 	}
+}
 
-	// test happy path
-	start := uint32(time.Now().Unix())
-	end := uint32(time.Now().Unix())
-	actualStart, actualEnd := a.adjustTimeRangeForSlack(start, end, 0)
-	assert.Equal(t, start, actualStart)
-	assert.Equal(t, end, actualEnd)
+func TestRescanBlocksWithTransformsSegmented(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath:         tempDir,
+		Encoding:         backend.EncNone,
+		SegmentSizeBytes: 1024,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
 
-	// test start out of range
-	now := uint32(time.Now().Unix())
-	start = uint32(time.Now().Add(-time.Hour).Unix())
-	end = uint32(time.Now().Unix())
-	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, 0)
-	assert.Equal(t, now, actualStart)
-	assert.Equal(t, end, actualEnd)
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "v1")
+	require.NoError(t, err, "unexpected error creating block")
 
-	// test end out of range
-	now = uint32(time.Now().Unix())
-	start = uint32(time.Now().Unix())
-	end = uint32(time.Now().Add(time.Hour).Unix())
-	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, 0)
-	assert.Equal(t, start, actualStart)
-	assert.Equal(t, now, actualEnd)
+	ids := make([][]byte, 0, 100)
+	for i := 0; i < 100; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		ids = append(ids, id)
 
-	// test additional start slack honored
-	start = uint32(time.Now().Add(-time.Hour).Unix())
-	end = uint32(time.Now().Unix())
-	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, time.Hour)
-	assert.Equal(t, start, actualStart)
-	assert.Equal(t, end, actualEnd)
-}
+		err = block.Append(id, id, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+	segments := block.data.allSegments()
+	require.Greater(t, len(segments), 1)
 
-func TestAppendReplayFind(t *testing.T) {
-	for _, e := range backend.SupportedEncoding {
-		t.Run(e.String(), func(t *testing.T) {
-			testAppendReplayFind(t, backend.EncZstd)
-		})
+	// the objects of all segments are transformed into a single file
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger(), func(id common.ID, obj []byte, dataEncoding string) ([]byte, string, error) {
+		return append(obj, 0xff), dataEncoding, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, len(ids), blocks[0].appender.Length())
+	assert.Len(t, blocks[0].data.allSegments(), 1)
+	for _, id := range ids {
+		obj, err := blocks[0].Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		assert.Equal(t, append(append([]byte(nil), id...), 0xff), obj)
+	}
+	for _, s := range segments[1:] {
+		assert.NoFileExists(t, segmentFilename(block.fullFilename(), s.index))
 	}
 }
 
-func testAppendReplayFind(t *testing.T, e backend.Encoding) {
+func TestRescanBlocksWithChecksum(t *testing.T) {
+	tempDir := t.TempDir()
 	wal, err := New(&Config{
-		Filepath: t.TempDir(),
-		Encoding: e,
+		Filepath: tempDir,
+		Encoding: backend.EncNone,
+		Checksum: true,
 	})
 	require.NoError(t, err, "unexpected error creating temp wal")
 
-	blockID := uuid.New()
-
-	block, err := wal.NewBlock(blockID, testTenantID, "")
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
 	require.NoError(t, err, "unexpected error creating block")
 
-	objects := 1000
-	objs := make([][]byte, 0, objects)
-	ids := make([][]byte, 0, objects)
-	for i := 0; i < objects; i++ {
+	ids := make([][]byte, 0, 10)
+	for i := 0; i < 10; i++ {
 		id := make([]byte, 16)
 		rand.Read(id)
-		obj := test.MakeTrace(rand.Int()%10, id)
 		ids = append(ids, id)
-		bObj, err := proto.Marshal(obj)
-		require.NoError(t, err)
-		objs = append(objs, bObj)
 
-		err = block.Append(id, bObj, 0, 0)
+		err = block.Append(id, id, 0, 0)
 		require.NoError(t, err, "unexpected error writing req")
 	}
 
+	// corrupt the object of a record in the middle of the file
+	records := block.appender.RecordsForID(ids[3])
+	require.Len(t, records, 1)
+	f, err := os.OpenFile(block.fullFilename(), os.O_RDWR, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xff, 0xff}, int64(records[0].Start)+int64(records[0].Length)-2)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	corruptBefore := testutil.ToFloat64(metricCorruptRecords)
+
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+
+	// only the corrupted record is skipped
+	b := blocks[0]
+	assert.Equal(t, 9, b.appender.Length())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricCorruptRecords)-corruptBefore)
 	for i, id := range ids {
-		obj, err := block.Find(id, &mockCombiner{})
+		obj, err := b.Find(id, &mockCombiner{})
 		require.NoError(t, err)
-		require.Equal(t, objs[i], obj)
+		if i == 3 {
+			assert.Nil(t, obj)
+			continue
+		}
+		assert.Equal(t, id, obj)
 	}
+}
 
-	// write garbage data at the end to confirm a partial block will load
-	appendFile, err := os.OpenFile(block.fullFilename(), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+func TestScrub(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath: tempDir,
+		Encoding: backend.EncNone,
+		Checksum: true,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	blocks := make([]*AppendBlock, 0, 2)
+	ids := make([][]byte, 0, 10)
+	for i := 0; i < 2; i++ {
+		block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+		require.NoError(t, err, "unexpected error creating block")
+		blocks = append(blocks, block)
+
+		for j := 0; j < 10; j++ {
+			id := make([]byte, 16)
+			rand.Read(id)
+			ids = append(ids, id)
+
+			err = block.Append(id, id, 0, 0)
+			require.NoError(t, err, "unexpected error writing req")
+		}
+	}
+
+	// corrupt the object of a record in the middle of the second block
+	records := blocks[1].appender.RecordsForID(ids[13])
+	require.Len(t, records, 1)
+	f, err := os.OpenFile(blocks[1].fullFilename(), os.O_RDWR, 0644)
 	require.NoError(t, err)
-	_, err = appendFile.Write([]byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01})
+	_, err = f.WriteAt([]byte{0xff, 0xff}, int64(records[0].Start)+int64(records[0].Length)-2)
 	require.NoError(t, err)
-	err = appendFile.Close()
+	require.NoError(t, f.Close())
+
+	// files written to recently are skipped
+	result, err := wal.Scrub(time.Hour, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, ScrubResult{}, result)
+
+	corruptBefore := testutil.ToFloat64(metricScrubCorruptRecords)
+
+	result, err = wal.Scrub(0, log.NewNopLogger())
 	require.NoError(t, err)
+	assert.Equal(t, 2, result.Files)
+	assert.Equal(t, 1, result.CorruptRecords)
+	assert.Equal(t, []string{filepath.Base(blocks[1].fullFilename())}, result.CorruptBlocks)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricScrubCorruptRecords)-corruptBefore)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricScrubCorruptBlocks))
+
+	// truncating the first block breaks its framing
+	info, err := os.Stat(blocks[0].fullFilename())
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(blocks[0].fullFilename(), info.Size()-1))
+
+	result, err = wal.Scrub(0, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Files)
+	assert.Len(t, result.CorruptBlocks, 2)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metricScrubCorruptBlocks))
+}
+
+func TestFileSystem(t *testing.T) {
+	tempDir := t.TempDir()
+	fs := &recordingFileSystem{}
+	wal, err := New(&Config{
+		Filepath:         tempDir,
+		Encoding:         backend.EncNone,
+		SegmentSizeBytes: 100,
+		FileSystem:       fs,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	ids := make([][]byte, 0, 10)
+	for i := 0; i < 10; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		ids = append(ids, id)
+
+		err = block.Append(id, id, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+
+	// every segment is created through the file system
+	segments := block.data.allSegments()
+	require.Greater(t, len(segments), 1)
+	require.Len(t, fs.created, len(segments))
+	for i, seg := range segments {
+		assert.Equal(t, segmentFilename(block.fullFilename(), seg.index), fs.created[i])
+	}
+	segmentFiles := append([]string(nil), fs.created...)
+
+	// so is the sidecar
+	require.NoError(t, block.Flush())
+	sidecar := sidecarFilename(block.fullFilename())
+	assert.Equal(t, sidecar+".tmp", fs.created[len(fs.created)-1])
 
 	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
 		return 0, 0, nil
 	}, 0, log.NewNopLogger())
-	require.NoError(t, err, "unexpected error getting blocks")
-	require.Len(t, blocks, 1)
-
-	iterator, err := blocks[0].Iterator(&mockCombiner{})
 	require.NoError(t, err)
-	defer iterator.Close()
+	require.Len(t, blocks, 1)
+	// the block is recovered from the sidecar, the segments are read on demand
+	assert.Equal(t, []string{sidecar}, fs.opened)
 
-	// append block find
-	for i, id := range ids {
-		obj, err := blocks[0].Find(id, &mockCombiner{})
+	b := blocks[0]
+	for _, id := range ids {
+		obj, err := b.Find(id, &mockCombiner{})
 		require.NoError(t, err)
-		require.Equal(t, objs[i], obj)
+		assert.Equal(t, id, obj)
 	}
+	assert.ElementsMatch(t, append(segmentFiles, sidecar), fs.opened)
 
-	i := 0
-	for {
-		id, obj, err := iterator.Next(context.Background())
-		if err == io.EOF {
-			break
-		} else {
-			require.NoError(t, err)
-		}
+	require.NoError(t, b.Clear())
+	assert.ElementsMatch(t, append(segmentFiles, sidecar), fs.removed)
+}
 
-		found := false
-		j := 0
-		for ; j < len(ids); j++ {
-			if bytes.Equal(ids[j], id) {
-				found = true
-				break
-			}
-		}
+// recordingFileSystem records the wal files created, opened and removed through it
+type recordingFileSystem struct {
+	OSFileSystem
 
-		require.True(t, found)
-		require.Equal(t, objs[j], obj)
-		require.Equal(t, ids[j], []byte(id))
-		i++
-	}
+	mtx                      sync.Mutex
+	created, opened, removed []string
+}
 
-	require.Equal(t, objects, i)
+func (fs *recordingFileSystem) Create(name string) (File, error) {
+	fs.mtx.Lock()
+	fs.created = append(fs.created, name)
+	fs.mtx.Unlock()
+	return fs.OSFileSystem.Create(name)
+}
 
-	err = blocks[0].Clear()
-	require.NoError(t, err)
+func (fs *recordingFileSystem) Open(name string) (File, error) {
+	fs.mtx.Lock()
+	fs.opened = append(fs.opened, name)
+	fs.mtx.Unlock()
+	return fs.OSFileSystem.Open(name)
 }
 
-func TestParseFilename(t *testing.T) {
-	tests := []struct {
-		name                 string
-		filename             string
-		expectUUID           uuid.UUID
-		expectTenant         string
-		expectedVersion      string
-		expectedEncoding     backend.Encoding
-		expectedDataEncoding string
-		expectError          bool
-	}{
-		{
-			name:                 "version, enc snappy and dataencoding",
-			filename:             "123e4567-e89b-12d3-a456-426614174000:foo:v2:snappy:dataencoding",
-			expectUUID:           uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
-			expectTenant:         "foo",
-			expectedVersion:      "v2",
-			expectedEncoding:     backend.EncSnappy,
-			expectedDataEncoding: "dataencoding",
-		},
-		{
-			name:                 "version, enc none and dataencoding",
-			filename:             "123e4567-e89b-12d3-a456-426614174000:foo:v2:none:dataencoding",
-			expectUUID:           uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
-			expectTenant:         "foo",
-			expectedVersion:      "v2",
-			expectedEncoding:     backend.EncNone,
-			expectedDataEncoding: "dataencoding",
-		},
-		{
-			name:                 "empty dataencoding",
-			filename:             "123e4567-e89b-12d3-a456-426614174000:foo:v2:snappy",
-			expectUUID:           uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
-			expectTenant:         "foo",
-			expectedVersion:      "v2",
-			expectedEncoding:     backend.EncSnappy,
-			expectedDataEncoding: "",
-		},
-		{
-			name:                 "empty dataencoding with semicolon",
-			filename:             "123e4567-e89b-12d3-a456-426614174000:foo:v2:snappy:",
-			expectUUID:           uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
-			expectTenant:         "foo",
-			expectedVersion:      "v2",
-			expectedEncoding:     backend.EncSnappy,
-			expectedDataEncoding: "",
-		},
-		{
-			name:        "path fails",
-			filename:    "/blerg/123e4567-e89b-12d3-a456-426614174000:foo",
-			expectError: true,
-		},
-		{
-			name:        "no :",
-			filename:    "123e4567-e89b-12d3-a456-426614174000",
-			expectError: true,
-		},
-		{
-			name:        "empty string",
-			filename:    "",
-			expectError: true,
-		},
-		{
-			name:        "bad uuid",
-			filename:    "123e4:foo",
-			expectError: true,
-		},
-		{
-			name:        "no tenant",
-			filename:    "123e4567-e89b-12d3-a456-426614174000:",
-			expectError: true,
+func (fs *recordingFileSystem) Remove(name string) error {
+	fs.mtx.Lock()
+	fs.removed = append(fs.removed, name)
+	fs.mtx.Unlock()
+	return fs.OSFileSystem.Remove(name)
+}
+
+func TestRotateCreateFails(t *testing.T) {
+	tempDir := t.TempDir()
+	fs := &failingCreateFileSystem{}
+	wal, err := New(&Config{
+		Filepath:         tempDir,
+		Encoding:         backend.EncNone,
+		SegmentSizeBytes: 100,
+		FileSystem:       fs,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	// the block keeps appending to its segment while new segments can't be created
+	fs.fail.Store(true)
+	ids := make([][]byte, 0, 20)
+	failed := 0
+	for i := 0; i < 20; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		ids = append(ids, id)
+
+		if i == 10 {
+			fs.fail.Store(false)
+		}
+		if err := block.Append(id, id, 0, 0); err != nil {
+			failed++
+		}
+	}
+	require.Greater(t, failed, 0)
+	require.Greater(t, len(block.data.allSegments()), 1)
+
+	for _, id := range ids {
+		obj, err := block.Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		assert.Equal(t, id, obj)
+	}
+
+	require.NoError(t, block.Flush())
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	require.Equal(t, len(ids), blocks[0].appender.Length())
+}
+
+// failingCreateFileSystem fails to create segment files while fail is set
+type failingCreateFileSystem struct {
+	OSFileSystem
+
+	fail atomic.Bool
+}
+
+func (fs *failingCreateFileSystem) Create(name string) (File, error) {
+	if fs.fail.Load() && strings.Contains(name, segmentSeparator) {
+		return nil, errors.New("create failed")
+	}
+	return fs.OSFileSystem.Create(name)
+}
+
+func TestRescanBlocksWithProgress(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath:          tempDir,
+		Encoding:          backend.EncNone,
+		ReplayConcurrency: 3,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	ids := make([]uuid.UUID, 0, 5)
+	for i := 0; i < 5; i++ {
+		block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+		require.NoError(t, err, "unexpected error creating block")
+		ids = append(ids, block.BlockID())
+
+		id := make([]byte, 16)
+		rand.Read(id)
+		err = block.Append(id, id, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+	// an empty file is removed on replay but still reported
+	empty, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	var progress []ReplayProgress
+	blocks, err := wal.RescanBlocksWithProgress(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, func(p ReplayProgress) {
+		progress = append(progress, p)
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 5)
+
+	require.Len(t, progress, 6)
+	for i, p := range progress {
+		assert.Equal(t, i+1, p.Replayed)
+		assert.Equal(t, 6, p.Total)
+		assert.NotEmpty(t, p.File)
+	}
+
+	replayedIDs := make([]uuid.UUID, 0, len(blocks))
+	for _, b := range blocks {
+		replayedIDs = append(replayedIDs, b.BlockID())
+	}
+	assert.ElementsMatch(t, ids, replayedIDs)
+
+	_, err = os.Stat(empty.fullFilename())
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRescanBlocksForTenants(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath: tempDir,
+		Encoding: backend.EncNone,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	blockIDs := map[string]uuid.UUID{}
+	for _, tenantID := range []string{"a", "b", "c"} {
+		block, err := wal.NewBlock(uuid.New(), tenantID, "")
+		require.NoError(t, err, "unexpected error creating block")
+		blockIDs[tenantID] = block.BlockID()
+
+		id := make([]byte, 16)
+		rand.Read(id)
+		err = block.Append(id, id, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+
+	rescan := func(filter *ReplayTenantFilter) []uuid.UUID {
+		blocks, err := wal.RescanBlocksForTenants(func([]byte, string) (uint32, uint32, error) {
+			return 0, 0, nil
+		}, 0, filter, nil, log.NewNopLogger())
+		require.NoError(t, err)
+
+		ids := make([]uuid.UUID, 0, len(blocks))
+		for _, b := range blocks {
+			ids = append(ids, b.BlockID())
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []uuid.UUID{blockIDs["a"], blockIDs["b"]}, rescan(NewReplayTenantFilter([]string{"a", "b"}, nil, nil)))
+	assert.ElementsMatch(t, []uuid.UUID{blockIDs["b"], blockIDs["c"]}, rescan(NewReplayTenantFilter(nil, []string{"a"}, nil)))
+	assert.ElementsMatch(t, []uuid.UUID{blockIDs["b"]}, rescan(NewReplayTenantFilter([]string{"a", "b"}, []string{"a"}, nil)))
+
+	// priority tenants are replayed first, in order
+	replayed := rescan(NewReplayTenantFilter(nil, nil, []string{"c", "a"}))
+	require.Len(t, replayed, 3)
+	assert.Equal(t, []uuid.UUID{blockIDs["c"], blockIDs["a"]}, replayed[:2])
+
+	// skipped files are left for a later replay
+	assert.ElementsMatch(t, []uuid.UUID{blockIDs["a"], blockIDs["b"], blockIDs["c"]}, rescan(nil))
+}
+
+func TestNewReplayTenantFilter(t *testing.T) {
+	assert.Nil(t, NewReplayTenantFilter(nil, nil, nil))
+
+	var filter *ReplayTenantFilter
+	assert.True(t, filter.Replays("a"))
+	assert.False(t, filter.Before("a", "b"))
+
+	filter = NewReplayTenantFilter([]string{"a"}, nil, nil)
+	assert.True(t, filter.Replays("a"))
+	assert.False(t, filter.Replays("b"))
+
+	filter = NewReplayTenantFilter(nil, []string{"a"}, nil)
+	assert.False(t, filter.Replays("a"))
+	assert.True(t, filter.Replays("b"))
+
+	filter = NewReplayTenantFilter(nil, nil, []string{"b", "a"})
+	assert.True(t, filter.Replays("c"))
+	assert.True(t, filter.Before("b", "a"))
+	assert.True(t, filter.Before("a", "c"))
+	assert.False(t, filter.Before("c", "d"))
+	assert.False(t, filter.Before("a", "b"))
+}
+
+func TestFlushPolicy(t *testing.T) {
+	_, err := New(&Config{Filepath: t.TempDir(), FlushPolicy: "sometimes"})
+	require.Error(t, err)
+	_, err = New(&Config{Filepath: t.TempDir(), FlushPolicy: FlushPolicyInterval})
+	require.Error(t, err)
+
+	syncs := func() uint64 {
+		m := &dto.Metric{}
+		require.NoError(t, metricSyncDuration.Write(m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	appendObjects := func(cfg *Config, count int) *AppendBlock {
+		w, err := New(cfg)
+		require.NoError(t, err)
+		block, err := w.NewBlock(uuid.New(), testTenantID, "")
+		require.NoError(t, err)
+		for i := 0; i < count; i++ {
+			id := make([]byte, 16)
+			rand.Read(id)
+			require.NoError(t, block.Append(id, id, 0, 0))
+		}
+		return block
+	}
+
+	// never leaves syncing to the os
+	before := syncs()
+	appendObjects(&Config{Filepath: t.TempDir(), FlushPolicy: FlushPolicyNever}, 5)
+	assert.Equal(t, before, syncs())
+
+	// always syncs every append
+	before = syncs()
+	appendObjects(&Config{Filepath: t.TempDir(), FlushPolicy: FlushPolicyAlways}, 5)
+	assert.Equal(t, before+5, syncs())
+
+	// interval syncs the appends once within the interval
+	before = syncs()
+	block := appendObjects(&Config{Filepath: t.TempDir(), FlushPolicy: FlushPolicyInterval, FlushInterval: 10 * time.Millisecond}, 5)
+	require.Eventually(t, func() bool {
+		return syncs() == before+1
+	}, time.Second, 5*time.Millisecond)
+
+	// no syncs are pending after the block is closed
+	require.NoError(t, block.Append(test.ValidTraceID(nil), []byte{0x01}, 0, 0))
+	require.NoError(t, block.Clear())
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, before+1, syncs())
+}
+
+func TestDiskQuota(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New(&Config{
+		Filepath:          dir,
+		MaxDiskUsageBytes: 1000,
+	})
+	require.NoError(t, err)
+
+	block, err := w.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err)
+
+	// appends are accepted until the limit is reached
+	obj := make([]byte, 100)
+	appended := 0
+	for {
+		err = block.Append(test.ValidTraceID(nil), obj, 0, 0)
+		if err != nil {
+			break
+		}
+		appended++
+	}
+	require.True(t, errors.Is(err, ErrWALFull))
+	require.True(t, w.Full())
+	require.Equal(t, 8, appended) // the pages of the objects are larger than the objects, the last one exceeds the max
+	require.Equal(t, appended, block.Meta().TotalObjects)
+
+	// the written bytes were counted
+	w.quota.measure()
+	require.True(t, w.Full())
+
+	// clearing the block frees the space with the next measurement
+	require.NoError(t, block.Clear())
+	w.quota.measure()
+	require.False(t, w.Full())
+
+	block, err = w.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err)
+	require.NoError(t, block.Append(test.ValidTraceID(nil), obj, 0, 0))
+
+	// other files in the wal folder count as well
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other"), make([]byte, 1000), 0644))
+	w.quota.measure()
+	require.True(t, errors.Is(block.Append(test.ValidTraceID(nil), obj, 0, 0), ErrWALFull))
+
+	// no limit without max disk usage
+	w, err = New(&Config{Filepath: dir})
+	require.NoError(t, err)
+	require.False(t, w.Full())
+}
+
+func TestAppendBlockStartEnd(t *testing.T) {
+	wal, err := New(&Config{
+		Filepath:       t.TempDir(),
+		Encoding:       backend.EncNone,
+		IngestionSlack: 2 * time.Minute,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	blockID := uuid.New()
+	block, err := wal.NewBlock(blockID, testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	// create a new block and confirm start/end times are correct
+	blockStart := uint32(time.Now().Unix())
+	blockEnd := uint32(time.Now().Add(time.Minute).Unix())
+
+	for i := 0; i < 10; i++ {
+		bytes := make([]byte, 16)
+		rand.Read(bytes)
+
+		err = block.Append(bytes, bytes, blockStart, blockEnd)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+
+	require.Equal(t, blockStart, uint32(block.meta.StartTime.Unix()))
+	require.Equal(t, blockEnd, uint32(block.meta.EndTime.Unix()))
+
+	// rescan the block and make sure that start/end times are correct
+	blockStart = uint32(time.Now().Add(-time.Hour).Unix())
+	blockEnd = uint32(time.Now().Unix())
+
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return blockStart, blockEnd, nil
+	}, time.Hour, log.NewNopLogger())
+	require.NoError(t, err, "unexpected error getting blocks")
+	require.Len(t, blocks, 1)
+
+	require.Equal(t, blockStart, uint32(blocks[0].meta.StartTime.Unix()))
+	require.Equal(t, blockEnd, uint32(blocks[0].meta.EndTime.Unix()))
+}
+
+func TestBlockSidecar(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath:       tempDir,
+		Encoding:       backend.EncNone,
+		IngestionSlack: 2 * time.Minute,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	start := uint32(time.Now().Add(-90 * time.Second).Unix())
+	end := uint32(time.Now().Add(time.Minute).Unix())
+	ids := make([][]byte, 0, 10)
+	for i := 0; i < 10; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		ids = append(ids, id)
+		require.NoError(t, block.Append(id, id, start, end))
+	}
+	require.NoError(t, block.Flush())
+	expected := *block.Meta()
+
+	calls := atomic.NewInt32(0)
+	rangeFn := func([]byte, string) (uint32, uint32, error) {
+		calls.Inc()
+		return 0, 0, nil
+	}
+
+	// an intact block is recovered from the sidecar without reading any object
+	blocks, err := wal.RescanBlocks(rangeFn, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, int32(0), calls.Load())
+	assert.Equal(t, expected.StartTime.Unix(), blocks[0].Meta().StartTime.Unix())
+	assert.Equal(t, expected.EndTime.Unix(), blocks[0].Meta().EndTime.Unix())
+	assert.Equal(t, expected.TotalObjects, blocks[0].Meta().TotalObjects)
+	assert.Equal(t, expected.MinID, blocks[0].Meta().MinID)
+	assert.Equal(t, expected.MaxID, blocks[0].Meta().MaxID)
+	for _, id := range ids {
+		obj, err := blocks[0].Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		assert.Equal(t, id, obj)
+	}
+
+	// the start time recovered from the sidecar is adjusted for slack like the start time of replayed objects
+	replayWAL, err := New(&Config{
+		Filepath:       tempDir,
+		Encoding:       backend.EncNone,
+		IngestionSlack: time.Minute,
+	})
+	require.NoError(t, err)
+	blocks, err = replayWAL.RescanBlocks(rangeFn, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Greater(t, blocks[0].Meta().StartTime.Unix(), expected.StartTime.Unix())
+	blocks, err = replayWAL.RescanBlocks(rangeFn, time.Minute, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, expected.StartTime.Unix(), blocks[0].Meta().StartTime.Unix())
+
+	// tombstones are recovered from the sidecar
+	require.NoError(t, block.Delete(ids[0]))
+	require.NoError(t, block.Flush())
+	blocks, err = wal.RescanBlocks(rangeFn, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, int32(0), calls.Load())
+	obj, err := blocks[0].Find(ids[0], &mockCombiner{})
+	require.NoError(t, err)
+	assert.Nil(t, obj)
+
+	// objects appended after the sidecar was written are replayed in full
+	id := make([]byte, 16)
+	rand.Read(id)
+	require.NoError(t, block.Append(id, id, start, end))
+
+	blocks, err = wal.RescanBlocks(rangeFn, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, int32(11), calls.Load())
+
+	// the sidecar is removed with the block and orphaned sidecars are removed on replay
+	require.NoError(t, blocks[0].Clear())
+	_, err = os.Stat(sidecarFilename(block.fullFilename()))
+	assert.True(t, os.IsNotExist(err))
+
+	orphan := sidecarFilename(filepath.Join(tempDir, "orphan"))
+	require.NoError(t, os.WriteFile(orphan, []byte("{}"), 0644))
+	blocks, err = wal.RescanBlocks(rangeFn, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Len(t, blocks, 0)
+	_, err = os.Stat(orphan)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestAdjustTimeRangeForSlack(t *testing.T) {
+	a := &AppendBlock{
+		meta: &backend.BlockMeta{
+			TenantID: "test",
 		},
-		{
-			name:        "no version",
-			filename:    "123e4567-e89b-12d3-a456-426614174000:test::none",
+		ingestionSlack: 2 * time.Minute,
+	}
+
+	// test happy path
+	start := uint32(time.Now().Unix())
+	end := uint32(time.Now().Unix())
+	actualStart, actualEnd := a.adjustTimeRangeForSlack(start, end, 0)
+	assert.Equal(t, start, actualStart)
+	assert.Equal(t, end, actualEnd)
+
+	// test start out of range
+	now := uint32(time.Now().Unix())
+	start = uint32(time.Now().Add(-time.Hour).Unix())
+	end = uint32(time.Now().Unix())
+	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, 0)
+	assert.Equal(t, now, actualStart)
+	assert.Equal(t, end, actualEnd)
+
+	// test end out of range
+	now = uint32(time.Now().Unix())
+	start = uint32(time.Now().Unix())
+	end = uint32(time.Now().Add(time.Hour).Unix())
+	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, 0)
+	assert.Equal(t, start, actualStart)
+	assert.Equal(t, now, actualEnd)
+
+	// test additional start slack honored
+	start = uint32(time.Now().Add(-time.Hour).Unix())
+	end = uint32(time.Now().Unix())
+	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, time.Hour)
+	assert.Equal(t, start, actualStart)
+	assert.Equal(t, end, actualEnd)
+
+	// test separate past and future slack
+	a.SetIngestionSlack(2*time.Hour, 0)
+	start = uint32(time.Now().Add(-time.Hour).Unix())
+	end = uint32(time.Now().Add(time.Minute).Unix())
+	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, 0)
+	assert.Equal(t, start, actualStart)
+	assert.Equal(t, end, actualEnd)
+
+	now = uint32(time.Now().Unix())
+	end = uint32(time.Now().Add(time.Hour).Unix())
+	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, 0)
+	assert.Equal(t, start, actualStart)
+	assert.Equal(t, now, actualEnd)
+
+	a.SetIngestionSlack(0, 2*time.Hour)
+	actualStart, actualEnd = a.adjustTimeRangeForSlack(start, end, 0)
+	assert.Equal(t, start, actualStart)
+	assert.Equal(t, end, actualEnd)
+}
+
+func TestAppendReplayFind(t *testing.T) {
+	for _, e := range backend.SupportedEncoding {
+		t.Run(e.String(), func(t *testing.T) {
+			testAppendReplayFind(t, backend.EncZstd)
+		})
+	}
+}
+
+func testAppendReplayFind(t *testing.T, e backend.Encoding) {
+	wal, err := New(&Config{
+		Filepath: t.TempDir(),
+		Encoding: e,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	blockID := uuid.New()
+
+	block, err := wal.NewBlock(blockID, testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	objects := 1000
+	objs := make([][]byte, 0, objects)
+	ids := make([][]byte, 0, objects)
+	for i := 0; i < objects; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		obj := test.MakeTrace(rand.Int()%10, id)
+		ids = append(ids, id)
+		bObj, err := proto.Marshal(obj)
+		require.NoError(t, err)
+		objs = append(objs, bObj)
+
+		err = block.Append(id, bObj, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+
+	for i, id := range ids {
+		obj, err := block.Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		require.Equal(t, objs[i], obj)
+	}
+
+	// write garbage data at the end to confirm a partial block will load
+	appendFile, err := os.OpenFile(block.fullFilename(), os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	require.NoError(t, err)
+	_, err = appendFile.Write([]byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01})
+	require.NoError(t, err)
+	err = appendFile.Close()
+	require.NoError(t, err)
+
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err, "unexpected error getting blocks")
+	require.Len(t, blocks, 1)
+
+	iterator, err := blocks[0].Iterator(&mockCombiner{})
+	require.NoError(t, err)
+	defer iterator.Close()
+
+	// append block find
+	for i, id := range ids {
+		obj, err := blocks[0].Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		require.Equal(t, objs[i], obj)
+	}
+
+	i := 0
+	for {
+		id, obj, err := iterator.Next(context.Background())
+		if err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+
+		found := false
+		j := 0
+		for ; j < len(ids); j++ {
+			if bytes.Equal(ids[j], id) {
+				found = true
+				break
+			}
+		}
+
+		require.True(t, found)
+		require.Equal(t, objs[j], obj)
+		require.Equal(t, ids[j], []byte(id))
+		i++
+	}
+
+	require.Equal(t, objects, i)
+
+	err = blocks[0].Clear()
+	require.NoError(t, err)
+}
+
+func TestParseFilename(t *testing.T) {
+	tests := []struct {
+		name                 string
+		filename             string
+		expectUUID           uuid.UUID
+		expectTenant         string
+		expectedVersion      string
+		expectedEncoding     backend.Encoding
+		expectedDataEncoding string
+		expectError          bool
+	}{
+		{
+			name:                 "version, enc snappy and dataencoding",
+			filename:             "123e4567-e89b-12d3-a456-426614174000:foo:v2:snappy:dataencoding",
+			expectUUID:           uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+			expectTenant:         "foo",
+			expectedVersion:      "v2",
+			expectedEncoding:     backend.EncSnappy,
+			expectedDataEncoding: "dataencoding",
+		},
+		{
+			name:                 "version, enc none and dataencoding",
+			filename:             "123e4567-e89b-12d3-a456-426614174000:foo:v2:none:dataencoding",
+			expectUUID:           uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+			expectTenant:         "foo",
+			expectedVersion:      "v2",
+			expectedEncoding:     backend.EncNone,
+			expectedDataEncoding: "dataencoding",
+		},
+		{
+			name:                 "empty dataencoding",
+			filename:             "123e4567-e89b-12d3-a456-426614174000:foo:v2:snappy",
+			expectUUID:           uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+			expectTenant:         "foo",
+			expectedVersion:      "v2",
+			expectedEncoding:     backend.EncSnappy,
+			expectedDataEncoding: "",
+		},
+		{
+			name:                 "empty dataencoding with semicolon",
+			filename:             "123e4567-e89b-12d3-a456-426614174000:foo:v2:snappy:",
+			expectUUID:           uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+			expectTenant:         "foo",
+			expectedVersion:      "v2",
+			expectedEncoding:     backend.EncSnappy,
+			expectedDataEncoding: "",
+		},
+		{
+			name:        "path fails",
+			filename:    "/blerg/123e4567-e89b-12d3-a456-426614174000:foo",
+			expectError: true,
+		},
+		{
+			name:        "no :",
+			filename:    "123e4567-e89b-12d3-a456-426614174000",
+			expectError: true,
+		},
+		{
+			name:        "empty string",
+			filename:    "",
+			expectError: true,
+		},
+		{
+			name:        "bad uuid",
+			filename:    "123e4:foo",
+			expectError: true,
+		},
+		{
+			name:        "no tenant",
+			filename:    "123e4567-e89b-12d3-a456-426614174000:",
+			expectError: true,
+		},
+		{
+			name:        "no version",
+			filename:    "123e4567-e89b-12d3-a456-426614174000:test::none",
 			expectError: true,
 		},
 		{
@@ -451,86 +1230,1089 @@ func TestParseFilename(t *testing.T) {
 		},
 	}
 
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			actualUUID, actualTenant, actualVersion, actualEncoding, actualDataEncoding, err := ParseFilename(tc.filename)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actualUUID, actualTenant, actualVersion, actualEncoding, actualDataEncoding, err := ParseFilename(tc.filename)
+
+			if tc.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectUUID, actualUUID)
+			require.Equal(t, tc.expectTenant, actualTenant)
+			require.Equal(t, tc.expectedEncoding, actualEncoding)
+			require.Equal(t, tc.expectedVersion, actualVersion)
+			require.Equal(t, tc.expectedDataEncoding, actualDataEncoding)
+		})
+	}
+}
+
+func BenchmarkWALNone(b *testing.B) {
+	benchmarkWriteFindReplay(b, backend.EncNone)
+}
+func BenchmarkWALSnappy(b *testing.B) {
+	benchmarkWriteFindReplay(b, backend.EncSnappy)
+}
+func BenchmarkWALLZ4(b *testing.B) {
+	benchmarkWriteFindReplay(b, backend.EncLZ4_1M)
+}
+func BenchmarkWALGZIP(b *testing.B) {
+	benchmarkWriteFindReplay(b, backend.EncGZIP)
+}
+func BenchmarkWALZSTD(b *testing.B) {
+	benchmarkWriteFindReplay(b, backend.EncZstd)
+}
+
+func BenchmarkWALS2(b *testing.B) {
+	benchmarkWriteFindReplay(b, backend.EncS2)
+}
+
+func benchmarkWriteFindReplay(b *testing.B, encoding backend.Encoding) {
+	objects := 1000
+	objs := make([][]byte, 0, objects)
+	ids := make([][]byte, 0, objects)
+	for i := 0; i < objects; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		obj := make([]byte, rand.Intn(100)+1)
+		rand.Read(obj)
+		ids = append(ids, id)
+		objs = append(objs, obj)
+	}
+	mockCombiner := &mockCombiner{}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		wal, _ := New(&Config{
+			Filepath: b.TempDir(),
+			Encoding: encoding,
+		})
+
+		blockID := uuid.New()
+		block, err := wal.NewBlock(blockID, testTenantID, "")
+		require.NoError(b, err)
+
+		// write
+		for j, obj := range objs {
+			err := block.Append(ids[j], obj, 0, 0)
+			require.NoError(b, err)
+		}
+
+		// find
+		for _, id := range ids {
+			_, err := block.Find(id, mockCombiner)
+			require.NoError(b, err)
+		}
+
+		// replay
+		_, err = wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+			return 0, 0, nil
+		}, 0, log.NewNopLogger())
+		require.NoError(b, err)
+	}
+}
+
+func TestSegmentedAppendBlock(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath:          tempDir,
+		Encoding:          backend.EncSnappy,
+		ReplayConcurrency: 3,
+		SegmentSizeBytes:  4 * 1024,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	objects := 200
+	objs := make([][]byte, 0, objects)
+	ids := make([][]byte, 0, objects)
+	for i := 0; i < objects; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		obj := test.MakeTrace(rand.Int()%10, id)
+		ids = append(ids, id)
+		bObj, err := proto.Marshal(obj)
+		require.NoError(t, err)
+		objs = append(objs, bObj)
+
+		err = block.Append(id, bObj, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+
+	segments := block.data.allSegments()
+	require.Greater(t, len(segments), 2)
+	for _, s := range segments {
+		_, err := os.Stat(segmentFilename(block.fullFilename(), s.index))
+		require.NoError(t, err)
+	}
+
+	for i, id := range ids {
+		obj, err := block.Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		require.Equal(t, objs[i], obj)
+	}
+
+	// write garbage data at the end of the first segment to confirm the following segments still load
+	appendFile, err := os.OpenFile(block.fullFilename(), os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = appendFile.Write([]byte{0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01})
+	require.NoError(t, err)
+	require.NoError(t, appendFile.Close())
+
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err, "unexpected error getting blocks")
+	require.Len(t, blocks, 1)
+	require.Len(t, blocks[0].data.allSegments(), len(segments))
+	require.Equal(t, objects, blocks[0].appender.Length())
+
+	for i, id := range ids {
+		obj, err := blocks[0].Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		require.Equal(t, objs[i], obj)
+	}
+
+	iterator, err := blocks[0].Iterator(&mockCombiner{})
+	require.NoError(t, err)
+	count := 0
+	for {
+		_, _, err := iterator.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		count++
+	}
+	iterator.Close()
+	require.Equal(t, objects, count)
+
+	// clearing the block removes all segments
+	require.NoError(t, blocks[0].Clear())
+	for _, s := range segments {
+		_, err := os.Stat(segmentFilename(block.fullFilename(), s.index))
+		require.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestMmapReads(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath:         tempDir,
+		Encoding:         backend.EncSnappy,
+		SegmentSizeBytes: 4 * 1024,
+		MmapReads:        true,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	objects := 100
+	objs := make([][]byte, 0, objects)
+	ids := make([][]byte, 0, objects)
+	for i := 0; i < objects; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		obj, err := proto.Marshal(test.MakeTrace(rand.Int()%10, id))
+		require.NoError(t, err)
+		ids = append(ids, id)
+		objs = append(objs, obj)
+
+		require.NoError(t, block.Append(id, obj, 0, 0))
+	}
+	require.Greater(t, len(block.data.allSegments()), 1)
+
+	// an empty block can't be mapped and is replayed as usual
+	_, err = wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err, "unexpected error getting blocks")
+	require.Len(t, blocks, 1)
+	require.Equal(t, objects, blocks[0].appender.Length())
+
+	for i, id := range ids {
+		obj, err := blocks[0].Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		require.Equal(t, objs[i], obj)
+	}
+	mapped := 0
+	for _, f := range blocks[0].data.files {
+		if _, ok := f.(*mmapFile); ok {
+			mapped++
+		}
+	}
+	assert.Greater(t, mapped, 1)
+
+	iterator, err := blocks[0].Iterator(&mockCombiner{})
+	require.NoError(t, err)
+	count := 0
+	for {
+		_, _, err := iterator.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		count++
+	}
+	iterator.Close()
+	require.Equal(t, objects, count)
+
+	require.NoError(t, blocks[0].Clear())
+}
+
+func TestMmapNextPage(t *testing.T) {
+	wal, err := New(&Config{
+		Filepath: t.TempDir(),
+		Encoding: backend.EncNone,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+	ids := make([][]byte, 0, 10)
+	for i := 0; i < 10; i++ {
+		id := test.ValidTraceID(nil)
+		ids = append(ids, id)
+		require.NoError(t, block.Append(id, id, 0, 0))
+	}
+
+	f, err := openFile(OSFileSystem{}, block.fullFilename(), true)
+	require.NoError(t, err)
+	defer f.Close()
+	m, ok := f.(*mmapFile)
+	require.True(t, ok)
+
+	// the pages of uncompressed files are slices of the mapping
+	dataReader, err := newDataReader(f, backend.EncNone, nil)
+	require.NoError(t, err)
+	mapStart := uintptr(unsafe.Pointer(&m.data[0]))
+	mapEnd := mapStart + uintptr(len(m.data))
+
+	var buffer []byte
+	var walked [][]byte
+	objectReader := v2.NewObjectReaderWriter()
+	for {
+		buffer, _, err = dataReader.NextPage(buffer)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		page := uintptr(unsafe.Pointer(&buffer[0]))
+		assert.True(t, page >= mapStart && page < mapEnd)
+		require.NoError(t, walkPage(buffer, objectReader, false, func(id []byte, _ []byte) error {
+			walked = append(walked, append([]byte(nil), id...))
+			return nil
+		}))
+	}
+	assert.Equal(t, ids, walked)
+}
+
+func TestBatchedAppendBlock(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath:           tempDir,
+		Encoding:           backend.EncSnappy,
+		BatchMaxObjects:    7,
+		BatchFlushInterval: time.Hour,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	objects := 50
+	objs := make([][]byte, 0, objects)
+	ids := make([][]byte, 0, objects)
+	for i := 0; i < objects; i++ {
+		id := make([]byte, 16)
+		rand.Read(id)
+		obj := test.MakeTrace(rand.Int()%10, id)
+		ids = append(ids, id)
+		bObj, err := proto.Marshal(obj)
+		require.NoError(t, err)
+		objs = append(objs, bObj)
+
+		err = block.Append(id, bObj, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+
+	// 7 full batches are written, the last object is buffered
+	require.Len(t, block.batch.ids, 1)
+	info, err := os.Stat(block.fullFilename())
+	require.NoError(t, err)
+	require.Greater(t, block.DataLength(), uint64(info.Size()))
+
+	// finding an object writes the batch
+	for i, id := range ids {
+		obj, err := block.Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		require.Equal(t, objs[i], obj)
+	}
+	require.Len(t, block.batch.ids, 0)
+
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err, "unexpected error getting blocks")
+	require.Len(t, blocks, 1)
+	require.Equal(t, objects, blocks[0].appender.Length())
+
+	for i, id := range ids {
+		obj, err := blocks[0].Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		require.Equal(t, objs[i], obj)
+	}
+
+	// the iterator returns every object once and in order
+	iterator, err := blocks[0].Iterator(&mockCombiner{})
+	require.NoError(t, err)
+	var iterated [][]byte
+	for {
+		id, _, err := iterator.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		iterated = append(iterated, id)
+	}
+	iterator.Close()
+	require.Len(t, iterated, objects)
+	for i := 1; i < len(iterated); i++ {
+		require.Equal(t, -1, bytes.Compare(iterated[i-1], iterated[i]))
+	}
+}
+
+func TestBatchFlushInterval(t *testing.T) {
+	_, err := New(&Config{Filepath: t.TempDir(), BatchMaxObjects: 10})
+	require.Error(t, err)
+
+	wal, err := New(&Config{
+		Filepath:           t.TempDir(),
+		BatchMaxBytes:      1024 * 1024,
+		BatchFlushInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+
+	id := test.ValidTraceID(nil)
+	require.NoError(t, block.Append(id, []byte{0x01}, 0, 0))
+	require.NoError(t, block.Append(id, []byte{0x02}, 0, 0))
+
+	// the batch is written on the timer
+	require.Eventually(t, func() bool {
+		info, err := os.Stat(block.fullFilename())
+		return err == nil && info.Size() > 0
+	}, time.Second, 10*time.Millisecond)
+
+	// the objects of an id in a batch share a record and are combined
+	iterator, err := block.Iterator(&mockCombiner{})
+	require.NoError(t, err)
+	defer iterator.Close()
+	count := 0
+	for {
+		_, _, err := iterator.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		count++
+	}
+	require.Equal(t, 1, count)
+}
+
+func TestAppendBlockAppendBatch(t *testing.T) {
+	for _, batchMaxObjects := range []int{0, 10} {
+		wal, err := New(&Config{
+			Filepath:           t.TempDir(),
+			Encoding:           backend.EncSnappy,
+			BatchMaxObjects:    batchMaxObjects,
+			BatchFlushInterval: time.Hour,
+		})
+		require.NoError(t, err, "unexpected error creating temp wal")
+
+		block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+		require.NoError(t, err, "unexpected error creating block")
+
+		// an object appended before the batch
+		first := test.ValidTraceID(nil)
+		require.NoError(t, block.Append(first, []byte{0x01}, 0, 0))
+
+		ids := []common.ID{test.ValidTraceID(nil), test.ValidTraceID(nil), test.ValidTraceID(nil)}
+		objs := [][]byte{{0x02}, {0x03}, {0x04}}
+		err = block.AppendBatch(ids, objs, make([]uint32, 3), make([]uint32, 3))
+		require.NoError(t, err)
+		require.Equal(t, 4, block.Meta().TotalObjects)
+
+		err = block.AppendBatch(ids, objs, nil, nil)
+		require.Error(t, err)
+
+		// only blocks batching records write pages with several objects, their files are marked
+		require.Equal(t, batchMaxObjects > 0, parseBatched(block.fullFilename()))
+		records := block.appender.Records()
+		require.Len(t, records, 4)
+		starts := map[uint64]struct{}{}
+		for _, r := range records {
+			starts[r.Start] = struct{}{}
+		}
+		if batchMaxObjects > 0 {
+			require.Len(t, starts, 2)
+		} else {
+			require.Len(t, starts, 4)
+		}
+
+		blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+			return 0, 0, nil
+		}, 0, log.NewNopLogger())
+		require.NoError(t, err, "unexpected error getting blocks")
+		require.Len(t, blocks, 1)
+		require.Equal(t, 4, blocks[0].appender.Length())
+
+		obj, err := blocks[0].Find(first, &mockCombiner{})
+		require.NoError(t, err)
+		require.Equal(t, []byte{0x01}, obj)
+		for i, id := range ids {
+			obj, err := blocks[0].Find(id, &mockCombiner{})
+			require.NoError(t, err)
+			require.Equal(t, objs[i], obj)
+		}
+
+		// replayed blocks can't be appended to
+		err = blocks[0].AppendBatch(ids, objs, make([]uint32, 3), make([]uint32, 3))
+		require.ErrorIs(t, err, common.ErrUnsupported)
+	}
+}
+
+func TestReplayBatchedRecordsRequireMarker(t *testing.T) {
+	wal, err := New(&Config{
+		Filepath:           t.TempDir(),
+		Encoding:           backend.EncNone,
+		BatchMaxObjects:    10,
+		BatchFlushInterval: time.Hour,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err, "unexpected error creating block")
+	ids := []common.ID{test.ValidTraceID(nil), test.ValidTraceID(nil)}
+	err = block.AppendBatch(ids, [][]byte{{0x01}, {0x02}}, make([]uint32, 2), make([]uint32, 2))
+	require.NoError(t, err)
+	require.Contains(t, block.fullFilename(), dictionarySeparator+batchedMarker)
+
+	f, err := os.Open(block.fullFilename())
+	require.NoError(t, err)
+	defer f.Close()
+
+	records, warning, err := replayWALRecords(f, backend.EncNone, nil, true, func([]byte, []byte) error { return nil })
+	require.NoError(t, err)
+	require.NoError(t, warning)
+	require.Len(t, records, 2)
+
+	// a page with several objects is invalid in a file without the marker
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	_, warning, err = replayWALRecords(f, backend.EncNone, nil, false, func([]byte, []byte) error { return nil })
+	require.NoError(t, err)
+	require.Error(t, warning)
+}
+
+func TestParseBatched(t *testing.T) {
+	id := uuid.New()
+	for name, expected := range map[string]bool{
+		id.String() + ".t.v2.snappy":                 false,
+		id.String() + ".t.v2.snappy+batch":           true,
+		id.String() + ".t.v2.snappy+batch~2":         true,
+		id.String() + ".t.v2.zstd+8000a1b2+batch.v2": true,
+		id.String() + ".t.v2.zstd+8000a1b2.v2":       false,
+		id.String() + ".t.v2.snappy+batch.v2":        true,
+		id.String() + ".t.v2.snappy.v2~3":            false,
+	} {
+		require.Equal(t, expected, parseBatched(name), name)
+		_, _, _, _, _, err := ParseFilename(strings.Split(name, segmentSeparator)[0])
+		require.NoError(t, err, name)
+	}
+
+	dictID, err := parseDictionaryID(id.String() + ".t.v2.zstd+8000a1b2+batch.v2")
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x8000a1b2), dictID)
+	dictID, err = parseDictionaryID(id.String() + ".t.v2.snappy+batch")
+	require.NoError(t, err)
+	require.Zero(t, dictID)
+}
+
+func TestGroupSegmentFiles(t *testing.T) {
+	files := groupSegmentFiles([]string{
+		"a.fake.v2.none~2",
+		"a.fake.v2.none",
+		"b.fake.v2.none",
+		"a.fake.v2.none~10",
+		"a.fake.v2.none~1",
+		"c.fake.v2.none~x",
+	})
+
+	assert.Equal(t, []walFile{
+		{name: "a.fake.v2.none", segments: []int{0, 1, 2, 10}},
+		{name: "b.fake.v2.none", segments: []int{0}},
+		{name: "c.fake.v2.none~x", segments: []int{0}},
+	}, files)
+}
+
+func TestParquetAppendBlock(t *testing.T) {
+	wal, err := New(&Config{
+		Filepath: t.TempDir(),
+		Encoding: backend.EncNone,
+		Version:  vparquet.VersionString,
+	})
+	require.NoError(t, err, "unexpected error creating temp wal")
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, model_v2.Encoding)
+	require.NoError(t, err, "unexpected error creating block")
+	require.Equal(t, vparquet.VersionString, block.Meta().Version)
+	require.True(t, block.SupportsSearch())
+
+	dec := model.MustNewSegmentDecoder(model_v2.Encoding)
+	objects := 100
+	ids := make([][]byte, 0, objects)
+	for i := 0; i < objects; i++ {
+		id := test.ValidTraceID(nil)
+		segment, err := dec.PrepareForWrite(test.MakeTrace(2, id), 0, 0)
+		require.NoError(t, err)
+		obj, err := dec.ToObject([][]byte{segment})
+		require.NoError(t, err)
+		ids = append(ids, id)
+
+		err = block.Append(id, obj, 0, 0)
+		require.NoError(t, err, "unexpected error writing req")
+	}
+	require.NoError(t, block.Flush())
+
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err, "unexpected error getting blocks")
+	require.Len(t, blocks, 1)
+	require.Equal(t, block.BlockID(), blocks[0].BlockID())
+	require.Equal(t, vparquet.VersionString, blocks[0].Meta().Version)
+	require.Equal(t, objects, blocks[0].Meta().TotalObjects)
 
-			if tc.expectError {
-				require.Error(t, err)
-				return
+	for _, id := range ids {
+		obj, err := blocks[0].Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		require.NotNil(t, obj)
+	}
+
+	iterator, err := blocks[0].Iterator(&mockCombiner{})
+	require.NoError(t, err)
+	count := 0
+	for {
+		_, _, err := iterator.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		count++
+	}
+	iterator.Close()
+	require.Equal(t, objects, count)
+
+	// vParquet blocks can't be transformed and are removed instead of replaying the original objects
+	blocks, err = wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger(), func(id common.ID, obj []byte, dataEncoding string) ([]byte, string, error) {
+		return obj, dataEncoding, nil
+	})
+	require.NoError(t, err)
+	require.Len(t, blocks, 0)
+	_, err = os.Stat(block.fullFilename())
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestParquetAppendBlockAppendTraces(t *testing.T) {
+	var replicated [][]byte
+	wal, err := New(&Config{
+		Filepath: t.TempDir(),
+		Encoding: backend.EncNone,
+		Version:  vparquet.VersionString,
+		Replicator: ReplicatorFunc(func(tenantID string, id common.ID, obj []byte) error {
+			replicated = append(replicated, append([]byte(nil), obj...))
+			return nil
+		}),
+	})
+	require.NoError(t, err)
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, model_v2.Encoding)
+	require.NoError(t, err)
+	require.True(t, block.SupportsTraces())
+
+	ids := []common.ID{test.ValidTraceID(nil), test.ValidTraceID(nil)}
+	traces := []*tempopb.Trace{test.MakeTrace(2, ids[0]), test.MakeTrace(2, ids[1])}
+	require.NoError(t, block.AppendTraces(ids, traces, []uint32{10, 20}, []uint32{15, 25}))
+	require.Equal(t, 2, block.Meta().TotalObjects)
+	wal.DrainReplication()
+
+	// the replicated objects are the traces marshalled like appended objects
+	dec := model.MustNewObjectDecoder(model_v2.Encoding)
+	require.Len(t, replicated, 2)
+	for i, obj := range replicated {
+		actual, err := dec.PrepareForRead(obj)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(traces[i], actual))
+	}
+
+	for i, id := range ids {
+		obj, err := block.Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		actual, err := dec.PrepareForRead(obj)
+		require.NoError(t, err)
+		require.Equal(t, len(traces[i].Batches), len(actual.Batches))
+	}
+
+	// v2 blocks only append objects
+	block, err = wal.NewBlockWithVersion(uuid.New(), testTenantID, model_v2.Encoding, v2.VersionString)
+	require.NoError(t, err)
+	require.False(t, block.SupportsTraces())
+	require.ErrorIs(t, block.AppendTraces(ids, traces, []uint32{0, 0}, []uint32{0, 0}), common.ErrUnsupported)
+}
+
+func TestNewBlockWithVersion(t *testing.T) {
+	wal, err := New(&Config{
+		Filepath: t.TempDir(),
+	})
+	require.NoError(t, err)
+
+	block, err := wal.NewBlockWithVersion(uuid.New(), testTenantID, "", v2.VersionString)
+	require.NoError(t, err)
+	require.Equal(t, v2.VersionString, block.Meta().Version)
+	require.False(t, block.SupportsSearch())
+
+	_, err = wal.NewBlockWithVersion(uuid.New(), testTenantID, "", "v3")
+	require.Error(t, err)
+
+	_, err = New(&Config{
+		Filepath: t.TempDir(),
+		Version:  "v3",
+	})
+	require.Error(t, err)
+}
+
+func TestReplicator(t *testing.T) {
+	type replicated struct {
+		tenantID string
+		id       []byte
+		obj      []byte
+	}
+	var records []replicated
+	fail := false
+
+	w, err := New(&Config{
+		Filepath: t.TempDir(),
+		Encoding: backend.EncNone,
+		Replicator: ReplicatorFunc(func(tenantID string, id common.ID, obj []byte) error {
+			if fail {
+				return errors.New("replication failed")
 			}
+			records = append(records, replicated{
+				tenantID: tenantID,
+				id:       append([]byte(nil), id...),
+				obj:      append([]byte(nil), obj...),
+			})
+			return nil
+		}),
+	})
+	require.NoError(t, err)
+
+	dec := model.MustNewSegmentDecoder(model_v2.Encoding)
+	makeObject := func(id []byte) []byte {
+		segment, err := dec.PrepareForWrite(test.MakeTrace(1, id), 0, 0)
+		require.NoError(t, err)
+		obj, err := dec.ToObject([][]byte{segment})
+		require.NoError(t, err)
+		return obj
+	}
+
+	for _, version := range []string{v2.VersionString, vparquet.VersionString} {
+		t.Run(version, func(t *testing.T) {
+			records = records[:0]
+			fail = false
 
+			block, err := w.NewBlockWithVersion(uuid.New(), testTenantID, model_v2.Encoding, version)
 			require.NoError(t, err)
-			require.Equal(t, tc.expectUUID, actualUUID)
-			require.Equal(t, tc.expectTenant, actualTenant)
-			require.Equal(t, tc.expectedEncoding, actualEncoding)
-			require.Equal(t, tc.expectedVersion, actualVersion)
-			require.Equal(t, tc.expectedDataEncoding, actualDataEncoding)
+
+			id := test.ValidTraceID(nil)
+			obj := makeObject(id)
+			require.NoError(t, block.Append(id, obj, 0, 0))
+
+			batchIDs := []common.ID{test.ValidTraceID(nil), test.ValidTraceID(nil)}
+			batchObjs := [][]byte{makeObject(batchIDs[0]), makeObject(batchIDs[1])}
+			require.NoError(t, block.AppendBatch(batchIDs, batchObjs, []uint32{0, 0}, []uint32{0, 0}))
+
+			w.DrainReplication()
+			require.Len(t, records, 3)
+			for i, r := range records {
+				assert.Equal(t, testTenantID, r.tenantID)
+				if i == 0 {
+					assert.Equal(t, id, r.id)
+					assert.Equal(t, obj, r.obj)
+				} else {
+					assert.Equal(t, []byte(batchIDs[i-1]), r.id)
+					assert.Equal(t, batchObjs[i-1], r.obj)
+				}
+			}
+
+			// a failed replication doesn't fail the append
+			w.DrainReplication()
+			fail = true
+			require.NoError(t, block.Append(test.ValidTraceID(nil), obj, 0, 0))
+			assert.Equal(t, 4, block.Meta().TotalObjects)
+			w.DrainReplication()
 		})
 	}
-}
 
-func BenchmarkWALNone(b *testing.B) {
-	benchmarkWriteFindReplay(b, backend.EncNone)
-}
-func BenchmarkWALSnappy(b *testing.B) {
-	benchmarkWriteFindReplay(b, backend.EncSnappy)
+	// batched objects are replicated once the batch is written
+	var batched []common.ID
+	w, err = New(&Config{
+		Filepath:           t.TempDir(),
+		Encoding:           backend.EncNone,
+		BatchMaxObjects:    10,
+		BatchFlushInterval: time.Hour,
+		Replicator: ReplicatorFunc(func(_ string, id common.ID, _ []byte) error {
+			batched = append(batched, id)
+			return nil
+		}),
+	})
+	require.NoError(t, err)
+
+	block, err := w.NewBlock(uuid.New(), testTenantID, model_v2.Encoding)
+	require.NoError(t, err)
+	id := test.ValidTraceID(nil)
+	require.NoError(t, block.Append(id, makeObject(id), 0, 0))
+	w.DrainReplication()
+	assert.Empty(t, batched)
+
+	require.NoError(t, block.Flush())
+	w.DrainReplication()
+	assert.Equal(t, []common.ID{id}, batched)
 }
-func BenchmarkWALLZ4(b *testing.B) {
-	benchmarkWriteFindReplay(b, backend.EncLZ4_1M)
+
+func TestAppendBlockTimeRangeIterator(t *testing.T) {
+	wal, err := New(&Config{
+		Filepath:       t.TempDir(),
+		Encoding:       backend.EncNone,
+		IngestionSlack: 24 * time.Hour,
+	})
+	require.NoError(t, err)
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err)
+
+	// object i started i*10m ago and lasted a minute, the start is stored in the object
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		start := uint32(now.Add(-time.Duration(i) * 10 * time.Minute).Unix())
+		obj := make([]byte, 4)
+		binary.BigEndian.PutUint32(obj, start)
+		require.NoError(t, block.Append(test.ValidTraceID([]byte{byte(i)}), obj, start, start+60))
+	}
+
+	start := uint32(now.Add(-35 * time.Minute).Unix())
+	end := uint32(now.Add(-15 * time.Minute).Unix())
+	expected := [][]byte{test.ValidTraceID([]byte{2}), test.ValidTraceID([]byte{3})}
+
+	ids := func(b *AppendBlock) [][]byte {
+		iter, err := b.TimeRangeIterator(&mockCombiner{}, start, end)
+		require.NoError(t, err)
+		defer iter.Close()
+
+		var ids [][]byte
+		for {
+			id, _, err := iter.Next(context.Background())
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			ids = append(ids, append([]byte(nil), id...))
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, expected, ids(block))
+
+	// the ranges of replayed blocks are read from the objects
+	blocks, err := wal.RescanBlocks(func(obj []byte, _ string) (uint32, uint32, error) {
+		start := binary.BigEndian.Uint32(obj)
+		return start, start + 60, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.ElementsMatch(t, expected, ids(blocks[0]))
 }
-func BenchmarkWALGZIP(b *testing.B) {
-	benchmarkWriteFindReplay(b, backend.EncGZIP)
+
+func TestRescanBlocksCombineDuplicates(t *testing.T) {
+	tempDir := t.TempDir()
+	wal, err := New(&Config{
+		Filepath:         tempDir,
+		Encoding:         backend.EncNone,
+		IngestionSlack:   time.Hour,
+		SegmentSizeBytes: 1024,
+	})
+	require.NoError(t, err)
+
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "")
+	require.NoError(t, err)
+
+	start := uint32(time.Now().Add(-time.Minute).Unix())
+	end := uint32(time.Now().Unix())
+
+	// every id is appended twice, the combiner keeps the larger object
+	ids := make([][]byte, 0, 20)
+	for i := 0; i < 20; i++ {
+		id := test.ValidTraceID(nil)
+		ids = append(ids, id)
+		require.NoError(t, block.Append(id, bytes.Repeat([]byte{0x01}, 100), start, end))
+	}
+	for _, id := range ids {
+		require.NoError(t, block.Append(id, bytes.Repeat([]byte{0x02}, 200), start, end))
+	}
+	require.Greater(t, len(block.data.allSegments()), 1)
+	require.NoError(t, block.Flush())
+
+	wal, err = New(&Config{
+		Filepath:                tempDir,
+		Encoding:                backend.EncNone,
+		IngestionSlack:          time.Hour,
+		ReplayCombineDuplicates: true,
+		ReplayCombiner:          &mockCombiner{},
+	})
+	require.NoError(t, err)
+
+	// the time range is recovered from the sidecar
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+
+	replayed := blocks[0]
+	assert.Equal(t, len(ids), replayed.length())
+	assert.Equal(t, len(ids), replayed.Meta().TotalObjects)
+	assert.Equal(t, start, uint32(replayed.Meta().StartTime.Unix()))
+	assert.Equal(t, end, uint32(replayed.Meta().EndTime.Unix()))
+	for _, id := range ids {
+		obj, err := replayed.Find(id, &mockCombiner{})
+		require.NoError(t, err)
+		assert.Equal(t, bytes.Repeat([]byte{0x02}, 200), obj)
+	}
+
+	// the combined objects replace the segments and the sidecar
+	files, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() {
+			names = append(names, f.Name())
+		}
+	}
+	assert.Equal(t, []string{filepath.Base(replayed.fullFilename())}, names)
 }
-func BenchmarkWALZSTD(b *testing.B) {
-	benchmarkWriteFindReplay(b, backend.EncZstd)
+
+func TestWALMetrics(t *testing.T) {
+	tenantID := "metrics-tenant"
+	wal, err := New(&Config{
+		Filepath: t.TempDir(),
+		Encoding: backend.EncNone,
+	})
+	require.NoError(t, err)
+
+	appendedBefore := testutil.ToFloat64(metricAppendedBytes.WithLabelValues(tenantID))
+
+	block, err := wal.NewBlock(uuid.New(), tenantID, "")
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricBlocksOutstanding.WithLabelValues(tenantID)))
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	require.NoError(t, block.Append(id, []byte("object"), 0, 0))
+	require.NoError(t, block.AppendBatch([]common.ID{id}, [][]byte{[]byte("batch")}, []uint32{0}, []uint32{0}))
+	assert.Equal(t, float64(16+6+16+5), testutil.ToFloat64(metricAppendedBytes.WithLabelValues(tenantID))-appendedBefore)
+
+	// replayed blocks are outstanding until they are cleared
+	blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+		return 0, 0, nil
+	}, 0, log.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, blocks, 1)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metricBlocksOutstanding.WithLabelValues(tenantID)))
+
+	require.NoError(t, blocks[0].Clear())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metricBlocksOutstanding.WithLabelValues(tenantID)))
+	// the replayed block cleared the file, only the count of the original block is left to drop
+	_ = block.Clear()
+	assert.Equal(t, float64(0), testutil.ToFloat64(metricBlocksOutstanding.WithLabelValues(tenantID)))
 }
 
-func BenchmarkWALS2(b *testing.B) {
-	benchmarkWriteFindReplay(b, backend.EncS2)
+func TestTombstones(t *testing.T) {
+	for _, batch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batch=%t", batch), func(t *testing.T) {
+			cfg := &Config{
+				Filepath: t.TempDir(),
+				Encoding: backend.EncNone,
+			}
+			if batch {
+				cfg.BatchMaxObjects = 2
+				cfg.BatchFlushInterval = time.Hour
+			}
+			wal, err := New(cfg)
+			require.NoError(t, err)
+
+			block, err := wal.NewBlock(uuid.New(), testTenantID, "v1")
+			require.NoError(t, err)
+
+			deleted := test.ValidTraceID(nil)
+			kept := test.ValidTraceID(nil)
+			require.NoError(t, block.Append(deleted, []byte{0x01}, 0, 0))
+			require.NoError(t, block.Append(kept, []byte{0x02}, 0, 0))
+			require.NoError(t, block.Delete(deleted))
+			// objects appended after the tombstone are deleted as well
+			require.NoError(t, block.Append(deleted, []byte{0x03}, 0, 0))
+
+			assertTombstoned := func(b *AppendBlock) {
+				obj, err := b.Find(deleted, &mockCombiner{})
+				require.NoError(t, err)
+				assert.Nil(t, obj)
+
+				obj, err = b.Find(kept, &mockCombiner{})
+				require.NoError(t, err)
+				assert.Equal(t, []byte{0x02}, obj)
+
+				iter, err := b.Iterator(&mockCombiner{})
+				require.NoError(t, err)
+				defer iter.Close()
+
+				id, obj, err := iter.Next(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, kept, []byte(id))
+				assert.Equal(t, []byte{0x02}, obj)
+				_, _, err = iter.Next(context.Background())
+				assert.Equal(t, io.EOF, err)
+			}
+			assertTombstoned(block)
+
+			// tombstones are replayed
+			blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+				return 0, 0, nil
+			}, 0, log.NewNopLogger())
+			require.NoError(t, err)
+			require.Len(t, blocks, 1)
+			assertTombstoned(blocks[0])
+
+			// replayed blocks can't be appended to
+			assert.ErrorIs(t, blocks[0].Delete(kept), common.ErrUnsupported)
+		})
+	}
+
+	wal, err := New(&Config{
+		Filepath: t.TempDir(),
+	})
+	require.NoError(t, err)
+	block, err := wal.NewBlockWithVersion(uuid.New(), testTenantID, model_v2.Encoding, vparquet.VersionString)
+	require.NoError(t, err)
+	assert.ErrorIs(t, block.Delete(test.ValidTraceID(nil)), common.ErrUnsupported)
 }
 
-func benchmarkWriteFindReplay(b *testing.B, encoding backend.Encoding) {
-	objects := 1000
-	objs := make([][]byte, 0, objects)
-	ids := make([][]byte, 0, objects)
-	for i := 0; i < objects; i++ {
-		id := make([]byte, 16)
-		rand.Read(id)
-		obj := make([]byte, rand.Intn(100)+1)
-		rand.Read(obj)
-		ids = append(ids, id)
-		objs = append(objs, obj)
+func TestZstdDictionary(t *testing.T) {
+	cfg := &Config{
+		Filepath: t.TempDir(),
+		Encoding: backend.EncZstd,
+		Checksum: true,
+		ZstdDictionary: ZstdDictionaryConfig{
+			Enabled:      true,
+			SampleBytes:  1000,
+			MaxSizeBytes: 4096,
+		},
 	}
-	mockCombiner := &mockCombiner{}
-	b.ResetTimer()
+	wal, err := New(cfg)
+	require.NoError(t, err)
 
-	for i := 0; i < b.N; i++ {
-		wal, _ := New(&Config{
-			Filepath: b.TempDir(),
-			Encoding: encoding,
-		})
+	newObj := func(i int) []byte {
+		return []byte(fmt.Sprintf(`{"service.name":"frontend","http.method":"GET","http.url":"/api/v1/items/%d"}`, i))
+	}
 
-		blockID := uuid.New()
-		block, err := wal.NewBlock(blockID, testTenantID, "")
-		require.NoError(b, err)
+	// the objects of the first block train the dictionary
+	sampled, err := wal.NewBlock(uuid.New(), testTenantID, "v1")
+	require.NoError(t, err)
+	assert.Nil(t, sampled.dictionary)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, sampled.Append(test.ValidTraceID(nil), newObj(i), 0, 0))
+	}
 
-		// write
-		for j, obj := range objs {
-			err := block.Append(ids[j], obj, 0, 0)
-			require.NoError(b, err)
-		}
+	block, err := wal.NewBlock(uuid.New(), testTenantID, "v1")
+	require.NoError(t, err)
+	require.NotNil(t, block.dictionary)
+	assert.Contains(t, block.fullFilename(), dictionarySeparator)
 
-		// find
-		for _, id := range ids {
-			_, err := block.Find(id, mockCombiner)
-			require.NoError(b, err)
+	// other encodings aren't compressed with the dictionary
+	snappy, err := wal.NewBlockWithVersionAndEncoding(uuid.New(), testTenantID, "v1", v2.VersionString, backend.EncSnappy)
+	require.NoError(t, err)
+	assert.Nil(t, snappy.dictionary)
+	require.NoError(t, snappy.Clear())
+
+	ids := make([]common.ID, 0, 10)
+	for i := 0; i < 10; i++ {
+		id := test.ValidTraceID(nil)
+		ids = append(ids, id)
+		require.NoError(t, block.Append(id, newObj(100+i), 0, 0))
+	}
+	assertObjects := func(b *AppendBlock) {
+		for i, id := range ids {
+			obj, err := b.Find(id, &mockCombiner{})
+			require.NoError(t, err)
+			assert.Equal(t, newObj(100+i), obj)
 		}
+	}
+	assertObjects(block)
 
-		// replay
-		_, err = wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
+	_, _, _, enc, dataEncoding, err := ParseFilename(filepath.Base(block.fullFilename()))
+	require.NoError(t, err)
+	assert.Equal(t, backend.EncZstd, enc)
+	assert.Equal(t, "v1", dataEncoding)
+
+	// the dictionary is loaded from the wal folder on replay
+	rescan := func() []*AppendBlock {
+		wal, err := New(cfg)
+		require.NoError(t, err)
+		blocks, err := wal.RescanBlocks(func([]byte, string) (uint32, uint32, error) {
 			return 0, 0, nil
 		}, 0, log.NewNopLogger())
-		require.NoError(b, err)
+		require.NoError(t, err)
+		return blocks
+	}
+	blocks := rescan()
+	require.Len(t, blocks, 2)
+	for _, b := range blocks {
+		if b.BlockID() == block.BlockID() {
+			require.NotNil(t, b.dictionary)
+			assert.Equal(t, block.dictionary.id, b.dictionary.id)
+			assertObjects(b)
+		}
+	}
+
+	// dictionaries are removed once no block is compressed with them
+	dictFile := filepath.Join(cfg.Filepath, dictionariesDir, testTenantID, dictionaryFilename(block.dictionary.id))
+	require.FileExists(t, dictFile)
+	for _, b := range blocks {
+		if b.BlockID() == block.BlockID() {
+			require.NoError(t, b.Clear())
+		}
 	}
+	require.Len(t, rescan(), 1)
+	assert.NoFileExists(t, dictFile)
 }